@@ -31,7 +31,10 @@ type GenerateRequest struct {
 	// Routing Annotations
 	Annotations *JobAnnotations `protobuf:"bytes,3,opt,name=annotations,proto3" json:"annotations,omitempty"`
 	// Generation options
-	Options       *GenerationOptions `protobuf:"bytes,4,opt,name=options,proto3" json:"options,omitempty"`
+	Options *GenerationOptions `protobuf:"bytes,4,opt,name=options,proto3" json:"options,omitempty"`
+	// Text received before an earlier GenerateStream broke; the stream
+	// continues it
+	ResumeFrom    string `protobuf:"bytes,5,opt,name=resume_from,json=resumeFrom,proto3" json:"resume_from,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -94,6 +97,13 @@ func (x *GenerateRequest) GetOptions() *GenerationOptions {
 	return nil
 }
 
+func (x *GenerateRequest) GetResumeFrom() string {
+	if x != nil {
+		return x.ResumeFrom
+	}
+	return ""
+}
+
 // JobAnnotations control routing behavior
 type JobAnnotations struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -378,7 +388,10 @@ type GenerateStreamResponse struct {
 	// Backend used (sent in first message)
 	BackendUsed string `protobuf:"bytes,3,opt,name=backend_used,json=backendUsed,proto3" json:"backend_used,omitempty"`
 	// Stats (sent in final message)
-	Stats         *GenerationStats `protobuf:"bytes,4,opt,name=stats,proto3" json:"stats,omitempty"`
+	Stats *GenerationStats `protobuf:"bytes,4,opt,name=stats,proto3" json:"stats,omitempty"`
+	// Token count and hash of the content (sent in the final message of a
+	// completed stream)
+	Integrity     *StreamIntegrity `protobuf:"bytes,5,opt,name=integrity,proto3" json:"integrity,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -441,6 +454,404 @@ func (x *GenerateStreamResponse) GetStats() *GenerationStats {
 	return nil
 }
 
+func (x *GenerateStreamResponse) GetIntegrity() *StreamIntegrity {
+	if x != nil {
+		return x.Integrity
+	}
+	return nil
+}
+
+// StreamIntegrity lets a client tell a complete stream from one truncated
+// or corrupted by a proxy restart or network reset: a stream whose final
+// message lacks it, or whose content doesn't match it, is incomplete
+type StreamIntegrity struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Tokens sent on this stream
+	Tokens int32 `protobuf:"varint,1,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	// Bytes of content, including text resumed from an earlier stream
+	Bytes int64 `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// Hex SHA-256 of the same content
+	Sha256 string `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	// Bytes of resume_from the content starts with
+	ResumedBytes  int64 `protobuf:"varint,4,opt,name=resumed_bytes,json=resumedBytes,proto3" json:"resumed_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamIntegrity) Reset() {
+	*x = StreamIntegrity{}
+	mi := &file_compute_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamIntegrity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamIntegrity) ProtoMessage() {}
+
+func (x *StreamIntegrity) ProtoReflect() protoreflect.Message {
+	mi := &file_compute_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamIntegrity.ProtoReflect.Descriptor instead.
+func (*StreamIntegrity) Descriptor() ([]byte, []int) {
+	return file_compute_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StreamIntegrity) GetTokens() int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+func (x *StreamIntegrity) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *StreamIntegrity) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *StreamIntegrity) GetResumedBytes() int64 {
+	if x != nil {
+		return x.ResumedBytes
+	}
+	return 0
+}
+
+// ChatRequest is a client message on a Chat stream
+type ChatRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Request:
+	//
+	//	*ChatRequest_Turn
+	//	*ChatRequest_Cancel
+	Request       isChatRequest_Request `protobuf_oneof:"request"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatRequest) Reset() {
+	*x = ChatRequest{}
+	mi := &file_compute_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatRequest) ProtoMessage() {}
+
+func (x *ChatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_compute_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatRequest.ProtoReflect.Descriptor instead.
+func (*ChatRequest) Descriptor() ([]byte, []int) {
+	return file_compute_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ChatRequest) GetRequest() isChatRequest_Request {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetTurn() *ChatTurn {
+	if x != nil {
+		if x, ok := x.Request.(*ChatRequest_Turn); ok {
+			return x.Turn
+		}
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetCancel() *ChatCancel {
+	if x != nil {
+		if x, ok := x.Request.(*ChatRequest_Cancel); ok {
+			return x.Cancel
+		}
+	}
+	return nil
+}
+
+type isChatRequest_Request interface {
+	isChatRequest_Request()
+}
+
+type ChatRequest_Turn struct {
+	// Next message of the conversation
+	Turn *ChatTurn `protobuf:"bytes,1,opt,name=turn,proto3,oneof"`
+}
+
+type ChatRequest_Cancel struct {
+	// Cancel the reply being generated; the conversation continues
+	Cancel *ChatCancel `protobuf:"bytes,2,opt,name=cancel,proto3,oneof"`
+}
+
+func (*ChatRequest_Turn) isChatRequest_Request() {}
+
+func (*ChatRequest_Cancel) isChatRequest_Request() {}
+
+// ChatTurn adds a message to the conversation
+type ChatTurn struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Message text
+	Content string `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	// "user" (default) messages are answered; "system" messages only add
+	// context
+	Role string `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	// Model, required on the first turn; later turns keep it when empty
+	Model string `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	// Routing annotations, read on the first turn
+	Annotations *JobAnnotations `protobuf:"bytes,4,opt,name=annotations,proto3" json:"annotations,omitempty"`
+	// Generation options for this turn's reply
+	Options       *GenerationOptions `protobuf:"bytes,5,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatTurn) Reset() {
+	*x = ChatTurn{}
+	mi := &file_compute_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatTurn) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatTurn) ProtoMessage() {}
+
+func (x *ChatTurn) ProtoReflect() protoreflect.Message {
+	mi := &file_compute_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatTurn.ProtoReflect.Descriptor instead.
+func (*ChatTurn) Descriptor() ([]byte, []int) {
+	return file_compute_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ChatTurn) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ChatTurn) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *ChatTurn) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ChatTurn) GetAnnotations() *JobAnnotations {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+func (x *ChatTurn) GetOptions() *GenerationOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+// ChatCancel stops the reply in progress
+type ChatCancel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatCancel) Reset() {
+	*x = ChatCancel{}
+	mi := &file_compute_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatCancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatCancel) ProtoMessage() {}
+
+func (x *ChatCancel) ProtoReflect() protoreflect.Message {
+	mi := &file_compute_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatCancel.ProtoReflect.Descriptor instead.
+func (*ChatCancel) Descriptor() ([]byte, []int) {
+	return file_compute_proto_rawDescGZIP(), []int{8}
+}
+
+// ChatResponse streams the reply to a turn
+type ChatResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Token or chunk of text
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// Whether this is the final message of the reply
+	Done bool `protobuf:"varint,2,opt,name=done,proto3" json:"done,omitempty"`
+	// Set on the final message when the reply was cancelled
+	Cancelled bool `protobuf:"varint,3,opt,name=cancelled,proto3" json:"cancelled,omitempty"`
+	// Backend generating the reply (sent in its first message)
+	BackendUsed string `protobuf:"bytes,4,opt,name=backend_used,json=backendUsed,proto3" json:"backend_used,omitempty"`
+	// Stats (sent in final message)
+	Stats *GenerationStats `protobuf:"bytes,5,opt,name=stats,proto3" json:"stats,omitempty"`
+	// Number of the user turn this reply answers, starting at 1
+	Turn int32 `protobuf:"varint,6,opt,name=turn,proto3" json:"turn,omitempty"`
+	// Why the reply failed, on the final message; the conversation
+	// continues without the failed turn
+	Error string `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	// Token count and hash of the reply (sent in the final message of a
+	// completed reply)
+	Integrity     *StreamIntegrity `protobuf:"bytes,8,opt,name=integrity,proto3" json:"integrity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatResponse) Reset() {
+	*x = ChatResponse{}
+	mi := &file_compute_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatResponse) ProtoMessage() {}
+
+func (x *ChatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_compute_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatResponse.ProtoReflect.Descriptor instead.
+func (*ChatResponse) Descriptor() ([]byte, []int) {
+	return file_compute_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ChatResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+func (x *ChatResponse) GetCancelled() bool {
+	if x != nil {
+		return x.Cancelled
+	}
+	return false
+}
+
+func (x *ChatResponse) GetBackendUsed() string {
+	if x != nil {
+		return x.BackendUsed
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetStats() *GenerationStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *ChatResponse) GetTurn() int32 {
+	if x != nil {
+		return x.Turn
+	}
+	return 0
+}
+
+func (x *ChatResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetIntegrity() *StreamIntegrity {
+	if x != nil {
+		return x.Integrity
+	}
+	return nil
+}
+
 // RoutingMetadata explains routing decision
 type RoutingMetadata struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -460,7 +871,7 @@ type RoutingMetadata struct {
 
 func (x *RoutingMetadata) Reset() {
 	*x = RoutingMetadata{}
-	mi := &file_compute_proto_msgTypes[5]
+	mi := &file_compute_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -472,7 +883,7 @@ func (x *RoutingMetadata) String() string {
 func (*RoutingMetadata) ProtoMessage() {}
 
 func (x *RoutingMetadata) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[5]
+	mi := &file_compute_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -485,7 +896,7 @@ func (x *RoutingMetadata) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RoutingMetadata.ProtoReflect.Descriptor instead.
 func (*RoutingMetadata) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{5}
+	return file_compute_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *RoutingMetadata) GetBackend() string {
@@ -542,7 +953,7 @@ type GenerationStats struct {
 
 func (x *GenerationStats) Reset() {
 	*x = GenerationStats{}
-	mi := &file_compute_proto_msgTypes[6]
+	mi := &file_compute_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -554,7 +965,7 @@ func (x *GenerationStats) String() string {
 func (*GenerationStats) ProtoMessage() {}
 
 func (x *GenerationStats) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[6]
+	mi := &file_compute_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -567,7 +978,7 @@ func (x *GenerationStats) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GenerationStats.ProtoReflect.Descriptor instead.
 func (*GenerationStats) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{6}
+	return file_compute_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *GenerationStats) GetTimeToFirstTokenMs() int32 {
@@ -620,7 +1031,7 @@ type EmbedRequest struct {
 
 func (x *EmbedRequest) Reset() {
 	*x = EmbedRequest{}
-	mi := &file_compute_proto_msgTypes[7]
+	mi := &file_compute_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -632,7 +1043,7 @@ func (x *EmbedRequest) String() string {
 func (*EmbedRequest) ProtoMessage() {}
 
 func (x *EmbedRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[7]
+	mi := &file_compute_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -645,7 +1056,7 @@ func (x *EmbedRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbedRequest.ProtoReflect.Descriptor instead.
 func (*EmbedRequest) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{7}
+	return file_compute_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *EmbedRequest) GetText() string {
@@ -684,7 +1095,7 @@ type EmbedResponse struct {
 
 func (x *EmbedResponse) Reset() {
 	*x = EmbedResponse{}
-	mi := &file_compute_proto_msgTypes[8]
+	mi := &file_compute_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -696,7 +1107,7 @@ func (x *EmbedResponse) String() string {
 func (*EmbedResponse) ProtoMessage() {}
 
 func (x *EmbedResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[8]
+	mi := &file_compute_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -709,7 +1120,7 @@ func (x *EmbedResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EmbedResponse.ProtoReflect.Descriptor instead.
 func (*EmbedResponse) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{8}
+	return file_compute_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *EmbedResponse) GetEmbedding() []float32 {
@@ -744,7 +1155,7 @@ type ListBackendsRequest struct {
 
 func (x *ListBackendsRequest) Reset() {
 	*x = ListBackendsRequest{}
-	mi := &file_compute_proto_msgTypes[9]
+	mi := &file_compute_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -756,7 +1167,7 @@ func (x *ListBackendsRequest) String() string {
 func (*ListBackendsRequest) ProtoMessage() {}
 
 func (x *ListBackendsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[9]
+	mi := &file_compute_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -769,7 +1180,7 @@ func (x *ListBackendsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListBackendsRequest.ProtoReflect.Descriptor instead.
 func (*ListBackendsRequest) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{9}
+	return file_compute_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *ListBackendsRequest) GetTypeFilter() string {
@@ -789,7 +1200,7 @@ type ListBackendsResponse struct {
 
 func (x *ListBackendsResponse) Reset() {
 	*x = ListBackendsResponse{}
-	mi := &file_compute_proto_msgTypes[10]
+	mi := &file_compute_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -801,7 +1212,7 @@ func (x *ListBackendsResponse) String() string {
 func (*ListBackendsResponse) ProtoMessage() {}
 
 func (x *ListBackendsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[10]
+	mi := &file_compute_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -814,7 +1225,7 @@ func (x *ListBackendsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListBackendsResponse.ProtoReflect.Descriptor instead.
 func (*ListBackendsResponse) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{10}
+	return file_compute_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *ListBackendsResponse) GetBackends() []*BackendInfo {
@@ -847,7 +1258,7 @@ type BackendInfo struct {
 
 func (x *BackendInfo) Reset() {
 	*x = BackendInfo{}
-	mi := &file_compute_proto_msgTypes[11]
+	mi := &file_compute_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -859,7 +1270,7 @@ func (x *BackendInfo) String() string {
 func (*BackendInfo) ProtoMessage() {}
 
 func (x *BackendInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[11]
+	mi := &file_compute_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -872,7 +1283,7 @@ func (x *BackendInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackendInfo.ProtoReflect.Descriptor instead.
 func (*BackendInfo) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{11}
+	return file_compute_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *BackendInfo) GetId() string {
@@ -939,7 +1350,7 @@ type BackendStatus struct {
 
 func (x *BackendStatus) Reset() {
 	*x = BackendStatus{}
-	mi := &file_compute_proto_msgTypes[12]
+	mi := &file_compute_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -951,7 +1362,7 @@ func (x *BackendStatus) String() string {
 func (*BackendStatus) ProtoMessage() {}
 
 func (x *BackendStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[12]
+	mi := &file_compute_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -964,7 +1375,7 @@ func (x *BackendStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackendStatus.ProtoReflect.Descriptor instead.
 func (*BackendStatus) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{12}
+	return file_compute_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *BackendStatus) GetState() string {
@@ -1007,7 +1418,7 @@ type BackendCapabilities struct {
 
 func (x *BackendCapabilities) Reset() {
 	*x = BackendCapabilities{}
-	mi := &file_compute_proto_msgTypes[13]
+	mi := &file_compute_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1019,7 +1430,7 @@ func (x *BackendCapabilities) String() string {
 func (*BackendCapabilities) ProtoMessage() {}
 
 func (x *BackendCapabilities) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[13]
+	mi := &file_compute_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1032,7 +1443,7 @@ func (x *BackendCapabilities) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackendCapabilities.ProtoReflect.Descriptor instead.
 func (*BackendCapabilities) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{13}
+	return file_compute_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *BackendCapabilities) GetGenerate() bool {
@@ -1089,7 +1500,7 @@ type BackendMetrics struct {
 
 func (x *BackendMetrics) Reset() {
 	*x = BackendMetrics{}
-	mi := &file_compute_proto_msgTypes[14]
+	mi := &file_compute_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1101,7 +1512,7 @@ func (x *BackendMetrics) String() string {
 func (*BackendMetrics) ProtoMessage() {}
 
 func (x *BackendMetrics) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[14]
+	mi := &file_compute_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1114,7 +1525,7 @@ func (x *BackendMetrics) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackendMetrics.ProtoReflect.Descriptor instead.
 func (*BackendMetrics) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{14}
+	return file_compute_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *BackendMetrics) GetAvgLatencyMs() int32 {
@@ -1161,7 +1572,7 @@ type HealthCheckRequest struct {
 
 func (x *HealthCheckRequest) Reset() {
 	*x = HealthCheckRequest{}
-	mi := &file_compute_proto_msgTypes[15]
+	mi := &file_compute_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1173,7 +1584,7 @@ func (x *HealthCheckRequest) String() string {
 func (*HealthCheckRequest) ProtoMessage() {}
 
 func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[15]
+	mi := &file_compute_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1186,7 +1597,7 @@ func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthCheckRequest.ProtoReflect.Descriptor instead.
 func (*HealthCheckRequest) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{15}
+	return file_compute_proto_rawDescGZIP(), []int{20}
 }
 
 // HealthCheckResponse
@@ -1204,7 +1615,7 @@ type HealthCheckResponse struct {
 
 func (x *HealthCheckResponse) Reset() {
 	*x = HealthCheckResponse{}
-	mi := &file_compute_proto_msgTypes[16]
+	mi := &file_compute_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1216,7 +1627,7 @@ func (x *HealthCheckResponse) String() string {
 func (*HealthCheckResponse) ProtoMessage() {}
 
 func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[16]
+	mi := &file_compute_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1229,7 +1640,7 @@ func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthCheckResponse.ProtoReflect.Descriptor instead.
 func (*HealthCheckResponse) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{16}
+	return file_compute_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *HealthCheckResponse) GetStatus() string {
@@ -1270,7 +1681,7 @@ type ExecutePipelineRequest struct {
 
 func (x *ExecutePipelineRequest) Reset() {
 	*x = ExecutePipelineRequest{}
-	mi := &file_compute_proto_msgTypes[17]
+	mi := &file_compute_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1282,7 +1693,7 @@ func (x *ExecutePipelineRequest) String() string {
 func (*ExecutePipelineRequest) ProtoMessage() {}
 
 func (x *ExecutePipelineRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[17]
+	mi := &file_compute_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1295,7 +1706,7 @@ func (x *ExecutePipelineRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecutePipelineRequest.ProtoReflect.Descriptor instead.
 func (*ExecutePipelineRequest) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{17}
+	return file_compute_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *ExecutePipelineRequest) GetPipelineId() string {
@@ -1345,7 +1756,7 @@ type PipelineOptions struct {
 
 func (x *PipelineOptions) Reset() {
 	*x = PipelineOptions{}
-	mi := &file_compute_proto_msgTypes[18]
+	mi := &file_compute_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1357,7 +1768,7 @@ func (x *PipelineOptions) String() string {
 func (*PipelineOptions) ProtoMessage() {}
 
 func (x *PipelineOptions) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[18]
+	mi := &file_compute_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1370,7 +1781,7 @@ func (x *PipelineOptions) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PipelineOptions.ProtoReflect.Descriptor instead.
 func (*PipelineOptions) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{18}
+	return file_compute_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *PipelineOptions) GetEnableStreaming() bool {
@@ -1431,7 +1842,7 @@ type ExecutePipelineResponse struct {
 
 func (x *ExecutePipelineResponse) Reset() {
 	*x = ExecutePipelineResponse{}
-	mi := &file_compute_proto_msgTypes[19]
+	mi := &file_compute_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1443,7 +1854,7 @@ func (x *ExecutePipelineResponse) String() string {
 func (*ExecutePipelineResponse) ProtoMessage() {}
 
 func (x *ExecutePipelineResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[19]
+	mi := &file_compute_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1456,7 +1867,7 @@ func (x *ExecutePipelineResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecutePipelineResponse.ProtoReflect.Descriptor instead.
 func (*ExecutePipelineResponse) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{19}
+	return file_compute_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *ExecutePipelineResponse) GetPipelineId() string {
@@ -1529,7 +1940,7 @@ type StageResult struct {
 
 func (x *StageResult) Reset() {
 	*x = StageResult{}
-	mi := &file_compute_proto_msgTypes[20]
+	mi := &file_compute_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1541,7 +1952,7 @@ func (x *StageResult) String() string {
 func (*StageResult) ProtoMessage() {}
 
 func (x *StageResult) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[20]
+	mi := &file_compute_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1554,7 +1965,7 @@ func (x *StageResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StageResult.ProtoReflect.Descriptor instead.
 func (*StageResult) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{20}
+	return file_compute_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *StageResult) GetStageId() string {
@@ -1625,14 +2036,16 @@ type StageMetadata struct {
 	// Forwarding reason
 	ForwardReason string `protobuf:"bytes,11,opt,name=forward_reason,json=forwardReason,proto3" json:"forward_reason,omitempty"`
 	// Attempt count
-	AttemptCount  int32 `protobuf:"varint,12,opt,name=attempt_count,json=attemptCount,proto3" json:"attempt_count,omitempty"`
+	AttemptCount int32 `protobuf:"varint,12,opt,name=attempt_count,json=attemptCount,proto3" json:"attempt_count,omitempty"`
+	// Dependency whose output a best_of merge chose
+	SelectedStage string `protobuf:"bytes,13,opt,name=selected_stage,json=selectedStage,proto3" json:"selected_stage,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *StageMetadata) Reset() {
 	*x = StageMetadata{}
-	mi := &file_compute_proto_msgTypes[21]
+	mi := &file_compute_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1644,7 +2057,7 @@ func (x *StageMetadata) String() string {
 func (*StageMetadata) ProtoMessage() {}
 
 func (x *StageMetadata) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[21]
+	mi := &file_compute_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1657,7 +2070,7 @@ func (x *StageMetadata) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StageMetadata.ProtoReflect.Descriptor instead.
 func (*StageMetadata) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{21}
+	return file_compute_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *StageMetadata) GetStartTimeUnix() int64 {
@@ -1744,6 +2157,13 @@ func (x *StageMetadata) GetAttemptCount() int32 {
 	return 0
 }
 
+func (x *StageMetadata) GetSelectedStage() string {
+	if x != nil {
+		return x.SelectedStage
+	}
+	return ""
+}
+
 // PipelineStreamResponse
 type PipelineStreamResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -1763,7 +2183,7 @@ type PipelineStreamResponse struct {
 
 func (x *PipelineStreamResponse) Reset() {
 	*x = PipelineStreamResponse{}
-	mi := &file_compute_proto_msgTypes[22]
+	mi := &file_compute_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1775,7 +2195,7 @@ func (x *PipelineStreamResponse) String() string {
 func (*PipelineStreamResponse) ProtoMessage() {}
 
 func (x *PipelineStreamResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_compute_proto_msgTypes[22]
+	mi := &file_compute_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1788,7 +2208,7 @@ func (x *PipelineStreamResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PipelineStreamResponse.ProtoReflect.Descriptor instead.
 func (*PipelineStreamResponse) Descriptor() ([]byte, []int) {
-	return file_compute_proto_rawDescGZIP(), []int{22}
+	return file_compute_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *PipelineStreamResponse) GetStageId() string {
@@ -1831,12 +2251,14 @@ var File_compute_proto protoreflect.FileDescriptor
 const file_compute_proto_rawDesc = "" +
 	"\n" +
 	"\rcompute.proto\x12\n" +
-	"compute.v1\"\xb6\x01\n" +
+	"compute.v1\"\xd7\x01\n" +
 	"\x0fGenerateRequest\x12\x16\n" +
 	"\x06prompt\x18\x01 \x01(\tR\x06prompt\x12\x14\n" +
 	"\x05model\x18\x02 \x01(\tR\x05model\x12<\n" +
 	"\vannotations\x18\x03 \x01(\v2\x1a.compute.v1.JobAnnotationsR\vannotations\x127\n" +
-	"\aoptions\x18\x04 \x01(\v2\x1d.compute.v1.GenerationOptionsR\aoptions\"\xf9\x02\n" +
+	"\aoptions\x18\x04 \x01(\v2\x1d.compute.v1.GenerationOptionsR\aoptions\x12\x1f\n" +
+	"\vresume_from\x18\x05 \x01(\tR\n" +
+	"resumeFrom\"\xf9\x02\n" +
 	"\x0eJobAnnotations\x12\x16\n" +
 	"\x06target\x18\x01 \x01(\tR\x06target\x12)\n" +
 	"\x10latency_critical\x18\x02 \x01(\bR\x0flatencyCritical\x126\n" +
@@ -1862,12 +2284,39 @@ const file_compute_proto_rawDesc = "" +
 	"\arouting\x18\x03 \x01(\v2\x1b.compute.v1.RoutingMetadataR\arouting\x121\n" +
 	"\x05stats\x18\x04 \x01(\v2\x1b.compute.v1.GenerationStatsR\x05stats\x12\x1d\n" +
 	"\n" +
-	"from_cache\x18\x05 \x01(\bR\tfromCache\"\x98\x01\n" +
+	"from_cache\x18\x05 \x01(\bR\tfromCache\"\xd3\x01\n" +
 	"\x16GenerateStreamResponse\x12\x14\n" +
 	"\x05token\x18\x01 \x01(\tR\x05token\x12\x12\n" +
 	"\x04done\x18\x02 \x01(\bR\x04done\x12!\n" +
 	"\fbackend_used\x18\x03 \x01(\tR\vbackendUsed\x121\n" +
-	"\x05stats\x18\x04 \x01(\v2\x1b.compute.v1.GenerationStatsR\x05stats\"\xcd\x01\n" +
+	"\x05stats\x18\x04 \x01(\v2\x1b.compute.v1.GenerationStatsR\x05stats\x129\n" +
+	"\tintegrity\x18\x05 \x01(\v2\x1b.compute.v1.StreamIntegrityR\tintegrity\"|\n" +
+	"\x0fStreamIntegrity\x12\x16\n" +
+	"\x06tokens\x18\x01 \x01(\x05R\x06tokens\x12\x14\n" +
+	"\x05bytes\x18\x02 \x01(\x03R\x05bytes\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\tR\x06sha256\x12#\n" +
+	"\rresumed_bytes\x18\x04 \x01(\x03R\fresumedBytes\"v\n" +
+	"\vChatRequest\x12*\n" +
+	"\x04turn\x18\x01 \x01(\v2\x14.compute.v1.ChatTurnH\x00R\x04turn\x120\n" +
+	"\x06cancel\x18\x02 \x01(\v2\x16.compute.v1.ChatCancelH\x00R\x06cancelB\t\n" +
+	"\arequest\"\xc5\x01\n" +
+	"\bChatTurn\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12<\n" +
+	"\vannotations\x18\x04 \x01(\v2\x1a.compute.v1.JobAnnotationsR\vannotations\x127\n" +
+	"\aoptions\x18\x05 \x01(\v2\x1d.compute.v1.GenerationOptionsR\aoptions\"\f\n" +
+	"\n" +
+	"ChatCancel\"\x91\x02\n" +
+	"\fChatResponse\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x12\n" +
+	"\x04done\x18\x02 \x01(\bR\x04done\x12\x1c\n" +
+	"\tcancelled\x18\x03 \x01(\bR\tcancelled\x12!\n" +
+	"\fbackend_used\x18\x04 \x01(\tR\vbackendUsed\x121\n" +
+	"\x05stats\x18\x05 \x01(\v2\x1b.compute.v1.GenerationStatsR\x05stats\x12\x12\n" +
+	"\x04turn\x18\x06 \x01(\x05R\x04turn\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x129\n" +
+	"\tintegrity\x18\b \x01(\v2\x1b.compute.v1.StreamIntegrityR\tintegrity\"\xcd\x01\n" +
 	"\x0fRoutingMetadata\x12\x18\n" +
 	"\abackend\x18\x01 \x01(\tR\abackend\x12\x16\n" +
 	"\x06reason\x18\x02 \x01(\tR\x06reason\x122\n" +
@@ -1962,7 +2411,7 @@ const file_compute_proto_rawDesc = "" +
 	"\asuccess\x18\x03 \x01(\bR\asuccess\x12\x16\n" +
 	"\x06output\x18\x04 \x01(\tR\x06output\x125\n" +
 	"\bmetadata\x18\x05 \x01(\v2\x19.compute.v1.StageMetadataR\bmetadata\x12\x14\n" +
-	"\x05error\x18\x06 \x01(\tR\x05error\"\x97\x03\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\"\xbe\x03\n" +
 	"\rStageMetadata\x12&\n" +
 	"\x0fstart_time_unix\x18\x01 \x01(\x03R\rstartTimeUnix\x12\"\n" +
 	"\rend_time_unix\x18\x02 \x01(\x03R\vendTimeUnix\x12\x1f\n" +
@@ -1980,13 +2429,14 @@ const file_compute_proto_rawDesc = "" +
 	"\tforwarded\x18\n" +
 	" \x01(\bR\tforwarded\x12%\n" +
 	"\x0eforward_reason\x18\v \x01(\tR\rforwardReason\x12#\n" +
-	"\rattempt_count\x18\f \x01(\x05R\fattemptCount\"\xf2\x01\n" +
+	"\rattempt_count\x18\f \x01(\x05R\fattemptCount\x12%\n" +
+	"\x0eselected_stage\x18\r \x01(\tR\rselectedStage\"\xf2\x01\n" +
 	"\x16PipelineStreamResponse\x12\x19\n" +
 	"\bstage_id\x18\x01 \x01(\tR\astageId\x12%\n" +
 	"\x0epartial_output\x18\x02 \x01(\tR\rpartialOutput\x12\x12\n" +
 	"\x04done\x18\x03 \x01(\bR\x04done\x12:\n" +
 	"\fstage_result\x18\x04 \x01(\v2\x17.compute.v1.StageResultR\vstageResult\x12F\n" +
-	"\ffinal_result\x18\x05 \x01(\v2#.compute.v1.ExecutePipelineResponseR\vfinalResult2\xcc\x04\n" +
+	"\ffinal_result\x18\x05 \x01(\v2#.compute.v1.ExecutePipelineResponseR\vfinalResult2\x8b\x05\n" +
 	"\x0eComputeService\x12E\n" +
 	"\bGenerate\x12\x1b.compute.v1.GenerateRequest\x1a\x1c.compute.v1.GenerateResponse\x12S\n" +
 	"\x0eGenerateStream\x12\x1b.compute.v1.GenerateRequest\x1a\".compute.v1.GenerateStreamResponse0\x01\x12<\n" +
@@ -1994,7 +2444,8 @@ const file_compute_proto_rawDesc = "" +
 	"\fListBackends\x12\x1f.compute.v1.ListBackendsRequest\x1a .compute.v1.ListBackendsResponse\x12N\n" +
 	"\vHealthCheck\x12\x1e.compute.v1.HealthCheckRequest\x1a\x1f.compute.v1.HealthCheckResponse\x12Z\n" +
 	"\x0fExecutePipeline\x12\".compute.v1.ExecutePipelineRequest\x1a#.compute.v1.ExecutePipelineResponse\x12a\n" +
-	"\x15ExecutePipelineStream\x12\".compute.v1.ExecutePipelineRequest\x1a\".compute.v1.PipelineStreamResponse0\x01BBZ@github.com/daoneill/ollama-proxy/api/gen/go/compute/v1;computev1b\x06proto3"
+	"\x15ExecutePipelineStream\x12\".compute.v1.ExecutePipelineRequest\x1a\".compute.v1.PipelineStreamResponse0\x01\x12=\n" +
+	"\x04Chat\x12\x17.compute.v1.ChatRequest\x1a\x18.compute.v1.ChatResponse(\x010\x01BBZ@github.com/daoneill/ollama-proxy/api/gen/go/compute/v1;computev1b\x06proto3"
 
 var (
 	file_compute_proto_rawDescOnce sync.Once
@@ -2008,77 +2459,91 @@ func file_compute_proto_rawDescGZIP() []byte {
 	return file_compute_proto_rawDescData
 }
 
-var file_compute_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
+var file_compute_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
 var file_compute_proto_goTypes = []any{
 	(*GenerateRequest)(nil),         // 0: compute.v1.GenerateRequest
 	(*JobAnnotations)(nil),          // 1: compute.v1.JobAnnotations
 	(*GenerationOptions)(nil),       // 2: compute.v1.GenerationOptions
 	(*GenerateResponse)(nil),        // 3: compute.v1.GenerateResponse
 	(*GenerateStreamResponse)(nil),  // 4: compute.v1.GenerateStreamResponse
-	(*RoutingMetadata)(nil),         // 5: compute.v1.RoutingMetadata
-	(*GenerationStats)(nil),         // 6: compute.v1.GenerationStats
-	(*EmbedRequest)(nil),            // 7: compute.v1.EmbedRequest
-	(*EmbedResponse)(nil),           // 8: compute.v1.EmbedResponse
-	(*ListBackendsRequest)(nil),     // 9: compute.v1.ListBackendsRequest
-	(*ListBackendsResponse)(nil),    // 10: compute.v1.ListBackendsResponse
-	(*BackendInfo)(nil),             // 11: compute.v1.BackendInfo
-	(*BackendStatus)(nil),           // 12: compute.v1.BackendStatus
-	(*BackendCapabilities)(nil),     // 13: compute.v1.BackendCapabilities
-	(*BackendMetrics)(nil),          // 14: compute.v1.BackendMetrics
-	(*HealthCheckRequest)(nil),      // 15: compute.v1.HealthCheckRequest
-	(*HealthCheckResponse)(nil),     // 16: compute.v1.HealthCheckResponse
-	(*ExecutePipelineRequest)(nil),  // 17: compute.v1.ExecutePipelineRequest
-	(*PipelineOptions)(nil),         // 18: compute.v1.PipelineOptions
-	(*ExecutePipelineResponse)(nil), // 19: compute.v1.ExecutePipelineResponse
-	(*StageResult)(nil),             // 20: compute.v1.StageResult
-	(*StageMetadata)(nil),           // 21: compute.v1.StageMetadata
-	(*PipelineStreamResponse)(nil),  // 22: compute.v1.PipelineStreamResponse
-	nil,                             // 23: compute.v1.JobAnnotations.CustomEntry
-	nil,                             // 24: compute.v1.HealthCheckResponse.BackendHealthEntry
-	nil,                             // 25: compute.v1.ExecutePipelineRequest.InputEntry
-	nil,                             // 26: compute.v1.ExecutePipelineResponse.FinalOutputEntry
+	(*StreamIntegrity)(nil),         // 5: compute.v1.StreamIntegrity
+	(*ChatRequest)(nil),             // 6: compute.v1.ChatRequest
+	(*ChatTurn)(nil),                // 7: compute.v1.ChatTurn
+	(*ChatCancel)(nil),              // 8: compute.v1.ChatCancel
+	(*ChatResponse)(nil),            // 9: compute.v1.ChatResponse
+	(*RoutingMetadata)(nil),         // 10: compute.v1.RoutingMetadata
+	(*GenerationStats)(nil),         // 11: compute.v1.GenerationStats
+	(*EmbedRequest)(nil),            // 12: compute.v1.EmbedRequest
+	(*EmbedResponse)(nil),           // 13: compute.v1.EmbedResponse
+	(*ListBackendsRequest)(nil),     // 14: compute.v1.ListBackendsRequest
+	(*ListBackendsResponse)(nil),    // 15: compute.v1.ListBackendsResponse
+	(*BackendInfo)(nil),             // 16: compute.v1.BackendInfo
+	(*BackendStatus)(nil),           // 17: compute.v1.BackendStatus
+	(*BackendCapabilities)(nil),     // 18: compute.v1.BackendCapabilities
+	(*BackendMetrics)(nil),          // 19: compute.v1.BackendMetrics
+	(*HealthCheckRequest)(nil),      // 20: compute.v1.HealthCheckRequest
+	(*HealthCheckResponse)(nil),     // 21: compute.v1.HealthCheckResponse
+	(*ExecutePipelineRequest)(nil),  // 22: compute.v1.ExecutePipelineRequest
+	(*PipelineOptions)(nil),         // 23: compute.v1.PipelineOptions
+	(*ExecutePipelineResponse)(nil), // 24: compute.v1.ExecutePipelineResponse
+	(*StageResult)(nil),             // 25: compute.v1.StageResult
+	(*StageMetadata)(nil),           // 26: compute.v1.StageMetadata
+	(*PipelineStreamResponse)(nil),  // 27: compute.v1.PipelineStreamResponse
+	nil,                             // 28: compute.v1.JobAnnotations.CustomEntry
+	nil,                             // 29: compute.v1.HealthCheckResponse.BackendHealthEntry
+	nil,                             // 30: compute.v1.ExecutePipelineRequest.InputEntry
+	nil,                             // 31: compute.v1.ExecutePipelineResponse.FinalOutputEntry
 }
 var file_compute_proto_depIdxs = []int32{
 	1,  // 0: compute.v1.GenerateRequest.annotations:type_name -> compute.v1.JobAnnotations
 	2,  // 1: compute.v1.GenerateRequest.options:type_name -> compute.v1.GenerationOptions
-	23, // 2: compute.v1.JobAnnotations.custom:type_name -> compute.v1.JobAnnotations.CustomEntry
-	5,  // 3: compute.v1.GenerateResponse.routing:type_name -> compute.v1.RoutingMetadata
-	6,  // 4: compute.v1.GenerateResponse.stats:type_name -> compute.v1.GenerationStats
-	6,  // 5: compute.v1.GenerateStreamResponse.stats:type_name -> compute.v1.GenerationStats
-	1,  // 6: compute.v1.EmbedRequest.annotations:type_name -> compute.v1.JobAnnotations
-	5,  // 7: compute.v1.EmbedResponse.routing:type_name -> compute.v1.RoutingMetadata
-	11, // 8: compute.v1.ListBackendsResponse.backends:type_name -> compute.v1.BackendInfo
-	12, // 9: compute.v1.BackendInfo.status:type_name -> compute.v1.BackendStatus
-	13, // 10: compute.v1.BackendInfo.capabilities:type_name -> compute.v1.BackendCapabilities
-	14, // 11: compute.v1.BackendInfo.metrics:type_name -> compute.v1.BackendMetrics
-	24, // 12: compute.v1.HealthCheckResponse.backend_health:type_name -> compute.v1.HealthCheckResponse.BackendHealthEntry
-	25, // 13: compute.v1.ExecutePipelineRequest.input:type_name -> compute.v1.ExecutePipelineRequest.InputEntry
-	18, // 14: compute.v1.ExecutePipelineRequest.options:type_name -> compute.v1.PipelineOptions
-	1,  // 15: compute.v1.ExecutePipelineRequest.annotations:type_name -> compute.v1.JobAnnotations
-	26, // 16: compute.v1.ExecutePipelineResponse.final_output:type_name -> compute.v1.ExecutePipelineResponse.FinalOutputEntry
-	20, // 17: compute.v1.ExecutePipelineResponse.stage_results:type_name -> compute.v1.StageResult
-	21, // 18: compute.v1.StageResult.metadata:type_name -> compute.v1.StageMetadata
-	20, // 19: compute.v1.PipelineStreamResponse.stage_result:type_name -> compute.v1.StageResult
-	19, // 20: compute.v1.PipelineStreamResponse.final_result:type_name -> compute.v1.ExecutePipelineResponse
-	0,  // 21: compute.v1.ComputeService.Generate:input_type -> compute.v1.GenerateRequest
-	0,  // 22: compute.v1.ComputeService.GenerateStream:input_type -> compute.v1.GenerateRequest
-	7,  // 23: compute.v1.ComputeService.Embed:input_type -> compute.v1.EmbedRequest
-	9,  // 24: compute.v1.ComputeService.ListBackends:input_type -> compute.v1.ListBackendsRequest
-	15, // 25: compute.v1.ComputeService.HealthCheck:input_type -> compute.v1.HealthCheckRequest
-	17, // 26: compute.v1.ComputeService.ExecutePipeline:input_type -> compute.v1.ExecutePipelineRequest
-	17, // 27: compute.v1.ComputeService.ExecutePipelineStream:input_type -> compute.v1.ExecutePipelineRequest
-	3,  // 28: compute.v1.ComputeService.Generate:output_type -> compute.v1.GenerateResponse
-	4,  // 29: compute.v1.ComputeService.GenerateStream:output_type -> compute.v1.GenerateStreamResponse
-	8,  // 30: compute.v1.ComputeService.Embed:output_type -> compute.v1.EmbedResponse
-	10, // 31: compute.v1.ComputeService.ListBackends:output_type -> compute.v1.ListBackendsResponse
-	16, // 32: compute.v1.ComputeService.HealthCheck:output_type -> compute.v1.HealthCheckResponse
-	19, // 33: compute.v1.ComputeService.ExecutePipeline:output_type -> compute.v1.ExecutePipelineResponse
-	22, // 34: compute.v1.ComputeService.ExecutePipelineStream:output_type -> compute.v1.PipelineStreamResponse
-	28, // [28:35] is the sub-list for method output_type
-	21, // [21:28] is the sub-list for method input_type
-	21, // [21:21] is the sub-list for extension type_name
-	21, // [21:21] is the sub-list for extension extendee
-	0,  // [0:21] is the sub-list for field type_name
+	28, // 2: compute.v1.JobAnnotations.custom:type_name -> compute.v1.JobAnnotations.CustomEntry
+	10, // 3: compute.v1.GenerateResponse.routing:type_name -> compute.v1.RoutingMetadata
+	11, // 4: compute.v1.GenerateResponse.stats:type_name -> compute.v1.GenerationStats
+	11, // 5: compute.v1.GenerateStreamResponse.stats:type_name -> compute.v1.GenerationStats
+	5,  // 6: compute.v1.GenerateStreamResponse.integrity:type_name -> compute.v1.StreamIntegrity
+	7,  // 7: compute.v1.ChatRequest.turn:type_name -> compute.v1.ChatTurn
+	8,  // 8: compute.v1.ChatRequest.cancel:type_name -> compute.v1.ChatCancel
+	1,  // 9: compute.v1.ChatTurn.annotations:type_name -> compute.v1.JobAnnotations
+	2,  // 10: compute.v1.ChatTurn.options:type_name -> compute.v1.GenerationOptions
+	11, // 11: compute.v1.ChatResponse.stats:type_name -> compute.v1.GenerationStats
+	5,  // 12: compute.v1.ChatResponse.integrity:type_name -> compute.v1.StreamIntegrity
+	1,  // 13: compute.v1.EmbedRequest.annotations:type_name -> compute.v1.JobAnnotations
+	10, // 14: compute.v1.EmbedResponse.routing:type_name -> compute.v1.RoutingMetadata
+	16, // 15: compute.v1.ListBackendsResponse.backends:type_name -> compute.v1.BackendInfo
+	17, // 16: compute.v1.BackendInfo.status:type_name -> compute.v1.BackendStatus
+	18, // 17: compute.v1.BackendInfo.capabilities:type_name -> compute.v1.BackendCapabilities
+	19, // 18: compute.v1.BackendInfo.metrics:type_name -> compute.v1.BackendMetrics
+	29, // 19: compute.v1.HealthCheckResponse.backend_health:type_name -> compute.v1.HealthCheckResponse.BackendHealthEntry
+	30, // 20: compute.v1.ExecutePipelineRequest.input:type_name -> compute.v1.ExecutePipelineRequest.InputEntry
+	23, // 21: compute.v1.ExecutePipelineRequest.options:type_name -> compute.v1.PipelineOptions
+	1,  // 22: compute.v1.ExecutePipelineRequest.annotations:type_name -> compute.v1.JobAnnotations
+	31, // 23: compute.v1.ExecutePipelineResponse.final_output:type_name -> compute.v1.ExecutePipelineResponse.FinalOutputEntry
+	25, // 24: compute.v1.ExecutePipelineResponse.stage_results:type_name -> compute.v1.StageResult
+	26, // 25: compute.v1.StageResult.metadata:type_name -> compute.v1.StageMetadata
+	25, // 26: compute.v1.PipelineStreamResponse.stage_result:type_name -> compute.v1.StageResult
+	24, // 27: compute.v1.PipelineStreamResponse.final_result:type_name -> compute.v1.ExecutePipelineResponse
+	0,  // 28: compute.v1.ComputeService.Generate:input_type -> compute.v1.GenerateRequest
+	0,  // 29: compute.v1.ComputeService.GenerateStream:input_type -> compute.v1.GenerateRequest
+	12, // 30: compute.v1.ComputeService.Embed:input_type -> compute.v1.EmbedRequest
+	14, // 31: compute.v1.ComputeService.ListBackends:input_type -> compute.v1.ListBackendsRequest
+	20, // 32: compute.v1.ComputeService.HealthCheck:input_type -> compute.v1.HealthCheckRequest
+	22, // 33: compute.v1.ComputeService.ExecutePipeline:input_type -> compute.v1.ExecutePipelineRequest
+	22, // 34: compute.v1.ComputeService.ExecutePipelineStream:input_type -> compute.v1.ExecutePipelineRequest
+	6,  // 35: compute.v1.ComputeService.Chat:input_type -> compute.v1.ChatRequest
+	3,  // 36: compute.v1.ComputeService.Generate:output_type -> compute.v1.GenerateResponse
+	4,  // 37: compute.v1.ComputeService.GenerateStream:output_type -> compute.v1.GenerateStreamResponse
+	13, // 38: compute.v1.ComputeService.Embed:output_type -> compute.v1.EmbedResponse
+	15, // 39: compute.v1.ComputeService.ListBackends:output_type -> compute.v1.ListBackendsResponse
+	21, // 40: compute.v1.ComputeService.HealthCheck:output_type -> compute.v1.HealthCheckResponse
+	24, // 41: compute.v1.ComputeService.ExecutePipeline:output_type -> compute.v1.ExecutePipelineResponse
+	27, // 42: compute.v1.ComputeService.ExecutePipelineStream:output_type -> compute.v1.PipelineStreamResponse
+	9,  // 43: compute.v1.ComputeService.Chat:output_type -> compute.v1.ChatResponse
+	36, // [36:44] is the sub-list for method output_type
+	28, // [28:36] is the sub-list for method input_type
+	28, // [28:28] is the sub-list for extension type_name
+	28, // [28:28] is the sub-list for extension extendee
+	0,  // [0:28] is the sub-list for field type_name
 }
 
 func init() { file_compute_proto_init() }
@@ -2086,13 +2551,17 @@ func file_compute_proto_init() {
 	if File_compute_proto != nil {
 		return
 	}
+	file_compute_proto_msgTypes[6].OneofWrappers = []any{
+		(*ChatRequest_Turn)(nil),
+		(*ChatRequest_Cancel)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_compute_proto_rawDesc), len(file_compute_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   27,
+			NumMessages:   32,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
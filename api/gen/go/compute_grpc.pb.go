@@ -26,6 +26,7 @@ const (
 	ComputeService_HealthCheck_FullMethodName           = "/compute.v1.ComputeService/HealthCheck"
 	ComputeService_ExecutePipeline_FullMethodName       = "/compute.v1.ComputeService/ExecutePipeline"
 	ComputeService_ExecutePipelineStream_FullMethodName = "/compute.v1.ComputeService/ExecutePipelineStream"
+	ComputeService_Chat_FullMethodName                  = "/compute.v1.ComputeService/Chat"
 )
 
 // ComputeServiceClient is the client API for ComputeService service.
@@ -48,6 +49,10 @@ type ComputeServiceClient interface {
 	ExecutePipeline(ctx context.Context, in *ExecutePipelineRequest, opts ...grpc.CallOption) (*ExecutePipelineResponse, error)
 	// ExecutePipelineStream executes a pipeline with streaming output
 	ExecutePipelineStream(ctx context.Context, in *ExecutePipelineRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PipelineStreamResponse], error)
+	// Chat holds a conversation on one stream: the client sends turns and
+	// cancellations, and the server streams each reply with the conversation
+	// so far as context, from the backend that served its earlier turns
+	Chat(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ChatRequest, ChatResponse], error)
 }
 
 type computeServiceClient struct {
@@ -146,6 +151,19 @@ func (c *computeServiceClient) ExecutePipelineStream(ctx context.Context, in *Ex
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ComputeService_ExecutePipelineStreamClient = grpc.ServerStreamingClient[PipelineStreamResponse]
 
+func (c *computeServiceClient) Chat(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ChatRequest, ChatResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ComputeService_ServiceDesc.Streams[2], ComputeService_Chat_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ChatRequest, ChatResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ComputeService_ChatClient = grpc.BidiStreamingClient[ChatRequest, ChatResponse]
+
 // ComputeServiceServer is the server API for ComputeService service.
 // All implementations must embed UnimplementedComputeServiceServer
 // for forward compatibility.
@@ -166,6 +184,10 @@ type ComputeServiceServer interface {
 	ExecutePipeline(context.Context, *ExecutePipelineRequest) (*ExecutePipelineResponse, error)
 	// ExecutePipelineStream executes a pipeline with streaming output
 	ExecutePipelineStream(*ExecutePipelineRequest, grpc.ServerStreamingServer[PipelineStreamResponse]) error
+	// Chat holds a conversation on one stream: the client sends turns and
+	// cancellations, and the server streams each reply with the conversation
+	// so far as context, from the backend that served its earlier turns
+	Chat(grpc.BidiStreamingServer[ChatRequest, ChatResponse]) error
 	mustEmbedUnimplementedComputeServiceServer()
 }
 
@@ -197,6 +219,9 @@ func (UnimplementedComputeServiceServer) ExecutePipeline(context.Context, *Execu
 func (UnimplementedComputeServiceServer) ExecutePipelineStream(*ExecutePipelineRequest, grpc.ServerStreamingServer[PipelineStreamResponse]) error {
 	return status.Error(codes.Unimplemented, "method ExecutePipelineStream not implemented")
 }
+func (UnimplementedComputeServiceServer) Chat(grpc.BidiStreamingServer[ChatRequest, ChatResponse]) error {
+	return status.Error(codes.Unimplemented, "method Chat not implemented")
+}
 func (UnimplementedComputeServiceServer) mustEmbedUnimplementedComputeServiceServer() {}
 func (UnimplementedComputeServiceServer) testEmbeddedByValue()                        {}
 
@@ -330,6 +355,13 @@ func _ComputeService_ExecutePipelineStream_Handler(srv interface{}, stream grpc.
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ComputeService_ExecutePipelineStreamServer = grpc.ServerStreamingServer[PipelineStreamResponse]
 
+func _ComputeService_Chat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ComputeServiceServer).Chat(&grpc.GenericServerStream[ChatRequest, ChatResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ComputeService_ChatServer = grpc.BidiStreamingServer[ChatRequest, ChatResponse]
+
 // ComputeService_ServiceDesc is the grpc.ServiceDesc for ComputeService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -369,6 +401,12 @@ var ComputeService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ComputeService_ExecutePipelineStream_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Chat",
+			Handler:       _ComputeService_Chat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "compute.proto",
 }
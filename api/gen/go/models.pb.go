@@ -0,0 +1,574 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v3.19.6
+// source: models.proto
+
+package computev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListModelsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Backend ID; empty lists every backend that manages models
+	BackendId     string `protobuf:"bytes,1,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsRequest) Reset() {
+	*x = ListModelsRequest{}
+	mi := &file_models_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsRequest) ProtoMessage() {}
+
+func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_models_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsRequest.ProtoReflect.Descriptor instead.
+func (*ListModelsRequest) Descriptor() ([]byte, []int) {
+	return file_models_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListModelsRequest) GetBackendId() string {
+	if x != nil {
+		return x.BackendId
+	}
+	return ""
+}
+
+type ListModelsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Backends      []*BackendModels       `protobuf:"bytes,1,rep,name=backends,proto3" json:"backends,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsResponse) Reset() {
+	*x = ListModelsResponse{}
+	mi := &file_models_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsResponse) ProtoMessage() {}
+
+func (x *ListModelsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_models_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsResponse.ProtoReflect.Descriptor instead.
+func (*ListModelsResponse) Descriptor() ([]byte, []int) {
+	return file_models_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListModelsResponse) GetBackends() []*BackendModels {
+	if x != nil {
+		return x.Backends
+	}
+	return nil
+}
+
+// Models installed on one backend
+type BackendModels struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	BackendId string                 `protobuf:"bytes,1,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
+	Models    []*InstalledModel      `protobuf:"bytes,2,rep,name=models,proto3" json:"models,omitempty"`
+	// Set when the backend could not be reached
+	Error         string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackendModels) Reset() {
+	*x = BackendModels{}
+	mi := &file_models_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackendModels) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackendModels) ProtoMessage() {}
+
+func (x *BackendModels) ProtoReflect() protoreflect.Message {
+	mi := &file_models_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackendModels.ProtoReflect.Descriptor instead.
+func (*BackendModels) Descriptor() ([]byte, []int) {
+	return file_models_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BackendModels) GetBackendId() string {
+	if x != nil {
+		return x.BackendId
+	}
+	return ""
+}
+
+func (x *BackendModels) GetModels() []*InstalledModel {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+func (x *BackendModels) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type InstalledModel struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	SizeBytes      int64                  `protobuf:"varint,2,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	Digest         string                 `protobuf:"bytes,3,opt,name=digest,proto3" json:"digest,omitempty"`
+	ModifiedAtUnix int64                  `protobuf:"varint,4,opt,name=modified_at_unix,json=modifiedAtUnix,proto3" json:"modified_at_unix,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *InstalledModel) Reset() {
+	*x = InstalledModel{}
+	mi := &file_models_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InstalledModel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstalledModel) ProtoMessage() {}
+
+func (x *InstalledModel) ProtoReflect() protoreflect.Message {
+	mi := &file_models_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstalledModel.ProtoReflect.Descriptor instead.
+func (*InstalledModel) Descriptor() ([]byte, []int) {
+	return file_models_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *InstalledModel) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *InstalledModel) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *InstalledModel) GetDigest() string {
+	if x != nil {
+		return x.Digest
+	}
+	return ""
+}
+
+func (x *InstalledModel) GetModifiedAtUnix() int64 {
+	if x != nil {
+		return x.ModifiedAtUnix
+	}
+	return 0
+}
+
+type PullModelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BackendId     string                 `protobuf:"bytes,1,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullModelRequest) Reset() {
+	*x = PullModelRequest{}
+	mi := &file_models_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullModelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullModelRequest) ProtoMessage() {}
+
+func (x *PullModelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_models_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullModelRequest.ProtoReflect.Descriptor instead.
+func (*PullModelRequest) Descriptor() ([]byte, []int) {
+	return file_models_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PullModelRequest) GetBackendId() string {
+	if x != nil {
+		return x.BackendId
+	}
+	return ""
+}
+
+func (x *PullModelRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+// Download progress of a pull. The last message has done set; a failed
+// pull ends the stream with an error status instead.
+type PullModelProgress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`        // backend status, e.g. "pulling 6a0746a1ec1a"
+	Completed     int64                  `protobuf:"varint,2,opt,name=completed,proto3" json:"completed,omitempty"` // bytes
+	Total         int64                  `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	Percent       float32                `protobuf:"fixed32,4,opt,name=percent,proto3" json:"percent,omitempty"`
+	EtaMs         int64                  `protobuf:"varint,5,opt,name=eta_ms,json=etaMs,proto3" json:"eta_ms,omitempty"` // 0 if unknown
+	Done          bool                   `protobuf:"varint,6,opt,name=done,proto3" json:"done,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullModelProgress) Reset() {
+	*x = PullModelProgress{}
+	mi := &file_models_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullModelProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullModelProgress) ProtoMessage() {}
+
+func (x *PullModelProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_models_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullModelProgress.ProtoReflect.Descriptor instead.
+func (*PullModelProgress) Descriptor() ([]byte, []int) {
+	return file_models_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PullModelProgress) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *PullModelProgress) GetCompleted() int64 {
+	if x != nil {
+		return x.Completed
+	}
+	return 0
+}
+
+func (x *PullModelProgress) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *PullModelProgress) GetPercent() float32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *PullModelProgress) GetEtaMs() int64 {
+	if x != nil {
+		return x.EtaMs
+	}
+	return 0
+}
+
+func (x *PullModelProgress) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+type DeleteModelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BackendId     string                 `protobuf:"bytes,1,opt,name=backend_id,json=backendId,proto3" json:"backend_id,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteModelRequest) Reset() {
+	*x = DeleteModelRequest{}
+	mi := &file_models_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteModelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteModelRequest) ProtoMessage() {}
+
+func (x *DeleteModelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_models_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteModelRequest.ProtoReflect.Descriptor instead.
+func (*DeleteModelRequest) Descriptor() ([]byte, []int) {
+	return file_models_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteModelRequest) GetBackendId() string {
+	if x != nil {
+		return x.BackendId
+	}
+	return ""
+}
+
+func (x *DeleteModelRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+type DeleteModelResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteModelResponse) Reset() {
+	*x = DeleteModelResponse{}
+	mi := &file_models_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteModelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteModelResponse) ProtoMessage() {}
+
+func (x *DeleteModelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_models_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteModelResponse.ProtoReflect.Descriptor instead.
+func (*DeleteModelResponse) Descriptor() ([]byte, []int) {
+	return file_models_proto_rawDescGZIP(), []int{7}
+}
+
+var File_models_proto protoreflect.FileDescriptor
+
+const file_models_proto_rawDesc = "" +
+	"\n" +
+	"\fmodels.proto\x12\n" +
+	"compute.v1\"2\n" +
+	"\x11ListModelsRequest\x12\x1d\n" +
+	"\n" +
+	"backend_id\x18\x01 \x01(\tR\tbackendId\"K\n" +
+	"\x12ListModelsResponse\x125\n" +
+	"\bbackends\x18\x01 \x03(\v2\x19.compute.v1.BackendModelsR\bbackends\"x\n" +
+	"\rBackendModels\x12\x1d\n" +
+	"\n" +
+	"backend_id\x18\x01 \x01(\tR\tbackendId\x122\n" +
+	"\x06models\x18\x02 \x03(\v2\x1a.compute.v1.InstalledModelR\x06models\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\x85\x01\n" +
+	"\x0eInstalledModel\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x02 \x01(\x03R\tsizeBytes\x12\x16\n" +
+	"\x06digest\x18\x03 \x01(\tR\x06digest\x12(\n" +
+	"\x10modified_at_unix\x18\x04 \x01(\x03R\x0emodifiedAtUnix\"G\n" +
+	"\x10PullModelRequest\x12\x1d\n" +
+	"\n" +
+	"backend_id\x18\x01 \x01(\tR\tbackendId\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\"\xa4\x01\n" +
+	"\x11PullModelProgress\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1c\n" +
+	"\tcompleted\x18\x02 \x01(\x03R\tcompleted\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\x03R\x05total\x12\x18\n" +
+	"\apercent\x18\x04 \x01(\x02R\apercent\x12\x15\n" +
+	"\x06eta_ms\x18\x05 \x01(\x03R\x05etaMs\x12\x12\n" +
+	"\x04done\x18\x06 \x01(\bR\x04done\"I\n" +
+	"\x12DeleteModelRequest\x12\x1d\n" +
+	"\n" +
+	"backend_id\x18\x01 \x01(\tR\tbackendId\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\"\x15\n" +
+	"\x13DeleteModelResponse2\xf7\x01\n" +
+	"\fModelService\x12K\n" +
+	"\n" +
+	"ListModels\x12\x1d.compute.v1.ListModelsRequest\x1a\x1e.compute.v1.ListModelsResponse\x12J\n" +
+	"\tPullModel\x12\x1c.compute.v1.PullModelRequest\x1a\x1d.compute.v1.PullModelProgress0\x01\x12N\n" +
+	"\vDeleteModel\x12\x1e.compute.v1.DeleteModelRequest\x1a\x1f.compute.v1.DeleteModelResponseBBZ@github.com/daoneill/ollama-proxy/api/gen/go/compute/v1;computev1b\x06proto3"
+
+var (
+	file_models_proto_rawDescOnce sync.Once
+	file_models_proto_rawDescData []byte
+)
+
+func file_models_proto_rawDescGZIP() []byte {
+	file_models_proto_rawDescOnce.Do(func() {
+		file_models_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_models_proto_rawDesc), len(file_models_proto_rawDesc)))
+	})
+	return file_models_proto_rawDescData
+}
+
+var file_models_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_models_proto_goTypes = []any{
+	(*ListModelsRequest)(nil),   // 0: compute.v1.ListModelsRequest
+	(*ListModelsResponse)(nil),  // 1: compute.v1.ListModelsResponse
+	(*BackendModels)(nil),       // 2: compute.v1.BackendModels
+	(*InstalledModel)(nil),      // 3: compute.v1.InstalledModel
+	(*PullModelRequest)(nil),    // 4: compute.v1.PullModelRequest
+	(*PullModelProgress)(nil),   // 5: compute.v1.PullModelProgress
+	(*DeleteModelRequest)(nil),  // 6: compute.v1.DeleteModelRequest
+	(*DeleteModelResponse)(nil), // 7: compute.v1.DeleteModelResponse
+}
+var file_models_proto_depIdxs = []int32{
+	2, // 0: compute.v1.ListModelsResponse.backends:type_name -> compute.v1.BackendModels
+	3, // 1: compute.v1.BackendModels.models:type_name -> compute.v1.InstalledModel
+	0, // 2: compute.v1.ModelService.ListModels:input_type -> compute.v1.ListModelsRequest
+	4, // 3: compute.v1.ModelService.PullModel:input_type -> compute.v1.PullModelRequest
+	6, // 4: compute.v1.ModelService.DeleteModel:input_type -> compute.v1.DeleteModelRequest
+	1, // 5: compute.v1.ModelService.ListModels:output_type -> compute.v1.ListModelsResponse
+	5, // 6: compute.v1.ModelService.PullModel:output_type -> compute.v1.PullModelProgress
+	7, // 7: compute.v1.ModelService.DeleteModel:output_type -> compute.v1.DeleteModelResponse
+	5, // [5:8] is the sub-list for method output_type
+	2, // [2:5] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_models_proto_init() }
+func file_models_proto_init() {
+	if File_models_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_models_proto_rawDesc), len(file_models_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_models_proto_goTypes,
+		DependencyIndexes: file_models_proto_depIdxs,
+		MessageInfos:      file_models_proto_msgTypes,
+	}.Build()
+	File_models_proto = out.File
+	file_models_proto_goTypes = nil
+	file_models_proto_depIdxs = nil
+}
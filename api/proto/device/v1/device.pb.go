@@ -32,6 +32,8 @@ const (
 	DeviceType_DEVICE_TYPE_SPEAKER     DeviceType = 4
 	DeviceType_DEVICE_TYPE_KEYBOARD    DeviceType = 5
 	DeviceType_DEVICE_TYPE_MOUSE       DeviceType = 6
+	DeviceType_DEVICE_TYPE_NPU         DeviceType = 7
+	DeviceType_DEVICE_TYPE_GPU         DeviceType = 8
 )
 
 // Enum value maps for DeviceType.
@@ -44,6 +46,8 @@ var (
 		4: "DEVICE_TYPE_SPEAKER",
 		5: "DEVICE_TYPE_KEYBOARD",
 		6: "DEVICE_TYPE_MOUSE",
+		7: "DEVICE_TYPE_NPU",
+		8: "DEVICE_TYPE_GPU",
 	}
 	DeviceType_value = map[string]int32{
 		"DEVICE_TYPE_UNSPECIFIED": 0,
@@ -53,6 +57,8 @@ var (
 		"DEVICE_TYPE_SPEAKER":     4,
 		"DEVICE_TYPE_KEYBOARD":    5,
 		"DEVICE_TYPE_MOUSE":       6,
+		"DEVICE_TYPE_NPU":         7,
+		"DEVICE_TYPE_GPU":         8,
 	}
 )
 
@@ -265,6 +271,11 @@ type Device struct {
 	Capabilities  map[string]string      `protobuf:"bytes,6,rep,name=capabilities,proto3" json:"capabilities,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	RegisteredAt  int64                  `protobuf:"varint,7,opt,name=registered_at,json=registeredAt,proto3" json:"registered_at,omitempty"` // Unix timestamp in nanoseconds
 	LastUsedAt    int64                  `protobuf:"varint,8,opt,name=last_used_at,json=lastUsedAt,proto3" json:"last_used_at,omitempty"`     // Unix timestamp in nanoseconds
+	InventoryKey  string                 `protobuf:"bytes,9,opt,name=inventory_key,json=inventoryKey,proto3" json:"inventory_key,omitempty"`  // Stable across restarts, unlike id
+	FriendlyName  string                 `protobuf:"bytes,10,opt,name=friendly_name,json=friendlyName,proto3" json:"friendly_name,omitempty"` // User-assigned, e.g. "eGPU dock RTX 4070"
+	Notes         string                 `protobuf:"bytes,11,opt,name=notes,proto3" json:"notes,omitempty"`
+	Hardware      string                 `protobuf:"bytes,12,opt,name=hardware,proto3" json:"hardware,omitempty"`       // Accelerators: routing hardware class, e.g. "npu"
+	Utilization   *DeviceUtilization     `protobuf:"bytes,13,opt,name=utilization,proto3" json:"utilization,omitempty"` // Accelerators: latest sample
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -355,6 +366,118 @@ func (x *Device) GetLastUsedAt() int64 {
 	return 0
 }
 
+func (x *Device) GetInventoryKey() string {
+	if x != nil {
+		return x.InventoryKey
+	}
+	return ""
+}
+
+func (x *Device) GetFriendlyName() string {
+	if x != nil {
+		return x.FriendlyName
+	}
+	return ""
+}
+
+func (x *Device) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
+func (x *Device) GetHardware() string {
+	if x != nil {
+		return x.Hardware
+	}
+	return ""
+}
+
+func (x *Device) GetUtilization() *DeviceUtilization {
+	if x != nil {
+		return x.Utilization
+	}
+	return nil
+}
+
+// Utilization of an accelerator over the last sampling interval
+type DeviceUtilization struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Percent          float64                `protobuf:"fixed64,1,opt,name=percent,proto3" json:"percent,omitempty"`
+	MemoryUsedBytes  uint64                 `protobuf:"varint,2,opt,name=memory_used_bytes,json=memoryUsedBytes,proto3" json:"memory_used_bytes,omitempty"`    // 0 when not reported
+	MemoryTotalBytes uint64                 `protobuf:"varint,3,opt,name=memory_total_bytes,json=memoryTotalBytes,proto3" json:"memory_total_bytes,omitempty"` // 0 when not reported
+	Source           string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`                                                // nvml, level-zero or sysfs
+	SampledAt        int64                  `protobuf:"varint,5,opt,name=sampled_at,json=sampledAt,proto3" json:"sampled_at,omitempty"`                        // Unix timestamp in nanoseconds
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *DeviceUtilization) Reset() {
+	*x = DeviceUtilization{}
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceUtilization) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceUtilization) ProtoMessage() {}
+
+func (x *DeviceUtilization) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceUtilization.ProtoReflect.Descriptor instead.
+func (*DeviceUtilization) Descriptor() ([]byte, []int) {
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DeviceUtilization) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *DeviceUtilization) GetMemoryUsedBytes() uint64 {
+	if x != nil {
+		return x.MemoryUsedBytes
+	}
+	return 0
+}
+
+func (x *DeviceUtilization) GetMemoryTotalBytes() uint64 {
+	if x != nil {
+		return x.MemoryTotalBytes
+	}
+	return 0
+}
+
+func (x *DeviceUtilization) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *DeviceUtilization) GetSampledAt() int64 {
+	if x != nil {
+		return x.SampledAt
+	}
+	return 0
+}
+
 // RegisterDevice request
 type RegisterDeviceRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -368,7 +491,7 @@ type RegisterDeviceRequest struct {
 
 func (x *RegisterDeviceRequest) Reset() {
 	*x = RegisterDeviceRequest{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[1]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -380,7 +503,7 @@ func (x *RegisterDeviceRequest) String() string {
 func (*RegisterDeviceRequest) ProtoMessage() {}
 
 func (x *RegisterDeviceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[1]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -393,7 +516,7 @@ func (x *RegisterDeviceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterDeviceRequest.ProtoReflect.Descriptor instead.
 func (*RegisterDeviceRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{1}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *RegisterDeviceRequest) GetType() DeviceType {
@@ -434,7 +557,7 @@ type RegisterDeviceResponse struct {
 
 func (x *RegisterDeviceResponse) Reset() {
 	*x = RegisterDeviceResponse{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[2]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -446,7 +569,7 @@ func (x *RegisterDeviceResponse) String() string {
 func (*RegisterDeviceResponse) ProtoMessage() {}
 
 func (x *RegisterDeviceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[2]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -459,7 +582,7 @@ func (x *RegisterDeviceResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterDeviceResponse.ProtoReflect.Descriptor instead.
 func (*RegisterDeviceResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{2}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *RegisterDeviceResponse) GetDeviceId() string {
@@ -479,7 +602,7 @@ type UnregisterDeviceRequest struct {
 
 func (x *UnregisterDeviceRequest) Reset() {
 	*x = UnregisterDeviceRequest{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[3]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -491,7 +614,7 @@ func (x *UnregisterDeviceRequest) String() string {
 func (*UnregisterDeviceRequest) ProtoMessage() {}
 
 func (x *UnregisterDeviceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[3]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -504,7 +627,7 @@ func (x *UnregisterDeviceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UnregisterDeviceRequest.ProtoReflect.Descriptor instead.
 func (*UnregisterDeviceRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{3}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *UnregisterDeviceRequest) GetDeviceId() string {
@@ -524,7 +647,7 @@ type UnregisterDeviceResponse struct {
 
 func (x *UnregisterDeviceResponse) Reset() {
 	*x = UnregisterDeviceResponse{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[4]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -536,7 +659,7 @@ func (x *UnregisterDeviceResponse) String() string {
 func (*UnregisterDeviceResponse) ProtoMessage() {}
 
 func (x *UnregisterDeviceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[4]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -549,7 +672,7 @@ func (x *UnregisterDeviceResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UnregisterDeviceResponse.ProtoReflect.Descriptor instead.
 func (*UnregisterDeviceResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{4}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *UnregisterDeviceResponse) GetSuccess() bool {
@@ -570,7 +693,7 @@ type ListDevicesRequest struct {
 
 func (x *ListDevicesRequest) Reset() {
 	*x = ListDevicesRequest{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[5]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -582,7 +705,7 @@ func (x *ListDevicesRequest) String() string {
 func (*ListDevicesRequest) ProtoMessage() {}
 
 func (x *ListDevicesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[5]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -595,7 +718,7 @@ func (x *ListDevicesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListDevicesRequest.ProtoReflect.Descriptor instead.
 func (*ListDevicesRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{5}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ListDevicesRequest) GetFilterType() DeviceType {
@@ -615,7 +738,7 @@ type ListDevicesResponse struct {
 
 func (x *ListDevicesResponse) Reset() {
 	*x = ListDevicesResponse{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[6]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -627,7 +750,7 @@ func (x *ListDevicesResponse) String() string {
 func (*ListDevicesResponse) ProtoMessage() {}
 
 func (x *ListDevicesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[6]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -640,7 +763,7 @@ func (x *ListDevicesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListDevicesResponse.ProtoReflect.Descriptor instead.
 func (*ListDevicesResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{6}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ListDevicesResponse) GetDevices() []*Device {
@@ -660,7 +783,7 @@ type GetDeviceRequest struct {
 
 func (x *GetDeviceRequest) Reset() {
 	*x = GetDeviceRequest{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[7]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -672,7 +795,7 @@ func (x *GetDeviceRequest) String() string {
 func (*GetDeviceRequest) ProtoMessage() {}
 
 func (x *GetDeviceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[7]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -685,7 +808,7 @@ func (x *GetDeviceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetDeviceRequest.ProtoReflect.Descriptor instead.
 func (*GetDeviceRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{7}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *GetDeviceRequest) GetDeviceId() string {
@@ -705,7 +828,7 @@ type GetDeviceResponse struct {
 
 func (x *GetDeviceResponse) Reset() {
 	*x = GetDeviceResponse{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[8]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -717,7 +840,7 @@ func (x *GetDeviceResponse) String() string {
 func (*GetDeviceResponse) ProtoMessage() {}
 
 func (x *GetDeviceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[8]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -730,7 +853,7 @@ func (x *GetDeviceResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetDeviceResponse.ProtoReflect.Descriptor instead.
 func (*GetDeviceResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{8}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *GetDeviceResponse) GetDevice() *Device {
@@ -740,6 +863,397 @@ func (x *GetDeviceResponse) GetDevice() *Device {
 	return nil
 }
 
+// RenameDevice request
+type RenameDeviceRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// A registered device's ID, or the inventory key of one not attached
+	DeviceId string `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	// Empty restores the discovered name
+	FriendlyName  string `protobuf:"bytes,2,opt,name=friendly_name,json=friendlyName,proto3" json:"friendly_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameDeviceRequest) Reset() {
+	*x = RenameDeviceRequest{}
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameDeviceRequest) ProtoMessage() {}
+
+func (x *RenameDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameDeviceRequest.ProtoReflect.Descriptor instead.
+func (*RenameDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RenameDeviceRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *RenameDeviceRequest) GetFriendlyName() string {
+	if x != nil {
+		return x.FriendlyName
+	}
+	return ""
+}
+
+// RenameDevice response
+type RenameDeviceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entry         *InventoryEntry        `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameDeviceResponse) Reset() {
+	*x = RenameDeviceResponse{}
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameDeviceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameDeviceResponse) ProtoMessage() {}
+
+func (x *RenameDeviceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameDeviceResponse.ProtoReflect.Descriptor instead.
+func (*RenameDeviceResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RenameDeviceResponse) GetEntry() *InventoryEntry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+// AnnotateDevice request
+type AnnotateDeviceRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// As for RenameDeviceRequest
+	DeviceId      string `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Notes         string `protobuf:"bytes,2,opt,name=notes,proto3" json:"notes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnnotateDeviceRequest) Reset() {
+	*x = AnnotateDeviceRequest{}
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnnotateDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnnotateDeviceRequest) ProtoMessage() {}
+
+func (x *AnnotateDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnnotateDeviceRequest.ProtoReflect.Descriptor instead.
+func (*AnnotateDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AnnotateDeviceRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *AnnotateDeviceRequest) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
+// AnnotateDevice response
+type AnnotateDeviceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entry         *InventoryEntry        `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnnotateDeviceResponse) Reset() {
+	*x = AnnotateDeviceResponse{}
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnnotateDeviceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnnotateDeviceResponse) ProtoMessage() {}
+
+func (x *AnnotateDeviceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnnotateDeviceResponse.ProtoReflect.Descriptor instead.
+func (*AnnotateDeviceResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *AnnotateDeviceResponse) GetEntry() *InventoryEntry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+// ListInventory request
+type ListInventoryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Optional filter by device type (empty = all types)
+	FilterType    DeviceType `protobuf:"varint,1,opt,name=filter_type,json=filterType,proto3,enum=device.v1.DeviceType" json:"filter_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListInventoryRequest) Reset() {
+	*x = ListInventoryRequest{}
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListInventoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListInventoryRequest) ProtoMessage() {}
+
+func (x *ListInventoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListInventoryRequest.ProtoReflect.Descriptor instead.
+func (*ListInventoryRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListInventoryRequest) GetFilterType() DeviceType {
+	if x != nil {
+		return x.FilterType
+	}
+	return DeviceType_DEVICE_TYPE_UNSPECIFIED
+}
+
+// ListInventory response
+type ListInventoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*InventoryEntry      `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListInventoryResponse) Reset() {
+	*x = ListInventoryResponse{}
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListInventoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListInventoryResponse) ProtoMessage() {}
+
+func (x *ListInventoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListInventoryResponse.ProtoReflect.Descriptor instead.
+func (*ListInventoryResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListInventoryResponse) GetEntries() []*InventoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// A device remembered across restarts
+type InventoryEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Type          DeviceType             `protobuf:"varint,2,opt,name=type,proto3,enum=device.v1.DeviceType" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"` // Name reported at discovery
+	Path          string                 `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`
+	FriendlyName  string                 `protobuf:"bytes,5,opt,name=friendly_name,json=friendlyName,proto3" json:"friendly_name,omitempty"`
+	Notes         string                 `protobuf:"bytes,6,opt,name=notes,proto3" json:"notes,omitempty"`
+	FirstSeen     int64                  `protobuf:"varint,7,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"` // Unix timestamp in nanoseconds
+	LastSeen      int64                  `protobuf:"varint,8,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`    // Unix timestamp in nanoseconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InventoryEntry) Reset() {
+	*x = InventoryEntry{}
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InventoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InventoryEntry) ProtoMessage() {}
+
+func (x *InventoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InventoryEntry.ProtoReflect.Descriptor instead.
+func (*InventoryEntry) Descriptor() ([]byte, []int) {
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *InventoryEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *InventoryEntry) GetType() DeviceType {
+	if x != nil {
+		return x.Type
+	}
+	return DeviceType_DEVICE_TYPE_UNSPECIFIED
+}
+
+func (x *InventoryEntry) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *InventoryEntry) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *InventoryEntry) GetFriendlyName() string {
+	if x != nil {
+		return x.FriendlyName
+	}
+	return ""
+}
+
+func (x *InventoryEntry) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
+func (x *InventoryEntry) GetFirstSeen() int64 {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return 0
+}
+
+func (x *InventoryEntry) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
 // RequestDeviceAccess request
 type RequestDeviceAccessRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -751,7 +1265,7 @@ type RequestDeviceAccessRequest struct {
 
 func (x *RequestDeviceAccessRequest) Reset() {
 	*x = RequestDeviceAccessRequest{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[9]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -763,7 +1277,7 @@ func (x *RequestDeviceAccessRequest) String() string {
 func (*RequestDeviceAccessRequest) ProtoMessage() {}
 
 func (x *RequestDeviceAccessRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[9]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -776,7 +1290,7 @@ func (x *RequestDeviceAccessRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestDeviceAccessRequest.ProtoReflect.Descriptor instead.
 func (*RequestDeviceAccessRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{9}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *RequestDeviceAccessRequest) GetDeviceId() string {
@@ -805,7 +1319,7 @@ type RequestDeviceAccessResponse struct {
 
 func (x *RequestDeviceAccessResponse) Reset() {
 	*x = RequestDeviceAccessResponse{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[10]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -817,7 +1331,7 @@ func (x *RequestDeviceAccessResponse) String() string {
 func (*RequestDeviceAccessResponse) ProtoMessage() {}
 
 func (x *RequestDeviceAccessResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[10]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -830,7 +1344,7 @@ func (x *RequestDeviceAccessResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RequestDeviceAccessResponse.ProtoReflect.Descriptor instead.
 func (*RequestDeviceAccessResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{10}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *RequestDeviceAccessResponse) GetGrantId() string {
@@ -865,7 +1379,7 @@ type ReleaseDeviceAccessRequest struct {
 
 func (x *ReleaseDeviceAccessRequest) Reset() {
 	*x = ReleaseDeviceAccessRequest{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[11]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -877,7 +1391,7 @@ func (x *ReleaseDeviceAccessRequest) String() string {
 func (*ReleaseDeviceAccessRequest) ProtoMessage() {}
 
 func (x *ReleaseDeviceAccessRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[11]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -890,7 +1404,7 @@ func (x *ReleaseDeviceAccessRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReleaseDeviceAccessRequest.ProtoReflect.Descriptor instead.
 func (*ReleaseDeviceAccessRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{11}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *ReleaseDeviceAccessRequest) GetDeviceId() string {
@@ -917,7 +1431,7 @@ type ReleaseDeviceAccessResponse struct {
 
 func (x *ReleaseDeviceAccessResponse) Reset() {
 	*x = ReleaseDeviceAccessResponse{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[12]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -929,7 +1443,7 @@ func (x *ReleaseDeviceAccessResponse) String() string {
 func (*ReleaseDeviceAccessResponse) ProtoMessage() {}
 
 func (x *ReleaseDeviceAccessResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[12]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -942,7 +1456,7 @@ func (x *ReleaseDeviceAccessResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReleaseDeviceAccessResponse.ProtoReflect.Descriptor instead.
 func (*ReleaseDeviceAccessResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{12}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *ReleaseDeviceAccessResponse) GetSuccess() bool {
@@ -965,7 +1479,7 @@ type SubscribeToDeviceRequest struct {
 
 func (x *SubscribeToDeviceRequest) Reset() {
 	*x = SubscribeToDeviceRequest{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[13]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -977,7 +1491,7 @@ func (x *SubscribeToDeviceRequest) String() string {
 func (*SubscribeToDeviceRequest) ProtoMessage() {}
 
 func (x *SubscribeToDeviceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[13]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -990,7 +1504,7 @@ func (x *SubscribeToDeviceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SubscribeToDeviceRequest.ProtoReflect.Descriptor instead.
 func (*SubscribeToDeviceRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{13}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *SubscribeToDeviceRequest) GetDeviceId() string {
@@ -1033,7 +1547,7 @@ type StreamConfig struct {
 
 func (x *StreamConfig) Reset() {
 	*x = StreamConfig{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[14]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1045,7 +1559,7 @@ func (x *StreamConfig) String() string {
 func (*StreamConfig) ProtoMessage() {}
 
 func (x *StreamConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[14]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1058,7 +1572,7 @@ func (x *StreamConfig) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamConfig.ProtoReflect.Descriptor instead.
 func (*StreamConfig) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{14}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *StreamConfig) GetFormat() string {
@@ -1117,7 +1631,7 @@ type DeviceDataFrame struct {
 
 func (x *DeviceDataFrame) Reset() {
 	*x = DeviceDataFrame{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[15]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1129,7 +1643,7 @@ func (x *DeviceDataFrame) String() string {
 func (*DeviceDataFrame) ProtoMessage() {}
 
 func (x *DeviceDataFrame) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[15]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1142,7 +1656,7 @@ func (x *DeviceDataFrame) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeviceDataFrame.ProtoReflect.Descriptor instead.
 func (*DeviceDataFrame) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{15}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *DeviceDataFrame) GetSequence() uint64 {
@@ -1200,7 +1714,7 @@ type DeviceCommand struct {
 
 func (x *DeviceCommand) Reset() {
 	*x = DeviceCommand{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[16]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1212,7 +1726,7 @@ func (x *DeviceCommand) String() string {
 func (*DeviceCommand) ProtoMessage() {}
 
 func (x *DeviceCommand) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[16]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1225,7 +1739,7 @@ func (x *DeviceCommand) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeviceCommand.ProtoReflect.Descriptor instead.
 func (*DeviceCommand) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{16}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *DeviceCommand) GetType() CommandType {
@@ -1253,7 +1767,7 @@ type WatchDevicesRequest struct {
 
 func (x *WatchDevicesRequest) Reset() {
 	*x = WatchDevicesRequest{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[17]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1265,7 +1779,7 @@ func (x *WatchDevicesRequest) String() string {
 func (*WatchDevicesRequest) ProtoMessage() {}
 
 func (x *WatchDevicesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[17]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1278,7 +1792,7 @@ func (x *WatchDevicesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WatchDevicesRequest.ProtoReflect.Descriptor instead.
 func (*WatchDevicesRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{17}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *WatchDevicesRequest) GetFilterType() DeviceType {
@@ -1305,7 +1819,7 @@ type DeviceEvent struct {
 
 func (x *DeviceEvent) Reset() {
 	*x = DeviceEvent{}
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[18]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1317,7 +1831,7 @@ func (x *DeviceEvent) String() string {
 func (*DeviceEvent) ProtoMessage() {}
 
 func (x *DeviceEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_device_v1_device_proto_msgTypes[18]
+	mi := &file_api_proto_device_v1_device_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1330,7 +1844,7 @@ func (x *DeviceEvent) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeviceEvent.ProtoReflect.Descriptor instead.
 func (*DeviceEvent) Descriptor() ([]byte, []int) {
-	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{18}
+	return file_api_proto_device_v1_device_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *DeviceEvent) GetType() EventType {
@@ -1365,7 +1879,7 @@ var File_api_proto_device_v1_device_proto protoreflect.FileDescriptor
 
 const file_api_proto_device_v1_device_proto_rawDesc = "" +
 	"\n" +
-	" api/proto/device/v1/device.proto\x12\tdevice.v1\"\xea\x02\n" +
+	" api/proto/device/v1/device.proto\x12\tdevice.v1\"\xa6\x04\n" +
 	"\x06Device\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12)\n" +
 	"\x04type\x18\x02 \x01(\x0e2\x15.device.v1.DeviceTypeR\x04type\x12\x12\n" +
@@ -1375,10 +1889,23 @@ const file_api_proto_device_v1_device_proto_rawDesc = "" +
 	"\fcapabilities\x18\x06 \x03(\v2#.device.v1.Device.CapabilitiesEntryR\fcapabilities\x12#\n" +
 	"\rregistered_at\x18\a \x01(\x03R\fregisteredAt\x12 \n" +
 	"\flast_used_at\x18\b \x01(\x03R\n" +
-	"lastUsedAt\x1a?\n" +
+	"lastUsedAt\x12#\n" +
+	"\rinventory_key\x18\t \x01(\tR\finventoryKey\x12#\n" +
+	"\rfriendly_name\x18\n" +
+	" \x01(\tR\ffriendlyName\x12\x14\n" +
+	"\x05notes\x18\v \x01(\tR\x05notes\x12\x1a\n" +
+	"\bhardware\x18\f \x01(\tR\bhardware\x12>\n" +
+	"\vutilization\x18\r \x01(\v2\x1c.device.v1.DeviceUtilizationR\vutilization\x1a?\n" +
 	"\x11CapabilitiesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x83\x02\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xbe\x01\n" +
+	"\x11DeviceUtilization\x12\x18\n" +
+	"\apercent\x18\x01 \x01(\x01R\apercent\x12*\n" +
+	"\x11memory_used_bytes\x18\x02 \x01(\x04R\x0fmemoryUsedBytes\x12,\n" +
+	"\x12memory_total_bytes\x18\x03 \x01(\x04R\x10memoryTotalBytes\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\x12\x1d\n" +
+	"\n" +
+	"sampled_at\x18\x05 \x01(\x03R\tsampledAt\"\x83\x02\n" +
 	"\x15RegisterDeviceRequest\x12)\n" +
 	"\x04type\x18\x01 \x01(\x0e2\x15.device.v1.DeviceTypeR\x04type\x12\x12\n" +
 	"\x04path\x18\x02 \x01(\tR\x04path\x12\x12\n" +
@@ -1401,7 +1928,32 @@ const file_api_proto_device_v1_device_proto_rawDesc = "" +
 	"\x10GetDeviceRequest\x12\x1b\n" +
 	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\">\n" +
 	"\x11GetDeviceResponse\x12)\n" +
-	"\x06device\x18\x01 \x01(\v2\x11.device.v1.DeviceR\x06device\"V\n" +
+	"\x06device\x18\x01 \x01(\v2\x11.device.v1.DeviceR\x06device\"W\n" +
+	"\x13RenameDeviceRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12#\n" +
+	"\rfriendly_name\x18\x02 \x01(\tR\ffriendlyName\"G\n" +
+	"\x14RenameDeviceResponse\x12/\n" +
+	"\x05entry\x18\x01 \x01(\v2\x19.device.v1.InventoryEntryR\x05entry\"J\n" +
+	"\x15AnnotateDeviceRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x14\n" +
+	"\x05notes\x18\x02 \x01(\tR\x05notes\"I\n" +
+	"\x16AnnotateDeviceResponse\x12/\n" +
+	"\x05entry\x18\x01 \x01(\v2\x19.device.v1.InventoryEntryR\x05entry\"N\n" +
+	"\x14ListInventoryRequest\x126\n" +
+	"\vfilter_type\x18\x01 \x01(\x0e2\x15.device.v1.DeviceTypeR\n" +
+	"filterType\"L\n" +
+	"\x15ListInventoryResponse\x123\n" +
+	"\aentries\x18\x01 \x03(\v2\x19.device.v1.InventoryEntryR\aentries\"\xec\x01\n" +
+	"\x0eInventoryEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12)\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x15.device.v1.DeviceTypeR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x12\n" +
+	"\x04path\x18\x04 \x01(\tR\x04path\x12#\n" +
+	"\rfriendly_name\x18\x05 \x01(\tR\ffriendlyName\x12\x14\n" +
+	"\x05notes\x18\x06 \x01(\tR\x05notes\x12\x1d\n" +
+	"\n" +
+	"first_seen\x18\a \x01(\x03R\tfirstSeen\x12\x1b\n" +
+	"\tlast_seen\x18\b \x01(\x03R\blastSeen\"V\n" +
 	"\x1aRequestDeviceAccessRequest\x12\x1b\n" +
 	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x1b\n" +
 	"\tclient_id\x18\x02 \x01(\tR\bclientId\"n\n" +
@@ -1454,7 +2006,7 @@ const file_api_proto_device_v1_device_proto_rawDesc = "" +
 	"\x04type\x18\x01 \x01(\x0e2\x14.device.v1.EventTypeR\x04type\x12)\n" +
 	"\x06device\x18\x02 \x01(\v2\x11.device.v1.DeviceR\x06device\x123\n" +
 	"\told_state\x18\x03 \x01(\x0e2\x16.device.v1.DeviceStateR\boldState\x12\x1c\n" +
-	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp*\xbf\x01\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp*\xe9\x01\n" +
 	"\n" +
 	"DeviceType\x12\x1b\n" +
 	"\x17DEVICE_TYPE_UNSPECIFIED\x10\x00\x12\x1a\n" +
@@ -1463,7 +2015,9 @@ const file_api_proto_device_v1_device_proto_rawDesc = "" +
 	"\x12DEVICE_TYPE_SCREEN\x10\x03\x12\x17\n" +
 	"\x13DEVICE_TYPE_SPEAKER\x10\x04\x12\x18\n" +
 	"\x14DEVICE_TYPE_KEYBOARD\x10\x05\x12\x15\n" +
-	"\x11DEVICE_TYPE_MOUSE\x10\x06*\x92\x01\n" +
+	"\x11DEVICE_TYPE_MOUSE\x10\x06\x12\x13\n" +
+	"\x0fDEVICE_TYPE_NPU\x10\a\x12\x13\n" +
+	"\x0fDEVICE_TYPE_GPU\x10\b*\x92\x01\n" +
 	"\vDeviceState\x12\x1c\n" +
 	"\x18DEVICE_STATE_UNSPECIFIED\x10\x00\x12\x1a\n" +
 	"\x16DEVICE_STATE_AVAILABLE\x10\x01\x12\x17\n" +
@@ -1482,12 +2036,15 @@ const file_api_proto_device_v1_device_proto_rawDesc = "" +
 	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x1b\n" +
 	"\x17EVENT_TYPE_DEVICE_ADDED\x10\x01\x12\x1d\n" +
 	"\x19EVENT_TYPE_DEVICE_REMOVED\x10\x02\x12#\n" +
-	"\x1fEVENT_TYPE_DEVICE_STATE_CHANGED\x10\x032\x92\x06\n" +
+	"\x1fEVENT_TYPE_DEVICE_STATE_CHANGED\x10\x032\x8e\b\n" +
 	"\rDeviceService\x12U\n" +
 	"\x0eRegisterDevice\x12 .device.v1.RegisterDeviceRequest\x1a!.device.v1.RegisterDeviceResponse\x12[\n" +
 	"\x10UnregisterDevice\x12\".device.v1.UnregisterDeviceRequest\x1a#.device.v1.UnregisterDeviceResponse\x12L\n" +
 	"\vListDevices\x12\x1d.device.v1.ListDevicesRequest\x1a\x1e.device.v1.ListDevicesResponse\x12F\n" +
-	"\tGetDevice\x12\x1b.device.v1.GetDeviceRequest\x1a\x1c.device.v1.GetDeviceResponse\x12d\n" +
+	"\tGetDevice\x12\x1b.device.v1.GetDeviceRequest\x1a\x1c.device.v1.GetDeviceResponse\x12O\n" +
+	"\fRenameDevice\x12\x1e.device.v1.RenameDeviceRequest\x1a\x1f.device.v1.RenameDeviceResponse\x12U\n" +
+	"\x0eAnnotateDevice\x12 .device.v1.AnnotateDeviceRequest\x1a!.device.v1.AnnotateDeviceResponse\x12R\n" +
+	"\rListInventory\x12\x1f.device.v1.ListInventoryRequest\x1a .device.v1.ListInventoryResponse\x12d\n" +
 	"\x13RequestDeviceAccess\x12%.device.v1.RequestDeviceAccessRequest\x1a&.device.v1.RequestDeviceAccessResponse\x12d\n" +
 	"\x13ReleaseDeviceAccess\x12%.device.v1.ReleaseDeviceAccessRequest\x1a&.device.v1.ReleaseDeviceAccessResponse\x12V\n" +
 	"\x11SubscribeToDevice\x12#.device.v1.SubscribeToDeviceRequest\x1a\x1a.device.v1.DeviceDataFrame0\x01\x12I\n" +
@@ -1507,76 +2064,96 @@ func file_api_proto_device_v1_device_proto_rawDescGZIP() []byte {
 }
 
 var file_api_proto_device_v1_device_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
-var file_api_proto_device_v1_device_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_api_proto_device_v1_device_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
 var file_api_proto_device_v1_device_proto_goTypes = []any{
 	(DeviceType)(0),                     // 0: device.v1.DeviceType
 	(DeviceState)(0),                    // 1: device.v1.DeviceState
 	(CommandType)(0),                    // 2: device.v1.CommandType
 	(EventType)(0),                      // 3: device.v1.EventType
 	(*Device)(nil),                      // 4: device.v1.Device
-	(*RegisterDeviceRequest)(nil),       // 5: device.v1.RegisterDeviceRequest
-	(*RegisterDeviceResponse)(nil),      // 6: device.v1.RegisterDeviceResponse
-	(*UnregisterDeviceRequest)(nil),     // 7: device.v1.UnregisterDeviceRequest
-	(*UnregisterDeviceResponse)(nil),    // 8: device.v1.UnregisterDeviceResponse
-	(*ListDevicesRequest)(nil),          // 9: device.v1.ListDevicesRequest
-	(*ListDevicesResponse)(nil),         // 10: device.v1.ListDevicesResponse
-	(*GetDeviceRequest)(nil),            // 11: device.v1.GetDeviceRequest
-	(*GetDeviceResponse)(nil),           // 12: device.v1.GetDeviceResponse
-	(*RequestDeviceAccessRequest)(nil),  // 13: device.v1.RequestDeviceAccessRequest
-	(*RequestDeviceAccessResponse)(nil), // 14: device.v1.RequestDeviceAccessResponse
-	(*ReleaseDeviceAccessRequest)(nil),  // 15: device.v1.ReleaseDeviceAccessRequest
-	(*ReleaseDeviceAccessResponse)(nil), // 16: device.v1.ReleaseDeviceAccessResponse
-	(*SubscribeToDeviceRequest)(nil),    // 17: device.v1.SubscribeToDeviceRequest
-	(*StreamConfig)(nil),                // 18: device.v1.StreamConfig
-	(*DeviceDataFrame)(nil),             // 19: device.v1.DeviceDataFrame
-	(*DeviceCommand)(nil),               // 20: device.v1.DeviceCommand
-	(*WatchDevicesRequest)(nil),         // 21: device.v1.WatchDevicesRequest
-	(*DeviceEvent)(nil),                 // 22: device.v1.DeviceEvent
-	nil,                                 // 23: device.v1.Device.CapabilitiesEntry
-	nil,                                 // 24: device.v1.RegisterDeviceRequest.CapabilitiesEntry
-	nil,                                 // 25: device.v1.DeviceDataFrame.MetadataEntry
-	nil,                                 // 26: device.v1.DeviceCommand.ParametersEntry
+	(*DeviceUtilization)(nil),           // 5: device.v1.DeviceUtilization
+	(*RegisterDeviceRequest)(nil),       // 6: device.v1.RegisterDeviceRequest
+	(*RegisterDeviceResponse)(nil),      // 7: device.v1.RegisterDeviceResponse
+	(*UnregisterDeviceRequest)(nil),     // 8: device.v1.UnregisterDeviceRequest
+	(*UnregisterDeviceResponse)(nil),    // 9: device.v1.UnregisterDeviceResponse
+	(*ListDevicesRequest)(nil),          // 10: device.v1.ListDevicesRequest
+	(*ListDevicesResponse)(nil),         // 11: device.v1.ListDevicesResponse
+	(*GetDeviceRequest)(nil),            // 12: device.v1.GetDeviceRequest
+	(*GetDeviceResponse)(nil),           // 13: device.v1.GetDeviceResponse
+	(*RenameDeviceRequest)(nil),         // 14: device.v1.RenameDeviceRequest
+	(*RenameDeviceResponse)(nil),        // 15: device.v1.RenameDeviceResponse
+	(*AnnotateDeviceRequest)(nil),       // 16: device.v1.AnnotateDeviceRequest
+	(*AnnotateDeviceResponse)(nil),      // 17: device.v1.AnnotateDeviceResponse
+	(*ListInventoryRequest)(nil),        // 18: device.v1.ListInventoryRequest
+	(*ListInventoryResponse)(nil),       // 19: device.v1.ListInventoryResponse
+	(*InventoryEntry)(nil),              // 20: device.v1.InventoryEntry
+	(*RequestDeviceAccessRequest)(nil),  // 21: device.v1.RequestDeviceAccessRequest
+	(*RequestDeviceAccessResponse)(nil), // 22: device.v1.RequestDeviceAccessResponse
+	(*ReleaseDeviceAccessRequest)(nil),  // 23: device.v1.ReleaseDeviceAccessRequest
+	(*ReleaseDeviceAccessResponse)(nil), // 24: device.v1.ReleaseDeviceAccessResponse
+	(*SubscribeToDeviceRequest)(nil),    // 25: device.v1.SubscribeToDeviceRequest
+	(*StreamConfig)(nil),                // 26: device.v1.StreamConfig
+	(*DeviceDataFrame)(nil),             // 27: device.v1.DeviceDataFrame
+	(*DeviceCommand)(nil),               // 28: device.v1.DeviceCommand
+	(*WatchDevicesRequest)(nil),         // 29: device.v1.WatchDevicesRequest
+	(*DeviceEvent)(nil),                 // 30: device.v1.DeviceEvent
+	nil,                                 // 31: device.v1.Device.CapabilitiesEntry
+	nil,                                 // 32: device.v1.RegisterDeviceRequest.CapabilitiesEntry
+	nil,                                 // 33: device.v1.DeviceDataFrame.MetadataEntry
+	nil,                                 // 34: device.v1.DeviceCommand.ParametersEntry
 }
 var file_api_proto_device_v1_device_proto_depIdxs = []int32{
 	0,  // 0: device.v1.Device.type:type_name -> device.v1.DeviceType
 	1,  // 1: device.v1.Device.state:type_name -> device.v1.DeviceState
-	23, // 2: device.v1.Device.capabilities:type_name -> device.v1.Device.CapabilitiesEntry
-	0,  // 3: device.v1.RegisterDeviceRequest.type:type_name -> device.v1.DeviceType
-	24, // 4: device.v1.RegisterDeviceRequest.capabilities:type_name -> device.v1.RegisterDeviceRequest.CapabilitiesEntry
-	0,  // 5: device.v1.ListDevicesRequest.filter_type:type_name -> device.v1.DeviceType
-	4,  // 6: device.v1.ListDevicesResponse.devices:type_name -> device.v1.Device
-	4,  // 7: device.v1.GetDeviceResponse.device:type_name -> device.v1.Device
-	18, // 8: device.v1.SubscribeToDeviceRequest.config:type_name -> device.v1.StreamConfig
-	25, // 9: device.v1.DeviceDataFrame.metadata:type_name -> device.v1.DeviceDataFrame.MetadataEntry
-	2,  // 10: device.v1.DeviceCommand.type:type_name -> device.v1.CommandType
-	26, // 11: device.v1.DeviceCommand.parameters:type_name -> device.v1.DeviceCommand.ParametersEntry
-	0,  // 12: device.v1.WatchDevicesRequest.filter_type:type_name -> device.v1.DeviceType
-	3,  // 13: device.v1.DeviceEvent.type:type_name -> device.v1.EventType
-	4,  // 14: device.v1.DeviceEvent.device:type_name -> device.v1.Device
-	1,  // 15: device.v1.DeviceEvent.old_state:type_name -> device.v1.DeviceState
-	5,  // 16: device.v1.DeviceService.RegisterDevice:input_type -> device.v1.RegisterDeviceRequest
-	7,  // 17: device.v1.DeviceService.UnregisterDevice:input_type -> device.v1.UnregisterDeviceRequest
-	9,  // 18: device.v1.DeviceService.ListDevices:input_type -> device.v1.ListDevicesRequest
-	11, // 19: device.v1.DeviceService.GetDevice:input_type -> device.v1.GetDeviceRequest
-	13, // 20: device.v1.DeviceService.RequestDeviceAccess:input_type -> device.v1.RequestDeviceAccessRequest
-	15, // 21: device.v1.DeviceService.ReleaseDeviceAccess:input_type -> device.v1.ReleaseDeviceAccessRequest
-	17, // 22: device.v1.DeviceService.SubscribeToDevice:input_type -> device.v1.SubscribeToDeviceRequest
-	20, // 23: device.v1.DeviceService.DeviceChannel:input_type -> device.v1.DeviceCommand
-	21, // 24: device.v1.DeviceService.WatchDevices:input_type -> device.v1.WatchDevicesRequest
-	6,  // 25: device.v1.DeviceService.RegisterDevice:output_type -> device.v1.RegisterDeviceResponse
-	8,  // 26: device.v1.DeviceService.UnregisterDevice:output_type -> device.v1.UnregisterDeviceResponse
-	10, // 27: device.v1.DeviceService.ListDevices:output_type -> device.v1.ListDevicesResponse
-	12, // 28: device.v1.DeviceService.GetDevice:output_type -> device.v1.GetDeviceResponse
-	14, // 29: device.v1.DeviceService.RequestDeviceAccess:output_type -> device.v1.RequestDeviceAccessResponse
-	16, // 30: device.v1.DeviceService.ReleaseDeviceAccess:output_type -> device.v1.ReleaseDeviceAccessResponse
-	19, // 31: device.v1.DeviceService.SubscribeToDevice:output_type -> device.v1.DeviceDataFrame
-	19, // 32: device.v1.DeviceService.DeviceChannel:output_type -> device.v1.DeviceDataFrame
-	22, // 33: device.v1.DeviceService.WatchDevices:output_type -> device.v1.DeviceEvent
-	25, // [25:34] is the sub-list for method output_type
-	16, // [16:25] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	31, // 2: device.v1.Device.capabilities:type_name -> device.v1.Device.CapabilitiesEntry
+	5,  // 3: device.v1.Device.utilization:type_name -> device.v1.DeviceUtilization
+	0,  // 4: device.v1.RegisterDeviceRequest.type:type_name -> device.v1.DeviceType
+	32, // 5: device.v1.RegisterDeviceRequest.capabilities:type_name -> device.v1.RegisterDeviceRequest.CapabilitiesEntry
+	0,  // 6: device.v1.ListDevicesRequest.filter_type:type_name -> device.v1.DeviceType
+	4,  // 7: device.v1.ListDevicesResponse.devices:type_name -> device.v1.Device
+	4,  // 8: device.v1.GetDeviceResponse.device:type_name -> device.v1.Device
+	20, // 9: device.v1.RenameDeviceResponse.entry:type_name -> device.v1.InventoryEntry
+	20, // 10: device.v1.AnnotateDeviceResponse.entry:type_name -> device.v1.InventoryEntry
+	0,  // 11: device.v1.ListInventoryRequest.filter_type:type_name -> device.v1.DeviceType
+	20, // 12: device.v1.ListInventoryResponse.entries:type_name -> device.v1.InventoryEntry
+	0,  // 13: device.v1.InventoryEntry.type:type_name -> device.v1.DeviceType
+	26, // 14: device.v1.SubscribeToDeviceRequest.config:type_name -> device.v1.StreamConfig
+	33, // 15: device.v1.DeviceDataFrame.metadata:type_name -> device.v1.DeviceDataFrame.MetadataEntry
+	2,  // 16: device.v1.DeviceCommand.type:type_name -> device.v1.CommandType
+	34, // 17: device.v1.DeviceCommand.parameters:type_name -> device.v1.DeviceCommand.ParametersEntry
+	0,  // 18: device.v1.WatchDevicesRequest.filter_type:type_name -> device.v1.DeviceType
+	3,  // 19: device.v1.DeviceEvent.type:type_name -> device.v1.EventType
+	4,  // 20: device.v1.DeviceEvent.device:type_name -> device.v1.Device
+	1,  // 21: device.v1.DeviceEvent.old_state:type_name -> device.v1.DeviceState
+	6,  // 22: device.v1.DeviceService.RegisterDevice:input_type -> device.v1.RegisterDeviceRequest
+	8,  // 23: device.v1.DeviceService.UnregisterDevice:input_type -> device.v1.UnregisterDeviceRequest
+	10, // 24: device.v1.DeviceService.ListDevices:input_type -> device.v1.ListDevicesRequest
+	12, // 25: device.v1.DeviceService.GetDevice:input_type -> device.v1.GetDeviceRequest
+	14, // 26: device.v1.DeviceService.RenameDevice:input_type -> device.v1.RenameDeviceRequest
+	16, // 27: device.v1.DeviceService.AnnotateDevice:input_type -> device.v1.AnnotateDeviceRequest
+	18, // 28: device.v1.DeviceService.ListInventory:input_type -> device.v1.ListInventoryRequest
+	21, // 29: device.v1.DeviceService.RequestDeviceAccess:input_type -> device.v1.RequestDeviceAccessRequest
+	23, // 30: device.v1.DeviceService.ReleaseDeviceAccess:input_type -> device.v1.ReleaseDeviceAccessRequest
+	25, // 31: device.v1.DeviceService.SubscribeToDevice:input_type -> device.v1.SubscribeToDeviceRequest
+	28, // 32: device.v1.DeviceService.DeviceChannel:input_type -> device.v1.DeviceCommand
+	29, // 33: device.v1.DeviceService.WatchDevices:input_type -> device.v1.WatchDevicesRequest
+	7,  // 34: device.v1.DeviceService.RegisterDevice:output_type -> device.v1.RegisterDeviceResponse
+	9,  // 35: device.v1.DeviceService.UnregisterDevice:output_type -> device.v1.UnregisterDeviceResponse
+	11, // 36: device.v1.DeviceService.ListDevices:output_type -> device.v1.ListDevicesResponse
+	13, // 37: device.v1.DeviceService.GetDevice:output_type -> device.v1.GetDeviceResponse
+	15, // 38: device.v1.DeviceService.RenameDevice:output_type -> device.v1.RenameDeviceResponse
+	17, // 39: device.v1.DeviceService.AnnotateDevice:output_type -> device.v1.AnnotateDeviceResponse
+	19, // 40: device.v1.DeviceService.ListInventory:output_type -> device.v1.ListInventoryResponse
+	22, // 41: device.v1.DeviceService.RequestDeviceAccess:output_type -> device.v1.RequestDeviceAccessResponse
+	24, // 42: device.v1.DeviceService.ReleaseDeviceAccess:output_type -> device.v1.ReleaseDeviceAccessResponse
+	27, // 43: device.v1.DeviceService.SubscribeToDevice:output_type -> device.v1.DeviceDataFrame
+	27, // 44: device.v1.DeviceService.DeviceChannel:output_type -> device.v1.DeviceDataFrame
+	30, // 45: device.v1.DeviceService.WatchDevices:output_type -> device.v1.DeviceEvent
+	34, // [34:46] is the sub-list for method output_type
+	22, // [22:34] is the sub-list for method input_type
+	22, // [22:22] is the sub-list for extension type_name
+	22, // [22:22] is the sub-list for extension extendee
+	0,  // [0:22] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_device_v1_device_proto_init() }
@@ -1590,7 +2167,7 @@ func file_api_proto_device_v1_device_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_device_v1_device_proto_rawDesc), len(file_api_proto_device_v1_device_proto_rawDesc)),
 			NumEnums:      4,
-			NumMessages:   23,
+			NumMessages:   31,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
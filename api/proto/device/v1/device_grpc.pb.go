@@ -23,6 +23,9 @@ const (
 	DeviceService_UnregisterDevice_FullMethodName    = "/device.v1.DeviceService/UnregisterDevice"
 	DeviceService_ListDevices_FullMethodName         = "/device.v1.DeviceService/ListDevices"
 	DeviceService_GetDevice_FullMethodName           = "/device.v1.DeviceService/GetDevice"
+	DeviceService_RenameDevice_FullMethodName        = "/device.v1.DeviceService/RenameDevice"
+	DeviceService_AnnotateDevice_FullMethodName      = "/device.v1.DeviceService/AnnotateDevice"
+	DeviceService_ListInventory_FullMethodName       = "/device.v1.DeviceService/ListInventory"
 	DeviceService_RequestDeviceAccess_FullMethodName = "/device.v1.DeviceService/RequestDeviceAccess"
 	DeviceService_ReleaseDeviceAccess_FullMethodName = "/device.v1.DeviceService/ReleaseDeviceAccess"
 	DeviceService_SubscribeToDevice_FullMethodName   = "/device.v1.DeviceService/SubscribeToDevice"
@@ -44,6 +47,12 @@ type DeviceServiceClient interface {
 	ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error)
 	// GetDevice returns details about a specific device
 	GetDevice(ctx context.Context, in *GetDeviceRequest, opts ...grpc.CallOption) (*GetDeviceResponse, error)
+	// RenameDevice gives a device a friendly name, kept across restarts
+	RenameDevice(ctx context.Context, in *RenameDeviceRequest, opts ...grpc.CallOption) (*RenameDeviceResponse, error)
+	// AnnotateDevice sets notes on a device, kept across restarts
+	AnnotateDevice(ctx context.Context, in *AnnotateDeviceRequest, opts ...grpc.CallOption) (*AnnotateDeviceResponse, error)
+	// ListInventory returns every device ever registered, attached or not
+	ListInventory(ctx context.Context, in *ListInventoryRequest, opts ...grpc.CallOption) (*ListInventoryResponse, error)
 	// RequestDeviceAccess requests access to a device
 	RequestDeviceAccess(ctx context.Context, in *RequestDeviceAccessRequest, opts ...grpc.CallOption) (*RequestDeviceAccessResponse, error)
 	// ReleaseDeviceAccess releases access to a device
@@ -104,6 +113,36 @@ func (c *deviceServiceClient) GetDevice(ctx context.Context, in *GetDeviceReques
 	return out, nil
 }
 
+func (c *deviceServiceClient) RenameDevice(ctx context.Context, in *RenameDeviceRequest, opts ...grpc.CallOption) (*RenameDeviceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenameDeviceResponse)
+	err := c.cc.Invoke(ctx, DeviceService_RenameDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deviceServiceClient) AnnotateDevice(ctx context.Context, in *AnnotateDeviceRequest, opts ...grpc.CallOption) (*AnnotateDeviceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AnnotateDeviceResponse)
+	err := c.cc.Invoke(ctx, DeviceService_AnnotateDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *deviceServiceClient) ListInventory(ctx context.Context, in *ListInventoryRequest, opts ...grpc.CallOption) (*ListInventoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListInventoryResponse)
+	err := c.cc.Invoke(ctx, DeviceService_ListInventory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *deviceServiceClient) RequestDeviceAccess(ctx context.Context, in *RequestDeviceAccessRequest, opts ...grpc.CallOption) (*RequestDeviceAccessResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(RequestDeviceAccessResponse)
@@ -189,6 +228,12 @@ type DeviceServiceServer interface {
 	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
 	// GetDevice returns details about a specific device
 	GetDevice(context.Context, *GetDeviceRequest) (*GetDeviceResponse, error)
+	// RenameDevice gives a device a friendly name, kept across restarts
+	RenameDevice(context.Context, *RenameDeviceRequest) (*RenameDeviceResponse, error)
+	// AnnotateDevice sets notes on a device, kept across restarts
+	AnnotateDevice(context.Context, *AnnotateDeviceRequest) (*AnnotateDeviceResponse, error)
+	// ListInventory returns every device ever registered, attached or not
+	ListInventory(context.Context, *ListInventoryRequest) (*ListInventoryResponse, error)
 	// RequestDeviceAccess requests access to a device
 	RequestDeviceAccess(context.Context, *RequestDeviceAccessRequest) (*RequestDeviceAccessResponse, error)
 	// ReleaseDeviceAccess releases access to a device
@@ -221,6 +266,15 @@ func (UnimplementedDeviceServiceServer) ListDevices(context.Context, *ListDevice
 func (UnimplementedDeviceServiceServer) GetDevice(context.Context, *GetDeviceRequest) (*GetDeviceResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetDevice not implemented")
 }
+func (UnimplementedDeviceServiceServer) RenameDevice(context.Context, *RenameDeviceRequest) (*RenameDeviceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RenameDevice not implemented")
+}
+func (UnimplementedDeviceServiceServer) AnnotateDevice(context.Context, *AnnotateDeviceRequest) (*AnnotateDeviceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AnnotateDevice not implemented")
+}
+func (UnimplementedDeviceServiceServer) ListInventory(context.Context, *ListInventoryRequest) (*ListInventoryResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListInventory not implemented")
+}
 func (UnimplementedDeviceServiceServer) RequestDeviceAccess(context.Context, *RequestDeviceAccessRequest) (*RequestDeviceAccessResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method RequestDeviceAccess not implemented")
 }
@@ -329,6 +383,60 @@ func _DeviceService_GetDevice_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DeviceService_RenameDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeviceServiceServer).RenameDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeviceService_RenameDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeviceServiceServer).RenameDevice(ctx, req.(*RenameDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DeviceService_AnnotateDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnnotateDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeviceServiceServer).AnnotateDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeviceService_AnnotateDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeviceServiceServer).AnnotateDevice(ctx, req.(*AnnotateDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DeviceService_ListInventory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInventoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeviceServiceServer).ListInventory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DeviceService_ListInventory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeviceServiceServer).ListInventory(ctx, req.(*ListInventoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DeviceService_RequestDeviceAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RequestDeviceAccessRequest)
 	if err := dec(in); err != nil {
@@ -417,6 +525,18 @@ var DeviceService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetDevice",
 			Handler:    _DeviceService_GetDevice_Handler,
 		},
+		{
+			MethodName: "RenameDevice",
+			Handler:    _DeviceService_RenameDevice_Handler,
+		},
+		{
+			MethodName: "AnnotateDevice",
+			Handler:    _DeviceService_AnnotateDevice_Handler,
+		},
+		{
+			MethodName: "ListInventory",
+			Handler:    _DeviceService_ListInventory_Handler,
+		},
 		{
 			MethodName: "RequestDeviceAccess",
 			Handler:    _DeviceService_RequestDeviceAccess_Handler,
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 )
@@ -10,6 +11,9 @@ import (
 const (
 	dbusInterface = "com.anthropic.OllamaProxy.Efficiency"
 	dbusPath      = "/com/anthropic/OllamaProxy/Efficiency"
+
+	routingInterface = "ie.fio.OllamaProxy.Routing"
+	routingPath      = "/com/anthropic/OllamaProxy/Routing"
 )
 
 func main() {
@@ -20,6 +24,16 @@ func main() {
 
 	command := os.Args[1]
 
+	// The kill switch lives on the proxy's Routing service
+	switch command {
+	case "stop":
+		stopGenerations(os.Args[2:])
+		return
+	case "resume":
+		resumeGenerations(os.Args[2:])
+		return
+	}
+
 	conn, err := dbus.ConnectSessionBus()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to connect to D-Bus: %v\n", err)
@@ -48,6 +62,8 @@ func main() {
 		getModeInfo(obj, os.Args[2])
 	case "status":
 		showStatus(obj)
+	case "schedule":
+		schedule(obj, os.Args[2:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		printUsage()
@@ -64,6 +80,15 @@ func printUsage() {
 	fmt.Println("  ai-efficiency list              List available modes")
 	fmt.Println("  ai-efficiency info <mode>       Get mode information")
 	fmt.Println("  ai-efficiency status            Show current status")
+	fmt.Println("  ai-efficiency schedule          List the mode schedule and the entry in force")
+	fmt.Println("  ai-efficiency schedule override <mode|off> [duration]")
+	fmt.Println("                                  Apply a mode in place of the schedule, or turn")
+	fmt.Println("                                  it off, for a duration (e.g. 2h) or until cleared")
+	fmt.Println("  ai-efficiency schedule clear    Put the schedule back in force")
+	fmt.Println("  ai-efficiency stop [backend] [--pause]")
+	fmt.Println("                                  Cancel in-flight generations (all backends")
+	fmt.Println("                                  by default), --pause also holds new ones")
+	fmt.Println("  ai-efficiency resume [backend]  Accept generations again after --pause")
 	fmt.Println()
 	fmt.Println("Available modes:")
 	fmt.Println("  Performance      - Maximum speed (NVIDIA GPU)")
@@ -209,3 +234,162 @@ func showStatus(obj dbus.BusObject) {
 		}
 	}
 }
+
+// scheduleEntry is a row of GetSchedule
+type scheduleEntry struct {
+	Name, Mode, Days, Start, End string
+	Active                       bool
+}
+
+func schedule(obj dbus.BusObject, args []string) {
+	if len(args) == 0 || args[0] == "list" {
+		listSchedule(obj)
+		return
+	}
+
+	switch args[0] {
+	case "override":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: ai-efficiency schedule override <mode|off> [duration]")
+			os.Exit(1)
+		}
+		var seconds uint32
+		if len(args) > 2 {
+			d, err := time.ParseDuration(args[2])
+			if err != nil || d <= 0 {
+				fmt.Fprintf(os.Stderr, "Invalid duration: %s\n", args[2])
+				os.Exit(1)
+			}
+			seconds = uint32(d.Seconds())
+		}
+		if err := obj.Call(dbusInterface+".OverrideSchedule", 0, args[1], seconds).Store(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to override schedule: %v\n", err)
+			os.Exit(1)
+		}
+		if args[1] == "off" {
+			fmt.Println("✓ Schedule turned off")
+		} else {
+			fmt.Printf("✓ Schedule overridden with: %s\n", args[1])
+		}
+	case "clear":
+		if err := obj.Call(dbusInterface+".ClearScheduleOverride", 0).Store(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to clear schedule override: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✓ Schedule back in force")
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown schedule command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func listSchedule(obj dbus.BusObject) {
+	var entries []scheduleEntry
+	if err := obj.Call(dbusInterface+".GetSchedule", 0).Store(&entries); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get schedule: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No efficiency schedule configured")
+	}
+	for _, e := range entries {
+		marker := " "
+		if e.Active {
+			marker = "●"
+		}
+		days := e.Days
+		if days == "" {
+			days = "daily"
+		}
+		fmt.Printf("%s %-16s %-16s %s-%s  %s\n", marker, e.Name, e.Mode, e.Start, e.End, days)
+	}
+
+	var entry, mode, override string
+	var remaining uint32
+	if err := obj.Call(dbusInterface+".GetScheduleStatus", 0).Store(&entry, &mode, &override, &remaining); err != nil {
+		return
+	}
+	switch {
+	case override == "off":
+		fmt.Print("\nSchedule off")
+	case override != "":
+		fmt.Printf("\nOverridden with %s", override)
+	case entry != "":
+		fmt.Printf("\nIn force: %s (%s)\n", entry, mode)
+		return
+	default:
+		return
+	}
+	if remaining > 0 {
+		fmt.Printf(" for %s", time.Duration(remaining)*time.Second)
+	}
+	fmt.Println()
+}
+
+// callRouting calls a method on the proxy's Routing service. It is on the
+// system bus when the proxy could connect to it, else the session bus.
+func callRouting(method string, args ...interface{}) *dbus.Call {
+	var call *dbus.Call
+	for _, connect := range []func(...dbus.ConnOption) (*dbus.Conn, error){dbus.ConnectSystemBus, dbus.ConnectSessionBus} {
+		conn, err := connect()
+		if err != nil {
+			continue
+		}
+		call = conn.Object(routingInterface, routingPath).Call(routingInterface+"."+method, 0, args...)
+		conn.Close()
+		if call.Err == nil {
+			return call
+		}
+	}
+	if call == nil {
+		fmt.Fprintln(os.Stderr, "Failed to connect to D-Bus")
+		os.Exit(1)
+	}
+	return call
+}
+
+func stopGenerations(args []string) {
+	backend := ""
+	pause := false
+	for _, arg := range args {
+		if arg == "--pause" {
+			pause = true
+		} else {
+			backend = arg
+		}
+	}
+
+	var cancelled int32
+	if err := callRouting("StopAll", backend, pause).Store(&cancelled); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to stop generations: %v\n", err)
+		os.Exit(1)
+	}
+
+	target := "all backends"
+	if backend != "" {
+		target = backend
+	}
+	fmt.Printf("✓ Stopped %d generation(s) on %s\n", cancelled, target)
+	if pause {
+		fmt.Println("New generations are paused; run 'ai-efficiency resume' to accept them again")
+	}
+}
+
+func resumeGenerations(args []string) {
+	backend := ""
+	if len(args) > 0 {
+		backend = args[0]
+	}
+
+	if err := callRouting("Resume", backend).Store(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resume generations: %v\n", err)
+		os.Exit(1)
+	}
+
+	target := "all backends"
+	if backend != "" {
+		target = backend
+	}
+	fmt.Printf("✓ Generations resumed on %s\n", target)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/audit"
+	"github.com/daoneill/ollama-proxy/pkg/config"
+	"github.com/daoneill/ollama-proxy/pkg/lifecycle"
+)
+
+// newAuditLog opens the audit log of inference requests, nil when auditing
+// is disabled
+func newAuditLog(cfg *config.Config) (*audit.Logger, error) {
+	al := cfg.Monitoring.AuditLog
+	if !al.Enabled {
+		return nil, nil
+	}
+	return audit.NewLogger(audit.Config{
+		Path:             al.Path,
+		MaxSizeMB:        al.MaxSizeMB,
+		MaxBackups:       al.MaxBackups,
+		PromptHashLength: al.PromptHashLength,
+		PromptHashKey:    al.PromptHashKey,
+		Redact:           al.Redact,
+	})
+}
+
+// auditSubsystem closes the audit log once the servers have stopped. It
+// was opened at startup, as an unwritable log must stop the proxy before
+// it serves anything.
+func auditSubsystem(auditLog *audit.Logger) lifecycle.Subsystem {
+	return lifecycle.Subsystem{
+		Name:    "audit",
+		Timeout: 5 * time.Second,
+		Fixed:   true,
+		Stop: func(context.Context) error {
+			return auditLog.Close()
+		},
+	}
+}
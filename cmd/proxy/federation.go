@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/daoneill/ollama-proxy/pkg/config"
+	"github.com/daoneill/ollama-proxy/pkg/federation"
+)
+
+// newFederationSigner loads this instance's key for signing requests to
+// federated backends (peer proxies), nil when no key is configured
+func newFederationSigner(cfg *config.Config) (*federation.Signer, error) {
+	fed := cfg.Server.Federation
+	if fed.PrivateKeyFile == "" {
+		return nil, nil
+	}
+	key, err := federation.LoadPrivateKey(fed.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return federation.NewSigner(fed.InstanceID, key), nil
+}
+
+// newFederationVerifier trusts requests signed by the configured peer
+// proxies, nil when none are
+func newFederationVerifier(cfg *config.Config) *federation.Verifier {
+	fed := cfg.Server.Federation
+	if len(fed.TrustedPeers) == 0 {
+		return nil
+	}
+	peers := make(map[string]federation.Peer, len(fed.TrustedPeers))
+	for id, peerCfg := range fed.TrustedPeers {
+		key, _ := federation.ParsePublicKey(peerCfg.PublicKey) // checked by ValidateConfig
+		peers[id] = federation.Peer{PublicKey: key, Permissions: peerCfg.Permissions}
+	}
+	return federation.NewVerifier(fed.InstanceID, peers,
+		parseDuration(fed.MaxClockSkew, federation.DefaultMaxSkew, "server.federation.max_clock_skew"))
+}
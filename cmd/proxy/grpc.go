@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/authz"
+	"github.com/daoneill/ollama-proxy/pkg/config"
+	"github.com/daoneill/ollama-proxy/pkg/drain"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"github.com/daoneill/ollama-proxy/pkg/server"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// grpcService is the gRPC server and the listener it serves once the
+// "servers" subsystem starts
+type grpcService struct {
+	server *grpc.Server
+	addr   string
+	tls    bool
+	lis    net.Listener
+}
+
+// newGRPCService creates the gRPC server with its interceptor chain,
+// message, keepalive and TLS options. Services are registered on
+// server before it listens.
+func newGRPCService(cfg *config.Config, authzPolicy *authz.Policy, authConfig auth.Config, ledger *usage.Ledger, drainer *drain.Drainer) *grpcService {
+	// Request IDs are assigned first so panics are reported with them;
+	// panic recovery then covers the other interceptors
+	unaryInterceptors := []grpc.UnaryServerInterceptor{middleware.UnaryRequestIDInterceptor(), middleware.UnaryRecoveryInterceptor()}
+	streamInterceptors := []grpc.StreamServerInterceptor{middleware.StreamRequestIDInterceptor(), middleware.StreamRecoveryInterceptor()}
+	if authzPolicy != nil {
+		unaryInterceptors = append(unaryInterceptors, authzPolicy.UnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, authzPolicy.StreamInterceptor())
+	} else if cfg.Server.Auth.Enabled {
+		// Without authz, calls carrying a key are still authenticated so
+		// their quota applies
+		unaryInterceptors = append(unaryInterceptors, auth.UnaryInterceptor(authConfig))
+		streamInterceptors = append(streamInterceptors, auth.StreamInterceptor(authConfig))
+	}
+	if authzPolicy != nil || cfg.Server.Auth.Enabled {
+		// Quotas apply to the key authenticated above
+		unaryInterceptors = append(unaryInterceptors, ledger.UnaryInterceptor())
+		streamInterceptors = append(streamInterceptors, ledger.StreamInterceptor())
+	}
+
+	// gRPC server options (message sizes, keepalive, compression)
+	grpcCfg := cfg.Server.GRPC
+	grpcOptions := server.GRPCOptions{
+		MaxRecvMsgSize:      grpcCfg.MaxRecvMsgSizeMB * 1024 * 1024,
+		MaxSendMsgSize:      grpcCfg.MaxSendMsgSizeMB * 1024 * 1024,
+		Compression:         grpcCfg.Compression,
+		KeepaliveTime:       parseDuration(grpcCfg.Keepalive.Time, 0, "server.grpc.keepalive.time"),
+		KeepaliveTimeout:    parseDuration(grpcCfg.Keepalive.Timeout, 0, "server.grpc.keepalive.timeout"),
+		MaxConnectionIdle:   parseDuration(grpcCfg.Keepalive.MaxConnectionIdle, 0, "server.grpc.keepalive.max_connection_idle"),
+		KeepaliveMinTime:    parseDuration(grpcCfg.Keepalive.MinTime, 0, "server.grpc.keepalive.min_time"),
+		PermitWithoutStream: grpcCfg.Keepalive.PermitWithoutStream,
+	}
+	grpcOpts := append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(append(unaryInterceptors, drainer.UnaryInterceptor())...),
+		grpc.ChainStreamInterceptor(append(streamInterceptors, drainer.StreamInterceptor())...),
+	}, grpcOptions.ServerOptions()...)
+	logging.Logger.Info("gRPC server options",
+		zap.Int("max_recv_msg_size_mb", grpcCfg.MaxRecvMsgSizeMB),
+		zap.Int("max_send_msg_size_mb", grpcCfg.MaxSendMsgSizeMB),
+		zap.String("compression", grpcCfg.Compression),
+	)
+
+	if !cfg.Server.TLS.Enabled {
+		logging.Logger.Warn("gRPC TLS disabled",
+			zap.String("warning", "not recommended for production"),
+		)
+	} else {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(grpcTLSConfig(cfg))))
+	}
+	return &grpcService{
+		server: grpc.NewServer(grpcOpts...),
+		addr:   fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.GRPCPort),
+		tls:    cfg.Server.TLS.Enabled,
+	}
+}
+
+// grpcTLSConfig loads the server certificate, and the client CA when
+// clients must present certificates (mTLS)
+func grpcTLSConfig(cfg *config.Config) *tls.Config {
+	cert, err := tls.LoadX509KeyPair(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+	if err != nil {
+		logging.Logger.Fatal("Failed to load TLS certificate", zap.Error(err))
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	// Optional mTLS if client CA provided
+	if cfg.Server.TLS.ClientCAFile == "" {
+		logging.Logger.Info("gRPC TLS enabled",
+			zap.String("security_level", "tls"),
+		)
+		return tlsConfig
+	}
+	caCert, err := os.ReadFile(cfg.Server.TLS.ClientCAFile)
+	if err != nil {
+		logging.Logger.Fatal("Failed to load client CA", zap.Error(err))
+	}
+
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCert) {
+		logging.Logger.Fatal("Failed to parse client CA certificate")
+	}
+
+	tlsConfig.ClientCAs = caCertPool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	logging.Logger.Info("gRPC mTLS enabled",
+		zap.String("security_level", "mutual_tls"),
+		zap.Bool("client_cert_required", true),
+	)
+	return tlsConfig
+}
+
+// listen binds the gRPC port, so a port in use fails startup before any
+// subsystem starts
+func (g *grpcService) listen() {
+	lis, err := net.Listen("tcp", g.addr)
+	if err != nil {
+		logging.Logger.Fatal("Failed to listen on gRPC port",
+			zap.String("address", g.addr),
+			zap.Error(err),
+		)
+	}
+	g.lis = lis
+}
+
+// serve serves gRPC on the listener until the server stops
+func (g *grpcService) serve() {
+	logging.Logger.Info("gRPC server listening",
+		zap.String("address", g.addr),
+		zap.Bool("tls", g.tls),
+		zap.Bool("reflection", true),
+	)
+	if err := g.server.Serve(g.lis); err != nil {
+		logging.Logger.Fatal("Failed to serve gRPC", zap.Error(err))
+	}
+}
+
+// stop waits for running RPCs to finish, or cuts them off when the drain
+// already ran out
+func (g *grpcService) stop(cutOff bool) {
+	if cutOff {
+		g.server.Stop()
+		return
+	}
+	g.server.GracefulStop()
+}
@@ -7,40 +7,78 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"net"
 	"net/http"
-	_ "net/http/pprof"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	pb "github.com/daoneill/ollama-proxy/api/gen/go"
 	devicev1 "github.com/daoneill/ollama-proxy/api/proto/device/v1"
+	"github.com/daoneill/ollama-proxy/pkg/alerting"
+	"github.com/daoneill/ollama-proxy/pkg/audit"
 	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/authz"
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/backends/cloud"
 	"github.com/daoneill/ollama-proxy/pkg/backends/ollama"
 	"github.com/daoneill/ollama-proxy/pkg/backends/openvino"
+	"github.com/daoneill/ollama-proxy/pkg/backends/sdwebui"
+	"github.com/daoneill/ollama-proxy/pkg/backends/vllm"
+	"github.com/daoneill/ollama-proxy/pkg/benchmark"
+	"github.com/daoneill/ollama-proxy/pkg/cache"
+	"github.com/daoneill/ollama-proxy/pkg/circuit"
+	"github.com/daoneill/ollama-proxy/pkg/confidence"
 	"github.com/daoneill/ollama-proxy/pkg/config"
+	"github.com/daoneill/ollama-proxy/pkg/container"
+	"github.com/daoneill/ollama-proxy/pkg/dashboard"
+	"github.com/daoneill/ollama-proxy/pkg/degrade"
 	dbusPkg "github.com/daoneill/ollama-proxy/pkg/dbus"
+	"github.com/daoneill/ollama-proxy/pkg/drain"
+	"github.com/daoneill/ollama-proxy/pkg/diagnostics"
 	"github.com/daoneill/ollama-proxy/pkg/device"
 	"github.com/daoneill/ollama-proxy/pkg/device/virtual"
 	"github.com/daoneill/ollama-proxy/pkg/efficiency"
+	"github.com/daoneill/ollama-proxy/pkg/energy"
+	"github.com/daoneill/ollama-proxy/pkg/ha"
+	http3http "github.com/daoneill/ollama-proxy/pkg/http/http3"
+	ollamahttp "github.com/daoneill/ollama-proxy/pkg/http/ollama"
 	openaihttp "github.com/daoneill/ollama-proxy/pkg/http/openai"
+	"github.com/daoneill/ollama-proxy/pkg/http/postprocess"
+	serverhttp "github.com/daoneill/ollama-proxy/pkg/http/server"
+	"github.com/daoneill/ollama-proxy/pkg/http/shaping"
 	websockethttp "github.com/daoneill/ollama-proxy/pkg/http/websocket"
+	"github.com/daoneill/ollama-proxy/pkg/labels"
+	"github.com/daoneill/ollama-proxy/pkg/langdetect"
 	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/maintenance"
+	"github.com/daoneill/ollama-proxy/pkg/lifecycle"
+	"github.com/daoneill/ollama-proxy/pkg/limits"
+	"github.com/daoneill/ollama-proxy/pkg/mediaio"
 	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"github.com/daoneill/ollama-proxy/pkg/models"
+	"github.com/daoneill/ollama-proxy/pkg/netidentity"
+	"github.com/daoneill/ollama-proxy/pkg/openapi"
 	"github.com/daoneill/ollama-proxy/pkg/pipeline"
+	"github.com/daoneill/ollama-proxy/pkg/policy"
 	"github.com/daoneill/ollama-proxy/pkg/ratelimit"
+	"github.com/daoneill/ollama-proxy/pkg/replay"
 	"github.com/daoneill/ollama-proxy/pkg/router"
 	"github.com/daoneill/ollama-proxy/pkg/server"
+	"github.com/daoneill/ollama-proxy/pkg/session"
 	"github.com/daoneill/ollama-proxy/pkg/settings"
+	"github.com/daoneill/ollama-proxy/pkg/speculative"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
+	"github.com/daoneill/ollama-proxy/pkg/tap"
 	"github.com/daoneill/ollama-proxy/pkg/thermal"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 	"gopkg.in/yaml.v3"
 )
@@ -117,8 +155,50 @@ func main() {
 
 	logging.Logger.Info("Configuration validated successfully")
 
+	// Crash telemetry for recovered panics
+	if crashReporter, err := middleware.NewCrashReporter(cfg.Monitoring.CrashLog.Dir, cfg.Monitoring.CrashLog.MaxFiles); err != nil {
+		logging.Logger.Warn("Crash log directory unavailable, panics will only be logged",
+			zap.String("dir", cfg.Monitoring.CrashLog.Dir),
+			zap.Error(err),
+		)
+	} else {
+		middleware.SetCrashReporter(crashReporter)
+	}
+
+	// Audit log of inference requests; auditing is required once enabled,
+	// so an unwritable log stops startup
+	auditLog, err := newAuditLog(cfg)
+	if err != nil {
+		logging.Logger.Fatal("Failed to open audit log", zap.Error(err))
+	}
+	if auditLog != nil {
+		audit.SetDefault(auditLog)
+		logging.Logger.Info("Audit log enabled",
+			zap.String("path", cfg.Monitoring.AuditLog.Path),
+			zap.Strings("redact", cfg.Monitoring.AuditLog.Redact),
+		)
+	}
+
 	ctx := context.Background()
 
+	// Warm standby failover: a standby leaves the virtual devices and D-Bus
+	// names to the active proxy and claims them only on takeover
+	var haNode *ha.Node
+	if cfg.HA.Enabled {
+		haNode, err = newHANode(cfg, *configPath)
+		if err != nil {
+			logging.Logger.Fatal("Failed to initialize HA", zap.Error(err))
+		}
+		logging.Logger.Info("HA failover enabled",
+			zap.String("role", cfg.HA.Role),
+			zap.String("peer", cfg.HA.Peer),
+		)
+	}
+
+	// Subsystems start together once everything is wired up and stop in
+	// reverse on shutdown; see the registrations before the signal loop
+	subsystems := lifecycle.NewManager()
+
 	// Initialize thermal monitor
 	var thermalMonitor *thermal.ThermalMonitor
 	if cfg.Thermal.Enabled {
@@ -143,12 +223,29 @@ func main() {
 			FanQuiet:     cfg.Thermal.Fan.Quiet,
 			FanModerate:  cfg.Thermal.Fan.Moderate,
 			FanLoud:      cfg.Thermal.Fan.Loud,
-			CooldownTime: 2 * time.Minute,
+			CooldownTime: parseDuration(cfg.Thermal.Cooldown, 2*time.Minute, "thermal.cooldown"),
+
+			PredictionSamples: cfg.Thermal.Prediction.Samples,
+			PredictionHorizon: parseDuration(cfg.Thermal.Prediction.Horizon, thermal.DefaultPredictionHorizon, "thermal.prediction.horizon"),
 		}
 
 		thermalMonitor = thermal.NewThermalMonitor(thermalConfig, updateInterval)
-		thermalMonitor.Start()
-		logging.Logger.Info("Thermal monitoring started",
+		thermalMonitor.OnPrediction(func(p thermal.Prediction) {
+			if p.Critical {
+				logging.Logger.Warn("Hardware predicted to reach critical temperature, shifting load away",
+					zap.String("hardware", p.Hardware),
+					zap.Float64("temperature", p.Temperature),
+					zap.Float64("slope_per_minute", p.Slope*60),
+					zap.Duration("time_to_critical", p.TimeToCritical),
+				)
+				return
+			}
+			logging.Logger.Info("Hardware no longer predicted to reach critical temperature",
+				zap.String("hardware", p.Hardware),
+				zap.Float64("temperature", p.Temperature),
+			)
+		})
+		logging.Logger.Info("Thermal monitoring enabled",
 			zap.Duration("update_interval", updateInterval),
 			zap.Float64("warning_temp", cfg.Thermal.Temperature.Warning),
 			zap.Float64("critical_temp", cfg.Thermal.Temperature.Critical),
@@ -160,36 +257,46 @@ func main() {
 	// Initialize efficiency manager
 	var efficiencyMgr *efficiency.EfficiencyManager
 	var dbusSvc *efficiency.DBusService
+	startEfficiencyDBus := func() {}
 	if cfg.Efficiency.Enabled {
-		// Parse default mode
-		defaultMode := efficiency.ModeBalanced
-		switch cfg.Efficiency.DefaultMode {
-		case "Performance":
-			defaultMode = efficiency.ModePerformance
-		case "Efficiency":
-			defaultMode = efficiency.ModeEfficiency
-		case "Quiet":
-			defaultMode = efficiency.ModeQuiet
-		case "Auto":
-			defaultMode = efficiency.ModeAuto
-		case "UltraEfficiency":
-			defaultMode = efficiency.ModeUltraEfficiency
-		}
+		defaultMode := parseEfficiencyMode(cfg.Efficiency.DefaultMode)
 
 		efficiencyMgr = efficiency.NewEfficiencyManager(defaultMode)
 		logging.Logger.Info("Efficiency manager initialized",
 			zap.String("mode", defaultMode.String()),
 		)
 
+		// Scheduled modes; validated with the config
+		if len(cfg.Efficiency.Schedule) > 0 {
+			entries := make([]efficiency.ScheduleEntry, 0, len(cfg.Efficiency.Schedule))
+			for _, entryCfg := range cfg.Efficiency.Schedule {
+				entry, err := efficiency.ParseScheduleEntry(entryCfg.Name, entryCfg.Mode, entryCfg.Days, entryCfg.Start, entryCfg.End)
+				if err != nil {
+					logging.Logger.Fatal("Invalid efficiency schedule", zap.Error(err))
+				}
+				entries = append(entries, entry)
+			}
+			efficiencyMgr.OnScheduleChange(func(status efficiency.ScheduleStatus) {
+				if dbusSvc != nil {
+					dbusSvc.NotifyScheduleChanged(status)
+				}
+			})
+			efficiencyMgr.SetSchedule(entries)
+			logging.Logger.Info("Efficiency schedule loaded", zap.Int("entries", len(entries)))
+		}
+
 		// Start D-Bus service if enabled
 		if cfg.Efficiency.DBusEnabled {
-			dbusSvc, err = efficiency.NewDBusService(efficiencyMgr)
-			if err != nil {
-				logging.Logger.Warn("D-Bus service failed to start",
-					zap.Error(err),
-					zap.String("note", "GNOME integration unavailable, CLI still works"),
-				)
-			} else {
+			startEfficiencyDBus = func() {
+				svc, err := efficiency.NewDBusService(efficiencyMgr)
+				if err != nil {
+					logging.Logger.Warn("D-Bus service failed to start",
+						zap.Error(err),
+						zap.String("note", "GNOME integration unavailable, CLI still works"),
+					)
+					return
+				}
+				dbusSvc = svc
 				if err := dbusSvc.Start(); err != nil {
 					logging.Logger.Warn("D-Bus service error", zap.Error(err))
 				} else {
@@ -203,6 +310,19 @@ func main() {
 		logging.Logger.Info("Efficiency modes disabled")
 	}
 
+	// Drive fans within the efficiency mode's fan limit
+	var fanController *thermal.FanController
+	if thermalMonitor != nil && cfg.Thermal.FanControl.Enabled {
+		fanController, err = newFanController(cfg, thermalMonitor, efficiencyMgr)
+		if err != nil {
+			logging.Logger.Fatal("Failed to configure fan control", zap.Error(err))
+		}
+		logging.Logger.Info("Fan control enabled",
+			zap.Int("devices", len(cfg.Thermal.FanControl.Devices)),
+			zap.Bool("dry_run", cfg.Thermal.FanControl.DryRun),
+		)
+	}
+
 	// Load GSettings (if available)
 	gsettings := settings.NewSettings()
 	if gsettings.IsAvailable() {
@@ -230,12 +350,14 @@ func main() {
 		} else {
 			deviceManager = dm
 
-			// Start auto-discovery if enabled
-			if cfg.Devices.AutoDiscover {
-				deviceManager.StartAutoDiscovery()
-				logging.Logger.Info("Device auto-discovery started",
-					zap.Bool("hotplug_detection", true),
+			// Remember devices and their friendly names across restarts
+			if inv, err := device.LoadInventory(cfg.Devices.InventoryFile); err != nil {
+				logging.Logger.Warn("Device inventory not loaded, friendly names will not survive restarts",
+					zap.String("path", cfg.Devices.InventoryFile),
+					zap.Error(err),
 				)
+			} else {
+				deviceManager.SetInventory(inv)
 			}
 
 			logging.Logger.Info("Device manager initialized",
@@ -247,6 +369,18 @@ func main() {
 		logging.Logger.Info("Device management disabled")
 	}
 
+	// Sample accelerator utilization for devices, metrics and routing
+	var utilizationMonitor *device.UtilizationMonitor
+	if cfg.Devices.Utilization.Enabled {
+		utilizationMonitor = device.NewUtilizationMonitor(
+			parseDuration(cfg.Devices.Utilization.Interval, device.DefaultUtilizationInterval, "devices.utilization.interval"),
+		)
+		if deviceManager != nil {
+			deviceManager.SetUtilizationMonitor(utilizationMonitor)
+		}
+		go utilizationMonitor.Run(ctx)
+	}
+
 	// Initialize router (thermal-aware if enabled, with optional forwarding)
 	var r interface {
 		RegisterBackend(backends.Backend) error
@@ -259,7 +393,85 @@ func main() {
 		DefaultBackendID: cfg.Routing.DefaultBackend,
 		PowerAware:       cfg.Routing.PowerAware,
 		AutoOptimize:     cfg.Routing.AutoOptimizeLatency,
+		BackendLanguages: make(map[string][]string),
+	}
+	for _, backendCfg := range cfg.Backends {
+		if backendCfg.Enabled && len(backendCfg.Languages) > 0 {
+			routerCfg.BackendLanguages[backendCfg.ID] = backendCfg.Languages
+		}
+	}
+
+	// Per-backend concurrency limits with a pluggable queueing policy
+	routerCfg.Scheduler = router.SchedulerConfig{
+		Enabled:              cfg.Routing.Scheduling.Enabled,
+		Policy:               cfg.Routing.Scheduling.Policy,
+		DefaultMaxConcurrent: cfg.Routing.Scheduling.MaxConcurrent,
+		MaxConcurrent:        make(map[string]int),
+		MaxQueueDepth:        cfg.Routing.Scheduling.MaxQueueDepth,
+		QueueTimeout:         parseDuration(cfg.Routing.Scheduling.QueueTimeout, 0, "routing.scheduling.queue_timeout"),
+	}
+	for _, backendCfg := range cfg.Backends {
+		if backendCfg.Enabled && backendCfg.Characteristics.MaxConcurrent > 0 {
+			routerCfg.Scheduler.MaxConcurrent[backendCfg.ID] = backendCfg.Characteristics.MaxConcurrent
+		}
+	}
+	// Hedged duplicates for latency-critical requests
+	routerCfg.Hedging = router.HedgingConfig{
+		Enabled: cfg.Routing.Hedging.Enabled,
+		Delay:   parseDuration(cfg.Routing.Hedging.Delay, router.DefaultHedgeDelay, "routing.hedging.delay"),
+	}
+	// Embedding batches split across backends
+	routerCfg.EmbedBatch = router.EmbedBatchConfig{
+		ChunkSize:     cfg.Routing.EmbedBatch.ChunkSize,
+		MaxPerBackend: cfg.Routing.EmbedBatch.MaxPerBackend,
+	}
+	if policy := cfg.Routing.Scheduling.Policy; policy != "" && !router.HasDiscipline(policy) {
+		logging.Logger.Warn("Unknown scheduling policy, using priority",
+			zap.String("policy", policy),
+		)
+	}
+
+	// Load-balancing strategies, globally or per model
+	routerCfg.LoadBalancing = router.LoadBalancingConfig{
+		Strategy:  cfg.Routing.LoadBalancing.Strategy,
+		Models:    cfg.Routing.LoadBalancing.Models,
+		Weights:   make(map[string]int),
+		EWMADecay: cfg.Routing.LoadBalancing.EWMADecay,
+	}
+	for _, backendCfg := range cfg.Backends {
+		if backendCfg.Enabled && backendCfg.Characteristics.Weight > 0 {
+			routerCfg.LoadBalancing.Weights[backendCfg.ID] = backendCfg.Characteristics.Weight
+		}
+	}
+	strategies := []string{cfg.Routing.LoadBalancing.Strategy}
+	for _, strategy := range cfg.Routing.LoadBalancing.Models {
+		strategies = append(strategies, strategy)
+	}
+	for _, strategy := range strategies {
+		if !router.HasBalancer(strategy) {
+			logging.Logger.Warn("Unknown load-balancing strategy, using scoring",
+				zap.String("strategy", strategy),
+			)
+		}
+	}
+	if cfg.Routing.LoadBalancing.Strategy != "" || len(cfg.Routing.LoadBalancing.Models) > 0 {
+		logging.Logger.Info("Load balancing configured",
+			zap.String("strategy", cfg.Routing.LoadBalancing.Strategy),
+			zap.Int("model_overrides", len(cfg.Routing.LoadBalancing.Models)),
+		)
+	}
+
+	// Configure prompt language detection
+	if name := cfg.Routing.LanguageDetection.Detector; name != "" {
+		if detector, ok := langdetect.Lookup(name); ok {
+			langdetect.SetDefault(detector)
+		} else {
+			logging.Logger.Warn("Unknown language detector, using heuristic",
+				zap.String("detector", name),
+			)
+		}
 	}
+	langdetect.SetMinConfidence(cfg.Routing.LanguageDetection.MinConfidence)
 
 	// Create base router
 	var baseRouter *router.Router
@@ -283,6 +495,154 @@ func main() {
 		)
 	}
 
+	// Requests with certain client labels may only use certain backends
+	if rules := cfg.Server.Labels.Backends; len(rules) > 0 {
+		labelPolicy := policy.NewPolicy()
+		for rule, ids := range rules {
+			label, value, _ := strings.Cut(rule, "=")
+			labelPolicy.SetLabelBackends(label, value, ids)
+		}
+		baseRouter.SetLabelPolicy(labelPolicy)
+		logging.Logger.Info("Label routing rules enabled", zap.Int("rules", len(rules)))
+	}
+
+	// Busy accelerators score lower, whoever keeps them busy
+	if utilizationMonitor != nil {
+		baseRouter.SetUtilizationSource(utilizationMonitor.HardwareUtilization)
+	}
+
+	// Requests report the energy they used, measured where RAPL or NVML
+	// can see the hardware and estimated elsewhere
+	if ec := cfg.Monitoring.Energy; ec.Enabled {
+		meter := energy.NewMeter(
+			parseDuration(ec.Interval, energy.DefaultInterval, "monitoring.energy.interval"),
+			energySources(ec.Sources)...,
+		)
+		go meter.Run(ctx)
+		baseRouter.SetEnergyMeter(meter)
+		logging.Logger.Info("Energy measurement enabled", zap.Strings("sources", ec.Sources))
+	}
+
+	// Requests that would run an accelerator out of memory go elsewhere
+	if cfg.Routing.MemoryAdmission.Enabled && utilizationMonitor != nil {
+		ma := cfg.Routing.MemoryAdmission
+		baseRouter.SetMemoryAdmission(utilizationMonitor.HardwareMemoryFree, router.MemoryAdmissionConfig{
+			Headroom:             uint64(ma.HeadroomMB) << 20,
+			DefaultContextLength: int32(ma.DefaultContextLength),
+			ResidentWindow:       parseDuration(ma.ResidentWindow, router.DefaultResidentWindow, "routing.memory_admission.resident_window"),
+		})
+		logging.Logger.Info("GPU memory admission enabled", zap.Int("headroom_mb", ma.HeadroomMB))
+	}
+
+	// Rejected requests are told when capacity is expected back
+	var cooldown router.CooldownSource
+	if thermalMonitor != nil {
+		cooldown = thermalMonitor.Cooldown
+	}
+	retryCfg := cfg.Routing.RetryAfter
+	baseRouter.SetRetryAdvice(cooldown, router.RetryAfterConfig{
+		Min:   parseDuration(retryCfg.Min, router.DefaultRetryAfterMin, "routing.retry_after.min"),
+		Max:   parseDuration(retryCfg.Max, router.DefaultRetryAfterMax, "routing.retry_after.max"),
+		Debug: retryCfg.DebugHeader,
+	})
+
+	// Candidates are weighed by the cost terms of the effective mode
+	if efficiencyMgr != nil {
+		weights := make(map[efficiency.EfficiencyMode]router.CostWeights)
+		for _, mode := range efficiency.AllModes() {
+			mc := efficiency.GetModeConfig(mode)
+			weights[mode] = router.CostWeights{
+				Energy:  mc.EnergyWeight,
+				Latency: mc.LatencyWeight,
+				Thermal: mc.ThermalWeight,
+				Queue:   mc.QueueWeight,
+			}
+		}
+		baseRouter.SetCostWeights(func() router.CostWeights {
+			return weights[efficiencyMgr.GetEffectiveMode()]
+		})
+	}
+
+	// Degradation profiles say what the proxy gives up under stress
+	var degradation *degrade.Manager
+	if dg := cfg.Server.Degradation; len(dg.Profiles) > 0 {
+		profiles := make([]degrade.Profile, 0, len(dg.Profiles))
+		for _, p := range dg.Profiles {
+			profiles = append(profiles, degrade.Profile{
+				Name: p.Name,
+				When: degrade.Conditions{
+					Thermal:      p.When.Thermal,
+					Throttling:   p.When.Throttling,
+					OnBattery:    p.When.OnBattery,
+					BatteryBelow: p.When.BatteryBelow,
+				},
+				DisableEndpoints: p.DisableEndpoints,
+				MaxTokens:        p.MaxTokens,
+				AllowedBackends:  p.Backends,
+				MaxPowerWatts:    p.MaxPowerWatts,
+			})
+		}
+		degradation = degrade.New(profiles, func() degrade.State {
+			return degradationState(thermalMonitor, efficiencyMgr)
+		}, parseDuration(dg.Interval, degrade.DefaultInterval, "server.degradation.interval"))
+		go degradation.Run(ctx)
+		logging.Logger.Info("Degradation profiles enabled", zap.Int("profiles", len(profiles)))
+	}
+
+	// Quiet windows enforce Quiet mode limits whatever mode is selected;
+	// an active degradation profile's limits take precedence
+	if efficiencyMgr != nil || degradation != nil {
+		quietCfg := efficiency.GetModeConfig(efficiency.ModeQuiet)
+		baseRouter.SetModeConstraints(func() (router.ModeConstraints, bool) {
+			if degradation != nil {
+				if mc, ok := degradation.Constraints(); ok {
+					return mc, true
+				}
+			}
+			if efficiencyMgr == nil {
+				return router.ModeConstraints{}, false
+			}
+			window, ok := efficiencyMgr.ActiveQuietWindow()
+			if !ok {
+				return router.ModeConstraints{}, false
+			}
+			return router.ModeConstraints{
+				Reason:          "quiet window: " + window.Reason,
+				MaxPowerWatts:   int32(quietCfg.MaxPowerWatts),
+				AllowedBackends: quietCfg.PreferredBackends,
+			}, true
+		})
+	}
+
+	// Record routing decisions for offline replay
+	var decisionLog *replay.Recorder
+	if cfg.Routing.DecisionLog.Enabled {
+		var thermalLookup replay.ThermalLookup
+		if thermalMonitor != nil {
+			thermalLookup = func(hardware string) (float64, bool, bool) {
+				state := thermalMonitor.GetState(hardware)
+				if state == nil {
+					return 0, false, false
+				}
+				return state.Temperature, state.Throttling, true
+			}
+		}
+		decisionLog, err = replay.NewRecorder(replay.RecorderConfig{
+			Path:       cfg.Routing.DecisionLog.Path,
+			SampleRate: cfg.Routing.DecisionLog.SampleRate,
+			MaxSizeMB:  cfg.Routing.DecisionLog.MaxSizeMB,
+		}, thermalLookup)
+		if err != nil {
+			logging.Logger.Warn("Routing decision log disabled", zap.Error(err))
+		} else {
+			baseRouter.SetDecisionObserver(decisionLog.Observe)
+			logging.Logger.Info("Recording routing decisions",
+				zap.String("path", cfg.Routing.DecisionLog.Path),
+				zap.Float64("sample_rate", cfg.Routing.DecisionLog.SampleRate),
+			)
+		}
+	}
+
 	// Optionally wrap with forwarding router
 	var forwardingRouter *router.ForwardingRouter
 	if cfg.Routing.Forwarding.Enabled {
@@ -293,6 +653,8 @@ func main() {
 			EscalationPath:       cfg.Routing.Forwarding.EscalationPath,
 			RespectThermalLimits: cfg.Routing.Forwarding.RespectThermalLimits,
 			ReturnBestAttempt:    cfg.Routing.Forwarding.ReturnBestAttempt,
+			AutoPull:             cfg.Routing.Forwarding.AutoPull,
+			AutoPullTimeout:      parseDuration(cfg.Routing.Forwarding.AutoPullTimeout, 30*time.Minute, "routing.forwarding.auto_pull_timeout"),
 		}
 
 		forwardingRouter = router.NewForwardingRouter(baseRouter, thermalRouter, forwardingCfg)
@@ -302,6 +664,42 @@ func main() {
 		)
 	}
 
+	// Sign requests to federated backends (peer proxies) with this
+	// instance's key
+	federationSigner, err := newFederationSigner(cfg)
+	if err != nil {
+		logging.Logger.Fatal("Failed to load federation key", zap.Error(err))
+	}
+	if federationSigner != nil {
+		logging.Logger.Info("Federation signing enabled",
+			zap.String("instance_id", cfg.Server.Federation.InstanceID),
+			zap.String("public_key", federationSigner.PublicKey()),
+		)
+	}
+
+	// Backends are registered behind a circuit breaker when enabled, so a
+	// failing backend stops receiving traffic before its next health check
+	circuitBackends := make(map[string]*circuit.Backend)
+	registerBackend := func(b backends.Backend) error {
+		if cbCfg := cfg.Routing.CircuitBreaker; cbCfg.Enabled {
+			maxFailures := cbCfg.MaxFailures
+			if maxFailures == 0 {
+				maxFailures = 5
+			}
+			cb := circuit.NewBackend(b, circuit.Config{
+				MaxFailures:    maxFailures,
+				ErrorRate:      cbCfg.ErrorRate,
+				MinRequests:    cbCfg.MinRequests,
+				Window:         parseDuration(cbCfg.Window, 30*time.Second, "routing.circuit_breaker.window"),
+				OpenTimeout:    parseDuration(cbCfg.OpenTimeout, 10*time.Second, "routing.circuit_breaker.open_timeout"),
+				HalfOpenProbes: cbCfg.HalfOpenProbes,
+			})
+			circuitBackends[b.ID()] = cb
+			b = cb
+		}
+		return r.RegisterBackend(b)
+	}
+
 	// Register backends
 	for _, backendCfg := range cfg.Backends {
 		if !backendCfg.Enabled {
@@ -325,7 +723,7 @@ func main() {
 				}
 			}
 
-			backend, err := ollama.NewOllamaBackend(ollama.Config{
+			ollamaCfg := ollama.Config{
 				BackendConfig: backends.BackendConfig{
 					ID:              backendCfg.ID,
 					Type:            backendCfg.Type,
@@ -338,7 +736,14 @@ func main() {
 					ModelCapability: modelCap,
 				},
 				Endpoint: backendCfg.Endpoint,
-			})
+			}
+			if backendCfg.Federated {
+				target := backendCfg.PeerInstance
+				ollamaCfg.WrapTransport = func(base http.RoundTripper) http.RoundTripper {
+					return federationSigner.Transport(target, base)
+				}
+			}
+			ollamaBackend, err := ollama.NewOllamaBackend(ollamaCfg)
 			if err != nil {
 				logging.Logger.Error("Failed to create backend",
 					zap.String("backend_id", backendCfg.ID),
@@ -347,6 +752,14 @@ func main() {
 				continue
 			}
 
+			// Optionally manage the engine as a container
+			var backend backends.Backend = ollamaBackend
+			if backendCfg.Container.Image != "" {
+				managed := newContainerBackend(backendCfg, ollamaBackend, "/api/tags")
+				go managed.Lifecycle().RunIdleReaper(ctx)
+				backend = managed
+			}
+
 			// Start backend
 			if err := backend.Start(ctx); err != nil {
 				logging.Logger.Warn("Backend failed to start, skipping registration",
@@ -362,7 +775,7 @@ func main() {
 				zap.String("endpoint", backendCfg.Endpoint),
 			)
 
-			if err := r.RegisterBackend(backend); err != nil {
+			if err := registerBackend(backend); err != nil {
 				logging.Logger.Error("Failed to register backend",
 					zap.String("backend_id", backendCfg.ID),
 					zap.Error(err),
@@ -370,7 +783,7 @@ func main() {
 				continue
 			}
 
-		case "openvino":
+		case "vllm":
 			// Build model capability
 			var modelCap *backends.ModelCapability
 			if backendCfg.ModelCapability.MaxModelSizeGB > 0 ||
@@ -383,7 +796,7 @@ func main() {
 				}
 			}
 
-			backend, err := openvino.NewOpenVINOLLMBackend(openvino.LLMConfig{
+			vllmBackend, err := vllm.NewVLLMBackend(vllm.Config{
 				BackendConfig: backends.BackendConfig{
 					ID:              backendCfg.ID,
 					Type:            backendCfg.Type,
@@ -395,149 +808,450 @@ func main() {
 					Priority:        backendCfg.Characteristics.Priority,
 					ModelCapability: modelCap,
 				},
-				Device:    backendCfg.Device,
-				ModelPath: backendCfg.ModelPath,
-				ModelName: backendCfg.ModelName,
-			}, logging.Logger)
+				Endpoint:  backendCfg.Endpoint,
+				APIKeyEnv: backendCfg.APIKeyEnv,
+			})
 			if err != nil {
-				logging.Logger.Error("Failed to create OpenVINO backend",
+				logging.Logger.Error("Failed to create backend",
 					zap.String("backend_id", backendCfg.ID),
 					zap.Error(err),
 				)
 				continue
 			}
 
+			// Optionally manage the engine as a container
+			var backend backends.Backend = vllmBackend
+			if backendCfg.Container.Image != "" {
+				managed := newContainerBackend(backendCfg, vllmBackend, "/health")
+				go managed.Lifecycle().RunIdleReaper(ctx)
+				backend = managed
+			}
+
 			// Start backend
 			if err := backend.Start(ctx); err != nil {
-				logging.Logger.Warn("OpenVINO backend failed to start, skipping registration",
+				logging.Logger.Warn("Backend failed to start, skipping registration",
 					zap.String("backend_id", backendCfg.ID),
 					zap.Error(err),
 				)
 				continue
 			}
 
-			logging.Logger.Info("OpenVINO backend started successfully",
+			logging.Logger.Info("Backend started successfully",
 				zap.String("backend_id", backendCfg.ID),
 				zap.String("hardware", backendCfg.Hardware),
-				zap.String("device", backendCfg.Device),
-				zap.String("model", backendCfg.ModelName),
+				zap.String("endpoint", backendCfg.Endpoint),
 			)
 
-			if err := r.RegisterBackend(backend); err != nil {
-				logging.Logger.Error("Failed to register OpenVINO backend",
+			if err := registerBackend(backend); err != nil {
+				logging.Logger.Error("Failed to register backend",
 					zap.String("backend_id", backendCfg.ID),
 					zap.Error(err),
 				)
 				continue
 			}
 
-		default:
-			logging.Logger.Warn("Unknown backend type",
-				zap.String("type", backendCfg.Type),
-			)
-		}
-	}
-
-	// Initialize pipeline system
-	// Note: Pipeline executor is always created for virtual device support
-	var pipelineExecutor *pipeline.PipelineExecutor
-	var pipelineLoader *pipeline.PipelineLoader
+		case "sdwebui":
+			// Build model capability
+			var modelCap *backends.ModelCapability
+			if backendCfg.ModelCapability.MaxModelSizeGB > 0 ||
+				len(backendCfg.ModelCapability.SupportedModelPatterns) > 0 {
+				modelCap = &backends.ModelCapability{
+					MaxModelSizeGB:         backendCfg.ModelCapability.MaxModelSizeGB,
+					SupportedModelPatterns: backendCfg.ModelCapability.SupportedModelPatterns,
+					PreferredModels:        backendCfg.ModelCapability.PreferredModels,
+					ExcludedPatterns:       backendCfg.ModelCapability.ExcludedPatterns,
+				}
+			}
 
-	// Create executor with all backends (required for virtual devices)
-	allBackends := r.ListBackends()
-	pipelineExecutor = pipeline.NewPipelineExecutor(allBackends)
-	logging.Logger.Info("Pipeline executor initialized",
-		zap.Int("backends", len(allBackends)),
-	)
+			sdBackend, err := sdwebui.NewSDWebUIBackend(sdwebui.Config{
+				BackendConfig: backends.BackendConfig{
+					ID:              backendCfg.ID,
+					Type:            backendCfg.Type,
+					Name:            backendCfg.Name,
+					Hardware:        backendCfg.Hardware,
+					Enabled:         backendCfg.Enabled,
+					PowerWatts:      backendCfg.Characteristics.PowerWatts,
+					AvgLatencyMs:    backendCfg.Characteristics.AvgLatencyMs,
+					Priority:        backendCfg.Characteristics.Priority,
+					ModelCapability: modelCap,
+				},
+				Endpoint: backendCfg.Endpoint,
+				AuthEnv:  backendCfg.APIKeyEnv,
+			})
+			if err != nil {
+				logging.Logger.Error("Failed to create backend",
+					zap.String("backend_id", backendCfg.ID),
+					zap.Error(err),
+				)
+				continue
+			}
 
-	if cfg.Pipelines.Enabled {
-		logging.Logger.Info("Loading pipeline configurations",
-			zap.String("config_file", cfg.Pipelines.ConfigFile),
-		)
+			// Optionally manage the server as a container
+			var backend backends.Backend = sdBackend
+			if backendCfg.Container.Image != "" {
+				managed := newContainerBackend(backendCfg, sdBackend, "/sdapi/v1/progress")
+				go managed.Lifecycle().RunIdleReaper(ctx)
+				backend = managed
+			}
 
-		// Load pipelines from config
-		pipelineLoader = pipeline.NewPipelineLoader()
-		if err := pipelineLoader.LoadFromFile(cfg.Pipelines.ConfigFile); err != nil {
-			logging.Logger.Warn("Failed to load pipelines", zap.Error(err))
-		} else {
-			pipelineIDs := pipelineLoader.ListPipelines()
-			logging.Logger.Info("Loaded pipelines",
-				zap.Int("count", len(pipelineIDs)),
-				zap.Strings("pipeline_ids", pipelineIDs),
-			)
-		}
-	} else {
-		logging.Logger.Info("Pipeline config loading disabled (executor still available)")
-	}
+			// Start backend
+			if err := backend.Start(ctx); err != nil {
+				logging.Logger.Warn("Backend failed to start, skipping registration",
+					zap.String("backend_id", backendCfg.ID),
+					zap.Error(err),
+				)
+				continue
+			}
 
-	// Initialize virtual device manager
-	var virtualDevMgr *virtual.VirtualDeviceManager
-	if cfg.VirtualDevices.Enabled {
-		vdm, err := virtual.NewVirtualDeviceManager(
-			deviceManager,
-			pipelineExecutor,
-			&cfg.VirtualDevices,
-			logging.Logger,
-		)
-		if err != nil {
-			logging.Logger.Warn("Failed to initialize virtual device manager",
-				zap.Error(err),
-				zap.String("note", "Virtual audio/video devices unavailable"),
+			logging.Logger.Info("Backend started successfully",
+				zap.String("backend_id", backendCfg.ID),
+				zap.String("hardware", backendCfg.Hardware),
+				zap.String("endpoint", backendCfg.Endpoint),
 			)
-		} else {
-			virtualDevMgr = vdm
 
-			// Collect backend information
-			backendInfos := make([]virtual.BackendInfo, 0)
-			for _, backendCfg := range cfg.Backends {
-				if !backendCfg.Enabled {
-					continue
-				}
-				backendInfos = append(backendInfos, virtual.BackendInfo{
-					ID:       backendCfg.ID,
-					Name:     backendCfg.Name,
-					Hardware: backendCfg.Hardware,
-				})
+			if err := registerBackend(backend); err != nil {
+				logging.Logger.Error("Failed to register backend",
+					zap.String("backend_id", backendCfg.ID),
+					zap.Error(err),
+				)
+				continue
 			}
 
-			// Create audio devices for each backend
-			for _, backendInfo := range backendInfos {
-				if err := virtualDevMgr.CreateDevicesForBackend(
-					backendInfo.ID,
-					backendInfo.Name,
-					backendInfo.Hardware,
-				); err != nil {
-					logging.Logger.Error("Failed to create virtual devices for backend",
-						zap.String("backend", backendInfo.ID),
-						zap.Error(err),
-					)
-				}
+		case "cloud":
+			if cfg.Routing.DisableCloudEgress {
+				logging.Logger.Info("Cloud egress disabled, skipping cloud backend",
+					zap.String("backend_id", backendCfg.ID),
+				)
+				continue
 			}
 
-			// Create virtual cameras (must be done together due to module loading)
-			if cfg.VirtualDevices.Video.Enabled && cfg.VirtualDevices.Video.Camera.Enabled {
-				if err := virtualDevMgr.CreateVirtualCameras(backendInfos); err != nil {
-					logging.Logger.Error("Failed to create virtual cameras",
-						zap.Error(err),
-					)
+			// Build model capability
+			var modelCap *backends.ModelCapability
+			if backendCfg.ModelCapability.MaxModelSizeGB > 0 ||
+				len(backendCfg.ModelCapability.SupportedModelPatterns) > 0 {
+				modelCap = &backends.ModelCapability{
+					MaxModelSizeGB:         backendCfg.ModelCapability.MaxModelSizeGB,
+					SupportedModelPatterns: backendCfg.ModelCapability.SupportedModelPatterns,
+					PreferredModels:        backendCfg.ModelCapability.PreferredModels,
+					ExcludedPatterns:       backendCfg.ModelCapability.ExcludedPatterns,
 				}
 			}
 
-			logging.Logger.Info("Virtual devices created",
-				zap.Int("microphones", virtualDevMgr.GetSourceCount()),
-				zap.Int("speakers", virtualDevMgr.GetSinkCount()),
-				zap.Int("cameras", virtualDevMgr.GetCameraCount()),
-			)
-
-		// Register backends with virtual device manager for meeting bridge
-		registeredBackends := r.ListBackends()
-		for _, backend := range registeredBackends {
-			// Register with virtual device manager
-			virtualDevMgr.RegisterBackend(backend.ID(), backend)
-			logging.Logger.Debug("Registered backend with virtual device manager",
-				zap.String("backend_id", backend.ID()),
-			)
-		}
+			// Separate input/output prices, or the blended cost_per_1k_tokens
+			inputCost, outputCost := backendCfg.Cloud.InputCostPer1K, backendCfg.Cloud.OutputCostPer1K
+			if inputCost == 0 && outputCost == 0 {
+				inputCost = backendCfg.Characteristics.CostPer1KTokens
+				outputCost = backendCfg.Characteristics.CostPer1KTokens
+			}
+
+			backend, err := cloud.NewCloudBackend(cloud.Config{
+				BackendConfig: backends.BackendConfig{
+					ID:              backendCfg.ID,
+					Type:            backendCfg.Type,
+					Name:            backendCfg.Name,
+					Hardware:        backends.HardwareCloud,
+					Enabled:         backendCfg.Enabled,
+					AvgLatencyMs:    backendCfg.Characteristics.AvgLatencyMs,
+					Priority:        backendCfg.Characteristics.Priority,
+					ModelCapability: modelCap,
+				},
+				Provider:        backendCfg.Cloud.Provider,
+				Endpoint:        backendCfg.Endpoint,
+				APIKeyEnv:       backendCfg.APIKeyEnv,
+				Models:          backendCfg.Cloud.Models,
+				InputCostPer1K:  inputCost,
+				OutputCostPer1K: outputCost,
+			})
+			if err != nil {
+				logging.Logger.Error("Failed to create backend",
+					zap.String("backend_id", backendCfg.ID),
+					zap.Error(err),
+				)
+				continue
+			}
+
+			// Start backend
+			if err := backend.Start(ctx); err != nil {
+				logging.Logger.Warn("Backend failed to start, skipping registration",
+					zap.String("backend_id", backendCfg.ID),
+					zap.Error(err),
+				)
+				continue
+			}
+
+			logging.Logger.Info("Backend started successfully",
+				zap.String("backend_id", backendCfg.ID),
+				zap.String("hardware", backends.HardwareCloud),
+				zap.String("provider", backend.Provider()),
+			)
+
+			if err := registerBackend(backend); err != nil {
+				logging.Logger.Error("Failed to register backend",
+					zap.String("backend_id", backendCfg.ID),
+					zap.Error(err),
+				)
+				continue
+			}
+
+		case "openvino":
+			// Build model capability
+			var modelCap *backends.ModelCapability
+			if backendCfg.ModelCapability.MaxModelSizeGB > 0 ||
+				len(backendCfg.ModelCapability.SupportedModelPatterns) > 0 {
+				modelCap = &backends.ModelCapability{
+					MaxModelSizeGB:         backendCfg.ModelCapability.MaxModelSizeGB,
+					SupportedModelPatterns: backendCfg.ModelCapability.SupportedModelPatterns,
+					PreferredModels:        backendCfg.ModelCapability.PreferredModels,
+					ExcludedPatterns:       backendCfg.ModelCapability.ExcludedPatterns,
+				}
+			}
+
+			backend, err := openvino.NewOpenVINOLLMBackend(openvino.LLMConfig{
+				BackendConfig: backends.BackendConfig{
+					ID:              backendCfg.ID,
+					Type:            backendCfg.Type,
+					Name:            backendCfg.Name,
+					Hardware:        backendCfg.Hardware,
+					Enabled:         backendCfg.Enabled,
+					PowerWatts:      backendCfg.Characteristics.PowerWatts,
+					AvgLatencyMs:    backendCfg.Characteristics.AvgLatencyMs,
+					Priority:        backendCfg.Characteristics.Priority,
+					ModelCapability: modelCap,
+				},
+				Device:    backendCfg.Device,
+				ModelPath: backendCfg.ModelPath,
+				ModelName: backendCfg.ModelName,
+			}, logging.Logger)
+			if err != nil {
+				logging.Logger.Error("Failed to create OpenVINO backend",
+					zap.String("backend_id", backendCfg.ID),
+					zap.Error(err),
+				)
+				continue
+			}
+
+			// Start backend
+			if err := backend.Start(ctx); err != nil {
+				logging.Logger.Warn("OpenVINO backend failed to start, skipping registration",
+					zap.String("backend_id", backendCfg.ID),
+					zap.Error(err),
+				)
+				continue
+			}
+
+			logging.Logger.Info("OpenVINO backend started successfully",
+				zap.String("backend_id", backendCfg.ID),
+				zap.String("hardware", backendCfg.Hardware),
+				zap.String("device", backendCfg.Device),
+				zap.String("model", backendCfg.ModelName),
+			)
+
+			if err := registerBackend(backend); err != nil {
+				logging.Logger.Error("Failed to register OpenVINO backend",
+					zap.String("backend_id", backendCfg.ID),
+					zap.Error(err),
+				)
+				continue
+			}
+
+		default:
+			logging.Logger.Warn("Unknown backend type",
+				zap.String("type", backendCfg.Type),
+			)
+		}
+	}
+
+	// Speculative decoding drafts on a small model for verifying backends
+	if spec := cfg.Routing.Speculative; spec.Enabled {
+		if draft, ok := baseRouter.GetBackend(spec.DraftBackend); ok {
+			baseRouter.SetSpeculativeDecoder(speculative.NewDecoder(draft, speculative.Config{
+				DraftModel:  spec.DraftModel,
+				DraftTokens: spec.DraftTokens,
+				MaxTokens:   spec.MaxTokens,
+			}))
+			logging.Logger.Info("Speculative decoding enabled",
+				zap.String("draft_backend", spec.DraftBackend),
+				zap.String("draft_model", spec.DraftModel),
+			)
+		} else {
+			logging.Logger.Warn("Speculative decoding disabled, draft backend not registered",
+				zap.String("draft_backend", spec.DraftBackend),
+			)
+		}
+	}
+
+	// Ensembles query several backends and return the best answer
+	if ens := cfg.Routing.Ensemble; ens.Enabled {
+		ensembleCfg := &router.EnsembleConfig{Size: ens.Size, Aliases: ens.Aliases}
+		if judge := ens.Judge; judge.Backend != "" {
+			if backend, ok := baseRouter.GetBackend(judge.Backend); ok {
+				ensembleCfg.Judge = &confidence.JudgeScorer{
+					Backend: backend,
+					Model:   judge.Model,
+					Timeout: parseDuration(judge.Timeout, 0, "routing.ensemble.judge.timeout"),
+				}
+			} else {
+				logging.Logger.Warn("Ensemble judge disabled, judge backend not registered",
+					zap.String("judge_backend", judge.Backend),
+				)
+			}
+		}
+		if ensembleCfg.Judge == nil {
+			ensembleCfg.Judge = confidence.NewConfidenceEstimator(confidenceConfig(cfg))
+		}
+		baseRouter.SetEnsemble(ensembleCfg)
+		logging.Logger.Info("Ensemble requests enabled",
+			zap.Int("aliases", len(ens.Aliases)),
+			zap.String("judge_model", ens.Judge.Model),
+		)
+	}
+
+	// Score forwarding attempts with the configured scorer
+	if forwardingRouter != nil {
+		heuristics := confidence.NewConfidenceEstimator(confidenceConfig(cfg))
+		switch judge := cfg.Routing.Forwarding.Judge; cfg.Routing.Forwarding.Scorer {
+		case "logprob":
+			forwardingRouter.SetScorer(&confidence.LogprobScorer{Fallback: heuristics})
+		case "judge":
+			if backend, ok := baseRouter.GetBackend(judge.Backend); ok {
+				forwardingRouter.SetScorer(&confidence.JudgeScorer{
+					Backend: backend,
+					Model:   judge.Model,
+					Timeout: parseDuration(judge.Timeout, 0, "routing.forwarding.judge.timeout"),
+				})
+			} else {
+				forwardingRouter.SetScorer(heuristics)
+				logging.Logger.Warn("Forwarding judge disabled, judge backend not registered",
+					zap.String("judge_backend", judge.Backend),
+				)
+			}
+		default:
+			forwardingRouter.SetScorer(heuristics)
+		}
+		scorer := cfg.Routing.Forwarding.Scorer
+		if scorer == "" {
+			scorer = "heuristic"
+		}
+		logging.Logger.Info("Forwarding confidence scorer configured",
+			zap.String("scorer", scorer),
+		)
+	}
+
+	// Initialize pipeline system; the executor always exists for virtual
+	// devices
+	pipelines := newPipelines(cfg, r.ListBackends(), baseRouter.Admission(), deviceManager)
+	pipelineExecutor, pipelineLoader := pipelines.executor, pipelines.loader
+
+	// Initialize virtual device manager
+	var virtualDevMgr *virtual.VirtualDeviceManager
+	startVirtualDevices := func() {
+		if !cfg.VirtualDevices.Enabled {
+			return
+		}
+		vdm, err := virtual.NewVirtualDeviceManager(
+			deviceManager,
+			pipelineExecutor,
+			&cfg.VirtualDevices,
+			logging.Logger,
+		)
+		if err != nil {
+			logging.Logger.Warn("Failed to initialize virtual device manager",
+				zap.Error(err),
+				zap.String("note", "Virtual audio/video devices unavailable"),
+			)
+			return
+		}
+		virtualDevMgr = vdm
+
+		// Collect backend information
+		backendInfos := make([]virtual.BackendInfo, 0)
+		for _, backendCfg := range cfg.Backends {
+			if !backendCfg.Enabled {
+				continue
+			}
+			backendInfos = append(backendInfos, virtual.BackendInfo{
+				ID:       backendCfg.ID,
+				Name:     backendCfg.Name,
+				Hardware: backendCfg.Hardware,
+			})
+		}
+
+		// Create audio devices for each backend
+		for _, backendInfo := range backendInfos {
+			if err := virtualDevMgr.CreateDevicesForBackend(
+				backendInfo.ID,
+				backendInfo.Name,
+				backendInfo.Hardware,
+			); err != nil {
+				logging.Logger.Error("Failed to create virtual devices for backend",
+					zap.String("backend", backendInfo.ID),
+					zap.Error(err),
+				)
+			}
+		}
+
+		// Create virtual cameras (must be done together due to module loading)
+		if cfg.VirtualDevices.Video.Enabled && cfg.VirtualDevices.Video.Camera.Enabled {
+			if err := virtualDevMgr.CreateVirtualCameras(backendInfos); err != nil {
+				logging.Logger.Error("Failed to create virtual cameras",
+					zap.Error(err),
+				)
+			}
+		}
+
+		logging.Logger.Info("Virtual devices created",
+			zap.Int("microphones", virtualDevMgr.GetSourceCount()),
+			zap.Int("speakers", virtualDevMgr.GetSinkCount()),
+			zap.Int("cameras", virtualDevMgr.GetCameraCount()),
+		)
+
+		// Register backends with virtual device manager for meeting bridge
+		registeredBackends := r.ListBackends()
+		for _, backend := range registeredBackends {
+			// Register with virtual device manager
+			virtualDevMgr.RegisterBackend(backend.ID(), backend)
+			logging.Logger.Debug("Registered backend with virtual device manager",
+				zap.String("backend_id", backend.ID()),
+			)
+		}
+
+		// Defer best-effort requests from the backends serving a live meeting
+		// and keep the machine quiet for the duration of the call
+		guardMeetings := cfg.VirtualDevices.MeetingGuard.Enabled
+		quietMeetings := efficiencyMgr != nil && cfg.Efficiency.QuietDuringMeetings
+		if guardMeetings || quietMeetings {
+			admission := baseRouter.Admission()
+			maxLow := cfg.VirtualDevices.MeetingGuard.MaxLowPriorityConcurrent
+			// Bounded so a bridge that never reports its end can't keep
+			// the machine quiet indefinitely
+			quietMax := parseDuration(cfg.Efficiency.MeetingQuietMax, 2*time.Hour, "efficiency.meeting_quiet_max")
+			virtualDevMgr.SetMeetingStateHandler(func(bridgeID string, serving []string, active bool) {
+				owner := "meeting:" + bridgeID
+				if !active {
+					if guardMeetings {
+						admission.Release(owner)
+					}
+					if quietMeetings && efficiencyMgr.EndQuietWindow(owner) && dbusSvc != nil {
+						dbusSvc.NotifyQuietWindowChanged()
+					}
+					return
+				}
+				if guardMeetings {
+					admission.Reserve(router.Reservation{
+						Owner:          owner,
+						BackendIDs:     serving,
+						Reason:         "live meeting bridge",
+						MaxLowPriority: maxLow,
+					})
+				}
+				if quietMeetings {
+					efficiencyMgr.StartQuietWindow(owner, "meeting", quietMax)
+					if dbusSvc != nil {
+						dbusSvc.NotifyQuietWindowChanged()
+					}
+				}
+			})
+		}
 
 		// Auto-start meeting bridge for NPU backend
 		if err := virtualDevMgr.StartMeetingBridge("ollama-npu"); err != nil {
@@ -551,8 +1265,8 @@ func main() {
 				zap.String("description", "Google Meet AI Assistant active"),
 			)
 		}
-		}
-	} else {
+	}
+	if !cfg.VirtualDevices.Enabled {
 		logging.Logger.Info("Virtual device management disabled")
 	}
 
@@ -564,52 +1278,88 @@ func main() {
 		grpcRouter = r.(*router.Router)
 	}
 
-	// Create gRPC server with optional TLS
-	var grpcServer *grpc.Server
-	if cfg.Server.TLS.Enabled {
-		cert, err := tls.LoadX509KeyPair(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
-		if err != nil {
-			logging.Logger.Fatal("Failed to load TLS certificate", zap.Error(err))
-		}
+	// Drain mode refuses new requests on every listener while in-flight
+	// ones finish before shutdown
+	drainer := drain.New(parseDuration(cfg.Server.Drain.Timeout, drain.DefaultTimeout, "server.drain.timeout"))
 
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12,
-		}
+	// Initialize authentication middleware
+	var authMiddleware func(http.Handler) http.Handler
+	keyBudgets := 0 // API keys with their own rate limit
+	keyQuotas := 0  // API keys with their own quota
+	authConfig := auth.Config{APIKeys: make(map[string]auth.APIKeyInfo)}
+	if cfg.Server.Auth.Enabled {
+		authConfig.Enabled = true
 
-		// Optional mTLS if client CA provided
-		if cfg.Server.TLS.ClientCAFile != "" {
-			caCert, err := os.ReadFile(cfg.Server.TLS.ClientCAFile)
-			if err != nil {
-				logging.Logger.Fatal("Failed to load client CA", zap.Error(err))
+		// Convert config API keys to auth.APIKeyInfo
+		for key, keyInfo := range cfg.Server.Auth.APIKeys {
+			info := apiKeyInfo(keyInfo)
+			if info.RateLimit != nil {
+				keyBudgets++
 			}
-
-			caCertPool := x509.NewCertPool()
-			if !caCertPool.AppendCertsFromPEM(caCert) {
-				logging.Logger.Fatal("Failed to parse client CA certificate")
+			if info.Quota != nil {
+				keyQuotas++
 			}
+			authConfig.APIKeys[key] = info
+		}
 
-			tlsConfig.ClientCAs = caCertPool
-			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
-			logging.Logger.Info("gRPC mTLS enabled",
-				zap.String("security_level", "mutual_tls"),
-				zap.Bool("client_cert_required", true),
-			)
+		// Keys can be created, rotated and revoked at runtime over the
+		// admin APIs; those created then are saved hashed to the key store
+		if path := cfg.Server.Auth.KeyStore; path != "" {
+			keyStore, err := auth.OpenKeyStore(path, authConfig.APIKeys)
+			if err != nil {
+				logging.Logger.Fatal("Failed to open API key store", zap.Error(err))
+			}
+			authConfig.Keys = keyStore
+			go keyStore.RunPersistence(context.Background(), time.Minute, func(err error) {
+				logging.Logger.Warn("Failed to persist API key last-used times", zap.Error(err))
+			})
 		} else {
-			logging.Logger.Info("gRPC TLS enabled",
-				zap.String("security_level", "tls"),
-			)
+			authConfig.Keys = auth.NewKeyStore(authConfig.APIKeys)
 		}
-
-		creds := credentials.NewTLS(tlsConfig)
-		grpcServer = grpc.NewServer(grpc.Creds(creds))
+		authMiddleware = auth.APIKeyMiddleware(authConfig)
+		logging.Logger.Info("API authentication enabled",
+			zap.Int("api_keys_count", authConfig.Keys.Len()),
+			zap.String("key_store", cfg.Server.Auth.KeyStore),
+		)
 	} else {
-		grpcServer = grpc.NewServer()
-		logging.Logger.Warn("gRPC TLS disabled",
-			zap.String("warning", "not recommended for production"),
+		// No-op middleware when auth is disabled
+		authMiddleware = func(next http.Handler) http.Handler {
+			return next
+		}
+		logging.Logger.Info("API authentication disabled",
+			zap.String("warning", "all requests will be accepted"),
+		)
+	}
+
+	// Roles from client certificates and API key permissions, checked on
+	// every admin, inference, pipeline and device route and RPC
+	var authzPolicy *authz.Policy
+	if az := cfg.Server.Authz; az.Enabled {
+		authzCfg := authz.Config{
+			Certificates:       make(map[string]authz.Role, len(az.ClientCerts)),
+			CertificateDefault: authz.Role(az.DefaultCertRole),
+			Anonymous:          authz.Role(az.AnonymousRole),
+			Keys:               authConfig,
+		}
+		for identity, role := range az.ClientCerts {
+			authzCfg.Certificates[identity] = authz.Role(role)
+		}
+		authzPolicy = authz.New(authzCfg)
+		logging.Logger.Info("Role-based authorization enabled",
+			zap.Int("client_certs", len(az.ClientCerts)),
+			zap.String("default_cert_role", az.DefaultCertRole),
+			zap.String("anonymous_role", az.AnonymousRole),
 		)
 	}
 
+	// Usage accounting for per-tenant cost and energy reports, with the
+	// running per-key totals for /v1/usage and quota enforcement
+	accounting := newUsageAccounting(cfg)
+
+	// Create gRPC server with optional TLS
+	grpcService := newGRPCService(cfg, authzPolicy, authConfig, accounting.ledger, drainer)
+	grpcServer := grpcService.server
+
 	// Pass forwarding router to server if enabled
 	computeServer := server.NewComputeServer(grpcRouter)
 	if forwardingRouter != nil {
@@ -624,6 +1374,78 @@ func main() {
 
 	pb.RegisterComputeServiceServer(grpcServer, computeServer)
 
+	// Model management for backends that pull and delete models (Ollama)
+	modelManager := models.NewManager(grpcRouter)
+	pb.RegisterModelServiceServer(grpcServer, server.NewModelServer(modelManager, authConfig))
+
+	// Pull the most requested models onto the hardware tier that suits
+	// their size, ahead of demand
+	var placement *models.Placement
+	if pl := cfg.Routing.Placement; pl.Enabled {
+		tiers := make([]models.PlacementTier, 0, len(pl.Tiers))
+		for _, tier := range pl.Tiers {
+			tiers = append(tiers, models.PlacementTier{MaxParamsB: tier.MaxParamsB, Backends: tier.Backends})
+		}
+		placement = models.NewPlacement(modelManager, models.PlacementConfig{
+			Interval:    parseDuration(pl.Interval, models.DefaultPlacementInterval, "routing.placement.interval"),
+			Window:      parseDuration(pl.Window, models.DefaultPlacementWindow, "routing.placement.window"),
+			TopModels:   pl.TopModels,
+			MinRequests: pl.MinRequests,
+			PullTimeout: parseDuration(pl.PullTimeout, models.DefaultPlacementPullTimeout, "routing.placement.pull_timeout"),
+			Tiers:       tiers,
+		})
+
+		// Count demand from routing decisions, alongside the decision log
+		baseRouter.AddDecisionObserver(func(rec *router.DecisionRecord) { placement.Observe(rec.Annotations.Model) })
+		go placement.Run(ctx)
+
+		logging.Logger.Info("Model placement enabled",
+			zap.Int("tiers", len(tiers)),
+		)
+	}
+
+	// Latency observed per backend, model and prompt length predicts the
+	// latency of requests, and is kept across restarts when a path is set
+	latencyEstimator := baseRouter.LatencyEstimator()
+	if lp := cfg.Routing.LatencyPrediction; lp.Path != "" {
+		if err := latencyEstimator.Load(lp.Path); err != nil {
+			logging.Logger.Warn("Failed to restore latency estimates", zap.Error(err))
+		}
+		go latencyEstimator.RunPersistence(ctx, lp.Path, parseDuration(lp.SaveInterval, time.Minute, "routing.latency_prediction.save_interval"), func(err error) {
+			logging.Logger.Warn("Failed to persist latency estimates", zap.Error(err))
+		})
+
+		logging.Logger.Info("Latency prediction persistent",
+			zap.String("path", lp.Path),
+			zap.Int("restored", len(latencyEstimator.Estimates())),
+		)
+	}
+
+	// Nightly benchmarks refresh the per-model latency routing scores on
+	var bench *benchmark.Benchmark
+	if bm := cfg.Routing.Benchmark; bm.Enabled {
+		window, _ := benchmark.ParseWindow(bm.Window) // checked by ValidateConfig
+		bench = benchmark.New(baseRouter, benchmark.Config{
+			Window:    window,
+			Prompt:    bm.Prompt,
+			MaxTokens: int32(bm.MaxTokens),
+			Timeout:   parseDuration(bm.Timeout, benchmark.DefaultTimeout, "routing.benchmark.timeout"),
+			Interval:  parseDuration(bm.Interval, benchmark.DefaultInterval, "routing.benchmark.interval"),
+			Path:      bm.Path,
+		})
+		go bench.Run(ctx)
+
+		logging.Logger.Info("Nightly benchmarks enabled",
+			zap.String("window", bm.Window),
+			zap.Int("measured", len(bench.Results())),
+		)
+	}
+
+	// HA peer sync (authenticated with the shared secret)
+	if haNode != nil {
+		haNode.RegisterGRPC(grpcServer, cfg.HA.SharedSecret)
+	}
+
 	// Register device service if device manager is enabled
 	if deviceManager != nil {
 		deviceGRPCService := device.NewGRPCService(deviceManager)
@@ -634,39 +1456,41 @@ func main() {
 	// Enable gRPC reflection for grpcurl
 	reflection.Register(grpcServer)
 
-	// Start gRPC server
-	grpcAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.GRPCPort)
-	lis, err := net.Listen("tcp", grpcAddr)
-	if err != nil {
-		logging.Logger.Fatal("Failed to listen on gRPC port",
-			zap.String("address", grpcAddr),
-			zap.Error(err),
-		)
-	}
-
-	go func() {
-		logging.Logger.Info("gRPC server listening",
-			zap.String("address", grpcAddr),
-			zap.Bool("tls", cfg.Server.TLS.Enabled),
-			zap.Bool("reflection", true),
-		)
-		if err := grpcServer.Serve(lis); err != nil {
-			logging.Logger.Fatal("Failed to serve gRPC", zap.Error(err))
-		}
-	}()
+	// Bind the gRPC port; serving starts with the servers
+	grpcService.listen()
 
-	// HTTP endpoints
+	// HTTP endpoints, served from the proxy's own mux
 	httpAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.HTTPPort)
+	timeoutsCfg := cfg.Server.Timeouts
+	httpServer := serverhttp.New(httpAddr, serverhttp.Timeouts{
+		ReadHeader:  parseDuration(timeoutsCfg.ReadHeader, serverhttp.DefaultReadHeaderTimeout, "server.timeouts.read_header"),
+		Read:        parseDuration(timeoutsCfg.Read, 0, "server.timeouts.read"),
+		Write:       parseDuration(timeoutsCfg.Write, 0, "server.timeouts.write"),
+		StreamWrite: parseDuration(timeoutsCfg.StreamWrite, 0, "server.timeouts.stream_write"),
+		Idle:        parseDuration(timeoutsCfg.Idle, serverhttp.DefaultIdleTimeout, "server.timeouts.idle"),
+	})
+	// Every response carries X-Request-ID, the caller's or a new one
+	httpServer.Wrap(middleware.RequestID)
+	httpServer.Use(serverhttp.Health, middleware.HTTPRecovery)
 
 	// Liveness probe - Kubernetes style (is server alive?)
-	http.HandleFunc("/healthz", middleware.RecoveryHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	httpServer.HandleFunc(serverhttp.Health, "/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "ok\n")
-	}))
+	})
 
 	// Readiness probe - Kubernetes style (ready to serve traffic?)
-	http.HandleFunc("/readyz", middleware.RecoveryHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	httpServer.HandleFunc(serverhttp.Health, "/readyz", func(w http.ResponseWriter, r *http.Request) {
+		// Draining: take this instance out of rotation and report progress
+		if status := drainer.Status(); status.Draining {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "draining: %d requests in flight, %s until shutdown\n",
+				status.InFlight, time.Until(status.Deadline).Round(time.Second))
+			return
+		}
+
 		backends := grpcRouter.ListBackends()
 		healthyCount := 0
 		for _, backend := range backends {
@@ -683,20 +1507,27 @@ func main() {
 			w.WriteHeader(http.StatusServiceUnavailable)
 			fmt.Fprintf(w, "not ready: 0/%d backends healthy\n", len(backends))
 		}
-	}))
+
+		// Degraded instances still serve, within the active profile's limits
+		if degradation != nil {
+			if status := degradation.Status(); status.Active {
+				fmt.Fprintf(w, "degraded: profile %s since %s\n", status.Profile, status.Since.Format(time.RFC3339))
+			}
+		}
+	})
 
 	// Detailed health endpoint (legacy)
-	http.HandleFunc("/health", middleware.RecoveryHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	httpServer.HandleFunc(serverhttp.Health, "/health", func(w http.ResponseWriter, r *http.Request) {
 		healthResp, _ := computeServer.HealthCheck(r.Context(), &pb.HealthCheckRequest{})
 		w.Header().Set("Content-Type", "text/plain")
 		fmt.Fprintf(w, "Status: %s\n", healthResp.Status)
 		for backend, status := range healthResp.BackendHealth {
 			fmt.Fprintf(w, "  %s: %s\n", backend, status)
 		}
-	}))
+	})
 
 	// Backends endpoint
-	http.HandleFunc("/backends", middleware.RecoveryHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	httpServer.HandleFunc(serverhttp.Health, "/backends", func(w http.ResponseWriter, r *http.Request) {
 		backendsResp, _ := computeServer.ListBackends(r.Context(), &pb.ListBackendsRequest{})
 		w.Header().Set("Content-Type", "text/plain")
 		fmt.Fprintf(w, "Available Backends:\n\n")
@@ -705,23 +1536,46 @@ func main() {
 			fmt.Fprintf(w, "  Name: %s\n", b.Name)
 			fmt.Fprintf(w, "  Hardware: %s\n", b.Hardware)
 			fmt.Fprintf(w, "  Status: %s\n", b.Status.State)
+			if cb, ok := circuitBackends[b.Id]; ok {
+				stats := cb.Breaker().Stats()
+				fmt.Fprintf(w, "  Circuit: %s since %s (%d consecutive failures, %d/%d failed in window)\n",
+					stats.State, stats.Since.Format(time.RFC3339), stats.ConsecutiveFailures, stats.Failures, stats.Requests)
+			}
+			if placement != nil {
+				for _, p := range placement.Status(b.Id) {
+					fmt.Fprintf(w, "  Placement: %s %s (%d requests in window)", p.Model, p.State, p.Requests)
+					if p.Error != "" {
+						fmt.Fprintf(w, ": %s", p.Error)
+					}
+					fmt.Fprintln(w)
+				}
+			}
 			fmt.Fprintf(w, "  Power: %.1fW\n", b.Metrics.PowerWatts)
 			fmt.Fprintf(w, "  Avg Latency: %dms\n\n", b.Metrics.AvgLatencyMs)
 		}
-	}))
+	})
 
 	// Thermal status endpoint
 	if thermalMonitor != nil {
-		http.HandleFunc("/thermal", middleware.RecoveryHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpServer.HandleFunc(serverhttp.Health, "/thermal", func(w http.ResponseWriter, r *http.Request) {
 			states := thermalMonitor.GetAllStates()
+			if fanController != nil {
+				for hw, state := range states {
+					if status, ok := fanController.Status(hw); ok && state != nil {
+						withFan := *state
+						withFan.FanControl = &status
+						states[hw] = &withFan
+					}
+				}
+			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(states)
-		}))
+		})
 	}
 
 	// Efficiency mode endpoint
 	if efficiencyMgr != nil {
-		http.HandleFunc("/efficiency", middleware.RecoveryHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpServer.HandleFunc(serverhttp.Health, "/efficiency", func(w http.ResponseWriter, r *http.Request) {
 			currentMode := efficiencyMgr.GetMode()
 			effectiveMode := efficiencyMgr.GetEffectiveMode()
 
@@ -733,37 +1587,88 @@ func main() {
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(response)
-		}))
+		})
 	}
 
-	// Initialize authentication middleware
-	var authMiddleware func(http.Handler) http.Handler
-	if cfg.Server.Auth.Enabled {
-		authConfig := auth.Config{
-			Enabled: true,
-			APIKeys: make(map[string]auth.APIKeyInfo),
+	// Bandwidth-aware streaming (stats always collected, batching optional)
+	streamDefaults := streaming.DefaultConfig()
+	keepaliveInterval := parseDuration(cfg.Server.Streaming.KeepaliveInterval, streamDefaults.KeepaliveInterval, "server.streaming.keepalive_interval")
+	if keepaliveInterval == 0 {
+		keepaliveInterval = -1 // "0s" turns keepalives off
+	}
+	streamTracker := streaming.NewTracker(streaming.Config{
+		Enabled:            cfg.Server.Streaming.BandwidthAware,
+		SlowWriteThreshold: parseDuration(cfg.Server.Streaming.SlowWriteThreshold, streamDefaults.SlowWriteThreshold, "server.streaming.slow_write_threshold"),
+		MaxBatchTokens:     cfg.Server.Streaming.MaxBatchTokens,
+		MaxBatchDelay:      parseDuration(cfg.Server.Streaming.MaxBatchDelay, streamDefaults.MaxBatchDelay, "server.streaming.max_batch_delay"),
+		KeepaliveInterval:  keepaliveInterval,
+		IdleTimeout:        parseDuration(cfg.Server.Streaming.IdleTimeout, 0, "server.streaming.idle_timeout"),
+	})
+	streaming.SetDefault(streamTracker)
+	if cfg.Server.Streaming.BandwidthAware {
+		streamCfg := streamTracker.Config()
+		logging.Logger.Info("Bandwidth-aware streaming enabled",
+			zap.Duration("slow_write_threshold", streamCfg.SlowWriteThreshold),
+			zap.Int("max_batch_tokens", streamCfg.MaxBatchTokens),
+			zap.Duration("max_batch_delay", streamCfg.MaxBatchDelay),
+		)
+	}
+
+	// Headless management over gRPC (proxyctl); reloads are handed to the
+	// signal loop so they never race the configuration it swaps
+	reloadRequests := make(chan chan error)
+	adminServer := server.NewAdminServer(authConfig, drainer, func(ctx context.Context) error {
+		reply := make(chan error, 1)
+		select {
+		case reloadRequests <- reply:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+		select {
+		case err := <-reply:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if pipelineLoader != nil {
+		adminServer.SetPipelines(pipelineLoader)
+	}
+	pb.RegisterAdminServiceServer(grpcServer, adminServer)
 
-		// Convert config API keys to auth.APIKeyInfo
-		for key, keyInfo := range cfg.Server.Auth.APIKeys {
-			authConfig.APIKeys[key] = auth.APIKeyInfo{
-				Name:        keyInfo.Name,
-				Permissions: keyInfo.Permissions,
-				Enabled:     keyInfo.Enabled,
+	// Tailnet and WireGuard peers authenticate as the user or machine
+	// behind them, falling back to API keys
+	if ni := cfg.Server.NetworkIdentity; ni.Enabled {
+		networkAuth, err := newNetworkIdentity(ni)
+		if err != nil {
+			logging.Logger.Fatal("Failed to configure network identity", zap.Error(err))
+		}
+		authMiddleware = networkAuth.Middleware(authMiddleware)
+		for _, id := range ni.Identities {
+			if id.RateLimit != nil {
+				keyBudgets++
+			}
+			if id.Quota != nil {
+				keyQuotas++
 			}
 		}
-
-		authMiddleware = auth.APIKeyMiddleware(authConfig)
-		logging.Logger.Info("API authentication enabled",
-			zap.Int("api_keys_count", len(authConfig.APIKeys)),
-		)
-	} else {
-		// No-op middleware when auth is disabled
-		authMiddleware = func(next http.Handler) http.Handler {
-			return next
+		provider := ni.Provider
+		if provider == "" {
+			provider = "tailscale"
 		}
-		logging.Logger.Info("API authentication disabled",
-			zap.String("warning", "all requests will be accepted"),
+		logging.Logger.Info("Network identity enabled",
+			zap.String("provider", provider),
+			zap.Int("identities", len(ni.Identities)),
+			zap.Bool("default", ni.Default != nil),
+		)
+	}
+
+	// Requests signed by trusted peer proxies authenticate as the client
+	// identity they carry
+	if verifier := newFederationVerifier(cfg); verifier != nil {
+		authMiddleware = verifier.Middleware(authMiddleware)
+		logging.Logger.Info("Federation verification enabled",
+			zap.Int("trusted_peers", len(cfg.Server.Federation.TrustedPeers)),
 		)
 	}
 
@@ -779,6 +1684,18 @@ func main() {
 			zap.Float64("rate_per_second", cfg.Server.RateLimit.Rate),
 			zap.Int("burst", cfg.Server.RateLimit.Burst),
 		)
+
+		// Authenticated keys draw from their own budgets; the IP limit
+		// still covers unauthenticated requests and keys without one
+		perKey := authRateLimit(cfg.Server.RateLimit.PerKey)
+		if (cfg.Server.Auth.Enabled || cfg.Server.NetworkIdentity.Enabled) && (perKey.Rate > 0 || perKey.StreamRate > 0 || keyBudgets > 0) {
+			rateLimitMiddleware = ratelimit.NewKeyRateLimiter(perKey, rateLimiter).Middleware
+			logging.Logger.Info("Per-key rate limiting enabled",
+				zap.Float64("rate_per_second", perKey.Rate),
+				zap.Float64("stream_rate_per_second", perKey.StreamRate),
+				zap.Int("keys_with_own_limit", keyBudgets),
+			)
+		}
 	} else {
 		// No-op middleware when rate limiting is disabled
 		rateLimitMiddleware = func(next http.Handler) http.Handler {
@@ -787,22 +1704,499 @@ func main() {
 		logging.Logger.Info("Rate limiting disabled")
 	}
 
-	// Chain middleware: recovery first, then auth, then rate limiting
-	applyMiddleware := func(handler http.HandlerFunc) http.Handler {
-		return middleware.HTTPRecovery(authMiddleware(rateLimitMiddleware(handler)))
+	// Named middleware available to route chains
+	mwCfg := cfg.Server.Middleware
+	mwRegistry := middleware.NewRegistry()
+	mwRegistry.Register("auth", authMiddleware)
+	mwRegistry.Register("rate_limit", rateLimitMiddleware)
+	mwRegistry.Register("logging", middleware.AccessLog)
+	mwRegistry.Register("cors", middleware.CORS(mwCfg.CORSOrigins))
+	bodyLimitMB := mwCfg.BodyLimitMB
+	if bodyLimitMB == 0 {
+		bodyLimitMB = 10
+	}
+	mwRegistry.Register("body_limit", middleware.BodyLimit(int64(bodyLimitMB)*1024*1024))
+	mwRegistry.Register("cache", middleware.ResponseCache(parseDuration(mwCfg.CacheTTL, 30*time.Second, "server.middleware.cache_ttl")))
+	for name, rl := range mwCfg.RateLimits {
+		limiter := ratelimit.NewIPRateLimiter(rate.Limit(rl.Rate), rl.Burst)
+		mwRegistry.Register("rate_limit:"+name, limiter.Middleware)
+	}
+
+	// Per-route chains (recovery always runs first); default keeps auth, rate limiting then quotas
+	routeChains := middleware.RouteChains{
+		Default: mwCfg.Default,
+		Routes:  mwCfg.Routes,
+	}
+	if len(routeChains.Default) == 0 {
+		routeChains.Default = []string{"auth", "rate_limit", "quota"}
+	}
+	logging.Logger.Info("HTTP middleware chains configured",
+		zap.Strings("default", routeChains.Default),
+		zap.Int("route_overrides", len(routeChains.Routes)),
+	)
+
+	// Admin routes authenticate with the same keys as the data plane,
+	// unless a loopback listener skips auth
+	httpServer.Use(serverhttp.Admin, middleware.HTTPRecovery, middleware.Skippable("auth", authMiddleware))
+	if authzPolicy != nil {
+		// Roles only decide the data plane: reads of the admin API expose
+		// keys, usage and diagnostics, so every admin route needs admin.
+		// Listeners skipping auth skip this too.
+		adminAccess := func(*http.Request) authz.Access { return authz.AccessAdmin }
+		httpServer.Use(serverhttp.Admin, middleware.Skippable("auth", authzPolicy.HTTPMiddleware(adminAccess)))
+	}
+
+	// Every admin route needs the admin permission, not just a valid key
+	httpServer.Use(serverhttp.Admin, auth.RequirePermission(auth.PermissionAdmin))
+
+	// Streams debug endpoint (per-connection pacing stats, with client
+	// addresses and models, so admin only)
+	httpServer.HandleFunc(serverhttp.Admin, "/debug/streams", streamTracker.Handler())
+
+	// Maintenance switch (data-plane only, admin and metrics stay up)
+	maintenanceState := maintenance.New()
+	maintenance.SetDefault(maintenanceState)
+	if cfg.Server.Maintenance.Banner != "" {
+		maintenanceState.SetBanner(cfg.Server.Maintenance.Banner)
+	}
+	if cfg.Server.Maintenance.Enabled {
+		maintenanceState.Enable(cfg.Server.Maintenance.Message,
+			parseDuration(cfg.Server.Maintenance.RetryAfter, maintenance.DefaultRetryAfter, "server.maintenance.retry_after"))
+	}
+	httpServer.Handle(serverhttp.Admin, "/admin/maintenance", maintenanceState.Handler())
+
+	// Drain and shut down without cutting off in-flight requests
+	httpServer.Handle(serverhttp.Admin, "/admin/drain", drainer.Handler())
+
+	// Emergency stop: cancel in-flight generations, optionally pausing new ones
+	httpServer.Handle(serverhttp.Admin, "/admin/stop-all", grpcRouter.KillSwitch().Handler())
+
+	// Backend request queues
+	if scheduler := baseRouter.Scheduler(); scheduler != nil {
+		httpServer.Handle(serverhttp.Admin, "/admin/queues", scheduler.Handler())
+	}
+
+	// Quiet ("do not disturb") windows
+	if efficiencyMgr != nil {
+		quietHandler := efficiencyMgr.QuietWindowHandler()
+		httpServer.Handle(serverhttp.Admin, "/admin/quiet", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			quietHandler(w, r)
+			if r.Method != http.MethodGet && dbusSvc != nil {
+				dbusSvc.NotifyQuietWindowChanged()
+			}
+		}))
+	}
+
+	// Runtime control mirroring the D-Bus services, for headless servers
+	httpServer.Handle(serverhttp.Admin, "/admin/backends", grpcRouter.BackendsHandler())
+	if forwardingRouter != nil {
+		httpServer.Handle(serverhttp.Admin, "/admin/routing", forwardingRouter.Handler())
+	}
+	if efficiencyMgr != nil {
+		modeHandler := efficiencyMgr.ModeHandler()
+		httpServer.Handle(serverhttp.Admin, "/admin/efficiency", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			oldMode := efficiencyMgr.GetMode()
+			modeHandler(w, r)
+			if dbusSvc != nil && efficiencyMgr.GetMode() != oldMode {
+				dbusSvc.NotifyModeChanged(oldMode)
+			}
+		}))
+		httpServer.Handle(serverhttp.Admin, "/admin/schedule", efficiencyMgr.ScheduleHandler())
+	}
+	if thermalMonitor != nil {
+		httpServer.Handle(serverhttp.Admin, "/admin/thermal", thermalMonitor.Handler())
+	}
+	if bench != nil {
+		httpServer.Handle(serverhttp.Admin, "/admin/benchmarks", bench.Handler())
+	}
+	if authConfig.Keys != nil {
+		httpServer.Handle(serverhttp.Admin, "/admin/keys", authConfig.Keys.Handler())
+		httpServer.Handle(serverhttp.Admin, "/admin/keys/rotate", authConfig.Keys.RotateHandler())
+	}
+	pipelines.handle(httpServer)
+	accounting.handle(httpServer, mwRegistry)
+	logQuotas(cfg, keyQuotas)
+
+	// Status dashboard for people who'd rather not use grpcurl
+	if dc := cfg.Server.Dashboard; dc.Enabled {
+		dash := dashboard.New(grpcRouter, dashboard.Config{
+			Interval:  parseDuration(dc.Interval, dashboard.DefaultInterval, "server.dashboard.interval"),
+			Decisions: dc.Decisions,
+		})
+		if thermalMonitor != nil {
+			dash.SetThermalSource(thermalMonitor.HardwareStatuses)
+		}
+		if degradation != nil {
+			dash.SetDegradationSource(degradation.Status)
+		}
+		baseRouter.AddDecisionObserver(dash.ObserveDecision)
+		accounting.recorder.Subscribe(dash.ObserveUsage)
+
+		httpServer.Handle(serverhttp.Health, "/ui/", dash.Handler())
+		httpServer.HandleFunc(serverhttp.Health, "/ui/api/status", dash.StatusHandler())
+		httpServer.HandleStream(serverhttp.Health, "/ui/api/ws", dash.WebSocketHandler())
+		logging.Logger.Info("Dashboard enabled", zap.String("path", "/ui/"))
+	}
+
+	// Live tap of decisions and completed requests for debugging
+	if tc := cfg.Monitoring.DebugTap; tc.Enabled {
+		debugTap := tap.New(tap.Config{Rate: tc.Rate, MaxClients: tc.MaxClients})
+		baseRouter.AddDecisionObserver(debugTap.ObserveDecision)
+		accounting.recorder.Subscribe(debugTap.ObserveUsage)
+		httpServer.HandleStream(serverhttp.Admin, "/debug/tap", debugTap.Handler())
+		logging.Logger.Info("Debug tap enabled", zap.String("path", "/debug/tap"))
+	}
+
+	// Response cache for repeated prompts (requests opt in with X-Cache-Enabled)
+	var responseCache *cache.Cache
+	if cfg.Cache.Enabled {
+		cacheCfg := cache.DefaultConfig()
+		if cfg.Cache.TTLSeconds > 0 {
+			cacheCfg.TTL = time.Duration(cfg.Cache.TTLSeconds) * time.Second
+		}
+		if cfg.Cache.MaxEntries > 0 {
+			cacheCfg.MaxEntries = cfg.Cache.MaxEntries
+		}
+		cacheCfg.MaxBytes = int64(cfg.Cache.MaxSizeMB) * 1024 * 1024
+		if cfg.Cache.Type == "disk" {
+			cacheCfg.Dir = cfg.Cache.Dir
+		}
+
+		responseCache, err = cache.New(cacheCfg)
+		if err != nil {
+			logging.Logger.Warn("Response cache snapshot not loaded", zap.Error(err))
+		}
+		if responseCache != nil {
+			cache.SetDefault(responseCache)
+			httpServer.Handle(serverhttp.Admin, "/admin/cache", responseCache.Handler())
+			go responseCache.RunPersistence(context.Background(), time.Minute, func(err error) {
+				logging.Logger.Warn("Failed to persist response cache", zap.Error(err))
+			})
+			logging.Logger.Info("Response cache enabled",
+				zap.Duration("ttl", cacheCfg.TTL),
+				zap.Int("max_entries", cacheCfg.MaxEntries),
+				zap.Bool("persistent", cacheCfg.Dir != ""),
+				zap.Int("restored", responseCache.Stats().Entries),
+			)
+		}
+	}
+
+	// Session affinity keeps multi-turn conversations on one backend
+	if cfg.Routing.Sessions.Enabled {
+		sessionCfg := session.Config{
+			TTL:         parseDuration(cfg.Routing.Sessions.TTL, session.DefaultTTL, "routing.sessions.ttl"),
+			MaxSessions: cfg.Routing.Sessions.MaxSessions,
+			Boost: session.BoostConfig{
+				Enabled:    cfg.Routing.Sessions.Boost.Enabled,
+				MaxTurnGap: parseDuration(cfg.Routing.Sessions.Boost.MaxTurnGap, session.DefaultMaxTurnGap, "routing.sessions.boost.max_turn_gap"),
+				HalfLife:   parseDuration(cfg.Routing.Sessions.Boost.HalfLife, session.DefaultHalfLife, "routing.sessions.boost.half_life"),
+				Threshold:  cfg.Routing.Sessions.Boost.Threshold,
+			},
+		}
+		if sessionCfg.MaxSessions == 0 {
+			sessionCfg.MaxSessions = session.DefaultMaxSessions
+		}
+		sessionManager := session.New(sessionCfg)
+		session.SetDefault(sessionManager)
+		httpServer.Handle(serverhttp.Admin, "/admin/sessions", sessionManager.Handler())
+		go sessionManager.RunJanitor(context.Background(), time.Minute)
+		logging.Logger.Info("Session affinity enabled",
+			zap.Duration("ttl", sessionCfg.TTL),
+			zap.Int("max_sessions", sessionCfg.MaxSessions),
+			zap.Bool("interactive_boost", sessionCfg.Boost.Enabled),
+		)
+	}
+
+	// Client request labels (X-Labels) for logs, metrics, usage records and
+	// policies. They are read ahead of every chain so access logs see them.
+	requestLabels := func(next http.Handler) http.Handler { return next }
+	if labelsCfg := cfg.Server.Labels; len(labelsCfg.Allowed) > 0 {
+		labelValidator := labels.NewValidator(labels.Config{
+			Allowed:        labelsCfg.Allowed,
+			MaxLabels:      labelsCfg.MaxLabels,
+			MaxValueLength: labelsCfg.MaxValueLength,
+			Reject:         labelsCfg.RejectInvalid,
+		})
+		requestLabels = labelValidator.Middleware
+		accounting.recorder.Subscribe(func(rec usage.Record) {
+			labelValidator.Observe(rec.Labels, rec.Backend, rec.Status, rec.TotalTokens())
+		})
+		logging.Logger.Info("Request labels enabled",
+			zap.Int("allowed_labels", len(labelsCfg.Allowed)),
+			zap.Bool("reject_invalid", labelsCfg.RejectInvalid),
+		)
+	}
+
+	// Post-processing of final responses, shared by every HTTP protocol.
+	// Retrieved sources for the citations step arrive in X-Retrieval-Sources.
+	retrievalSources := func(next http.Handler) http.Handler { return next }
+	if steps := cfg.Server.PostProcessing.Steps; len(steps) > 0 {
+		chain, err := postprocess.Build(steps)
+		if err != nil {
+			logging.Logger.Fatal("Invalid post-processing steps", zap.Error(err))
+		}
+		postprocess.SetDefault(chain)
+		retrievalSources = postprocess.Middleware
+		logging.Logger.Info("Response post-processing enabled",
+			zap.Strings("steps", chain.Steps()),
+		)
+	}
+
+	// Request shaping bounds generations that leave max_tokens or
+	// temperature unset, more tightly in battery modes; degradation
+	// profiles cap max_tokens outright
+	if shapingCfg := cfg.Server.Shaping; shapingCfg.MaxTokens > 0 || shapingCfg.Temperature != nil ||
+		len(shapingCfg.Models) > 0 || len(shapingCfg.Modes) > 0 || degradation != nil {
+		shaperCfg := shaping.Config{
+			Default: shapingDefaults(shapingCfg.ShapingConfig),
+			Models:  make(map[string]shaping.Defaults, len(shapingCfg.Models)),
+			Modes:   make(map[efficiency.EfficiencyMode]shaping.Defaults, len(shapingCfg.Modes)),
+		}
+		if degradation != nil {
+			shaperCfg.Limit = degradation.MaxTokens
+		}
+		for pattern, defaults := range shapingCfg.Models {
+			shaperCfg.Models[pattern] = shapingDefaults(defaults)
+		}
+		for name, defaults := range shapingCfg.Modes {
+			shaperCfg.Modes[parseEfficiencyMode(name)] = shapingDefaults(defaults)
+		}
+		var modeSource shaping.ModeSource
+		if efficiencyMgr != nil {
+			modeSource = efficiencyMgr.GetEffectiveMode
+		}
+		shaping.SetDefault(shaping.New(shaperCfg, modeSource))
+		logging.Logger.Info("Request shaping enabled",
+			zap.Int32("max_tokens", shaperCfg.Default.MaxTokens),
+			zap.Int("models", len(shaperCfg.Models)),
+			zap.Int("modes", len(shaperCfg.Modes)),
+		)
+	}
+
+	// Inference routes that only list what is available
+	readOnlyRoutes := map[string]bool{"/v1/models": true, "/v1/capabilities": true, "/api/tags": true}
+
+	// Request body limits per endpoint class, and prompt length limits
+	// that API keys can override. Multipart uploads have their own class;
+	// chunked video uploads are bounded by server.video.max_upload_mb.
+	limitsCfg := limits.DefaultConfig()
+	for class, mb := range map[limits.Class]int{
+		limits.ClassJSON:  cfg.Server.Limits.BodyMB.JSON,
+		limits.ClassAudio: cfg.Server.Limits.BodyMB.Audio,
+		limits.ClassImage: cfg.Server.Limits.BodyMB.Image,
+	} {
+		if mb > 0 {
+			limitsCfg.MaxBodyBytes[class] = int64(mb) * 1024 * 1024
+		}
+	}
+	limitsCfg.MaxPromptTokens = cfg.Server.Limits.MaxPromptTokens
+	requestLimits := limits.New(limitsCfg)
+	bodyClasses := map[string]limits.Class{
+		"/v1/audio/transcriptions": limits.ClassAudio,
+		"/v1/images/edits":         limits.ClassImage,
+	}
+
+	// Browser clients on other origins; preflights are answered here,
+	// before draining or authentication, and errors carry the headers too
+	if corsCfg := cfg.Server.CORS; corsCfg.Enabled {
+		allowedHeaders := corsCfg.AllowedHeaders
+		if len(allowedHeaders) == 0 {
+			allowedHeaders = []string{"Authorization", "Content-Type"}
+			for _, h := range openaihttp.RoutingRequestHeaders {
+				allowedHeaders = append(allowedHeaders, h.Name)
+			}
+		}
+		exposedHeaders := corsCfg.ExposedHeaders
+		if len(exposedHeaders) == 0 {
+			for _, h := range openaihttp.RoutingResponseHeaders {
+				exposedHeaders = append(exposedHeaders, h.Name)
+			}
+			exposedHeaders = append(exposedHeaders, "X-Request-ID", "Retry-After", "X-Quota-Exceeded")
+		}
+		maxAge, _ := time.ParseDuration(corsCfg.MaxAge)
+		httpServer.Use(serverhttp.Inference, middleware.CORSWithConfig(middleware.CORSConfig{
+			AllowedOrigins:   corsCfg.AllowedOrigins,
+			AllowedHeaders:   allowedHeaders,
+			ExposedHeaders:   exposedHeaders,
+			AllowCredentials: corsCfg.AllowCredentials,
+			MaxAge:           maxAge,
+		}))
 	}
 
+	// Inference routes share drain, label and post-processing handling;
+	// the configured chain is looked up per route
+	httpServer.Use(serverhttp.Inference, drainer.Middleware, requestLabels, retrievalSources)
+	applyMiddleware := func(path string, handler http.HandlerFunc) http.Handler {
+		var inner http.Handler = handler
+		if degradation != nil {
+			inner = degradation.Middleware(inner)
+		}
+		if path != openaihttp.VideoUploadsPath {
+			// After auth and authz, so the key's prompt limit is known
+			class, ok := bodyClasses[path]
+			if !ok {
+				class = limits.ClassJSON
+			}
+			inner = requestLimits.Middleware(class)(inner)
+		}
+		if authzPolicy != nil {
+			// Innermost, so the route's auth middleware has run
+			access := authz.AccessInference
+			if readOnlyRoutes[path] {
+				access = authz.AccessRead
+			}
+			inner = middleware.Skippable("auth", authzPolicy.HTTPMiddleware(func(*http.Request) authz.Access { return access }))(inner)
+		}
+		if path != "/v1/models" {
+			// The model list stays up to report the maintenance window
+			inner = maintenanceState.Middleware(inner)
+		}
+		wrapped, err := routeChains.Wrap(mwRegistry, path, inner)
+		if err != nil {
+			logging.Logger.Fatal("Invalid middleware chain", zap.Error(err))
+		}
+		return wrapped
+	}
+
+	// Per-tenant I/O accounting and throughput caps for audio and image endpoints
+	const bytesPerMB = 1024 * 1024
+	mediaCfg := mediaio.Config{
+		DefaultBytesPerSec: cfg.Server.MediaIO.MBPerSecond * bytesPerMB,
+		TenantBytesPerSec:  make(map[string]float64, len(cfg.Server.MediaIO.Tenants)),
+		BurstBytes:         int(cfg.Server.MediaIO.BurstMB * bytesPerMB),
+	}
+	for tenant, mbps := range cfg.Server.MediaIO.Tenants {
+		mediaCfg.TenantBytesPerSec[tenant] = mbps * bytesPerMB
+	}
+	mediaMeter := mediaio.NewMeter(mediaCfg)
+	httpServer.Handle(serverhttp.Admin, "/admin/io", mediaMeter.Handler())
+
+	// Model pull and load progress as a server-sent event stream
+	httpServer.HandleStream(serverhttp.Admin, "/admin/events", backends.DefaultProgress.Handler())
+
+	// List, pull and delete models on backends that manage them
+	httpServer.Handle(serverhttp.Admin, "/admin/models", modelManager.Handler())
+	httpServer.HandleStream(serverhttp.Admin, "/admin/models/pull", modelManager.PullHandler())
+
 	// OpenAI-compatible endpoints with middleware
-	http.Handle("/v1/chat/completions", applyMiddleware(openaihttp.HandleChatCompletion(grpcRouter)))
-	http.Handle("/v1/completions", applyMiddleware(openaihttp.HandleCompletion(grpcRouter)))
-	http.Handle("/v1/embeddings", applyMiddleware(openaihttp.HandleEmbedding(grpcRouter)))
-	http.Handle("/v1/models", applyMiddleware(openaihttp.HandleModels(grpcRouter)))
+	httpServer.HandleStream(serverhttp.Inference, "/v1/chat/completions", applyMiddleware("/v1/chat/completions", openaihttp.HandleChatCompletion(grpcRouter)))
+	httpServer.HandleStream(serverhttp.Inference, "/v1/completions", applyMiddleware("/v1/completions", openaihttp.HandleCompletion(grpcRouter)))
+	httpServer.Handle(serverhttp.Inference, "/v1/embeddings", applyMiddleware("/v1/embeddings", openaihttp.HandleEmbedding(grpcRouter)))
+	httpServer.Handle(serverhttp.Inference, "/v1/models", applyMiddleware("/v1/models", openaihttp.HandleModels(grpcRouter)))
+	httpServer.Handle(serverhttp.Inference, "/v1/capabilities", applyMiddleware("/v1/capabilities", openaihttp.HandleCapabilities(grpcRouter)))
+
+	// Any key may read its own usage. It skips the route chain's rate limit
+	// and quota, so a key over its quota can still see why.
+	var usageHandler http.Handler = accounting.ledger.Handler()
+	if authzPolicy != nil {
+		usageHandler = middleware.Skippable("auth", authzPolicy.HTTPMiddleware(func(*http.Request) authz.Access { return authz.AccessRead }))(usageHandler)
+	}
+	httpServer.Handle(serverhttp.Inference, "/v1/usage", middleware.Skippable("auth", authMiddleware)(usageHandler))
+	httpServer.Handle(serverhttp.Inference, "/v1/audio/transcriptions", applyMiddleware("/v1/audio/transcriptions", mediaMeter.Wrap(mediaio.MediaAudio, openaihttp.HandleTranscription(grpcRouter))))
+	httpServer.HandleStream(serverhttp.Inference, "/v1/audio/speech", applyMiddleware("/v1/audio/speech", mediaMeter.Wrap(mediaio.MediaAudio, openaihttp.HandleSpeech(grpcRouter))))
+
+	// Native Ollama API so the proxy can stand in for a local Ollama
+	httpServer.HandleStream(serverhttp.Inference, "/api/generate", applyMiddleware("/api/generate", ollamahttp.HandleGenerate(grpcRouter)))
+	httpServer.HandleStream(serverhttp.Inference, "/api/chat", applyMiddleware("/api/chat", ollamahttp.HandleChat(grpcRouter)))
+	httpServer.Handle(serverhttp.Inference, "/api/embeddings", applyMiddleware("/api/embeddings", ollamahttp.HandleEmbeddings(grpcRouter)))
+	httpServer.Handle(serverhttp.Inference, "/api/tags", applyMiddleware("/api/tags", ollamahttp.HandleTags(grpcRouter)))
+
+	// Images returned with response_format=url are held in memory for an hour
+	imageStore := openaihttp.NewImageStore(openaihttp.DefaultImageURLTTL, openaihttp.DefaultMaxStoredImages)
+	httpServer.Handle(serverhttp.Inference, "/v1/images/generations", applyMiddleware("/v1/images/generations", mediaMeter.Wrap(mediaio.MediaImage, openaihttp.HandleImageGeneration(grpcRouter, imageStore))))
+	httpServer.Handle(serverhttp.Inference, "/v1/images/edits", applyMiddleware("/v1/images/edits", mediaMeter.Wrap(mediaio.MediaImage, openaihttp.HandleImageEdit(grpcRouter, imageStore))))
+	httpServer.Handle(serverhttp.Inference, openaihttp.ImageFilesPath, applyMiddleware(openaihttp.ImageFilesPath, mediaMeter.Wrap(mediaio.MediaImage, imageStore.HandleImageFile())))
+
+	// Large videos are uploaded in resumable chunks and analyzed in the
+	// background, with progress as server-sent events
+	videoStore, err := openaihttp.NewVideoStore(openaihttp.VideoStoreConfig{
+		Dir:            cfg.Server.Video.Dir,
+		MaxUploadBytes: int64(cfg.Server.Video.MaxUploadMB) * bytesPerMB,
+		MaxTotalBytes:  int64(cfg.Server.Video.MaxTotalMB) * bytesPerMB,
+		MaxUploadsPerKey: cfg.Server.Video.MaxUploadsPerKey,
+		UploadTTL:      parseDuration(cfg.Server.Video.UploadTTL, openaihttp.DefaultVideoUploadTTL, "server.video.upload_ttl"),
+		ResultTTL:      parseDuration(cfg.Server.Video.ResultTTL, openaihttp.DefaultVideoResultTTL, "server.video.result_ttl"),
+		CheckpointInterval: parseDuration(cfg.Server.Video.CheckpointInterval, openaihttp.DefaultVideoCheckpoint,
+			"server.video.checkpoint_interval"),
+	})
+	if err != nil {
+		logging.Logger.Fatal("Failed to create video store", zap.Error(err))
+	}
+	defer videoStore.Close()
+	for _, path := range []string{openaihttp.VideoUploadsPath, openaihttp.VideoUploadsPath + "/"} {
+		httpServer.Handle(serverhttp.Inference, path, applyMiddleware(openaihttp.VideoUploadsPath, mediaMeter.Wrap(mediaio.MediaVideo, videoStore.HandleVideoUploads())))
+	}
+	for _, path := range []string{openaihttp.VideoAnalysesPath, openaihttp.VideoAnalysesPath + "/"} {
+		httpServer.HandleStream(serverhttp.Inference, path, applyMiddleware(openaihttp.VideoAnalysesPath, videoStore.HandleVideoAnalyses(grpcRouter)))
+	}
+
+	// Text-to-video jobs checkpoint as they run; pick up those the last
+	// shutdown interrupted
+	for _, path := range []string{openaihttp.VideoGenerationsPath, openaihttp.VideoGenerationsPath + "/"} {
+		httpServer.HandleStream(serverhttp.Inference, path, applyMiddleware(openaihttp.VideoGenerationsPath, mediaMeter.Wrap(mediaio.MediaVideo, videoStore.HandleVideoGenerations(grpcRouter))))
+	}
+	if resumed := videoStore.ResumeGenerations(grpcRouter); resumed > 0 {
+		logging.Logger.Info("Resumed video generations", zap.Int("count", resumed))
+	}
 
 	// WebSocket endpoint for ultra-low latency streaming (with middleware)
-	http.Handle("/v1/stream/ws", applyMiddleware(websockethttp.HandleWebSocketStream(grpcRouter)))
+	// Cross-origin upgrades are rejected unless the origin is allowed,
+	// by default the CORS origins when CORS is enabled
+	wsOrigins := cfg.Server.WebSocket.AllowedOrigins
+	if len(wsOrigins) == 0 && cfg.Server.CORS.Enabled {
+		wsOrigins = cfg.Server.CORS.AllowedOrigins
+	}
+	// Same-origin pages count only on the proxy's own names, not on any
+	// domain rebound to its address
+	wsHostnames := append([]string{cfg.Server.Host}, cfg.Server.WebSocket.Hostnames...)
+	if hostname, err := os.Hostname(); err == nil {
+		wsHostnames = append(wsHostnames, hostname)
+	}
+	websockethttp.SetOriginPolicy(websockethttp.NewOriginPolicy(wsOrigins, wsHostnames))
+	websockethttp.SetLimits(websockethttp.Limits{
+		MaxConcurrentRequests: cfg.Server.WebSocket.MaxConcurrentRequests,
+		MaxMessageBytes:       int64(cfg.Server.WebSocket.MaxMessageKB) * 1024,
+		TokensPerMinute:       cfg.Server.WebSocket.TokensPerMinute,
+	})
+	httpServer.HandleStream(serverhttp.Inference, "/v1/stream/ws", applyMiddleware("/v1/stream/ws", websockethttp.HandleWebSocketStream(grpcRouter)))
+
+	// OpenAPI 3.1 document generated from the handler types, for typed clients
+	apiDoc := openapi.NewDocument("Ollama Proxy API", Version,
+		"OpenAI-compatible and native Ollama endpoints with power- and latency-aware routing annotations")
+	apiDoc.SetBearerAuth(cfg.Server.Auth.Enabled)
+	plainText := []openapi.Content{{Type: "text/plain", Body: openapi.Schema{"type": "string"}}}
+	if cfg.Server.Auth.Enabled {
+		apiDoc.AddCommonResponse(openapi.Response{Status: http.StatusUnauthorized, Description: "Missing or invalid API key", Content: plainText})
+		apiDoc.AddCommonResponse(openapi.Response{Status: http.StatusForbidden, Description: "API key is disabled", Content: plainText})
+	}
+	apiDoc.AddCommonResponse(openapi.Response{
+		Status:      http.StatusRequestEntityTooLarge,
+		Description: "Request body or prompt over the configured limit (code request_too_large or prompt_too_long)",
+		Content:     []openapi.Content{{Type: "application/json", Body: openapi.Schema{"type": "object"}}},
+	})
+	apiDoc.AddCommonResponse(openapi.Response{
+		Status:      http.StatusTooManyRequests,
+		Description: "Rate limit or usage quota exceeded",
+		Content:     plainText,
+		Headers: []openapi.Header{
+			{Name: "Retry-After", Description: "Seconds until the request can be retried", Schema: openapi.Schema{"type": "integer"}},
+			{Name: "X-Quota-Exceeded", Description: "The exhausted quota limit, e.g. daily_tokens"},
+		},
+	})
+	apiDoc.AddCommonResponse(openapi.Response{
+		Status:      http.StatusServiceUnavailable,
+		Description: "Maintenance mode, or no backend has capacity",
+		Headers: []openapi.Header{
+			{Name: "Retry-After", Description: "Seconds until the maintenance window ends, or until the queue ahead drains and overheated hardware cools", Schema: openapi.Schema{"type": "integer"}},
+			{Name: router.RetryAfterDebugHeader, Description: "How Retry-After was computed, when routing.retry_after.debug_header is set"},
+		},
+	})
+	openaihttp.DescribeAPI(apiDoc)
+	ollamahttp.DescribeAPI(apiDoc)
+	httpServer.HandleFunc(serverhttp.Health, "/openapi.json", apiDoc.Handler())
 
 	// Version endpoint
-	http.HandleFunc("/version", middleware.RecoveryHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	httpServer.HandleFunc(serverhttp.Health, "/version", func(w http.ResponseWriter, r *http.Request) {
 		versionInfo := map[string]string{
 			"version":    Version,
 			"git_commit": GitCommit,
@@ -810,7 +2204,7 @@ func main() {
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(versionInfo)
-	}))
+	})
 
 	// Prometheus metrics endpoint
 	if cfg.Monitoring.Enabled && cfg.Monitoring.PrometheusPort > 0 {
@@ -839,57 +2233,88 @@ func main() {
 				zap.String("endpoints", "/debug/pprof/*"),
 			)
 
-			// pprof handlers are automatically registered via import _ "net/http/pprof"
-			if err := http.ListenAndServe(pprofAddr, nil); err != nil {
+			pprofMux := http.NewServeMux()
+			pprofMux.HandleFunc("/debug/pprof/", pprof.Index)
+			pprofMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+			pprofMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+			pprofMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+			pprofMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+			if err := http.ListenAndServe(pprofAddr, pprofMux); err != nil {
 				logging.Logger.Error("pprof server failed", zap.Error(err))
 			}
 		}()
 	}
 
-	go func() {
-		protocol := "http"
-		if cfg.Server.TLS.Enabled {
-			protocol = "https"
+	// Optional HTTP/3 listener for data-plane endpoints
+	http3Port := cfg.Server.HTTP3.Port
+	if http3Port == 0 {
+		http3Port = cfg.Server.HTTPPort
+	}
+	http3Enabled := cfg.Server.HTTP3.Enabled && cfg.Server.TLS.Enabled
+	var http3Server *http3http.Server
+	serveHTTP3 := func() {}
+	if http3Enabled {
+		if !http3http.Supported {
+			logging.Logger.Warn("HTTP/3 enabled in config but not compiled in, serving TCP only",
+				zap.Error(http3http.ErrUnsupported),
+			)
+			http3Enabled = false
+		} else {
+			http3Addr := fmt.Sprintf("%s:%d", cfg.Server.Host, http3Port)
+			dataPlaneMux := http.NewServeMux()
+			dataPlaneMux.Handle("/v1/", httpServer.Handler())
+			dataPlaneMux.Handle("/api/", httpServer.Handler())
+
+			http3Server = http3http.NewServer(http3Addr, cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile, dataPlaneMux)
+			serveHTTP3 = func() {
+				logging.Logger.Info("HTTP/3 server listening",
+					zap.String("address", http3Addr),
+					zap.String("protocol", "quic"),
+				)
+				if err := http3Server.ListenAndServe(); err != nil && !drainer.Draining() {
+					logging.Logger.Error("HTTP/3 server failed", zap.Error(err))
+				}
+			}
 		}
+	}
 
-		wsProtocol := "ws"
-		if cfg.Server.TLS.Enabled {
-			wsProtocol = "wss"
+	listeners, err := httpListeners(cfg, mwRegistry)
+	if err != nil {
+		logging.Logger.Fatal("Failed to configure HTTP listeners", zap.Error(err))
+	}
+	for i := range listeners {
+		l := &listeners[i]
+		protocol, wsProtocol := "http", "ws"
+		if l.TLS != nil {
+			protocol, wsProtocol = "https", "wss"
+			if http3Enabled {
+				// Advertise the QUIC listener so clients can upgrade
+				l.Middleware = append(l.Middleware, func(next http.Handler) http.Handler {
+					return http3http.AdvertiseHandler(next, http3Port)
+				})
+			}
 		}
 
 		logging.Logger.Info("HTTP server listening",
-			zap.String("address", httpAddr),
+			zap.String("listener", l.Name),
+			zap.String("address", l.Addr),
 			zap.String("protocol", protocol),
-			zap.Bool("tls", cfg.Server.TLS.Enabled),
+			zap.Bool("tls", l.TLS != nil),
+			zap.Any("groups", l.Groups),
+			zap.Strings("skip_middleware", l.Skip),
 		)
 		logging.Logger.Info("HTTP endpoints available",
-			zap.String("health", fmt.Sprintf("%s://%s/health", protocol, httpAddr)),
-			zap.String("backends", fmt.Sprintf("%s://%s/backends", protocol, httpAddr)),
-			zap.String("openai_api", fmt.Sprintf("%s://%s/v1/", protocol, httpAddr)),
-			zap.String("websocket", fmt.Sprintf("%s://%s/v1/stream/ws", wsProtocol, httpAddr)),
+			zap.String("listener", l.Name),
+			zap.String("health", fmt.Sprintf("%s://%s/health", protocol, l.Addr)),
+			zap.String("backends", fmt.Sprintf("%s://%s/backends", protocol, l.Addr)),
+			zap.String("openai_api", fmt.Sprintf("%s://%s/v1/", protocol, l.Addr)),
+			zap.String("websocket", fmt.Sprintf("%s://%s/v1/stream/ws", wsProtocol, l.Addr)),
 			zap.Bool("thermal", thermalMonitor != nil),
 			zap.Bool("efficiency", efficiencyMgr != nil),
 		)
 
-		if cfg.Server.TLS.Enabled {
-			tlsConfig := &tls.Config{
-				MinVersion: tls.VersionTLS12,
-			}
-
-			httpServer := &http.Server{
-				Addr:      httpAddr,
-				TLSConfig: tlsConfig,
-			}
-
-			if err := httpServer.ListenAndServeTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile); err != nil {
-				logging.Logger.Fatal("Failed to serve HTTPS", zap.Error(err))
-			}
-		} else {
-			if err := http.ListenAndServe(httpAddr, nil); err != nil {
-				logging.Logger.Fatal("Failed to serve HTTP", zap.Error(err))
-			}
-		}
-	}()
+	}
 
 	// Start extended D-Bus services (backends, routing, thermal, system state)
 	var backendsDBus *dbusPkg.BackendsService
@@ -897,7 +2322,12 @@ func main() {
 	var thermalDBus *dbusPkg.ThermalService
 	var systemDBus *dbusPkg.SystemService
 
-	if cfg.Efficiency.DBusEnabled {
+	startDBusServices := func() {
+		if !cfg.Efficiency.DBusEnabled {
+			return
+		}
+		var err error
+
 		// Backends monitoring service
 		backendsDBus, err = dbusPkg.NewBackendsService(grpcRouter)
 		if err != nil {
@@ -933,30 +2363,259 @@ func main() {
 				} else {
 					logging.Logger.Info("D-Bus Thermal service started")
 				}
-			}
-		}
+			}
+		}
+
+		// System state service
+		if efficiencyMgr != nil {
+			systemDBus, err = dbusPkg.NewSystemService(efficiencyMgr)
+			if err != nil {
+				logging.Logger.Warn("Failed to create System D-Bus service", zap.Error(err))
+			} else {
+				systemDBus.SetMaintenanceState(maintenanceState)
+				if err := systemDBus.Start(); err != nil {
+					logging.Logger.Warn("System D-Bus service failed to start", zap.Error(err))
+				} else {
+					logging.Logger.Info("D-Bus System State service started")
+				}
+			}
+		}
+	}
+
+	// Start background health checker
+	go healthCheckLoop(ctx, grpcRouter)
+
+	// Start thermal update loop (updates efficiency manager)
+	if thermalMonitor != nil && efficiencyMgr != nil {
+		go thermalUpdateLoop(thermalMonitor, efficiencyMgr)
+	}
+
+	// Notice scheduled mode switches as entries start and end
+	if efficiencyMgr != nil && len(cfg.Efficiency.Schedule) > 0 {
+		go efficiencyMgr.RunSchedule(ctx)
+	}
+
+	// Built-in threshold alerting
+	var alertEngine *alerting.Engine
+	if cfg.Monitoring.Alerting.Enabled {
+		// Alerts go to whichever System D-Bus service is running when they fire
+		var emitDBus func(context.Context, alerting.Alert) error
+		if cfg.Efficiency.DBusEnabled && efficiencyMgr != nil {
+			emitDBus = func(ctx context.Context, alert alerting.Alert) error {
+				if systemDBus == nil {
+					return fmt.Errorf("D-Bus System service not running")
+				}
+				return systemDBus.EmitAlert(ctx, alert)
+			}
+		}
+		alertEngine, err = newAlertEngine(cfg, grpcRouter, thermalMonitor, accounting.recorder, emitDBus)
+		if err != nil {
+			logging.Logger.Error("Failed to create alerting engine", zap.Error(err))
+		} else {
+			httpServer.Handle(serverhttp.Admin, "/admin/alerts", alertEngine.Handler())
+			logging.Logger.Info("Alerting enabled",
+				zap.Int("rules", len(cfg.Monitoring.Alerting.Rules)),
+			)
+		}
+	}
+
+	// Self-diagnostics (also used by `proxyctl doctor`)
+	httpServer.Handle(serverhttp.Admin, "/admin/diagnostics", newDiagnosticsRunner(cfg, grpcRouter, thermalMonitor).Handler())
+
+	// Subsystems, in start order. They stop in reverse on shutdown and
+	// can be restarted on their own from /admin/subsystems.
+	register := func(sub lifecycle.Subsystem) {
+		if err := subsystems.Register(sub); err != nil {
+			logging.Logger.Fatal("Failed to register subsystem", zap.Error(err))
+		}
+	}
+	// A standby leaves the D-Bus names and virtual devices to the active
+	// node and starts them only on takeover
+	claimed := haNode != nil
+
+	// The audit log and usage records outlive the servers feeding them
+	if auditLog != nil {
+		register(auditSubsystem(auditLog))
+	}
+	register(accounting.subsystem())
+
+	if thermalMonitor != nil {
+		register(lifecycle.Subsystem{
+			Name:    "thermal",
+			Timeout: 5 * time.Second,
+			Start: func(context.Context) error {
+				thermalMonitor.Start()
+				return nil
+			},
+			Stop: func(context.Context) error {
+				thermalMonitor.Stop()
+				return nil
+			},
+		})
+	}
+
+	if fanController != nil {
+		// Stopping hands the fans back to automatic control
+		register(lifecycle.Subsystem{
+			Name:    "fan-control",
+			Timeout: 5 * time.Second,
+			Start: func(context.Context) error {
+				fanController.Start()
+				return nil
+			},
+			Stop: func(context.Context) error {
+				fanController.Stop()
+				return nil
+			},
+		})
+	}
+
+	if deviceManager != nil && cfg.Devices.AutoDiscover {
+		register(lifecycle.Subsystem{
+			Name:    "devices",
+			Timeout: 10 * time.Second,
+			Start: func(context.Context) error {
+				return deviceManager.StartAutoDiscovery()
+			},
+			Stop: func(context.Context) error {
+				return deviceManager.StopAutoDiscovery()
+			},
+		})
+	}
+
+	if cfg.Efficiency.DBusEnabled {
+		register(lifecycle.Subsystem{
+			Name:    "dbus",
+			Timeout: 10 * time.Second,
+			Manual:  claimed,
+			Start: func(context.Context) error {
+				startEfficiencyDBus()
+				startDBusServices()
+				return nil
+			},
+			Stop: func(context.Context) error {
+				if dbusSvc != nil {
+					dbusSvc.Stop()
+					dbusSvc = nil
+				}
+				if backendsDBus != nil {
+					backendsDBus.Stop()
+					backendsDBus = nil
+				}
+				if routingDBus != nil {
+					routingDBus.Stop()
+					routingDBus = nil
+				}
+				if thermalDBus != nil {
+					thermalDBus.Stop()
+					thermalDBus = nil
+				}
+				if systemDBus != nil {
+					systemDBus.Stop()
+					systemDBus = nil
+				}
+				return nil
+			},
+		})
+	}
 
-		// System state service
-		if efficiencyMgr != nil {
-			systemDBus, err = dbusPkg.NewSystemService(efficiencyMgr)
-			if err != nil {
-				logging.Logger.Warn("Failed to create System D-Bus service", zap.Error(err))
-			} else {
-				if err := systemDBus.Start(); err != nil {
-					logging.Logger.Warn("System D-Bus service failed to start", zap.Error(err))
-				} else {
-					logging.Logger.Info("D-Bus System State service started")
+	if cfg.VirtualDevices.Enabled {
+		register(lifecycle.Subsystem{
+			Name:   "virtual-devices",
+			Manual: claimed,
+			Start: func(context.Context) error {
+				startVirtualDevices()
+				return nil
+			},
+			Stop: func(context.Context) error {
+				if virtualDevMgr == nil {
+					return nil
 				}
-			}
-		}
+				err := virtualDevMgr.Stop()
+				virtualDevMgr = nil
+				return err
+			},
+		})
 	}
 
-	// Start background health checker
-	go healthCheckLoop(ctx, grpcRouter)
+	if pipelineLoader != nil {
+		register(pipelines.subsystem())
+	}
 
-	// Start thermal update loop (updates efficiency manager)
-	if thermalMonitor != nil && efficiencyMgr != nil {
-		go thermalUpdateLoop(thermalMonitor, efficiencyMgr)
+	if alertEngine != nil {
+		register(lifecycle.Subsystem{
+			Name:    "alerting",
+			Timeout: 5 * time.Second,
+			Start: func(context.Context) error {
+				alertEngine.Start()
+				return nil
+			},
+			Stop: func(context.Context) error {
+				alertEngine.Stop()
+				return nil
+			},
+		})
+	}
+
+	// The servers carry the admin API, so they start last, stop first and
+	// can't be restarted on their own. Requests still running when they
+	// stop were given the drain to finish; cutOff is set when the drain
+	// ran out.
+	cutOff := false
+	register(lifecycle.Subsystem{
+		Name:    "servers",
+		Timeout: 10 * time.Second,
+		Fixed:   true,
+		Start: func(context.Context) error {
+			go grpcService.serve()
+			go serveHTTP3()
+			for _, l := range listeners {
+				go func(l serverhttp.Listener) {
+					if err := httpServer.ServeListener(l); err != nil && err != http.ErrServerClosed {
+						logging.Logger.Fatal("Failed to serve HTTP",
+							zap.String("listener", l.Name),
+							zap.Error(err),
+						)
+					}
+				}(l)
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			// Close the listeners; requests still running are cut off
+			shutdownCtx, cancelShutdown := context.WithTimeout(ctx, time.Second)
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				httpServer.Close()
+			}
+			cancelShutdown()
+			if http3Server != nil {
+				http3Server.Close()
+			}
+			grpcService.stop(cutOff)
+			return nil
+		},
+	})
+	httpServer.Handle(serverhttp.Admin, "/admin/subsystems", subsystems.Handler())
+
+	// Warm standby failover: mirror learned state and hand over resources
+	if haNode != nil {
+		registerHAState(haNode, efficiencyMgr, maintenanceState)
+		haNode.OnActivate(func() {
+			for _, name := range []string{"dbus", "virtual-devices"} {
+				subsystems.Start(ctx, name)
+			}
+		})
+		haNode.OnDeactivate(func() {
+			for _, name := range []string{"virtual-devices", "dbus"} {
+				subsystems.Stop(ctx, name)
+			}
+		})
+		httpServer.Handle(serverhttp.Admin, "/admin/ha", haNode.Handler())
+		haNode.Start(ctx)
+	}
+
+	if err := subsystems.StartAll(ctx); err != nil {
+		logging.Logger.Warn("Some subsystems failed to start", zap.Error(err))
 	}
 
 	// Print startup summary
@@ -966,45 +2625,70 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
-	for {
-		sig := <-sigChan
+	// reloadConfig reloads the configuration file, as on SIGHUP
+	reloadConfig := func() error {
+		newCfg, err := loadConfig(*configPath)
+		if err != nil {
+			return fmt.Errorf("failed to reload config: %w", err)
+		}
 
-		if sig == syscall.SIGHUP {
-			logging.Logger.Info("Received SIGHUP, reloading configuration")
+		configValidator := newCfg
+		if err := config.ValidateConfig(configValidator); err != nil {
+			return fmt.Errorf("invalid configuration on reload: %w", err)
+		}
 
-			newCfg, err := loadConfig(*configPath)
-			if err != nil {
-				logging.Logger.Error("Failed to reload config", zap.Error(err))
-				continue
-			}
+		// Apply CLI overrides
+		if *logLevel != "" {
+			newCfg.Monitoring.LogLevel = *logLevel
+		}
+		if *grpcPort > 0 {
+			newCfg.Server.GRPCPort = *grpcPort
+		}
+		if *httpPort > 0 {
+			newCfg.Server.HTTPPort = *httpPort
+		}
 
-			configValidator := newCfg
-			if err := config.ValidateConfig(configValidator); err != nil {
-				logging.Logger.Error("Invalid configuration on reload", zap.Error(err))
-				continue
-			}
+		// Apply environment variable overrides
+		configForEnv := newCfg
+		config.ApplyEnvOverrides(configForEnv)
 
-			// Apply CLI overrides
-			if *logLevel != "" {
-				newCfg.Monitoring.LogLevel = *logLevel
-			}
-			if *grpcPort > 0 {
-				newCfg.Server.GRPCPort = *grpcPort
-			}
-			if *httpPort > 0 {
-				newCfg.Server.HTTPPort = *httpPort
+		// Update configuration
+		cfg = newCfg
+		logging.Logger.Info("Configuration reloaded successfully")
+
+		// Pipeline definitions are re-read too, for setups not watching them
+		if pipelineLoader != nil {
+			if err := pipelineLoader.Reload(); err != nil {
+				logging.Logger.Warn("Failed to reload pipelines", zap.Error(err))
 			}
+		}
 
-			// Apply environment variable overrides
-			configForEnv := newCfg
-			config.ApplyEnvOverrides(configForEnv)
+		// Note: Some configuration changes may require restart
+		// This reload only updates runtime-changeable settings
+		return nil
+	}
 
-			// Update configuration
-			cfg = newCfg
-			logging.Logger.Info("Configuration reloaded successfully")
+	for {
+		var sig os.Signal
+		select {
+		case sig = <-sigChan:
+		case <-drainer.Started():
+			// Drain requested on /admin/drain or the admin API
+		case reply := <-reloadRequests:
+			logging.Logger.Info("Reload requested over the admin API")
+			err := reloadConfig()
+			if err != nil {
+				logging.Logger.Error("Failed to reload configuration", zap.Error(err))
+			}
+			reply <- err
+			continue
+		}
 
-			// Note: Some configuration changes may require restart
-			// This reload only updates runtime-changeable settings
+		if sig == syscall.SIGHUP {
+			logging.Logger.Info("Received SIGHUP, reloading configuration")
+			if err := reloadConfig(); err != nil {
+				logging.Logger.Error("Failed to reload configuration", zap.Error(err))
+			}
 			continue
 		}
 
@@ -1012,16 +2696,67 @@ func main() {
 		break
 	}
 
+	// Refuse new requests and let in-flight ones finish; a second signal
+	// stops waiting
+	reason := "admin request"
+	if drainer.Start("signal", 0) {
+		reason = "signal"
+	}
+	logging.Logger.Info("Draining before shutdown",
+		zap.String("reason", reason),
+		zap.Time("deadline", drainer.Deadline()),
+	)
+	drainCtx, cancelDrain := context.WithCancel(context.Background())
+	go func() {
+		for {
+			select {
+			case sig := <-sigChan:
+				if sig == syscall.SIGHUP {
+					continue
+				}
+				logging.Logger.Warn("Second signal received, cutting off in-flight requests")
+				cancelDrain()
+			case <-drainCtx.Done():
+			}
+			return
+		}
+	}()
+	remaining := drainer.Wait(drainCtx)
+	cancelDrain()
+	if remaining > 0 {
+		logging.Logger.Warn("Drain deadline reached, cutting off in-flight requests",
+			zap.Int("in_flight", remaining),
+		)
+	} else {
+		logging.Logger.Info("Drain complete")
+	}
+
+	cutOff = remaining > 0
+
 	logging.Logger.Info("Shutting down gracefully...")
 
-	// Stop services
-	if thermalMonitor != nil {
-		thermalMonitor.Stop()
-		logging.Logger.Info("Thermal monitor stopped")
+	// HA stops first so a takeover can't restart what is being stopped
+	if haNode != nil {
+		haNode.Stop()
+	}
+
+	// Servers first, then the rest in reverse start order
+	if err := subsystems.StopAll(context.Background()); err != nil {
+		logging.Logger.Error("Some subsystems failed to stop", zap.Error(err))
+	}
+
+	if err := responseCache.Save(); err != nil {
+		logging.Logger.Error("Failed to persist response cache", zap.Error(err))
+	}
+	if authConfig.Keys != nil {
+		if err := authConfig.Keys.Flush(); err != nil {
+			logging.Logger.Error("Failed to persist API key last-used times", zap.Error(err))
+		}
 	}
-	if dbusSvc != nil {
-		dbusSvc.Stop()
-		logging.Logger.Info("D-Bus Efficiency service stopped")
+	if path := cfg.Routing.LatencyPrediction.Path; path != "" {
+		if err := latencyEstimator.Save(path); err != nil {
+			logging.Logger.Error("Failed to persist latency estimates", zap.Error(err))
+		}
 	}
 
 	// Stop device manager
@@ -1033,34 +2768,27 @@ func main() {
 		}
 	}
 
-	// Stop virtual device manager
-	if virtualDevMgr != nil {
-		if err := virtualDevMgr.Stop(); err != nil {
-			logging.Logger.Error("Error stopping virtual device manager", zap.Error(err))
-		} else {
-			logging.Logger.Info("Virtual device manager stopped")
+	// Stop backend containers managed by the proxy
+	for _, backend := range grpcRouter.ListBackends() {
+		if cb, ok := backend.(*circuit.Backend); ok {
+			backend = cb.Unwrap()
+		}
+		if managed, ok := backend.(*container.ManagedBackend); ok {
+			stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := managed.Stop(stopCtx); err != nil {
+				logging.Logger.Warn("Failed to stop backend container",
+					zap.String("backend_id", backend.ID()),
+					zap.Error(err),
+				)
+			}
+			cancel()
 		}
 	}
 
-	// Stop extended D-Bus services
-	if backendsDBus != nil {
-		backendsDBus.Stop()
-		logging.Logger.Info("D-Bus Backends service stopped")
-	}
-	if routingDBus != nil {
-		routingDBus.Stop()
-		logging.Logger.Info("D-Bus Routing service stopped")
-	}
-	if thermalDBus != nil {
-		thermalDBus.Stop()
-		logging.Logger.Info("D-Bus Thermal service stopped")
-	}
-	if systemDBus != nil {
-		systemDBus.Stop()
-		logging.Logger.Info("D-Bus System service stopped")
+	if decisionLog != nil {
+		decisionLog.Close()
 	}
 
-	grpcServer.GracefulStop()
 	logging.Logger.Info("Shutdown complete")
 }
 
@@ -1078,6 +2806,459 @@ func loadConfig(path string) (*config.Config, error) {
 	return &cfg, nil
 }
 
+// newContainerBackend wraps a backend with Docker/Podman lifecycle management
+func newContainerBackend(backendCfg config.BackendConfig, b backends.Backend, defaultHealthPath string) *container.ManagedBackend {
+	c := backendCfg.Container
+
+	runtime := c.Runtime
+	if runtime == "" {
+		runtime = "docker"
+	}
+	socket := c.Socket
+	if socket == "" {
+		socket = container.DefaultSocket(runtime)
+	}
+	name := c.Name
+	if name == "" {
+		name = "ollama-proxy-" + backendCfg.ID
+	}
+	healthPath := c.HealthPath
+	if healthPath == "" {
+		healthPath = defaultHealthPath
+	}
+
+	lc := container.NewLifecycle(container.NewClient(socket), container.LifecycleConfig{
+		Spec: container.Spec{
+			Name:      name,
+			Image:     c.Image,
+			Command:   c.Command,
+			Env:       c.Env,
+			Ports:     c.Ports,
+			Volumes:   c.Volumes,
+			GPUs:      c.GPUs,
+			Devices:   c.Devices,
+			ShmSizeMB: c.ShmSizeMB,
+		},
+		HealthURL:    strings.TrimSuffix(backendCfg.Endpoint, "/") + healthPath,
+		OnDemand:     c.OnDemand,
+		IdleTimeout:  parseDuration(c.IdleTimeout, 0, "backends.container.idle_timeout"),
+		StartTimeout: parseDuration(c.StartTimeout, 5*time.Minute, "backends.container.start_timeout"),
+	})
+
+	logging.Logger.Info("Backend container management enabled",
+		zap.String("backend_id", backendCfg.ID),
+		zap.String("runtime", runtime),
+		zap.String("container", name),
+		zap.String("image", c.Image),
+		zap.Bool("on_demand", c.OnDemand),
+	)
+
+	return container.NewManagedBackend(b, lc)
+}
+
+// parseDuration parses a config duration string, falling back to def when empty or invalid
+// newDiagnosticsRunner registers the checks relevant to the current config
+func newDiagnosticsRunner(cfg *config.Config, r *router.Router, tm *thermal.ThermalMonitor) *diagnostics.Runner {
+	runner := diagnostics.NewRunner(10 * time.Second)
+
+	expectedModels := make(map[string][]string)
+	for _, backendCfg := range cfg.Backends {
+		if backendCfg.Enabled {
+			expectedModels[backendCfg.ID] = backendCfg.ModelCapability.PreferredModels
+		}
+	}
+	runner.Register("backends", "reachability", diagnostics.BackendReachability(r))
+	runner.Register("models", "availability", diagnostics.ModelAvailability(r, expectedModels))
+	runner.Register("thermal", "sensors", diagnostics.ThermalSensors(tm))
+
+	if cfg.Efficiency.DBusEnabled {
+		names := append([]string{efficiency.BusName}, dbusPkg.ServiceNames()...)
+		runner.Register("dbus", "names", diagnostics.DBusNames(names))
+	}
+	if cfg.VirtualDevices.Enabled && cfg.VirtualDevices.Video.Enabled {
+		runner.Register("virtual_devices", "kernel modules", diagnostics.KernelModules([]string{"v4l2loopback"}))
+	}
+	if cfg.Server.TLS.Enabled {
+		runner.Register("tls", "certificate", diagnostics.TLSCertificate(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile, 14*24*time.Hour))
+	}
+
+	return runner
+}
+
+// newAlertEngine builds the alerting engine with its metric sources and notifiers
+func newAlertEngine(cfg *config.Config, r *router.Router, tm *thermal.ThermalMonitor, recorder *usage.Recorder, emitDBus func(context.Context, alerting.Alert) error) (*alerting.Engine, error) {
+	alertCfg := cfg.Monitoring.Alerting
+
+	rules := make([]alerting.Rule, 0, len(alertCfg.Rules))
+	for _, ruleCfg := range alertCfg.Rules {
+		rules = append(rules, alerting.Rule{
+			Name:      ruleCfg.Name,
+			Metric:    ruleCfg.Metric,
+			Subject:   ruleCfg.Subject,
+			Op:        ruleCfg.Op,
+			Threshold: ruleCfg.Threshold,
+			For:       parseDuration(ruleCfg.For, 0, "monitoring.alerting.rules.for"),
+			Severity:  ruleCfg.Severity,
+			Notify:    ruleCfg.Notify,
+		})
+	}
+
+	engine, err := alerting.NewEngine(alerting.Config{
+		Interval: parseDuration(alertCfg.Interval, 15*time.Second, "monitoring.alerting.interval"),
+		Rules:    rules,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	engine.AddSource(alerting.BackendSource(r))
+	engine.AddSource(alerting.UsageSource(recorder, parseDuration(alertCfg.UsageWindow, 5*time.Minute, "monitoring.alerting.usage_window")))
+	if tm != nil {
+		engine.AddSource(alerting.ThermalSource(tm))
+	}
+
+	for name, webhook := range alertCfg.Webhooks {
+		engine.AddNotifier(alerting.NewWebhookNotifier(name, webhook.URL, webhook.Headers))
+	}
+	if emitDBus != nil {
+		engine.AddNotifier(alerting.NewFuncNotifier("dbus", emitDBus))
+	}
+
+	return engine, nil
+}
+
+// newUsageExporter builds the configured usage export sink and exporter
+func newUsageExporter(cfg *config.Config) (*usage.Exporter, error) {
+	exportCfg := cfg.Server.Reports.Export
+
+	var sink usage.Sink
+	switch exportCfg.Type {
+	case "file":
+		fileSink, err := usage.NewFileSink(usage.FileSinkConfig{
+			Path:       exportCfg.Path,
+			Format:     exportCfg.Format,
+			MaxSizeMB:  exportCfg.MaxSizeMB,
+			MaxBackups: exportCfg.MaxBackups,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sink = fileSink
+	case "otlp":
+		otlpSink, err := usage.NewOTLPSink(usage.OTLPSinkConfig{
+			Endpoint: exportCfg.Endpoint,
+			Headers:  exportCfg.Headers,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sink = otlpSink
+	default:
+		return nil, fmt.Errorf("unknown usage export type: %s", exportCfg.Type)
+	}
+
+	exporterCfg := usage.DefaultExporterConfig()
+	if exportCfg.BufferSize > 0 {
+		exporterCfg.BufferSize = exportCfg.BufferSize
+	}
+	if exportCfg.BatchSize > 0 {
+		exporterCfg.BatchSize = exportCfg.BatchSize
+	}
+	exporterCfg.FlushInterval = parseDuration(exportCfg.FlushInterval, exporterCfg.FlushInterval, "server.reports.export.flush_interval")
+
+	return usage.NewExporter(sink, exporterCfg), nil
+}
+
+// newHANode creates the failover node and its gRPC client for the peer
+func newHANode(cfg *config.Config, configPath string) (*ha.Node, error) {
+	creds := insecure.NewCredentials()
+	if cfg.HA.TLSCAFile != "" {
+		caCert, err := os.ReadFile(cfg.HA.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ha tls_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse ha tls_ca_file")
+		}
+		creds = credentials.NewTLS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12})
+	}
+
+	peer, err := ha.DialPeer(cfg.HA.Peer, cfg.HA.SharedSecret, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	return ha.NewNode(ha.Config{
+		NodeID:           cfg.HA.NodeID,
+		Role:             cfg.HA.Role,
+		SyncInterval:     parseDuration(cfg.HA.SyncInterval, ha.DefaultSyncInterval, "ha.sync_interval"),
+		FailoverTimeout:  parseDuration(cfg.HA.FailoverTimeout, ha.DefaultFailoverTimeout, "ha.failover_timeout"),
+		ConfigPath:       configPath,
+		MirrorConfigPath: cfg.HA.MirrorConfigPath,
+	}, peer)
+}
+
+// httpListenerConfigs returns the configured HTTP listeners, or a single
+// one on host:http_port with server.tls when none are
+func httpListenerConfigs(cfg *config.Config) []config.ListenerConfig {
+	if len(cfg.Server.Listeners) > 0 {
+		return cfg.Server.Listeners
+	}
+	l := config.ListenerConfig{
+		Name:    "default",
+		Address: fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.HTTPPort),
+	}
+	l.TLS = cfg.Server.TLS
+	return []config.ListenerConfig{l}
+}
+
+// httpListeners builds the HTTP listeners, resolving their middleware from
+// the registry and loading their certificates
+func httpListeners(cfg *config.Config, registry *middleware.Registry) ([]serverhttp.Listener, error) {
+	configs := httpListenerConfigs(cfg)
+	listeners := make([]serverhttp.Listener, 0, len(configs))
+	for _, c := range configs {
+		l := serverhttp.Listener{
+			Name: c.Name,
+			Addr: c.Address,
+			Skip: c.SkipMiddleware,
+		}
+		for _, group := range c.Groups {
+			l.Groups = append(l.Groups, serverhttp.Group(group))
+		}
+		if len(c.Middleware) > 0 {
+			mw, err := registry.Build(c.Middleware)
+			if err != nil {
+				return nil, fmt.Errorf("listener %s: %w", c.Name, err)
+			}
+			l.Middleware = []middleware.Middleware{mw}
+		}
+
+		if c.TLS.Enabled {
+			cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("listener %s: failed to load TLS certificate: %w", c.Name, err)
+			}
+			l.TLS = &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				MinVersion:   tls.VersionTLS12,
+			}
+			if c.TLS.ClientCAFile != "" {
+				caCert, err := os.ReadFile(c.TLS.ClientCAFile)
+				if err != nil {
+					return nil, fmt.Errorf("listener %s: failed to read client_ca_file: %w", c.Name, err)
+				}
+				pool := x509.NewCertPool()
+				if !pool.AppendCertsFromPEM(caCert) {
+					return nil, fmt.Errorf("listener %s: failed to parse client_ca_file", c.Name)
+				}
+				l.TLS.ClientCAs = pool
+				l.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// haEfficiencyState is the efficiency state mirrored to the standby
+type haEfficiencyState struct {
+	Mode         efficiency.EfficiencyMode `json:"mode"`
+	QuietWindows []efficiency.QuietWindow  `json:"quiet_windows"`
+}
+
+// apiKeyInfo converts a configured API key or network identity
+func apiKeyInfo(c config.APIKeyConfig) auth.APIKeyInfo {
+	info := auth.APIKeyInfo{
+		Name:        c.Name,
+		Tenant:      c.Tenant,
+		Permissions: c.Permissions,
+		Enabled:     c.Enabled,
+
+		MaxPromptTokens: c.MaxPromptTokens,
+	}
+	if c.RateLimit != nil {
+		limit := authRateLimit(*c.RateLimit)
+		info.RateLimit = &limit
+	}
+	if c.Quota != nil {
+		quota := authQuota(*c.Quota)
+		info.Quota = &quota
+	}
+	if c.Shaping != nil {
+		info.Shaping = &auth.Shaping{
+			MaxTokens:   c.Shaping.MaxTokens,
+			Temperature: c.Shaping.Temperature,
+		}
+	}
+	return info
+}
+
+// newNetworkIdentity creates the authenticator for tailnet or WireGuard peers
+func newNetworkIdentity(c config.NetworkIdentityConfig) (*netidentity.Authenticator, error) {
+	cfg := netidentity.Config{
+		Identities: make(map[string]auth.APIKeyInfo, len(c.Identities)),
+		CacheTTL:   parseDuration(c.CacheTTL, netidentity.DefaultCacheTTL, "server.network_identity.cache_ttl"),
+	}
+	for name, id := range c.Identities {
+		cfg.Identities[name] = apiKeyInfo(id)
+	}
+	if c.Default != nil {
+		info := apiKeyInfo(*c.Default)
+		cfg.Default = &info
+	}
+	for _, cidr := range c.Networks {
+		prefix, err := netidentity.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Networks = append(cfg.Networks, prefix)
+	}
+
+	var resolver netidentity.Resolver
+	if c.Provider == "wireguard" {
+		wg, err := netidentity.NewWireGuard(c.Peers)
+		if err != nil {
+			return nil, err
+		}
+		if len(cfg.Networks) == 0 {
+			cfg.Networks = wg.Networks()
+		}
+		resolver = wg
+	} else {
+		if len(cfg.Networks) == 0 {
+			cfg.Networks = netidentity.TailnetNetworks
+		}
+		resolver = netidentity.NewTailscale(c.Socket)
+	}
+	return netidentity.New(resolver, cfg), nil
+}
+
+// authRateLimit converts a configured key budget
+func authRateLimit(c config.RateLimitConfig) auth.RateLimit {
+	return auth.RateLimit{
+		Rate:        c.Rate,
+		Burst:       c.Burst,
+		StreamRate:  c.StreamRate,
+		StreamBurst: c.StreamBurst,
+	}
+}
+
+// authQuota converts a configured usage quota
+func authQuota(c config.QuotaConfig) auth.Quota {
+	return auth.Quota{
+		DailyTokens:     c.DailyTokens,
+		MonthlyTokens:   c.MonthlyTokens,
+		DailyRequests:   c.DailyRequests,
+		MonthlyRequests: c.MonthlyRequests,
+	}
+}
+
+// shapingDefaults converts configured request shaping defaults
+func shapingDefaults(c config.ShapingConfig) shaping.Defaults {
+	return shaping.Defaults{
+		MaxTokens:   c.MaxTokens,
+		Temperature: c.Temperature,
+	}
+}
+
+// parseEfficiencyMode maps a configured mode name to its mode, Balanced
+// if unknown
+func parseEfficiencyMode(name string) efficiency.EfficiencyMode {
+	switch name {
+	case "Performance":
+		return efficiency.ModePerformance
+	case "Efficiency":
+		return efficiency.ModeEfficiency
+	case "Quiet":
+		return efficiency.ModeQuiet
+	case "Auto":
+		return efficiency.ModeAuto
+	case "UltraEfficiency":
+		return efficiency.ModeUltraEfficiency
+	}
+	return efficiency.ModeBalanced
+}
+
+// registerHAState mirrors the efficiency mode, quiet windows and
+// maintenance switch from the active proxy to the standby
+func registerHAState(node *ha.Node, em *efficiency.EfficiencyManager, ms *maintenance.State) {
+	if em != nil {
+		node.RegisterState("efficiency",
+			func() interface{} {
+				return haEfficiencyState{Mode: em.GetMode(), QuietWindows: em.QuietWindows()}
+			},
+			func(raw json.RawMessage) error {
+				var state haEfficiencyState
+				if err := json.Unmarshal(raw, &state); err != nil {
+					return err
+				}
+				em.SetMode(state.Mode)
+				em.ReplaceQuietWindows(state.QuietWindows)
+				return nil
+			},
+		)
+	}
+
+	node.RegisterState("maintenance",
+		func() interface{} { return ms.Status() },
+		func(raw json.RawMessage) error {
+			var status maintenance.Status
+			if err := json.Unmarshal(raw, &status); err != nil {
+				return err
+			}
+			local := ms.Status()
+			if status.Banner != local.Banner {
+				ms.SetBanner(status.Banner)
+			}
+			if status.Enabled && (!local.Enabled || status.Message != local.Message) {
+				ms.Enable(status.Message, time.Duration(status.RetryAfterSeconds)*time.Second)
+			} else if !status.Enabled && local.Enabled {
+				ms.Disable()
+			}
+			return nil
+		},
+	)
+}
+
+// confidenceConfig returns the heuristic confidence weights, the defaults
+// when none are configured
+func confidenceConfig(cfg *config.Config) *confidence.Config {
+	c := cfg.Routing.Confidence
+	if c.LengthWeight == 0 && c.PatternWeight == 0 && c.ModelWeight == 0 {
+		return confidence.DefaultConfig()
+	}
+	conf := confidence.DefaultConfig()
+	if c.MinLengthChars > 0 {
+		conf.MinLengthChars = c.MinLengthChars
+	}
+	if c.MaxLengthChars > 0 {
+		conf.MaxLengthChars = c.MaxLengthChars
+	}
+	conf.LengthWeight = c.LengthWeight
+	conf.PatternWeight = c.PatternWeight
+	conf.ModelWeight = c.ModelWeight
+	return conf
+}
+
+func parseDuration(value string, def time.Duration, field string) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logging.Logger.Warn("Invalid duration in config, using default",
+			zap.String("field", field),
+			zap.String("value", value),
+			zap.Duration("default", def),
+			zap.Error(err),
+		)
+		return def
+	}
+	return d
+}
+
 func healthCheckLoop(ctx context.Context, r *router.Router) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
@@ -1102,6 +3283,95 @@ func healthCheckLoop(ctx context.Context, r *router.Router) {
 }
 
 // thermalUpdateLoop updates efficiency manager with thermal state
+// energySources builds the configured energy sources; none means the
+// meter's default of RAPL and NVML
+func energySources(names []string) []energy.Source {
+	var sources []energy.Source
+	for _, name := range names {
+		switch name {
+		case "rapl":
+			sources = append(sources, energy.NewRAPLSource("/sys"))
+		case "nvml":
+			sources = append(sources, energy.NewNVMLSource())
+		}
+	}
+	return sources
+}
+
+// degradationState reads the state degradation profiles are matched
+// against: the hottest device's level against the configured thresholds,
+// and the battery as last reported to the efficiency manager
+func degradationState(tm *thermal.ThermalMonitor, em *efficiency.EfficiencyManager) degrade.State {
+	var state degrade.State
+	if tm != nil {
+		cfg := tm.GetConfig()
+		for _, ts := range tm.GetAllStates() {
+			if ts == nil {
+				continue
+			}
+			state.Throttling = state.Throttling || ts.Throttling
+			switch {
+			case cfg.TempCritical > 0 && ts.Temperature >= cfg.TempCritical:
+				state.Thermal = degrade.ThermalCritical
+			case cfg.TempWarning > 0 && ts.Temperature >= cfg.TempWarning && state.Thermal == "":
+				state.Thermal = degrade.ThermalWarning
+			}
+		}
+	}
+	if em != nil {
+		sys := em.GetSystemState()
+		state.OnBattery = sys.OnBattery
+		state.BatteryPercent = sys.BatteryPercent
+	}
+	return state
+}
+
+// newFanController builds the fan controller from config. Its profile
+// follows the efficiency mode: Quiet and Ultra Efficiency keep fans to the
+// quiet limit, Performance allows the loud limit and everything else
+// (including running without efficiency modes) the moderate one.
+func newFanController(cfg *config.Config, tm *thermal.ThermalMonitor, em *efficiency.EfficiencyManager) (*thermal.FanController, error) {
+	fanCfg := cfg.Thermal.FanControl
+
+	controlCfg := thermal.FanControlConfig{
+		Interval: parseDuration(fanCfg.Interval, thermal.DefaultFanControlInterval, "thermal.fan_control.interval"),
+		DryRun:   fanCfg.DryRun,
+		Profiles: make(map[string]thermal.FanProfile, len(fanCfg.Profiles)),
+	}
+	for hw, deviceCfg := range fanCfg.Devices {
+		driver, err := thermal.NewFanDriver(deviceCfg.Driver, deviceCfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("fan control device %s: %w", hw, err)
+		}
+		device := thermal.FanDevice{Hardware: hw, Driver: driver}
+		for _, point := range deviceCfg.Curve {
+			device.Curve = append(device.Curve, thermal.CurvePoint{Temperature: point.Temperature, Percent: point.Percent})
+		}
+		controlCfg.Devices = append(controlCfg.Devices, device)
+	}
+	for name, profileCfg := range fanCfg.Profiles {
+		profile := thermal.DefaultFanProfiles[name]
+		if profileCfg.Hysteresis > 0 {
+			profile.Hysteresis = profileCfg.Hysteresis
+		}
+		profile.MinInterval = parseDuration(profileCfg.MinInterval, profile.MinInterval, "thermal.fan_control.profiles."+name+".min_interval")
+		controlCfg.Profiles[name] = profile
+	}
+
+	return thermal.NewFanController(tm, controlCfg, func() string {
+		if em == nil {
+			return thermal.FanProfileModerate
+		}
+		switch em.GetEffectiveMode() {
+		case efficiency.ModeQuiet, efficiency.ModeUltraEfficiency:
+			return thermal.FanProfileQuiet
+		case efficiency.ModePerformance:
+			return thermal.FanProfileLoud
+		}
+		return thermal.FanProfileModerate
+	}), nil
+}
+
 func thermalUpdateLoop(tm *thermal.ThermalMonitor, em *efficiency.EfficiencyManager) {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
@@ -1129,9 +3399,8 @@ func thermalUpdateLoop(tm *thermal.ThermalMonitor, em *efficiency.EfficiencyMana
 			avgTemp := totalTemp / float64(count)
 			avgFan := totalFan / count
 
-			// Check time for quiet hours
-			hour := time.Now().Hour()
-			quietHours := (hour >= 22 || hour < 6)
+			// Quiet hours follow the efficiency schedule (22:00-06:00 without one)
+			quietHours := em.InQuietHours(time.Now())
 
 			// Update efficiency manager state
 			// Note: Battery info would come from system monitoring
@@ -1230,7 +3499,7 @@ func printStartupSummary(cfg *config.Config, r *router.Router, tm *thermal.Therm
 
 	// API endpoints
 	grpcAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.GRPCPort)
-	httpAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.HTTPPort)
+	httpAddr := httpListenerConfigs(cfg)[0].Address
 
 	logging.Logger.Info("API endpoints",
 		zap.String("grpc", grpcAddr),
@@ -1239,7 +3508,12 @@ func printStartupSummary(cfg *config.Config, r *router.Router, tm *thermal.Therm
 		zap.String("openai_chat", fmt.Sprintf("http://%s/v1/chat/completions", httpAddr)),
 		zap.String("openai_completions", fmt.Sprintf("http://%s/v1/completions", httpAddr)),
 		zap.String("openai_embeddings", fmt.Sprintf("http://%s/v1/embeddings", httpAddr)),
+		zap.String("openai_transcriptions", fmt.Sprintf("http://%s/v1/audio/transcriptions", httpAddr)),
+		zap.String("openai_speech", fmt.Sprintf("http://%s/v1/audio/speech", httpAddr)),
+		zap.String("openai_images", fmt.Sprintf("http://%s/v1/images/generations", httpAddr)),
 		zap.String("openai_models", fmt.Sprintf("http://%s/v1/models", httpAddr)),
+		zap.String("capabilities", fmt.Sprintf("http://%s/v1/capabilities", httpAddr)),
+		zap.String("openapi", fmt.Sprintf("http://%s/openapi.json", httpAddr)),
 		zap.Bool("thermal_endpoint", tm != nil),
 		zap.Bool("efficiency_endpoint", em != nil),
 	)
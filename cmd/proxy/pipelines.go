@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/config"
+	"github.com/daoneill/ollama-proxy/pkg/device"
+	serverhttp "github.com/daoneill/ollama-proxy/pkg/http/server"
+	"github.com/daoneill/ollama-proxy/pkg/ingest"
+	"github.com/daoneill/ollama-proxy/pkg/lifecycle"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/pipeline"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"go.uber.org/zap"
+)
+
+// pipelines runs multi-stage pipelines. The executor always exists, as
+// virtual devices use it; definitions and live sources are loaded only
+// when pipelines are enabled.
+type pipelines struct {
+	executor *pipeline.PipelineExecutor
+	loader   *pipeline.PipelineLoader // nil unless pipelines.enabled
+	sources  *ingest.Manager          // nil unless pipelines.enabled
+
+	watch     bool
+	autostart []string // live source IDs
+
+	mu        sync.Mutex
+	stopWatch context.CancelFunc
+}
+
+// newPipelines creates the executor over every backend and loads the
+// configured pipelines and live sources
+func newPipelines(cfg *config.Config, all []backends.Backend, admission *router.AdmissionController, deviceManager *device.DeviceManager) *pipelines {
+	p := &pipelines{executor: pipeline.NewPipelineExecutor(all)}
+	logging.Logger.Info("Pipeline executor initialized",
+		zap.Int("backends", len(all)),
+	)
+
+	// A pipeline holding a reserved backend defers best-effort requests
+	// from it for the rest of its execution
+	p.executor.SetReserveFunc(func(owner, backendID string) func() {
+		admission.Reserve(router.Reservation{
+			Owner:      owner,
+			BackendIDs: []string{backendID},
+			Reason:     "pipeline reservation",
+		})
+		return func() { admission.Release(owner) }
+	})
+
+	// Tool stages may only call the tools configured here
+	if len(cfg.Pipelines.Tools) > 0 {
+		tools := make(map[string]pipeline.Tool, len(cfg.Pipelines.Tools))
+		for _, tc := range cfg.Pipelines.Tools {
+			timeout := parseDuration(tc.Timeout, pipeline.DefaultToolTimeout, "pipelines.tools.timeout")
+			switch tc.Type {
+			case "command":
+				tools[tc.Name] = &pipeline.CommandTool{
+					Command:        tc.Command,
+					Env:            tc.Env,
+					Dir:            tc.Dir,
+					Timeout:        timeout,
+					MaxOutputBytes: tc.MaxOutputBytes,
+				}
+			case "http":
+				tools[tc.Name] = &pipeline.HTTPTool{
+					URL:            tc.URL,
+					Headers:        tc.Headers,
+					Timeout:        timeout,
+					MaxOutputBytes: tc.MaxOutputBytes,
+				}
+			}
+		}
+		p.executor.SetTools(tools)
+		logging.Logger.Info("Pipeline tools configured", zap.Int("tools", len(tools)))
+	}
+
+	if !cfg.Pipelines.Enabled {
+		logging.Logger.Info("Pipeline config loading disabled (executor still available)")
+		return p
+	}
+
+	logging.Logger.Info("Loading pipeline configurations",
+		zap.String("config_file", cfg.Pipelines.ConfigFile),
+	)
+	p.loader = pipeline.NewPipelineLoader()
+	if err := p.loader.LoadFromFile(cfg.Pipelines.ConfigFile); err != nil {
+		logging.Logger.Warn("Failed to load pipelines", zap.Error(err))
+	} else {
+		pipelineIDs := p.loader.ListPipelines()
+		logging.Logger.Info("Loaded pipelines",
+			zap.Int("count", len(pipelineIDs)),
+			zap.Strings("pipeline_ids", pipelineIDs),
+		)
+	}
+
+	// Pipelines created through the admin API are saved one per file,
+	// and override those in the config file
+	definitionsDir := cfg.Pipelines.DefinitionsDir
+	if definitionsDir == "" {
+		definitionsDir = filepath.Join(filepath.Dir(cfg.Pipelines.ConfigFile), "pipelines.d")
+	}
+	if err := p.loader.SetDefinitionsDir(definitionsDir); err != nil {
+		logging.Logger.Warn("Failed to load pipeline definitions", zap.Error(err))
+	}
+	p.watch = cfg.Pipelines.Watch
+
+	// Live RTSP and camera sources feeding pipelines continuously
+	p.sources = ingest.NewManager(p.executor, p.loader.GetPipeline, ingest.FFmpegCapture(cfg.Pipelines.FFmpegPath))
+	if deviceManager != nil {
+		p.sources.SetCameraResolver(func(name string) (string, error) {
+			if strings.HasPrefix(name, "/dev/") {
+				return name, nil
+			}
+			return deviceManager.CameraPath(name)
+		})
+	}
+	for _, src := range cfg.Pipelines.Sources {
+		err := p.sources.Add(ingest.SourceConfig{
+			ID:       src.ID,
+			Type:     src.Type,
+			URL:      src.URL,
+			Device:   src.Device,
+			Pipeline: src.Pipeline,
+			Media:    src.Media,
+			FPS:      src.FPS,
+			Window:   parseDuration(src.Window, ingest.DefaultWindow, "pipelines.sources.window"),
+		})
+		if err != nil {
+			logging.Logger.Warn("Failed to set up live source", zap.String("source", src.ID), zap.Error(err))
+			continue
+		}
+		if src.Autostart {
+			p.autostart = append(p.autostart, src.ID)
+		}
+	}
+	return p
+}
+
+// handle registers the admin routes managing pipelines and live sources
+func (p *pipelines) handle(httpServer *serverhttp.Server) {
+	if p.loader == nil {
+		return
+	}
+	httpServer.Handle(serverhttp.Admin, "/admin/pipelines", p.loader.Handler())
+	httpServer.Handle(serverhttp.Admin, "/admin/pipelines/validate", p.loader.ValidateHandler())
+	httpServer.Handle(serverhttp.Admin, "/admin/sources", p.sources.Handler())
+}
+
+// subsystem watches the pipeline files, when configured, and runs the
+// live sources marked autostart
+func (p *pipelines) subsystem() lifecycle.Subsystem {
+	return lifecycle.Subsystem{
+		Name: "pipelines",
+		Start: func(context.Context) error {
+			if p.watch {
+				ctx, cancel := context.WithCancel(context.Background())
+				p.mu.Lock()
+				p.stopWatch = cancel
+				p.mu.Unlock()
+				go p.watchFiles(ctx)
+			}
+			for _, id := range p.autostart {
+				if err := p.sources.Start(id); err != nil {
+					logging.Logger.Warn("Failed to start live source", zap.String("source", id), zap.Error(err))
+				}
+			}
+			return nil
+		},
+		Stop: func(context.Context) error {
+			p.mu.Lock()
+			if p.stopWatch != nil {
+				p.stopWatch()
+				p.stopWatch = nil
+			}
+			p.mu.Unlock()
+			p.sources.StopAll()
+			return nil
+		},
+	}
+}
+
+// watchFiles reloads the pipelines when their files change, until ctx ends
+func (p *pipelines) watchFiles(ctx context.Context) {
+	err := p.loader.Watch(ctx, func(err error) {
+		if err != nil {
+			logging.Logger.Warn("Failed to reload pipelines", zap.Error(err))
+			return
+		}
+		logging.Logger.Info("Pipelines reloaded",
+			zap.Int("count", len(p.loader.ListPipelines())),
+		)
+	})
+	if err != nil {
+		logging.Logger.Warn("Failed to watch pipeline files", zap.Error(err))
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/config"
+	serverhttp "github.com/daoneill/ollama-proxy/pkg/http/server"
+	"github.com/daoneill/ollama-proxy/pkg/lifecycle"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+	"go.uber.org/zap"
+)
+
+// usageAccounting records usage for per-tenant cost and energy reports,
+// keeps the per-key totals quotas are enforced on, and exports records to
+// external billing systems
+type usageAccounting struct {
+	recorder *usage.Recorder
+	ledger   *usage.Ledger
+	store    usage.Store     // nil unless server.reports.store is set
+	exporter *usage.Exporter // nil unless server.reports.export is set
+}
+
+// newUsageAccounting creates the process-wide usage recorder and restores
+// the records kept in its store
+func newUsageAccounting(cfg *config.Config) *usageAccounting {
+	usageCfg := usage.DefaultConfig()
+	usageCfg.Retention = parseDuration(cfg.Server.Reports.Retention, usageCfg.Retention, "server.reports.retention")
+	if cfg.Server.Reports.MaxRecords > 0 {
+		usageCfg.MaxRecords = cfg.Server.Reports.MaxRecords
+	}
+	usageCfg.PricePerKWh = cfg.Server.Reports.PricePerKWh
+	usageCfg.PricePer1KTokens = make(map[string]float64)
+	for _, backendCfg := range cfg.Backends {
+		if backendCfg.Characteristics.CostPer1KTokens > 0 {
+			usageCfg.PricePer1KTokens[backendCfg.ID] = backendCfg.Characteristics.CostPer1KTokens
+		}
+	}
+	if storeCfg := cfg.Server.Reports.Store; storeCfg.Type != "" {
+		store, err := usage.OpenStore(storeCfg.Type, storeCfg.Path)
+		if err != nil {
+			logging.Logger.Warn("Usage store not opened, usage will not survive restarts",
+				zap.String("type", storeCfg.Type),
+				zap.Error(err),
+			)
+		} else {
+			usageCfg.Store = store
+		}
+	}
+
+	u := &usageAccounting{
+		recorder: usage.NewRecorder(usageCfg),
+		ledger:   usage.NewLedger(authQuota(cfg.Server.Auth.Quota)),
+		store:    usageCfg.Store,
+	}
+	usage.SetDefault(u.recorder)
+	u.recorder.Subscribe(u.ledger.Observe)
+
+	if u.store != nil {
+		// Quotas need the whole month even when retention is shorter
+		since := usage.MonthStart(time.Now())
+		if usageCfg.Retention == 0 {
+			since = time.Time{}
+		} else if retained := time.Now().Add(-usageCfg.Retention); retained.Before(since) {
+			since = retained
+		}
+		restored, err := u.recorder.Restore(since)
+		if err != nil {
+			logging.Logger.Warn("Failed to restore usage records", zap.Error(err))
+		}
+		for _, rec := range restored {
+			u.ledger.Observe(rec)
+		}
+		logging.Logger.Info("Usage records restored",
+			zap.String("store", cfg.Server.Reports.Store.Type),
+			zap.Int("records", len(restored)),
+		)
+	}
+
+	// Usage export for external billing systems
+	if cfg.Server.Reports.Export.Type != "" {
+		exporter, err := newUsageExporter(cfg)
+		if err != nil {
+			logging.Logger.Error("Failed to create usage exporter", zap.Error(err))
+		} else {
+			u.exporter = exporter
+			u.recorder.Subscribe(exporter.Enqueue)
+			logging.Logger.Info("Usage export enabled",
+				zap.String("sink", exporter.Stats().Sink),
+			)
+		}
+	}
+	return u
+}
+
+// handle registers the report routes and the "quota" route middleware
+func (u *usageAccounting) handle(httpServer *serverhttp.Server, registry *middleware.Registry) {
+	registry.Register("quota", u.ledger.Middleware)
+	httpServer.Handle(serverhttp.Admin, "/admin/reports", u.recorder.ReportHandler())
+	httpServer.Handle(serverhttp.Admin, "/admin/reports/records", u.recorder.RecordsHandler())
+	if u.exporter != nil {
+		httpServer.Handle(serverhttp.Admin, "/admin/reports/export", u.exporter.StatsHandler())
+	}
+}
+
+// subsystem delivers exported records while running. Stopping flushes the
+// queue and closes the store, so it happens only with the process.
+func (u *usageAccounting) subsystem() lifecycle.Subsystem {
+	return lifecycle.Subsystem{
+		Name:    "usage",
+		Timeout: 15 * time.Second,
+		Fixed:   true,
+		Start: func(context.Context) error {
+			if u.exporter != nil {
+				u.exporter.Start()
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			var err error
+			if u.exporter != nil {
+				stopCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+				if err = u.exporter.Stop(stopCtx); err != nil {
+					err = fmt.Errorf("failed to close usage export sink: %w", err)
+				}
+				cancel()
+			}
+			if u.store != nil {
+				u.store.Close()
+			}
+			return err
+		},
+	}
+}
+
+// logQuotas reports the quotas in force once every source of keys is
+// known; keyQuotas is how many keys or identities have their own
+func logQuotas(cfg *config.Config, keyQuotas int) {
+	quota := authQuota(cfg.Server.Auth.Quota)
+	keyed := cfg.Server.Auth.Enabled || cfg.Server.NetworkIdentity.Enabled
+	if !keyed || (quota == auth.Quota{} && keyQuotas == 0) {
+		return
+	}
+	logging.Logger.Info("Usage quotas enabled",
+		zap.Int64("daily_tokens", quota.DailyTokens),
+		zap.Int64("monthly_tokens", quota.MonthlyTokens),
+		zap.Int64("daily_requests", quota.DailyRequests),
+		zap.Int64("monthly_requests", quota.MonthlyRequests),
+		zap.Int("keys_with_own_quota", keyQuotas),
+	)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcFlags are the connection flags shared by the commands that talk to
+// the proxy over gRPC
+type grpcFlags struct {
+	addr       *string
+	apiKey     *string
+	useTLS     *bool
+	ca         *string
+	cert       *string
+	key        *string
+	serverName *string
+	timeout    *time.Duration
+	asJSON     *bool
+}
+
+func addGRPCFlags(fs *flag.FlagSet) *grpcFlags {
+	addr := os.Getenv("OLLAMA_PROXY_GRPC_ADDR")
+	if addr == "" {
+		addr = client.DefaultGRPCAddr
+	}
+	return &grpcFlags{
+		addr:       fs.String("grpc-addr", addr, "proxy gRPC address"),
+		apiKey:     fs.String("api-key", os.Getenv("OLLAMA_PROXY_API_KEY"), "API key (admin permission for reload, drain and keys)"),
+		useTLS:     fs.Bool("tls", false, "connect with TLS (implied by --ca, --cert and --key)"),
+		ca:         fs.String("ca", "", "CA certificate verifying the proxy (default system roots)"),
+		cert:       fs.String("cert", "", "client certificate for mTLS"),
+		key:        fs.String("key", "", "client key for mTLS"),
+		serverName: fs.String("server-name", "", "name to verify the proxy's certificate against"),
+		timeout:    fs.Duration("timeout", 30*time.Second, "request timeout"),
+		asJSON:     fs.Bool("json", false, "print the raw JSON response"),
+	}
+}
+
+// connect creates a client and a context bounded by --timeout
+func (f *grpcFlags) connect() (*client.Client, context.Context, context.CancelFunc) {
+	cfg := client.Config{GRPCAddr: *f.addr, APIKey: *f.apiKey, Timeout: *f.timeout}
+	if *f.useTLS || *f.ca != "" || *f.cert != "" || *f.key != "" {
+		tlsCfg, err := client.LoadTLSConfig(*f.ca, *f.cert, *f.key, *f.serverName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid TLS settings: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.TLS = tlsCfg
+	}
+
+	c, err := client.New(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid address: %v\n", err)
+		os.Exit(1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *f.timeout)
+	return c, ctx, cancel
+}
+
+// fail reports a failed RPC with a hint for the usual causes and exits
+func (f *grpcFlags) fail(err error) {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		fmt.Fprintf(os.Stderr, "Proxy rejected the request: %s\n", st.Message())
+		fmt.Fprintln(os.Stderr, "  -> pass an admin key with --api-key or set OLLAMA_PROXY_API_KEY")
+	case codes.Unavailable:
+		fmt.Fprintf(os.Stderr, "Failed to reach proxy at %s: %s\n", *f.addr, st.Message())
+		fmt.Fprintln(os.Stderr, "  -> check the proxy is running, pass --grpc-addr, and use --tls/--ca if server.tls is enabled")
+	case codes.Unimplemented:
+		fmt.Fprintf(os.Stderr, "Proxy does not support this command: %s\n", st.Message())
+		fmt.Fprintln(os.Stderr, "  -> upgrade the proxy to a version with the gRPC admin service")
+	case codes.DeadlineExceeded:
+		fmt.Fprintf(os.Stderr, "Request timed out after %s\n", *f.timeout)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %s\n", st.Message())
+	}
+	os.Exit(1)
+}
+
+// printJSON prints a response as indented JSON
+func printJSON(m proto.Message) {
+	data, _ := protojson.MarshalOptions{Multiline: true, Indent: "  ", EmitUnpopulated: true}.Marshal(m)
+	fmt.Println(string(data))
+}
+
+func health(args []string) {
+	fs := flag.NewFlagSet("health", flag.ExitOnError)
+	conn := addGRPCFlags(fs)
+	fs.Parse(args)
+
+	c, ctx, cancel := conn.connect()
+	defer cancel()
+	defer c.Close()
+
+	resp, err := c.HealthCheck(ctx)
+	if err != nil {
+		conn.fail(err)
+	}
+
+	if *conn.asJSON {
+		printJSON(resp)
+	} else {
+		fmt.Printf("Proxy: %s\n", resp.Status)
+		ids := make([]string, 0, len(resp.BackendHealth))
+		for id := range resp.BackendHealth {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			mark := "✓"
+			if resp.BackendHealth[id] != "healthy" {
+				mark = "✗"
+			}
+			fmt.Printf("  %s %-24s %s\n", mark, id, resp.BackendHealth[id])
+		}
+	}
+
+	if resp.Status != "healthy" {
+		os.Exit(1)
+	}
+}
+
+func listBackends(args []string) {
+	fs := flag.NewFlagSet("backends", flag.ExitOnError)
+	conn := addGRPCFlags(fs)
+	fs.Parse(args)
+
+	c, ctx, cancel := conn.connect()
+	defer cancel()
+	defer c.Close()
+
+	backends, err := c.ListBackends(ctx)
+	if err != nil {
+		conn.fail(err)
+	}
+
+	if *conn.asJSON {
+		printJSON(&pb.ListBackendsResponse{Backends: backends})
+		return
+	}
+	fmt.Printf("%-24s %-8s %-10s %8s  %s\n", "ID", "HARDWARE", "STATE", "LATENCY", "MODELS")
+	for _, b := range backends {
+		var latency int32
+		if b.Metrics != nil {
+			latency = b.Metrics.AvgLatencyMs
+		}
+		var models []string
+		if b.Capabilities != nil {
+			models = b.Capabilities.Models
+		}
+		fmt.Printf("%-24s %-8s %-10s %6dms  %s\n", b.Id, b.Hardware, b.Status.GetState(), latency, strings.Join(models, ","))
+	}
+}
+
+func generate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	conn := addGRPCFlags(fs)
+	model := fs.String("model", "", "model (default: the proxy picks)")
+	fs.Parse(args)
+
+	prompt := strings.Join(fs.Args(), " ")
+	if prompt == "" {
+		prompt = "Reply with the single word: ok"
+	}
+
+	c, ctx, cancel := conn.connect()
+	defer cancel()
+	defer c.Close()
+
+	resp, err := c.Generate(ctx, client.GenerateRequest{Prompt: prompt, Model: *model})
+	if err != nil {
+		conn.fail(err)
+	}
+
+	if *conn.asJSON {
+		printJSON(resp)
+		return
+	}
+	fmt.Println(strings.TrimSpace(resp.Response))
+	fmt.Println()
+	fmt.Printf("Backend: %s", resp.BackendUsed)
+	if s := resp.Stats; s != nil {
+		fmt.Printf("  first token %dms, total %dms, %d tokens (%.1f tok/s)",
+			s.TimeToFirstTokenMs, s.TotalTimeMs, s.TokensGenerated, s.TokensPerSecond)
+	}
+	if resp.FromCache {
+		fmt.Print("  [cached]")
+	}
+	fmt.Println()
+}
+
+func reload(args []string) {
+	fs := flag.NewFlagSet("reload", flag.ExitOnError)
+	conn := addGRPCFlags(fs)
+	fs.Parse(args)
+
+	c, ctx, cancel := conn.connect()
+	defer cancel()
+	defer c.Close()
+
+	if err := c.Reload(ctx); err != nil {
+		conn.fail(err)
+	}
+	fmt.Println("✓ Configuration reloaded")
+}
+
+func drainProxy(args []string) {
+	fs := flag.NewFlagSet("drain", flag.ExitOnError)
+	conn := addGRPCFlags(fs)
+	reason := fs.String("reason", "", "reason logged by the proxy (default \"admin request\")")
+	drainTimeout := fs.Duration("drain-timeout", 0, "how long in-flight requests may run (default server.drain.timeout)")
+	fs.Parse(args)
+
+	c, ctx, cancel := conn.connect()
+	defer cancel()
+	defer c.Close()
+
+	var st *pb.DrainStatus
+	var err error
+	if fs.Arg(0) == "status" {
+		st, err = c.DrainStatus(ctx)
+	} else {
+		st, err = c.Drain(ctx, *reason, *drainTimeout)
+	}
+	if err != nil {
+		conn.fail(err)
+	}
+
+	if *conn.asJSON {
+		printJSON(st)
+		return
+	}
+	if !st.Draining {
+		fmt.Println("Not draining")
+		return
+	}
+	fmt.Printf("Draining (%s): %d in flight, shutting down by %s\n",
+		st.Reason, st.InFlight, time.Unix(st.DeadlineUnix, 0).Format(time.RFC3339))
+}
+
+func keys(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"list"}, args...)
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("keys "+action, flag.ExitOnError)
+	conn := addGRPCFlags(fs)
+	tenant := fs.String("tenant", "", "tenant for usage reporting (create; default the name)")
+	permissions := fs.String("permissions", "", "comma-separated permissions, e.g. admin (create)")
+	fs.Parse(args[1:])
+
+	name := fs.Arg(0)
+	if action != "list" && name == "" {
+		fmt.Fprintf(os.Stderr, "Usage: proxyctl keys %s [flags] <name>\n", action)
+		os.Exit(1)
+	}
+
+	c, ctx, cancel := conn.connect()
+	defer cancel()
+	defer c.Close()
+
+	switch action {
+	case "list":
+		list, err := c.ListAPIKeys(ctx)
+		if err != nil {
+			conn.fail(err)
+		}
+		if *conn.asJSON {
+			printJSON(&pb.ListAPIKeysResponse{Keys: list})
+			return
+		}
+		fmt.Printf("%-24s %-16s %-8s %-8s %s\n", "NAME", "TENANT", "ENABLED", "KEY", "PERMISSIONS")
+		for _, k := range list {
+			fmt.Printf("%-24s %-16s %-8t %-8s %s\n", k.Name, k.Tenant, k.Enabled, k.KeyHint, strings.Join(k.Permissions, ","))
+		}
+
+	case "create":
+		var perms []string
+		if *permissions != "" {
+			perms = strings.Split(*permissions, ",")
+		}
+		resp, err := c.CreateAPIKey(ctx, name, *tenant, perms)
+		if err != nil {
+			conn.fail(err)
+		}
+		if *conn.asJSON {
+			printJSON(resp)
+			return
+		}
+		fmt.Printf("✓ Created API key %s\n", name)
+		fmt.Printf("  %s\n", resp.Key)
+		fmt.Println("  -> store it now; it is not shown again, and lasts until the proxy restarts unless server.auth.key_store is set")
+
+	case "enable", "disable":
+		if _, err := c.SetAPIKeyEnabled(ctx, name, action == "enable"); err != nil {
+			conn.fail(err)
+		}
+		fmt.Printf("✓ API key %s %sd\n", name, action)
+
+	case "delete":
+		if err := c.DeleteAPIKey(ctx, name); err != nil {
+			conn.fail(err)
+		}
+		fmt.Printf("✓ Deleted API key %s\n", name)
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown keys command: %s (list, create, enable, disable, delete)\n", action)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/config"
+	"github.com/daoneill/ollama-proxy/pkg/diagnostics"
+	"gopkg.in/yaml.v3"
+)
+
+// proxyUnits are the systemd units the proxy runs as: the GNOME user
+// service and the system service
+var proxyUnits = [][]string{
+	{"--user", "-u", "ie.fio.ollamaproxy.service"},
+	{"-u", "ollama-proxy.service"},
+}
+
+// maxCrashFiles is how many of the newest crash files a bundle includes
+const maxCrashFiles = 5
+
+func supportBundle(args []string) {
+	fs := flag.NewFlagSet("support-bundle", flag.ExitOnError)
+	baseURL := fs.String("url", "http://localhost:8080", "proxy base URL")
+	apiKey := fs.String("api-key", os.Getenv("OLLAMA_PROXY_API_KEY"), "admin API key")
+	configPath := fs.String("config", "config/config.yaml", "proxy configuration file")
+	output := fs.String("output", "", "archive path (default ollama-proxy-support-<time>.zip)")
+	logLines := fs.Int("log-lines", 2000, "recent log lines to include")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	fs.Parse(args)
+
+	if *output == "" {
+		*output = fmt.Sprintf("ollama-proxy-support-%s.zip", time.Now().Format("20060102-150405"))
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", *output, err)
+		os.Exit(1)
+	}
+
+	c := &collector{
+		bundle:  diagnostics.NewBundle(f),
+		client:  &http.Client{Timeout: *timeout},
+		baseURL: strings.TrimRight(*baseURL, "/"),
+		apiKey:  *apiKey,
+	}
+
+	cfg := c.addConfig(*configPath)
+	c.addSystem()
+	c.addHTTP("version.json", c.baseURL+"/version")
+	c.addHTTP("diagnostics.json", c.baseURL+"/admin/diagnostics")
+	c.addMetrics(cfg)
+	c.addGoroutines(cfg)
+	c.addLogs(*logLines)
+	c.addCrashLogs(cfg)
+
+	err = c.bundle.Close()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", *output, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n✓ Support bundle written to %s\n", *output)
+	fmt.Println("  Secrets are redacted, but review the archive before attaching it to a bug report")
+}
+
+// collector gathers support bundle files, reporting each as it goes
+type collector struct {
+	bundle  *diagnostics.Bundle
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+func (c *collector) add(name string, data []byte) {
+	c.bundle.Add(name, data)
+	fmt.Printf("  [ OK ] %s\n", name)
+}
+
+func (c *collector) skip(name string, err error) {
+	c.bundle.Skip(name, err)
+	fmt.Printf("  [SKIP] %s: %v\n", name, err)
+}
+
+// addConfig adds the sanitized configuration, returning it parsed (nil if
+// unreadable) to locate the metrics, pprof and crash log endpoints
+func (c *collector) addConfig(path string) *config.Config {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.skip("config.yaml", fmt.Errorf("%w (pass --config)", err))
+		return nil
+	}
+	sanitized, err := diagnostics.SanitizeConfig(data)
+	if err != nil {
+		c.skip("config.yaml", fmt.Errorf("invalid YAML: %w", err))
+		return nil
+	}
+	c.add("config.yaml", sanitized)
+
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil
+	}
+	if err := config.ValidateConfig(&cfg); err != nil {
+		c.add("config-errors.txt", []byte(err.Error()+"\n"))
+	}
+	return &cfg
+}
+
+// addSystem adds the host and proxyctl build details
+func (c *collector) addSystem() {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "collected:  %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "os/arch:    %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&sb, "cpus:       %d\n", runtime.NumCPU())
+	fmt.Fprintf(&sb, "proxyctl:   built with %s\n", runtime.Version())
+	if out, err := exec.Command("uname", "-srvm").Output(); err == nil {
+		fmt.Fprintf(&sb, "kernel:     %s", out)
+	}
+	c.add("system.txt", []byte(sb.String()))
+}
+
+// addHTTP adds the body of a GET request to the proxy
+func (c *collector) addHTTP(name, target string) {
+	data, err := c.get(target)
+	if err != nil {
+		c.skip(name, err)
+		return
+	}
+	c.add(name, diagnostics.SanitizeLog(data))
+}
+
+func (c *collector) get(target string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// Diagnostics answer 503 with a report of failed checks
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("%s returned %d", target, resp.StatusCode)
+	}
+	if resp.StatusCode == http.StatusServiceUnavailable && !json.Valid(body) {
+		return nil, fmt.Errorf("%s returned %d", target, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// sidecarURL is the proxy's host on another port, e.g. the metrics listener
+func (c *collector) sidecarURL(port int, path string) string {
+	host := "localhost"
+	if u, err := url.Parse(c.baseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return "http://" + net.JoinHostPort(host, fmt.Sprint(port)) + path
+}
+
+func (c *collector) addMetrics(cfg *config.Config) {
+	if cfg == nil || !cfg.Monitoring.Enabled || cfg.Monitoring.PrometheusPort == 0 {
+		c.skip("metrics.txt", errors.New("metrics disabled (monitoring.prometheus_port)"))
+		return
+	}
+	c.addHTTP("metrics.txt", c.sidecarURL(cfg.Monitoring.PrometheusPort, "/metrics"))
+}
+
+func (c *collector) addGoroutines(cfg *config.Config) {
+	if cfg == nil || !cfg.Monitoring.PprofEnabled || cfg.Monitoring.PprofPort == 0 {
+		c.skip("goroutines.txt", errors.New("pprof disabled (monitoring.pprof_enabled)"))
+		return
+	}
+	c.addHTTP("goroutines.txt", c.sidecarURL(cfg.Monitoring.PprofPort, "/debug/pprof/goroutine?debug=2"))
+}
+
+// addLogs adds the proxy's recent journal entries from whichever unit it
+// runs as
+func (c *collector) addLogs(lines int) {
+	for _, unit := range proxyUnits {
+		args := append(append([]string{}, unit...), "-n", fmt.Sprint(lines), "--no-pager", "-o", "short-iso")
+		out, err := exec.Command("journalctl", args...).Output()
+		if err != nil || len(bytes.TrimSpace(out)) == 0 || bytes.HasPrefix(out, []byte("-- No entries --")) {
+			continue
+		}
+		c.add("logs.txt", diagnostics.SanitizeLog(out))
+		return
+	}
+	c.skip("logs.txt", errors.New("no journal entries for ie.fio.ollamaproxy.service or ollama-proxy.service"))
+}
+
+// addCrashLogs adds the newest crash files, which hold panic stack traces
+func (c *collector) addCrashLogs(cfg *config.Config) {
+	if cfg == nil || cfg.Monitoring.CrashLog.Dir == "" {
+		return
+	}
+	dir := cfg.Monitoring.CrashLog.Dir
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		c.skip("crash/", err)
+		return
+	}
+
+	type crashFile struct {
+		name    string
+		modTime time.Time
+	}
+	var files []crashFile
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && info.Mode().IsRegular() {
+			files = append(files, crashFile{e.Name(), info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	if len(files) > maxCrashFiles {
+		files = files[:maxCrashFiles]
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file.name))
+		if err != nil {
+			c.skip("crash/"+file.name, err)
+			continue
+		}
+		c.add("crash/"+file.name, diagnostics.SanitizeLog(data))
+	}
+}
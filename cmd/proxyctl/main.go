@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/client"
+	"github.com/daoneill/ollama-proxy/pkg/diagnostics"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	command := os.Args[1]
+
+	switch command {
+	case "doctor":
+		doctor(os.Args[2:])
+	case "route-replay":
+		routeReplay(os.Args[2:])
+	case "support-bundle":
+		supportBundle(os.Args[2:])
+	case "health":
+		health(os.Args[2:])
+	case "backends":
+		listBackends(os.Args[2:])
+	case "generate":
+		generate(os.Args[2:])
+	case "reload":
+		reload(os.Args[2:])
+	case "drain":
+		drainProxy(os.Args[2:])
+	case "keys":
+		keys(os.Args[2:])
+	case "help", "-h", "--help":
+		printUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println("Ollama Proxy Control")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  proxyctl doctor [flags]         Run self-diagnostics against a running proxy")
+	fmt.Println("  proxyctl route-replay [flags]   Replay recorded routing decisions against a candidate config")
+	fmt.Println("  proxyctl support-bundle [flags] Collect sanitized config, logs and diagnostics for a bug report")
+	fmt.Println()
+	fmt.Println("  proxyctl health [flags]         Check the proxy's and each backend's health")
+	fmt.Println("  proxyctl backends [flags]       List backends with their state and models")
+	fmt.Println("  proxyctl generate [flags] [prompt]  Run a test generation")
+	fmt.Println("  proxyctl reload [flags]         Reload the configuration file, as on SIGHUP")
+	fmt.Println("  proxyctl drain [flags] [status] Drain and shut down the proxy, or show the drain status")
+	fmt.Println("  proxyctl keys [list|create|enable|disable|delete] [flags] [name]  Manage API keys")
+	fmt.Println()
+	fmt.Println("Doctor flags:")
+	fmt.Println("  --url <url>        Proxy base URL (default http://localhost:8080)")
+	fmt.Println("  --api-key <key>    Admin API key (default $OLLAMA_PROXY_API_KEY)")
+	fmt.Println("  --json             Print the raw JSON report")
+	fmt.Println("  --timeout <dur>    Request timeout (default 30s)")
+	fmt.Println()
+	fmt.Println("Route-replay flags:")
+	fmt.Println("  --config <file>    Candidate configuration (required)")
+	fmt.Println("  --log <file>       Decision log (default routing.decision_log.path)")
+	fmt.Println("  --json             Print the raw JSON report")
+	fmt.Println("  --limit <n>        Changed decisions to list (default 20, 0 = all)")
+	fmt.Println()
+	fmt.Println("Support-bundle flags:")
+	fmt.Println("  --url <url>        Proxy base URL (default http://localhost:8080)")
+	fmt.Println("  --api-key <key>    Admin API key (default $OLLAMA_PROXY_API_KEY)")
+	fmt.Println("  --config <file>    Proxy configuration (default config/config.yaml)")
+	fmt.Println("  --output <file>    Archive path (default ollama-proxy-support-<time>.zip)")
+	fmt.Println("  --log-lines <n>    Recent journal lines to include (default 2000)")
+	fmt.Println("  --timeout <dur>    Request timeout (default 30s)")
+	fmt.Println()
+	fmt.Println("gRPC flags (health, backends, generate, reload, drain, keys):")
+	fmt.Println("  --grpc-addr <addr> Proxy gRPC address (default $OLLAMA_PROXY_GRPC_ADDR or localhost:50051)")
+	fmt.Println("  --api-key <key>    API key; reload, drain and keys need the admin permission (default $OLLAMA_PROXY_API_KEY)")
+	fmt.Println("  --tls              Connect with TLS (implied by --ca, --cert and --key)")
+	fmt.Println("  --ca <file>        CA certificate verifying the proxy (default system roots)")
+	fmt.Println("  --cert <file>      Client certificate for mTLS")
+	fmt.Println("  --key <file>       Client key for mTLS")
+	fmt.Println("  --server-name <n>  Name to verify the proxy's certificate against")
+	fmt.Println("  --json             Print the raw JSON response")
+	fmt.Println("  --timeout <dur>    Request timeout (default 30s)")
+	fmt.Println("  --model <name>     Model for generate (default: the proxy picks)")
+	fmt.Println("  --reason <text>    Reason for drain, logged by the proxy")
+	fmt.Println("  --drain-timeout <dur>  How long in-flight requests may run (default server.drain.timeout)")
+	fmt.Println("  --tenant <name>    Tenant for keys create (default the name)")
+	fmt.Println("  --permissions <p>  Comma-separated permissions for keys create, e.g. admin")
+}
+
+func doctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	baseURL := fs.String("url", "http://localhost:8080", "proxy base URL")
+	apiKey := fs.String("api-key", os.Getenv("OLLAMA_PROXY_API_KEY"), "admin API key")
+	asJSON := fs.Bool("json", false, "print the raw JSON report")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	fs.Parse(args)
+
+	c, err := client.New(client.Config{HTTPURL: *baseURL, APIKey: *apiKey, Timeout: *timeout})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid URL: %v\n", err)
+		os.Exit(1)
+	}
+	defer c.Close()
+
+	report, err := c.Diagnostics(context.Background())
+	var apiErr *client.Error
+	switch {
+	case err == nil:
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden):
+		fmt.Fprintf(os.Stderr, "Proxy rejected the request (%d)\n", apiErr.StatusCode)
+		fmt.Fprintln(os.Stderr, "  -> pass an admin key with --api-key or set OLLAMA_PROXY_API_KEY")
+		os.Exit(1)
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		fmt.Fprintln(os.Stderr, "Proxy does not expose /admin/diagnostics")
+		fmt.Fprintln(os.Stderr, "  -> upgrade the proxy to a version with self-diagnostics")
+		os.Exit(1)
+	case errors.As(err, &apiErr):
+		fmt.Fprintf(os.Stderr, "Unexpected status %d: %s\n", apiErr.StatusCode, apiErr.Message)
+		os.Exit(1)
+	default:
+		fmt.Fprintf(os.Stderr, "Failed to reach proxy at %s: %v\n", *baseURL, err)
+		fmt.Fprintln(os.Stderr, "  -> check the proxy is running (systemctl status ollama-proxy) or pass --url")
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("Ollama Proxy diagnostics (%s)\n", *baseURL)
+		report.WriteText(os.Stdout)
+		if report.Status == diagnostics.StatusOK {
+			fmt.Println("\n✓ All checks passed")
+		}
+	}
+
+	if report.Status == diagnostics.StatusFail {
+		os.Exit(1)
+	}
+}
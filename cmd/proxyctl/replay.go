@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/daoneill/ollama-proxy/pkg/config"
+	"github.com/daoneill/ollama-proxy/pkg/replay"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultDecisionLog is where the shipped config records decisions
+const defaultDecisionLog = "/var/lib/ollama-proxy/routing-decisions.jsonl"
+
+func routeReplay(args []string) {
+	fs := flag.NewFlagSet("route-replay", flag.ExitOnError)
+	configPath := fs.String("config", "", "candidate configuration to replay against")
+	logPath := fs.String("log", "", "decision log (default: the candidate's routing.decision_log.path)")
+	asJSON := fs.Bool("json", false, "print the raw JSON report")
+	limit := fs.Int("limit", 20, "changed decisions to list (0 = all)")
+	fs.Parse(args)
+
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, "route-replay requires --config <candidate.yaml>")
+		os.Exit(1)
+	}
+
+	cfg, err := loadCandidateConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid candidate config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *logPath == "" {
+		*logPath = cfg.Routing.DecisionLog.Path
+	}
+	if *logPath == "" {
+		*logPath = defaultDecisionLog
+	}
+	f, err := os.Open(*logPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open decision log: %v\n", err)
+		fmt.Fprintln(os.Stderr, "  -> enable routing.decision_log on the running proxy or pass --log")
+		os.Exit(1)
+	}
+	records, err := replay.ReadRecords(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read decision log %s: %v\n", *logPath, err)
+		os.Exit(1)
+	}
+
+	report := replay.Replay(records, candidateFromConfig(cfg))
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	fmt.Printf("Routing replay of %s against %s\n\n", *logPath, *configPath)
+	report.WriteText(os.Stdout, *limit)
+}
+
+// loadCandidateConfig parses and validates a configuration file
+func loadCandidateConfig(path string) (*config.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if err := config.ValidateConfig(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// candidateFromConfig builds the router configuration the proxy would run
+// with, limited to the settings that influence backend selection
+func candidateFromConfig(cfg *config.Config) replay.Candidate {
+	c := replay.Candidate{
+		Router: router.Config{
+			DefaultBackendID: cfg.Routing.DefaultBackend,
+			PowerAware:       cfg.Routing.PowerAware,
+			AutoOptimize:     cfg.Routing.AutoOptimizeLatency,
+			BackendLanguages: make(map[string][]string),
+			LoadBalancing: router.LoadBalancingConfig{
+				Strategy:  cfg.Routing.LoadBalancing.Strategy,
+				Models:    cfg.Routing.LoadBalancing.Models,
+				Weights:   make(map[string]int),
+				EWMADecay: cfg.Routing.LoadBalancing.EWMADecay,
+			},
+		},
+	}
+
+	for _, b := range cfg.Backends {
+		if !b.Enabled {
+			continue
+		}
+		if len(b.Languages) > 0 {
+			c.Router.BackendLanguages[b.ID] = b.Languages
+		}
+		if b.Characteristics.Weight > 0 {
+			c.Router.LoadBalancing.Weights[b.ID] = b.Characteristics.Weight
+		}
+		c.Backends = append(c.Backends, replay.BackendSpec{
+			ID:                     b.ID,
+			Type:                   b.Type,
+			Hardware:               b.Hardware,
+			PowerWatts:             b.Characteristics.PowerWatts,
+			AvgLatencyMs:           b.Characteristics.AvgLatencyMs,
+			Priority:               b.Characteristics.Priority,
+			SupportedModelPatterns: b.ModelCapability.SupportedModelPatterns,
+			PreferredModels:        b.ModelCapability.PreferredModels,
+			ExcludedPatterns:       b.ModelCapability.ExcludedPatterns,
+		})
+	}
+	return c
+}
@@ -0,0 +1,370 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// Alert states
+const (
+	StatePending  = "pending"
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Sample is one metric observation, e.g. backend_up{ollama-npu} = 0
+type Sample struct {
+	Metric  string
+	Subject string // backend ID or hardware name
+	Value   float64
+}
+
+// Source produces the current samples for rule evaluation
+type Source interface {
+	Samples(now time.Time) []Sample
+}
+
+// SourceFunc adapts a function to the Source interface
+type SourceFunc func(now time.Time) []Sample
+
+// Samples calls f(now)
+func (f SourceFunc) Samples(now time.Time) []Sample {
+	return f(now)
+}
+
+// Rule is a threshold condition over a metric
+type Rule struct {
+	Name      string
+	Metric    string
+	Subject   string // "" or "*" matches every subject
+	Op        string // ">", ">=", "<", "<=", "==", "!="
+	Threshold float64
+	For       time.Duration // condition must hold this long before firing
+	Severity  string        // "info", "warning", "critical"
+	Notify    []string      // notifier names; empty = all
+}
+
+// Validate checks the rule is well formed
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("alert rule missing name")
+	}
+	if r.Metric == "" {
+		return fmt.Errorf("alert rule %s missing metric", r.Name)
+	}
+	if _, ok := operators[r.Op]; !ok {
+		return fmt.Errorf("alert rule %s has invalid op %q", r.Name, r.Op)
+	}
+	return nil
+}
+
+func (r Rule) matches(s Sample) bool {
+	if s.Metric != r.Metric {
+		return false
+	}
+	return r.Subject == "" || r.Subject == "*" || r.Subject == s.Subject
+}
+
+var operators = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// Alert is the state of a rule for one subject
+type Alert struct {
+	Rule       string    `json:"rule"`
+	Subject    string    `json:"subject"`
+	Metric     string    `json:"metric"`
+	Severity   string    `json:"severity"`
+	State      string    `json:"state"`
+	Value      float64   `json:"value"`
+	Threshold  float64   `json:"threshold"`
+	Op         string    `json:"op"`
+	ActiveAt   time.Time `json:"active_at"`          // condition first seen
+	FiredAt    time.Time `json:"fired_at,omitempty"` // notification sent
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+}
+
+// Message returns a human readable summary
+func (a Alert) Message() string {
+	if a.State == StateResolved {
+		return fmt.Sprintf("[resolved] %s: %s %s is back to %.2f", a.Rule, a.Subject, a.Metric, a.Value)
+	}
+	return fmt.Sprintf("[%s] %s: %s %s = %.2f (%s %.2f)", a.Severity, a.Rule, a.Subject, a.Metric, a.Value, a.Op, a.Threshold)
+}
+
+// Config configures the engine
+type Config struct {
+	Interval        time.Duration
+	NotifierTimeout time.Duration
+	Rules           []Rule
+}
+
+// Engine evaluates rules on an interval and notifies on firing/resolving
+type Engine struct {
+	cfg Config
+
+	mu        sync.Mutex
+	sources   []Source
+	notifiers map[string]Notifier
+	active    map[string]*Alert // rule + subject -> alert
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewEngine creates a new alerting engine
+func NewEngine(cfg Config) (*Engine, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.NotifierTimeout <= 0 {
+		cfg.NotifierTimeout = 10 * time.Second
+	}
+	for _, rule := range cfg.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Engine{
+		cfg:       cfg,
+		notifiers: make(map[string]Notifier),
+		active:    make(map[string]*Alert),
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// AddSource registers a metric source
+func (e *Engine) AddSource(s Source) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sources = append(e.sources, s)
+}
+
+// AddNotifier registers a notifier under its name
+func (e *Engine) AddNotifier(n Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.notifiers[n.Name()] = n
+}
+
+// Start begins periodic evaluation
+func (e *Engine) Start() {
+	go func() {
+		ticker := time.NewTicker(e.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-e.ctx.Done():
+				return
+			case now := <-ticker.C:
+				middleware.Safe(middleware.ScopeBackground, "alerting", func() { e.Evaluate(now) })
+			}
+		}
+	}()
+}
+
+// Stop halts evaluation
+func (e *Engine) Stop() {
+	e.cancel()
+}
+
+// Evaluate runs all rules against current samples and sends notifications
+// for alerts that started firing or resolved. It returns those transitions.
+func (e *Engine) Evaluate(now time.Time) []Alert {
+	e.mu.Lock()
+	sources := append([]Source(nil), e.sources...)
+	e.mu.Unlock()
+
+	var samples []Sample
+	for _, src := range sources {
+		samples = append(samples, src.Samples(now)...)
+	}
+
+	e.mu.Lock()
+	var transitions []Alert
+	seen := make(map[string]bool)
+
+	for _, rule := range e.cfg.Rules {
+		cmp := operators[rule.Op]
+		for _, s := range samples {
+			if !rule.matches(s) {
+				continue
+			}
+			key := rule.Name + "/" + s.Subject
+			seen[key] = true
+
+			alert, exists := e.active[key]
+			if !cmp(s.Value, rule.Threshold) {
+				if exists {
+					delete(e.active, key)
+					if alert.State == StateFiring {
+						alert.State = StateResolved
+						alert.Value = s.Value
+						alert.ResolvedAt = now
+						transitions = append(transitions, *alert)
+					}
+				}
+				continue
+			}
+
+			if !exists {
+				alert = &Alert{
+					Rule:      rule.Name,
+					Subject:   s.Subject,
+					Metric:    rule.Metric,
+					Severity:  rule.Severity,
+					State:     StatePending,
+					Threshold: rule.Threshold,
+					Op:        rule.Op,
+					ActiveAt:  now,
+				}
+				e.active[key] = alert
+			}
+			alert.Value = s.Value
+
+			if alert.State == StatePending && now.Sub(alert.ActiveAt) >= rule.For {
+				alert.State = StateFiring
+				alert.FiredAt = now
+				transitions = append(transitions, *alert)
+			}
+		}
+	}
+
+	// Subjects that stopped reporting (e.g. removed backend) resolve
+	for key, alert := range e.active {
+		if seen[key] {
+			continue
+		}
+		delete(e.active, key)
+		if alert.State == StateFiring {
+			alert.State = StateResolved
+			alert.ResolvedAt = now
+			transitions = append(transitions, *alert)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, alert := range transitions {
+		e.notify(alert)
+	}
+	return transitions
+}
+
+func (e *Engine) ruleByName(name string) (Rule, bool) {
+	for _, rule := range e.cfg.Rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// notify sends the alert to the rule's notifiers (all when unspecified)
+func (e *Engine) notify(alert Alert) {
+	rule, _ := e.ruleByName(alert.Rule)
+
+	e.mu.Lock()
+	var targets []Notifier
+	if len(rule.Notify) == 0 {
+		for _, n := range e.notifiers {
+			targets = append(targets, n)
+		}
+	} else {
+		for _, name := range rule.Notify {
+			if n, ok := e.notifiers[name]; ok {
+				targets = append(targets, n)
+			} else if logging.Logger != nil {
+				logging.Logger.Warn("Alert rule references unknown notifier",
+					zap.String("rule", rule.Name),
+					zap.String("notifier", name),
+				)
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	if logging.Logger != nil {
+		logging.Logger.Info("Alert "+alert.State,
+			zap.String("rule", alert.Rule),
+			zap.String("subject", alert.Subject),
+			zap.Float64("value", alert.Value),
+		)
+	}
+
+	for _, n := range targets {
+		ctx, cancel := context.WithTimeout(e.ctx, e.cfg.NotifierTimeout)
+		if err := n.Notify(ctx, alert); err != nil && logging.Logger != nil {
+			logging.Logger.Warn("Alert notification failed",
+				zap.String("notifier", n.Name()),
+				zap.String("rule", alert.Rule),
+				zap.Error(err),
+			)
+		}
+		cancel()
+	}
+}
+
+// Alerts returns pending and firing alerts, sorted by rule and subject
+func (e *Engine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]Alert, 0, len(e.active))
+	for _, alert := range e.active {
+		out = append(out, *alert)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Rule != out[j].Rule {
+			return out[i].Rule < out[j].Rule
+		}
+		return out[i].Subject < out[j].Subject
+	})
+	return out
+}
+
+// Handler serves /admin/alerts with configured rules and active alerts
+func (e *Engine) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		rules := make([]map[string]interface{}, 0, len(e.cfg.Rules))
+		for _, rule := range e.cfg.Rules {
+			rules = append(rules, map[string]interface{}{
+				"name":      rule.Name,
+				"metric":    rule.Metric,
+				"subject":   rule.Subject,
+				"op":        rule.Op,
+				"threshold": rule.Threshold,
+				"for":       rule.For.String(),
+				"severity":  rule.Severity,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"rules":  rules,
+			"alerts": e.Alerts(),
+		})
+	}
+}
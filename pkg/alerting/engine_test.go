@@ -0,0 +1,189 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingNotifier captures notified alerts
+type recordingNotifier struct {
+	mu     sync.Mutex
+	name   string
+	alerts []Alert
+}
+
+func (n *recordingNotifier) Name() string { return n.name }
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+// staticSource returns whatever samples are currently set
+type staticSource struct {
+	samples []Sample
+}
+
+func (s *staticSource) Samples(now time.Time) []Sample { return s.samples }
+
+func TestEngine_FiresAfterForAndResolves(t *testing.T) {
+	engine, err := NewEngine(Config{Rules: []Rule{{
+		Name:      "backend-down",
+		Metric:    MetricBackendUp,
+		Subject:   "*",
+		Op:        "<",
+		Threshold: 1,
+		For:       2 * time.Minute,
+		Severity:  "critical",
+	}}})
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	src := &staticSource{samples: []Sample{{Metric: MetricBackendUp, Subject: "ollama-npu", Value: 0}}}
+	engine.AddSource(src)
+	notifier := &recordingNotifier{name: "test"}
+	engine.AddNotifier(notifier)
+
+	start := time.Now()
+	if got := engine.Evaluate(start); len(got) != 0 {
+		t.Fatalf("Expected pending alert not to notify, got %v", got)
+	}
+	if alerts := engine.Alerts(); len(alerts) != 1 || alerts[0].State != StatePending {
+		t.Fatalf("Expected one pending alert, got %+v", alerts)
+	}
+
+	engine.Evaluate(start.Add(time.Minute))
+	if len(notifier.alerts) != 0 {
+		t.Fatal("Expected no notification before For elapsed")
+	}
+
+	engine.Evaluate(start.Add(2 * time.Minute))
+	if len(notifier.alerts) != 1 || notifier.alerts[0].State != StateFiring {
+		t.Fatalf("Expected firing notification, got %+v", notifier.alerts)
+	}
+
+	// Still down: no repeated notification
+	engine.Evaluate(start.Add(3 * time.Minute))
+	if len(notifier.alerts) != 1 {
+		t.Errorf("Expected a single firing notification, got %d", len(notifier.alerts))
+	}
+
+	src.samples[0].Value = 1
+	engine.Evaluate(start.Add(4 * time.Minute))
+	if len(notifier.alerts) != 2 || notifier.alerts[1].State != StateResolved {
+		t.Fatalf("Expected resolved notification, got %+v", notifier.alerts)
+	}
+	if len(engine.Alerts()) != 0 {
+		t.Error("Expected no active alerts after resolve")
+	}
+}
+
+func TestEngine_PendingClearsSilently(t *testing.T) {
+	engine, _ := NewEngine(Config{Rules: []Rule{{
+		Name: "hot", Metric: MetricTemperatureCelsius, Op: ">", Threshold: 85, For: time.Minute,
+	}}})
+	src := &staticSource{samples: []Sample{{Metric: MetricTemperatureCelsius, Subject: "nvidia", Value: 90}}}
+	engine.AddSource(src)
+	notifier := &recordingNotifier{name: "test"}
+	engine.AddNotifier(notifier)
+
+	now := time.Now()
+	engine.Evaluate(now)
+	src.samples[0].Value = 70
+	engine.Evaluate(now.Add(30 * time.Second))
+
+	if len(notifier.alerts) != 0 || len(engine.Alerts()) != 0 {
+		t.Errorf("Expected short spike not to alert, got %+v", notifier.alerts)
+	}
+}
+
+func TestEngine_SubjectAndNotifierSelection(t *testing.T) {
+	engine, _ := NewEngine(Config{Rules: []Rule{{
+		Name: "npu-hot", Metric: MetricTemperatureCelsius, Subject: "npu", Op: ">=", Threshold: 80, Notify: []string{"ops"},
+	}}})
+	engine.AddSource(&staticSource{samples: []Sample{
+		{Metric: MetricTemperatureCelsius, Subject: "npu", Value: 80},
+		{Metric: MetricTemperatureCelsius, Subject: "nvidia", Value: 95},
+	}})
+	ops := &recordingNotifier{name: "ops"}
+	other := &recordingNotifier{name: "other"}
+	engine.AddNotifier(ops)
+	engine.AddNotifier(other)
+
+	engine.Evaluate(time.Now())
+
+	if len(ops.alerts) != 1 || ops.alerts[0].Subject != "npu" {
+		t.Errorf("Expected only the npu alert to reach ops, got %+v", ops.alerts)
+	}
+	if len(other.alerts) != 0 {
+		t.Errorf("Expected notifier not listed in rule to be skipped, got %+v", other.alerts)
+	}
+}
+
+func TestEngine_MissingSubjectResolves(t *testing.T) {
+	engine, _ := NewEngine(Config{Rules: []Rule{{
+		Name: "down", Metric: MetricBackendUp, Op: "==", Threshold: 0,
+	}}})
+	src := &staticSource{samples: []Sample{{Metric: MetricBackendUp, Subject: "old", Value: 0}}}
+	engine.AddSource(src)
+	notifier := &recordingNotifier{name: "test"}
+	engine.AddNotifier(notifier)
+
+	engine.Evaluate(time.Now())
+	src.samples = nil
+	engine.Evaluate(time.Now())
+
+	if len(notifier.alerts) != 2 || notifier.alerts[1].State != StateResolved {
+		t.Errorf("Expected alert for removed subject to resolve, got %+v", notifier.alerts)
+	}
+}
+
+func TestNewEngine_InvalidRule(t *testing.T) {
+	if _, err := NewEngine(Config{Rules: []Rule{{Name: "x", Metric: "m", Op: "~"}}}); err == nil {
+		t.Error("Expected error for invalid operator")
+	}
+	if _, err := NewEngine(Config{Rules: []Rule{{Name: "x", Op: ">"}}}); err == nil {
+		t.Error("Expected error for missing metric")
+	}
+}
+
+func TestEngine_Handler(t *testing.T) {
+	engine, _ := NewEngine(Config{Rules: []Rule{{
+		Name: "slow", Metric: MetricLatencyP95Ms, Op: ">", Threshold: 2000, For: time.Minute,
+	}}})
+	engine.AddSource(&staticSource{samples: []Sample{{Metric: MetricLatencyP95Ms, Subject: "ollama-igpu", Value: 2500}}})
+	engine.Evaluate(time.Now())
+
+	w := httptest.NewRecorder()
+	engine.Handler()(w, httptest.NewRequest(http.MethodGet, "/admin/alerts", nil))
+
+	var body struct {
+		Rules  []map[string]interface{} `json:"rules"`
+		Alerts []Alert                  `json:"alerts"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Rules) != 1 || body.Rules[0]["for"] != "1m0s" {
+		t.Errorf("Unexpected rules: %+v", body.Rules)
+	}
+	if len(body.Alerts) != 1 || body.Alerts[0].State != StatePending {
+		t.Errorf("Expected pending alert, got %+v", body.Alerts)
+	}
+}
+
+func TestAlert_Message(t *testing.T) {
+	alert := Alert{Rule: "hot", Subject: "nvidia", Metric: "temperature_celsius", Severity: "warning", State: StateFiring, Value: 91, Op: ">", Threshold: 85}
+	if msg := alert.Message(); !strings.Contains(msg, "[warning] hot: nvidia") || !strings.Contains(msg, "> 85.00") {
+		t.Errorf("Unexpected message: %s", msg)
+	}
+}
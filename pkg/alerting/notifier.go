@@ -0,0 +1,93 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Notifier delivers alert transitions (firing and resolved)
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// funcNotifier adapts a function to the Notifier interface
+type funcNotifier struct {
+	name string
+	fn   func(ctx context.Context, alert Alert) error
+}
+
+// NewFuncNotifier wraps fn as a named notifier (e.g. the D-Bus signal)
+func NewFuncNotifier(name string, fn func(ctx context.Context, alert Alert) error) Notifier {
+	return &funcNotifier{name: name, fn: fn}
+}
+
+func (n *funcNotifier) Name() string { return n.name }
+
+func (n *funcNotifier) Notify(ctx context.Context, alert Alert) error {
+	return n.fn(ctx, alert)
+}
+
+// WebhookNotifier posts alerts as JSON to a URL. The payload carries a
+// "text" field so Slack/Mattermost incoming webhooks render it directly.
+type WebhookNotifier struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier
+func NewWebhookNotifier(name, url string, headers map[string]string) *WebhookNotifier {
+	return &WebhookNotifier{
+		name:    name,
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the notifier name
+func (n *WebhookNotifier) Name() string {
+	return n.name
+}
+
+// Notify posts the alert
+func (n *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(struct {
+		Text  string `json:"text"`
+		Alert Alert  `json:"alert"`
+	}{
+		Text:  alert.Message(),
+		Alert: alert,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
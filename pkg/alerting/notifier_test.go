@@ -0,0 +1,62 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var payload struct {
+		Text  string `json:"text"`
+		Alert Alert  `json:"alert"`
+	}
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Token")
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier("ops", srv.URL, map[string]string{"X-Token": "secret"})
+	alert := Alert{Rule: "down", Subject: "ollama-npu", State: StateFiring, Severity: "critical"}
+	if err := n.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if token != "secret" {
+		t.Errorf("Expected custom header, got %q", token)
+	}
+	if payload.Alert.Subject != "ollama-npu" || payload.Text == "" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier("ops", srv.URL, nil)
+	if err := n.Notify(context.Background(), Alert{}); err == nil {
+		t.Error("Expected error for non-2xx status")
+	}
+}
+
+func TestFuncNotifier(t *testing.T) {
+	var got Alert
+	n := NewFuncNotifier("dbus", func(ctx context.Context, alert Alert) error {
+		got = alert
+		return nil
+	})
+	if n.Name() != "dbus" {
+		t.Errorf("Expected name dbus, got %s", n.Name())
+	}
+	n.Notify(context.Background(), Alert{Rule: "r"})
+	if got.Rule != "r" {
+		t.Error("Expected wrapped function to be called")
+	}
+}
@@ -0,0 +1,109 @@
+package alerting
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/thermal"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+)
+
+// Metric names understood by the built-in sources
+const (
+	MetricBackendUp          = "backend_up"          // 1 healthy, 0 down
+	MetricErrorRate          = "error_rate"          // 0-1 over the backend's lifetime
+	MetricAvgLatencyMs       = "avg_latency_ms"      // backend's running average
+	MetricLatencyP95Ms       = "latency_p95_ms"      // from recent usage records
+	MetricRequests           = "requests"            // requests in the usage window
+	MetricTemperatureCelsius = "temperature_celsius" // per hardware
+	MetricFanPercent         = "fan_percent"         // per hardware
+	MetricPowerWatts         = "power_watts"         // per hardware
+	MetricThrottling         = "throttling"          // 1 when thermally throttled
+)
+
+// BackendLister is satisfied by *router.Router
+type BackendLister interface {
+	ListBackends() []backends.Backend
+}
+
+// BackendSource reports health, error rate and latency per backend
+func BackendSource(lister BackendLister) Source {
+	return SourceFunc(func(now time.Time) []Sample {
+		var out []Sample
+		for _, b := range lister.ListBackends() {
+			up := 0.0
+			if b.IsHealthy() {
+				up = 1
+			}
+			out = append(out, Sample{Metric: MetricBackendUp, Subject: b.ID(), Value: up})
+
+			if m := b.GetMetrics(); m != nil && m.RequestCount > 0 {
+				out = append(out,
+					Sample{Metric: MetricErrorRate, Subject: b.ID(), Value: float64(m.ErrorCount) / float64(m.RequestCount)},
+					Sample{Metric: MetricAvgLatencyMs, Subject: b.ID(), Value: float64(m.AvgLatencyMs)},
+				)
+			}
+		}
+		return out
+	})
+}
+
+// ThermalSource reports temperature, fan, power and throttling per hardware
+func ThermalSource(tm *thermal.ThermalMonitor) Source {
+	return SourceFunc(func(now time.Time) []Sample {
+		var out []Sample
+		for hw, state := range tm.GetAllStates() {
+			throttling := 0.0
+			if state.Throttling {
+				throttling = 1
+			}
+			out = append(out,
+				Sample{Metric: MetricTemperatureCelsius, Subject: hw, Value: state.Temperature},
+				Sample{Metric: MetricFanPercent, Subject: hw, Value: float64(state.FanPercent)},
+				Sample{Metric: MetricPowerWatts, Subject: hw, Value: state.PowerDraw},
+				Sample{Metric: MetricThrottling, Subject: hw, Value: throttling},
+			)
+		}
+		return out
+	})
+}
+
+// UsageSource reports p95 latency and request count per backend over the
+// trailing window of usage records
+func UsageSource(recorder *usage.Recorder, window time.Duration) Source {
+	return SourceFunc(func(now time.Time) []Sample {
+		durations := make(map[string][]int64)
+		for _, rec := range recorder.Query(now.Add(-window), now.Add(time.Second)) {
+			durations[rec.Backend] = append(durations[rec.Backend], rec.DurationMs)
+		}
+
+		var out []Sample
+		for backend, ds := range durations {
+			out = append(out,
+				Sample{Metric: MetricLatencyP95Ms, Subject: backend, Value: float64(percentile(ds, 0.95))},
+				Sample{Metric: MetricRequests, Subject: backend, Value: float64(len(ds))},
+			)
+		}
+		return out
+	})
+}
+
+// percentile returns the nearest-rank percentile of values
+func percentile(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,80 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+)
+
+// stubBackend reports fixed health and metrics
+type stubBackend struct {
+	backends.Backend
+	id      string
+	healthy bool
+	metrics *backends.BackendMetrics
+}
+
+func (s *stubBackend) ID() string                           { return s.id }
+func (s *stubBackend) IsHealthy() bool                      { return s.healthy }
+func (s *stubBackend) GetMetrics() *backends.BackendMetrics { return s.metrics }
+
+type stubLister []backends.Backend
+
+func (l stubLister) ListBackends() []backends.Backend { return l }
+
+func samplesByKey(samples []Sample) map[string]float64 {
+	out := make(map[string]float64)
+	for _, s := range samples {
+		out[s.Metric+"/"+s.Subject] = s.Value
+	}
+	return out
+}
+
+func TestBackendSource(t *testing.T) {
+	src := BackendSource(stubLister{
+		&stubBackend{id: "npu", healthy: true, metrics: &backends.BackendMetrics{RequestCount: 10, ErrorCount: 2, AvgLatencyMs: 120}},
+		&stubBackend{id: "nvidia", healthy: false, metrics: &backends.BackendMetrics{}},
+	})
+
+	got := samplesByKey(src.Samples(time.Now()))
+	if got["backend_up/npu"] != 1 || got["backend_up/nvidia"] != 0 {
+		t.Errorf("Unexpected health samples: %v", got)
+	}
+	if got["error_rate/npu"] != 0.2 || got["avg_latency_ms/npu"] != 120 {
+		t.Errorf("Unexpected metric samples: %v", got)
+	}
+	if _, ok := got["error_rate/nvidia"]; ok {
+		t.Error("Expected no error rate for a backend without requests")
+	}
+}
+
+func TestUsageSource(t *testing.T) {
+	recorder := usage.NewRecorder(usage.DefaultConfig())
+	now := time.Now()
+	for i := 1; i <= 20; i++ {
+		recorder.Record(usage.Record{Time: now, Backend: "igpu", DurationMs: int64(i * 100)})
+	}
+	recorder.Record(usage.Record{Time: now.Add(-time.Hour), Backend: "igpu", DurationMs: 99999})
+
+	got := samplesByKey(UsageSource(recorder, 5*time.Minute).Samples(now))
+	if got["latency_p95_ms/igpu"] != 1900 {
+		t.Errorf("Expected p95 of 1900ms, got %v", got["latency_p95_ms/igpu"])
+	}
+	if got["requests/igpu"] != 20 {
+		t.Errorf("Expected 20 requests in window, got %v", got["requests/igpu"])
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	if percentile(nil, 0.95) != 0 {
+		t.Error("Expected 0 for empty input")
+	}
+	if got := percentile([]int64{5}, 0.95); got != 5 {
+		t.Errorf("Expected single value, got %d", got)
+	}
+	if got := percentile([]int64{4, 1, 3, 2}, 0.5); got != 2 {
+		t.Errorf("Expected median 2, got %d", got)
+	}
+}
@@ -0,0 +1,214 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/rotating"
+	"go.uber.org/zap"
+)
+
+// Fields that can be redacted from audit records
+const (
+	FieldKey        = "key"
+	FieldTenant     = "tenant"
+	FieldLabels     = "labels"
+	FieldPromptHash = "prompt_hash"
+	FieldError      = "error"
+)
+
+// RedactableFields lists the fields Config.Redact accepts
+var RedactableFields = []string{FieldKey, FieldTenant, FieldLabels, FieldPromptHash, FieldError}
+
+// DefaultPromptHashLength is how many hex characters of the prompt hash
+// are kept
+const DefaultPromptHashLength = 16
+
+// maxErrorLength bounds error messages, which may quote the request
+const maxErrorLength = 256
+
+// Record is one inference request in the audit log
+type Record struct {
+	Time             time.Time         `json:"time"`
+	RequestID        string            `json:"request_id,omitempty"`
+	Key              string            `json:"key,omitempty"` // API key name, never the key itself
+	Tenant           string            `json:"tenant,omitempty"`
+	Endpoint         string            `json:"endpoint"`
+	Model            string            `json:"model"`
+	Backend          string            `json:"backend"`
+	Status           string            `json:"status"` // "success" or "error"
+	Error            string            `json:"error,omitempty"`
+	LatencyMs        int64             `json:"latency_ms"`
+	PromptTokens     int64             `json:"prompt_tokens"`
+	CompletionTokens int64             `json:"completion_tokens"`
+	PromptHash       string            `json:"prompt_hash,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Thermal          *Thermal          `json:"thermal,omitempty"` // at dispatch, when monitored
+}
+
+// Thermal is the state of the backend's hardware when a request was
+// dispatched, to correlate slow requests with throttling
+type Thermal struct {
+	TemperatureC float64 `json:"temperature_c"`
+	FanPercent   int     `json:"fan_percent"`
+	Throttling   bool    `json:"throttling"`
+}
+
+// Config configures the audit log
+type Config struct {
+	Path       string
+	MaxSizeMB  int // rotate when the file exceeds this size (0 = never)
+	MaxBackups int // rotated files to keep as path.1 ... path.N
+
+	// PromptHashLength is how many hex characters of the prompt's SHA-256
+	// are kept (0 = DefaultPromptHashLength, at most 64)
+	PromptHashLength int
+
+	// PromptHashKey makes prompt hashes an HMAC, so they cannot be matched
+	// against guessed prompts without the key (empty = plain SHA-256)
+	PromptHashKey string
+
+	// Redact lists fields left out of every record (see RedactableFields)
+	Redact []string
+}
+
+// Logger appends audit records to a rotating JSONL file. Records are
+// written and synced before Log returns, so none are lost on a crash.
+type Logger struct {
+	cfg    Config
+	redact map[string]bool
+
+	mu   sync.Mutex
+	file *rotating.File
+}
+
+// NewLogger opens (or creates) the audit log
+func NewLogger(cfg Config) (*Logger, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("audit log requires a path")
+	}
+	if cfg.PromptHashLength <= 0 || cfg.PromptHashLength > sha256.Size*2 {
+		cfg.PromptHashLength = DefaultPromptHashLength
+	}
+
+	l := &Logger{cfg: cfg, redact: make(map[string]bool, len(cfg.Redact))}
+	for _, field := range cfg.Redact {
+		l.redact[field] = true
+	}
+	file, err := rotating.Open(rotating.Config{
+		Path:       cfg.Path,
+		Name:       "audit log",
+		MaxSizeMB:  cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		DirMode:    0o750,
+		FileMode:   0o600,
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.file = file
+	return l, nil
+}
+
+// defaultLogger is the process-wide audit log, nil when auditing is
+// disabled
+var defaultLogger atomic.Pointer[Logger]
+
+// Default returns the process-wide audit log, nil when auditing is disabled
+func Default() *Logger {
+	return defaultLogger.Load()
+}
+
+// SetDefault replaces the process-wide audit log
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+}
+
+// PromptHash returns the truncated hash of a prompt as it appears in
+// records, so a known prompt can be looked up in the log
+func (l *Logger) PromptHash(prompt string) string {
+	var sum []byte
+	if l.cfg.PromptHashKey != "" {
+		mac := hmac.New(sha256.New, []byte(l.cfg.PromptHashKey))
+		mac.Write([]byte(prompt))
+		sum = mac.Sum(nil)
+	} else {
+		digest := sha256.Sum256([]byte(prompt))
+		sum = digest[:]
+	}
+	return hex.EncodeToString(sum)[:l.cfg.PromptHashLength]
+}
+
+// Log writes a record of a request, hashing its prompt. It does nothing
+// on a nil Logger. Write failures are logged rather than failing the
+// request.
+func (l *Logger) Log(rec Record, prompt string) {
+	if l == nil {
+		return
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	if prompt != "" {
+		rec.PromptHash = l.PromptHash(prompt)
+	}
+	if len(rec.Error) > maxErrorLength {
+		rec.Error = rec.Error[:maxErrorLength]
+	}
+	l.applyRedaction(&rec)
+
+	if err := l.write(rec); err != nil && logging.Logger != nil {
+		logging.Logger.Error("Failed to write audit record",
+			zap.String("request_id", rec.RequestID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (l *Logger) applyRedaction(rec *Record) {
+	if l.redact[FieldKey] {
+		rec.Key = ""
+	}
+	if l.redact[FieldTenant] {
+		rec.Tenant = ""
+	}
+	if l.redact[FieldLabels] {
+		rec.Labels = nil
+	}
+	if l.redact[FieldPromptHash] {
+		rec.PromptHash = ""
+	}
+	if l.redact[FieldError] {
+		rec.Error = ""
+	}
+}
+
+func (l *Logger) write(rec Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(line); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Close closes the audit log
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("Invalid JSONL line: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestLogger_Log(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "requests.jsonl")
+	l, err := NewLogger(Config{Path: path})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer l.Close()
+
+	l.Log(Record{Key: "ci-key", Model: "llama3", Backend: "npu", Status: "success", PromptTokens: 3}, "Hello")
+	l.Log(Record{Key: "ci-key", Model: "llama3", Backend: "npu", Status: "error", Error: strings.Repeat("x", 1000)}, "")
+
+	records := readRecords(t, path)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	// sha256("Hello") truncated to 16 hex characters
+	if records[0].PromptHash != "185f8db32271fe25" || records[0].Time.IsZero() {
+		t.Errorf("Expected a timestamped record with a truncated hash, got %+v", records[0])
+	}
+	if records[1].PromptHash != "" || len(records[1].Error) != maxErrorLength {
+		t.Errorf("Expected no hash and a truncated error, got %+v", records[1])
+	}
+
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the audit log to be private, got %v", info.Mode().Perm())
+	}
+}
+
+func TestLogger_Redact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	l, err := NewLogger(Config{Path: path, Redact: []string{FieldKey, FieldLabels, FieldPromptHash}})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer l.Close()
+
+	l.Log(Record{Key: "ci-key", Tenant: "lab-a", Labels: map[string]string{"team": "ml"}}, "Hello")
+
+	rec := readRecords(t, path)[0]
+	if rec.Key != "" || rec.Labels != nil || rec.PromptHash != "" {
+		t.Errorf("Expected redacted fields to be left out, got %+v", rec)
+	}
+	if rec.Tenant != "lab-a" {
+		t.Errorf("Expected other fields to be kept, got %+v", rec)
+	}
+}
+
+func TestLogger_PromptHash(t *testing.T) {
+	plain, _ := NewLogger(Config{Path: filepath.Join(t.TempDir(), "a.jsonl"), PromptHashLength: 64})
+	keyed, _ := NewLogger(Config{Path: filepath.Join(t.TempDir(), "b.jsonl"), PromptHashKey: "secret"})
+	defer plain.Close()
+	defer keyed.Close()
+
+	if got := plain.PromptHash("Hello"); got != "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969" {
+		t.Errorf("Expected the full SHA-256, got %s", got)
+	}
+	if got := keyed.PromptHash("Hello"); len(got) != DefaultPromptHashLength || got == "185f8db32271fe25" {
+		t.Errorf("Expected a keyed hash, got %s", got)
+	}
+}
+
+func TestLogger_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	l, err := NewLogger(Config{Path: path, MaxSizeMB: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer l.Close()
+
+	// Each record carries a maximal error, so a few thousand fill 1MB
+	rec := Record{Model: "llama3", Error: strings.Repeat("x", maxErrorLength)}
+	for i := 0; i < 5000; i++ {
+		l.Log(rec, "")
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("Expected a rotated file: %v", err)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected at most 2 backups, got err %v", err)
+	}
+}
+
+func TestLogger_Nil(t *testing.T) {
+	var l *Logger
+	l.Log(Record{Model: "llama3"}, "Hello") // auditing disabled, must not panic
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataKey returns the API key in a call's authorization metadata,
+// with or without a "Bearer " prefix
+func MetadataKey(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", false
+	}
+	key := values[0]
+	if len(key) > 7 && strings.EqualFold(key[:7], "bearer ") {
+		key = key[7:]
+	}
+	return key, true
+}
+
+// authenticateRPC stores the metadata of the call's API key in the
+// context, refusing invalid or disabled keys. Calls without a key pass
+// through unauthenticated.
+func authenticateRPC(ctx context.Context, cfg Config) (context.Context, error) {
+	key, ok := MetadataKey(ctx)
+	if !ok || !cfg.Enabled {
+		return ctx, nil
+	}
+	info, valid := ValidateAPIKey(cfg, key)
+	if !valid {
+		return nil, status.Error(codes.Unauthenticated, "invalid or disabled API key")
+	}
+	return WithKeyInfo(ctx, info), nil
+}
+
+// UnaryInterceptor authenticates the API key a unary RPC carries, so usage
+// and quotas are kept per key as on HTTP. It doesn't require one; authz
+// does that.
+func UnaryInterceptor(cfg Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateRPC(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor authenticates streaming RPCs like UnaryInterceptor
+func StreamInterceptor(cfg Config) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateRPC(ss.Context(), cfg)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &keyStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// keyStream carries the authenticated key in the stream's context
+type keyStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *keyStream) Context() context.Context {
+	return s.ctx
+}
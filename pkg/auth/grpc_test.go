@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	interceptor := UnaryInterceptor(Config{Enabled: true, APIKeys: map[string]APIKeyInfo{
+		"app-key": {Name: "app", Enabled: true},
+		"old-key": {Name: "old", Enabled: false},
+	}})
+	info := &grpc.UnaryServerInfo{FullMethod: "/compute.v1.ComputeService/Generate"}
+
+	call := func(key string) (APIKeyInfo, bool, error) {
+		ctx := context.Background()
+		if key != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+key))
+		}
+		var got APIKeyInfo
+		var ok bool
+		_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			got, ok = KeyInfoFromContext(ctx)
+			return nil, nil
+		})
+		return got, ok, err
+	}
+
+	if got, ok, err := call("app-key"); err != nil || !ok || got.Name != "app" || got.ID == "" {
+		t.Errorf("Expected the key's metadata in the context, got %+v %v (%v)", got, ok, err)
+	}
+	if _, _, err := call("old-key"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected a disabled key refused, got %v", err)
+	}
+	if _, ok, err := call(""); err != nil || ok {
+		t.Errorf("Expected a call without a key to pass unauthenticated, got %v (%v)", ok, err)
+	}
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// createKeyRequest is the body of POST /admin/keys
+type createKeyRequest struct {
+	Name        string     `json:"name"`
+	Tenant      string     `json:"tenant"`
+	Permissions []string   `json:"permissions"`
+	RateLimit   *RateLimit `json:"rate_limit"`
+	Quota       *Quota     `json:"quota"`
+
+	MaxPromptTokens int `json:"max_prompt_tokens"`
+}
+
+// updateKeyRequest is the body of PATCH /admin/keys. Absent fields are
+// left alone; a null rate_limit or quota returns the key to the server's
+// default.
+type updateKeyRequest struct {
+	Tenant      *string         `json:"tenant"`
+	Permissions *[]string       `json:"permissions"`
+	Enabled     *bool           `json:"enabled"`
+	RateLimit   json.RawMessage `json:"rate_limit"`
+	Quota       json.RawMessage `json:"quota"`
+
+	MaxPromptTokens *int `json:"max_prompt_tokens"`
+}
+
+// newKeyResponse carries a created or rotated key, shown only once
+type newKeyResponse struct {
+	Key  string   `json:"key"`
+	Info KeyEntry `json:"info"`
+}
+
+// Handler serves /admin/keys: GET lists the keys, POST creates one, PATCH
+// changes the settings of ?name= and DELETE revokes it
+func (s *KeyStore) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req createKeyRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Name == "" {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+			if !validLimits(req.RateLimit, req.Quota) || req.MaxPromptTokens < 0 {
+				http.Error(w, "rate limits, quotas and max_prompt_tokens cannot be negative", http.StatusBadRequest)
+				return
+			}
+
+			key, entry, err := s.Create(APIKeyInfo{
+				Name:            req.Name,
+				Tenant:          req.Tenant,
+				Permissions:     req.Permissions,
+				RateLimit:       req.RateLimit,
+				Quota:           req.Quota,
+				MaxPromptTokens: req.MaxPromptTokens,
+			})
+			if err != nil {
+				keyStoreError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(newKeyResponse{Key: key, Info: entry})
+			return
+		case http.MethodPatch:
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+			var req updateKeyRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			var rateLimit *RateLimit
+			var quota *Quota
+			if (req.RateLimit != nil && json.Unmarshal(req.RateLimit, &rateLimit) != nil) ||
+				(req.Quota != nil && json.Unmarshal(req.Quota, &quota) != nil) {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if !validLimits(rateLimit, quota) || (req.MaxPromptTokens != nil && *req.MaxPromptTokens < 0) {
+				http.Error(w, "rate limits, quotas and max_prompt_tokens cannot be negative", http.StatusBadRequest)
+				return
+			}
+
+			entry, err := s.Update(name, func(info *APIKeyInfo) {
+				if req.Tenant != nil {
+					info.Tenant = *req.Tenant
+				}
+				if req.Permissions != nil {
+					info.Permissions = *req.Permissions
+				}
+				if req.Enabled != nil {
+					info.Enabled = *req.Enabled
+				}
+				if req.RateLimit != nil {
+					info.RateLimit = rateLimit
+				}
+				if req.Quota != nil {
+					info.Quota = quota
+				}
+				if req.MaxPromptTokens != nil {
+					info.MaxPromptTokens = *req.MaxPromptTokens
+				}
+			})
+			if err != nil {
+				keyStoreError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entry)
+			return
+		case http.MethodDelete:
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+			if err := s.Delete(name); err != nil {
+				keyStoreError(w, err)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": s.List(),
+		})
+	}
+}
+
+// RotateHandler serves POST /admin/keys/rotate?name=, which replaces a
+// key with a new one and returns it
+func (s *KeyStore) RotateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+
+		key, entry, err := s.Rotate(name)
+		if err != nil {
+			keyStoreError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newKeyResponse{Key: key, Info: entry})
+	}
+}
+
+// validLimits reports whether a key's rate limit and quota are usable
+func validLimits(rl *RateLimit, q *Quota) bool {
+	if rl != nil && (rl.Rate < 0 || rl.Burst < 0 || rl.StreamRate < 0 || rl.StreamBurst < 0) {
+		return false
+	}
+	if q != nil && (q.DailyTokens < 0 || q.MonthlyTokens < 0 || q.DailyRequests < 0 || q.MonthlyRequests < 0) {
+		return false
+	}
+	return true
+}
+
+// keyStoreError writes a key store error with a matching status
+func keyStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrKeyExists), errors.Is(err, ErrKeyConfigured):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
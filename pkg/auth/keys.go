@@ -0,0 +1,399 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HashPrefix marks a configured API key given as the hex SHA-256 of the
+// key rather than the key itself
+const HashPrefix = "sha256:"
+
+// Errors returned by KeyStore
+var (
+	ErrKeyNotFound   = errors.New("api key not found")
+	ErrKeyExists     = errors.New("api key already exists")
+	ErrKeyConfigured = errors.New("api key is defined in the configuration")
+)
+
+// HashKey returns the hex SHA-256 of a key, as stored
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyID returns the ID of a configured key, given either as the key or
+// as HashPrefix and its hash: the key's hash, as HashKey returns it
+func KeyID(key string) string {
+	if h, ok := strings.CutPrefix(key, HashPrefix); ok {
+		return strings.ToLower(h)
+	}
+	return HashKey(key)
+}
+
+// KeyEntry is an API key as listed by KeyStore, with the key itself masked
+type KeyEntry struct {
+	APIKeyInfo
+	Hint       string    `json:"hint"`                 // last characters of the key, e.g. "...3f9a"
+	Configured bool      `json:"configured,omitempty"` // from the configuration; changes last until restart
+	CreatedAt  time.Time `json:"created_at,omitzero"`
+	LastUsed   time.Time `json:"last_used,omitzero"`
+}
+
+// storedKey is a key as written to the store file
+type storedKey struct {
+	Hash string `json:"hash"`
+	KeyEntry
+}
+
+// keyRecord is a key held by the store
+type keyRecord struct {
+	entry    KeyEntry
+	lastUsed atomic.Int64 // unix nanoseconds, 0 = never
+}
+
+func (r *keyRecord) snapshot() KeyEntry {
+	entry := r.entry
+	if used := r.lastUsed.Load(); used != 0 {
+		entry.LastUsed = time.Unix(0, used).UTC()
+	}
+	return entry
+}
+
+// KeyStore holds API keys that can be created, rotated, changed and
+// revoked while the proxy runs. Only the SHA-256 of each key is kept.
+// Keys are addressed by name; several configured keys may share one, and
+// changes apply to all of them. Keys created at runtime are written to
+// the store file, if there is one; changes to configured keys are not
+// written back to the configuration.
+type KeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*keyRecord // SHA-256 of the key -> record
+	path string                // store file; empty keeps keys in memory
+
+	used atomic.Bool // a key was used since the last save
+	now  func() time.Time
+}
+
+// NewKeyStore creates an in-memory store holding keys. Keys prefixed with
+// HashPrefix are taken as the SHA-256 of the key.
+func NewKeyStore(keys map[string]APIKeyInfo) *KeyStore {
+	s := &KeyStore{keys: make(map[string]*keyRecord, len(keys)), now: time.Now}
+	for key, info := range keys {
+		hash, hint := KeyID(key), keyHint(key)
+		if strings.HasPrefix(key, HashPrefix) {
+			hint = ""
+		}
+		s.keys[hash] = &keyRecord{entry: KeyEntry{APIKeyInfo: info, Hint: hint, Configured: true}}
+	}
+	return s
+}
+
+// OpenKeyStore creates a store holding keys and the keys saved in the
+// file at path, which is created on the first change
+func OpenKeyStore(path string, keys map[string]APIKeyInfo) (*KeyStore, error) {
+	s := NewKeyStore(keys)
+	s.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api key store: %w", err)
+	}
+
+	var file struct {
+		Keys []storedKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse api key store %s: %w", path, err)
+	}
+	for _, stored := range file.Keys {
+		if len(stored.Hash) != sha256.Size*2 || stored.Name == "" {
+			return nil, fmt.Errorf("api key store %s has an invalid key %q", path, stored.Name)
+		}
+		if _, ok := s.keys[stored.Hash]; ok {
+			continue // configured keys win
+		}
+		rec := &keyRecord{entry: stored.KeyEntry}
+		rec.entry.Configured = false
+		if !stored.LastUsed.IsZero() {
+			rec.lastUsed.Store(stored.LastUsed.UnixNano())
+		}
+		rec.entry.LastUsed = time.Time{}
+		s.keys[stored.Hash] = rec
+	}
+	return s, nil
+}
+
+// Lookup returns the metadata of a key and records its use
+func (s *KeyStore) Lookup(key string) (APIKeyInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hash := HashKey(key)
+	rec, ok := s.keys[hash]
+	if !ok {
+		return APIKeyInfo{}, false
+	}
+	rec.lastUsed.Store(s.now().UnixNano())
+	s.used.Store(true)
+	info := rec.entry.APIKeyInfo
+	info.ID = hash
+	return info, true
+}
+
+// Len returns the number of keys
+func (s *KeyStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// List returns the keys sorted by name
+func (s *KeyStore) List() []KeyEntry {
+	s.mu.RLock()
+	entries := make([]KeyEntry, 0, len(s.keys))
+	for _, rec := range s.keys {
+		entries = append(entries, rec.snapshot())
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Hint < entries[j].Hint
+	})
+	return entries
+}
+
+// Create generates a new enabled key for info.Name and returns it with
+// its entry. The name must be unique. The key itself is not kept, so it
+// can't be shown again.
+func (s *KeyStore) Create(info APIKeyInfo) (string, KeyEntry, error) {
+	if info.Name == "" {
+		return "", KeyEntry{}, errors.New("api key name is required")
+	}
+	key, err := generateKey()
+	if err != nil {
+		return "", KeyEntry{}, err
+	}
+	info.Enabled = true
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range s.keys {
+		if rec.entry.Name == info.Name {
+			return "", KeyEntry{}, fmt.Errorf("%w: %s", ErrKeyExists, info.Name)
+		}
+	}
+
+	hash := HashKey(key)
+	rec := &keyRecord{entry: KeyEntry{APIKeyInfo: info, Hint: keyHint(key), CreatedAt: s.now().UTC()}}
+	s.keys[hash] = rec
+	if err := s.saveLocked(); err != nil {
+		delete(s.keys, hash)
+		return "", KeyEntry{}, err
+	}
+	return key, rec.snapshot(), nil
+}
+
+// Rotate replaces the key named name with a newly generated one that
+// keeps its settings, and returns it. The old key stops working at once.
+// Configured keys can't be rotated, as the old key would come back on
+// restart.
+func (s *KeyStore) Rotate(name string) (string, KeyEntry, error) {
+	key, err := generateKey()
+	if err != nil {
+		return "", KeyEntry{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var oldHash string
+	var old *keyRecord
+	for hash, rec := range s.keys {
+		if rec.entry.Name != name {
+			continue
+		}
+		if rec.entry.Configured {
+			return "", KeyEntry{}, fmt.Errorf("%w: %s", ErrKeyConfigured, name)
+		}
+		oldHash, old = hash, rec
+	}
+	if old == nil {
+		return "", KeyEntry{}, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+
+	hash := HashKey(key)
+	rec := &keyRecord{entry: old.entry}
+	rec.entry.Hint = keyHint(key)
+	rec.entry.CreatedAt = s.now().UTC()
+	delete(s.keys, oldHash)
+	s.keys[hash] = rec
+	if err := s.saveLocked(); err != nil {
+		delete(s.keys, hash)
+		s.keys[oldHash] = old
+		return "", KeyEntry{}, err
+	}
+	return key, rec.snapshot(), nil
+}
+
+// Update changes the settings of the keys named name. update may change
+// anything but the name.
+func (s *KeyStore) Update(name string, update func(*APIKeyInfo)) (KeyEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := make(map[*keyRecord]APIKeyInfo)
+	var entry KeyEntry
+	for _, rec := range s.keys {
+		if rec.entry.Name != name {
+			continue
+		}
+		previous[rec] = rec.entry.APIKeyInfo
+		update(&rec.entry.APIKeyInfo)
+		rec.entry.Name = name
+		entry = rec.snapshot()
+	}
+	if len(previous) == 0 {
+		return KeyEntry{}, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	if err := s.saveLocked(); err != nil {
+		for rec, info := range previous {
+			rec.entry.APIKeyInfo = info
+		}
+		return KeyEntry{}, err
+	}
+	return entry, nil
+}
+
+// SetEnabled enables or disables the keys named name
+func (s *KeyStore) SetEnabled(name string, enabled bool) (KeyEntry, error) {
+	return s.Update(name, func(info *APIKeyInfo) { info.Enabled = enabled })
+}
+
+// Delete revokes the keys named name
+func (s *KeyStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := make(map[string]*keyRecord)
+	for hash, rec := range s.keys {
+		if rec.entry.Name == name {
+			removed[hash] = rec
+			delete(s.keys, hash)
+		}
+	}
+	if len(removed) == 0 {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	if err := s.saveLocked(); err != nil {
+		for hash, rec := range removed {
+			s.keys[hash] = rec
+		}
+		return err
+	}
+	return nil
+}
+
+// Flush saves the keys' last-used times if any key was used since the
+// last save
+func (s *KeyStore) Flush() error {
+	if !s.used.Load() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked()
+}
+
+// RunPersistence flushes last-used times every interval until ctx is done
+func (s *KeyStore) RunPersistence(ctx context.Context, interval time.Duration, onError func(error)) {
+	if s.path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// saveLocked writes the keys created at runtime to the store file,
+// replacing it atomically; callers hold s.mu
+func (s *KeyStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	s.used.Store(false)
+
+	file := struct {
+		Keys []storedKey `json:"keys"`
+	}{Keys: make([]storedKey, 0, len(s.keys))}
+	for hash, rec := range s.keys {
+		if !rec.entry.Configured {
+			file.Keys = append(file.Keys, storedKey{Hash: hash, KeyEntry: rec.snapshot()})
+		}
+	}
+	sort.Slice(file.Keys, func(i, j int) bool { return file.Keys[i].Name < file.Keys[j].Name })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create api key store directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		s.used.Store(true)
+		return fmt.Errorf("failed to write api key store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		s.used.Store(true)
+		return fmt.Errorf("failed to write api key store: %w", err)
+	}
+	return nil
+}
+
+// generateKey returns 24 random bytes as hex
+func generateKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// keyHint masks all but the last four characters of a key
+func keyHint(key string) string {
+	if len(key) <= 8 {
+		return "..."
+	}
+	return "..." + key[len(key)-4:]
+}
@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyStore(t *testing.T) {
+	store := NewKeyStore(map[string]APIKeyInfo{
+		"configured-key-1234": {Name: "ops", Permissions: []string{PermissionAdmin}, Enabled: true},
+	})
+
+	key, entry, err := store.Create(APIKeyInfo{Name: "ci", Tenant: "build"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(key) != 48 || !entry.Enabled || entry.Hint != "..."+key[44:] {
+		t.Errorf("Unexpected key %q entry %+v", key, entry)
+	}
+	if info, ok := store.Lookup(key); !ok || info.Tenant != "build" {
+		t.Errorf("Expected the new key to be found, got %+v %v", info, ok)
+	}
+	if _, _, err := store.Create(APIKeyInfo{Name: "ci"}); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("Expected ErrKeyExists for a duplicate name, got %v", err)
+	}
+	if _, _, err := store.Create(APIKeyInfo{}); err == nil {
+		t.Error("Expected an error without a name")
+	}
+
+	list := store.List()
+	if len(list) != 2 || list[0].Name != "ci" || list[1].Name != "ops" || list[1].Hint != "...1234" {
+		t.Errorf("Unexpected list: %+v", list)
+	}
+
+	if _, err := store.SetEnabled("ci", false); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+	if info, _ := store.Lookup(key); info.Enabled {
+		t.Error("Expected the key to be disabled")
+	}
+	if _, err := store.SetEnabled("nobody", true); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	if err := store.Delete("ci"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok := store.Lookup(key); ok || store.Len() != 1 {
+		t.Error("Expected the key to be deleted")
+	}
+	if err := store.Delete("ci"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound on a second delete, got %v", err)
+	}
+}
+
+func TestAPIKeyMiddleware_KeyStore(t *testing.T) {
+	store := NewKeyStore(nil)
+	cfg := Config{Enabled: true, Keys: store}
+	handler := APIKeyMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	call := func(key string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	key, _, _ := store.Create(APIKeyInfo{Name: "runtime"})
+	if code := call(key); code != http.StatusOK {
+		t.Errorf("Expected a key created at runtime to be accepted, got %d", code)
+	}
+	store.SetEnabled("runtime", false)
+	if code := call(key); code != http.StatusForbidden {
+		t.Errorf("Expected a disabled key to be refused, got %d", code)
+	}
+	store.Delete("runtime")
+	if code := call(key); code != http.StatusUnauthorized {
+		t.Errorf("Expected a deleted key to be refused, got %d", code)
+	}
+}
+
+func TestKeyStore_HashedKey(t *testing.T) {
+	store := NewKeyStore(map[string]APIKeyInfo{
+		HashPrefix + HashKey("secret-key"): {Name: "ops", Enabled: true},
+	})
+	if info, ok := store.Lookup("secret-key"); !ok || info.Name != "ops" {
+		t.Errorf("Expected the hashed key to be found, got %+v %v", info, ok)
+	}
+	if _, ok := store.Lookup(HashPrefix + HashKey("secret-key")); ok {
+		t.Error("Expected the hash itself not to be accepted as a key")
+	}
+	if list := store.List(); len(list) != 1 || list[0].Hint != "" || !list[0].Configured || list[0].LastUsed.IsZero() {
+		t.Errorf("Unexpected list: %+v", list)
+	}
+
+	cfg := Config{Enabled: true, APIKeys: map[string]APIKeyInfo{
+		HashPrefix + HashKey("secret-key"): {Name: "ops", Enabled: true},
+	}}
+	if _, ok := ValidateAPIKey(cfg, "secret-key"); !ok {
+		t.Error("Expected a hashed key to validate without a store")
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	// Keys sharing a name keep distinct IDs, however they are configured
+	cfg := Config{Enabled: true, APIKeys: map[string]APIKeyInfo{
+		"key-one":                       {Name: "app", Enabled: true},
+		HashPrefix + HashKey("key-two"): {Name: "app", Enabled: true},
+	}}
+	one, _ := ValidateAPIKey(cfg, "key-one")
+	two, _ := ValidateAPIKey(cfg, "key-two")
+	if one.ID != KeyID("key-one") || two.ID != KeyID(HashPrefix+HashKey("key-two")) || one.ClientID() == two.ClientID() {
+		t.Errorf("Expected distinct key IDs, got %q and %q", one.ID, two.ID)
+	}
+
+	store := NewKeyStore(cfg.APIKeys)
+	if info, _ := store.Lookup("key-two"); info.ID != two.ID {
+		t.Errorf("Expected the store to report the same ID, got %q", info.ID)
+	}
+	if got := (APIKeyInfo{Name: "app"}).ClientID(); got != "name:app" {
+		t.Errorf("Expected the name without an ID, got %q", got)
+	}
+}
+
+func TestKeyStore_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store, err := OpenKeyStore(path, map[string]APIKeyInfo{
+		"configured-key-1234": {Name: "ops", Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("OpenKeyStore failed: %v", err)
+	}
+
+	key, _, err := store.Create(APIKeyInfo{Name: "ci", Permissions: []string{"inference"}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	limit := &RateLimit{Rate: 5, Burst: 10}
+	if _, err := store.Update("ci", func(info *APIKeyInfo) { info.RateLimit = limit; info.Name = "renamed" }); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	store.Lookup(key)
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the store file to be written: %v", err)
+	}
+	if strings.Contains(string(data), key) || !strings.Contains(string(data), HashKey(key)) {
+		t.Errorf("Expected only the key's hash to be stored, got %s", data)
+	}
+	if strings.Contains(string(data), "configured-key-1234") || strings.Contains(string(data), `"ops"`) {
+		t.Errorf("Expected configured keys not to be stored, got %s", data)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the store file to be private, got %v", info.Mode())
+	}
+
+	reopened, err := OpenKeyStore(path, nil)
+	if err != nil {
+		t.Fatalf("Reopening failed: %v", err)
+	}
+	info, ok := reopened.Lookup(key)
+	if !ok || info.Name != "ci" || info.RateLimit == nil || info.RateLimit.Rate != 5 {
+		t.Fatalf("Expected the key to survive a restart, got %+v %v", info, ok)
+	}
+	if list := reopened.List(); len(list) != 1 || list[0].CreatedAt.IsZero() || list[0].LastUsed.IsZero() {
+		t.Errorf("Expected created and last-used times to be kept, got %+v", list)
+	}
+
+	rotated, entry, err := reopened.Rotate("ci")
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if rotated == key || entry.Hint != keyHint(rotated) || entry.RateLimit == nil {
+		t.Errorf("Unexpected rotation: %q %+v", rotated, entry)
+	}
+	if _, ok := reopened.Lookup(key); ok {
+		t.Error("Expected the old key to stop working after rotation")
+	}
+	if _, ok := reopened.Lookup(rotated); !ok {
+		t.Error("Expected the rotated key to work")
+	}
+	if _, _, err := store.Rotate("ops"); !errors.Is(err, ErrKeyConfigured) {
+		t.Errorf("Expected configured keys not to rotate, got %v", err)
+	}
+
+	if err := reopened.Delete("ci"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if again, _ := OpenKeyStore(path, nil); again.Len() != 0 {
+		t.Error("Expected a revoked key to stay revoked after a restart")
+	}
+}
+
+func TestKeyStoreHandler(t *testing.T) {
+	store := NewKeyStore(nil)
+	handler := store.Handler()
+	call := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	w := call("POST", "/admin/keys", `{"name":"ci","permissions":["inference"],"rate_limit":{"rate":2,"burst":4}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var created newKeyResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if info, ok := store.Lookup(created.Key); !ok || info.RateLimit == nil || info.RateLimit.Burst != 4 {
+		t.Errorf("Expected the created key to work with its rate limit, got %+v %v", info, ok)
+	}
+	if w := call("POST", "/admin/keys", `{"name":"ci"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate name, got %d", w.Code)
+	}
+	if w := call("POST", "/admin/keys", `{"name":"bad","quota":{"daily_tokens":-1}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative quota, got %d", w.Code)
+	}
+
+	w = call("PATCH", "/admin/keys?name=ci", `{"permissions":["read"],"rate_limit":null}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if info, _ := store.Lookup(created.Key); info.RateLimit != nil || len(info.Permissions) != 1 || info.Permissions[0] != "read" {
+		t.Errorf("Expected permissions changed and the rate limit cleared, got %+v", info)
+	}
+	if w := call("PATCH", "/admin/keys?name=nobody", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown key, got %d", w.Code)
+	}
+
+	rotate := store.RotateHandler()
+	w = httptest.NewRecorder()
+	rotate.ServeHTTP(w, httptest.NewRequest("POST", "/admin/keys/rotate?name=ci", nil))
+	var rotated newKeyResponse
+	json.NewDecoder(w.Body).Decode(&rotated)
+	if w.Code != http.StatusOK || rotated.Key == "" || rotated.Key == created.Key {
+		t.Errorf("Expected a new key, got %d %+v", w.Code, rotated)
+	}
+
+	w = call("GET", "/admin/keys", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), rotated.Key) || !strings.Contains(w.Body.String(), `"hint":"`+keyHint(rotated.Key)) {
+		t.Errorf("Expected the key listed masked, got %d %s", w.Code, w.Body)
+	}
+
+	if w := call("DELETE", "/admin/keys?name=ci", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+	if _, ok := store.Lookup(rotated.Key); ok {
+		t.Error("Expected the key to be revoked")
+	}
+}
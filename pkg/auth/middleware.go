@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/subtle"
 	"net/http"
 	"strings"
@@ -10,13 +11,106 @@ import (
 type Config struct {
 	Enabled bool
 	APIKeys map[string]APIKeyInfo // key -> metadata
+
+	// Keys replaces APIKeys when set, so keys can change at runtime
+	Keys *KeyStore
+}
+
+// lookup returns the metadata of a key from Keys or APIKeys, where it may
+// be listed by its hash
+func (c Config) lookup(key string) (APIKeyInfo, bool) {
+	if c.Keys != nil {
+		return c.Keys.Lookup(key)
+	}
+	hash := HashKey(key)
+	info, ok := c.APIKeys[key]
+	if !ok {
+		info, ok = c.APIKeys[HashPrefix+hash]
+	}
+	info.ID = hash
+	return info, ok
 }
 
 // APIKeyInfo holds metadata about an API key
 type APIKeyInfo struct {
-	Name        string
-	Permissions []string
-	Enabled     bool
+	// ID tells credentials apart where names may not: the hash of an API
+	// key, or the network identity or federated peer a request stands
+	// for. It is set on authentication and never listed or stored.
+	ID string `json:"-"`
+
+	Name        string     `json:"name"`
+	Tenant      string     `json:"tenant,omitempty"` // groups keys for usage reporting, defaults to Name
+	Permissions []string   `json:"permissions"`
+	Enabled     bool       `json:"enabled"`
+	RateLimit   *RateLimit `json:"rate_limit,omitempty"` // nil = the server's per-key default
+	Quota       *Quota     `json:"quota,omitempty"`      // nil = the server's default quota
+	Shaping     *Shaping   `json:"shaping,omitempty"`    // nil = the server's request shaping defaults
+
+	MaxPromptTokens int `json:"max_prompt_tokens,omitempty"` // estimated; 0 = the server's limit
+}
+
+// ClientID identifies the credential a request was made with, for state
+// kept per key such as rate limits: its ID, or its name when it has none
+func (i APIKeyInfo) ClientID() string {
+	if i.ID != "" {
+		return i.ID
+	}
+	return "name:" + i.Name
+}
+
+// Shaping overrides the server's defaults for generation settings a
+// request leaves unset. A zero MaxTokens or nil Temperature keeps the
+// server's default.
+type Shaping struct {
+	MaxTokens   int32    `json:"max_tokens,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+}
+
+// RateLimit is an API key's token-bucket budget in requests per second.
+// Streaming requests hold a backend far longer than single responses, so
+// they draw from their own bucket when StreamRate is set; otherwise both
+// share one. A zero Rate is unlimited.
+type RateLimit struct {
+	Rate        float64 `json:"rate"`
+	Burst       int     `json:"burst"`
+	StreamRate  float64 `json:"stream_rate,omitempty"`
+	StreamBurst int     `json:"stream_burst,omitempty"`
+}
+
+// Quota caps an API key's usage per calendar day and month (UTC). Tokens
+// are prompt plus completion tokens. A zero limit is unlimited.
+type Quota struct {
+	DailyTokens     int64 `json:"daily_tokens,omitempty"`
+	MonthlyTokens   int64 `json:"monthly_tokens,omitempty"`
+	DailyRequests   int64 `json:"daily_requests,omitempty"`
+	MonthlyRequests int64 `json:"monthly_requests,omitempty"`
+}
+
+type contextKey string
+
+const keyInfoContextKey contextKey = "api_key_info"
+
+// WithKeyInfo stores the authenticated key metadata in the context
+func WithKeyInfo(ctx context.Context, info APIKeyInfo) context.Context {
+	return context.WithValue(ctx, keyInfoContextKey, info)
+}
+
+// KeyInfoFromContext returns the authenticated key metadata, if any
+func KeyInfoFromContext(ctx context.Context) (APIKeyInfo, bool) {
+	info, ok := ctx.Value(keyInfoContextKey).(APIKeyInfo)
+	return info, ok
+}
+
+// TenantFromContext returns the tenant of the authenticated key (empty if unauthenticated)
+func TenantFromContext(ctx context.Context) string {
+	info, ok := KeyInfoFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if info.Tenant != "" {
+		return info.Tenant
+	}
+	return info.Name
 }
 
 // APIKeyMiddleware creates HTTP middleware for API key authentication
@@ -45,7 +139,7 @@ func APIKeyMiddleware(cfg Config) func(http.Handler) http.Handler {
 			}
 
 			// Validate API key
-			keyInfo, valid := cfg.APIKeys[key]
+			keyInfo, valid := cfg.lookup(key)
 			if !valid {
 				http.Error(w, "Invalid API key", http.StatusUnauthorized)
 				return
@@ -57,8 +151,8 @@ func APIKeyMiddleware(cfg Config) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Key is valid, proceed
-			next.ServeHTTP(w, r)
+			// Key is valid, proceed with key metadata attached for downstream accounting
+			next.ServeHTTP(w, r.WithContext(WithKeyInfo(r.Context(), keyInfo)))
 		})
 	}
 }
@@ -74,7 +168,7 @@ func ValidateAPIKey(cfg Config, key string) (APIKeyInfo, bool) {
 		return APIKeyInfo{}, true
 	}
 
-	keyInfo, exists := cfg.APIKeys[key]
+	keyInfo, exists := cfg.lookup(key)
 	if !exists {
 		return APIKeyInfo{}, false
 	}
@@ -86,6 +180,24 @@ func ValidateAPIKey(cfg Config, key string) (APIKeyInfo, bool) {
 	return keyInfo, true
 }
 
+// PermissionAdmin lets a key change the proxy at runtime through the admin API
+const PermissionAdmin = "admin"
+
+// RequirePermission creates HTTP middleware that rejects authenticated keys
+// lacking permission. It runs after APIKeyMiddleware; requests without key
+// metadata (authentication disabled) pass through.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if keyInfo, ok := KeyInfoFromContext(r.Context()); ok && !HasPermission(keyInfo, permission) {
+				http.Error(w, "API key lacks the "+permission+" permission", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // HasPermission checks if an API key has a specific permission
 func HasPermission(keyInfo APIKeyInfo, permission string) bool {
 	for _, p := range keyInfo.Permissions {
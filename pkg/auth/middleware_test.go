@@ -287,3 +287,68 @@ func TestHasPermission(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIKeyMiddleware_KeyInfoInContext(t *testing.T) {
+	cfg := Config{
+		Enabled: true,
+		APIKeys: map[string]APIKeyInfo{
+			"lab-key": {Name: "alice", Tenant: "vision-lab", Enabled: true},
+			"solo":    {Name: "bob", Enabled: true},
+		},
+	}
+
+	var tenant string
+	handler := APIKeyMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = TenantFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer lab-key")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if tenant != "vision-lab" {
+		t.Errorf("Expected tenant vision-lab, got %q", tenant)
+	}
+
+	// Tenant falls back to key name
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer solo")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if tenant != "bob" {
+		t.Errorf("Expected tenant to default to key name bob, got %q", tenant)
+	}
+}
+
+func TestTenantFromContext_Unauthenticated(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	if tenant := TenantFromContext(req.Context()); tenant != "" {
+		t.Errorf("Expected empty tenant, got %q", tenant)
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	handler := RequirePermission(PermissionAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		info   *APIKeyInfo
+		expect int
+	}{
+		{"unauthenticated", nil, http.StatusOK},
+		{"admin", &APIKeyInfo{Permissions: []string{"read", PermissionAdmin}}, http.StatusOK},
+		{"wildcard", &APIKeyInfo{Permissions: []string{"*"}}, http.StatusOK},
+		{"read only", &APIKeyInfo{Permissions: []string{"read"}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin/backends", nil)
+		if tt.info != nil {
+			req = req.WithContext(WithKeyInfo(req.Context(), *tt.info))
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tt.expect {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.expect, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Package authz decides what an authenticated caller may do. Callers are
+// given a role - admin, inference or read-only - from the client
+// certificate they present (its CN or SANs) or from the permissions of
+// their API key, and each HTTP route and gRPC method needs a level of
+// access that the role must allow.
+package authz
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+)
+
+// Role is a set of allowed access levels
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"     // everything, including runtime changes
+	RoleInference Role = "inference" // generation, pipelines and devices, plus reads
+	RoleReadOnly  Role = "read-only" // health, status and listings
+)
+
+// Access is the level of access a request needs
+type Access int
+
+const (
+	AccessRead      Access = iota // status and listings
+	AccessInference               // generation, pipelines and device use
+	AccessAdmin                   // runtime changes
+)
+
+func (a Access) String() string {
+	switch a {
+	case AccessRead:
+		return "read"
+	case AccessInference:
+		return "inference"
+	}
+	return "admin"
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, error) {
+	switch Role(name) {
+	case RoleAdmin, RoleInference, RoleReadOnly:
+		return Role(name), nil
+	}
+	return "", fmt.Errorf("unknown role %q (must be admin, inference or read-only)", name)
+}
+
+// Allows reports whether the role grants access
+func (r Role) Allows(access Access) bool {
+	switch r {
+	case RoleAdmin:
+		return true
+	case RoleInference:
+		return access <= AccessInference
+	case RoleReadOnly:
+		return access == AccessRead
+	}
+	return false
+}
+
+// RoleFromPermissions maps API key permissions to the broadest role they
+// name: "*" or "admin" is admin, "inference" or "write" is inference and
+// "read" or "read-only" is read-only. Keys naming none of them keep the
+// inference access every valid key has always had.
+func RoleFromPermissions(permissions []string) Role {
+	role := Role("")
+	for _, p := range permissions {
+		switch p {
+		case "*", string(RoleAdmin):
+			return RoleAdmin
+		case string(RoleInference), "write":
+			role = RoleInference
+		case string(RoleReadOnly), "read":
+			if role == "" {
+				role = RoleReadOnly
+			}
+		}
+	}
+	if role == "" {
+		return RoleInference
+	}
+	return role
+}
+
+// Config maps callers to roles
+type Config struct {
+	// Certificates maps a client certificate identity - a URI, DNS or
+	// email SAN, or the subject CN - to a role
+	Certificates map[string]Role
+
+	// CertificateDefault is the role of verified certificates not listed;
+	// empty refuses them
+	CertificateDefault Role
+
+	// Anonymous is the role of callers with neither a certificate nor an
+	// API key, e.g. when authentication is disabled; empty refuses them
+	Anonymous Role
+
+	// Keys looks up API keys presented on gRPC calls
+	Keys auth.Config
+}
+
+// Policy resolves callers to roles and checks their access
+type Policy struct {
+	cfg Config
+}
+
+// New creates a policy
+func New(cfg Config) *Policy {
+	return &Policy{cfg: cfg}
+}
+
+// Identity is who a caller was resolved as
+type Identity struct {
+	Name   string // certificate identity, key name, or empty when anonymous
+	Source string // "certificate", "api_key" or "anonymous"
+	Role   Role   // empty when the caller has no role
+}
+
+// certificateIdentities returns the names a certificate can be configured
+// under, SANs before the CN
+func certificateIdentities(cert *x509.Certificate) []string {
+	var names []string
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	return names
+}
+
+// CertificateRole returns the role of a verified client certificate and
+// the identity it matched
+func (p *Policy) CertificateRole(cert *x509.Certificate) Identity {
+	names := certificateIdentities(cert)
+	for _, name := range names {
+		if role, ok := p.cfg.Certificates[name]; ok {
+			return Identity{Name: name, Source: "certificate", Role: role}
+		}
+	}
+	id := Identity{Source: "certificate", Role: p.cfg.CertificateDefault}
+	if len(names) > 0 {
+		id.Name = names[len(names)-1]
+	}
+	return id
+}
+
+// resolve picks the caller's identity: a verified client certificate,
+// then an API key, then anonymous
+func (p *Policy) resolve(cert *x509.Certificate, key *auth.APIKeyInfo) Identity {
+	if cert != nil {
+		return p.CertificateRole(cert)
+	}
+	if key != nil {
+		return Identity{Name: key.Name, Source: "api_key", Role: RoleFromPermissions(key.Permissions)}
+	}
+	return Identity{Source: "anonymous", Role: p.cfg.Anonymous}
+}
+
+type contextKey struct{}
+
+// WithIdentity stores the caller's identity in the context
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// IdentityFromContext returns the identity a request was authorized as
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok
+}
+
+// deniedMessage explains a refusal
+func deniedMessage(id Identity, access Access) string {
+	if id.Role == "" {
+		if id.Source == "anonymous" {
+			return "authentication required"
+		}
+		return fmt.Sprintf("%s %q has no role", strings.ReplaceAll(id.Source, "_", " "), id.Name)
+	}
+	return fmt.Sprintf("role %s does not allow %s access", id.Role, access)
+}
+
+// HTTPMiddleware refuses requests whose caller's role does not allow the
+// access the request needs. It runs after API key authentication, whose
+// key metadata it reads; a verified client certificate takes precedence.
+func (p *Policy) HTTPMiddleware(access func(*http.Request) Access) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var cert *x509.Certificate
+			if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+				cert = r.TLS.VerifiedChains[0][0]
+			}
+			var key *auth.APIKeyInfo
+			if info, ok := auth.KeyInfoFromContext(r.Context()); ok {
+				key = &info
+			}
+
+			id := p.resolve(cert, key)
+			need := access(r)
+			if !id.Role.Allows(need) {
+				code := http.StatusForbidden
+				if id.Role == "" && id.Source == "anonymous" {
+					code = http.StatusUnauthorized
+				}
+				http.Error(w, deniedMessage(id, need), code)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+		})
+	}
+}
+
+// MethodAccess is the access an admin route needs by HTTP method: reads
+// for GET and HEAD, admin for changes
+func MethodAccess(r *http.Request) Access {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return AccessRead
+	}
+	return AccessAdmin
+}
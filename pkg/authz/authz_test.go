@@ -0,0 +1,206 @@
+package authz
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRole_Allows(t *testing.T) {
+	tests := []struct {
+		role   Role
+		access Access
+		want   bool
+	}{
+		{RoleAdmin, AccessAdmin, true},
+		{RoleInference, AccessInference, true},
+		{RoleInference, AccessRead, true},
+		{RoleInference, AccessAdmin, false},
+		{RoleReadOnly, AccessRead, true},
+		{RoleReadOnly, AccessInference, false},
+		{"", AccessRead, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Allows(tt.access); got != tt.want {
+			t.Errorf("%q.Allows(%s) = %v, want %v", tt.role, tt.access, got, tt.want)
+		}
+	}
+
+	if _, err := ParseRole("superuser"); err == nil {
+		t.Error("Expected an error for an unknown role")
+	}
+}
+
+func TestRoleFromPermissions(t *testing.T) {
+	tests := []struct {
+		permissions []string
+		want        Role
+	}{
+		{[]string{"*"}, RoleAdmin},
+		{[]string{"read", "admin"}, RoleAdmin},
+		{[]string{"read", "write"}, RoleInference},
+		{[]string{"read"}, RoleReadOnly},
+		{[]string{"read-only"}, RoleReadOnly},
+		{nil, RoleInference},
+		{[]string{"billing"}, RoleInference},
+	}
+	for _, tt := range tests {
+		if got := RoleFromPermissions(tt.permissions); got != tt.want {
+			t.Errorf("RoleFromPermissions(%v) = %q, want %q", tt.permissions, got, tt.want)
+		}
+	}
+}
+
+func TestCertificateRole(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.com/app")
+	p := New(Config{
+		Certificates: map[string]Role{
+			"spiffe://example.com/app": RoleInference,
+			"ops.example.com":          RoleAdmin,
+		},
+		CertificateDefault: RoleReadOnly,
+	})
+
+	tests := []struct {
+		cert *x509.Certificate
+		want Identity
+	}{
+		{&x509.Certificate{URIs: []*url.URL{spiffe}, Subject: pkix.Name{CommonName: "ops.example.com"}},
+			Identity{Name: "spiffe://example.com/app", Source: "certificate", Role: RoleInference}},
+		{&x509.Certificate{Subject: pkix.Name{CommonName: "ops.example.com"}},
+			Identity{Name: "ops.example.com", Source: "certificate", Role: RoleAdmin}},
+		{&x509.Certificate{DNSNames: []string{"laptop.example.com"}},
+			Identity{Name: "laptop.example.com", Source: "certificate", Role: RoleReadOnly}},
+	}
+	for _, tt := range tests {
+		if got := p.CertificateRole(tt.cert); got != tt.want {
+			t.Errorf("CertificateRole = %+v, want %+v", got, tt.want)
+		}
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	p := New(Config{Certificates: map[string]Role{"ops": RoleAdmin}})
+	handler := p.HTTPMiddleware(MethodAccess)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := IdentityFromContext(r.Context()); !ok {
+			t.Error("Expected the identity in the request context")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	call := func(method string, key *auth.APIKeyInfo, cn string) int {
+		req := httptest.NewRequest(method, "/admin/backends", nil)
+		if key != nil {
+			req = req.WithContext(auth.WithKeyInfo(req.Context(), *key))
+		}
+		if cn != "" {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}}}
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	reader := &auth.APIKeyInfo{Name: "dash", Permissions: []string{"read"}}
+	app := &auth.APIKeyInfo{Name: "app"}
+	tests := []struct {
+		name   string
+		method string
+		key    *auth.APIKeyInfo
+		cn     string
+		want   int
+	}{
+		{"read-only key reads", http.MethodGet, reader, "", http.StatusOK},
+		{"read-only key changes", http.MethodPost, reader, "", http.StatusForbidden},
+		{"inference key changes", http.MethodPut, app, "", http.StatusForbidden},
+		{"admin certificate changes", http.MethodPost, nil, "ops", http.StatusOK},
+		{"certificate wins over key", http.MethodPost, reader, "ops", http.StatusOK},
+		{"unlisted certificate", http.MethodGet, nil, "guest", http.StatusForbidden},
+		{"anonymous", http.MethodGet, nil, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if got := call(tt.method, tt.key, tt.cn); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	p := New(Config{
+		Certificates: map[string]Role{"ops": RoleAdmin},
+		Keys: auth.Config{Enabled: true, APIKeys: map[string]auth.APIKeyInfo{
+			"reader-key": {Name: "dash", Permissions: []string{"read"}, Enabled: true},
+		}},
+	})
+	interceptor := p.UnaryInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	withCert := func(cn string) context.Context {
+		info := credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}},
+		}}
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: info})
+	}
+	withKey := func(key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+key))
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		want   codes.Code
+	}{
+		{"admin certificate drains", withCert("ops"), "/compute.v1.AdminService/Drain", codes.OK},
+		{"read-only key reads drain status", withKey("reader-key"), "/compute.v1.AdminService/GetDrainStatus", codes.PermissionDenied},
+		{"read-only key lists backends", withKey("reader-key"), "/compute.v1.ComputeService/ListBackends", codes.OK},
+		{"read-only key generates", withKey("reader-key"), "/compute.v1.ComputeService/Generate", codes.PermissionDenied},
+		{"read-only key runs a pipeline", withKey("reader-key"), "/compute.v1.ComputeService/ExecutePipeline", codes.PermissionDenied},
+		{"read-only key renames a device", withKey("reader-key"), "/device.v1.DeviceService/RenameDevice", codes.PermissionDenied},
+		{"read-only key lists devices", withKey("reader-key"), "/device.v1.DeviceService/ListDevices", codes.OK},
+		{"unknown key", withKey("nope"), "/compute.v1.ComputeService/ListBackends", codes.Unauthenticated},
+		{"anonymous", context.Background(), "/compute.v1.ComputeService/HealthCheck", codes.Unauthenticated},
+		{"health probes are exempt", context.Background(), "/grpc.health.v1.Health/Check", codes.OK},
+		{"unknown methods need admin", withKey("reader-key"), "/other.v1.Service/Do", codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		_, err := interceptor(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+		if status.Code(err) != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestUnaryInterceptor_StoresKeyInfo(t *testing.T) {
+	p := New(Config{
+		Keys: auth.Config{Enabled: true, APIKeys: map[string]auth.APIKeyInfo{
+			"app-key": {Name: "app", Permissions: []string{"inference"}, Enabled: true},
+		}},
+	})
+	var got auth.APIKeyInfo
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = auth.KeyInfoFromContext(ctx)
+		return "ok", nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer app-key"))
+	if _, err := p.UnaryInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/compute.v1.ComputeService/Generate"}, handler); err != nil {
+		t.Fatalf("Expected the call allowed, got %v", err)
+	}
+	if got.Name != "app" || got.ID == "" {
+		t.Errorf("Expected the key's metadata for usage accounting, got %+v", got)
+	}
+}
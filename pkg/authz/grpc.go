@@ -0,0 +1,133 @@
+package authz
+
+import (
+	"context"
+	"crypto/x509"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// exempt are gRPC services that authorize calls themselves or must stay
+// reachable: health probes, and HA peers, which share a secret
+var exempt = []string{
+	"/grpc.health.v1.Health/",
+	"/ollamaproxy.ha.v1.Failover/",
+}
+
+// RPCAccess is the access each gRPC method needs. A key ending in "/"
+// covers a whole service; exact methods win. Methods not listed need
+// admin access, including every AdminService call.
+var RPCAccess = map[string]Access{
+	"/compute.v1.ComputeService/":                AccessInference,
+	"/compute.v1.ComputeService/ListBackends":    AccessRead,
+	"/compute.v1.ComputeService/HealthCheck":     AccessRead,
+	"/compute.v1.ModelService/ListModels":        AccessRead,
+	"/device.v1.DeviceService/":                  AccessInference,
+	"/device.v1.DeviceService/ListDevices":       AccessRead,
+	"/device.v1.DeviceService/GetDevice":         AccessRead,
+	"/device.v1.DeviceService/ListInventory":     AccessRead,
+	"/device.v1.DeviceService/WatchDevices":      AccessRead,
+	"/device.v1.DeviceService/RenameDevice":      AccessAdmin,
+	"/device.v1.DeviceService/AnnotateDevice":    AccessAdmin,
+	"/grpc.reflection.v1.ServerReflection/":      AccessRead,
+	"/grpc.reflection.v1alpha.ServerReflection/": AccessRead,
+}
+
+// methodAccess returns the access a method needs, and false if it is exempt
+func methodAccess(fullMethod string) (Access, bool) {
+	for _, prefix := range exempt {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return 0, false
+		}
+	}
+	if access, ok := RPCAccess[fullMethod]; ok {
+		return access, true
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		if access, ok := RPCAccess[fullMethod[:i+1]]; ok {
+			return access, true
+		}
+	}
+	return AccessAdmin, true
+}
+
+// authorizeRPC resolves the caller of an RPC and checks its access. The
+// caller is known by its verified client certificate or, failing that,
+// by an API key in the authorization metadata, whose metadata is then
+// stored in the context.
+func (p *Policy) authorizeRPC(ctx context.Context, fullMethod string) (context.Context, error) {
+	access, ok := methodAccess(fullMethod)
+	if !ok {
+		return ctx, nil
+	}
+
+	var cert *x509.Certificate
+	if pr, ok := peer.FromContext(ctx); ok {
+		if info, ok := pr.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 && len(info.State.VerifiedChains[0]) > 0 {
+			cert = info.State.VerifiedChains[0][0]
+		}
+	}
+
+	var key *auth.APIKeyInfo
+	if cert == nil && p.cfg.Keys.Enabled {
+		if value, ok := auth.MetadataKey(ctx); ok {
+			info, valid := auth.ValidateAPIKey(p.cfg.Keys, value)
+			if !valid {
+				return nil, status.Error(codes.Unauthenticated, "invalid or disabled API key")
+			}
+			key = &info
+		}
+	}
+
+	id := p.resolve(cert, key)
+	if !id.Role.Allows(access) {
+		code := codes.PermissionDenied
+		if id.Role == "" && id.Source == "anonymous" {
+			code = codes.Unauthenticated
+		}
+		return nil, status.Error(code, deniedMessage(id, access))
+	}
+	if key != nil {
+		// Usage and quotas are kept per key, as on HTTP
+		ctx = auth.WithKeyInfo(ctx, *key)
+	}
+	return WithIdentity(ctx, id), nil
+}
+
+// UnaryInterceptor refuses unary RPCs the caller's role does not allow
+func (p *Policy) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := p.authorizeRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor refuses streaming RPCs like UnaryInterceptor
+func (p *Policy) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := p.authorizeRPC(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &identityStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// identityStream carries the authorized identity in the stream's context
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
@@ -275,7 +275,11 @@ func (b *AnthropicBackend) Generate(ctx context.Context, req *backends.GenerateR
 
 	resp, err := b.client.Do(httpReq)
 	if err != nil {
-		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		if ctx.Err() != context.Canceled {
+			// A request the caller cancelled (e.g. a hedge that lost) is not a
+			// backend failure; a timeout still is
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -297,7 +301,9 @@ func (b *AnthropicBackend) Generate(ctx context.Context, req *backends.GenerateR
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
-		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
 		return nil, err
 	}
 
@@ -84,6 +84,51 @@ const (
 	MediaTypeAuto     MediaType = "auto"      // Auto-detect from prompt
 )
 
+// Capability names an operation a backend must support to be routed to
+type Capability string
+
+const (
+	CapabilityAudioToText  Capability = "audio_to_text"  // Speech recognition
+	CapabilityTextToAudio  Capability = "text_to_audio"  // Speech synthesis
+	CapabilityTextToImage  Capability = "text_to_image"  // Image generation
+	CapabilityImageToImage Capability = "image_to_image" // Image edits and inpainting
+	CapabilityVideoToText  Capability = "video_to_text"  // Video analysis
+	CapabilityTextToVideo  Capability = "text_to_video"  // Video generation
+	CapabilityToolCalling  Capability = "tool_calling"   // Chat with tools
+)
+
+// SupportsCapability reports whether b supports c. The empty capability,
+// for text requests, matches every backend but image generators that
+// neither generate text nor embed.
+func SupportsCapability(b Backend, c Capability) bool {
+	switch c {
+	case CapabilityAudioToText:
+		return b.SupportsAudioToText()
+	case CapabilityTextToAudio:
+		return b.SupportsTextToAudio()
+	case CapabilityTextToImage:
+		return b.SupportsTextToImage()
+	case CapabilityImageToImage:
+		return SupportsImageEdit(b)
+	case CapabilityVideoToText:
+		return b.SupportsVideoToText()
+	case CapabilityTextToVideo:
+		return b.SupportsTextToVideo()
+	case CapabilityToolCalling:
+		return SupportsToolCalling(b)
+	}
+	return !b.SupportsTextToImage() || b.SupportsGenerate() || b.SupportsEmbed()
+}
+
+// HardwareCloud is the Hardware of backends that forward to a hosted API
+const HardwareCloud = "cloud"
+
+// IsCloud reports whether b sends requests off this machine to a hosted
+// API. Routing treats such backends as a last resort.
+func IsCloud(b Backend) bool {
+	return b.Hardware() == HardwareCloud
+}
+
 // Priority levels for request prioritization
 type Priority int
 
@@ -103,20 +148,57 @@ type Annotations struct {
 	MaxLatencyMs           int32
 	MaxPowerWatts          int32
 	MediaType              MediaType         // Type of workload
+	Capability             Capability        // Required backend operation, e.g. audio_to_text
+	Language               string            // Detected prompt language (ISO 639-1), "" if unknown
+	Model                  string            // Requested model, selects per-model load balancing
 
 	// Priority queuing
 	Priority               Priority          // Request priority level
 	RequestID              string            // Unique request ID for tracking
 	DeadlineMs             int64             // Absolute deadline (Unix ms)
 
+	// Client labels (X-Labels), e.g. team=ml, for policies
+	Labels                 map[string]string
+
+	// Draft tokens on a small model and verify them on the routed one
+	// (X-Speculative)
+	Speculative            bool
+
+	// Query several backends and return the best answer (X-Ensemble),
+	// with every backend's answer alongside (X-Ensemble-Candidates)
+	Ensemble               bool
+	EnsembleCandidates     bool
+
+	// Memory the request needs beyond the model's weights
+	ContextLength          int32             // tokens of KV cache, 0 = the backend's default
+	Batch                  int32             // sequences generated together, 0 = 1
+
+	// Estimated prompt length in tokens, 0 = unknown, for predicting the
+	// request's latency
+	PromptTokens           int32
+
 	Custom                 map[string]string
 }
 
+// EstimatePromptTokens approximates a prompt's length in tokens (~4
+// characters each), for Annotations.PromptTokens
+func EstimatePromptTokens(prompt string) int32 {
+	return int32((len(prompt) + 3) / 4)
+}
+
 // GenerateRequest for text generation
 type GenerateRequest struct {
 	Prompt  string
 	Model   string
 	Options *GenerationOptions
+
+	// Tools the model may call, on backends that support tool calling
+	// (see ToolCaller). Messages then holds the conversation Prompt was
+	// built from, and ToolChoice is "" for the model to decide,
+	// "required", or the name of the one tool to call.
+	Tools      []ToolDefinition
+	Messages   []ChatMessage
+	ToolChoice string
 }
 
 // GenerationOptions for inference
@@ -127,12 +209,33 @@ type GenerationOptions struct {
 	TopK          int32
 	Stop          []string
 	ContextLength int32
+
+	// Raw sends Prompt without the model's prompt template, so the model
+	// continues the text rather than answering it
+	Raw bool
+
+	// Logprobs asks for the log probability of each generated token, on
+	// backends that report them
+	Logprobs bool
+
+	// TemperatureSet marks Temperature as chosen by the client or its
+	// defaults, so a zero asks for greedy decoding rather than the
+	// backend's default temperature
+	TemperatureSet bool
 }
 
 // GenerateResponse from backend
 type GenerateResponse struct {
 	Response string
 	Stats    *GenerationStats
+
+	// TokenLogprobs are the generated tokens' log probabilities when
+	// requested with GenerationOptions.Logprobs; nil if not reported
+	TokenLogprobs []float64
+
+	// ToolCalls are the tools the model called instead of, or as well
+	// as, answering
+	ToolCalls []ToolCall
 }
 
 // GenerationStats for a generation
@@ -142,6 +245,8 @@ type GenerationStats struct {
 	TokensGenerated    int32
 	TokensPerSecond    float32
 	EnergyWh           float32
+	EnergyMeasured     bool    // EnergyWh was metered by RAPL or NVML, not estimated
+	CostUSD            float64 // billed price of the request, cloud backends only
 }
 
 // StreamReader for streaming responses
@@ -155,12 +260,19 @@ type StreamChunk struct {
 	Token string
 	Done  bool
 	Stats *GenerationStats
+
+	// ToolCalls are tool calls completed in this chunk
+	ToolCalls []ToolCall
 }
 
 // EmbedRequest for embeddings
 type EmbedRequest struct {
 	Text  string
 	Model string
+
+	// Dimensions asks for an embedding shortened to this size, 0 for the
+	// model's own. Backends that can't shorten embeddings ignore it.
+	Dimensions int
 }
 
 // EmbedResponse with embeddings
@@ -360,6 +472,9 @@ type ImageGenRequest struct {
 	GuidanceScale  float32           // Prompt adherence (1-20, default 7.5)
 	Seed           int64             // Random seed for reproducibility
 	BatchSize      int32             // Number of images to generate
+	InitImage      []byte            // Source image for edits (img2img), nil for generation
+	Mask           []byte            // Edit mask; transparent areas are regenerated
+	Strength       float32           // How far edits depart from InitImage (0-1), 0 = backend default
 	Options        map[string]string // Model-specific options
 }
 
@@ -465,6 +580,7 @@ type VideoChunk struct {
 	FrameNum   int32       // Current frame number
 	TimeMs     int64       // Time in milliseconds
 	Done       bool        // True if complete
+	Preview    []byte      // Generation: optional still image of the latest frame
 }
 
 // VideoGenRequest for text-to-video generation
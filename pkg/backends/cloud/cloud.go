@@ -0,0 +1,747 @@
+// Package cloud implements a backend that forwards to a hosted model API
+// (OpenAI or Anthropic, or any OpenAI-compatible service). It is meant as
+// the last rung of the escalation path, for prompts or models the local
+// backends can't handle, and prices every request it sends.
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+)
+
+// Supported providers
+const (
+	ProviderOpenAI    = "openai"    // OpenAI and OpenAI-compatible APIs (default)
+	ProviderAnthropic = "anthropic" // Anthropic Messages API
+)
+
+// DefaultMaxTokens caps generation when the request sets no limit; the
+// Anthropic API requires one
+const DefaultMaxTokens = 4096
+
+// anthropicVersion is the Messages API version the backend speaks
+const anthropicVersion = "2023-06-01"
+
+// CloudBackend implements Backend for a hosted model API
+type CloudBackend struct {
+	mu sync.RWMutex
+
+	// Config
+	id       string
+	name     string
+	provider string
+	endpoint string
+	apiKey   string
+	models   map[string]string // requested model -> provider model, "*" = any
+
+	// Pricing, USD per 1K tokens
+	inputCostPer1K  float64
+	outputCostPer1K float64
+
+	// Characteristics
+	avgLatencyMs int32
+	priority     int
+
+	// Model capabilities
+	modelCapability *backends.ModelCapability
+
+	// Health
+	healthy      atomic.Bool
+	lastCheck    time.Time
+	checkTimeout time.Duration
+
+	// Metrics
+	metrics *backends.BackendMetrics
+
+	// HTTP client
+	client *http.Client
+}
+
+// Config for cloud backend
+type Config struct {
+	backends.BackendConfig
+	Provider  string // "openai" (default) or "anthropic"
+	Endpoint  string // API base URL, defaults to the provider's
+	APIKey    string // Direct API key
+	APIKeyEnv string // Or env var name
+
+	// Models maps requested model names to the provider's, so a request
+	// escalated from a local model can be served, e.g.
+	// {"llama3:70b": "gpt-4o", "*": "gpt-4o-mini"}
+	Models map[string]string
+
+	// Billed prices in USD per 1K tokens
+	InputCostPer1K  float64
+	OutputCostPer1K float64
+}
+
+// NewCloudBackend creates a new cloud API backend
+func NewCloudBackend(cfg Config) (*CloudBackend, error) {
+	provider := cfg.Provider
+	if provider == "" {
+		provider = ProviderOpenAI
+	}
+
+	endpoint := cfg.Endpoint
+	switch provider {
+	case ProviderOpenAI:
+		if endpoint == "" {
+			endpoint = "https://api.openai.com/v1"
+		}
+	case ProviderAnthropic:
+		if endpoint == "" {
+			endpoint = "https://api.anthropic.com/v1"
+		}
+	default:
+		return nil, fmt.Errorf("unknown cloud provider %q", provider)
+	}
+
+	// Get API key from env if specified
+	apiKey := cfg.APIKey
+	if cfg.APIKeyEnv != "" {
+		apiKey = os.Getenv(cfg.APIKeyEnv)
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key required (set APIKey or APIKeyEnv)")
+	}
+
+	backend := &CloudBackend{
+		id:              cfg.ID,
+		name:            cfg.Name,
+		provider:        provider,
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		apiKey:          apiKey,
+		models:          cfg.Models,
+		inputCostPer1K:  cfg.InputCostPer1K,
+		outputCostPer1K: cfg.OutputCostPer1K,
+		avgLatencyMs:    cfg.AvgLatencyMs,
+		priority:        cfg.Priority,
+		modelCapability: cfg.ModelCapability,
+		checkTimeout:    10 * time.Second,
+		metrics: &backends.BackendMetrics{
+			LoadedModels: []string{},
+		},
+		client: &http.Client{
+			Timeout: 120 * time.Second,
+		},
+	}
+
+	backend.healthy.Store(false)
+	return backend, nil
+}
+
+// ID returns backend identifier
+func (b *CloudBackend) ID() string {
+	return b.id
+}
+
+// Type returns backend type
+func (b *CloudBackend) Type() string {
+	return "cloud"
+}
+
+// Name returns human-readable name
+func (b *CloudBackend) Name() string {
+	return b.name
+}
+
+// Hardware returns "cloud": requests leave this machine
+func (b *CloudBackend) Hardware() string {
+	return backends.HardwareCloud
+}
+
+// Provider returns the API the backend speaks
+func (b *CloudBackend) Provider() string {
+	return b.provider
+}
+
+// IsHealthy returns current health status
+func (b *CloudBackend) IsHealthy() bool {
+	return b.healthy.Load()
+}
+
+// newRequest builds an authenticated request to the provider's API
+func (b *CloudBackend) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.endpoint+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if b.provider == ProviderAnthropic {
+		req.Header.Set("x-api-key", b.apiKey)
+		req.Header.Set("anthropic-version", anthropicVersion)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+	return req, nil
+}
+
+// HealthCheck lists the provider's models, which also validates the key
+func (b *CloudBackend) HealthCheck(ctx context.Context) error {
+	checkCtx, cancel := context.WithTimeout(ctx, b.checkTimeout)
+	defer cancel()
+
+	req, err := b.newRequest(checkCtx, "GET", "/models", nil)
+	if err != nil {
+		b.healthy.Store(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		b.healthy.Store(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b.healthy.Store(false)
+		return fmt.Errorf("health check failed: status %d", resp.StatusCode)
+	}
+
+	b.healthy.Store(true)
+	b.mu.Lock()
+	b.lastCheck = time.Now()
+	b.mu.Unlock()
+
+	return nil
+}
+
+// PowerWatts returns 0: cloud services don't draw local power
+func (b *CloudBackend) PowerWatts() float64 {
+	return 0
+}
+
+// AvgLatencyMs returns average latency
+func (b *CloudBackend) AvgLatencyMs() int32 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.metrics.RequestCount > 0 {
+		return b.metrics.AvgLatencyMs
+	}
+	return b.avgLatencyMs
+}
+
+// Priority returns backend priority
+func (b *CloudBackend) Priority() int {
+	return b.priority
+}
+
+// SupportsGenerate returns true
+func (b *CloudBackend) SupportsGenerate() bool {
+	return true
+}
+
+// SupportsStream returns true
+func (b *CloudBackend) SupportsStream() bool {
+	return true
+}
+
+// SupportsEmbed returns true for OpenAI-compatible providers
+func (b *CloudBackend) SupportsEmbed() bool {
+	return b.provider == ProviderOpenAI
+}
+
+// ListModels fetches the provider's models
+func (b *CloudBackend) ListModels(ctx context.Context) ([]string, error) {
+	req, err := b.newRequest(ctx, "GET", "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s API error: status %d", b.provider, resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, len(result.Data))
+	for i, m := range result.Data {
+		models[i] = m.ID
+	}
+	return models, nil
+}
+
+// providerModel returns the provider's name for a requested model
+func (b *CloudBackend) providerModel(model string) string {
+	if m, ok := b.models[model]; ok {
+		return m
+	}
+	if m, ok := b.models["*"]; ok {
+		return m
+	}
+	return model
+}
+
+// SupportsModel checks if this backend can serve the specified model:
+// models mapped to a provider model, or matching the configured patterns
+// (by default the provider's own model families)
+func (b *CloudBackend) SupportsModel(modelName string) bool {
+	if _, ok := b.models[modelName]; ok {
+		return true
+	}
+	if _, ok := b.models["*"]; ok {
+		return true
+	}
+
+	if b.modelCapability == nil {
+		for _, pattern := range b.GetSupportedModelPatterns() {
+			if matchesPattern(modelName, pattern) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Check excluded patterns
+	for _, pattern := range b.modelCapability.ExcludedPatterns {
+		if matchesPattern(modelName, pattern) {
+			return false
+		}
+	}
+
+	// Check supported patterns
+	if len(b.modelCapability.SupportedModelPatterns) == 0 {
+		return true // No restrictions
+	}
+
+	for _, pattern := range b.modelCapability.SupportedModelPatterns {
+		if matchesPattern(modelName, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetMaxModelSizeGB returns maximum model size (N/A for cloud)
+func (b *CloudBackend) GetMaxModelSizeGB() int {
+	return 999 // Cloud services have no size limit
+}
+
+// GetSupportedModelPatterns returns patterns of supported models
+func (b *CloudBackend) GetSupportedModelPatterns() []string {
+	if b.modelCapability != nil {
+		return b.modelCapability.SupportedModelPatterns
+	}
+	if b.provider == ProviderAnthropic {
+		return []string{"claude-*"}
+	}
+	return []string{"gpt-*", "o1*", "o3*", "o4*", "text-embedding-*"}
+}
+
+// GetPreferredModels returns list of preferred models
+func (b *CloudBackend) GetPreferredModels() []string {
+	if b.modelCapability == nil {
+		return []string{}
+	}
+	return b.modelCapability.PreferredModels
+}
+
+// cost returns the billed price of a request
+func (b *CloudBackend) cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*b.inputCostPer1K + float64(outputTokens)*b.outputCostPer1K) / 1000.0
+}
+
+// stats builds generation stats for a finished request and records its cost
+func (b *CloudBackend) stats(model string, inputTokens, outputTokens int, elapsed time.Duration) *backends.GenerationStats {
+	cost := b.cost(inputTokens, outputTokens)
+	metrics.RecordCloudRequest(b.id, model, inputTokens, outputTokens, cost)
+
+	return &backends.GenerationStats{
+		TotalTimeMs:     int32(elapsed.Milliseconds()),
+		TokensGenerated: int32(outputTokens),
+		TokensPerSecond: float32(outputTokens) / float32(elapsed.Seconds()),
+		EnergyWh:        0, // Cloud service
+		CostUSD:         cost,
+	}
+}
+
+// generateRequest builds the provider's request body for req
+func (b *CloudBackend) generateRequest(req *backends.GenerateRequest, stream bool) (string, map[string]interface{}) {
+	body := map[string]interface{}{
+		"model": b.providerModel(req.Model),
+		"messages": []map[string]string{
+			{"role": "user", "content": req.Prompt},
+		},
+	}
+
+	maxTokens := int32(0)
+	if req.Options != nil {
+		maxTokens = req.Options.MaxTokens
+		if req.Options.Temperature > 0 {
+			body["temperature"] = req.Options.Temperature
+		}
+		if req.Options.TopP > 0 {
+			body["top_p"] = req.Options.TopP
+		}
+	}
+
+	if b.provider == ProviderAnthropic {
+		if maxTokens <= 0 {
+			maxTokens = DefaultMaxTokens
+		}
+		body["max_tokens"] = maxTokens
+		if req.Options != nil && len(req.Options.Stop) > 0 {
+			body["stop_sequences"] = req.Options.Stop
+		}
+		if stream {
+			body["stream"] = true
+		}
+		return "/messages", body
+	}
+
+	if maxTokens > 0 {
+		body["max_tokens"] = maxTokens
+	}
+	if req.Options != nil && len(req.Options.Stop) > 0 {
+		body["stop"] = req.Options.Stop
+	}
+	if stream {
+		body["stream"] = true
+		body["stream_options"] = map[string]bool{"include_usage": true}
+	}
+	return "/chat/completions", body
+}
+
+// send posts body to path, updating metrics on failure. The caller closes
+// the response body and records success.
+func (b *CloudBackend) send(ctx context.Context, path string, body interface{}, start time.Time) (*http.Response, error) {
+	httpReq, err := b.newRequest(ctx, "POST", path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != context.Canceled {
+			// A request the caller cancelled (e.g. a hedge that lost) is not a
+			// backend failure; a timeout still is
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s API error: %d - %s", b.provider, resp.StatusCode, string(bodyBytes))
+	}
+	return resp, nil
+}
+
+// Generate performs text generation via the provider's API
+func (b *CloudBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	start := time.Now()
+
+	path, body := b.generateRequest(req, false)
+	resp, err := b.send(ctx, path, body, start)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		// OpenAI
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		// Anthropic
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage usage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, err
+	}
+
+	elapsed := time.Since(start)
+	b.UpdateMetrics(int32(elapsed.Milliseconds()), true)
+
+	response := ""
+	if len(result.Choices) > 0 {
+		response = result.Choices[0].Message.Content
+	} else if len(result.Content) > 0 {
+		response = result.Content[0].Text
+	}
+
+	input, output := result.Usage.tokens()
+	return &backends.GenerateResponse{
+		Response: response,
+		Stats:    b.stats(body["model"].(string), input, output, elapsed),
+	}, nil
+}
+
+// usage is token usage as reported by either provider
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`     // OpenAI
+	CompletionTokens int `json:"completion_tokens"` // OpenAI
+	InputTokens      int `json:"input_tokens"`      // Anthropic
+	OutputTokens     int `json:"output_tokens"`     // Anthropic
+}
+
+// tokens returns input and output tokens
+func (u usage) tokens() (int, int) {
+	return u.PromptTokens + u.InputTokens, u.CompletionTokens + u.OutputTokens
+}
+
+// Embed generates embeddings via an OpenAI-compatible API
+func (b *CloudBackend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	if b.provider != ProviderOpenAI {
+		return nil, fmt.Errorf("embeddings not supported by %s API", b.provider)
+	}
+	start := time.Now()
+
+	model := b.providerModel(req.Model)
+	body := map[string]interface{}{
+		"model": model,
+		"input": req.Text,
+	}
+	if req.Dimensions > 0 {
+		body["dimensions"] = req.Dimensions
+	}
+	resp, err := b.send(ctx, "/embeddings", body, start)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage usage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		return nil, fmt.Errorf("%s API returned no embedding", b.provider)
+	}
+
+	elapsed := time.Since(start)
+	b.UpdateMetrics(int32(elapsed.Milliseconds()), true)
+
+	input, _ := result.Usage.tokens()
+	stats := b.stats(model, input, 0, elapsed)
+	stats.TokensGenerated = 0
+	stats.TokensPerSecond = 0
+	return &backends.EmbedResponse{
+		Embedding: result.Data[0].Embedding,
+		Stats:     stats,
+	}, nil
+}
+
+// UpdateMetrics updates backend metrics
+func (b *CloudBackend) UpdateMetrics(latencyMs int32, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	atomic.AddInt64(&b.metrics.RequestCount, 1)
+
+	if success {
+		atomic.AddInt64(&b.metrics.SuccessCount, 1)
+		atomic.AddInt64(&b.metrics.TotalLatencyMs, int64(latencyMs))
+
+		if b.metrics.RequestCount > 0 {
+			b.metrics.AvgLatencyMs = int32(b.metrics.TotalLatencyMs / b.metrics.RequestCount)
+		}
+	} else {
+		atomic.AddInt64(&b.metrics.ErrorCount, 1)
+	}
+
+	if b.metrics.RequestCount > 0 {
+		b.metrics.ErrorRate = float32(b.metrics.ErrorCount) / float32(b.metrics.RequestCount)
+	}
+}
+
+// GetMetrics returns current metrics
+func (b *CloudBackend) GetMetrics() *backends.BackendMetrics {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return &backends.BackendMetrics{
+		RequestCount:   b.metrics.RequestCount,
+		SuccessCount:   b.metrics.SuccessCount,
+		ErrorCount:     b.metrics.ErrorCount,
+		TotalLatencyMs: b.metrics.TotalLatencyMs,
+		AvgLatencyMs:   b.metrics.AvgLatencyMs,
+		ErrorRate:      b.metrics.ErrorRate,
+		LoadedModels:   b.metrics.LoadedModels,
+	}
+}
+
+// Start initializes the backend
+func (b *CloudBackend) Start(ctx context.Context) error {
+	return b.HealthCheck(ctx)
+}
+
+// Stop shuts down the backend
+func (b *CloudBackend) Stop(ctx context.Context) error {
+	return nil
+}
+
+// matchesPattern checks if model name matches a pattern
+func matchesPattern(modelName, pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	if modelName == pattern {
+		return true
+	}
+
+	// Simple wildcard matching
+	if strings.HasPrefix(pattern, "*") && strings.HasSuffix(pattern, "*") {
+		substr := strings.Trim(pattern, "*")
+		return strings.Contains(modelName, substr)
+	}
+
+	if strings.HasSuffix(pattern, "*") {
+		prefix := strings.TrimSuffix(pattern, "*")
+		return strings.HasPrefix(modelName, prefix)
+	}
+
+	if strings.HasPrefix(pattern, "*") {
+		suffix := strings.TrimPrefix(pattern, "*")
+		return strings.HasSuffix(modelName, suffix)
+	}
+
+	return false
+}
+
+// ============================================================
+// Multimedia Capability Methods
+// ============================================================
+
+// SupportsAudioToText returns false
+func (b *CloudBackend) SupportsAudioToText() bool {
+	return false
+}
+
+// SupportsTextToAudio returns false
+func (b *CloudBackend) SupportsTextToAudio() bool {
+	return false
+}
+
+// SupportsImageToText returns false
+func (b *CloudBackend) SupportsImageToText() bool {
+	return false
+}
+
+// SupportsTextToImage returns false
+func (b *CloudBackend) SupportsTextToImage() bool {
+	return false
+}
+
+// SupportsVideoToText returns false
+func (b *CloudBackend) SupportsVideoToText() bool {
+	return false
+}
+
+// SupportsTextToVideo returns false
+func (b *CloudBackend) SupportsTextToVideo() bool {
+	return false
+}
+
+// TranscribeAudio is not supported
+func (b *CloudBackend) TranscribeAudio(ctx context.Context, req *backends.TranscribeRequest) (*backends.TranscribeResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by cloud backend")
+}
+
+// TranscribeAudioStream is not supported
+func (b *CloudBackend) TranscribeAudioStream(ctx context.Context, req *backends.TranscribeRequest) (backends.AudioStreamReader, error) {
+	return nil, fmt.Errorf("audio transcription not supported by cloud backend")
+}
+
+// SynthesizeSpeech is not supported
+func (b *CloudBackend) SynthesizeSpeech(ctx context.Context, req *backends.SynthesizeRequest) (*backends.SynthesizeResponse, error) {
+	return nil, fmt.Errorf("speech synthesis not supported by cloud backend")
+}
+
+// SynthesizeSpeechStream is not supported
+func (b *CloudBackend) SynthesizeSpeechStream(ctx context.Context, req *backends.SynthesizeRequest) (backends.AudioStreamWriter, error) {
+	return nil, fmt.Errorf("speech synthesis not supported by cloud backend")
+}
+
+// AnalyzeImage is not supported
+func (b *CloudBackend) AnalyzeImage(ctx context.Context, req *backends.ImageAnalysisRequest) (*backends.ImageAnalysisResponse, error) {
+	return nil, fmt.Errorf("image analysis not supported by cloud backend")
+}
+
+// GenerateImage is not supported
+func (b *CloudBackend) GenerateImage(ctx context.Context, req *backends.ImageGenRequest) (*backends.ImageGenResponse, error) {
+	return nil, fmt.Errorf("image generation not supported by cloud backend")
+}
+
+// GenerateImageStream is not supported
+func (b *CloudBackend) GenerateImageStream(ctx context.Context, req *backends.ImageGenRequest) (backends.ImageStreamReader, error) {
+	return nil, fmt.Errorf("image generation not supported by cloud backend")
+}
+
+// AnalyzeVideo is not supported
+func (b *CloudBackend) AnalyzeVideo(ctx context.Context, req *backends.VideoAnalysisRequest) (*backends.VideoAnalysisResponse, error) {
+	return nil, fmt.Errorf("video analysis not supported by cloud backend")
+}
+
+// AnalyzeVideoStream is not supported
+func (b *CloudBackend) AnalyzeVideoStream(ctx context.Context, req *backends.VideoAnalysisRequest) (backends.VideoStreamReader, error) {
+	return nil, fmt.Errorf("video analysis not supported by cloud backend")
+}
+
+// GenerateVideo is not supported
+func (b *CloudBackend) GenerateVideo(ctx context.Context, req *backends.VideoGenRequest) (*backends.VideoGenResponse, error) {
+	return nil, fmt.Errorf("video generation not supported by cloud backend")
+}
+
+// GenerateVideoStream is not supported
+func (b *CloudBackend) GenerateVideoStream(ctx context.Context, req *backends.VideoGenRequest) (backends.VideoStreamReader, error) {
+	return nil, fmt.Errorf("video generation not supported by cloud backend")
+}
@@ -0,0 +1,197 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// newProviderServer fakes the OpenAI and Anthropic APIs, recording the
+// model of the last generation request
+func newProviderServer(t *testing.T, lastModel *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		openaiAuth := r.Header.Get("Authorization") == "Bearer sk-test"
+		anthropicAuth := r.Header.Get("x-api-key") == "sk-test" && r.Header.Get("anthropic-version") != ""
+		if !openaiAuth && !anthropicAuth {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if model, ok := body["model"].(string); ok && lastModel != nil {
+			*lastModel = model
+		}
+		stream := body["stream"] == true
+
+		switch r.URL.Path {
+		case "/models":
+			fmt.Fprint(w, `{"data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`)
+		case "/chat/completions":
+			if stream {
+				fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n")
+				fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\" there\"}}]}\n\n")
+				fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":1000,\"completion_tokens\":2000}}\n\n")
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				return
+			}
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"Hello there"}}],"usage":{"prompt_tokens":1000,"completion_tokens":2000}}`)
+		case "/messages":
+			if body["max_tokens"] == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if stream {
+				fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":1000,\"output_tokens\":1}}}\n\n")
+				fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n")
+				fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\" there\"}}\n\n")
+				fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":2000}}\n\n")
+				fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+				return
+			}
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"Hello there"}],"usage":{"input_tokens":1000,"output_tokens":2000}}`)
+		case "/embeddings":
+			fmt.Fprint(w, `{"data":[{"embedding":[0.5,0.25]}],"usage":{"prompt_tokens":1000}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestBackend(t *testing.T, server *httptest.Server, provider string) *CloudBackend {
+	backend, err := NewCloudBackend(Config{
+		BackendConfig:   backends.BackendConfig{ID: "cloud-" + provider},
+		Provider:        provider,
+		Endpoint:        server.URL,
+		APIKey:          "sk-test",
+		Models:          map[string]string{"llama3:405b": "big-model"},
+		InputCostPer1K:  0.001,
+		OutputCostPer1K: 0.002,
+	})
+	if err != nil {
+		t.Fatalf("NewCloudBackend failed: %v", err)
+	}
+	return backend
+}
+
+func TestNewCloudBackend(t *testing.T) {
+	if _, err := NewCloudBackend(Config{Provider: "openai"}); err == nil {
+		t.Error("Expected error for missing API key")
+	}
+	if _, err := NewCloudBackend(Config{Provider: "gemini", APIKey: "k"}); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+
+	backend, err := NewCloudBackend(Config{APIKey: "k"})
+	if err != nil {
+		t.Fatalf("NewCloudBackend failed: %v", err)
+	}
+	if backend.Provider() != ProviderOpenAI || !backends.IsCloud(backend) || backend.PowerWatts() != 0 {
+		t.Errorf("Unexpected defaults: provider %s, hardware %s", backend.Provider(), backend.Hardware())
+	}
+}
+
+func TestCloudBackend_SupportsModel(t *testing.T) {
+	backend, _ := NewCloudBackend(Config{APIKey: "k", Models: map[string]string{"llama3:405b": "gpt-4o"}})
+
+	if !backend.SupportsModel("gpt-4o-mini") {
+		t.Error("Expected the provider's own models to be supported")
+	}
+	if !backend.SupportsModel("llama3:405b") {
+		t.Error("Expected mapped models to be supported")
+	}
+	if backend.SupportsModel("qwen2.5:0.5b") {
+		t.Error("Expected unmapped local models to be unsupported")
+	}
+}
+
+func TestCloudBackend_Generate(t *testing.T) {
+	for _, provider := range []string{ProviderOpenAI, ProviderAnthropic} {
+		t.Run(provider, func(t *testing.T) {
+			var lastModel string
+			server := newProviderServer(t, &lastModel)
+			defer server.Close()
+			backend := newTestBackend(t, server, provider)
+
+			if err := backend.Start(context.Background()); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+
+			resp, err := backend.Generate(context.Background(), &backends.GenerateRequest{Prompt: "Hi", Model: "llama3:405b"})
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if resp.Response != "Hello there" || lastModel != "big-model" {
+				t.Errorf("Unexpected response %q from model %s", resp.Response, lastModel)
+			}
+			// 1000 input tokens at $0.001/1K + 2000 output at $0.002/1K
+			if resp.Stats.TokensGenerated != 2000 || math.Abs(resp.Stats.CostUSD-0.005) > 1e-9 {
+				t.Errorf("Expected 2000 tokens costing $0.005, got %d costing $%f", resp.Stats.TokensGenerated, resp.Stats.CostUSD)
+			}
+		})
+	}
+}
+
+func TestCloudBackend_GenerateStream(t *testing.T) {
+	for _, provider := range []string{ProviderOpenAI, ProviderAnthropic} {
+		t.Run(provider, func(t *testing.T) {
+			server := newProviderServer(t, nil)
+			defer server.Close()
+			backend := newTestBackend(t, server, provider)
+
+			stream, err := backend.GenerateStream(context.Background(), &backends.GenerateRequest{Prompt: "Hi", Model: "gpt-4o"})
+			if err != nil {
+				t.Fatalf("GenerateStream failed: %v", err)
+			}
+			defer stream.Close()
+
+			var text string
+			var final *backends.StreamChunk
+			for {
+				chunk, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv failed: %v", err)
+				}
+				if chunk.Done {
+					final = chunk
+					continue
+				}
+				text += chunk.Token
+			}
+
+			if text != "Hello there" {
+				t.Errorf("Expected 'Hello there', got %q", text)
+			}
+			if final == nil || math.Abs(final.Stats.CostUSD-0.005) > 1e-9 {
+				t.Errorf("Expected a final chunk costing $0.005, got %+v", final)
+			}
+		})
+	}
+}
+
+func TestCloudBackend_Embed(t *testing.T) {
+	server := newProviderServer(t, nil)
+	defer server.Close()
+
+	resp, err := newTestBackend(t, server, ProviderOpenAI).Embed(context.Background(), &backends.EmbedRequest{Text: "Hi", Model: "text-embedding-3-small"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(resp.Embedding) != 2 || math.Abs(resp.Stats.CostUSD-0.001) > 1e-9 {
+		t.Errorf("Unexpected embedding %v costing $%f", resp.Embedding, resp.Stats.CostUSD)
+	}
+
+	if _, err := newTestBackend(t, server, ProviderAnthropic).Embed(context.Background(), &backends.EmbedRequest{Text: "Hi"}); err == nil {
+		t.Error("Expected embeddings to be unsupported by Anthropic")
+	}
+}
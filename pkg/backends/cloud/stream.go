@@ -0,0 +1,152 @@
+package cloud
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// cloudStreamReader reads a server-sent event stream from either provider.
+// OpenAI ends the stream with "data: [DONE]", Anthropic with a
+// message_stop event; usage arrives in a final chunk (OpenAI) or split
+// across message_start and message_delta (Anthropic).
+type cloudStreamReader struct {
+	scanner *bufio.Scanner
+	resp    *http.Response
+	start   time.Time
+	backend *CloudBackend
+	model   string
+
+	firstTokenMs int32
+	started      bool
+	usage        usage
+	done         bool
+}
+
+// GenerateStream performs streaming text generation
+func (b *CloudBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	start := time.Now()
+
+	path, body := b.generateRequest(req, true)
+	resp, err := b.send(ctx, path, body, start)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+
+	return &cloudStreamReader{
+		scanner: scanner,
+		resp:    resp,
+		start:   start,
+		backend: b,
+		model:   body["model"].(string),
+	}, nil
+}
+
+// streamEvent holds the fields of either provider's stream events
+type streamEvent struct {
+	// OpenAI
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *usage `json:"usage"`
+
+	// Anthropic
+	Type  string `json:"type"`
+	Delta struct {
+		Text string `json:"text"`
+	} `json:"delta"`
+	Message struct {
+		Usage usage `json:"usage"`
+	} `json:"message"`
+}
+
+// Recv returns the next token, and a final chunk with stats and cost at
+// the end of the stream
+func (r *cloudStreamReader) Recv() (*backends.StreamChunk, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	for r.scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(r.scanner.Text()), "data:")
+		if !ok {
+			continue // event names, blank separators and comments
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return r.finish(), nil
+		}
+
+		var event streamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, err
+		}
+
+		token := ""
+		switch event.Type {
+		case "message_start":
+			r.usage.InputTokens = event.Message.Usage.InputTokens
+		case "message_delta":
+			if event.Usage != nil {
+				r.usage.OutputTokens = event.Usage.OutputTokens
+			}
+		case "content_block_delta":
+			token = event.Delta.Text
+		case "message_stop":
+			return r.finish(), nil
+		case "":
+			if event.Usage != nil {
+				r.usage.PromptTokens = event.Usage.PromptTokens
+				r.usage.CompletionTokens = event.Usage.CompletionTokens
+			}
+			if len(event.Choices) > 0 {
+				token = event.Choices[0].Delta.Content
+			}
+		}
+		if token == "" {
+			continue
+		}
+
+		if !r.started {
+			r.started = true
+			r.firstTokenMs = int32(time.Since(r.start).Milliseconds())
+		}
+		return &backends.StreamChunk{Token: token}, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		r.backend.UpdateMetrics(int32(time.Since(r.start).Milliseconds()), false)
+		return nil, err
+	}
+	// Connection closed without an end event
+	r.done = true
+	return nil, io.EOF
+}
+
+// finish ends the stream, returning the final chunk
+func (r *cloudStreamReader) finish() *backends.StreamChunk {
+	r.done = true
+	elapsed := time.Since(r.start)
+	r.backend.UpdateMetrics(int32(elapsed.Milliseconds()), true)
+
+	input, output := r.usage.tokens()
+	stats := r.backend.stats(r.model, input, output, elapsed)
+	stats.TimeToFirstTokenMs = r.firstTokenMs
+	return &backends.StreamChunk{Done: true, Stats: stats}
+}
+
+// Close closes the stream
+func (r *cloudStreamReader) Close() error {
+	return r.resp.Body.Close()
+}
@@ -0,0 +1,48 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Cold start reasons
+const (
+	ColdStartModelLoad      = "model_load"      // the model is not resident and must be loaded
+	ColdStartContainerStart = "container_start" // an on-demand container is stopped
+)
+
+// ColdStart describes why the first response of a request will be slow
+type ColdStart struct {
+	Reason   string
+	Model    string
+	Estimate time.Duration // 0 when unknown
+}
+
+// Message is a short human-readable status, e.g. "loading model llama3:8b, ~12s"
+func (c *ColdStart) Message() string {
+	msg := "loading model " + c.Model
+	if c.Reason == ColdStartContainerStart {
+		msg = "starting backend for " + c.Model
+	}
+	if c.Estimate > 0 {
+		msg += fmt.Sprintf(", ~%ds", int((c.Estimate+time.Second-1)/time.Second))
+	}
+	return msg
+}
+
+// ColdStarter is implemented by backends that can tell ahead of a request
+// whether serving a model first needs a slow load
+type ColdStarter interface {
+	// ColdStart returns nil when the model is warm or its state is unknown
+	ColdStart(ctx context.Context, model string) *ColdStart
+}
+
+// CheckColdStart returns b's cold start for model, nil when it is warm or
+// b cannot tell
+func CheckColdStart(ctx context.Context, b Backend, model string) *ColdStart {
+	if cs, ok := b.(ColdStarter); ok {
+		return cs.ColdStart(ctx, model)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+)
+
+// DraftVerdict is a model's judgement of a draft continuation of a prompt
+type DraftVerdict struct {
+	// Accepted is the longest prefix of the draft the model would have
+	// generated itself, greedily
+	Accepted       string
+	AcceptedTokens int
+	DraftTokens    int // in the verifying model's tokenizer
+
+	// Next is the token the model generates after Accepted: its
+	// correction of the first rejected draft token, or a bonus token when
+	// the whole draft was accepted
+	Next string
+
+	// Done is set when Next ends the generation (end of sequence or a stop
+	// sequence)
+	Done bool
+}
+
+// DraftVerifier is implemented by backends that can score a draft
+// continuation in one forward pass, as speculative decoding needs (prompt
+// logprobs with echo on OpenAI-compatible servers such as vLLM). Checking
+// the draft costs about as much as generating a single token.
+type DraftVerifier interface {
+	// SupportsDraftVerification is false when the backend can't score
+	// drafts, e.g. it wraps a backend that can't
+	SupportsDraftVerification() bool
+	// VerifyDraft judges draft as a continuation of req.Prompt, which is
+	// sent without a prompt template
+	VerifyDraft(ctx context.Context, req *GenerateRequest, draft string) (*DraftVerdict, error)
+}
+
+// SupportsDraftVerification reports whether b scores draft continuations
+func SupportsDraftVerification(b Backend) bool {
+	dv, ok := b.(DraftVerifier)
+	return ok && dv.SupportsDraftVerification()
+}
+
+// VerifyDraft calls b's VerifyDraft. Wrapping backends use it to pass the
+// call through.
+func VerifyDraft(ctx context.Context, b Backend, req *GenerateRequest, draft string) (*DraftVerdict, error) {
+	if dv, ok := b.(DraftVerifier); ok && dv.SupportsDraftVerification() {
+		return dv.VerifyDraft(ctx, req, draft)
+	}
+	return nil, fmt.Errorf("backend %s cannot verify drafts", b.ID())
+}
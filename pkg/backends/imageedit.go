@@ -0,0 +1,17 @@
+package backends
+
+// ImageEditor is implemented by backends that can edit an image as well as
+// generate one: image-to-image from ImageGenRequest.InitImage, inpainting
+// with its Mask, and Strength to set how much of the source is kept.
+// Backends without it ignore InitImage, so edits must not be routed to them.
+type ImageEditor interface {
+	// SupportsImageEdit is false when the backend can't edit, e.g. it
+	// wraps a backend that can't
+	SupportsImageEdit() bool
+}
+
+// SupportsImageEdit reports whether b edits images
+func SupportsImageEdit(b Backend) bool {
+	ie, ok := b.(ImageEditor)
+	return ok && ie.SupportsImageEdit()
+}
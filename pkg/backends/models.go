@@ -0,0 +1,80 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrModelsNotManaged is returned for model management on a backend that
+// cannot pull or delete models
+var ErrModelsNotManaged = errors.New("backend does not support model management")
+
+// InstalledModel is a model downloaded to a backend
+type InstalledModel struct {
+	Name       string    `json:"name"`
+	SizeBytes  int64     `json:"size_bytes"`
+	Digest     string    `json:"digest,omitempty"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// ModelManager is implemented by backends whose models can be pulled and
+// deleted at runtime, such as Ollama
+type ModelManager interface {
+	// ManagesModels is false for wrappers around a backend that can't
+	ManagesModels() bool
+	// InstalledModels lists the models downloaded to the backend
+	InstalledModels(ctx context.Context) ([]InstalledModel, error)
+	// PullModel downloads a model, publishing progress to DefaultProgress
+	PullModel(ctx context.Context, model string) error
+	// DeleteModel removes a downloaded model
+	DeleteModel(ctx context.Context, model string) error
+}
+
+// ManagesModels reports whether b can pull and delete models
+func ManagesModels(b Backend) bool {
+	mm, ok := b.(ModelManager)
+	return ok && mm.ManagesModels()
+}
+
+// InstalledModels lists the models downloaded to b
+func InstalledModels(ctx context.Context, b Backend) ([]InstalledModel, error) {
+	if mm, ok := b.(ModelManager); ok && mm.ManagesModels() {
+		return mm.InstalledModels(ctx)
+	}
+	return nil, ErrModelsNotManaged
+}
+
+// PullModel downloads model to b
+func PullModel(ctx context.Context, b Backend, model string) error {
+	if mm, ok := b.(ModelManager); ok && mm.ManagesModels() {
+		return mm.PullModel(ctx, model)
+	}
+	return ErrModelsNotManaged
+}
+
+// DeleteModel removes model from b
+func DeleteModel(ctx context.Context, b Backend, model string) error {
+	if mm, ok := b.(ModelManager); ok && mm.ManagesModels() {
+		return mm.DeleteModel(ctx, model)
+	}
+	return ErrModelsNotManaged
+}
+
+// HasModel reports whether model is downloaded to b. A name without a tag
+// matches any tag, so "llama3" is installed when "llama3:8b" is.
+func HasModel(ctx context.Context, b Backend, model string) (bool, error) {
+	installed, err := InstalledModels(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	model = strings.ToLower(model)
+	for _, m := range installed {
+		name := strings.ToLower(m.Name)
+		if name == model || strings.HasPrefix(name, model+":") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
 	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
 	"go.uber.org/zap"
 )
 
@@ -49,6 +50,19 @@ type OllamaBackend struct {
 	// Metrics
 	metrics *backends.BackendMetrics
 
+	// Observed model load times (model -> last load_duration), used to
+	// estimate cold starts
+	loadTimes map[string]time.Duration
+
+	// Models resident in memory per /api/ps, fetched at most every
+	// residentTTL and added to as requests load models
+	resident        map[string]bool
+	residentFetched time.Time
+	residentTTL     time.Duration
+
+	// Set once the server has ignored a request for several sequences
+	noSequences atomic.Bool
+
 	// HTTP client
 	client *http.Client
 }
@@ -57,6 +71,10 @@ type OllamaBackend struct {
 type Config struct {
 	backends.BackendConfig
 	Endpoint string
+
+	// WrapTransport, if set, wraps the HTTP transport, e.g. to sign
+	// requests to a peer proxy
+	WrapTransport func(http.RoundTripper) http.RoundTripper
 }
 
 // NewOllamaBackend creates a new Ollama backend instance
@@ -78,6 +96,8 @@ func NewOllamaBackend(cfg Config) (*OllamaBackend, error) {
 		metrics: &backends.BackendMetrics{
 			LoadedModels: []string{},
 		},
+		loadTimes:   make(map[string]time.Duration),
+		residentTTL: residentModelsTTL,
 		client: &http.Client{
 			Timeout: 120 * time.Second, // Longer timeout for voice/streaming workloads
 			Transport: &http.Transport{
@@ -104,6 +124,11 @@ func NewOllamaBackend(cfg Config) (*OllamaBackend, error) {
 			},
 		},
 	}
+	if cfg.WrapTransport != nil {
+		backend.client.Transport = cfg.WrapTransport(backend.client.Transport)
+	}
+	// Outermost, so a signing transport sees the request ID header
+	backend.client.Transport = middleware.RequestIDTransport(backend.client.Transport)
 
 	backend.healthy.Store(false) // Will be set by health check
 	return backend, nil
@@ -233,8 +258,142 @@ func (b *OllamaBackend) ListModels(ctx context.Context) ([]string, error) {
 	return models, nil
 }
 
+// coldStartProbeTimeout bounds the /api/ps and /api/tags lookups made
+// before a request, so a slow backend does not delay it further
+const coldStartProbeTimeout = 500 * time.Millisecond
+
+// residentModelsTTL is how long a /api/ps listing is reused, so streamed
+// requests do not each cost a lookup
+const residentModelsTTL = 10 * time.Second
+
+// minObservedLoad separates a real model load from the few milliseconds
+// Ollama reports for a resident model
+const minObservedLoad = 500 * time.Millisecond
+
+// loadBytesPerSecond estimates load time from model size when no load has
+// been observed yet
+const loadBytesPerSecond = 500 << 20
+
+// observeLoad remembers that model is resident after a request, and how
+// long loading it took
+func (b *OllamaBackend) observeLoad(model string, d time.Duration) {
+	if model == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.resident != nil {
+		b.resident[normalizeModelName(model)] = true
+	}
+	if d >= minObservedLoad {
+		b.loadTimes[normalizeModelName(model)] = d
+	}
+}
+
+// residentModels returns the models Ollama holds in memory, from /api/ps
+// when the last listing is older than residentTTL
+func (b *OllamaBackend) residentModels(ctx context.Context) (map[string]bool, error) {
+	b.mu.RLock()
+	resident, fresh := b.resident, time.Since(b.residentFetched) < b.residentTTL
+	b.mu.RUnlock()
+	if resident != nil && fresh {
+		return resident, nil
+	}
+
+	var running struct {
+		Models []struct {
+			Name  string `json:"name"`
+			Model string `json:"model"`
+		} `json:"models"`
+	}
+	if err := b.getJSON(ctx, "/api/ps", &running); err != nil {
+		return nil, err
+	}
+	resident = make(map[string]bool, 2*len(running.Models))
+	for _, m := range running.Models {
+		resident[normalizeModelName(m.Name)] = true
+		resident[normalizeModelName(m.Model)] = true
+	}
+	b.mu.Lock()
+	b.resident, b.residentFetched = resident, time.Now()
+	b.mu.Unlock()
+	return resident, nil
+}
+
+// normalizeModelName applies Ollama's default ":latest" tag
+func normalizeModelName(model string) string {
+	if !strings.Contains(model, ":") {
+		return model + ":latest"
+	}
+	return model
+}
+
+// ColdStart reports whether model must be loaded before it can respond,
+// using Ollama's list of resident models. The estimate is the last
+// observed load of the model, or derived from its size on disk.
+func (b *OllamaBackend) ColdStart(ctx context.Context, model string) *backends.ColdStart {
+	if model == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, coldStartProbeTimeout)
+	defer cancel()
+
+	resident, err := b.residentModels(ctx)
+	if err != nil {
+		return nil // unknown
+	}
+	want := normalizeModelName(model)
+	b.mu.RLock()
+	warm, estimate := resident[want], b.loadTimes[want]
+	b.mu.RUnlock()
+	if warm {
+		return nil
+	}
+
+	cs := &backends.ColdStart{Reason: backends.ColdStartModelLoad, Model: model, Estimate: estimate}
+	if cs.Estimate > 0 {
+		return cs
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+			Size int64  `json:"size"`
+		} `json:"models"`
+	}
+	if err := b.getJSON(ctx, "/api/tags", &tags); err == nil {
+		for _, m := range tags.Models {
+			if normalizeModelName(m.Name) == want {
+				cs.Estimate = time.Duration(m.Size) * time.Second / loadBytesPerSecond
+				break
+			}
+		}
+	}
+	return cs
+}
+
+// getJSON decodes a GET response from the Ollama API
+func (b *OllamaBackend) getJSON(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama error: status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
 // Generate performs text generation
 func (b *OllamaBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	if len(req.Tools) > 0 {
+		return b.generateChat(ctx, req)
+	}
 	start := time.Now()
 
 	// Build Ollama request
@@ -262,6 +421,12 @@ func (b *OllamaBackend) Generate(ctx context.Context, req *backends.GenerateRequ
 		if req.Options.TopK > 0 {
 			options["top_k"] = req.Options.TopK
 		}
+		if req.Options.Raw {
+			ollamaReq["raw"] = true
+		}
+		if req.Options.Logprobs {
+			ollamaReq["logprobs"] = true // ignored before Ollama 0.12.11
+		}
 	}
 
 	ollamaReq["options"] = options
@@ -279,7 +444,11 @@ func (b *OllamaBackend) Generate(ctx context.Context, req *backends.GenerateRequ
 
 	resp, err := b.client.Do(httpReq)
 	if err != nil {
-		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		if ctx.Err() != context.Canceled {
+			// A request the caller cancelled (e.g. a hedge that lost) is not a
+			// backend failure; a timeout still is
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -291,15 +460,22 @@ func (b *OllamaBackend) Generate(ctx context.Context, req *backends.GenerateRequ
 	}
 
 	var ollamaResp struct {
-		Response string `json:"response"`
-		Context  []int  `json:"context"`
-		Done     bool   `json:"done"`
+		Response     string `json:"response"`
+		Context      []int  `json:"context"`
+		Done         bool   `json:"done"`
+		LoadDuration int64  `json:"load_duration"` // nanoseconds
+		Logprobs     []struct {
+			Logprob float64 `json:"logprob"`
+		} `json:"logprobs"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
 		return nil, err
 	}
+	b.observeLoad(req.Model, time.Duration(ollamaResp.LoadDuration))
 
 	elapsed := time.Since(start)
 	latencyMs := int32(elapsed.Milliseconds())
@@ -309,6 +485,11 @@ func (b *OllamaBackend) Generate(ctx context.Context, req *backends.GenerateRequ
 	// Calculate energy consumption
 	energyWh := (b.powerWatts * elapsed.Seconds()) / 3600.0
 
+	var logprobs []float64
+	for _, lp := range ollamaResp.Logprobs {
+		logprobs = append(logprobs, lp.Logprob)
+	}
+
 	return &backends.GenerateResponse{
 		Response: ollamaResp.Response,
 		Stats: &backends.GenerationStats{
@@ -317,15 +498,130 @@ func (b *OllamaBackend) Generate(ctx context.Context, req *backends.GenerateRequ
 			TokensPerSecond: float32(len(ollamaResp.Context)) / float32(elapsed.Seconds()),
 			EnergyWh:        float32(energyWh),
 		},
+		TokenLogprobs: logprobs,
 	}, nil
 }
 
+// SupportsSequences is true until the server answers a request for several
+// sequences with just one. Ollama builds without parallel sequence support
+// ignore n on the OpenAI-compatible API.
+func (b *OllamaBackend) SupportsSequences() bool {
+	return !b.noSequences.Load()
+}
+
+// GenerateSequences asks the OpenAI-compatible /v1/completions endpoint for
+// n completions in one request, the best n of bestOf when bestOf > n
+func (b *OllamaBackend) GenerateSequences(ctx context.Context, req *backends.GenerateRequest, n, bestOf int) ([]*backends.GenerateResponse, error) {
+	start := time.Now()
+
+	completionReq := map[string]interface{}{
+		"model":       req.Model,
+		"prompt":      req.Prompt,
+		"n":           n,
+		"max_tokens":  256,
+		"temperature": 0.7,
+	}
+	if bestOf > n {
+		completionReq["best_of"] = bestOf
+	}
+	if req.Options != nil {
+		if req.Options.Temperature > 0 {
+			completionReq["temperature"] = req.Options.Temperature
+		}
+		if req.Options.TopP > 0 {
+			completionReq["top_p"] = req.Options.TopP
+		}
+		if req.Options.MaxTokens > 0 {
+			completionReq["max_tokens"] = req.Options.MaxTokens
+		}
+	}
+
+	body, err := json.Marshal(completionReq)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", b.endpoint+"/v1/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var completionResp struct {
+		Choices []struct {
+			Text string `json:"text"`
+		} `json:"choices"`
+		Usage struct {
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&completionResp); err != nil {
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, err
+	}
+
+	elapsed := time.Since(start)
+	latencyMs := int32(elapsed.Milliseconds())
+	b.UpdateMetrics(latencyMs, true)
+
+	if n > 1 && len(completionResp.Choices) < n {
+		if !b.noSequences.Swap(true) && logging.Logger != nil {
+			logging.FromContext(ctx).Info("Backend ignores n, generating sequences separately",
+				zap.String("backend_id", b.id),
+				zap.Int("requested", n),
+				zap.Int("returned", len(completionResp.Choices)),
+			)
+		}
+	}
+	if len(completionResp.Choices) == 0 {
+		return nil, nil
+	}
+
+	// Usage and energy cover every choice; attribute them evenly
+	count := len(completionResp.Choices)
+	tokens := int32(completionResp.Usage.CompletionTokens / count)
+	energyWh := (b.powerWatts * elapsed.Seconds()) / 3600.0 / float64(count)
+
+	resps := make([]*backends.GenerateResponse, count)
+	for i, choice := range completionResp.Choices {
+		resps[i] = &backends.GenerateResponse{
+			Response: choice.Text,
+			Stats: &backends.GenerationStats{
+				TotalTimeMs:     latencyMs,
+				TokensGenerated: tokens,
+				TokensPerSecond: float32(tokens) / float32(elapsed.Seconds()),
+				EnergyWh:        float32(energyWh),
+			},
+		}
+	}
+	return resps, nil
+}
+
 // ollamaStreamReader implements StreamReader for Ollama streaming
 type ollamaStreamReader struct {
 	scanner  *bufio.Scanner
 	resp     *http.Response
 	start    time.Time
 	backend  *OllamaBackend
+	model    string
+	log      *zap.Logger // carries the request ID
 
 	// Latency tracking
 	firstToken     bool
@@ -336,6 +632,10 @@ type ollamaStreamReader struct {
 
 // GenerateStream performs streaming text generation
 func (b *OllamaBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	if len(req.Tools) > 0 {
+		return b.generateChatStream(ctx, req)
+	}
+
 	// Build Ollama request
 	ollamaReq := map[string]interface{}{
 		"model":  req.Model,
@@ -361,6 +661,9 @@ func (b *OllamaBackend) GenerateStream(ctx context.Context, req *backends.Genera
 		if req.Options.TopK > 0 {
 			options["top_k"] = req.Options.TopK
 		}
+		if req.Options.Raw {
+			ollamaReq["raw"] = true
+		}
 	}
 
 	ollamaReq["options"] = options
@@ -396,6 +699,8 @@ func (b *OllamaBackend) GenerateStream(ctx context.Context, req *backends.Genera
 		resp:          resp,
 		start:         time.Now(),
 		backend:       b,
+		model:         req.Model,
+		log:           logging.FromContext(ctx),
 		firstToken:    true,
 		lastTokenTime: time.Now(),
 	}, nil
@@ -413,13 +718,23 @@ func (r *ollamaStreamReader) Recv() (*backends.StreamChunk, error) {
 	}
 
 	var chunk struct {
-		Response string `json:"response"`
-		Done     bool   `json:"done"`
+		Response     string `json:"response"`
+		Done         bool   `json:"done"`
+		LoadDuration int64  `json:"load_duration"` // nanoseconds, on the final chunk
+
+		// Set instead of Response on /api/chat streams
+		Message struct {
+			Content   string           `json:"content"`
+			ToolCalls []ollamaToolCall `json:"tool_calls"`
+		} `json:"message"`
 	}
 
 	if err := json.Unmarshal(r.scanner.Bytes(), &chunk); err != nil {
 		return nil, err
 	}
+	if chunk.Message.Content != "" {
+		chunk.Response = chunk.Message.Content
+	}
 
 	// Track TTFT (Time To First Token)
 	if r.firstToken && chunk.Response != "" {
@@ -429,7 +744,7 @@ func (r *ollamaStreamReader) Recv() (*backends.StreamChunk, error) {
 
 		// Log TTFT for voice quality monitoring
 		if logging.Logger != nil {
-			logging.Logger.Debug("Time to first token",
+			r.log.Debug("Time to first token",
 				zap.String("backend", r.backend.ID()),
 				zap.Int64("ttft_ms", ttft.Milliseconds()),
 			)
@@ -442,7 +757,7 @@ func (r *ollamaStreamReader) Recv() (*backends.StreamChunk, error) {
 		// Log if latency is unusually high (>100ms indicates issue)
 		if interTokenLatency.Milliseconds() > 100 {
 			if logging.Logger != nil {
-				logging.Logger.Warn("High inter-token latency",
+				r.log.Warn("High inter-token latency",
 					zap.String("backend", r.backend.ID()),
 					zap.Int("token", r.tokenCount),
 					zap.Int64("latency_ms", interTokenLatency.Milliseconds()),
@@ -456,6 +771,7 @@ func (r *ollamaStreamReader) Recv() (*backends.StreamChunk, error) {
 
 	var stats *backends.GenerationStats
 	if chunk.Done {
+		r.backend.observeLoad(r.model, time.Duration(chunk.LoadDuration))
 		elapsed := time.Since(r.start)
 		latencyMs := int32(elapsed.Milliseconds())
 		r.backend.UpdateMetrics(latencyMs, true)
@@ -479,7 +795,7 @@ func (r *ollamaStreamReader) Recv() (*backends.StreamChunk, error) {
 		}
 
 		if logging.Logger != nil {
-			logging.Logger.Info("Streaming summary",
+			r.log.Info("Streaming summary",
 				zap.String("backend", r.backend.ID()),
 				zap.Int64("ttft_ms", ttft.Milliseconds()),
 				zap.Int64("avg_inter_token_ms", avgInterToken.Milliseconds()),
@@ -491,9 +807,10 @@ func (r *ollamaStreamReader) Recv() (*backends.StreamChunk, error) {
 	}
 
 	return &backends.StreamChunk{
-		Token: chunk.Response,
-		Done:  chunk.Done,
-		Stats: stats,
+		Token:     chunk.Response,
+		Done:      chunk.Done,
+		Stats:     stats,
+		ToolCalls: convertToolCalls(chunk.Message.ToolCalls),
 	}, nil
 }
 
@@ -766,7 +1083,7 @@ func (b *OllamaBackend) TranscribeAudio(ctx context.Context, req *backends.Trans
 	}
 
 	if logging.Logger != nil {
-		logging.Logger.Info("Audio transcription completed (whisper.cpp)",
+		logging.FromContext(ctx).Info("Audio transcription completed (whisper.cpp)",
 			zap.String("backend", b.ID()),
 			zap.Int("audio_bytes", len(audioData)),
 			zap.Int64("latency_ms", elapsed.Milliseconds()),
@@ -949,7 +1266,7 @@ func (b *OllamaBackend) SynthesizeSpeech(ctx context.Context, req *backends.Synt
 	durationMs := int32(durationSeconds * 1000)
 
 	if logging.Logger != nil {
-		logging.Logger.Info("Speech synthesis completed (piper)",
+		logging.FromContext(ctx).Info("Speech synthesis completed (piper)",
 			zap.String("backend", b.ID()),
 			zap.Int("text_length", len(req.Text)),
 			zap.Int("audio_bytes", len(audioData)),
@@ -1156,7 +1473,11 @@ func (b *OllamaBackend) PullModel(ctx context.Context, modelName string) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := b.client.Do(req)
+	// Large models take far longer than the client timeout to download;
+	// the caller's context bounds the pull instead
+	pullClient := *b.client
+	pullClient.Timeout = 0
+	resp, err := pullClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to execute pull request: %w", err)
 	}
@@ -1167,37 +1488,39 @@ func (b *OllamaBackend) PullModel(ctx context.Context, modelName string) error {
 		return fmt.Errorf("pull request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// Read streaming progress
+	// Read streaming progress and forward it to progress subscribers
 	scanner := bufio.NewScanner(resp.Body)
+	pull := newPullProgress(b.id, modelName)
 	var lastProgress string
 	for scanner.Scan() {
-		line := scanner.Bytes()
-		var progress map[string]interface{}
-		if err := json.Unmarshal(line, &progress); err != nil {
+		var progress pullStatus
+		if err := json.Unmarshal(scanner.Bytes(), &progress); err != nil {
 			continue
 		}
-
-		// Log progress updates
-		if status, ok := progress["status"].(string); ok {
-			if status != lastProgress {
-				logging.Logger.Info("Model pull progress",
-					zap.String("backend", b.id),
-					zap.String("model", modelName),
-					zap.String("status", status),
-				)
-				lastProgress = status
-			}
+		if progress.Error != "" {
+			err := fmt.Errorf("pull failed: %s", progress.Error)
+			pull.finish(err)
+			return err
 		}
+		pull.update(progress, time.Now())
 
-		// Check for completion
-		if completed, ok := progress["completed"].(bool); ok && completed {
-			break
+		// Log progress updates
+		if progress.Status != lastProgress {
+			logging.Logger.Info("Model pull progress",
+				zap.String("backend", b.id),
+				zap.String("model", modelName),
+				zap.String("status", progress.Status),
+			)
+			lastProgress = progress.Status
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading pull stream: %w", err)
+		err = fmt.Errorf("error reading pull stream: %w", err)
+		pull.finish(err)
+		return err
 	}
+	pull.finish(nil)
 
 	logging.Logger.Info("Model pull completed",
 		zap.String("backend", b.id),
@@ -1207,6 +1530,134 @@ func (b *OllamaBackend) PullModel(ctx context.Context, modelName string) error {
 	return nil
 }
 
+// pullStatus is one line of Ollama's /api/pull stream
+type pullStatus struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
+}
+
+// pullProgress turns a pull stream into progress events, publishing only
+// when the status or the whole percentage changes
+type pullProgress struct {
+	last backends.Progress
+
+	// Download rate of the current layer, for the ETA
+	digest         string
+	digestStart    time.Time
+	digestBaseline int64
+}
+
+func newPullProgress(backendID, model string) *pullProgress {
+	p := &pullProgress{last: backends.Progress{Backend: backendID, Model: model, Phase: backends.ProgressPull}}
+	backends.DefaultProgress.Publish(p.last)
+	return p
+}
+
+func (p *pullProgress) update(s pullStatus, now time.Time) {
+	next := p.last
+	next.Time = now
+	next.Status = s.Status
+	next.Completed, next.Total, next.ETAMs = s.Completed, s.Total, 0
+	if s.Total > 0 {
+		next.Percent = float64(s.Completed) / float64(s.Total) * 100
+	}
+
+	if s.Digest != p.digest {
+		p.digest, p.digestStart, p.digestBaseline = s.Digest, now, s.Completed
+	} else if elapsed := now.Sub(p.digestStart); elapsed > 0 && s.Completed > p.digestBaseline && s.Total > 0 {
+		rate := float64(s.Completed-p.digestBaseline) / elapsed.Seconds()
+		next.ETAMs = int64(float64(s.Total-s.Completed) / rate * 1000)
+	}
+
+	if next.Status == p.last.Status && int(next.Percent) == int(p.last.Percent) {
+		p.last = next
+		return
+	}
+	p.last = next
+	backends.DefaultProgress.Publish(next)
+}
+
+func (p *pullProgress) finish(err error) {
+	done := p.last
+	done.Time, done.Done, done.ETAMs = time.Now(), true, 0
+	if err != nil {
+		done.Error = err.Error()
+	} else {
+		done.Percent = 100
+	}
+	backends.DefaultProgress.Publish(done)
+}
+
+// ManagesModels returns true; Ollama pulls and deletes models on request
+func (b *OllamaBackend) ManagesModels() bool {
+	return true
+}
+
+// InstalledModels lists the models downloaded to Ollama with their sizes
+func (b *OllamaBackend) InstalledModels(ctx context.Context) ([]backends.InstalledModel, error) {
+	var tags struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			Size       int64     `json:"size"`
+			Digest     string    `json:"digest"`
+			ModifiedAt time.Time `json:"modified_at"`
+		} `json:"models"`
+	}
+	if err := b.getJSON(ctx, "/api/tags", &tags); err != nil {
+		return nil, err
+	}
+
+	models := make([]backends.InstalledModel, len(tags.Models))
+	for i, m := range tags.Models {
+		models[i] = backends.InstalledModel{
+			Name:       m.Name,
+			SizeBytes:  m.Size,
+			Digest:     m.Digest,
+			ModifiedAt: m.ModifiedAt,
+		}
+	}
+	return models, nil
+}
+
+// DeleteModel removes a downloaded model from Ollama
+func (b *OllamaBackend) DeleteModel(ctx context.Context, modelName string) error {
+	body, err := json.Marshal(map[string]string{"model": modelName})
+	if err != nil {
+		return fmt.Errorf("failed to marshal delete request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", b.endpoint+"/api/delete", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute delete request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("delete request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	b.mu.Lock()
+	delete(b.loadTimes, normalizeModelName(modelName))
+	delete(b.resident, normalizeModelName(modelName))
+	b.mu.Unlock()
+
+	logging.Logger.Info("Model deleted",
+		zap.String("backend", b.id),
+		zap.String("model", modelName),
+	)
+	return nil
+}
+
 // EnsureModel checks if a model exists and pulls it if not
 func (b *OllamaBackend) EnsureModel(ctx context.Context, modelName string) error {
 	// Check if model exists
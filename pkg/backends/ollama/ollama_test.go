@@ -7,12 +7,26 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
 )
 
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	if err := logging.InitLogger("info", false); err != nil {
+		panic(err)
+	}
+	defer logging.Sync()
+
+	os.Exit(m.Run())
+}
+
 func TestNewOllamaBackend(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -434,6 +448,12 @@ func TestOllamaBackend_Generate(t *testing.T) {
 			"response": "Hello, world!",
 			"done":     true,
 		}
+		if req["logprobs"] == true {
+			response["logprobs"] = []map[string]interface{}{
+				{"token": "Hello", "logprob": -0.1},
+				{"token": ", world!", "logprob": -0.2},
+			}
+		}
 		json.NewEncoder(w).Encode(response)
 	}))
 	defer server.Close()
@@ -458,6 +478,18 @@ func TestOllamaBackend_Generate(t *testing.T) {
 	if resp.Response != "Hello, world!" {
 		t.Errorf("Expected response 'Hello, world!', got '%s'", resp.Response)
 	}
+	if resp.TokenLogprobs != nil {
+		t.Errorf("Expected no logprobs unless asked for, got %v", resp.TokenLogprobs)
+	}
+
+	req.Options = &backends.GenerationOptions{Logprobs: true}
+	resp, err = backend.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(resp.TokenLogprobs) != 2 || resp.TokenLogprobs[1] != -0.2 {
+		t.Errorf("Expected the token logprobs reported, got %v", resp.TokenLogprobs)
+	}
 }
 
 func TestOllamaBackend_GenerateStream(t *testing.T) {
@@ -653,6 +685,65 @@ func TestOllamaBackend_ListModels(t *testing.T) {
 	}
 }
 
+func TestOllamaBackend_ModelManagement(t *testing.T) {
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/tags":
+			w.Write([]byte(`{"models": [{"name": "llama3:8b", "size": 4661224676, "digest": "365c0bd3c000", "modified_at": "2026-05-01T10:00:00Z"}]}`))
+		case r.URL.Path == "/api/delete" && r.Method == "DELETE":
+			var body struct {
+				Model string `json:"model"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Model != "llama3:8b" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":"model not found"}`))
+				return
+			}
+			deleted = body.Model
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	backend, err := NewOllamaBackend(Config{
+		BackendConfig: backends.BackendConfig{ID: "test"},
+		Endpoint:      server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	ctx := context.Background()
+	if !backends.ManagesModels(backend) {
+		t.Fatal("Expected Ollama to manage models")
+	}
+	models, err := backends.InstalledModels(ctx, backend)
+	if err != nil {
+		t.Fatalf("InstalledModels failed: %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "llama3:8b" || models[0].SizeBytes != 4661224676 || models[0].ModifiedAt.IsZero() {
+		t.Errorf("Unexpected installed models %+v", models)
+	}
+	for model, want := range map[string]bool{"llama3:8b": true, "llama3": true, "llama3:70b": false, "qwen2": false} {
+		if has, err := backends.HasModel(ctx, backend, model); err != nil || has != want {
+			t.Errorf("HasModel(%s) = %v, %v; want %v", model, has, err, want)
+		}
+	}
+
+	if err := backends.DeleteModel(ctx, backend, "llama3:8b"); err != nil {
+		t.Fatalf("DeleteModel failed: %v", err)
+	}
+	if deleted != "llama3:8b" {
+		t.Errorf("Expected llama3:8b deleted, got %q", deleted)
+	}
+	if err := backend.DeleteModel(ctx, "missing:1b"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
 func TestOllamaBackend_ListModels_Error(t *testing.T) {
 	// Create test server that returns error
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1470,3 +1561,282 @@ func TestOllamaBackend_matchesPattern_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestOllamaBackend_ColdStart(t *testing.T) {
+	var psCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/ps":
+			psCalls.Add(1)
+			w.Write([]byte(`{"models":[{"name":"llama3:latest","model":"llama3:latest"}]}`))
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"llama3:latest","size":4000000000},{"name":"qwen2:72b","size":5242880000}]}`))
+		case "/api/generate":
+			w.Write([]byte(`{"response":"hi","done":true,"load_duration":7000000000}`))
+		}
+	}))
+	defer server.Close()
+
+	backend, _ := NewOllamaBackend(Config{
+		BackendConfig: backends.BackendConfig{ID: "test"},
+		Endpoint:      server.URL,
+	})
+	ctx := context.Background()
+
+	// Resident models are warm, with or without the default tag
+	if cs := backend.ColdStart(ctx, "llama3"); cs != nil {
+		t.Errorf("Expected llama3 to be warm, got %+v", cs)
+	}
+
+	// Unloaded models are estimated from their size
+	cs := backend.ColdStart(ctx, "qwen2:72b")
+	if cs == nil || cs.Reason != backends.ColdStartModelLoad {
+		t.Fatalf("Expected qwen2:72b to need loading, got %+v", cs)
+	}
+	if cs.Estimate != 10*time.Second {
+		t.Errorf("Expected a 10s estimate from size, got %v", cs.Estimate)
+	}
+
+	// The resident models are listed once for both lookups
+	if n := psCalls.Load(); n != 1 {
+		t.Errorf("Expected one /api/ps call, got %d", n)
+	}
+
+	// A request leaves its model resident
+	if _, err := backend.Generate(ctx, &backends.GenerateRequest{Model: "qwen2:72b", Prompt: "hi"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if cs := backend.ColdStart(ctx, "qwen2:72b"); cs != nil {
+		t.Errorf("Expected qwen2:72b to be warm after a request, got %+v", cs)
+	}
+
+	// Once evicted, the observed load replaces the size estimate
+	backend.residentTTL = 0
+	if cs := backend.ColdStart(ctx, "qwen2:72b"); cs == nil || cs.Estimate != 7*time.Second {
+		t.Errorf("Expected the observed 7s load, got %+v", cs)
+	}
+}
+
+func TestOllamaBackend_ColdStart_Unreachable(t *testing.T) {
+	backend, _ := NewOllamaBackend(Config{
+		BackendConfig: backends.BackendConfig{ID: "test"},
+		Endpoint:      "http://127.0.0.1:1",
+	})
+	if cs := backend.ColdStart(context.Background(), "llama3"); cs != nil {
+		t.Errorf("Expected no cold start when the backend cannot be asked, got %+v", cs)
+	}
+}
+
+func TestOllamaBackend_PullModel_Progress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"pulling manifest"}
+{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a07","total":1000,"completed":0}
+{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a07","total":1000,"completed":2}
+{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a07","total":1000,"completed":500}
+{"status":"success"}
+`))
+	}))
+	defer server.Close()
+
+	events, unsubscribe := backends.DefaultProgress.Subscribe(16)
+	defer unsubscribe()
+
+	backend, _ := NewOllamaBackend(Config{
+		BackendConfig: backends.BackendConfig{ID: "test"},
+		Endpoint:      server.URL,
+	})
+	if err := backend.PullModel(context.Background(), "llama3"); err != nil {
+		t.Fatalf("PullModel failed: %v", err)
+	}
+
+	var got []backends.Progress
+	for len(got) == 0 || !got[len(got)-1].Done {
+		select {
+		case p := <-events:
+			got = append(got, p)
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for progress, got %+v", got)
+		}
+	}
+
+	// Start, manifest, layer at 0%, 50%, success, done; 0.2% is below a whole step
+	if len(got) != 6 {
+		t.Fatalf("Expected 6 events, got %d: %+v", len(got), got)
+	}
+	half := got[3]
+	if half.Phase != backends.ProgressPull || half.Percent != 50 || half.Completed != 500 || half.Total != 1000 {
+		t.Errorf("Expected the layer at 50%%, got %+v", half)
+	}
+	if last := got[5]; last.Percent != 100 || last.Error != "" {
+		t.Errorf("Expected a successful final event, got %+v", last)
+	}
+	if active := backends.DefaultProgress.Active(); len(active) != 0 {
+		t.Errorf("Expected no pulls in flight, got %+v", active)
+	}
+}
+
+func TestPullProgress_ETA(t *testing.T) {
+	pull := newPullProgress("test", "llama3")
+	start := time.Now()
+	pull.update(pullStatus{Status: "pulling a", Digest: "a", Total: 1000, Completed: 100}, start)
+	pull.update(pullStatus{Status: "pulling a", Digest: "a", Total: 1000, Completed: 300}, start.Add(2*time.Second))
+
+	// 200 bytes in 2s leaves 700 bytes for 7s
+	if pull.last.Percent != 30 || pull.last.ETAMs != 7000 {
+		t.Errorf("Expected 30%% with 7s left, got %+v", pull.last)
+	}
+
+	// A new layer restarts the rate
+	pull.update(pullStatus{Status: "pulling b", Digest: "b", Total: 1000, Completed: 0}, start.Add(3*time.Second))
+	if pull.last.ETAMs != 0 {
+		t.Errorf("Expected no ETA at the start of a layer, got %+v", pull.last)
+	}
+	pull.finish(nil)
+}
+
+func TestOllamaBackend_PullModel_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"pulling manifest"}
+{"error":"pull model manifest: file does not exist"}
+`))
+	}))
+	defer server.Close()
+
+	events, unsubscribe := backends.DefaultProgress.Subscribe(16)
+	defer unsubscribe()
+
+	backend, _ := NewOllamaBackend(Config{
+		BackendConfig: backends.BackendConfig{ID: "test"},
+		Endpoint:      server.URL,
+	})
+	err := backend.PullModel(context.Background(), "nope")
+	if err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Fatalf("Expected the pull error, got %v", err)
+	}
+
+	for {
+		select {
+		case p := <-events:
+			if !p.Done {
+				continue
+			}
+			if !strings.Contains(p.Error, "file does not exist") {
+				t.Errorf("Expected the error on the final event, got %+v", p)
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the final event")
+		}
+	}
+}
+
+// sequenceServer simulates an inference server on one accelerator: each
+// request holds it for the prompt's prefill plus a decode phase in which
+// all of the request's sequences run as one batch. Requests for
+// /v1/completions get n choices, or a single choice with ignoreN; other
+// paths are served as /api/generate.
+func sequenceServer(tb testing.TB, prefill, decode time.Duration, ignoreN bool) *httptest.Server {
+	var accelerator sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			N int `json:"n"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.N < 1 || ignoreN {
+			req.N = 1
+		}
+
+		accelerator.Lock()
+		time.Sleep(prefill + decode)
+		accelerator.Unlock()
+
+		if r.URL.Path != "/v1/completions" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"response": "answer", "done": true})
+			return
+		}
+		choices := make([]map[string]interface{}, req.N)
+		for i := range choices {
+			choices[i] = map[string]interface{}{"text": fmt.Sprintf("answer %d", i)}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": choices,
+			"usage":   map[string]int{"completion_tokens": 10 * req.N},
+		})
+	}))
+	tb.Cleanup(server.Close)
+	return server
+}
+
+func newSequenceBackend(tb testing.TB, server *httptest.Server) *OllamaBackend {
+	backend, err := NewOllamaBackend(Config{
+		BackendConfig: backends.BackendConfig{ID: "test-id"},
+		Endpoint:      server.URL,
+	})
+	if err != nil {
+		tb.Fatalf("NewOllamaBackend failed: %v", err)
+	}
+	return backend
+}
+
+func TestOllamaBackend_GenerateSequences(t *testing.T) {
+	backend := newSequenceBackend(t, sequenceServer(t, 0, 0, false))
+	req := &backends.GenerateRequest{Model: "llama3", Prompt: "Hi"}
+
+	resps, err := backends.GenerateSequences(context.Background(), backend, req, 3, 3, nil)
+	if err != nil {
+		t.Fatalf("GenerateSequences failed: %v", err)
+	}
+	if len(resps) != 3 || resps[2].Response != "answer 2" {
+		t.Fatalf("Expected three choices from one request, got %d", len(resps))
+	}
+	if resps[0].Stats.TokensGenerated != 10 {
+		t.Errorf("Expected completion tokens split per choice (10), got %d", resps[0].Stats.TokensGenerated)
+	}
+	if !backend.SupportsSequences() {
+		t.Error("Expected parallel sequences to stay enabled")
+	}
+}
+
+func TestOllamaBackend_GenerateSequences_Unsupported(t *testing.T) {
+	backend := newSequenceBackend(t, sequenceServer(t, 0, 0, true))
+	req := &backends.GenerateRequest{Model: "llama3", Prompt: "Hi"}
+
+	resps, err := backends.GenerateSequences(context.Background(), backend, req, 3, 3, nil)
+	if err != nil {
+		t.Fatalf("GenerateSequences failed: %v", err)
+	}
+	if len(resps) != 3 || resps[0].Response != "answer 0" || resps[1].Response != "answer" {
+		t.Errorf("Expected the missing choices generated separately, got %d", len(resps))
+	}
+	if backend.SupportsSequences() {
+		t.Error("Expected parallel sequences disabled once the server ignored n")
+	}
+}
+
+// BenchmarkGenerateSequences compares n=4 as one request against four
+// separate requests on a simulated single-accelerator server, where the
+// separate requests each pay for the prompt's prefill and queue behind one
+// another
+func BenchmarkGenerateSequences(b *testing.B) {
+	const n = 4
+	req := &backends.GenerateRequest{Model: "llama3", Prompt: "Write a haiku"}
+
+	for _, bc := range []struct {
+		name   string
+		native bool
+	}{
+		{"native", true},
+		{"separate", false},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			backend := newSequenceBackend(b, sequenceServer(b, 2*time.Millisecond, time.Millisecond, false))
+			backend.noSequences.Store(!bc.native)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := backends.GenerateSequences(context.Background(), backend, req, n, n, nil); err != nil {
+					b.Fatalf("GenerateSequences failed: %v", err)
+				}
+			}
+		})
+	}
+}
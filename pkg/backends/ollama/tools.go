@@ -0,0 +1,235 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+)
+
+// SupportsToolCalling returns true: requests with tools go to /api/chat.
+// Models without tool support are refused there by Ollama.
+func (b *OllamaBackend) SupportsToolCalling() bool {
+	return true
+}
+
+// ollamaToolCall is a tool call on /api/chat, with the arguments as an
+// object rather than OpenAI's JSON string
+type ollamaToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// chatRequest builds an /api/chat request offering the model req.Tools
+func chatRequest(req *backends.GenerateRequest, stream bool) map[string]interface{} {
+	tools := make([]map[string]interface{}, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		// Ollama can't be made to call a tool; offering only the one
+		// chosen is the closest it gets
+		if req.ToolChoice != "" && req.ToolChoice != "required" && tool.Name != req.ToolChoice {
+			continue
+		}
+		params := tool.Parameters
+		if len(params) == 0 {
+			params = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		tools = append(tools, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  params,
+			},
+		})
+	}
+
+	messages := make([]map[string]interface{}, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		m := map[string]interface{}{
+			"role":    msg.Role,
+			"content": msg.Content,
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]map[string]interface{}, 0, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				args := json.RawMessage(call.Arguments)
+				if !json.Valid(args) {
+					args = json.RawMessage("{}")
+				}
+				calls = append(calls, map[string]interface{}{
+					"function": map[string]interface{}{"name": call.Name, "arguments": args},
+				})
+			}
+			m["tool_calls"] = calls
+		}
+		if msg.Role == "tool" && msg.Name != "" {
+			m["tool_name"] = msg.Name
+		}
+		messages = append(messages, m)
+	}
+
+	// Tool schemas and results don't fit the small voice context, so the
+	// model's own context length applies unless the request sets one
+	options := map[string]interface{}{"temperature": 0.7}
+	if req.Options != nil {
+		if req.Options.Temperature > 0 {
+			options["temperature"] = req.Options.Temperature
+		}
+		if req.Options.TopP > 0 {
+			options["top_p"] = req.Options.TopP
+		}
+		if req.Options.TopK > 0 {
+			options["top_k"] = req.Options.TopK
+		}
+		if req.Options.MaxTokens > 0 {
+			options["num_predict"] = req.Options.MaxTokens
+		}
+		if req.Options.ContextLength > 0 {
+			options["num_ctx"] = req.Options.ContextLength
+		}
+		if len(req.Options.Stop) > 0 {
+			options["stop"] = req.Options.Stop
+		}
+	}
+
+	return map[string]interface{}{
+		"model":    req.Model,
+		"messages": messages,
+		"tools":    tools,
+		"stream":   stream,
+		"options":  options,
+	}
+}
+
+// postChat sends an /api/chat request
+func (b *OllamaBackend) postChat(ctx context.Context, req *backends.GenerateRequest, stream bool) (*http.Response, error) {
+	body, err := json.Marshal(chatRequest(req, stream))
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", b.endpoint+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return b.client.Do(httpReq)
+}
+
+// generateChat answers a request with tools through /api/chat
+func (b *OllamaBackend) generateChat(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	start := time.Now()
+
+	resp, err := b.postChat(ctx, req, false)
+	if err != nil {
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var chatResp struct {
+		Message struct {
+			Content   string           `json:"content"`
+			ToolCalls []ollamaToolCall `json:"tool_calls"`
+		} `json:"message"`
+		EvalCount    int32 `json:"eval_count"`
+		LoadDuration int64 `json:"load_duration"` // nanoseconds
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, err
+	}
+	b.observeLoad(req.Model, time.Duration(chatResp.LoadDuration))
+
+	elapsed := time.Since(start)
+	latencyMs := int32(elapsed.Milliseconds())
+	b.UpdateMetrics(latencyMs, true)
+	energyWh := (b.powerWatts * elapsed.Seconds()) / 3600.0
+
+	return &backends.GenerateResponse{
+		Response:  chatResp.Message.Content,
+		ToolCalls: convertToolCalls(chatResp.Message.ToolCalls),
+		Stats: &backends.GenerationStats{
+			TotalTimeMs:     latencyMs,
+			TokensGenerated: chatResp.EvalCount,
+			TokensPerSecond: float32(chatResp.EvalCount) / float32(elapsed.Seconds()),
+			EnergyWh:        float32(energyWh),
+		},
+	}, nil
+}
+
+// generateChatStream streams a request with tools through /api/chat
+func (b *OllamaBackend) generateChatStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	resp, err := b.postChat(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("ollama error: status %d", resp.StatusCode)
+	}
+
+	// A chunk carrying tool calls holds all their arguments, so lines can
+	// be much longer than in token streams
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+
+	return &ollamaStreamReader{
+		scanner:       scanner,
+		resp:          resp,
+		start:         time.Now(),
+		backend:       b,
+		model:         req.Model,
+		log:           logging.FromContext(ctx),
+		firstToken:    true,
+		lastTokenTime: time.Now(),
+	}, nil
+}
+
+// convertToolCalls converts /api/chat tool calls, giving each an ID when
+// Ollama doesn't
+func convertToolCalls(calls []ollamaToolCall) []backends.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]backends.ToolCall, len(calls))
+	for i, call := range calls {
+		id := call.ID
+		if id == "" {
+			id = newToolCallID()
+		}
+		args := string(call.Function.Arguments)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		out[i] = backends.ToolCall{ID: id, Name: call.Function.Name, Arguments: args}
+	}
+	return out
+}
+
+func newToolCallID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
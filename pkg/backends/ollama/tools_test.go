@@ -0,0 +1,119 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func toolRequest() *backends.GenerateRequest {
+	return &backends.GenerateRequest{
+		Prompt: "User: Weather in Dublin?\nAssistant:",
+		Model:  "llama3.1",
+		Tools: []backends.ToolDefinition{
+			{Name: "get_weather", Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`)},
+			{Name: "get_time"},
+		},
+		Messages: []backends.ChatMessage{
+			{Role: "user", Content: "Weather in Cork?"},
+			{Role: "assistant", ToolCalls: []backends.ToolCall{{ID: "call_0", Name: "get_weather", Arguments: `{"city":"Cork"}`}}},
+			{Role: "tool", Content: "12C", ToolCallID: "call_0", Name: "get_weather"},
+			{Role: "user", Content: "And Dublin?"},
+		},
+	}
+}
+
+func TestOllamaBackend_GenerateWithTools(t *testing.T) {
+	var sent struct {
+		Messages []struct {
+			Role      string           `json:"role"`
+			ToolName  string           `json:"tool_name"`
+			ToolCalls []ollamaToolCall `json:"tool_calls"`
+		} `json:"messages"`
+		Tools []struct {
+			Function struct {
+				Name       string          `json:"name"`
+				Parameters json.RawMessage `json:"parameters"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected path '/api/chat', got '%s'", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Dublin"}}}]},"done":true,"eval_count":9}`))
+	}))
+	defer server.Close()
+
+	backend, _ := NewOllamaBackend(Config{BackendConfig: backends.BackendConfig{ID: "test"}, Endpoint: server.URL})
+	if !backends.SupportsToolCalling(backend) {
+		t.Fatal("Expected Ollama to support tool calling")
+	}
+
+	resp, err := backend.Generate(context.Background(), toolRequest())
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "get_weather" || resp.ToolCalls[0].Arguments != `{"city":"Dublin"}` {
+		t.Fatalf("Unexpected tool calls %+v", resp.ToolCalls)
+	}
+	if !strings.HasPrefix(resp.ToolCalls[0].ID, "call_") || resp.Stats.TokensGenerated != 9 {
+		t.Errorf("Expected a generated call ID and eval_count tokens, got %+v, %+v", resp.ToolCalls[0], resp.Stats)
+	}
+
+	if len(sent.Tools) != 2 || sent.Tools[1].Function.Name != "get_time" || !strings.Contains(string(sent.Tools[1].Function.Parameters), `"object"`) {
+		t.Errorf("Expected both tools sent, with an empty schema for get_time, got %+v", sent.Tools)
+	}
+	if len(sent.Messages) != 4 || sent.Messages[2].ToolName != "get_weather" || string(sent.Messages[1].ToolCalls[0].Function.Arguments) != `{"city":"Cork"}` {
+		t.Errorf("Expected the conversation with tool calls as objects, got %+v", sent.Messages)
+	}
+
+	// A chosen tool is the only one offered
+	req := toolRequest()
+	req.ToolChoice = "get_time"
+	if _, err := backend.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(sent.Tools) != 1 || sent.Tools[0].Function.Name != "get_time" {
+		t.Errorf("Expected only get_time offered, got %+v", sent.Tools)
+	}
+}
+
+func TestOllamaBackend_GenerateStreamWithTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":{"role":"assistant","content":"Checking"},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_9","function":{"name":"get_weather","arguments":{"city":"Dublin"}}}]},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":""},"done":true}` + "\n"))
+	}))
+	defer server.Close()
+
+	backend, _ := NewOllamaBackend(Config{BackendConfig: backends.BackendConfig{ID: "test"}, Endpoint: server.URL})
+	stream, err := backend.GenerateStream(context.Background(), toolRequest())
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	var text strings.Builder
+	var calls []backends.ToolCall
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		text.WriteString(chunk.Token)
+		calls = append(calls, chunk.ToolCalls...)
+		if chunk.Done {
+			break
+		}
+	}
+	if text.String() != "Checking" || len(calls) != 1 || calls[0].ID != "call_9" {
+		t.Errorf("Unexpected stream: %q, %+v", text.String(), calls)
+	}
+}
@@ -44,6 +44,9 @@ type OpenAIBackend struct {
 
 	// HTTP client
 	client *http.Client
+
+	// Set once the endpoint has ignored a request for several sequences
+	noSequences atomic.Bool
 }
 
 // Config for OpenAI backend
@@ -272,8 +275,22 @@ func (b *OpenAIBackend) GetPreferredModels() []string {
 	return b.modelCapability.PreferredModels
 }
 
-// Generate performs text generation via OpenAI API
-func (b *OpenAIBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+// chatResponse is the part of a /chat/completions response the backend uses
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// chatCompletion sends req to /chat/completions with extra parameters
+// added to the request body, updating the backend's metrics
+func (b *OpenAIBackend) chatCompletion(ctx context.Context, req *backends.GenerateRequest, extra map[string]interface{}) (*chatResponse, time.Duration, error) {
 	start := time.Now()
 
 	// Build OpenAI API request
@@ -295,15 +312,18 @@ func (b *OpenAIBackend) Generate(ctx context.Context, req *backends.GenerateRequ
 			openaiReq["max_tokens"] = req.Options.MaxTokens
 		}
 	}
+	for k, v := range extra {
+		openaiReq[k] = v
+	}
 
 	body, err := json.Marshal(openaiReq)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", b.endpoint+"/chat/completions", bytes.NewReader(body))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	httpReq.Header.Set("Authorization", "Bearer "+b.apiKey)
@@ -311,37 +331,40 @@ func (b *OpenAIBackend) Generate(ctx context.Context, req *backends.GenerateRequ
 
 	resp, err := b.client.Do(httpReq)
 	if err != nil {
-		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
-		return nil, err
+		if ctx.Err() != context.Canceled {
+			// A request the caller cancelled (e.g. a hedge that lost) is not a
+			// backend failure; a timeout still is
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API error: %d - %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var openaiResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-		Usage struct {
-			TotalTokens int `json:"total_tokens"`
-		} `json:"usage"`
+		return nil, 0, fmt.Errorf("OpenAI API error: %d - %s", resp.StatusCode, string(bodyBytes))
 	}
 
+	var openaiResp chatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&openaiResp); err != nil {
-		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
-		return nil, err
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, 0, err
 	}
 
 	elapsed := time.Since(start)
-	latencyMs := int32(elapsed.Milliseconds())
+	b.UpdateMetrics(int32(elapsed.Milliseconds()), true)
+	return &openaiResp, elapsed, nil
+}
 
-	b.UpdateMetrics(latencyMs, true)
+// Generate performs text generation via OpenAI API
+func (b *OpenAIBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	openaiResp, elapsed, err := b.chatCompletion(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	// Cloud services don't consume local power
 	energyWh := float32(0)
@@ -354,7 +377,7 @@ func (b *OpenAIBackend) Generate(ctx context.Context, req *backends.GenerateRequ
 	return &backends.GenerateResponse{
 		Response: response,
 		Stats: &backends.GenerationStats{
-			TotalTimeMs:     latencyMs,
+			TotalTimeMs:     int32(elapsed.Milliseconds()),
 			TokensGenerated: int32(openaiResp.Usage.TotalTokens),
 			TokensPerSecond: float32(openaiResp.Usage.TotalTokens) / float32(elapsed.Seconds()),
 			EnergyWh:        energyWh,
@@ -362,6 +385,52 @@ func (b *OpenAIBackend) Generate(ctx context.Context, req *backends.GenerateRequ
 	}, nil
 }
 
+// SupportsSequences is true until the endpoint answers a request for
+// several sequences with just one
+func (b *OpenAIBackend) SupportsSequences() bool {
+	return !b.noSequences.Load()
+}
+
+// GenerateSequences asks for n completions in one request, the best n of
+// bestOf when bestOf > n. OpenAI-compatible servers that ignore n reply
+// with a single choice, after which the backend stops offering it.
+func (b *OpenAIBackend) GenerateSequences(ctx context.Context, req *backends.GenerateRequest, n, bestOf int) ([]*backends.GenerateResponse, error) {
+	extra := map[string]interface{}{"n": n}
+	if bestOf > n {
+		extra["best_of"] = bestOf
+	}
+	openaiResp, elapsed, err := b.chatCompletion(ctx, req, extra)
+	if err != nil {
+		return nil, err
+	}
+	if n > 1 && len(openaiResp.Choices) < n {
+		b.noSequences.Store(true)
+	}
+	if len(openaiResp.Choices) == 0 {
+		return nil, nil
+	}
+
+	// Usage covers every choice; attribute it evenly
+	tokens := openaiResp.Usage.CompletionTokens
+	if tokens == 0 {
+		tokens = openaiResp.Usage.TotalTokens
+	}
+	perChoice := int32(tokens / len(openaiResp.Choices))
+
+	resps := make([]*backends.GenerateResponse, len(openaiResp.Choices))
+	for i, choice := range openaiResp.Choices {
+		resps[i] = &backends.GenerateResponse{
+			Response: choice.Message.Content,
+			Stats: &backends.GenerationStats{
+				TotalTimeMs:     int32(elapsed.Milliseconds()),
+				TokensGenerated: perChoice,
+				TokensPerSecond: float32(perChoice) / float32(elapsed.Seconds()),
+			},
+		}
+	}
+	return resps, nil
+}
+
 // GenerateStream performs streaming text generation (placeholder)
 func (b *OpenAIBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
 	// TODO: Implement streaming support
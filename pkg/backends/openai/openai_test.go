@@ -3,6 +3,7 @@ package openai
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -1515,3 +1516,57 @@ func TestOpenAIBackend_matchesPattern_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// sequenceServer answers /chat/completions with n choices, or a single
+// choice with ignoreN
+func sequenceServer(t *testing.T, ignoreN bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			N int `json:"n"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.N < 1 || ignoreN {
+			req.N = 1
+		}
+		choices := make([]map[string]interface{}, req.N)
+		for i := range choices {
+			choices[i] = map[string]interface{}{"message": map[string]string{"content": fmt.Sprintf("answer %d", i)}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": choices,
+			"usage":   map[string]int{"completion_tokens": 10 * req.N, "total_tokens": 15 * req.N},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAIBackend_GenerateSequences(t *testing.T) {
+	for _, ignoreN := range []bool{false, true} {
+		backend, _ := NewOpenAIBackend(Config{
+			BackendConfig: backends.BackendConfig{ID: "vllm", Name: "vLLM"},
+			APIKey:        "test-key",
+			Endpoint:      sequenceServer(t, ignoreN).URL,
+		})
+		if !backend.SupportsSequences() {
+			t.Fatal("Expected parallel sequences to be offered until the endpoint ignores n")
+		}
+
+		resps, err := backend.GenerateSequences(context.Background(), &backends.GenerateRequest{Model: "llama3", Prompt: "Hi"}, 3, 3)
+		if err != nil {
+			t.Fatalf("GenerateSequences failed: %v", err)
+		}
+		if ignoreN {
+			if len(resps) != 1 || backend.SupportsSequences() {
+				t.Errorf("Expected one choice and parallel sequences disabled, got %d", len(resps))
+			}
+			continue
+		}
+		if len(resps) != 3 || resps[2].Response != "answer 2" || !backend.SupportsSequences() {
+			t.Fatalf("Expected three choices, got %d", len(resps))
+		}
+		if resps[0].Stats.TokensGenerated != 10 {
+			t.Errorf("Expected completion tokens split per choice (10), got %d", resps[0].Stats.TokensGenerated)
+		}
+	}
+}
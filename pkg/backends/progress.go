@@ -0,0 +1,266 @@
+package backends
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Progress phases
+const (
+	ProgressPull  = "pull"  // downloading a model
+	ProgressLoad  = "load"  // loading a model into memory
+	ProgressStart = "start" // starting an on-demand backend
+)
+
+// ProgressInterval is how often WatchLoad reports an estimated load
+var ProgressInterval = time.Second
+
+// Progress is a model pull or load progress event
+type Progress struct {
+	Time    time.Time `json:"time"`
+	Backend string    `json:"backend"`
+	Model   string    `json:"model"`
+	Phase   string    `json:"phase"`
+	Status  string    `json:"status,omitempty"` // backend status, e.g. "pulling 6a0746a1ec1a"
+
+	Completed int64   `json:"completed,omitempty"` // bytes, for pulls
+	Total     int64   `json:"total,omitempty"`
+	Percent   float64 `json:"percent"`
+	ETAMs     int64   `json:"eta_ms,omitempty"` // 0 if unknown
+
+	// Estimated is set when Percent and ETAMs are extrapolated from an
+	// earlier load rather than reported by the backend
+	Estimated bool `json:"estimated,omitempty"`
+
+	Done  bool   `json:"done,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Message is a short human-readable status, e.g. "loading model llama3:8b: 40%, ~6s left"
+func (p *Progress) Message() string {
+	var msg string
+	switch p.Phase {
+	case ProgressPull:
+		msg = "pulling model " + p.Model
+	case ProgressStart:
+		msg = "starting backend for " + p.Model
+	default:
+		msg = "loading model " + p.Model
+	}
+	switch {
+	case p.Error != "":
+		return msg + ": " + p.Error
+	case p.Done:
+		return msg + ": done"
+	}
+	if p.Status != "" && p.Phase == ProgressPull {
+		msg += ": " + p.Status
+	}
+	msg += fmt.Sprintf(": %.0f%%", p.Percent)
+	if p.ETAMs > 0 {
+		msg += fmt.Sprintf(", ~%ds left", (p.ETAMs+999)/1000)
+	}
+	return msg
+}
+
+// ProgressBroker fans progress events out to subscribers such as the admin
+// event stream, and remembers the operations still in flight so late
+// subscribers see them
+type ProgressBroker struct {
+	mu     sync.Mutex
+	subs   map[chan Progress]struct{}
+	active map[string]Progress // backend/model/phase -> latest event
+}
+
+// NewProgressBroker creates an empty broker
+func NewProgressBroker() *ProgressBroker {
+	return &ProgressBroker{
+		subs:   make(map[chan Progress]struct{}),
+		active: make(map[string]Progress),
+	}
+}
+
+// DefaultProgress is the process-wide broker backends report to
+var DefaultProgress = NewProgressBroker()
+
+// Publish sends an event to every subscriber. Subscribers that are not
+// keeping up miss events rather than stalling the backend.
+func (b *ProgressBroker) Publish(p Progress) {
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := p.Backend + "/" + p.Model + "/" + p.Phase
+	if p.Done {
+		delete(b.active, id)
+	} else {
+		b.active[id] = p
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of events and a function that unsubscribes
+// and closes it
+func (b *ProgressBroker) Subscribe(buffer int) (<-chan Progress, func()) {
+	ch := make(chan Progress, buffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Active returns the latest event of each operation still in flight,
+// oldest first
+func (b *ProgressBroker) Active() []Progress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	active := make([]Progress, 0, len(b.active))
+	for _, p := range b.active {
+		active = append(active, p)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Time.Before(active[j].Time) })
+	return active
+}
+
+// heartbeatInterval keeps idle event streams open through proxies
+const heartbeatInterval = 15 * time.Second
+
+// Handler serves progress events as a server-sent event stream, starting
+// with the operations already in flight. ?backend= and ?model= filter it.
+func (b *ProgressBroker) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		backend, model := r.URL.Query().Get("backend"), r.URL.Query().Get("model")
+
+		events, unsubscribe := b.Subscribe(64)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		send := func(p Progress) {
+			if (backend != "" && p.Backend != backend) || (model != "" && p.Model != model) {
+				return
+			}
+			data, _ := json.Marshal(p)
+			fmt.Fprintf(w, "event: model_progress\ndata: %s\n\n", data)
+		}
+		for _, p := range b.Active() {
+			send(p)
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case p := <-events:
+				send(p)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// WatchLoad reports the progress of a cold start while the caller waits
+// for the backend. Backends cannot report load progress, so percent and
+// ETA are extrapolated from cs.Estimate (0% throughout when unknown).
+// Every ProgressInterval an event is published to DefaultProgress and
+// passed to report, which may be nil. The returned function ends the
+// watch with the outcome of the wait; report is not called after it
+// returns, so report may write to the client's stream.
+func WatchLoad(backendID string, cs *ColdStart, report func(Progress)) func(error) {
+	phase := ProgressLoad
+	if cs.Reason == ColdStartContainerStart {
+		phase = ProgressStart
+	}
+	start := time.Now()
+	progress := func(now time.Time) Progress {
+		p := Progress{Time: now, Backend: backendID, Model: cs.Model, Phase: phase}
+		if cs.Estimate > 0 {
+			elapsed := now.Sub(start)
+			p.Estimated = true
+			p.Percent = float64(elapsed) / float64(cs.Estimate) * 100
+			if p.Percent > 99 {
+				p.Percent = 99 // overran the estimate
+			}
+			if eta := cs.Estimate - elapsed; eta > 0 {
+				p.ETAMs = eta.Milliseconds()
+			}
+		}
+		return p
+	}
+	DefaultProgress.Publish(progress(start))
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				p := progress(now)
+				DefaultProgress.Publish(p)
+				if report != nil {
+					report(p)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			close(stop)
+			<-stopped
+
+			p := Progress{Backend: backendID, Model: cs.Model, Phase: phase, Percent: 100, Done: true}
+			if err != nil {
+				p.Percent = 0
+				p.Error = err.Error()
+			}
+			DefaultProgress.Publish(p)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package backends
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchLoad(t *testing.T) {
+	defer func(interval time.Duration) { ProgressInterval = interval }(ProgressInterval)
+	ProgressInterval = 10 * time.Millisecond
+
+	events, unsubscribe := DefaultProgress.Subscribe(64)
+	defer unsubscribe()
+
+	var mu sync.Mutex
+	var reported []Progress
+	cs := &ColdStart{Reason: ColdStartModelLoad, Model: "llama3", Estimate: time.Second}
+	stop := WatchLoad("npu", cs, func(p Progress) {
+		mu.Lock()
+		reported = append(reported, p)
+		mu.Unlock()
+	})
+	time.Sleep(55 * time.Millisecond)
+	stop(nil)
+
+	mu.Lock()
+	n := len(reported)
+	mu.Unlock()
+	if n < 2 {
+		t.Fatalf("Expected progress while loading, got %d events", n)
+	}
+	p := reported[n-1]
+	if p.Phase != ProgressLoad || !p.Estimated || p.Percent <= 0 || p.Percent >= 99 || p.ETAMs <= 0 || p.ETAMs >= 1000 {
+		t.Errorf("Expected an estimated load in progress, got %+v", p)
+	}
+
+	// Nothing is reported once the wait is over
+	time.Sleep(30 * time.Millisecond)
+	mu.Lock()
+	if len(reported) != n {
+		t.Errorf("Expected no reports after stop, got %d more", len(reported)-n)
+	}
+	mu.Unlock()
+
+	// Subscribers see the start and the completion
+	var last Progress
+	for len(events) > 0 {
+		last = <-events
+	}
+	if !last.Done || last.Percent != 100 {
+		t.Errorf("Expected a completed load, got %+v", last)
+	}
+}
+
+func TestWatchLoad_Failed(t *testing.T) {
+	broker := DefaultProgress
+	events, unsubscribe := broker.Subscribe(8)
+	defer unsubscribe()
+
+	stop := WatchLoad("npu", &ColdStart{Reason: ColdStartContainerStart, Model: "llama3"}, nil)
+	if active := broker.Active(); len(active) != 1 || active[0].Phase != ProgressStart {
+		t.Fatalf("Expected the start in flight, got %+v", active)
+	}
+	stop(errors.New("container exited"))
+
+	var last Progress
+	for len(events) > 0 {
+		last = <-events
+	}
+	if !last.Done || last.Error != "container exited" {
+		t.Errorf("Expected a failed start, got %+v", last)
+	}
+	if active := broker.Active(); len(active) != 0 {
+		t.Errorf("Expected nothing in flight, got %+v", active)
+	}
+}
+
+func TestProgress_Message(t *testing.T) {
+	tests := []struct {
+		p    Progress
+		want string
+	}{
+		{Progress{Model: "llama3", Phase: ProgressLoad, Percent: 41.6, ETAMs: 6200}, "loading model llama3: 42%, ~7s left"},
+		{Progress{Model: "llama3", Phase: ProgressPull, Status: "pulling 6a07", Percent: 10}, "pulling model llama3: pulling 6a07: 10%"},
+		{Progress{Model: "llama3", Phase: ProgressStart, Done: true}, "starting backend for llama3: done"},
+		{Progress{Model: "llama3", Phase: ProgressPull, Done: true, Error: "not found"}, "pulling model llama3: not found"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Message(); got != tt.want {
+			t.Errorf("Message() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestProgressBroker_Handler(t *testing.T) {
+	broker := NewProgressBroker()
+	broker.Publish(Progress{Backend: "npu", Model: "llama3", Phase: ProgressPull, Percent: 20})
+	broker.Publish(Progress{Backend: "npu", Model: "qwen2", Phase: ProgressLoad})
+
+	server := httptest.NewServer(broker.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?model=llama3")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "data: ") {
+				lines <- scanner.Text()
+			}
+		}
+	}()
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for an event")
+			return ""
+		}
+	}
+
+	// The pull already in flight comes first, the other model is filtered
+	if line := next(); !strings.Contains(line, `"model":"llama3"`) || !strings.Contains(line, `"percent":20`) {
+		t.Errorf("Expected the active pull, got %s", line)
+	}
+	broker.Publish(Progress{Backend: "npu", Model: "qwen2", Phase: ProgressLoad, Done: true})
+	broker.Publish(Progress{Backend: "npu", Model: "llama3", Phase: ProgressPull, Percent: 100, Done: true})
+	if line := next(); !strings.Contains(line, `"done":true`) || !strings.Contains(line, `"model":"llama3"`) {
+		t.Errorf("Expected the finished pull, got %s", line)
+	}
+}
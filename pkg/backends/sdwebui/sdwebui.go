@@ -0,0 +1,845 @@
+// Package sdwebui implements an image generation backend for the Stable
+// Diffusion WebUI API (AUTOMATIC1111 and compatible forks such as Forge
+// and SD.Next), serving text-to-image and image edits
+package sdwebui
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// Defaults for requests that leave them unset
+const (
+	DefaultSteps             = 20
+	DefaultCFGScale          = 7.0
+	DefaultDenoisingStrength = 0.75 // edits keep a quarter of the source image
+)
+
+// DefaultProgressInterval is how often streaming generation polls progress
+const DefaultProgressInterval = 500 * time.Millisecond
+
+// SDWebUIBackend implements Backend for a Stable Diffusion WebUI server
+type SDWebUIBackend struct {
+	mu sync.RWMutex
+
+	// Config
+	id       string
+	name     string
+	hardware string
+	endpoint string
+	auth     string // user:password for servers started with --api-auth
+
+	// Characteristics
+	powerWatts   float64
+	avgLatencyMs int32
+	priority     int
+
+	// Model capabilities
+	modelCapability *backends.ModelCapability
+
+	// Health
+	healthy      atomic.Bool
+	lastCheck    time.Time
+	checkTimeout time.Duration
+
+	// Streaming
+	progressInterval time.Duration
+
+	// Metrics
+	metrics *backends.BackendMetrics
+
+	// HTTP client
+	client *http.Client
+}
+
+// Config for Stable Diffusion WebUI backend
+type Config struct {
+	backends.BackendConfig
+	Endpoint         string        // server root, e.g. http://localhost:7860
+	Auth             string        // optional user:password, for --api-auth
+	AuthEnv          string        // or the env var holding it
+	ProgressInterval time.Duration // streaming progress poll interval
+}
+
+// NewSDWebUIBackend creates a new Stable Diffusion WebUI backend instance
+func NewSDWebUIBackend(cfg Config) (*SDWebUIBackend, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+
+	auth := cfg.Auth
+	if cfg.AuthEnv != "" {
+		auth = os.Getenv(cfg.AuthEnv)
+		if auth == "" {
+			return nil, fmt.Errorf("API auth env var %s is empty", cfg.AuthEnv)
+		}
+	}
+	if auth != "" && !strings.Contains(auth, ":") {
+		return nil, fmt.Errorf("API auth must be user:password")
+	}
+
+	interval := cfg.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	backend := &SDWebUIBackend{
+		id:               cfg.ID,
+		name:             cfg.Name,
+		hardware:         cfg.Hardware,
+		endpoint:         strings.TrimSuffix(cfg.Endpoint, "/"),
+		auth:             auth,
+		powerWatts:       cfg.PowerWatts,
+		avgLatencyMs:     cfg.AvgLatencyMs,
+		priority:         cfg.Priority,
+		modelCapability:  cfg.ModelCapability,
+		checkTimeout:     5 * time.Second,
+		progressInterval: interval,
+		metrics: &backends.BackendMetrics{
+			LoadedModels: []string{},
+		},
+		client: &http.Client{
+			Timeout: 600 * time.Second, // large batches and high step counts
+			Transport: &http.Transport{
+				MaxIdleConns:        10,
+				MaxIdleConnsPerHost: 4,
+				IdleConnTimeout:     90 * time.Second,
+				DialContext: (&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+			},
+		},
+	}
+	backend.client.Transport = middleware.RequestIDTransport(backend.client.Transport)
+
+	backend.healthy.Store(false) // Will be set by health check
+	return backend, nil
+}
+
+// ID returns backend identifier
+func (b *SDWebUIBackend) ID() string {
+	return b.id
+}
+
+// Type returns backend type
+func (b *SDWebUIBackend) Type() string {
+	return "sdwebui"
+}
+
+// Name returns human-readable name
+func (b *SDWebUIBackend) Name() string {
+	return b.name
+}
+
+// Hardware returns hardware type
+func (b *SDWebUIBackend) Hardware() string {
+	return b.hardware
+}
+
+// IsHealthy returns current health status
+func (b *SDWebUIBackend) IsHealthy() bool {
+	return b.healthy.Load()
+}
+
+// newRequest builds a request to the server, authenticated when auth is set
+func (b *SDWebUIBackend) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.endpoint+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if user, password, ok := strings.Cut(b.auth, ":"); ok {
+		req.SetBasicAuth(user, password)
+	}
+	return req, nil
+}
+
+// do sends a request and decodes a JSON response into out
+func (b *SDWebUIBackend) do(ctx context.Context, method, path string, body, out interface{}) error {
+	req, err := b.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("sdwebui error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// HealthCheck probes the progress endpoint, which answers even while a
+// generation is running
+func (b *SDWebUIBackend) HealthCheck(ctx context.Context) error {
+	checkCtx, cancel := context.WithTimeout(ctx, b.checkTimeout)
+	defer cancel()
+
+	if err := b.do(checkCtx, "GET", "/sdapi/v1/progress?skip_current_image=true", nil, nil); err != nil {
+		b.healthy.Store(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	b.healthy.Store(true)
+	b.mu.Lock()
+	b.lastCheck = time.Now()
+	b.mu.Unlock()
+
+	return nil
+}
+
+// PowerWatts returns estimated power consumption
+func (b *SDWebUIBackend) PowerWatts() float64 {
+	return b.powerWatts
+}
+
+// AvgLatencyMs returns average latency
+func (b *SDWebUIBackend) AvgLatencyMs() int32 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.metrics.RequestCount > 0 {
+		return b.metrics.AvgLatencyMs
+	}
+	return b.avgLatencyMs // Return configured estimate
+}
+
+// Priority returns backend priority
+func (b *SDWebUIBackend) Priority() int {
+	return b.priority
+}
+
+// SupportsGenerate returns false; the server only generates images
+func (b *SDWebUIBackend) SupportsGenerate() bool {
+	return false
+}
+
+// SupportsStream returns false
+func (b *SDWebUIBackend) SupportsStream() bool {
+	return false
+}
+
+// SupportsEmbed returns false
+func (b *SDWebUIBackend) SupportsEmbed() bool {
+	return false
+}
+
+// ListModels returns the server's checkpoints
+func (b *SDWebUIBackend) ListModels(ctx context.Context) ([]string, error) {
+	var checkpoints []struct {
+		Title     string `json:"title"`
+		ModelName string `json:"model_name"`
+	}
+	if err := b.do(ctx, "GET", "/sdapi/v1/sd-models", nil, &checkpoints); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, len(checkpoints))
+	for i, c := range checkpoints {
+		models[i] = c.ModelName
+	}
+
+	b.mu.Lock()
+	b.metrics.LoadedModels = models
+	b.mu.Unlock()
+
+	return models, nil
+}
+
+// imageRequest builds a txt2img or, with an init image, img2img request
+// body and returns it with its path
+func imageRequest(req *backends.ImageGenRequest) (string, map[string]interface{}, error) {
+	steps := req.Steps
+	if steps <= 0 {
+		steps = DefaultSteps
+	}
+	cfgScale := float64(req.GuidanceScale)
+	if cfgScale <= 0 {
+		cfgScale = DefaultCFGScale
+	}
+	batch := req.BatchSize
+	if batch <= 0 {
+		batch = 1
+	}
+	seed := req.Seed
+	if seed == 0 {
+		seed = -1 // random
+	}
+
+	body := map[string]interface{}{
+		"prompt":          req.Prompt,
+		"negative_prompt": req.NegativePrompt,
+		"steps":           steps,
+		"cfg_scale":       cfgScale,
+		"seed":            seed,
+		"batch_size":      batch,
+	}
+	if req.Width > 0 {
+		body["width"] = req.Width
+	}
+	if req.Height > 0 {
+		body["height"] = req.Height
+	}
+	if req.Model != "" {
+		// Switch checkpoint for this request and keep it loaded for the next
+		body["override_settings"] = map[string]string{"sd_model_checkpoint": req.Model}
+		body["override_settings_restore_afterwards"] = false
+	}
+	if sampler := req.Options["sampler"]; sampler != "" {
+		body["sampler_name"] = sampler
+	}
+
+	if req.InitImage == nil {
+		return "/sdapi/v1/txt2img", body, nil
+	}
+
+	// Strength takes precedence over the denoising_strength option
+	strength := DefaultDenoisingStrength
+	if req.Strength > 1 || req.Strength < 0 {
+		return "", nil, fmt.Errorf("invalid strength %v (0-1)", req.Strength)
+	} else if req.Strength > 0 {
+		strength = float64(req.Strength)
+	} else if s := req.Options["denoising_strength"]; s != "" {
+		parsed, err := strconv.ParseFloat(s, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return "", nil, fmt.Errorf("invalid denoising_strength %q (0-1)", s)
+		}
+		strength = parsed
+	}
+	body["init_images"] = []string{base64.StdEncoding.EncodeToString(req.InitImage)}
+	body["denoising_strength"] = strength
+	if req.Mask != nil {
+		body["mask"] = base64.StdEncoding.EncodeToString(req.Mask)
+		// Transparent mask areas are regenerated, as with OpenAI edits
+		body["inpainting_mask_invert"] = 1
+	}
+	return "/sdapi/v1/img2img", body, nil
+}
+
+// imageResponse is a txt2img/img2img response
+type imageResponse struct {
+	Images []string `json:"images"` // base64
+	Info   string   `json:"info"`   // JSON-encoded generation parameters
+}
+
+// GenerateImage performs text-to-image generation, or an edit when the
+// request has an init image
+func (b *SDWebUIBackend) GenerateImage(ctx context.Context, req *backends.ImageGenRequest) (*backends.ImageGenResponse, error) {
+	path, body, err := imageRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	var result imageResponse
+	if err := b.do(ctx, "POST", path, body, &result); err != nil {
+		if ctx.Err() != context.Canceled {
+			// A request the caller cancelled is not a backend failure
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, err
+	}
+	elapsed := time.Since(start)
+
+	images, err := decodeImages(result, req)
+	if err != nil {
+		b.UpdateMetrics(int32(elapsed.Milliseconds()), false)
+		return nil, err
+	}
+	b.UpdateMetrics(int32(elapsed.Milliseconds()), true)
+
+	return &backends.ImageGenResponse{
+		Images: images,
+		Stats: &backends.GenerationStats{
+			TotalTimeMs: int32(elapsed.Milliseconds()),
+			EnergyWh:    float32(b.powerWatts * elapsed.Seconds() / 3600.0),
+		},
+	}, nil
+}
+
+// decodeImages decodes the generated images with the seeds that made them
+func decodeImages(result imageResponse, req *backends.ImageGenRequest) ([]backends.GeneratedImage, error) {
+	if len(result.Images) == 0 {
+		return nil, fmt.Errorf("sdwebui returned no images")
+	}
+
+	var info struct {
+		AllSeeds []int64 `json:"all_seeds"`
+		Width    int32   `json:"width"`
+		Height   int32   `json:"height"`
+	}
+	if result.Info != "" {
+		json.Unmarshal([]byte(result.Info), &info) // best effort
+	}
+
+	// Servers may append extra images such as a grid or the mask; keep
+	// the batch only
+	count := len(result.Images)
+	if req.BatchSize > 0 && count > int(req.BatchSize) {
+		count = int(req.BatchSize)
+	}
+
+	images := make([]backends.GeneratedImage, count)
+	for i := 0; i < count; i++ {
+		data, err := base64.StdEncoding.DecodeString(stripDataURL(result.Images[i]))
+		if err != nil {
+			return nil, fmt.Errorf("sdwebui returned an invalid image: %w", err)
+		}
+		images[i] = backends.GeneratedImage{
+			ImageData: data,
+			Format:    detectFormat(data),
+			Width:     req.Width,
+			Height:    req.Height,
+		}
+		if info.Width > 0 {
+			images[i].Width, images[i].Height = info.Width, info.Height
+		}
+		if i < len(info.AllSeeds) {
+			images[i].Seed = info.AllSeeds[i]
+		}
+	}
+	return images, nil
+}
+
+// stripDataURL removes a "data:image/png;base64," prefix some forks add
+func stripDataURL(s string) string {
+	if strings.HasPrefix(s, "data:") {
+		if _, data, ok := strings.Cut(s, ","); ok {
+			return data
+		}
+	}
+	return s
+}
+
+// detectFormat identifies the encoding of image data. The server encodes
+// images in its configured samples format, whatever the request asked for.
+func detectFormat(data []byte) backends.ImageFormat {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return backends.ImageFormatJPEG
+	case "image/webp":
+		return backends.ImageFormatWEBP
+	}
+	return backends.ImageFormatPNG
+}
+
+// progressResponse is the server's progress of the running generation
+type progressResponse struct {
+	Progress float32 `json:"progress"` // 0-1
+	State    struct {
+		SamplingStep  int32 `json:"sampling_step"`
+		SamplingSteps int32 `json:"sampling_steps"`
+	} `json:"state"`
+	CurrentImage string `json:"current_image"` // base64 preview, when live previews are on
+}
+
+// GenerateImageStream generates images, streaming progress (with a preview
+// image when the server's live previews are enabled) until the final chunk
+// carries the first generated image. The server runs one generation at a
+// time, so progress describes this request once it starts.
+func (b *SDWebUIBackend) GenerateImageStream(ctx context.Context, req *backends.ImageGenRequest) (backends.ImageStreamReader, error) {
+	if _, _, err := imageRequest(req); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &imageStreamReader{
+		backend: b,
+		ctx:     ctx,
+		cancel:  cancel,
+		result:  make(chan imageResult, 1),
+		ticker:  time.NewTicker(b.progressInterval),
+	}
+	go func() {
+		resp, err := b.GenerateImage(ctx, req)
+		r.result <- imageResult{resp: resp, err: err}
+	}()
+	return r, nil
+}
+
+type imageResult struct {
+	resp *backends.ImageGenResponse
+	err  error
+}
+
+// imageStreamReader polls a generation's progress until it finishes
+type imageStreamReader struct {
+	backend *SDWebUIBackend
+	ctx     context.Context
+	cancel  context.CancelFunc
+	result  chan imageResult
+	ticker  *time.Ticker
+
+	lastStep int32
+	done     bool
+}
+
+// Recv returns the next progress update or the final image
+func (r *imageStreamReader) Recv() (*backends.ImageChunk, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	for {
+		select {
+		case res := <-r.result:
+			r.done = true
+			if res.err != nil {
+				return nil, res.err
+			}
+			return &backends.ImageChunk{
+				ImageData:   res.resp.Images[0].ImageData,
+				Progress:    1,
+				CurrentStep: r.lastStep,
+				TotalSteps:  r.lastStep,
+				Done:        true,
+			}, nil
+
+		case <-r.ticker.C:
+			var p progressResponse
+			if err := r.backend.do(r.ctx, "GET", "/sdapi/v1/progress", nil, &p); err != nil {
+				continue // the generation request reports real failures
+			}
+			if p.State.SamplingStep == 0 || p.State.SamplingStep == r.lastStep {
+				continue // not started, or no new step
+			}
+			r.lastStep = p.State.SamplingStep
+
+			chunk := &backends.ImageChunk{
+				Progress:    p.Progress,
+				CurrentStep: p.State.SamplingStep,
+				TotalSteps:  p.State.SamplingSteps,
+			}
+			if p.CurrentImage != "" {
+				chunk.ImageData, _ = base64.StdEncoding.DecodeString(stripDataURL(p.CurrentImage))
+			}
+			return chunk, nil
+
+		case <-r.ctx.Done():
+			r.done = true
+			return nil, r.ctx.Err()
+		}
+	}
+}
+
+// Close stops the stream, interrupting the generation if it is unfinished
+func (r *imageStreamReader) Close() error {
+	r.ticker.Stop()
+	r.cancel()
+	if r.done {
+		return nil
+	}
+	r.done = true
+
+	// Cancelling the request does not stop the server's sampler
+	ctx, cancel := context.WithTimeout(context.Background(), r.backend.checkTimeout)
+	defer cancel()
+	if err := r.backend.do(ctx, "POST", "/sdapi/v1/interrupt", nil, nil); err != nil && logging.Logger != nil {
+		logging.Logger.Warn("Failed to interrupt image generation",
+			zap.String("backend", r.backend.ID()),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// UpdateMetrics updates backend metrics
+func (b *SDWebUIBackend) UpdateMetrics(latencyMs int32, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	atomic.AddInt64(&b.metrics.RequestCount, 1)
+
+	if success {
+		atomic.AddInt64(&b.metrics.SuccessCount, 1)
+		atomic.AddInt64(&b.metrics.TotalLatencyMs, int64(latencyMs))
+
+		// Update rolling average
+		if b.metrics.RequestCount > 0 {
+			b.metrics.AvgLatencyMs = int32(b.metrics.TotalLatencyMs / b.metrics.RequestCount)
+		}
+	} else {
+		atomic.AddInt64(&b.metrics.ErrorCount, 1)
+	}
+
+	// Calculate error rate
+	if b.metrics.RequestCount > 0 {
+		b.metrics.ErrorRate = float32(b.metrics.ErrorCount) / float32(b.metrics.RequestCount)
+	}
+}
+
+// GetMetrics returns current metrics
+func (b *SDWebUIBackend) GetMetrics() *backends.BackendMetrics {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Return copy
+	return &backends.BackendMetrics{
+		RequestCount:   b.metrics.RequestCount,
+		SuccessCount:   b.metrics.SuccessCount,
+		ErrorCount:     b.metrics.ErrorCount,
+		TotalLatencyMs: b.metrics.TotalLatencyMs,
+		AvgLatencyMs:   b.metrics.AvgLatencyMs,
+		ErrorRate:      b.metrics.ErrorRate,
+		LoadedModels:   b.metrics.LoadedModels,
+	}
+}
+
+// Start performs an initial health check and learns the checkpoints
+func (b *SDWebUIBackend) Start(ctx context.Context) error {
+	if err := b.HealthCheck(ctx); err != nil {
+		return err
+	}
+	if _, err := b.ListModels(ctx); err != nil && logging.Logger != nil {
+		logging.Logger.Warn("Failed to list Stable Diffusion checkpoints",
+			zap.String("backend_id", b.id),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// Stop shuts down the backend
+func (b *SDWebUIBackend) Stop(ctx context.Context) error {
+	// Nothing to clean up for HTTP client
+	return nil
+}
+
+// SupportsModel checks if this backend can run the specified checkpoint.
+// Once the server's checkpoints are known they are the limit.
+func (b *SDWebUIBackend) SupportsModel(modelName string) bool {
+	b.mu.RLock()
+	served := b.metrics.LoadedModels
+	b.mu.RUnlock()
+	if len(served) > 0 {
+		found := false
+		for _, m := range served {
+			if m == modelName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if b.modelCapability == nil {
+		return true // No restrictions if not configured
+	}
+
+	// Check excluded patterns first
+	for _, pattern := range b.modelCapability.ExcludedPatterns {
+		if matchesPattern(modelName, pattern) {
+			return false
+		}
+	}
+
+	// If no supported patterns specified, allow all (except excluded)
+	if len(b.modelCapability.SupportedModelPatterns) == 0 {
+		return true
+	}
+
+	// Check if model matches any supported pattern
+	for _, pattern := range b.modelCapability.SupportedModelPatterns {
+		if matchesPattern(modelName, pattern) {
+			return true
+		}
+	}
+
+	// Check preferred models (exact match)
+	for _, preferred := range b.modelCapability.PreferredModels {
+		if modelName == preferred {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetMaxModelSizeGB returns maximum model size this backend can handle
+func (b *SDWebUIBackend) GetMaxModelSizeGB() int {
+	if b.modelCapability == nil {
+		return 999 // No limit if not configured
+	}
+	return b.modelCapability.MaxModelSizeGB
+}
+
+// GetSupportedModelPatterns returns patterns of supported models
+func (b *SDWebUIBackend) GetSupportedModelPatterns() []string {
+	if b.modelCapability == nil {
+		return []string{"*"} // Support all if not configured
+	}
+	return b.modelCapability.SupportedModelPatterns
+}
+
+// GetPreferredModels returns list of preferred models for this backend
+func (b *SDWebUIBackend) GetPreferredModels() []string {
+	if b.modelCapability == nil {
+		return []string{}
+	}
+	return b.modelCapability.PreferredModels
+}
+
+// matchesPattern checks if model name matches a pattern (simple glob-like matching)
+func matchesPattern(modelName, pattern string) bool {
+	// Handle wildcard patterns
+	if pattern == "*" {
+		return true
+	}
+
+	// Exact match
+	if modelName == pattern {
+		return true
+	}
+
+	// Pattern: "sdxl*" matches "sdxl_base_1.0"
+	if strings.HasSuffix(pattern, "*") && !strings.HasPrefix(pattern, "*") {
+		return strings.HasPrefix(modelName, strings.TrimSuffix(pattern, "*"))
+	}
+
+	// Pattern: "*turbo*" matches any checkpoint with "turbo" in name
+	if strings.HasPrefix(pattern, "*") && strings.HasSuffix(pattern, "*") {
+		substr := strings.Trim(pattern, "*")
+		return strings.Contains(strings.ToLower(modelName), strings.ToLower(substr))
+	}
+
+	return false
+}
+
+// ============================================================
+// Unsupported Operations
+// ============================================================
+
+// Generate is not supported
+func (b *SDWebUIBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	return nil, fmt.Errorf("text generation not supported by Stable Diffusion WebUI backend")
+}
+
+// GenerateStream is not supported
+func (b *SDWebUIBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	return nil, fmt.Errorf("text generation not supported by Stable Diffusion WebUI backend")
+}
+
+// Embed is not supported
+func (b *SDWebUIBackend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	return nil, fmt.Errorf("embeddings not supported by Stable Diffusion WebUI backend")
+}
+
+// SupportsAudioToText returns false
+func (b *SDWebUIBackend) SupportsAudioToText() bool {
+	return false
+}
+
+// SupportsTextToAudio returns false
+func (b *SDWebUIBackend) SupportsTextToAudio() bool {
+	return false
+}
+
+// SupportsImageToText returns false
+func (b *SDWebUIBackend) SupportsImageToText() bool {
+	return false
+}
+
+// SupportsTextToImage returns true
+func (b *SDWebUIBackend) SupportsTextToImage() bool {
+	return true
+}
+
+// SupportsImageEdit returns true: edits use img2img, with inpainting
+// when a mask is given
+func (b *SDWebUIBackend) SupportsImageEdit() bool {
+	return true
+}
+
+// SupportsVideoToText returns false
+func (b *SDWebUIBackend) SupportsVideoToText() bool {
+	return false
+}
+
+// SupportsTextToVideo returns false
+func (b *SDWebUIBackend) SupportsTextToVideo() bool {
+	return false
+}
+
+// TranscribeAudio is not supported
+func (b *SDWebUIBackend) TranscribeAudio(ctx context.Context, req *backends.TranscribeRequest) (*backends.TranscribeResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by Stable Diffusion WebUI backend")
+}
+
+// TranscribeAudioStream is not supported
+func (b *SDWebUIBackend) TranscribeAudioStream(ctx context.Context, req *backends.TranscribeRequest) (backends.AudioStreamReader, error) {
+	return nil, fmt.Errorf("audio transcription not supported by Stable Diffusion WebUI backend")
+}
+
+// SynthesizeSpeech is not supported
+func (b *SDWebUIBackend) SynthesizeSpeech(ctx context.Context, req *backends.SynthesizeRequest) (*backends.SynthesizeResponse, error) {
+	return nil, fmt.Errorf("speech synthesis not supported by Stable Diffusion WebUI backend")
+}
+
+// SynthesizeSpeechStream is not supported
+func (b *SDWebUIBackend) SynthesizeSpeechStream(ctx context.Context, req *backends.SynthesizeRequest) (backends.AudioStreamWriter, error) {
+	return nil, fmt.Errorf("speech synthesis not supported by Stable Diffusion WebUI backend")
+}
+
+// AnalyzeImage is not supported
+func (b *SDWebUIBackend) AnalyzeImage(ctx context.Context, req *backends.ImageAnalysisRequest) (*backends.ImageAnalysisResponse, error) {
+	return nil, fmt.Errorf("image analysis not supported by Stable Diffusion WebUI backend")
+}
+
+// AnalyzeVideo is not supported
+func (b *SDWebUIBackend) AnalyzeVideo(ctx context.Context, req *backends.VideoAnalysisRequest) (*backends.VideoAnalysisResponse, error) {
+	return nil, fmt.Errorf("video analysis not supported by Stable Diffusion WebUI backend")
+}
+
+// AnalyzeVideoStream is not supported
+func (b *SDWebUIBackend) AnalyzeVideoStream(ctx context.Context, req *backends.VideoAnalysisRequest) (backends.VideoStreamReader, error) {
+	return nil, fmt.Errorf("video analysis not supported by Stable Diffusion WebUI backend")
+}
+
+// GenerateVideo is not supported
+func (b *SDWebUIBackend) GenerateVideo(ctx context.Context, req *backends.VideoGenRequest) (*backends.VideoGenResponse, error) {
+	return nil, fmt.Errorf("video generation not supported by Stable Diffusion WebUI backend")
+}
+
+// GenerateVideoStream is not supported
+func (b *SDWebUIBackend) GenerateVideoStream(ctx context.Context, req *backends.VideoGenRequest) (backends.VideoStreamReader, error) {
+	return nil, fmt.Errorf("video generation not supported by Stable Diffusion WebUI backend")
+}
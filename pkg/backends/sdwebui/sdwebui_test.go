@@ -0,0 +1,348 @@
+package sdwebui
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	if err := logging.InitLogger("info", false); err != nil {
+		panic(err)
+	}
+	defer logging.Sync()
+
+	os.Exit(m.Run())
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// testServer serves the WebUI API for one checkpoint, recording the last
+// generation request
+type testServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	path        string
+	body        map[string]interface{}
+	release     chan struct{} // when set, generation waits for it
+	step        atomic.Int32
+	interrupted atomic.Bool
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "sd" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/sdapi/v1/progress":
+			step := s.step.Load()
+			fmt.Fprintf(w, `{"progress":%g,"state":{"sampling_step":%d,"sampling_steps":4},"current_image":%q}`,
+				float64(step)/4, step, base64.StdEncoding.EncodeToString([]byte("preview")))
+		case "/sdapi/v1/sd-models":
+			fmt.Fprint(w, `[{"title":"sdxl_base_1.0.safetensors [31e35c80fc]","model_name":"sdxl_base_1.0"}]`)
+		case "/sdapi/v1/interrupt":
+			s.interrupted.Store(true)
+		case "/sdapi/v1/txt2img", "/sdapi/v1/img2img":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			s.mu.Lock()
+			s.path, s.body = r.URL.Path, body
+			s.mu.Unlock()
+
+			if s.release != nil {
+				select {
+				case <-s.release:
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			// A batch of two, plus the grid the server appends
+			image := base64.StdEncoding.EncodeToString(pngHeader)
+			info, _ := json.Marshal(map[string]interface{}{"all_seeds": []int64{42, 43}, "width": 512, "height": 768})
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"images": []string{image, image, "data:image/png;base64," + image},
+				"info":   string(info),
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// last returns the path and body of the last generation request
+func (s *testServer) last() (string, map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.path, s.body
+}
+
+func newTestBackend(t *testing.T, url string) *SDWebUIBackend {
+	b, err := NewSDWebUIBackend(Config{
+		BackendConfig:    backends.BackendConfig{ID: "sd", Hardware: "nvidia", PowerWatts: 300},
+		Endpoint:         url,
+		Auth:             "sd:secret",
+		ProgressInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewSDWebUIBackend failed: %v", err)
+	}
+	return b
+}
+
+func TestNewSDWebUIBackend_Validation(t *testing.T) {
+	if _, err := NewSDWebUIBackend(Config{}); err == nil {
+		t.Error("Expected error without endpoint")
+	}
+	if _, err := NewSDWebUIBackend(Config{Endpoint: "http://localhost:7860", Auth: "nopassword"}); err == nil {
+		t.Error("Expected error for auth without password")
+	}
+	t.Setenv("SD_AUTH", "")
+	if _, err := NewSDWebUIBackend(Config{Endpoint: "http://localhost:7860", AuthEnv: "SD_AUTH"}); err == nil {
+		t.Error("Expected error for empty auth env var")
+	}
+}
+
+func TestStart_HealthAndModels(t *testing.T) {
+	srv := newTestServer(t)
+	b := newTestBackend(t, srv.URL)
+
+	if err := b.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !b.IsHealthy() {
+		t.Error("Expected backend to be healthy")
+	}
+	if !b.SupportsModel("sdxl_base_1.0") {
+		t.Error("Expected served checkpoint to be supported")
+	}
+	if b.SupportsModel("dall-e-3") {
+		t.Error("Expected unknown checkpoint to be unsupported once checkpoints are known")
+	}
+
+	bad := newTestBackend(t, srv.URL)
+	bad.auth = "sd:wrong"
+	if err := bad.HealthCheck(context.Background()); err == nil || bad.IsHealthy() {
+		t.Error("Expected health check to fail with wrong credentials")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	b := newTestBackend(t, "http://localhost:7860")
+	if !backends.SupportsCapability(b, backends.CapabilityTextToImage) {
+		t.Error("Expected text-to-image support")
+	}
+	if backends.SupportsCapability(b, "") {
+		t.Error("Expected text requests not to route to an image-only backend")
+	}
+	if _, err := b.Generate(context.Background(), &backends.GenerateRequest{}); err == nil {
+		t.Error("Expected Generate to be unsupported")
+	}
+}
+
+func TestGenerateImage_TextToImage(t *testing.T) {
+	srv := newTestServer(t)
+	b := newTestBackend(t, srv.URL)
+
+	resp, err := b.GenerateImage(context.Background(), &backends.ImageGenRequest{
+		Prompt:         "a lighthouse at dusk",
+		NegativePrompt: "blurry",
+		Model:          "sdxl_base_1.0",
+		Width:          512,
+		Height:         768,
+		BatchSize:      2,
+		Options:        map[string]string{"sampler": "Euler a"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateImage failed: %v", err)
+	}
+
+	path, body := srv.last()
+	if path != "/sdapi/v1/txt2img" {
+		t.Errorf("Expected txt2img, got %s", path)
+	}
+	for key, want := range map[string]interface{}{
+		"prompt":          "a lighthouse at dusk",
+		"negative_prompt": "blurry",
+		"width":           float64(512),
+		"height":          float64(768),
+		"batch_size":      float64(2),
+		"steps":           float64(DefaultSteps),
+		"cfg_scale":       DefaultCFGScale,
+		"seed":            float64(-1),
+		"sampler_name":    "Euler a",
+	} {
+		if got := body[key]; got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	if settings, _ := body["override_settings"].(map[string]interface{}); settings["sd_model_checkpoint"] != "sdxl_base_1.0" {
+		t.Errorf("Expected checkpoint override, got %v", body["override_settings"])
+	}
+
+	// The grid image is dropped
+	if len(resp.Images) != 2 {
+		t.Fatalf("Expected 2 images, got %d", len(resp.Images))
+	}
+	for i, img := range resp.Images {
+		if img.Format != backends.ImageFormatPNG || img.Width != 512 || img.Height != 768 {
+			t.Errorf("Image %d: unexpected %s %dx%d", i, img.Format, img.Width, img.Height)
+		}
+		if img.Seed != int64(42+i) {
+			t.Errorf("Image %d: seed = %d, want %d", i, img.Seed, 42+i)
+		}
+	}
+	if resp.Stats == nil {
+		t.Error("Expected generation stats")
+	}
+	if m := b.GetMetrics(); m.SuccessCount != 1 {
+		t.Errorf("Expected 1 successful request, got %d", m.SuccessCount)
+	}
+}
+
+func TestGenerateImage_Edit(t *testing.T) {
+	srv := newTestServer(t)
+	b := newTestBackend(t, srv.URL)
+
+	_, err := b.GenerateImage(context.Background(), &backends.ImageGenRequest{
+		Prompt:    "add a boat",
+		Seed:      7,
+		InitImage: []byte("source"),
+		Mask:      []byte("mask"),
+		Options:   map[string]string{"denoising_strength": "0.5"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateImage failed: %v", err)
+	}
+
+	path, body := srv.last()
+	if path != "/sdapi/v1/img2img" {
+		t.Errorf("Expected img2img, got %s", path)
+	}
+	if images, _ := body["init_images"].([]interface{}); len(images) != 1 || images[0] != base64.StdEncoding.EncodeToString([]byte("source")) {
+		t.Errorf("Unexpected init_images %v", body["init_images"])
+	}
+	if body["mask"] != base64.StdEncoding.EncodeToString([]byte("mask")) {
+		t.Errorf("Unexpected mask %v", body["mask"])
+	}
+	if body["denoising_strength"] != 0.5 || body["seed"] != float64(7) {
+		t.Errorf("Unexpected denoising_strength %v or seed %v", body["denoising_strength"], body["seed"])
+	}
+
+	_, err = b.GenerateImage(context.Background(), &backends.ImageGenRequest{
+		InitImage: []byte("source"),
+		Options:   map[string]string{"denoising_strength": "2"},
+	})
+	if err == nil {
+		t.Error("Expected error for out of range denoising_strength")
+	}
+}
+
+func TestGenerateImage_EditStrength(t *testing.T) {
+	srv := newTestServer(t)
+	b := newTestBackend(t, srv.URL)
+
+	_, err := b.GenerateImage(context.Background(), &backends.ImageGenRequest{
+		InitImage: []byte("source"),
+		Strength:  0.25,
+		Options:   map[string]string{"denoising_strength": "0.5"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateImage failed: %v", err)
+	}
+	if _, body := srv.last(); body["denoising_strength"] != 0.25 {
+		t.Errorf("Expected strength to override the option, got %v", body["denoising_strength"])
+	}
+
+	if _, err := b.GenerateImage(context.Background(), &backends.ImageGenRequest{InitImage: []byte("source"), Strength: 1.5}); err == nil {
+		t.Error("Expected error for out of range strength")
+	}
+}
+
+func TestGenerateImageStream_Progress(t *testing.T) {
+	srv := newTestServer(t)
+	srv.release = make(chan struct{})
+	b := newTestBackend(t, srv.URL)
+
+	stream, err := b.GenerateImageStream(context.Background(), &backends.ImageGenRequest{Prompt: "a fox", Steps: 4})
+	if err != nil {
+		t.Fatalf("GenerateImageStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	// Steps advance as the test reads them
+	for step := int32(1); step <= 2; step++ {
+		srv.step.Store(step)
+		chunk, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if chunk.Done || chunk.CurrentStep != step || chunk.TotalSteps != 4 || chunk.Progress != float32(step)/4 {
+			t.Errorf("Unexpected progress chunk %+v", chunk)
+		}
+		if string(chunk.ImageData) != "preview" {
+			t.Errorf("Expected preview image, got %q", chunk.ImageData)
+		}
+	}
+
+	close(srv.release)
+	var final *backends.ImageChunk
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if chunk.Done {
+			final = chunk
+			break
+		}
+	}
+	if final.Progress != 1 || string(final.ImageData) != string(pngHeader) {
+		t.Errorf("Unexpected final chunk %+v", final)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected EOF after final chunk, got %v", err)
+	}
+
+	stream.Close()
+	if srv.interrupted.Load() {
+		t.Error("Expected a finished generation not to be interrupted")
+	}
+}
+
+func TestGenerateImageStream_CloseInterrupts(t *testing.T) {
+	srv := newTestServer(t)
+	srv.release = make(chan struct{})
+	defer close(srv.release)
+	b := newTestBackend(t, srv.URL)
+
+	stream, err := b.GenerateImageStream(context.Background(), &backends.ImageGenRequest{Prompt: "a fox"})
+	if err != nil {
+		t.Fatalf("GenerateImageStream failed: %v", err)
+	}
+	stream.Close()
+
+	if !srv.interrupted.Load() {
+		t.Error("Expected Close to interrupt an unfinished generation")
+	}
+}
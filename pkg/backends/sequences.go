@@ -0,0 +1,122 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// SequenceGenerator is implemented by backends that can generate several
+// completions of one prompt in a single request (n and best_of on
+// OpenAI-compatible servers such as vLLM). The prompt is processed once
+// and its KV cache shared, which is much cheaper than separate requests.
+type SequenceGenerator interface {
+	// SupportsSequences is false once the backend has shown it ignores n
+	SupportsSequences() bool
+	// GenerateSequences returns up to n completions, the best n of bestOf
+	// candidates when bestOf > n. A backend that turns out not to support
+	// it returns fewer.
+	GenerateSequences(ctx context.Context, req *GenerateRequest, n, bestOf int) ([]*GenerateResponse, error)
+}
+
+// SupportsSequences reports whether b generates several completions in
+// one request
+func SupportsSequences(b Backend) bool {
+	sg, ok := b.(SequenceGenerator)
+	return ok && sg.SupportsSequences()
+}
+
+// NativeSequences calls b's GenerateSequences if it supports it, and
+// otherwise returns no completions. Wrapping backends use it to pass the
+// call through.
+func NativeSequences(ctx context.Context, b Backend, req *GenerateRequest, n, bestOf int) ([]*GenerateResponse, error) {
+	if sg, ok := b.(SequenceGenerator); ok && sg.SupportsSequences() {
+		return sg.GenerateSequences(ctx, req, n, bestOf)
+	}
+	return nil, nil
+}
+
+// GenerateSequences returns n completions of req from b, generated in a
+// single request when b supports it and otherwise as concurrent Generate
+// calls. With bestOf > n, bestOf candidates are generated and the n that
+// rank highest kept; a native backend ranks them itself, otherwise rank
+// scores them (nil keeps the first n).
+func GenerateSequences(ctx context.Context, b Backend, req *GenerateRequest, n, bestOf int, rank func(*GenerateResponse) float64) ([]*GenerateResponse, error) {
+	if n < 1 {
+		n = 1
+	}
+	if bestOf < n {
+		bestOf = n
+	}
+
+	var resps []*GenerateResponse
+	if SupportsSequences(b) {
+		var err error
+		resps, err = NativeSequences(ctx, b, req, n, bestOf)
+		if err != nil {
+			return nil, err
+		}
+		if len(resps) >= n {
+			return resps[:n], nil
+		}
+		// The backend ignored n: top up with separate requests
+		bestOf = n
+	}
+
+	extra, err := generateSeparately(ctx, b, req, bestOf-len(resps))
+	if err != nil {
+		return nil, err
+	}
+	resps = append(resps, extra...)
+	if len(resps) > n && rank != nil {
+		scores := make(map[*GenerateResponse]float64, len(resps))
+		for _, resp := range resps {
+			scores[resp] = rank(resp)
+		}
+		sort.SliceStable(resps, func(i, j int) bool {
+			return scores[resps[i]] > scores[resps[j]]
+		})
+	}
+	return resps[:n], nil
+}
+
+// generateSeparately runs count Generate calls concurrently, failing if
+// any fails
+func generateSeparately(ctx context.Context, b Backend, req *GenerateRequest, count int) ([]*GenerateResponse, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resps := make([]*GenerateResponse, count)
+	errs := make([]error, count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resps[i], errs[i] = b.Generate(ctx, req)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Report the failure that cancelled the others
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return resps, nil
+}
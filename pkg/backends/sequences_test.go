@@ -0,0 +1,91 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// sequenceBackend answers Generate with numbered responses. Only Generate
+// is implemented; the embedded interface is nil.
+type sequenceBackend struct {
+	Backend
+	calls atomic.Int32
+	err   error
+}
+
+func (b *sequenceBackend) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	n := b.calls.Add(1)
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &GenerateResponse{Response: strings.Repeat("x", int(n))}, nil
+}
+
+// nativeSequenceBackend generates up to max sequences in one request
+type nativeSequenceBackend struct {
+	sequenceBackend
+	max         int
+	nativeCalls int
+}
+
+func (b *nativeSequenceBackend) SupportsSequences() bool { return b.max > 1 }
+
+func (b *nativeSequenceBackend) GenerateSequences(ctx context.Context, req *GenerateRequest, n, bestOf int) ([]*GenerateResponse, error) {
+	b.nativeCalls++
+	if n > b.max {
+		n = b.max
+	}
+	resps := make([]*GenerateResponse, n)
+	for i := range resps {
+		resps[i] = &GenerateResponse{Response: "native"}
+	}
+	return resps, nil
+}
+
+func TestGenerateSequences_Native(t *testing.T) {
+	b := &nativeSequenceBackend{max: 8}
+	resps, err := GenerateSequences(context.Background(), b, &GenerateRequest{}, 3, 5, nil)
+	if err != nil {
+		t.Fatalf("GenerateSequences failed: %v", err)
+	}
+	if len(resps) != 3 || b.nativeCalls != 1 || b.calls.Load() != 0 {
+		t.Errorf("Expected 3 completions from one native request, got %d (%d native, %d separate)", len(resps), b.nativeCalls, b.calls.Load())
+	}
+}
+
+func TestGenerateSequences_NativeTopUp(t *testing.T) {
+	// The backend returns fewer sequences than asked for
+	resps, err := GenerateSequences(context.Background(), &nativeSequenceBackend{max: 2}, &GenerateRequest{}, 4, 4, nil)
+	if err != nil {
+		t.Fatalf("GenerateSequences failed: %v", err)
+	}
+	if len(resps) != 4 || resps[0].Response != "native" || resps[1].Response != "native" || resps[3].Response == "native" {
+		t.Errorf("Expected 2 native completions topped up with 2 separate ones, got %d", len(resps))
+	}
+}
+
+func TestGenerateSequences_SeparateRanked(t *testing.T) {
+	b := &sequenceBackend{}
+	resps, err := GenerateSequences(context.Background(), b, &GenerateRequest{}, 2, 4, func(resp *GenerateResponse) float64 {
+		return float64(len(resp.Response))
+	})
+	if err != nil {
+		t.Fatalf("GenerateSequences failed: %v", err)
+	}
+	if b.calls.Load() != 4 {
+		t.Errorf("Expected best_of separate requests, got %d", b.calls.Load())
+	}
+	if len(resps) != 2 || resps[0].Response != "xxxx" || resps[1].Response != "xxx" {
+		t.Errorf("Expected the two highest ranked candidates, got %d", len(resps))
+	}
+}
+
+func TestGenerateSequences_Error(t *testing.T) {
+	b := &sequenceBackend{err: errors.New("out of memory")}
+	if _, err := GenerateSequences(context.Background(), b, &GenerateRequest{}, 3, 3, nil); err == nil || err.Error() != "out of memory" {
+		t.Errorf("Expected the backend's error, got %v", err)
+	}
+}
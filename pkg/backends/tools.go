@@ -0,0 +1,48 @@
+package backends
+
+import "encoding/json"
+
+// ToolDefinition is a function the model may call, as in OpenAI's tools
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage // JSON schema of the arguments, nil for none
+}
+
+// ToolCall is a model's call of a tool
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON object
+}
+
+// ChatMessage is one message of the conversation a GenerateRequest's
+// Prompt was built from
+type ChatMessage struct {
+	Role    string // system, user, assistant or tool
+	Content string
+
+	// ToolCalls are the calls an assistant message made
+	ToolCalls []ToolCall
+
+	// ToolCallID and Name identify the call a tool message answers
+	ToolCallID string
+	Name       string
+}
+
+// ToolCaller is implemented by backends that can offer a model tools and
+// return its calls of them: GenerateRequest.Tools, with the conversation
+// in Messages, in; GenerateResponse.ToolCalls and StreamChunk.ToolCalls
+// out. Backends without it ignore Tools, so requests with tools must not
+// be routed to them.
+type ToolCaller interface {
+	// SupportsToolCalling is false when the backend can't call tools,
+	// e.g. it wraps a backend that can't
+	SupportsToolCalling() bool
+}
+
+// SupportsToolCalling reports whether b offers models tools
+func SupportsToolCalling(b Backend) bool {
+	tc, ok := b.(ToolCaller)
+	return ok && tc.SupportsToolCalling()
+}
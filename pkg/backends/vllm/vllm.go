@@ -0,0 +1,1101 @@
+// Package vllm implements a backend for vLLM's OpenAI-compatible server,
+// typically a datacenter GPU host at the top of the escalation path
+package vllm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxTokens caps generation when the request sets no limit; vLLM's
+// own default for completions is only 16 tokens
+const DefaultMaxTokens = 1024
+
+// VLLMBackend implements Backend for a vLLM server
+type VLLMBackend struct {
+	mu sync.RWMutex
+
+	// Config
+	id       string
+	name     string
+	hardware string
+	endpoint string
+	apiKey   string
+
+	// Characteristics
+	powerWatts   float64
+	avgLatencyMs int32
+	priority     int
+
+	// Model capabilities
+	modelCapability *backends.ModelCapability
+
+	// Health
+	healthy      atomic.Bool
+	lastCheck    time.Time
+	checkTimeout time.Duration
+
+	// Metrics
+	metrics *backends.BackendMetrics
+
+	// HTTP client
+	client *http.Client
+}
+
+// Config for vLLM backend
+type Config struct {
+	backends.BackendConfig
+	Endpoint  string // server root, e.g. http://gpu01:8000
+	APIKey    string // optional, for servers started with --api-key
+	APIKeyEnv string // or the env var holding it
+}
+
+// NewVLLMBackend creates a new vLLM backend instance
+func NewVLLMBackend(cfg Config) (*VLLMBackend, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+
+	apiKey := cfg.APIKey
+	if cfg.APIKeyEnv != "" {
+		apiKey = os.Getenv(cfg.APIKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("API key env var %s is empty", cfg.APIKeyEnv)
+		}
+	}
+
+	backend := &VLLMBackend{
+		id:              cfg.ID,
+		name:            cfg.Name,
+		hardware:        cfg.Hardware,
+		endpoint:        strings.TrimSuffix(cfg.Endpoint, "/"),
+		apiKey:          apiKey,
+		powerWatts:      cfg.PowerWatts,
+		avgLatencyMs:    cfg.AvgLatencyMs,
+		priority:        cfg.Priority,
+		modelCapability: cfg.ModelCapability,
+		checkTimeout:    5 * time.Second,
+		metrics: &backends.BackendMetrics{
+			LoadedModels: []string{},
+		},
+		client: &http.Client{
+			Timeout: 300 * time.Second, // large models on a busy server
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 20,
+				IdleConnTimeout:     90 * time.Second,
+				DialContext: (&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSHandshakeTimeout: 5 * time.Second,
+				ForceAttemptHTTP2:   true,
+			},
+		},
+	}
+	backend.client.Transport = middleware.RequestIDTransport(backend.client.Transport)
+
+	backend.healthy.Store(false) // Will be set by health check
+	return backend, nil
+}
+
+// ID returns backend identifier
+func (b *VLLMBackend) ID() string {
+	return b.id
+}
+
+// Type returns backend type
+func (b *VLLMBackend) Type() string {
+	return "vllm"
+}
+
+// Name returns human-readable name
+func (b *VLLMBackend) Name() string {
+	return b.name
+}
+
+// Hardware returns hardware type
+func (b *VLLMBackend) Hardware() string {
+	return b.hardware
+}
+
+// IsHealthy returns current health status
+func (b *VLLMBackend) IsHealthy() bool {
+	return b.healthy.Load()
+}
+
+// newRequest builds a request to the server, authenticated when a key is set
+func (b *VLLMBackend) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.endpoint+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+	return req, nil
+}
+
+// HealthCheck probes vLLM's /health endpoint
+func (b *VLLMBackend) HealthCheck(ctx context.Context) error {
+	checkCtx, cancel := context.WithTimeout(ctx, b.checkTimeout)
+	defer cancel()
+
+	req, err := b.newRequest(checkCtx, "GET", "/health", nil)
+	if err != nil {
+		b.healthy.Store(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		b.healthy.Store(false)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b.healthy.Store(false)
+		return fmt.Errorf("health check failed: status %d", resp.StatusCode)
+	}
+
+	b.healthy.Store(true)
+	b.mu.Lock()
+	b.lastCheck = time.Now()
+	b.mu.Unlock()
+
+	return nil
+}
+
+// PowerWatts returns estimated power consumption
+func (b *VLLMBackend) PowerWatts() float64 {
+	return b.powerWatts
+}
+
+// AvgLatencyMs returns average latency
+func (b *VLLMBackend) AvgLatencyMs() int32 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.metrics.RequestCount > 0 {
+		return b.metrics.AvgLatencyMs
+	}
+	return b.avgLatencyMs // Return configured estimate
+}
+
+// Priority returns backend priority
+func (b *VLLMBackend) Priority() int {
+	return b.priority
+}
+
+// SupportsGenerate returns true
+func (b *VLLMBackend) SupportsGenerate() bool {
+	return true
+}
+
+// SupportsStream returns true
+func (b *VLLMBackend) SupportsStream() bool {
+	return true
+}
+
+// SupportsEmbed returns true; the server must be running an embedding model
+func (b *VLLMBackend) SupportsEmbed() bool {
+	return true
+}
+
+// ListModels returns the models the server was started with
+func (b *VLLMBackend) ListModels(ctx context.Context) ([]string, error) {
+	req, err := b.newRequest(ctx, "GET", "/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vllm error: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, len(result.Data))
+	for i, m := range result.Data {
+		models[i] = m.ID
+	}
+
+	b.mu.Lock()
+	b.metrics.LoadedModels = models
+	b.mu.Unlock()
+
+	return models, nil
+}
+
+// completionRequest builds a /v1/completions request body, for raw
+// prompts that continue text and for prompt scoring
+func completionRequest(req *backends.GenerateRequest, stream bool) map[string]interface{} {
+	body := requestBody(req, stream)
+	body["prompt"] = req.Prompt
+	if req.Options != nil && req.Options.Logprobs {
+		body["logprobs"] = 0 // the sampled tokens only
+	}
+	return body
+}
+
+// chatRequest builds a /v1/chat/completions request body, so the server
+// applies the model's chat template as for the OpenAI backend
+func chatRequest(req *backends.GenerateRequest, stream bool) map[string]interface{} {
+	body := requestBody(req, stream)
+	body["messages"] = []map[string]string{
+		{"role": "user", "content": req.Prompt},
+	}
+	if req.Options != nil && req.Options.Logprobs {
+		body["logprobs"] = true
+	}
+	return body
+}
+
+// requestBody holds the parameters completion and chat requests share
+func requestBody(req *backends.GenerateRequest, stream bool) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":      req.Model,
+		"max_tokens": DefaultMaxTokens,
+	}
+	if stream {
+		body["stream"] = true
+		body["stream_options"] = map[string]bool{"include_usage": true}
+	}
+
+	if req.Options != nil {
+		if req.Options.MaxTokens > 0 {
+			body["max_tokens"] = req.Options.MaxTokens
+		}
+		if req.Options.Temperature > 0 || req.Options.TemperatureSet {
+			body["temperature"] = req.Options.Temperature
+		}
+		if req.Options.TopP > 0 {
+			body["top_p"] = req.Options.TopP
+		}
+		if req.Options.TopK > 0 {
+			body["top_k"] = req.Options.TopK
+		}
+		if len(req.Options.Stop) > 0 {
+			body["stop"] = req.Options.Stop
+		}
+	}
+	return body
+}
+
+// raw reports whether req skips the chat template and goes to /v1/completions
+func raw(req *backends.GenerateRequest) bool {
+	return req.Options != nil && req.Options.Raw
+}
+
+// completionResponse is a /v1/completions response
+type completionResponse struct {
+	Choices []struct {
+		Text         string `json:"text"`
+		FinishReason string `json:"finish_reason"`
+		Logprobs     *struct {
+			Tokens        []string             `json:"tokens"`
+			TokenLogprobs []*float64           `json:"token_logprobs"`
+			TopLogprobs   []map[string]float64 `json:"top_logprobs"`
+			TextOffset    []int                `json:"text_offset"`
+		} `json:"logprobs"`
+	} `json:"choices"`
+	Usage struct {
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// chatResponse is a /v1/chat/completions response
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Logprobs *struct {
+			Content []struct {
+				Logprob *float64 `json:"logprob"`
+			} `json:"content"`
+		} `json:"logprobs"`
+	} `json:"choices"`
+	Usage struct {
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// generated is one choice of a completion or chat response
+type generated struct {
+	text     string
+	logprobs []*float64 // nil unless requested
+}
+
+// generate sends req as a chat request, or a completion request when raw,
+// for n sequences, with token logprobs when asked for, and returns its
+// choices and usage
+func (b *VLLMBackend) generate(ctx context.Context, req *backends.GenerateRequest, n int, logprobs bool) ([]generated, int, time.Duration, error) {
+	if raw(req) {
+		body := completionRequest(req, false)
+		if n > 1 {
+			body["n"] = n
+		}
+		if logprobs {
+			body["logprobs"] = 0
+		}
+		var completion completionResponse
+		elapsed, err := b.post(ctx, "/v1/completions", body, &completion)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		choices := make([]generated, len(completion.Choices))
+		for i, c := range completion.Choices {
+			choices[i].text = c.Text
+			if c.Logprobs != nil {
+				choices[i].logprobs = c.Logprobs.TokenLogprobs
+			}
+		}
+		return choices, completion.Usage.CompletionTokens, elapsed, nil
+	}
+
+	body := chatRequest(req, false)
+	if n > 1 {
+		body["n"] = n
+	}
+	if logprobs {
+		body["logprobs"] = true
+	}
+	var chat chatResponse
+	elapsed, err := b.post(ctx, "/v1/chat/completions", body, &chat)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	choices := make([]generated, len(chat.Choices))
+	for i, c := range chat.Choices {
+		choices[i].text = c.Message.Content
+		if c.Logprobs != nil {
+			for _, lp := range c.Logprobs.Content {
+				choices[i].logprobs = append(choices[i].logprobs, lp.Logprob)
+			}
+		}
+	}
+	return choices, chat.Usage.CompletionTokens, elapsed, nil
+}
+
+// complete sends a /v1/completions request
+func (b *VLLMBackend) complete(ctx context.Context, body map[string]interface{}) (*completionResponse, time.Duration, error) {
+	var completion completionResponse
+	elapsed, err := b.post(ctx, "/v1/completions", body, &completion)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &completion, elapsed, nil
+}
+
+// post sends a generation request and decodes the reply into out,
+// updating the backend's metrics
+func (b *VLLMBackend) post(ctx context.Context, path string, body map[string]interface{}, out interface{}) (time.Duration, error) {
+	start := time.Now()
+
+	httpReq, err := b.newRequest(ctx, "POST", path, body)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != context.Canceled {
+			// A request the caller cancelled (e.g. a hedge that lost) is not a
+			// backend failure; a timeout still is
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("vllm error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return 0, err
+	}
+
+	elapsed := time.Since(start)
+	b.UpdateMetrics(int32(elapsed.Milliseconds()), true)
+	return elapsed, nil
+}
+
+// stats builds generation stats for tokens generated in elapsed, with
+// shares of the request's energy split between its sequences
+func (b *VLLMBackend) stats(tokens int, elapsed time.Duration, shares int) *backends.GenerationStats {
+	energyWh := (b.powerWatts * elapsed.Seconds()) / 3600.0 / float64(shares)
+	return &backends.GenerationStats{
+		TotalTimeMs:     int32(elapsed.Milliseconds()),
+		TokensGenerated: int32(tokens),
+		TokensPerSecond: float32(tokens) / float32(elapsed.Seconds()),
+		EnergyWh:        float32(energyWh),
+	}
+}
+
+// Generate performs text generation
+func (b *VLLMBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	choices, tokens, elapsed, err := b.generate(ctx, req, 1, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &backends.GenerateResponse{
+		Stats: b.stats(tokens, elapsed, 1),
+	}
+	if len(choices) > 0 {
+		resp.Response = choices[0].text
+		for _, lp := range choices[0].logprobs {
+			if lp != nil {
+				resp.TokenLogprobs = append(resp.TokenLogprobs, *lp)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// SupportsSequences returns true: vLLM decodes n sequences of one prompt
+// as a batch sharing the prompt's KV cache
+func (b *VLLMBackend) SupportsSequences() bool {
+	return true
+}
+
+// GenerateSequences generates n completions in one request. With bestOf >
+// n it samples bestOf and keeps the n with the highest mean token log
+// probability, which is what best_of does on servers that still accept it.
+func (b *VLLMBackend) GenerateSequences(ctx context.Context, req *backends.GenerateRequest, n, bestOf int) ([]*backends.GenerateResponse, error) {
+	// Candidates are ranked by their token logprobs
+	choices, tokens, elapsed, err := b.generate(ctx, req, max(n, bestOf), bestOf > n)
+	if err != nil {
+		return nil, err
+	}
+	count := len(choices)
+	if count == 0 {
+		return nil, nil
+	}
+
+	order := make([]int, count)
+	scores := make([]float64, count)
+	for i, choice := range choices {
+		order[i] = i
+		scores[i] = meanLogprob(choice.logprobs)
+	}
+	if bestOf > n {
+		sort.SliceStable(order, func(i, j int) bool {
+			return scores[order[i]] > scores[order[j]]
+		})
+	}
+	if len(order) > n {
+		order = order[:n]
+	}
+
+	// Usage and energy cover every sampled sequence; attribute them evenly
+	tokens /= count
+	resps := make([]*backends.GenerateResponse, len(order))
+	for i, c := range order {
+		resps[i] = &backends.GenerateResponse{
+			Response: choices[c].text,
+			Stats:    b.stats(tokens, elapsed, count),
+		}
+	}
+	return resps, nil
+}
+
+// SupportsDraftVerification returns true: with echo, vLLM returns the
+// log probabilities of the prompt's tokens, so a draft appended to the
+// prompt is scored in the same forward pass as the next token
+func (b *VLLMBackend) SupportsDraftVerification() bool {
+	return true
+}
+
+// VerifyDraft scores draft as a greedy continuation of req.Prompt: a draft
+// token is accepted while it is the model's most likely token
+func (b *VLLMBackend) VerifyDraft(ctx context.Context, req *backends.GenerateRequest, draft string) (*backends.DraftVerdict, error) {
+	text := req.Prompt + draft
+	body := completionRequest(&backends.GenerateRequest{Model: req.Model, Prompt: text}, false)
+	body["max_tokens"] = 1
+	body["temperature"] = 0
+	body["echo"] = true
+	body["logprobs"] = 1
+
+	completion, _, err := b.complete(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	if len(completion.Choices) == 0 || completion.Choices[0].Logprobs == nil {
+		return nil, fmt.Errorf("vllm returned no prompt logprobs")
+	}
+	choice := completion.Choices[0]
+	lp := choice.Logprobs
+
+	// vLLM's text offsets count characters, not bytes
+	runes := []rune(text)
+	promptLen := utf8.RuneCountInString(req.Prompt)
+
+	verdict := &backends.DraftVerdict{}
+	rejected, generated := false, false
+	for i, tok := range lp.Tokens {
+		if i >= len(lp.TextOffset) {
+			break
+		}
+		offset := lp.TextOffset[i]
+		if offset < promptLen {
+			continue // prompt
+		}
+		if offset >= len(runes) {
+			// The token generated after the whole draft
+			if !rejected {
+				verdict.Next = tok
+				verdict.Done = choice.FinishReason == "stop"
+			}
+			generated = true
+			break
+		}
+
+		verdict.DraftTokens++
+		if rejected {
+			continue
+		}
+		if i < len(lp.TopLogprobs) {
+			if best, ok := mostLikely(lp.TopLogprobs[i]); ok && best != tok {
+				// The model would have generated best here instead
+				rejected = true
+				verdict.Accepted = string(runes[promptLen:offset])
+				verdict.Next = best
+				continue
+			}
+		}
+		verdict.AcceptedTokens++
+	}
+	if !rejected {
+		verdict.Accepted = draft
+		// An end of sequence has no token text
+		verdict.Done = verdict.Done || !generated
+	}
+	return verdict, nil
+}
+
+// mostLikely returns the token with the highest log probability
+func mostLikely(top map[string]float64) (string, bool) {
+	best, bestLp, found := "", math.Inf(-1), false
+	for tok, lp := range top {
+		if lp > bestLp || (lp == bestLp && tok < best) {
+			best, bestLp, found = tok, lp, true
+		}
+	}
+	return best, found
+}
+
+// meanLogprob averages a sequence's token log probabilities, -Inf when
+// there are none
+func meanLogprob(logprobs []*float64) float64 {
+	sum, count := 0.0, 0
+	for _, lp := range logprobs {
+		if lp != nil {
+			sum += *lp
+			count++
+		}
+	}
+	if count == 0 {
+		return math.Inf(-1)
+	}
+	return sum / float64(count)
+}
+
+// vllmStreamReader reads a server-sent event chat or completion stream
+type vllmStreamReader struct {
+	scanner *bufio.Scanner
+	resp    *http.Response
+	start   time.Time
+	backend *VLLMBackend
+
+	firstTokenMs int32
+	tokens       int
+	usageTokens  int
+	done         bool
+}
+
+// GenerateStream performs streaming text generation
+func (b *VLLMBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	start := time.Now()
+
+	path, body := "/v1/chat/completions", chatRequest(req, true)
+	if raw(req) {
+		path, body = "/v1/completions", completionRequest(req, true)
+	}
+	httpReq, err := b.newRequest(ctx, "POST", path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("vllm error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+
+	return &vllmStreamReader{
+		scanner: scanner,
+		resp:    resp,
+		start:   start,
+		backend: b,
+	}, nil
+}
+
+// Recv returns the next token, and a final chunk with stats when the
+// server sends [DONE]
+func (r *vllmStreamReader) Recv() (*backends.StreamChunk, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	for r.scanner.Scan() {
+		line := strings.TrimSpace(r.scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue // blank separators and comments
+		}
+		data = strings.TrimSpace(data)
+
+		if data == "[DONE]" {
+			r.done = true
+			elapsed := time.Since(r.start)
+			r.backend.UpdateMetrics(int32(elapsed.Milliseconds()), true)
+
+			tokens := r.usageTokens
+			if tokens == 0 {
+				tokens = r.tokens
+			}
+			stats := r.backend.stats(tokens, elapsed, 1)
+			stats.TimeToFirstTokenMs = r.firstTokenMs
+			return &backends.StreamChunk{Done: true, Stats: stats}, nil
+		}
+
+		// Completion chunks carry text, chat chunks a delta
+		var chunk struct {
+			Choices []struct {
+				Text  string `json:"text"`
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, err
+		}
+		if chunk.Usage != nil {
+			r.usageTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 {
+			continue // usage-only chunk
+		}
+		token := chunk.Choices[0].Text + chunk.Choices[0].Delta.Content
+		if token == "" {
+			continue // role-only or empty chunk
+		}
+
+		if r.tokens == 0 {
+			r.firstTokenMs = int32(time.Since(r.start).Milliseconds())
+			if logging.Logger != nil {
+				logging.Logger.Debug("Time to first token",
+					zap.String("backend", r.backend.ID()),
+					zap.Int32("ttft_ms", r.firstTokenMs),
+				)
+			}
+		}
+		r.tokens++
+		return &backends.StreamChunk{Token: token}, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		r.backend.UpdateMetrics(int32(time.Since(r.start).Milliseconds()), false)
+		return nil, err
+	}
+	// Connection closed without [DONE]
+	r.done = true
+	return nil, io.EOF
+}
+
+// Close closes the stream
+func (r *vllmStreamReader) Close() error {
+	return r.resp.Body.Close()
+}
+
+// Embed generates an embedding via /v1/embeddings
+func (b *VLLMBackend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	start := time.Now()
+
+	httpReq, err := b.newRequest(ctx, "POST", "/v1/embeddings", map[string]interface{}{
+		"model": req.Model,
+		"input": req.Text,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != context.Canceled {
+			b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("vllm error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		b.UpdateMetrics(int32(time.Since(start).Milliseconds()), false)
+		return nil, fmt.Errorf("vllm returned no embedding")
+	}
+
+	elapsed := time.Since(start)
+	b.UpdateMetrics(int32(elapsed.Milliseconds()), true)
+
+	return &backends.EmbedResponse{
+		Embedding: result.Data[0].Embedding,
+		Stats:     b.stats(result.Usage.PromptTokens, elapsed, 1),
+	}, nil
+}
+
+// UpdateMetrics updates backend metrics
+func (b *VLLMBackend) UpdateMetrics(latencyMs int32, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	atomic.AddInt64(&b.metrics.RequestCount, 1)
+
+	if success {
+		atomic.AddInt64(&b.metrics.SuccessCount, 1)
+		atomic.AddInt64(&b.metrics.TotalLatencyMs, int64(latencyMs))
+
+		// Update rolling average
+		if b.metrics.RequestCount > 0 {
+			b.metrics.AvgLatencyMs = int32(b.metrics.TotalLatencyMs / b.metrics.RequestCount)
+		}
+	} else {
+		atomic.AddInt64(&b.metrics.ErrorCount, 1)
+	}
+
+	// Calculate error rate
+	if b.metrics.RequestCount > 0 {
+		b.metrics.ErrorRate = float32(b.metrics.ErrorCount) / float32(b.metrics.RequestCount)
+	}
+}
+
+// GetMetrics returns current metrics
+func (b *VLLMBackend) GetMetrics() *backends.BackendMetrics {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Return copy
+	return &backends.BackendMetrics{
+		RequestCount:   b.metrics.RequestCount,
+		SuccessCount:   b.metrics.SuccessCount,
+		ErrorCount:     b.metrics.ErrorCount,
+		TotalLatencyMs: b.metrics.TotalLatencyMs,
+		AvgLatencyMs:   b.metrics.AvgLatencyMs,
+		ErrorRate:      b.metrics.ErrorRate,
+		LoadedModels:   b.metrics.LoadedModels,
+	}
+}
+
+// Start performs an initial health check and learns the served models
+func (b *VLLMBackend) Start(ctx context.Context) error {
+	if err := b.HealthCheck(ctx); err != nil {
+		return err
+	}
+	if _, err := b.ListModels(ctx); err != nil && logging.Logger != nil {
+		logging.Logger.Warn("Failed to list vLLM models",
+			zap.String("backend_id", b.id),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// Stop shuts down the backend
+func (b *VLLMBackend) Stop(ctx context.Context) error {
+	// Nothing to clean up for HTTP client
+	return nil
+}
+
+// SupportsModel checks if this backend can run the specified model. A vLLM
+// server serves only the models it was started with, so once those are
+// known they are the limit.
+func (b *VLLMBackend) SupportsModel(modelName string) bool {
+	b.mu.RLock()
+	served := b.metrics.LoadedModels
+	b.mu.RUnlock()
+	if len(served) > 0 {
+		found := false
+		for _, m := range served {
+			if m == modelName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if b.modelCapability == nil {
+		return true // No restrictions if not configured
+	}
+
+	// Check excluded patterns first
+	for _, pattern := range b.modelCapability.ExcludedPatterns {
+		if matchesPattern(modelName, pattern) {
+			return false
+		}
+	}
+
+	// If no supported patterns specified, allow all (except excluded)
+	if len(b.modelCapability.SupportedModelPatterns) == 0 {
+		return true
+	}
+
+	// Check if model matches any supported pattern
+	for _, pattern := range b.modelCapability.SupportedModelPatterns {
+		if matchesPattern(modelName, pattern) {
+			return true
+		}
+	}
+
+	// Check preferred models (exact match)
+	for _, preferred := range b.modelCapability.PreferredModels {
+		if modelName == preferred {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetMaxModelSizeGB returns maximum model size this backend can handle
+func (b *VLLMBackend) GetMaxModelSizeGB() int {
+	if b.modelCapability == nil {
+		return 999 // No limit if not configured
+	}
+	return b.modelCapability.MaxModelSizeGB
+}
+
+// GetSupportedModelPatterns returns patterns of supported models
+func (b *VLLMBackend) GetSupportedModelPatterns() []string {
+	if b.modelCapability == nil {
+		return []string{"*"} // Support all if not configured
+	}
+	return b.modelCapability.SupportedModelPatterns
+}
+
+// GetPreferredModels returns list of preferred models for this backend
+func (b *VLLMBackend) GetPreferredModels() []string {
+	if b.modelCapability == nil {
+		return []string{}
+	}
+	return b.modelCapability.PreferredModels
+}
+
+// matchesPattern checks if model name matches a pattern (simple glob-like matching)
+func matchesPattern(modelName, pattern string) bool {
+	// Handle wildcard patterns
+	if pattern == "*" {
+		return true
+	}
+
+	// Exact match
+	if modelName == pattern {
+		return true
+	}
+
+	// Pattern: "*:0.5b" matches "qwen2.5:0.5b", "tinyllama:0.5b"
+	if strings.HasPrefix(pattern, "*:") {
+		suffix := strings.TrimPrefix(pattern, "*:")
+		return strings.HasSuffix(modelName, ":"+suffix)
+	}
+
+	// Pattern: "meta-llama/*" matches "meta-llama/Llama-3.1-70B-Instruct"
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(modelName, strings.TrimSuffix(pattern, "*"))
+	}
+
+	// Pattern: "*70b*" matches any model with "70b" in name
+	if strings.HasPrefix(pattern, "*") && strings.HasSuffix(pattern, "*") {
+		substr := strings.Trim(pattern, "*")
+		return strings.Contains(strings.ToLower(modelName), strings.ToLower(substr))
+	}
+
+	return false
+}
+
+// ============================================================
+// Multimedia Capability Methods
+// ============================================================
+
+// SupportsAudioToText returns false
+func (b *VLLMBackend) SupportsAudioToText() bool {
+	return false
+}
+
+// SupportsTextToAudio returns false
+func (b *VLLMBackend) SupportsTextToAudio() bool {
+	return false
+}
+
+// SupportsImageToText returns false
+func (b *VLLMBackend) SupportsImageToText() bool {
+	return false
+}
+
+// SupportsTextToImage returns false
+func (b *VLLMBackend) SupportsTextToImage() bool {
+	return false
+}
+
+// SupportsVideoToText returns false
+func (b *VLLMBackend) SupportsVideoToText() bool {
+	return false
+}
+
+// SupportsTextToVideo returns false
+func (b *VLLMBackend) SupportsTextToVideo() bool {
+	return false
+}
+
+// TranscribeAudio is not supported
+func (b *VLLMBackend) TranscribeAudio(ctx context.Context, req *backends.TranscribeRequest) (*backends.TranscribeResponse, error) {
+	return nil, fmt.Errorf("audio transcription not supported by vLLM backend")
+}
+
+// TranscribeAudioStream is not supported
+func (b *VLLMBackend) TranscribeAudioStream(ctx context.Context, req *backends.TranscribeRequest) (backends.AudioStreamReader, error) {
+	return nil, fmt.Errorf("audio transcription not supported by vLLM backend")
+}
+
+// SynthesizeSpeech is not supported
+func (b *VLLMBackend) SynthesizeSpeech(ctx context.Context, req *backends.SynthesizeRequest) (*backends.SynthesizeResponse, error) {
+	return nil, fmt.Errorf("speech synthesis not supported by vLLM backend")
+}
+
+// SynthesizeSpeechStream is not supported
+func (b *VLLMBackend) SynthesizeSpeechStream(ctx context.Context, req *backends.SynthesizeRequest) (backends.AudioStreamWriter, error) {
+	return nil, fmt.Errorf("speech synthesis not supported by vLLM backend")
+}
+
+// AnalyzeImage is not supported
+func (b *VLLMBackend) AnalyzeImage(ctx context.Context, req *backends.ImageAnalysisRequest) (*backends.ImageAnalysisResponse, error) {
+	return nil, fmt.Errorf("image analysis not supported by vLLM backend")
+}
+
+// GenerateImage is not supported
+func (b *VLLMBackend) GenerateImage(ctx context.Context, req *backends.ImageGenRequest) (*backends.ImageGenResponse, error) {
+	return nil, fmt.Errorf("image generation not supported by vLLM backend")
+}
+
+// GenerateImageStream is not supported
+func (b *VLLMBackend) GenerateImageStream(ctx context.Context, req *backends.ImageGenRequest) (backends.ImageStreamReader, error) {
+	return nil, fmt.Errorf("image generation not supported by vLLM backend")
+}
+
+// AnalyzeVideo is not supported
+func (b *VLLMBackend) AnalyzeVideo(ctx context.Context, req *backends.VideoAnalysisRequest) (*backends.VideoAnalysisResponse, error) {
+	return nil, fmt.Errorf("video analysis not supported by vLLM backend")
+}
+
+// AnalyzeVideoStream is not supported
+func (b *VLLMBackend) AnalyzeVideoStream(ctx context.Context, req *backends.VideoAnalysisRequest) (backends.VideoStreamReader, error) {
+	return nil, fmt.Errorf("video analysis not supported by vLLM backend")
+}
+
+// GenerateVideo is not supported
+func (b *VLLMBackend) GenerateVideo(ctx context.Context, req *backends.VideoGenRequest) (*backends.VideoGenResponse, error) {
+	return nil, fmt.Errorf("video generation not supported by vLLM backend")
+}
+
+// GenerateVideoStream is not supported
+func (b *VLLMBackend) GenerateVideoStream(ctx context.Context, req *backends.VideoGenRequest) (backends.VideoStreamReader, error) {
+	return nil, fmt.Errorf("video generation not supported by vLLM backend")
+}
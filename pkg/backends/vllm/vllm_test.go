@@ -0,0 +1,480 @@
+package vllm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	if err := logging.InitLogger("info", false); err != nil {
+		panic(err)
+	}
+	defer logging.Sync()
+
+	os.Exit(m.Run())
+}
+
+// newTestServer serves vLLM's OpenAI-compatible endpoints for one model,
+// recording the last completion request body
+func newTestServer(t *testing.T, last *map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/health":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/models":
+			fmt.Fprint(w, `{"object":"list","data":[{"id":"meta-llama/Llama-3.1-70B-Instruct","object":"model"}]}`)
+		case "/v1/completions", "/v1/chat/completions":
+			chat := r.URL.Path == "/v1/chat/completions"
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			body["path"] = r.URL.Path
+			if last != nil {
+				*last = body
+			}
+
+			if body["stream"] == true {
+				w.Header().Set("Content-Type", "text/event-stream")
+				if chat {
+					fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+				}
+				for _, tok := range []string{"Hello", " world"} {
+					if chat {
+						fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", tok)
+					} else {
+						fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"text\":%q}]}\n\n", tok)
+					}
+				}
+				fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"completion_tokens\":2}}\n\n")
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				return
+			}
+
+			n := 1
+			if v, ok := body["n"].(float64); ok {
+				n = int(v)
+			}
+			choices := make([]map[string]interface{}, n)
+			for i := range choices {
+				text := fmt.Sprintf("completion %d", i)
+				// Later candidates are more likely
+				logprobs := []float64{-float64(n - i), -1}
+				if chat {
+					content := make([]map[string]float64, len(logprobs))
+					for j, lp := range logprobs {
+						content[j] = map[string]float64{"logprob": lp}
+					}
+					choices[i] = map[string]interface{}{
+						"message":  map[string]string{"role": "assistant", "content": text},
+						"logprobs": map[string]interface{}{"content": content},
+					}
+				} else {
+					choices[i] = map[string]interface{}{
+						"text":     text,
+						"logprobs": map[string]interface{}{"token_logprobs": logprobs},
+					}
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"choices": choices,
+				"usage":   map[string]int{"completion_tokens": 3 * n},
+			})
+		case "/v1/embeddings":
+			fmt.Fprint(w, `{"data":[{"embedding":[0.1,0.2,0.3]}],"usage":{"prompt_tokens":4}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestBackend(t *testing.T, server *httptest.Server) *VLLMBackend {
+	backend, err := NewVLLMBackend(Config{
+		BackendConfig: backends.BackendConfig{
+			ID:         "vllm-gpu",
+			Hardware:   "gpu",
+			PowerWatts: 700,
+		},
+		Endpoint: server.URL + "/",
+		APIKey:   "secret",
+	})
+	if err != nil {
+		t.Fatalf("NewVLLMBackend failed: %v", err)
+	}
+	return backend
+}
+
+func TestNewVLLMBackend(t *testing.T) {
+	if _, err := NewVLLMBackend(Config{}); err == nil {
+		t.Error("Expected error for missing endpoint")
+	}
+
+	os.Unsetenv("VLLM_TEST_KEY")
+	if _, err := NewVLLMBackend(Config{Endpoint: "http://gpu01:8000", APIKeyEnv: "VLLM_TEST_KEY"}); err == nil {
+		t.Error("Expected error for empty API key env var")
+	}
+}
+
+func TestVLLMBackend_StartAndListModels(t *testing.T) {
+	server := newTestServer(t, nil)
+	defer server.Close()
+	backend := newTestBackend(t, server)
+
+	if backend.Type() != "vllm" {
+		t.Errorf("Expected type vllm, got %s", backend.Type())
+	}
+	if err := backend.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !backend.IsHealthy() {
+		t.Error("Expected backend to be healthy")
+	}
+
+	// Only the served model is supported once the models are known
+	if !backend.SupportsModel("meta-llama/Llama-3.1-70B-Instruct") {
+		t.Error("Expected the served model to be supported")
+	}
+	if backend.SupportsModel("qwen2.5:0.5b") {
+		t.Error("Expected a model the server doesn't serve to be unsupported")
+	}
+}
+
+func TestVLLMBackend_HealthCheckUnauthorized(t *testing.T) {
+	server := newTestServer(t, nil)
+	defer server.Close()
+	backend := newTestBackend(t, server)
+	backend.apiKey = "wrong"
+
+	if err := backend.HealthCheck(context.Background()); err == nil {
+		t.Error("Expected health check to fail with the wrong API key")
+	}
+	if backend.IsHealthy() {
+		t.Error("Expected backend to be unhealthy")
+	}
+}
+
+func TestVLLMBackend_Generate(t *testing.T) {
+	var last map[string]interface{}
+	server := newTestServer(t, &last)
+	defer server.Close()
+	backend := newTestBackend(t, server)
+
+	resp, err := backend.Generate(context.Background(), &backends.GenerateRequest{
+		Prompt: "Hi",
+		Model:  "meta-llama/Llama-3.1-70B-Instruct",
+		Options: &backends.GenerationOptions{
+			Temperature: 0.2,
+			Stop:        []string{"\n"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Response != "completion 0" || resp.Stats.TokensGenerated != 3 {
+		t.Errorf("Unexpected response %q with %d tokens", resp.Response, resp.Stats.TokensGenerated)
+	}
+	if last["path"] != "/v1/chat/completions" || last["max_tokens"] != float64(DefaultMaxTokens) || last["temperature"] != 0.2 {
+		t.Errorf("Unexpected request body %v", last)
+	}
+	messages, _ := last["messages"].([]interface{})
+	if len(messages) != 1 || last["prompt"] != nil {
+		t.Errorf("Expected the prompt as a chat message, got %v", last)
+	}
+	if m := backend.GetMetrics(); m.SuccessCount != 1 {
+		t.Errorf("Expected one successful request, got %d", m.SuccessCount)
+	}
+	if _, ok := last["logprobs"]; ok {
+		t.Error("Expected logprobs only when asked for")
+	}
+
+	resp, err = backend.Generate(context.Background(), &backends.GenerateRequest{
+		Prompt:  "Hi",
+		Model:   "meta-llama/Llama-3.1-70B-Instruct",
+		Options: &backends.GenerationOptions{Logprobs: true},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if last["logprobs"] != true || len(resp.TokenLogprobs) != 2 || resp.TokenLogprobs[0] != -1 {
+		t.Errorf("Expected the token logprobs reported, got %v from %v", resp.TokenLogprobs, last)
+	}
+}
+
+func TestVLLMBackend_GenerateRaw(t *testing.T) {
+	var last map[string]interface{}
+	server := newTestServer(t, &last)
+	defer server.Close()
+	backend := newTestBackend(t, server)
+
+	// Raw prompts continue text, so they skip the chat template
+	resp, err := backend.Generate(context.Background(), &backends.GenerateRequest{
+		Prompt:  "Once upon a time",
+		Model:   "m",
+		Options: &backends.GenerationOptions{Raw: true, Logprobs: true},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if last["path"] != "/v1/completions" || last["prompt"] != "Once upon a time" || last["logprobs"] != float64(0) {
+		t.Errorf("Expected a completion request, got %v", last)
+	}
+	if resp.Response != "completion 0" || len(resp.TokenLogprobs) != 2 {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+}
+
+func TestVLLMBackend_ZeroTemperature(t *testing.T) {
+	var last map[string]interface{}
+	server := newTestServer(t, &last)
+	defer server.Close()
+	backend := newTestBackend(t, server)
+
+	req := &backends.GenerateRequest{Prompt: "Hi", Model: "m", Options: &backends.GenerationOptions{}}
+	if _, err := backend.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, ok := last["temperature"]; ok {
+		t.Errorf("Expected no temperature when unset, got %v", last)
+	}
+
+	req.Options.TemperatureSet = true
+	if _, err := backend.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if temp, ok := last["temperature"]; !ok || temp != float64(0) {
+		t.Errorf("Expected an explicit zero temperature sent, got %v", last)
+	}
+}
+
+func TestVLLMBackend_GenerateStream(t *testing.T) {
+	var last map[string]interface{}
+	server := newTestServer(t, &last)
+	defer server.Close()
+	backend := newTestBackend(t, server)
+
+	stream, err := backend.GenerateStream(context.Background(), &backends.GenerateRequest{Prompt: "Hi", Model: "m"})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	var text string
+	var final *backends.StreamChunk
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if chunk.Done {
+			final = chunk
+			continue
+		}
+		text += chunk.Token
+	}
+
+	if text != "Hello world" || last["path"] != "/v1/chat/completions" {
+		t.Errorf("Expected 'Hello world' from a chat stream, got %q from %v", text, last)
+	}
+	if final == nil || final.Stats.TokensGenerated != 2 {
+		t.Errorf("Expected a final chunk with the reported usage, got %+v", final)
+	}
+}
+
+func TestVLLMBackend_GenerateStreamRaw(t *testing.T) {
+	var last map[string]interface{}
+	server := newTestServer(t, &last)
+	defer server.Close()
+	backend := newTestBackend(t, server)
+
+	stream, err := backend.GenerateStream(context.Background(), &backends.GenerateRequest{
+		Prompt: "Hi", Model: "m", Options: &backends.GenerationOptions{Raw: true},
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	var text string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		text += chunk.Token
+	}
+	if text != "Hello world" || last["path"] != "/v1/completions" {
+		t.Errorf("Expected a completion stream, got %q from %v", text, last)
+	}
+}
+
+func TestVLLMBackend_GenerateSequences(t *testing.T) {
+	var last map[string]interface{}
+	server := newTestServer(t, &last)
+	defer server.Close()
+	backend := newTestBackend(t, server)
+	req := &backends.GenerateRequest{Prompt: "Hi", Model: "m"}
+
+	resps, err := backends.GenerateSequences(context.Background(), backend, req, 3, 3, nil)
+	if err != nil {
+		t.Fatalf("GenerateSequences failed: %v", err)
+	}
+	if len(resps) != 3 || resps[2].Response != "completion 2" || last["n"] != float64(3) {
+		t.Errorf("Expected 3 completions from one request, got %d", len(resps))
+	}
+
+	// best_of samples more and keeps the most likely
+	resps, err = backend.GenerateSequences(context.Background(), req, 2, 4)
+	if err != nil {
+		t.Fatalf("GenerateSequences failed: %v", err)
+	}
+	if last["n"] != float64(4) || last["logprobs"] != true {
+		t.Errorf("Expected 4 candidates with logprobs, got %v", last)
+	}
+	if len(resps) != 2 || resps[0].Response != "completion 3" || resps[1].Response != "completion 2" {
+		t.Errorf("Expected the two most likely candidates, got %d", len(resps))
+	}
+}
+
+// echoServer answers echo requests continuing the prompt made of
+// promptTokens as a model that greedily generates " Paris." and then a
+// newline
+func echoServer(t *testing.T, last *map[string]interface{}, promptTokens ...string) *httptest.Server {
+	prefix := strings.Join(promptTokens, "")
+	preferred := map[string]string{
+		prefix:             " Paris",
+		prefix + " Paris":  ".",
+		prefix + " Paris.": "\n",
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		*last = body
+
+		prompt := body["prompt"].(string)
+		tokens := append([]string(nil), promptTokens...)
+		text := prefix
+		top := make([]map[string]float64, len(tokens))
+		for len(text) < len(prompt) {
+			tok := "."
+			for _, word := range []string{" Paris", " Lyon"} {
+				if strings.HasPrefix(prompt[len(text):], word) {
+					tok = word
+				}
+			}
+			top = append(top, map[string]float64{preferred[text]: -0.1, tok: -3})
+			tokens = append(tokens, tok)
+			text += tok
+		}
+		// The generated token
+		tokens = append(tokens, preferred[text])
+		top = append(top, map[string]float64{preferred[text]: -0.1})
+
+		// Like vLLM, offsets count characters
+		offsets := make([]int, len(tokens))
+		for i := 1; i < len(tokens); i++ {
+			offsets[i] = offsets[i-1] + utf8.RuneCountInString(tokens[i-1])
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"text":          strings.Join(tokens, ""),
+				"finish_reason": "length",
+				"logprobs": map[string]interface{}{
+					"tokens":       tokens,
+					"top_logprobs": top,
+					"text_offset":  offsets,
+				},
+			}},
+		})
+	}))
+}
+
+func TestVLLMBackend_VerifyDraft(t *testing.T) {
+	var last map[string]interface{}
+	server := echoServer(t, &last, "The", " capital", " of", " France", " is")
+	defer server.Close()
+	backend := newTestBackend(t, server)
+	req := &backends.GenerateRequest{Prompt: "The capital of France is", Model: "m"}
+
+	if !backends.SupportsDraftVerification(backend) {
+		t.Fatal("Expected vLLM to verify drafts")
+	}
+
+	verdict, err := backend.VerifyDraft(context.Background(), req, " Paris.")
+	if err != nil {
+		t.Fatalf("VerifyDraft failed: %v", err)
+	}
+	if last["echo"] != true || last["max_tokens"] != float64(1) || last["prompt"] != "The capital of France is Paris." {
+		t.Errorf("Expected a one-token echo request over prompt and draft, got %v", last)
+	}
+	if verdict.Accepted != " Paris." || verdict.AcceptedTokens != 2 || verdict.DraftTokens != 2 || verdict.Next != "\n" || verdict.Done {
+		t.Errorf("Expected the whole draft accepted with a bonus token, got %+v", verdict)
+	}
+
+	// The model prefers "." over " Lyon"
+	verdict, err = backend.VerifyDraft(context.Background(), req, " Paris Lyon")
+	if err != nil {
+		t.Fatalf("VerifyDraft failed: %v", err)
+	}
+	if verdict.Accepted != " Paris" || verdict.AcceptedTokens != 1 || verdict.DraftTokens != 2 || verdict.Next != "." {
+		t.Errorf("Expected the draft cut at the rejected token, got %+v", verdict)
+	}
+}
+
+func TestVLLMBackend_VerifyDraftMultibytePrompt(t *testing.T) {
+	var last map[string]interface{}
+	server := echoServer(t, &last, "La", " capitale", " de", " la", " République", " française", " est")
+	defer server.Close()
+	backend := newTestBackend(t, server)
+	req := &backends.GenerateRequest{Prompt: "La capitale de la République française est", Model: "m"}
+
+	verdict, err := backend.VerifyDraft(context.Background(), req, " Paris.")
+	if err != nil {
+		t.Fatalf("VerifyDraft failed: %v", err)
+	}
+	if verdict.Accepted != " Paris." || verdict.AcceptedTokens != 2 || verdict.DraftTokens != 2 || verdict.Next != "\n" || verdict.Done {
+		t.Errorf("Expected the whole draft verified with a bonus token, got %+v", verdict)
+	}
+
+	verdict, err = backend.VerifyDraft(context.Background(), req, " Paris Lyon")
+	if err != nil {
+		t.Fatalf("VerifyDraft failed: %v", err)
+	}
+	if verdict.Accepted != " Paris" || verdict.AcceptedTokens != 1 || verdict.DraftTokens != 2 || verdict.Next != "." {
+		t.Errorf("Expected the draft cut at the rejected token, got %+v", verdict)
+	}
+}
+
+func TestVLLMBackend_Embed(t *testing.T) {
+	server := newTestServer(t, nil)
+	defer server.Close()
+	backend := newTestBackend(t, server)
+
+	resp, err := backend.Embed(context.Background(), &backends.EmbedRequest{Text: "Hi", Model: "m"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(resp.Embedding) != 3 {
+		t.Errorf("Expected 3 dimensions, got %d", len(resp.Embedding))
+	}
+}
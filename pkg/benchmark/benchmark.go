@@ -0,0 +1,544 @@
+// Package benchmark measures every model on every local backend with a
+// short standard prompt during a nightly window, and refreshes the
+// router's per-model latency and throughput table with the results.
+package benchmark
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"go.uber.org/zap"
+)
+
+// Defaults for Config
+const (
+	DefaultPrompt    = "List the first ten prime numbers."
+	DefaultMaxTokens = 32
+	DefaultTimeout   = 2 * time.Minute
+	DefaultInterval  = 10 * time.Minute
+)
+
+// ErrBusy stops a scheduled run when requests arrive; the rest of the
+// models are measured at the next check
+var ErrBusy = errors.New("benchmark paused: proxy is serving requests")
+
+// Router is the part of the router the benchmark uses
+type Router interface {
+	ListBackends() []backends.Backend
+	InFlight() int
+	SetModelPerformance(table []router.ModelPerformance)
+}
+
+// Window is a daily time range in local time. An end before the start
+// wraps past midnight.
+type Window struct {
+	Start time.Duration // since midnight
+	End   time.Duration
+}
+
+// ParseWindow parses a window such as "02:00-05:00"
+func ParseWindow(s string) (Window, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid window %q, want HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return Window{}, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return Window{}, err
+	}
+	if start == end {
+		return Window{}, fmt.Errorf("invalid window %q: empty", s)
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Opened returns when the occurrence of the window containing t opened,
+// and false when t is outside the window
+func (w Window) Opened(t time.Time) (time.Time, bool) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	switch {
+	case w.Start < w.End:
+		if offset >= w.Start && offset < w.End {
+			return midnight.Add(w.Start), true
+		}
+	case offset >= w.Start:
+		return midnight.Add(w.Start), true
+	case offset < w.End:
+		return midnight.AddDate(0, 0, -1).Add(w.Start), true
+	}
+	return time.Time{}, false
+}
+
+// Config configures the benchmark
+type Config struct {
+	Window    Window        // scheduled runs happen inside it
+	Prompt    string        // the standard prompt
+	MaxTokens int32         // tokens generated per model
+	Timeout   time.Duration // per backend and model
+	Interval  time.Duration // how often the window and idleness are checked
+	Path      string        // results kept across restarts ("" = memory only)
+}
+
+// Result is one model measured on one backend
+type Result struct {
+	Backend         string    `json:"backend"`
+	Hardware        string    `json:"hardware"`
+	Model           string    `json:"model"`
+	TTFTMs          float64   `json:"ttft_ms"`
+	TotalMs         float64   `json:"total_ms"`
+	Tokens          int32     `json:"tokens"`
+	TokensPerSecond float64   `json:"tokens_per_second"`
+	Error           string    `json:"error,omitempty"`
+	RanAt           time.Time `json:"ran_at"`
+}
+
+// Status is the state served by the admin endpoint
+type Status struct {
+	Running bool      `json:"running"`
+	LastRun time.Time `json:"last_run,omitempty"` // last run that measured every model
+	Window  string    `json:"window"`
+	Results []Result  `json:"results"`
+}
+
+// Benchmark measures models on a schedule
+type Benchmark struct {
+	router Router
+	config Config
+	now    func() time.Time
+
+	run sync.Mutex // held while a run is in progress
+
+	mu      sync.Mutex
+	running bool
+	lastRun time.Time
+	results map[string]Result // backend + model -> latest
+}
+
+// New creates a benchmark, restoring results saved at cfg.Path into the
+// router's table
+func New(r Router, cfg Config) *Benchmark {
+	if cfg.Prompt == "" {
+		cfg.Prompt = DefaultPrompt
+	}
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = DefaultMaxTokens
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+
+	b := &Benchmark{
+		router:  r,
+		config:  cfg,
+		now:     time.Now,
+		results: make(map[string]Result),
+	}
+	if err := b.load(); err != nil && logging.Logger != nil {
+		logging.Logger.Warn("Failed to load benchmark results", zap.Error(err))
+	}
+	b.publish()
+	return b
+}
+
+func resultKey(backendID, model string) string {
+	return backendID + "\x00" + model
+}
+
+// Run checks every interval whether the window is open and the proxy idle,
+// and measures the models not yet measured in this window, until ctx ends
+func (b *Benchmark) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.RunScheduled(ctx)
+		}
+	}
+}
+
+// RunScheduled measures the models not measured since the window opened,
+// if it is open, stopping as soon as the proxy has requests in flight
+func (b *Benchmark) RunScheduled(ctx context.Context) error {
+	opened, ok := b.config.Window.Opened(b.now())
+	if !ok {
+		return nil
+	}
+	return b.measure(ctx, opened, true)
+}
+
+// RunNow measures every model whatever the time and load
+func (b *Benchmark) RunNow(ctx context.Context) error {
+	return b.measure(ctx, time.Time{}, false)
+}
+
+// pair is a model on a backend
+type pair struct {
+	backend backends.Backend
+	model   string
+}
+
+// measure benchmarks each pair whose latest result is older than since.
+// With idleOnly it stops when requests are in flight.
+func (b *Benchmark) measure(ctx context.Context, since time.Time, idleOnly bool) error {
+	if !b.run.TryLock() {
+		return nil // already running
+	}
+	defer b.run.Unlock()
+
+	b.setRunning(true)
+	defer b.setRunning(false)
+
+	err := b.measurePairs(ctx, since, idleOnly)
+	if err == nil {
+		b.mu.Lock()
+		b.lastRun = b.now()
+		b.mu.Unlock()
+	}
+	// Keep what was measured even when the run was cut short
+	if saveErr := b.save(); saveErr != nil && logging.Logger != nil {
+		logging.Logger.Warn("Failed to save benchmark results", zap.Error(saveErr))
+	}
+	return err
+}
+
+func (b *Benchmark) measurePairs(ctx context.Context, since time.Time, idleOnly bool) error {
+	for _, p := range b.pairs(ctx) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		b.mu.Lock()
+		last, measured := b.results[resultKey(p.backend.ID(), p.model)]
+		b.mu.Unlock()
+		if measured && last.RanAt.After(since) {
+			continue
+		}
+		if idleOnly && b.router.InFlight() > 0 {
+			return ErrBusy
+		}
+
+		res := b.measureOne(ctx, p)
+		b.mu.Lock()
+		b.results[resultKey(res.Backend, res.Model)] = res
+		b.mu.Unlock()
+		b.publish()
+	}
+	return nil
+}
+
+// pairs lists the models of every healthy local backend that generates.
+// Cloud backends are skipped, each run would be billed.
+func (b *Benchmark) pairs(ctx context.Context) []pair {
+	list := b.router.ListBackends()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID() < list[j].ID() })
+
+	var pairs []pair
+	for _, backend := range list {
+		if !backend.IsHealthy() || !backend.SupportsGenerate() || backends.IsCloud(backend) {
+			continue
+		}
+		models, err := backend.ListModels(ctx)
+		if err != nil {
+			if logging.Logger != nil {
+				logging.Logger.Warn("Benchmark could not list models",
+					zap.String("backend", backend.ID()), zap.Error(err))
+			}
+			continue
+		}
+		sort.Strings(models)
+		for _, model := range models {
+			pairs = append(pairs, pair{backend: backend, model: model})
+		}
+	}
+	return pairs
+}
+
+// measureOne streams the standard prompt through model on its backend,
+// timing the first token and the generation rate
+func (b *Benchmark) measureOne(ctx context.Context, p pair) Result {
+	ctx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	defer cancel()
+
+	res := Result{Backend: p.backend.ID(), Hardware: p.backend.Hardware(), Model: p.model, RanAt: b.now()}
+	req := &backends.GenerateRequest{
+		Prompt:  b.config.Prompt,
+		Model:   p.model,
+		Options: &backends.GenerationOptions{MaxTokens: b.config.MaxTokens},
+	}
+
+	start := time.Now()
+	var ttft time.Duration
+	var stats *backends.GenerationStats
+	var chunks int32
+	if p.backend.SupportsStream() {
+		stream, err := p.backend.GenerateStream(ctx, req)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		defer stream.Close()
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				res.Error = err.Error()
+				return res
+			}
+			if ttft == 0 && chunk.Token != "" {
+				ttft = time.Since(start)
+			}
+			if chunk.Token != "" {
+				chunks++
+			}
+			if chunk.Stats != nil {
+				stats = chunk.Stats
+			}
+			if chunk.Done {
+				break
+			}
+		}
+	} else {
+		resp, err := p.backend.Generate(ctx, req)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		stats = resp.Stats
+	}
+	total := time.Since(start)
+
+	res.TotalMs = float64(total) / float64(time.Millisecond)
+	res.TTFTMs = float64(ttft) / float64(time.Millisecond)
+	res.Tokens = chunks
+	if stats != nil {
+		if stats.TimeToFirstTokenMs > 0 {
+			res.TTFTMs = float64(stats.TimeToFirstTokenMs)
+		}
+		if stats.TokensGenerated > 0 {
+			res.Tokens = stats.TokensGenerated
+		}
+		res.TokensPerSecond = float64(stats.TokensPerSecond)
+	}
+	if res.TTFTMs == 0 {
+		res.TTFTMs = res.TotalMs
+	}
+	if res.TokensPerSecond == 0 && res.Tokens > 0 {
+		if gen := res.TotalMs - res.TTFTMs; gen > 0 {
+			res.TokensPerSecond = float64(res.Tokens) / (gen / 1000)
+		}
+	}
+	return res
+}
+
+func (b *Benchmark) setRunning(running bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.running = running
+}
+
+// Results returns the latest result of every model, sorted by backend and
+// model
+func (b *Benchmark) Results() []Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	list := make([]Result, 0, len(b.results))
+	for _, r := range b.results {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Backend != list[j].Backend {
+			return list[i].Backend < list[j].Backend
+		}
+		return list[i].Model < list[j].Model
+	})
+	return list
+}
+
+// Status returns the schedule and latest results
+func (b *Benchmark) Status() Status {
+	results := b.Results()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Status{
+		Running: b.running,
+		LastRun: b.lastRun,
+		Window:  formatWindow(b.config.Window),
+		Results: results,
+	}
+}
+
+func formatWindow(w Window) string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// publish hands the successful results to the router and metrics
+func (b *Benchmark) publish() {
+	results := b.Results()
+	table := make([]router.ModelPerformance, 0, len(results))
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		table = append(table, router.ModelPerformance{
+			Backend:         r.Backend,
+			Model:           r.Model,
+			TTFTMs:          r.TTFTMs,
+			TokensPerSecond: r.TokensPerSecond,
+			MeasuredAt:      r.RanAt,
+		})
+		metrics.SetBenchmarkResult(r.Backend, r.Model, r.TTFTMs, r.TokensPerSecond)
+	}
+	b.router.SetModelPerformance(table)
+}
+
+// savedResults is the file format at Config.Path
+type savedResults struct {
+	LastRun time.Time `json:"last_run"`
+	Results []Result  `json:"results"`
+}
+
+func (b *Benchmark) load() error {
+	if b.config.Path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(b.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved savedResults
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", b.config.Path, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastRun = saved.LastRun
+	for _, r := range saved.Results {
+		b.results[resultKey(r.Backend, r.Model)] = r
+	}
+	return nil
+}
+
+// save writes the results, then renames them into place so a crash never
+// leaves a truncated file
+func (b *Benchmark) save() error {
+	if b.config.Path == "" {
+		return nil
+	}
+	results := b.Results()
+	b.mu.Lock()
+	saved := savedResults{LastRun: b.lastRun, Results: results}
+	b.mu.Unlock()
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.config.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to save benchmark results: %w", err)
+	}
+	tmp := b.config.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save benchmark results: %w", err)
+	}
+	if err := os.Rename(tmp, b.config.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save benchmark results: %w", err)
+	}
+	return nil
+}
+
+// ResultsCSVHeader is the column order used for CSV exports
+var ResultsCSVHeader = []string{
+	"ran_at", "backend", "hardware", "model", "ttft_ms", "total_ms", "tokens", "tokens_per_second", "error",
+}
+
+// CSVRow formats a result using ResultsCSVHeader order
+func (r Result) CSVRow() []string {
+	return []string{
+		r.RanAt.UTC().Format(time.RFC3339),
+		r.Backend,
+		r.Hardware,
+		r.Model,
+		strconv.FormatFloat(r.TTFTMs, 'f', 1, 64),
+		strconv.FormatFloat(r.TotalMs, 'f', 1, 64),
+		strconv.FormatInt(int64(r.Tokens), 10),
+		strconv.FormatFloat(r.TokensPerSecond, 'f', 2, 64),
+		r.Error,
+	}
+}
+
+// Handler serves the benchmark admin endpoint. GET returns the status,
+// or the results as CSV with ?format=csv; POST starts a run of every
+// model in the background.
+func (b *Benchmark) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			if req.URL.Query().Get("format") == "csv" {
+				w.Header().Set("Content-Type", "text/csv")
+				w.Header().Set("Content-Disposition", "attachment; filename=benchmarks.csv")
+				cw := csv.NewWriter(w)
+				defer cw.Flush()
+				cw.Write(ResultsCSVHeader)
+				for _, r := range b.Results() {
+					cw.Write(r.CSVRow())
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(b.Status())
+
+		case http.MethodPost:
+			go b.RunNow(context.Background())
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]string{"status": "started"})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
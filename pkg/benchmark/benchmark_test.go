@@ -0,0 +1,202 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// fakeBackend streams a fixed reply for each of its models
+type fakeBackend struct {
+	backends.Backend
+	id       string
+	hardware string
+	models   []string
+	failing  string // model whose generation fails
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeBackend) ID() string             { return f.id }
+func (f *fakeBackend) Hardware() string       { return f.hardware }
+func (f *fakeBackend) IsHealthy() bool        { return true }
+func (f *fakeBackend) SupportsGenerate() bool { return true }
+func (f *fakeBackend) SupportsStream() bool   { return true }
+func (f *fakeBackend) ListModels(ctx context.Context) ([]string, error) {
+	return f.models, nil
+}
+
+func (f *fakeBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	if req.Model == f.failing {
+		return nil, errors.New("model not loaded")
+	}
+	return &fakeStream{chunks: []*backends.StreamChunk{
+		{Token: "2"},
+		{Token: " 3"},
+		{Done: true, Stats: &backends.GenerationStats{TimeToFirstTokenMs: 120, TokensGenerated: 2, TokensPerSecond: 40}},
+	}}, nil
+}
+
+func (f *fakeBackend) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+type fakeStream struct {
+	chunks []*backends.StreamChunk
+}
+
+func (s *fakeStream) Recv() (*backends.StreamChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	c := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return c, nil
+}
+
+func (s *fakeStream) Close() error { return nil }
+
+// fakeRouter records the performance table it is given
+type fakeRouter struct {
+	backends []backends.Backend
+	inFlight int
+
+	mu    sync.Mutex
+	table []router.ModelPerformance
+}
+
+func (r *fakeRouter) ListBackends() []backends.Backend { return r.backends }
+func (r *fakeRouter) InFlight() int                    { return r.inFlight }
+func (r *fakeRouter) SetModelPerformance(table []router.ModelPerformance) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.table = table
+}
+
+func TestParseWindow(t *testing.T) {
+	w, err := ParseWindow("23:30-04:00")
+	if err != nil {
+		t.Fatalf("ParseWindow failed: %v", err)
+	}
+	day := func(h, m int) time.Time { return time.Date(2026, 3, 10, h, m, 0, 0, time.Local) }
+
+	if opened, ok := w.Opened(day(23, 45)); !ok || !opened.Equal(day(23, 30)) {
+		t.Errorf("Expected the window opened at 23:30, got %v, %v", opened, ok)
+	}
+	if opened, ok := w.Opened(day(2, 0)); !ok || !opened.Equal(day(23, 30).AddDate(0, 0, -1)) {
+		t.Errorf("Expected the window opened the previous evening, got %v, %v", opened, ok)
+	}
+	if _, ok := w.Opened(day(12, 0)); ok {
+		t.Error("Expected noon outside the window")
+	}
+
+	for _, bad := range []string{"02:00", "2am-5am", "03:00-03:00"} {
+		if _, err := ParseWindow(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestBenchmark_RunScheduled(t *testing.T) {
+	gpu := &fakeBackend{id: "ollama-gpu", hardware: "nvidia", models: []string{"llama3:8b", "nomic-embed-text"}, failing: "nomic-embed-text"}
+	cloud := &fakeBackend{id: "openai", hardware: backends.HardwareCloud, models: []string{"gpt-4o"}}
+	r := &fakeRouter{backends: []backends.Backend{gpu, cloud}}
+	path := filepath.Join(t.TempDir(), "benchmarks.json")
+
+	b := New(r, Config{Window: Window{Start: 2 * time.Hour, End: 5 * time.Hour}, Path: path})
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	b.now = func() time.Time { return now }
+
+	if err := b.RunScheduled(context.Background()); err != nil || gpu.callCount() != 0 {
+		t.Fatalf("Expected nothing outside the window, got %v after %d calls", err, gpu.callCount())
+	}
+
+	// Traffic holds the run off
+	now = time.Date(2026, 3, 11, 2, 10, 0, 0, time.Local)
+	r.inFlight = 1
+	if err := b.RunScheduled(context.Background()); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Expected ErrBusy while serving, got %v", err)
+	}
+
+	r.inFlight = 0
+	if err := b.RunScheduled(context.Background()); err != nil {
+		t.Fatalf("RunScheduled failed: %v", err)
+	}
+	if cloud.callCount() != 0 {
+		t.Error("Expected cloud backends skipped")
+	}
+	results := b.Results()
+	if len(results) != 2 || results[0].Model != "llama3:8b" || results[0].TTFTMs != 120 || results[0].TokensPerSecond != 40 {
+		t.Fatalf("Unexpected results %+v", results)
+	}
+	if results[1].Error == "" {
+		t.Error("Expected the failing model's error recorded")
+	}
+	if len(r.table) != 1 || r.table[0].Backend != "ollama-gpu" || r.table[0].TTFTMs != 120 {
+		t.Errorf("Expected only the successful result in the routing table, got %+v", r.table)
+	}
+
+	// A later check in the same window doesn't measure again
+	now = now.Add(time.Hour)
+	calls := gpu.callCount()
+	b.RunScheduled(context.Background())
+	if gpu.callCount() != calls {
+		t.Errorf("Expected no new measurements in the same window, got %d", gpu.callCount()-calls)
+	}
+
+	// Results survive a restart
+	restored := &fakeRouter{}
+	b = New(restored, Config{Path: path})
+	if len(b.Results()) != 2 || len(restored.table) != 1 || b.Status().LastRun.IsZero() {
+		t.Errorf("Expected saved results restored, got %+v", b.Status())
+	}
+}
+
+func TestBenchmark_Handler(t *testing.T) {
+	gpu := &fakeBackend{id: "ollama-gpu", hardware: "nvidia", models: []string{"llama3:8b"}}
+	b := New(&fakeRouter{backends: []backends.Backend{gpu}}, Config{})
+	if err := b.RunNow(context.Background()); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	handler := b.Handler()
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/admin/benchmarks", nil))
+	var status Status
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if len(status.Results) != 1 || status.Window != "00:00-00:00" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/admin/benchmarks?format=csv", nil))
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(rows) != 2 || rows[0][0] != "ran_at" || rows[1][3] != "llama3:8b" {
+		t.Errorf("Unexpected CSV export %v, %v", rows, err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodDelete, "/admin/benchmarks", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,367 @@
+// Package cache stores generated responses keyed on prompt, model and
+// sampling options so repeated requests are answered without a backend
+// call. Requests opt in with the CacheEnabled annotation (X-Cache-Enabled).
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+)
+
+const (
+	// DefaultTTL is how long a response stays cached
+	DefaultTTL = time.Hour
+
+	// DefaultMaxEntries bounds the number of cached responses
+	DefaultMaxEntries = 10000
+
+	// snapshotFile holds persisted entries inside Config.Dir
+	snapshotFile = "responses.json"
+
+	// metricsType labels this cache in the cache hit/miss metrics
+	metricsType = "response"
+)
+
+// Config configures the response cache
+type Config struct {
+	TTL        time.Duration
+	MaxEntries int   // least recently used entries are evicted beyond this
+	MaxBytes   int64 // total cached response bytes, 0 = unlimited
+
+	// Dir persists entries across restarts (empty = memory only)
+	Dir string
+}
+
+// DefaultConfig returns an in-memory cache configuration
+func DefaultConfig() Config {
+	return Config{
+		TTL:        DefaultTTL,
+		MaxEntries: DefaultMaxEntries,
+	}
+}
+
+// Entry is a cached generation result
+type Entry struct {
+	Response  string                    `json:"response"`
+	Stats     *backends.GenerationStats `json:"stats,omitempty"`
+	BackendID string                    `json:"backend_id"`
+	Model     string                    `json:"model"`
+	StoredAt  time.Time                 `json:"stored_at"`
+	ExpiresAt time.Time                 `json:"expires_at"`
+}
+
+// Stats summarizes cache usage
+type Stats struct {
+	Entries int    `json:"entries"`
+	Bytes   int64  `json:"bytes"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+// Cache is an LRU response cache with TTL expiry. A nil *Cache is valid and
+// caches nothing, so callers need not check whether caching is enabled.
+type Cache struct {
+	cfg Config
+
+	mu     sync.Mutex
+	ll     *list.List               // front = most recently used
+	items  map[string]*list.Element // key -> element holding *item
+	bytes  int64
+	hits   uint64
+	misses uint64
+
+	saveMu sync.Mutex // serializes snapshot writes
+}
+
+type item struct {
+	key   string
+	entry Entry
+}
+
+// Default is the process-wide response cache (nil = caching disabled)
+var Default *Cache
+
+// SetDefault replaces the process-wide cache
+func SetDefault(c *Cache) {
+	Default = c
+}
+
+// New creates a cache, loading persisted entries from cfg.Dir when set
+func New(cfg Config) (*Cache, error) {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+
+	c := &Cache{
+		cfg:   cfg,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+
+	if cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create cache dir: %w", err)
+		}
+		if err := c.load(); err != nil {
+			return c, fmt.Errorf("load cache snapshot: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Key derives the cache key for a generation request. Keys are scoped to
+// the caller's tenant so cached answers are never shared between tenants.
+func Key(ctx context.Context, model, prompt string, opts *backends.GenerationOptions) string {
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(strconv.Itoa(len(s))))
+		h.Write([]byte{':'})
+		h.Write([]byte(s))
+	}
+
+	write(auth.TenantFromContext(ctx))
+	write(model)
+	write(prompt)
+	if opts != nil {
+		write(fmt.Sprintf("%d|%g|%g|%d|%d", opts.MaxTokens, opts.Temperature, opts.TopP, opts.TopK, opts.ContextLength))
+		for _, stop := range opts.Stop {
+			write(stop)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached entry for key and records a hit or miss
+func (c *Cache) Get(key string) (Entry, bool) {
+	if c == nil {
+		return Entry{}, false
+	}
+
+	c.mu.Lock()
+	elem, ok := c.items[key]
+	if ok && time.Now().After(elem.Value.(*item).entry.ExpiresAt) {
+		c.removeLocked(elem)
+		ok = false
+	}
+	var entry Entry
+	if ok {
+		c.ll.MoveToFront(elem)
+		entry = elem.Value.(*item).entry
+		c.hits++
+	} else {
+		c.misses++
+	}
+	c.mu.Unlock()
+
+	if ok {
+		metrics.RecordCacheHit(metricsType)
+	} else {
+		metrics.RecordCacheMiss(metricsType)
+	}
+	return entry, ok
+}
+
+// Put stores an entry under key. Empty responses are not cached.
+func (c *Cache) Put(key string, entry Entry) {
+	if c == nil || entry.Response == "" {
+		return
+	}
+
+	now := time.Now()
+	if entry.StoredAt.IsZero() {
+		entry.StoredAt = now
+	}
+	entry.ExpiresAt = now.Add(c.cfg.TTL)
+
+	size := int64(len(entry.Response))
+	if c.cfg.MaxBytes > 0 && size > c.cfg.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeLocked(elem)
+	}
+	c.items[key] = c.ll.PushFront(&item{key: key, entry: entry})
+	c.bytes += size
+
+	for c.ll.Len() > c.cfg.MaxEntries || (c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes) {
+		c.removeLocked(c.ll.Back())
+	}
+}
+
+// Purge removes every entry
+func (c *Cache) Purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.bytes = 0
+	c.mu.Unlock()
+}
+
+// Stats returns current usage
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Entries: c.ll.Len(),
+		Bytes:   c.bytes,
+		Hits:    c.hits,
+		Misses:  c.misses,
+	}
+}
+
+func (c *Cache) removeLocked(elem *list.Element) {
+	it := elem.Value.(*item)
+	c.ll.Remove(elem)
+	delete(c.items, it.key)
+	c.bytes -= int64(len(it.entry.Response))
+}
+
+// persisted is the on-disk form of one entry
+type persisted struct {
+	Key   string `json:"key"`
+	Entry Entry  `json:"entry"`
+}
+
+// Save writes unexpired entries to cfg.Dir. It is a no-op for memory-only
+// caches.
+func (c *Cache) Save() error {
+	if c == nil || c.cfg.Dir == "" {
+		return nil
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	snapshot := make([]persisted, 0, c.ll.Len())
+	// Oldest first so load restores the same LRU order
+	for elem := c.ll.Back(); elem != nil; elem = elem.Prev() {
+		it := elem.Value.(*item)
+		if now.Before(it.entry.ExpiresAt) {
+			snapshot = append(snapshot, persisted{Key: it.key, Entry: it.entry})
+		}
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	// Write then rename so a crash never leaves a truncated snapshot
+	path := filepath.Join(c.cfg.Dir, snapshotFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load restores entries written by Save, dropping expired ones
+func (c *Cache) load() error {
+	data, err := os.ReadFile(filepath.Join(c.cfg.Dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshot []persisted
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range snapshot {
+		if now.After(p.Entry.ExpiresAt) {
+			continue
+		}
+		if elem, ok := c.items[p.Key]; ok {
+			c.removeLocked(elem)
+		}
+		c.items[p.Key] = c.ll.PushFront(&item{key: p.Key, entry: p.Entry})
+		c.bytes += int64(len(p.Entry.Response))
+	}
+	for c.ll.Len() > c.cfg.MaxEntries || (c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes) {
+		c.removeLocked(c.ll.Back())
+	}
+	return nil
+}
+
+// RunPersistence saves the cache every interval until ctx ends. Errors are
+// passed to onError when it is not nil.
+func (c *Cache) RunPersistence(ctx context.Context, interval time.Duration, onError func(error)) {
+	if c == nil || c.cfg.Dir == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Save(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Handler serves cache statistics (GET) and purges the cache (DELETE)
+func (c *Cache) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodDelete:
+			c.Purge()
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		response := map[string]interface{}{
+			"ttl_seconds": int64(c.cfg.TTL.Seconds()),
+			"max_entries": c.cfg.MaxEntries,
+			"max_bytes":   c.cfg.MaxBytes,
+			"persistent":  c.cfg.Dir != "",
+			"stats":       c.Stats(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
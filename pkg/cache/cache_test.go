@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestCacheGetPut(t *testing.T) {
+	c, err := New(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := Key(context.Background(), "llama3", "hello", nil)
+	if _, ok := c.Get(key); ok {
+		t.Fatal("Expected miss on empty cache")
+	}
+
+	c.Put(key, Entry{Response: "hi there", BackendID: "ollama-npu"})
+	entry, ok := c.Get(key)
+	if !ok || entry.Response != "hi there" || entry.BackendID != "ollama-npu" {
+		t.Fatalf("Expected cached entry, got %+v %v", entry, ok)
+	}
+
+	// Empty responses are never cached
+	c.Put("empty", Entry{})
+	if _, ok := c.Get("empty"); ok {
+		t.Error("Expected empty response not cached")
+	}
+
+	stats := c.Stats()
+	if stats.Entries != 1 || stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	ctx := context.Background()
+	base := Key(ctx, "llama3", "hello", &backends.GenerationOptions{Temperature: 0.7})
+
+	if base != Key(ctx, "llama3", "hello", &backends.GenerationOptions{Temperature: 0.7}) {
+		t.Error("Expected identical requests to share a key")
+	}
+	if base == Key(ctx, "llama3", "hello", &backends.GenerationOptions{Temperature: 0.2}) {
+		t.Error("Expected temperature to change the key")
+	}
+	if base == Key(ctx, "qwen2.5", "hello", &backends.GenerationOptions{Temperature: 0.7}) {
+		t.Error("Expected model to change the key")
+	}
+	// Length prefixes keep field boundaries unambiguous
+	if Key(ctx, "ab", "c", nil) == Key(ctx, "a", "bc", nil) {
+		t.Error("Expected distinct keys for shifted field boundaries")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c, _ := New(Config{TTL: time.Minute, MaxEntries: 2})
+	c.Put("a", Entry{Response: "1"})
+	c.Put("b", Entry{Response: "2"})
+	c.Get("a") // a is now most recently used
+	c.Put("c", Entry{Response: "3"})
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected least recently used entry evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Expected recently used entry kept")
+	}
+
+	sized, _ := New(Config{TTL: time.Minute, MaxBytes: 5})
+	sized.Put("a", Entry{Response: "abc"})
+	sized.Put("b", Entry{Response: "def"})
+	if stats := sized.Stats(); stats.Entries != 1 || stats.Bytes != 3 {
+		t.Errorf("Expected byte limit to evict, got %+v", stats)
+	}
+	sized.Put("huge", Entry{Response: "too large"})
+	if _, ok := sized.Get("huge"); ok {
+		t.Error("Expected oversized response not cached")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c, _ := New(Config{TTL: time.Millisecond})
+	c.Put("a", Entry{Response: "1"})
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected expired entry to miss")
+	}
+}
+
+func TestCachePersistence(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(Config{TTL: time.Minute, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", Entry{Response: "persisted", Model: "llama3"})
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	restored, err := New(Config{TTL: time.Minute, Dir: dir})
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if entry, ok := restored.Get("a"); !ok || entry.Response != "persisted" {
+		t.Errorf("Expected entry restored from disk, got %+v %v", entry, ok)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	c.Put("a", Entry{Response: "1"})
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected nil cache to miss")
+	}
+	if err := c.Save(); err != nil {
+		t.Errorf("Expected nil cache save to be a no-op, got %v", err)
+	}
+}
+
+func TestCacheHandler(t *testing.T) {
+	c, _ := New(DefaultConfig())
+	c.Put("a", Entry{Response: "1"})
+
+	w := httptest.NewRecorder()
+	c.Handler()(w, httptest.NewRequest(http.MethodDelete, "/admin/cache", nil))
+	if w.Code != http.StatusOK || c.Stats().Entries != 0 {
+		t.Errorf("Expected DELETE to purge, got %d with %+v", w.Code, c.Stats())
+	}
+
+	w = httptest.NewRecorder()
+	c.Handler()(w, httptest.NewRequest(http.MethodPost, "/admin/cache", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", w.Code)
+	}
+}
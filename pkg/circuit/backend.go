@@ -0,0 +1,205 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// Backend wraps a backend's Generate, GenerateStream and Embed calls in a
+// circuit breaker. While the circuit is open the backend reports itself
+// unhealthy so the router sends traffic elsewhere, without waiting for the
+// next health check.
+type Backend struct {
+	backends.Backend
+	breaker *CircuitBreaker
+}
+
+// NewBackend wraps b in a circuit breaker configured by cfg. State changes
+// are logged and exported as metrics.
+func NewBackend(b backends.Backend, cfg Config) *Backend {
+	id := b.ID()
+	next := cfg.OnStateChange
+	cfg.OnStateChange = func(from, to State) {
+		metrics.RecordCircuitState(id, int(to), to.String())
+		if logging.Logger != nil {
+			logging.Logger.Warn("Backend circuit breaker state changed",
+				zap.String("backend_id", id),
+				zap.String("from", from.String()),
+				zap.String("to", to.String()),
+			)
+		}
+		if next != nil {
+			next(from, to)
+		}
+	}
+	metrics.BackendCircuitState.WithLabelValues(id).Set(float64(StateClosed))
+	return &Backend{Backend: b, breaker: New(cfg)}
+}
+
+// Breaker returns the backend's circuit breaker
+func (cb *Backend) Breaker() *CircuitBreaker {
+	return cb.breaker
+}
+
+// Unwrap returns the wrapped backend
+func (cb *Backend) Unwrap() backends.Backend {
+	return cb.Backend
+}
+
+// IsHealthy is false while the circuit rejects requests
+func (cb *Backend) IsHealthy() bool {
+	return cb.breaker.Ready() && cb.Backend.IsHealthy()
+}
+
+// ColdStart asks the wrapped backend whether the model is loaded
+func (cb *Backend) ColdStart(ctx context.Context, model string) *backends.ColdStart {
+	return backends.CheckColdStart(ctx, cb.Backend, model)
+}
+
+// ManagesModels asks the wrapped backend whether it pulls and deletes models
+func (cb *Backend) ManagesModels() bool {
+	return backends.ManagesModels(cb.Backend)
+}
+
+// InstalledModels lists the wrapped backend's models. Model management
+// bypasses the breaker: it is an admin action, not traffic.
+func (cb *Backend) InstalledModels(ctx context.Context) ([]backends.InstalledModel, error) {
+	return backends.InstalledModels(ctx, cb.Backend)
+}
+
+// PullModel pulls a model onto the wrapped backend
+func (cb *Backend) PullModel(ctx context.Context, model string) error {
+	return backends.PullModel(ctx, cb.Backend, model)
+}
+
+// DeleteModel deletes a model from the wrapped backend
+func (cb *Backend) DeleteModel(ctx context.Context, model string) error {
+	return backends.DeleteModel(ctx, cb.Backend, model)
+}
+
+// allow admits a request made with ctx or counts its rejection
+func (cb *Backend) allow(ctx context.Context) (func(error), error) {
+	done, err := cb.breaker.AllowContext(ctx)
+	if err != nil {
+		metrics.RecordCircuitRejected(cb.Backend.ID())
+		return nil, err
+	}
+	return done, nil
+}
+
+// Generate runs through the circuit breaker
+func (cb *Backend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	done, err := cb.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cb.Backend.Generate(ctx, req)
+	done(err)
+	return resp, err
+}
+
+// SupportsSequences asks the wrapped backend whether it generates several
+// completions in one request
+func (cb *Backend) SupportsSequences() bool {
+	return backends.SupportsSequences(cb.Backend)
+}
+
+// GenerateSequences runs through the circuit breaker
+func (cb *Backend) GenerateSequences(ctx context.Context, req *backends.GenerateRequest, n, bestOf int) ([]*backends.GenerateResponse, error) {
+	done, err := cb.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resps, err := backends.NativeSequences(ctx, cb.Backend, req, n, bestOf)
+	done(err)
+	return resps, err
+}
+
+// SupportsImageEdit asks the wrapped backend whether it edits images
+func (cb *Backend) SupportsImageEdit() bool {
+	return backends.SupportsImageEdit(cb.Backend)
+}
+
+// SupportsToolCalling asks the wrapped backend whether it offers models
+// tools
+func (cb *Backend) SupportsToolCalling() bool {
+	return backends.SupportsToolCalling(cb.Backend)
+}
+
+// SupportsDraftVerification asks the wrapped backend whether it scores
+// draft continuations
+func (cb *Backend) SupportsDraftVerification() bool {
+	return backends.SupportsDraftVerification(cb.Backend)
+}
+
+// VerifyDraft runs through the circuit breaker
+func (cb *Backend) VerifyDraft(ctx context.Context, req *backends.GenerateRequest, draft string) (*backends.DraftVerdict, error) {
+	done, err := cb.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	verdict, err := backends.VerifyDraft(ctx, cb.Backend, req, draft)
+	done(err)
+	return verdict, err
+}
+
+// GenerateStream runs through the circuit breaker. The stream's outcome is
+// reported when it ends, so a backend failing mid-stream counts too.
+func (cb *Backend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	done, err := cb.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := cb.Backend.GenerateStream(ctx, req)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	return &reportingStreamReader{StreamReader: reader, done: done}, nil
+}
+
+// Embed runs through the circuit breaker
+func (cb *Backend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	done, err := cb.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cb.Backend.Embed(ctx, req)
+	done(err)
+	return resp, err
+}
+
+// reportingStreamReader reports a stream's outcome to the breaker: the
+// first error other than io.EOF is a failure and reaching the end is a
+// success. A stream closed before its end counts as neither.
+type reportingStreamReader struct {
+	backends.StreamReader
+	done func(error)
+}
+
+// Recv reads the next chunk, reporting the stream's end
+func (r *reportingStreamReader) Recv() (*backends.StreamChunk, error) {
+	chunk, err := r.StreamReader.Recv()
+	switch {
+	case errors.Is(err, io.EOF):
+		r.done(nil)
+	case err != nil:
+		r.done(err)
+	case chunk != nil && chunk.Done:
+		r.done(nil)
+	}
+	return chunk, err
+}
+
+// Close closes the stream
+func (r *reportingStreamReader) Close() error {
+	err := r.StreamReader.Close()
+	r.done(errAbandoned)
+	return err
+}
@@ -0,0 +1,267 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// stubBackend fails Generate while err is set
+type stubBackend struct {
+	backends.Backend
+	err    error
+	chunks []*backends.StreamChunk
+	calls  int
+}
+
+func (s *stubBackend) ID() string      { return "npu" }
+func (s *stubBackend) IsHealthy() bool { return true }
+
+func (s *stubBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &backends.GenerateResponse{Response: "ok"}, nil
+}
+
+func (s *stubBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	s.calls++
+	return &stubStream{chunks: s.chunks, err: s.err}, nil
+}
+
+type stubStream struct {
+	chunks []*backends.StreamChunk
+	err    error
+}
+
+func (s *stubStream) Recv() (*backends.StreamChunk, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *stubStream) Close() error { return nil }
+
+// clock is a manually advanced time source
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestBackend_OpensAndRecovers(t *testing.T) {
+	stub := &stubBackend{err: errors.New("connection refused")}
+	var transitions []string
+	b := NewBackend(stub, Config{
+		MaxFailures:    3,
+		OpenTimeout:    10 * time.Second,
+		HalfOpenProbes: 1,
+		OnStateChange:  func(from, to State) { transitions = append(transitions, to.String()) },
+	})
+	c := &clock{t: time.Now()}
+	b.breaker.now = c.now
+
+	for i := 0; i < 3; i++ {
+		b.Generate(context.Background(), &backends.GenerateRequest{})
+	}
+	if b.IsHealthy() {
+		t.Fatal("Expected an open circuit to report the backend unhealthy")
+	}
+	if _, err := b.Generate(context.Background(), &backends.GenerateRequest{}); !errors.Is(err, ErrOpen) || stub.calls != 3 {
+		t.Fatalf("Expected the open circuit to reject without calling the backend, got %v after %d calls", err, stub.calls)
+	}
+
+	// After the timeout one probe goes through and closes the circuit
+	c.advance(11 * time.Second)
+	if !b.IsHealthy() {
+		t.Fatal("Expected the backend to be offered for probing after the timeout")
+	}
+	stub.err = nil
+	if _, err := b.Generate(context.Background(), &backends.GenerateRequest{}); err != nil {
+		t.Fatalf("Expected the probe to pass, got %v", err)
+	}
+	if got := b.Breaker().GetState(); got != StateClosed {
+		t.Errorf("Expected the circuit closed after a successful probe, got %v", got)
+	}
+	if want := "open,half-open,closed"; strings.Join(transitions, ",") != want {
+		t.Errorf("Expected transitions %s, got %v", want, transitions)
+	}
+}
+
+func TestBreaker_HalfOpenProbeLimit(t *testing.T) {
+	cb := New(Config{MaxFailures: 1, OpenTimeout: time.Second, HalfOpenProbes: 2})
+	c := &clock{t: time.Now()}
+	cb.now = c.now
+
+	cb.Call(func() error { return errors.New("boom") })
+	c.advance(2 * time.Second)
+
+	first, err := cb.Allow()
+	if err != nil {
+		t.Fatalf("Expected a first probe, got %v", err)
+	}
+	second, _ := cb.Allow()
+	if _, err := cb.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected a third concurrent probe to be rejected, got %v", err)
+	}
+	if cb.Ready() {
+		t.Error("Expected the breaker not ready while all probes are in flight")
+	}
+
+	first(nil)
+	if cb.GetState() != StateHalfOpen {
+		t.Errorf("Expected to stay half-open until every probe succeeds, got %v", cb.GetState())
+	}
+	second(nil)
+	if cb.GetState() != StateClosed {
+		t.Errorf("Expected closed after the probes succeeded, got %v", cb.GetState())
+	}
+}
+
+func TestBreaker_ErrorRate(t *testing.T) {
+	cb := New(Config{ErrorRate: 0.5, MinRequests: 10, Window: 10 * time.Second, OpenTimeout: time.Second})
+	c := &clock{t: time.Now()}
+	cb.now = c.now
+
+	// Alternating failures never hit a consecutive limit but do hit the rate
+	for i := 0; i < 9; i++ {
+		cb.Call(func() error {
+			if i%2 == 0 {
+				return errors.New("flap")
+			}
+			return nil
+		})
+	}
+	if cb.GetState() != StateClosed {
+		t.Fatalf("Expected closed below min_requests, got %v", cb.GetState())
+	}
+	cb.Call(func() error { return errors.New("flap") })
+	if cb.GetState() != StateOpen {
+		t.Fatalf("Expected the error rate to open the circuit, got %v", cb.GetState())
+	}
+	if stats := cb.Stats(); stats.Requests != 10 || stats.Failures != 6 {
+		t.Errorf("Expected 6/10 failures in the window, got %+v", stats)
+	}
+
+	// Old failures leave the window
+	cb.Reset()
+	for i := 0; i < 5; i++ {
+		cb.Call(func() error { return errors.New("flap") })
+		cb.Call(func() error { return nil })
+	}
+	c.advance(20 * time.Second)
+	if stats := cb.Stats(); stats.Requests != 0 {
+		t.Errorf("Expected an empty window, got %+v", stats)
+	}
+}
+
+func TestBreaker_CanceledIsNeutral(t *testing.T) {
+	cb := New(Config{MaxFailures: 1, OpenTimeout: time.Second})
+	cb.Call(func() error { return context.Canceled })
+	cb.Call(func() error { return context.DeadlineExceeded })
+	if cb.GetState() != StateClosed || cb.GetFailures() != 0 {
+		t.Errorf("Expected caller cancellations not to count, got %v with %d failures", cb.GetState(), cb.GetFailures())
+	}
+}
+
+func TestBackend_DeadlineOutcome(t *testing.T) {
+	// The backend's own timeout fires while the client is still waiting
+	timeout := fmt.Errorf("Post \"http://ollama:11434/api/generate\": %w", context.DeadlineExceeded)
+	b := NewBackend(&stubBackend{err: timeout}, Config{MaxFailures: 1, OpenTimeout: time.Minute})
+	b.Generate(context.Background(), &backends.GenerateRequest{})
+	if b.Breaker().GetState() != StateOpen {
+		t.Errorf("Expected a backend timeout to open the circuit, got %v", b.Breaker().GetState())
+	}
+
+	// The client cancelled or its own deadline passed
+	for _, err := range []error{context.Canceled, context.DeadlineExceeded} {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		b = NewBackend(&stubBackend{err: err}, Config{MaxFailures: 1, OpenTimeout: time.Minute})
+		b.Generate(ctx, &backends.GenerateRequest{})
+		if stats := b.Breaker().Stats(); stats.State != "closed" || stats.Requests != 0 {
+			t.Errorf("Expected %v after the client gave up not to count, got %+v", err, stats)
+		}
+	}
+}
+
+func TestBackend_StreamOutcome(t *testing.T) {
+	stub := &stubBackend{
+		chunks: []*backends.StreamChunk{{Token: "hi"}},
+		err:    errors.New("connection reset"),
+	}
+	b := NewBackend(stub, Config{MaxFailures: 1, OpenTimeout: time.Minute})
+
+	reader, err := b.GenerateStream(context.Background(), &backends.GenerateRequest{})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if _, err := reader.Recv(); err != nil {
+		t.Fatalf("Expected the first chunk, got %v", err)
+	}
+	if b.Breaker().GetState() != StateClosed {
+		t.Fatal("Expected no outcome before the stream ends")
+	}
+	reader.Recv()
+	reader.Close()
+	if b.Breaker().GetState() != StateOpen {
+		t.Errorf("Expected a failure mid-stream to open the circuit, got %v", b.Breaker().GetState())
+	}
+
+	// A stream abandoned by the client counts as neither
+	b = NewBackend(&stubBackend{chunks: []*backends.StreamChunk{{Token: "hi"}}}, Config{MaxFailures: 1})
+	reader, _ = b.GenerateStream(context.Background(), &backends.GenerateRequest{})
+	reader.Recv()
+	reader.Close()
+	if stats := b.Breaker().Stats(); stats.Requests != 0 {
+		t.Errorf("Expected an abandoned stream not to be counted, got %+v", stats)
+	}
+}
+
+// managingStub is a stubBackend that pulls models
+type managingStub struct {
+	stubBackend
+	pulled []string
+}
+
+func (s *managingStub) ManagesModels() bool { return true }
+func (s *managingStub) InstalledModels(ctx context.Context) ([]backends.InstalledModel, error) {
+	return nil, nil
+}
+func (s *managingStub) PullModel(ctx context.Context, model string) error {
+	s.pulled = append(s.pulled, model)
+	return nil
+}
+func (s *managingStub) DeleteModel(ctx context.Context, model string) error { return nil }
+
+func TestBackend_ModelManagementBypassesBreaker(t *testing.T) {
+	if backends.ManagesModels(NewBackend(&stubBackend{}, Config{MaxFailures: 1})) {
+		t.Error("Expected a backend without model management not to gain it")
+	}
+
+	stub := &managingStub{stubBackend: stubBackend{err: errors.New("boom")}}
+	b := NewBackend(stub, Config{MaxFailures: 1, OpenTimeout: time.Minute})
+	b.Generate(context.Background(), &backends.GenerateRequest{})
+	if b.Breaker().GetState() != StateOpen {
+		t.Fatal("Expected the circuit to open")
+	}
+
+	if err := backends.PullModel(context.Background(), b, "llama3:8b"); err != nil {
+		t.Fatalf("PullModel failed: %v", err)
+	}
+	if len(stub.pulled) != 1 || stub.pulled[0] != "llama3:8b" {
+		t.Errorf("Expected the pull to reach the backend, got %v", stub.pulled)
+	}
+}
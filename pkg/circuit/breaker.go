@@ -1,6 +1,8 @@
 package circuit
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -32,102 +34,275 @@ func (s State) String() string {
 	}
 }
 
+// ErrOpen is returned for requests the breaker does not let through
+var ErrOpen = errors.New("circuit breaker open")
+
+// Config tunes a circuit breaker. The circuit opens on MaxFailures
+// consecutive failures or when ErrorRate of the requests in the last
+// Window failed, whichever comes first.
+type Config struct {
+	MaxFailures int // consecutive failures that open the circuit (0 = off)
+
+	ErrorRate   float64       // failure fraction that opens the circuit (0 = off)
+	MinRequests int           // requests in Window before ErrorRate applies (default 10)
+	Window      time.Duration // error rate window (default 30s)
+
+	// OpenTimeout is how long the circuit stays open before probing
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is how many requests may probe a half-open circuit at
+	// once, and how many must succeed to close it (default 2)
+	HalfOpenProbes int
+
+	// OnStateChange is called, without the lock held, after each transition
+	OnStateChange func(from, to State)
+}
+
+// windowBuckets is the resolution of the error rate window
+const windowBuckets = 10
+
+type bucket struct {
+	start    time.Time
+	requests int
+	failures int
+}
+
 // CircuitBreaker implements the circuit breaker pattern
 type CircuitBreaker struct {
-	mu           sync.RWMutex
-	state        State
-	failures     int
-	successes    int
-	lastFailure  time.Time
+	mu              sync.RWMutex
+	cfg             Config
+	state           State
+	failures        int // consecutive
+	successes       int // in half-open
+	probes          int // in flight in half-open
+	lastFailure     time.Time
 	lastStateChange time.Time
-
-	maxFailures int
-	timeout     time.Duration
-	halfOpenMax int // max successes needed in half-open to close
+	buckets         [windowBuckets]bucket
+	now             func() time.Time
 }
 
-// NewCircuitBreaker creates a new circuit breaker
+// NewCircuitBreaker creates a circuit breaker that opens after maxFailures
+// consecutive failures and probes again after timeout
 func NewCircuitBreaker(maxFailures int, timeout time.Duration) *CircuitBreaker {
+	return New(Config{MaxFailures: maxFailures, OpenTimeout: timeout})
+}
+
+// New creates a circuit breaker from cfg
+func New(cfg Config) *CircuitBreaker {
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 30 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 2 // Need 2 successes to close
+	}
 	return &CircuitBreaker{
-		state:        StateClosed,
-		maxFailures:  maxFailures,
-		timeout:      timeout,
-		halfOpenMax:  2, // Need 2 successes to close
+		cfg:             cfg,
+		state:           StateClosed,
 		lastStateChange: time.Now(),
+		now:             time.Now,
 	}
 }
 
-// Call executes the function with circuit breaker protection
-func (cb *CircuitBreaker) Call(fn func() error) error {
-	cb.mu.Lock()
+// setStateLocked moves to state and returns the notification to run once
+// the lock is released
+func (cb *CircuitBreaker) setStateLocked(state State) func() {
+	from := cb.state
+	if from == state {
+		return func() {}
+	}
+	cb.state = state
+	cb.lastStateChange = cb.now()
+	cb.successes = 0
+	cb.probes = 0
+	if state == StateClosed {
+		cb.failures = 0
+		cb.buckets = [windowBuckets]bucket{}
+	}
+	if cb.cfg.OnStateChange == nil {
+		return func() {}
+	}
+	return func() { cb.cfg.OnStateChange(from, state) }
+}
 
-	// Check if we should transition from open to half-open
-	if cb.state == StateOpen {
-		if time.Since(cb.lastFailure) > cb.timeout {
-			cb.state = StateHalfOpen
-			cb.successes = 0
-			cb.failures = 0
-			cb.lastStateChange = time.Now()
-		} else {
-			cb.mu.Unlock()
-			return fmt.Errorf("circuit breaker open (will retry in %v)",
-				cb.timeout-time.Since(cb.lastFailure))
-		}
+// halfOpenDueLocked reports whether an open circuit has waited long enough
+// to be probed
+func (cb *CircuitBreaker) halfOpenDueLocked(now time.Time) bool {
+	return cb.state == StateOpen && now.Sub(cb.lastFailure) > cb.cfg.OpenTimeout
+}
+
+// Ready reports whether a request would be let through now, so callers
+// such as the router can pick another backend instead
+func (cb *CircuitBreaker) Ready() bool {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	switch cb.state {
+	case StateOpen:
+		return cb.halfOpenDueLocked(cb.now())
+	case StateHalfOpen:
+		return cb.probes < cb.cfg.HalfOpenProbes
+	}
+	return true
+}
+
+// Allow admits a request, returning a function to report its outcome with,
+// or ErrOpen. Requests that end with context.Canceled or
+// context.DeadlineExceeded count as neither success nor failure, since the
+// caller gave up rather than the service failing.
+func (cb *CircuitBreaker) Allow() (func(error), error) {
+	return cb.allow(nil)
+}
+
+// AllowContext is Allow for a request made with ctx. A context error only
+// counts as neither success nor failure once ctx itself is done, when the
+// client cancelled or its deadline passed; a timeout of the service's own,
+// such as its HTTP client's, is a failure.
+func (cb *CircuitBreaker) AllowContext(ctx context.Context) (func(error), error) {
+	return cb.allow(ctx)
+}
+
+// allow admits a request made with ctx, nil if unknown
+func (cb *CircuitBreaker) allow(ctx context.Context) (func(error), error) {
+	cb.mu.Lock()
+	now := cb.now()
+	notify := func() {}
+	if cb.halfOpenDueLocked(now) {
+		notify = cb.setStateLocked(StateHalfOpen)
 	}
 
-	// In half-open state, allow limited requests
-	if cb.state == StateHalfOpen {
-		if cb.failures > 0 {
-			// Already had a failure in half-open, stay open
+	switch cb.state {
+	case StateOpen:
+		retry := cb.cfg.OpenTimeout - now.Sub(cb.lastFailure)
+		cb.mu.Unlock()
+		return nil, fmt.Errorf("%w (will retry in %v)", ErrOpen, retry)
+	case StateHalfOpen:
+		if cb.probes >= cb.cfg.HalfOpenProbes {
 			cb.mu.Unlock()
-			return fmt.Errorf("circuit breaker half-open but failing")
+			notify()
+			return nil, fmt.Errorf("%w (half-open, probes in flight)", ErrOpen)
 		}
+		cb.probes++
 	}
-
+	probe := cb.state == StateHalfOpen
 	cb.mu.Unlock()
+	notify()
 
-	// Execute the function
-	err := fn()
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { cb.done(err, probe, callerGaveUp(ctx, err)) })
+	}, nil
+}
+
+// errAbandoned reports a request the caller stopped before its outcome
+// was known, such as a stream closed early
+var errAbandoned = errors.New("request abandoned")
 
+// callerGaveUp reports whether err is the caller abandoning a request made
+// with ctx rather than the service failing it
+func callerGaveUp(ctx context.Context, err error) bool {
+	if errors.Is(err, errAbandoned) {
+		return true
+	}
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return ctx == nil || ctx.Err() != nil
+}
+
+// done records the outcome of a request admitted as a half-open probe or
+// not. Requests the caller gave up on count as neither.
+func (cb *CircuitBreaker) done(err error, probe, gaveUp bool) {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	now := cb.now()
+	probe = probe && cb.state == StateHalfOpen // not if a probe already decided
+	if probe && cb.probes > 0 {
+		cb.probes--
+	}
 
+	if gaveUp {
+		cb.mu.Unlock()
+		return
+	}
+
+	b := cb.bucketLocked(now)
+	b.requests++
+	notify := func() {}
 	if err != nil {
+		b.failures++
 		cb.failures++
-		cb.lastFailure = time.Now()
-
-		if cb.state == StateHalfOpen {
+		cb.lastFailure = now
+		switch {
+		case probe:
 			// Failure in half-open goes back to open
-			cb.state = StateOpen
-			cb.lastStateChange = time.Now()
-		} else if cb.failures >= cb.maxFailures {
-			// Too many failures, open the circuit
-			cb.state = StateOpen
-			cb.lastStateChange = time.Now()
+			notify = cb.setStateLocked(StateOpen)
+		case cb.state == StateClosed && cb.trippedLocked(now):
+			notify = cb.setStateLocked(StateOpen)
 		}
+	} else {
+		switch {
+		case probe:
+			cb.successes++
+			if cb.successes >= cb.cfg.HalfOpenProbes {
+				// Enough successes, close the circuit
+				notify = cb.setStateLocked(StateClosed)
+			}
+		case cb.state == StateClosed:
+			// Reset failure count on success
+			cb.failures = 0
+		}
+	}
+	cb.mu.Unlock()
+	notify()
+}
 
-		return err
+// bucketLocked returns the window bucket for now, clearing stale ones
+func (cb *CircuitBreaker) bucketLocked(now time.Time) *bucket {
+	width := cb.cfg.Window / windowBuckets
+	start := now.Truncate(width)
+	b := &cb.buckets[(start.UnixNano()/int64(width))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
 	}
+	return b
+}
 
-	// Success
-	if cb.state == StateHalfOpen {
-		cb.successes++
-		if cb.successes >= cb.halfOpenMax {
-			// Enough successes, close the circuit
-			cb.state = StateClosed
-			cb.failures = 0
-			cb.successes = 0
-			cb.lastStateChange = time.Now()
+// windowLocked sums the requests and failures in the error rate window
+func (cb *CircuitBreaker) windowLocked(now time.Time) (requests, failures int) {
+	for _, b := range cb.buckets {
+		if now.Sub(b.start) < cb.cfg.Window {
+			requests += b.requests
+			failures += b.failures
 		}
-	} else if cb.state == StateClosed {
-		// Reset failure count on success
-		cb.failures = 0
 	}
+	return requests, failures
+}
+
+// trippedLocked reports whether a closed circuit should open
+func (cb *CircuitBreaker) trippedLocked(now time.Time) bool {
+	if cb.cfg.MaxFailures > 0 && cb.failures >= cb.cfg.MaxFailures {
+		return true
+	}
+	if cb.cfg.ErrorRate > 0 {
+		requests, failures := cb.windowLocked(now)
+		return requests >= cb.cfg.MinRequests && float64(failures)/float64(requests) >= cb.cfg.ErrorRate
+	}
+	return false
+}
 
-	return nil
+// Call executes the function with circuit breaker protection
+func (cb *CircuitBreaker) Call(fn func() error) error {
+	done, err := cb.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
 }
 
-// State returns the current circuit breaker state
+// GetState returns the current circuit breaker state
 func (cb *CircuitBreaker) GetState() State {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
@@ -141,12 +316,39 @@ func (cb *CircuitBreaker) GetFailures() int {
 	return cb.failures
 }
 
+// Stats is a snapshot of a circuit breaker
+type Stats struct {
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Requests            int       `json:"requests"` // in the error rate window
+	Failures            int       `json:"failures"`
+	ErrorRate           float64   `json:"error_rate"`
+	Since               time.Time `json:"since"` // last state change
+}
+
+// Stats returns a snapshot of the breaker
+func (cb *CircuitBreaker) Stats() Stats {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	requests, failures := cb.windowLocked(cb.now())
+	s := Stats{
+		State:               cb.state.String(),
+		ConsecutiveFailures: cb.failures,
+		Requests:            requests,
+		Failures:            failures,
+		Since:               cb.lastStateChange,
+	}
+	if requests > 0 {
+		s.ErrorRate = float64(failures) / float64(requests)
+	}
+	return s
+}
+
 // Reset manually resets the circuit breaker
 func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
-	cb.state = StateClosed
+	notify := cb.setStateLocked(StateClosed)
 	cb.failures = 0
-	cb.successes = 0
-	cb.lastStateChange = time.Now()
+	cb.mu.Unlock()
+	notify()
 }
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/diagnostics"
+	"github.com/daoneill/ollama-proxy/pkg/maintenance"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// Diagnostics runs the proxy's self-diagnostics. A report with failed
+// checks is returned without an error.
+func (c *Client) Diagnostics(ctx context.Context) (*diagnostics.Report, error) {
+	var report diagnostics.Report
+	err := c.doJSON(ctx, request{
+		method: http.MethodGet,
+		path:   "/admin/diagnostics",
+		retry:  true,
+		accept: []int{http.StatusOK, http.StatusServiceUnavailable}, // 503 carries failed checks
+	}, &report)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Maintenance returns the maintenance mode status
+func (c *Client) Maintenance(ctx context.Context) (*maintenance.Status, error) {
+	var status maintenance.Status
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: "/admin/maintenance", retry: true}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SetMaintenance turns maintenance mode on, rejecting new requests with
+// message and a Retry-After of retryAfter (0 for the proxy default), or
+// off
+func (c *Client) SetMaintenance(ctx context.Context, enabled bool, message string, retryAfter time.Duration) (*maintenance.Status, error) {
+	body := map[string]interface{}{
+		"enabled":             enabled,
+		"message":             message,
+		"retry_after_seconds": int(retryAfter / time.Second),
+	}
+	var status maintenance.Status
+	if err := c.doJSON(ctx, request{method: http.MethodPost, path: "/admin/maintenance", body: body, retry: true}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// KillSwitchResult is the kill switch status after a stop or resume
+type KillSwitchResult struct {
+	router.KillSwitchStatus
+	Cancelled int `json:"cancelled"`
+}
+
+// StopGenerations cancels the in-flight generations on backend, or on
+// every backend when backend is empty. With pause, new requests are
+// rejected until ResumeGenerations.
+func (c *Client) StopGenerations(ctx context.Context, backend string, pause bool) (*KillSwitchResult, error) {
+	return c.killSwitch(ctx, map[string]interface{}{"backend": backend, "pause": pause})
+}
+
+// ResumeGenerations undoes a paused stop
+func (c *Client) ResumeGenerations(ctx context.Context, backend string) (*KillSwitchResult, error) {
+	return c.killSwitch(ctx, map[string]interface{}{"backend": backend, "resume": true})
+}
+
+func (c *Client) killSwitch(ctx context.Context, body map[string]interface{}) (*KillSwitchResult, error) {
+	var result KillSwitchResult
+	// Not retried: a stop that reached the proxy may have cancelled
+	// generations already
+	if err := c.doJSON(ctx, request{method: http.MethodPost, path: "/admin/stop-all", body: body}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Events streams model pull and load progress until ctx is cancelled or fn
+// returns an error. backend and model, if set, filter the events.
+func (c *Client) Events(ctx context.Context, backend, model string, fn func(*backends.Progress) error) error {
+	query := url.Values{}
+	if backend != "" {
+		query.Set("backend", backend)
+	}
+	if model != "" {
+		query.Set("model", model)
+	}
+	path := "/admin/events"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: path, stream: true, retry: true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = readEvents(resp, func(event, data string) error {
+		var p backends.Progress
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return fmt.Errorf("invalid progress event: %w", err)
+		}
+		return fn(&p)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
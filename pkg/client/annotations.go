@@ -0,0 +1,144 @@
+package client
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+)
+
+// Annotations steer how the proxy routes a request. On HTTP they are sent
+// as the X-* routing headers, on gRPC as JobAnnotations; fields marked
+// HTTP only have no gRPC equivalent and are dropped there.
+type Annotations struct {
+	Target          string // backend ID or hardware, e.g. "ollama-nvidia" or "npu"
+	LatencyCritical bool
+	PowerEfficient  bool
+	CacheEnabled    bool
+	MaxLatencyMs    int32
+	MaxPowerWatts   int32
+
+	MediaType string            // text, code, image, audio, realtime; HTTP only
+	Priority  string            // best-effort, normal, high, critical; HTTP only
+	RequestID string            // X-Request-ID, x-request-id metadata on gRPC
+	Deadline  time.Time         // HTTP only
+	Labels    map[string]string // e.g. team=ml, for policies and reports; HTTP only
+
+	// Speculative drafts tokens on a small model for the routed backend to
+	// verify, when it can; HTTP only
+	Speculative bool
+
+	// Ensemble queries several backends and returns the best answer, with
+	// every backend's answer in the response when EnsembleCandidates is
+	// set; HTTP only
+	Ensemble           bool
+	EnsembleCandidates bool
+
+	Custom map[string]string
+}
+
+// proto converts the annotations for a gRPC request
+func (a *Annotations) proto() *pb.JobAnnotations {
+	if a == nil {
+		return nil
+	}
+	return &pb.JobAnnotations{
+		Target:                a.Target,
+		LatencyCritical:       a.LatencyCritical,
+		PreferPowerEfficiency: a.PowerEfficient,
+		CacheEnabled:          a.CacheEnabled,
+		MaxLatencyMs:          a.MaxLatencyMs,
+		MaxPowerWatts:         a.MaxPowerWatts,
+		Custom:                a.Custom,
+	}
+}
+
+// headers converts the annotations to routing headers
+func (a *Annotations) headers() http.Header {
+	h := http.Header{}
+	if a == nil {
+		return h
+	}
+	set := func(key, value string) {
+		if value != "" {
+			h.Set(key, value)
+		}
+	}
+	setBool := func(key string, value bool) {
+		if value {
+			h.Set(key, "true")
+		}
+	}
+	setInt := func(key string, value int64) {
+		if value > 0 {
+			h.Set(key, strconv.FormatInt(value, 10))
+		}
+	}
+
+	set("X-Target-Backend", a.Target)
+	setBool("X-Latency-Critical", a.LatencyCritical)
+	setBool("X-Power-Efficient", a.PowerEfficient)
+	setBool("X-Cache-Enabled", a.CacheEnabled)
+	setInt("X-Max-Latency-Ms", int64(a.MaxLatencyMs))
+	setInt("X-Max-Power-Watts", int64(a.MaxPowerWatts))
+	set("X-Media-Type", a.MediaType)
+	set("X-Priority", a.Priority)
+	set("X-Request-ID", a.RequestID)
+	setBool("X-Speculative", a.Speculative)
+	setBool("X-Ensemble", a.Ensemble)
+	setBool("X-Ensemble-Candidates", a.EnsembleCandidates)
+	if !a.Deadline.IsZero() {
+		setInt("X-Deadline-Ms", a.Deadline.UnixMilli())
+	}
+	if len(a.Labels) > 0 {
+		pairs := make([]string, 0, len(a.Labels))
+		for key, value := range a.Labels {
+			pairs = append(pairs, key+"="+value)
+		}
+		sort.Strings(pairs)
+		h.Set("X-Labels", strings.Join(pairs, ","))
+	}
+	for key, value := range a.Custom {
+		h.Set("X-Custom-"+key, value)
+	}
+	return h
+}
+
+// Routing is how the proxy routed an HTTP request, from its response
+// headers
+type Routing struct {
+	Backend string // X-Backend-Used
+	Reason  string // X-Routing-Reason
+	Cache   string // X-Cache: HIT or MISS, "" when caching is off
+	Hedged  string // X-Hedged-To, the duplicate's backend
+
+	// Speculative decoding: the drafting backend and accepted/drafted
+	// token counts, e.g. "42/56"
+	SpeculativeDraft    string
+	SpeculativeAccepted string
+
+	// Ensemble: the backends queried and how the answer was chosen (vote,
+	// judge or single)
+	EnsembleMembers []string
+	EnsembleMethod  string
+}
+
+func routingFrom(h http.Header) Routing {
+	routing := Routing{
+		Backend: h.Get("X-Backend-Used"),
+		Reason:  h.Get("X-Routing-Reason"),
+		Cache:   h.Get("X-Cache"),
+		Hedged:  h.Get("X-Hedged-To"),
+
+		SpeculativeDraft:    h.Get("X-Speculative-Draft"),
+		SpeculativeAccepted: h.Get("X-Speculative-Accepted"),
+	}
+	if members := h.Get("X-Ensemble-Members"); members != "" {
+		routing.EnsembleMembers = strings.Split(members, ",")
+		routing.EnsembleMethod = h.Get("X-Ensemble-Method")
+	}
+	return routing
+}
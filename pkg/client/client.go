@@ -0,0 +1,302 @@
+// Package client is a Go client for a running proxy: generation over gRPC
+// and the OpenAI-compatible HTTP API with routing annotations, streaming,
+// model management and the admin endpoints. Every call takes a context,
+// and transient failures (unreachable proxy, 429, 502-504, gRPC
+// UNAVAILABLE) are retried with backoff.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Defaults for Config
+const (
+	DefaultHTTPURL      = "http://localhost:8080"
+	DefaultGRPCAddr     = "localhost:50051"
+	DefaultTimeout      = 30 * time.Second
+	DefaultMaxRetries   = 2
+	DefaultRetryBackoff = 200 * time.Millisecond
+)
+
+// maxRetryAfter caps how long a Retry-After header can delay a retry
+const maxRetryAfter = 30 * time.Second
+
+// Config configures a Client
+type Config struct {
+	HTTPURL  string // proxy base URL, default DefaultHTTPURL
+	GRPCAddr string // gRPC address, default DefaultGRPCAddr
+	APIKey   string // sent as a bearer token on HTTP requests and RPCs
+
+	// Timeout bounds each HTTP attempt that does not stream; the context
+	// bounds the whole call. Default DefaultTimeout.
+	Timeout time.Duration
+
+	// MaxRetries is how many times a transient failure is retried; a
+	// negative value disables retries. Default DefaultMaxRetries.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubling after
+	// each. Default DefaultRetryBackoff.
+	RetryBackoff time.Duration
+
+	// HTTPClient sends HTTP requests, default a client without a timeout
+	HTTPClient *http.Client
+
+	// GRPCOptions replace the default dial options (plaintext, or TLS
+	// when TLS is set)
+	GRPCOptions []grpc.DialOption
+
+	// TLS secures the default HTTP client and gRPC connection, e.g. from
+	// LoadTLSConfig. Nil means plaintext gRPC and Go's HTTPS defaults.
+	TLS *tls.Config
+}
+
+// Client talks to one proxy. It is safe for concurrent use.
+type Client struct {
+	cfg     Config
+	baseURL string
+	http    *http.Client
+	conn    *grpc.ClientConn
+	compute pb.ComputeServiceClient
+	models  pb.ModelServiceClient
+	admin   pb.AdminServiceClient
+}
+
+// New creates a client for the proxy. The gRPC connection is established
+// lazily, so an unreachable proxy is not an error here.
+func New(cfg Config) (*Client, error) {
+	if cfg.HTTPURL == "" {
+		cfg.HTTPURL = DefaultHTTPURL
+	}
+	if cfg.GRPCAddr == "" {
+		cfg.GRPCAddr = DefaultGRPCAddr
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	} else if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+		if cfg.TLS != nil {
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = cfg.TLS
+			httpClient.Transport = transport
+		}
+	}
+	opts := cfg.GRPCOptions
+	if len(opts) == 0 {
+		creds := insecure.NewCredentials()
+		if cfg.TLS != nil {
+			creds = credentials.NewTLS(cfg.TLS)
+		}
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	}
+	if cfg.APIKey != "" {
+		opts = append(opts[:len(opts):len(opts)],
+			grpc.WithChainUnaryInterceptor(apiKeyUnaryInterceptor(cfg.APIKey)),
+			grpc.WithChainStreamInterceptor(apiKeyStreamInterceptor(cfg.APIKey)),
+		)
+	}
+
+	conn, err := grpc.NewClient(cfg.GRPCAddr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+	return &Client{
+		cfg:     cfg,
+		baseURL: strings.TrimRight(cfg.HTTPURL, "/"),
+		http:    httpClient,
+		conn:    conn,
+		compute: pb.NewComputeServiceClient(conn),
+		models:  pb.NewModelServiceClient(conn),
+		admin:   pb.NewAdminServiceClient(conn),
+	}, nil
+}
+
+// Close closes the gRPC connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Error is a non-success HTTP response from the proxy
+type Error struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // from Retry-After, 0 if absent
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("proxy returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the request may succeed if retried
+func (e *Error) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// newError builds an Error from a response, taking the message from an
+// OpenAI-style error body when there is one
+func newError(resp *http.Response) *Error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	e := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+		e.Message = apiErr.Error.Message
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		e.RetryAfter = min(time.Duration(secs)*time.Second, maxRetryAfter)
+	}
+	return e
+}
+
+// retry runs attempt until it succeeds, fails permanently or the retries
+// run out. attempt reports whether its error is worth retrying.
+func (c *Client) retry(ctx context.Context, attempt func() (retryable bool, err error)) error {
+	backoff := c.cfg.RetryBackoff
+	for n := 0; ; n++ {
+		retryable, err := attempt()
+		if err == nil || !retryable || n >= c.cfg.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		wait := backoff
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// request describes one HTTP call
+type request struct {
+	method  string
+	path    string
+	body    interface{} // JSON encoded when not nil
+	headers http.Header
+	stream  bool // no per-attempt timeout; the caller reads the body
+	retry   bool // safe to send again
+	accept  []int
+}
+
+// do sends req, retrying transient failures, and returns the response
+// with a status in req.accept (default 200). The caller closes the body.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return nil, err
+		}
+	}
+	accept := req.accept
+	if len(accept) == 0 {
+		accept = []int{http.StatusOK}
+	}
+
+	var resp *http.Response
+	err := c.retry(ctx, func() (bool, error) {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if !req.stream {
+			attemptCtx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+		}
+
+		httpReq, err := http.NewRequestWithContext(attemptCtx, req.method, c.baseURL+req.path, bytes.NewReader(payload))
+		if err != nil {
+			cancel()
+			return false, err
+		}
+		for key, values := range req.headers {
+			httpReq.Header[key] = values
+		}
+		if payload != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		if c.cfg.APIKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+		}
+
+		r, err := c.http.Do(httpReq)
+		if err != nil {
+			cancel()
+			return req.retry, err
+		}
+		for _, code := range accept {
+			if r.StatusCode == code {
+				r.Body = &cancelBody{ReadCloser: r.Body, cancel: cancel}
+				resp = r
+				return false, nil
+			}
+		}
+		apiErr := newError(r)
+		r.Body.Close()
+		cancel()
+		return req.retry && apiErr.Temporary(), apiErr
+	})
+	return resp, err
+}
+
+// doJSON sends req and decodes the response into out, if not nil
+func (c *Client) doJSON(ctx context.Context, req request, out interface{}) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", req.path, err)
+	}
+	return nil
+}
+
+// cancelBody releases an attempt's timeout when its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
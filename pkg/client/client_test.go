@@ -0,0 +1,260 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/http/openai"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestClient(t *testing.T, httpURL, grpcAddr string) *Client {
+	t.Helper()
+	c, err := New(Config{HTTPURL: httpURL, GRPCAddr: grpcAddr, APIKey: "secret", RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestChatCompletion_RetriesAndAnnotations(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, `{"error":{"message":"backend overloaded"}}`, http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Missing API key, got %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("X-Target-Backend") != "ollama-npu" || r.Header.Get("X-Priority") != "high" ||
+			r.Header.Get("X-Labels") != "app=docs,team=ml" || r.Header.Get("X-Custom-Tenant") != "acme" {
+			t.Errorf("Missing routing headers: %v", r.Header)
+		}
+		if r.Header.Get("X-Power-Efficient") != "" {
+			t.Error("Unset annotations should not be sent")
+		}
+		w.Header().Set("X-Backend-Used", "ollama-npu")
+		w.Header().Set("X-Routing-Reason", "explicit target")
+		fmt.Fprint(w, `{"id":"1","choices":[{"message":{"role":"assistant","content":"Paris"}}]}`)
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL, "localhost:0")
+
+	resp, routing, err := c.ChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "qwen2.5:0.5b"}, &Annotations{
+		Target:   "ollama-npu",
+		Priority: "high",
+		Labels:   map[string]string{"team": "ml", "app": "docs"},
+		Custom:   map[string]string{"Tenant": "acme"},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Paris" || routing.Backend != "ollama-npu" || routing.Reason != "explicit target" {
+		t.Errorf("Unexpected response %+v routed %+v", resp, routing)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected one retry, got %d calls", calls.Load())
+	}
+}
+
+func TestChatCompletion_PermanentErrorNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, `{"error":{"message":"model not found"}}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL, "localhost:0")
+
+	_, _, err := c.ChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "missing"}, nil)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "model not found" {
+		t.Fatalf("Expected the 404 as an Error, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected no retries, got %d calls", calls.Load())
+	}
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Backend-Used", "ollama-nvidia")
+		fmt.Fprint(w, ": loading model llama3:8b\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL, "localhost:0")
+
+	var text string
+	routing, err := c.ChatCompletionStream(context.Background(), openai.ChatCompletionRequest{Model: "llama3:8b"}, nil, func(chunk *openai.ChatCompletionChunk) error {
+		text += chunk.Choices[0].Delta.Content
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream failed: %v", err)
+	}
+	if text != "Hello" || routing.Backend != "ollama-nvidia" {
+		t.Errorf("Expected Hello from ollama-nvidia, got %q from %q", text, routing.Backend)
+	}
+}
+
+func TestChatCompletionStream_ErrorEvent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"error\":{\"message\":\"backend disconnected\"}}\n\n")
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL, "localhost:0")
+
+	_, err := c.ChatCompletionStream(context.Background(), openai.ChatCompletionRequest{}, nil, func(*openai.ChatCompletionChunk) error { return nil })
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || streamErr.Message != "backend disconnected" {
+		t.Errorf("Expected the stream error, got %v", err)
+	}
+}
+
+func TestDiagnostics_FailedChecks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/diagnostics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"status":"fail","results":[{"name":"ollama-npu","status":"fail"}]}`)
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL, "localhost:0")
+
+	report, err := c.Diagnostics(context.Background())
+	if err != nil {
+		t.Fatalf("Diagnostics failed: %v", err)
+	}
+	if report.Status != "fail" || len(report.Results) != 1 {
+		t.Errorf("Expected the failed report, got %+v", report)
+	}
+}
+
+// flakyCompute fails each call's first attempt as unreachable
+type flakyCompute struct {
+	pb.UnimplementedComputeServiceServer
+	generateCalls atomic.Int32
+	streamCalls   atomic.Int32
+}
+
+func (f *flakyCompute) Generate(ctx context.Context, req *pb.GenerateRequest) (*pb.GenerateResponse, error) {
+	if f.generateCalls.Add(1) == 1 {
+		return nil, status.Error(codes.Unavailable, "restarting")
+	}
+	return &pb.GenerateResponse{Response: "hi " + req.Annotations.GetTarget()}, nil
+}
+
+func (f *flakyCompute) GenerateStream(req *pb.GenerateRequest, stream pb.ComputeService_GenerateStreamServer) error {
+	switch f.streamCalls.Add(1) {
+	case 1:
+		return status.Error(codes.Unavailable, "restarting")
+	case 2:
+		stream.Send(&pb.GenerateStreamResponse{Token: "a"})
+		stream.Send(&pb.GenerateStreamResponse{Token: "b", Done: true})
+		return nil
+	}
+	// A failure after tokens were sent is not retried
+	stream.Send(&pb.GenerateStreamResponse{Token: "a"})
+	return status.Error(codes.Unavailable, "backend lost")
+}
+
+func TestGRPC_Retries(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	srv := grpc.NewServer()
+	compute := &flakyCompute{}
+	pb.RegisterComputeServiceServer(srv, compute)
+	go srv.Serve(lis)
+	defer srv.Stop()
+	c := newTestClient(t, "http://localhost:0", lis.Addr().String())
+
+	resp, err := c.Generate(context.Background(), GenerateRequest{Prompt: "hello", Annotations: &Annotations{Target: "npu"}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Response != "hi npu" || compute.generateCalls.Load() != 2 {
+		t.Errorf("Expected a retried response, got %q after %d calls", resp.Response, compute.generateCalls.Load())
+	}
+
+	var tokens string
+	err = c.GenerateStream(context.Background(), GenerateRequest{Prompt: "hello"}, func(msg *pb.GenerateStreamResponse) error {
+		tokens += msg.Token
+		return nil
+	})
+	if err != nil || tokens != "ab" {
+		t.Fatalf("Expected a retried stream, got %q, %v", tokens, err)
+	}
+
+	tokens = ""
+	err = c.GenerateStream(context.Background(), GenerateRequest{Prompt: "hello"}, func(msg *pb.GenerateStreamResponse) error {
+		tokens += msg.Token
+		return nil
+	})
+	if status.Code(err) != codes.Unavailable || tokens != "a" || compute.streamCalls.Load() != 3 {
+		t.Errorf("Expected the mid-stream failure without a retry, got %q, %v after %d calls", tokens, err, compute.streamCalls.Load())
+	}
+}
+
+// keyedAdmin answers Reload only for callers presenting the API key
+type keyedAdmin struct {
+	pb.UnimplementedAdminServiceServer
+}
+
+func (keyedAdmin) Reload(ctx context.Context, req *pb.ReloadRequest) (*pb.ReloadResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if got := md.Get("authorization"); len(got) != 1 || got[0] != "Bearer secret" {
+		return nil, status.Errorf(codes.Unauthenticated, "authorization = %v", got)
+	}
+	return &pb.ReloadResponse{ReloadedAtUnix: 1}, nil
+}
+
+func TestGRPC_SendsAPIKey(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterAdminServiceServer(srv, keyedAdmin{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+	c := newTestClient(t, "http://localhost:0", lis.Addr().String())
+
+	if err := c.Reload(context.Background()); err != nil {
+		t.Errorf("Expected the API key in the RPC metadata, got %v", err)
+	}
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	if _, err := LoadTLSConfig("", "client.pem", "", ""); err == nil {
+		t.Error("Expected an error for a certificate without a key")
+	}
+	if _, err := LoadTLSConfig("/nonexistent/ca.pem", "", "", ""); err == nil {
+		t.Error("Expected an error for a missing CA")
+	}
+	cfg, err := LoadTLSConfig("", "", "", "proxy.internal")
+	if err != nil || cfg.ServerName != "proxy.internal" || cfg.RootCAs != nil {
+		t.Errorf("Expected system roots with a server name override, got %+v, %v", cfg, err)
+	}
+}
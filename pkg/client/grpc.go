@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GenerateRequest is a gRPC generation request
+type GenerateRequest struct {
+	Prompt      string
+	Model       string // "" lets the proxy pick
+	Annotations *Annotations
+	Options     *pb.GenerationOptions
+}
+
+func (r GenerateRequest) proto() *pb.GenerateRequest {
+	return &pb.GenerateRequest{
+		Prompt:      r.Prompt,
+		Model:       r.Model,
+		Annotations: r.Annotations.proto(),
+		Options:     r.Options,
+	}
+}
+
+// withRequestID sends the annotations' request ID as x-request-id metadata
+func withRequestID(ctx context.Context, a *Annotations) context.Context {
+	if a == nil || a.RequestID == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "x-request-id", a.RequestID)
+}
+
+// retryableCode reports whether a gRPC error may succeed if retried: the
+// proxy was unreachable or shed the request
+func retryableCode(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// unary calls a unary RPC with retries
+func unary[T any](c *Client, ctx context.Context, call func(context.Context) (T, error)) (T, error) {
+	var out T
+	err := c.retry(ctx, func() (bool, error) {
+		var err error
+		out, err = call(ctx)
+		return retryableCode(err), err
+	})
+	return out, err
+}
+
+// receive calls fn with each message of a server stream until it ends.
+// Opening the stream is retried until the first message arrives; after
+// that a failure is returned, since fn has seen part of the result.
+func receive[T any](c *Client, ctx context.Context, open func(context.Context) (grpc.ServerStreamingClient[T], error), fn func(*T) error) error {
+	return c.retry(ctx, func() (bool, error) {
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := open(streamCtx)
+		if err != nil {
+			return retryableCode(err), err
+		}
+		for received := false; ; received = true {
+			msg, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return false, nil
+			}
+			if err != nil {
+				return !received && retryableCode(err), err
+			}
+			if err := fn(msg); err != nil {
+				return false, err
+			}
+		}
+	})
+}
+
+// Generate generates a completion for a prompt
+func (c *Client) Generate(ctx context.Context, req GenerateRequest) (*pb.GenerateResponse, error) {
+	in := req.proto()
+	ctx = withRequestID(ctx, req.Annotations)
+	return unary(c, ctx, func(ctx context.Context) (*pb.GenerateResponse, error) {
+		return c.compute.Generate(ctx, in)
+	})
+}
+
+// GenerateStream generates a completion, calling fn with each token as it
+// arrives; the last message has Done set and carries the stats. An error
+// from fn stops the stream and is returned.
+func (c *Client) GenerateStream(ctx context.Context, req GenerateRequest, fn func(*pb.GenerateStreamResponse) error) error {
+	in := req.proto()
+	ctx = withRequestID(ctx, req.Annotations)
+	return receive(c, ctx, func(ctx context.Context) (grpc.ServerStreamingClient[pb.GenerateStreamResponse], error) {
+		return c.compute.GenerateStream(ctx, in)
+	}, fn)
+}
+
+// Chat opens a conversation stream: send turns and cancellations on it
+// and receive each reply's tokens, ending with a Done message. The
+// conversation lives on the stream, so Chat is not retried.
+func (c *Client) Chat(ctx context.Context) (pb.ComputeService_ChatClient, error) {
+	return c.compute.Chat(ctx)
+}
+
+// Embed returns the embedding of text
+func (c *Client) Embed(ctx context.Context, text, model string, annotations *Annotations) (*pb.EmbedResponse, error) {
+	in := &pb.EmbedRequest{Text: text, Model: model, Annotations: annotations.proto()}
+	ctx = withRequestID(ctx, annotations)
+	return unary(c, ctx, func(ctx context.Context) (*pb.EmbedResponse, error) {
+		return c.compute.Embed(ctx, in)
+	})
+}
+
+// ListBackends returns the proxy's backends with their health and load
+func (c *Client) ListBackends(ctx context.Context) ([]*pb.BackendInfo, error) {
+	resp, err := unary(c, ctx, func(ctx context.Context) (*pb.ListBackendsResponse, error) {
+		return c.compute.ListBackends(ctx, &pb.ListBackendsRequest{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Backends, nil
+}
+
+// HealthCheck returns the proxy's and each backend's health
+func (c *Client) HealthCheck(ctx context.Context) (*pb.HealthCheckResponse, error) {
+	return unary(c, ctx, func(ctx context.Context) (*pb.HealthCheckResponse, error) {
+		return c.compute.HealthCheck(ctx, &pb.HealthCheckRequest{})
+	})
+}
+
+// ListModels returns the models installed on backendID, or on every
+// backend that manages models when backendID is empty
+func (c *Client) ListModels(ctx context.Context, backendID string) ([]*pb.BackendModels, error) {
+	resp, err := unary(c, ctx, func(ctx context.Context) (*pb.ListModelsResponse, error) {
+		return c.models.ListModels(ctx, &pb.ListModelsRequest{BackendId: backendID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Backends, nil
+}
+
+// PullModel pulls a model onto a backend. fn, which may be nil, is called
+// with the download progress, ending with a message with Done set.
+func (c *Client) PullModel(ctx context.Context, backendID, model string, fn func(*pb.PullModelProgress) error) error {
+	if fn == nil {
+		fn = func(*pb.PullModelProgress) error { return nil }
+	}
+	in := &pb.PullModelRequest{BackendId: backendID, Model: model}
+	return receive(c, ctx, func(ctx context.Context) (grpc.ServerStreamingClient[pb.PullModelProgress], error) {
+		return c.models.PullModel(ctx, in)
+	}, fn)
+}
+
+// DeleteModel deletes a model from a backend
+func (c *Client) DeleteModel(ctx context.Context, backendID, model string) error {
+	_, err := unary(c, ctx, func(ctx context.Context) (*pb.DeleteModelResponse, error) {
+		return c.models.DeleteModel(ctx, &pb.DeleteModelRequest{BackendId: backendID, Model: model})
+	})
+	return err
+}
+
+// Reload makes the proxy reload its configuration file, as on SIGHUP
+func (c *Client) Reload(ctx context.Context) error {
+	_, err := unary(c, ctx, func(ctx context.Context) (*pb.ReloadResponse, error) {
+		return c.admin.Reload(ctx, &pb.ReloadRequest{})
+	})
+	return err
+}
+
+// Drain starts draining the proxy, which then shuts down. reason and
+// timeout (0 for the proxy default) are optional.
+func (c *Client) Drain(ctx context.Context, reason string, timeout time.Duration) (*pb.DrainStatus, error) {
+	in := &pb.DrainRequest{Reason: reason, TimeoutSeconds: int32(timeout / time.Second)}
+	return unary(c, ctx, func(ctx context.Context) (*pb.DrainStatus, error) {
+		return c.admin.Drain(ctx, in)
+	})
+}
+
+// DrainStatus returns the proxy's drain status
+func (c *Client) DrainStatus(ctx context.Context) (*pb.DrainStatus, error) {
+	return unary(c, ctx, func(ctx context.Context) (*pb.DrainStatus, error) {
+		return c.admin.GetDrainStatus(ctx, &pb.GetDrainStatusRequest{})
+	})
+}
+
+// ListAPIKeys returns the proxy's API keys, with their values masked
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*pb.APIKey, error) {
+	resp, err := unary(c, ctx, func(ctx context.Context) (*pb.ListAPIKeysResponse, error) {
+		return c.admin.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// CreateAPIKey creates an API key and returns it. It is not retried, so a
+// lost response cannot leave a second key behind.
+func (c *Client) CreateAPIKey(ctx context.Context, name, tenant string, permissions []string) (*pb.CreateAPIKeyResponse, error) {
+	return c.admin.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: name, Tenant: tenant, Permissions: permissions})
+}
+
+// SetAPIKeyEnabled enables or disables the API key named name
+func (c *Client) SetAPIKeyEnabled(ctx context.Context, name string, enabled bool) (*pb.APIKey, error) {
+	return unary(c, ctx, func(ctx context.Context) (*pb.APIKey, error) {
+		return c.admin.SetAPIKeyEnabled(ctx, &pb.SetAPIKeyEnabledRequest{Name: name, Enabled: enabled})
+	})
+}
+
+// DeleteAPIKey deletes the API key named name
+func (c *Client) DeleteAPIKey(ctx context.Context, name string) error {
+	_, err := unary(c, ctx, func(ctx context.Context) (*pb.DeleteAPIKeyResponse, error) {
+		return c.admin.DeleteAPIKey(ctx, &pb.DeleteAPIKeyRequest{Name: name})
+	})
+	return err
+}
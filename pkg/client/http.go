@@ -0,0 +1,116 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/http/openai"
+)
+
+// StreamError is an error the proxy reported after a stream started
+type StreamError struct {
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	return "stream failed: " + e.Message
+}
+
+// ChatCompletion sends an OpenAI-compatible chat completion
+func (c *Client) ChatCompletion(ctx context.Context, req openai.ChatCompletionRequest, annotations *Annotations) (*openai.ChatCompletionResponse, Routing, error) {
+	req.Stream = false
+	resp, err := c.do(ctx, request{
+		method:  http.MethodPost,
+		path:    "/v1/chat/completions",
+		body:    req,
+		headers: annotations.headers(),
+		retry:   true,
+	})
+	if err != nil {
+		return nil, Routing{}, err
+	}
+	defer resp.Body.Close()
+
+	var out openai.ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, Routing{}, fmt.Errorf("invalid chat completion response: %w", err)
+	}
+	return &out, routingFrom(resp.Header), nil
+}
+
+// ChatCompletionStream streams an OpenAI-compatible chat completion,
+// calling fn with each chunk. The request is retried until the proxy
+// accepts it; an error from fn stops the stream and is returned.
+func (c *Client) ChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest, annotations *Annotations, fn func(*openai.ChatCompletionChunk) error) (Routing, error) {
+	req.Stream = true
+	resp, err := c.do(ctx, request{
+		method:  http.MethodPost,
+		path:    "/v1/chat/completions",
+		body:    req,
+		headers: annotations.headers(),
+		stream:  true,
+		retry:   true,
+	})
+	if err != nil {
+		return Routing{}, err
+	}
+	defer resp.Body.Close()
+	routing := routingFrom(resp.Header)
+
+	err = readEvents(resp, func(event, data string) error {
+		if event == "error" {
+			return streamError(data)
+		}
+		var chunk openai.ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("invalid chunk: %w", err)
+		}
+		return fn(&chunk)
+	})
+	return routing, err
+}
+
+// readEvents calls fn with the event name ("" for plain data) and data of
+// each server-sent event until the stream or [DONE] ends it. Comments,
+// such as cold start and keepalive notices, are skipped.
+func readEvents(resp *http.Response, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			event = ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				return nil
+			}
+			if err := fn(event, data); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// streamError decodes an error event
+func streamError(data string) error {
+	var event struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(data), &event) != nil || event.Error.Message == "" {
+		return &StreamError{Message: data}
+	}
+	return &StreamError{Message: event.Error.Message}
+}
@@ -0,0 +1,168 @@
+package confidence
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// Answer is a generated response to be scored
+type Answer struct {
+	Prompt   string
+	Response string
+	Model    string
+	Backend  backends.Backend
+
+	// TokenLogprobs are the response tokens' log probabilities, nil when
+	// the backend did not report them
+	TokenLogprobs []float64
+}
+
+// ConfidenceScorer judges whether an answer is good enough to return or
+// should be escalated to a bigger backend
+type ConfidenceScorer interface {
+	Score(ctx context.Context, answer *Answer) (*ConfidenceScore, error)
+}
+
+// LogprobUser is implemented by scorers that read Answer.TokenLogprobs, so
+// generations are asked for them
+type LogprobUser interface {
+	UsesLogprobs() bool
+}
+
+// UsesLogprobs reports whether s needs the answer's token logprobs
+func UsesLogprobs(s ConfidenceScorer) bool {
+	u, ok := s.(LogprobUser)
+	return ok && u.UsesLogprobs()
+}
+
+// Score implements ConfidenceScorer with the length, pattern and model
+// heuristics of Estimate
+func (ce *ConfidenceEstimator) Score(_ context.Context, answer *Answer) (*ConfidenceScore, error) {
+	return ce.Estimate(answer.Prompt, answer.Response, answer.Model, answer.Backend), nil
+}
+
+// LogprobScorer scores an answer by how likely the model found its own
+// tokens: the geometric mean token probability, exp(mean logprob). A model
+// that was guessing spreads probability over many tokens, so its answer
+// scores low even when it reads fluently.
+type LogprobScorer struct {
+	// Fallback scores answers from backends that don't report logprobs,
+	// nil = heuristics with DefaultConfig
+	Fallback ConfidenceScorer
+}
+
+// UsesLogprobs is true
+func (s *LogprobScorer) UsesLogprobs() bool {
+	return true
+}
+
+// Score implements ConfidenceScorer
+func (s *LogprobScorer) Score(ctx context.Context, answer *Answer) (*ConfidenceScore, error) {
+	if len(answer.TokenLogprobs) == 0 {
+		fallback := s.Fallback
+		if fallback == nil {
+			fallback = NewConfidenceEstimator(nil)
+		}
+		return fallback.Score(ctx, answer)
+	}
+
+	sum := 0.0
+	for _, lp := range answer.TokenLogprobs {
+		sum += lp
+	}
+	mean := math.Exp(sum / float64(len(answer.TokenLogprobs)))
+	return &ConfidenceScore{
+		Overall:       mean,
+		Reasoning:     fmt.Sprintf("mean token probability %.2f over %d tokens", mean, len(answer.TokenLogprobs)),
+		Uncertainties: make([]string, 0),
+	}, nil
+}
+
+// judgeMaxChars caps the question and answer shown to the judge, which is
+// usually a small model with a short context
+const judgeMaxChars = 4000
+
+const judgePrompt = `You are reviewing an assistant's answer to a user's question.
+
+Question:
+%s
+
+Answer:
+%s
+
+Is this answer adequate: correct, complete and on topic? Reply with only YES or NO.`
+
+// JudgeScorer asks a small judge model whether an answer is adequate
+// before it is escalated to a bigger backend. When the judge reports
+// logprobs, the score is its probability of YES; otherwise YES scores 1 and
+// NO scores 0.
+type JudgeScorer struct {
+	Backend backends.Backend
+	Model   string
+	Timeout time.Duration // 0 = bounded by the request only
+}
+
+// Score implements ConfidenceScorer. It fails when the judge can't be
+// reached or answers with neither YES nor NO.
+func (s *JudgeScorer) Score(ctx context.Context, answer *Answer) (*ConfidenceScore, error) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	resp, err := s.Backend.Generate(ctx, &backends.GenerateRequest{
+		Model:  s.Model,
+		Prompt: fmt.Sprintf(judgePrompt, truncate(answer.Prompt), truncate(answer.Response)),
+		Options: &backends.GenerationOptions{
+			MaxTokens: 4,
+			Logprobs:  true,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("judge %s on %s: %w", s.Model, s.Backend.ID(), err)
+	}
+
+	verdict := strings.ToLower(strings.TrimLeft(resp.Response, " \t\r\n*\"'"))
+	var adequate bool
+	switch {
+	case strings.HasPrefix(verdict, "yes"):
+		adequate = true
+	case strings.HasPrefix(verdict, "no"):
+	default:
+		return nil, fmt.Errorf("judge %s gave no verdict: %q", s.Model, resp.Response)
+	}
+
+	// The first token carries the verdict
+	certainty := 1.0
+	if len(resp.TokenLogprobs) > 0 {
+		certainty = math.Exp(resp.TokenLogprobs[0])
+	}
+	score := &ConfidenceScore{Overall: certainty, Uncertainties: make([]string, 0)}
+	if adequate {
+		score.Reasoning = fmt.Sprintf("judge %s: adequate", s.Model)
+	} else {
+		score.Overall = 1 - certainty
+		score.Reasoning = fmt.Sprintf("judge %s: inadequate", s.Model)
+		score.Uncertainties = append(score.Uncertainties, "Judge model found the answer inadequate")
+	}
+	return score, nil
+}
+
+// truncate cuts text to judgeMaxChars, on a rune boundary
+func truncate(text string) string {
+	if len(text) <= judgeMaxChars {
+		return text
+	}
+	cut := judgeMaxChars
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
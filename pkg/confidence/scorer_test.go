@@ -0,0 +1,107 @@
+package confidence
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// judgeBackend answers every generation with a fixed verdict
+type judgeBackend struct {
+	*mockBackend
+	verdict  string
+	logprobs []float64
+	err      error
+	last     *backends.GenerateRequest
+}
+
+func (j *judgeBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	j.last = req
+	if j.err != nil {
+		return nil, j.err
+	}
+	return &backends.GenerateResponse{Response: j.verdict, TokenLogprobs: j.logprobs}, nil
+}
+
+func TestLogprobScorer(t *testing.T) {
+	scorer := &LogprobScorer{}
+	if !UsesLogprobs(scorer) || UsesLogprobs(NewConfidenceEstimator(nil)) {
+		t.Error("Expected only the logprob scorer to ask for logprobs")
+	}
+
+	answer := &Answer{
+		Response:      "Paris",
+		Model:         "llama3:7b",
+		TokenLogprobs: []float64{math.Log(0.9), math.Log(0.4)},
+	}
+	score, err := scorer.Score(context.Background(), answer)
+	if err != nil {
+		t.Fatalf("Score failed: %v", err)
+	}
+	if math.Abs(score.Overall-0.6) > 1e-9 {
+		t.Errorf("Expected the geometric mean probability 0.6, got %v", score.Overall)
+	}
+
+	// Without logprobs the heuristics decide
+	answer.TokenLogprobs = nil
+	score, err = scorer.Score(context.Background(), answer)
+	if err != nil {
+		t.Fatalf("Score failed: %v", err)
+	}
+	if want := NewConfidenceEstimator(nil).Estimate("", "Paris", "llama3:7b", nil); score.Overall != want.Overall {
+		t.Errorf("Expected the heuristic score %v, got %v", want.Overall, score.Overall)
+	}
+}
+
+func TestJudgeScorer(t *testing.T) {
+	judge := &judgeBackend{mockBackend: &mockBackend{id: "npu"}}
+	scorer := &JudgeScorer{Backend: judge, Model: "qwen2.5:0.5b"}
+	answer := &Answer{Prompt: "What is the capital of France?", Response: "Paris."}
+
+	tests := []struct {
+		verdict  string
+		logprobs []float64
+		want     float64
+	}{
+		{"YES", nil, 1},
+		{" no.", nil, 0},
+		{"**Yes**", []float64{math.Log(0.8)}, 0.8},
+		{"NO", []float64{math.Log(0.75)}, 0.25},
+	}
+	for _, tt := range tests {
+		judge.verdict, judge.logprobs = tt.verdict, tt.logprobs
+		score, err := scorer.Score(context.Background(), answer)
+		if err != nil {
+			t.Fatalf("%q: Score failed: %v", tt.verdict, err)
+		}
+		if math.Abs(score.Overall-tt.want) > 1e-9 {
+			t.Errorf("%q: expected %v, got %v", tt.verdict, tt.want, score.Overall)
+		}
+	}
+	if judge.last.Model != "qwen2.5:0.5b" || !strings.Contains(judge.last.Prompt, "capital of France") || !judge.last.Options.Logprobs {
+		t.Errorf("Unexpected judge request %+v", judge.last)
+	}
+
+	judge.verdict = "It depends"
+	if _, err := scorer.Score(context.Background(), answer); err == nil {
+		t.Error("Expected an error without a verdict")
+	}
+	judge.err = errors.New("connection refused")
+	if _, err := scorer.Score(context.Background(), answer); err == nil {
+		t.Error("Expected an error when the judge fails")
+	}
+
+	// Long answers are cut to fit the judge's context
+	judge.err, judge.verdict = nil, "yes"
+	answer.Response = strings.Repeat("é", judgeMaxChars)
+	if _, err := scorer.Score(context.Background(), answer); err != nil {
+		t.Fatalf("Score failed: %v", err)
+	}
+	if len(judge.last.Prompt) > 2*judgeMaxChars+len(judgePrompt) {
+		t.Errorf("Expected the answer truncated, got a %d byte prompt", len(judge.last.Prompt))
+	}
+}
@@ -115,26 +115,7 @@ func TestApplyEnvOverrides_BackendEndpoint(t *testing.T) {
 	cfg := &Config{}
 	// Create a backend using the Config's Backends type
 	backend := cfg.Backends
-	backend = append(backend, struct {
-		ID       string `yaml:"id"`
-		Type     string `yaml:"type"`
-		Name     string `yaml:"name"`
-		Hardware string `yaml:"hardware"`
-		Enabled  bool   `yaml:"enabled"`
-		Endpoint string `yaml:"endpoint"`
-		Characteristics struct {
-			PowerWatts         float64 `yaml:"power_watts"`
-			AvgLatencyMs       int32   `yaml:"avg_latency_ms"`
-			MaxTokensPerSecond int32   `yaml:"max_tokens_per_second"`
-			Priority           int     `yaml:"priority"`
-		} `yaml:"characteristics"`
-		ModelCapability struct {
-			MaxModelSizeGB         int      `yaml:"max_model_size_gb"`
-			SupportedModelPatterns []string `yaml:"supported_model_patterns"`
-			PreferredModels        []string `yaml:"preferred_models"`
-			ExcludedPatterns       []string `yaml:"excluded_patterns"`
-		} `yaml:"model_capability"`
-	}{
+	backend = append(backend, BackendConfig{
 		ID:       "ollama-npu",
 		Endpoint: "http://old-npu:11434",
 	})
@@ -1,9 +1,28 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/daoneill/ollama-proxy/pkg/audit"
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/authz"
+	"github.com/daoneill/ollama-proxy/pkg/benchmark"
 	"github.com/daoneill/ollama-proxy/pkg/device/virtual"
+	"github.com/daoneill/ollama-proxy/pkg/efficiency"
+	"github.com/daoneill/ollama-proxy/pkg/federation"
+	"github.com/daoneill/ollama-proxy/pkg/http/postprocess"
+	"github.com/daoneill/ollama-proxy/pkg/labels"
+	"github.com/daoneill/ollama-proxy/pkg/netidentity"
 )
 
 // Config structure matching config.yaml
@@ -18,53 +37,187 @@ type Config struct {
 			KeyFile      string `yaml:"key_file"`
 			ClientCAFile string `yaml:"client_ca_file"`
 		} `yaml:"tls"`
-		Auth struct {
-			Enabled bool `yaml:"enabled"`
-			APIKeys map[string]struct {
-				Name        string   `yaml:"name"`
-				Permissions []string `yaml:"permissions"`
-				Enabled     bool     `yaml:"enabled"`
-			} `yaml:"api_keys"`
+		Listeners []ListenerConfig `yaml:"listeners"` // replace host:http_port and tls when set
+		Auth      struct {
+			Enabled bool                    `yaml:"enabled"`
+			APIKeys map[string]APIKeyConfig `yaml:"api_keys"` // key, or "sha256:" and its hex SHA-256
+			Quota   QuotaConfig             `yaml:"quota"`    // keys without their own quota
+
+			// KeyStore is the file keys created through /admin/keys are
+			// saved to, hashed; empty keeps them until restart
+			KeyStore string `yaml:"key_store"`
 		} `yaml:"auth"`
 		RateLimit struct {
-			Enabled bool    `yaml:"enabled"`
-			Rate    float64 `yaml:"rate"`
-			Burst   int     `yaml:"burst"`
+			Enabled bool            `yaml:"enabled"`
+			Rate    float64         `yaml:"rate"` // per client IP
+			Burst   int             `yaml:"burst"`
+			PerKey  RateLimitConfig `yaml:"per_key"` // authenticated keys without their own rate_limit
 		} `yaml:"rate_limit"`
+		Streaming struct {
+			BandwidthAware     bool   `yaml:"bandwidth_aware"`
+			SlowWriteThreshold string `yaml:"slow_write_threshold"` // e.g. "50ms"
+			MaxBatchTokens     int    `yaml:"max_batch_tokens"`
+			MaxBatchDelay      string `yaml:"max_batch_delay"`    // e.g. "250ms"
+			KeepaliveInterval  string `yaml:"keepalive_interval"` // e.g. "15s", "0s" disables
+			IdleTimeout        string `yaml:"idle_timeout"`       // e.g. "2m", empty for no limit
+		} `yaml:"streaming"`
+		HTTP3 struct {
+			Enabled bool `yaml:"enabled"`
+			Port    int  `yaml:"port"` // UDP port, defaults to http_port
+		} `yaml:"http3"`
+		GRPC struct {
+			MaxRecvMsgSizeMB int    `yaml:"max_recv_msg_size_mb"`
+			MaxSendMsgSizeMB int    `yaml:"max_send_msg_size_mb"`
+			Compression      string `yaml:"compression"` // "", "none", "gzip"
+			Keepalive        struct {
+				Time                string `yaml:"time"`    // e.g. "2h"
+				Timeout             string `yaml:"timeout"` // e.g. "20s"
+				MaxConnectionIdle   string `yaml:"max_connection_idle"`
+				MinTime             string `yaml:"min_time"` // minimum client ping interval
+				PermitWithoutStream bool   `yaml:"permit_without_stream"`
+			} `yaml:"keepalive"`
+		} `yaml:"grpc"`
+		Middleware struct {
+			Default     []string            `yaml:"default"` // chain for routes not listed below
+			Routes      map[string][]string `yaml:"routes"`  // exact path or prefix ending in "*"
+			BodyLimitMB int                 `yaml:"body_limit_mb"`
+			CORSOrigins []string            `yaml:"cors_origins"`
+			CacheTTL    string              `yaml:"cache_ttl"` // e.g. "30s"
+			RateLimits  map[string]struct {
+				Rate  float64 `yaml:"rate"`
+				Burst int     `yaml:"burst"`
+			} `yaml:"rate_limits"` // named limiters, referenced as "rate_limit:<name>"
+		} `yaml:"middleware"`
+		Limits struct {
+			BodyMB struct {
+				JSON  int `yaml:"json"`  // generation, embedding, speech and image generation requests; 0 = 10
+				Audio int `yaml:"audio"` // audio transcription uploads; 0 = 25
+				Image int `yaml:"image"` // image edit uploads; 0 = 9
+			} `yaml:"body_mb"`
+			MaxPromptTokens int `yaml:"max_prompt_tokens"` // estimated, 0 = unlimited; keys may set their own
+		} `yaml:"limits"`
+		CORS      CORSConfig `yaml:"cors"`
+		WebSocket struct {
+			// Browser origins allowed to open /v1/stream/ws, e.g.
+			// "https://app.example.com", "https://*.example.com" or "*".
+			// Non-browser clients are always allowed, and same-origin
+			// pages when the proxy is reached under an IP address,
+			// localhost, the machine's hostname or one of Hostnames.
+			AllowedOrigins        []string `yaml:"allowed_origins"`
+			Hostnames             []string `yaml:"hostnames"`               // other names the proxy is served under, e.g. "proxy.lan"
+			MaxConcurrentRequests int      `yaml:"max_concurrent_requests"` // per client, 0 = unlimited
+			MaxMessageKB          int      `yaml:"max_message_kb"`          // largest client message, 0 = 1024
+			TokensPerMinute       int      `yaml:"tokens_per_minute"`       // per client, 0 = unlimited
+		} `yaml:"websocket"`
+		Maintenance struct {
+			Enabled    bool   `yaml:"enabled"`
+			Message    string `yaml:"message"`
+			RetryAfter string `yaml:"retry_after"` // e.g. "10m"
+			Banner     string `yaml:"banner"`      // notice shown in /v1/models
+		} `yaml:"maintenance"`
+		Drain struct {
+			Timeout string `yaml:"timeout"` // how long in-flight requests may finish on shutdown, e.g. "30s"
+		} `yaml:"drain"`
+		Timeouts struct {
+			ReadHeader  string `yaml:"read_header"`  // default "10s"
+			Read        string `yaml:"read"`         // whole request including the body, empty = no limit
+			Write       string `yaml:"write"`        // non-streaming responses, empty = no limit
+			StreamWrite string `yaml:"stream_write"` // streaming responses (SSE, WebSocket), empty = no limit
+			Idle        string `yaml:"idle"`         // keep-alive connections, default "2m"
+		} `yaml:"timeouts"`
+		Dashboard struct {
+			Enabled   bool   `yaml:"enabled"`   // serve the status dashboard at /ui
+			Interval  string `yaml:"interval"`  // between live updates, default "2s"
+			Decisions int    `yaml:"decisions"` // recent routing decisions shown, default 50
+		} `yaml:"dashboard"`
+		Reports struct {
+			Retention   string  `yaml:"retention"`     // e.g. "720h"
+			MaxRecords  int     `yaml:"max_records"`   // in-memory cap on usage records
+			PricePerKWh float64 `yaml:"price_per_kwh"` // electricity price for local hardware
+			Store       struct {
+				Type string `yaml:"type"` // "file" or a registered store (empty = memory only)
+				Path string `yaml:"path"` // file path or store location
+			} `yaml:"store"`
+			Export struct {
+				Type          string            `yaml:"type"`           // "file" or "otlp" (empty = disabled)
+				Path          string            `yaml:"path"`           // file sink
+				Format        string            `yaml:"format"`         // "jsonl" or "csv"
+				MaxSizeMB     int               `yaml:"max_size_mb"`    // rotate after this size
+				MaxBackups    int               `yaml:"max_backups"`    // rotated files to keep
+				Endpoint      string            `yaml:"endpoint"`       // otlp sink, e.g. http://collector:4318/v1/logs
+				Headers       map[string]string `yaml:"headers"`        // otlp request headers
+				BufferSize    int               `yaml:"buffer_size"`    // records held while the sink is down
+				BatchSize     int               `yaml:"batch_size"`
+				FlushInterval string            `yaml:"flush_interval"` // e.g. "5s"
+			} `yaml:"export"`
+		} `yaml:"reports"`
+		MediaIO struct {
+			MBPerSecond float64            `yaml:"mb_per_second"` // per-tenant cap on audio/image/video transfers, 0 = unlimited
+			BurstMB     float64            `yaml:"burst_mb"`      // defaults to one second of mb_per_second
+			Tenants     map[string]float64 `yaml:"tenants"`       // per-tenant mb_per_second overrides
+		} `yaml:"media_io"`
+		Video struct {
+			Dir         string `yaml:"dir"`           // chunked uploads and analysis results (default: under the temp dir)
+			MaxUploadMB int    `yaml:"max_upload_mb"` // largest video accepted, 0 = 8192
+			MaxTotalMB  int    `yaml:"max_total_mb"`  // all open uploads together, 0 = 32768
+
+			// Open uploads per API key (per address without auth), 0 = 4
+			MaxUploadsPerKey int `yaml:"max_uploads_per_key"`
+
+			UploadTTL   string `yaml:"upload_ttl"`    // idle uploads are removed after this, e.g. "24h"
+			ResultTTL   string `yaml:"result_ttl"`    // finished analyses are kept this long, e.g. "24h"
+
+			// How often running generations checkpoint, e.g. "5s"
+			CheckpointInterval string `yaml:"checkpoint_interval"`
+		} `yaml:"video"`
+		Labels struct {
+			Allowed        map[string][]string `yaml:"allowed"`          // label -> allowed values, empty = any value (X-Labels off when unset)
+			MaxLabels      int                 `yaml:"max_labels"`       // per request, 0 = 8
+			MaxValueLength int                 `yaml:"max_value_length"` // 0 = 64
+			RejectInvalid  bool                `yaml:"reject_invalid"`   // 400 instead of dropping labels outside the allowlist
+			Backends       map[string][]string `yaml:"backends"`         // "label=value" -> the only backends its requests may use
+		} `yaml:"labels"`
+
+		// Signed forwarding between proxies
+		Federation FederationConfig `yaml:"federation"`
+
+		// Authenticate tailnet and WireGuard peers by who they are
+		NetworkIdentity NetworkIdentityConfig `yaml:"network_identity"`
+
+		// Roles for client certificates and API keys, checked per route and RPC
+		Authz AuthzConfig `yaml:"authz"`
+
+		// Steps applied in order to final response text on every HTTP protocol
+		PostProcessing struct {
+			Steps []string `yaml:"steps"` // strip_html, normalize_markdown, citations (off when empty)
+		} `yaml:"post_processing"`
+
+		// Defaults for max_tokens and temperature when a request leaves them unset
+		Shaping struct {
+			ShapingConfig `yaml:",inline"`         // every request
+			Models        map[string]ShapingConfig `yaml:"models"` // model name or glob, e.g. "llama3:*"
+			Modes         map[string]ShapingConfig `yaml:"modes"`  // efficiency mode; max_tokens caps the other defaults
+		} `yaml:"shaping"`
+
+		// What the proxy gives up under thermal or battery stress
+		Degradation struct {
+			Interval string                     `yaml:"interval"` // how often system state is checked, default "10s"
+			Profiles []DegradationProfileConfig `yaml:"profiles"` // first match wins, most severe first (off when empty)
+		} `yaml:"degradation"`
 	} `yaml:"server"`
 
-	Backends []struct {
-		ID       string `yaml:"id"`
-		Type     string `yaml:"type"`
-		Name     string `yaml:"name"`
-		Hardware string `yaml:"hardware"`
-		Enabled  bool   `yaml:"enabled"`
-		Endpoint string `yaml:"endpoint"`
-
-		// OpenVINO-specific fields
-		Device    string `yaml:"device"`     // "CPU", "GPU", "NPU" for OpenVINO backends
-		ModelPath string `yaml:"model_path"` // Path to OpenVINO model directory
-		ModelName string `yaml:"model_name"` // Model name/identifier
-
-		Characteristics struct {
-			PowerWatts         float64 `yaml:"power_watts"`
-			AvgLatencyMs       int32   `yaml:"avg_latency_ms"`
-			MaxTokensPerSecond int32   `yaml:"max_tokens_per_second"`
-			Priority           int     `yaml:"priority"`
-		} `yaml:"characteristics"`
-		ModelCapability struct {
-			MaxModelSizeGB         int      `yaml:"max_model_size_gb"`
-			SupportedModelPatterns []string `yaml:"supported_model_patterns"`
-			PreferredModels        []string `yaml:"preferred_models"`
-			ExcludedPatterns       []string `yaml:"excluded_patterns"`
-		} `yaml:"model_capability"`
-	} `yaml:"backends"`
+	Backends []BackendConfig `yaml:"backends"`
 
 	Routing struct {
 		DefaultBackend      string `yaml:"default_backend"`
 		PowerAware          bool   `yaml:"power_aware"`
 		FallbackStrategy    string `yaml:"fallback_strategy"`
 		AutoOptimizeLatency bool   `yaml:"auto_optimize_latency"`
+
+		// DisableCloudEgress keeps every request on this machine: cloud
+		// backends are not started even when configured and enabled
+		DisableCloudEgress bool `yaml:"disable_cloud_egress"`
+
 		Forwarding struct {
 			Enabled              bool     `yaml:"enabled"`
 			MinConfidence        float64  `yaml:"min_confidence"`
@@ -72,6 +225,22 @@ type Config struct {
 			EscalationPath       []string `yaml:"escalation_path"`
 			RespectThermalLimits bool     `yaml:"respect_thermal_limits"`
 			ReturnBestAttempt    bool     `yaml:"return_best_attempt"`
+
+			// AutoPull pulls a model missing from every backend onto the
+			// next escalation rung that can manage models, then retries
+			// there
+			AutoPull        bool   `yaml:"auto_pull"`
+			AutoPullTimeout string `yaml:"auto_pull_timeout"`
+
+			// Scorer judges whether an attempt's answer is confident
+			// enough: heuristic (default, weighted by routing.confidence),
+			// logprob, or judge
+			Scorer string `yaml:"scorer"`
+			Judge  struct {
+				Backend string `yaml:"backend"` // backend running the judge model
+				Model   string `yaml:"model"`   // small model asked "is this answer adequate?"
+				Timeout string `yaml:"timeout"` // e.g. "10s", empty = bounded by the request only
+			} `yaml:"judge"`
 		} `yaml:"forwarding"`
 		Confidence struct {
 			MinLengthChars int     `yaml:"min_length_chars"`
@@ -80,14 +249,188 @@ type Config struct {
 			PatternWeight  float64 `yaml:"pattern_weight"`
 			ModelWeight    float64 `yaml:"model_weight"`
 		} `yaml:"confidence"`
+		LanguageDetection struct {
+			Detector      string  `yaml:"detector"`       // "heuristic" (default), "none", or a registered detector
+			MinConfidence float64 `yaml:"min_confidence"` // below this the language is not routed on (default 0.5)
+		} `yaml:"language_detection"`
+		Scheduling struct {
+			Enabled       bool   `yaml:"enabled"`
+			Policy        string `yaml:"policy"`          // fifo, priority (default), edf, fair_share
+			MaxConcurrent int    `yaml:"max_concurrent"`  // per backend unless overridden, 0 = unlimited
+			MaxQueueDepth int    `yaml:"max_queue_depth"` // waiting requests per backend, 0 = unlimited
+			QueueTimeout  string `yaml:"queue_timeout"`   // e.g. "30s", empty = until the client gives up
+		} `yaml:"scheduling"`
+		Sessions struct {
+			Enabled     bool   `yaml:"enabled"`
+			TTL         string `yaml:"ttl"`          // idle time before a conversation is unpinned, e.g. "30m"
+			MaxSessions int    `yaml:"max_sessions"` // least recently used are evicted beyond this
+
+			// Boost raises interactive conversations a priority level
+			Boost struct {
+				Enabled    bool    `yaml:"enabled"`
+				MaxTurnGap string  `yaml:"max_turn_gap"` // longest pause between turns that counts as interactive, e.g. "2m"
+				HalfLife   string  `yaml:"half_life"`    // time for the interactivity score to halve, e.g. "5m"
+				Threshold  float64 `yaml:"threshold"`    // score at which turns are boosted (default 1.5)
+			} `yaml:"boost"`
+		} `yaml:"sessions"`
+		LoadBalancing struct {
+			Strategy  string            `yaml:"strategy"`   // "score" (default), round_robin, weighted, least_outstanding, ewma_latency
+			Models    map[string]string `yaml:"models"`     // model name or glob -> strategy
+			EWMADecay float64           `yaml:"ewma_decay"` // weight of the newest latency sample (default 0.3)
+		} `yaml:"load_balancing"`
+		// Placement pulls the most requested models onto the backends of
+		// the tier that suits their size
+		Placement struct {
+			Enabled     bool                  `yaml:"enabled"`
+			Interval    string                `yaml:"interval"`     // reconcile period (default 5m)
+			Window      string                `yaml:"window"`       // demand is counted over this window (default 1h)
+			TopModels   int                   `yaml:"top_models"`   // most requested models placed (default 5)
+			MinRequests int                   `yaml:"min_requests"` // requests in the window before placing (default 10)
+			PullTimeout string                `yaml:"pull_timeout"` // per pull (default 30m)
+			Tiers       []PlacementTierConfig `yaml:"tiers"`
+		} `yaml:"placement"`
+		DecisionLog struct {
+			Enabled    bool    `yaml:"enabled"`
+			Path       string  `yaml:"path"`        // JSONL file replayed by `proxyctl route-replay`
+			SampleRate float64 `yaml:"sample_rate"` // fraction of decisions recorded (default 1)
+			MaxSizeMB  int     `yaml:"max_size_mb"` // rotate to path.1 beyond this, 0 = never
+		} `yaml:"decision_log"`
+		CircuitBreaker struct {
+			Enabled        bool    `yaml:"enabled"`
+			MaxFailures    int     `yaml:"max_failures"`     // consecutive failures that open a backend's circuit (default 5)
+			ErrorRate      float64 `yaml:"error_rate"`       // failure fraction over window that opens it, 0 = off
+			MinRequests    int     `yaml:"min_requests"`     // requests in window before error_rate applies (default 10)
+			Window         string  `yaml:"window"`           // error rate window, e.g. "30s"
+			OpenTimeout    string  `yaml:"open_timeout"`     // wait before half-open probing, e.g. "10s"
+			HalfOpenProbes int     `yaml:"half_open_probes"` // concurrent probes, and successes needed to close (default 2)
+		} `yaml:"circuit_breaker"`
+		Hedging struct {
+			Enabled bool   `yaml:"enabled"`
+			Delay   string `yaml:"delay"` // wait for the first backend before duplicating, e.g. "250ms"
+		} `yaml:"hedging"`
+		// RetryAfter bounds the Retry-After sent when a request is turned
+		// away for lack of capacity. It is computed from the queue ahead,
+		// the backend's latency and any thermal cooldown.
+		RetryAfter struct {
+			Min         string `yaml:"min"`          // default "1s"
+			Max         string `yaml:"max"`          // default "2m"
+			DebugHeader bool   `yaml:"debug_header"` // explain it in X-Retry-After-Debug
+		} `yaml:"retry_after"`
+		// MemoryAdmission re-routes requests away from accelerators without
+		// the free memory for the model and its KV cache, as sampled by
+		// devices.utilization
+		MemoryAdmission struct {
+			Enabled              bool   `yaml:"enabled"`
+			HeadroomMB           int    `yaml:"headroom_mb"`            // left free beyond the estimate (default 512)
+			DefaultContextLength int    `yaml:"default_context_length"` // for requests that set none (default 4096)
+			ResidentWindow       string `yaml:"resident_window"`        // a model routed this recently counts as loaded (default 5m)
+		} `yaml:"memory_admission"`
+		// Benchmark times every local (backend, model) pair with a short
+		// standard prompt in a nightly window while the proxy is idle, and
+		// scores backends on the measured latency for each model
+		Benchmark struct {
+			Enabled   bool   `yaml:"enabled"`
+			Window    string `yaml:"window"`     // local time, e.g. "02:00-05:00"
+			Prompt    string `yaml:"prompt"`     // default asks for ten primes
+			MaxTokens int    `yaml:"max_tokens"` // generated per model (default 32)
+			Timeout   string `yaml:"timeout"`    // per backend and model (default 2m)
+			Interval  string `yaml:"interval"`   // how often the window and idleness are checked (default 10m)
+			Path      string `yaml:"path"`       // results kept across restarts
+		} `yaml:"benchmark"`
+		// LatencyPrediction keeps the latency observed per backend, model
+		// and prompt length, which requests' latency is predicted from,
+		// across restarts
+		LatencyPrediction struct {
+			Path         string `yaml:"path"`          // empty = kept in memory only
+			SaveInterval string `yaml:"save_interval"` // default "1m"
+		} `yaml:"latency_prediction"`
+
+		// Speculative decoding for requests sent with X-Speculative: a
+		// small model on the draft backend drafts tokens for backends that
+		// can verify them (vLLM)
+		Speculative struct {
+			Enabled      bool   `yaml:"enabled"`
+			DraftBackend string `yaml:"draft_backend"`
+			DraftModel   string `yaml:"draft_model"`
+			DraftTokens  int    `yaml:"draft_tokens"` // per round, default 8
+			MaxTokens    int    `yaml:"max_tokens"`   // when the request sets none, default 256
+		} `yaml:"speculative"`
+
+		// Ensemble answers requests sent with X-Ensemble, or for one of
+		// the aliases, from several backends at once: the majority of
+		// short, classification-style answers, or the judge's pick of
+		// free-form ones
+		Ensemble struct {
+			Enabled bool                `yaml:"enabled"`
+			Size    int                 `yaml:"size"`    // backends an X-Ensemble request goes to, 2-3 (default 3)
+			Aliases map[string][]string `yaml:"aliases"` // model name requests use -> 1-3 models queried together
+			Judge   struct {
+				Backend string `yaml:"backend"` // backend running the judge model, empty = heuristics
+				Model   string `yaml:"model"`
+				Timeout string `yaml:"timeout"` // e.g. "10s", empty = bounded by the request only
+			} `yaml:"judge"`
+		} `yaml:"ensemble"`
+
+		// Splitting of /v1/embeddings batches across the backends that
+		// embed the model
+		EmbedBatch struct {
+			ChunkSize     int `yaml:"chunk_size"`      // inputs a backend takes at a time, default 32
+			MaxPerBackend int `yaml:"max_per_backend"` // concurrent embeddings without a max_concurrent limit, default 4
+		} `yaml:"embed_batch"`
 	} `yaml:"routing"`
 
+	// Response cache for requests sent with X-Cache-Enabled
+	Cache struct {
+		Enabled    bool   `yaml:"enabled"`
+		Type       string `yaml:"type"` // "memory" (default) or "disk" (memory plus persistence in dir)
+		TTLSeconds int    `yaml:"ttl_seconds"`
+		MaxSizeMB  int    `yaml:"max_size_mb"` // total cached response size, 0 = unlimited
+		MaxEntries int    `yaml:"max_entries"`
+		Dir        string `yaml:"dir"` // snapshot directory for type disk
+	} `yaml:"cache"`
+
 	Monitoring struct {
 		Enabled        bool   `yaml:"enabled"`
 		PrometheusPort int    `yaml:"prometheus_port"`
 		LogLevel       string `yaml:"log_level"`
 		PprofEnabled   bool   `yaml:"pprof_enabled"`
 		PprofPort      int    `yaml:"pprof_port"`
+		CrashLog       struct {
+			Dir      string `yaml:"dir"`       // crash files with stack traces (empty = log only)
+			MaxFiles int    `yaml:"max_files"` // oldest files are removed beyond this
+		} `yaml:"crash_log"`
+		DebugTap struct {
+			Enabled    bool    `yaml:"enabled"`     // stream live decisions and requests at /debug/tap (admin)
+			Rate       float64 `yaml:"rate"`        // events per second per client, default 20
+			MaxClients int     `yaml:"max_clients"` // concurrent taps, default 4
+		} `yaml:"debug_tap"`
+
+		// Energy measures each request's energy from RAPL and NVML
+		// counters instead of estimating it from backend power_watts
+		Energy struct {
+			Enabled  bool     `yaml:"enabled"`
+			Interval string   `yaml:"interval"` // sampling interval, default "1s"
+			Sources  []string `yaml:"sources"`  // rapl, nvml (default both)
+		} `yaml:"energy"`
+		AuditLog struct {
+			Enabled          bool     `yaml:"enabled"`
+			Path             string   `yaml:"path"`               // JSONL file, one record per inference request
+			MaxSizeMB        int      `yaml:"max_size_mb"`        // rotate after this size, 0 = never
+			MaxBackups       int      `yaml:"max_backups"`        // rotated files to keep as path.1 ... path.N
+			PromptHashLength int      `yaml:"prompt_hash_length"` // hex chars of the prompt hash kept (default 16)
+			PromptHashKey    string   `yaml:"prompt_hash_key"`    // HMAC key for prompt hashes (empty = plain SHA-256)
+			Redact           []string `yaml:"redact"`             // fields to leave out: key, tenant, labels, prompt_hash, error
+		} `yaml:"audit_log"`
+		Alerting struct {
+			Enabled     bool   `yaml:"enabled"`
+			Interval    string `yaml:"interval"`     // evaluation interval, e.g. "15s"
+			UsageWindow string `yaml:"usage_window"` // window for latency percentiles, e.g. "5m"
+			Webhooks    map[string]struct {
+				URL     string            `yaml:"url"`
+				Headers map[string]string `yaml:"headers"`
+			} `yaml:"webhooks"` // named webhook notifiers
+			Rules []AlertRuleConfig `yaml:"rules"`
+		} `yaml:"alerting"`
 	} `yaml:"monitoring"`
 
 	Thermal struct {
@@ -103,26 +446,367 @@ type Config struct {
 			Moderate int `yaml:"moderate"`
 			Loud     int `yaml:"loud"`
 		} `yaml:"fan"`
+		Cooldown string `yaml:"cooldown"` // how long overheated hardware is left to cool, default "2m"
+
+		// Shift load away from hardware whose temperature trend reaches
+		// critical within Horizon
+		Prediction struct {
+			Samples int    `yaml:"samples"` // readings the trend is fitted over, default 6
+			Horizon string `yaml:"horizon"` // how far ahead to project, default "1m"
+		} `yaml:"prediction"`
+
+		// Drive fans from per-device curves, capped at the fan limit of
+		// the current efficiency mode
+		FanControl struct {
+			Enabled  bool   `yaml:"enabled"`
+			DryRun   bool   `yaml:"dry_run"`  // log fan speeds without setting them
+			Interval string `yaml:"interval"` // default "5s"
+
+			Devices  map[string]FanDeviceConfig  `yaml:"devices"`  // keyed by hardware as the thermal monitor reports it
+			Profiles map[string]FanProfileConfig `yaml:"profiles"` // keyed by quiet, moderate or loud
+		} `yaml:"fan_control"`
 	} `yaml:"thermal"`
 
 	Efficiency struct {
 		Enabled     bool   `yaml:"enabled"`
 		DefaultMode string `yaml:"default_mode"`
 		DBusEnabled bool   `yaml:"dbus_enabled"`
+
+		// Enforce Quiet mode while a meeting bridge is live
+		QuietDuringMeetings bool   `yaml:"quiet_during_meetings"`
+		MeetingQuietMax     string `yaml:"meeting_quiet_max"` // longest such window, default "2h"
+
+		// Switch modes by time of day; the first active entry wins over
+		// default_mode
+		Schedule []ScheduleEntryConfig `yaml:"schedule"`
 	} `yaml:"efficiency"`
 
 	Pipelines struct {
-		Enabled    bool   `yaml:"enabled"`
-		ConfigFile string `yaml:"config_file"`
+		Enabled    bool               `yaml:"enabled"`
+		ConfigFile string             `yaml:"config_file"`
+		FFmpegPath string             `yaml:"ffmpeg_path"` // decodes live sources, "" = ffmpeg from PATH
+		Sources    []LiveSourceConfig `yaml:"sources"`
+
+		// DefinitionsDir holds pipelines created through the admin API,
+		// one <id>.yaml each ("" = pipelines.d beside config_file)
+		DefinitionsDir string `yaml:"definitions_dir"`
+
+		// Watch reloads pipelines when config_file or a file in
+		// definitions_dir changes
+		Watch bool `yaml:"watch"`
+
+		// Tools are the commands and webhooks tool stages may call; no
+		// others can be called
+		Tools []PipelineToolConfig `yaml:"tools"`
 	} `yaml:"pipelines"`
 
 	Devices struct {
 		Enabled      bool `yaml:"enabled"`
 		AutoDiscover bool `yaml:"auto_discover"`
+
+		// InventoryFile persists discovered devices with their friendly
+		// names and notes ("" = kept in memory only)
+		InventoryFile string `yaml:"inventory_file"`
+
+		// Utilization samples NPU and GPU utilization (NVML, Level Zero,
+		// sysfs) for ListDevices, metrics and routing
+		Utilization struct {
+			Enabled  bool   `yaml:"enabled"`
+			Interval string `yaml:"interval"` // default "5s"
+		} `yaml:"utilization"`
 	} `yaml:"devices"`
 
 	// Virtual device configuration
 	VirtualDevices virtual.Config `yaml:"virtual_devices"`
+
+	// Warm standby failover between two proxies
+	HA struct {
+		Enabled          bool   `yaml:"enabled"`
+		NodeID           string `yaml:"node_id"` // defaults to "<hostname>-<role>"
+		Role             string `yaml:"role"`    // "primary" or "standby"
+		Peer             string `yaml:"peer"`    // other proxy's gRPC address, host:port
+		SharedSecret     string `yaml:"shared_secret"`
+		SyncInterval     string `yaml:"sync_interval"`      // e.g. "2s"
+		FailoverTimeout  string `yaml:"failover_timeout"`   // e.g. "10s"
+		TLSCAFile        string `yaml:"tls_ca_file"`        // verify a TLS peer (empty = plaintext)
+		MirrorConfigPath string `yaml:"mirror_config_path"` // standby writes the primary's config here
+	} `yaml:"ha"`
+}
+
+// BackendConfig describes a single backend entry in config.yaml
+type BackendConfig struct {
+	ID       string `yaml:"id"`
+	Type     string `yaml:"type"`
+	Name     string `yaml:"name"`
+	Hardware string `yaml:"hardware"`
+	Enabled  bool   `yaml:"enabled"`
+	Endpoint string `yaml:"endpoint"`
+
+	// OpenVINO-specific fields
+	Device    string `yaml:"device"`     // "CPU", "GPU", "NPU" for OpenVINO backends
+	ModelPath string `yaml:"model_path"` // Path to OpenVINO model directory
+	ModelName string `yaml:"model_name"` // Model name/identifier
+
+	// Env var holding the API key (vLLM's --api-key, the cloud provider's
+	// key, Stable Diffusion WebUI's --api-auth user:password)
+	APIKeyEnv string `yaml:"api_key_env"`
+
+	// Cloud-specific fields, for backends forwarding to a hosted API
+	Cloud struct {
+		Provider        string            `yaml:"provider"`           // "openai" (default, also OpenAI-compatible APIs) or "anthropic"
+		Models          map[string]string `yaml:"models"`             // requested model -> provider model, "*" for any
+		InputCostPer1K  float64           `yaml:"input_cost_per_1k"`  // USD per 1K prompt tokens
+		OutputCostPer1K float64           `yaml:"output_cost_per_1k"` // USD per 1K completion tokens
+	} `yaml:"cloud"`
+
+	Characteristics struct {
+		PowerWatts         float64 `yaml:"power_watts"`
+		AvgLatencyMs       int32   `yaml:"avg_latency_ms"`
+		MaxTokensPerSecond int32   `yaml:"max_tokens_per_second"`
+		Priority           int     `yaml:"priority"`
+		CostPer1KTokens    float64 `yaml:"cost_per_1k_tokens"` // billed price, e.g. for cloud backends
+		MaxConcurrent      int     `yaml:"max_concurrent"`     // overrides routing.scheduling.max_concurrent
+		Weight             int     `yaml:"weight"`             // share of traffic under the weighted strategy (default 1)
+	} `yaml:"characteristics"`
+	ModelCapability struct {
+		MaxModelSizeGB         int      `yaml:"max_model_size_gb"`
+		SupportedModelPatterns []string `yaml:"supported_model_patterns"`
+		PreferredModels        []string `yaml:"preferred_models"`
+		ExcludedPatterns       []string `yaml:"excluded_patterns"`
+	} `yaml:"model_capability"`
+
+	// Languages lists the prompt languages (ISO 639-1) this backend's models
+	// handle well; routing steers other languages elsewhere. Empty = any.
+	Languages []string `yaml:"languages"`
+
+	// Container runs the backend engine in a Docker/Podman container (optional)
+	Container ContainerConfig `yaml:"container"`
+
+	// Federated marks the endpoint as another proxy: requests to it are
+	// signed and carry the client's identity (needs server.federation).
+	// PeerInstance is that proxy's instance_id; signatures are bound to it.
+	Federated    bool   `yaml:"federated"`
+	PeerInstance string `yaml:"peer_instance"`
+}
+
+// FederationConfig signs requests forwarded to peer proxies (backends with
+// federated set) and verifies requests signed by trusted peers, so the
+// original client's key, tenant and labels are attributed downstream
+type FederationConfig struct {
+	InstanceID     string                          `yaml:"instance_id"`      // names this proxy to peers
+	PrivateKeyFile string                          `yaml:"private_key_file"` // ed25519 PEM, e.g. from "openssl genpkey -algorithm ed25519"
+	TrustedPeers   map[string]FederationPeerConfig `yaml:"trusted_peers"`    // by peer instance ID
+	MaxClockSkew   string                          `yaml:"max_clock_skew"`   // e.g. "1m" (default)
+}
+
+// FederationPeerConfig is a peer proxy whose signed requests are trusted.
+// They are authorized with the peer's permissions; the client key a peer
+// propagates only attributes usage, quotas and audit records.
+type FederationPeerConfig struct {
+	PublicKey   string   `yaml:"public_key"`  // base64, logged by the peer at startup
+	Permissions []string `yaml:"permissions"` // as for an API key, e.g. ["inference"]
+}
+
+// AuthzConfig gives callers a role - admin, inference or read-only - from
+// their client certificate's CN or SANs on gRPC, or their API key's
+// permissions, and checks it on every admin, inference, pipeline and
+// device route and RPC
+type AuthzConfig struct {
+	Enabled         bool              `yaml:"enabled"`
+	ClientCerts     map[string]string `yaml:"client_certs"`      // certificate URI/DNS/email SAN or CN -> role
+	DefaultCertRole string            `yaml:"default_cert_role"` // verified certificates not listed, "" = refused
+	AnonymousRole   string            `yaml:"anonymous_role"`    // callers without a certificate or key, "" = refused
+}
+
+// NetworkIdentityConfig authenticates requests from tailnet or WireGuard
+// peers as the user or machine behind them, without an API key. Requests
+// with an Authorization header, or from peers neither listed nor covered
+// by default, still authenticate with API keys.
+type NetworkIdentityConfig struct {
+	Enabled  bool              `yaml:"enabled"`
+	Provider string            `yaml:"provider"`  // "tailscale" (default) or "wireguard"
+	Socket   string            `yaml:"socket"`    // tailscaled LocalAPI socket, default /var/run/tailscale/tailscaled.sock
+	Networks []string          `yaml:"networks"`  // CIDRs peers connect from, default the tailnet ranges or wireguard peers
+	Peers    map[string]string `yaml:"peers"`     // wireguard: peer AllowedIPs (CIDR or address) -> name
+	CacheTTL string            `yaml:"cache_ttl"` // how long a peer's identity is reused, e.g. "1m" (default)
+
+	// Identities by machine name, tag (tag:<name>) or login name, most
+	// specific match first. Name defaults to the matched name.
+	Identities map[string]APIKeyConfig `yaml:"identities"`
+	Default    *APIKeyConfig           `yaml:"default"` // peers not listed, nil = they need an API key
+}
+
+// CORSConfig lets browser apps on other origins call the inference and
+// WebSocket routes. Preflight requests are answered before authentication.
+type CORSConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	AllowedOrigins   []string `yaml:"allowed_origins"`   // e.g. "https://app.example.com", "https://*.example.com" or "*"
+	AllowedHeaders   []string `yaml:"allowed_headers"`   // request headers, default Authorization, Content-Type and the routing headers; "*" = any
+	ExposedHeaders   []string `yaml:"exposed_headers"`   // response headers, default the routing, request ID and retry headers
+	AllowCredentials bool     `yaml:"allow_credentials"` // cookies and HTTP auth; not with origin "*"
+	MaxAge           string   `yaml:"max_age"`           // how long browsers cache a preflight, e.g. "10m"
+}
+
+// ListenerConfig is an address serving HTTP routes under its own policy,
+// e.g. a loopback admin listener without auth next to a LAN one with TLS
+type ListenerConfig struct {
+	Name           string   `yaml:"name"`
+	Address        string   `yaml:"address"`         // host:port
+	Groups         []string `yaml:"groups"`          // health, admin, inference; empty = all
+	SkipMiddleware []string `yaml:"skip_middleware"` // e.g. auth, loopback addresses only
+	Middleware     []string `yaml:"middleware"`      // runs before each route's own chain
+	TLS            struct {
+		Enabled      bool   `yaml:"enabled"`
+		CertFile     string `yaml:"cert_file"`
+		KeyFile      string `yaml:"key_file"`
+		ClientCAFile string `yaml:"client_ca_file"`
+	} `yaml:"tls"`
+}
+
+// APIKeyConfig describes an API key
+type APIKeyConfig struct {
+	Name        string           `yaml:"name"`
+	Tenant      string           `yaml:"tenant"` // usage reporting group, defaults to name
+	Permissions []string         `yaml:"permissions"`
+	Enabled     bool             `yaml:"enabled"`
+	RateLimit   *RateLimitConfig `yaml:"rate_limit"` // overrides rate_limit.per_key
+	Quota       *QuotaConfig     `yaml:"quota"`      // overrides auth.quota
+	Shaping     *ShapingConfig   `yaml:"shaping"`    // overrides server.shaping
+
+	MaxPromptTokens int `yaml:"max_prompt_tokens"` // overrides server.limits.max_prompt_tokens
+}
+
+// ShapingConfig sets generation defaults for requests that leave
+// max_tokens or temperature unset. Zero max_tokens and an unset
+// temperature apply nothing.
+type ShapingConfig struct {
+	MaxTokens   int32    `yaml:"max_tokens"`
+	Temperature *float32 `yaml:"temperature"`
+}
+
+// ScheduleEntryConfig applies an efficiency mode during a daily time window
+type ScheduleEntryConfig struct {
+	Name  string   `yaml:"name"`
+	Mode  string   `yaml:"mode"`
+	Days  []string `yaml:"days"`  // "mon".."sun", "weekdays", "weekends"; empty = every day
+	Start string   `yaml:"start"` // "HH:MM"
+	End   string   `yaml:"end"`   // "HH:MM", at or before start runs past midnight
+}
+
+// FanDeviceConfig selects how one device's fans are driven
+type FanDeviceConfig struct {
+	Driver string          `yaml:"driver"` // hwmon, nbfc or nvidia
+	Path   string          `yaml:"path"`   // hwmon PWM file, or nvidia GPU index
+	Curve  []FanCurvePoint `yaml:"curve"`  // default ramps to 100% at the critical temperature
+}
+
+// FanCurvePoint is a fan speed for a temperature
+type FanCurvePoint struct {
+	Temperature float64 `yaml:"temperature"`
+	Percent     int     `yaml:"percent"`
+}
+
+// FanProfileConfig overrides how eagerly fans slow down under a profile
+type FanProfileConfig struct {
+	Hysteresis  float64 `yaml:"hysteresis"`   // degrees below a curve point before slowing
+	MinInterval string  `yaml:"min_interval"` // between two changes of a device's speed
+}
+
+// DegradationProfileConfig is applied while all its conditions hold
+type DegradationProfileConfig struct {
+	Name string `yaml:"name"`
+	When struct {
+		Thermal      string `yaml:"thermal"`       // "warning" or "critical": any device that hot or hotter
+		Throttling   bool   `yaml:"throttling"`    // any device throttling
+		OnBattery    bool   `yaml:"on_battery"`
+		BatteryBelow int    `yaml:"battery_below"` // percent, on battery only
+	} `yaml:"when"`
+	DisableEndpoints []string `yaml:"disable_endpoints"` // path prefixes answered with 503, e.g. "/v1/images"
+	MaxTokens        int32    `yaml:"max_tokens"`        // caps every generation, explicit max_tokens included
+	Backends         []string `yaml:"backends"`          // only these serve requests, e.g. the NPU
+	MaxPowerWatts    int32    `yaml:"max_power_watts"`
+}
+
+// QuotaConfig caps an API key's usage per UTC calendar day and month.
+// Tokens count prompt plus completion tokens. Zero is unlimited.
+type QuotaConfig struct {
+	DailyTokens     int64 `yaml:"daily_tokens"`
+	MonthlyTokens   int64 `yaml:"monthly_tokens"`
+	DailyRequests   int64 `yaml:"daily_requests"`
+	MonthlyRequests int64 `yaml:"monthly_requests"`
+}
+
+// RateLimitConfig is an API key's request budget in requests per second.
+// Streaming requests use stream_rate when set, otherwise they share rate.
+// A zero rate is unlimited; a zero burst defaults to the rate.
+type RateLimitConfig struct {
+	Rate        float64 `yaml:"rate"`
+	Burst       int     `yaml:"burst"`
+	StreamRate  float64 `yaml:"stream_rate"`
+	StreamBurst int     `yaml:"stream_burst"`
+}
+
+// AlertRuleConfig is a threshold alert over an internal metric
+type AlertRuleConfig struct {
+	Name      string   `yaml:"name"`
+	Metric    string   `yaml:"metric"`  // e.g. backend_up, latency_p95_ms, temperature_celsius
+	Subject   string   `yaml:"subject"` // backend ID or hardware; "*" or empty for all
+	Op        string   `yaml:"op"`      // ">", ">=", "<", "<=", "==", "!="
+	Threshold float64  `yaml:"threshold"`
+	For       string   `yaml:"for"` // e.g. "2m"
+	Severity  string   `yaml:"severity"`
+	Notify    []string `yaml:"notify"` // "dbus" or webhook names; empty = all
+}
+
+// LiveSourceConfig is an RTSP stream or local camera feeding a pipeline
+type LiveSourceConfig struct {
+	ID        string `yaml:"id"`
+	Type      string `yaml:"type"`     // rtsp or camera
+	URL       string `yaml:"url"`      // rtsp
+	Device    string `yaml:"device"`   // camera: device manager ID or name, or /dev/videoN
+	Pipeline  string `yaml:"pipeline"` // pipeline ID from the pipelines config file
+	Media     string `yaml:"media"`    // video (default) or audio
+	FPS       int    `yaml:"fps"`      // frames decoded per second, default 1
+	Window    string `yaml:"window"`   // media per pipeline run, e.g. "10s"
+	Autostart bool   `yaml:"autostart"`
+}
+
+// PipelineToolConfig is a command or webhook pipeline tool stages may call
+type PipelineToolConfig struct {
+	Name           string            `yaml:"name"`
+	Type           string            `yaml:"type"`             // command or http
+	Command        []string          `yaml:"command"`          // command: absolute program path and arguments, run without a shell
+	Env            []string          `yaml:"env"`              // command: KEY=value, the whole environment
+	Dir            string            `yaml:"dir"`              // command: working directory ("" = a fresh temporary one)
+	URL            string            `yaml:"url"`              // http: webhook the input is POSTed to
+	Headers        map[string]string `yaml:"headers"`          // http
+	Timeout        string            `yaml:"timeout"`          // default 30s
+	MaxOutputBytes int64             `yaml:"max_output_bytes"` // default 1 MiB
+}
+
+// PlacementTierConfig is the backends that hold models up to a size
+type PlacementTierConfig struct {
+	MaxParamsB float64  `yaml:"max_params_b"` // billions of parameters, 0 = no limit
+	Backends   []string `yaml:"backends"`
+}
+
+// ContainerConfig declares a container managed through the Docker/Podman API
+type ContainerConfig struct {
+	Runtime      string         `yaml:"runtime"` // "docker" or "podman"
+	Socket       string         `yaml:"socket"`  // defaults to the runtime's standard socket
+	Name         string         `yaml:"name"`    // defaults to "ollama-proxy-<backend id>"
+	Image        string         `yaml:"image"`
+	Command      []string       `yaml:"command"`
+	Env          []string       `yaml:"env"`
+	Ports        map[string]int `yaml:"ports"` // container port -> host port
+	Volumes      []string       `yaml:"volumes"`
+	GPUs         string         `yaml:"gpus"`    // "all", count, or device IDs "0,1"
+	Devices      []string       `yaml:"devices"` // e.g. "/dev/dri"
+	ShmSizeMB    int            `yaml:"shm_size_mb"`
+	HealthPath   string         `yaml:"health_path"` // polled on endpoint after start
+	OnDemand     bool           `yaml:"on_demand"`
+	IdleTimeout  string         `yaml:"idle_timeout"`  // e.g. "15m"
+	StartTimeout string         `yaml:"start_timeout"` // e.g. "5m"
 }
 
 // ValidateConfig validates the configuration
@@ -148,6 +832,317 @@ func ValidateConfig(cfg *Config) error {
 		}
 	}
 
+	if err := validateListeners(cfg); err != nil {
+		return err
+	}
+
+	// Validate HTTP/3 configuration (QUIC always requires TLS)
+	if cfg.Server.HTTP3.Enabled {
+		if !cfg.Server.TLS.Enabled {
+			return fmt.Errorf("HTTP/3 enabled but TLS is disabled (QUIC requires TLS)")
+		}
+		// 0 shares the TCP http_port number
+		if cfg.Server.HTTP3.Port < 0 || cfg.Server.HTTP3.Port > 65535 {
+			return fmt.Errorf("invalid HTTP/3 port: %d (must be 1-65535, or 0 for http_port)", cfg.Server.HTTP3.Port)
+		}
+	}
+
+	// Validate gRPC server options
+	if cfg.Server.GRPC.MaxRecvMsgSizeMB < 0 {
+		return fmt.Errorf("gRPC max_recv_msg_size_mb cannot be negative: %d", cfg.Server.GRPC.MaxRecvMsgSizeMB)
+	}
+	if cfg.Server.GRPC.MaxSendMsgSizeMB < 0 {
+		return fmt.Errorf("gRPC max_send_msg_size_mb cannot be negative: %d", cfg.Server.GRPC.MaxSendMsgSizeMB)
+	}
+	switch cfg.Server.GRPC.Compression {
+	case "", "none", "gzip":
+		// valid
+	default:
+		return fmt.Errorf("invalid gRPC compression: %s (must be none or gzip)", cfg.Server.GRPC.Compression)
+	}
+
+	// Validate middleware chains
+	if err := validateMiddleware(cfg); err != nil {
+		return err
+	}
+
+	// Validate request size limits
+	if lim := cfg.Server.Limits; lim.BodyMB.JSON < 0 || lim.BodyMB.Audio < 0 || lim.BodyMB.Image < 0 || lim.MaxPromptTokens < 0 {
+		return fmt.Errorf("server limits cannot be negative")
+	}
+	for _, key := range cfg.Server.Auth.APIKeys {
+		if key.MaxPromptTokens < 0 {
+			return fmt.Errorf("api key %s max_prompt_tokens cannot be negative", key.Name)
+		}
+	}
+
+	// Validate API keys given by their hash
+	for key, info := range cfg.Server.Auth.APIKeys {
+		hash, ok := strings.CutPrefix(key, auth.HashPrefix)
+		if !ok {
+			continue
+		}
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("api key %s: %s must be followed by a hex SHA-256", info.Name, auth.HashPrefix)
+		}
+	}
+
+	// Validate rate limits
+	if rl := cfg.Server.RateLimit; rl.Rate < 0 || rl.Burst < 0 {
+		return fmt.Errorf("rate_limit rate and burst cannot be negative")
+	}
+	if err := cfg.Server.RateLimit.PerKey.validate(); err != nil {
+		return fmt.Errorf("rate_limit per_key: %w", err)
+	}
+	for _, key := range cfg.Server.Auth.APIKeys {
+		if key.RateLimit == nil {
+			continue
+		}
+		if err := key.RateLimit.validate(); err != nil {
+			return fmt.Errorf("api key %s rate_limit: %w", key.Name, err)
+		}
+	}
+
+	// Validate usage quotas
+	if err := cfg.Server.Auth.Quota.validate(); err != nil {
+		return fmt.Errorf("auth quota: %w", err)
+	}
+	for _, key := range cfg.Server.Auth.APIKeys {
+		if key.Quota == nil {
+			continue
+		}
+		if err := key.Quota.validate(); err != nil {
+			return fmt.Errorf("api key %s quota: %w", key.Name, err)
+		}
+	}
+
+	// Validate request shaping
+	shaping := cfg.Server.Shaping
+	if err := shaping.validate(); err != nil {
+		return fmt.Errorf("server shaping: %w", err)
+	}
+	for pattern, defaults := range shaping.Models {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("server shaping model %q: invalid pattern", pattern)
+		}
+		if err := defaults.validate(); err != nil {
+			return fmt.Errorf("server shaping model %s: %w", pattern, err)
+		}
+	}
+	for mode, defaults := range shaping.Modes {
+		if !shapingModes[mode] {
+			return fmt.Errorf("server shaping: invalid efficiency mode: %s (must be Performance, Balanced, Efficiency, Quiet or UltraEfficiency)", mode)
+		}
+		if err := defaults.validate(); err != nil {
+			return fmt.Errorf("server shaping mode %s: %w", mode, err)
+		}
+	}
+	for _, key := range cfg.Server.Auth.APIKeys {
+		if key.Shaping == nil {
+			continue
+		}
+		if err := key.Shaping.validate(); err != nil {
+			return fmt.Errorf("api key %s shaping: %w", key.Name, err)
+		}
+	}
+
+	// Validate streaming pacing configuration
+	if cfg.Server.Streaming.MaxBatchTokens < 0 {
+		return fmt.Errorf("streaming max_batch_tokens cannot be negative: %d",
+			cfg.Server.Streaming.MaxBatchTokens)
+	}
+	for field, value := range map[string]string{
+		"keepalive_interval": cfg.Server.Streaming.KeepaliveInterval,
+		"idle_timeout":       cfg.Server.Streaming.IdleTimeout,
+	} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("streaming %s must be a non-negative duration: %q", field, value)
+		}
+	}
+
+	// Validate shutdown drain
+	if timeout := cfg.Server.Drain.Timeout; timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			return fmt.Errorf("drain timeout must be a positive duration: %q", timeout)
+		}
+	}
+
+	// Validate HTTP server timeouts
+	for field, value := range map[string]string{
+		"read_header":  cfg.Server.Timeouts.ReadHeader,
+		"read":         cfg.Server.Timeouts.Read,
+		"write":        cfg.Server.Timeouts.Write,
+		"stream_write": cfg.Server.Timeouts.StreamWrite,
+		"idle":         cfg.Server.Timeouts.Idle,
+	} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("server timeouts %s must be a non-negative duration: %q", field, value)
+		}
+	}
+
+	// Validate the dashboard
+	if interval := cfg.Server.Dashboard.Interval; interval != "" {
+		if d, err := time.ParseDuration(interval); err != nil || d <= 0 {
+			return fmt.Errorf("dashboard interval must be a positive duration: %q", interval)
+		}
+	}
+	if cfg.Server.Dashboard.Decisions < 0 {
+		return fmt.Errorf("dashboard decisions cannot be negative: %d", cfg.Server.Dashboard.Decisions)
+	}
+
+	// Validate usage report pricing
+	if cfg.Server.Reports.PricePerKWh < 0 {
+		return fmt.Errorf("reports price_per_kwh cannot be negative: %.4f",
+			cfg.Server.Reports.PricePerKWh)
+	}
+	if cfg.Server.Reports.MaxRecords < 0 {
+		return fmt.Errorf("reports max_records cannot be negative: %d",
+			cfg.Server.Reports.MaxRecords)
+	}
+
+	if cfg.Server.Reports.Store.Type == "file" && cfg.Server.Reports.Store.Path == "" {
+		return fmt.Errorf("reports store type file requires a path")
+	}
+
+	if err := validateUsageExport(cfg); err != nil {
+		return err
+	}
+
+	// Validate media I/O throughput caps
+	if cfg.Server.MediaIO.MBPerSecond < 0 {
+		return fmt.Errorf("media_io mb_per_second cannot be negative: %.2f", cfg.Server.MediaIO.MBPerSecond)
+	}
+	if cfg.Server.Video.MaxUploadMB < 0 {
+		return fmt.Errorf("video max_upload_mb cannot be negative: %d", cfg.Server.Video.MaxUploadMB)
+	}
+	if cfg.Server.Video.MaxTotalMB < 0 {
+		return fmt.Errorf("video max_total_mb cannot be negative: %d", cfg.Server.Video.MaxTotalMB)
+	}
+	if cfg.Server.Video.MaxUploadsPerKey < 0 {
+		return fmt.Errorf("video max_uploads_per_key cannot be negative: %d", cfg.Server.Video.MaxUploadsPerKey)
+	}
+	if cfg.Server.MediaIO.BurstMB < 0 {
+		return fmt.Errorf("media_io burst_mb cannot be negative: %.2f", cfg.Server.MediaIO.BurstMB)
+	}
+	for tenant, limit := range cfg.Server.MediaIO.Tenants {
+		if limit < 0 {
+			return fmt.Errorf("media_io limit for tenant %s cannot be negative: %.2f", tenant, limit)
+		}
+	}
+
+	// Validate request label allowlist
+	if cfg.Server.Labels.MaxLabels < 0 {
+		return fmt.Errorf("labels max_labels cannot be negative: %d", cfg.Server.Labels.MaxLabels)
+	}
+	if cfg.Server.Labels.MaxValueLength < 0 {
+		return fmt.Errorf("labels max_value_length cannot be negative: %d", cfg.Server.Labels.MaxValueLength)
+	}
+	maxValueLength := cfg.Server.Labels.MaxValueLength
+	if maxValueLength == 0 {
+		maxValueLength = labels.DefaultMaxValueLength
+	}
+	for name, values := range cfg.Server.Labels.Allowed {
+		if !labels.ValidName(name) {
+			return fmt.Errorf("invalid label name %q: use lowercase letters, digits, '_', '-' and '.'", name)
+		}
+		for _, value := range values {
+			if !labels.ValidValue(value, maxValueLength) {
+				return fmt.Errorf("invalid value %q for label %s", value, name)
+			}
+		}
+	}
+
+	// Validate federation
+	fed := cfg.Server.Federation
+	if fed.PrivateKeyFile != "" && fed.InstanceID == "" {
+		return fmt.Errorf("federation private_key_file requires instance_id")
+	}
+	if len(fed.TrustedPeers) > 0 && fed.InstanceID == "" {
+		return fmt.Errorf("federation trusted_peers requires instance_id, which peers sign requests for")
+	}
+	for peer, peerCfg := range fed.TrustedPeers {
+		if peer == "" {
+			return fmt.Errorf("federation trusted peer with empty instance ID")
+		}
+		if _, err := federation.ParsePublicKey(peerCfg.PublicKey); err != nil {
+			return fmt.Errorf("federation trusted peer %s: invalid public key: %w", peer, err)
+		}
+	}
+	if fed.MaxClockSkew != "" {
+		if skew, err := time.ParseDuration(fed.MaxClockSkew); err != nil || skew <= 0 {
+			return fmt.Errorf("invalid federation max_clock_skew: %s", fed.MaxClockSkew)
+		}
+	}
+
+	if err := validateNetworkIdentity(cfg); err != nil {
+		return err
+	}
+
+	if err := validateAuthz(cfg); err != nil {
+		return err
+	}
+
+	// Validate response post-processing
+	if _, err := postprocess.Build(cfg.Server.PostProcessing.Steps); err != nil {
+		return fmt.Errorf("server post_processing: %w", err)
+	}
+
+	if err := validateAlerting(cfg); err != nil {
+		return err
+	}
+
+	if err := validateCORS(cfg.Server.CORS); err != nil {
+		return err
+	}
+
+	for _, origin := range cfg.Server.WebSocket.AllowedOrigins {
+		if !validOrigin(origin) {
+			return fmt.Errorf("invalid websocket allowed origin: %q (must be \"*\" or scheme://host[:port])", origin)
+		}
+	}
+
+	if ws := cfg.Server.WebSocket; ws.MaxConcurrentRequests < 0 || ws.MaxMessageKB < 0 || ws.TokensPerMinute < 0 {
+		return fmt.Errorf("websocket limits cannot be negative")
+	}
+
+	if cfg.Monitoring.CrashLog.MaxFiles < 0 {
+		return fmt.Errorf("monitoring crash_log max_files cannot be negative: %d",
+			cfg.Monitoring.CrashLog.MaxFiles)
+	}
+
+	if tap := cfg.Monitoring.DebugTap; tap.Rate < 0 || tap.MaxClients < 0 {
+		return fmt.Errorf("monitoring debug_tap rate and max_clients cannot be negative")
+	}
+
+	// Validate audit log
+	if al := cfg.Monitoring.AuditLog; al.Enabled {
+		if al.Path == "" {
+			return fmt.Errorf("monitoring audit_log enabled but path not specified")
+		}
+		if al.MaxSizeMB < 0 || al.MaxBackups < 0 {
+			return fmt.Errorf("monitoring audit_log max_size_mb and max_backups cannot be negative")
+		}
+		if al.PromptHashLength < 0 || al.PromptHashLength > 64 {
+			return fmt.Errorf("invalid monitoring audit_log prompt_hash_length: %d (must be 0-64)", al.PromptHashLength)
+		}
+		for _, field := range al.Redact {
+			known := false
+			for _, f := range audit.RedactableFields {
+				known = known || f == field
+			}
+			if !known {
+				return fmt.Errorf("invalid monitoring audit_log redact field: %s (must be one of %s)",
+					field, strings.Join(audit.RedactableFields, ", "))
+			}
+		}
+	}
+
 	// Validate at least one backend enabled
 	enabledCount := 0
 	backendIDs := make(map[string]bool)
@@ -166,6 +1161,22 @@ func ValidateConfig(cfg *Config) error {
 		return fmt.Errorf("no backends enabled in configuration")
 	}
 
+	// Validate label routing rules
+	for rule, ids := range cfg.Server.Labels.Backends {
+		name, value, ok := strings.Cut(rule, "=")
+		if !ok || !labels.ValidName(name) || !labels.ValidValue(value, maxValueLength) {
+			return fmt.Errorf("invalid labels backends rule %q: use label=value", rule)
+		}
+		if _, allowed := cfg.Server.Labels.Allowed[name]; !allowed {
+			return fmt.Errorf("labels backends rule %q names label %s, which is not in labels allowed", rule, name)
+		}
+		for _, id := range ids {
+			if !backendIDs[id] {
+				return fmt.Errorf("labels backends rule %q backend '%s' not found in enabled backends", rule, id)
+			}
+		}
+	}
+
 	// Validate backend configurations
 	for _, backend := range cfg.Backends {
 		if backend.Enabled {
@@ -176,6 +1187,16 @@ func ValidateConfig(cfg *Config) error {
 				return fmt.Errorf("backend %s missing type", backend.ID)
 			}
 
+			// Container validation
+			if c := backend.Container; c.Image != "" {
+				if c.Runtime != "" && c.Runtime != "docker" && c.Runtime != "podman" {
+					return fmt.Errorf("backend %s: invalid container runtime %s (must be docker or podman)", backend.ID, c.Runtime)
+				}
+				if c.OnDemand && backend.Endpoint == "" {
+					return fmt.Errorf("backend %s: on-demand container requires an endpoint", backend.ID)
+				}
+			}
+
 			// Type-specific validation
 			switch backend.Type {
 			case "openvino":
@@ -189,16 +1210,39 @@ func ValidateConfig(cfg *Config) error {
 				if backend.ModelName == "" {
 					return fmt.Errorf("backend %s (type openvino) missing model_name field", backend.ID)
 				}
-			case "ollama", "openai", "anthropic":
+			case "ollama", "vllm", "sdwebui", "openai", "anthropic":
 				// HTTP-based backends require endpoint
 				if backend.Endpoint == "" {
 					return fmt.Errorf("backend %s missing endpoint", backend.ID)
 				}
+			case "cloud":
+				// The endpoint defaults to the provider's API, the key is required
+				if p := backend.Cloud.Provider; p != "" && p != "openai" && p != "anthropic" {
+					return fmt.Errorf("backend %s: invalid cloud provider %s (must be openai or anthropic)", backend.ID, p)
+				}
+				if backend.APIKeyEnv == "" {
+					return fmt.Errorf("backend %s (type cloud) missing api_key_env", backend.ID)
+				}
+				if backend.Cloud.InputCostPer1K < 0 || backend.Cloud.OutputCostPer1K < 0 {
+					return fmt.Errorf("backend %s has negative cloud token cost", backend.ID)
+				}
 			default:
 				// Unknown backend type - warn but don't fail
 				// This allows for future extensibility
 			}
 
+			if backend.Federated {
+				if backend.Type != "ollama" {
+					return fmt.Errorf("backend %s: federated is only supported for ollama backends", backend.ID)
+				}
+				if fed.PrivateKeyFile == "" {
+					return fmt.Errorf("backend %s is federated but server.federation has no private_key_file", backend.ID)
+				}
+				if backend.PeerInstance == "" {
+					return fmt.Errorf("backend %s is federated but has no peer_instance", backend.ID)
+				}
+			}
+
 			if backend.Characteristics.PowerWatts < 0 {
 				return fmt.Errorf("backend %s has negative power_watts: %.2f",
 					backend.ID, backend.Characteristics.PowerWatts)
@@ -207,10 +1251,22 @@ func ValidateConfig(cfg *Config) error {
 				return fmt.Errorf("backend %s has negative avg_latency_ms: %d",
 					backend.ID, backend.Characteristics.AvgLatencyMs)
 			}
+			if backend.Characteristics.Weight < 0 {
+				return fmt.Errorf("backend %s has negative weight: %d",
+					backend.ID, backend.Characteristics.Weight)
+			}
+			if backend.Characteristics.MaxConcurrent < 0 {
+				return fmt.Errorf("backend %s has negative max_concurrent: %d",
+					backend.ID, backend.Characteristics.MaxConcurrent)
+			}
 			if backend.Characteristics.Priority < 0 {
 				return fmt.Errorf("backend %s has negative priority: %d",
 					backend.ID, backend.Characteristics.Priority)
 			}
+			if backend.Characteristics.CostPer1KTokens < 0 {
+				return fmt.Errorf("backend %s has negative cost_per_1k_tokens: %.4f",
+					backend.ID, backend.Characteristics.CostPer1KTokens)
+			}
 		}
 	}
 
@@ -239,40 +1295,435 @@ func ValidateConfig(cfg *Config) error {
 					backendID)
 			}
 		}
+		switch judge := cfg.Routing.Forwarding.Judge; cfg.Routing.Forwarding.Scorer {
+		case "", "heuristic", "logprob":
+		case "judge":
+			if !backendIDs[judge.Backend] {
+				return fmt.Errorf("forwarding judge backend '%s' not found in enabled backends", judge.Backend)
+			}
+			if judge.Model == "" {
+				return fmt.Errorf("forwarding judge requires a model")
+			}
+			if judge.Timeout != "" {
+				if d, err := time.ParseDuration(judge.Timeout); err != nil || d <= 0 {
+					return fmt.Errorf("forwarding judge timeout must be a positive duration: %q", judge.Timeout)
+				}
+			}
+		default:
+			return fmt.Errorf("unknown forwarding scorer %q (expected heuristic, logprob or judge)",
+				cfg.Routing.Forwarding.Scorer)
+		}
 	}
 
-	// Validate confidence weights
-	if cfg.Routing.Confidence.LengthWeight < 0 || cfg.Routing.Confidence.LengthWeight > 1 {
-		return fmt.Errorf("confidence length_weight %.2f out of range [0, 1]",
-			cfg.Routing.Confidence.LengthWeight)
-	}
-	if cfg.Routing.Confidence.PatternWeight < 0 || cfg.Routing.Confidence.PatternWeight > 1 {
-		return fmt.Errorf("confidence pattern_weight %.2f out of range [0, 1]",
-			cfg.Routing.Confidence.PatternWeight)
-	}
-	if cfg.Routing.Confidence.ModelWeight < 0 || cfg.Routing.Confidence.ModelWeight > 1 {
-		return fmt.Errorf("confidence model_weight %.2f out of range [0, 1]",
-			cfg.Routing.Confidence.ModelWeight)
-	}
-
-	// Validate thermal thresholds
-	if cfg.Thermal.Enabled {
-		if cfg.Thermal.Temperature.Warning >= cfg.Thermal.Temperature.Critical {
-			return fmt.Errorf("thermal warning temp %.1f must be less than critical temp %.1f",
-				cfg.Thermal.Temperature.Warning, cfg.Thermal.Temperature.Critical)
+	// Validate model placement
+	if pl := cfg.Routing.Placement; pl.Enabled {
+		if len(pl.Tiers) == 0 {
+			return fmt.Errorf("routing placement enabled but no tiers configured")
 		}
-		if cfg.Thermal.Temperature.Critical >= cfg.Thermal.Temperature.Shutdown {
-			return fmt.Errorf("thermal critical temp %.1f must be less than shutdown temp %.1f",
-				cfg.Thermal.Temperature.Critical, cfg.Thermal.Temperature.Shutdown)
+		if pl.TopModels < 0 || pl.MinRequests < 0 {
+			return fmt.Errorf("routing placement top_models and min_requests cannot be negative")
 		}
-		if cfg.Thermal.Fan.Quiet > cfg.Thermal.Fan.Moderate {
-			return fmt.Errorf("fan quiet %d cannot be greater than moderate %d",
-				cfg.Thermal.Fan.Quiet, cfg.Thermal.Fan.Moderate)
+		for field, value := range map[string]string{"interval": pl.Interval, "window": pl.Window, "pull_timeout": pl.PullTimeout} {
+			if value == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				return fmt.Errorf("invalid routing placement %s: %s", field, value)
+			}
+		}
+		for i, tier := range pl.Tiers {
+			if tier.MaxParamsB < 0 {
+				return fmt.Errorf("routing placement tier %d: max_params_b cannot be negative", i)
+			}
+			if len(tier.Backends) == 0 {
+				return fmt.Errorf("routing placement tier %d has no backends", i)
+			}
+			for _, backendID := range tier.Backends {
+				if !backendIDs[backendID] {
+					return fmt.Errorf("routing placement backend '%s' not found in enabled backends", backendID)
+				}
+			}
+		}
+	}
+
+	// Validate degradation profiles
+	if dg := cfg.Server.Degradation; len(dg.Profiles) > 0 {
+		if dg.Interval != "" {
+			if d, err := time.ParseDuration(dg.Interval); err != nil || d <= 0 {
+				return fmt.Errorf("invalid server degradation interval: %s", dg.Interval)
+			}
+		}
+		names := make(map[string]bool)
+		for i, p := range dg.Profiles {
+			if p.Name == "" {
+				return fmt.Errorf("server degradation profile %d has no name", i)
+			}
+			if names[p.Name] {
+				return fmt.Errorf("duplicate server degradation profile: %s", p.Name)
+			}
+			names[p.Name] = true
+
+			when := p.When
+			switch when.Thermal {
+			case "", "warning", "critical":
+			default:
+				return fmt.Errorf("server degradation profile %s: invalid thermal level %q (must be warning or critical)", p.Name, when.Thermal)
+			}
+			if when.BatteryBelow < 0 || when.BatteryBelow > 100 {
+				return fmt.Errorf("server degradation profile %s: battery_below %d out of range [0, 100]", p.Name, when.BatteryBelow)
+			}
+			if when.Thermal == "" && !when.Throttling && !when.OnBattery && when.BatteryBelow == 0 {
+				return fmt.Errorf("server degradation profile %s has no conditions", p.Name)
+			}
+			if p.MaxTokens < 0 || p.MaxPowerWatts < 0 {
+				return fmt.Errorf("server degradation profile %s: max_tokens and max_power_watts cannot be negative", p.Name)
+			}
+			for _, endpoint := range p.DisableEndpoints {
+				if !strings.HasPrefix(endpoint, "/") {
+					return fmt.Errorf("server degradation profile %s: endpoint %q must start with /", p.Name, endpoint)
+				}
+			}
+			for _, backendID := range p.Backends {
+				if !backendIDs[backendID] {
+					return fmt.Errorf("server degradation profile %s: backend '%s' not found in enabled backends", p.Name, backendID)
+				}
+			}
+		}
+	}
+
+	// Validate circuit breaker
+	if cb := cfg.Routing.CircuitBreaker; cb.Enabled {
+		if cb.MaxFailures < 0 || cb.MinRequests < 0 || cb.HalfOpenProbes < 0 {
+			return fmt.Errorf("routing circuit_breaker max_failures, min_requests and half_open_probes cannot be negative")
+		}
+		if cb.ErrorRate < 0 || cb.ErrorRate > 1 {
+			return fmt.Errorf("invalid routing circuit_breaker error_rate: %.2f (must be 0-1)", cb.ErrorRate)
+		}
+		for field, value := range map[string]string{"window": cb.Window, "open_timeout": cb.OpenTimeout} {
+			if value == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				return fmt.Errorf("invalid routing circuit_breaker %s: %s", field, value)
+			}
+		}
+	}
+
+	// Validate hedging
+	if delay := cfg.Routing.Hedging.Delay; delay != "" {
+		if d, err := time.ParseDuration(delay); err != nil || d <= 0 {
+			return fmt.Errorf("invalid routing hedging delay: %s", delay)
+		}
+	}
+
+	// Validate embedding batches
+	if eb := cfg.Routing.EmbedBatch; eb.ChunkSize < 0 || eb.MaxPerBackend < 0 {
+		return fmt.Errorf("routing embed_batch chunk_size and max_per_backend cannot be negative")
+	}
+
+	// Validate device utilization sampling
+	if interval := cfg.Devices.Utilization.Interval; interval != "" {
+		if d, err := time.ParseDuration(interval); err != nil || d <= 0 {
+			return fmt.Errorf("invalid devices utilization interval: %s", interval)
+		}
+	}
+
+	// Validate energy measurement
+	if interval := cfg.Monitoring.Energy.Interval; interval != "" {
+		if d, err := time.ParseDuration(interval); err != nil || d <= 0 {
+			return fmt.Errorf("invalid monitoring energy interval: %s", interval)
+		}
+	}
+	for _, source := range cfg.Monitoring.Energy.Sources {
+		if source != "rapl" && source != "nvml" {
+			return fmt.Errorf("invalid monitoring energy source %q (must be rapl or nvml)", source)
+		}
+	}
+
+	// Validate Retry-After bounds
+	retryBounds := make(map[string]time.Duration)
+	for field, value := range map[string]string{"min": cfg.Routing.RetryAfter.Min, "max": cfg.Routing.RetryAfter.Max} {
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid routing retry_after %s: %s", field, value)
+		}
+		retryBounds[field] = d
+	}
+	if min, max := retryBounds["min"], retryBounds["max"]; min > 0 && max > 0 && min > max {
+		return fmt.Errorf("routing retry_after min %s exceeds max %s", cfg.Routing.RetryAfter.Min, cfg.Routing.RetryAfter.Max)
+	}
+
+	// Validate memory admission
+	if ma := cfg.Routing.MemoryAdmission; ma.Enabled {
+		if !cfg.Devices.Utilization.Enabled {
+			return fmt.Errorf("routing memory_admission requires devices utilization to be enabled")
+		}
+		if ma.HeadroomMB < 0 || ma.DefaultContextLength < 0 {
+			return fmt.Errorf("routing memory_admission headroom_mb and default_context_length cannot be negative")
+		}
+		if window := ma.ResidentWindow; window != "" {
+			if d, err := time.ParseDuration(window); err != nil || d <= 0 {
+				return fmt.Errorf("invalid routing memory_admission resident_window: %s", window)
+			}
+		}
+	}
+
+	// Validate nightly benchmarks
+	if bm := cfg.Routing.Benchmark; bm.Enabled {
+		if _, err := benchmark.ParseWindow(bm.Window); err != nil {
+			return fmt.Errorf("invalid routing benchmark window: %w", err)
+		}
+		if bm.MaxTokens < 0 {
+			return fmt.Errorf("routing benchmark max_tokens cannot be negative")
+		}
+		for field, value := range map[string]string{"timeout": bm.Timeout, "interval": bm.Interval} {
+			if value == "" {
+				continue
+			}
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				return fmt.Errorf("invalid routing benchmark %s: %s", field, value)
+			}
+		}
+	}
+
+	if v := cfg.Routing.LatencyPrediction.SaveInterval; v != "" {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			return fmt.Errorf("invalid routing latency_prediction save_interval: %s", v)
+		}
+	}
+
+	// Validate speculative decoding
+	if spec := cfg.Routing.Speculative; spec.Enabled {
+		if !backendIDs[spec.DraftBackend] {
+			return fmt.Errorf("routing speculative draft_backend '%s' not found in enabled backends", spec.DraftBackend)
+		}
+		if spec.DraftModel == "" {
+			return fmt.Errorf("routing speculative enabled but no draft_model configured")
+		}
+		if spec.DraftTokens < 0 || spec.MaxTokens < 0 {
+			return fmt.Errorf("routing speculative draft_tokens and max_tokens cannot be negative")
+		}
+	}
+
+	// Validate ensembles
+	if ens := cfg.Routing.Ensemble; ens.Enabled {
+		if ens.Size != 0 && (ens.Size < 2 || ens.Size > 3) {
+			return fmt.Errorf("routing ensemble size %d out of range [2, 3]", ens.Size)
+		}
+		for alias, models := range ens.Aliases {
+			if len(models) == 0 || len(models) > 3 {
+				return fmt.Errorf("routing ensemble alias '%s' needs 1-3 models, got %d", alias, len(models))
+			}
+			for _, model := range models {
+				if model == "" {
+					return fmt.Errorf("routing ensemble alias '%s' has an empty model", alias)
+				}
+			}
+		}
+		if judge := ens.Judge; judge.Backend != "" {
+			if !backendIDs[judge.Backend] {
+				return fmt.Errorf("routing ensemble judge backend '%s' not found in enabled backends", judge.Backend)
+			}
+			if judge.Model == "" {
+				return fmt.Errorf("routing ensemble judge backend set but no model configured")
+			}
+		}
+		if timeout := ens.Judge.Timeout; timeout != "" {
+			if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+				return fmt.Errorf("invalid routing ensemble judge timeout: %s", timeout)
+			}
+		}
+	}
+
+	// Validate confidence weights
+	if cfg.Routing.Confidence.LengthWeight < 0 || cfg.Routing.Confidence.LengthWeight > 1 {
+		return fmt.Errorf("confidence length_weight %.2f out of range [0, 1]",
+			cfg.Routing.Confidence.LengthWeight)
+	}
+	if cfg.Routing.Confidence.PatternWeight < 0 || cfg.Routing.Confidence.PatternWeight > 1 {
+		return fmt.Errorf("confidence pattern_weight %.2f out of range [0, 1]",
+			cfg.Routing.Confidence.PatternWeight)
+	}
+	if cfg.Routing.Confidence.ModelWeight < 0 || cfg.Routing.Confidence.ModelWeight > 1 {
+		return fmt.Errorf("confidence model_weight %.2f out of range [0, 1]",
+			cfg.Routing.Confidence.ModelWeight)
+	}
+
+	// Validate language detection
+	if cfg.Routing.LanguageDetection.MinConfidence < 0 || cfg.Routing.LanguageDetection.MinConfidence > 1 {
+		return fmt.Errorf("language_detection min_confidence %.2f out of range [0, 1]",
+			cfg.Routing.LanguageDetection.MinConfidence)
+	}
+
+	// Validate scheduling
+	if sched := cfg.Routing.Scheduling; sched.MaxConcurrent < 0 || sched.MaxQueueDepth < 0 {
+		return fmt.Errorf("scheduling limits cannot be negative")
+	}
+	if cfg.Routing.Scheduling.QueueTimeout != "" {
+		if _, err := time.ParseDuration(cfg.Routing.Scheduling.QueueTimeout); err != nil {
+			return fmt.Errorf("invalid scheduling queue_timeout %q: %w", cfg.Routing.Scheduling.QueueTimeout, err)
+		}
+	}
+
+	// Validate session affinity
+	if cfg.Routing.Sessions.MaxSessions < 0 {
+		return fmt.Errorf("sessions max_sessions cannot be negative: %d", cfg.Routing.Sessions.MaxSessions)
+	}
+	if cfg.Routing.Sessions.TTL != "" {
+		if ttl, err := time.ParseDuration(cfg.Routing.Sessions.TTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid sessions ttl %q: must be a positive duration", cfg.Routing.Sessions.TTL)
+		}
+	}
+	for name, value := range map[string]string{
+		"max_turn_gap": cfg.Routing.Sessions.Boost.MaxTurnGap,
+		"half_life":    cfg.Routing.Sessions.Boost.HalfLife,
+	} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid sessions boost %s %q: must be a positive duration", name, value)
+		}
+	}
+	if cfg.Routing.Sessions.Boost.Threshold < 0 {
+		return fmt.Errorf("sessions boost threshold cannot be negative: %.2f", cfg.Routing.Sessions.Boost.Threshold)
+	}
+
+	// Validate load balancing
+	if d := cfg.Routing.LoadBalancing.EWMADecay; d < 0 || d > 1 {
+		return fmt.Errorf("load_balancing ewma_decay %.2f out of range [0, 1]", d)
+	}
+	for model, strategy := range cfg.Routing.LoadBalancing.Models {
+		if model == "" || strategy == "" {
+			return fmt.Errorf("load_balancing models entries need a model and a strategy")
+		}
+	}
+
+	// Validate routing decision log
+	if dl := cfg.Routing.DecisionLog; dl.Enabled {
+		if dl.Path == "" {
+			return fmt.Errorf("decision_log path is required when enabled")
+		}
+		if dl.SampleRate < 0 || dl.SampleRate > 1 {
+			return fmt.Errorf("decision_log sample_rate %.2f out of range [0, 1]", dl.SampleRate)
+		}
+		if dl.MaxSizeMB < 0 {
+			return fmt.Errorf("decision_log max_size_mb cannot be negative: %d", dl.MaxSizeMB)
+		}
+	}
+
+	// Validate response cache
+	if cfg.Cache.Enabled {
+		switch cfg.Cache.Type {
+		case "", "memory":
+		case "disk":
+			if cfg.Cache.Dir == "" {
+				return fmt.Errorf("cache type disk requires a dir")
+			}
+		default:
+			return fmt.Errorf("invalid cache type: %s (must be memory or disk)", cfg.Cache.Type)
+		}
+		if cfg.Cache.TTLSeconds < 0 || cfg.Cache.MaxSizeMB < 0 || cfg.Cache.MaxEntries < 0 {
+			return fmt.Errorf("cache limits cannot be negative")
+		}
+	}
+
+	// Validate meeting guard
+	if cfg.VirtualDevices.MeetingGuard.MaxLowPriorityConcurrent < 0 {
+		return fmt.Errorf("virtual_devices meeting_guard max_low_priority_concurrent cannot be negative: %d",
+			cfg.VirtualDevices.MeetingGuard.MaxLowPriorityConcurrent)
+	}
+
+	if err := validateHA(cfg); err != nil {
+		return err
+	}
+
+	if err := validateLiveSources(cfg); err != nil {
+		return err
+	}
+
+	if err := validatePipelineTools(cfg); err != nil {
+		return err
+	}
+
+	// Validate thermal thresholds
+	if cfg.Thermal.Enabled {
+		if cfg.Thermal.Temperature.Warning >= cfg.Thermal.Temperature.Critical {
+			return fmt.Errorf("thermal warning temp %.1f must be less than critical temp %.1f",
+				cfg.Thermal.Temperature.Warning, cfg.Thermal.Temperature.Critical)
+		}
+		if cfg.Thermal.Temperature.Critical >= cfg.Thermal.Temperature.Shutdown {
+			return fmt.Errorf("thermal critical temp %.1f must be less than shutdown temp %.1f",
+				cfg.Thermal.Temperature.Critical, cfg.Thermal.Temperature.Shutdown)
+		}
+		if cfg.Thermal.Fan.Quiet > cfg.Thermal.Fan.Moderate {
+			return fmt.Errorf("fan quiet %d cannot be greater than moderate %d",
+				cfg.Thermal.Fan.Quiet, cfg.Thermal.Fan.Moderate)
 		}
 		if cfg.Thermal.Fan.Moderate > cfg.Thermal.Fan.Loud {
 			return fmt.Errorf("fan moderate %d cannot be greater than loud %d",
 				cfg.Thermal.Fan.Moderate, cfg.Thermal.Fan.Loud)
 		}
+		if cooldown := cfg.Thermal.Cooldown; cooldown != "" {
+			if d, err := time.ParseDuration(cooldown); err != nil || d < 0 {
+				return fmt.Errorf("thermal cooldown must be a non-negative duration: %q", cooldown)
+			}
+		}
+		if samples := cfg.Thermal.Prediction.Samples; samples < 0 || samples == 1 {
+			return fmt.Errorf("thermal prediction samples must be at least 2: %d", samples)
+		}
+		if horizon := cfg.Thermal.Prediction.Horizon; horizon != "" {
+			if d, err := time.ParseDuration(horizon); err != nil || d <= 0 {
+				return fmt.Errorf("thermal prediction horizon must be a positive duration: %q", horizon)
+			}
+		}
+		if fc := cfg.Thermal.FanControl; fc.Enabled {
+			if fc.Interval != "" {
+				if d, err := time.ParseDuration(fc.Interval); err != nil || d <= 0 {
+					return fmt.Errorf("thermal fan control interval must be a positive duration: %q", fc.Interval)
+				}
+			}
+			if len(fc.Devices) == 0 {
+				return fmt.Errorf("thermal fan control needs at least one device")
+			}
+			for hw, device := range fc.Devices {
+				switch device.Driver {
+				case "hwmon":
+					if device.Path == "" {
+						return fmt.Errorf("thermal fan control device %s: hwmon driver needs a path", hw)
+					}
+				case "nbfc", "nvidia":
+				default:
+					return fmt.Errorf("thermal fan control device %s: unknown driver %q (must be hwmon, nbfc or nvidia)", hw, device.Driver)
+				}
+				for i, point := range device.Curve {
+					if point.Percent < 0 || point.Percent > 100 {
+						return fmt.Errorf("thermal fan control device %s: curve percent %d must be 0-100", hw, point.Percent)
+					}
+					if i > 0 && point.Temperature <= device.Curve[i-1].Temperature {
+						return fmt.Errorf("thermal fan control device %s: curve temperatures must increase", hw)
+					}
+				}
+			}
+			for name, profile := range fc.Profiles {
+				switch name {
+				case "quiet", "moderate", "loud":
+				default:
+					return fmt.Errorf("thermal fan control profile %q must be quiet, moderate or loud", name)
+				}
+				if profile.Hysteresis < 0 {
+					return fmt.Errorf("thermal fan control profile %s: hysteresis cannot be negative", name)
+				}
+				if profile.MinInterval != "" {
+					if d, err := time.ParseDuration(profile.MinInterval); err != nil || d < 0 {
+						return fmt.Errorf("thermal fan control profile %s: min_interval must be a non-negative duration: %q", name, profile.MinInterval)
+					}
+				}
+			}
+		}
 	}
 
 	// Validate monitoring configuration
@@ -302,6 +1753,507 @@ func ValidateConfig(cfg *Config) error {
 			return fmt.Errorf("invalid efficiency mode: %s (must be Performance, Balanced, Efficiency, Quiet, Auto, or UltraEfficiency)",
 				cfg.Efficiency.DefaultMode)
 		}
+		if v := cfg.Efficiency.MeetingQuietMax; v != "" {
+			if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+				return fmt.Errorf("invalid efficiency meeting_quiet_max: %s", v)
+			}
+		}
+		names := make(map[string]bool, len(cfg.Efficiency.Schedule))
+		for i, entry := range cfg.Efficiency.Schedule {
+			if entry.Name == "" {
+				return fmt.Errorf("efficiency schedule entry %d needs a name", i)
+			}
+			if names[entry.Name] {
+				return fmt.Errorf("duplicate efficiency schedule entry: %s", entry.Name)
+			}
+			names[entry.Name] = true
+			if _, err := efficiency.ParseScheduleEntry(entry.Name, entry.Mode, entry.Days, entry.Start, entry.End); err != nil {
+				return fmt.Errorf("invalid efficiency %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// builtinMiddleware lists the middleware names usable in route chains
+var builtinMiddleware = map[string]bool{
+	"auth":       true,
+	"rate_limit": true,
+	"cors":       true,
+	"body_limit": true,
+	"cache":      true,
+	"logging":    true,
+	"quota":      true,
+}
+
+// validate checks that a key budget is non-negative
+func (rl RateLimitConfig) validate() error {
+	if rl.Rate < 0 || rl.Burst < 0 || rl.StreamRate < 0 || rl.StreamBurst < 0 {
+		return fmt.Errorf("rates and bursts cannot be negative")
+	}
+	return nil
+}
+
+// shapingModes are the efficiency modes shaping defaults can be set for.
+// Auto only selects one of them, so it has no defaults of its own.
+var shapingModes = map[string]bool{
+	"Performance":     true,
+	"Balanced":        true,
+	"Efficiency":      true,
+	"Quiet":           true,
+	"UltraEfficiency": true,
+}
+
+// validate checks that shaping defaults are in range
+func (s ShapingConfig) validate() error {
+	if s.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens cannot be negative: %d", s.MaxTokens)
+	}
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2: %g", *s.Temperature)
+	}
+	return nil
+}
+
+// validate checks that quota limits are non-negative
+func (q QuotaConfig) validate() error {
+	if q.DailyTokens < 0 || q.MonthlyTokens < 0 || q.DailyRequests < 0 || q.MonthlyRequests < 0 {
+		return fmt.Errorf("limits cannot be negative")
+	}
+	return nil
+}
+
+// validateMiddleware checks that every configured chain references known middleware
+func validateMiddleware(cfg *Config) error {
+	mw := cfg.Server.Middleware
+
+	for name, rl := range mw.RateLimits {
+		if rl.Rate <= 0 || rl.Burst <= 0 {
+			return fmt.Errorf("rate limit %s must have positive rate and burst", name)
+		}
+	}
+
+	check := func(route string, names []string) error {
+		for _, name := range names {
+			if builtinMiddleware[name] {
+				continue
+			}
+			if strings.HasPrefix(name, "rate_limit:") {
+				if _, ok := mw.RateLimits[strings.TrimPrefix(name, "rate_limit:")]; ok {
+					continue
+				}
+			}
+			return fmt.Errorf("unknown middleware %q in chain for %s", name, route)
+		}
+		return nil
+	}
+
+	if err := check("default", mw.Default); err != nil {
+		return err
+	}
+	for route, names := range mw.Routes {
+		if err := check(route, names); err != nil {
+			return err
+		}
+	}
+	for _, l := range cfg.Server.Listeners {
+		if err := check("listener "+l.Name, l.Middleware); err != nil {
+			return err
+		}
+		if err := check("listener "+l.Name, l.SkipMiddleware); err != nil {
+			return err
+		}
+	}
+
+	if mw.BodyLimitMB < 0 {
+		return fmt.Errorf("middleware body_limit_mb cannot be negative: %d", mw.BodyLimitMB)
+	}
+
+	return nil
+}
+
+// validateAuthz checks the roles callers map to
+func validateAuthz(cfg *Config) error {
+	az := cfg.Server.Authz
+	if !az.Enabled {
+		return nil
+	}
+
+	for identity, role := range az.ClientCerts {
+		if identity == "" {
+			return fmt.Errorf("authz client_certs: identity cannot be empty")
+		}
+		if _, err := authz.ParseRole(role); err != nil {
+			return fmt.Errorf("authz client_certs %s: %w", identity, err)
+		}
+	}
+	for field, role := range map[string]string{"default_cert_role": az.DefaultCertRole, "anonymous_role": az.AnonymousRole} {
+		if role == "" {
+			continue
+		}
+		if _, err := authz.ParseRole(role); err != nil {
+			return fmt.Errorf("authz %s: %w", field, err)
+		}
+	}
+
+	// Certificates are only verified, and so only trusted, with a client CA
+	if len(az.ClientCerts) > 0 || az.DefaultCertRole != "" {
+		clientCA := cfg.Server.TLS.Enabled && cfg.Server.TLS.ClientCAFile != ""
+		for _, l := range cfg.Server.Listeners {
+			clientCA = clientCA || (l.TLS.Enabled && l.TLS.ClientCAFile != "")
+		}
+		if !clientCA {
+			return fmt.Errorf("authz client_certs require a tls client_ca_file to verify client certificates")
+		}
+	}
+	return nil
+}
+
+// validateNetworkIdentity checks the peer networks and the limits of the
+// identities they map to
+func validateNetworkIdentity(cfg *Config) error {
+	ni := cfg.Server.NetworkIdentity
+	if !ni.Enabled {
+		return nil
+	}
+
+	switch ni.Provider {
+	case "", "tailscale":
+		// valid
+	case "wireguard":
+		if len(ni.Peers) == 0 {
+			return fmt.Errorf("network_identity provider wireguard requires peers")
+		}
+	default:
+		return fmt.Errorf("invalid network_identity provider: %s (must be tailscale or wireguard)", ni.Provider)
+	}
+	for cidr, name := range ni.Peers {
+		if _, err := netidentity.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("network_identity peer %s: invalid address range: %w", cidr, err)
+		}
+		if name == "" {
+			return fmt.Errorf("network_identity peer %s: name is required", cidr)
+		}
+	}
+	for _, cidr := range ni.Networks {
+		if _, err := netidentity.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("network_identity network %s: %w", cidr, err)
+		}
+	}
+	if ni.CacheTTL != "" {
+		if ttl, err := time.ParseDuration(ni.CacheTTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid network_identity cache_ttl: %s", ni.CacheTTL)
+		}
+	}
+
+	identities := make(map[string]APIKeyConfig, len(ni.Identities)+1)
+	for name, id := range ni.Identities {
+		identities[name] = id
+	}
+	if ni.Default != nil {
+		identities["default"] = *ni.Default
+	}
+	for name, id := range identities {
+		if id.RateLimit != nil {
+			if err := id.RateLimit.validate(); err != nil {
+				return fmt.Errorf("network_identity %s rate_limit: %w", name, err)
+			}
+		}
+		if id.Quota != nil {
+			if err := id.Quota.validate(); err != nil {
+				return fmt.Errorf("network_identity %s quota: %w", name, err)
+			}
+		}
+		if id.Shaping != nil {
+			if err := id.Shaping.validate(); err != nil {
+				return fmt.Errorf("network_identity %s shaping: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateListeners checks the HTTP listeners. Authentication may only be
+// skipped where nobody but local users can connect.
+func validateListeners(cfg *Config) error {
+	names := make(map[string]bool, len(cfg.Server.Listeners))
+	for _, l := range cfg.Server.Listeners {
+		if l.Name == "" {
+			return fmt.Errorf("listener %s: name is required", l.Address)
+		}
+		if names[l.Name] {
+			return fmt.Errorf("duplicate listener name: %s", l.Name)
+		}
+		names[l.Name] = true
+
+		host, port, err := net.SplitHostPort(l.Address)
+		if err != nil {
+			return fmt.Errorf("listener %s: invalid address %q: %w", l.Name, l.Address, err)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("listener %s: invalid port %q (must be 1-65535)", l.Name, port)
+		}
+		for _, group := range l.Groups {
+			switch group {
+			case "health", "admin", "inference":
+				// valid
+			default:
+				return fmt.Errorf("listener %s: unknown group %q (must be health, admin or inference)", l.Name, group)
+			}
+		}
+		if l.TLS.Enabled && (l.TLS.CertFile == "" || l.TLS.KeyFile == "") {
+			return fmt.Errorf("listener %s: TLS enabled but cert_file or key_file not specified", l.Name)
+		}
+		if slices.Contains(l.SkipMiddleware, "auth") && !loopbackHost(host) {
+			return fmt.Errorf("listener %s: auth can only be skipped on a loopback address, not %q", l.Name, l.Address)
+		}
+	}
+	return nil
+}
+
+func loopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateUsageExport checks the usage export sink configuration
+func validateUsageExport(cfg *Config) error {
+	export := cfg.Server.Reports.Export
+
+	switch export.Type {
+	case "":
+		return nil
+	case "file":
+		if export.Path == "" {
+			return fmt.Errorf("reports export type file requires a path")
+		}
+		if export.Format != "" && export.Format != "jsonl" && export.Format != "csv" {
+			return fmt.Errorf("invalid reports export format: %s (must be jsonl or csv)", export.Format)
+		}
+	case "otlp":
+		if export.Endpoint == "" {
+			return fmt.Errorf("reports export type otlp requires an endpoint")
+		}
+	default:
+		return fmt.Errorf("invalid reports export type: %s (must be file or otlp)", export.Type)
+	}
+
+	if export.BufferSize < 0 || export.BatchSize < 0 || export.MaxSizeMB < 0 || export.MaxBackups < 0 {
+		return fmt.Errorf("reports export sizes cannot be negative")
+	}
+
+	return nil
+}
+
+// validOrigin reports whether origin is "*" or scheme://host[:port], where
+// host may start with "*." to match subdomains
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	return u.Path == "" || u.Path == "/"
+}
+
+// validateCORS checks the cross-origin settings of the inference routes
+func validateCORS(cors CORSConfig) error {
+	if !cors.Enabled {
+		return nil
+	}
+	if len(cors.AllowedOrigins) == 0 {
+		return fmt.Errorf("server cors requires allowed_origins when enabled")
+	}
+	for _, origin := range cors.AllowedOrigins {
+		if !validOrigin(origin) {
+			return fmt.Errorf("invalid cors allowed origin: %q (must be \"*\" or scheme://host[:port])", origin)
+		}
+		if origin == "*" && cors.AllowCredentials {
+			return fmt.Errorf("server cors cannot allow credentials from any origin (\"*\")")
+		}
+	}
+	if cors.MaxAge != "" {
+		if d, err := time.ParseDuration(cors.MaxAge); err != nil || d < 0 {
+			return fmt.Errorf("invalid cors max_age: %s", cors.MaxAge)
+		}
+	}
+	return nil
+}
+
+// validateAlerting checks alert rules and notifier references
+func validateAlerting(cfg *Config) error {
+	alerting := cfg.Monitoring.Alerting
+	if !alerting.Enabled {
+		return nil
+	}
+
+	validOps := map[string]bool{">": true, ">=": true, "<": true, "<=": true, "==": true, "!=": true}
+	names := make(map[string]bool)
+
+	for name, webhook := range alerting.Webhooks {
+		if name == "dbus" {
+			return fmt.Errorf("alerting webhook name %q is reserved", name)
+		}
+		if webhook.URL == "" {
+			return fmt.Errorf("alerting webhook %s missing url", name)
+		}
+	}
+
+	for i, rule := range alerting.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("alert rule %d missing name", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate alert rule name: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.Metric == "" {
+			return fmt.Errorf("alert rule %s missing metric", rule.Name)
+		}
+		if !validOps[rule.Op] {
+			return fmt.Errorf("alert rule %s has invalid op %q", rule.Name, rule.Op)
+		}
+		if rule.For != "" {
+			if _, err := time.ParseDuration(rule.For); err != nil {
+				return fmt.Errorf("alert rule %s has invalid for duration: %s", rule.Name, rule.For)
+			}
+		}
+		for _, notifier := range rule.Notify {
+			if _, ok := alerting.Webhooks[notifier]; !ok && notifier != "dbus" {
+				return fmt.Errorf("alert rule %s references unknown notifier: %s", rule.Name, notifier)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateLiveSources checks the pipeline live sources
+func validateLiveSources(cfg *Config) error {
+	ids := make(map[string]bool)
+	for i, src := range cfg.Pipelines.Sources {
+		if src.ID == "" {
+			return fmt.Errorf("pipeline source %d missing id", i)
+		}
+		if ids[src.ID] {
+			return fmt.Errorf("duplicate pipeline source id: %s", src.ID)
+		}
+		ids[src.ID] = true
+
+		if src.Pipeline == "" {
+			return fmt.Errorf("pipeline source %s missing pipeline", src.ID)
+		}
+		switch src.Type {
+		case "rtsp":
+			if src.URL == "" {
+				return fmt.Errorf("pipeline source %s missing url", src.ID)
+			}
+		case "camera":
+			if src.Device == "" {
+				return fmt.Errorf("pipeline source %s missing device", src.ID)
+			}
+		default:
+			return fmt.Errorf("invalid pipeline source %s type: %s (must be rtsp or camera)", src.ID, src.Type)
+		}
+		if src.Media != "" && src.Media != "video" && src.Media != "audio" {
+			return fmt.Errorf("invalid pipeline source %s media: %s (must be video or audio)", src.ID, src.Media)
+		}
+		if src.FPS < 0 {
+			return fmt.Errorf("pipeline source %s fps cannot be negative: %d", src.ID, src.FPS)
+		}
+		if src.Window != "" {
+			if d, err := time.ParseDuration(src.Window); err != nil || d <= 0 {
+				return fmt.Errorf("invalid pipeline source %s window: %s", src.ID, src.Window)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePipelineTools checks the tools pipeline tool stages may call
+func validatePipelineTools(cfg *Config) error {
+	names := make(map[string]bool)
+	for i, tool := range cfg.Pipelines.Tools {
+		if tool.Name == "" {
+			return fmt.Errorf("pipeline tool %d missing name", i)
+		}
+		if names[tool.Name] {
+			return fmt.Errorf("duplicate pipeline tool name: %s", tool.Name)
+		}
+		names[tool.Name] = true
+
+		switch tool.Type {
+		case "command":
+			if len(tool.Command) == 0 {
+				return fmt.Errorf("pipeline tool %s missing command", tool.Name)
+			}
+			if !filepath.IsAbs(tool.Command[0]) {
+				return fmt.Errorf("pipeline tool %s command must be an absolute path: %s", tool.Name, tool.Command[0])
+			}
+			for _, env := range tool.Env {
+				if !strings.Contains(env, "=") {
+					return fmt.Errorf("invalid pipeline tool %s env entry: %s (must be KEY=value)", tool.Name, env)
+				}
+			}
+		case "http":
+			u, err := url.Parse(tool.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid pipeline tool %s url: %s", tool.Name, tool.URL)
+			}
+		default:
+			return fmt.Errorf("invalid pipeline tool %s type: %s (must be command or http)", tool.Name, tool.Type)
+		}
+
+		if tool.Timeout != "" {
+			if d, err := time.ParseDuration(tool.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("invalid pipeline tool %s timeout: %s", tool.Name, tool.Timeout)
+			}
+		}
+		if tool.MaxOutputBytes < 0 {
+			return fmt.Errorf("pipeline tool %s max_output_bytes cannot be negative", tool.Name)
+		}
+	}
+	return nil
+}
+
+// validateHA checks the warm standby failover settings
+func validateHA(cfg *Config) error {
+	ha := cfg.HA
+	if !ha.Enabled {
+		return nil
+	}
+
+	if ha.Role != "primary" && ha.Role != "standby" {
+		return fmt.Errorf("invalid ha role: %s (must be primary or standby)", ha.Role)
+	}
+	if ha.Peer == "" {
+		return fmt.Errorf("ha enabled but peer not specified")
+	}
+	if ha.SharedSecret == "" {
+		return fmt.Errorf("ha enabled but shared_secret not specified")
+	}
+
+	var syncInterval, failoverTimeout time.Duration
+	var err error
+	if ha.SyncInterval != "" {
+		if syncInterval, err = time.ParseDuration(ha.SyncInterval); err != nil || syncInterval <= 0 {
+			return fmt.Errorf("invalid ha sync_interval: %s", ha.SyncInterval)
+		}
+	}
+	if ha.FailoverTimeout != "" {
+		if failoverTimeout, err = time.ParseDuration(ha.FailoverTimeout); err != nil || failoverTimeout <= 0 {
+			return fmt.Errorf("invalid ha failover_timeout: %s", ha.FailoverTimeout)
+		}
+	}
+	if syncInterval > 0 && failoverTimeout > 0 && failoverTimeout <= syncInterval {
+		return fmt.Errorf("ha failover_timeout %s must be longer than sync_interval %s", ha.FailoverTimeout, ha.SyncInterval)
 	}
 
 	return nil
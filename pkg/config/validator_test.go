@@ -13,26 +13,7 @@ func validConfig() *Config {
 	cfg.Server.Host = "localhost"
 
 	// Add at least one enabled backend
-	cfg.Backends = []struct {
-		ID       string `yaml:"id"`
-		Type     string `yaml:"type"`
-		Name     string `yaml:"name"`
-		Hardware string `yaml:"hardware"`
-		Enabled  bool   `yaml:"enabled"`
-		Endpoint string `yaml:"endpoint"`
-		Characteristics struct {
-			PowerWatts         float64 `yaml:"power_watts"`
-			AvgLatencyMs       int32   `yaml:"avg_latency_ms"`
-			MaxTokensPerSecond int32   `yaml:"max_tokens_per_second"`
-			Priority           int     `yaml:"priority"`
-		} `yaml:"characteristics"`
-		ModelCapability struct {
-			MaxModelSizeGB         int      `yaml:"max_model_size_gb"`
-			SupportedModelPatterns []string `yaml:"supported_model_patterns"`
-			PreferredModels        []string `yaml:"preferred_models"`
-			ExcludedPatterns       []string `yaml:"excluded_patterns"`
-		} `yaml:"model_capability"`
-	}{
+	cfg.Backends = []BackendConfig{
 		{
 			ID:       "backend-1",
 			Type:     "ollama",
@@ -240,6 +221,47 @@ func TestValidateConfig_BackendMissingEndpoint(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_VLLMBackendMissingEndpoint(t *testing.T) {
+	cfg := validConfig()
+	cfg.Backends[0].Type = "vllm"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected vllm backend to be valid, got: %v", err)
+	}
+
+	cfg.Backends[0].Endpoint = ""
+	err := ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "missing endpoint") {
+		t.Errorf("Expected 'missing endpoint' error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_CloudBackend(t *testing.T) {
+	cfg := validConfig()
+	cfg.Backends[0].Type = "cloud"
+	cfg.Backends[0].Endpoint = "" // defaults to the provider's API
+	cfg.Backends[0].APIKeyEnv = "OPENAI_API_KEY"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected cloud backend to be valid, got: %v", err)
+	}
+
+	cfg.Backends[0].Cloud.Provider = "gemini"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid cloud provider") {
+		t.Errorf("Expected invalid provider error, got: %v", err)
+	}
+
+	cfg.Backends[0].Cloud.Provider = "anthropic"
+	cfg.Backends[0].APIKeyEnv = ""
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "api_key_env") {
+		t.Errorf("Expected missing api_key_env error, got: %v", err)
+	}
+
+	cfg.Backends[0].APIKeyEnv = "ANTHROPIC_API_KEY"
+	cfg.Backends[0].Cloud.OutputCostPer1K = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "cloud token cost") {
+		t.Errorf("Expected negative cost error, got: %v", err)
+	}
+}
+
 func TestValidateConfig_BackendNegativePowerWatts(t *testing.T) {
 	cfg := validConfig()
 	cfg.Backends[0].Characteristics.PowerWatts = -5.0
@@ -361,6 +383,106 @@ func TestValidateConfig_EscalationPathInvalidBackend(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_DebugTap(t *testing.T) {
+	cfg := validConfig()
+	cfg.Monitoring.DebugTap.Enabled = true
+	cfg.Monitoring.DebugTap.Rate = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "debug_tap") {
+		t.Errorf("Expected an error for a negative tap rate, got: %v", err)
+	}
+
+	cfg.Monitoring.DebugTap.Rate = 5
+	cfg.Monitoring.DebugTap.MaxClients = 2
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid debug tap config should not error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Degradation(t *testing.T) {
+	cfg := validConfig()
+	profile := DegradationProfileConfig{Name: "thermal-critical", Backends: []string{"backend-1"}}
+	cfg.Server.Degradation.Profiles = []DegradationProfileConfig{profile}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "no conditions") {
+		t.Errorf("Expected an error for a profile without conditions, got: %v", err)
+	}
+
+	cfg.Server.Degradation.Profiles[0].When.Thermal = "toasty"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid thermal level") {
+		t.Errorf("Expected an error for an unknown thermal level, got: %v", err)
+	}
+
+	cfg.Server.Degradation.Profiles[0].When.Thermal = "critical"
+	cfg.Server.Degradation.Profiles[0].Backends = []string{"nonexistent-backend"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an error for an unknown backend, got: %v", err)
+	}
+
+	cfg.Server.Degradation.Profiles[0].Backends = []string{"backend-1"}
+	cfg.Server.Degradation.Profiles[0].DisableEndpoints = []string{"/v1/images", "/v1/video"}
+	cfg.Server.Degradation.Profiles[0].MaxTokens = 256
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid degradation config should not error, got: %v", err)
+	}
+
+	cfg.Server.Degradation.Profiles = append(cfg.Server.Degradation.Profiles, cfg.Server.Degradation.Profiles[0])
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("Expected an error for a duplicate profile, got: %v", err)
+	}
+}
+
+func TestValidateConfig_ForwardingScorer(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.Forwarding.Enabled = true
+	cfg.Routing.Forwarding.Scorer = "vibes"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "unknown forwarding scorer") {
+		t.Errorf("Expected an error for an unknown scorer, got: %v", err)
+	}
+
+	cfg.Routing.Forwarding.Scorer = "judge"
+	cfg.Routing.Forwarding.Judge.Backend = "nonexistent-backend"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "judge backend") {
+		t.Errorf("Expected an error for an unknown judge backend, got: %v", err)
+	}
+
+	cfg.Routing.Forwarding.Judge.Backend = "backend-1"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "requires a model") {
+		t.Errorf("Expected an error for a judge without a model, got: %v", err)
+	}
+
+	cfg.Routing.Forwarding.Judge.Model = "qwen2.5:0.5b"
+	cfg.Routing.Forwarding.Judge.Timeout = "soon"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "judge timeout") {
+		t.Errorf("Expected an error for an invalid judge timeout, got: %v", err)
+	}
+
+	cfg.Routing.Forwarding.Judge.Timeout = "10s"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid judge config should not error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Placement(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.Placement.Enabled = true
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "no tiers") {
+		t.Errorf("Expected an error for placement without tiers, got: %v", err)
+	}
+
+	cfg.Routing.Placement.Tiers = []PlacementTierConfig{
+		{MaxParamsB: 3, Backends: []string{"backend-1"}},
+		{Backends: []string{"nonexistent-backend"}},
+	}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "nonexistent-backend") {
+		t.Errorf("Expected an error for an unknown placement backend, got: %v", err)
+	}
+
+	cfg.Routing.Placement.Tiers = cfg.Routing.Placement.Tiers[:1]
+	cfg.Routing.Placement.Window = "1h"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid placement config should not error, got: %v", err)
+	}
+}
+
 func TestValidateConfig_LengthWeightOutOfRange_TooLow(t *testing.T) {
 	cfg := validConfig()
 	cfg.Routing.Confidence.LengthWeight = -0.1
@@ -487,6 +609,136 @@ func TestValidateConfig_ThermalValidConfig(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_ThermalPrediction(t *testing.T) {
+	cfg := validConfig()
+	cfg.Thermal.Enabled = true
+	cfg.Thermal.Temperature.Warning = 70.0
+	cfg.Thermal.Temperature.Critical = 85.0
+	cfg.Thermal.Temperature.Shutdown = 95.0
+	cfg.Thermal.Prediction.Samples = 8
+	cfg.Thermal.Prediction.Horizon = "90s"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid thermal prediction should not error, got: %v", err)
+	}
+
+	cfg.Thermal.Prediction.Samples = 1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "prediction samples") {
+		t.Errorf("Expected a prediction samples error for a single sample, got: %v", err)
+	}
+
+	cfg.Thermal.Prediction.Samples = 0
+	cfg.Thermal.Prediction.Horizon = "0s"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "prediction horizon") {
+		t.Errorf("Expected a prediction horizon error for a zero horizon, got: %v", err)
+	}
+}
+
+func TestValidateConfig_ThermalFanControl(t *testing.T) {
+	cfg := validConfig()
+	cfg.Thermal.Enabled = true
+	cfg.Thermal.Temperature.Warning = 70.0
+	cfg.Thermal.Temperature.Critical = 85.0
+	cfg.Thermal.Temperature.Shutdown = 95.0
+	cfg.Thermal.FanControl.Enabled = true
+	cfg.Thermal.FanControl.Interval = "10s"
+	cfg.Thermal.FanControl.Devices = map[string]FanDeviceConfig{
+		"cpu": {Driver: "hwmon", Path: "/sys/class/hwmon/hwmon3/pwm1", Curve: []FanCurvePoint{
+			{Temperature: 50, Percent: 20},
+			{Temperature: 80, Percent: 100},
+		}},
+		"nvidia": {Driver: "nvidia"},
+	}
+	cfg.Thermal.FanControl.Profiles = map[string]FanProfileConfig{
+		"quiet": {Hysteresis: 6, MinInterval: "1m"},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid fan control should not error, got: %v", err)
+	}
+
+	cfg.Thermal.FanControl.Devices["npu"] = FanDeviceConfig{Driver: "hwmon"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "needs a path") {
+		t.Errorf("Expected a missing path error for hwmon, got: %v", err)
+	}
+
+	cfg.Thermal.FanControl.Devices["npu"] = FanDeviceConfig{Driver: "ipmi"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "unknown driver") {
+		t.Errorf("Expected an unknown driver error, got: %v", err)
+	}
+
+	cfg.Thermal.FanControl.Devices["npu"] = FanDeviceConfig{Driver: "nbfc", Curve: []FanCurvePoint{
+		{Temperature: 60, Percent: 40},
+		{Temperature: 60, Percent: 80},
+	}}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "must increase") {
+		t.Errorf("Expected a curve order error, got: %v", err)
+	}
+
+	cfg.Thermal.FanControl.Devices["npu"] = FanDeviceConfig{Driver: "nbfc", Curve: []FanCurvePoint{{Temperature: 60, Percent: 120}}}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "0-100") {
+		t.Errorf("Expected a curve percent error, got: %v", err)
+	}
+
+	delete(cfg.Thermal.FanControl.Devices, "npu")
+	cfg.Thermal.FanControl.Profiles["silent"] = FanProfileConfig{}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "must be quiet, moderate or loud") {
+		t.Errorf("Expected an unknown profile error, got: %v", err)
+	}
+
+	delete(cfg.Thermal.FanControl.Profiles, "silent")
+	cfg.Thermal.FanControl.Profiles["loud"] = FanProfileConfig{Hysteresis: -1}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "hysteresis") {
+		t.Errorf("Expected a negative hysteresis error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_EfficiencySchedule(t *testing.T) {
+	cfg := validConfig()
+	cfg.Efficiency.Enabled = true
+	cfg.Efficiency.DefaultMode = "Balanced"
+	cfg.Efficiency.Schedule = []ScheduleEntryConfig{
+		{Name: "nights", Mode: "Quiet", Start: "22:00", End: "06:00"},
+		{Name: "office", Mode: "Performance", Days: []string{"weekdays"}, Start: "09:00", End: "17:00"},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid schedule should not error, got: %v", err)
+	}
+
+	tests := []struct {
+		entry ScheduleEntryConfig
+		want  string
+	}{
+		{ScheduleEntryConfig{Mode: "Quiet", Start: "22:00", End: "06:00"}, "needs a name"},
+		{ScheduleEntryConfig{Name: "nights", Mode: "Quiet", Start: "22:00", End: "06:00"}, "duplicate"},
+		{ScheduleEntryConfig{Name: "late", Mode: "Silent", Start: "22:00", End: "06:00"}, "unknown mode"},
+		{ScheduleEntryConfig{Name: "late", Mode: "Quiet", Days: []string{"holidays"}, Start: "22:00", End: "06:00"}, "unknown day"},
+		{ScheduleEntryConfig{Name: "late", Mode: "Quiet", Start: "10pm", End: "06:00"}, "HH:MM"},
+	}
+	for _, tt := range tests {
+		cfg.Efficiency.Schedule = []ScheduleEntryConfig{{Name: "nights", Mode: "Quiet", Start: "22:00", End: "06:00"}, tt.entry}
+		if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected %q error for %+v, got: %v", tt.want, tt.entry, err)
+		}
+	}
+}
+
+func TestValidateConfig_MeetingQuietMax(t *testing.T) {
+	cfg := validConfig()
+	cfg.Efficiency.Enabled = true
+	cfg.Efficiency.DefaultMode = "Balanced"
+	cfg.Efficiency.QuietDuringMeetings = true
+	cfg.Efficiency.MeetingQuietMax = "90m"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid meeting_quiet_max should not error, got: %v", err)
+	}
+
+	for _, value := range []string{"2 hours", "0s", "-1h"} {
+		cfg.Efficiency.MeetingQuietMax = value
+		if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "meeting_quiet_max") {
+			t.Errorf("Expected a meeting_quiet_max error for %q, got: %v", value, err)
+		}
+	}
+}
+
 func TestValidateConfig_FanQuietGreaterThanModerate(t *testing.T) {
 	cfg := validConfig()
 	cfg.Thermal.Enabled = true
@@ -709,3 +961,1196 @@ func TestValidateConfig_EmptyDefaultBackend(t *testing.T) {
 		t.Errorf("Empty default backend should be allowed, got: %v", err)
 	}
 }
+
+func TestValidateConfig_StreamingNegativeBatchTokens(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Streaming.MaxBatchTokens = -1
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for negative max_batch_tokens")
+	}
+	if !strings.Contains(err.Error(), "max_batch_tokens") {
+		t.Errorf("Expected 'max_batch_tokens' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_HTTP3RequiresTLS(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.HTTP3.Enabled = true
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for HTTP/3 without TLS")
+	}
+	if !strings.Contains(err.Error(), "QUIC requires TLS") {
+		t.Errorf("Expected 'QUIC requires TLS' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_HTTP3InvalidPort(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.TLS.Enabled = true
+	cfg.Server.TLS.CertFile = "/tmp/cert.pem"
+	cfg.Server.TLS.KeyFile = "/tmp/key.pem"
+	cfg.Server.HTTP3.Enabled = true
+	cfg.Server.HTTP3.Port = 70000
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for invalid HTTP/3 port")
+	}
+	if !strings.Contains(err.Error(), "invalid HTTP/3 port") {
+		t.Errorf("Expected 'invalid HTTP/3 port' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_HTTP3DefaultPort(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.TLS.Enabled = true
+	cfg.Server.TLS.CertFile = "/tmp/cert.pem"
+	cfg.Server.TLS.KeyFile = "/tmp/key.pem"
+	cfg.Server.HTTP3.Enabled = true
+	cfg.Server.HTTP3.Port = 0
+
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected port 0 to share http_port, got: %v", err)
+	}
+}
+
+func TestValidateConfig_GRPCInvalidCompression(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.GRPC.Compression = "brotli"
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for unsupported gRPC compression")
+	}
+	if !strings.Contains(err.Error(), "invalid gRPC compression") {
+		t.Errorf("Expected 'invalid gRPC compression' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_GRPCNegativeMessageSize(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.GRPC.MaxRecvMsgSizeMB = -1
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for negative max_recv_msg_size_mb")
+	}
+	if !strings.Contains(err.Error(), "max_recv_msg_size_mb") {
+		t.Errorf("Expected 'max_recv_msg_size_mb' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_MiddlewareUnknownName(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Middleware.Routes = map[string][]string{
+		"/v1/models": {"auth", "compress"},
+	}
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for unknown middleware")
+	}
+	if !strings.Contains(err.Error(), `unknown middleware "compress"`) {
+		t.Errorf("Expected unknown middleware error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_MiddlewareNamedRateLimit(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Middleware.Routes = map[string][]string{
+		"/v1/images/*": {"auth", "rate_limit:strict"},
+	}
+
+	// Undefined limiter is rejected
+	if err := ValidateConfig(cfg); err == nil {
+		t.Fatal("Expected error for undefined named rate limit")
+	}
+
+	cfg.Server.Middleware.RateLimits = map[string]struct {
+		Rate  float64 `yaml:"rate"`
+		Burst int     `yaml:"burst"`
+	}{
+		"strict": {Rate: 1, Burst: 2},
+	}
+
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected named rate limit to be accepted, got: %v", err)
+	}
+}
+
+func TestValidateConfig_ContainerInvalidRuntime(t *testing.T) {
+	cfg := validConfig()
+	cfg.Backends[0].Container.Image = "ollama/ollama:latest"
+	cfg.Backends[0].Container.Runtime = "lxc"
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for invalid container runtime")
+	}
+	if !strings.Contains(err.Error(), "invalid container runtime") {
+		t.Errorf("Expected 'invalid container runtime' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_ReportsNegativePrice(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Reports.PricePerKWh = -0.1
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for negative price_per_kwh")
+	}
+	if !strings.Contains(err.Error(), "price_per_kwh") {
+		t.Errorf("Expected 'price_per_kwh' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_MediaIONegativeTenantLimit(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.MediaIO.MBPerSecond = 10
+	cfg.Server.MediaIO.Tenants = map[string]float64{"team-a": -1}
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for negative media_io tenant limit")
+	}
+	if !strings.Contains(err.Error(), "media_io limit for tenant team-a") {
+		t.Errorf("Expected 'media_io limit for tenant team-a' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_VideoNegativeMaxUpload(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Video.MaxUploadMB = -1
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for negative video max_upload_mb")
+	}
+	if !strings.Contains(err.Error(), "max_upload_mb") {
+		t.Errorf("Expected 'max_upload_mb' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_NegativeTokenCost(t *testing.T) {
+	cfg := validConfig()
+	cfg.Backends[0].Characteristics.CostPer1KTokens = -1
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected error for negative cost_per_1k_tokens")
+	}
+	if !strings.Contains(err.Error(), "cost_per_1k_tokens") {
+		t.Errorf("Expected 'cost_per_1k_tokens' in error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_UsageExport(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Reports.Export.Type = "kafka"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid reports export type") {
+		t.Errorf("Expected invalid export type error, got: %v", err)
+	}
+
+	cfg.Server.Reports.Export.Type = "file"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "requires a path") {
+		t.Errorf("Expected missing path error, got: %v", err)
+	}
+
+	cfg.Server.Reports.Export.Path = "/var/lib/ollama-proxy/usage.jsonl"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected file export to be valid, got: %v", err)
+	}
+
+	cfg.Server.Reports.Export.Type = "otlp"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "requires an endpoint") {
+		t.Errorf("Expected missing endpoint error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Alerting(t *testing.T) {
+	cfg := validConfig()
+	cfg.Monitoring.Alerting.Enabled = true
+	cfg.Monitoring.Alerting.Rules = []AlertRuleConfig{
+		{Name: "backend-down", Metric: "backend_up", Op: "<", Threshold: 1, For: "2m", Notify: []string{"dbus"}},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid alerting config, got: %v", err)
+	}
+
+	cfg.Monitoring.Alerting.Rules[0].Op = "gt"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid op") {
+		t.Errorf("Expected invalid op error, got: %v", err)
+	}
+
+	cfg.Monitoring.Alerting.Rules[0].Op = "<"
+	cfg.Monitoring.Alerting.Rules[0].Notify = []string{"pager"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "unknown notifier") {
+		t.Errorf("Expected unknown notifier error, got: %v", err)
+	}
+
+	cfg.Monitoring.Alerting.Rules[0].Notify = nil
+	cfg.Monitoring.Alerting.Rules[0].For = "soon"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid for duration") {
+		t.Errorf("Expected invalid duration error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_CrashLog(t *testing.T) {
+	cfg := validConfig()
+	cfg.Monitoring.CrashLog.Dir = "/tmp/crashes"
+	cfg.Monitoring.CrashLog.MaxFiles = 10
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid crash log config, got: %v", err)
+	}
+
+	cfg.Monitoring.CrashLog.MaxFiles = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "max_files") {
+		t.Errorf("Expected max_files error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_WebSocketOrigins(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.WebSocket.AllowedOrigins = []string{"*", "https://app.example.com", "https://*.example.com:8443"}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid origins, got: %v", err)
+	}
+
+	for _, origin := range []string{"app.example.com", "https://app.example.com/path", ""} {
+		cfg.Server.WebSocket.AllowedOrigins = []string{origin}
+		if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "allowed origin") {
+			t.Errorf("Expected invalid origin error for %q, got: %v", origin, err)
+		}
+	}
+}
+
+func TestValidateConfig_WebSocketLimits(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.WebSocket.MaxConcurrentRequests = 4
+	cfg.Server.WebSocket.TokensPerMinute = 6000
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid websocket limits, got: %v", err)
+	}
+
+	cfg.Server.WebSocket.MaxMessageKB = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "websocket limits") {
+		t.Errorf("Expected websocket limits error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_LanguageDetection(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.LanguageDetection.Detector = "heuristic"
+	cfg.Routing.LanguageDetection.MinConfidence = 0.6
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid language detection config, got: %v", err)
+	}
+
+	cfg.Routing.LanguageDetection.MinConfidence = 1.5
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "language_detection min_confidence") {
+		t.Errorf("Expected min_confidence error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_MeetingGuard(t *testing.T) {
+	cfg := validConfig()
+	cfg.VirtualDevices.MeetingGuard.Enabled = true
+	cfg.VirtualDevices.MeetingGuard.MaxLowPriorityConcurrent = 1
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid meeting guard config, got: %v", err)
+	}
+
+	cfg.VirtualDevices.MeetingGuard.MaxLowPriorityConcurrent = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "max_low_priority_concurrent") {
+		t.Errorf("Expected max_low_priority_concurrent error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Sessions(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.Sessions.Enabled = true
+	cfg.Routing.Sessions.TTL = "30m"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid sessions config, got: %v", err)
+	}
+
+	cfg.Routing.Sessions.TTL = "-1m"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "sessions ttl") {
+		t.Errorf("Expected sessions ttl error, got: %v", err)
+	}
+
+	cfg.Routing.Sessions.TTL = ""
+	cfg.Routing.Sessions.MaxSessions = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "max_sessions") {
+		t.Errorf("Expected max_sessions error, got: %v", err)
+	}
+
+	cfg.Routing.Sessions.MaxSessions = 0
+	cfg.Routing.Sessions.Boost.Enabled = true
+	cfg.Routing.Sessions.Boost.HalfLife = "soon"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "half_life") {
+		t.Errorf("Expected boost half_life error, got: %v", err)
+	}
+
+	cfg.Routing.Sessions.Boost.HalfLife = "5m"
+	cfg.Routing.Sessions.Boost.Threshold = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "threshold") {
+		t.Errorf("Expected boost threshold error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_LoadBalancing(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.LoadBalancing.Strategy = "least_outstanding"
+	cfg.Routing.LoadBalancing.Models = map[string]string{"llama3*": "weighted"}
+	cfg.Backends[0].Characteristics.Weight = 3
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid load balancing config, got: %v", err)
+	}
+
+	cfg.Routing.LoadBalancing.EWMADecay = 1.5
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "ewma_decay") {
+		t.Errorf("Expected ewma_decay error, got: %v", err)
+	}
+
+	cfg.Routing.LoadBalancing.EWMADecay = 0
+	cfg.Backends[0].Characteristics.Weight = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "negative weight") {
+		t.Errorf("Expected negative weight error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_KeyRateLimits(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.RateLimit.PerKey = RateLimitConfig{Rate: 5, StreamRate: 1}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid per_key rate limit, got: %v", err)
+	}
+
+	cfg.Server.RateLimit.PerKey.StreamBurst = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "per_key") {
+		t.Errorf("Expected per_key error, got: %v", err)
+	}
+	cfg.Server.RateLimit.PerKey.StreamBurst = 0
+
+	cfg.Server.Auth.APIKeys = map[string]APIKeyConfig{
+		"sk-batch": {Name: "batch", Enabled: true, RateLimit: &RateLimitConfig{Rate: -1}},
+	}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "api key batch") {
+		t.Errorf("Expected api key rate_limit error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Quotas(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Auth.Quota = QuotaConfig{DailyTokens: 100000, MonthlyRequests: 5000}
+	cfg.Server.Auth.APIKeys = map[string]APIKeyConfig{
+		"sk-batch": {Name: "batch", Enabled: true, Quota: &QuotaConfig{MonthlyTokens: 10000000}},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid quotas, got: %v", err)
+	}
+
+	cfg.Server.Auth.Quota.DailyRequests = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "auth quota") {
+		t.Errorf("Expected auth quota error, got: %v", err)
+	}
+	cfg.Server.Auth.Quota.DailyRequests = 0
+
+	cfg.Server.Auth.APIKeys["sk-batch"].Quota.DailyTokens = -5
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "api key batch quota") {
+		t.Errorf("Expected api key quota error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_UsageStore(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Reports.Store.Type = "file"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "store type file requires a path") {
+		t.Errorf("Expected store path error, got: %v", err)
+	}
+
+	cfg.Server.Reports.Store.Path = "/var/lib/ollama-proxy/usage.jsonl"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid store, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Labels(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Labels.Allowed = map[string][]string{"team": {"ml", "search"}, "app": nil}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid labels, got: %v", err)
+	}
+
+	tests := []struct {
+		allowed map[string][]string
+		want    string
+	}{
+		{map[string][]string{"Team": nil}, "invalid label name"},
+		{map[string][]string{"team": {"machine learning"}}, "invalid value"},
+	}
+	for _, tt := range tests {
+		cfg.Server.Labels.Allowed = tt.allowed
+		if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected %q error for %v, got: %v", tt.want, tt.allowed, err)
+		}
+	}
+
+	cfg.Server.Labels.Allowed = map[string][]string{"team": {"ml"}}
+	cfg.Server.Labels.Backends = map[string][]string{"team=ml": {"backend-1"}}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid label backends, got: %v", err)
+	}
+	for rule, want := range map[string]string{"team": "use label=value", "app=docs": "not in labels allowed", "team=search": ""} {
+		cfg.Server.Labels.Backends = map[string][]string{rule: {"backend-1"}}
+		if err := ValidateConfig(cfg); want == "" && err != nil || want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("Expected %q error for rule %s, got: %v", want, rule, err)
+		}
+	}
+	cfg.Server.Labels.Backends = map[string][]string{"team=ml": {"missing"}}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected unknown backend error, got: %v", err)
+	}
+	cfg.Server.Labels.Backends = nil
+
+	cfg.Server.Labels.Allowed = nil
+	cfg.Server.Labels.MaxLabels = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "max_labels") {
+		t.Errorf("Expected max_labels error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Federation(t *testing.T) {
+	cfg := validConfig()
+	cfg.Backends[0].Federated = true
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "no private_key_file") {
+		t.Errorf("Expected private_key_file error, got: %v", err)
+	}
+
+	cfg.Server.Federation.InstanceID = "edge-1"
+	cfg.Server.Federation.PrivateKeyFile = "/etc/ollama-proxy/federation.pem"
+	cfg.Server.Federation.TrustedPeers = map[string]FederationPeerConfig{
+		"edge-2": {PublicKey: "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=", Permissions: []string{"inference"}},
+	}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "no peer_instance") {
+		t.Errorf("Expected peer_instance error, got: %v", err)
+	}
+
+	cfg.Backends[0].PeerInstance = "pool-1"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid federation config, got: %v", err)
+	}
+
+	cfg.Server.Federation.TrustedPeers["edge-3"] = FederationPeerConfig{PublicKey: "not-a-key"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "trusted peer edge-3") {
+		t.Errorf("Expected trusted peer error, got: %v", err)
+	}
+	delete(cfg.Server.Federation.TrustedPeers, "edge-3")
+
+	cfg.Server.Federation.InstanceID = ""
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "private_key_file requires instance_id") {
+		t.Errorf("Expected instance_id error, got: %v", err)
+	}
+
+	// A proxy that only verifies still needs the instance ID requests are signed for
+	cfg.Server.Federation.PrivateKeyFile = ""
+	cfg.Backends[0].Federated = false
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "trusted_peers requires instance_id") {
+		t.Errorf("Expected instance_id error, got: %v", err)
+	}
+	cfg.Server.Federation.InstanceID = "pool-1"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected a verifying-only proxy to be valid, got: %v", err)
+	}
+}
+
+func TestValidateConfig_AuditLog(t *testing.T) {
+	cfg := validConfig()
+	cfg.Monitoring.AuditLog.Enabled = true
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "audit_log enabled but path") {
+		t.Errorf("Expected audit_log path error, got: %v", err)
+	}
+
+	cfg.Monitoring.AuditLog.Path = "/var/log/ollama-proxy/audit.jsonl"
+	cfg.Monitoring.AuditLog.Redact = []string{"key", "prompt_hash"}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid audit_log config, got: %v", err)
+	}
+
+	cfg.Monitoring.AuditLog.Redact = []string{"prompt"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "redact field: prompt") {
+		t.Errorf("Expected redact field error, got: %v", err)
+	}
+
+	cfg.Monitoring.AuditLog.Redact = nil
+	cfg.Monitoring.AuditLog.PromptHashLength = 65
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "prompt_hash_length") {
+		t.Errorf("Expected prompt_hash_length error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_PostProcessing(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.PostProcessing.Steps = []string{"strip_html", "normalize_markdown", "citations"}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid post_processing config, got: %v", err)
+	}
+
+	cfg.Server.PostProcessing.Steps = []string{"strip_html", "translate"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "unknown post-processing step: translate") {
+		t.Errorf("Expected unknown step error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Hedging(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.Hedging.Enabled = true
+	cfg.Routing.Hedging.Delay = "150ms"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid hedging config, got: %v", err)
+	}
+
+	cfg.Routing.Hedging.Delay = "-1s"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "hedging delay") {
+		t.Errorf("Expected hedging delay error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_DeviceUtilization(t *testing.T) {
+	cfg := validConfig()
+	cfg.Devices.Utilization.Enabled = true
+	cfg.Devices.Utilization.Interval = "10s"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid utilization config, got: %v", err)
+	}
+
+	cfg.Devices.Utilization.Interval = "often"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "utilization interval") {
+		t.Errorf("Expected utilization interval error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Energy(t *testing.T) {
+	cfg := validConfig()
+	cfg.Monitoring.Energy.Enabled = true
+	cfg.Monitoring.Energy.Interval = "500ms"
+	cfg.Monitoring.Energy.Sources = []string{"rapl", "nvml"}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid energy config, got: %v", err)
+	}
+
+	cfg.Monitoring.Energy.Sources = []string{"ipmi"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "energy source") {
+		t.Errorf("Expected energy source error, got: %v", err)
+	}
+
+	cfg.Monitoring.Energy.Sources = nil
+	cfg.Monitoring.Energy.Interval = "0s"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "energy interval") {
+		t.Errorf("Expected energy interval error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_RetryAfter(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.RetryAfter.Min = "2s"
+	cfg.Routing.RetryAfter.Max = "1m"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid retry_after config, got: %v", err)
+	}
+
+	cfg.Routing.RetryAfter.Max = "1s"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "exceeds max") {
+		t.Errorf("Expected a bounds error, got: %v", err)
+	}
+
+	cfg.Routing.RetryAfter.Max = "soon"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "retry_after max") {
+		t.Errorf("Expected an invalid max error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_MemoryAdmission(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.MemoryAdmission.Enabled = true
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "devices utilization") {
+		t.Errorf("Expected an error without utilization sampling, got: %v", err)
+	}
+
+	cfg.Devices.Utilization.Enabled = true
+	cfg.Routing.MemoryAdmission.HeadroomMB = 1024
+	cfg.Routing.MemoryAdmission.ResidentWindow = "10m"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid memory admission config, got: %v", err)
+	}
+
+	cfg.Routing.MemoryAdmission.HeadroomMB = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "cannot be negative") {
+		t.Errorf("Expected a negative headroom error, got: %v", err)
+	}
+
+	cfg.Routing.MemoryAdmission.HeadroomMB = 0
+	cfg.Routing.MemoryAdmission.ResidentWindow = "forever"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "resident_window") {
+		t.Errorf("Expected a resident window error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Dashboard(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Dashboard.Enabled = true
+	cfg.Server.Dashboard.Interval = "1s"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid dashboard config, got: %v", err)
+	}
+
+	cfg.Server.Dashboard.Interval = "0s"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "dashboard interval") {
+		t.Errorf("Expected an interval error, got: %v", err)
+	}
+
+	cfg.Server.Dashboard.Interval = ""
+	cfg.Server.Dashboard.Decisions = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "dashboard decisions") {
+		t.Errorf("Expected a negative decisions error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Benchmark(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.Benchmark.Enabled = true
+	cfg.Routing.Benchmark.Window = "02:00-05:00"
+	cfg.Routing.Benchmark.Timeout = "90s"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid benchmark config, got: %v", err)
+	}
+
+	cfg.Routing.Benchmark.Window = ""
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "benchmark window") {
+		t.Errorf("Expected a missing window error, got: %v", err)
+	}
+
+	cfg.Routing.Benchmark.Window = "23:00-01:00"
+	cfg.Routing.Benchmark.Interval = "hourly"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "benchmark interval") {
+		t.Errorf("Expected an interval error, got: %v", err)
+	}
+
+	cfg.Routing.Benchmark.Interval = ""
+	cfg.Routing.Benchmark.MaxTokens = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "max_tokens") {
+		t.Errorf("Expected a negative max_tokens error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Speculative(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.Speculative.Enabled = true
+	cfg.Routing.Speculative.DraftBackend = "nonexistent-backend"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "nonexistent-backend") {
+		t.Errorf("Expected an error for an unknown draft backend, got: %v", err)
+	}
+
+	cfg.Routing.Speculative.DraftBackend = "backend-1"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "draft_model") {
+		t.Errorf("Expected an error without a draft model, got: %v", err)
+	}
+
+	cfg.Routing.Speculative.DraftModel = "qwen2.5:0.5b"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid speculative config should not error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Ensemble(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.Ensemble.Enabled = true
+	cfg.Routing.Ensemble.Size = 5
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "ensemble size") {
+		t.Errorf("Expected an ensemble size error, got: %v", err)
+	}
+
+	cfg.Routing.Ensemble.Size = 2
+	cfg.Routing.Ensemble.Aliases = map[string][]string{"ensemble-small": {"a", "b", "c", "d"}}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "ensemble-small") {
+		t.Errorf("Expected an error for an alias of 4 models, got: %v", err)
+	}
+
+	cfg.Routing.Ensemble.Aliases = map[string][]string{"ensemble-small": {"llama3.2:3b", "qwen2.5:3b"}}
+	cfg.Routing.Ensemble.Judge.Backend = "nonexistent-backend"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "nonexistent-backend") {
+		t.Errorf("Expected an error for an unknown judge backend, got: %v", err)
+	}
+
+	cfg.Routing.Ensemble.Judge.Backend = "backend-1"
+	cfg.Routing.Ensemble.Judge.Model = "qwen2.5:0.5b"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid ensemble config should not error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_EmbedBatch(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.EmbedBatch.ChunkSize = 64
+	cfg.Routing.EmbedBatch.MaxPerBackend = 2
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid embed_batch config should not error, got: %v", err)
+	}
+
+	cfg.Routing.EmbedBatch.ChunkSize = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "embed_batch") {
+		t.Errorf("Expected an embed_batch error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_CircuitBreaker(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.CircuitBreaker.Enabled = true
+	cfg.Routing.CircuitBreaker.ErrorRate = 0.5
+	cfg.Routing.CircuitBreaker.Window = "30s"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid circuit_breaker config, got: %v", err)
+	}
+
+	cfg.Routing.CircuitBreaker.ErrorRate = 1.5
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "error_rate") {
+		t.Errorf("Expected error_rate error, got: %v", err)
+	}
+
+	cfg.Routing.CircuitBreaker.ErrorRate = 0
+	cfg.Routing.CircuitBreaker.OpenTimeout = "soon"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "open_timeout") {
+		t.Errorf("Expected open_timeout error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_DecisionLog(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.DecisionLog.Enabled = true
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "decision_log path") {
+		t.Errorf("Expected decision_log path error, got: %v", err)
+	}
+
+	cfg.Routing.DecisionLog.Path = "/var/lib/ollama-proxy/routing-decisions.jsonl"
+	cfg.Routing.DecisionLog.SampleRate = 0.1
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid decision_log config, got: %v", err)
+	}
+
+	cfg.Routing.DecisionLog.SampleRate = 2
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "sample_rate") {
+		t.Errorf("Expected sample_rate error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Scheduling(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routing.Scheduling.Enabled = true
+	cfg.Routing.Scheduling.MaxConcurrent = 2
+	cfg.Routing.Scheduling.QueueTimeout = "30s"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid scheduling config, got: %v", err)
+	}
+
+	cfg.Routing.Scheduling.QueueTimeout = "soon"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "queue_timeout") {
+		t.Errorf("Expected queue_timeout error, got: %v", err)
+	}
+
+	cfg.Routing.Scheduling.QueueTimeout = ""
+	cfg.Routing.Scheduling.MaxQueueDepth = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "scheduling limits") {
+		t.Errorf("Expected scheduling limits error, got: %v", err)
+	}
+
+	cfg.Routing.Scheduling.MaxQueueDepth = 0
+	cfg.Backends[0].Characteristics.MaxConcurrent = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "max_concurrent") {
+		t.Errorf("Expected backend max_concurrent error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Cache(t *testing.T) {
+	cfg := validConfig()
+	cfg.Cache.Enabled = true
+	cfg.Cache.TTLSeconds = 600
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid memory cache config, got: %v", err)
+	}
+
+	cfg.Cache.Type = "disk"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "requires a dir") {
+		t.Errorf("Expected disk cache dir error, got: %v", err)
+	}
+	cfg.Cache.Dir = "/var/cache/ollama-proxy"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid disk cache config, got: %v", err)
+	}
+
+	cfg.Cache.Type = "redis"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid cache type") {
+		t.Errorf("Expected cache type error, got: %v", err)
+	}
+
+	cfg.Cache.Type = "memory"
+	cfg.Cache.MaxEntries = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "cache limits") {
+		t.Errorf("Expected cache limits error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_HA(t *testing.T) {
+	cfg := validConfig()
+	cfg.HA.Enabled = true
+	cfg.HA.Role = "standby"
+	cfg.HA.Peer = "primary.local:50051"
+	cfg.HA.SharedSecret = "s3cret"
+	cfg.HA.SyncInterval = "2s"
+	cfg.HA.FailoverTimeout = "10s"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid ha config, got: %v", err)
+	}
+
+	cfg.HA.Role = "leader"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid ha role") {
+		t.Errorf("Expected ha role error, got: %v", err)
+	}
+	cfg.HA.Role = "primary"
+
+	cfg.HA.SharedSecret = ""
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "shared_secret") {
+		t.Errorf("Expected shared_secret error, got: %v", err)
+	}
+	cfg.HA.SharedSecret = "s3cret"
+
+	cfg.HA.FailoverTimeout = "1s"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "must be longer than sync_interval") {
+		t.Errorf("Expected failover timeout error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Shaping(t *testing.T) {
+	cfg := validConfig()
+	temperature := float32(0.3)
+	cfg.Server.Shaping.MaxTokens = 2048
+	cfg.Server.Shaping.Models = map[string]ShapingConfig{"llama3:*": {MaxTokens: 1024}}
+	cfg.Server.Shaping.Modes = map[string]ShapingConfig{"UltraEfficiency": {MaxTokens: 256, Temperature: &temperature}}
+	cfg.Server.Auth.APIKeys = map[string]APIKeyConfig{
+		"sk-batch": {Name: "batch", Enabled: true, Shaping: &ShapingConfig{MaxTokens: 8192}},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid shaping, got: %v", err)
+	}
+
+	cfg.Server.Shaping.Modes["Auto"] = ShapingConfig{MaxTokens: 512}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid efficiency mode") {
+		t.Errorf("Expected invalid mode error, got: %v", err)
+	}
+	delete(cfg.Server.Shaping.Modes, "Auto")
+
+	cfg.Server.Shaping.Models["llama3:["] = ShapingConfig{MaxTokens: 512}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("Expected invalid pattern error, got: %v", err)
+	}
+	delete(cfg.Server.Shaping.Models, "llama3:[")
+
+	temperature = 3
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "temperature") {
+		t.Errorf("Expected temperature range error, got: %v", err)
+	}
+	temperature = 0.3
+
+	cfg.Server.Auth.APIKeys["sk-batch"].Shaping.MaxTokens = -1
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "api key batch shaping") {
+		t.Errorf("Expected api key shaping error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_StreamingIdleTimeout(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Streaming.KeepaliveInterval = "0s"
+	cfg.Server.Streaming.IdleTimeout = "2m"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected disabled keepalives and an idle timeout to be valid, got: %v", err)
+	}
+
+	cfg.Server.Streaming.IdleTimeout = "-1s"
+	err := ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "idle_timeout") {
+		t.Errorf("Expected an idle_timeout error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_InvalidDrainTimeout(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Drain.Timeout = "0s"
+
+	err := ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "drain timeout") {
+		t.Errorf("Expected a drain timeout error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_ServerTimeouts(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Timeouts.Write = "2m"
+	cfg.Server.Timeouts.StreamWrite = "0s"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected server timeouts to be valid, got: %v", err)
+	}
+
+	cfg.Server.Timeouts.Read = "soon"
+	err := ValidateConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "timeouts read") {
+		t.Errorf("Expected a read timeout error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_LiveSources(t *testing.T) {
+	cfg := validConfig()
+	cfg.Pipelines.Sources = []LiveSourceConfig{
+		{ID: "door", Type: "rtsp", URL: "rtsp://cam/stream", Pipeline: "describe", Window: "10s"},
+		{ID: "desk", Type: "camera", Device: "/dev/video0", Pipeline: "describe"},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid sources, got: %v", err)
+	}
+
+	cfg.Pipelines.Sources[1].ID = "door"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "duplicate pipeline source") {
+		t.Errorf("Expected a duplicate source error, got: %v", err)
+	}
+
+	cfg.Pipelines.Sources[1].ID = "desk"
+	cfg.Pipelines.Sources[1].Device = ""
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "missing device") {
+		t.Errorf("Expected a missing device error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Listeners(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Listeners = []ListenerConfig{
+		{Name: "admin", Address: "127.0.0.1:8081", Groups: []string{"health", "admin"}, SkipMiddleware: []string{"auth"}},
+		{Name: "lan", Address: ":8443", Middleware: []string{"rate_limit"}},
+	}
+	cfg.Server.Listeners[1].TLS.Enabled = true
+	cfg.Server.Listeners[1].TLS.CertFile = "/etc/proxy/cert.pem"
+	cfg.Server.Listeners[1].TLS.KeyFile = "/etc/proxy/key.pem"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid listeners, got: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(l *ListenerConfig)
+		want   string
+	}{
+		{"duplicate name", func(l *ListenerConfig) { l.Name = "admin" }, "duplicate listener name"},
+		{"bad address", func(l *ListenerConfig) { l.Address = "8443" }, "invalid address"},
+		{"bad port", func(l *ListenerConfig) { l.Address = ":0" }, "invalid port"},
+		{"unknown group", func(l *ListenerConfig) { l.Groups = []string{"metrics"} }, "unknown group"},
+		{"unknown middleware", func(l *ListenerConfig) { l.Middleware = []string{"compress"} }, `unknown middleware "compress"`},
+		{"missing key", func(l *ListenerConfig) { l.TLS.KeyFile = "" }, "cert_file or key_file"},
+		{"auth skipped on LAN", func(l *ListenerConfig) { l.SkipMiddleware = []string{"auth"} }, "loopback"},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.Server.Listeners = []ListenerConfig{{Name: "admin", Address: "localhost:8081"}, {Name: "lan", Address: ":8443"}}
+		cfg.Server.Listeners[1].TLS.Enabled = true
+		cfg.Server.Listeners[1].TLS.CertFile = "/etc/proxy/cert.pem"
+		cfg.Server.Listeners[1].TLS.KeyFile = "/etc/proxy/key.pem"
+		tt.modify(&cfg.Server.Listeners[1])
+
+		err := ValidateConfig(cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got: %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestValidateConfig_NetworkIdentity(t *testing.T) {
+	cfg := validConfig()
+	ni := &cfg.Server.NetworkIdentity
+	ni.Enabled = true
+	ni.Identities = map[string]APIKeyConfig{
+		"alice@example.com": {Permissions: []string{"*"}, Enabled: true},
+		"tag:ci":            {Enabled: true, Quota: &QuotaConfig{DailyTokens: 100000}},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid tailscale config, got: %v", err)
+	}
+
+	ni.Provider = "wireguard"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "requires peers") {
+		t.Errorf("Expected peers error, got: %v", err)
+	}
+	ni.Peers = map[string]string{"10.8.0.2": "laptop", "10.8.0.0/24": "office"}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid wireguard config, got: %v", err)
+	}
+
+	ni.Peers["10.8.0.0/40"] = "bad"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid address range") {
+		t.Errorf("Expected address range error, got: %v", err)
+	}
+	delete(ni.Peers, "10.8.0.0/40")
+
+	ni.Default = &APIKeyConfig{Enabled: true, Quota: &QuotaConfig{DailyTokens: -1}}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "network_identity default quota") {
+		t.Errorf("Expected default quota error, got: %v", err)
+	}
+	ni.Default = nil
+
+	ni.Provider = "zerotier"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid network_identity provider") {
+		t.Errorf("Expected provider error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Authz(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Authz.Enabled = true
+	cfg.Server.Authz.AnonymousRole = "read-only"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid authz should not error, got: %v", err)
+	}
+
+	cfg.Server.Authz.ClientCerts = map[string]string{"ops.example.com": "admin"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "client_ca_file") {
+		t.Errorf("Expected certificate roles to need a client CA, got: %v", err)
+	}
+
+	cfg.Server.TLS.Enabled = true
+	cfg.Server.TLS.CertFile = "server.pem"
+	cfg.Server.TLS.KeyFile = "server-key.pem"
+	cfg.Server.TLS.ClientCAFile = "ca.pem"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Certificate roles with a client CA should not error, got: %v", err)
+	}
+
+	cfg.Server.Authz.ClientCerts["spiffe://example.com/app"] = "superuser"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "unknown role") {
+		t.Errorf("Expected an unknown role error, got: %v", err)
+	}
+	delete(cfg.Server.Authz.ClientCerts, "spiffe://example.com/app")
+
+	cfg.Server.Authz.DefaultCertRole = "guest"
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "default_cert_role") {
+		t.Errorf("Expected a default_cert_role error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_HashedAPIKey(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Auth.Enabled = true
+	cfg.Server.Auth.APIKeys = map[string]APIKeyConfig{
+		"sha256:" + strings.Repeat("ab", 32): {Name: "ops", Enabled: true},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("A hashed API key should not error, got: %v", err)
+	}
+
+	cfg.Server.Auth.APIKeys = map[string]APIKeyConfig{
+		"sha256:not-a-hash": {Name: "ops", Enabled: true},
+	}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Errorf("Expected an invalid hash error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_Limits(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Limits.BodyMB.JSON = 4
+	cfg.Server.Limits.MaxPromptTokens = 8000
+	cfg.Server.Auth.APIKeys = map[string]APIKeyConfig{"sk-batch": {Name: "batch", MaxPromptTokens: 32000}}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid limits should not error, got: %v", err)
+	}
+
+	cfg.Server.Limits.BodyMB.Audio = -1
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("Expected a negative body limit to error")
+	}
+	cfg.Server.Limits.BodyMB.Audio = 0
+
+	cfg.Server.Auth.APIKeys["sk-batch"] = APIKeyConfig{Name: "batch", MaxPromptTokens: -1}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "max_prompt_tokens") {
+		t.Errorf("Expected a negative key prompt limit to error, got: %v", err)
+	}
+}
+
+func TestValidateConfig_CORS(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.CORS = CORSConfig{
+		Enabled:          true,
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.com"},
+		AllowCredentials: true,
+		MaxAge:           "10m",
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Valid CORS settings should not error, got: %v", err)
+	}
+
+	cfg.Server.CORS.AllowedOrigins = []string{"*"}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "credentials") {
+		t.Errorf("Expected credentials with any origin to error, got: %v", err)
+	}
+
+	cfg.Server.CORS.AllowCredentials = false
+	cfg.Server.CORS.MaxAge = "soon"
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("Expected an invalid max_age to error")
+	}
+
+	cfg.Server.CORS = CORSConfig{Enabled: true}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("Expected CORS without origins to error")
+	}
+}
+
+func TestValidateConfig_PipelineTools(t *testing.T) {
+	cfg := validConfig()
+	cfg.Pipelines.Tools = []PipelineToolConfig{
+		{Name: "calc", Type: "command", Command: []string{"/usr/bin/bc", "-l"}, Timeout: "5s"},
+		{Name: "search", Type: "http", URL: "https://search.internal/query", MaxOutputBytes: 65536},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid tools, got: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(tool *PipelineToolConfig)
+		want   string
+	}{
+		{"duplicate name", func(tool *PipelineToolConfig) { tool.Name = "calc" }, "duplicate pipeline tool"},
+		{"bad type", func(tool *PipelineToolConfig) { tool.Type = "shell" }, "must be command or http"},
+		{"relative command", func(tool *PipelineToolConfig) { tool.Type, tool.Command = "command", []string{"bc"} }, "absolute path"},
+		{"bad env", func(tool *PipelineToolConfig) {
+			tool.Type, tool.Command, tool.Env = "command", []string{"/bin/cat"}, []string{"HOME"}
+		}, "KEY=value"},
+		{"bad url", func(tool *PipelineToolConfig) { tool.URL = "file:///etc/passwd" }, "invalid pipeline tool search url"},
+		{"bad timeout", func(tool *PipelineToolConfig) { tool.Timeout = "-1s" }, "timeout"},
+		{"negative output", func(tool *PipelineToolConfig) { tool.MaxOutputBytes = -1 }, "cannot be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Pipelines.Tools = []PipelineToolConfig{
+				{Name: "calc", Type: "command", Command: []string{"/usr/bin/bc"}},
+				{Name: "search", Type: "http", URL: "https://search.internal/query"},
+			}
+			tt.modify(&cfg.Pipelines.Tools[1])
+			if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package container
+
+import (
+	"context"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// ManagedBackend wraps a backend whose engine runs in a container.
+// On-demand containers are started on the first request and reported
+// healthy while stopped so the router can still select them.
+type ManagedBackend struct {
+	backends.Backend
+	lifecycle *Lifecycle
+}
+
+// NewManagedBackend wraps a backend with container lifecycle management
+func NewManagedBackend(b backends.Backend, lc *Lifecycle) *ManagedBackend {
+	return &ManagedBackend{
+		Backend:   b,
+		lifecycle: lc,
+	}
+}
+
+// Lifecycle returns the container lifecycle manager
+func (mb *ManagedBackend) Lifecycle() *Lifecycle {
+	return mb.lifecycle
+}
+
+// sleeping reports whether the container is stopped but can be woken
+func (mb *ManagedBackend) sleeping() bool {
+	return mb.lifecycle.Config().OnDemand && !mb.lifecycle.Running()
+}
+
+// Start starts the container (unless on-demand) and then the backend
+func (mb *ManagedBackend) Start(ctx context.Context) error {
+	if mb.lifecycle.Config().OnDemand {
+		return nil
+	}
+	if err := mb.lifecycle.EnsureRunning(ctx); err != nil {
+		return err
+	}
+	return mb.Backend.Start(ctx)
+}
+
+// Stop stops the backend and its container
+func (mb *ManagedBackend) Stop(ctx context.Context) error {
+	if err := mb.Backend.Stop(ctx); err != nil {
+		return err
+	}
+	return mb.lifecycle.Stop(ctx)
+}
+
+// IsHealthy reports sleeping on-demand containers as available
+func (mb *ManagedBackend) IsHealthy() bool {
+	if mb.sleeping() {
+		return true
+	}
+	return mb.Backend.IsHealthy()
+}
+
+// HealthCheck skips probing while an on-demand container is stopped
+func (mb *ManagedBackend) HealthCheck(ctx context.Context) error {
+	if mb.sleeping() {
+		return nil
+	}
+	return mb.Backend.HealthCheck(ctx)
+}
+
+// ColdStart reports a stopped on-demand container as a cold start, and
+// otherwise asks the backend whether the model is loaded
+func (mb *ManagedBackend) ColdStart(ctx context.Context, model string) *backends.ColdStart {
+	if mb.sleeping() {
+		return &backends.ColdStart{
+			Reason:   backends.ColdStartContainerStart,
+			Model:    model,
+			Estimate: mb.lifecycle.LastStartDuration(),
+		}
+	}
+	return backends.CheckColdStart(ctx, mb.Backend, model)
+}
+
+// ManagesModels asks the backend whether it pulls and deletes models
+func (mb *ManagedBackend) ManagesModels() bool {
+	return backends.ManagesModels(mb.Backend)
+}
+
+// InstalledModels wakes the container before listing its models
+func (mb *ManagedBackend) InstalledModels(ctx context.Context) ([]backends.InstalledModel, error) {
+	if err := mb.wake(ctx); err != nil {
+		return nil, err
+	}
+	defer mb.lifecycle.Release()
+	return backends.InstalledModels(ctx, mb.Backend)
+}
+
+// PullModel wakes the container and keeps it busy until the pull ends
+func (mb *ManagedBackend) PullModel(ctx context.Context, model string) error {
+	if err := mb.wake(ctx); err != nil {
+		return err
+	}
+	defer mb.lifecycle.Release()
+	return backends.PullModel(ctx, mb.Backend, model)
+}
+
+// DeleteModel wakes the container before deleting a model
+func (mb *ManagedBackend) DeleteModel(ctx context.Context, model string) error {
+	if err := mb.wake(ctx); err != nil {
+		return err
+	}
+	defer mb.lifecycle.Release()
+	return backends.DeleteModel(ctx, mb.Backend, model)
+}
+
+// wake starts the container if needed and refreshes backend health
+func (mb *ManagedBackend) wake(ctx context.Context) error {
+	wasRunning := mb.lifecycle.Running()
+	if err := mb.lifecycle.Acquire(ctx); err != nil {
+		return err
+	}
+	if !wasRunning {
+		mb.Backend.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// Generate wakes the container before generating
+func (mb *ManagedBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	if err := mb.wake(ctx); err != nil {
+		return nil, err
+	}
+	defer mb.lifecycle.Release()
+	return mb.Backend.Generate(ctx, req)
+}
+
+// SupportsSequences asks the backend whether it generates several
+// completions in one request
+func (mb *ManagedBackend) SupportsSequences() bool {
+	return backends.SupportsSequences(mb.Backend)
+}
+
+// GenerateSequences wakes the container before generating
+func (mb *ManagedBackend) GenerateSequences(ctx context.Context, req *backends.GenerateRequest, n, bestOf int) ([]*backends.GenerateResponse, error) {
+	if err := mb.wake(ctx); err != nil {
+		return nil, err
+	}
+	defer mb.lifecycle.Release()
+	return backends.NativeSequences(ctx, mb.Backend, req, n, bestOf)
+}
+
+// SupportsImageEdit asks the backend whether it edits images
+func (mb *ManagedBackend) SupportsImageEdit() bool {
+	return backends.SupportsImageEdit(mb.Backend)
+}
+
+// SupportsToolCalling asks the backend whether it offers models tools
+func (mb *ManagedBackend) SupportsToolCalling() bool {
+	return backends.SupportsToolCalling(mb.Backend)
+}
+
+// SupportsDraftVerification asks the backend whether it scores draft
+// continuations
+func (mb *ManagedBackend) SupportsDraftVerification() bool {
+	return backends.SupportsDraftVerification(mb.Backend)
+}
+
+// VerifyDraft wakes the container before verifying
+func (mb *ManagedBackend) VerifyDraft(ctx context.Context, req *backends.GenerateRequest, draft string) (*backends.DraftVerdict, error) {
+	if err := mb.wake(ctx); err != nil {
+		return nil, err
+	}
+	defer mb.lifecycle.Release()
+	return backends.VerifyDraft(ctx, mb.Backend, req, draft)
+}
+
+// GenerateStream wakes the container and keeps it busy until the stream closes
+func (mb *ManagedBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	if err := mb.wake(ctx); err != nil {
+		return nil, err
+	}
+
+	reader, err := mb.Backend.GenerateStream(ctx, req)
+	if err != nil {
+		mb.lifecycle.Release()
+		return nil, err
+	}
+	return &releasingStreamReader{StreamReader: reader, release: mb.lifecycle.Release}, nil
+}
+
+// Embed wakes the container before embedding
+func (mb *ManagedBackend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	if err := mb.wake(ctx); err != nil {
+		return nil, err
+	}
+	defer mb.lifecycle.Release()
+	return mb.Backend.Embed(ctx, req)
+}
+
+// releasingStreamReader releases the lifecycle when the stream closes
+type releasingStreamReader struct {
+	backends.StreamReader
+	release func()
+	closed  bool
+}
+
+// Close closes the stream and releases the container
+func (r *releasingStreamReader) Close() error {
+	err := r.StreamReader.Close()
+	if !r.closed {
+		r.closed = true
+		r.release()
+	}
+	return err
+}
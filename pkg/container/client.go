@@ -0,0 +1,171 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound is returned when the container does not exist
+var ErrNotFound = errors.New("container not found")
+
+// apiVersion is the Docker Engine API version used for requests.
+// Podman's Docker-compatible API accepts the same paths.
+const apiVersion = "v1.41"
+
+// DefaultSocket returns the default API socket for a runtime ("docker" or "podman")
+func DefaultSocket(runtime string) string {
+	if runtime == "podman" {
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			return filepath.Join(dir, "podman", "podman.sock")
+		}
+		return "/run/podman/podman.sock"
+	}
+	return "/var/run/docker.sock"
+}
+
+// Client talks to a Docker-compatible engine API over a unix socket
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+// NewClient creates a new engine API client
+func NewClient(socket string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+
+	return &Client{
+		socket: socket,
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   5 * time.Minute, // image pulls can be slow
+		},
+	}
+}
+
+// State is the subset of container inspect output the proxy needs
+type State struct {
+	ID      string
+	Running bool
+	Status  string // "created", "running", "exited", ...
+}
+
+// Inspect returns the container state, or ErrNotFound
+func (c *Client) Inspect(ctx context.Context, name string) (*State, error) {
+	var out struct {
+		ID    string `json:"Id"`
+		State struct {
+			Status  string `json:"Status"`
+			Running bool   `json:"Running"`
+		} `json:"State"`
+	}
+
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return &State{
+		ID:      out.ID,
+		Running: out.State.Running,
+		Status:  out.State.Status,
+	}, nil
+}
+
+// Pull fetches an image
+func (c *Client) Pull(ctx context.Context, image string) error {
+	query := url.Values{"fromImage": {image}}
+	return c.do(ctx, http.MethodPost, "/images/create", query, nil, nil)
+}
+
+// Create creates a container from the spec and returns its ID
+func (c *Client) Create(ctx context.Context, spec Spec) (string, error) {
+	var out struct {
+		ID string `json:"Id"`
+	}
+
+	query := url.Values{"name": {spec.Name}}
+	if err := c.do(ctx, http.MethodPost, "/containers/create", query, spec.createBody(), &out); err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+// Start starts a created or stopped container
+func (c *Client) Start(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/start", nil, nil, nil)
+}
+
+// Stop stops a running container, killing it after timeout
+func (c *Client) Stop(ctx context.Context, name string, timeout time.Duration) error {
+	query := url.Values{"t": {strconv.Itoa(int(timeout.Seconds()))}}
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/stop", query, nil, nil)
+}
+
+// do performs an API request. 304 (already started/stopped) counts as success.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	u := "http://engine/" + apiVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("container API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode == http.StatusNotModified:
+		return nil
+	case resp.StatusCode >= 300:
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("container API %s %s: status %d: %s", method, path, resp.StatusCode, apiErr.Message)
+	}
+
+	if out == nil {
+		// Drain streaming responses (e.g. pull progress) so the call completes
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
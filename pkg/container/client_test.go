@@ -0,0 +1,209 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	if err := logging.InitLogger("info", false); err != nil {
+		panic(err)
+	}
+	defer logging.Sync()
+
+	os.Exit(m.Run())
+}
+
+// fakeEngine is a minimal Docker-compatible API served on a unix socket
+type fakeEngine struct {
+	mu      sync.Mutex
+	exists  bool
+	running bool
+	created map[string]interface{}
+	calls   []string
+}
+
+func (fe *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/"+apiVersion)
+	fe.calls = append(fe.calls, r.Method+" "+path)
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/json"):
+		if !fe.exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "no such container"})
+			return
+		}
+		status := "exited"
+		if fe.running {
+			status = "running"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Id":    "abc123",
+			"State": map[string]interface{}{"Status": status, "Running": fe.running},
+		})
+	case path == "/containers/create":
+		json.NewDecoder(r.Body).Decode(&fe.created)
+		fe.exists = true
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"Id": "abc123"})
+	case strings.HasSuffix(path, "/start"):
+		if fe.running {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fe.running = true
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasSuffix(path, "/stop"):
+		fe.running = false
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "unexpected call"})
+	}
+}
+
+func startFakeEngine(t *testing.T) (*fakeEngine, *Client) {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "engine.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	engine := &fakeEngine{}
+	srv := httptest.NewUnstartedServer(engine)
+	srv.Listener = listener
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return engine, NewClient(socket)
+}
+
+func TestDefaultSocket(t *testing.T) {
+	if got := DefaultSocket("docker"); got != "/var/run/docker.sock" {
+		t.Errorf("Unexpected docker socket: %s", got)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if got := DefaultSocket("podman"); got != "/run/user/1000/podman/podman.sock" {
+		t.Errorf("Unexpected podman socket: %s", got)
+	}
+}
+
+func TestClient_Lifecycle(t *testing.T) {
+	engine, client := startFakeEngine(t)
+	ctx := context.Background()
+
+	if _, err := client.Inspect(ctx, "vllm"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	id, err := client.Create(ctx, Spec{
+		Name:  "vllm",
+		Image: "vllm/vllm-openai:latest",
+		Ports: map[string]int{"8000": 8000},
+		GPUs:  "all",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if id != "abc123" {
+		t.Errorf("Expected container ID abc123, got %s", id)
+	}
+
+	if err := client.Start(ctx, "vllm"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	// Starting again returns 304 which is not an error
+	if err := client.Start(ctx, "vllm"); err != nil {
+		t.Errorf("Expected second start to succeed, got %v", err)
+	}
+
+	state, err := client.Inspect(ctx, "vllm")
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if !state.Running || state.Status != "running" {
+		t.Errorf("Expected running container, got %+v", state)
+	}
+
+	if err := client.Stop(ctx, "vllm", 10*time.Second); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	if engine.running {
+		t.Error("Expected container to be stopped")
+	}
+	if engine.created["Image"] != "vllm/vllm-openai:latest" {
+		t.Errorf("Unexpected create body: %v", engine.created)
+	}
+}
+
+func TestSpec_CreateBody(t *testing.T) {
+	body := Spec{
+		Image:     "vllm/vllm-openai:latest",
+		Command:   []string{"--model", "mistral"},
+		Env:       []string{"HF_TOKEN=x"},
+		Ports:     map[string]int{"8000": 18000},
+		Devices:   []string{"/dev/dri", "/dev/accel/accel0:/dev/accel0"},
+		GPUs:      "0,1",
+		ShmSizeMB: 1024,
+	}.createBody()
+
+	if _, ok := body["ExposedPorts"].(map[string]struct{})["8000/tcp"]; !ok {
+		t.Errorf("Expected 8000/tcp to be exposed, got %v", body["ExposedPorts"])
+	}
+
+	hostConfig := body["HostConfig"].(map[string]interface{})
+	bindings := hostConfig["PortBindings"].(map[string][]map[string]string)
+	if bindings["8000/tcp"][0]["HostPort"] != "18000" {
+		t.Errorf("Unexpected port binding: %v", bindings)
+	}
+
+	devices := hostConfig["Devices"].([]map[string]string)
+	if len(devices) != 2 || devices[1]["PathInContainer"] != "/dev/accel0" {
+		t.Errorf("Unexpected devices: %v", devices)
+	}
+
+	requests := hostConfig["DeviceRequests"].([]map[string]interface{})
+	ids, _ := requests[0]["DeviceIDs"].([]string)
+	if len(ids) != 2 || ids[0] != "0" {
+		t.Errorf("Expected GPU device IDs [0 1], got %v", requests[0])
+	}
+
+	if hostConfig["ShmSize"] != int64(1024*1024*1024) {
+		t.Errorf("Unexpected shm size: %v", hostConfig["ShmSize"])
+	}
+}
+
+func TestGPURequest(t *testing.T) {
+	if gpuRequest("") != nil || gpuRequest("none") != nil {
+		t.Error("Expected no device request when GPUs unset")
+	}
+	if req := gpuRequest("all"); req["Count"] != -1 {
+		t.Errorf("Expected Count -1 for all GPUs, got %v", req)
+	}
+	if req := gpuRequest("2"); req["Count"] != 2 {
+		t.Errorf("Expected Count 2, got %v", req)
+	}
+}
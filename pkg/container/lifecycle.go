@@ -0,0 +1,345 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// Runtime is the engine API used by Lifecycle
+type Runtime interface {
+	Inspect(ctx context.Context, name string) (*State, error)
+	Pull(ctx context.Context, image string) error
+	Create(ctx context.Context, spec Spec) (string, error)
+	Start(ctx context.Context, name string) error
+	Stop(ctx context.Context, name string, timeout time.Duration) error
+}
+
+// LifecycleConfig controls how a backend container is managed
+type LifecycleConfig struct {
+	Spec Spec
+
+	// HealthURL is polled after start until it returns 200
+	HealthURL string
+
+	// OnDemand starts the container on first request instead of at boot
+	OnDemand bool
+
+	// IdleTimeout stops an on-demand container after this long without
+	// requests (0 = never)
+	IdleTimeout time.Duration
+
+	// StartTimeout bounds how long a start (including health wait) may take
+	StartTimeout time.Duration
+
+	// StopTimeout is the grace period before the runtime kills the container
+	StopTimeout time.Duration
+}
+
+// Lifecycle starts, stops and health-checks a single backend container
+type Lifecycle struct {
+	runtime Runtime
+	cfg     LifecycleConfig
+	probe   *http.Client
+
+	mu        sync.Mutex
+	running   bool
+	inflight  int
+	lastUsed  time.Time
+	starting  chan struct{} // closed when an in-progress start finishes
+	stopping  chan struct{} // closed when in-progress stops finish
+	stoppers  int           // stops sharing stopping
+	startErr  error
+	lastStart time.Duration // how long the last successful start took
+}
+
+// NewLifecycle creates a container lifecycle manager
+func NewLifecycle(runtime Runtime, cfg LifecycleConfig) *Lifecycle {
+	if cfg.StartTimeout <= 0 {
+		cfg.StartTimeout = 5 * time.Minute
+	}
+	if cfg.StopTimeout <= 0 {
+		cfg.StopTimeout = 30 * time.Second
+	}
+
+	return &Lifecycle{
+		runtime: runtime,
+		cfg:     cfg,
+		probe:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Config returns the lifecycle configuration
+func (l *Lifecycle) Config() LifecycleConfig {
+	return l.cfg
+}
+
+// Running reports whether the container is believed to be running
+func (l *Lifecycle) Running() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.running
+}
+
+// LastStartDuration returns how long the last successful start took, 0
+// before the first
+func (l *Lifecycle) LastStartDuration() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastStart
+}
+
+// EnsureRunning creates and starts the container if needed and waits for it
+// to become healthy. Concurrent callers share a single start.
+func (l *Lifecycle) EnsureRunning(ctx context.Context) error {
+	l.mu.Lock()
+	for l.stopping != nil {
+		// Let a stop finish rather than racing it with a start
+		wait := l.stopping
+		l.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		l.mu.Lock()
+	}
+	if l.running {
+		l.mu.Unlock()
+		return nil
+	}
+	if l.starting != nil {
+		wait := l.starting
+		l.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		l.mu.Lock()
+		err := l.startErr
+		l.mu.Unlock()
+		return err
+	}
+	l.starting = make(chan struct{})
+	l.mu.Unlock()
+
+	startCtx, cancel := context.WithTimeout(ctx, l.cfg.StartTimeout)
+	startedAt := time.Now()
+	err := l.start(startCtx)
+	cancel()
+
+	l.mu.Lock()
+	l.running = err == nil
+	l.startErr = err
+	if err == nil {
+		l.lastStart = time.Since(startedAt)
+	}
+	l.lastUsed = time.Now()
+	close(l.starting)
+	l.starting = nil
+	l.mu.Unlock()
+
+	return err
+}
+
+func (l *Lifecycle) start(ctx context.Context) error {
+	name := l.cfg.Spec.Name
+	startedAt := time.Now()
+
+	state, err := l.runtime.Inspect(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		logging.Logger.Info("Creating backend container",
+			zap.String("container", name),
+			zap.String("image", l.cfg.Spec.Image),
+		)
+		if _, err = l.runtime.Create(ctx, l.cfg.Spec); errors.Is(err, ErrNotFound) {
+			// Image missing locally
+			if err := l.runtime.Pull(ctx, l.cfg.Spec.Image); err != nil {
+				return fmt.Errorf("failed to pull image %s: %w", l.cfg.Spec.Image, err)
+			}
+			_, err = l.runtime.Create(ctx, l.cfg.Spec)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create container %s: %w", name, err)
+		}
+		state = &State{}
+	} else if err != nil {
+		return fmt.Errorf("failed to inspect container %s: %w", name, err)
+	}
+
+	if !state.Running {
+		if err := l.runtime.Start(ctx, name); err != nil {
+			return fmt.Errorf("failed to start container %s: %w", name, err)
+		}
+	}
+
+	if err := l.waitHealthy(ctx); err != nil {
+		return fmt.Errorf("container %s did not become healthy: %w", name, err)
+	}
+
+	logging.Logger.Info("Backend container ready",
+		zap.String("container", name),
+		zap.Duration("startup", time.Since(startedAt)),
+	)
+	return nil
+}
+
+// waitHealthy polls the health URL until it returns 200
+func (l *Lifecycle) waitHealthy(ctx context.Context) error {
+	if l.cfg.HealthURL == "" {
+		return nil
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.cfg.HealthURL, nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := l.probe.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Acquire ensures the container is running and marks a request in flight.
+// Call Release when the request finishes. The running check and the
+// in-flight mark happen under one lock, so an idle stop can never slip in
+// between them.
+func (l *Lifecycle) Acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.running {
+			l.inflight++
+			l.lastUsed = time.Now()
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		if err := l.EnsureRunning(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// Release marks a request as finished
+func (l *Lifecycle) Release() {
+	l.mu.Lock()
+	if l.inflight > 0 {
+		l.inflight--
+	}
+	l.lastUsed = time.Now()
+	l.mu.Unlock()
+}
+
+// Stop stops the container
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	l.beginStopLocked()
+	l.mu.Unlock()
+	return l.finishStop(ctx)
+}
+
+// beginStopLocked marks the container stopped, holding off starts until
+// finishStop
+func (l *Lifecycle) beginStopLocked() {
+	l.running = false
+	if l.stopping == nil {
+		l.stopping = make(chan struct{})
+	}
+	l.stoppers++
+}
+
+// finishStop stops the container and releases waiting starts
+func (l *Lifecycle) finishStop(ctx context.Context) error {
+	err := l.runtime.Stop(ctx, l.cfg.Spec.Name, l.cfg.StopTimeout)
+
+	l.mu.Lock()
+	l.stoppers--
+	if l.stoppers == 0 {
+		close(l.stopping)
+		l.stopping = nil
+	}
+	l.mu.Unlock()
+
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// StopIfIdle stops an on-demand container that has been idle past IdleTimeout.
+// It returns true if the container was stopped.
+func (l *Lifecycle) StopIfIdle(ctx context.Context) (bool, error) {
+	if !l.cfg.OnDemand || l.cfg.IdleTimeout <= 0 {
+		return false, nil
+	}
+
+	// Decide and mark stopped under one lock so no request is admitted
+	// to a container on its way down
+	l.mu.Lock()
+	idle := l.running && l.inflight == 0 && time.Since(l.lastUsed) >= l.cfg.IdleTimeout
+	if idle {
+		l.beginStopLocked()
+	}
+	l.mu.Unlock()
+	if !idle {
+		return false, nil
+	}
+
+	logging.Logger.Info("Stopping idle backend container",
+		zap.String("container", l.cfg.Spec.Name),
+		zap.Duration("idle_timeout", l.cfg.IdleTimeout),
+	)
+	return true, l.finishStop(ctx)
+}
+
+// RunIdleReaper stops the container when idle until ctx is cancelled
+func (l *Lifecycle) RunIdleReaper(ctx context.Context) {
+	if !l.cfg.OnDemand || l.cfg.IdleTimeout <= 0 {
+		return
+	}
+
+	interval := l.cfg.IdleTimeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			middleware.Safe(middleware.ScopeBackground, "container-idle-reaper", func() {
+				if _, err := l.StopIfIdle(ctx); err != nil {
+					logging.Logger.Warn("Failed to stop idle container",
+						zap.String("container", l.cfg.Spec.Name),
+						zap.Error(err),
+					)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package container
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// fakeRuntime records lifecycle calls without a real engine
+type fakeRuntime struct {
+	mu        sync.Mutex
+	exists    bool
+	running   bool
+	hasImage  bool
+	starts    int
+	stops     int
+	pulls     int
+	startWait time.Duration
+	stopWait  time.Duration
+}
+
+func (f *fakeRuntime) Inspect(ctx context.Context, name string) (*State, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.exists {
+		return nil, ErrNotFound
+	}
+	return &State{Running: f.running}, nil
+}
+
+func (f *fakeRuntime) Pull(ctx context.Context, image string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pulls++
+	f.hasImage = true
+	return nil
+}
+
+func (f *fakeRuntime) Create(ctx context.Context, spec Spec) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.hasImage {
+		return "", ErrNotFound
+	}
+	f.exists = true
+	return "id", nil
+}
+
+func (f *fakeRuntime) Start(ctx context.Context, name string) error {
+	time.Sleep(f.startWait)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.starts++
+	f.running = true
+	return nil
+}
+
+func (f *fakeRuntime) Stop(ctx context.Context, name string, timeout time.Duration) error {
+	time.Sleep(f.stopWait)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stops++
+	f.running = false
+	return nil
+}
+
+func TestLifecycle_EnsureRunningPullsAndStarts(t *testing.T) {
+	health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer health.Close()
+
+	rt := &fakeRuntime{}
+	lc := NewLifecycle(rt, LifecycleConfig{
+		Spec:      Spec{Name: "vllm", Image: "vllm/vllm-openai"},
+		HealthURL: health.URL,
+	})
+
+	if err := lc.EnsureRunning(context.Background()); err != nil {
+		t.Fatalf("EnsureRunning failed: %v", err)
+	}
+	if !lc.Running() {
+		t.Error("Expected lifecycle to report running")
+	}
+	if rt.pulls != 1 || rt.starts != 1 {
+		t.Errorf("Expected 1 pull and 1 start, got pulls=%d starts=%d", rt.pulls, rt.starts)
+	}
+
+	// Already running: no further calls
+	if err := lc.EnsureRunning(context.Background()); err != nil {
+		t.Fatalf("EnsureRunning failed: %v", err)
+	}
+	if rt.starts != 1 {
+		t.Errorf("Expected no additional start, got %d", rt.starts)
+	}
+}
+
+func TestLifecycle_ConcurrentStartsShared(t *testing.T) {
+	rt := &fakeRuntime{exists: true, startWait: 20 * time.Millisecond}
+	lc := NewLifecycle(rt, LifecycleConfig{Spec: Spec{Name: "vllm"}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := lc.EnsureRunning(context.Background()); err != nil {
+				t.Errorf("EnsureRunning failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if rt.starts != 1 {
+		t.Errorf("Expected a single shared start, got %d", rt.starts)
+	}
+}
+
+func TestLifecycle_HealthTimeout(t *testing.T) {
+	health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer health.Close()
+
+	rt := &fakeRuntime{exists: true}
+	lc := NewLifecycle(rt, LifecycleConfig{
+		Spec:         Spec{Name: "vllm"},
+		HealthURL:    health.URL,
+		StartTimeout: 100 * time.Millisecond,
+	})
+
+	if err := lc.EnsureRunning(context.Background()); err == nil {
+		t.Fatal("Expected health wait to time out")
+	}
+	if lc.Running() {
+		t.Error("Expected lifecycle not to report running after failed start")
+	}
+}
+
+func TestLifecycle_StopIfIdle(t *testing.T) {
+	rt := &fakeRuntime{exists: true}
+	lc := NewLifecycle(rt, LifecycleConfig{
+		Spec:        Spec{Name: "vllm"},
+		OnDemand:    true,
+		IdleTimeout: 10 * time.Millisecond,
+	})
+
+	if err := lc.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// In-flight request keeps it alive
+	if stopped, _ := lc.StopIfIdle(context.Background()); stopped {
+		t.Error("Expected busy container not to be stopped")
+	}
+
+	lc.Release()
+	time.Sleep(20 * time.Millisecond)
+
+	stopped, err := lc.StopIfIdle(context.Background())
+	if err != nil {
+		t.Fatalf("StopIfIdle failed: %v", err)
+	}
+	if !stopped || rt.stops != 1 {
+		t.Errorf("Expected idle container to be stopped, stopped=%v stops=%d", stopped, rt.stops)
+	}
+}
+
+func TestLifecycle_AcquireWaitsForIdleStop(t *testing.T) {
+	rt := &fakeRuntime{exists: true, stopWait: 50 * time.Millisecond}
+	lc := NewLifecycle(rt, LifecycleConfig{
+		Spec:        Spec{Name: "vllm"},
+		OnDemand:    true,
+		IdleTimeout: 10 * time.Millisecond,
+	})
+	if err := lc.EnsureRunning(context.Background()); err != nil {
+		t.Fatalf("EnsureRunning failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		lc.StopIfIdle(context.Background())
+		close(stopped)
+	}()
+	for lc.Running() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A request arriving mid-stop must wait and restart the container,
+	// not land on the one going down
+	if err := lc.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	<-stopped
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if !rt.running || rt.stops != 1 || rt.starts != 2 {
+		t.Errorf("Expected the container restarted after the stop, running=%v stops=%d starts=%d", rt.running, rt.stops, rt.starts)
+	}
+	if !lc.Running() {
+		t.Error("Expected the lifecycle to report running")
+	}
+}
+
+// stubBackend is a minimal backend for ManagedBackend tests
+type stubBackend struct {
+	backends.Backend
+	healthy   bool
+	generates int
+	checks    int
+}
+
+func (s *stubBackend) IsHealthy() bool { return s.healthy }
+
+func (s *stubBackend) HealthCheck(ctx context.Context) error {
+	s.checks++
+	s.healthy = true
+	return nil
+}
+
+func (s *stubBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	s.generates++
+	return &backends.GenerateResponse{Response: "ok"}, nil
+}
+
+func (s *stubBackend) Start(ctx context.Context) error { return nil }
+func (s *stubBackend) Stop(ctx context.Context) error  { return nil }
+
+func TestManagedBackend_OnDemand(t *testing.T) {
+	rt := &fakeRuntime{exists: true}
+	inner := &stubBackend{}
+	mb := NewManagedBackend(inner, NewLifecycle(rt, LifecycleConfig{
+		Spec:     Spec{Name: "vllm"},
+		OnDemand: true,
+	}))
+
+	if err := mb.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if rt.starts != 0 {
+		t.Error("Expected on-demand container not to start at boot")
+	}
+	if !mb.IsHealthy() {
+		t.Error("Expected sleeping on-demand backend to be reported healthy")
+	}
+	if err := mb.HealthCheck(context.Background()); err != nil || inner.checks != 0 {
+		t.Error("Expected health check to be skipped while sleeping")
+	}
+
+	resp, err := mb.Generate(context.Background(), &backends.GenerateRequest{})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Response != "ok" || inner.generates != 1 {
+		t.Errorf("Expected request to reach inner backend, got %+v", resp)
+	}
+	if rt.starts != 1 {
+		t.Errorf("Expected container to start on first request, got %d starts", rt.starts)
+	}
+	if inner.checks != 1 {
+		t.Errorf("Expected health refresh after wake, got %d checks", inner.checks)
+	}
+
+	if err := mb.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if rt.stops != 1 {
+		t.Errorf("Expected container to be stopped, got %d stops", rt.stops)
+	}
+}
+
+func TestManagedBackend_ColdStart(t *testing.T) {
+	rt := &fakeRuntime{exists: true, startWait: 20 * time.Millisecond}
+	mb := NewManagedBackend(&stubBackend{}, NewLifecycle(rt, LifecycleConfig{
+		Spec:     Spec{Name: "vllm"},
+		OnDemand: true,
+	}))
+	ctx := context.Background()
+	mb.Start(ctx)
+
+	cs := mb.ColdStart(ctx, "llama3")
+	if cs == nil || cs.Reason != backends.ColdStartContainerStart || cs.Estimate != 0 {
+		t.Fatalf("Expected a container start without estimate, got %+v", cs)
+	}
+
+	if _, err := mb.Generate(ctx, &backends.GenerateRequest{}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if cs := mb.ColdStart(ctx, "llama3"); cs != nil {
+		t.Errorf("Expected a running container to be warm, got %+v", cs)
+	}
+
+	// The next wake is estimated from the last start
+	mb.Stop(ctx)
+	if cs := mb.ColdStart(ctx, "llama3"); cs == nil || cs.Estimate < 20*time.Millisecond {
+		t.Errorf("Expected the last start time as estimate, got %+v", cs)
+	}
+}
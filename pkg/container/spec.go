@@ -0,0 +1,95 @@
+package container
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Spec declares the container backing a backend
+type Spec struct {
+	Name    string
+	Image   string
+	Command []string
+	Env     []string       // "KEY=value"
+	Ports   map[string]int // container port ("8000/tcp") -> host port
+	Volumes []string       // "host:container[:ro]"
+
+	// GPUs requests GPUs via the runtime's device requests:
+	// "all", a count ("2"), or device IDs ("0,1"). Empty requests none.
+	GPUs string
+
+	// Devices maps host devices into the container (e.g. "/dev/dri", "/dev/accel/accel0")
+	Devices []string
+
+	ShmSizeMB int
+}
+
+// createBody builds the engine API create payload
+func (s Spec) createBody() map[string]interface{} {
+	exposed := make(map[string]struct{}, len(s.Ports))
+	bindings := make(map[string][]map[string]string, len(s.Ports))
+	for containerPort, hostPort := range s.Ports {
+		port := containerPort
+		if !strings.Contains(port, "/") {
+			port += "/tcp"
+		}
+		exposed[port] = struct{}{}
+		bindings[port] = []map[string]string{{"HostPort": strconv.Itoa(hostPort)}}
+	}
+
+	devices := make([]map[string]string, 0, len(s.Devices))
+	for _, dev := range s.Devices {
+		hostPath, containerPath := dev, dev
+		if parts := strings.SplitN(dev, ":", 2); len(parts) == 2 {
+			hostPath, containerPath = parts[0], parts[1]
+		}
+		devices = append(devices, map[string]string{
+			"PathOnHost":        hostPath,
+			"PathInContainer":   containerPath,
+			"CgroupPermissions": "rwm",
+		})
+	}
+
+	hostConfig := map[string]interface{}{
+		"PortBindings": bindings,
+		"Binds":        s.Volumes,
+		"Devices":      devices,
+	}
+	if req := gpuRequest(s.GPUs); req != nil {
+		hostConfig["DeviceRequests"] = []map[string]interface{}{req}
+	}
+	if s.ShmSizeMB > 0 {
+		hostConfig["ShmSize"] = int64(s.ShmSizeMB) * 1024 * 1024
+	}
+
+	body := map[string]interface{}{
+		"Image":        s.Image,
+		"Env":          s.Env,
+		"ExposedPorts": exposed,
+		"HostConfig":   hostConfig,
+	}
+	if len(s.Command) > 0 {
+		body["Cmd"] = s.Command
+	}
+	return body
+}
+
+// gpuRequest converts the GPUs setting into an engine device request
+func gpuRequest(gpus string) map[string]interface{} {
+	gpus = strings.TrimSpace(gpus)
+	if gpus == "" || gpus == "none" {
+		return nil
+	}
+
+	req := map[string]interface{}{
+		"Capabilities": [][]string{{"gpu"}},
+	}
+	if gpus == "all" {
+		req["Count"] = -1
+	} else if n, err := strconv.Atoi(gpus); err == nil {
+		req["Count"] = n
+	} else {
+		req["DeviceIDs"] = strings.Split(gpus, ",")
+	}
+	return req
+}
@@ -0,0 +1,300 @@
+// Package dashboard serves a single-page status dashboard at /ui. It shows
+// backend health, thermal state, recent routing decisions, token
+// throughput and queue depth, from a JSON status endpoint and a WebSocket
+// that pushes the same status as it changes.
+package dashboard
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/degrade"
+	proxyws "github.com/daoneill/ollama-proxy/pkg/http/websocket"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/thermal"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+	"github.com/gorilla/websocket"
+)
+
+//go:embed static
+var static embed.FS
+
+// Defaults for Config
+const (
+	DefaultInterval         = 2 * time.Second
+	DefaultDecisions        = 50
+	DefaultThroughputWindow = time.Minute
+)
+
+// writeWait bounds a single status push to a WebSocket client
+const writeWait = 10 * time.Second
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	CheckOrigin:     proxyws.CheckOrigin,
+}
+
+// Router is the part of the router the dashboard reads
+type Router interface {
+	BackendStatuses() []router.BackendStatus
+	InFlight() int
+}
+
+// ThermalSource returns the thermal state of every monitored device
+type ThermalSource func() []thermal.HardwareStatus
+
+// DegradationSource returns the active degradation profile
+type DegradationSource func() degrade.Status
+
+// Config controls what the dashboard keeps and how often it pushes
+type Config struct {
+	Interval         time.Duration // between WebSocket pushes
+	Decisions        int           // recent routing decisions kept
+	ThroughputWindow time.Duration // throughput is averaged over it
+}
+
+// Decision is one routing decision as shown on the dashboard
+type Decision struct {
+	Time     time.Time `json:"time"`
+	Model    string    `json:"model,omitempty"`
+	Backend  string    `json:"backend,omitempty"`
+	Hardware string    `json:"hardware,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Throughput is completed work over the throughput window
+type Throughput struct {
+	WindowSeconds   float64            `json:"window_seconds"`
+	Requests        int                `json:"requests"`
+	Tokens          int64              `json:"tokens"` // completion tokens
+	TokensPerSecond float64            `json:"tokens_per_second"`
+	Backends        map[string]float64 `json:"backends"` // tokens per second by backend
+}
+
+// Status is everything the dashboard shows
+type Status struct {
+	Time       time.Time                `json:"time"`
+	QueueDepth int                      `json:"queue_depth"` // requests in flight across backends
+	Backends   []router.BackendStatus   `json:"backends"`
+	Thermal    []thermal.HardwareStatus `json:"thermal,omitempty"`
+	Degraded   *degrade.Status          `json:"degradation,omitempty"` // nil unless a profile is active
+	Throughput Throughput               `json:"throughput"`
+	Decisions  []Decision               `json:"decisions"` // newest first
+}
+
+// completion is a finished request counted towards throughput
+type completion struct {
+	time    time.Time
+	backend string
+	tokens  int64
+}
+
+// Dashboard collects decisions and usage for the status API
+type Dashboard struct {
+	router Router
+	cfg    Config
+	now    func() time.Time
+
+	mu          sync.Mutex
+	thermal     ThermalSource
+	degradation DegradationSource
+	decisions   []Decision // ring, next holds the oldest once full
+	next        int
+	completions []completion
+}
+
+// New creates a dashboard reading backends from r
+func New(r Router, cfg Config) *Dashboard {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Decisions <= 0 {
+		cfg.Decisions = DefaultDecisions
+	}
+	if cfg.ThroughputWindow <= 0 {
+		cfg.ThroughputWindow = DefaultThroughputWindow
+	}
+	return &Dashboard{
+		router: r,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// SetThermalSource adds thermal state to the status (nil = none)
+func (d *Dashboard) SetThermalSource(fn ThermalSource) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.thermal = fn
+}
+
+// SetDegradationSource adds the active degradation profile to the status
+// (nil = none)
+func (d *Dashboard) SetDegradationSource(fn DegradationSource) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.degradation = fn
+}
+
+// ObserveDecision records a routing decision. It is a router.DecisionObserver.
+func (d *Dashboard) ObserveDecision(rec *router.DecisionRecord) {
+	decision := Decision{
+		Time:  d.now(),
+		Model: rec.Annotations.Model,
+	}
+	if rec.Err != nil {
+		decision.Error = rec.Err.Error()
+	}
+	if dec := rec.Decision; dec != nil {
+		if dec.ModelUsed != "" {
+			decision.Model = dec.ModelUsed
+		}
+		decision.Backend = dec.Backend.ID()
+		decision.Hardware = dec.Backend.Hardware()
+		decision.Reason = dec.Reason
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.decisions) < d.cfg.Decisions {
+		d.decisions = append(d.decisions, decision)
+		return
+	}
+	d.decisions[d.next] = decision
+	d.next = (d.next + 1) % len(d.decisions)
+}
+
+// ObserveUsage counts a completed request towards throughput. It is a
+// usage.Recorder subscriber.
+func (d *Dashboard) ObserveUsage(rec usage.Record) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.completions = append(d.completions, completion{
+		time:    rec.Time,
+		backend: rec.Backend,
+		tokens:  rec.CompletionTokens,
+	})
+	d.pruneLocked(d.now())
+}
+
+// pruneLocked drops completions older than the throughput window. Caller
+// must hold d.mu.
+func (d *Dashboard) pruneLocked(now time.Time) {
+	cutoff := now.Add(-d.cfg.ThroughputWindow)
+	i := 0
+	for i < len(d.completions) && d.completions[i].time.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		d.completions = append(d.completions[:0], d.completions[i:]...)
+	}
+}
+
+// Status returns the current dashboard state
+func (d *Dashboard) Status() Status {
+	now := d.now()
+	status := Status{
+		Time:       now,
+		QueueDepth: d.router.InFlight(),
+		Backends:   d.router.BackendStatuses(),
+	}
+
+	d.mu.Lock()
+	thermalSource := d.thermal
+	degradationSource := d.degradation
+
+	d.pruneLocked(now)
+	window := d.cfg.ThroughputWindow.Seconds()
+	status.Throughput = Throughput{
+		WindowSeconds: window,
+		Requests:      len(d.completions),
+		Backends:      make(map[string]float64),
+	}
+	for _, c := range d.completions {
+		status.Throughput.Tokens += c.tokens
+		if c.backend != "" {
+			status.Throughput.Backends[c.backend] += float64(c.tokens) / window
+		}
+	}
+	status.Throughput.TokensPerSecond = float64(status.Throughput.Tokens) / window
+
+	status.Decisions = make([]Decision, 0, len(d.decisions))
+	for i := len(d.decisions) - 1; i >= 0; i-- {
+		status.Decisions = append(status.Decisions, d.decisions[(d.next+i)%len(d.decisions)])
+	}
+	d.mu.Unlock()
+
+	if thermalSource != nil {
+		status.Thermal = thermalSource()
+	}
+	if degradationSource != nil {
+		if degraded := degradationSource(); degraded.Active {
+			status.Degraded = &degraded
+		}
+	}
+	return status
+}
+
+// Handler serves the dashboard's static assets; mount it at "/ui/"
+func (d *Dashboard) Handler() http.Handler {
+	assets, _ := fs.Sub(static, "static") // the directory is embedded
+	return http.StripPrefix("/ui/", http.FileServer(http.FS(assets)))
+}
+
+// StatusHandler serves the current status as JSON (GET only)
+func (d *Dashboard) StatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(d.Status())
+	}
+}
+
+// WebSocketHandler pushes the status to the client every Interval until
+// it disconnects. Clients send nothing.
+func (d *Dashboard) WebSocketHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // the upgrader has replied
+		}
+		defer conn.Close()
+
+		// The request's read deadline would end the connection; reads only
+		// watch for the client going away
+		conn.SetReadDeadline(time.Time{})
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(d.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteJSON(d.Status()); err != nil {
+				return
+			}
+			select {
+			case <-gone:
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
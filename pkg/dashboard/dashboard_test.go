@@ -0,0 +1,133 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/degrade"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/thermal"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+	"github.com/gorilla/websocket"
+)
+
+type fakeBackend struct {
+	backends.Backend
+	id string
+}
+
+func (f *fakeBackend) ID() string       { return f.id }
+func (f *fakeBackend) Hardware() string { return "nvidia" }
+
+type fakeRouter struct {
+	inFlight int
+}
+
+func (r *fakeRouter) InFlight() int { return r.inFlight }
+func (r *fakeRouter) BackendStatuses() []router.BackendStatus {
+	return []router.BackendStatus{{ID: "ollama-nvidia", Hardware: "nvidia", Healthy: true, Enabled: true, QueueDepth: r.inFlight}}
+}
+
+func decision(model, backend string) *router.DecisionRecord {
+	return &router.DecisionRecord{
+		Annotations: backends.Annotations{Model: model},
+		Decision:    &router.RoutingDecision{Backend: &fakeBackend{id: backend}, Reason: "lowest latency"},
+	}
+}
+
+func TestDashboard_Status(t *testing.T) {
+	d := New(&fakeRouter{inFlight: 2}, Config{Decisions: 2})
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	d.SetThermalSource(func() []thermal.HardwareStatus {
+		return []thermal.HardwareStatus{{Hardware: "nvidia", Temperature: 71, Usable: true}}
+	})
+
+	d.ObserveDecision(decision("llama3:8b", "ollama-nvidia"))
+	d.ObserveDecision(&router.DecisionRecord{Annotations: backends.Annotations{Model: "llama3:70b"}, Err: errors.New("no backend fits")})
+	d.ObserveDecision(decision("qwen2:7b", "ollama-npu"))
+
+	d.ObserveUsage(usage.Record{Time: now.Add(-2 * time.Minute), Backend: "ollama-nvidia", CompletionTokens: 9000})
+	d.ObserveUsage(usage.Record{Time: now.Add(-10 * time.Second), Backend: "ollama-nvidia", CompletionTokens: 600})
+	d.ObserveUsage(usage.Record{Time: now.Add(-5 * time.Second), Backend: "ollama-npu", CompletionTokens: 120})
+
+	status := d.Status()
+	if status.QueueDepth != 2 || len(status.Backends) != 1 || len(status.Thermal) != 1 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if status.Degraded != nil {
+		t.Errorf("Expected no degradation without a source, got %+v", status.Degraded)
+	}
+	if len(status.Decisions) != 2 || status.Decisions[0].Model != "qwen2:7b" || status.Decisions[1].Error == "" {
+		t.Errorf("Expected the two newest decisions, newest first, got %+v", status.Decisions)
+	}
+
+	tp := status.Throughput
+	if tp.Requests != 2 || tp.Tokens != 720 || tp.TokensPerSecond != 12 {
+		t.Errorf("Expected throughput over the last minute only, got %+v", tp)
+	}
+	if tp.Backends["ollama-nvidia"] != 10 || tp.Backends["ollama-npu"] != 2 {
+		t.Errorf("Unexpected per-backend throughput %+v", tp.Backends)
+	}
+
+	d.SetDegradationSource(func() degrade.Status { return degrade.Status{Active: true, Profile: "thermal-critical"} })
+	if status := d.Status(); status.Degraded == nil || status.Degraded.Profile != "thermal-critical" {
+		t.Errorf("Expected the active degradation profile, got %+v", status.Degraded)
+	}
+}
+
+func TestDashboard_Handlers(t *testing.T) {
+	d := New(&fakeRouter{}, Config{Interval: 10 * time.Millisecond})
+	d.ObserveDecision(decision("llama3:8b", "ollama-nvidia"))
+
+	mux := http.NewServeMux()
+	mux.Handle("/ui/", d.Handler())
+	mux.HandleFunc("/ui/api/status", d.StatusHandler())
+	mux.HandleFunc("/ui/api/ws", d.WebSocketHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ui/")
+	if err != nil {
+		t.Fatalf("GET /ui/ failed: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), "app.js") {
+		t.Errorf("Expected the embedded page, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/ui/api/status")
+	if err != nil {
+		t.Fatalf("GET /ui/api/status failed: %v", err)
+	}
+	var status Status
+	json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	if len(status.Decisions) != 1 || status.Decisions[0].Backend != "ollama-nvidia" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ui/api/ws", nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial failed: %v", err)
+	}
+	defer conn.Close()
+	for i := 0; i < 2; i++ {
+		var pushed Status
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if err := conn.ReadJSON(&pushed); err != nil {
+			t.Fatalf("Expected status push %d, got %v", i+1, err)
+		}
+		if len(pushed.Backends) != 1 {
+			t.Errorf("Unexpected pushed status %+v", pushed)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Renders the proxy status pushed over api/ws, polling api/status when
+// WebSockets are unavailable.
+(function () {
+  "use strict";
+
+  var POLL_MS = 2000;
+  var RECONNECT_MS = 5000;
+
+  function $(id) { return document.getElementById(id); }
+
+  function cell(row, text, className) {
+    var td = document.createElement("td");
+    td.textContent = text;
+    if (className) td.className = className;
+    row.appendChild(td);
+    return td;
+  }
+
+  function fill(tbody, items, columns, render) {
+    tbody.replaceChildren();
+    if (!items || items.length === 0) {
+      var row = tbody.insertRow();
+      var td = cell(row, "None", "empty");
+      td.colSpan = columns;
+      return;
+    }
+    items.forEach(function (item) { render(tbody.insertRow(), item); });
+  }
+
+  function fixed(n, digits) { return Number(n || 0).toFixed(digits); }
+
+  function setConnection(text, className) {
+    var badge = $("connection");
+    badge.textContent = text;
+    badge.className = "badge " + className;
+  }
+
+  function render(status) {
+    var throughput = status.throughput || {};
+    var backendTokens = throughput.backends || {};
+    var healthy = (status.backends || []).filter(function (b) { return b.healthy; }).length;
+
+    $("queue-depth").textContent = status.queue_depth;
+    $("tokens-per-second").textContent = fixed(throughput.tokens_per_second, 1);
+    $("requests").textContent = throughput.requests + " / " + Math.round(throughput.window_seconds) + "s";
+    $("healthy").textContent = healthy + " / " + (status.backends || []).length;
+
+    var degraded = status.degradation;
+    $("degradation").hidden = !degraded;
+    if (degraded) {
+      var limits = [];
+      if (degraded.disable_endpoints) limits.push("disabled: " + degraded.disable_endpoints.join(", "));
+      if (degraded.max_tokens) limits.push("max_tokens " + degraded.max_tokens);
+      if (degraded.allowed_backends) limits.push("backends: " + degraded.allowed_backends.join(", "));
+      if (degraded.max_power_watts) limits.push("power " + degraded.max_power_watts + " W");
+      $("degradation").textContent = "Degraded: " + degraded.profile + " since " +
+        new Date(degraded.since).toLocaleTimeString() + (limits.length ? " (" + limits.join("; ") + ")" : "");
+    }
+
+    fill($("backends"), status.backends, 7, function (row, b) {
+      cell(row, b.name || b.id);
+      cell(row, b.hardware);
+      if (!b.enabled) cell(row, "disabled", "warn");
+      else if (!b.healthy) cell(row, "unhealthy", "bad");
+      else cell(row, "healthy", "ok");
+      cell(row, b.queue_depth);
+      cell(row, b.avg_latency_ms + " ms");
+      cell(row, fixed(backendTokens[b.id], 1));
+      cell(row, fixed(b.power_watts, 0) + " W");
+    });
+
+    $("thermal-section").hidden = !status.thermal;
+    fill($("thermal"), status.thermal, 6, function (row, t) {
+      cell(row, t.hardware);
+      cell(row, fixed(t.temperature, 1) + " °C");
+      cell(row, t.fan_percent + "%");
+      cell(row, fixed(t.power_draw, 1) + " W");
+      cell(row, t.utilization + "%");
+      if (!t.usable) cell(row, t.reason || "unusable", "bad");
+      else if (t.throttling) cell(row, "throttling", "warn");
+      else cell(row, "ok", "ok");
+    });
+
+    fill($("decisions"), status.decisions, 4, function (row, d) {
+      cell(row, new Date(d.time).toLocaleTimeString());
+      cell(row, d.model || "-");
+      cell(row, d.backend || "-");
+      if (d.error) cell(row, d.error, "bad");
+      else cell(row, d.reason || "");
+    });
+  }
+
+  function poll() {
+    fetch("api/status", { cache: "no-store" })
+      .then(function (resp) {
+        if (!resp.ok) throw new Error(resp.statusText);
+        return resp.json();
+      })
+      .then(function (status) {
+        setConnection("polling", "warn");
+        render(status);
+      })
+      .catch(function () { setConnection("offline", "bad"); })
+      .finally(function () { setTimeout(poll, POLL_MS); });
+  }
+
+  function connect() {
+    if (!("WebSocket" in window)) {
+      poll();
+      return;
+    }
+    var scheme = location.protocol === "https:" ? "wss:" : "ws:";
+    var path = location.pathname.replace(/[^/]*$/, "") + "api/ws";
+    var ws = new WebSocket(scheme + "//" + location.host + path);
+    var opened = false;
+
+    ws.onopen = function () {
+      opened = true;
+      setConnection("live", "ok");
+    };
+    ws.onmessage = function (event) { render(JSON.parse(event.data)); };
+    ws.onclose = function () {
+      if (!opened) {
+        // Blocked by a proxy or the origin policy
+        poll();
+        return;
+      }
+      setConnection("reconnecting", "warn");
+      setTimeout(connect, RECONNECT_MS);
+    };
+  }
+
+  connect();
+})();
@@ -115,6 +115,20 @@ func (rs *RoutingService) Start() error {
 							{Name: "reason", Type: "s", Direction: "out"},
 						},
 					},
+					{
+						Name: "StopAll",
+						Args: []introspect.Arg{
+							{Name: "backend", Type: "s", Direction: "in"},
+							{Name: "pause", Type: "b", Direction: "in"},
+							{Name: "cancelled", Type: "i", Direction: "out"},
+						},
+					},
+					{
+						Name: "Resume",
+						Args: []introspect.Arg{
+							{Name: "backend", Type: "s", Direction: "in"},
+						},
+					},
 				},
 				Properties: []introspect.Property{
 					{
@@ -237,7 +251,8 @@ func (rs *RoutingService) GetBackendDistribution() (map[string]float64, *dbus.Er
 func (rs *RoutingService) SimulateRouting(annotationsMap map[string]dbus.Variant) (string, string, *dbus.Error) {
 	// Convert D-Bus variant map to annotations
 	annotations := &backends.Annotations{
-		Custom: make(map[string]string),
+		Priority: backends.PriorityNormal,
+		Custom:   make(map[string]string),
 	}
 
 	// Parse common annotation fields
@@ -286,6 +301,19 @@ func (rs *RoutingService) SimulateRouting(annotationsMap map[string]dbus.Variant
 	return backendID, decision.Reason, nil
 }
 
+// StopAll cancels in-flight generations on backend, or on every backend when
+// empty, optionally pausing new ones (D-Bus method)
+func (rs *RoutingService) StopAll(backend string, pause bool) (int32, *dbus.Error) {
+	return int32(rs.router.KillSwitch().Stop(backend, pause)), nil
+}
+
+// Resume lets generations be routed to backend again, or to every backend
+// when empty (D-Bus method)
+func (rs *RoutingService) Resume(backend string) *dbus.Error {
+	rs.router.KillSwitch().Resume(backend)
+	return nil
+}
+
 // RecordDecision records a routing decision (called by the application)
 func (rs *RoutingService) RecordDecision(backend string, reason string, estimatedPowerW float64, estimatedLatency int32) {
 	rs.mu.Lock()
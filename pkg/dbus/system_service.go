@@ -1,10 +1,14 @@
 package dbus
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/daoneill/ollama-proxy/pkg/alerting"
 	"github.com/daoneill/ollama-proxy/pkg/efficiency"
 	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/maintenance"
 	"github.com/godbus/dbus/v5"
 	"github.com/godbus/dbus/v5/introspect"
 	"github.com/godbus/dbus/v5/prop"
@@ -16,11 +20,17 @@ const (
 	systemPath      = "/com/anthropic/OllamaProxy/SystemState"
 )
 
+// ServiceNames returns the well-known bus names owned by the extended services
+func ServiceNames() []string {
+	return []string{backendsInterface, routingInterface, thermalInterface, systemInterface}
+}
+
 // SystemService exposes system state via D-Bus
 type SystemService struct {
-	conn    *dbus.Conn
-	manager *efficiency.EfficiencyManager
-	props   *prop.Properties
+	conn        *dbus.Conn
+	manager     *efficiency.EfficiencyManager
+	props       *prop.Properties
+	maintenance *maintenance.State
 }
 
 // NewSystemService creates a D-Bus service for system state
@@ -46,6 +56,14 @@ func NewSystemService(manager *efficiency.EfficiencyManager) (*SystemService, er
 	return svc, nil
 }
 
+// SetMaintenanceState enables the maintenance methods (optional)
+func (ss *SystemService) SetMaintenanceState(m *maintenance.State) {
+	ss.maintenance = m
+	if m != nil {
+		m.OnChange(ss.EmitMaintenanceChanged)
+	}
+}
+
 // Start registers the D-Bus service
 func (ss *SystemService) Start() error {
 	// Request name
@@ -90,6 +108,26 @@ func (ss *SystemService) Start() error {
 							{Name: "quiet_hours", Type: "b", Direction: "out"},
 						},
 					},
+					{
+						Name: "GetMaintenance",
+						Args: []introspect.Arg{
+							{Name: "status", Type: "a{sv}", Direction: "out"},
+						},
+					},
+					{
+						Name: "SetMaintenance",
+						Args: []introspect.Arg{
+							{Name: "enabled", Type: "b", Direction: "in"},
+							{Name: "message", Type: "s", Direction: "in"},
+							{Name: "retry_after_seconds", Type: "i", Direction: "in"},
+						},
+					},
+					{
+						Name: "SetBanner",
+						Args: []introspect.Arg{
+							{Name: "banner", Type: "s", Direction: "in"},
+						},
+					},
 				},
 				Properties: []introspect.Property{
 					{
@@ -127,6 +165,23 @@ func (ss *SystemService) Start() error {
 							{Name: "active", Type: "b"},
 						},
 					},
+					{
+						Name: "MaintenanceChanged",
+						Args: []introspect.Arg{
+							{Name: "enabled", Type: "b"},
+							{Name: "message", Type: "s"},
+						},
+					},
+					{
+						Name: "Alert",
+						Args: []introspect.Arg{
+							{Name: "rule", Type: "s"},
+							{Name: "subject", Type: "s"},
+							{Name: "severity", Type: "s"},
+							{Name: "state", Type: "s"},
+							{Name: "message", Type: "s"},
+						},
+					},
 				},
 			},
 		},
@@ -200,6 +255,62 @@ func (ss *SystemService) IsQuietHours() (bool, *dbus.Error) {
 	return state.QuietHours, nil
 }
 
+// GetMaintenance returns the maintenance switch and banner (D-Bus method)
+func (ss *SystemService) GetMaintenance() (map[string]dbus.Variant, *dbus.Error) {
+	if ss.maintenance == nil {
+		return nil, dbus.MakeFailedError(fmt.Errorf("maintenance control not available"))
+	}
+
+	status := ss.maintenance.Status()
+	return map[string]dbus.Variant{
+		"enabled":             dbus.MakeVariant(status.Enabled),
+		"message":             dbus.MakeVariant(status.Message),
+		"retry_after_seconds": dbus.MakeVariant(int32(status.RetryAfterSeconds)),
+		"banner":              dbus.MakeVariant(status.Banner),
+	}, nil
+}
+
+// SetMaintenance toggles maintenance mode (D-Bus method)
+func (ss *SystemService) SetMaintenance(enabled bool, message string, retryAfterSeconds int32) *dbus.Error {
+	if ss.maintenance == nil {
+		return dbus.MakeFailedError(fmt.Errorf("maintenance control not available"))
+	}
+
+	if enabled {
+		ss.maintenance.Enable(message, time.Duration(retryAfterSeconds)*time.Second)
+	} else {
+		ss.maintenance.Disable()
+	}
+	return nil
+}
+
+// SetBanner sets the informational banner shown in /v1/models (D-Bus method)
+func (ss *SystemService) SetBanner(banner string) *dbus.Error {
+	if ss.maintenance == nil {
+		return dbus.MakeFailedError(fmt.Errorf("maintenance control not available"))
+	}
+
+	ss.maintenance.SetBanner(banner)
+	return nil
+}
+
+// EmitMaintenanceChanged emits maintenance changed signal
+func (ss *SystemService) EmitMaintenanceChanged(status maintenance.Status) {
+	if ss.conn != nil {
+		ss.conn.Emit(systemPath, systemInterface+".MaintenanceChanged",
+			status.Enabled, status.Message)
+	}
+}
+
+// EmitAlert emits an alert signal (used as the "dbus" alert notifier)
+func (ss *SystemService) EmitAlert(ctx context.Context, alert alerting.Alert) error {
+	if ss.conn == nil {
+		return fmt.Errorf("D-Bus connection not available")
+	}
+	return ss.conn.Emit(systemPath, systemInterface+".Alert",
+		alert.Rule, alert.Subject, alert.Severity, alert.State, alert.Message())
+}
+
 // EmitPowerSourceChanged emits power source changed signal (called by application)
 func (ss *SystemService) EmitPowerSourceChanged(onBattery bool) {
 	if ss.conn != nil {
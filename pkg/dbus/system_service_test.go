@@ -1,9 +1,12 @@
 package dbus
 
 import (
+	"context"
 	"testing"
 
+	"github.com/daoneill/ollama-proxy/pkg/alerting"
 	"github.com/daoneill/ollama-proxy/pkg/efficiency"
+	"github.com/daoneill/ollama-proxy/pkg/maintenance"
 )
 
 // TestSystemServiceConstants tests the package constants
@@ -572,3 +575,71 @@ func TestSystemStateEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestMaintenanceMethods tests the maintenance D-Bus methods
+func TestMaintenanceMethods(t *testing.T) {
+	svc := &SystemService{
+		manager: efficiency.NewEfficiencyManager(efficiency.ModeBalanced),
+	}
+
+	// Without maintenance state the methods fail
+	if _, err := svc.GetMaintenance(); err == nil {
+		t.Error("Expected error when maintenance state not set")
+	}
+	if err := svc.SetMaintenance(true, "", 0); err == nil {
+		t.Error("Expected error when maintenance state not set")
+	}
+
+	state := maintenance.New()
+	svc.SetMaintenanceState(state)
+
+	if err := svc.SetMaintenance(true, "upgrading", 30); err != nil {
+		t.Fatalf("SetMaintenance failed: %v", err)
+	}
+	if err := svc.SetBanner("back at noon"); err != nil {
+		t.Fatalf("SetBanner failed: %v", err)
+	}
+
+	status, err := svc.GetMaintenance()
+	if err != nil {
+		t.Fatalf("GetMaintenance failed: %v", err)
+	}
+	if enabled, ok := status["enabled"].Value().(bool); !ok || !enabled {
+		t.Errorf("Expected enabled=true, got %v", status["enabled"])
+	}
+	if retry, ok := status["retry_after_seconds"].Value().(int32); !ok || retry != 30 {
+		t.Errorf("Expected retry_after_seconds=30, got %v", status["retry_after_seconds"])
+	}
+	if banner, ok := status["banner"].Value().(string); !ok || banner != "back at noon" {
+		t.Errorf("Expected banner 'back at noon', got %v", status["banner"])
+	}
+
+	if err := svc.SetMaintenance(false, "", 0); err != nil {
+		t.Fatalf("SetMaintenance(false) failed: %v", err)
+	}
+	if state.Enabled() {
+		t.Error("Expected maintenance to be disabled")
+	}
+}
+
+func TestEmitAlert_NoConnection(t *testing.T) {
+	svc := &SystemService{
+		manager: efficiency.NewEfficiencyManager(efficiency.ModeBalanced),
+	}
+
+	if err := svc.EmitAlert(context.Background(), alerting.Alert{Rule: "down"}); err == nil {
+		t.Error("Expected error when D-Bus connection not available")
+	}
+}
+
+func TestServiceNames(t *testing.T) {
+	names := ServiceNames()
+	if len(names) != 4 {
+		t.Fatalf("Expected 4 service names, got %v", names)
+	}
+	for _, name := range names {
+		if name == "" {
+			t.Error("Expected non-empty service name")
+		}
+	}
+}
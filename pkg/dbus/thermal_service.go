@@ -42,6 +42,7 @@ func NewThermalService(monitor *thermal.ThermalMonitor) (*ThermalService, error)
 		conn:    conn,
 		monitor: monitor,
 	}
+	monitor.OnPrediction(svc.emitPrediction)
 
 	return svc, nil
 }
@@ -135,6 +136,21 @@ func (ts *ThermalService) Start() error {
 							{Name: "hardware", Type: "s"},
 						},
 					},
+					{
+						Name: "CriticalPredicted",
+						Args: []introspect.Arg{
+							{Name: "hardware", Type: "s"},
+							{Name: "temperature", Type: "d"},
+							{Name: "seconds_to_critical", Type: "d"},
+						},
+					},
+					{
+						Name: "CriticalPredictionCleared",
+						Args: []introspect.Arg{
+							{Name: "hardware", Type: "s"},
+							{Name: "temperature", Type: "d"},
+						},
+					},
 				},
 			},
 		},
@@ -275,6 +291,33 @@ func (ts *ThermalService) EmitThrottlingStopped(hardware string) {
 	}
 }
 
+// EmitCriticalPredicted emits a signal that hardware's temperature trend
+// reaches critical in secondsToCritical (0 if already there)
+func (ts *ThermalService) EmitCriticalPredicted(hardware string, temperature, secondsToCritical float64) {
+	if ts.conn != nil {
+		ts.conn.Emit(thermalPath, thermalInterface+".CriticalPredicted",
+			hardware, temperature, secondsToCritical)
+	}
+}
+
+// EmitCriticalPredictionCleared emits a signal that hardware is no longer
+// heading for critical temperature
+func (ts *ThermalService) EmitCriticalPredictionCleared(hardware string, temperature float64) {
+	if ts.conn != nil {
+		ts.conn.Emit(thermalPath, thermalInterface+".CriticalPredictionCleared",
+			hardware, temperature)
+	}
+}
+
+// emitPrediction forwards a change in the monitor's prediction
+func (ts *ThermalService) emitPrediction(p thermal.Prediction) {
+	if p.Critical {
+		ts.EmitCriticalPredicted(p.Hardware, p.Temperature, p.TimeToCritical.Seconds())
+		return
+	}
+	ts.EmitCriticalPredictionCleared(p.Hardware, p.Temperature)
+}
+
 // makePropertyMap creates property map for D-Bus
 func (ts *ThermalService) makePropertyMap() map[string]map[string]*prop.Prop {
 	states := ts.monitor.GetAllStates()
@@ -230,6 +230,8 @@ func TestEmitMethodsWithNilConn(t *testing.T) {
 		{"EmitThermalWarning", func() { svc.EmitThermalWarning("cpu", 85.0) }},
 		{"EmitThrottlingStarted", func() { svc.EmitThrottlingStarted("gpu") }},
 		{"EmitThrottlingStopped", func() { svc.EmitThrottlingStopped("gpu") }},
+		{"EmitCriticalPredicted", func() { svc.EmitCriticalPredicted("gpu", 80.0, 30.0) }},
+		{"EmitCriticalPredictionCleared", func() { svc.EmitCriticalPredictionCleared("gpu", 70.0) }},
 		{"UpdatePropertiesThermal", func() { svc.UpdateProperties() }},
 	}
 
@@ -0,0 +1,244 @@
+// Package degrade applies configured degradation profiles while the
+// machine is under stress. A profile names the conditions it answers
+// (thermal level, throttling, battery) and what the proxy gives up while
+// they hold: endpoints that answer 503, a hard cap on max_tokens, and the
+// backends and power budget routing may use. Behavior under stress is
+// then what the configuration says, not whatever the router happens to
+// fall back to.
+package degrade
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"go.uber.org/zap"
+)
+
+// DefaultInterval is how often system state is checked
+const DefaultInterval = 10 * time.Second
+
+// Thermal levels, from the configured warning and critical temperatures
+const (
+	ThermalWarning  = "warning"
+	ThermalCritical = "critical"
+)
+
+var thermalRank = map[string]int{"": 0, ThermalWarning: 1, ThermalCritical: 2}
+
+// State is the system state profiles are matched against
+type State struct {
+	Thermal        string // hottest device's level, "" when below warning
+	Throttling     bool   // any device is throttling
+	OnBattery      bool
+	BatteryPercent int
+}
+
+// StateSource reports the current system state
+type StateSource func() State
+
+// Conditions activate a profile when all that are set hold
+type Conditions struct {
+	Thermal      string // ThermalWarning or ThermalCritical: that level or hotter
+	Throttling   bool
+	OnBattery    bool
+	BatteryBelow int // percent, on battery only
+}
+
+// Match reports whether s meets the conditions
+func (c Conditions) Match(s State) bool {
+	if c.Thermal != "" && thermalRank[s.Thermal] < thermalRank[c.Thermal] {
+		return false
+	}
+	if c.Throttling && !s.Throttling {
+		return false
+	}
+	if (c.OnBattery || c.BatteryBelow > 0) && !s.OnBattery {
+		return false
+	}
+	return c.BatteryBelow == 0 || s.BatteryPercent < c.BatteryBelow
+}
+
+// Profile is what the proxy gives up while its conditions hold
+type Profile struct {
+	Name             string
+	When             Conditions
+	DisableEndpoints []string // path prefixes answered with 503, e.g. "/v1/images"
+	MaxTokens        int32    // caps every generation, including explicit max_tokens; 0 = no cap
+	AllowedBackends  []string // empty = any backend
+	MaxPowerWatts    int32    // 0 = no cap
+}
+
+// Status is the active profile, if any
+type Status struct {
+	Active           bool      `json:"active"`
+	Profile          string    `json:"profile,omitempty"`
+	Since            time.Time `json:"since,omitempty"`
+	DisableEndpoints []string  `json:"disable_endpoints,omitempty"`
+	MaxTokens        int32     `json:"max_tokens,omitempty"`
+	AllowedBackends  []string  `json:"allowed_backends,omitempty"`
+	MaxPowerWatts    int32     `json:"max_power_watts,omitempty"`
+}
+
+// Manager activates the first profile, in configured order, whose
+// conditions hold
+type Manager struct {
+	profiles []Profile
+	source   StateSource
+	interval time.Duration
+
+	mu     sync.RWMutex
+	active *Profile
+	since  time.Time
+}
+
+// New creates a manager. Profiles are checked in order, so list the most
+// severe first. interval <= 0 uses DefaultInterval.
+func New(profiles []Profile, source StateSource, interval time.Duration) *Manager {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Manager{profiles: profiles, source: source, interval: interval}
+}
+
+// Evaluate re-reads the system state and switches profiles if it changed
+func (m *Manager) Evaluate() {
+	state := m.source()
+	var next *Profile
+	for i := range m.profiles {
+		if m.profiles[i].When.Match(state) {
+			next = &m.profiles[i]
+			break
+		}
+	}
+
+	m.mu.Lock()
+	prev := m.active
+	if next != prev {
+		m.active = next
+		m.since = time.Now()
+	}
+	m.mu.Unlock()
+
+	if next == prev || logging.Logger == nil {
+		return
+	}
+	if next != nil {
+		logging.Logger.Warn("Degradation profile activated",
+			zap.String("profile", next.Name),
+			zap.String("thermal", state.Thermal),
+			zap.Bool("throttling", state.Throttling),
+			zap.Bool("on_battery", state.OnBattery),
+			zap.Int("battery_percent", state.BatteryPercent),
+		)
+	} else {
+		logging.Logger.Info("Degradation profile cleared", zap.String("profile", prev.Name))
+	}
+}
+
+// Run evaluates the profiles every interval until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	m.Evaluate()
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Evaluate()
+		}
+	}
+}
+
+// Active returns the active profile
+func (m *Manager) Active() (Profile, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.active == nil {
+		return Profile{}, false
+	}
+	return *m.active, true
+}
+
+// Status returns the active profile and since when it has applied
+func (m *Manager) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.active == nil {
+		return Status{}
+	}
+	return Status{
+		Active:           true,
+		Profile:          m.active.Name,
+		Since:            m.since,
+		DisableEndpoints: m.active.DisableEndpoints,
+		MaxTokens:        m.active.MaxTokens,
+		AllowedBackends:  m.active.AllowedBackends,
+		MaxPowerWatts:    m.active.MaxPowerWatts,
+	}
+}
+
+// MaxTokens returns the active profile's max_tokens cap, 0 when none
+func (m *Manager) MaxTokens() int32 {
+	p, _ := m.Active()
+	return p.MaxTokens
+}
+
+// Constraints returns the active profile's routing limits. It is a
+// router.ModeConstraintSource; profiles that limit neither backends nor
+// power report none.
+func (m *Manager) Constraints() (router.ModeConstraints, bool) {
+	p, ok := m.Active()
+	if !ok || (len(p.AllowedBackends) == 0 && p.MaxPowerWatts == 0) {
+		return router.ModeConstraints{}, false
+	}
+	return router.ModeConstraints{
+		Reason:          "degradation profile: " + p.Name,
+		MaxPowerWatts:   p.MaxPowerWatts,
+		AllowedBackends: p.AllowedBackends,
+	}, true
+}
+
+// disabled reports whether the active profile disables path
+func (m *Manager) disabled(path string) (string, bool) {
+	p, ok := m.Active()
+	if !ok {
+		return "", false
+	}
+	for _, prefix := range p.DisableEndpoints {
+		if strings.HasPrefix(path, prefix) {
+			return p.Name, true
+		}
+	}
+	return "", false
+}
+
+// Middleware answers requests to endpoints the active profile disables
+// with 503, asking clients to retry once the state is next checked
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		profile, disabled := m.disabled(r.URL.Path)
+		if !disabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(int(m.interval.Seconds())))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": "Endpoint disabled by degradation profile " + profile,
+				"type":    "service_unavailable",
+				"code":    "degraded",
+			},
+		})
+	})
+}
@@ -0,0 +1,106 @@
+package degrade
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testManager(state *State) *Manager {
+	return New([]Profile{
+		{
+			Name:             "thermal-critical",
+			When:             Conditions{Thermal: ThermalCritical},
+			DisableEndpoints: []string{"/v1/images", "/v1/video"},
+			MaxTokens:        256,
+			AllowedBackends:  []string{"ollama-npu"},
+		},
+		{
+			Name:      "low-battery",
+			When:      Conditions{BatteryBelow: 20},
+			MaxTokens: 512,
+		},
+		{
+			Name:          "hot",
+			When:          Conditions{Thermal: ThermalWarning, Throttling: true},
+			MaxPowerWatts: 15,
+		},
+	}, func() State { return *state }, 0)
+}
+
+func TestManager_Evaluate(t *testing.T) {
+	state := State{}
+	m := testManager(&state)
+
+	tests := []struct {
+		name    string
+		state   State
+		profile string
+	}{
+		{"normal", State{}, ""},
+		{"warning alone", State{Thermal: ThermalWarning}, ""},
+		{"warning and throttling", State{Thermal: ThermalWarning, Throttling: true}, "hot"},
+		{"critical also meets warning", State{Thermal: ThermalCritical, Throttling: true}, "thermal-critical"},
+		{"low battery on AC", State{BatteryPercent: 10}, ""},
+		{"low battery", State{OnBattery: true, BatteryPercent: 10}, "low-battery"},
+		{"battery fine", State{OnBattery: true, BatteryPercent: 60}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state = tt.state
+			m.Evaluate()
+			status := m.Status()
+			if status.Profile != tt.profile || status.Active != (tt.profile != "") {
+				t.Errorf("Expected profile %q, got %+v", tt.profile, status)
+			}
+		})
+	}
+}
+
+func TestManager_Limits(t *testing.T) {
+	state := State{Thermal: ThermalCritical}
+	m := testManager(&state)
+	m.Evaluate()
+
+	if got := m.MaxTokens(); got != 256 {
+		t.Errorf("Expected max_tokens capped to 256, got %d", got)
+	}
+	mc, ok := m.Constraints()
+	if !ok || len(mc.AllowedBackends) != 1 || mc.AllowedBackends[0] != "ollama-npu" {
+		t.Errorf("Expected NPU-only routing, got %+v, %v", mc, ok)
+	}
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for path, code := range map[string]int{
+		"/v1/images/generations": http.StatusServiceUnavailable,
+		"/v1/video/uploads/abc":  http.StatusServiceUnavailable,
+		"/v1/chat/completions":   http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != code {
+			t.Errorf("%s: expected %d, got %d", path, code, rec.Code)
+		}
+		if code == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "10" {
+			t.Errorf("%s: expected Retry-After 10, got %q", path, rec.Header().Get("Retry-After"))
+		}
+	}
+
+	// A profile that only caps tokens leaves routing alone
+	state = State{OnBattery: true, BatteryPercent: 5}
+	m.Evaluate()
+	if _, ok := m.Constraints(); ok {
+		t.Error("Expected no routing constraints from the low-battery profile")
+	}
+
+	state = State{}
+	m.Evaluate()
+	if m.MaxTokens() != 0 {
+		t.Error("Expected no cap once the stress clears")
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/images/generations", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected images re-enabled, got %d", rec.Code)
+	}
+}
@@ -130,6 +130,63 @@ func (s *GRPCService) GetDevice(ctx context.Context, req *devicev1.GetDeviceRequ
 	}, nil
 }
 
+// RenameDevice gives a device a friendly name
+func (s *GRPCService) RenameDevice(ctx context.Context, req *devicev1.RenameDeviceRequest) (*devicev1.RenameDeviceResponse, error) {
+	s.logger.Info("gRPC RenameDevice called",
+		zap.String("device_id", req.DeviceId),
+		zap.String("friendly_name", req.FriendlyName),
+	)
+
+	if dbusErr := s.deviceManager.RenameDevice(req.DeviceId, req.FriendlyName, "ie.fio.OllamaProxy.gRPC"); dbusErr != nil {
+		return nil, status.Errorf(codes.Internal, "failed to rename device: %v", dbusErr)
+	}
+
+	entry, _ := s.deviceManager.InventoryEntry(req.DeviceId)
+	return &devicev1.RenameDeviceResponse{
+		Entry: convertInventoryEntryToProto(entry),
+	}, nil
+}
+
+// AnnotateDevice sets notes on a device
+func (s *GRPCService) AnnotateDevice(ctx context.Context, req *devicev1.AnnotateDeviceRequest) (*devicev1.AnnotateDeviceResponse, error) {
+	s.logger.Info("gRPC AnnotateDevice called",
+		zap.String("device_id", req.DeviceId),
+	)
+
+	if dbusErr := s.deviceManager.AnnotateDevice(req.DeviceId, req.Notes, "ie.fio.OllamaProxy.gRPC"); dbusErr != nil {
+		return nil, status.Errorf(codes.Internal, "failed to annotate device: %v", dbusErr)
+	}
+
+	entry, _ := s.deviceManager.InventoryEntry(req.DeviceId)
+	return &devicev1.AnnotateDeviceResponse{
+		Entry: convertInventoryEntryToProto(entry),
+	}, nil
+}
+
+// ListInventory lists every device ever registered
+func (s *GRPCService) ListInventory(ctx context.Context, req *devicev1.ListInventoryRequest) (*devicev1.ListInventoryResponse, error) {
+	s.logger.Debug("gRPC ListInventory called",
+		zap.String("filter_type", req.FilterType.String()),
+	)
+
+	filterType := ""
+	if req.FilterType != devicev1.DeviceType_DEVICE_TYPE_UNSPECIFIED {
+		filterType = deviceTypeToString(req.FilterType)
+	}
+
+	var entries []*devicev1.InventoryEntry
+	for _, e := range s.deviceManager.Inventory().List() {
+		if filterType != "" && string(e.Type) != filterType {
+			continue
+		}
+		entries = append(entries, convertInventoryEntryToProto(e))
+	}
+
+	return &devicev1.ListInventoryResponse{
+		Entries: entries,
+	}, nil
+}
+
 // RequestDeviceAccess requests access to a device
 func (s *GRPCService) RequestDeviceAccess(ctx context.Context, req *devicev1.RequestDeviceAccessRequest) (*devicev1.RequestDeviceAccessResponse, error) {
 	s.logger.Info("gRPC RequestDeviceAccess called",
@@ -268,6 +325,33 @@ func convertDBusDeviceToProto(deviceMap map[string]dbus.Variant) *devicev1.Devic
 			device.LastUsedAt = t.UnixNano()
 		}
 	}
+	if v, ok := deviceMap["InventoryKey"]; ok {
+		device.InventoryKey = v.Value().(string)
+	}
+	if v, ok := deviceMap["FriendlyName"]; ok {
+		device.FriendlyName = v.Value().(string)
+	}
+	if v, ok := deviceMap["Notes"]; ok {
+		device.Notes = v.Value().(string)
+	}
+	if v, ok := deviceMap["Hardware"]; ok {
+		device.Hardware = v.Value().(string)
+	}
+	if v, ok := deviceMap["Utilization"]; ok {
+		device.Utilization = &devicev1.DeviceUtilization{Percent: v.Value().(float64)}
+		if v, ok := deviceMap["MemoryUsedBytes"]; ok {
+			device.Utilization.MemoryUsedBytes = v.Value().(uint64)
+		}
+		if v, ok := deviceMap["MemoryTotalBytes"]; ok {
+			device.Utilization.MemoryTotalBytes = v.Value().(uint64)
+		}
+		if v, ok := deviceMap["UtilizationSource"]; ok {
+			device.Utilization.Source = v.Value().(string)
+		}
+		if v, ok := deviceMap["UtilizationSampledAt"]; ok {
+			device.Utilization.SampledAt = time.Unix(v.Value().(int64), 0).UnixNano()
+		}
+	}
 	if v, ok := deviceMap["Capabilities"]; ok {
 		if caps, ok := v.Value().(map[string]dbus.Variant); ok {
 			device.Capabilities = make(map[string]string)
@@ -280,6 +364,20 @@ func convertDBusDeviceToProto(deviceMap map[string]dbus.Variant) *devicev1.Devic
 	return device
 }
 
+// Helper: Convert an inventory entry to protobuf
+func convertInventoryEntryToProto(e InventoryEntry) *devicev1.InventoryEntry {
+	return &devicev1.InventoryEntry{
+		Key:          e.Key,
+		Type:         stringToDeviceType(string(e.Type)),
+		Name:         e.Name,
+		Path:         e.Path,
+		FriendlyName: e.FriendlyName,
+		Notes:        e.Notes,
+		FirstSeen:    e.FirstSeen.UnixNano(),
+		LastSeen:     e.LastSeen.UnixNano(),
+	}
+}
+
 // Helper: Convert protobuf DeviceType to string
 func deviceTypeToString(dt devicev1.DeviceType) string {
 	switch dt {
@@ -295,6 +393,10 @@ func deviceTypeToString(dt devicev1.DeviceType) string {
 		return "keyboard"
 	case devicev1.DeviceType_DEVICE_TYPE_MOUSE:
 		return "mouse"
+	case devicev1.DeviceType_DEVICE_TYPE_NPU:
+		return "npu"
+	case devicev1.DeviceType_DEVICE_TYPE_GPU:
+		return "gpu"
 	default:
 		return ""
 	}
@@ -315,6 +417,10 @@ func stringToDeviceType(s string) devicev1.DeviceType {
 		return devicev1.DeviceType_DEVICE_TYPE_KEYBOARD
 	case "mouse":
 		return devicev1.DeviceType_DEVICE_TYPE_MOUSE
+	case "npu":
+		return devicev1.DeviceType_DEVICE_TYPE_NPU
+	case "gpu":
+		return devicev1.DeviceType_DEVICE_TYPE_GPU
 	default:
 		return devicev1.DeviceType_DEVICE_TYPE_UNSPECIFIED
 	}
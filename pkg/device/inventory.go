@@ -0,0 +1,194 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// InventoryEntry is what is remembered about a device across restarts
+type InventoryEntry struct {
+	Key          string     `json:"key"`
+	Type         DeviceType `json:"type"`
+	Name         string     `json:"name"` // name reported at discovery
+	Path         string     `json:"path"`
+	FriendlyName string     `json:"friendly_name,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+	FirstSeen    time.Time  `json:"first_seen"`
+	LastSeen     time.Time  `json:"last_seen"`
+}
+
+// DisplayName is the user-assigned name, or the discovered one
+func (e *InventoryEntry) DisplayName() string {
+	if e.FriendlyName != "" {
+		return e.FriendlyName
+	}
+	return e.Name
+}
+
+// ToDBusVariant converts the entry to a D-Bus variant map
+func (e *InventoryEntry) ToDBusVariant() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"Key":          dbus.MakeVariant(e.Key),
+		"Type":         dbus.MakeVariant(string(e.Type)),
+		"Name":         dbus.MakeVariant(e.Name),
+		"Path":         dbus.MakeVariant(e.Path),
+		"FriendlyName": dbus.MakeVariant(e.FriendlyName),
+		"Notes":        dbus.MakeVariant(e.Notes),
+		"FirstSeen":    dbus.MakeVariant(e.FirstSeen.Unix()),
+		"LastSeen":     dbus.MakeVariant(e.LastSeen.Unix()),
+	}
+}
+
+// Inventory remembers every device ever registered, with user-assigned
+// names and notes, in a JSON file. Device IDs change on every
+// registration, so entries are keyed by where the device is attached.
+type Inventory struct {
+	path string // empty = not persisted
+
+	mu      sync.Mutex
+	entries map[string]*InventoryEntry
+}
+
+// LoadInventory reads the inventory at path, starting empty if the file
+// doesn't exist yet. An empty path keeps the inventory in memory only.
+func LoadInventory(path string) (*Inventory, error) {
+	inv := &Inventory{
+		path:    path,
+		entries: make(map[string]*InventoryEntry),
+	}
+	if path == "" {
+		return inv, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return inv, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device inventory: %w", err)
+	}
+
+	var entries []*InventoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid device inventory %s: %w", path, err)
+	}
+	for _, e := range entries {
+		inv.entries[e.Key] = e
+	}
+	return inv, nil
+}
+
+// InventoryKey identifies a device across restarts and replugs into the
+// same port: its sysfs path when discovery recorded one, otherwise its
+// type and device node
+func InventoryKey(d *Device) string {
+	if devpath, ok := d.Capabilities["devpath"].(string); ok && devpath != "" {
+		return string(d.Type) + ":" + devpath
+	}
+	return string(d.Type) + ":" + d.Path
+}
+
+// Seen records a registration of d and returns its entry
+func (inv *Inventory) Seen(d *Device) (InventoryEntry, error) {
+	key := InventoryKey(d)
+	now := time.Now()
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	e, ok := inv.entries[key]
+	if !ok {
+		e = &InventoryEntry{Key: key, FirstSeen: now}
+		inv.entries[key] = e
+	}
+	e.Type = d.Type
+	e.Name = d.Name
+	e.Path = d.Path
+	e.LastSeen = now
+	return *e, inv.saveLocked()
+}
+
+// Rename sets the friendly name of the device with key, "" to clear it
+func (inv *Inventory) Rename(key, friendlyName string) (InventoryEntry, error) {
+	return inv.update(key, func(e *InventoryEntry) { e.FriendlyName = friendlyName })
+}
+
+// Annotate sets the notes on the device with key, "" to clear them
+func (inv *Inventory) Annotate(key, notes string) (InventoryEntry, error) {
+	return inv.update(key, func(e *InventoryEntry) { e.Notes = notes })
+}
+
+func (inv *Inventory) update(key string, fn func(e *InventoryEntry)) (InventoryEntry, error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	e, ok := inv.entries[key]
+	if !ok {
+		return InventoryEntry{}, fmt.Errorf("device not in inventory: %s", key)
+	}
+	fn(e)
+	return *e, inv.saveLocked()
+}
+
+// Get returns the entry with key
+func (inv *Inventory) Get(key string) (InventoryEntry, bool) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if e, ok := inv.entries[key]; ok {
+		return *e, true
+	}
+	return InventoryEntry{}, false
+}
+
+// List returns every known device, most recently seen first
+func (inv *Inventory) List() []InventoryEntry {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	list := make([]InventoryEntry, 0, len(inv.entries))
+	for _, e := range inv.entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].LastSeen.Equal(list[j].LastSeen) {
+			return list[i].LastSeen.After(list[j].LastSeen)
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// saveLocked writes the inventory, then renames it into place so a crash
+// never leaves a truncated file
+func (inv *Inventory) saveLocked() error {
+	if inv.path == "" {
+		return nil
+	}
+
+	entries := make([]*InventoryEntry, 0, len(inv.entries))
+	for _, e := range inv.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(inv.path), 0o755); err != nil {
+		return fmt.Errorf("failed to save device inventory: %w", err)
+	}
+	tmp := inv.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save device inventory: %w", err)
+	}
+	if err := os.Rename(tmp, inv.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save device inventory: %w", err)
+	}
+	return nil
+}
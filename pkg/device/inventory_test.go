@@ -0,0 +1,68 @@
+package device
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestInventory_PersistsFriendlyNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.json")
+	inv, err := LoadInventory(path)
+	if err != nil {
+		t.Fatalf("LoadInventory failed: %v", err)
+	}
+
+	camera := &Device{
+		ID:           "camera-Camera /dev/video0-1",
+		Type:         DeviceTypeCamera,
+		Name:         "Camera /dev/video0",
+		Path:         "/dev/video0",
+		Capabilities: map[string]interface{}{"devpath": "/devices/pci0000:00/0000:00:14.0/usb1/1-2"},
+	}
+	entry, err := inv.Seen(camera)
+	if err != nil {
+		t.Fatalf("Seen failed: %v", err)
+	}
+	if entry.Key != "camera:/devices/pci0000:00/0000:00:14.0/usb1/1-2" {
+		t.Errorf("Expected the sysfs path as key, got %s", entry.Key)
+	}
+	if _, err := inv.Rename(entry.Key, "Desk webcam"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if _, err := inv.Annotate(entry.Key, "Left USB-C port"); err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	if _, err := inv.Rename("camera:/dev/video9", "Missing"); err == nil {
+		t.Error("Expected renaming an unknown device to fail")
+	}
+
+	// Restart: the replugged camera gets a new ID and device node
+	inv, err = LoadInventory(path)
+	if err != nil {
+		t.Fatalf("LoadInventory failed: %v", err)
+	}
+	camera.ID = "camera-Camera /dev/video2-2"
+	camera.Path = "/dev/video2"
+	entry, err = inv.Seen(camera)
+	if err != nil {
+		t.Fatalf("Seen failed: %v", err)
+	}
+	if entry.DisplayName() != "Desk webcam" || entry.Notes != "Left USB-C port" || entry.Path != "/dev/video2" {
+		t.Errorf("Expected the name and notes to survive a restart, got %+v", entry)
+	}
+}
+
+func TestInventory_KeyWithoutDevpath(t *testing.T) {
+	inv, _ := LoadInventory("")
+	mic := &Device{Type: DeviceTypeMicrophone, Name: "Built-in Microphone", Path: "/dev/snd/pcmC0D0c"}
+	entry, err := inv.Seen(mic)
+	if err != nil {
+		t.Fatalf("Seen failed: %v", err)
+	}
+	if entry.Key != "microphone:/dev/snd/pcmC0D0c" || entry.DisplayName() != "Built-in Microphone" {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if got := inv.List(); len(got) != 1 {
+		t.Errorf("Expected one device, got %+v", got)
+	}
+}
@@ -32,6 +32,7 @@ type DeviceManager struct {
 	logger       *zap.Logger
 	udevMonitor  *UdevMonitor
 	polkit       *PolkitAuthorizer
+	inventory    *Inventory
 	ctx          context.Context
 	cancel       context.CancelFunc
 }
@@ -51,6 +52,7 @@ func NewDeviceManager() (*DeviceManager, error) {
 		accessGrants: make(map[string]*AccessGrant),
 		logger:       logging.Logger,
 		polkit:       NewPolkitAuthorizer(conn, logging.Logger),
+		inventory:    &Inventory{entries: make(map[string]*InventoryEntry)},
 		ctx:          ctx,
 		cancel:       cancel,
 	}
@@ -120,6 +122,26 @@ func (dm *DeviceManager) export() error {
 							{Name: "device", Type: "a{sv}", Direction: "out"},
 						},
 					},
+					{
+						Name: "RenameDevice",
+						Args: []introspect.Arg{
+							{Name: "device_id", Type: "s", Direction: "in"},
+							{Name: "friendly_name", Type: "s", Direction: "in"},
+						},
+					},
+					{
+						Name: "AnnotateDevice",
+						Args: []introspect.Arg{
+							{Name: "device_id", Type: "s", Direction: "in"},
+							{Name: "notes", Type: "s", Direction: "in"},
+						},
+					},
+					{
+						Name: "ListInventory",
+						Args: []introspect.Arg{
+							{Name: "devices", Type: "aa{sv}", Direction: "out"},
+						},
+					},
 					{
 						Name: "RequestDeviceAccess",
 						Args: []introspect.Arg{
@@ -153,6 +175,13 @@ func (dm *DeviceManager) export() error {
 							{Name: "device_id", Type: "s"},
 						},
 					},
+					{
+						Name: "DeviceRenamed",
+						Args: []introspect.Arg{
+							{Name: "device_id", Type: "s"},
+							{Name: "friendly_name", Type: "s"},
+						},
+					},
 					{
 						Name: "DeviceStateChanged",
 						Args: []introspect.Arg{
@@ -267,6 +296,7 @@ func (dm *DeviceManager) RegisterDevice(deviceType, devicePath, deviceName strin
 	}
 
 	dm.devices[deviceID] = device
+	dm.recordInventory(device)
 
 	dm.logger.Info("Device registered",
 		zap.String("device_id", deviceID),
@@ -281,8 +311,7 @@ func (dm *DeviceManager) RegisterDevice(deviceType, devicePath, deviceName strin
 	}
 
 	// Update properties
-	dm.props.SetMust(deviceManagerInterface, "TotalDevices", dm.getTotalDevices())
-	dm.props.SetMust(deviceManagerInterface, "AvailableDevices", dm.getAvailableDevices())
+	dm.updateCountPropsLocked()
 
 	return deviceID, nil
 }
@@ -309,8 +338,7 @@ func (dm *DeviceManager) UnregisterDevice(deviceID string) *dbus.Error {
 	}
 
 	// Update properties
-	dm.props.SetMust(deviceManagerInterface, "TotalDevices", dm.getTotalDevices())
-	dm.props.SetMust(deviceManagerInterface, "AvailableDevices", dm.getAvailableDevices())
+	dm.updateCountPropsLocked()
 
 	return nil
 }
@@ -347,6 +375,76 @@ func (dm *DeviceManager) GetDevice(deviceID string) (map[string]dbus.Variant, *d
 	return device.ToDBusVariant(), nil
 }
 
+// RenameDevice gives a device a friendly name, kept across restarts.
+// device_id is a registered device's ID or, for a device that isn't
+// attached, its inventory key. An empty name restores the discovered one.
+func (dm *DeviceManager) RenameDevice(deviceID, friendlyName string, sender dbus.Sender) *dbus.Error {
+	if err := dm.authorizeInventoryChange(sender, "rename_device", deviceID); err != nil {
+		return err
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	key := dm.inventoryKeyLocked(deviceID)
+	if _, known := dm.inventory.Get(key); !known {
+		return dbus.MakeFailedError(fmt.Errorf("device not found: %s", deviceID))
+	}
+	entry, err := dm.inventory.Rename(key, friendlyName)
+	if err != nil {
+		dm.logger.Error("Failed to save device inventory", zap.Error(err))
+	}
+	dm.applyInventoryLocked(entry)
+
+	dm.logger.Info("Device renamed",
+		zap.String("device_id", deviceID),
+		zap.String("friendly_name", friendlyName),
+		zap.String("sender", string(sender)))
+
+	if err := dm.conn.Emit(deviceManagerPath, deviceManagerInterface+".DeviceRenamed", deviceID, entry.DisplayName()); err != nil {
+		dm.logger.Error("Failed to emit DeviceRenamed signal", zap.Error(err))
+	}
+
+	return nil
+}
+
+// AnnotateDevice sets free-form notes on a device, kept across restarts.
+// device_id is as for RenameDevice.
+func (dm *DeviceManager) AnnotateDevice(deviceID, notes string, sender dbus.Sender) *dbus.Error {
+	if err := dm.authorizeInventoryChange(sender, "annotate_device", deviceID); err != nil {
+		return err
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	key := dm.inventoryKeyLocked(deviceID)
+	if _, known := dm.inventory.Get(key); !known {
+		return dbus.MakeFailedError(fmt.Errorf("device not found: %s", deviceID))
+	}
+	entry, err := dm.inventory.Annotate(key, notes)
+	if err != nil {
+		dm.logger.Error("Failed to save device inventory", zap.Error(err))
+	}
+	dm.applyInventoryLocked(entry)
+
+	dm.logger.Info("Device annotated",
+		zap.String("device_id", deviceID),
+		zap.String("sender", string(sender)))
+
+	return nil
+}
+
+// ListInventory returns every device ever registered, attached or not,
+// most recently seen first
+func (dm *DeviceManager) ListInventory() ([]map[string]dbus.Variant, *dbus.Error) {
+	var result []map[string]dbus.Variant
+	for _, e := range dm.Inventory().List() {
+		result = append(result, e.ToDBusVariant())
+	}
+	return result, nil
+}
+
 // RequestDeviceAccess grants access to a device for a client
 func (dm *DeviceManager) RequestDeviceAccess(deviceID, clientID string, sender dbus.Sender) (string, string, string, *dbus.Error) {
 	dm.mu.Lock()
@@ -449,8 +547,168 @@ func (dm *DeviceManager) ReleaseDeviceAccess(deviceID, clientID string) *dbus.Er
 	return nil
 }
 
+// Utilization
+
+// SetUtilizationMonitor registers the accelerators monitor samples as
+// devices and keeps their utilization current
+func (dm *DeviceManager) SetUtilizationMonitor(m *UtilizationMonitor) {
+	m.OnSample(dm.applyUtilization)
+}
+
+// applyUtilization records a round of samples, registering accelerators
+// seen for the first time
+func (dm *DeviceManager) applyUtilization(samples []AcceleratorSample) {
+	for _, sample := range samples {
+		device := dm.acceleratorDevice(sample.DevPath)
+		if device == nil {
+			capabilities := map[string]dbus.Variant{
+				"devpath":  dbus.MakeVariant(sample.DevPath), // keys the inventory
+				"hardware": dbus.MakeVariant(sample.Hardware),
+			}
+			deviceID, err := dm.RegisterDevice(string(sample.Type), sample.Path, sample.Name, capabilities, "ie.fio.OllamaProxy.System")
+			if err != nil {
+				dm.logger.Error("Failed to register accelerator",
+					zap.String("devpath", sample.DevPath),
+					zap.Error(err))
+				continue
+			}
+			dm.mu.RLock()
+			device = dm.devices[deviceID]
+			dm.mu.RUnlock()
+			if device == nil {
+				continue
+			}
+		}
+		device.setUtilization(sample)
+	}
+}
+
+// acceleratorDevice finds the registered accelerator at devPath
+func (dm *DeviceManager) acceleratorDevice(devPath string) *Device {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	for _, device := range dm.devices {
+		if device.Type != DeviceTypeNPU && device.Type != DeviceTypeGPU {
+			continue
+		}
+		if path, _ := device.Capabilities["devpath"].(string); path == devPath {
+			return device
+		}
+	}
+	return nil
+}
+
+// CameraPath returns the device path of a registered camera, matched by
+// ID, friendly name, name or inventory key
+func (dm *DeviceManager) CameraPath(name string) (string, error) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	for _, device := range dm.devices {
+		if device.Type != DeviceTypeCamera {
+			continue
+		}
+		if device.ID == name || device.FriendlyName == name || device.Name == name || device.InventoryKey == name {
+			return device.Path, nil
+		}
+	}
+	return "", fmt.Errorf("camera not found: %s", name)
+}
+
+// Inventory
+
+// SetInventory replaces the in-memory inventory with a persistent one and
+// records the devices already registered in it
+func (dm *DeviceManager) SetInventory(inv *Inventory) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.inventory = inv
+	for _, device := range dm.devices {
+		dm.recordInventory(device)
+	}
+}
+
+// Inventory returns the device inventory
+func (dm *DeviceManager) Inventory() *Inventory {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.inventory
+}
+
+// InventoryEntry returns the inventory entry of a registered device's ID
+// or an inventory key
+func (dm *DeviceManager) InventoryEntry(deviceID string) (InventoryEntry, bool) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.inventory.Get(dm.inventoryKeyLocked(deviceID))
+}
+
+// recordInventory notes device in the inventory and gives it the name and
+// notes the user assigned it before. Caller holds dm.mu.
+func (dm *DeviceManager) recordInventory(device *Device) {
+	entry, err := dm.inventory.Seen(device)
+	if err != nil {
+		dm.logger.Error("Failed to save device inventory",
+			zap.String("device_id", device.ID),
+			zap.Error(err))
+	}
+	device.applyInventory(entry)
+}
+
+// inventoryKeyLocked resolves a registered device's ID to its inventory
+// key; anything else is taken to be a key already
+func (dm *DeviceManager) inventoryKeyLocked(deviceID string) string {
+	if device, exists := dm.devices[deviceID]; exists {
+		return InventoryKey(device)
+	}
+	return deviceID
+}
+
+// applyInventoryLocked updates the registered devices behind entry
+func (dm *DeviceManager) applyInventoryLocked(entry InventoryEntry) {
+	for _, device := range dm.devices {
+		if InventoryKey(device) == entry.Key {
+			device.applyInventory(entry)
+		}
+	}
+}
+
+// authorizeInventoryChange checks Polkit for renaming and annotating, which
+// need the same authorization as registering devices
+func (dm *DeviceManager) authorizeInventoryChange(sender dbus.Sender, action, deviceID string) *dbus.Error {
+	if string(sender) == "ie.fio.OllamaProxy.System" {
+		return nil
+	}
+
+	authorized, err := dm.polkit.CheckDeviceRegister(string(sender))
+	if err != nil {
+		dm.logger.Error("Failed to check authorization",
+			zap.String("sender", string(sender)),
+			zap.Error(err),
+		)
+		return dbus.MakeFailedError(fmt.Errorf("authorization check failed: %w", err))
+	}
+	dm.polkit.LogAuditEvent(string(sender), action, deviceID, authorized)
+	if !authorized {
+		return dbus.MakeFailedError(fmt.Errorf("permission denied: not authorized to modify devices"))
+	}
+	return nil
+}
+
 // Property getters
 
+// updateCountPropsLocked publishes the device counts. Caller holds dm.mu,
+// which the getters below would take again.
+func (dm *DeviceManager) updateCountPropsLocked() {
+	available := int32(0)
+	for _, device := range dm.devices {
+		if device.GetState() == DeviceStateAvailable {
+			available++
+		}
+	}
+	dm.props.SetMust(deviceManagerInterface, "TotalDevices", int32(len(dm.devices)))
+	dm.props.SetMust(deviceManagerInterface, "AvailableDevices", available)
+}
+
 func (dm *DeviceManager) getTotalDevices() int32 {
 	dm.mu.RLock()
 	defer dm.mu.RUnlock()
@@ -501,6 +759,7 @@ func (dm *DeviceManager) handleUdevEvent(event UdevEvent) {
 		capabilities := map[string]dbus.Variant{
 			"subsystem": dbus.MakeVariant(event.Subsystem),
 			"devtype":   dbus.MakeVariant(event.DevType),
+			"devpath":   dbus.MakeVariant(event.DevPath),
 		}
 
 		if _, err := dm.RegisterDevice(
@@ -560,6 +819,20 @@ func (dm *DeviceManager) StartAutoDiscovery() error {
 	return nil
 }
 
+// StopAutoDiscovery stops hotplug detection started by StartAutoDiscovery.
+// Devices already registered stay registered.
+func (dm *DeviceManager) StopAutoDiscovery() error {
+	if dm.udevMonitor == nil {
+		return nil
+	}
+
+	err := dm.udevMonitor.Stop()
+	dm.udevMonitor = nil
+
+	dm.logger.Info("Device auto-discovery stopped")
+	return err
+}
+
 // processUdevEvents processes hotplug events from udev
 func (dm *DeviceManager) processUdevEvents() {
 	for event := range dm.udevMonitor.Events() {
@@ -581,6 +854,7 @@ func (dm *DeviceManager) processUdevEvents() {
 			for k, v := range caps {
 				dbuscaps[k] = dbus.MakeVariant(v)
 			}
+			dbuscaps["devpath"] = dbus.MakeVariant(event.DevPath) // keys the inventory
 
 			deviceID, err := dm.RegisterDevice(deviceType, event.DevName, deviceName, dbuscaps, "ie.fio.OllamaProxy.System")
 			if err != nil {
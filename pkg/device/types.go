@@ -17,6 +17,8 @@ const (
 	DeviceTypeSpeaker    DeviceType = "speaker"
 	DeviceTypeKeyboard   DeviceType = "keyboard"
 	DeviceTypeMouse      DeviceType = "mouse"
+	DeviceTypeNPU        DeviceType = "npu"
+	DeviceTypeGPU        DeviceType = "gpu"
 )
 
 // DeviceState represents the current state of a device
@@ -39,7 +41,17 @@ type Device struct {
 	State        DeviceState            `json:"state"`
 	RegisteredAt time.Time              `json:"registered_at"`
 	LastUsedAt   time.Time              `json:"last_used_at,omitempty"`
-	mu           sync.RWMutex
+
+	// From the inventory: stable across restarts, unlike ID
+	InventoryKey string `json:"inventory_key,omitempty"`
+	FriendlyName string `json:"friendly_name,omitempty"` // user-assigned, e.g. "eGPU dock RTX 4070"
+	Notes        string `json:"notes,omitempty"`
+
+	// Latest sample, accelerators only
+	Hardware    string       `json:"hardware,omitempty"` // routing hardware class, e.g. npu
+	Utilization *Utilization `json:"utilization,omitempty"`
+
+	mu sync.RWMutex
 }
 
 // AccessGrant represents permission for a client to access a device
@@ -73,6 +85,24 @@ func (d *Device) UpdateLastUsed() {
 	d.LastUsedAt = time.Now()
 }
 
+// applyInventory copies the user-assigned name and notes from e (thread-safe)
+func (d *Device) applyInventory(e InventoryEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.InventoryKey = e.Key
+	d.FriendlyName = e.FriendlyName
+	d.Notes = e.Notes
+}
+
+// setUtilization records an accelerator's latest sample (thread-safe)
+func (d *Device) setUtilization(s AcceleratorSample) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Hardware = s.Hardware
+	u := s.Utilization
+	d.Utilization = &u
+}
+
 // ToDBusVariant converts the device to a D-Bus variant map
 func (d *Device) ToDBusVariant() map[string]dbus.Variant {
 	d.mu.RLock()
@@ -85,12 +115,24 @@ func (d *Device) ToDBusVariant() map[string]dbus.Variant {
 		"Path":         dbus.MakeVariant(d.Path),
 		"State":        dbus.MakeVariant(string(d.State)),
 		"RegisteredAt": dbus.MakeVariant(d.RegisteredAt.Unix()),
+		"InventoryKey": dbus.MakeVariant(d.InventoryKey),
+		"FriendlyName": dbus.MakeVariant(d.FriendlyName),
+		"Notes":        dbus.MakeVariant(d.Notes),
 	}
 
 	if !d.LastUsedAt.IsZero() {
 		result["LastUsedAt"] = dbus.MakeVariant(d.LastUsedAt.Unix())
 	}
 
+	if d.Utilization != nil {
+		result["Hardware"] = dbus.MakeVariant(d.Hardware)
+		result["Utilization"] = dbus.MakeVariant(d.Utilization.Percent)
+		result["MemoryUsedBytes"] = dbus.MakeVariant(d.Utilization.MemoryUsedBytes)
+		result["MemoryTotalBytes"] = dbus.MakeVariant(d.Utilization.MemoryTotalBytes)
+		result["UtilizationSource"] = dbus.MakeVariant(d.Utilization.Source)
+		result["UtilizationSampledAt"] = dbus.MakeVariant(d.Utilization.SampledAt.Unix())
+	}
+
 	// Convert capabilities to variant
 	caps := make(map[string]dbus.Variant)
 	for k, v := range d.Capabilities {
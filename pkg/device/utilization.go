@@ -0,0 +1,532 @@
+package device
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+)
+
+// DefaultUtilizationInterval is how often accelerators are sampled
+const DefaultUtilizationInterval = 5 * time.Second
+
+// Utilization is how busy an accelerator was over the last sampling
+// interval
+type Utilization struct {
+	Percent          float64   `json:"percent"`                      // 0-100
+	MemoryUsedBytes  uint64    `json:"memory_used_bytes,omitempty"`  // 0 when not reported
+	MemoryTotalBytes uint64    `json:"memory_total_bytes,omitempty"` // 0 when not reported
+	Source           string    `json:"source"`                       // sampler: nvml, level-zero or sysfs
+	SampledAt        time.Time `json:"sampled_at"`
+}
+
+// AcceleratorSample is one accelerator's utilization
+type AcceleratorSample struct {
+	DevPath  string     // sysfs device path, e.g. /devices/pci0000:00/0000:00:0b.0
+	Type     DeviceType // DeviceTypeNPU or DeviceTypeGPU
+	Hardware string     // routing hardware class: npu, igpu, nvidia or gpu
+	Name     string
+	Path     string // device node, e.g. /dev/accel/accel0
+	Utilization
+}
+
+// UtilizationSampler reads the utilization of the accelerators one
+// interface can see
+type UtilizationSampler interface {
+	Name() string
+	// Sample returns no samples, not an error, when there is nothing to
+	// sample; an error means the interface itself is unavailable
+	Sample(ctx context.Context) ([]AcceleratorSample, error)
+}
+
+// commandRunner runs a command and returns its standard output
+type commandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// UtilizationMonitor samples accelerators periodically and keeps the
+// latest sample of each
+type UtilizationMonitor struct {
+	samplers []UtilizationSampler
+	interval time.Duration
+
+	mu        sync.RWMutex
+	samples   map[string]AcceleratorSample // devpath -> latest
+	listeners []func([]AcceleratorSample)
+	failing   map[string]bool // samplers whose last round failed, logged once
+}
+
+// NewUtilizationMonitor creates a monitor polling samplers every interval
+// (0 = DefaultUtilizationInterval). With no samplers it uses NVML, Level
+// Zero and sysfs, in that order; a device seen by several keeps the first
+// sampler's reading.
+func NewUtilizationMonitor(interval time.Duration, samplers ...UtilizationSampler) *UtilizationMonitor {
+	if interval <= 0 {
+		interval = DefaultUtilizationInterval
+	}
+	if len(samplers) == 0 {
+		samplers = []UtilizationSampler{
+			&NVMLSampler{run: runCommand, sysRoot: "/sys"},
+			&LevelZeroSampler{run: runCommand, sysRoot: "/sys"},
+			NewSysfsSampler("/sys"),
+		}
+	}
+	return &UtilizationMonitor{
+		samplers: samplers,
+		interval: interval,
+		samples:  make(map[string]AcceleratorSample),
+		failing:  make(map[string]bool),
+	}
+}
+
+// OnSample registers fn to receive every round of samples
+func (m *UtilizationMonitor) OnSample(fn func([]AcceleratorSample)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Run samples until ctx is cancelled
+func (m *UtilizationMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		middleware.Safe(middleware.ScopeBackground, "device-utilization", func() { m.SampleNow(ctx) })
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SampleNow runs one round of sampling and returns its samples
+func (m *UtilizationMonitor) SampleNow(ctx context.Context) []AcceleratorSample {
+	var round []AcceleratorSample
+	seen := make(map[string]bool)
+	for _, sampler := range m.samplers {
+		samples, err := sampler.Sample(ctx)
+		m.noteFailure(sampler.Name(), err)
+		for _, s := range samples {
+			if seen[s.DevPath] {
+				continue
+			}
+			seen[s.DevPath] = true
+			round = append(round, s)
+		}
+	}
+
+	m.mu.Lock()
+	for _, s := range round {
+		m.samples[s.DevPath] = s
+	}
+	listeners := m.listeners
+	m.mu.Unlock()
+
+	for _, s := range round {
+		metrics.SetDeviceUtilization(s.DevPath, s.Name, s.Hardware, s.Percent)
+		if s.MemoryTotalBytes > 0 {
+			metrics.SetDeviceMemoryUsed(s.DevPath, s.Name, s.Hardware, s.MemoryUsedBytes)
+		}
+	}
+	for _, fn := range listeners {
+		fn(round)
+	}
+	return round
+}
+
+// noteFailure logs a sampler becoming unavailable or recovering, once
+func (m *UtilizationMonitor) noteFailure(sampler string, err error) {
+	m.mu.Lock()
+	was := m.failing[sampler]
+	m.failing[sampler] = err != nil
+	m.mu.Unlock()
+
+	if logging.Logger == nil || was == (err != nil) {
+		return
+	}
+	if err != nil {
+		logging.Logger.Debug("Utilization sampler unavailable", zap.String("sampler", sampler), zap.Error(err))
+	} else {
+		logging.Logger.Debug("Utilization sampler recovered", zap.String("sampler", sampler))
+	}
+}
+
+// Samples returns the latest sample of every accelerator, ordered by
+// device path
+func (m *UtilizationMonitor) Samples() []AcceleratorSample {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]AcceleratorSample, 0, len(m.samples))
+	for _, s := range m.samples {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].DevPath < list[j].DevPath })
+	return list
+}
+
+// HardwareUtilization is the mean utilization of the accelerators of a
+// hardware class, ignoring samples older than three intervals. It reports
+// false when there are none.
+func (m *UtilizationMonitor) HardwareUtilization(hardware string) (float64, bool) {
+	cutoff := time.Now().Add(-3 * m.interval)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sum, n := 0.0, 0
+	for _, s := range m.samples {
+		if s.Hardware == hardware && s.SampledAt.After(cutoff) {
+			sum += s.Percent
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// HardwareMemoryFree is the free memory, in bytes, of the roomiest
+// accelerator of a hardware class, since a model is loaded onto one. It
+// ignores samples older than three intervals or without a memory total and
+// reports false when there are none.
+func (m *UtilizationMonitor) HardwareMemoryFree(hardware string) (uint64, bool) {
+	cutoff := time.Now().Add(-3 * m.interval)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var free uint64
+	found := false
+	for _, s := range m.samples {
+		if s.Hardware != hardware || !s.SampledAt.After(cutoff) || s.MemoryTotalBytes == 0 {
+			continue
+		}
+		var f uint64
+		if s.MemoryTotalBytes > s.MemoryUsedBytes {
+			f = s.MemoryTotalBytes - s.MemoryUsedBytes
+		}
+		if !found || f > free {
+			free = f
+		}
+		found = true
+	}
+	return free, found
+}
+
+// NVMLSampler reads NVIDIA GPUs through nvidia-smi, which reports NVML's
+// utilization counters
+type NVMLSampler struct {
+	run     commandRunner
+	sysRoot string
+}
+
+func (s *NVMLSampler) Name() string { return "nvml" }
+
+func (s *NVMLSampler) Sample(ctx context.Context) ([]AcceleratorSample, error) {
+	out, err := s.run(ctx, "nvidia-smi",
+		"--query-gpu=index,pci.bus_id,name,utilization.gpu,memory.used,memory.total",
+		"--format=csv,noheader,nounits")
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi failed: %w", err)
+	}
+
+	now := time.Now()
+	var samples []AcceleratorSample
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) < 6 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		percent, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			continue // [N/A] on GPUs without utilization counters
+		}
+		usedMiB, _ := strconv.ParseUint(fields[4], 10, 64)
+		totalMiB, _ := strconv.ParseUint(fields[5], 10, 64)
+		samples = append(samples, AcceleratorSample{
+			DevPath:  pciDevPath(s.sysRoot, fields[1]),
+			Type:     DeviceTypeGPU,
+			Hardware: "nvidia",
+			Name:     fields[2],
+			Path:     "/dev/nvidia" + fields[0],
+			Utilization: Utilization{
+				Percent:          percent,
+				MemoryUsedBytes:  usedMiB << 20,
+				MemoryTotalBytes: totalMiB << 20,
+				Source:           s.Name(),
+				SampledAt:        now,
+			},
+		})
+	}
+	return samples, nil
+}
+
+// LevelZeroSampler reads Intel GPUs through xpu-smi, which reports Level
+// Zero Sysman engine and memory counters
+type LevelZeroSampler struct {
+	run     commandRunner
+	sysRoot string
+}
+
+func (s *LevelZeroSampler) Name() string { return "level-zero" }
+
+// xpuDiscovery is the output of xpu-smi discovery -j
+type xpuDiscovery struct {
+	DeviceList []struct {
+		DeviceID   int    `json:"device_id"`
+		DeviceName string `json:"device_name"`
+		PCIAddress string `json:"pci_bdf_address"`
+	} `json:"device_list"`
+}
+
+func (s *LevelZeroSampler) Sample(ctx context.Context) ([]AcceleratorSample, error) {
+	out, err := s.run(ctx, "xpu-smi", "discovery", "-j")
+	if err != nil {
+		return nil, fmt.Errorf("xpu-smi failed: %w", err)
+	}
+	var discovery xpuDiscovery
+	if err := json.Unmarshal(out, &discovery); err != nil {
+		return nil, fmt.Errorf("unexpected xpu-smi discovery output: %w", err)
+	}
+
+	var samples []AcceleratorSample
+	for _, dev := range discovery.DeviceList {
+		// Metrics 0 and 18: GPU utilization (%) and memory used (MiB)
+		out, err := s.run(ctx, "xpu-smi", "dump", "-d", strconv.Itoa(dev.DeviceID), "-m", "0,18", "-n", "1")
+		if err != nil {
+			continue
+		}
+		percent, usedMiB, ok := parseXPUDump(out)
+		if !ok {
+			continue
+		}
+		samples = append(samples, AcceleratorSample{
+			DevPath:  pciDevPath(s.sysRoot, dev.PCIAddress),
+			Type:     DeviceTypeGPU,
+			Hardware: "igpu",
+			Name:     dev.DeviceName,
+			Path:     "/dev/dri/card" + strconv.Itoa(dev.DeviceID),
+			Utilization: Utilization{
+				Percent:         percent,
+				MemoryUsedBytes: uint64(usedMiB * (1 << 20)),
+				Source:          s.Name(),
+				SampledAt:       time.Now(),
+			},
+		})
+	}
+	return samples, nil
+}
+
+// parseXPUDump reads the utilization and memory columns of xpu-smi dump
+// CSV output, from its last row
+func parseXPUDump(out []byte) (percent, memoryMiB float64, ok bool) {
+	r := csv.NewReader(strings.NewReader(string(out)))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil || len(rows) < 2 {
+		return 0, 0, false
+	}
+	header, last := rows[0], rows[len(rows)-1]
+	for i, column := range header {
+		if i >= len(last) {
+			break
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(last[i]), 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(column, "GPU Utilization"):
+			percent, ok = value, true
+		case strings.HasPrefix(column, "GPU Memory Used"):
+			memoryMiB = value
+		}
+	}
+	return percent, memoryMiB, ok
+}
+
+// SysfsSampler reads utilization the kernel exposes in sysfs: busy time of
+// Intel NPUs (intel_vpu, npu_busy_time_us) and busy percentage of GPUs
+// whose driver reports it (gpu_busy_percent, e.g. amdgpu)
+type SysfsSampler struct {
+	root string
+	now  func() time.Time
+
+	mu      sync.Mutex
+	npuBusy map[string]npuBusyReading // devpath -> previous reading
+}
+
+type npuBusyReading struct {
+	busyUs uint64
+	at     time.Time
+}
+
+// NewSysfsSampler creates a sampler reading the sysfs tree at root
+func NewSysfsSampler(root string) *SysfsSampler {
+	return &SysfsSampler{root: root, now: time.Now, npuBusy: make(map[string]npuBusyReading)}
+}
+
+func (s *SysfsSampler) Name() string { return "sysfs" }
+
+func (s *SysfsSampler) Sample(ctx context.Context) ([]AcceleratorSample, error) {
+	samples := s.sampleNPUs()
+	return append(samples, s.sampleGPUs()...), nil
+}
+
+// sampleNPUs turns the NPUs' cumulative busy time into the busy share
+// since the previous sample. The first sample of an NPU only records its
+// busy time.
+func (s *SysfsSampler) sampleNPUs() []AcceleratorSample {
+	matches, _ := filepath.Glob(filepath.Join(s.root, "class/accel/accel*/device/npu_busy_time_us"))
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var samples []AcceleratorSample
+	for _, busyPath := range matches {
+		busyUs, err := readUint(busyPath)
+		if err != nil {
+			continue
+		}
+		accel := filepath.Base(filepath.Dir(filepath.Dir(busyPath)))
+		devPath := s.devPath(filepath.Dir(busyPath))
+
+		prev, ok := s.npuBusy[devPath]
+		s.npuBusy[devPath] = npuBusyReading{busyUs: busyUs, at: now}
+		elapsed := now.Sub(prev.at)
+		if !ok || elapsed <= 0 || busyUs < prev.busyUs {
+			continue
+		}
+		percent := float64(busyUs-prev.busyUs) / float64(elapsed.Microseconds()) * 100
+		samples = append(samples, AcceleratorSample{
+			DevPath:  devPath,
+			Type:     DeviceTypeNPU,
+			Hardware: "npu",
+			Name:     "Intel NPU",
+			Path:     "/dev/accel/" + accel,
+			Utilization: Utilization{
+				Percent:   min(percent, 100),
+				Source:    s.Name(),
+				SampledAt: now,
+			},
+		})
+	}
+	return samples
+}
+
+func (s *SysfsSampler) sampleGPUs() []AcceleratorSample {
+	matches, _ := filepath.Glob(filepath.Join(s.root, "class/drm/card*/device/gpu_busy_percent"))
+	now := s.now()
+
+	var samples []AcceleratorSample
+	for _, busyPath := range matches {
+		card := filepath.Base(filepath.Dir(filepath.Dir(busyPath)))
+		if strings.Contains(card, "-") {
+			continue // connector, e.g. card0-DP-1
+		}
+		percent, err := readUint(busyPath)
+		if err != nil {
+			continue
+		}
+		dir := filepath.Dir(busyPath)
+		hardware, name := gpuVendor(dir)
+		sample := AcceleratorSample{
+			DevPath:  s.devPath(dir),
+			Type:     DeviceTypeGPU,
+			Hardware: hardware,
+			Name:     name,
+			Path:     "/dev/dri/" + card,
+			Utilization: Utilization{
+				Percent:   float64(percent),
+				Source:    s.Name(),
+				SampledAt: now,
+			},
+		}
+		if used, err := readUint(filepath.Join(dir, "mem_info_vram_used")); err == nil {
+			sample.MemoryUsedBytes = used
+		}
+		if total, err := readUint(filepath.Join(dir, "mem_info_vram_total")); err == nil {
+			sample.MemoryTotalBytes = total
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// devPath resolves a sysfs device directory to the path udev reports,
+// relative to the sysfs root
+func (s *SysfsSampler) devPath(dir string) string {
+	return sysDevPath(s.root, dir)
+}
+
+// gpuVendor names a GPU's hardware class from its PCI vendor ID
+func gpuVendor(deviceDir string) (hardware, name string) {
+	vendor, _ := os.ReadFile(filepath.Join(deviceDir, "vendor"))
+	switch strings.TrimSpace(string(vendor)) {
+	case "0x8086":
+		return "igpu", "Intel GPU"
+	case "0x10de":
+		return "nvidia", "NVIDIA GPU"
+	case "0x1002":
+		return "gpu", "AMD GPU"
+	default:
+		return "gpu", "GPU"
+	}
+}
+
+// pciDevPath resolves a PCI address such as 00000000:01:00.0 to its sysfs
+// device path, or returns the normalized address when it can't
+func pciDevPath(sysRoot, address string) string {
+	address = strings.ToLower(address)
+	if len(address) > 12 {
+		address = address[len(address)-12:] // 8-digit domain from nvidia-smi
+	}
+	if address == "" {
+		return ""
+	}
+	return sysDevPath(sysRoot, filepath.Join(sysRoot, "bus/pci/devices", address))
+}
+
+// sysDevPath resolves symlinks in a sysfs path and strips the sysfs root,
+// e.g. /sys/class/accel/accel0/device -> /devices/pci0000:00/0000:00:0b.0
+func sysDevPath(sysRoot, path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if root, err := filepath.EvalSymlinks(sysRoot); err == nil {
+		sysRoot = root
+	}
+	if rel, err := filepath.Rel(sysRoot, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return "/" + rel
+	}
+	return path
+}
+
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,170 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSysfs creates file under root with content
+func writeSysfs(t *testing.T, root, file, content string) {
+	t.Helper()
+	path := filepath.Join(root, file)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSysfsSampler_NPUBusyTime(t *testing.T) {
+	root := t.TempDir()
+	devDir := "devices/pci0000:00/0000:00:0b.0"
+	writeSysfs(t, root, devDir+"/npu_busy_time_us", "1000000\n")
+	os.MkdirAll(filepath.Join(root, "class/accel/accel0"), 0o755)
+	if err := os.Symlink(filepath.Join(root, devDir), filepath.Join(root, "class/accel/accel0/device")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSysfsSampler(root)
+	now := time.Unix(1000, 0)
+	s.now = func() time.Time { return now }
+
+	// The first reading has nothing to compare with
+	if samples, _ := s.Sample(context.Background()); len(samples) != 0 {
+		t.Fatalf("Expected no samples from the first reading, got %+v", samples)
+	}
+
+	// Busy for 1.5s of the next 2s
+	now = now.Add(2 * time.Second)
+	writeSysfs(t, root, devDir+"/npu_busy_time_us", "2500000\n")
+	samples, err := s.Sample(context.Background())
+	if err != nil || len(samples) != 1 {
+		t.Fatalf("Expected one sample, got %+v, %v", samples, err)
+	}
+	got := samples[0]
+	if got.Percent != 75 || got.Hardware != "npu" || got.Type != DeviceTypeNPU {
+		t.Errorf("Expected the NPU 75%% busy, got %+v", got)
+	}
+	if got.DevPath != "/"+devDir || got.Path != "/dev/accel/accel0" {
+		t.Errorf("Expected udev's device path, got %s and %s", got.DevPath, got.Path)
+	}
+}
+
+func TestSysfsSampler_GPUBusyPercent(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, "class/drm/card1/device/gpu_busy_percent", "42\n")
+	writeSysfs(t, root, "class/drm/card1/device/vendor", "0x1002\n")
+	writeSysfs(t, root, "class/drm/card1/device/mem_info_vram_used", "1073741824\n")
+	writeSysfs(t, root, "class/drm/card1/device/mem_info_vram_total", "8589934592\n")
+	writeSysfs(t, root, "class/drm/card1-DP-1/device/gpu_busy_percent", "42\n")
+
+	samples, _ := NewSysfsSampler(root).Sample(context.Background())
+	if len(samples) != 1 {
+		t.Fatalf("Expected one GPU, got %+v", samples)
+	}
+	got := samples[0]
+	if got.Percent != 42 || got.Hardware != "gpu" || got.MemoryUsedBytes != 1<<30 || got.MemoryTotalBytes != 8<<30 {
+		t.Errorf("Unexpected sample %+v", got)
+	}
+}
+
+func TestNVMLSampler_ParsesNvidiaSMI(t *testing.T) {
+	s := &NVMLSampler{
+		sysRoot: t.TempDir(),
+		run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("0, 00000000:01:00.0, NVIDIA GeForce RTX 4070, 63, 2048, 12282\n1, 00000000:02:00.0, Tesla K80, [N/A], 0, 11441\n"), nil
+		},
+	}
+	samples, err := s.Sample(context.Background())
+	if err != nil || len(samples) != 1 {
+		t.Fatalf("Expected one GPU with utilization, got %+v, %v", samples, err)
+	}
+	got := samples[0]
+	if got.Percent != 63 || got.Hardware != "nvidia" || got.MemoryUsedBytes != 2048<<20 || got.Path != "/dev/nvidia0" {
+		t.Errorf("Unexpected sample %+v", got)
+	}
+	if got.DevPath != "/bus/pci/devices/0000:01:00.0" {
+		t.Errorf("Expected the PCI address as device path, got %s", got.DevPath)
+	}
+}
+
+func TestLevelZeroSampler_ParsesXPUSMI(t *testing.T) {
+	s := &LevelZeroSampler{
+		sysRoot: t.TempDir(),
+		run: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if args[0] == "discovery" {
+				return []byte(`{"device_list":[{"device_id":0,"device_name":"Intel(R) Arc(TM) A770 Graphics","pci_bdf_address":"0000:03:00.0"}]}`), nil
+			}
+			return []byte("Timestamp, DeviceId, GPU Utilization (%), GPU Memory Used (MiB)\n06:14:46.000,    0, 37.50, 512.00\n"), nil
+		},
+	}
+	samples, err := s.Sample(context.Background())
+	if err != nil || len(samples) != 1 {
+		t.Fatalf("Expected one GPU, got %+v, %v", samples, err)
+	}
+	if got := samples[0]; got.Percent != 37.5 || got.Hardware != "igpu" || got.MemoryUsedBytes != 512<<20 {
+		t.Errorf("Unexpected sample %+v", got)
+	}
+}
+
+type fixedSampler struct {
+	name    string
+	samples []AcceleratorSample
+	err     error
+}
+
+func (f *fixedSampler) Name() string { return f.name }
+func (f *fixedSampler) Sample(ctx context.Context) ([]AcceleratorSample, error) {
+	return f.samples, f.err
+}
+
+func TestUtilizationMonitor_HardwareUtilization(t *testing.T) {
+	now := time.Now()
+	gpu := func(devPath string, percent float64, source string) AcceleratorSample {
+		return AcceleratorSample{DevPath: devPath, Hardware: "nvidia", Utilization: Utilization{Percent: percent, Source: source, SampledAt: now}}
+	}
+	m := NewUtilizationMonitor(time.Second,
+		&fixedSampler{name: "nvml", samples: []AcceleratorSample{gpu("/gpu0", 80, "nvml"), gpu("/gpu1", 40, "nvml")}},
+		&fixedSampler{name: "broken", err: fmt.Errorf("not installed")},
+		&fixedSampler{name: "sysfs", samples: []AcceleratorSample{gpu("/gpu0", 10, "sysfs")}},
+	)
+
+	var received []AcceleratorSample
+	m.OnSample(func(samples []AcceleratorSample) { received = samples })
+	m.SampleNow(context.Background())
+
+	if len(received) != 2 || received[0].Source != "nvml" {
+		t.Errorf("Expected each GPU once, from the first sampler, got %+v", received)
+	}
+	if percent, ok := m.HardwareUtilization("nvidia"); !ok || percent != 60 {
+		t.Errorf("Expected the mean of both GPUs, got %v, %v", percent, ok)
+	}
+	if _, ok := m.HardwareUtilization("npu"); ok {
+		t.Error("Expected no utilization for unsampled hardware")
+	}
+}
+
+func TestUtilizationMonitor_HardwareMemoryFree(t *testing.T) {
+	now := time.Now()
+	gpu := func(devPath string, used, total uint64) AcceleratorSample {
+		return AcceleratorSample{DevPath: devPath, Hardware: "nvidia", Utilization: Utilization{MemoryUsedBytes: used, MemoryTotalBytes: total, SampledAt: now}}
+	}
+	m := NewUtilizationMonitor(time.Second, &fixedSampler{name: "nvml", samples: []AcceleratorSample{
+		gpu("/gpu0", 20<<30, 24<<30),
+		gpu("/gpu1", 2<<30, 12<<30),
+		{DevPath: "/npu0", Hardware: "npu", Utilization: Utilization{Percent: 30, SampledAt: now}},
+	}})
+	m.SampleNow(context.Background())
+
+	if free, ok := m.HardwareMemoryFree("nvidia"); !ok || free != 10<<30 {
+		t.Errorf("Expected the roomiest GPU's 10 GiB free, got %d, %v", free, ok)
+	}
+	if _, ok := m.HardwareMemoryFree("npu"); ok {
+		t.Error("Expected no free memory for hardware that doesn't report it")
+	}
+}
@@ -10,6 +10,18 @@ type Config struct {
 
 	// Backend-specific model configuration
 	BackendModels map[string]BackendModelConfig `yaml:"backend_models"`
+
+	MeetingGuard MeetingGuardConfig `yaml:"meeting_guard"`
+}
+
+// MeetingGuardConfig defers best-effort requests from the backends serving
+// a live meeting bridge so batch work cannot add latency to the meeting
+type MeetingGuardConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Best-effort requests still allowed to run concurrently on each
+	// meeting backend (0 = defer all of them)
+	MaxLowPriorityConcurrent int `yaml:"max_low_priority_concurrent"`
 }
 
 // AudioConfig holds audio device configuration
@@ -119,6 +131,10 @@ func DefaultConfig() *Config {
 				},
 			},
 		},
+		MeetingGuard: MeetingGuardConfig{
+			Enabled:                  false,
+			MaxLowPriorityConcurrent: 0,
+		},
 		BackendModels: map[string]BackendModelConfig{
 			"ollama-npu": {
 				STTModel: "whisper-tiny",
@@ -30,6 +30,9 @@ type VirtualDeviceManager struct {
 	videoBridges    map[string]*VideoBridge         // backend -> video bridge
 	meetingBridges  map[string]*MeetingAudioBridge  // backend -> meeting bridge
 
+	// Notified when a meeting bridge starts or stops
+	meetingStateHandler MeetingStateHandler
+
 	// Backend registry (for meeting bridges)
 	backends        map[string]interface{} // backend ID -> backend interface
 
@@ -327,6 +330,9 @@ func (vdm *VirtualDeviceManager) Stop() error {
 				zap.Error(err),
 			)
 		}
+		if vdm.meetingStateHandler != nil {
+			vdm.meetingStateHandler(backendID, nil, false)
+		}
 	}
 
 	// Stop all audio bridges
@@ -460,6 +466,20 @@ func (vdm *VirtualDeviceManager) RegisterBackend(backendID string, backend inter
 	)
 }
 
+// MeetingStateHandler is notified when a meeting bridge starts (active) or
+// stops. servingBackends lists the backends doing STT/TTS and LLM work for
+// the bridge and is nil when it stops. It is called with the manager locked
+// and must not call back into the VirtualDeviceManager.
+type MeetingStateHandler func(bridgeBackendID string, servingBackends []string, active bool)
+
+// SetMeetingStateHandler registers the handler notified of meeting bridge
+// state changes. Set it before starting any bridge.
+func (vdm *VirtualDeviceManager) SetMeetingStateHandler(handler MeetingStateHandler) {
+	vdm.mu.Lock()
+	defer vdm.mu.Unlock()
+	vdm.meetingStateHandler = handler
+}
+
 // StartMeetingBridge starts a meeting audio bridge for a specific backend
 // This enables the Google Meet AI assistant functionality
 func (vdm *VirtualDeviceManager) StartMeetingBridge(backendID string) error {
@@ -597,6 +617,14 @@ func (vdm *VirtualDeviceManager) StartMeetingBridge(backendID string) error {
 		zap.String("microphone_sink", micDevice.Name),
 	)
 
+	if vdm.meetingStateHandler != nil {
+		serving := []string{backendID}
+		if llmBackendID != backendID {
+			serving = append(serving, llmBackendID)
+		}
+		vdm.meetingStateHandler(backendID, serving, true)
+	}
+
 	return nil
 }
 
@@ -620,6 +648,10 @@ func (vdm *VirtualDeviceManager) StopMeetingBridge(backendID string) error {
 		zap.String("backend_id", backendID),
 	)
 
+	if vdm.meetingStateHandler != nil {
+		vdm.meetingStateHandler(backendID, nil, false)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,209 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces secrets removed from support bundle files
+const Redacted = "REDACTED"
+
+// Bundle is a support bundle: a zip archive of files collected from a proxy
+// for a bug report. Every file added or skipped is listed in MANIFEST.txt,
+// so a partial bundle still shows what is missing and why.
+type Bundle struct {
+	zw      *zip.Writer
+	created time.Time
+	entries []bundleEntry
+	err     error // first write error
+}
+
+type bundleEntry struct {
+	name string
+	size int
+	err  error // why the file was skipped, nil when added
+}
+
+// NewBundle starts a support bundle written to w
+func NewBundle(w io.Writer) *Bundle {
+	return &Bundle{zw: zip.NewWriter(w), created: time.Now()}
+}
+
+// Add writes a file to the bundle
+func (b *Bundle) Add(name string, data []byte) {
+	if b.err != nil {
+		return
+	}
+	f, err := b.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: b.created})
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err != nil {
+		b.err = fmt.Errorf("writing %s: %w", name, err)
+		return
+	}
+	b.entries = append(b.entries, bundleEntry{name: name, size: len(data)})
+}
+
+// Skip records that a file could not be collected
+func (b *Bundle) Skip(name string, err error) {
+	b.entries = append(b.entries, bundleEntry{name: name, err: err})
+}
+
+// Close writes the manifest and finishes the archive
+func (b *Bundle) Close() error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Ollama Proxy support bundle, created %s\n\n", b.created.UTC().Format(time.RFC3339))
+	for _, e := range b.entries {
+		if e.err != nil {
+			fmt.Fprintf(&sb, "  [MISSING] %s: %v\n", e.name, e.err)
+		} else {
+			fmt.Fprintf(&sb, "  [ OK ]    %s (%d bytes)\n", e.name, e.size)
+		}
+	}
+	b.Add("MANIFEST.txt", []byte(sb.String()))
+
+	if err := b.zw.Close(); err != nil && b.err == nil {
+		b.err = err
+	}
+	return b.err
+}
+
+// secretKeys are configuration keys whose values are always redacted
+var secretKeys = map[string]bool{
+	"api_key":         true,
+	"password":        true,
+	"secret":          true,
+	"token":           true,
+	"prompt_hash_key": true,
+}
+
+// SanitizeConfig redacts secrets from a YAML configuration file: API keys
+// (the keys of server.auth.api_keys), request headers, environment values
+// of pipeline tools and containers, credentials embedded in URLs, webhook
+// URLs past their host, and values of keys such as password or token.
+// Comments and layout are kept.
+func SanitizeConfig(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	sanitizeNode(&doc, "", false)
+	return yaml.Marshal(&doc)
+}
+
+// sanitizeNode redacts secrets below node, the value of key. inWebhooks is
+// set below monitoring.alerting.webhooks.
+func sanitizeNode(node *yaml.Node, key string, inWebhooks bool) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			sanitizeNode(child, key, inWebhooks)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			switch {
+			case key == "api_keys":
+				// API keys are the mapping's keys
+				k.Value = fmt.Sprintf("%s-%d", Redacted, i/2+1)
+				k.Style = 0
+				sanitizeNode(v, k.Value, inWebhooks)
+			case k.Value == "headers":
+				redactScalars(v)
+			case k.Value == "env":
+				redactEnv(v)
+			case isSecretKey(k.Value):
+				redactScalars(v)
+			default:
+				sanitizeNode(v, k.Value, inWebhooks || k.Value == "webhooks")
+			}
+		}
+	case yaml.ScalarNode:
+		node.Value = sanitizeURL(node.Value, inWebhooks)
+	}
+}
+
+func isSecretKey(key string) bool {
+	return secretKeys[key] || strings.HasSuffix(key, "_token") ||
+		strings.HasSuffix(key, "_secret") || strings.HasSuffix(key, "_password")
+}
+
+// redactScalars replaces every scalar value below node
+func redactScalars(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Value != "" {
+			node.Value = Redacted
+			node.Tag = "!!str"
+			node.Style = yaml.DoubleQuotedStyle
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			redactScalars(node.Content[i])
+		}
+	default:
+		for _, child := range node.Content {
+			redactScalars(child)
+		}
+	}
+}
+
+// redactEnv redacts the values of KEY=value entries below node, keeping
+// the variable names
+func redactEnv(node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		redactScalars(node)
+		return
+	}
+	for _, entry := range node.Content {
+		name, _, ok := strings.Cut(entry.Value, "=")
+		if entry.Kind != yaml.ScalarNode || !ok {
+			redactScalars(entry)
+			continue
+		}
+		entry.Value = name + "=" + Redacted
+		entry.Tag = "!!str"
+		entry.Style = yaml.DoubleQuotedStyle
+	}
+}
+
+// sanitizeURL strips credentials and query strings from an http(s) URL, and
+// with hostOnly everything after the host, e.g. a chat webhook's token path
+func sanitizeURL(value string, hostOnly bool) string {
+	if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return Redacted
+	}
+	if u.User == nil && u.RawQuery == "" && (!hostOnly || u.Path == "" || u.Path == "/") {
+		return value
+	}
+	if u.User != nil {
+		u.User = url.User(Redacted)
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = Redacted
+	}
+	if hostOnly && u.Path != "" && u.Path != "/" {
+		u.Path = "/" + Redacted
+	}
+	return u.String()
+}
+
+// bearerToken matches credentials that may appear in log lines
+var bearerToken = regexp.MustCompile(`(?i)(bearer\s+|api[_-]?key["=:\s]+)[A-Za-z0-9._~+/=-]+`)
+
+// SanitizeLog redacts bearer tokens and API keys from log output
+func SanitizeLog(data []byte) []byte {
+	return bearerToken.ReplaceAll(data, []byte("${1}"+Redacted))
+}
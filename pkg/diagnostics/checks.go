@@ -0,0 +1,304 @@
+package diagnostics
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/thermal"
+	"github.com/godbus/dbus/v5"
+)
+
+// BackendLister is satisfied by *router.Router
+type BackendLister interface {
+	ListBackends() []backends.Backend
+}
+
+// BackendReachability health-checks every registered backend
+func BackendReachability(lister BackendLister) CheckFunc {
+	return func(ctx context.Context) []Result {
+		list := lister.ListBackends()
+		if len(list) == 0 {
+			return []Result{{
+				Name:    "backends",
+				Status:  StatusFail,
+				Message: "no backends registered",
+				Hint:    "enable at least one backend in config.yaml",
+			}}
+		}
+
+		// Health checks run concurrently so one slow backend doesn't starve the rest
+		results := make([]Result, len(list))
+		var wg sync.WaitGroup
+		for i, b := range list {
+			wg.Add(1)
+			go func(i int, b backends.Backend) {
+				defer wg.Done()
+				start := time.Now()
+				res := Result{Name: b.ID()}
+				if err := b.HealthCheck(ctx); err != nil {
+					res.Status = StatusFail
+					res.Message = fmt.Sprintf("unreachable: %v", err)
+					res.Hint = fmt.Sprintf("check that the %s service for %s is running and its endpoint is correct", b.Type(), b.ID())
+				} else {
+					res.Status = StatusOK
+					res.Message = fmt.Sprintf("healthy (%s on %s)", b.Type(), b.Hardware())
+				}
+				res.DurationMs = time.Since(start).Milliseconds()
+				results[i] = res
+			}(i, b)
+		}
+		wg.Wait()
+		return results
+	}
+}
+
+// ModelAvailability checks each backend lists models, and that the expected
+// models (backend ID -> model names, e.g. preferred models) are present
+func ModelAvailability(lister BackendLister, expected map[string][]string) CheckFunc {
+	return func(ctx context.Context) []Result {
+		var results []Result
+		for _, b := range lister.ListBackends() {
+			models, err := b.ListModels(ctx)
+			if err != nil {
+				results = append(results, Result{
+					Name:    b.ID(),
+					Status:  StatusWarn,
+					Message: fmt.Sprintf("could not list models: %v", err),
+				})
+				continue
+			}
+			if len(models) == 0 {
+				results = append(results, Result{
+					Name:    b.ID(),
+					Status:  StatusWarn,
+					Message: "no models installed",
+					Hint:    "pull a model, e.g. `ollama pull qwen2.5:0.5b`",
+				})
+				continue
+			}
+
+			installed := make(map[string]bool, len(models))
+			for _, m := range models {
+				installed[m] = true
+			}
+			var missing []string
+			for _, m := range expected[b.ID()] {
+				if !installed[m] {
+					missing = append(missing, m)
+				}
+			}
+
+			if len(missing) > 0 {
+				results = append(results, Result{
+					Name:    b.ID(),
+					Status:  StatusWarn,
+					Message: fmt.Sprintf("%d models installed, missing: %s", len(models), strings.Join(missing, ", ")),
+					Hint:    fmt.Sprintf("pull the missing models on %s or remove them from preferred_models", b.ID()),
+				})
+				continue
+			}
+			results = append(results, Result{
+				Name:    b.ID(),
+				Status:  StatusOK,
+				Message: fmt.Sprintf("%d models installed", len(models)),
+			})
+		}
+		return results
+	}
+}
+
+// DBusNames checks the proxy owns its well-known D-Bus names on the system
+// or session bus
+func DBusNames(names []string) CheckFunc {
+	return func(ctx context.Context) []Result {
+		var conns []*dbus.Conn
+		if conn, err := dbus.ConnectSystemBus(); err == nil {
+			conns = append(conns, conn)
+		}
+		if conn, err := dbus.ConnectSessionBus(); err == nil {
+			conns = append(conns, conn)
+		}
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+
+		if len(conns) == 0 {
+			return []Result{{
+				Name:    "bus",
+				Status:  StatusWarn,
+				Message: "no D-Bus system or session bus available",
+				Hint:    "D-Bus integration is disabled; set DBUS_SESSION_BUS_ADDRESS or run under a desktop session",
+			}}
+		}
+
+		var results []Result
+		for _, name := range names {
+			owned := false
+			for _, conn := range conns {
+				var has bool
+				if err := conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.NameHasOwner", 0, name).Store(&has); err == nil && has {
+					owned = true
+					break
+				}
+			}
+			if owned {
+				results = append(results, Result{Name: name, Status: StatusOK, Message: "name owned"})
+			} else {
+				results = append(results, Result{
+					Name:    name,
+					Status:  StatusWarn,
+					Message: "name not owned",
+					Hint:    "another process may hold the name, or the bus policy denies it (see /etc/dbus-1/system.d)",
+				})
+			}
+		}
+		return results
+	}
+}
+
+// KernelModules checks the given modules appear in /proc/modules
+func KernelModules(modules []string) CheckFunc {
+	return kernelModules("/proc/modules", modules)
+}
+
+func kernelModules(procModules string, modules []string) CheckFunc {
+	return func(ctx context.Context) []Result {
+		loaded := make(map[string]bool)
+		f, err := os.Open(procModules)
+		if err != nil {
+			return []Result{{
+				Name:    "modules",
+				Status:  StatusSkip,
+				Message: fmt.Sprintf("cannot read %s: %v", procModules, err),
+			}}
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+				loaded[fields[0]] = true
+			}
+		}
+
+		var results []Result
+		for _, m := range modules {
+			// /proc/modules uses underscores (snd-aloop -> snd_aloop)
+			if loaded[strings.ReplaceAll(m, "-", "_")] {
+				results = append(results, Result{Name: m, Status: StatusOK, Message: "loaded"})
+			} else {
+				results = append(results, Result{
+					Name:    m,
+					Status:  StatusFail,
+					Message: "not loaded",
+					Hint:    fmt.Sprintf("install the module package and run `sudo modprobe %s`", m),
+				})
+			}
+		}
+		return results
+	}
+}
+
+// ThermalSensors checks the thermal monitor has discovered sensors
+func ThermalSensors(tm *thermal.ThermalMonitor) CheckFunc {
+	return thermalSensors(tm, "/sys/class/thermal")
+}
+
+func thermalSensors(tm *thermal.ThermalMonitor, sysThermal string) CheckFunc {
+	return func(ctx context.Context) []Result {
+		zones, _ := filepath.Glob(filepath.Join(sysThermal, "thermal_zone*"))
+
+		if tm == nil {
+			return []Result{{
+				Name:    "monitor",
+				Status:  StatusSkip,
+				Message: fmt.Sprintf("thermal monitoring disabled (%d kernel thermal zones present)", len(zones)),
+			}}
+		}
+
+		states := tm.GetAllStates()
+		if len(states) == 0 {
+			return []Result{{
+				Name:    "monitor",
+				Status:  StatusWarn,
+				Message: fmt.Sprintf("no sensors discovered (%d kernel thermal zones present)", len(zones)),
+				Hint:    "install lm-sensors / nvidia-smi, or check permissions on /sys/class/hwmon",
+			}}
+		}
+
+		hardware := make([]string, 0, len(states))
+		for hw := range states {
+			hardware = append(hardware, hw)
+		}
+		sort.Strings(hardware)
+
+		var results []Result
+		for _, hw := range hardware {
+			state := states[hw]
+			res := Result{
+				Name:    hw,
+				Status:  StatusOK,
+				Message: fmt.Sprintf("%.1f°C, updated %s ago", state.Temperature, time.Since(state.UpdatedAt).Round(time.Second)),
+			}
+			if time.Since(state.UpdatedAt) > 5*time.Minute {
+				res.Status = StatusWarn
+				res.Hint = "sensor readings are stale; check the thermal monitor logs"
+			}
+			results = append(results, res)
+		}
+		return results
+	}
+}
+
+// TLSCertificate checks the configured certificate loads and is not expiring
+func TLSCertificate(certFile, keyFile string, warnBefore time.Duration) CheckFunc {
+	return func(ctx context.Context) []Result {
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return []Result{{
+				Name:    certFile,
+				Status:  StatusFail,
+				Message: fmt.Sprintf("cannot load certificate: %v", err),
+				Hint:    "check cert_file/key_file paths, permissions, and that the key matches the certificate",
+			}}
+		}
+
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return []Result{{Name: certFile, Status: StatusFail, Message: fmt.Sprintf("cannot parse certificate: %v", err)}}
+		}
+
+		remaining := time.Until(cert.NotAfter)
+		res := Result{
+			Name:    certFile,
+			Status:  StatusOK,
+			Message: fmt.Sprintf("valid until %s (%d days)", cert.NotAfter.Format("2006-01-02"), int(remaining.Hours()/24)),
+		}
+		switch {
+		case remaining <= 0:
+			res.Status = StatusFail
+			res.Message = fmt.Sprintf("expired on %s", cert.NotAfter.Format("2006-01-02"))
+			res.Hint = "renew the certificate and restart the proxy"
+		case time.Now().Before(cert.NotBefore):
+			res.Status = StatusFail
+			res.Message = fmt.Sprintf("not valid until %s", cert.NotBefore.Format("2006-01-02"))
+			res.Hint = "check the system clock"
+		case remaining < warnBefore:
+			res.Status = StatusWarn
+			res.Hint = "renew the certificate soon"
+		}
+		return []Result{res}
+	}
+}
@@ -0,0 +1,146 @@
+package diagnostics
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// stubBackend is a minimal backend for check tests
+type stubBackend struct {
+	backends.Backend
+	id        string
+	healthErr error
+	models    []string
+}
+
+func (s *stubBackend) ID() string       { return s.id }
+func (s *stubBackend) Type() string     { return "ollama" }
+func (s *stubBackend) Hardware() string { return "npu" }
+
+func (s *stubBackend) HealthCheck(ctx context.Context) error { return s.healthErr }
+
+func (s *stubBackend) ListModels(ctx context.Context) ([]string, error) { return s.models, nil }
+
+type stubLister []backends.Backend
+
+func (l stubLister) ListBackends() []backends.Backend { return l }
+
+func TestBackendReachability(t *testing.T) {
+	check := BackendReachability(stubLister{
+		&stubBackend{id: "npu"},
+		&stubBackend{id: "nvidia", healthErr: fmt.Errorf("connection refused")},
+	})
+
+	results := check(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Status != StatusOK || results[1].Status != StatusFail || results[1].Hint == "" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+
+	if res := BackendReachability(stubLister{})(context.Background()); res[0].Status != StatusFail {
+		t.Error("Expected failure with no backends")
+	}
+}
+
+func TestModelAvailability(t *testing.T) {
+	check := ModelAvailability(stubLister{
+		&stubBackend{id: "npu", models: []string{"qwen2.5:0.5b"}},
+		&stubBackend{id: "igpu", models: []string{"llama3:8b"}},
+		&stubBackend{id: "empty"},
+	}, map[string][]string{"igpu": {"llama3:8b", "mistral:7b"}})
+
+	results := check(context.Background())
+	want := []string{StatusOK, StatusWarn, StatusWarn}
+	for i, status := range want {
+		if results[i].Status != status {
+			t.Errorf("Result %d: expected %s, got %+v", i, status, results[i])
+		}
+	}
+}
+
+func TestKernelModules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "modules")
+	os.WriteFile(path, []byte("v4l2loopback 49152 0 - Live 0x0\nsnd_aloop 36864 0 - Live 0x0\n"), 0o644)
+
+	results := kernelModules(path, []string{"v4l2loopback", "snd-aloop", "uinput"})(context.Background())
+	if results[0].Status != StatusOK || results[1].Status != StatusOK {
+		t.Errorf("Expected loaded modules to pass, got %+v", results[:2])
+	}
+	if results[2].Status != StatusFail {
+		t.Errorf("Expected missing module to fail, got %+v", results[2])
+	}
+
+	if res := kernelModules(filepath.Join(t.TempDir(), "missing"), []string{"x"})(context.Background()); res[0].Status != StatusSkip {
+		t.Errorf("Expected skip when /proc/modules unreadable, got %+v", res)
+	}
+}
+
+func TestThermalSensors_Disabled(t *testing.T) {
+	res := thermalSensors(nil, t.TempDir())(context.Background())
+	if len(res) != 1 || res[0].Status != StatusSkip {
+		t.Errorf("Expected skip when thermal monitoring disabled, got %+v", res)
+	}
+}
+
+func writeCert(t *testing.T, notAfter time.Time) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+func TestTLSCertificate(t *testing.T) {
+	certFile, keyFile := writeCert(t, time.Now().Add(90*24*time.Hour))
+	if res := TLSCertificate(certFile, keyFile, 14*24*time.Hour)(context.Background()); res[0].Status != StatusOK {
+		t.Errorf("Expected valid certificate, got %+v", res[0])
+	}
+
+	certFile, keyFile = writeCert(t, time.Now().Add(3*24*time.Hour))
+	if res := TLSCertificate(certFile, keyFile, 14*24*time.Hour)(context.Background()); res[0].Status != StatusWarn {
+		t.Errorf("Expected expiring certificate to warn, got %+v", res[0])
+	}
+
+	certFile, keyFile = writeCert(t, time.Now().Add(-time.Minute))
+	if res := TLSCertificate(certFile, keyFile, 14*24*time.Hour)(context.Background()); res[0].Status != StatusFail {
+		t.Errorf("Expected expired certificate to fail, got %+v", res[0])
+	}
+
+	if res := TLSCertificate("/nonexistent.pem", "/nonexistent.key", 0)(context.Background()); res[0].Status != StatusFail {
+		t.Errorf("Expected missing certificate to fail, got %+v", res[0])
+	}
+}
@@ -0,0 +1,211 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check result statuses, ordered by severity
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Result is the outcome of a single check
+type Result struct {
+	Category   string `json:"category"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	Hint       string `json:"hint,omitempty"` // what to do about a warn/fail
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// CheckFunc runs a check, returning one or more results
+type CheckFunc func(ctx context.Context) []Result
+
+// Report is the full diagnostics output
+type Report struct {
+	Time    time.Time      `json:"time"`
+	Status  string         `json:"status"` // worst status across results
+	Summary map[string]int `json:"summary"`
+	Results []Result       `json:"results"`
+}
+
+type check struct {
+	category string
+	name     string
+	fn       CheckFunc
+}
+
+// Runner holds the registered checks
+type Runner struct {
+	mu      sync.RWMutex
+	checks  []check
+	timeout time.Duration
+}
+
+// NewRunner creates a runner with a per-check timeout
+func NewRunner(timeout time.Duration) *Runner {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Runner{timeout: timeout}
+}
+
+// Register adds a check. category groups related checks in the report.
+func (r *Runner) Register(category, name string, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check{category: category, name: name, fn: fn})
+}
+
+// Run executes all checks concurrently and builds the report
+func (r *Runner) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	resultSets := make([][]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			resultSets[i] = r.runOne(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{
+		Time:    time.Now(),
+		Status:  StatusOK,
+		Summary: map[string]int{StatusOK: 0, StatusWarn: 0, StatusFail: 0, StatusSkip: 0},
+	}
+	for _, results := range resultSets {
+		for _, res := range results {
+			report.Results = append(report.Results, res)
+			report.Summary[res.Status]++
+			if severity(res.Status) > severity(report.Status) {
+				report.Status = res.Status
+			}
+		}
+	}
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].Category < report.Results[j].Category
+	})
+	return report
+}
+
+func (r *Runner) runOne(ctx context.Context, c check) []Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan []Result, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- []Result{{Status: StatusFail, Message: fmt.Sprintf("check panicked: %v", rec)}}
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+
+	var results []Result
+	select {
+	case results = <-done:
+	case <-ctx.Done():
+		results = []Result{{
+			Status:  StatusFail,
+			Message: fmt.Sprintf("check timed out after %s", r.timeout),
+		}}
+	}
+
+	elapsed := time.Since(start).Milliseconds()
+	for i := range results {
+		if results[i].Category == "" {
+			results[i].Category = c.category
+		}
+		if results[i].Name == "" {
+			results[i].Name = c.name
+		}
+		if results[i].DurationMs == 0 {
+			results[i].DurationMs = elapsed
+		}
+	}
+	return results
+}
+
+func severity(status string) int {
+	switch status {
+	case StatusFail:
+		return 2
+	case StatusWarn:
+		return 1
+	}
+	return 0
+}
+
+// WriteText writes a human readable report, one line per check
+func (rep Report) WriteText(w io.Writer) {
+	labels := map[string]string{
+		StatusOK:   "[ OK ]",
+		StatusWarn: "[WARN]",
+		StatusFail: "[FAIL]",
+		StatusSkip: "[SKIP]",
+	}
+
+	category := ""
+	for _, res := range rep.Results {
+		if res.Category != category {
+			category = res.Category
+			fmt.Fprintf(w, "\n%s\n", category)
+		}
+		fmt.Fprintf(w, "  %s %s: %s\n", labels[res.Status], res.Name, res.Message)
+		if res.Hint != "" && res.Status != StatusOK {
+			fmt.Fprintf(w, "         -> %s\n", res.Hint)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d ok, %d warnings, %d failures, %d skipped\n",
+		rep.Summary[StatusOK], rep.Summary[StatusWarn], rep.Summary[StatusFail], rep.Summary[StatusSkip])
+}
+
+// Handler serves /admin/diagnostics as JSON, or text with ?format=text.
+// The status code is 200 unless a check failed (503).
+func (r *Runner) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		report := r.Run(req.Context())
+
+		status := http.StatusOK
+		if report.Status == StatusFail {
+			status = http.StatusServiceUnavailable
+		}
+
+		if req.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(status)
+			report.WriteText(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
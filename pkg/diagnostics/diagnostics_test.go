@@ -0,0 +1,106 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func staticCheck(results ...Result) CheckFunc {
+	return func(ctx context.Context) []Result { return results }
+}
+
+func TestRunner_AggregatesStatus(t *testing.T) {
+	r := NewRunner(time.Second)
+	r.Register("backends", "reachability", staticCheck(Result{Name: "npu", Status: StatusOK, Message: "healthy"}))
+	r.Register("tls", "certificate", staticCheck(Result{Status: StatusWarn, Message: "expires soon"}))
+
+	report := r.Run(context.Background())
+
+	if report.Status != StatusWarn {
+		t.Errorf("Expected overall warn, got %s", report.Status)
+	}
+	if report.Summary[StatusOK] != 1 || report.Summary[StatusWarn] != 1 {
+		t.Errorf("Unexpected summary: %v", report.Summary)
+	}
+	// Category and name default from registration
+	tlsResult := report.Results[1]
+	if tlsResult.Category != "tls" || tlsResult.Name != "certificate" {
+		t.Errorf("Expected defaults from registration, got %+v", tlsResult)
+	}
+}
+
+func TestRunner_TimeoutAndPanic(t *testing.T) {
+	r := NewRunner(20 * time.Millisecond)
+	r.Register("slow", "hang", func(ctx context.Context) []Result {
+		time.Sleep(time.Second)
+		return nil
+	})
+	r.Register("broken", "panic", func(ctx context.Context) []Result {
+		panic("boom")
+	})
+
+	start := time.Now()
+	report := r.Run(context.Background())
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Expected slow check to be cut off by the timeout")
+	}
+
+	if report.Status != StatusFail || report.Summary[StatusFail] != 2 {
+		t.Fatalf("Expected two failures, got %+v", report)
+	}
+	for _, res := range report.Results {
+		if res.Category == "slow" && !strings.Contains(res.Message, "timed out") {
+			t.Errorf("Expected timeout message, got %s", res.Message)
+		}
+		if res.Category == "broken" && !strings.Contains(res.Message, "panicked") {
+			t.Errorf("Expected panic message, got %s", res.Message)
+		}
+	}
+}
+
+func TestReport_WriteText(t *testing.T) {
+	r := NewRunner(time.Second)
+	r.Register("modules", "kernel", staticCheck(Result{Name: "v4l2loopback", Status: StatusFail, Message: "not loaded", Hint: "sudo modprobe v4l2loopback"}))
+
+	var buf bytes.Buffer
+	r.Run(context.Background()).WriteText(&buf)
+
+	out := buf.String()
+	if !strings.Contains(out, "[FAIL] v4l2loopback: not loaded") || !strings.Contains(out, "-> sudo modprobe v4l2loopback") {
+		t.Errorf("Unexpected text report:\n%s", out)
+	}
+	if !strings.Contains(out, "0 ok, 0 warnings, 1 failures") {
+		t.Errorf("Expected summary line, got:\n%s", out)
+	}
+}
+
+func TestRunner_Handler(t *testing.T) {
+	r := NewRunner(time.Second)
+	r.Register("backends", "reachability", staticCheck(Result{Name: "npu", Status: StatusFail, Message: "unreachable"}))
+
+	w := httptest.NewRecorder()
+	r.Handler()(w, httptest.NewRequest(http.MethodGet, "/admin/diagnostics", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when a check fails, got %d", w.Code)
+	}
+	var report Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if len(report.Results) != 1 || report.Results[0].Name != "npu" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	w = httptest.NewRecorder()
+	r.Handler()(w, httptest.NewRequest(http.MethodGet, "/admin/diagnostics?format=text", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected text output, got %s", w.Header().Get("Content-Type"))
+	}
+}
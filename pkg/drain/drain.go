@@ -0,0 +1,301 @@
+package drain
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultTimeout is how long in-flight requests may run once draining starts
+const DefaultTimeout = 30 * time.Second
+
+// Request kinds tracked while draining
+const (
+	KindHTTP      = "http"
+	KindGRPC      = "grpc"
+	KindWebSocket = "websocket"
+)
+
+// Status is a point-in-time view of a drain
+type Status struct {
+	Draining bool           `json:"draining"`
+	Reason   string         `json:"reason,omitempty"`
+	Since    time.Time      `json:"since,omitempty"`
+	Deadline time.Time      `json:"deadline,omitempty"`
+	InFlight int            `json:"in_flight"`
+	ByKind   map[string]int `json:"by_kind,omitempty"`
+}
+
+// Drainer tracks in-flight requests and, once draining, turns new ones
+// away so the process can stop without cutting off streams. Draining is
+// one-way: the process is expected to shut down once Wait returns.
+type Drainer struct {
+	timeout time.Duration
+
+	mu       sync.Mutex
+	draining bool
+	reason   string
+	since    time.Time
+	deadline time.Time
+	inFlight map[string]int
+	total    int
+	idle     chan struct{} // closed when total drops to zero while draining
+
+	started chan struct{} // closed when draining starts
+}
+
+// New creates a drainer whose drains last timeout (0 = DefaultTimeout)
+func New(timeout time.Duration) *Drainer {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Drainer{
+		timeout:  timeout,
+		inFlight: make(map[string]int),
+		idle:     make(chan struct{}),
+		started:  make(chan struct{}),
+	}
+}
+
+// Start begins draining with the configured timeout, or timeout when
+// positive. It returns false if a drain was already in progress.
+func (d *Drainer) Start(reason string, timeout time.Duration) bool {
+	if timeout <= 0 {
+		timeout = d.timeout
+	}
+
+	d.mu.Lock()
+	if d.draining {
+		d.mu.Unlock()
+		return false
+	}
+	d.draining = true
+	d.reason = reason
+	d.since = time.Now()
+	d.deadline = d.since.Add(timeout)
+	inFlight := d.total
+	if inFlight == 0 {
+		close(d.idle)
+	}
+	close(d.started)
+	d.mu.Unlock()
+
+	if logging.Logger != nil {
+		logging.Logger.Warn("Draining: new requests are refused",
+			zap.String("reason", reason),
+			zap.Int("in_flight", inFlight),
+			zap.Duration("timeout", timeout),
+		)
+	}
+	return true
+}
+
+// Started is closed when draining starts
+func (d *Drainer) Started() <-chan struct{} {
+	return d.started
+}
+
+// Draining reports whether a drain is in progress
+func (d *Drainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// Deadline returns when the drain gives up on in-flight requests, zero
+// before draining starts
+func (d *Drainer) Deadline() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.deadline
+}
+
+// Track registers a request of kind. It returns false while draining, when
+// the request must be refused; otherwise done must be called when the
+// request ends.
+func (d *Drainer) Track(kind string) (done func(), ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return nil, false
+	}
+	d.inFlight[kind]++
+	d.total++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.inFlight[kind]--
+			d.total--
+			if d.total == 0 && d.draining {
+				close(d.idle)
+			}
+		})
+	}, true
+}
+
+// Wait blocks until draining has started and every in-flight request has
+// ended, or the drain deadline or ctx ends first. It returns the number of
+// requests still running.
+func (d *Drainer) Wait(ctx context.Context) int {
+	select {
+	case <-d.started:
+	case <-ctx.Done():
+		return d.Status().InFlight
+	}
+
+	timer := time.NewTimer(time.Until(d.Deadline()))
+	defer timer.Stop()
+	select {
+	case <-d.idle:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return d.Status().InFlight
+}
+
+// Status returns the current drain state
+func (d *Drainer) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := Status{
+		Draining: d.draining,
+		Reason:   d.reason,
+		Since:    d.since,
+		Deadline: d.deadline,
+		InFlight: d.total,
+	}
+	for kind, n := range d.inFlight {
+		if n > 0 {
+			if status.ByKind == nil {
+				status.ByKind = make(map[string]int)
+			}
+			status.ByKind[kind] = n
+		}
+	}
+	return status
+}
+
+// Middleware tracks data-plane requests and refuses new ones with 503 while
+// draining. WebSocket upgrades are counted separately and stay in flight
+// for the life of the connection.
+func (d *Drainer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kind := KindHTTP
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			kind = KindWebSocket
+		}
+		done, ok := d.Track(kind)
+		if !ok {
+			retryAfter := int(time.Until(d.Deadline()).Seconds()) + 1
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			w.Header().Set("Connection", "close")
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]interface{}{
+					"message": "Server is shutting down",
+					"type":    "service_unavailable",
+					"code":    "draining",
+				},
+			})
+			return
+		}
+		defer done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isHealthMethod reports whether a gRPC method is a health check, which
+// load balancers keep calling while the server drains
+func isHealthMethod(fullMethod string) bool {
+	return strings.Contains(fullMethod, "Health")
+}
+
+// UnaryInterceptor tracks unary RPCs and refuses new ones with
+// codes.Unavailable while draining
+func (d *Drainer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isHealthMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		done, ok := d.Track(KindGRPC)
+		if !ok {
+			return nil, status.Error(codes.Unavailable, "server is shutting down")
+		}
+		defer done()
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor tracks streaming RPCs like UnaryInterceptor
+func (d *Drainer) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isHealthMethod(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		done, ok := d.Track(KindGRPC)
+		if !ok {
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+		defer done()
+		return handler(srv, ss)
+	}
+}
+
+// startRequest is the admin API payload
+type startRequest struct {
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	Reason         string `json:"reason"`
+}
+
+// Handler serves the admin drain endpoint. GET returns the status; POST
+// starts draining, after which the process shuts down.
+func (d *Drainer) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := http.StatusOK
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req startRequest
+			// The body is optional
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.TimeoutSeconds < 0 {
+				http.Error(w, "timeout_seconds cannot be negative", http.StatusBadRequest)
+				return
+			}
+			if req.Reason == "" {
+				req.Reason = "admin request"
+			}
+			d.Start(req.Reason, time.Duration(req.TimeoutSeconds)*time.Second)
+			code = http.StatusAccepted
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(d.Status())
+	}
+}
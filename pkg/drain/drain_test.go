@@ -0,0 +1,127 @@
+package drain
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDrainer_RefusesNewAndWaitsForInFlight(t *testing.T) {
+	d := New(time.Minute)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := d.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("streamed"))
+	}))
+
+	inFlight := httptest.NewRecorder()
+	finished := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(inFlight, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+		close(finished)
+	}()
+	<-started
+
+	if !d.Start("test", 0) || d.Start("again", 0) {
+		t.Fatal("Expected only the first Start to begin a drain")
+	}
+	if status := d.Status(); !status.Draining || status.InFlight != 1 || status.ByKind[KindHTTP] != 1 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	refused := httptest.NewRecorder()
+	handler.ServeHTTP(refused, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if refused.Code != http.StatusServiceUnavailable || refused.Header().Get("Retry-After") == "" || !strings.Contains(refused.Body.String(), "draining") {
+		t.Errorf("Expected 503 with Retry-After while draining, got %d %q", refused.Code, refused.Body.String())
+	}
+
+	waited := make(chan int)
+	go func() { waited <- d.Wait(context.Background()) }()
+	select {
+	case <-waited:
+		t.Fatal("Wait returned with a request in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-finished
+	if remaining := <-waited; remaining != 0 {
+		t.Errorf("Expected no requests left, got %d", remaining)
+	}
+	if inFlight.Body.String() != "streamed" {
+		t.Errorf("Expected the in-flight request to finish, got %q", inFlight.Body.String())
+	}
+}
+
+func TestDrainer_Deadline(t *testing.T) {
+	d := New(time.Minute)
+	done, _ := d.Track(KindWebSocket)
+	defer done()
+
+	d.Start("test", 20*time.Millisecond)
+	start := time.Now()
+	if remaining := d.Wait(context.Background()); remaining != 1 {
+		t.Errorf("Expected the stuck request reported, got %d", remaining)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Wait to stop at the deadline, took %v", elapsed)
+	}
+}
+
+func TestDrainer_GRPC(t *testing.T) {
+	d := New(0)
+	unary := d.UnaryInterceptor()
+	call := func(method string) error {
+		_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+		return err
+	}
+
+	if err := call("/compute.v1.ComputeService/Generate"); err != nil {
+		t.Fatalf("Expected the call to pass before draining, got %v", err)
+	}
+	d.Start("test", 0)
+	if err := call("/compute.v1.ComputeService/Generate"); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable while draining, got %v", err)
+	}
+	if err := call("/compute.v1.ComputeService/HealthCheck"); err != nil {
+		t.Errorf("Expected health checks to pass while draining, got %v", err)
+	}
+}
+
+func TestDrainer_Handler(t *testing.T) {
+	d := New(0)
+	h := d.Handler()
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/admin/drain", strings.NewReader(`{"timeout_seconds":5,"reason":"upgrade"}`)))
+	var status Status
+	json.NewDecoder(rec.Body).Decode(&status)
+	if rec.Code != http.StatusAccepted || !status.Draining || status.Reason != "upgrade" {
+		t.Fatalf("Expected the drain started, got %d %+v", rec.Code, status)
+	}
+	if left := time.Until(status.Deadline); left <= 0 || left > 5*time.Second {
+		t.Errorf("Expected a 5s deadline, got %v", left)
+	}
+	select {
+	case <-d.Started():
+	default:
+		t.Error("Expected Started closed")
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/admin/drain", strings.NewReader(`{"timeout_seconds":-1}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a negative timeout, got %d", rec.Code)
+	}
+}
@@ -2,6 +2,8 @@ package efficiency
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/logging"
 	"github.com/godbus/dbus/v5"
@@ -15,6 +17,9 @@ const (
 	dbusPath      = "/com/anthropic/OllamaProxy/Efficiency"
 )
 
+// BusName is the well-known D-Bus name owned by the efficiency service
+const BusName = dbusInterface
+
 // DBusService exposes efficiency mode control via D-Bus
 type DBusService struct {
 	conn    *dbus.Conn
@@ -98,6 +103,49 @@ func (ds *DBusService) Start() error {
 							{Name: "info", Type: "a{sv}", Direction: "out"},
 						},
 					},
+					{
+						Name: "StartQuietWindow",
+						Args: []introspect.Arg{
+							{Name: "reason", Type: "s", Direction: "in"},
+							{Name: "durationSeconds", Type: "u", Direction: "in"},
+						},
+					},
+					{
+						Name: "EndQuietWindow",
+					},
+					{
+						Name: "GetQuietWindow",
+						Args: []introspect.Arg{
+							{Name: "active", Type: "b", Direction: "out"},
+							{Name: "reason", Type: "s", Direction: "out"},
+							{Name: "remainingSeconds", Type: "u", Direction: "out"},
+						},
+					},
+					{
+						Name: "GetSchedule",
+						Args: []introspect.Arg{
+							{Name: "entries", Type: "a(sssssb)", Direction: "out"},
+						},
+					},
+					{
+						Name: "GetScheduleStatus",
+						Args: []introspect.Arg{
+							{Name: "entry", Type: "s", Direction: "out"},
+							{Name: "mode", Type: "s", Direction: "out"},
+							{Name: "override", Type: "s", Direction: "out"},
+							{Name: "overrideRemainingSeconds", Type: "u", Direction: "out"},
+						},
+					},
+					{
+						Name: "OverrideSchedule",
+						Args: []introspect.Arg{
+							{Name: "mode", Type: "s", Direction: "in"},
+							{Name: "durationSeconds", Type: "u", Direction: "in"},
+						},
+					},
+					{
+						Name: "ClearScheduleOverride",
+					},
 				},
 				Signals: []introspect.Signal{
 					{
@@ -107,6 +155,20 @@ func (ds *DBusService) Start() error {
 							{Name: "newMode", Type: "s"},
 						},
 					},
+					{
+						Name: "QuietWindowChanged",
+						Args: []introspect.Arg{
+							{Name: "active", Type: "b"},
+							{Name: "reason", Type: "s"},
+						},
+					},
+					{
+						Name: "ScheduleChanged",
+						Args: []introspect.Arg{
+							{Name: "entry", Type: "s"},
+							{Name: "mode", Type: "s"},
+						},
+					},
 				},
 				Properties: []introspect.Property{
 					{
@@ -119,6 +181,11 @@ func (ds *DBusService) Start() error {
 						Type:   "s",
 						Access: "read",
 					},
+					{
+						Name:   "QuietWindowActive",
+						Type:   "b",
+						Access: "read",
+					},
 				},
 			},
 		},
@@ -238,6 +305,137 @@ func (ds *DBusService) GetModeInfo(modeName string) (map[string]dbus.Variant, *d
 	return info, nil
 }
 
+// StartQuietWindow declares a manual quiet window (D-Bus method). A zero
+// duration lasts until EndQuietWindow.
+func (ds *DBusService) StartQuietWindow(reason string, durationSeconds uint32) *dbus.Error {
+	if reason == "" {
+		reason = "manual"
+	}
+	ds.manager.StartQuietWindow(ManualQuietOwner, reason, time.Duration(durationSeconds)*time.Second)
+	ds.NotifyQuietWindowChanged()
+	return nil
+}
+
+// EndQuietWindow ends the manual quiet window (D-Bus method)
+func (ds *DBusService) EndQuietWindow() *dbus.Error {
+	ds.manager.EndQuietWindow(ManualQuietOwner)
+	ds.NotifyQuietWindowChanged()
+	return nil
+}
+
+// GetQuietWindow returns the active quiet window, if any (D-Bus method)
+func (ds *DBusService) GetQuietWindow() (bool, string, uint32, *dbus.Error) {
+	window, ok := ds.manager.ActiveQuietWindow()
+	if !ok {
+		return false, "", 0, nil
+	}
+
+	var remaining uint32
+	if !window.Until.IsZero() {
+		remaining = uint32(time.Until(window.Until).Seconds())
+	}
+	return true, window.Reason, remaining, nil
+}
+
+// NotifyQuietWindowChanged emits QuietWindowChanged and refreshes the
+// properties. Call it after quiet windows change outside D-Bus, e.g. when a
+// meeting starts.
+func (ds *DBusService) NotifyQuietWindowChanged() {
+	window, active := ds.manager.ActiveQuietWindow()
+
+	if ds.conn != nil {
+		ds.conn.Emit(dbusPath, dbusInterface+".QuietWindowChanged", active, window.Reason)
+	}
+
+	if ds.props != nil {
+		ds.props.SetMust(dbusInterface, "QuietWindowActive", active)
+		ds.props.SetMust(dbusInterface, "EffectiveMode", ds.manager.GetEffectiveMode().String())
+	}
+}
+
+// ScheduleEntryRow is a schedule entry as returned by GetSchedule: name,
+// mode, comma-separated days (empty = every day), start, end and whether
+// it is active now
+type ScheduleEntryRow struct {
+	Name   string
+	Mode   string
+	Days   string
+	Start  string
+	End    string
+	Active bool
+}
+
+// GetSchedule returns the efficiency schedule (D-Bus method)
+func (ds *DBusService) GetSchedule() ([]ScheduleEntryRow, *dbus.Error) {
+	status := ds.manager.ScheduleStatus(time.Now())
+	rows := make([]ScheduleEntryRow, len(status.Entries))
+	for i, entry := range status.Entries {
+		rows[i] = ScheduleEntryRow{
+			Name:   entry.Name,
+			Mode:   entry.Mode,
+			Days:   strings.Join(entry.Days, ","),
+			Start:  entry.Start,
+			End:    entry.End,
+			Active: entry.Active,
+		}
+	}
+	return rows, nil
+}
+
+// GetScheduleStatus returns the entry in force, the mode the schedule
+// applies and any override with its remaining time (D-Bus method)
+func (ds *DBusService) GetScheduleStatus() (string, string, string, uint32, *dbus.Error) {
+	status := ds.manager.ScheduleStatus(time.Now())
+	var remaining uint32
+	if !status.OverrideUntil.IsZero() {
+		remaining = uint32(time.Until(status.OverrideUntil).Seconds())
+	}
+	return status.Active, status.Mode, status.Override, remaining, nil
+}
+
+// OverrideSchedule applies mode in place of the schedule, or suspends it
+// with "off" (D-Bus method). A zero duration lasts until
+// ClearScheduleOverride.
+func (ds *DBusService) OverrideSchedule(mode string, durationSeconds uint32) *dbus.Error {
+	if err := ds.manager.OverrideSchedule(mode, time.Duration(durationSeconds)*time.Second); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// ClearScheduleOverride puts the schedule back in force (D-Bus method)
+func (ds *DBusService) ClearScheduleOverride() *dbus.Error {
+	ds.manager.ClearScheduleOverride()
+	return nil
+}
+
+// NotifyScheduleChanged emits ScheduleChanged and refreshes the effective
+// mode. Subscribe it to the manager's OnScheduleChange.
+func (ds *DBusService) NotifyScheduleChanged(status ScheduleStatus) {
+	if ds.conn != nil {
+		ds.conn.Emit(dbusPath, dbusInterface+".ScheduleChanged", status.Active, status.Mode)
+	}
+
+	if ds.props != nil {
+		ds.props.SetMust(dbusInterface, "EffectiveMode", ds.manager.GetEffectiveMode().String())
+	}
+}
+
+// NotifyModeChanged emits ModeChanged and refreshes the properties. Call it
+// after the mode changes outside D-Bus, e.g. through the admin API.
+func (ds *DBusService) NotifyModeChanged(oldMode EfficiencyMode) {
+	newMode := ds.manager.GetMode()
+
+	if ds.conn != nil {
+		ds.conn.Emit(dbusPath, dbusInterface+".ModeChanged", oldMode.String(), newMode.String())
+	}
+
+	if ds.props != nil {
+		ds.props.SetMust(dbusInterface, "CurrentMode", newMode.String())
+		ds.props.SetMust(dbusInterface, "EffectiveMode", ds.manager.GetEffectiveMode().String())
+	}
+}
+
 // makePropertyMap creates property map for D-Bus
 func (ds *DBusService) makePropertyMap() map[string]map[string]*prop.Prop {
 	return map[string]map[string]*prop.Prop{
@@ -255,6 +453,11 @@ func (ds *DBusService) makePropertyMap() map[string]map[string]*prop.Prop {
 				Writable: false,
 				Emit:     prop.EmitTrue,
 			},
+			"QuietWindowActive": {
+				Value:    len(ds.manager.QuietWindows()) > 0,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+			},
 		},
 	}
 }
@@ -1,6 +1,10 @@
 package efficiency
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
@@ -1461,3 +1465,52 @@ func TestEfficiencyManager_SystemState_AllFields(t *testing.T) {
 		}
 	}
 }
+
+func TestParseMode(t *testing.T) {
+	for _, mode := range AllModes() {
+		if got, err := ParseMode(mode.String()); err != nil || got != mode {
+			t.Errorf("ParseMode(%q) = %v, %v", mode.String(), got, err)
+		}
+	}
+	if got, err := ParseMode("ultraefficiency"); err != nil || got != ModeUltraEfficiency {
+		t.Errorf("Expected case and spaces ignored, got %v, %v", got, err)
+	}
+	if _, err := ParseMode("Turbo"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+func TestEfficiencyManager_ModeHandler(t *testing.T) {
+	em := NewEfficiencyManager(ModeBalanced)
+	handler := em.ModeHandler()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPut, "/admin/efficiency", strings.NewReader(`{"mode":"Quiet"}`)))
+	var response map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || response["current_mode"] != "Quiet" || em.GetMode() != ModeQuiet {
+		t.Errorf("Expected the mode changed to Quiet, got %d %v", w.Code, response)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPut, "/admin/efficiency", strings.NewReader(`{"mode":"Turbo"}`)))
+	if w.Code != http.StatusBadRequest || em.GetMode() != ModeQuiet {
+		t.Errorf("Expected 400 and the mode unchanged, got %d %s", w.Code, em.GetMode())
+	}
+}
+
+func TestGetModeConfig_CostWeights(t *testing.T) {
+	perf := GetModeConfig(ModePerformance)
+	if perf.LatencyWeight <= perf.EnergyWeight {
+		t.Errorf("Expected Performance to weigh latency over energy, got %+v", perf)
+	}
+	for _, mode := range []EfficiencyMode{ModeEfficiency, ModeUltraEfficiency} {
+		if c := GetModeConfig(mode); c.EnergyWeight <= c.LatencyWeight {
+			t.Errorf("Expected %s to weigh energy over latency, got %+v", mode, c)
+		}
+	}
+	quiet := GetModeConfig(ModeQuiet)
+	if quiet.ThermalWeight <= quiet.EnergyWeight || quiet.ThermalWeight <= quiet.LatencyWeight {
+		t.Errorf("Expected Quiet to weigh thermal headroom most, got %+v", quiet)
+	}
+}
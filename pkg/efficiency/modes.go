@@ -1,8 +1,12 @@
 package efficiency
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
 )
@@ -73,6 +77,12 @@ type ModeConfig struct {
 	// Prefer classification to save power
 	UseClassification bool
 
+	// Relative weights of each term of a backend's routing cost
+	EnergyWeight  float64
+	LatencyWeight float64
+	ThermalWeight float64
+	QueueWeight   float64
+
 	// Description for UI
 	Description string
 
@@ -91,6 +101,10 @@ func GetModeConfig(mode EfficiencyMode) *ModeConfig {
 			OverrideCriticalFlag:    false,
 			ThrottleLatencyCritical: false,
 			UseClassification:       false,
+			EnergyWeight:            0.25,
+			LatencyWeight:           3,
+			ThermalWeight:           0.5,
+			QueueWeight:             1,
 			Description:             "Maximum speed. Always use fastest backend available.",
 			Icon:                    "🚀",
 		},
@@ -103,6 +117,10 @@ func GetModeConfig(mode EfficiencyMode) *ModeConfig {
 			OverrideCriticalFlag:    true,
 			ThrottleLatencyCritical: false,
 			UseClassification:       true,
+			EnergyWeight:            1,
+			LatencyWeight:           1,
+			ThermalWeight:           1,
+			QueueWeight:             1,
 			Description:             "Smart routing based on task complexity. Good balance of speed and efficiency.",
 			Icon:                    "⚖️",
 		},
@@ -115,6 +133,10 @@ func GetModeConfig(mode EfficiencyMode) *ModeConfig {
 			OverrideCriticalFlag:    true,
 			ThrottleLatencyCritical: true,
 			UseClassification:       true,
+			EnergyWeight:            3,
+			LatencyWeight:           0.5,
+			ThermalWeight:           1,
+			QueueWeight:             1,
 			Description:             "Minimize power consumption. Prefer NPU and Intel GPU.",
 			Icon:                    "🔋",
 		},
@@ -127,6 +149,10 @@ func GetModeConfig(mode EfficiencyMode) *ModeConfig {
 			OverrideCriticalFlag:    true,
 			ThrottleLatencyCritical: true,
 			UseClassification:       true,
+			EnergyWeight:            1.5,
+			LatencyWeight:           0.5,
+			ThermalWeight:           3,
+			QueueWeight:             1,
 			Description:             "Minimize fan noise. Use silent backends only.",
 			Icon:                    "🔇",
 		},
@@ -139,6 +165,10 @@ func GetModeConfig(mode EfficiencyMode) *ModeConfig {
 			OverrideCriticalFlag:    true,
 			ThrottleLatencyCritical: false,
 			UseClassification:       true,
+			EnergyWeight:            1,
+			LatencyWeight:           1,
+			ThermalWeight:           1,
+			QueueWeight:             1,
 			Description:             "Automatically adjust based on battery, temperature, and time of day.",
 			Icon:                    "🤖",
 		},
@@ -151,6 +181,10 @@ func GetModeConfig(mode EfficiencyMode) *ModeConfig {
 			OverrideCriticalFlag:    true,
 			ThrottleLatencyCritical: true,
 			UseClassification:       true,
+			EnergyWeight:            5,
+			LatencyWeight:           0.25,
+			ThermalWeight:           1,
+			QueueWeight:             0.5,
 			Description:             "Maximum battery life. NPU only, accept slower responses.",
 			Icon:                    "🪫",
 		},
@@ -174,12 +208,23 @@ type EfficiencyManager struct {
 	avgTemp        float64
 	avgFanSpeed    int
 	quietHours     bool
+
+	// Declared quiet windows force Quiet mode while any is active
+	quietWindows map[string]QuietWindow // owner -> window
+
+	// Scheduled modes apply over the selected mode while an entry is
+	// active, unless overridden
+	schedule          []ScheduleEntry
+	scheduleOverride  *ScheduleOverride
+	scheduleListeners []func(ScheduleStatus)
+	scheduleKey       string // what the schedule applied at the last CheckSchedule
 }
 
 // NewEfficiencyManager creates manager with default mode
 func NewEfficiencyManager(defaultMode EfficiencyMode) *EfficiencyManager {
 	return &EfficiencyManager{
-		mode: defaultMode,
+		mode:         defaultMode,
+		quietWindows: make(map[string]QuietWindow),
 	}
 }
 
@@ -197,13 +242,24 @@ func (em *EfficiencyManager) GetMode() EfficiencyMode {
 	return em.mode
 }
 
-// GetEffectiveMode returns the actual mode to use (resolves Auto)
+// GetEffectiveMode returns the actual mode to use (resolves Auto). An
+// active quiet window overrides the scheduled mode, which overrides the
+// selected mode.
 func (em *EfficiencyManager) GetEffectiveMode() EfficiencyMode {
 	em.mu.RLock()
 	defer em.mu.RUnlock()
 
-	if em.mode != ModeAuto {
-		return em.mode
+	now := time.Now()
+	if em.quietWindowActiveLocked(now) {
+		return ModeQuiet
+	}
+
+	mode := em.mode
+	if scheduled, ok := em.scheduledModeLocked(now); ok {
+		mode = scheduled
+	}
+	if mode != ModeAuto {
+		return mode
 	}
 
 	// Auto mode - determine based on system state
@@ -333,6 +389,18 @@ func (em *EfficiencyManager) GetModeDescription() string {
 	effectiveMode := em.GetEffectiveMode()
 	config := GetModeConfig(effectiveMode)
 
+	if window, ok := em.ActiveQuietWindow(); ok {
+		return fmt.Sprintf("%s (quiet window: %s): %s", mode.String(), window.Reason, config.Description)
+	}
+
+	if status := em.ScheduleStatus(time.Now()); status.Mode != "" {
+		source := "schedule: " + status.Active
+		if status.Override != "" {
+			source = "schedule override"
+		}
+		return fmt.Sprintf("%s (%s, %s): %s", mode.String(), source, effectiveMode.String(), config.Description)
+	}
+
 	if mode == ModeAuto {
 		return fmt.Sprintf("Auto (%s): %s", effectiveMode.String(), config.Description)
 	}
@@ -374,3 +442,60 @@ func AllModes() []EfficiencyMode {
 		ModeUltraEfficiency,
 	}
 }
+
+// ParseMode returns the mode with the given name. Case and spaces are
+// ignored, so "UltraEfficiency" and "Ultra Efficiency" name the same mode.
+func ParseMode(name string) (EfficiencyMode, error) {
+	key := strings.ToLower(strings.ReplaceAll(name, " ", ""))
+	for _, mode := range AllModes() {
+		if strings.ToLower(strings.ReplaceAll(mode.String(), " ", "")) == key {
+			return mode, nil
+		}
+	}
+	return ModeBalanced, fmt.Errorf("unknown mode: %s", name)
+}
+
+// modeRequest is the mode admin API payload
+type modeRequest struct {
+	Mode string `json:"mode"`
+}
+
+// ModeHandler serves the efficiency mode: GET returns it and PUT or POST
+// {"mode": "Quiet"} changes it
+func (em *EfficiencyManager) ModeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req modeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			mode, err := ParseMode(req.Mode)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			em.SetMode(mode)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		modes := AllModes()
+		names := make([]string, len(modes))
+		for i, m := range modes {
+			names[i] = m.String()
+		}
+		response := map[string]interface{}{
+			"current_mode":   em.GetMode().String(),
+			"effective_mode": em.GetEffectiveMode().String(),
+			"description":    em.GetModeDescription(),
+			"modes":          names,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
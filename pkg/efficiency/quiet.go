@@ -0,0 +1,174 @@
+package efficiency
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// ManualQuietOwner owns quiet windows declared by the user over D-Bus or HTTP
+const ManualQuietOwner = "manual"
+
+// QuietWindow is a declared "do not disturb" period. While any window is
+// active the effective mode is Quiet, whatever mode the user selected.
+type QuietWindow struct {
+	Owner   string    `json:"owner"`  // e.g. "manual", "meeting:ollama-npu"
+	Reason  string    `json:"reason"` // shown in logs and routing reasons
+	Started time.Time `json:"started"`
+	Until   time.Time `json:"until,omitempty"` // zero = until ended
+}
+
+// active reports whether the window is still in force at now
+func (w QuietWindow) active(now time.Time) bool {
+	return w.Until.IsZero() || now.Before(w.Until)
+}
+
+// StartQuietWindow declares a quiet window for owner, replacing any
+// previous window of the same owner. A zero duration lasts until
+// EndQuietWindow is called.
+func (em *EfficiencyManager) StartQuietWindow(owner, reason string, duration time.Duration) QuietWindow {
+	now := time.Now()
+	window := QuietWindow{
+		Owner:   owner,
+		Reason:  reason,
+		Started: now,
+	}
+	if duration > 0 {
+		window.Until = now.Add(duration)
+	}
+
+	em.mu.Lock()
+	if em.quietWindows == nil {
+		em.quietWindows = make(map[string]QuietWindow)
+	}
+	em.quietWindows[owner] = window
+	em.mu.Unlock()
+
+	if logging.Logger != nil {
+		logging.Logger.Info("Quiet window started, enforcing Quiet mode",
+			zap.String("owner", owner),
+			zap.String("reason", reason),
+			zap.Duration("duration", duration),
+		)
+	}
+	return window
+}
+
+// EndQuietWindow ends owner's quiet window. It reports whether one existed.
+func (em *EfficiencyManager) EndQuietWindow(owner string) bool {
+	em.mu.Lock()
+	_, existed := em.quietWindows[owner]
+	delete(em.quietWindows, owner)
+	em.mu.Unlock()
+
+	if existed && logging.Logger != nil {
+		logging.Logger.Info("Quiet window ended", zap.String("owner", owner))
+	}
+	return existed
+}
+
+// QuietWindows returns the active quiet windows ordered by owner
+func (em *EfficiencyManager) QuietWindows() []QuietWindow {
+	now := time.Now()
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	list := make([]QuietWindow, 0, len(em.quietWindows))
+	for owner, window := range em.quietWindows {
+		if !window.active(now) {
+			delete(em.quietWindows, owner) // expired
+			continue
+		}
+		list = append(list, window)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Owner < list[j].Owner })
+	return list
+}
+
+// ReplaceQuietWindows replaces all quiet windows, keeping their start and
+// end times. Used to mirror an HA peer's windows.
+func (em *EfficiencyManager) ReplaceQuietWindows(windows []QuietWindow) {
+	now := time.Now()
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	em.quietWindows = make(map[string]QuietWindow, len(windows))
+	for _, window := range windows {
+		if window.active(now) {
+			em.quietWindows[window.Owner] = window
+		}
+	}
+}
+
+// ActiveQuietWindow returns the first active quiet window, if any
+func (em *EfficiencyManager) ActiveQuietWindow() (QuietWindow, bool) {
+	windows := em.QuietWindows()
+	if len(windows) == 0 {
+		return QuietWindow{}, false
+	}
+	return windows[0], true
+}
+
+// quietWindowActiveLocked reports whether any window is in force.
+// Caller must hold em.mu.
+func (em *EfficiencyManager) quietWindowActiveLocked(now time.Time) bool {
+	for _, window := range em.quietWindows {
+		if window.active(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// quietWindowRequest is the body of POST /admin/quiet
+type quietWindowRequest struct {
+	Reason          string `json:"reason"`
+	DurationSeconds int    `json:"duration_seconds"` // 0 = until DELETE
+}
+
+// QuietWindowHandler serves quiet windows: GET lists them, POST starts a
+// manual window and DELETE ends it
+func (em *EfficiencyManager) QuietWindowHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req quietWindowRequest
+			if r.ContentLength != 0 {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if req.DurationSeconds < 0 {
+				http.Error(w, "duration_seconds must be non-negative", http.StatusBadRequest)
+				return
+			}
+			if req.Reason == "" {
+				req.Reason = "manual"
+			}
+			em.StartQuietWindow(ManualQuietOwner, req.Reason, time.Duration(req.DurationSeconds)*time.Second)
+		case http.MethodDelete:
+			em.EndQuietWindow(ManualQuietOwner)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		windows := em.QuietWindows()
+		response := map[string]interface{}{
+			"active":         len(windows) > 0,
+			"effective_mode": em.GetEffectiveMode().String(),
+			"windows":        windows,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,126 @@
+package efficiency
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuietWindow_OverridesMode(t *testing.T) {
+	em := NewEfficiencyManager(ModePerformance)
+
+	em.StartQuietWindow("meeting:ollama-npu", "meeting", 0)
+	if mode := em.GetEffectiveMode(); mode != ModeQuiet {
+		t.Errorf("Expected Quiet during quiet window, got %s", mode)
+	}
+	if em.GetMode() != ModePerformance {
+		t.Error("Expected selected mode to be left unchanged")
+	}
+	if desc := em.GetModeDescription(); !strings.Contains(desc, "quiet window: meeting") {
+		t.Errorf("Expected description to mention quiet window, got %q", desc)
+	}
+
+	// Overlapping windows: quiet until the last one ends
+	em.StartQuietWindow(ManualQuietOwner, "focus", 0)
+	em.EndQuietWindow("meeting:ollama-npu")
+	if mode := em.GetEffectiveMode(); mode != ModeQuiet {
+		t.Errorf("Expected Quiet while manual window remains, got %s", mode)
+	}
+
+	if !em.EndQuietWindow(ManualQuietOwner) {
+		t.Error("Expected EndQuietWindow to report existing window")
+	}
+	if em.EndQuietWindow(ManualQuietOwner) {
+		t.Error("Expected EndQuietWindow to report no window the second time")
+	}
+	if mode := em.GetEffectiveMode(); mode != ModePerformance {
+		t.Errorf("Expected Performance after windows end, got %s", mode)
+	}
+}
+
+func TestQuietWindow_Expires(t *testing.T) {
+	em := NewEfficiencyManager(ModeBalanced)
+	em.StartQuietWindow(ManualQuietOwner, "nap", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := em.ActiveQuietWindow(); ok {
+		t.Error("Expected expired window to be inactive")
+	}
+	if mode := em.GetEffectiveMode(); mode != ModeBalanced {
+		t.Errorf("Expected Balanced after window expiry, got %s", mode)
+	}
+}
+
+func TestQuietWindowHandler(t *testing.T) {
+	em := NewEfficiencyManager(ModePerformance)
+	handler := em.QuietWindowHandler()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/admin/quiet",
+		strings.NewReader(`{"reason":"podcast","duration_seconds":600}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Active        bool          `json:"active"`
+		EffectiveMode string        `json:"effective_mode"`
+		Windows       []QuietWindow `json:"windows"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if !resp.Active || resp.EffectiveMode != "Quiet" || len(resp.Windows) != 1 || resp.Windows[0].Reason != "podcast" {
+		t.Errorf("Unexpected response after POST: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodDelete, "/admin/quiet", nil))
+	if _, ok := em.ActiveQuietWindow(); ok {
+		t.Error("Expected DELETE to end the manual window")
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/admin/quiet", strings.NewReader(`{"duration_seconds":-1}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for negative duration, got %d", w.Code)
+	}
+}
+
+func TestDBusQuietWindow(t *testing.T) {
+	em := NewEfficiencyManager(ModePerformance)
+	ds := &DBusService{manager: em}
+
+	if err := ds.StartQuietWindow("", 60); err != nil {
+		t.Fatalf("StartQuietWindow failed: %v", err)
+	}
+	active, reason, remaining, _ := ds.GetQuietWindow()
+	if !active || reason != "manual" || remaining == 0 || remaining > 60 {
+		t.Errorf("Unexpected quiet window: active=%v reason=%q remaining=%d", active, reason, remaining)
+	}
+
+	ds.EndQuietWindow()
+	if active, _, _, _ := ds.GetQuietWindow(); active {
+		t.Error("Expected no quiet window after EndQuietWindow")
+	}
+}
+
+func TestReplaceQuietWindows(t *testing.T) {
+	em := NewEfficiencyManager(ModeBalanced)
+	em.StartQuietWindow("local", "focus", 0)
+
+	started := time.Now().Add(-time.Minute)
+	em.ReplaceQuietWindows([]QuietWindow{
+		{Owner: "meeting:ollama-npu", Reason: "meeting", Started: started},
+		{Owner: "expired", Reason: "old", Started: started, Until: time.Now().Add(-time.Second)},
+	})
+
+	windows := em.QuietWindows()
+	if len(windows) != 1 || windows[0].Owner != "meeting:ollama-npu" {
+		t.Fatalf("Expected only the mirrored active window, got %+v", windows)
+	}
+	if !windows[0].Started.Equal(started) {
+		t.Errorf("Expected start time to be preserved, got %v", windows[0].Started)
+	}
+}
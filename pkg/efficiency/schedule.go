@@ -0,0 +1,379 @@
+package efficiency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// ScheduleCheckInterval is how often RunSchedule looks for an entry
+// starting or ending
+const ScheduleCheckInterval = 30 * time.Second
+
+// ScheduleEntry switches the effective mode to Mode during a daily time
+// window, e.g. Quiet from 22:00 to 06:00 on weekdays
+type ScheduleEntry struct {
+	Name string
+	Mode EfficiencyMode
+	Days []time.Weekday // empty = every day
+
+	// Start and End are offsets from midnight. A window whose End is at or
+	// before its Start runs past midnight into the next day; Start equal
+	// to End covers the whole day.
+	Start time.Duration
+	End   time.Duration
+}
+
+var weekdays = map[string][]time.Weekday{
+	"sun": {time.Sunday}, "mon": {time.Monday}, "tue": {time.Tuesday}, "wed": {time.Wednesday},
+	"thu": {time.Thursday}, "fri": {time.Friday}, "sat": {time.Saturday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+}
+
+// ParseScheduleEntry builds an entry from its config form: a mode name,
+// days such as "mon", "Friday", "weekdays" or "weekends" (none = every
+// day), and "HH:MM" start and end times
+func ParseScheduleEntry(name, mode string, days []string, start, end string) (ScheduleEntry, error) {
+	entry := ScheduleEntry{Name: name}
+	var err error
+	if entry.Mode, err = ParseMode(mode); err != nil {
+		return entry, fmt.Errorf("schedule %s: %w", name, err)
+	}
+	for _, day := range days {
+		key := strings.ToLower(day)
+		if len(key) > 3 && key != "weekdays" && key != "weekends" {
+			key = key[:3]
+		}
+		matched, ok := weekdays[key]
+		if !ok {
+			return entry, fmt.Errorf("schedule %s: unknown day %q", name, day)
+		}
+		entry.Days = append(entry.Days, matched...)
+	}
+	if entry.Start, err = parseClock(start); err != nil {
+		return entry, fmt.Errorf("schedule %s: start: %w", name, err)
+	}
+	if entry.End, err = parseClock(end); err != nil {
+		return entry, fmt.Errorf("schedule %s: end: %w", name, err)
+	}
+	return entry, nil
+}
+
+// parseClock parses "HH:MM" as an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("time must be HH:MM, got %q", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// Active reports whether now falls inside the entry's window
+func (e ScheduleEntry) Active(now time.Time) bool {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	offset := now.Sub(midnight)
+	switch {
+	case e.Start == e.End:
+		return e.on(now.Weekday())
+	case e.Start < e.End:
+		return e.on(now.Weekday()) && offset >= e.Start && offset < e.End
+	}
+	// Past midnight: the tail of the window started yesterday
+	if offset >= e.Start {
+		return e.on(now.Weekday())
+	}
+	return offset < e.End && e.on((now.Weekday()+6)%7)
+}
+
+func (e ScheduleEntry) on(day time.Weekday) bool {
+	if len(e.Days) == 0 {
+		return true
+	}
+	for _, d := range e.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduleEntryStatus is an entry as served by ScheduleHandler
+type ScheduleEntryStatus struct {
+	Name   string   `json:"name"`
+	Mode   string   `json:"mode"`
+	Days   []string `json:"days,omitempty"` // empty = every day
+	Start  string   `json:"start"`
+	End    string   `json:"end"`
+	Active bool     `json:"active"`
+}
+
+// ScheduleOverride replaces the schedule until Until. A Suspended override
+// turns the schedule off, leaving the selected mode in force; otherwise
+// Mode applies in place of whatever the schedule says.
+type ScheduleOverride struct {
+	Mode      EfficiencyMode
+	Suspended bool
+	Until     time.Time // zero = until cleared
+}
+
+func (o *ScheduleOverride) active(now time.Time) bool {
+	return o != nil && (o.Until.IsZero() || now.Before(o.Until))
+}
+
+// ScheduleStatus is the schedule's current state
+type ScheduleStatus struct {
+	Entries []ScheduleEntryStatus `json:"entries"`
+
+	// Entry in force and the mode the schedule applies; both empty when
+	// no entry is active or the schedule is suspended
+	Active string `json:"active,omitempty"`
+	Mode   string `json:"mode,omitempty"`
+
+	// Override in force: a mode, or "off" while the schedule is suspended
+	Override      string    `json:"override,omitempty"`
+	OverrideUntil time.Time `json:"override_until,omitempty"`
+}
+
+// ScheduleOff is the override mode name that suspends the schedule
+const ScheduleOff = "off"
+
+// SetSchedule replaces the schedule. When entries overlap the first
+// active one wins.
+func (em *EfficiencyManager) SetSchedule(entries []ScheduleEntry) {
+	em.mu.Lock()
+	em.schedule = append([]ScheduleEntry(nil), entries...)
+	em.mu.Unlock()
+	em.CheckSchedule(time.Now())
+}
+
+// OverrideSchedule applies mode in place of the schedule for duration (0 =
+// until ClearScheduleOverride). ScheduleOff suspends the schedule instead.
+func (em *EfficiencyManager) OverrideSchedule(mode string, duration time.Duration) error {
+	override := &ScheduleOverride{Suspended: strings.EqualFold(mode, ScheduleOff)}
+	if !override.Suspended {
+		parsed, err := ParseMode(mode)
+		if err != nil {
+			return err
+		}
+		override.Mode = parsed
+	}
+	if duration > 0 {
+		override.Until = time.Now().Add(duration)
+	}
+
+	em.mu.Lock()
+	em.scheduleOverride = override
+	em.mu.Unlock()
+
+	if logging.Logger != nil {
+		logging.Logger.Info("Efficiency schedule overridden",
+			zap.String("mode", mode),
+			zap.Duration("duration", duration),
+		)
+	}
+	em.CheckSchedule(time.Now())
+	return nil
+}
+
+// ClearScheduleOverride puts the schedule back in force. It reports
+// whether an override existed.
+func (em *EfficiencyManager) ClearScheduleOverride() bool {
+	em.mu.Lock()
+	existed := em.scheduleOverride.active(time.Now())
+	em.scheduleOverride = nil
+	em.mu.Unlock()
+
+	if existed && logging.Logger != nil {
+		logging.Logger.Info("Efficiency schedule override cleared")
+	}
+	em.CheckSchedule(time.Now())
+	return existed
+}
+
+// OnScheduleChange registers fn to be called whenever the entry or mode
+// the schedule applies changes, including through an override. fn must
+// not block.
+func (em *EfficiencyManager) OnScheduleChange(fn func(ScheduleStatus)) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.scheduleListeners = append(em.scheduleListeners, fn)
+}
+
+// CheckSchedule notifies schedule listeners if an entry started or ended
+// or an override expired since the last check
+func (em *EfficiencyManager) CheckSchedule(now time.Time) {
+	em.mu.Lock()
+	status := em.scheduleStatusLocked(now)
+	var key string // empty while the schedule applies nothing, as before the first check
+	if status.Mode != "" || status.Override != "" {
+		key = status.Active + "|" + status.Mode + "|" + status.Override
+	}
+	if key == em.scheduleKey {
+		em.mu.Unlock()
+		return
+	}
+	em.scheduleKey = key
+	listeners := make([]func(ScheduleStatus), len(em.scheduleListeners))
+	copy(listeners, em.scheduleListeners)
+	em.mu.Unlock()
+
+	if logging.Logger != nil {
+		logging.Logger.Info("Efficiency schedule changed",
+			zap.String("entry", status.Active),
+			zap.String("mode", status.Mode),
+			zap.String("override", status.Override),
+		)
+	}
+	for _, fn := range listeners {
+		fn(status)
+	}
+}
+
+// RunSchedule calls CheckSchedule every ScheduleCheckInterval until ctx
+// is done
+func (em *EfficiencyManager) RunSchedule(ctx context.Context) {
+	ticker := time.NewTicker(ScheduleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			em.CheckSchedule(now)
+		}
+	}
+}
+
+// ScheduleStatus returns the schedule and what it applies at now
+func (em *EfficiencyManager) ScheduleStatus(now time.Time) ScheduleStatus {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+	return em.scheduleStatusLocked(now)
+}
+
+// scheduleStatusLocked builds the status at now. Caller must hold em.mu.
+func (em *EfficiencyManager) scheduleStatusLocked(now time.Time) ScheduleStatus {
+	status := ScheduleStatus{Entries: make([]ScheduleEntryStatus, 0, len(em.schedule))}
+	for _, entry := range em.schedule {
+		s := ScheduleEntryStatus{
+			Name:   entry.Name,
+			Mode:   entry.Mode.String(),
+			Start:  formatClock(entry.Start),
+			End:    formatClock(entry.End),
+			Active: entry.Active(now),
+		}
+		for _, day := range entry.Days {
+			s.Days = append(s.Days, day.String()[:3])
+		}
+		status.Entries = append(status.Entries, s)
+	}
+
+	if o := em.scheduleOverride; o.active(now) {
+		status.OverrideUntil = o.Until
+		if o.Suspended {
+			status.Override = ScheduleOff
+			return status
+		}
+		status.Override = o.Mode.String()
+		status.Mode = o.Mode.String()
+		return status
+	}
+	if entry, ok := em.activeEntryLocked(now); ok {
+		status.Active = entry.Name
+		status.Mode = entry.Mode.String()
+	}
+	return status
+}
+
+// activeEntryLocked returns the first entry active at now. Caller must
+// hold em.mu.
+func (em *EfficiencyManager) activeEntryLocked(now time.Time) (ScheduleEntry, bool) {
+	for _, entry := range em.schedule {
+		if entry.Active(now) {
+			return entry, true
+		}
+	}
+	return ScheduleEntry{}, false
+}
+
+// scheduledModeLocked returns the mode the schedule applies at now: the
+// override's, else the active entry's. Caller must hold em.mu.
+func (em *EfficiencyManager) scheduledModeLocked(now time.Time) (EfficiencyMode, bool) {
+	if o := em.scheduleOverride; o.active(now) {
+		return o.Mode, !o.Suspended
+	}
+	entry, ok := em.activeEntryLocked(now)
+	return entry.Mode, ok
+}
+
+// InQuietHours reports whether now is in quiet hours, which Auto mode
+// treats as a reason to pick Quiet: inside an active Quiet entry of the
+// schedule or, without a schedule, between 22:00 and 06:00
+func (em *EfficiencyManager) InQuietHours(now time.Time) bool {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+	if len(em.schedule) == 0 {
+		return now.Hour() >= 22 || now.Hour() < 6
+	}
+	for _, entry := range em.schedule {
+		if entry.Mode == ModeQuiet && entry.Active(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleOverrideRequest is the body of POST /admin/schedule
+type scheduleOverrideRequest struct {
+	Mode            string `json:"mode"`             // a mode, or "off" to suspend the schedule
+	DurationSeconds int    `json:"duration_seconds"` // 0 = until DELETE
+}
+
+// ScheduleHandler serves the schedule: GET returns it with the entry in
+// force, POST {"mode": "Quiet", "duration_seconds": 3600} overrides it
+// ("off" suspends it) and DELETE clears the override
+func (em *EfficiencyManager) ScheduleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req scheduleOverrideRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.DurationSeconds < 0 {
+				http.Error(w, "duration_seconds must be non-negative", http.StatusBadRequest)
+				return
+			}
+			if err := em.OverrideSchedule(req.Mode, time.Duration(req.DurationSeconds)*time.Second); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			em.ClearScheduleOverride()
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		response := map[string]interface{}{
+			"schedule":       em.ScheduleStatus(time.Now()),
+			"effective_mode": em.GetEffectiveMode().String(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,258 @@
+package efficiency
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// allDay is an entry active all day, every day
+func allDay(name string, mode EfficiencyMode) ScheduleEntry {
+	return ScheduleEntry{Name: name, Mode: mode}
+}
+
+func TestParseScheduleEntry(t *testing.T) {
+	entry, err := ParseScheduleEntry("nights", "Quiet", []string{"weekdays", "Saturday"}, "22:00", "06:30")
+	if err != nil {
+		t.Fatalf("ParseScheduleEntry failed: %v", err)
+	}
+	if entry.Mode != ModeQuiet || entry.Start != 22*time.Hour || entry.End != 6*time.Hour+30*time.Minute {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if len(entry.Days) != 6 || entry.Days[5] != time.Saturday {
+		t.Errorf("Expected Monday-Saturday, got %v", entry.Days)
+	}
+
+	tests := []struct {
+		mode       string
+		days       []string
+		start, end string
+		want       string
+	}{
+		{"Loud", nil, "09:00", "17:00", "unknown mode"},
+		{"Quiet", []string{"someday"}, "09:00", "17:00", "unknown day"},
+		{"Quiet", nil, "9am", "17:00", "start"},
+		{"Quiet", nil, "09:00", "25:00", "end"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseScheduleEntry("bad", tt.mode, tt.days, tt.start, tt.end); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseScheduleEntry(%q, %v, %q, %q) error = %v, want %q", tt.mode, tt.days, tt.start, tt.end, err, tt.want)
+		}
+	}
+}
+
+func TestScheduleEntry_Active(t *testing.T) {
+	// 2026-10-16 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.Local)
+	}
+
+	office, _ := ParseScheduleEntry("office", "Performance", []string{"weekdays"}, "09:00", "17:00")
+	nights, _ := ParseScheduleEntry("nights", "Quiet", []string{"fri"}, "22:00", "06:00")
+	sunday, _ := ParseScheduleEntry("sunday", "Efficiency", []string{"sun"}, "00:00", "00:00")
+
+	tests := []struct {
+		entry ScheduleEntry
+		at    time.Time
+		want  bool
+	}{
+		{office, at(16, 9, 0), true},
+		{office, at(16, 16, 59), true},
+		{office, at(16, 17, 0), false},
+		{office, at(17, 10, 0), false}, // Saturday
+		{nights, at(16, 23, 0), true},
+		{nights, at(17, 5, 59), true}, // Saturday morning, started Friday
+		{nights, at(17, 6, 0), false},
+		{nights, at(16, 5, 0), false}, // Friday morning belongs to Thursday night
+		{nights, at(16, 21, 59), false},
+		{sunday, at(17, 12, 0), false},
+		{sunday, at(18, 23, 59), true},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.Active(tt.at); got != tt.want {
+			t.Errorf("%s.Active(%s) = %v, want %v", tt.entry.Name, tt.at.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestSchedule_OverridesSelectedMode(t *testing.T) {
+	em := NewEfficiencyManager(ModePerformance)
+
+	var changes []ScheduleStatus
+	em.OnScheduleChange(func(status ScheduleStatus) { changes = append(changes, status) })
+
+	em.SetSchedule([]ScheduleEntry{allDay("always", ModeEfficiency)})
+	if mode := em.GetEffectiveMode(); mode != ModeEfficiency {
+		t.Errorf("Expected the scheduled Efficiency mode, got %s", mode)
+	}
+	if em.GetMode() != ModePerformance {
+		t.Error("Expected the selected mode to be left unchanged")
+	}
+	if desc := em.GetModeDescription(); !strings.Contains(desc, "schedule: always") {
+		t.Errorf("Expected the description to mention the schedule, got %q", desc)
+	}
+	if len(changes) != 1 || changes[0].Active != "always" || changes[0].Mode != "Efficiency" {
+		t.Errorf("Expected one change to the always entry, got %+v", changes)
+	}
+
+	// Quiet windows still win
+	em.StartQuietWindow(ManualQuietOwner, "focus", 0)
+	if mode := em.GetEffectiveMode(); mode != ModeQuiet {
+		t.Errorf("Expected a quiet window to override the schedule, got %s", mode)
+	}
+	em.EndQuietWindow(ManualQuietOwner)
+
+	// Checking again without a change notifies nobody
+	em.CheckSchedule(time.Now())
+	if len(changes) != 1 {
+		t.Errorf("Expected no change notification, got %d", len(changes))
+	}
+
+	em.SetSchedule(nil)
+	if mode := em.GetEffectiveMode(); mode != ModePerformance {
+		t.Errorf("Expected the selected mode without a schedule, got %s", mode)
+	}
+	if len(changes) != 2 || changes[1].Mode != "" {
+		t.Errorf("Expected a change back to no scheduled mode, got %+v", changes)
+	}
+}
+
+func TestSchedule_Override(t *testing.T) {
+	em := NewEfficiencyManager(ModeBalanced)
+	em.SetSchedule([]ScheduleEntry{allDay("always", ModeQuiet)})
+
+	if err := em.OverrideSchedule("Performance", time.Hour); err != nil {
+		t.Fatalf("OverrideSchedule failed: %v", err)
+	}
+	status := em.ScheduleStatus(time.Now())
+	if em.GetEffectiveMode() != ModePerformance || status.Override != "Performance" || status.OverrideUntil.IsZero() {
+		t.Errorf("Expected a Performance override for an hour, got %s %+v", em.GetEffectiveMode(), status)
+	}
+
+	if err := em.OverrideSchedule(ScheduleOff, 0); err != nil {
+		t.Fatalf("OverrideSchedule off failed: %v", err)
+	}
+	if mode := em.GetEffectiveMode(); mode != ModeBalanced {
+		t.Errorf("Expected the selected mode while the schedule is off, got %s", mode)
+	}
+	if status = em.ScheduleStatus(time.Now()); status.Override != ScheduleOff || status.Mode != "" || !status.Entries[0].Active {
+		t.Errorf("Unexpected status while suspended: %+v", status)
+	}
+
+	if err := em.OverrideSchedule("Loud", 0); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+
+	if !em.ClearScheduleOverride() {
+		t.Error("Expected ClearScheduleOverride to report the override")
+	}
+	if mode := em.GetEffectiveMode(); mode != ModeQuiet {
+		t.Errorf("Expected the schedule back in force, got %s", mode)
+	}
+
+	// Expired overrides no longer apply
+	em.OverrideSchedule("Performance", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if mode := em.GetEffectiveMode(); mode != ModeQuiet {
+		t.Errorf("Expected an expired override to lapse, got %s", mode)
+	}
+}
+
+func TestSchedule_AutoResolves(t *testing.T) {
+	em := NewEfficiencyManager(ModePerformance)
+	em.UpdateSystemState(10, true, 50, 30, false)
+	em.SetSchedule([]ScheduleEntry{allDay("auto", ModeAuto)})
+
+	if mode := em.GetEffectiveMode(); mode != ModeUltraEfficiency {
+		t.Errorf("Expected a scheduled Auto to resolve on low battery, got %s", mode)
+	}
+}
+
+func TestInQuietHours(t *testing.T) {
+	em := NewEfficiencyManager(ModeAuto)
+	night := time.Date(2026, 10, 16, 23, 0, 0, 0, time.Local)
+	noon := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+
+	if !em.InQuietHours(night) || em.InQuietHours(noon) {
+		t.Error("Expected 22:00-06:00 quiet hours without a schedule")
+	}
+
+	lunch, _ := ParseScheduleEntry("lunch", "Quiet", nil, "11:30", "13:00")
+	office, _ := ParseScheduleEntry("office", "Performance", nil, "09:00", "17:00")
+	em.SetSchedule([]ScheduleEntry{office, lunch})
+	if em.InQuietHours(night) || !em.InQuietHours(noon) {
+		t.Error("Expected quiet hours to follow the schedule's Quiet entries")
+	}
+}
+
+func TestScheduleHandler(t *testing.T) {
+	em := NewEfficiencyManager(ModeBalanced)
+	em.SetSchedule([]ScheduleEntry{allDay("always", ModeQuiet)})
+	handler := em.ScheduleHandler()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/admin/schedule", nil))
+	var resp struct {
+		Schedule      ScheduleStatus `json:"schedule"`
+		EffectiveMode string         `json:"effective_mode"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.EffectiveMode != "Quiet" || resp.Schedule.Active != "always" || len(resp.Schedule.Entries) != 1 {
+		t.Errorf("Unexpected schedule: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/admin/schedule", strings.NewReader(`{"mode":"Performance","duration_seconds":600}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.EffectiveMode != "Performance" || resp.Schedule.Override != "Performance" {
+		t.Errorf("Unexpected response after override: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodDelete, "/admin/schedule", nil))
+	if em.GetEffectiveMode() != ModeQuiet {
+		t.Error("Expected DELETE to clear the override")
+	}
+
+	for _, body := range []string{`{"mode":"Loud"}`, `{"mode":"Quiet","duration_seconds":-1}`, `not json`} {
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, "/admin/schedule", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestDBusSchedule(t *testing.T) {
+	em := NewEfficiencyManager(ModeBalanced)
+	nights, _ := ParseScheduleEntry("nights", "Quiet", []string{"fri", "sat"}, "22:00", "06:00")
+	em.SetSchedule([]ScheduleEntry{allDay("always", ModeEfficiency), nights})
+	ds := &DBusService{manager: em}
+
+	rows, _ := ds.GetSchedule()
+	if len(rows) != 2 || rows[0].Name != "always" || !rows[0].Active || rows[1].Days != "Fri,Sat" || rows[1].Start != "22:00" {
+		t.Errorf("Unexpected schedule rows: %+v", rows)
+	}
+
+	if err := ds.OverrideSchedule("off", 60); err != nil {
+		t.Fatalf("OverrideSchedule failed: %v", err)
+	}
+	entry, mode, override, remaining, _ := ds.GetScheduleStatus()
+	if entry != "" || mode != "" || override != ScheduleOff || remaining == 0 || remaining > 60 {
+		t.Errorf("Unexpected status: entry=%q mode=%q override=%q remaining=%d", entry, mode, override, remaining)
+	}
+	if err := ds.OverrideSchedule("Loud", 0); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+
+	ds.ClearScheduleOverride()
+	if entry, mode, _, _, _ := ds.GetScheduleStatus(); entry != "always" || mode != "Efficiency" {
+		t.Errorf("Expected the always entry back in force, got %q %q", entry, mode)
+	}
+}
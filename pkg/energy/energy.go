@@ -0,0 +1,261 @@
+// Package energy measures the energy requests actually use. Sources read
+// cumulative energy counters per power domain (Intel RAPL, NVIDIA NVML);
+// a Meter samples them and splits each interval's energy on a device
+// among the requests running on it, in proportion to how much of the
+// interval each was running. A request's energy is therefore its share of
+// everything the device drew while it ran, idle draw included, rather
+// than a static PowerWatts times its duration.
+package energy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// DefaultInterval is how often energy counters are sampled
+const DefaultInterval = time.Second
+
+// Counter is one power domain's cumulative energy
+type Counter struct {
+	Hardware string  // routing hardware class the domain powers: cpu, igpu or nvidia
+	Domain   string  // e.g. "intel-rapl:0" or "nvidia0"
+	Joules   float64 // since an arbitrary start, never decreasing
+}
+
+// Source reads the energy counters one interface can see
+type Source interface {
+	Name() string
+	// Read returns no counters, not an error, when there is nothing to
+	// read; an error means the interface itself is unavailable
+	Read(ctx context.Context) ([]Counter, error)
+}
+
+// hardwareState is the energy of one hardware class, summed over its
+// domains, and the requests running on it
+type hardwareState struct {
+	joules float64   // latest reading
+	at     time.Time // when it was read
+	watts  float64   // average over the last interval
+
+	// Energy up to attributedAt has been split among spans
+	attributedJ  float64
+	attributedAt time.Time
+
+	spans map[*Span]struct{}
+}
+
+// Meter samples energy sources and attributes energy to requests
+type Meter struct {
+	sources  []Source
+	interval time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	hardware map[string]*hardwareState
+	failing  map[string]bool // sources whose last read failed, logged once
+}
+
+// NewMeter creates a meter sampling sources every interval (0 =
+// DefaultInterval). With no sources it reads RAPL and NVML.
+func NewMeter(interval time.Duration, sources ...Source) *Meter {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if len(sources) == 0 {
+		sources = []Source{NewRAPLSource("/sys"), NewNVMLSource()}
+	}
+	return &Meter{
+		sources:  sources,
+		interval: interval,
+		now:      time.Now,
+		hardware: make(map[string]*hardwareState),
+		failing:  make(map[string]bool),
+	}
+}
+
+// Run samples until ctx is cancelled
+func (m *Meter) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		middleware.Safe(middleware.ScopeBackground, "energy-meter", func() { m.Sample(ctx) })
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample reads every source once and attributes the energy used since
+// the previous sample
+func (m *Meter) Sample(ctx context.Context) {
+	totals := make(map[string]float64)
+	for _, source := range m.sources {
+		counters, err := source.Read(ctx)
+		m.noteFailure(source.Name(), err)
+		for _, c := range counters {
+			totals[c.Hardware] += c.Joules
+		}
+	}
+
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hardware, joules := range totals {
+		st, ok := m.hardware[hardware]
+		if !ok {
+			m.hardware[hardware] = &hardwareState{
+				joules:       joules,
+				at:           now,
+				attributedJ:  joules,
+				attributedAt: now,
+				spans:        make(map[*Span]struct{}),
+			}
+			continue
+		}
+		if dt := now.Sub(st.at).Seconds(); dt > 0 && joules >= st.joules {
+			st.watts = (joules - st.joules) / dt
+		}
+		st.joules, st.at = joules, now
+		st.attribute(now, joules)
+		metrics.SetDevicePower(hardware, st.watts)
+	}
+}
+
+// attribute splits the energy between attributedJ and joules among the
+// spans running in the window ending at now, each by the part of the
+// window it ran for. Energy with no span running is idle and dropped.
+func (st *hardwareState) attribute(now time.Time, joules float64) {
+	energy := joules - st.attributedJ
+	start := st.attributedAt
+	if joules > st.attributedJ {
+		st.attributedJ = joules
+	}
+	st.attributedAt = now
+	if energy <= 0 || len(st.spans) == 0 {
+		return
+	}
+
+	var total time.Duration
+	for span := range st.spans {
+		total += span.overlap(start, now)
+	}
+	if total <= 0 {
+		return
+	}
+	for span := range st.spans {
+		span.joules += energy * float64(span.overlap(start, now)) / float64(total)
+	}
+}
+
+// Measures reports whether the energy of hardware is metered
+func (m *Meter) Measures(hardware string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.hardware[hardware]
+	return ok
+}
+
+// Power returns the average power of hardware over the last interval
+func (m *Meter) Power(hardware string) (float64, bool) {
+	if m == nil {
+		return 0, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.hardware[hardware]
+	if !ok {
+		return 0, false
+	}
+	return st.watts, true
+}
+
+// Start begins measuring a request running on hardware. It returns nil,
+// whose End reports nothing, when the hardware is not metered.
+func (m *Meter) Start(hardware string) *Span {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.hardware[hardware]
+	if !ok {
+		return nil
+	}
+	span := &Span{meter: m, hardware: hardware, start: m.now()}
+	st.spans[span] = struct{}{}
+	return span
+}
+
+// Span is one request's share of its hardware's energy
+type Span struct {
+	meter    *Meter
+	hardware string
+	start    time.Time
+	joules   float64
+	ended    bool
+}
+
+// overlap is how long the span ran within [from, to]
+func (s *Span) overlap(from, to time.Time) time.Duration {
+	if s.start.After(from) {
+		from = s.start
+	}
+	if d := to.Sub(from); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// End stops measuring and returns the request's energy in watt-hours. The
+// energy since the last sample is extrapolated from the hardware's recent
+// power, and corrected for at the next sample. ok is false for a nil span
+// or one already ended.
+func (s *Span) End() (wh float64, ok bool) {
+	if s == nil {
+		return 0, false
+	}
+	m := s.meter
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s.ended {
+		return 0, false
+	}
+	s.ended = true
+
+	st := m.hardware[s.hardware]
+	now := m.now()
+	if since := now.Sub(st.at).Seconds(); since > 0 {
+		st.attribute(now, st.joules+st.watts*since)
+	}
+	delete(st.spans, s)
+	return s.joules / 3600, true
+}
+
+// noteFailure logs a source becoming unavailable or recovering, once
+func (m *Meter) noteFailure(source string, err error) {
+	m.mu.Lock()
+	was := m.failing[source]
+	m.failing[source] = err != nil
+	m.mu.Unlock()
+
+	if logging.Logger == nil || was == (err != nil) {
+		return
+	}
+	if err != nil {
+		logging.Logger.Debug("Energy source unavailable", zap.String("source", source), zap.Error(err))
+	} else {
+		logging.Logger.Debug("Energy source recovered", zap.String("source", source))
+	}
+}
@@ -0,0 +1,173 @@
+package energy
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeSource reports fixed cumulative joules per hardware class
+type fakeSource struct {
+	joules map[string]float64
+}
+
+func (s *fakeSource) Name() string { return "fake" }
+
+func (s *fakeSource) Read(ctx context.Context) ([]Counter, error) {
+	var counters []Counter
+	for hw, j := range s.joules {
+		counters = append(counters, Counter{Hardware: hw, Domain: hw + "0", Joules: j})
+	}
+	return counters, nil
+}
+
+// newTestMeter returns a meter over source with a settable clock
+func newTestMeter(source Source) (*Meter, *time.Time) {
+	now := time.Unix(1000, 0)
+	m := NewMeter(time.Second, source)
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestMeter_SplitsEnergyByOverlap(t *testing.T) {
+	src := &fakeSource{joules: map[string]float64{"nvidia": 0}}
+	m, now := newTestMeter(src)
+	m.Sample(context.Background())
+
+	if m.Start("npu") != nil {
+		t.Fatal("Expected no span on hardware without a source")
+	}
+
+	// a runs the whole 2s window, b the second half
+	a := m.Start("nvidia")
+	*now = now.Add(time.Second)
+	b := m.Start("nvidia")
+	*now = now.Add(time.Second)
+	src.joules["nvidia"] = 300
+	m.Sample(context.Background())
+
+	// Then b alone for another second, ending exactly at the sample
+	wa, ok := a.End()
+	if !ok || !approx(wa, 200.0/3600) {
+		t.Errorf("Expected a to get 200 J, got %v Wh (ok=%v)", wa*3600, ok)
+	}
+	*now = now.Add(time.Second)
+	src.joules["nvidia"] = 450
+	m.Sample(context.Background())
+	wb, _ := b.End()
+	if !approx(wb, 250.0/3600) {
+		t.Errorf("Expected b to get 100 + 150 J, got %v J", wb*3600)
+	}
+
+	if _, ok := a.End(); ok {
+		t.Error("Expected a second End to report nothing")
+	}
+	if watts, _ := m.Power("nvidia"); watts != 150 {
+		t.Errorf("Expected 150 W over the last interval, got %v", watts)
+	}
+}
+
+func TestMeter_ExtrapolatesBetweenSamples(t *testing.T) {
+	src := &fakeSource{joules: map[string]float64{"cpu": 0}}
+	m, now := newTestMeter(src)
+	m.Sample(context.Background())
+	*now = now.Add(time.Second)
+	src.joules["cpu"] = 40
+	m.Sample(context.Background()) // 40 W, nothing running
+
+	span := m.Start("cpu")
+	*now = now.Add(500 * time.Millisecond)
+	wh, _ := span.End()
+	if !approx(wh*3600, 20) {
+		t.Errorf("Expected half a second at 40 W, got %v J", wh*3600)
+	}
+
+	// The next sample attributes only what the extrapolation didn't
+	next := m.Start("cpu")
+	*now = now.Add(500 * time.Millisecond)
+	src.joules["cpu"] = 90
+	m.Sample(context.Background())
+	wh, _ = next.End()
+	if !approx(wh*3600, 30) {
+		t.Errorf("Expected the remaining 30 J, got %v J", wh*3600)
+	}
+}
+
+func TestMeter_NilIsSafe(t *testing.T) {
+	var m *Meter
+	if m.Start("cpu") != nil || m.Measures("cpu") {
+		t.Error("Expected a nil meter to measure nothing")
+	}
+	if _, ok := m.Start("cpu").End(); ok {
+		t.Error("Expected a nil span to report nothing")
+	}
+}
+
+func TestRAPLSource_UnwrapsCounters(t *testing.T) {
+	root := t.TempDir()
+	write := func(file, content string) {
+		path := filepath.Join(root, "class/powercap", file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("intel-rapl:0/name", "package-0\n")
+	write("intel-rapl:0/energy_uj", "9000000\n")
+	write("intel-rapl:0/max_energy_range_uj", "10000000\n")
+	write("intel-rapl:0:1/name", "uncore\n")
+	write("intel-rapl:0:1/energy_uj", "100\n")
+	write("intel-rapl:1/name", "psys\n")
+	write("intel-rapl:1/energy_uj", "5\n")
+
+	s := NewRAPLSource(root)
+	counters, err := s.Read(context.Background())
+	if err != nil || len(counters) != 2 {
+		t.Fatalf("Expected package and uncore, got %+v, %v", counters, err)
+	}
+
+	// The package counter wraps: 9 J -> 10 J (max) -> 2 J
+	write("intel-rapl:0/energy_uj", "2000000\n")
+	write("intel-rapl:0:1/energy_uj", "500100\n")
+	counters, _ = s.Read(context.Background())
+	got := make(map[string]float64)
+	for _, c := range counters {
+		got[c.Hardware] = c.Joules
+	}
+	if !approx(got["cpu"], 3) || !approx(got["igpu"], 0.5) {
+		t.Errorf("Expected cpu 3 J and igpu 0.5 J, got %v", got)
+	}
+}
+
+func TestRAPLSource_NoPowercap(t *testing.T) {
+	if _, err := NewRAPLSource(t.TempDir()).Read(context.Background()); err == nil {
+		t.Error("Expected an error without RAPL domains")
+	}
+}
+
+func TestNVMLSource_IntegratesPower(t *testing.T) {
+	out := "0, 100.00\n1, [N/A]\n"
+	now := time.Unix(1000, 0)
+	s := NewNVMLSource()
+	s.now = func() time.Time { return now }
+	s.run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte(out), nil
+	}
+
+	if counters, err := s.Read(context.Background()); err != nil || len(counters) != 1 || counters[0].Joules != 0 {
+		t.Fatalf("Expected one GPU at 0 J, got %+v, %v", counters, err)
+	}
+	now = now.Add(2 * time.Second)
+	out = "0, 200.00\n1, [N/A]\n"
+	counters, _ := s.Read(context.Background())
+	if len(counters) != 1 || counters[0].Hardware != "nvidia" || counters[0].Domain != "nvidia0" || counters[0].Joules != 300 {
+		t.Errorf("Expected 2s averaging 150 W = 300 J, got %+v", counters)
+	}
+}
@@ -0,0 +1,174 @@
+package energy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandRunner runs a command and returns its standard output
+type commandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// RAPLSource reads Intel RAPL energy counters through the powercap
+// interface, which AMD Zen CPUs also expose. The package domain is
+// reported as cpu and the uncore (integrated graphics) as igpu; since the
+// package includes the uncore, cpu covers an iGPU's draw too.
+//
+// Since Linux 5.10 energy_uj is readable only by root; grant access with
+// a udev rule or run the proxy with CAP_DAC_READ_SEARCH.
+type RAPLSource struct {
+	sysRoot string
+
+	mu      sync.Mutex
+	domains map[string]*raplDomain
+}
+
+// raplDomain unwraps one domain's counter, which wraps at
+// max_energy_range_uj
+type raplDomain struct {
+	lastUJ  uint64
+	totalUJ float64
+}
+
+// NewRAPLSource creates a RAPL source reading under sysRoot ("/sys")
+func NewRAPLSource(sysRoot string) *RAPLSource {
+	return &RAPLSource{sysRoot: sysRoot, domains: make(map[string]*raplDomain)}
+}
+
+func (s *RAPLSource) Name() string { return "rapl" }
+
+func (s *RAPLSource) Read(ctx context.Context) ([]Counter, error) {
+	dirs, err := filepath.Glob(filepath.Join(s.sysRoot, "class", "powercap", "intel-rapl:*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no RAPL domains under %s", filepath.Join(s.sysRoot, "class", "powercap"))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var counters []Counter
+	for _, dir := range dirs {
+		hardware := raplHardware(readString(filepath.Join(dir, "name")))
+		if hardware == "" {
+			continue
+		}
+		uj, err := readUint(filepath.Join(dir, "energy_uj"))
+		if err != nil {
+			return nil, err // most often permission denied
+		}
+
+		domain := filepath.Base(dir)
+		d, ok := s.domains[domain]
+		switch {
+		case !ok:
+			d = &raplDomain{}
+			s.domains[domain] = d
+		case uj >= d.lastUJ:
+			d.totalUJ += float64(uj - d.lastUJ)
+		default:
+			if max, err := readUint(filepath.Join(dir, "max_energy_range_uj")); err == nil && max > d.lastUJ {
+				d.totalUJ += float64(max - d.lastUJ + uj)
+			}
+		}
+		d.lastUJ = uj
+		counters = append(counters, Counter{Hardware: hardware, Domain: domain, Joules: d.totalUJ / 1e6})
+	}
+	return counters, nil
+}
+
+// raplHardware maps a RAPL domain name to the hardware class it powers
+func raplHardware(name string) string {
+	switch {
+	case strings.HasPrefix(name, "package-"):
+		return "cpu"
+	case name == "uncore":
+		return "igpu"
+	}
+	return "" // core, dram and psys overlap the package or aren't a backend's
+}
+
+// NVMLSource reads NVIDIA GPU power draw through nvidia-smi, which
+// reports NVML's power readings, and integrates it into energy
+type NVMLSource struct {
+	run commandRunner
+	now func() time.Time
+
+	mu   sync.Mutex
+	gpus map[string]*nvmlGPU
+}
+
+// nvmlGPU integrates one GPU's power samples
+type nvmlGPU struct {
+	at     time.Time
+	watts  float64
+	joules float64
+}
+
+// NewNVMLSource creates an NVML source
+func NewNVMLSource() *NVMLSource {
+	return &NVMLSource{run: runCommand, now: time.Now, gpus: make(map[string]*nvmlGPU)}
+}
+
+func (s *NVMLSource) Name() string { return "nvml" }
+
+func (s *NVMLSource) Read(ctx context.Context) ([]Counter, error) {
+	out, err := s.run(ctx, "nvidia-smi", "--query-gpu=index,power.draw", "--format=csv,noheader,nounits")
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi failed: %w", err)
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var counters []Counter
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 {
+			continue
+		}
+		domain := "nvidia" + strings.TrimSpace(fields[0])
+		watts, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil {
+			continue // [N/A] on GPUs without power readings
+		}
+
+		gpu, ok := s.gpus[domain]
+		if !ok {
+			gpu = &nvmlGPU{}
+			s.gpus[domain] = gpu
+		} else if dt := now.Sub(gpu.at).Seconds(); dt > 0 {
+			gpu.joules += (gpu.watts + watts) / 2 * dt
+		}
+		gpu.at, gpu.watts = now, watts
+		counters = append(counters, Counter{Hardware: "nvidia", Domain: domain, Joules: gpu.joules})
+	}
+	return counters, nil
+}
+
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
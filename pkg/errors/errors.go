@@ -13,6 +13,7 @@ const (
 	CodeBackendCapacity       = 1004
 	CodeBackendUnsupported    = 1005
 	CodeCircuitBreakerOpen    = 1006
+	CodeInsufficientMemory    = 1007
 
 	// Routing errors (2xxx)
 	CodeRoutingFailed         = 2001
@@ -146,6 +147,23 @@ func (e *CircuitBreakerOpenError) Code() int {
 	return CodeCircuitBreakerOpen
 }
 
+// InsufficientMemoryError indicates no backend has the accelerator memory
+// a request needs
+type InsufficientMemoryError struct {
+	Model          string
+	RequiredBytes  uint64
+	AvailableBytes uint64 // most free on any backend that could serve it
+}
+
+func (e *InsufficientMemoryError) Error() string {
+	return fmt.Sprintf("insufficient memory for model %s: needs %d MiB, at most %d MiB free",
+		e.Model, e.RequiredBytes>>20, e.AvailableBytes>>20)
+}
+
+func (e *InsufficientMemoryError) Code() int {
+	return CodeInsufficientMemory
+}
+
 // RoutingError indicates routing failed
 type RoutingError struct {
 	Reason       string
@@ -303,6 +321,15 @@ func NewBackendUnsupportedError(backendID, model, reason string) *BackendUnsuppo
 	}
 }
 
+// NewInsufficientMemoryError creates a new InsufficientMemoryError
+func NewInsufficientMemoryError(model string, required, available uint64) *InsufficientMemoryError {
+	return &InsufficientMemoryError{
+		Model:          model,
+		RequiredBytes:  required,
+		AvailableBytes: available,
+	}
+}
+
 // NewValidationError creates a new ValidationError
 func NewValidationError(field string, value interface{}, reason string) *ValidationError {
 	return &ValidationError{
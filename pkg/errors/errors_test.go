@@ -147,6 +147,22 @@ func TestCircuitBreakerOpenError(t *testing.T) {
 	}
 }
 
+func TestInsufficientMemoryError(t *testing.T) {
+	err := NewInsufficientMemoryError("llama3:70b", 48<<30, 22<<30)
+
+	if got := err.Code(); got != CodeInsufficientMemory {
+		t.Errorf("Code() = %v, want %v", got, CodeInsufficientMemory)
+	}
+
+	errMsg := err.Error()
+	wantContains := []string{"insufficient memory", "llama3:70b", "49152 MiB", "22528 MiB"}
+	for _, want := range wantContains {
+		if !strings.Contains(errMsg, want) {
+			t.Errorf("Error() = %q, want to contain %q", errMsg, want)
+		}
+	}
+}
+
 func TestRoutingError(t *testing.T) {
 	tests := []struct {
 		name        string
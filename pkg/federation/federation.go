@@ -0,0 +1,376 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/labels"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// Headers of a signed request
+const (
+	HeaderInstance  = "X-Proxy-Instance"  // signing proxy's instance ID
+	HeaderTimestamp = "X-Proxy-Timestamp" // unix seconds
+	HeaderClaims    = "X-Proxy-Claims"    // base64url JSON Claims
+	HeaderSignature = "X-Proxy-Signature" // base64url ed25519 signature
+)
+
+// DefaultMaxSkew is how far a signed request's timestamp may be from the
+// receiver's clock
+const DefaultMaxSkew = time.Minute
+
+// maxSignedBody bounds the request bodies a Verifier hashes
+const maxSignedBody = 32 << 20
+
+// Claims carry the identity of the client a request is forwarded for
+type Claims struct {
+	Key    string            `json:"key,omitempty"`    // API key name
+	Tenant string            `json:"tenant,omitempty"` // tenant of the key
+	Labels map[string]string `json:"labels,omitempty"` // X-Labels accepted by the first proxy
+	Via    []string          `json:"via,omitempty"`    // instances the request passed through, first first
+
+	// Set by Sign: the instance the request is for, and a unique ID so
+	// a captured request cannot be replayed
+	Target string `json:"target,omitempty"`
+	ID     string `json:"jti,omitempty"`
+}
+
+// ClaimsFromContext returns the claims of the client a request is being
+// served for: its authenticated key, labels and the peers it came through
+func ClaimsFromContext(ctx context.Context) Claims {
+	var c Claims
+	if info, ok := auth.KeyInfoFromContext(ctx); ok {
+		c.Key = info.Name
+		c.Tenant = auth.TenantFromContext(ctx)
+	}
+	c.Labels = labels.FromContext(ctx)
+	if via, ok := ctx.Value(viaContextKey{}).([]string); ok {
+		c.Via = append([]string(nil), via...)
+	}
+	return c
+}
+
+type viaContextKey struct{}
+
+// signingString is what a signature covers
+func signingString(method, uri, instance, timestamp, claims string, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	return []byte(strings.Join([]string{"v1", method, uri, instance, timestamp, claims, hex.EncodeToString(sum[:])}, "\n"))
+}
+
+// Signer signs requests this proxy forwards to peer proxies
+type Signer struct {
+	instance string
+	key      ed25519.PrivateKey
+	now      func() time.Time
+}
+
+// NewSigner creates a signer for instance
+func NewSigner(instance string, key ed25519.PrivateKey) *Signer {
+	return &Signer{instance: instance, key: key, now: time.Now}
+}
+
+// Instance returns the signer's instance ID
+func (s *Signer) Instance() string {
+	return s.instance
+}
+
+// PublicKey returns the base64 public key peers verify this signer with
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Sign adds the signature headers to req, asserting claims to the peer
+// instance target. The body is read and replaced so the request can still
+// be sent.
+func (s *Signer) Sign(req *http.Request, target string, claims Claims) error {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate request ID: %w", err)
+	}
+	claims.Via = append(claims.Via, s.instance)
+	claims.Target = target
+	claims.ID = hex.EncodeToString(nonce)
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	sig := ed25519.Sign(s.key, signingString(req.Method, req.URL.RequestURI(), s.instance, timestamp, encoded, body))
+
+	req.Header.Set(HeaderInstance, s.instance)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderClaims, encoded)
+	req.Header.Set(HeaderSignature, base64.RawURLEncoding.EncodeToString(sig))
+	return nil
+}
+
+// Transport wraps base so every request it sends is signed for the peer
+// instance target with the claims of the client in the request's context
+func (s *Signer) Transport(target string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		if err := s.Sign(req, target, ClaimsFromContext(req.Context())); err != nil {
+			return nil, err
+		}
+		return base.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Peer is a proxy whose signed requests are trusted
+type Peer struct {
+	PublicKey ed25519.PublicKey
+
+	// Permissions authorize every request the peer signs, whichever
+	// client key it propagates
+	Permissions []string
+}
+
+// Verifier authenticates requests signed by trusted peer proxies
+type Verifier struct {
+	instance string          // this proxy, the target requests must be signed for
+	peers    map[string]Peer // by instance ID
+	maxSkew  time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	seen   map[string]time.Time // request IDs within the skew window -> expiry
+	pruned time.Time
+}
+
+// NewVerifier creates a verifier for requests signed for instance by
+// peers, by instance ID
+func NewVerifier(instance string, peers map[string]Peer, maxSkew time.Duration) *Verifier {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	return &Verifier{
+		instance: instance,
+		peers:    peers,
+		maxSkew:  maxSkew,
+		now:      time.Now,
+		seen:     make(map[string]time.Time),
+	}
+}
+
+// Verify checks req's signature and returns the claims it carries. Each
+// signed request is accepted once, and only by the instance it was signed
+// for. The body is read and replaced.
+func (v *Verifier) Verify(req *http.Request) (Claims, error) {
+	instance := req.Header.Get(HeaderInstance)
+	peer, ok := v.peers[instance]
+	if !ok {
+		return Claims{}, fmt.Errorf("unknown peer %q", instance)
+	}
+
+	timestamp := req.Header.Get(HeaderTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return Claims{}, fmt.Errorf("invalid timestamp")
+	}
+	if skew := v.now().Sub(time.Unix(unix, 0)); skew > v.maxSkew || skew < -v.maxSkew {
+		return Claims{}, fmt.Errorf("timestamp outside the allowed skew of %s", v.maxSkew)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(req.Header.Get(HeaderSignature))
+	if err != nil {
+		return Claims{}, fmt.Errorf("malformed signature")
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(req.Body, maxSignedBody+1))
+		if err != nil {
+			return Claims{}, fmt.Errorf("failed to read request body: %w", err)
+		}
+		if len(body) > maxSignedBody {
+			return Claims{}, fmt.Errorf("request body too large to verify")
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	encoded := req.Header.Get(HeaderClaims)
+	if !ed25519.Verify(peer.PublicKey, signingString(req.Method, req.URL.RequestURI(), instance, timestamp, encoded, body), sig) {
+		return Claims{}, fmt.Errorf("invalid signature")
+	}
+
+	var claims Claims
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(data, &claims) != nil {
+		return Claims{}, fmt.Errorf("malformed claims")
+	}
+	if claims.Target != v.instance {
+		return Claims{}, fmt.Errorf("signed for %q, not this instance", claims.Target)
+	}
+	if claims.ID == "" {
+		return Claims{}, fmt.Errorf("missing request ID")
+	}
+	if !v.firstUse(instance+"/"+claims.ID, time.Unix(unix, 0).Add(v.maxSkew)) {
+		return Claims{}, fmt.Errorf("replayed request")
+	}
+	return claims, nil
+}
+
+// firstUse records a request ID until expires, after which its timestamp
+// is rejected anyway, and reports whether it was new
+func (v *Verifier) firstUse(id string, expires time.Time) bool {
+	now := v.now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.pruned) > v.maxSkew {
+		for seen, exp := range v.seen {
+			if now.After(exp) {
+				delete(v.seen, seen)
+			}
+		}
+		v.pruned = now
+	}
+	if _, ok := v.seen[id]; ok {
+		return false
+	}
+	v.seen[id] = expires
+	return true
+}
+
+// identity is the key a verified request is attributed to: the propagated
+// key, or the peer itself when the request was not made for a client. It
+// has the peer's permissions; the propagated key name is only used for
+// attribution, since a peer could claim any name.
+func (v *Verifier) identity(instance string, claims Claims) auth.APIKeyInfo {
+	info := auth.APIKeyInfo{
+		ID:          "federation:" + instance,
+		Name:        instance,
+		Permissions: append([]string(nil), v.peers[instance].Permissions...),
+		Enabled:     true,
+	}
+	if claims.Key != "" {
+		info.ID += "/" + claims.Key
+		info.Name = claims.Key
+		info.Tenant = claims.Tenant
+	}
+	return info
+}
+
+// Middleware authenticates requests signed by a trusted peer as the
+// client identity they carry, so quotas, usage and audit attribute them to
+// the original key rather than the peer, with the peer's permissions.
+// Unsigned requests are passed to fallback, normally API key
+// authentication; badly signed ones get 401.
+func (v *Verifier) Middleware(fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		unsigned := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(HeaderSignature) == "" {
+				unsigned.ServeHTTP(w, r)
+				return
+			}
+
+			instance := r.Header.Get(HeaderInstance)
+			peer := instance
+			if _, ok := v.peers[instance]; !ok {
+				peer = "unknown" // keep metric cardinality bounded
+			}
+
+			claims, err := v.Verify(r)
+			if err != nil {
+				metrics.RecordFederatedRequest(peer, "rejected")
+				if logging.Logger != nil {
+					logging.Logger.Warn("Rejected signed request",
+						zap.String("peer", instance),
+						zap.String("path", r.URL.Path),
+						zap.Error(err),
+					)
+				}
+				http.Error(w, "Invalid proxy signature: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+			metrics.RecordFederatedRequest(peer, "verified")
+
+			info := v.identity(instance, claims)
+			ctx := auth.WithKeyInfo(r.Context(), info)
+			ctx = context.WithValue(ctx, viaContextKey{}, claims.Via)
+			if len(claims.Labels) > 0 && labels.FromContext(ctx) == nil {
+				ctx = labels.NewContext(ctx, claims.Labels)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// LoadPrivateKey reads an ed25519 private key from a PEM (PKCS #8) file,
+// as written by "openssl genpkey -algorithm ed25519"
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 key", path)
+	}
+	return key, nil
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key, as logged by a
+// signing proxy at startup
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("expected %d bytes, got %d", ed25519.PublicKeySize, len(data))
+	}
+	return ed25519.PublicKey(data), nil
+}
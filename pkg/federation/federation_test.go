@@ -0,0 +1,227 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/labels"
+)
+
+func testPeers(t *testing.T) (*Signer, *Verifier) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	peers := map[string]Peer{"edge-1": {PublicKey: public, Permissions: []string{"inference"}}}
+	return NewSigner("edge-1", private), NewVerifier("pool-1", peers, 0)
+}
+
+// signedRequest signs a request for the verifier's instance, pool-1
+func signedRequest(t *testing.T, s *Signer, body string, claims Claims) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/generate?stream=false", strings.NewReader(body))
+	if err := s.Sign(req, "pool-1", claims); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	return req
+}
+
+func TestVerify(t *testing.T) {
+	signer, verifier := testPeers(t)
+
+	req := signedRequest(t, signer, `{"model":"llama3"}`, Claims{Key: "alice", Tenant: "ml", Labels: map[string]string{"team": "ml"}})
+	claims, err := verifier.Verify(req)
+	if err != nil {
+		t.Fatalf("Expected a valid signature, got %v", err)
+	}
+	if claims.Key != "alice" || claims.Tenant != "ml" || claims.Labels["team"] != "ml" {
+		t.Errorf("Expected the propagated identity, got %+v", claims)
+	}
+	if len(claims.Via) != 1 || claims.Via[0] != "edge-1" {
+		t.Errorf("Expected the signer in via, got %v", claims.Via)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"model":"llama3"}` {
+		t.Errorf("Expected the body to be readable after verifying, got %q", body)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(*http.Request)
+		want   string
+	}{
+		{"body", func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"model":"llama3:70b"}`)) }, "invalid signature"},
+		{"path", func(r *http.Request) { r.URL.RawQuery = "stream=true" }, "invalid signature"},
+		{"claims", func(r *http.Request) {
+			forged := signedRequest(t, signer, "", Claims{Key: "admin"})
+			r.Header.Set(HeaderClaims, forged.Header.Get(HeaderClaims))
+		}, "invalid signature"},
+		{"peer", func(r *http.Request) { r.Header.Set(HeaderInstance, "edge-9") }, `unknown peer "edge-9"`},
+		{"target", func(r *http.Request) {
+			other := httptest.NewRequest(http.MethodPost, "/api/generate?stream=false", strings.NewReader(`{"model":"llama3"}`))
+			signer.Sign(other, "pool-2", Claims{Key: "alice"})
+			*r = *other
+		}, `signed for "pool-2"`},
+	}
+	for _, tt := range tests {
+		req := signedRequest(t, signer, `{"model":"llama3"}`, Claims{Key: "alice"})
+		tt.tamper(req)
+		if _, err := verifier.Verify(req); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestVerify_Replay(t *testing.T) {
+	signer, verifier := testPeers(t)
+
+	req := signedRequest(t, signer, `{"model":"llama3"}`, Claims{Key: "alice"})
+	replay := req.Clone(req.Context())
+	replay.Body = io.NopCloser(strings.NewReader(`{"model":"llama3"}`))
+	if _, err := verifier.Verify(req); err != nil {
+		t.Fatalf("Expected a valid signature, got %v", err)
+	}
+	if _, err := verifier.Verify(replay); err == nil || !strings.Contains(err.Error(), "replayed") {
+		t.Errorf("Expected a replayed request to be rejected, got %v", err)
+	}
+
+	// Identical requests are signed with different IDs
+	if _, err := verifier.Verify(signedRequest(t, signer, `{"model":"llama3"}`, Claims{Key: "alice"})); err != nil {
+		t.Errorf("Expected a new request to verify, got %v", err)
+	}
+
+	// IDs are forgotten once their timestamp would be rejected anyway
+	verifier.now = func() time.Time { return time.Now().Add(2 * DefaultMaxSkew) }
+	verifier.firstUse("edge-1/other", verifier.now())
+	if len(verifier.seen) != 1 {
+		t.Errorf("Expected expired request IDs pruned, got %d", len(verifier.seen))
+	}
+}
+
+func TestVerify_ClockSkew(t *testing.T) {
+	signer, verifier := testPeers(t)
+	signer.now = func() time.Time { return time.Now().Add(-2 * DefaultMaxSkew) }
+
+	req := signedRequest(t, signer, "", Claims{})
+	if _, err := verifier.Verify(req); err == nil || !strings.Contains(err.Error(), "skew") {
+		t.Errorf("Expected a stale request to be rejected, got %v", err)
+	}
+}
+
+func TestVerifier_Middleware(t *testing.T) {
+	signer, verifier := testPeers(t)
+
+	var seen auth.APIKeyInfo
+	var seenLabels labels.Labels
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = auth.KeyInfoFromContext(r.Context())
+		seenLabels = labels.FromContext(r.Context())
+	})
+	fallbackCalled := false
+	fallback := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fallbackCalled = true
+			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+		})
+	}
+	handler := verifier.Middleware(fallback)(next)
+
+	// The propagated key and tenant attribute the request; the peer's
+	// permissions authorize it
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedRequest(t, signer, "{}", Claims{Key: "alice", Tenant: "ml", Labels: map[string]string{"app": "docsbot"}}))
+	if rec.Code != http.StatusOK || seen.Name != "alice" || seen.Tenant != "ml" || seen.ID != "federation:edge-1/alice" {
+		t.Errorf("Expected alice attributed through edge-1, got %d %+v", rec.Code, seen)
+	}
+	if seen.Quota != nil || strings.Join(seen.Permissions, ",") != "inference" {
+		t.Errorf("Expected only the peer's permissions, got %+v", seen)
+	}
+	if seenLabels["app"] != "docsbot" {
+		t.Errorf("Expected the propagated labels, got %v", seenLabels)
+	}
+
+	// A claimed admin key gets no more than the peer may do; peer
+	// requests without a client (e.g. health checks) are the peer's
+	handler.ServeHTTP(httptest.NewRecorder(), signedRequest(t, signer, "{}", Claims{Key: "admin"}))
+	if seen.Name != "admin" || auth.HasPermission(seen, auth.PermissionAdmin) {
+		t.Errorf("Expected admin attributed without admin permission, got %+v", seen)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), signedRequest(t, signer, "", Claims{}))
+	if seen.Name != "edge-1" || seen.ID != "federation:edge-1" {
+		t.Errorf("Expected the peer's identity, got %+v", seen)
+	}
+
+	rec = httptest.NewRecorder()
+	req := signedRequest(t, signer, "{}", Claims{Key: "alice"})
+	req.Header.Set(HeaderInstance, "edge-9")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || fallbackCalled {
+		t.Errorf("Expected a bad signature to be rejected, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tags", nil))
+	if !fallbackCalled || rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected unsigned requests to use API key auth, got %d", rec.Code)
+	}
+}
+
+func TestSigner_Transport(t *testing.T) {
+	signer, verifier := testPeers(t)
+
+	var claims Claims
+	var verifyErr error
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, verifyErr = verifier.Verify(r)
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	ctx := auth.WithKeyInfo(context.Background(), auth.APIKeyInfo{Name: "alice", Tenant: "research"})
+	ctx = labels.NewContext(ctx, labels.Labels{"team": "ml"})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/api/generate", bytes.NewReader([]byte(`{"model":"llama3"}`)))
+
+	client := &http.Client{Transport: signer.Transport("pool-1", nil)}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if verifyErr != nil {
+		t.Fatalf("Expected the forwarded request to verify, got %v", verifyErr)
+	}
+	if claims.Key != "alice" || claims.Tenant != "research" || claims.Labels["team"] != "ml" || claims.Target != "pool-1" {
+		t.Errorf("Expected the client's identity for pool-1, got %+v", claims)
+	}
+	if string(body) != `{"model":"llama3"}` {
+		t.Errorf("Expected the body forwarded intact, got %q", body)
+	}
+	if req.Header.Get(HeaderSignature) != "" {
+		t.Error("Expected the caller's request to be left unsigned")
+	}
+}
+
+func TestClaimsFromContext_Via(t *testing.T) {
+	signer, verifier := testPeers(t)
+
+	// A request relayed through this proxy keeps the chain of peers
+	var ctx context.Context
+	handler := verifier.Middleware(func(next http.Handler) http.Handler { return next })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { ctx = r.Context() }))
+	handler.ServeHTTP(httptest.NewRecorder(), signedRequest(t, signer, "", Claims{Key: "alice", Via: []string{"edge-0"}}))
+
+	claims := ClaimsFromContext(ctx)
+	if claims.Key != "alice" || strings.Join(claims.Via, ",") != "edge-0,edge-1" {
+		t.Errorf("Expected the relayed identity, got %+v", claims)
+	}
+}
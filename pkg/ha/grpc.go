@@ -0,0 +1,134 @@
+package ha
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// The failover service carries JSON-encoded PeerStatus/Snapshot values in
+// BytesValue messages, so it needs no generated code.
+const (
+	serviceName = "ollamaproxy.ha.v1.Failover"
+	syncMethod  = "/" + serviceName + "/Sync"
+
+	// secretMetadataKey carries the shared secret; snapshots include the
+	// config, API keys and all
+	secretMetadataKey = "x-ha-secret"
+)
+
+// failoverServer is the handler type of the hand-written service descriptor
+type failoverServer interface {
+	sync(ctx context.Context, req *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*failoverServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Sync",
+			Handler:    syncHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ha",
+}
+
+func syncHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(wrapperspb.BytesValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(failoverServer).sync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: syncMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(failoverServer).sync(ctx, req.(*wrapperspb.BytesValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// grpcService serves Sync for a node
+type grpcService struct {
+	node   *Node
+	secret string
+}
+
+// RegisterGRPC exposes the node's failover service on s. Calls must carry
+// the shared secret.
+func (n *Node) RegisterGRPC(s *grpc.Server, secret string) {
+	s.RegisterService(&serviceDesc, &grpcService{node: n, secret: secret})
+}
+
+func (g *grpcService) sync(ctx context.Context, req *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(secretMetadataKey)
+	if len(values) == 0 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(g.secret)) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "invalid ha secret")
+	}
+
+	var remote PeerStatus
+	if err := json.Unmarshal(req.GetValue(), &remote); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid peer status: %v", err)
+	}
+
+	data, err := json.Marshal(g.node.HandleSync(remote))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode snapshot: %v", err)
+	}
+	return wrapperspb.Bytes(data), nil
+}
+
+// GRPCPeer reaches the other proxy's failover service
+type GRPCPeer struct {
+	conn   *grpc.ClientConn
+	secret string
+}
+
+// DialPeer creates a client for the peer at addr. The connection is
+// established lazily, so an unreachable peer is not an error here.
+func DialPeer(addr, secret string, creds credentials.TransportCredentials) (*GRPCPeer, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ha peer client: %w", err)
+	}
+	return &GRPCPeer{conn: conn, secret: secret}, nil
+}
+
+// Sync sends our status to the peer and returns its snapshot
+func (p *GRPCPeer) Sync(ctx context.Context, local PeerStatus) (*Snapshot, error) {
+	data, err := json.Marshal(local)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, secretMetadataKey, p.secret)
+	out := new(wrapperspb.BytesValue)
+	if err := p.conn.Invoke(ctx, syncMethod, wrapperspb.Bytes(data), out); err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(out.GetValue(), &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot from peer: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Close closes the peer connection
+func (p *GRPCPeer) Close() error {
+	return p.conn.Close()
+}
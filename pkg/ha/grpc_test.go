@@ -0,0 +1,81 @@
+package ha
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func startFailoverServer(t *testing.T, node *Node, secret string) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	node.RegisterGRPC(server, secret)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestGRPCPeer_Sync(t *testing.T) {
+	primary := newTestNode(t, RolePrimary, &fakePeer{sync: unreachable})
+	primary.RegisterState("mode", func() interface{} { return "Balanced" }, nil)
+	primary.syncOnce(context.Background())
+
+	addr := startFailoverServer(t, primary, "s3cret")
+
+	peer, err := DialPeer(addr, "s3cret", insecure.NewCredentials())
+	if err != nil {
+		t.Fatalf("DialPeer failed: %v", err)
+	}
+	defer peer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	snapshot, err := peer.Sync(ctx, PeerStatus{NodeID: "standby", Role: RoleStandby, State: StateStandby})
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if snapshot.NodeID != RolePrimary || snapshot.State != StateActive || snapshot.Epoch != 1 {
+		t.Errorf("Unexpected snapshot status: %+v", snapshot.PeerStatus)
+	}
+	if string(snapshot.Learned["mode"]) != `"Balanced"` {
+		t.Errorf("Expected mirrored mode state, got %s", snapshot.Learned["mode"])
+	}
+	if primary.Status().PeerNodeID != "standby" {
+		t.Error("Expected server to record the caller as its peer")
+	}
+}
+
+func TestGRPCPeer_RejectsWrongSecret(t *testing.T) {
+	node := newTestNode(t, RolePrimary, &fakePeer{sync: unreachable})
+	addr := startFailoverServer(t, node, "s3cret")
+
+	peer, err := DialPeer(addr, "guess", insecure.NewCredentials())
+	if err != nil {
+		t.Fatalf("DialPeer failed: %v", err)
+	}
+	defer peer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = peer.Sync(ctx, PeerStatus{NodeID: "intruder", State: StateActive, Epoch: 99})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated, got %v", err)
+	}
+	if node.Status().PeerNodeID == "intruder" {
+		t.Error("Unauthenticated caller must not affect node state")
+	}
+}
@@ -0,0 +1,608 @@
+package ha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// Configured roles. The primary activates on startup unless it finds an
+// active peer; the standby only activates when the primary disappears.
+const (
+	RolePrimary = "primary"
+	RoleStandby = "standby"
+)
+
+// Default timings used when the config leaves them empty
+const (
+	DefaultSyncInterval    = 2 * time.Second
+	DefaultFailoverTimeout = 10 * time.Second
+)
+
+// State is the failover state of a node
+type State string
+
+const (
+	StateStarting State = "starting" // primary before its first peer check
+	StateActive   State = "active"   // owns virtual devices and D-Bus names
+	StateStandby  State = "standby"  // mirrors the active peer
+)
+
+// Config configures a failover node
+type Config struct {
+	NodeID           string
+	Role             string        // RolePrimary or RoleStandby
+	SyncInterval     time.Duration // how often the peer is polled
+	FailoverTimeout  time.Duration // peer silence before a standby takes over
+	ConfigPath       string        // local config file sent to the standby
+	MirrorConfigPath string        // where a standby writes the mirrored config (empty = memory only)
+}
+
+// PeerStatus identifies a node and its claim on the shared resources.
+// Epoch is the fencing token: it increases on every takeover and the node
+// with the highest epoch wins when two active nodes meet.
+type PeerStatus struct {
+	NodeID string `json:"node_id"`
+	Role   string `json:"role"`
+	State  State  `json:"state"`
+	Epoch  uint64 `json:"epoch"`
+}
+
+// Snapshot is what a node returns to its peer on every sync. Config and
+// learned state are only included by the active node.
+type Snapshot struct {
+	PeerStatus
+	Config  []byte                     `json:"config,omitempty"`
+	Learned map[string]json.RawMessage `json:"learned,omitempty"`
+	Taken   time.Time                  `json:"taken"`
+}
+
+// Peer is the other proxy of the pair
+type Peer interface {
+	Sync(ctx context.Context, local PeerStatus) (*Snapshot, error)
+}
+
+// Status is a point-in-time view of the node for the admin API
+type Status struct {
+	NodeID        string    `json:"node_id"`
+	Role          string    `json:"role"`
+	State         State     `json:"state"`
+	Epoch         uint64    `json:"epoch"`
+	PeerNodeID    string    `json:"peer_node_id,omitempty"`
+	PeerState     State     `json:"peer_state,omitempty"`
+	PeerEpoch     uint64    `json:"peer_epoch,omitempty"`
+	PeerReachable bool      `json:"peer_reachable"`
+	LastContact   time.Time `json:"last_contact,omitempty"`
+	LastSync      time.Time `json:"last_sync,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	Failovers     int       `json:"failovers"`
+	MirroredState []string  `json:"mirrored_state,omitempty"`
+}
+
+// stateProvider mirrors one piece of learned state between nodes
+type stateProvider struct {
+	get   func() interface{}
+	apply func(json.RawMessage) error
+}
+
+// Node runs the active/standby protocol for one proxy
+type Node struct {
+	cfg  Config
+	peer Peer
+
+	mu           sync.Mutex
+	state        State
+	epoch        uint64
+	peerStatus   PeerStatus
+	reachable    bool
+	seenPeer     bool
+	lastContact  time.Time
+	lastSync     time.Time
+	lastError    string
+	failovers    int
+	mirrored     []byte
+	providers    map[string]stateProvider
+	onActivate   []func()
+	onDeactivate []func()
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewNode creates a failover node talking to peer
+func NewNode(cfg Config, peer Peer) (*Node, error) {
+	if cfg.Role != RolePrimary && cfg.Role != RoleStandby {
+		return nil, fmt.Errorf("invalid ha role: %q (must be primary or standby)", cfg.Role)
+	}
+	if peer == nil {
+		return nil, fmt.Errorf("ha peer is nil")
+	}
+	if cfg.NodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("ha node_id not set and hostname unavailable: %w", err)
+		}
+		cfg.NodeID = hostname + "-" + cfg.Role
+	}
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = DefaultSyncInterval
+	}
+	if cfg.FailoverTimeout <= 0 {
+		cfg.FailoverTimeout = DefaultFailoverTimeout
+	}
+
+	state := StateStandby
+	if cfg.Role == RolePrimary {
+		state = StateStarting
+	}
+
+	return &Node{
+		cfg:       cfg,
+		peer:      peer,
+		state:     state,
+		providers: make(map[string]stateProvider),
+	}, nil
+}
+
+// RegisterState mirrors a piece of learned state: get runs on the active
+// node on every sync, apply runs on the standby with the peer's value.
+func (n *Node) RegisterState(name string, get func() interface{}, apply func(json.RawMessage) error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.providers[name] = stateProvider{get: get, apply: apply}
+}
+
+// OnActivate registers a callback run when this node takes ownership of
+// the virtual devices and D-Bus names
+func (n *Node) OnActivate(fn func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.onActivate = append(n.onActivate, fn)
+}
+
+// OnDeactivate registers a callback run when this node is fenced and must
+// give up the virtual devices and D-Bus names
+func (n *Node) OnDeactivate(fn func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.onDeactivate = append(n.onDeactivate, fn)
+}
+
+// Start begins polling the peer. The first check runs immediately so a
+// primary with no active peer claims its resources without delay.
+func (n *Node) Start(ctx context.Context) {
+	n.mu.Lock()
+	if n.stopCh != nil {
+		n.mu.Unlock()
+		return
+	}
+	n.stopCh = make(chan struct{})
+	n.doneCh = make(chan struct{})
+	n.mu.Unlock()
+
+	go func(stopCh, doneCh chan struct{}) {
+		defer close(doneCh)
+
+		ticker := time.NewTicker(n.cfg.SyncInterval)
+		defer ticker.Stop()
+
+		for {
+			middleware.Safe(middleware.ScopeBackground, "ha-sync", func() { n.syncOnce(ctx) })
+
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}(n.stopCh, n.doneCh)
+}
+
+// Stop ends peer polling. Resources stay claimed; callers release them on shutdown.
+func (n *Node) Stop() {
+	n.mu.Lock()
+	stopCh, doneCh := n.stopCh, n.doneCh
+	n.stopCh = nil
+	n.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// Active reports whether this node currently owns the shared resources
+func (n *Node) Active() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.state == StateActive
+}
+
+// Status returns the current failover status
+func (n *Node) Status() Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	status := Status{
+		NodeID:        n.cfg.NodeID,
+		Role:          n.cfg.Role,
+		State:         n.state,
+		Epoch:         n.epoch,
+		PeerNodeID:    n.peerStatus.NodeID,
+		PeerState:     n.peerStatus.State,
+		PeerEpoch:     n.peerStatus.Epoch,
+		PeerReachable: n.reachable,
+		LastContact:   n.lastContact,
+		LastSync:      n.lastSync,
+		LastError:     n.lastError,
+		Failovers:     n.failovers,
+	}
+	for name := range n.providers {
+		status.MirroredState = append(status.MirroredState, name)
+	}
+	sort.Strings(status.MirroredState)
+	return status
+}
+
+// MirroredConfig returns the last config received from the active peer
+func (n *Node) MirroredConfig() []byte {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.mirrored
+}
+
+// Promote makes this node active with a new epoch, fencing the peer.
+// Used for manual switchover; the old active node demotes on its next sync.
+func (n *Node) Promote(reason string) {
+	n.mu.Lock()
+	if n.state == StateActive {
+		n.mu.Unlock()
+		return
+	}
+	callbacks := n.activateLocked(reason)
+	n.mu.Unlock()
+
+	runCallbacks(callbacks)
+}
+
+// localStatusLocked returns this node's status. Caller must hold n.mu.
+func (n *Node) localStatusLocked() PeerStatus {
+	return PeerStatus{
+		NodeID: n.cfg.NodeID,
+		Role:   n.cfg.Role,
+		State:  n.state,
+		Epoch:  n.epoch,
+	}
+}
+
+// syncOnce polls the peer and applies the failover rules
+func (n *Node) syncOnce(ctx context.Context) {
+	n.mu.Lock()
+	local := n.localStatusLocked()
+	n.mu.Unlock()
+
+	syncCtx, cancel := context.WithTimeout(ctx, n.cfg.SyncInterval)
+	snapshot, err := n.peer.Sync(syncCtx, local)
+	cancel()
+
+	now := time.Now()
+	var callbacks []func()
+
+	n.mu.Lock()
+	if err != nil {
+		n.reachable = false
+		n.lastError = err.Error()
+		callbacks = n.peerUnreachableLocked(now)
+		n.mu.Unlock()
+		runCallbacks(callbacks)
+		return
+	}
+
+	n.reachable = true
+	n.lastError = ""
+	n.lastContact = now
+	n.seenPeer = true
+	n.peerStatus = snapshot.PeerStatus
+	if snapshot.Epoch > n.epoch && n.state != StateActive {
+		n.epoch = snapshot.Epoch
+	}
+	callbacks = n.peerSeenLocked(snapshot.PeerStatus, "peer reports active")
+
+	var apply *Snapshot
+	if n.state == StateStandby && snapshot.PeerStatus.State == StateActive {
+		apply = snapshot
+		n.lastSync = now
+	}
+	providers := n.providersLocked()
+	n.mu.Unlock()
+
+	runCallbacks(callbacks)
+	if apply != nil {
+		n.applySnapshot(apply, providers)
+	}
+}
+
+// peerUnreachableLocked handles a failed sync. Caller must hold n.mu.
+func (n *Node) peerUnreachableLocked(now time.Time) []func() {
+	switch n.state {
+	case StateStarting:
+		// No active peer answered: the primary claims its resources
+		return n.activateLocked("peer unreachable at startup")
+	case StateStandby:
+		// A standby that has never seen its primary does not take over, so a
+		// wrong peer address cannot produce two active nodes
+		if !n.seenPeer {
+			return nil
+		}
+		if now.Sub(n.lastContact) < n.cfg.FailoverTimeout {
+			return nil
+		}
+		n.failovers++
+		return n.activateLocked(fmt.Sprintf("peer unreachable for %s", now.Sub(n.lastContact).Round(time.Second)))
+	}
+	return nil
+}
+
+// peerSeenLocked applies the fencing rules after hearing from the peer,
+// either through our sync or through its. Caller must hold n.mu.
+func (n *Node) peerSeenLocked(peer PeerStatus, reason string) []func() {
+	peerActive := peer.State == StateActive
+
+	switch n.state {
+	case StateStarting:
+		if peerActive {
+			// The standby took over while we were down; stay standby until it fails
+			n.state = StateStandby
+			n.epoch = peer.Epoch
+			logging.Logger.Warn("HA peer is active, starting as standby",
+				zap.String("node_id", n.cfg.NodeID),
+				zap.String("peer", peer.NodeID),
+				zap.Uint64("peer_epoch", peer.Epoch),
+			)
+			return nil
+		}
+		return n.activateLocked("peer is not active")
+
+	case StateActive:
+		if peerActive && peerWins(peer, n.localStatusLocked()) {
+			return n.deactivateLocked(peer, reason)
+		}
+
+	case StateStandby:
+		// Neither node owns the resources: the configured primary takes them
+		if !peerActive && peer.State == StateStandby && n.cfg.Role == RolePrimary {
+			return n.activateLocked("peer is standby")
+		}
+	}
+	return nil
+}
+
+// peerWins reports whether peer's claim beats local's when both are active.
+// Higher epoch wins; ties go to the lower node ID so both sides agree.
+func peerWins(peer, local PeerStatus) bool {
+	if peer.Epoch != local.Epoch {
+		return peer.Epoch > local.Epoch
+	}
+	return peer.NodeID < local.NodeID
+}
+
+// activateLocked claims the resources under a new epoch. Caller must hold n.mu.
+func (n *Node) activateLocked(reason string) []func() {
+	if n.peerStatus.Epoch > n.epoch {
+		n.epoch = n.peerStatus.Epoch
+	}
+	n.epoch++
+	n.state = StateActive
+
+	logging.Logger.Warn("HA node active, claiming devices and D-Bus names",
+		zap.String("node_id", n.cfg.NodeID),
+		zap.String("role", n.cfg.Role),
+		zap.Uint64("epoch", n.epoch),
+		zap.String("reason", reason),
+	)
+
+	callbacks := make([]func(), len(n.onActivate))
+	copy(callbacks, n.onActivate)
+	return callbacks
+}
+
+// deactivateLocked fences this node after a peer with a stronger claim
+// appeared. Caller must hold n.mu.
+func (n *Node) deactivateLocked(peer PeerStatus, reason string) []func() {
+	n.state = StateStandby
+	n.epoch = peer.Epoch
+
+	logging.Logger.Warn("HA node fenced, releasing devices and D-Bus names",
+		zap.String("node_id", n.cfg.NodeID),
+		zap.String("peer", peer.NodeID),
+		zap.Uint64("peer_epoch", peer.Epoch),
+		zap.String("reason", reason),
+	)
+
+	callbacks := make([]func(), len(n.onDeactivate))
+	copy(callbacks, n.onDeactivate)
+	return callbacks
+}
+
+// providersLocked copies the state providers. Caller must hold n.mu.
+func (n *Node) providersLocked() map[string]stateProvider {
+	providers := make(map[string]stateProvider, len(n.providers))
+	for name, p := range n.providers {
+		providers[name] = p
+	}
+	return providers
+}
+
+// applySnapshot mirrors the active peer's config and learned state
+func (n *Node) applySnapshot(snapshot *Snapshot, providers map[string]stateProvider) {
+	if len(snapshot.Config) > 0 {
+		n.mirrorConfig(snapshot.Config)
+	}
+
+	for name, raw := range snapshot.Learned {
+		p, ok := providers[name]
+		if !ok || p.apply == nil {
+			continue
+		}
+		if err := p.apply(raw); err != nil {
+			logging.Logger.Warn("Failed to apply mirrored HA state",
+				zap.String("state", name),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// mirrorConfig stores the peer's config, writing it to disk when it changed
+func (n *Node) mirrorConfig(data []byte) {
+	n.mu.Lock()
+	changed := !bytes.Equal(n.mirrored, data)
+	if changed {
+		n.mirrored = append([]byte(nil), data...)
+	}
+	n.mu.Unlock()
+
+	if !changed || n.cfg.MirrorConfigPath == "" {
+		return
+	}
+
+	if err := writeFileAtomic(n.cfg.MirrorConfigPath, data); err != nil {
+		logging.Logger.Warn("Failed to write mirrored config",
+			zap.String("path", n.cfg.MirrorConfigPath),
+			zap.Error(err),
+		)
+		return
+	}
+	logging.Logger.Info("Mirrored config from active peer",
+		zap.String("path", n.cfg.MirrorConfigPath),
+		zap.Int("bytes", len(data)),
+	)
+}
+
+// HandleSync answers a peer's sync: it records the peer's claim, applies
+// the fencing rules and returns our status plus, when active, our config
+// and learned state.
+func (n *Node) HandleSync(remote PeerStatus) *Snapshot {
+	n.mu.Lock()
+	n.reachable = true
+	n.lastContact = time.Now()
+	n.seenPeer = true
+	n.peerStatus = remote
+	callbacks := n.peerSeenLocked(remote, "peer synced as active")
+	local := n.localStatusLocked()
+	providers := n.providersLocked()
+	n.mu.Unlock()
+
+	runCallbacks(callbacks)
+
+	snapshot := &Snapshot{PeerStatus: local, Taken: time.Now()}
+	if local.State != StateActive {
+		return snapshot
+	}
+
+	if n.cfg.ConfigPath != "" {
+		data, err := os.ReadFile(n.cfg.ConfigPath)
+		if err != nil {
+			logging.Logger.Warn("Failed to read config for HA peer",
+				zap.String("path", n.cfg.ConfigPath),
+				zap.Error(err),
+			)
+		} else {
+			snapshot.Config = data
+		}
+	}
+
+	snapshot.Learned = make(map[string]json.RawMessage, len(providers))
+	for name, p := range providers {
+		if p.get == nil {
+			continue
+		}
+		raw, err := json.Marshal(p.get())
+		if err != nil {
+			logging.Logger.Warn("Failed to encode HA state",
+				zap.String("state", name),
+				zap.Error(err),
+			)
+			continue
+		}
+		snapshot.Learned[name] = raw
+	}
+	return snapshot
+}
+
+// promoteRequest is the admin API payload
+type promoteRequest struct {
+	Action string `json:"action"` // "promote"
+	Reason string `json:"reason"`
+}
+
+// Handler serves the admin HA endpoint.
+// GET returns the status; POST {"action":"promote"} forces a switchover.
+func (n *Node) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req promoteRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Action != "promote" {
+				http.Error(w, "Unknown action (must be promote)", http.StatusBadRequest)
+				return
+			}
+			reason := req.Reason
+			if reason == "" {
+				reason = "manual switchover"
+			}
+			n.Promote(reason)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(n.Status())
+	}
+}
+
+func runCallbacks(callbacks []func()) {
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+// writeFileAtomic replaces path with data via a temp file and rename
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ha-config-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
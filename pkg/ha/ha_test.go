@@ -0,0 +1,244 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	if err := logging.InitLogger("info", false); err != nil {
+		panic(err)
+	}
+	defer logging.Sync()
+
+	os.Exit(m.Run())
+}
+
+// fakePeer answers syncs from a function
+type fakePeer struct {
+	sync func(local PeerStatus) (*Snapshot, error)
+}
+
+func (p *fakePeer) Sync(ctx context.Context, local PeerStatus) (*Snapshot, error) {
+	return p.sync(local)
+}
+
+func unreachable(PeerStatus) (*Snapshot, error) {
+	return nil, errors.New("connection refused")
+}
+
+func newTestNode(t *testing.T, role string, peer *fakePeer) *Node {
+	t.Helper()
+	node, err := NewNode(Config{
+		NodeID:          role,
+		Role:            role,
+		SyncInterval:    10 * time.Millisecond,
+		FailoverTimeout: 50 * time.Millisecond,
+	}, peer)
+	if err != nil {
+		t.Fatalf("NewNode failed: %v", err)
+	}
+	return node
+}
+
+func TestNewNode_InvalidRole(t *testing.T) {
+	if _, err := NewNode(Config{Role: "leader"}, &fakePeer{sync: unreachable}); err == nil {
+		t.Error("Expected error for invalid role")
+	}
+}
+
+func TestNode_PrimaryActivatesWhenPeerUnreachable(t *testing.T) {
+	node := newTestNode(t, RolePrimary, &fakePeer{sync: unreachable})
+
+	activated := 0
+	node.OnActivate(func() { activated++ })
+
+	node.syncOnce(context.Background())
+
+	if !node.Active() {
+		t.Fatal("Expected primary to activate with no peer")
+	}
+	if activated != 1 {
+		t.Errorf("Expected 1 activation, got %d", activated)
+	}
+	if node.Status().Epoch != 1 {
+		t.Errorf("Expected epoch 1, got %d", node.Status().Epoch)
+	}
+}
+
+func TestNode_PrimaryStartsAsStandbyWhenPeerActive(t *testing.T) {
+	peer := &fakePeer{sync: func(PeerStatus) (*Snapshot, error) {
+		return &Snapshot{PeerStatus: PeerStatus{NodeID: "standby", State: StateActive, Epoch: 3}}, nil
+	}}
+	node := newTestNode(t, RolePrimary, peer)
+	node.OnActivate(func() { t.Error("Primary must not claim resources while the peer is active") })
+
+	node.syncOnce(context.Background())
+
+	status := node.Status()
+	if status.State != StateStandby {
+		t.Errorf("Expected standby, got %s", status.State)
+	}
+	if status.Epoch != 3 {
+		t.Errorf("Expected epoch to follow peer (3), got %d", status.Epoch)
+	}
+}
+
+func TestNode_StandbyWaitsForFirstContact(t *testing.T) {
+	node := newTestNode(t, RoleStandby, &fakePeer{sync: unreachable})
+
+	node.syncOnce(context.Background())
+	time.Sleep(60 * time.Millisecond)
+	node.syncOnce(context.Background())
+
+	if node.Active() {
+		t.Error("Standby must not take over a peer it has never seen")
+	}
+}
+
+func TestNode_StandbyTakesOverAfterTimeout(t *testing.T) {
+	up := true
+	peer := &fakePeer{sync: func(PeerStatus) (*Snapshot, error) {
+		if !up {
+			return nil, errors.New("connection refused")
+		}
+		return &Snapshot{PeerStatus: PeerStatus{NodeID: "primary", State: StateActive, Epoch: 1}}, nil
+	}}
+	node := newTestNode(t, RoleStandby, peer)
+
+	activated := 0
+	node.OnActivate(func() { activated++ })
+
+	node.syncOnce(context.Background())
+	if node.Active() {
+		t.Fatal("Standby must stay passive while the primary answers")
+	}
+
+	up = false
+	node.syncOnce(context.Background())
+	if node.Active() {
+		t.Fatal("Standby must wait for the failover timeout")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	node.syncOnce(context.Background())
+
+	status := node.Status()
+	if status.State != StateActive || activated != 1 {
+		t.Fatalf("Expected takeover, got state %s with %d activations", status.State, activated)
+	}
+	if status.Epoch != 2 {
+		t.Errorf("Expected epoch above the primary's (2), got %d", status.Epoch)
+	}
+	if status.Failovers != 1 {
+		t.Errorf("Expected 1 failover, got %d", status.Failovers)
+	}
+}
+
+func TestNode_FencedByHigherEpoch(t *testing.T) {
+	node := newTestNode(t, RolePrimary, &fakePeer{sync: unreachable})
+	node.syncOnce(context.Background())
+	if !node.Active() {
+		t.Fatal("Expected primary to be active")
+	}
+
+	released := 0
+	node.OnDeactivate(func() { released++ })
+
+	// A lower epoch does not fence us
+	node.HandleSync(PeerStatus{NodeID: "standby", State: StateActive, Epoch: 0})
+	if !node.Active() || released != 0 {
+		t.Fatal("Lower-epoch peer must not fence the active node")
+	}
+
+	snapshot := node.HandleSync(PeerStatus{NodeID: "standby", State: StateActive, Epoch: 2})
+	if node.Active() {
+		t.Fatal("Expected node to be fenced by higher epoch")
+	}
+	if released != 1 {
+		t.Errorf("Expected resources released once, got %d", released)
+	}
+	if snapshot.State != StateStandby {
+		t.Errorf("Expected snapshot to report standby, got %s", snapshot.State)
+	}
+}
+
+func TestPeerWins_TieBreak(t *testing.T) {
+	a := PeerStatus{NodeID: "a", Epoch: 2}
+	b := PeerStatus{NodeID: "b", Epoch: 2}
+
+	if !peerWins(a, b) || peerWins(b, a) {
+		t.Error("Expected lower node ID to win an epoch tie on both sides")
+	}
+}
+
+func TestNode_StandbyMirrorsStateAndConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(configPath, []byte("server:\n  http_port: 8080\n"), 0o600)
+
+	primary, _ := NewNode(Config{NodeID: "primary", Role: RolePrimary, ConfigPath: configPath}, &fakePeer{sync: unreachable})
+	primary.RegisterState("mode", func() interface{} { return "Quiet" }, nil)
+	primary.syncOnce(context.Background())
+
+	mirrorPath := filepath.Join(dir, "mirrored.yaml")
+	standby, _ := NewNode(Config{NodeID: "standby", Role: RoleStandby, MirrorConfigPath: mirrorPath}, &fakePeer{
+		sync: func(local PeerStatus) (*Snapshot, error) { return primary.HandleSync(local), nil },
+	})
+
+	var mode string
+	standby.RegisterState("mode", nil, func(raw json.RawMessage) error {
+		return json.Unmarshal(raw, &mode)
+	})
+	standby.syncOnce(context.Background())
+
+	if mode != "Quiet" {
+		t.Errorf("Expected mirrored mode Quiet, got %q", mode)
+	}
+	data, err := os.ReadFile(mirrorPath)
+	if err != nil {
+		t.Fatalf("Expected mirrored config on disk: %v", err)
+	}
+	if !strings.Contains(string(data), "http_port: 8080") {
+		t.Errorf("Unexpected mirrored config: %s", data)
+	}
+	if standby.Status().LastSync.IsZero() {
+		t.Error("Expected LastSync to be set")
+	}
+}
+
+func TestNode_HandlerPromote(t *testing.T) {
+	node := newTestNode(t, RoleStandby, &fakePeer{sync: unreachable})
+	handler := node.Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/ha", strings.NewReader(`{"action":"promote"}`))
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var status Status
+	json.NewDecoder(w.Body).Decode(&status)
+	if status.State != StateActive {
+		t.Errorf("Expected active after promote, got %s", status.State)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/ha", strings.NewReader(`{"action":"explode"}`))
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown action, got %d", w.Code)
+	}
+}
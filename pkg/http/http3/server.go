@@ -0,0 +1,96 @@
+// Package http3 provides an optional HTTP/3 (QUIC) listener for the
+// data-plane endpoints. QUIC avoids TCP head-of-line blocking, which helps
+// mobile clients streaming tokens over lossy Wi-Fi.
+//
+// The QUIC transport is only compiled in with the "http3" build tag:
+//
+//	go build -tags http3 ./cmd/proxy
+//
+// Without the tag, ListenAndServe returns ErrUnsupported and the proxy keeps
+// serving over TCP only.
+package http3
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrUnsupported is returned when the binary was built without QUIC support
+var ErrUnsupported = errors.New("HTTP/3 support not compiled in (build with -tags http3)")
+
+// Server serves an HTTP handler over QUIC
+type Server struct {
+	Addr     string
+	CertFile string
+	KeyFile  string
+	Handler  http.Handler
+
+	mu     sync.Mutex
+	impl   serverImpl
+	closed bool
+}
+
+// NewServer creates a new HTTP/3 server
+func NewServer(addr, certFile, keyFile string, handler http.Handler) *Server {
+	return &Server{
+		Addr:     addr,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		Handler:  handler,
+	}
+}
+
+// ListenAndServe listens on the UDP address and serves requests until closed
+func (s *Server) ListenAndServe() error {
+	if !Supported {
+		return ErrUnsupported
+	}
+	return s.listenAndServe()
+}
+
+// Close shuts down the listener. A server closed before it started never
+// starts.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.impl == nil {
+		return nil
+	}
+	return s.impl.Close()
+}
+
+// start records the transport so Close can reach it, failing once closed
+func (s *Server) start(impl serverImpl) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return http.ErrServerClosed
+	}
+	s.impl = impl
+	return nil
+}
+
+// serverImpl is the transport-specific part of the server
+type serverImpl interface {
+	Close() error
+}
+
+// AltSvc returns the Alt-Svc header value advertising HTTP/3 on the given port
+func AltSvc(port int) string {
+	return fmt.Sprintf(`h3=":%d"; ma=86400`, port)
+}
+
+// AdvertiseHandler wraps a TCP handler so TLS responses advertise the HTTP/3
+// listener, letting clients upgrade on their next request
+func AdvertiseHandler(next http.Handler, port int) http.Handler {
+	altSvc := AltSvc(port)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Alt-Svc", altSvc)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,26 @@
+//go:build http3
+
+package http3
+
+import (
+	"crypto/tls"
+
+	qhttp3 "github.com/quic-go/quic-go/http3"
+)
+
+// Supported reports whether QUIC support is compiled in
+const Supported = true
+
+func (s *Server) listenAndServe() error {
+	srv := &qhttp3.Server{
+		Addr:    s.Addr,
+		Handler: s.Handler,
+		TLSConfig: qhttp3.ConfigureTLSConfig(&tls.Config{
+			MinVersion: tls.VersionTLS13,
+		}),
+	}
+	if err := s.start(srv); err != nil {
+		return err
+	}
+	return srv.ListenAndServeTLS(s.CertFile, s.KeyFile)
+}
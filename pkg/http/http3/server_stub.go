@@ -0,0 +1,10 @@
+//go:build !http3
+
+package http3
+
+// Supported reports whether QUIC support is compiled in
+const Supported = false
+
+func (s *Server) listenAndServe() error {
+	return ErrUnsupported
+}
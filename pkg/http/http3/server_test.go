@@ -0,0 +1,92 @@
+package http3
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAltSvc(t *testing.T) {
+	got := AltSvc(8443)
+	want := `h3=":8443"; ma=86400`
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestAdvertiseHandler_TLS(t *testing.T) {
+	called := false
+	handler := AdvertiseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}), 8443)
+
+	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.TLS = &tls.ConnectionState{}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !called {
+		t.Error("Expected wrapped handler to be called")
+	}
+	if got := rec.Header().Get("Alt-Svc"); got != AltSvc(8443) {
+		t.Errorf("Expected Alt-Svc header %q, got %q", AltSvc(8443), got)
+	}
+}
+
+func TestAdvertiseHandler_PlainHTTP(t *testing.T) {
+	handler := AdvertiseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), 8443)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/models", nil))
+
+	if got := rec.Header().Get("Alt-Svc"); got != "" {
+		t.Errorf("Expected no Alt-Svc header without TLS, got %q", got)
+	}
+}
+
+func TestServer_Unsupported(t *testing.T) {
+	if Supported {
+		t.Skip("QUIC support compiled in")
+	}
+
+	srv := NewServer(":0", "cert.pem", "key.pem", http.NotFoundHandler())
+	if err := srv.ListenAndServe(); err != ErrUnsupported {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Errorf("Expected Close on idle server to succeed, got %v", err)
+	}
+}
+
+// closeCounter is a transport stub that counts Close calls
+type closeCounter struct{ closes int }
+
+func (c *closeCounter) Close() error {
+	c.closes++
+	return nil
+}
+
+func TestServer_CloseStopsStartedTransport(t *testing.T) {
+	srv := NewServer(":0", "cert.pem", "key.pem", http.NotFoundHandler())
+	impl := &closeCounter{}
+	if err := srv.start(impl); err != nil {
+		t.Fatalf("Expected start to succeed, got %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Fatalf("Expected Close to succeed, got %v", err)
+	}
+	if impl.closes != 1 {
+		t.Errorf("Expected the transport closed once, got %d", impl.closes)
+	}
+}
+
+func TestServer_StartAfterClose(t *testing.T) {
+	srv := NewServer(":0", "cert.pem", "key.pem", http.NotFoundHandler())
+	if err := srv.Close(); err != nil {
+		t.Fatalf("Expected Close to succeed, got %v", err)
+	}
+	if err := srv.start(&closeCounter{}); err != http.ErrServerClosed {
+		t.Errorf("Expected a closed server not to start, got %v", err)
+	}
+}
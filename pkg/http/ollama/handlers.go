@@ -0,0 +1,462 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	proxyerrors "github.com/daoneill/ollama-proxy/pkg/errors"
+	"github.com/daoneill/ollama-proxy/pkg/http/openai"
+	"github.com/daoneill/ollama-proxy/pkg/http/postprocess"
+	"github.com/daoneill/ollama-proxy/pkg/http/shaping"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+	"go.uber.org/zap"
+)
+
+// HandleGenerate handles /api/generate
+func HandleGenerate(r *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var genReq GenerateRequest
+		if err := json.NewDecoder(req.Body).Decode(&genReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+		if genReq.Model == "" {
+			writeError(w, http.StatusBadRequest, "model is required")
+			return
+		}
+
+		// An empty prompt only asks Ollama to load the model
+		if genReq.Prompt == "" {
+			writeJSON(w, GenerateResponse{
+				Model:      genReq.Model,
+				CreatedAt:  time.Now().UTC(),
+				Done:       true,
+				DoneReason: "load",
+			})
+			return
+		}
+
+		prompt := genReq.Prompt
+		if genReq.System != "" && !genReq.Raw {
+			prompt = genReq.System + "\n\n" + prompt
+		}
+
+		annotations := openai.ParseRoutingHeaders(req)
+		req = openai.DetectLanguage(req, annotations, genReq.Prompt)
+
+		internalReq := &backends.GenerateRequest{
+			Prompt:  prompt,
+			Model:   genReq.Model,
+			Options: convertOptions(genReq.Options),
+		}
+		shapeOptions(req, genReq.Model, internalReq.Options, genReq.Options)
+		annotations.ContextLength = internalReq.Options.ContextLength
+		annotations.PromptTokens = backends.EstimatePromptTokens(internalReq.Prompt)
+
+		decision, ok := route(w, req, r, annotations, genReq.Model)
+		if !ok {
+			return
+		}
+
+		build := func(token string, done bool, stats *backends.GenerationStats) interface{} {
+			resp := GenerateResponse{
+				Model:     genReq.Model,
+				CreatedAt: time.Now().UTC(),
+				Response:  token,
+				Done:      done,
+			}
+			if done {
+				resp.DoneReason = "stop"
+				resp.Metrics = convertMetrics(stats)
+			}
+			return resp
+		}
+
+		if streamEnabled(genReq.Stream) {
+			serveStream(w, req, decision, internalReq, build)
+			return
+		}
+		serveOnce(w, req, decision, internalReq, build)
+	}
+}
+
+// HandleChat handles /api/chat
+func HandleChat(r *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var chatReq ChatRequest
+		if err := json.NewDecoder(req.Body).Decode(&chatReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+		if chatReq.Model == "" {
+			writeError(w, http.StatusBadRequest, "model is required")
+			return
+		}
+
+		// No messages only asks Ollama to load the model
+		if len(chatReq.Messages) == 0 {
+			writeJSON(w, ChatResponse{
+				Model:      chatReq.Model,
+				CreatedAt:  time.Now().UTC(),
+				Message:    Message{Role: "assistant"},
+				Done:       true,
+				DoneReason: "load",
+			})
+			return
+		}
+
+		// Reuse the OpenAI adapter's role-marked prompt format
+		messages := make([]openai.ChatCompletionMessage, len(chatReq.Messages))
+		for i, msg := range chatReq.Messages {
+			messages[i] = openai.ChatCompletionMessage{Role: msg.Role, Content: msg.Content}
+		}
+		internalReq := openai.ConvertChatCompletionRequest(&openai.ChatCompletionRequest{
+			Model:    chatReq.Model,
+			Messages: messages,
+		})
+		internalReq.Options = convertOptions(chatReq.Options)
+		shapeOptions(req, chatReq.Model, internalReq.Options, chatReq.Options)
+
+		annotations := openai.ParseRoutingHeaders(req)
+		req = openai.DetectLanguage(req, annotations, lastUserMessage(chatReq.Messages))
+		annotations.ContextLength = internalReq.Options.ContextLength
+		annotations.PromptTokens = backends.EstimatePromptTokens(internalReq.Prompt)
+
+		decision, ok := route(w, req, r, annotations, chatReq.Model)
+		if !ok {
+			return
+		}
+
+		build := func(token string, done bool, stats *backends.GenerationStats) interface{} {
+			resp := ChatResponse{
+				Model:     chatReq.Model,
+				CreatedAt: time.Now().UTC(),
+				Message:   Message{Role: "assistant", Content: token},
+				Done:      done,
+			}
+			if done {
+				resp.DoneReason = "stop"
+				resp.Metrics = convertMetrics(stats)
+			}
+			return resp
+		}
+
+		if streamEnabled(chatReq.Stream) {
+			serveStream(w, req, decision, internalReq, build)
+			return
+		}
+		serveOnce(w, req, decision, internalReq, build)
+	}
+}
+
+// HandleEmbeddings handles /api/embeddings
+func HandleEmbeddings(r *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		var embedReq EmbeddingsRequest
+		if err := json.NewDecoder(req.Body).Decode(&embedReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+		if embedReq.Model == "" {
+			writeError(w, http.StatusBadRequest, "model is required")
+			return
+		}
+
+		annotations := openai.ParseRoutingHeaders(req)
+		decision, ok := route(w, req, r, annotations, embedReq.Model)
+		if !ok {
+			return
+		}
+		if !decision.Backend.SupportsEmbed() {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("backend %s does not support embeddings", decision.Backend.ID()))
+			return
+		}
+
+		resp, err := decision.Backend.Embed(req.Context(), &backends.EmbedRequest{
+			Text:  embedReq.Prompt,
+			Model: embedReq.Model,
+		})
+		if err != nil {
+			writeGenerationError(w, fmt.Sprintf("embedding failed: %v", err), err)
+			return
+		}
+
+		openai.WriteRoutingHeaders(w, decision)
+		writeJSON(w, EmbeddingsResponse{Embedding: resp.Embedding})
+	}
+}
+
+// HandleTags handles /api/tags, listing the models of healthy backends
+func HandleTags(r *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		modelsMap := make(map[string]bool)
+		for _, backend := range r.ListBackends() {
+			if !backend.IsHealthy() {
+				continue
+			}
+			models, err := backend.ListModels(req.Context())
+			if err != nil {
+				// Skip backends that fail to list models
+				continue
+			}
+			for _, model := range models {
+				modelsMap[model] = true
+			}
+		}
+
+		names := make([]string, 0, len(modelsMap))
+		for name := range modelsMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		now := time.Now().UTC()
+		response := TagsResponse{Models: make([]ModelInfo, 0, len(names))}
+		for _, name := range names {
+			response.Models = append(response.Models, ModelInfo{
+				Name:       name,
+				Model:      name,
+				ModifiedAt: now,
+				Details:    ModelDetails{Families: []string{}},
+			})
+		}
+
+		writeJSON(w, response)
+	}
+}
+
+// route selects a backend for model, writing an error and returning false
+// when none can serve it
+func route(w http.ResponseWriter, req *http.Request, r *router.Router, annotations *backends.Annotations, model string) (*router.RoutingDecision, bool) {
+	annotations.Model = model
+	decision, err := r.RouteRequest(req.Context(), annotations)
+	if err != nil {
+		router.WriteRetryAfter(w.Header(), err)
+		var memoryErr *proxyerrors.InsufficientMemoryError
+		if errors.As(err, &memoryErr) {
+			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("insufficient_memory: %v", err))
+			return nil, false
+		}
+		writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("routing failed: %v", err))
+		return nil, false
+	}
+	if !decision.Backend.SupportsModel(model) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("model %q not found, try pulling it first", model))
+		return nil, false
+	}
+	return decision, true
+}
+
+// chunkBuilder renders a response chunk in the endpoint's format
+type chunkBuilder func(token string, done bool, stats *backends.GenerationStats) interface{}
+
+// serveOnce generates a complete response and writes it as one object
+func serveOnce(w http.ResponseWriter, req *http.Request, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, build chunkBuilder) {
+	tracker := usage.Track(req.Context(), decision, req.URL.Path, internalReq.Model, internalReq.Prompt)
+	resp, err := decision.Backend.Generate(req.Context(), internalReq)
+	tracker.FinishResponse(resp, err)
+	if err != nil {
+		writeGenerationError(w, fmt.Sprintf("generation failed: %v", err), err)
+		return
+	}
+	resp = postprocess.Default.Response(req.Context(), resp)
+
+	openai.WriteRoutingHeaders(w, decision)
+	openai.WriteEnergyHeaders(w, resp.Stats)
+	writeJSON(w, build(resp.Response, true, resp.Stats))
+}
+
+// serveStream streams newline-delimited JSON chunks, ending with a done
+// chunk that carries the metrics
+func serveStream(w http.ResponseWriter, req *http.Request, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, build chunkBuilder) {
+	if !decision.Backend.SupportsStream() {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("backend %s does not support streaming", decision.Backend.ID()))
+		return
+	}
+
+	tracker := usage.Track(req.Context(), decision, req.URL.Path, internalReq.Model, internalReq.Prompt)
+	reader, err := decision.Backend.GenerateStream(req.Context(), internalReq)
+	if err != nil {
+		tracker.FinishResponse(nil, err)
+		writeGenerationError(w, fmt.Sprintf("streaming failed: %v", err), err)
+		return
+	}
+	defer reader.Close()
+	reader = tracker.Stream(postprocess.Default.Stream(req.Context(), reader))
+
+	openai.WriteRoutingHeaders(w, decision)
+	openai.DeclareEnergyTrailers(w)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	// Track send pacing so constrained links can be batched
+	stream := streaming.Default.Open("ndjson", req.RemoteAddr, internalReq.Model)
+	defer stream.Close()
+
+	writeLine := func(v interface{}, tokens int) {
+		data, _ := json.Marshal(v)
+		writeStart := time.Now()
+		n, _ := w.Write(append(data, '\n'))
+		if flusher != nil {
+			flusher.Flush()
+		}
+		stream.RecordWrite(n, tokens, time.Since(writeStart))
+	}
+
+	for {
+		chunk, err := reader.Recv()
+		if errors.Is(err, io.EOF) && stream.Pending() > 0 {
+			// Flush tokens still held back by the pacer
+			chunk, err = &backends.StreamChunk{Done: true}, nil
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// Backend ended without a final chunk
+				tracker.FinishResponse(nil, nil)
+				writeLine(build("", true, nil), 0)
+				return
+			}
+			// Send what the client is owed, then report the failure
+			// in-stream as Ollama does (headers are already sent)
+			if token, tokens := stream.Flush(); tokens > 0 {
+				writeLine(build(token, false, nil), tokens)
+			}
+			tracker.FinishResponse(nil, err)
+			if logging.Logger != nil {
+				logging.FromContext(req.Context()).Error("Streaming error", zap.Error(err))
+			}
+			writeLine(ErrorResponse{Error: err.Error(), RequestID: w.Header().Get(middleware.RequestIDHeader)}, 0)
+			return
+		}
+
+		token, tokens := stream.Batch(chunk.Token, chunk.Done)
+		if tokens == 0 {
+			continue
+		}
+		writeLine(build(token, chunk.Done, chunk.Stats), tokens)
+
+		if chunk.Done {
+			tracker.FinishResponse(nil, nil)
+			openai.WriteEnergyHeaders(w, chunk.Stats)
+			return
+		}
+	}
+}
+
+// streamEnabled applies Ollama's default of streaming unless disabled
+func streamEnabled(stream *bool) bool {
+	return stream == nil || *stream
+}
+
+// convertOptions maps Ollama options to generation options
+func convertOptions(opts *Options) *backends.GenerationOptions {
+	options := &backends.GenerationOptions{}
+	if opts == nil {
+		return options
+	}
+	if opts.Temperature != nil {
+		options.Temperature = *opts.Temperature
+		options.TemperatureSet = true
+	}
+	if opts.TopP != nil {
+		options.TopP = *opts.TopP
+	}
+	if opts.TopK != nil {
+		options.TopK = *opts.TopK
+	}
+	if opts.NumPredict != nil {
+		options.MaxTokens = *opts.NumPredict
+	}
+	if opts.NumCtx != nil {
+		options.ContextLength = *opts.NumCtx
+	}
+	if len(opts.Stop) > 0 {
+		options.Stop = opts.Stop
+	}
+	return options
+}
+
+// shapeOptions bounds a generation the client left open: unset num_predict
+// and temperature take the request shaping defaults
+func shapeOptions(req *http.Request, model string, options *backends.GenerationOptions, opts *Options) {
+	maxTokensSet := opts != nil && opts.NumPredict != nil
+	temperatureSet := opts != nil && opts.Temperature != nil
+	shaping.Default.Apply(req.Context(), model, options, maxTokensSet, temperatureSet)
+}
+
+// convertMetrics maps generation stats to Ollama's nanosecond metrics
+func convertMetrics(stats *backends.GenerationStats) Metrics {
+	if stats == nil {
+		return Metrics{}
+	}
+	total := int64(stats.TotalTimeMs) * int64(time.Millisecond)
+	promptEval := int64(stats.TimeToFirstTokenMs) * int64(time.Millisecond)
+	return Metrics{
+		TotalDuration:      total,
+		PromptEvalDuration: promptEval,
+		EvalCount:          stats.TokensGenerated,
+		EvalDuration:       total - promptEval,
+	}
+}
+
+// lastUserMessage returns the text language detection should look at
+func lastUserMessage(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// writeGenerationError reports a failed backend call with the same status
+// the OpenAI-compatible API uses: 503 for a full backend queue, an expired
+// queue wait or a stopped generation, so clients back off.
+func writeGenerationError(w http.ResponseWriter, message string, err error) {
+	status, _ := openai.GenerationErrorStatus(w.Header(), err)
+	writeError(w, status, message)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an Ollama-style error response
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, RequestID: w.Header().Get(middleware.RequestIDHeader)})
+}
@@ -0,0 +1,376 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+)
+
+// mockBackend implements backends.Backend for testing
+type mockBackend struct {
+	id        string
+	models    []string
+	lastReq   *backends.GenerateRequest
+	chunks    []*backends.StreamChunk
+	streamErr error
+	embedding []float32
+}
+
+func (m *mockBackend) ID() string                                       { return m.id }
+func (m *mockBackend) Type() string                                     { return "mock" }
+func (m *mockBackend) Name() string                                     { return "mock-" + m.id }
+func (m *mockBackend) Hardware() string                                 { return "cpu" }
+func (m *mockBackend) IsHealthy() bool                                  { return true }
+func (m *mockBackend) PowerWatts() float64                              { return 10.0 }
+func (m *mockBackend) AvgLatencyMs() int32                              { return 100 }
+func (m *mockBackend) Priority() int                                    { return 1 }
+func (m *mockBackend) SupportsGenerate() bool                           { return true }
+func (m *mockBackend) SupportsStream() bool                             { return true }
+func (m *mockBackend) SupportsEmbed() bool                              { return true }
+func (m *mockBackend) ListModels(ctx context.Context) ([]string, error) { return m.models, nil }
+func (m *mockBackend) SupportsModel(model string) bool {
+	for _, name := range m.models {
+		if name == model {
+			return true
+		}
+	}
+	return false
+}
+func (m *mockBackend) GetMaxModelSizeGB() int                      { return 50 }
+func (m *mockBackend) GetSupportedModelPatterns() []string         { return []string{} }
+func (m *mockBackend) GetPreferredModels() []string                { return []string{} }
+func (m *mockBackend) UpdateMetrics(latencyMs int32, success bool) {}
+func (m *mockBackend) GetMetrics() *backends.BackendMetrics        { return &backends.BackendMetrics{} }
+func (m *mockBackend) Start(ctx context.Context) error             { return nil }
+func (m *mockBackend) Stop(ctx context.Context) error              { return nil }
+func (m *mockBackend) HealthCheck(ctx context.Context) error       { return nil }
+
+// Multimedia capability methods
+func (m *mockBackend) SupportsAudioToText() bool { return false }
+func (m *mockBackend) SupportsTextToAudio() bool { return false }
+func (m *mockBackend) SupportsImageToText() bool { return false }
+func (m *mockBackend) SupportsTextToImage() bool { return false }
+func (m *mockBackend) SupportsVideoToText() bool { return false }
+func (m *mockBackend) SupportsTextToVideo() bool { return false }
+func (m *mockBackend) TranscribeAudio(ctx context.Context, req *backends.TranscribeRequest) (*backends.TranscribeResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) TranscribeAudioStream(ctx context.Context, req *backends.TranscribeRequest) (backends.AudioStreamReader, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) SynthesizeSpeech(ctx context.Context, req *backends.SynthesizeRequest) (*backends.SynthesizeResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) SynthesizeSpeechStream(ctx context.Context, req *backends.SynthesizeRequest) (backends.AudioStreamWriter, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) AnalyzeImage(ctx context.Context, req *backends.ImageAnalysisRequest) (*backends.ImageAnalysisResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) GenerateImage(ctx context.Context, req *backends.ImageGenRequest) (*backends.ImageGenResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) GenerateImageStream(ctx context.Context, req *backends.ImageGenRequest) (backends.ImageStreamReader, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) AnalyzeVideo(ctx context.Context, req *backends.VideoAnalysisRequest) (*backends.VideoAnalysisResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) AnalyzeVideoStream(ctx context.Context, req *backends.VideoAnalysisRequest) (backends.VideoStreamReader, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) GenerateVideo(ctx context.Context, req *backends.VideoGenRequest) (*backends.VideoGenResponse, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockBackend) GenerateVideoStream(ctx context.Context, req *backends.VideoGenRequest) (backends.VideoStreamReader, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	m.lastReq = req
+	return &backends.GenerateResponse{
+		Response: "Hello there",
+		Stats:    &backends.GenerationStats{TotalTimeMs: 200, TimeToFirstTokenMs: 50, TokensGenerated: 3},
+	}, nil
+}
+
+func (m *mockBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	m.lastReq = req
+	return &mockStreamReader{chunks: m.chunks, err: m.streamErr}, nil
+}
+
+func (m *mockBackend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	return &backends.EmbedResponse{Embedding: m.embedding}, nil
+}
+
+// mockStreamReader replays chunks then returns err, or io.EOF
+type mockStreamReader struct {
+	chunks []*backends.StreamChunk
+	index  int
+	err    error
+}
+
+func (m *mockStreamReader) Recv() (*backends.StreamChunk, error) {
+	if m.index >= len(m.chunks) {
+		if m.err != nil {
+			return nil, m.err
+		}
+		return nil, io.EOF
+	}
+	chunk := m.chunks[m.index]
+	m.index++
+	return chunk, nil
+}
+
+func (m *mockStreamReader) Close() error { return nil }
+
+func newTestRouter(backend *mockBackend) *router.Router {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(backend)
+	return r
+}
+
+func post(handler http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return w
+}
+
+func TestHandleGenerate_NonStreaming(t *testing.T) {
+	backend := &mockBackend{id: "ollama-npu", models: []string{"llama3"}}
+	w := post(HandleGenerate(newTestRouter(backend)), "/api/generate",
+		`{"model":"llama3","prompt":"Hi","system":"Be brief","stream":false,"options":{"temperature":0.2,"num_predict":64,"num_ctx":2048}}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp GenerateResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Response != "Hello there" || !resp.Done || resp.DoneReason != "stop" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if resp.EvalCount != 3 || resp.TotalDuration != 200_000_000 || resp.EvalDuration != 150_000_000 {
+		t.Errorf("Expected metrics in nanoseconds, got %+v", resp.Metrics)
+	}
+	if w.Header().Get("X-Backend-Used") != "ollama-npu" {
+		t.Errorf("Expected routing headers, got %q", w.Header().Get("X-Backend-Used"))
+	}
+
+	opts := backend.lastReq.Options
+	if opts.Temperature != 0.2 || opts.MaxTokens != 64 || opts.ContextLength != 2048 {
+		t.Errorf("Options not mapped: %+v", opts)
+	}
+	if backend.lastReq.Prompt != "Be brief\n\nHi" {
+		t.Errorf("Expected system prompt prepended, got %q", backend.lastReq.Prompt)
+	}
+}
+
+func TestHandleGenerate_StreamsByDefault(t *testing.T) {
+	backend := &mockBackend{id: "ollama-npu", models: []string{"llama3"}, chunks: []*backends.StreamChunk{
+		{Token: "Hel"},
+		{Token: "lo", Done: true, Stats: &backends.GenerationStats{TokensGenerated: 2}},
+	}}
+	w := post(HandleGenerate(newTestRouter(backend)), "/api/generate", `{"model":"llama3","prompt":"Hi"}`)
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON stream, got %q", ct)
+	}
+
+	var lines []GenerateResponse
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var chunk GenerateResponse
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("Invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, chunk)
+	}
+	if len(lines) != 2 || lines[0].Response != "Hel" || lines[0].Done {
+		t.Fatalf("Unexpected stream: %+v", lines)
+	}
+	if last := lines[1]; !last.Done || last.Response != "lo" || last.EvalCount != 2 {
+		t.Errorf("Expected final chunk with metrics, got %+v", last)
+	}
+}
+
+func TestHandleGenerate_StreamErrorFlushesHeldTokens(t *testing.T) {
+	// Every write counts as slow, so tokens after the first are held back
+	prev := streaming.Default
+	streaming.SetDefault(streaming.NewTracker(streaming.Config{Enabled: true, SlowWriteThreshold: time.Nanosecond, MaxBatchTokens: 100, MaxBatchDelay: time.Minute}))
+	t.Cleanup(func() { streaming.SetDefault(prev) })
+
+	backend := &mockBackend{id: "ollama-npu", models: []string{"llama3"}, chunks: []*backends.StreamChunk{
+		{Token: "a"}, {Token: "b"}, {Token: "c"},
+	}, streamErr: fmt.Errorf("backend reset")}
+	w := post(HandleGenerate(newTestRouter(backend)), "/api/generate", `{"model":"llama3","prompt":"Hi"}`)
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 || lines[0]["response"] != "a" || lines[1]["response"] != "bc" || lines[1]["done"] != false {
+		t.Fatalf("Expected the held tokens flushed before the error, got %+v", lines)
+	}
+	if lines[2]["error"] != "backend reset" {
+		t.Errorf("Expected the error last, got %+v", lines[2])
+	}
+}
+
+func TestHandleGenerate_RecordsUsage(t *testing.T) {
+	recorder := usage.NewRecorder(usage.DefaultConfig())
+	prev := usage.Default()
+	usage.SetDefault(recorder)
+	defer usage.SetDefault(prev)
+
+	backend := &mockBackend{id: "ollama-npu", models: []string{"llama3"}, chunks: []*backends.StreamChunk{
+		{Token: "Hel"},
+		{Token: "lo", Done: true, Stats: &backends.GenerationStats{TokensGenerated: 2}},
+	}}
+	handler := HandleGenerate(newTestRouter(backend))
+	for _, body := range []string{`{"model":"llama3","prompt":"Hi"}`, `{"model":"llama3","prompt":"Hi","stream":false}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/generate", strings.NewReader(body))
+		req = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: "ci-key"}))
+		handler(httptest.NewRecorder(), req)
+	}
+
+	records := recorder.Query(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if len(records) != 2 {
+		t.Fatalf("Expected streamed and single responses recorded, got %d", len(records))
+	}
+	for _, rec := range records {
+		if rec.Key != "ci-key" || rec.Endpoint != "/api/generate" || rec.Backend != "ollama-npu" || rec.Status != "success" {
+			t.Errorf("Unexpected record: %+v", rec)
+		}
+		if rec.PromptTokens == 0 || rec.CompletionTokens == 0 {
+			t.Errorf("Expected token counts, got %+v", rec)
+		}
+	}
+}
+
+func TestHandleGenerate_Errors(t *testing.T) {
+	handler := HandleGenerate(newTestRouter(&mockBackend{id: "ollama-npu", models: []string{"llama3"}}))
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"invalid json", `{`, http.StatusBadRequest},
+		{"missing model", `{"prompt":"Hi"}`, http.StatusBadRequest},
+		{"unknown model", `{"model":"mistral","prompt":"Hi","stream":false}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(handler, "/api/generate", tt.body)
+			if w.Code != tt.code {
+				t.Errorf("Expected %d, got %d", tt.code, w.Code)
+			}
+			var errResp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+				t.Errorf("Expected Ollama error body, got %v", err)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/api/generate", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", w.Code)
+	}
+}
+
+func TestHandleGenerate_EmptyPromptLoadsModel(t *testing.T) {
+	backend := &mockBackend{id: "ollama-npu", models: []string{"llama3"}}
+	w := post(HandleGenerate(newTestRouter(backend)), "/api/generate", `{"model":"llama3"}`)
+
+	var resp GenerateResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if !resp.Done || resp.DoneReason != "load" || backend.lastReq != nil {
+		t.Errorf("Expected load response without generation, got %+v", resp)
+	}
+}
+
+func TestHandleChat_NonStreaming(t *testing.T) {
+	backend := &mockBackend{id: "ollama-npu", models: []string{"llama3"}}
+	w := post(HandleChat(newTestRouter(backend)), "/api/chat",
+		`{"model":"llama3","stream":false,"messages":[{"role":"system","content":"Be brief"},{"role":"user","content":"Hi"}]}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ChatResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Message.Role != "assistant" || resp.Message.Content != "Hello there" || !resp.Done {
+		t.Errorf("Unexpected chat response: %+v", resp)
+	}
+	if !strings.Contains(backend.lastReq.Prompt, "System: Be brief") || !strings.Contains(backend.lastReq.Prompt, "User: Hi") {
+		t.Errorf("Expected role-marked prompt, got %q", backend.lastReq.Prompt)
+	}
+}
+
+func TestHandleChat_Streaming(t *testing.T) {
+	backend := &mockBackend{id: "ollama-npu", models: []string{"llama3"}, chunks: []*backends.StreamChunk{
+		{Token: "Hi"},
+	}}
+	w := post(HandleChat(newTestRouter(backend)), "/api/chat", `{"model":"llama3","messages":[{"role":"user","content":"Hi"}]}`)
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected token line and closing done line, got %q", w.Body.String())
+	}
+	var last ChatResponse
+	json.Unmarshal([]byte(lines[1]), &last)
+	if !last.Done {
+		t.Errorf("Expected stream to end with done chunk, got %s", lines[1])
+	}
+}
+
+func TestHandleEmbeddings(t *testing.T) {
+	backend := &mockBackend{id: "ollama-npu", models: []string{"nomic-embed-text"}, embedding: []float32{0.1, 0.2}}
+	w := post(HandleEmbeddings(newTestRouter(backend)), "/api/embeddings", `{"model":"nomic-embed-text","prompt":"hello"}`)
+
+	var resp EmbeddingsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Embedding) != 2 {
+		t.Errorf("Expected embedding, got %+v", resp)
+	}
+}
+
+func TestHandleTags(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "ollama-npu", models: []string{"qwen2.5:0.5b", "llama3"}})
+	r.RegisterBackend(&mockBackend{id: "ollama-igpu", models: []string{"llama3"}})
+
+	w := httptest.NewRecorder()
+	HandleTags(r)(w, httptest.NewRequest(http.MethodGet, "/api/tags", nil))
+
+	var resp TagsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Models) != 2 || resp.Models[0].Name != "llama3" || resp.Models[1].Name != "qwen2.5:0.5b" {
+		t.Errorf("Expected deduplicated, sorted models, got %+v", resp.Models)
+	}
+}
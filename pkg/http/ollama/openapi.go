@@ -0,0 +1,89 @@
+package ollama
+
+import (
+	"net/http"
+
+	"github.com/daoneill/ollama-proxy/pkg/http/openai"
+	"github.com/daoneill/ollama-proxy/pkg/openapi"
+)
+
+// errorModel is Ollama's error body. Ollama has no error codes, so the
+// taxonomy is by status only.
+var errorModel = &openapi.ErrorModel{
+	Body: ErrorResponse{},
+	Types: []openapi.ErrorType{
+		{Type: "ollama_bad_request", Status: http.StatusBadRequest, Description: "The request body is invalid or the backend cannot serve the operation"},
+		{Type: "ollama_model_not_found", Status: http.StatusNotFound, Description: "The routed backend does not serve the model"},
+		{Type: "ollama_method_not_allowed", Status: http.StatusMethodNotAllowed, Description: "The HTTP method is not supported on this path"},
+		{Type: "ollama_internal_error", Status: http.StatusInternalServerError, Description: "The backend failed to serve the request"},
+		{Type: "ollama_unavailable", Status: http.StatusServiceUnavailable, Description: "No backend can serve the request, or its queue is full"},
+	},
+}
+
+// DescribeAPI registers the native Ollama endpoints with doc
+func DescribeAPI(doc *openapi.Document) {
+	tags := []string{"ollama"}
+	streamed := func(description string, v interface{}) []openapi.Response {
+		return []openapi.Response{{
+			Status:      http.StatusOK,
+			Description: description,
+			Content: []openapi.Content{
+				{Type: "application/x-ndjson", Body: v},
+				{Type: "application/json", Body: v},
+			},
+			Headers: openai.RoutingResponseHeaders,
+		}}
+	}
+
+	doc.Add(openapi.Operation{
+		Method:      http.MethodPost,
+		Path:        "/api/generate",
+		ID:          "ollamaGenerate",
+		Summary:     "Generate a completion",
+		Description: "Streams newline-delimited JSON chunks unless stream is false",
+		Tags:        tags,
+		Headers:     openai.RoutingRequestHeaders,
+		Request:     []openapi.Content{{Type: "application/json", Body: GenerateRequest{}}},
+		Responses:   streamed("Completion or chunk stream", GenerateResponse{}),
+		Errors:      errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:      http.MethodPost,
+		Path:        "/api/chat",
+		ID:          "ollamaChat",
+		Summary:     "Generate a chat response",
+		Description: "Streams newline-delimited JSON chunks unless stream is false",
+		Tags:        tags,
+		Headers:     openai.RoutingRequestHeaders,
+		Request:     []openapi.Content{{Type: "application/json", Body: ChatRequest{}}},
+		Responses:   streamed("Chat response or chunk stream", ChatResponse{}),
+		Errors:      errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:  http.MethodPost,
+		Path:    "/api/embeddings",
+		ID:      "ollamaEmbeddings",
+		Summary: "Generate an embedding",
+		Tags:    tags,
+		Headers: openai.RoutingRequestHeaders,
+		Request: []openapi.Content{{Type: "application/json", Body: EmbeddingsRequest{}}},
+		Responses: []openapi.Response{{
+			Status:  http.StatusOK,
+			Content: []openapi.Content{{Type: "application/json", Body: EmbeddingsResponse{}}},
+			Headers: openai.RoutingResponseHeaders,
+		}},
+		Errors: errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/api/tags",
+		ID:      "ollamaTags",
+		Summary: "List models served by healthy backends",
+		Tags:    tags,
+		Responses: []openapi.Response{{
+			Status:  http.StatusOK,
+			Content: []openapi.Content{{Type: "application/json", Body: TagsResponse{}}},
+		}},
+		Errors: errorModel,
+	})
+}
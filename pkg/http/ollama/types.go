@@ -0,0 +1,116 @@
+// Package ollama serves the native Ollama REST API (/api/generate,
+// /api/chat, /api/embeddings, /api/tags) on top of the router, so tools
+// written for a local Ollama instance can point at the proxy unchanged.
+package ollama
+
+import "time"
+
+// Options is the subset of Ollama model options mapped to generation
+// options. Unknown options are accepted and ignored.
+type Options struct {
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	TopK        *int32   `json:"top_k,omitempty"`
+	NumPredict  *int32   `json:"num_predict,omitempty"`
+	NumCtx      *int32   `json:"num_ctx,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// GenerateRequest is the body of POST /api/generate
+type GenerateRequest struct {
+	Model   string   `json:"model"`
+	Prompt  string   `json:"prompt"`
+	System  string   `json:"system,omitempty"`
+	Stream  *bool    `json:"stream,omitempty"` // Ollama streams unless false
+	Raw     bool     `json:"raw,omitempty"`
+	Options *Options `json:"options,omitempty"`
+}
+
+// GenerateResponse is a /api/generate response or stream chunk
+type GenerateResponse struct {
+	Model      string    `json:"model"`
+	CreatedAt  time.Time `json:"created_at"`
+	Response   string    `json:"response"`
+	Done       bool      `json:"done"`
+	DoneReason string    `json:"done_reason,omitempty"`
+	Metrics
+}
+
+// Message is a chat message
+type Message struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+// ChatRequest is the body of POST /api/chat
+type ChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   *bool     `json:"stream,omitempty"` // Ollama streams unless false
+	Options  *Options  `json:"options,omitempty"`
+}
+
+// ChatResponse is a /api/chat response or stream chunk
+type ChatResponse struct {
+	Model      string    `json:"model"`
+	CreatedAt  time.Time `json:"created_at"`
+	Message    Message   `json:"message"`
+	Done       bool      `json:"done"`
+	DoneReason string    `json:"done_reason,omitempty"`
+	Metrics
+}
+
+// Metrics are the timing fields of a final response. Durations are in
+// nanoseconds, as in Ollama.
+type Metrics struct {
+	TotalDuration      int64 `json:"total_duration,omitempty"`
+	PromptEvalCount    int32 `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64 `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int32 `json:"eval_count,omitempty"`
+	EvalDuration       int64 `json:"eval_duration,omitempty"`
+}
+
+// EmbeddingsRequest is the body of POST /api/embeddings
+type EmbeddingsRequest struct {
+	Model   string   `json:"model"`
+	Prompt  string   `json:"prompt"`
+	Options *Options `json:"options,omitempty"`
+}
+
+// EmbeddingsResponse is the /api/embeddings response
+type EmbeddingsResponse struct {
+	Embedding []float32 `json:"embedding"`
+}
+
+// ModelDetails describes a model in /api/tags. The proxy does not know
+// model file details, so most fields are empty.
+type ModelDetails struct {
+	ParentModel       string   `json:"parent_model"`
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// ModelInfo is one model in /api/tags
+type ModelInfo struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+// TagsResponse is the /api/tags response
+type TagsResponse struct {
+	Models []ModelInfo `json:"models"`
+}
+
+// ErrorResponse is Ollama's error body
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"` // the response's X-Request-ID
+}
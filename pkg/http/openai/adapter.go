@@ -8,18 +8,25 @@ import (
 	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
 )
 
 // ConvertChatCompletionRequest converts OpenAI chat completion request to internal format
 func ConvertChatCompletionRequest(req *ChatCompletionRequest) *backends.GenerateRequest {
-	// Concatenate messages into a single prompt with role markers
-	prompt := buildPromptFromMessages(req.Messages)
+	// Concatenate messages into a single prompt with role markers. A
+	// resumed reply ends the prompt, so the model continues it.
+	messages := req.Messages
+	if req.ResumeFrom != "" {
+		messages = append(messages[:len(messages):len(messages)], ChatCompletionMessage{Role: "assistant", Content: req.ResumeFrom})
+	}
+	prompt := buildPromptFromMessages(messages)
 
 	// Build generation options
 	options := &backends.GenerationOptions{}
 
 	if req.Temperature != nil {
 		options.Temperature = *req.Temperature
+		options.TemperatureSet = true
 	}
 
 	if req.TopP != nil {
@@ -43,14 +50,15 @@ func ConvertChatCompletionRequest(req *ChatCompletionRequest) *backends.Generate
 
 // ConvertCompletionRequest converts OpenAI completion request to internal format
 func ConvertCompletionRequest(req *CompletionRequest) *backends.GenerateRequest {
-	// Extract prompt (can be string or []string)
-	prompt := extractPrompt(req.Prompt)
+	// Extract prompt (can be string or []string), continuing resumed text
+	prompt := extractPrompt(req.Prompt) + req.ResumeFrom
 
 	// Build generation options
 	options := &backends.GenerationOptions{}
 
 	if req.Temperature != nil {
 		options.Temperature = *req.Temperature
+		options.TemperatureSet = true
 	}
 
 	if req.TopP != nil {
@@ -78,8 +86,9 @@ func ConvertEmbeddingRequest(req *EmbeddingRequest) *backends.EmbedRequest {
 	text := extractPrompt(req.Input)
 
 	return &backends.EmbedRequest{
-		Text:  text,
-		Model: req.Model,
+		Text:       text,
+		Model:      req.Model,
+		Dimensions: req.Dimensions,
 	}
 }
 
@@ -98,21 +107,25 @@ func ConvertToOpenAIChatResponse(req *ChatCompletionRequest, resp *backends.Gene
 		completionTokens = estimateTokens(resp.Response)
 	}
 
+	choice := ChatCompletionChoice{
+		Index: 0,
+		Message: ChatCompletionMessage{
+			Role:    "assistant",
+			Content: resp.Response,
+		},
+		FinishReason: "stop",
+	}
+	if len(resp.ToolCalls) > 0 {
+		choice.Message.ToolCalls = openAIToolCalls(resp.ToolCalls, -1)
+		choice.FinishReason = finishReasonToolCalls
+	}
+
 	return &ChatCompletionResponse{
 		ID:      completionID,
 		Object:  "chat.completion",
 		Created: timestamp,
 		Model:   req.Model,
-		Choices: []ChatCompletionChoice{
-			{
-				Index: 0,
-				Message: ChatCompletionMessage{
-					Role:    "assistant",
-					Content: resp.Response,
-				},
-				FinishReason: "stop",
-			},
-		},
+		Choices: []ChatCompletionChoice{choice},
 		Usage: ChatCompletionUsage{
 			PromptTokens:     promptTokens,
 			CompletionTokens: completionTokens,
@@ -180,9 +193,19 @@ func ConvertToOpenAIEmbeddingResponse(req *EmbeddingRequest, resp *backends.Embe
 
 // buildPromptFromMessages concatenates messages into a single prompt with role markers
 func buildPromptFromMessages(messages []ChatCompletionMessage) string {
-	var parts []string
+	// Size the prompt up front so it is built in a single allocation
+	size := len("Assistant:")
+	for _, msg := range messages {
+		size += len(msg.Role) + len(msg.Content) + len(": \n")
+	}
+	var sb strings.Builder
+	sb.Grow(size)
 
 	for _, msg := range messages {
+		if msg.Content == "" {
+			continue
+		}
+
 		var prefix string
 		switch strings.ToLower(msg.Role) {
 		case "system":
@@ -195,17 +218,23 @@ func buildPromptFromMessages(messages []ChatCompletionMessage) string {
 			prefix = msg.Role
 		}
 
-		if msg.Content != "" {
-			parts = append(parts, fmt.Sprintf("%s: %s", prefix, msg.Content))
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
 		}
+		sb.WriteString(prefix)
+		sb.WriteString(": ")
+		sb.WriteString(msg.Content)
 	}
 
 	// Add assistant prompt at the end if the last message wasn't from assistant
-	if len(messages) > 0 && strings.ToLower(messages[len(messages)-1].Role) != "assistant" {
-		parts = append(parts, "Assistant:")
+	if len(messages) > 0 && !strings.EqualFold(messages[len(messages)-1].Role, "assistant") {
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString("Assistant:")
 	}
 
-	return strings.Join(parts, "\n")
+	return sb.String()
 }
 
 // extractPrompt extracts string prompt from interface{} (can be string or []string)
@@ -240,12 +269,8 @@ func generateCompletionID(prefix string) string {
 	return fmt.Sprintf("%s-%s", prefix, hex.EncodeToString(b))
 }
 
-// estimateTokens provides a rough token count estimate
-// This is a simple approximation: ~4 characters per token on average
+// estimateTokens provides a rough token count estimate, the same one
+// usage accounting uses
 func estimateTokens(text string) int32 {
-	if text == "" {
-		return 0
-	}
-	// Rough approximation: divide character count by 4
-	return int32((len(text) + 3) / 4)
+	return usage.EstimateTokens(text)
 }
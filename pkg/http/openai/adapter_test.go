@@ -405,3 +405,27 @@ func TestConvertToOpenAICompletionResponse_TokenEstimation(t *testing.T) {
 		t.Errorf("CompletionTokens = %d, want %d", result.Usage.CompletionTokens, expectedTokens)
 	}
 }
+
+func TestConvertChatCompletionRequest_ResumeFrom(t *testing.T) {
+	messages := []ChatCompletionMessage{{Role: "user", Content: "Hello"}}
+	req := &ChatCompletionRequest{Model: "test-model", Messages: messages, ResumeFrom: "Hi there"}
+
+	result := ConvertChatCompletionRequest(req)
+
+	if !strings.HasSuffix(result.Prompt, "Assistant: Hi there") {
+		t.Errorf("Expected the prompt to end with the resumed reply, got %q", result.Prompt)
+	}
+	if len(req.Messages) != 1 {
+		t.Errorf("Expected the request's messages left unchanged, got %d", len(req.Messages))
+	}
+}
+
+func TestConvertCompletionRequest_ResumeFrom(t *testing.T) {
+	req := &CompletionRequest{Model: "test-model", Prompt: "Once upon", ResumeFrom: " a time"}
+
+	result := ConvertCompletionRequest(req)
+
+	if result.Prompt != "Once upon a time" {
+		t.Errorf("Expected prompt 'Once upon a time', got %q", result.Prompt)
+	}
+}
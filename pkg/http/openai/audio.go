@@ -0,0 +1,269 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// MaxAudioUploadBytes matches OpenAI's 25 MB limit on transcription uploads
+const MaxAudioUploadBytes = 25 * 1024 * 1024
+
+// Transcription response formats
+const (
+	TranscriptionFormatJSON        = "json"
+	TranscriptionFormatText        = "text"
+	TranscriptionFormatSRT         = "srt"
+	TranscriptionFormatVTT         = "vtt"
+	TranscriptionFormatVerboseJSON = "verbose_json"
+)
+
+// HandleTranscription handles /v1/audio/transcriptions (multipart upload)
+func HandleTranscription(r *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Only accept POST
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+			return
+		}
+
+		req.Body = http.MaxBytesReader(w, req.Body, MaxAudioUploadBytes)
+		if err := req.ParseMultipartForm(MaxAudioUploadBytes); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("Audio file exceeds %d MB", MaxAudioUploadBytes/(1024*1024)), "invalid_request_error")
+				return
+			}
+			writeError(w, http.StatusBadRequest, "Request must be multipart/form-data", "invalid_request_error")
+			return
+		}
+		defer req.MultipartForm.RemoveAll()
+
+		file, header, err := req.FormFile("file")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "File is required", "invalid_request_error")
+			return
+		}
+		defer file.Close()
+
+		audio, err := io.ReadAll(file)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Failed to read audio file", "invalid_request_error")
+			return
+		}
+
+		transReq := TranscriptionRequest{
+			Model:          req.FormValue("model"),
+			Language:       req.FormValue("language"),
+			Prompt:         req.FormValue("prompt"),
+			ResponseFormat: req.FormValue("response_format"),
+			Filename:       header.Filename,
+		}
+		if transReq.ResponseFormat == "" {
+			transReq.ResponseFormat = TranscriptionFormatJSON
+		}
+
+		// Validate required fields
+		if transReq.Model == "" {
+			writeError(w, http.StatusBadRequest, "Model is required", "invalid_request_error")
+			return
+		}
+		if len(audio) == 0 {
+			writeError(w, http.StatusBadRequest, "File is empty", "invalid_request_error")
+			return
+		}
+		switch transReq.ResponseFormat {
+		case TranscriptionFormatJSON, TranscriptionFormatText, TranscriptionFormatSRT,
+			TranscriptionFormatVTT, TranscriptionFormatVerboseJSON:
+		default:
+			writeError(w, http.StatusBadRequest,
+				fmt.Sprintf("Unsupported response_format %q (json, text, srt, vtt, verbose_json)", transReq.ResponseFormat),
+				"invalid_request_error")
+			return
+		}
+
+		// Parse routing headers and require a speech-to-text backend
+		annotations := ParseRoutingHeaders(req)
+		if annotations.MediaType == "" {
+			annotations.MediaType = backends.MediaTypeAudio
+		}
+		annotations.Capability = backends.CapabilityAudioToText
+
+		// Route request
+		decision, err := r.RouteRequest(req.Context(), annotations)
+		if err != nil {
+			writeRoutingError(w, err)
+			return
+		}
+
+		// An explicit target bypasses capability filtering
+		if !decision.Backend.SupportsAudioToText() {
+			writeError(w, http.StatusBadRequest, "Backend does not support audio transcription", "invalid_request_error")
+			return
+		}
+
+		// Check if backend supports the model
+		if !decision.Backend.SupportsModel(transReq.Model) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Model %s not available", transReq.Model), "model_not_found")
+			return
+		}
+
+		tracker := newUsageTracker(req.Context(), decision, "/v1/audio/transcriptions", transReq.Model, transReq.Prompt)
+
+		// Execute request
+		resp, err := decision.Backend.TranscribeAudio(req.Context(), ConvertTranscriptionRequest(&transReq, audio))
+		if err != nil {
+			tracker.Finish(0, nil, err)
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Transcription failed: %v", err), "internal_error")
+			return
+		}
+		tracker.Finish(estimateTokens(resp.Text), resp.Stats, nil)
+
+		// Write routing headers
+		WriteRoutingHeaders(w, decision)
+
+		writeTranscription(w, transReq.ResponseFormat, resp)
+	}
+}
+
+// ConvertTranscriptionRequest converts an OpenAI transcription request to
+// the internal format. Timestamps are requested for subtitle formats.
+func ConvertTranscriptionRequest(req *TranscriptionRequest, audio []byte) *backends.TranscribeRequest {
+	internal := &backends.TranscribeRequest{
+		AudioData: audio,
+		Model:     req.Model,
+		Language:  req.Language,
+		Format:    audioFormatFromFilename(req.Filename),
+		EnableTimestamps: req.ResponseFormat == TranscriptionFormatSRT ||
+			req.ResponseFormat == TranscriptionFormatVTT ||
+			req.ResponseFormat == TranscriptionFormatVerboseJSON,
+	}
+	if req.Prompt != "" {
+		internal.Options = map[string]string{"prompt": req.Prompt}
+	}
+	return internal
+}
+
+// audioFormatFromFilename maps an upload's extension to an audio format.
+// Unknown containers (m4a, webm, ...) are passed through for the backend.
+func audioFormatFromFilename(name string) backends.AudioFormat {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	switch ext {
+	case "wav", "wave":
+		return backends.AudioFormatWAV
+	case "mp3", "mpga", "mpeg":
+		return backends.AudioFormatMP3
+	case "ogg", "oga", "opus":
+		return backends.AudioFormatOPUS
+	case "flac":
+		return backends.AudioFormatFLAC
+	case "pcm", "raw":
+		return backends.AudioFormatPCM
+	}
+	return backends.AudioFormat(ext)
+}
+
+// writeTranscription writes the transcript in the requested format
+func writeTranscription(w http.ResponseWriter, format string, resp *backends.TranscribeResponse) {
+	switch format {
+	case TranscriptionFormatText:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, resp.Text+"\n")
+
+	case TranscriptionFormatSRT:
+		w.Header().Set("Content-Type", "application/x-subrip; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, FormatSRT(transcriptSegments(resp)))
+
+	case TranscriptionFormatVTT:
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, FormatVTT(transcriptSegments(resp)))
+
+	case TranscriptionFormatVerboseJSON:
+		segments := transcriptSegments(resp)
+		verbose := VerboseTranscriptionResponse{
+			Task:     "transcribe",
+			Language: resp.Language,
+			Text:     resp.Text,
+			Segments: make([]TranscriptionSegment, len(segments)),
+		}
+		for i, seg := range segments {
+			verbose.Segments[i] = TranscriptionSegment{
+				ID:    i,
+				Start: float64(seg.StartMs) / 1000,
+				End:   float64(seg.EndMs) / 1000,
+				Text:  seg.Text,
+			}
+		}
+		if len(segments) > 0 {
+			verbose.Duration = float64(segments[len(segments)-1].EndMs) / 1000
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(verbose)
+
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(TranscriptionResponse{Text: resp.Text})
+	}
+}
+
+// transcriptSegments returns the backend's segments, or a single segment
+// covering the whole text when the backend returned no timestamps. Its
+// length is estimated at ~15 characters of speech per second.
+func transcriptSegments(resp *backends.TranscribeResponse) []backends.TranscriptSegment {
+	if len(resp.Segments) > 0 {
+		return resp.Segments
+	}
+	if resp.Text == "" {
+		return nil
+	}
+	endMs := int64(len(resp.Text)) * 1000 / 15
+	if endMs < 1000 {
+		endMs = 1000
+	}
+	return []backends.TranscriptSegment{{Text: resp.Text, StartMs: 0, EndMs: endMs}}
+}
+
+// FormatSRT renders segments as SubRip subtitles
+func FormatSRT(segments []backends.TranscriptSegment) string {
+	var b strings.Builder
+	for i, seg := range segments {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1,
+			formatTimestamp(seg.StartMs, ","), formatTimestamp(seg.EndMs, ","), strings.TrimSpace(seg.Text))
+	}
+	return b.String()
+}
+
+// FormatVTT renders segments as WebVTT subtitles
+func FormatVTT(segments []backends.TranscriptSegment) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, seg := range segments {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n",
+			formatTimestamp(seg.StartMs, "."), formatTimestamp(seg.EndMs, "."), strings.TrimSpace(seg.Text))
+	}
+	return b.String()
+}
+
+// formatTimestamp formats milliseconds as HH:MM:SS<sep>mmm
+func formatTimestamp(ms int64, sep string) string {
+	if ms < 0 {
+		ms = 0
+	}
+	h := ms / 3600000
+	m := ms / 60000 % 60
+	s := ms / 1000 % 60
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", h, m, s, sep, ms%1000)
+}
@@ -0,0 +1,220 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// audioBackend is a mockBackend with speech-to-text support
+type audioBackend struct {
+	mockBackend
+	resp    *backends.TranscribeResponse
+	lastReq *backends.TranscribeRequest
+}
+
+func (m *audioBackend) SupportsAudioToText() bool { return true }
+
+func (m *audioBackend) TranscribeAudio(ctx context.Context, req *backends.TranscribeRequest) (*backends.TranscribeResponse, error) {
+	m.lastReq = req
+	return m.resp, nil
+}
+
+func newTranscriptionRequest(t *testing.T, fields map[string]string, filename string, audio []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	if filename != "" {
+		fw, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(audio)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func newAudioRouter() (*router.Router, *audioBackend) {
+	r := router.NewRouter(router.Config{})
+	// The text-only backend must be skipped by capability filtering
+	r.RegisterBackend(&mockBackend{id: "text-backend", supportsModel: true})
+	backend := &audioBackend{
+		mockBackend: mockBackend{id: "whisper-backend", supportsModel: true},
+		resp: &backends.TranscribeResponse{
+			Text:     "hello world",
+			Language: "en",
+			Segments: []backends.TranscriptSegment{
+				{Text: "hello", StartMs: 0, EndMs: 1200},
+				{Text: " world", StartMs: 1200, EndMs: 3723456},
+			},
+		},
+	}
+	r.RegisterBackend(backend)
+	return r, backend
+}
+
+func TestHandleTranscription_JSON(t *testing.T) {
+	r, backend := newAudioRouter()
+	req := newTranscriptionRequest(t, map[string]string{"model": "whisper-base", "language": "en"}, "clip.wav", []byte("RIFF..."))
+	w := httptest.NewRecorder()
+
+	HandleTranscription(r)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp TranscriptionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Text != "hello world" {
+		t.Errorf("Expected text 'hello world', got %q", resp.Text)
+	}
+	if backend.lastReq == nil || backend.lastReq.Format != backends.AudioFormatWAV || backend.lastReq.Language != "en" {
+		t.Errorf("Unexpected backend request: %+v", backend.lastReq)
+	}
+	if backend.lastReq.EnableTimestamps {
+		t.Error("Expected no timestamps for json format")
+	}
+	if got := w.Header().Get("X-Backend-Used"); got != "whisper-backend" {
+		t.Errorf("Expected whisper-backend, got %s", got)
+	}
+}
+
+func TestHandleTranscription_Formats(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+		contains    []string
+	}{
+		{"text", "text/plain", []string{"hello world\n"}},
+		{"srt", "application/x-subrip", []string{"1\n00:00:00,000 --> 00:00:01,200\nhello\n", "2\n00:00:01,200 --> 01:02:03,456\nworld\n"}},
+		{"vtt", "text/vtt", []string{"WEBVTT\n\n", "00:00:01.200 --> 01:02:03.456\nworld"}},
+		{"verbose_json", "application/json", []string{`"task":"transcribe"`, `"end":1.2`, `"duration":3723.456`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			r, backend := newAudioRouter()
+			req := newTranscriptionRequest(t, map[string]string{"model": "whisper-base", "response_format": tt.format}, "clip.mp3", []byte("ID3"))
+			w := httptest.NewRecorder()
+
+			HandleTranscription(r)(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("Expected content type %s, got %s", tt.contentType, ct)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("Expected body to contain %q, got:\n%s", want, w.Body.String())
+				}
+			}
+			if tt.format != "text" && !backend.lastReq.EnableTimestamps {
+				t.Error("Expected timestamps to be requested")
+			}
+		})
+	}
+}
+
+func TestHandleTranscription_Validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		filename string
+		status   int
+	}{
+		{"missing file", map[string]string{"model": "whisper-base"}, "", http.StatusBadRequest},
+		{"missing model", map[string]string{}, "clip.wav", http.StatusBadRequest},
+		{"bad format", map[string]string{"model": "whisper-base", "response_format": "docx"}, "clip.wav", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newAudioRouter()
+			w := httptest.NewRecorder()
+			HandleTranscription(r)(w, newTranscriptionRequest(t, tt.fields, tt.filename, []byte("data")))
+			if w.Code != tt.status {
+				t.Errorf("Expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Not multipart
+	r, _ := newAudioRouter()
+	w := httptest.NewRecorder()
+	HandleTranscription(r)(w, httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", strings.NewReader("{}")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for non-multipart body, got %d", w.Code)
+	}
+
+	// Wrong method
+	w = httptest.NewRecorder()
+	HandleTranscription(r)(w, httptest.NewRequest(http.MethodGet, "/v1/audio/transcriptions", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
+
+func TestHandleTranscription_NoAudioBackend(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "text-backend", supportsModel: true})
+
+	w := httptest.NewRecorder()
+	HandleTranscription(r)(w, newTranscriptionRequest(t, map[string]string{"model": "whisper-base"}, "clip.wav", []byte("data")))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without an audio backend, got %d", w.Code)
+	}
+
+	// Explicitly targeting a text backend bypasses the filter but is rejected
+	req := newTranscriptionRequest(t, map[string]string{"model": "whisper-base"}, "clip.wav", []byte("data"))
+	req.Header.Set("X-Target-Backend", "text-backend")
+	w = httptest.NewRecorder()
+	HandleTranscription(r)(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for text-only target, got %d", w.Code)
+	}
+}
+
+func TestTranscriptSegments_Fallback(t *testing.T) {
+	segs := transcriptSegments(&backends.TranscribeResponse{Text: "short"})
+	if len(segs) != 1 || segs[0].EndMs != 1000 {
+		t.Errorf("Expected one 1s segment, got %+v", segs)
+	}
+	if segs := transcriptSegments(&backends.TranscribeResponse{}); segs != nil {
+		t.Errorf("Expected no segments for empty text, got %+v", segs)
+	}
+}
+
+func TestAudioFormatFromFilename(t *testing.T) {
+	tests := map[string]backends.AudioFormat{
+		"a.WAV":  backends.AudioFormatWAV,
+		"a.mp3":  backends.AudioFormatMP3,
+		"a.ogg":  backends.AudioFormatOPUS,
+		"a.flac": backends.AudioFormatFLAC,
+		"a.m4a":  backends.AudioFormat("m4a"),
+		"noext":  backends.AudioFormat(""),
+	}
+	for name, want := range tests {
+		if got := audioFormatFromFilename(name); got != want {
+			t.Errorf("audioFormatFromFilename(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
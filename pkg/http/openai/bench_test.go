@@ -0,0 +1,79 @@
+//go:build !race
+
+package openai
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+var benchMessages = []ChatCompletionMessage{
+	{Role: "system", Content: "You are a helpful assistant."},
+	{Role: "user", Content: "What is the capital of Ireland?"},
+	{Role: "assistant", Content: "Dublin."},
+	{Role: "user", Content: "And its population?"},
+}
+
+func BenchmarkParseRoutingHeaders(b *testing.B) {
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer sk-test")
+	req.Header.Set("X-Latency-Critical", "true")
+	req.Header.Set("X-Request-ID", "req-123")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseRoutingHeaders(req)
+	}
+}
+
+func BenchmarkConvertChatCompletionRequest(b *testing.B) {
+	temperature := float32(0.7)
+	req := &ChatCompletionRequest{Model: "llama3", Messages: benchMessages, Temperature: &temperature}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ConvertChatCompletionRequest(req)
+	}
+}
+
+func BenchmarkEncodeSSEChunk(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, err := encodeChatChunk(" Dublin", false, "chatcmpl-123", "llama3", 1700000000)
+		if err != nil {
+			b.Fatal(err)
+		}
+		putSSEBuffer(buf)
+	}
+}
+
+// TestHotPath_AllocBudget fails when request parsing, prompt building or
+// SSE encoding allocates more than it did when the budget was last
+// reviewed. Raise a budget only for a deliberate feature, never to absorb
+// an accidental regression.
+func TestHotPath_AllocBudget(t *testing.T) {
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set("X-Latency-Critical", "true")
+	req.Header.Set("X-Request-ID", "req-123")
+	chatReq := &ChatCompletionRequest{Model: "llama3", Messages: benchMessages}
+
+	for _, tc := range []struct {
+		name   string
+		budget float64
+		fn     func()
+	}{
+		{"ParseRoutingHeaders", 2, func() { ParseRoutingHeaders(req) }},
+		{"ConvertChatCompletionRequest", 3, func() { ConvertChatCompletionRequest(chatReq) }},
+		{"encodeChatChunk", 1, func() {
+			buf, _ := encodeChatChunk(" Dublin", false, "chatcmpl-123", "llama3", 1700000000)
+			buf.WriteTo(io.Discard)
+			putSSEBuffer(buf)
+		}},
+	} {
+		if allocs := testing.AllocsPerRun(100, tc.fn); allocs > tc.budget {
+			t.Errorf("%s allocates %.0f times, budget %.0f", tc.name, allocs, tc.budget)
+		}
+	}
+}
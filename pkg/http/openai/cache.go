@@ -0,0 +1,131 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/cache"
+	"github.com/daoneill/ollama-proxy/pkg/http/postprocess"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// responseCache is a request's view of the response cache. The zero value
+// (caching not requested or disabled) never hits and never stores.
+type responseCache struct {
+	key   string
+	model string
+}
+
+// lookupCache checks the response cache for requests that opted in with
+// X-Cache-Enabled. It sets X-Cache to HIT or MISS.
+func lookupCache(ctx context.Context, w http.ResponseWriter, annotations *backends.Annotations, model string, req *backends.GenerateRequest) (responseCache, *cache.Entry) {
+	if !annotations.CacheEnabled || cache.Default == nil {
+		return responseCache{}, nil
+	}
+
+	rc := responseCache{key: cache.Key(ctx, model, req.Prompt, req.Options), model: model}
+	if entry, ok := cache.Default.Get(rc.key); ok {
+		w.Header().Set("X-Cache", "HIT")
+		if entry.BackendID != "" {
+			w.Header().Set("X-Backend-Used", entry.BackendID)
+		}
+		entry.Response = postprocess.Default.Apply(ctx, entry.Response)
+		return rc, &entry
+	}
+	w.Header().Set("X-Cache", "MISS")
+	return rc, nil
+}
+
+// store caches a successful response
+func (rc responseCache) store(decision *router.RoutingDecision, resp *backends.GenerateResponse) {
+	if rc.key == "" || resp == nil {
+		return
+	}
+	entry := cache.Entry{
+		Response: resp.Response,
+		Stats:    resp.Stats,
+		Model:    rc.model,
+	}
+	if decision != nil && decision.Backend != nil {
+		entry.BackendID = decision.Backend.ID()
+	}
+	cache.Default.Put(rc.key, entry)
+}
+
+// capture wraps a stream so the full response can be cached once it
+// completes
+func (rc responseCache) capture(reader backends.StreamReader) *captureStreamReader {
+	return &captureStreamReader{StreamReader: reader, enabled: rc.key != ""}
+}
+
+// captureStreamReader accumulates streamed tokens for caching
+type captureStreamReader struct {
+	backends.StreamReader
+	enabled bool
+	text    strings.Builder
+	stats   *backends.GenerationStats
+}
+
+func (r *captureStreamReader) Recv() (*backends.StreamChunk, error) {
+	chunk, err := r.StreamReader.Recv()
+	if r.enabled && chunk != nil {
+		r.text.WriteString(chunk.Token)
+		if chunk.Stats != nil {
+			r.stats = chunk.Stats
+		}
+	}
+	return chunk, err
+}
+
+// response returns the captured stream as a response
+func (r *captureStreamReader) response() *backends.GenerateResponse {
+	return &backends.GenerateResponse{Response: r.text.String(), Stats: r.stats}
+}
+
+// cachedStreamReader replays a cached response as a single final chunk
+type cachedStreamReader struct {
+	entry *cache.Entry
+	sent  bool
+}
+
+func (r *cachedStreamReader) Recv() (*backends.StreamChunk, error) {
+	if r.sent {
+		return nil, io.EOF
+	}
+	r.sent = true
+	return &backends.StreamChunk{Token: r.entry.Response, Done: true, Stats: r.entry.Stats}, nil
+}
+
+func (r *cachedStreamReader) Close() error {
+	return nil
+}
+
+// serveCachedChatCompletion answers a chat completion from the cache
+func serveCachedChatCompletion(w http.ResponseWriter, entry *cache.Entry, chatReq *ChatCompletionRequest) {
+	if chatReq.Stream {
+		StreamChatCompletion(w, &cachedStreamReader{entry: entry}, chatReq.Model, generateCompletionID("chatcmpl"))
+		return
+	}
+
+	openaiResp := ConvertToOpenAIChatResponse(chatReq, &backends.GenerateResponse{Response: entry.Response, Stats: entry.Stats})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(openaiResp)
+}
+
+// serveCachedCompletion answers a text completion from the cache
+func serveCachedCompletion(w http.ResponseWriter, entry *cache.Entry, compReq *CompletionRequest) {
+	if compReq.Stream {
+		StreamCompletion(w, &cachedStreamReader{entry: entry}, compReq.Model, generateCompletionID("cmpl"))
+		return
+	}
+
+	openaiResp := ConvertToOpenAICompletionResponse(compReq, &backends.GenerateResponse{Response: entry.Response, Stats: entry.Stats})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(openaiResp)
+}
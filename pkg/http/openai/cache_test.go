@@ -0,0 +1,134 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/cache"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// countingBackend counts generation calls so tests can tell cache hits
+// from backend calls
+type countingBackend struct {
+	*mockBackend
+	calls int
+}
+
+func (c *countingBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	c.calls++
+	return c.mockBackend.Generate(ctx, req)
+}
+
+func (c *countingBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	c.calls++
+	return &mockStreamReader{chunks: []backends.StreamChunk{
+		{Token: "Hello"},
+		{Token: " world", Done: true},
+	}}, nil
+}
+
+func withResponseCache(t *testing.T) {
+	t.Helper()
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.SetDefault(c)
+	t.Cleanup(func() { cache.SetDefault(nil) })
+}
+
+func TestHandleChatCompletion_Cache(t *testing.T) {
+	withResponseCache(t)
+
+	backend := &countingBackend{mockBackend: &mockBackend{id: "test-backend", supportsModel: true}}
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(backend)
+	handler := HandleChatCompletion(r)
+
+	send := func(cacheEnabled bool) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ChatCompletionRequest{
+			Model:    "test-model",
+			Messages: []ChatCompletionMessage{{Role: "user", Content: "Hello"}},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body))
+		if cacheEnabled {
+			req.Header.Set("X-Cache-Enabled", "true")
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	if w := send(true); w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("Expected first request to miss, got %q", w.Header().Get("X-Cache"))
+	}
+
+	w := send(true)
+	if w.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("Expected repeat request to hit, got %q", w.Header().Get("X-Cache"))
+	}
+	if w.Header().Get("X-Backend-Used") != "test-backend" {
+		t.Errorf("Expected cached backend ID, got %q", w.Header().Get("X-Backend-Used"))
+	}
+	var resp ChatCompletionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Hello! How can I help you?" {
+		t.Errorf("Expected cached content, got %q", resp.Choices[0].Message.Content)
+	}
+	if backend.calls != 1 {
+		t.Errorf("Expected one backend call, got %d", backend.calls)
+	}
+
+	// Requests that don't opt in bypass the cache
+	if w := send(false); w.Header().Get("X-Cache") != "" {
+		t.Errorf("Expected no X-Cache header without opt-in, got %q", w.Header().Get("X-Cache"))
+	}
+	if backend.calls != 2 {
+		t.Errorf("Expected uncached request to reach backend, got %d calls", backend.calls)
+	}
+}
+
+func TestHandleCompletion_CacheStreaming(t *testing.T) {
+	withResponseCache(t)
+
+	backend := &countingBackend{mockBackend: &mockBackend{id: "test-backend", supportsModel: true, supportsStream: true}}
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(backend)
+	handler := HandleCompletion(r)
+
+	send := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(CompletionRequest{Model: "test-model", Prompt: "Say hello", Stream: true})
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", bytes.NewBuffer(body))
+		req.Header.Set("X-Cache-Enabled", "true")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	if w := send(); w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("Expected first request to miss, got %q", w.Header().Get("X-Cache"))
+	}
+
+	w := send()
+	if w.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("Expected repeat request to hit, got %q", w.Header().Get("X-Cache"))
+	}
+	if !strings.Contains(w.Body.String(), "Hello world") {
+		t.Errorf("Expected replayed stream to contain full text, got %s", w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "[DONE]") {
+		t.Error("Expected replayed stream to terminate")
+	}
+	if backend.calls != 1 {
+		t.Errorf("Expected one backend call, got %d", backend.calls)
+	}
+}
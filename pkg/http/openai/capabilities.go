@@ -0,0 +1,185 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// Operations reported by /v1/capabilities
+const (
+	OperationGenerate        = "generate"
+	OperationStream          = "stream"
+	OperationToolCalling     = "tool_calling"
+	OperationEmbed           = "embed"
+	OperationSpeechToText    = "speech_to_text"
+	OperationTextToSpeech    = "text_to_speech"
+	OperationImageGeneration = "image_generation"
+	OperationImageAnalysis   = "image_analysis"
+	OperationVideoGeneration = "video_generation"
+	OperationVideoAnalysis   = "video_analysis"
+)
+
+// capabilityOperations lists every operation in response order
+var capabilityOperations = []string{
+	OperationGenerate,
+	OperationStream,
+	OperationToolCalling,
+	OperationEmbed,
+	OperationSpeechToText,
+	OperationTextToSpeech,
+	OperationImageGeneration,
+	OperationImageAnalysis,
+	OperationVideoGeneration,
+	OperationVideoAnalysis,
+}
+
+// capabilityModelsTimeout bounds each backend's model listing so one slow
+// backend cannot stall the whole matrix
+const capabilityModelsTimeout = 3 * time.Second
+
+// CapabilitiesResponse is the /v1/capabilities matrix
+type CapabilitiesResponse struct {
+	Object     string              `json:"object"` // "capabilities"
+	Created    int64               `json:"created"`
+	Operations []string            `json:"operations"`
+	Available  map[string][]string `json:"available"` // operation -> healthy backend IDs
+	Models     map[string][]string `json:"models"`    // model -> backend IDs serving it
+	Backends   []BackendCapability `json:"backends"`
+}
+
+// BackendCapability describes what one backend can do right now
+type BackendCapability struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Type        string          `json:"type"`
+	Hardware    string          `json:"hardware"`
+	Healthy     bool            `json:"healthy"`
+	Operations  map[string]bool `json:"operations"`
+	Models      []string        `json:"models"`
+	ModelsError string          `json:"models_error,omitempty"`
+}
+
+// backendOperations reports which operations b supports
+func backendOperations(b backends.Backend) map[string]bool {
+	return map[string]bool{
+		OperationGenerate:        b.SupportsGenerate(),
+		OperationStream:          b.SupportsStream(),
+		OperationToolCalling:     backends.SupportsToolCalling(b),
+		OperationEmbed:           b.SupportsEmbed(),
+		OperationSpeechToText:    b.SupportsAudioToText(),
+		OperationTextToSpeech:    b.SupportsTextToAudio(),
+		OperationImageGeneration: b.SupportsTextToImage(),
+		OperationImageAnalysis:   b.SupportsImageToText(),
+		OperationVideoGeneration: b.SupportsTextToVideo(),
+		OperationVideoAnalysis:   b.SupportsVideoToText(),
+	}
+}
+
+// describeBackend builds b's row of the matrix. Models are listed live;
+// when listing fails the backend's preferred models are reported instead.
+func describeBackend(ctx context.Context, b backends.Backend) BackendCapability {
+	row := BackendCapability{
+		ID:         b.ID(),
+		Name:       b.Name(),
+		Type:       b.Type(),
+		Hardware:   b.Hardware(),
+		Healthy:    b.IsHealthy(),
+		Operations: backendOperations(b),
+	}
+
+	seen := make(map[string]bool)
+	add := func(models []string) {
+		for _, model := range models {
+			if model != "" && !seen[model] {
+				seen[model] = true
+				row.Models = append(row.Models, model)
+			}
+		}
+	}
+
+	if row.Healthy {
+		listCtx, cancel := context.WithTimeout(ctx, capabilityModelsTimeout)
+		models, err := b.ListModels(listCtx)
+		cancel()
+		if err != nil {
+			row.ModelsError = err.Error()
+		}
+		add(models)
+	}
+	add(b.GetPreferredModels())
+
+	sort.Strings(row.Models)
+	if row.Models == nil {
+		row.Models = []string{}
+	}
+	return row
+}
+
+// BuildCapabilities queries every registered backend and assembles the matrix
+func BuildCapabilities(ctx context.Context, r *router.Router) CapabilitiesResponse {
+	list := r.ListBackends()
+	rows := make([]BackendCapability, len(list))
+
+	var wg sync.WaitGroup
+	for i, b := range list {
+		wg.Add(1)
+		go func(i int, b backends.Backend) {
+			defer wg.Done()
+			rows[i] = describeBackend(ctx, b)
+		}(i, b)
+	}
+	wg.Wait()
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+
+	response := CapabilitiesResponse{
+		Object:     "capabilities",
+		Created:    time.Now().Unix(),
+		Operations: capabilityOperations,
+		Available:  make(map[string][]string, len(capabilityOperations)),
+		Models:     make(map[string][]string),
+		Backends:   rows,
+	}
+	for _, op := range capabilityOperations {
+		response.Available[op] = []string{}
+	}
+	for _, row := range rows {
+		if !row.Healthy {
+			continue
+		}
+		for op, supported := range row.Operations {
+			if supported {
+				response.Available[op] = append(response.Available[op], row.ID)
+			}
+		}
+		for _, model := range row.Models {
+			response.Models[model] = append(response.Models[model], row.ID)
+		}
+	}
+	return response
+}
+
+// HandleCapabilities handles /v1/capabilities. The matrix is rebuilt on
+// every request so it tracks backend health and model changes.
+func HandleCapabilities(r *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+			return
+		}
+
+		response := BuildCapabilities(req.Context(), r)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
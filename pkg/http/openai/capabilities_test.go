@@ -0,0 +1,82 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// offlineBackend is unhealthy and cannot list its models
+type offlineBackend struct {
+	mockBackend
+}
+
+func (m *offlineBackend) IsHealthy() bool { return false }
+func (m *offlineBackend) ListModels(ctx context.Context) ([]string, error) {
+	return nil, fmt.Errorf("connection refused")
+}
+func (m *offlineBackend) GetPreferredModels() []string { return []string{"offline-model"} }
+
+func TestHandleCapabilities(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "text", supportsStream: true})
+	r.RegisterBackend(&imageBackend{mockBackend: mockBackend{id: "sdxl"}})
+	r.RegisterBackend(&offlineBackend{mockBackend: mockBackend{id: "down"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/capabilities", nil)
+	w := httptest.NewRecorder()
+	HandleCapabilities(r)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp CapabilitiesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(resp.Backends) != 3 || resp.Backends[0].ID != "down" {
+		t.Fatalf("Expected 3 backends sorted by ID, got %+v", resp.Backends)
+	}
+	if got := resp.Available[OperationImageGeneration]; len(got) != 1 || got[0] != "sdxl" {
+		t.Errorf("Expected only sdxl to generate images, got %v", got)
+	}
+	if got := resp.Available[OperationStream]; len(got) != 1 || got[0] != "text" {
+		t.Errorf("Expected only text to stream, got %v", got)
+	}
+	if got := resp.Available[OperationVideoGeneration]; got == nil || len(got) != 0 {
+		t.Errorf("Expected empty video_generation list, got %v", got)
+	}
+	if got := resp.Models["test-model"]; len(got) != 2 {
+		t.Errorf("Expected test-model on 2 healthy backends, got %v", got)
+	}
+	if _, ok := resp.Models["offline-model"]; ok {
+		t.Error("Unhealthy backend models must not be advertised as available")
+	}
+
+	down := resp.Backends[0]
+	if down.Healthy || len(down.Models) != 1 || down.Models[0] != "offline-model" {
+		t.Errorf("Expected unhealthy backend with preferred models, got %+v", down)
+	}
+	if !down.Operations[OperationGenerate] {
+		t.Error("Expected operations to be reported for unhealthy backends")
+	}
+}
+
+func TestHandleCapabilities_MethodNotAllowed(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/capabilities", nil)
+	w := httptest.NewRecorder()
+	HandleCapabilities(r)(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+}
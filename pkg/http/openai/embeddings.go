@@ -0,0 +1,172 @@
+package openai
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// embeddingInputs returns the texts of an embedding request's input when it
+// is a string or an array of strings. Token arrays are not split.
+func embeddingInputs(input interface{}) ([]string, bool) {
+	switch v := input.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	case []interface{}:
+		inputs := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			inputs[i] = s
+		}
+		return inputs, true
+	default:
+		return nil, false
+	}
+}
+
+// validateEmbeddingOptions checks an embedding request's dimensions and
+// encoding_format
+func validateEmbeddingOptions(req *EmbeddingRequest) error {
+	switch req.EncodingFormat {
+	case "", "float", "base64":
+	default:
+		return fmt.Errorf("encoding_format must be float or base64, got %q", req.EncodingFormat)
+	}
+	if req.Dimensions < 0 {
+		return fmt.Errorf("dimensions must be positive")
+	}
+	return nil
+}
+
+// fitEmbedding shortens embedding to dimensions (0 keeps it whole) for
+// backends that returned the model's full size. Like models trained to be
+// shortened, the kept prefix is rescaled to unit length so cosine and dot
+// product similarities still agree.
+func fitEmbedding(embedding []float32, dimensions int) ([]float32, error) {
+	if dimensions == 0 || len(embedding) == dimensions {
+		return embedding, nil
+	}
+	if len(embedding) < dimensions {
+		return nil, fmt.Errorf("dimensions %d exceeds the model's %d", dimensions, len(embedding))
+	}
+
+	fitted := embedding[:dimensions:dimensions]
+	var sum float64
+	for _, v := range fitted {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return fitted, nil
+	}
+	scale := 1 / math.Sqrt(sum)
+	for i, v := range fitted {
+		fitted[i] = float32(float64(v) * scale)
+	}
+	return fitted, nil
+}
+
+// MarshalJSON writes the embedding as an array of numbers, or with
+// encoding_format base64 as the base64 of its little-endian float32s,
+// a fraction of the size of the numbers for large batches
+func (d EmbeddingData) MarshalJSON() ([]byte, error) {
+	type embeddingData EmbeddingData
+	if !d.base64 || d.Embedding == nil {
+		return json.Marshal(embeddingData(d))
+	}
+
+	buf := make([]byte, 4*len(d.Embedding))
+	for i, v := range d.Embedding {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return json.Marshal(struct {
+		Object    string       `json:"object"`
+		Index     int          `json:"index"`
+		Embedding string       `json:"embedding"`
+		Error     *ErrorDetail `json:"error,omitempty"`
+	}{d.Object, d.Index, base64.StdEncoding.EncodeToString(buf), d.Error})
+}
+
+// handleEmbeddingBatch embeds a batch of inputs across the backends that
+// serve the model. Inputs that fail carry an error in their place in the
+// response; the request only fails when all of them do.
+func handleEmbeddingBatch(w http.ResponseWriter, req *http.Request, r *router.Router, embedReq *EmbeddingRequest, inputs []string, annotations *backends.Annotations) {
+	start := time.Now()
+	result, err := r.EmbedBatch(req.Context(), annotations, embedReq.Model, embedReq.Dimensions, inputs)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+	recordEmbeddingBatchUsage(req, r, embedReq.Model, inputs, result, start)
+
+	if result.Failed == len(inputs) {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Embedding failed: %v", result.Items[0].Err), "internal_error")
+		return
+	}
+
+	resp := &EmbeddingResponse{
+		Object: "list",
+		Data:   make([]EmbeddingData, len(result.Items)),
+		Model:  embedReq.Model,
+	}
+	for i, item := range result.Items {
+		resp.Data[i] = EmbeddingData{Object: "embedding", Index: item.Index, base64: embedReq.EncodingFormat == "base64"}
+		if item.Err != nil {
+			resp.Data[i].Error = &ErrorDetail{Message: item.Err.Error(), Type: "internal_error"}
+			continue
+		}
+		// Every input is embedded by the same model, so a size it can't
+		// reach fails the whole batch
+		if resp.Data[i].Embedding, err = fitEmbedding(item.Embedding, embedReq.Dimensions); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+		resp.Usage.PromptTokens += estimateTokens(inputs[i])
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+
+	w.Header().Set("X-Backend-Used", strings.Join(result.Backends, ","))
+	if result.Failed > 0 {
+		w.Header().Set("X-Embedding-Failed", fmt.Sprintf("%d", result.Failed))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// recordEmbeddingBatchUsage accounts for a batch once per backend that
+// embedded part of it, with the inputs that backend took
+func recordEmbeddingBatchUsage(req *http.Request, r *router.Router, model string, inputs []string, result *router.EmbedBatchResult, start time.Time) {
+	texts := make(map[string][]string)
+	errs := make(map[string]error)
+	for i, item := range result.Items {
+		if item.Backend == "" {
+			continue
+		}
+		texts[item.Backend] = append(texts[item.Backend], inputs[i])
+		if item.Err != nil && errs[item.Backend] == nil {
+			errs[item.Backend] = item.Err
+		}
+	}
+	for id, batch := range texts {
+		backend, ok := r.GetBackend(id)
+		if !ok {
+			continue
+		}
+		tracker := newUsageTracker(req.Context(), &router.RoutingDecision{Backend: backend}, "/v1/embeddings", model, strings.Join(batch, "\n"))
+		tracker.Start = start
+		tracker.Finish(0, nil, errs[id])
+	}
+}
@@ -0,0 +1,170 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// embedBackend is a mockBackend embedding text as its length, failing on
+// "bad"
+type embedBackend struct {
+	mockBackend
+}
+
+func (m *embedBackend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	if req.Text == "bad" {
+		return nil, errors.New("input rejected")
+	}
+	return &backends.EmbedResponse{Embedding: []float32{float32(len(req.Text))}}, nil
+}
+
+func postEmbeddings(t *testing.T, r *router.Router, input interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(EmbeddingRequest{Model: "test-model", Input: input})
+	w := httptest.NewRecorder()
+	HandleEmbedding(r)(w, httptest.NewRequest(http.MethodPost, "/v1/embeddings", bytes.NewBuffer(body)))
+	return w
+}
+
+func TestHandleEmbedding_Batch(t *testing.T) {
+	r := router.NewRouter(router.Config{EmbedBatch: router.EmbedBatchConfig{ChunkSize: 2}})
+	r.RegisterBackend(&embedBackend{mockBackend{id: "npu", supportsModel: true}})
+	r.RegisterBackend(&embedBackend{mockBackend{id: "gpu", supportsModel: true}})
+
+	w := postEmbeddings(t, r, []string{"a", "bb", "bad", "dddd", "eeeee"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-Embedding-Failed"); got != "1" {
+		t.Errorf("Expected one failed input, got %q", got)
+	}
+
+	var resp EmbeddingResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Data) != 5 {
+		t.Fatalf("Expected an item per input, got %d", len(resp.Data))
+	}
+	for i, want := range []float32{1, 2, 0, 4, 5} {
+		data := resp.Data[i]
+		if data.Index != i {
+			t.Errorf("Expected items in input order, got %d at %d", data.Index, i)
+		}
+		if i == 2 {
+			if data.Error == nil || data.Embedding != nil {
+				t.Errorf("Expected an error for the bad input, got %+v", data)
+			}
+			continue
+		}
+		if data.Error != nil || len(data.Embedding) != 1 || data.Embedding[0] != want {
+			t.Errorf("Unexpected item %d: %+v", i, data)
+		}
+	}
+}
+
+func TestHandleEmbedding_BatchAllFailed(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&embedBackend{mockBackend{id: "npu", supportsModel: true}})
+
+	w := postEmbeddings(t, r, []interface{}{"bad", "bad"})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 when every input fails, got %d", w.Code)
+	}
+}
+
+// vectorBackend embeds every input as the same 4-dimensional vector
+type vectorBackend struct {
+	mockBackend
+	lastReq *backends.EmbedRequest
+}
+
+func (m *vectorBackend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	m.lastReq = req
+	return &backends.EmbedResponse{Embedding: []float32{3, 4, 12, 84}}, nil
+}
+
+func TestHandleEmbedding_DimensionsAndBase64(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	backend := &vectorBackend{mockBackend: mockBackend{id: "npu", supportsModel: true}}
+	r.RegisterBackend(backend)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		HandleEmbedding(r)(w, httptest.NewRequest(http.MethodPost, "/v1/embeddings", bytes.NewBufferString(body)))
+		return w
+	}
+
+	// The backend returns the full vector, so it is shortened and rescaled
+	w := post(`{"model":"test-model","input":"hello","dimensions":2}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if backend.lastReq.Dimensions != 2 {
+		t.Errorf("Expected dimensions passed to the backend, got %d", backend.lastReq.Dimensions)
+	}
+	var resp EmbeddingResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got := resp.Data[0].Embedding; len(got) != 2 || got[0] != 0.6 || got[1] != 0.8 {
+		t.Errorf("Expected [0.6 0.8], got %v", got)
+	}
+
+	// Batches are encoded per item
+	w = post(`{"model":"test-model","input":["a","b"],"dimensions":2,"encoding_format":"base64"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var encoded struct {
+		Data []struct {
+			Embedding string `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&encoded); err != nil {
+		t.Fatalf("Failed to decode base64 response: %v", err)
+	}
+	if len(encoded.Data) != 2 {
+		t.Fatalf("Expected two items, got %d", len(encoded.Data))
+	}
+	for _, data := range encoded.Data {
+		raw, err := base64.StdEncoding.DecodeString(data.Embedding)
+		if err != nil || len(raw) != 8 {
+			t.Fatalf("Expected 8 bytes of base64, got %q: %v", data.Embedding, err)
+		}
+		if v := math.Float32frombits(binary.LittleEndian.Uint32(raw[4:])); v != 0.8 {
+			t.Errorf("Expected 0.8 as the second float32, got %v", v)
+		}
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"too many dimensions", `"input":"hello","dimensions":8`, "exceeds the model's 4"},
+		{"negative dimensions", `"input":"hello","dimensions":-1`, "must be positive"},
+		{"unknown format", `"input":"hello","encoding_format":"int8"`, "encoding_format"},
+		{"batch too many dimensions", `"input":["a","b"],"dimensions":8`, "exceeds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(`{"model":"test-model",` + tt.body + `}`)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("Expected 400 containing %q, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
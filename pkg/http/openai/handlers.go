@@ -3,13 +3,21 @@ package openai
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	proxyerrors "github.com/daoneill/ollama-proxy/pkg/errors"
+	"github.com/daoneill/ollama-proxy/pkg/http/postprocess"
+	"github.com/daoneill/ollama-proxy/pkg/http/shaping"
 	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/maintenance"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
 	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
 	"go.uber.org/zap"
 )
 
@@ -38,17 +46,70 @@ func HandleChatCompletion(r *router.Router) http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, "Messages are required", "invalid_request_error")
 			return
 		}
+		n, _, err := sequenceCounts(chatReq.N, nil, chatReq.Stream)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+		if chatReq.ResumeFrom != "" && !chatReq.Stream {
+			writeError(w, http.StatusBadRequest, "resume_from requires stream", "invalid_request_error")
+			return
+		}
+		tools, toolChoice, err := chatTools(&chatReq)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+		if len(tools) > 0 && n > 1 {
+			writeError(w, http.StatusBadRequest, "tools cannot be combined with n > 1", "invalid_request_error")
+			return
+		}
 
 		// Parse routing headers
 		annotations := ParseRoutingHeaders(req)
+		annotations.Model = chatReq.Model
+		annotations.Batch = int32(n)
+		if n > 1 {
+			// A cached reply holds a single completion
+			annotations.CacheEnabled = false
+		}
+		if chatReq.ResumeFrom != "" {
+			// A resumed stream continues the client's text, not a cached reply
+			annotations.CacheEnabled = false
+			req = req.WithContext(streaming.WithResumed(req.Context(), chatReq.ResumeFrom))
+		}
+		if len(tools) > 0 {
+			// Only backends that call tools can answer, and a cached
+			// reply holds no tool calls
+			annotations.Capability = backends.CapabilityToolCalling
+			annotations.CacheEnabled = false
+		}
+		req = DetectLanguage(req, annotations, buildPromptFromMessages(chatReq.Messages))
 
 		// Convert to internal format
 		internalReq := ConvertChatCompletionRequest(&chatReq)
+		if len(tools) > 0 {
+			internalReq.Tools, internalReq.ToolChoice = tools, toolChoice
+			internalReq.Messages = chatMessages(chatReq.Messages)
+		}
+		annotations.PromptTokens = backends.EstimatePromptTokens(internalReq.Prompt)
+		// Bound generations the client left open
+		shaping.Default.Apply(req.Context(), chatReq.Model, internalReq.Options, chatReq.MaxTokens != nil, chatReq.Temperature != nil)
+
+		// Serve repeated requests from the response cache
+		rc, cached := lookupCache(req.Context(), w, annotations, chatReq.Model, internalReq)
+		if cached != nil {
+			serveCachedChatCompletion(w, cached, &chatReq)
+			return
+		}
+
+		// Keep multi-turn conversations on the backend holding their context
+		sa := lookupSession(req.Context(), annotations, &chatReq)
 
 		// Route request
 		decision, err := r.RouteRequest(req.Context(), annotations)
 		if err != nil {
-			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("Routing failed: %v", err), "service_unavailable")
+			writeRoutingError(w, err)
 			return
 		}
 
@@ -57,29 +118,47 @@ func HandleChatCompletion(r *router.Router) http.HandlerFunc {
 			writeError(w, http.StatusNotFound, fmt.Sprintf("Model %s not available", chatReq.Model), "model_not_found")
 			return
 		}
+		// An explicit target bypasses capability filtering
+		if len(tools) > 0 && !backends.SupportsToolCalling(decision.Backend) {
+			writeError(w, http.StatusBadRequest, "Backend does not support tool calling", "invalid_request_error")
+			return
+		}
+		sa.pin(w, decision)
 
 		// Handle streaming vs non-streaming
-		if chatReq.Stream {
-			handleChatCompletionStreaming(w, req.Context(), decision, internalReq, &chatReq)
+		if n > 1 {
+			handleChatCompletionSequences(w, req.Context(), decision, internalReq, &chatReq, n)
+		} else if chatReq.Stream {
+			handleChatCompletionStreaming(w, req.Context(), decision, internalReq, &chatReq, rc)
 		} else {
-			handleChatCompletionNonStreaming(w, req.Context(), decision, internalReq, &chatReq)
+			handleChatCompletionNonStreaming(w, req.Context(), decision, internalReq, &chatReq, rc)
 		}
 	}
 }
 
-func handleChatCompletionNonStreaming(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, chatReq *ChatCompletionRequest) {
+func handleChatCompletionNonStreaming(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, chatReq *ChatCompletionRequest, rc responseCache) {
+	tracker := newUsageTracker(ctx, decision, "/v1/chat/completions", chatReq.Model, buildPromptFromMessages(chatReq.Messages))
+
 	// Execute request
 	resp, err := decision.Backend.Generate(ctx, internalReq)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Generation failed: %v", err), "internal_error")
+		tracker.Finish(0, nil, err)
+		writeGenerationError(w, fmt.Sprintf("Generation failed: %v", err), err)
 		return
 	}
 
+	// Cache the raw output, post-processing applies on every reply
+	rc.store(decision, resp)
+	resp = postprocess.Default.Response(ctx, resp)
+
 	// Convert to OpenAI format
 	openaiResp := ConvertToOpenAIChatResponse(chatReq, resp)
+	openaiResp.Ensemble = ensembleCandidates(decision)
+	tracker.Finish(openaiResp.Usage.CompletionTokens, resp.Stats, nil)
 
 	// Write routing headers
 	WriteRoutingHeaders(w, decision)
+	WriteEnergyHeaders(w, resp.Stats)
 
 	// Write response
 	w.Header().Set("Content-Type", "application/json")
@@ -87,34 +166,65 @@ func handleChatCompletionNonStreaming(w http.ResponseWriter, ctx context.Context
 	json.NewEncoder(w).Encode(openaiResp)
 }
 
-func handleChatCompletionStreaming(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, chatReq *ChatCompletionRequest) {
+func handleChatCompletionStreaming(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, chatReq *ChatCompletionRequest, rc responseCache) {
 	// Check if backend supports streaming
 	if !decision.Backend.SupportsStream() {
 		writeError(w, http.StatusBadRequest, "Backend does not support streaming", "invalid_request_error")
 		return
 	}
 
+	tracker := newUsageTracker(ctx, decision, "/v1/chat/completions", chatReq.Model, buildPromptFromMessages(chatReq.Messages))
+
+	// Let the client know up front if the model has to load first, and
+	// how the load is going until it has
+	loading := announceColdStart(ctx, w, decision, internalReq.Model)
+
 	// Execute streaming request
 	reader, err := decision.Backend.GenerateStream(ctx, internalReq)
+	if loading != nil {
+		loading(err)
+	}
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Streaming failed: %v", err), "internal_error")
+		tracker.Finish(0, nil, err)
+		if loading != nil {
+			writeStreamError(w, fmt.Errorf("Streaming failed: %v", err))
+			return
+		}
+		writeGenerationError(w, fmt.Sprintf("Streaming failed: %v", err), err)
 		return
 	}
+	captured := rc.capture(reader)
+	counted := &usage.StreamCounter{StreamReader: postprocess.Default.Stream(ctx, captured)}
 
-	// Write routing headers before streaming
+	// Write routing headers before streaming, energy after it
 	WriteRoutingHeaders(w, decision)
+	DeclareEnergyTrailers(w)
 
 	// Generate completion ID
 	completionID := generateCompletionID("chatcmpl")
 
 	// Stream response
-	if err := StreamChatCompletion(w, reader, chatReq.Model, completionID); err != nil {
+	err = StreamChatCompletionContext(ctx, w, counted, chatReq.Model, completionID)
+	tracker.Finish(counted.CompletionTokens(), counted.Stats, err)
+	WriteEnergyHeaders(w, counted.Stats)
+	if err != nil {
 		// Can't send error after streaming has started
 		// Just log it
+		if errors.Is(err, context.Canceled) {
+			if logging.Logger != nil {
+				logging.FromContext(ctx).Info("Client disconnected, backend stream cancelled",
+					zap.String("backend", decision.Backend.ID()),
+					zap.Int32("tokens_sent", counted.CompletionTokens()),
+				)
+			}
+			return
+		}
 		if logging.Logger != nil {
-			logging.Logger.Error("Streaming error", zap.Error(err))
+			logging.FromContext(ctx).Error("Streaming error", zap.Error(err))
 		}
+		return
 	}
+	rc.store(decision, captured.response())
 }
 
 // HandleCompletion handles /v1/completions endpoint
@@ -142,17 +252,48 @@ func HandleCompletion(r *router.Router) http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, "Prompt is required", "invalid_request_error")
 			return
 		}
+		n, bestOf, err := sequenceCounts(compReq.N, compReq.BestOf, compReq.Stream)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+		if compReq.ResumeFrom != "" && !compReq.Stream {
+			writeError(w, http.StatusBadRequest, "resume_from requires stream", "invalid_request_error")
+			return
+		}
 
 		// Parse routing headers
 		annotations := ParseRoutingHeaders(req)
+		annotations.Model = compReq.Model
+		annotations.Batch = int32(bestOf)
+		if bestOf > 1 {
+			// A cached reply holds a single completion
+			annotations.CacheEnabled = false
+		}
+		if compReq.ResumeFrom != "" {
+			// A resumed stream continues the client's text, not a cached reply
+			annotations.CacheEnabled = false
+			req = req.WithContext(streaming.WithResumed(req.Context(), compReq.ResumeFrom))
+		}
+		req = DetectLanguage(req, annotations, extractPrompt(compReq.Prompt))
 
 		// Convert to internal format
 		internalReq := ConvertCompletionRequest(&compReq)
+		annotations.PromptTokens = backends.EstimatePromptTokens(internalReq.Prompt)
+		// Bound generations the client left open
+		shaping.Default.Apply(req.Context(), compReq.Model, internalReq.Options, compReq.MaxTokens != nil, compReq.Temperature != nil)
+
+		// Serve repeated requests from the response cache
+		rc, cached := lookupCache(req.Context(), w, annotations, compReq.Model, internalReq)
+		if cached != nil {
+			serveCachedCompletion(w, cached, &compReq)
+			return
+		}
 
 		// Route request
 		decision, err := r.RouteRequest(req.Context(), annotations)
 		if err != nil {
-			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("Routing failed: %v", err), "service_unavailable")
+			writeRoutingError(w, err)
 			return
 		}
 
@@ -163,27 +304,39 @@ func HandleCompletion(r *router.Router) http.HandlerFunc {
 		}
 
 		// Handle streaming vs non-streaming
-		if compReq.Stream {
-			handleCompletionStreaming(w, req.Context(), decision, internalReq, &compReq)
+		if bestOf > 1 {
+			handleCompletionSequences(w, req.Context(), decision, internalReq, &compReq, n, bestOf)
+		} else if compReq.Stream {
+			handleCompletionStreaming(w, req.Context(), decision, internalReq, &compReq, rc)
 		} else {
-			handleCompletionNonStreaming(w, req.Context(), decision, internalReq, &compReq)
+			handleCompletionNonStreaming(w, req.Context(), decision, internalReq, &compReq, rc)
 		}
 	}
 }
 
-func handleCompletionNonStreaming(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, compReq *CompletionRequest) {
+func handleCompletionNonStreaming(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, compReq *CompletionRequest, rc responseCache) {
+	tracker := newUsageTracker(ctx, decision, "/v1/completions", compReq.Model, extractPrompt(compReq.Prompt))
+
 	// Execute request
 	resp, err := decision.Backend.Generate(ctx, internalReq)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Generation failed: %v", err), "internal_error")
+		tracker.Finish(0, nil, err)
+		writeGenerationError(w, fmt.Sprintf("Generation failed: %v", err), err)
 		return
 	}
 
+	// Cache the raw output, post-processing applies on every reply
+	rc.store(decision, resp)
+	resp = postprocess.Default.Response(ctx, resp)
+
 	// Convert to OpenAI format
 	openaiResp := ConvertToOpenAICompletionResponse(compReq, resp)
+	openaiResp.Ensemble = ensembleCandidates(decision)
+	tracker.Finish(openaiResp.Usage.CompletionTokens, resp.Stats, nil)
 
 	// Write routing headers
 	WriteRoutingHeaders(w, decision)
+	WriteEnergyHeaders(w, resp.Stats)
 
 	// Write response
 	w.Header().Set("Content-Type", "application/json")
@@ -191,31 +344,53 @@ func handleCompletionNonStreaming(w http.ResponseWriter, ctx context.Context, de
 	json.NewEncoder(w).Encode(openaiResp)
 }
 
-func handleCompletionStreaming(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, compReq *CompletionRequest) {
+func handleCompletionStreaming(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, compReq *CompletionRequest, rc responseCache) {
 	// Check if backend supports streaming
 	if !decision.Backend.SupportsStream() {
 		writeError(w, http.StatusBadRequest, "Backend does not support streaming", "invalid_request_error")
 		return
 	}
 
+	tracker := newUsageTracker(ctx, decision, "/v1/completions", compReq.Model, extractPrompt(compReq.Prompt))
+
+	// Let the client know up front if the model has to load first, and
+	// how the load is going until it has
+	loading := announceColdStart(ctx, w, decision, internalReq.Model)
+
 	// Execute streaming request
 	reader, err := decision.Backend.GenerateStream(ctx, internalReq)
+	if loading != nil {
+		loading(err)
+	}
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Streaming failed: %v", err), "internal_error")
+		tracker.Finish(0, nil, err)
+		if loading != nil {
+			writeStreamError(w, fmt.Errorf("Streaming failed: %v", err))
+			return
+		}
+		writeGenerationError(w, fmt.Sprintf("Streaming failed: %v", err), err)
 		return
 	}
+	captured := rc.capture(reader)
+	counted := &usage.StreamCounter{StreamReader: postprocess.Default.Stream(ctx, captured)}
 
-	// Write routing headers before streaming
+	// Write routing headers before streaming, energy after it
 	WriteRoutingHeaders(w, decision)
+	DeclareEnergyTrailers(w)
 
 	// Generate completion ID
 	completionID := generateCompletionID("cmpl")
 
 	// Stream response
-	if err := StreamCompletion(w, reader, compReq.Model, completionID); err != nil {
+	err = StreamCompletionContext(ctx, w, counted, compReq.Model, completionID)
+	tracker.Finish(counted.CompletionTokens(), counted.Stats, err)
+	WriteEnergyHeaders(w, counted.Stats)
+	if err != nil {
 		// Can't send error after streaming has started
 		fmt.Printf("Streaming error: %v\n", err)
+		return
 	}
+	rc.store(decision, captured.response())
 }
 
 // HandleEmbedding handles /v1/embeddings endpoint
@@ -243,9 +418,20 @@ func HandleEmbedding(r *router.Router) http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, "Input is required", "invalid_request_error")
 			return
 		}
+		if err := validateEmbeddingOptions(&embedReq); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
 
 		// Parse routing headers
 		annotations := ParseRoutingHeaders(req)
+		annotations.Model = embedReq.Model
+
+		// Batches are split across every backend that embeds the model
+		if inputs, ok := embeddingInputs(embedReq.Input); ok && len(inputs) > 1 {
+			handleEmbeddingBatch(w, req, r, &embedReq, inputs, annotations)
+			return
+		}
 
 		// Convert to internal format
 		internalReq := ConvertEmbeddingRequest(&embedReq)
@@ -253,7 +439,7 @@ func HandleEmbedding(r *router.Router) http.HandlerFunc {
 		// Route request
 		decision, err := r.RouteRequest(req.Context(), annotations)
 		if err != nil {
-			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("Routing failed: %v", err), "service_unavailable")
+			writeRoutingError(w, err)
 			return
 		}
 
@@ -269,15 +455,24 @@ func HandleEmbedding(r *router.Router) http.HandlerFunc {
 			return
 		}
 
+		tracker := newUsageTracker(req.Context(), decision, "/v1/embeddings", embedReq.Model, extractPrompt(embedReq.Input))
+
 		// Execute request
 		resp, err := decision.Backend.Embed(req.Context(), internalReq)
+		tracker.Finish(0, nil, err)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Embedding failed: %v", err), "internal_error")
 			return
 		}
 
+		if resp.Embedding, err = fitEmbedding(resp.Embedding, embedReq.Dimensions); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+
 		// Convert to OpenAI format
 		openaiResp := ConvertToOpenAIEmbeddingResponse(&embedReq, resp)
+		openaiResp.Data[0].base64 = embedReq.EncodingFormat == "base64"
 
 		// Write routing headers
 		WriteRoutingHeaders(w, decision)
@@ -343,6 +538,14 @@ func HandleModels(r *router.Router) http.HandlerFunc {
 			Data:   modelsList,
 		}
 
+		// Surface planned downtime notices
+		if status := maintenance.Default.Status(); status.Banner != "" || status.Enabled {
+			response.Metadata = &ModelsMetadata{
+				Banner:      status.Banner,
+				Maintenance: status.Enabled,
+			}
+		}
+
 		// Write response
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -350,6 +553,43 @@ func HandleModels(r *router.Router) http.HandlerFunc {
 	}
 }
 
+// writeRoutingError reports a request no backend was routed for, with a
+// Retry-After of when one is expected to free up
+func writeRoutingError(w http.ResponseWriter, err error) {
+	message := fmt.Sprintf("Routing failed: %v", err)
+	router.WriteRetryAfter(w.Header(), err)
+	var memoryErr *proxyerrors.InsufficientMemoryError
+	if errors.As(err, &memoryErr) {
+		writeError(w, http.StatusServiceUnavailable, message, "insufficient_memory")
+		return
+	}
+	writeError(w, http.StatusServiceUnavailable, message, "service_unavailable")
+}
+
+// writeGenerationError reports a failed backend call. A full backend queue
+// or an expired queue wait is reported as 503 so clients back off and
+// retry; anything else is an internal error.
+func writeGenerationError(w http.ResponseWriter, message string, err error) {
+	status, code := GenerationErrorStatus(w.Header(), err)
+	writeError(w, status, message, code)
+}
+
+// GenerationErrorStatus maps a failed backend call to its HTTP status and
+// error code, setting Retry-After on h when the backend is saturated. The
+// Ollama-compatible API shares it so both report failures alike.
+func GenerationErrorStatus(h http.Header, err error) (int, string) {
+	var capacityErr *proxyerrors.BackendCapacityError
+	var timeoutErr *proxyerrors.BackendTimeoutError
+	if errors.As(err, &capacityErr) || errors.As(err, &timeoutErr) {
+		router.WriteRetryAfter(h, err)
+		return http.StatusServiceUnavailable, "server_overloaded"
+	}
+	if errors.Is(err, router.ErrGenerationStopped) {
+		return http.StatusServiceUnavailable, "generation_stopped"
+	}
+	return http.StatusInternalServerError, "internal_error"
+}
+
 // writeError writes an OpenAI-compatible error response
 func writeError(w http.ResponseWriter, statusCode int, message string, errorType string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -357,9 +597,10 @@ func writeError(w http.ResponseWriter, statusCode int, message string, errorType
 
 	errorResp := ErrorResponse{
 		Error: ErrorDetail{
-			Message: message,
-			Type:    errorType,
-			Code:    errorType,
+			Message:   message,
+			Type:      errorType,
+			Code:      errorType,
+			RequestID: w.Header().Get(middleware.RequestIDHeader),
 		},
 	}
 
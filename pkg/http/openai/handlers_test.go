@@ -7,11 +7,23 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/daoneill/ollama-proxy/pkg/audit"
+	"github.com/daoneill/ollama-proxy/pkg/auth"
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/labels"
+	proxyerrors "github.com/daoneill/ollama-proxy/pkg/errors"
+	"github.com/daoneill/ollama-proxy/pkg/http/postprocess"
+	"github.com/daoneill/ollama-proxy/pkg/langdetect"
+	"github.com/daoneill/ollama-proxy/pkg/maintenance"
 	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/thermal"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
 )
 
 // mockBackend implements backends.Backend interface for testing
@@ -165,6 +177,27 @@ func TestHandleChatCompletion_MissingMessages(t *testing.T) {
 	}
 }
 
+func TestHandleChatCompletion_ResumeWithoutStream(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	handler := HandleChatCompletion(r)
+
+	reqBody := ChatCompletionRequest{
+		Model:      "test-model",
+		Messages:   []ChatCompletionMessage{{Role: "user", Content: "Hello"}},
+		ResumeFrom: "Hi",
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "resume_from requires stream") {
+		t.Errorf("Expected 400 for resume_from without stream, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 func TestHandleChatCompletion_Success(t *testing.T) {
 	backend := &mockBackend{
 		id:            "test-backend",
@@ -203,6 +236,44 @@ func TestHandleChatCompletion_Success(t *testing.T) {
 	}
 }
 
+func TestHandleChatCompletion_Ensemble(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "backend-a", supportsModel: true, generateResp: &backends.GenerateResponse{Response: "Positive"}})
+	r.RegisterBackend(&mockBackend{id: "backend-b", supportsModel: true, generateResp: &backends.GenerateResponse{Response: "positive."}})
+	r.SetEnsemble(&router.EnsembleConfig{Size: 2})
+
+	handler := HandleChatCompletion(r)
+
+	reqBody := ChatCompletionRequest{
+		Model:    "test-model",
+		Messages: []ChatCompletionMessage{{Role: "user", Content: "Sentiment of: great!"}},
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body))
+	req.Header.Set("X-Ensemble", "true")
+	req.Header.Set("X-Ensemble-Candidates", "true")
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Ensemble-Method") != "vote" || len(strings.Split(w.Header().Get("X-Ensemble-Members"), ",")) != 2 {
+		t.Errorf("Expected a vote between 2 members, got method %q members %q",
+			w.Header().Get("X-Ensemble-Method"), w.Header().Get("X-Ensemble-Members"))
+	}
+
+	var resp ChatCompletionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Ensemble == nil || len(resp.Ensemble.Candidates) != 2 || resp.Ensemble.Winner != w.Header().Get("X-Backend-Used") {
+		t.Errorf("Expected both candidates and the winner in the body, got %+v", resp.Ensemble)
+	}
+}
+
 func TestHandleCompletion_Success(t *testing.T) {
 	backend := &mockBackend{
 		id:            "test-backend",
@@ -286,6 +357,35 @@ func TestHandleModels_Success(t *testing.T) {
 	}
 }
 
+func TestHandleModels_Banner(t *testing.T) {
+	prev := maintenance.Default
+	state := maintenance.New()
+	state.SetBanner("Planned downtime Saturday 02:00 UTC")
+	maintenance.SetDefault(state)
+	defer maintenance.SetDefault(prev)
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "test-backend"})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	w := httptest.NewRecorder()
+	HandleModels(r)(w, req)
+
+	var resp ModelsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Metadata == nil {
+		t.Fatal("Expected metadata with banner")
+	}
+	if resp.Metadata.Banner != "Planned downtime Saturday 02:00 UTC" {
+		t.Errorf("Unexpected banner: %q", resp.Metadata.Banner)
+	}
+	if resp.Metadata.Maintenance {
+		t.Error("Expected maintenance flag to be false")
+	}
+}
+
 func TestParseRoutingHeaders(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	req.Header.Set("X-Target-Backend", "test-backend")
@@ -315,6 +415,7 @@ func TestParseRoutingHeaders_AllHeaders(t *testing.T) {
 	req.Header.Set("X-Priority", "critical")
 	req.Header.Set("X-Request-ID", "req-12345")
 	req.Header.Set("X-Deadline-Ms", "1234567890")
+	req.Header.Set("X-Speculative", "true")
 	req.Header.Set("X-Custom-Key1", "value1")
 	req.Header.Set("X-Custom-Key2", "value2")
 
@@ -350,6 +451,9 @@ func TestParseRoutingHeaders_AllHeaders(t *testing.T) {
 	if annotations.DeadlineMs != 1234567890 {
 		t.Errorf("Expected DeadlineMs 1234567890, got %d", annotations.DeadlineMs)
 	}
+	if !annotations.Speculative {
+		t.Error("Expected Speculative to be true")
+	}
 	if annotations.Custom["Key1"] != "value1" {
 		t.Errorf("Expected Custom[Key1]='value1', got %s", annotations.Custom["Key1"])
 	}
@@ -512,6 +616,58 @@ func TestWriteError(t *testing.T) {
 	}
 }
 
+func TestWriteError_RequestID(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Request-ID", "trace-123")
+
+	writeError(w, http.StatusBadRequest, "Test error", "test_error_code")
+
+	var errResp ErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if errResp.Error.RequestID != "trace-123" {
+		t.Errorf("Expected the response's request ID in the error, got %q", errResp.Error.RequestID)
+	}
+}
+
+func TestWriteGenerationError(t *testing.T) {
+	busy := httptest.NewRecorder()
+	writeGenerationError(busy, "Generation failed", fmt.Errorf("wrapped: %w", &proxyerrors.BackendCapacityError{BackendID: "npu"}))
+	if busy.Code != http.StatusServiceUnavailable || busy.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 503 with Retry-After for a full queue, got %d %v", busy.Code, busy.Header())
+	}
+
+	failed := httptest.NewRecorder()
+	writeGenerationError(failed, "Generation failed", fmt.Errorf("boom"))
+	if failed.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for other errors, got %d", failed.Code)
+	}
+}
+
+func TestWriteRoutingError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{proxyerrors.NewInsufficientMemoryError("llama3:70b", 48<<30, 12<<30), "insufficient_memory"},
+		{proxyerrors.NewNoBackendsError(2, 0, nil), "service_unavailable"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writeRoutingError(w, tt.err)
+
+		var errResp ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+			t.Fatalf("Failed to decode error response: %v", err)
+		}
+		if w.Code != http.StatusServiceUnavailable || errResp.Error.Code != tt.code {
+			t.Errorf("Expected 503 %s, got %d %s", tt.code, w.Code, errResp.Error.Code)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Errorf("Expected Retry-After with %s", tt.code)
+		}
+	}
+}
+
 func TestHandleChatCompletion_ModelNotSupported(t *testing.T) {
 	backend := &mockBackend{
 		id:            "test-backend",
@@ -991,6 +1147,29 @@ func TestWriteRoutingHeaders_Minimal(t *testing.T) {
 	}
 }
 
+func TestWriteEnergyHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteEnergyHeaders(w, &backends.GenerationStats{EnergyWh: 0.0125, EnergyMeasured: true})
+	if got := w.Header().Get("X-Energy-Wh"); got != "0.0125" {
+		t.Errorf("Expected X-Energy-Wh 0.0125, got %q", got)
+	}
+	if got := w.Header().Get("X-Energy-Source"); got != "measured" {
+		t.Errorf("Expected X-Energy-Source measured, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	WriteEnergyHeaders(w, &backends.GenerationStats{EnergyWh: 0.5})
+	if got := w.Header().Get("X-Energy-Source"); got != "estimated" {
+		t.Errorf("Expected X-Energy-Source estimated, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	WriteEnergyHeaders(w, nil)
+	if got := w.Header().Get("X-Energy-Wh"); got != "" {
+		t.Errorf("Expected no energy header without stats, got %q", got)
+	}
+}
+
 // ===== Additional Tests for Better Coverage =====
 
 // Test HandleEmbedding succeeds when backend supports embeddings
@@ -1238,6 +1417,130 @@ func TestHandleCompletion_StreamingSuccess(t *testing.T) {
 	}
 }
 
+// coldBackend streams after reporting that its model must load first
+type coldBackend struct {
+	*mockBackendWithStream
+	coldStart *backends.ColdStart
+}
+
+func (b *coldBackend) ColdStart(ctx context.Context, model string) *backends.ColdStart {
+	return b.coldStart
+}
+
+// Test a cold start is announced before the first token
+func TestHandleChatCompletion_StreamingColdStart(t *testing.T) {
+	backend := &coldBackend{
+		mockBackendWithStream: &mockBackendWithStream{
+			mockBackend: &mockBackend{id: "test-backend", supportsModel: true, supportsStream: true},
+			reader:      &mockStreamReader{chunks: []backends.StreamChunk{{Token: "Hi", Done: true}}},
+		},
+		coldStart: &backends.ColdStart{Reason: backends.ColdStartModelLoad, Model: "test-model", Estimate: 11500 * time.Millisecond},
+	}
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(backend)
+
+	body, _ := json.Marshal(ChatCompletionRequest{
+		Model:    "test-model",
+		Stream:   true,
+		Messages: []ChatCompletionMessage{{Role: "user", Content: "Hello"}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	HandleChatCompletion(r)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-Cold-Start"); got != "model_load" {
+		t.Errorf("Expected X-Cold-Start model_load, got %q", got)
+	}
+	if got := w.Header().Get("X-Cold-Start-Estimate-Ms"); got != "11500" {
+		t.Errorf("Expected X-Cold-Start-Estimate-Ms 11500, got %q", got)
+	}
+	if !strings.HasPrefix(w.Body.String(), ": loading model test-model, ~12s\n\ndata: ") {
+		t.Errorf("Expected the notice before the first chunk, got %q", w.Body.String())
+	}
+}
+
+// slowColdBackend takes a while to start streaming after a cold start
+type slowColdBackend struct {
+	*coldBackend
+	delay time.Duration
+}
+
+func (b *slowColdBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	time.Sleep(b.delay)
+	return b.coldBackend.GenerateStream(ctx, req)
+}
+
+// Test load progress is sent as comments until the first token
+func TestHandleChatCompletion_StreamingColdStartProgress(t *testing.T) {
+	defer func(interval time.Duration) { backends.ProgressInterval = interval }(backends.ProgressInterval)
+	backends.ProgressInterval = 10 * time.Millisecond
+
+	backend := &slowColdBackend{
+		coldBackend: &coldBackend{
+			mockBackendWithStream: &mockBackendWithStream{
+				mockBackend: &mockBackend{id: "test-backend", supportsModel: true, supportsStream: true},
+				reader:      &mockStreamReader{chunks: []backends.StreamChunk{{Token: "Hi", Done: true}}},
+			},
+			coldStart: &backends.ColdStart{Reason: backends.ColdStartModelLoad, Model: "test-model", Estimate: time.Second},
+		},
+		delay: 50 * time.Millisecond,
+	}
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(backend)
+
+	body, _ := json.Marshal(ChatCompletionRequest{
+		Model:    "test-model",
+		Stream:   true,
+		Messages: []ChatCompletionMessage{{Role: "user", Content: "Hello"}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	HandleChatCompletion(r)(w, req)
+
+	got := w.Body.String()
+	progress := strings.Index(got, `: progress {"time":`)
+	if progress < 0 || !strings.Contains(got, `"phase":"load"`) || !strings.Contains(got, `"estimated":true`) {
+		t.Fatalf("Expected progress comments, got %q", got)
+	}
+	if data := strings.Index(got, "data: "); data < progress || strings.Contains(got[data:], ": progress") {
+		t.Errorf("Expected progress only before the first chunk, got %q", got)
+	}
+}
+
+// Test a stream that fails after the cold start notice ends with an error event
+func TestHandleCompletion_StreamingColdStartError(t *testing.T) {
+	backend := &coldBackend{
+		mockBackendWithStream: &mockBackendWithStream{
+			mockBackend: &mockBackend{id: "test-backend", supportsModel: true, supportsStream: true, streamErr: fmt.Errorf("load failed")},
+		},
+		coldStart: &backends.ColdStart{Reason: backends.ColdStartContainerStart, Model: "test-model"},
+	}
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(backend)
+
+	body, _ := json.Marshal(CompletionRequest{Model: "test-model", Prompt: "Hello", Stream: true})
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	HandleCompletion(r)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the stream to have started, got %d", w.Code)
+	}
+	if got := w.Body.String(); !strings.HasPrefix(got, ": starting backend for test-model\n\n") ||
+		!strings.Contains(got, "event: error\n") || !strings.Contains(got, "load failed") {
+		t.Errorf("Expected notice then error event, got %q", got)
+	}
+}
+
 // Test ParseRoutingHeaders with invalid numeric values
 func TestParseRoutingHeaders_InvalidNumericValues(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
@@ -1894,6 +2197,32 @@ func TestWriteRoutingHeaders_AllFields(t *testing.T) {
 	}
 }
 
+func TestWriteRoutingHeaders_Thermal(t *testing.T) {
+	decision := &router.RoutingDecision{
+		Backend: &mockBackend{id: "test-backend"},
+		Thermal: &router.ThermalSnapshot{
+			Hardware:     "nvidia",
+			ThermalState: thermal.ThermalState{Temperature: 84.25, Throttling: true},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	WriteRoutingHeaders(w, decision)
+	if got := w.Header().Get("X-Backend-Temperature-C"); got != "84.2" {
+		t.Errorf("Expected X-Backend-Temperature-C 84.2, got %q", got)
+	}
+	if got := w.Header().Get("X-Backend-Throttling"); got != "true" {
+		t.Errorf("Expected X-Backend-Throttling true, got %q", got)
+	}
+
+	// Without thermal monitoring neither header is set
+	w = httptest.NewRecorder()
+	WriteRoutingHeaders(w, &router.RoutingDecision{Backend: &mockBackend{id: "test-backend"}})
+	if w.Header().Get("X-Backend-Temperature-C") != "" || w.Header().Get("X-Backend-Throttling") != "" {
+		t.Error("Expected no thermal headers without a snapshot")
+	}
+}
+
 // Test WriteRoutingHeaders with zero values
 func TestWriteRoutingHeaders_ZeroValues(t *testing.T) {
 	backend := &mockBackend{
@@ -1923,3 +2252,219 @@ func TestWriteRoutingHeaders_ZeroValues(t *testing.T) {
 		t.Error("Should not set X-Estimated-Latency-Ms for 0 value")
 	}
 }
+
+func TestHandleChatCompletion_RecordsUsage(t *testing.T) {
+	recorder := usage.NewRecorder(usage.DefaultConfig())
+	prev := usage.Default()
+	usage.SetDefault(recorder)
+	defer usage.SetDefault(prev)
+
+	backend := &mockBackend{
+		id:            "test-backend",
+		supportsModel: true,
+	}
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(backend)
+
+	handler := HandleChatCompletion(r)
+
+	reqBody := ChatCompletionRequest{
+		Model: "test-model",
+		Messages: []ChatCompletionMessage{
+			{Role: "user", Content: "Hello"},
+		},
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body))
+	req = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: "ci-key", Tenant: "lab-a"}))
+	req = req.WithContext(labels.NewContext(req.Context(), labels.Labels{"app": "docsbot"}))
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	records := recorder.Query(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 usage record, got %d", len(records))
+	}
+	rec := records[0]
+	if rec.Tenant != "lab-a" || rec.Key != "ci-key" {
+		t.Errorf("Expected tenant lab-a and key ci-key, got %s/%s", rec.Tenant, rec.Key)
+	}
+	if rec.Backend != "test-backend" || rec.Status != "success" {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+	if rec.CompletionTokens != 7 || rec.PromptTokens == 0 {
+		t.Errorf("Expected token counts to be recorded, got %+v", rec)
+	}
+	if rec.Labels["app"] != "docsbot" {
+		t.Errorf("Expected request labels on the record, got %v", rec.Labels)
+	}
+}
+
+func TestHandleChatCompletion_AuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.NewLogger(audit.Config{Path: path, Redact: []string{audit.FieldTenant}})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer auditLog.Close()
+	audit.SetDefault(auditLog)
+	defer audit.SetDefault(nil)
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "test-backend", supportsModel: true})
+
+	messages := []ChatCompletionMessage{{Role: "user", Content: "Hello"}}
+	body, _ := json.Marshal(ChatCompletionRequest{Model: "test-model", Messages: messages})
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body))
+	req = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: "ci-key", Tenant: "lab-a"}))
+	HandleChatCompletion(r)(httptest.NewRecorder(), req)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	var rec audit.Record
+	if err := json.Unmarshal(bytes.TrimSpace(data), &rec); err != nil {
+		t.Fatalf("Expected one JSONL record, got %q: %v", data, err)
+	}
+	if rec.Key != "ci-key" || rec.Tenant != "" || rec.Backend != "test-backend" || rec.Endpoint != "/v1/chat/completions" {
+		t.Errorf("Unexpected audit record: %+v", rec)
+	}
+	if rec.Status != "success" || rec.CompletionTokens != 7 || rec.PromptTokens == 0 {
+		t.Errorf("Expected outcome and token counts, got %+v", rec)
+	}
+	if want := auditLog.PromptHash(buildPromptFromMessages(messages)); rec.PromptHash != want {
+		t.Errorf("Expected prompt hash %s, got %s", want, rec.PromptHash)
+	}
+	if strings.Contains(string(data), "Hello") {
+		t.Error("Expected the prompt itself to stay out of the audit log")
+	}
+}
+
+func TestHandleChatCompletion_PostProcessing(t *testing.T) {
+	chain, _ := postprocess.Build([]string{postprocess.StepStripHTML, postprocess.StepCitations})
+	postprocess.SetDefault(chain)
+	defer postprocess.SetDefault(nil)
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "test-backend", supportsModel: true, generateResp: &backends.GenerateResponse{
+		Response: "Restart it [1].<script>alert(1)</script>",
+	}})
+
+	body, _ := json.Marshal(ChatCompletionRequest{Model: "test-model", Messages: []ChatCompletionMessage{{Role: "user", Content: "How?"}}})
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body))
+	req = req.WithContext(postprocess.WithSources(req.Context(), []postprocess.Source{{Title: "Runbook"}}))
+	w := httptest.NewRecorder()
+	HandleChatCompletion(r)(w, req)
+
+	var resp ChatCompletionResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Choices) != 1 {
+		t.Fatalf("Expected one choice, got %d (status %d)", len(resp.Choices), w.Code)
+	}
+	if got, want := resp.Choices[0].Message.Content, "Restart it [1].\n\nSources:\n[1] Runbook"; got != want {
+		t.Errorf("Expected post-processed content %q, got %q", want, got)
+	}
+}
+
+func TestParseRoutingHeaders_Labels(t *testing.T) {
+	// Only labels the middleware accepted reach the annotations
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("X-Labels", "team=ml,unchecked=1")
+	if annotations := ParseRoutingHeaders(req); annotations.Labels != nil {
+		t.Errorf("Expected no labels without the middleware, got %v", annotations.Labels)
+	}
+
+	req = req.WithContext(labels.NewContext(req.Context(), labels.Labels{"team": "ml"}))
+	if annotations := ParseRoutingHeaders(req); annotations.Labels["team"] != "ml" || len(annotations.Labels) != 1 {
+		t.Errorf("Expected team=ml, got %v", annotations.Labels)
+	}
+}
+
+func TestParseRoutingHeaders_PrioritySources(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		header   map[string]string
+		expected backends.Priority
+	}{
+		{"query parameter", "/v1/chat/completions?priority=critical", nil, backends.PriorityCritical},
+		{"query parameter low", "/v1/chat/completions?priority=low", nil, backends.PriorityBestEffort},
+		{"rfc 9218 urgency 0", "/v1/chat/completions", map[string]string{"Priority": "u=0"}, backends.PriorityCritical},
+		{"rfc 9218 urgency with incremental", "/v1/chat/completions", map[string]string{"Priority": "i, u=1"}, backends.PriorityHigh},
+		{"rfc 9218 default urgency", "/v1/chat/completions", map[string]string{"Priority": "u=3"}, backends.PriorityNormal},
+		{"rfc 9218 background", "/v1/chat/completions", map[string]string{"Priority": "u=7"}, backends.PriorityBestEffort},
+		{"rfc 9218 invalid falls back", "/v1/chat/completions", map[string]string{"Priority": "u=9"}, backends.PriorityNormal},
+		{"x-priority wins over query", "/v1/chat/completions?priority=low", map[string]string{"X-Priority": "high"}, backends.PriorityHigh},
+		{"query wins over rfc header", "/v1/chat/completions?priority=high", map[string]string{"Priority": "u=7"}, backends.PriorityHigh},
+		{"unknown query falls back", "/v1/chat/completions?priority=urgent", map[string]string{"Priority": "u=0"}, backends.PriorityCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.url, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			if got := ParseRoutingHeaders(req).Priority; got != tt.expected {
+				t.Errorf("Expected priority %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		text    string
+		want    string // annotation language
+		ctxLang string
+	}{
+		{"english prompt", nil, "What is the capital of France and why is it important?", "en", "en"},
+		{"french prompt", nil, "Pourquoi le ciel est bleu? Explique avec des exemples", "fr", "fr"},
+		{"header override", map[string]string{"X-Language": "DE"}, "What is the weather like?", "de", "de"},
+		{"locale only is not routed on", map[string]string{"Accept-Language": "fr-CA"}, "ok", "", "fr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			annotations := ParseRoutingHeaders(req)
+
+			req = DetectLanguage(req, annotations, tt.text)
+
+			if annotations.Language != tt.want {
+				t.Errorf("annotations.Language = %q, want %q", annotations.Language, tt.want)
+			}
+			result, ok := langdetect.FromContext(req.Context())
+			if !ok || result.Language != tt.ctxLang {
+				t.Errorf("context result = %+v (%v), want %q", result, ok, tt.ctxLang)
+			}
+		})
+	}
+}
+
+func TestHandleChatCompletion_DetectedLanguageHeader(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "test-backend", supportsModel: true, generateResp: &backends.GenerateResponse{Response: "Bonjour"}})
+
+	body := `{"model":"llama3","messages":[{"role":"user","content":"Pourquoi le ciel est bleu? Explique avec des exemples"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	HandleChatCompletion(r)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if lang := w.Header().Get("X-Detected-Language"); lang != "fr" {
+		t.Errorf("X-Detected-Language = %q, want fr", lang)
+	}
+}
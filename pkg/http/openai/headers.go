@@ -7,14 +7,14 @@ import (
 	"strings"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/labels"
+	"github.com/daoneill/ollama-proxy/pkg/langdetect"
 	"github.com/daoneill/ollama-proxy/pkg/router"
 )
 
 // ParseRoutingHeaders extracts routing annotations from HTTP request headers
 func ParseRoutingHeaders(r *http.Request) *backends.Annotations {
-	annotations := &backends.Annotations{
-		Custom: make(map[string]string),
-	}
+	annotations := &backends.Annotations{}
 
 	// X-Target-Backend: Explicit backend selection (e.g., "ollama-nvidia", "ollama-npu")
 	if target := r.Header.Get("X-Target-Backend"); target != "" {
@@ -26,6 +26,21 @@ func ParseRoutingHeaders(r *http.Request) *backends.Annotations {
 		annotations.LatencyCritical = parseBool(latency)
 	}
 
+	// X-Speculative: Draft on a small model for the routed one to verify (true/false)
+	if spec := r.Header.Get("X-Speculative"); spec != "" {
+		annotations.Speculative = parseBool(spec)
+	}
+
+	// X-Ensemble: Query several backends and return the best answer (true/false)
+	if ensemble := r.Header.Get("X-Ensemble"); ensemble != "" {
+		annotations.Ensemble = parseBool(ensemble)
+	}
+
+	// X-Ensemble-Candidates: Return every ensemble member's answer (true/false)
+	if candidates := r.Header.Get("X-Ensemble-Candidates"); candidates != "" {
+		annotations.EnsembleCandidates = parseBool(candidates)
+	}
+
 	// X-Power-Efficient: Route to lowest power backend (true/false)
 	if power := r.Header.Get("X-Power-Efficient"); power != "" {
 		annotations.PreferPowerEfficiency = parseBool(power)
@@ -69,18 +84,18 @@ func ParseRoutingHeaders(r *http.Request) *backends.Annotations {
 		}
 	}
 
-	// X-Priority: Explicit priority level (best-effort, normal, high, critical)
-	if priority := r.Header.Get("X-Priority"); priority != "" {
-		switch strings.ToLower(priority) {
-		case "best-effort", "low":
-			annotations.Priority = backends.PriorityBestEffort
-		case "normal":
-			annotations.Priority = backends.PriorityNormal
-		case "high":
-			annotations.Priority = backends.PriorityHigh
-		case "critical", "realtime":
-			annotations.Priority = backends.PriorityCritical
+	// Priority, most explicit first:
+	//   X-Priority header (best-effort, normal, high, critical)
+	//   ?priority= query parameter, for clients behind header-stripping proxies
+	//   RFC 9218 Priority header urgency (u=0 most urgent ... u=7)
+	if xPriority := r.Header.Get("X-Priority"); xPriority != "" {
+		if priority, ok := parsePriorityName(xPriority); ok {
+			annotations.Priority = priority
 		}
+	} else if priority, ok := parsePriorityName(priorityParam(r)); ok {
+		annotations.Priority = priority
+	} else if priority, ok := parseUrgency(r.Header.Get("Priority")); ok {
+		annotations.Priority = priority
 	} else {
 		// Auto-set priority based on other headers
 		if annotations.LatencyCritical || annotations.MediaType == backends.MediaTypeRealtime {
@@ -104,10 +119,18 @@ func ParseRoutingHeaders(r *http.Request) *backends.Annotations {
 		}
 	}
 
+	// X-Labels: client labels, already validated by the labels middleware
+	if l := labels.FromContext(r.Context()); len(l) > 0 {
+		annotations.Labels = l
+	}
+
 	// X-Custom-*: Custom annotations (e.g., X-Custom-Priority: high)
 	for key, values := range r.Header {
 		if strings.HasPrefix(key, "X-Custom-") && len(values) > 0 {
 			customKey := strings.TrimPrefix(key, "X-Custom-")
+			if annotations.Custom == nil {
+				annotations.Custom = make(map[string]string)
+			}
 			annotations.Custom[customKey] = values[0]
 		}
 	}
@@ -126,6 +149,30 @@ func WriteRoutingHeaders(w http.ResponseWriter, decision *router.RoutingDecision
 		w.Header().Set("X-Backend-Used", decision.Backend.ID())
 	}
 
+	// X-Hedged-To: Backend a hedged duplicate was sent to, if one was
+	if hedged, ok := decision.Backend.(*router.HedgedBackend); ok && hedged.Hedged() {
+		w.Header().Set("X-Hedged-To", hedged.HedgeID())
+	}
+
+	// X-Ensemble-Members, X-Ensemble-Method: Backends an ensemble request
+	// was sent to and how the answer was chosen
+	if len(decision.Ensemble) > 0 {
+		w.Header().Set("X-Ensemble-Members", strings.Join(decision.Ensemble, ","))
+		if ens, ok := decision.Backend.(*router.EnsembleBackend); ok && ens.Result() != nil {
+			w.Header().Set("X-Ensemble-Method", ens.Result().Method)
+		}
+	}
+
+	// X-Speculative-Draft, X-Speculative-Accepted: Backend that drafted and
+	// how many draft tokens the routed backend kept
+	if spec := decision.Speculative; spec != nil {
+		stats := spec.Stats()
+		w.Header().Set("X-Speculative-Draft", spec.DraftID())
+		if stats.Rounds > 0 {
+			w.Header().Set("X-Speculative-Accepted", fmt.Sprintf("%d/%d", stats.AcceptedTokens, stats.DraftedTokens))
+		}
+	}
+
 	// X-Routing-Reason: Why this backend was selected
 	if decision.Reason != "" {
 		w.Header().Set("X-Routing-Reason", decision.Reason)
@@ -145,6 +192,123 @@ func WriteRoutingHeaders(w http.ResponseWriter, decision *router.RoutingDecision
 	if len(decision.Alternatives) > 0 {
 		w.Header().Set("X-Alternatives", strings.Join(decision.Alternatives, ","))
 	}
+
+	// X-Backend-Temperature-C / X-Backend-Throttling: Thermal state of the
+	// backend's hardware at dispatch, when thermal monitoring is enabled
+	if decision.Thermal != nil {
+		w.Header().Set("X-Backend-Temperature-C", fmt.Sprintf("%.1f", decision.Thermal.Temperature))
+		w.Header().Set("X-Backend-Throttling", strconv.FormatBool(decision.Thermal.Throttling))
+	}
+
+	// X-Detected-Language: Prompt language the routing decision considered
+	if decision.DetectedLanguage != "" {
+		w.Header().Set("X-Detected-Language", decision.DetectedLanguage)
+	}
+}
+
+// ensembleCandidates returns every ensemble member's answer for the
+// response body, nil unless the client asked for them
+func ensembleCandidates(decision *router.RoutingDecision) *router.EnsembleResult {
+	if ens, ok := decision.Backend.(*router.EnsembleBackend); ok && ens.ReturnsCandidates() {
+		return ens.Result()
+	}
+	return nil
+}
+
+// energyTrailers are the energy headers, declared as trailers on streams
+// since a stream's energy is known only once it ends
+const energyTrailers = "X-Energy-Wh, X-Energy-Source"
+
+// DeclareEnergyTrailers announces the energy headers as trailers; call it
+// before a stream writes its first byte and WriteEnergyHeaders after it ends
+func DeclareEnergyTrailers(w http.ResponseWriter) {
+	w.Header().Set("Trailer", energyTrailers)
+}
+
+// WriteEnergyHeaders reports the energy a request used: X-Energy-Wh, and
+// X-Energy-Source "measured" when RAPL or NVML metered it or "estimated"
+// when the backend derived it from its power rating
+func WriteEnergyHeaders(w http.ResponseWriter, stats *backends.GenerationStats) {
+	if stats == nil || stats.EnergyWh <= 0 {
+		return
+	}
+	w.Header().Set("X-Energy-Wh", strconv.FormatFloat(float64(stats.EnergyWh), 'g', 6, 32))
+	if stats.EnergyMeasured {
+		w.Header().Set("X-Energy-Source", "measured")
+	} else {
+		w.Header().Set("X-Energy-Source", "estimated")
+	}
+}
+
+// DetectLanguage records the prompt's language on the annotations and in
+// the request context. An X-Language header overrides detection; otherwise
+// the configured detector runs with Accept-Language as a locale hint, and
+// only a confident result is used for routing.
+func DetectLanguage(req *http.Request, annotations *backends.Annotations, text string) *http.Request {
+	var result langdetect.Result
+	if lang := strings.TrimSpace(req.Header.Get("X-Language")); lang != "" {
+		result = langdetect.Result{Language: strings.ToLower(lang), Confidence: 1, Source: "header"}
+	} else {
+		result = langdetect.Detect(text, langdetect.LocaleHint(req.Header.Get("Accept-Language")))
+	}
+
+	if result.Confident() {
+		annotations.Language = result.Language
+	}
+	return req.WithContext(langdetect.NewContext(req.Context(), result))
+}
+
+// priorityParam returns the ?priority= query parameter, without parsing
+// the query of the many requests that have none
+func priorityParam(r *http.Request) string {
+	if r.URL.RawQuery == "" {
+		return ""
+	}
+	return r.URL.Query().Get("priority")
+}
+
+// parsePriorityName maps a priority name to backends.Priority
+func parsePriorityName(s string) (backends.Priority, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "best-effort", "low":
+		return backends.PriorityBestEffort, true
+	case "normal":
+		return backends.PriorityNormal, true
+	case "high":
+		return backends.PriorityHigh, true
+	case "critical", "realtime":
+		return backends.PriorityCritical, true
+	}
+	return 0, false
+}
+
+// parseUrgency maps the urgency of an RFC 9218 Priority header (e.g.
+// "u=1, i") to backends.Priority: 0 critical, 1-2 high, 3-4 normal (3 is the
+// RFC default), 5-7 best-effort.
+func parseUrgency(header string) (backends.Priority, bool) {
+	for rest := header; rest != ""; {
+		var param string
+		param, rest, _ = strings.Cut(rest, ",")
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || strings.TrimSpace(key) != "u" {
+			continue
+		}
+		urgency, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || urgency < 0 || urgency > 7 {
+			return 0, false
+		}
+		switch {
+		case urgency == 0:
+			return backends.PriorityCritical, true
+		case urgency <= 2:
+			return backends.PriorityHigh, true
+		case urgency <= 4:
+			return backends.PriorityNormal, true
+		default:
+			return backends.PriorityBestEffort, true
+		}
+	}
+	return 0, false
 }
 
 // parseBool converts string to bool, accepting various formats
@@ -0,0 +1,440 @@
+package openai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/google/uuid"
+)
+
+// Image request limits, matching OpenAI's
+const (
+	MaxImagePromptChars = 4000
+	MaxImagesPerRequest = 10
+	MaxImageUploadBytes = 4 * 1024 * 1024
+	DefaultImageSize    = "1024x1024"
+)
+
+// Image response formats
+const (
+	ImageFormatURL     = "url"
+	ImageFormatB64JSON = "b64_json"
+)
+
+// ImageFilesPath serves images returned with response_format=url
+const ImageFilesPath = "/v1/images/files/"
+
+// Image store defaults
+const (
+	DefaultImageURLTTL     = time.Hour
+	DefaultMaxStoredImages = 256
+)
+
+// ImageStore holds generated images for response_format=url. Images are
+// kept in memory and expire after the TTL, like OpenAI's hosted URLs.
+type ImageStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	maxImages int
+	images    map[string]*storedImage
+	order     []string // insertion order, for eviction
+}
+
+type storedImage struct {
+	data      []byte
+	format    backends.ImageFormat
+	expiresAt time.Time
+}
+
+// NewImageStore creates an image store. Zero values use the defaults.
+func NewImageStore(ttl time.Duration, maxImages int) *ImageStore {
+	if ttl <= 0 {
+		ttl = DefaultImageURLTTL
+	}
+	if maxImages <= 0 {
+		maxImages = DefaultMaxStoredImages
+	}
+	return &ImageStore{
+		ttl:       ttl,
+		maxImages: maxImages,
+		images:    make(map[string]*storedImage),
+	}
+}
+
+// Put stores an image and returns its ID
+func (s *ImageStore) Put(data []byte, format backends.ImageFormat) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictLocked(now)
+
+	id := uuid.New().String()
+	s.images[id] = &storedImage{data: data, format: format, expiresAt: now.Add(s.ttl)}
+	s.order = append(s.order, id)
+	return id
+}
+
+// Get returns a stored image that has not expired
+func (s *ImageStore) Get(id string) ([]byte, backends.ImageFormat, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	img, ok := s.images[id]
+	if !ok || time.Now().After(img.expiresAt) {
+		return nil, "", false
+	}
+	return img.data, img.format, true
+}
+
+// evictLocked drops expired images and the oldest beyond the cap
+func (s *ImageStore) evictLocked(now time.Time) {
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if img, ok := s.images[id]; ok && now.Before(img.expiresAt) {
+			kept = append(kept, id)
+		} else {
+			delete(s.images, id)
+		}
+	}
+	for len(kept) >= s.maxImages {
+		delete(s.images, kept[0])
+		kept = kept[1:]
+	}
+	s.order = kept
+}
+
+// HandleImageFile serves stored images at /v1/images/files/{id}
+func (s *ImageStore) HandleImageFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+			return
+		}
+
+		id := strings.TrimPrefix(req.URL.Path, ImageFilesPath)
+		data, format, ok := s.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, "Image not found or expired", "not_found")
+			return
+		}
+
+		w.Header().Set("Content-Type", imageContentType(format))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(s.ttl.Seconds())))
+		w.WriteHeader(http.StatusOK)
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+	}
+}
+
+// HandleImageGeneration handles /v1/images/generations
+func HandleImageGeneration(r *router.Router, store *ImageStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Only accept POST
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+			return
+		}
+
+		// Parse request
+		var imgReq ImageGenerationRequest
+		if err := json.NewDecoder(req.Body).Decode(&imgReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+			return
+		}
+
+		internalReq, err := ConvertImageGenerationRequest(&imgReq)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+
+		serveImages(w, req, r, store, "/v1/images/generations", &imgReq, internalReq)
+	}
+}
+
+// HandleImageEdit handles /v1/images/edits (multipart upload). The source
+// image and optional mask are passed to the backend as InitImage and Mask,
+// and the optional strength field (0-1) sets how far the edit departs from
+// the source. Edits are only routed to backends that support them.
+func HandleImageEdit(r *router.Router, store *ImageStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Only accept POST
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+			return
+		}
+
+		// Image and mask, plus form fields
+		req.Body = http.MaxBytesReader(w, req.Body, 2*MaxImageUploadBytes+1024*1024)
+		if err := req.ParseMultipartForm(2 * MaxImageUploadBytes); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("Images must be under %d MB", MaxImageUploadBytes/(1024*1024)), "invalid_request_error")
+				return
+			}
+			writeError(w, http.StatusBadRequest, "Request must be multipart/form-data", "invalid_request_error")
+			return
+		}
+		defer req.MultipartForm.RemoveAll()
+
+		image, err := readImageUpload(req, "image")
+		if err != nil || image == nil {
+			writeError(w, http.StatusBadRequest, "Image is required (PNG under 4 MB)", "invalid_request_error")
+			return
+		}
+		mask, err := readImageUpload(req, "mask")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Mask must be a PNG under 4 MB", "invalid_request_error")
+			return
+		}
+
+		imgReq := ImageGenerationRequest{
+			Model:          req.FormValue("model"),
+			Prompt:         req.FormValue("prompt"),
+			Size:           req.FormValue("size"),
+			ResponseFormat: req.FormValue("response_format"),
+			OutputFormat:   req.FormValue("output_format"),
+		}
+		if n := req.FormValue("n"); n != "" {
+			parsed, err := strconv.Atoi(n)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "n must be an integer", "invalid_request_error")
+				return
+			}
+			imgReq.N = parsed
+		}
+
+		internalReq, err := ConvertImageGenerationRequest(&imgReq)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+			return
+		}
+		internalReq.InitImage = image
+		internalReq.Mask = mask
+		if v := req.FormValue("strength"); v != "" {
+			strength, err := strconv.ParseFloat(v, 32)
+			if err != nil || strength <= 0 || strength > 1 {
+				writeError(w, http.StatusBadRequest, "strength must be a number above 0 and at most 1", "invalid_request_error")
+				return
+			}
+			internalReq.Strength = float32(strength)
+		}
+
+		serveImages(w, req, r, store, "/v1/images/edits", &imgReq, internalReq)
+	}
+}
+
+// readImageUpload reads an optional file field, enforcing the upload limit
+func readImageUpload(req *http.Request, field string) ([]byte, error) {
+	file, _, err := req.FormFile(field)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxImageUploadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxImageUploadBytes {
+		return nil, fmt.Errorf("%s exceeds %d bytes", field, MaxImageUploadBytes)
+	}
+	return data, nil
+}
+
+// serveImages routes, generates and writes the images response
+func serveImages(w http.ResponseWriter, req *http.Request, r *router.Router, store *ImageStore, endpoint string,
+	imgReq *ImageGenerationRequest, internalReq *backends.ImageGenRequest) {
+	if imgReq.ResponseFormat == ImageFormatURL && store == nil {
+		writeError(w, http.StatusBadRequest, "response_format url is not enabled", "invalid_request_error")
+		return
+	}
+
+	// Parse routing headers and require an image generation backend, or
+	// one that edits for requests with a source image
+	annotations := ParseRoutingHeaders(req)
+	if annotations.MediaType == "" {
+		annotations.MediaType = backends.MediaTypeImage
+	}
+	annotations.Capability = backends.CapabilityTextToImage
+	if internalReq.InitImage != nil {
+		annotations.Capability = backends.CapabilityImageToImage
+	}
+
+	// Route request
+	decision, err := r.RouteRequest(req.Context(), annotations)
+	if err != nil {
+		writeRoutingError(w, err)
+		return
+	}
+
+	// An explicit target bypasses capability filtering
+	if !decision.Backend.SupportsTextToImage() {
+		writeError(w, http.StatusBadRequest, "Backend does not support image generation", "invalid_request_error")
+		return
+	}
+	if internalReq.InitImage != nil && !backends.SupportsImageEdit(decision.Backend) {
+		writeError(w, http.StatusBadRequest, "Backend does not support image edits", "invalid_request_error")
+		return
+	}
+
+	// Check if backend supports the model
+	if !decision.Backend.SupportsModel(internalReq.Model) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Model %s not available", internalReq.Model), "model_not_found")
+		return
+	}
+
+	tracker := newUsageTracker(req.Context(), decision, endpoint, internalReq.Model, internalReq.Prompt)
+
+	// Execute request
+	resp, err := decision.Backend.GenerateImage(req.Context(), internalReq)
+	if err != nil {
+		tracker.Finish(0, nil, err)
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Image generation failed: %v", err), "internal_error")
+		return
+	}
+	tracker.Finish(0, resp.Stats, nil)
+
+	out := ImageResponse{
+		Created: time.Now().Unix(),
+		Data:    make([]ImageData, 0, len(resp.Images)),
+	}
+	for _, img := range resp.Images {
+		format := img.Format
+		if format == "" {
+			format = internalReq.Format
+		}
+		if imgReq.ResponseFormat == ImageFormatURL {
+			id := store.Put(img.ImageData, format)
+			out.Data = append(out.Data, ImageData{URL: requestBaseURL(req) + ImageFilesPath + id})
+		} else {
+			out.Data = append(out.Data, ImageData{B64JSON: base64.StdEncoding.EncodeToString(img.ImageData)})
+		}
+	}
+
+	// Write routing headers
+	WriteRoutingHeaders(w, decision)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(out)
+}
+
+// ConvertImageGenerationRequest validates an OpenAI image request and
+// converts it to the internal format, applying OpenAI's defaults
+func ConvertImageGenerationRequest(req *ImageGenerationRequest) (*backends.ImageGenRequest, error) {
+	if req.Model == "" {
+		return nil, errors.New("Model is required")
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, errors.New("Prompt is required")
+	}
+	if len([]rune(req.Prompt)) > MaxImagePromptChars {
+		return nil, fmt.Errorf("Prompt exceeds %d characters", MaxImagePromptChars)
+	}
+
+	if req.N == 0 {
+		req.N = 1
+	}
+	if req.N < 1 || req.N > MaxImagesPerRequest {
+		return nil, fmt.Errorf("n must be between 1 and %d", MaxImagesPerRequest)
+	}
+
+	if req.ResponseFormat == "" {
+		req.ResponseFormat = ImageFormatURL
+	}
+	if req.ResponseFormat != ImageFormatURL && req.ResponseFormat != ImageFormatB64JSON {
+		return nil, fmt.Errorf("Unsupported response_format %q (url, b64_json)", req.ResponseFormat)
+	}
+
+	if req.Size == "" {
+		req.Size = DefaultImageSize
+	}
+	width, height, err := parseImageSize(req.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	format := backends.ImageFormatPNG
+	switch strings.ToLower(req.OutputFormat) {
+	case "", "png":
+	case "jpeg", "jpg":
+		format = backends.ImageFormatJPEG
+	case "webp":
+		format = backends.ImageFormatWEBP
+	default:
+		return nil, fmt.Errorf("Unsupported output_format %q (png, jpeg, webp)", req.OutputFormat)
+	}
+
+	internal := &backends.ImageGenRequest{
+		Prompt:    req.Prompt,
+		Model:     req.Model,
+		Width:     width,
+		Height:    height,
+		Format:    format,
+		BatchSize: int32(req.N),
+	}
+	if req.Quality != "" || req.Style != "" {
+		internal.Options = make(map[string]string)
+		if req.Quality != "" {
+			internal.Options["quality"] = req.Quality
+		}
+		if req.Style != "" {
+			internal.Options["style"] = req.Style
+		}
+	}
+	return internal, nil
+}
+
+// parseImageSize parses "WIDTHxHEIGHT"
+func parseImageSize(size string) (int32, int32, error) {
+	w, h, ok := strings.Cut(strings.ToLower(size), "x")
+	if ok {
+		width, errW := strconv.Atoi(w)
+		height, errH := strconv.Atoi(h)
+		if errW == nil && errH == nil && width >= 64 && height >= 64 && width <= 4096 && height <= 4096 {
+			return int32(width), int32(height), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("Invalid size %q (WIDTHxHEIGHT, 64-4096 pixels)", size)
+}
+
+// requestBaseURL reconstructs the externally visible base URL
+func requestBaseURL(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + req.Host
+}
+
+// imageContentType returns the MIME type for an image format
+func imageContentType(format backends.ImageFormat) string {
+	switch format {
+	case backends.ImageFormatJPEG:
+		return "image/jpeg"
+	case backends.ImageFormatWEBP:
+		return "image/webp"
+	}
+	return "image/png"
+}
@@ -0,0 +1,272 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// imageBackend is a mockBackend with image generation support, and edit
+// support unless noEdit is set
+type imageBackend struct {
+	mockBackend
+	lastReq *backends.ImageGenRequest
+	noEdit  bool
+}
+
+func (m *imageBackend) SupportsTextToImage() bool { return true }
+func (m *imageBackend) SupportsImageEdit() bool   { return !m.noEdit }
+
+func (m *imageBackend) GenerateImage(ctx context.Context, req *backends.ImageGenRequest) (*backends.ImageGenResponse, error) {
+	m.lastReq = req
+	resp := &backends.ImageGenResponse{}
+	for i := int32(0); i < req.BatchSize; i++ {
+		resp.Images = append(resp.Images, backends.GeneratedImage{
+			ImageData: []byte("\x89PNG-image"),
+			Format:    req.Format,
+			Width:     req.Width,
+			Height:    req.Height,
+		})
+	}
+	return resp, nil
+}
+
+func newImageRouter() (*router.Router, *imageBackend) {
+	r := router.NewRouter(router.Config{})
+	// The text-only backend must be skipped by capability filtering
+	r.RegisterBackend(&mockBackend{id: "text-backend", supportsModel: true})
+	backend := &imageBackend{mockBackend: mockBackend{id: "sd-backend", supportsModel: true}}
+	r.RegisterBackend(backend)
+	return r, backend
+}
+
+func TestHandleImageGeneration_B64JSON(t *testing.T) {
+	r, backend := newImageRouter()
+	body := `{"model":"sdxl","prompt":"a lighthouse at dusk","n":2,"size":"512x768","response_format":"b64_json"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/generations", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	HandleImageGeneration(r, NewImageStore(0, 0))(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Backend-Used") != "sd-backend" {
+		t.Errorf("Expected routing to sd-backend, got %q", w.Header().Get("X-Backend-Used"))
+	}
+
+	var resp ImageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 2 {
+		t.Fatalf("Expected 2 images, got %d", len(resp.Data))
+	}
+	decoded, err := base64.StdEncoding.DecodeString(resp.Data[0].B64JSON)
+	if err != nil || string(decoded) != "\x89PNG-image" {
+		t.Errorf("Unexpected b64_json payload %q (%v)", resp.Data[0].B64JSON, err)
+	}
+	if backend.lastReq.Width != 512 || backend.lastReq.Height != 768 || backend.lastReq.Format != backends.ImageFormatPNG {
+		t.Errorf("Unexpected backend request %+v", backend.lastReq)
+	}
+}
+
+func TestHandleImageGeneration_URL(t *testing.T) {
+	r, _ := newImageRouter()
+	store := NewImageStore(time.Minute, 0)
+	body := `{"model":"sdxl","prompt":"a lighthouse at dusk","output_format":"webp"}`
+	req := httptest.NewRequest(http.MethodPost, "http://proxy.local:8080/v1/images/generations", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	HandleImageGeneration(r, store)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ImageResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data) != 1 || !strings.HasPrefix(resp.Data[0].URL, "http://proxy.local:8080"+ImageFilesPath) {
+		t.Fatalf("Expected hosted image URL, got %+v", resp.Data)
+	}
+
+	// The URL serves the stored image
+	fileReq := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(resp.Data[0].URL, "http://proxy.local:8080"), nil)
+	fw := httptest.NewRecorder()
+	store.HandleImageFile()(fw, fileReq)
+	if fw.Code != http.StatusOK || fw.Header().Get("Content-Type") != "image/webp" || fw.Body.String() != "\x89PNG-image" {
+		t.Errorf("Unexpected image file response %d %q %q", fw.Code, fw.Header().Get("Content-Type"), fw.Body.String())
+	}
+
+	missing := httptest.NewRecorder()
+	store.HandleImageFile()(missing, httptest.NewRequest(http.MethodGet, ImageFilesPath+"nope", nil))
+	if missing.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown image, got %d", missing.Code)
+	}
+}
+
+func TestHandleImageGeneration_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing model", `{"prompt":"x"}`},
+		{"missing prompt", `{"model":"sdxl"}`},
+		{"too many", `{"model":"sdxl","prompt":"x","n":11}`},
+		{"bad size", `{"model":"sdxl","prompt":"x","size":"huge"}`},
+		{"bad response format", `{"model":"sdxl","prompt":"x","response_format":"file"}`},
+		{"bad output format", `{"model":"sdxl","prompt":"x","output_format":"gif"}`},
+		{"invalid json", `{`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newImageRouter()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v1/images/generations", strings.NewReader(tt.body))
+			HandleImageGeneration(r, NewImageStore(0, 0))(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleImageGeneration_NoImageBackend(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "text-backend", supportsModel: true})
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/generations", strings.NewReader(`{"model":"sdxl","prompt":"x"}`))
+	w := httptest.NewRecorder()
+
+	HandleImageGeneration(r, NewImageStore(0, 0))(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleImageEdit(t *testing.T) {
+	r, backend := newImageRouter()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("model", "sdxl")
+	mw.WriteField("prompt", "add a boat")
+	mw.WriteField("response_format", "b64_json")
+	fw, _ := mw.CreateFormFile("image", "source.png")
+	fw.Write([]byte("source-png"))
+	fw, _ = mw.CreateFormFile("mask", "mask.png")
+	fw.Write([]byte("mask-png"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/edits", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	HandleImageEdit(r, NewImageStore(0, 0))(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if string(backend.lastReq.InitImage) != "source-png" || string(backend.lastReq.Mask) != "mask-png" {
+		t.Errorf("Expected image and mask passed to backend, got %+v", backend.lastReq)
+	}
+}
+
+func newImageEditRequest(strength string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("model", "sdxl")
+	mw.WriteField("prompt", "add a boat")
+	mw.WriteField("response_format", "b64_json")
+	if strength != "" {
+		mw.WriteField("strength", strength)
+	}
+	fw, _ := mw.CreateFormFile("image", "source.png")
+	fw.Write([]byte("source-png"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/edits", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestHandleImageEdit_RoutesToEditor(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	generator := &imageBackend{mockBackend: mockBackend{id: "generator", supportsModel: true}, noEdit: true}
+	editor := &imageBackend{mockBackend: mockBackend{id: "editor", supportsModel: true}}
+	r.RegisterBackend(generator)
+	r.RegisterBackend(editor)
+
+	w := httptest.NewRecorder()
+	HandleImageEdit(r, NewImageStore(0, 0))(w, newImageEditRequest("0.4"))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if generator.lastReq != nil || editor.lastReq == nil {
+		t.Fatal("Expected the edit routed to the backend that edits")
+	}
+	if editor.lastReq.Strength != 0.4 {
+		t.Errorf("Expected strength 0.4 passed to backend, got %v", editor.lastReq.Strength)
+	}
+}
+
+func TestHandleImageEdit_InvalidStrength(t *testing.T) {
+	r, _ := newImageRouter()
+
+	for _, strength := range []string{"0", "1.5", "strong"} {
+		w := httptest.NewRecorder()
+		HandleImageEdit(r, NewImageStore(0, 0))(w, newImageEditRequest(strength))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for strength %q, got %d", strength, w.Code)
+		}
+	}
+}
+
+func TestHandleImageEdit_MissingImage(t *testing.T) {
+	r, _ := newImageRouter()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("model", "sdxl")
+	mw.WriteField("prompt", "add a boat")
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/edits", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	HandleImageEdit(r, NewImageStore(0, 0))(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestImageStoreEviction(t *testing.T) {
+	store := NewImageStore(time.Minute, 2)
+	first := store.Put([]byte("1"), backends.ImageFormatPNG)
+	store.Put([]byte("2"), backends.ImageFormatPNG)
+	store.Put([]byte("3"), backends.ImageFormatPNG)
+
+	if _, _, ok := store.Get(first); ok {
+		t.Error("Expected oldest image evicted beyond the cap")
+	}
+
+	expiring := NewImageStore(time.Nanosecond, 0)
+	id := expiring.Put([]byte("x"), backends.ImageFormatPNG)
+	time.Sleep(time.Millisecond)
+	if _, _, ok := expiring.Get(id); ok {
+		t.Error("Expected expired image to be unavailable")
+	}
+}
@@ -0,0 +1,342 @@
+package openai
+
+import (
+	"net/http"
+
+	"github.com/daoneill/ollama-proxy/pkg/openapi"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+)
+
+// RoutingRequestHeaders are the annotation headers read by ParseRoutingHeaders
+// and DetectLanguage
+var RoutingRequestHeaders = []openapi.Header{
+	{Name: "X-Target-Backend", Description: "Route to this backend ID, bypassing scoring"},
+	{Name: "X-Latency-Critical", Description: "Prefer the fastest backend (true/false)", Schema: openapi.Schema{"type": "boolean"}},
+	{Name: "X-Power-Efficient", Description: "Prefer the lowest-power backend (true/false)", Schema: openapi.Schema{"type": "boolean"}},
+	{Name: "X-Max-Latency-Ms", Description: "Maximum acceptable latency in milliseconds", Schema: openapi.Schema{"type": "integer"}},
+	{Name: "X-Max-Power-Watts", Description: "Maximum power budget in watts", Schema: openapi.Schema{"type": "integer"}},
+	{Name: "X-Cache-Enabled", Description: "Serve and store this request in the response cache (true/false)", Schema: openapi.Schema{"type": "boolean"}},
+	{Name: "X-Media-Type", Description: "Workload hint for routing", Schema: openapi.Schema{"type": "string", "enum": []string{"text", "code", "image", "audio", "realtime", "auto"}}},
+	{Name: "X-Priority", Description: "Queue priority", Schema: openapi.Schema{"type": "string", "enum": []string{"best-effort", "low", "normal", "high", "critical", "realtime"}}},
+	{Name: "Priority", Description: "RFC 9218 priority; urgency u=0 is critical, u=7 best-effort. Ignored when X-Priority is set"},
+	{Name: "X-Request-ID", Description: "Caller-supplied request ID for tracing"},
+	{Name: "X-Deadline-Ms", Description: "Absolute deadline in Unix milliseconds", Schema: openapi.Schema{"type": "integer", "format": "int64"}},
+	{Name: "X-Language", Description: "Prompt language (ISO 639-1), overriding detection"},
+	{Name: "Accept-Language", Description: "Locale hint for prompt language detection"},
+	{Name: "X-Speculative", Description: "Decode speculatively: a small model on the configured draft backend drafts tokens for the routed backend to verify. Ignored unless the routed backend can verify drafts", Schema: openapi.Schema{"type": "boolean"}},
+	{Name: "X-Ensemble", Description: "Send the request to 2-3 backends in parallel and return the majority answer, or the one the judge model scores highest for free-form answers. Streams arrive as a single chunk. Ignored unless ensembles are enabled", Schema: openapi.Schema{"type": "boolean"}},
+	{Name: "X-Ensemble-Candidates", Description: "Return every ensemble member's answer in the response's ensemble field (non-streaming only)", Schema: openapi.Schema{"type": "boolean"}},
+	{Name: "X-Labels", Description: "Comma-separated name=value labels (e.g. team=ml,app=docsbot) for logs, metrics and usage reports; checked against the configured allowlist"},
+}
+
+// RoutingResponseHeaders are the headers written by WriteRoutingHeaders, cold
+// start notices and the response cache
+var RoutingResponseHeaders = []openapi.Header{
+	{Name: "X-Backend-Used", Description: "Backend that served the request"},
+	{Name: "X-Routing-Reason", Description: "Why the backend was selected"},
+	{Name: "X-Hedged-To", Description: "Backend a hedged duplicate of a latency-critical request was sent to; X-Backend-Used names the one that answered"},
+	{Name: "X-Ensemble-Members", Description: "Comma-separated backends an ensemble request was sent to; X-Backend-Used names the one whose answer won"},
+	{Name: "X-Ensemble-Method", Description: "How an ensemble request's answer was chosen", Schema: openapi.Schema{"type": "string", "enum": []string{"vote", "judge", "single"}}},
+	{Name: "X-Speculative-Draft", Description: "Backend that drafted tokens for a speculatively decoded request"},
+	{Name: "X-Speculative-Accepted", Description: "Draft tokens the routed backend accepted, of those drafted (e.g. 42/56); absent on streams, whose headers precede decoding"},
+	{Name: "X-Embedding-Failed", Description: "Inputs of an embedding batch that failed, each reported with an error in place of its embedding", Schema: openapi.Schema{"type": "integer"}},
+	{Name: "X-Estimated-Power-Watts", Description: "Estimated power draw of the selected backend", Schema: openapi.Schema{"type": "number"}},
+	{Name: "X-Energy-Wh", Description: "Energy the request used in watt-hours; a trailer on streams, whose energy is known only once they end", Schema: openapi.Schema{"type": "number"}},
+	{Name: "X-Energy-Source", Description: "measured when RAPL or NVML metered X-Energy-Wh, estimated when the backend derived it from its power rating", Schema: openapi.Schema{"type": "string", "enum": []string{"measured", "estimated"}}},
+	{Name: "X-Estimated-Latency-Ms", Description: "Estimated latency of the selected backend", Schema: openapi.Schema{"type": "integer"}},
+	{Name: "X-Alternatives", Description: "Comma-separated backends that could also have served the request"},
+	{Name: "X-Backend-Temperature-C", Description: "Temperature of the backend's hardware when the request was dispatched, with thermal monitoring enabled", Schema: openapi.Schema{"type": "number"}},
+	{Name: "X-Backend-Throttling", Description: "Whether the backend's hardware was thermally throttling when the request was dispatched", Schema: openapi.Schema{"type": "boolean"}},
+	{Name: "X-Detected-Language", Description: "Prompt language the routing decision considered"},
+	{Name: "X-Session-Backend", Description: "Backend the conversation (session_id or user) is pinned to"},
+	{Name: "X-Cache", Description: "HIT when served from the response cache", Schema: openapi.Schema{"type": "string", "enum": []string{"HIT", "MISS"}}},
+	{Name: "X-Cold-Start", Description: "Set on streams that wait for a model load or container start before the first token", Schema: openapi.Schema{"type": "string", "enum": []string{"model_load", "container_start"}}},
+	{Name: "X-Cold-Start-Estimate-Ms", Description: "Expected cold start wait in milliseconds", Schema: openapi.Schema{"type": "integer"}},
+}
+
+// ErrorTypes is the taxonomy of error types returned in ErrorResponse
+var ErrorTypes = []openapi.ErrorType{
+	{Type: "invalid_request_error", Status: http.StatusBadRequest, Description: "The request body or parameters are invalid"},
+	{Type: "not_found", Status: http.StatusNotFound, Description: "The requested resource does not exist or has expired"},
+	{Type: "model_not_found", Status: http.StatusNotFound, Description: "The routed backend does not serve the requested model"},
+	{Type: "method_not_allowed", Status: http.StatusMethodNotAllowed, Description: "The HTTP method is not supported on this path"},
+	{Type: "invalid_request_error", Status: http.StatusRequestEntityTooLarge, Description: "The uploaded file exceeds the size limit"},
+	{Type: "invalid_request_error", Status: http.StatusRequestedRangeNotSatisfiable, Description: "A video chunk's Content-Range does not fit the upload"},
+	{Type: "internal_error", Status: http.StatusInternalServerError, Description: "The backend failed to serve the request"},
+	{Type: "service_unavailable", Status: http.StatusServiceUnavailable, Description: "No backend can serve the request"},
+	{Type: "insufficient_memory", Status: http.StatusServiceUnavailable, Description: "No backend has the free accelerator memory for the model and its context"},
+	{Type: "server_overloaded", Status: http.StatusServiceUnavailable, Description: "The backend queue is full or the queue wait expired; retry after Retry-After seconds"},
+	{Type: "generation_stopped", Status: http.StatusServiceUnavailable, Description: "An operator stopped in-flight generations with the kill switch"},
+}
+
+// errorModel is the OpenAI error body with its taxonomy
+var errorModel = &openapi.ErrorModel{Body: ErrorResponse{}, Types: ErrorTypes}
+
+// jsonContent is a JSON body of v's type
+func jsonContent(v interface{}) []openapi.Content {
+	return []openapi.Content{{Type: "application/json", Body: v}}
+}
+
+// routed returns a 200 response carrying the routing headers
+func routed(description string, content ...openapi.Content) []openapi.Response {
+	return []openapi.Response{{
+		Status:      http.StatusOK,
+		Description: description,
+		Content:     content,
+		Headers:     RoutingResponseHeaders,
+	}}
+}
+
+// DescribeAPI registers the OpenAI-compatible endpoints with doc
+func DescribeAPI(doc *openapi.Document) {
+	tags := []string{"openai"}
+	sse := func(v interface{}) openapi.Content {
+		return openapi.Content{Type: "text/event-stream", Body: openapi.EventStream{Event: v}}
+	}
+
+	doc.Add(openapi.Operation{
+		Method:  http.MethodPost,
+		Path:    "/v1/chat/completions",
+		ID:      "createChatCompletion",
+		Summary: "Create a chat completion",
+		Tags:    tags,
+		Headers: RoutingRequestHeaders,
+		Request: jsonContent(ChatCompletionRequest{}),
+		Responses: routed("Completion, or an event stream when stream is true",
+			openapi.Content{Type: "application/json", Body: ChatCompletionResponse{}},
+			sse(ChatCompletionChunk{}),
+		),
+		Errors: errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:  http.MethodPost,
+		Path:    "/v1/completions",
+		ID:      "createCompletion",
+		Summary: "Create a text completion (legacy)",
+		Tags:    tags,
+		Headers: RoutingRequestHeaders,
+		Request: jsonContent(CompletionRequest{}),
+		Responses: routed("Completion, or an event stream when stream is true",
+			openapi.Content{Type: "application/json", Body: CompletionResponse{}},
+			sse(CompletionChunk{}),
+		),
+		Errors: errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:    http.MethodPost,
+		Path:      "/v1/embeddings",
+		ID:        "createEmbedding",
+		Summary:   "Create embeddings",
+		Tags:      tags,
+		Headers:   RoutingRequestHeaders,
+		Request:   jsonContent(EmbeddingRequest{}),
+		Responses: routed("Embeddings", jsonContent(EmbeddingResponse{})...),
+		Errors:    errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    "/v1/models",
+		ID:      "listModels",
+		Summary: "List models served by healthy backends",
+		Tags:    tags,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Models", Content: jsonContent(ModelsResponse{})},
+		},
+		Errors: errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:      http.MethodGet,
+		Path:        "/v1/usage",
+		ID:          "getUsage",
+		Summary:     "Usage and quota of the calling API key",
+		Description: "Tokens, requests and estimated energy for the current UTC day and month, with the key's quota limits",
+		Tags:        tags,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Usage", Content: jsonContent(usage.KeyUsage{})},
+		},
+	})
+	doc.Add(openapi.Operation{
+		Method:      http.MethodGet,
+		Path:        "/v1/capabilities",
+		ID:          "getCapabilities",
+		Summary:     "Backend capability matrix",
+		Description: "Which backends support which operations and models, rebuilt from live backend state",
+		Tags:        []string{"proxy"},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Capability matrix", Content: jsonContent(CapabilitiesResponse{})},
+		},
+		Errors: errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:  http.MethodPost,
+		Path:    "/v1/audio/transcriptions",
+		ID:      "createTranscription",
+		Summary: "Transcribe audio",
+		Tags:    tags,
+		Headers: RoutingRequestHeaders,
+		Request: []openapi.Content{{Type: "multipart/form-data", Body: openapi.Schema{
+			"type":     "object",
+			"required": []string{"file"},
+			"properties": map[string]interface{}{
+				"file":            openapi.Schema{"type": "string", "contentMediaType": "application/octet-stream"},
+				"model":           openapi.Schema{"type": "string"},
+				"language":        openapi.Schema{"type": "string"},
+				"prompt":          openapi.Schema{"type": "string"},
+				"response_format": openapi.Schema{"type": "string", "enum": []string{"json", "text", "srt", "vtt", "verbose_json"}},
+			},
+		}}},
+		Responses: routed("Transcription in the requested format",
+			openapi.Content{Type: "application/json", Body: openapi.OneOf{TranscriptionResponse{}, VerboseTranscriptionResponse{}}},
+			openapi.Content{Type: "text/plain", Body: openapi.Schema{"type": "string"}},
+		),
+		Errors: errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:  http.MethodPost,
+		Path:    "/v1/audio/speech",
+		ID:      "createSpeech",
+		Summary: "Synthesize speech",
+		Tags:    tags,
+		Headers: RoutingRequestHeaders,
+		Request: jsonContent(SpeechRequest{}),
+		Responses: routed("Audio in the requested format",
+			openapi.Content{Type: "application/octet-stream", Body: openapi.Schema{"type": "string", "contentMediaType": "audio/*"}},
+		),
+		Errors: errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:    http.MethodPost,
+		Path:      "/v1/images/generations",
+		ID:        "createImage",
+		Summary:   "Generate images",
+		Tags:      tags,
+		Headers:   RoutingRequestHeaders,
+		Request:   jsonContent(ImageGenerationRequest{}),
+		Responses: routed("Generated images", jsonContent(ImageResponse{})...),
+		Errors:    errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:  http.MethodPost,
+		Path:    "/v1/images/edits",
+		ID:      "createImageEdit",
+		Summary: "Edit an image",
+		Tags:    tags,
+		Headers: RoutingRequestHeaders,
+		Request: []openapi.Content{{Type: "multipart/form-data", Body: openapi.AllOf{
+			ImageGenerationRequest{},
+			openapi.Schema{
+				"type":     "object",
+				"required": []string{"image"},
+				"properties": map[string]interface{}{
+					"image":    openapi.Schema{"type": "string", "contentMediaType": "image/*"},
+					"mask":     openapi.Schema{"type": "string", "contentMediaType": "image/png"},
+					"strength": openapi.Schema{"type": "number", "exclusiveMinimum": 0, "maximum": 1},
+				},
+			},
+		}}},
+		Responses: routed("Edited images", jsonContent(ImageResponse{})...),
+		Errors:    errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:  http.MethodGet,
+		Path:    ImageFilesPath + "{id}",
+		ID:      "getImageFile",
+		Summary: "Download an image returned with response_format=url",
+		Tags:    []string{"proxy"},
+		Parameters: []openapi.Parameter{
+			{Name: "id", In: "path", Description: "Image file name from the generation response"},
+		},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Image bytes", Content: []openapi.Content{
+				{Type: "image/*", Body: openapi.Schema{"type": "string", "contentMediaType": "image/*"}},
+			}},
+		},
+		Errors: errorModel,
+	})
+
+	uploadID := []openapi.Parameter{{Name: "id", In: "path", Description: "Upload ID"}}
+	analysisID := []openapi.Parameter{{Name: "id", In: "path", Description: "Analysis ID"}}
+	videoTags := []string{"video"}
+	doc.Add(openapi.Operation{
+		Method:      http.MethodPost,
+		Path:        VideoUploadsPath,
+		ID:          "createVideoUpload",
+		Summary:     "Open a resumable video upload",
+		Description: "The video is then sent in chunks with PUT, so multi-gigabyte files need no single request body.",
+		Tags:        videoTags,
+		Request:     jsonContent(VideoUploadRequest{}),
+		Responses:   []openapi.Response{{Status: http.StatusCreated, Description: "Upload session", Content: jsonContent(VideoUpload{})}},
+		Errors:      errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:      http.MethodPut,
+		Path:        VideoUploadsPath + "/{id}",
+		ID:          "uploadVideoChunk",
+		Summary:     "Upload a chunk of a video",
+		Description: "Chunks are sent in order, each starting at the upload's received count. A chunk starting elsewhere gets 409 with the upload state to resume from.",
+		Tags:        videoTags,
+		Parameters:  uploadID,
+		Headers:     []openapi.Header{{Name: "Content-Range", Description: "bytes START-END/TOTAL"}},
+		Request:     []openapi.Content{{Type: "application/octet-stream", Body: openapi.Schema{"type": "string", "contentMediaType": "video/*"}}},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Upload state after the chunk", Content: jsonContent(VideoUpload{})},
+			{Status: http.StatusConflict, Description: "Chunk out of order; resume from received", Content: jsonContent(VideoUpload{})},
+		},
+		Errors: errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:     http.MethodGet,
+		Path:       VideoUploadsPath + "/{id}",
+		ID:         "getVideoUpload",
+		Summary:    "Get the state of a video upload",
+		Tags:       videoTags,
+		Parameters: uploadID,
+		Responses:  []openapi.Response{{Status: http.StatusOK, Description: "Upload state", Content: jsonContent(VideoUpload{})}},
+		Errors:     errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:      http.MethodPost,
+		Path:        VideoAnalysesPath,
+		ID:          "createVideoAnalysis",
+		Summary:     "Analyze an uploaded video in the background",
+		Description: "Routed to a backend that supports video analysis. Follow progress at the events endpoint.",
+		Tags:        videoTags,
+		Headers:     RoutingRequestHeaders,
+		Request:     jsonContent(VideoAnalysisRequest{}),
+		Responses:   []openapi.Response{{Status: http.StatusAccepted, Description: "Queued analysis", Content: jsonContent(VideoAnalysis{})}},
+		Errors:      errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:     http.MethodGet,
+		Path:       VideoAnalysesPath + "/{id}",
+		ID:         "getVideoAnalysis",
+		Summary:    "Get the state of a video analysis",
+		Tags:       videoTags,
+		Parameters: analysisID,
+		Responses:  []openapi.Response{{Status: http.StatusOK, Description: "Analysis state", Content: jsonContent(VideoAnalysis{})}},
+		Errors:     errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:     http.MethodGet,
+		Path:       VideoAnalysesPath + "/{id}/events",
+		ID:         "streamVideoAnalysisEvents",
+		Summary:    "Stream the progress of a video analysis",
+		Tags:       videoTags,
+		Parameters: analysisID,
+		Responses:  []openapi.Response{{Status: http.StatusOK, Description: "video_analysis events until it finishes", Content: []openapi.Content{sse(VideoAnalysis{})}}},
+		Errors:     errorModel,
+	})
+	doc.Add(openapi.Operation{
+		Method:     http.MethodGet,
+		Path:       VideoAnalysesPath + "/{id}/result",
+		ID:         "getVideoAnalysisResult",
+		Summary:    "Download the result of a video analysis",
+		Tags:       videoTags,
+		Parameters: analysisID,
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "Analysis result", Content: jsonContent(VideoAnalysisResult{})},
+			{Status: http.StatusConflict, Description: "Analysis not succeeded", Content: jsonContent(VideoAnalysis{})},
+		},
+		Errors: errorModel,
+	})
+}
@@ -0,0 +1,127 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/openapi"
+)
+
+// handlerSources returns the package's non-test Go sources
+func handlerSources(t *testing.T) map[string]string {
+	t.Helper()
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("Failed to list sources: %v", err)
+	}
+	sources := make(map[string]string)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		sources[file] = string(data)
+	}
+	return sources
+}
+
+// TestOpenAPI_HeadersInSync fails when a handler reads or writes an X-
+// header that the OpenAPI document does not describe
+func TestOpenAPI_HeadersInSync(t *testing.T) {
+	documented := make(map[string]bool)
+	for _, h := range append(RoutingRequestHeaders, RoutingResponseHeaders...) {
+		documented[h.Name] = true
+	}
+	// Transport headers, not part of the API contract
+	undocumented := map[string]bool{"X-Forwarded-Proto": true, "X-Accel-Buffering": true}
+
+	headerRe := regexp.MustCompile(`Header(?:\(\))?\.(?:Get|Set|Add)\("(X-[A-Za-z-]+)"`)
+	for file, src := range handlerSources(t) {
+		for _, m := range headerRe.FindAllStringSubmatch(src, -1) {
+			if !documented[m[1]] && !undocumented[m[1]] {
+				t.Errorf("%s uses header %s, which is missing from the OpenAPI headers", file, m[1])
+			}
+		}
+	}
+}
+
+// TestOpenAPI_ErrorTypesInSync fails when a handler returns an error type
+// and status pair that is missing from ErrorTypes
+func TestOpenAPI_ErrorTypesInSync(t *testing.T) {
+	statuses := map[string]int{
+		"StatusBadRequest":                   http.StatusBadRequest,
+		"StatusNotFound":                     http.StatusNotFound,
+		"StatusMethodNotAllowed":             http.StatusMethodNotAllowed,
+		"StatusRequestEntityTooLarge":        http.StatusRequestEntityTooLarge,
+		"StatusRequestedRangeNotSatisfiable": http.StatusRequestedRangeNotSatisfiable,
+		"StatusInternalServerError":          http.StatusInternalServerError,
+		"StatusServiceUnavailable":           http.StatusServiceUnavailable,
+	}
+	documented := make(map[openapi.ErrorType]bool)
+	for _, et := range ErrorTypes {
+		documented[openapi.ErrorType{Type: et.Type, Status: et.Status}] = true
+	}
+
+	callRe := regexp.MustCompile(`(?s)^\s*http\.(\w+),.*,\s*"(\w+)"\)$`)
+	for file, src := range handlerSources(t) {
+		for _, call := range strings.Split(src, "writeError(w,")[1:] {
+			call = call[:strings.Index(call, ")\n")+1]
+			m := callRe.FindStringSubmatch(call)
+			if m == nil {
+				continue
+			}
+			status, ok := statuses[m[1]]
+			if !ok {
+				t.Errorf("%s: add http.%s to this test's status table", file, m[1])
+				continue
+			}
+			if !documented[openapi.ErrorType{Type: m[2], Status: status}] {
+				t.Errorf("%s returns %s with status %d, which is missing from ErrorTypes", file, m[2], status)
+			}
+		}
+	}
+}
+
+func TestDescribeAPI(t *testing.T) {
+	doc := openapi.NewDocument("test", "1", "")
+	DescribeAPI(doc)
+
+	raw, err := json.Marshal(doc.Build())
+	if err != nil {
+		t.Fatalf("Failed to marshal document: %v", err)
+	}
+	var parsed struct {
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	for path, method := range map[string]string{
+		"/v1/chat/completions":     "post",
+		"/v1/capabilities":         "get",
+		"/v1/audio/transcriptions": "post",
+		"/v1/images/files/{id}":    "get",
+	} {
+		if _, ok := parsed.Paths[path][method]; !ok {
+			t.Errorf("Expected %s %s in document", method, path)
+		}
+	}
+	for _, name := range []string{"ChatCompletionRequest", "ChatCompletionChunk", "ErrorResponse", "VerboseTranscriptionResponse"} {
+		if _, ok := parsed.Components.Schemas[name]; !ok {
+			t.Errorf("Expected component schema %s", name)
+		}
+	}
+}
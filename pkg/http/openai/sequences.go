@@ -0,0 +1,127 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/confidence"
+	"github.com/daoneill/ollama-proxy/pkg/http/postprocess"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// MaxSequences caps n and best_of on one request
+const MaxSequences = 16
+
+// sequenceCounts validates a request's n and best_of, returning the
+// number of completions to return and of candidates to generate
+func sequenceCounts(nParam, bestOfParam *int, stream bool) (int, int, error) {
+	n, bestOf := 1, 1
+	if nParam != nil {
+		n = *nParam
+	}
+	if bestOfParam != nil {
+		bestOf = *bestOfParam
+	}
+	if n < 1 || n > MaxSequences {
+		return 0, 0, fmt.Errorf("n must be between 1 and %d", MaxSequences)
+	}
+	if bestOfParam == nil {
+		bestOf = n
+	}
+	if bestOf < n || bestOf > MaxSequences {
+		return 0, 0, fmt.Errorf("best_of must be between n and %d", MaxSequences)
+	}
+	if stream && bestOf > 1 {
+		return 0, 0, fmt.Errorf("n and best_of greater than 1 are not supported when streaming")
+	}
+	return n, bestOf, nil
+}
+
+// rankEstimator scores best_of candidates when the backend can't rank them
+var rankEstimator = confidence.NewConfidenceEstimator(confidence.DefaultConfig())
+
+// generateSequences generates n completions, the best of bestOf, on the
+// selected backend: natively when it supports parallel sequences and
+// otherwise as separate requests ranked by estimated confidence
+func generateSequences(ctx context.Context, decision *router.RoutingDecision, req *backends.GenerateRequest, prompt string, n, bestOf int) ([]*backends.GenerateResponse, error) {
+	return backends.GenerateSequences(ctx, decision.Backend, req, n, bestOf, func(resp *backends.GenerateResponse) float64 {
+		return rankEstimator.Estimate(prompt, resp.Response, req.Model, decision.Backend).Overall
+	})
+}
+
+// sequenceTokens returns the completion tokens of resp, estimated when the
+// backend didn't report them
+func sequenceTokens(resp *backends.GenerateResponse) int32 {
+	if resp.Stats != nil && resp.Stats.TokensGenerated > 0 {
+		return resp.Stats.TokensGenerated
+	}
+	return estimateTokens(resp.Response)
+}
+
+func handleChatCompletionSequences(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, chatReq *ChatCompletionRequest, n int) {
+	prompt := buildPromptFromMessages(chatReq.Messages)
+	tracker := newUsageTracker(ctx, decision, "/v1/chat/completions", chatReq.Model, prompt)
+
+	resps, err := generateSequences(ctx, decision, internalReq, prompt, n, n)
+	if err != nil {
+		tracker.Finish(0, nil, err)
+		writeGenerationError(w, fmt.Sprintf("Generation failed: %v", err), err)
+		return
+	}
+
+	openaiResp := ConvertToOpenAIChatResponse(chatReq, postprocess.Default.Response(ctx, resps[0]))
+	for i, resp := range resps[1:] {
+		resp = postprocess.Default.Response(ctx, resp)
+		openaiResp.Choices = append(openaiResp.Choices, ChatCompletionChoice{
+			Index: i + 1,
+			Message: ChatCompletionMessage{
+				Role:    "assistant",
+				Content: resp.Response,
+			},
+			FinishReason: "stop",
+		})
+		openaiResp.Usage.CompletionTokens += sequenceTokens(resp)
+	}
+	openaiResp.Usage.TotalTokens = openaiResp.Usage.PromptTokens + openaiResp.Usage.CompletionTokens
+	tracker.Finish(openaiResp.Usage.CompletionTokens, resps[0].Stats, nil)
+
+	WriteRoutingHeaders(w, decision)
+	WriteEnergyHeaders(w, resps[0].Stats)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(openaiResp)
+}
+
+func handleCompletionSequences(w http.ResponseWriter, ctx context.Context, decision *router.RoutingDecision, internalReq *backends.GenerateRequest, compReq *CompletionRequest, n, bestOf int) {
+	prompt := extractPrompt(compReq.Prompt)
+	tracker := newUsageTracker(ctx, decision, "/v1/completions", compReq.Model, prompt)
+
+	resps, err := generateSequences(ctx, decision, internalReq, prompt, n, bestOf)
+	if err != nil {
+		tracker.Finish(0, nil, err)
+		writeGenerationError(w, fmt.Sprintf("Generation failed: %v", err), err)
+		return
+	}
+
+	openaiResp := ConvertToOpenAICompletionResponse(compReq, postprocess.Default.Response(ctx, resps[0]))
+	for i, resp := range resps[1:] {
+		resp = postprocess.Default.Response(ctx, resp)
+		openaiResp.Choices = append(openaiResp.Choices, CompletionChoice{
+			Text:         resp.Response,
+			Index:        i + 1,
+			FinishReason: "stop",
+		})
+		openaiResp.Usage.CompletionTokens += sequenceTokens(resp)
+	}
+	openaiResp.Usage.TotalTokens = openaiResp.Usage.PromptTokens + openaiResp.Usage.CompletionTokens
+	tracker.Finish(openaiResp.Usage.CompletionTokens, resps[0].Stats, nil)
+
+	WriteRoutingHeaders(w, decision)
+	WriteEnergyHeaders(w, resps[0].Stats)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(openaiResp)
+}
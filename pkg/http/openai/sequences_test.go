@@ -0,0 +1,101 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestSequenceCounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		n, bestOf  *int
+		stream     bool
+		wantN      int
+		wantBestOf int
+		wantErr    bool
+	}{
+		{name: "defaults", wantN: 1, wantBestOf: 1},
+		{name: "n only", n: intPtr(3), wantN: 3, wantBestOf: 3},
+		{name: "best of", n: intPtr(2), bestOf: intPtr(5), wantN: 2, wantBestOf: 5},
+		{name: "zero n", n: intPtr(0), wantErr: true},
+		{name: "too many", n: intPtr(MaxSequences + 1), wantErr: true},
+		{name: "best of below n", n: intPtr(3), bestOf: intPtr(2), wantErr: true},
+		{name: "streaming several", n: intPtr(2), stream: true, wantErr: true},
+		{name: "streaming one", n: intPtr(1), stream: true, wantN: 1, wantBestOf: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, bestOf, err := sequenceCounts(tt.n, tt.bestOf, tt.stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sequenceCounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (n != tt.wantN || bestOf != tt.wantBestOf) {
+				t.Errorf("sequenceCounts() = %d, %d, want %d, %d", n, bestOf, tt.wantN, tt.wantBestOf)
+			}
+		})
+	}
+}
+
+func TestHandleChatCompletion_N(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "test-backend", supportsModel: true, generateResp: &backends.GenerateResponse{
+		Response: "Hello",
+		Stats:    &backends.GenerationStats{TokensGenerated: 4},
+	}})
+
+	body, _ := json.Marshal(ChatCompletionRequest{
+		Model:    "test-model",
+		Messages: []ChatCompletionMessage{{Role: "user", Content: "Hi"}},
+		N:        intPtr(3),
+	})
+	w := httptest.NewRecorder()
+	HandleChatCompletion(r)(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body)))
+
+	var resp ChatCompletionResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || len(resp.Choices) != 3 {
+		t.Fatalf("Expected three choices, got %d (status %d)", len(resp.Choices), w.Code)
+	}
+	for i, choice := range resp.Choices {
+		if choice.Index != i || choice.Message.Content != "Hello" {
+			t.Errorf("Unexpected choice %d: %+v", i, choice)
+		}
+	}
+	if resp.Usage.CompletionTokens != 12 {
+		t.Errorf("Expected usage summed over choices (12), got %d", resp.Usage.CompletionTokens)
+	}
+}
+
+func TestHandleCompletion_BestOf(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "test-backend", supportsModel: true, generateResp: &backends.GenerateResponse{
+		Response: "Paris is the capital of France.",
+	}})
+
+	body, _ := json.Marshal(CompletionRequest{Model: "test-model", Prompt: "The capital of France is", BestOf: intPtr(3)})
+	w := httptest.NewRecorder()
+	HandleCompletion(r)(w, httptest.NewRequest(http.MethodPost, "/v1/completions", bytes.NewBuffer(body)))
+
+	var resp CompletionResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || len(resp.Choices) != 1 {
+		t.Fatalf("Expected the single best choice, got %d (status %d)", len(resp.Choices), w.Code)
+	}
+
+	// Streaming several sequences is rejected
+	body, _ = json.Marshal(CompletionRequest{Model: "test-model", Prompt: "Hi", N: intPtr(2), Stream: true})
+	w = httptest.NewRecorder()
+	HandleCompletion(r)(w, httptest.NewRequest(http.MethodPost, "/v1/completions", bytes.NewBuffer(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a streamed n > 1, got %d", w.Code)
+	}
+}
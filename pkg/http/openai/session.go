@@ -0,0 +1,73 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/session"
+)
+
+// sessionAffinity is a request's view of its conversation session. The
+// zero value (no session ID or affinity disabled) never pins.
+type sessionAffinity struct {
+	key      session.Key
+	pinnedTo string // backend serving earlier turns, "" for a new session
+	override bool   // X-Target-Backend chose a different backend
+}
+
+// lookupSession routes a chat turn to the backend that served the
+// conversation's earlier turns, boosting its priority while the
+// conversation is interactive. An explicit X-Target-Backend wins and
+// re-pins the session to that backend.
+func lookupSession(ctx context.Context, annotations *backends.Annotations, chatReq *ChatCompletionRequest) sessionAffinity {
+	id := chatReq.SessionID
+	if id == "" {
+		id = chatReq.User
+	}
+	if id == "" || session.Default == nil {
+		return sessionAffinity{}
+	}
+
+	sa := sessionAffinity{key: session.KeyFor(ctx, id)}
+	backendID, ok := session.Default.Lookup(sa.key)
+	if !ok {
+		return sa
+	}
+	sa.pinnedTo = backendID
+
+	// Active interactive conversations go ahead of normal-priority work.
+	// Explicit best-effort turns are left alone, and critical stays
+	// reserved for realtime traffic.
+	if annotations.Priority == backends.PriorityNormal && session.Default.Boosted(sa.key) {
+		annotations.Priority = backends.PriorityHigh
+	}
+
+	if annotations.Target != "" && annotations.Target != "auto" {
+		sa.override = annotations.Target != backendID
+		return sa
+	}
+	// The router falls back to normal selection when the pinned backend is
+	// unhealthy or no longer registered
+	annotations.Target = backendID
+	return sa
+}
+
+// pin records the backend chosen for this turn
+func (sa sessionAffinity) pin(w http.ResponseWriter, decision *router.RoutingDecision) {
+	if sa.key.ID == "" || decision == nil || decision.Backend == nil {
+		return
+	}
+
+	backendID := decision.Backend.ID()
+	result := ""
+	switch {
+	case sa.override:
+		result = session.ResultOverride
+	case sa.pinnedTo != "" && sa.pinnedTo != backendID:
+		result = session.ResultRepinned
+	}
+	session.Default.Pin(sa.key, backendID, result)
+	w.Header().Set("X-Session-Backend", backendID)
+}
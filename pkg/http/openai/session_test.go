@@ -0,0 +1,139 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/session"
+)
+
+func withSessions(t *testing.T) *session.Manager {
+	t.Helper()
+	m := session.New(session.Config{})
+	session.SetDefault(m)
+	t.Cleanup(func() { session.SetDefault(nil) })
+	return m
+}
+
+func TestHandleChatCompletion_SessionAffinity(t *testing.T) {
+	sessions := withSessions(t)
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "backend-a", supportsModel: true})
+	r.RegisterBackend(&mockBackend{id: "backend-b", supportsModel: true})
+	handler := HandleChatCompletion(r)
+
+	send := func(chatReq ChatCompletionRequest, target string) *httptest.ResponseRecorder {
+		chatReq.Model = "test-model"
+		chatReq.Messages = []ChatCompletionMessage{{Role: "user", Content: "Hello"}}
+		body, _ := json.Marshal(chatReq)
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body))
+		if target != "" {
+			req.Header.Set("X-Target-Backend", target)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	// First turn pins the session to whichever backend served it
+	w := send(ChatCompletionRequest{SessionID: "conv-1"}, "")
+	first := w.Header().Get("X-Session-Backend")
+	if first == "" || first != w.Header().Get("X-Backend-Used") {
+		t.Fatalf("Expected session pinned to the serving backend, got %q", first)
+	}
+
+	// Later turns stay on it
+	for i := 0; i < 5; i++ {
+		w = send(ChatCompletionRequest{SessionID: "conv-1"}, "")
+		if got := w.Header().Get("X-Backend-Used"); got != first {
+			t.Fatalf("Turn %d routed to %s, expected pinned %s", i+2, got, first)
+		}
+	}
+
+	// X-Target-Backend overrides and re-pins
+	other := "backend-a"
+	if first == other {
+		other = "backend-b"
+	}
+	w = send(ChatCompletionRequest{SessionID: "conv-1"}, other)
+	if w.Header().Get("X-Backend-Used") != other || w.Header().Get("X-Session-Backend") != other {
+		t.Fatalf("Expected override to %s, got %s", other, w.Header().Get("X-Backend-Used"))
+	}
+	w = send(ChatCompletionRequest{SessionID: "conv-1"}, "")
+	if w.Header().Get("X-Backend-Used") != other {
+		t.Errorf("Expected session re-pinned to %s, got %s", other, w.Header().Get("X-Backend-Used"))
+	}
+
+	if got := sessions.Stats().Lookups[session.ResultOverride]; got != 1 {
+		t.Errorf("Expected 1 override, got %d", got)
+	}
+}
+
+func TestHandleChatCompletion_SessionFromUser(t *testing.T) {
+	sessions := withSessions(t)
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "test-backend", supportsModel: true})
+
+	body, _ := json.Marshal(ChatCompletionRequest{
+		Model:    "test-model",
+		User:     "alice",
+		Messages: []ChatCompletionMessage{{Role: "user", Content: "Hello"}},
+	})
+	w := httptest.NewRecorder()
+	HandleChatCompletion(r)(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body)))
+
+	if _, ok := sessions.Lookup(session.Key{ID: "alice"}); !ok {
+		t.Error("Expected the user field to key the session")
+	}
+}
+
+func TestHandleChatCompletion_NoSessionID(t *testing.T) {
+	sessions := withSessions(t)
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "test-backend", supportsModel: true})
+
+	body, _ := json.Marshal(ChatCompletionRequest{
+		Model:    "test-model",
+		Messages: []ChatCompletionMessage{{Role: "user", Content: "Hello"}},
+	})
+	w := httptest.NewRecorder()
+	HandleChatCompletion(r)(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBuffer(body)))
+
+	if w.Header().Get("X-Session-Backend") != "" || sessions.Stats().Active != 0 {
+		t.Error("Requests without session_id or user must not be pinned")
+	}
+}
+
+func TestLookupSession_PriorityBoost(t *testing.T) {
+	m := session.New(session.Config{Boost: session.BoostConfig{Enabled: true, Threshold: 0.5}})
+	session.SetDefault(m)
+	t.Cleanup(func() { session.SetDefault(nil) })
+
+	ctx := context.Background()
+	chatReq := &ChatCompletionRequest{SessionID: "chat"}
+	key := session.KeyFor(ctx, "chat")
+	m.Pin(key, "backend-a", "")
+	m.Pin(key, "backend-a", "")
+
+	annotations := &backends.Annotations{Priority: backends.PriorityNormal}
+	lookupSession(ctx, annotations, chatReq)
+	if annotations.Priority != backends.PriorityHigh {
+		t.Errorf("Expected interactive turn boosted to high, got %d", annotations.Priority)
+	}
+
+	// Best-effort work stays where the client put it
+	annotations = &backends.Annotations{Priority: backends.PriorityBestEffort}
+	lookupSession(ctx, annotations, chatReq)
+	if annotations.Priority != backends.PriorityBestEffort {
+		t.Errorf("Expected best-effort turn left alone, got %d", annotations.Priority)
+	}
+}
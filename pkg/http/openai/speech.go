@@ -0,0 +1,281 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"go.uber.org/zap"
+)
+
+// MaxSpeechInputChars matches OpenAI's limit on /v1/audio/speech input
+const MaxSpeechInputChars = 4096
+
+// Speech response formats
+const (
+	SpeechFormatMP3  = "mp3"
+	SpeechFormatWAV  = "wav"
+	SpeechFormatOpus = "opus"
+	SpeechFormatOGG  = "ogg"
+	SpeechFormatFLAC = "flac"
+	SpeechFormatPCM  = "pcm"
+)
+
+// HandleSpeech handles /v1/audio/speech (text-to-speech)
+func HandleSpeech(r *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Only accept POST
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+			return
+		}
+
+		// Parse request
+		var speechReq SpeechRequest
+		if err := json.NewDecoder(req.Body).Decode(&speechReq); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+			return
+		}
+		if speechReq.ResponseFormat == "" {
+			speechReq.ResponseFormat = SpeechFormatMP3
+		}
+
+		// Validate required fields
+		if speechReq.Model == "" {
+			writeError(w, http.StatusBadRequest, "Model is required", "invalid_request_error")
+			return
+		}
+		if strings.TrimSpace(speechReq.Input) == "" {
+			writeError(w, http.StatusBadRequest, "Input is required", "invalid_request_error")
+			return
+		}
+		if len([]rune(speechReq.Input)) > MaxSpeechInputChars {
+			writeError(w, http.StatusBadRequest,
+				fmt.Sprintf("Input exceeds %d characters", MaxSpeechInputChars), "invalid_request_error")
+			return
+		}
+		if speechReq.Speed != 0 && (speechReq.Speed < 0.25 || speechReq.Speed > 4.0) {
+			writeError(w, http.StatusBadRequest, "Speed must be between 0.25 and 4.0", "invalid_request_error")
+			return
+		}
+		format, ok := speechFormat(speechReq.ResponseFormat)
+		if !ok {
+			writeError(w, http.StatusBadRequest,
+				fmt.Sprintf("Unsupported response_format %q (mp3, wav, opus, ogg, flac, pcm)", speechReq.ResponseFormat),
+				"invalid_request_error")
+			return
+		}
+
+		// Parse routing headers and require a text-to-speech backend
+		annotations := ParseRoutingHeaders(req)
+		if annotations.MediaType == "" {
+			annotations.MediaType = backends.MediaTypeAudio
+		}
+		annotations.Capability = backends.CapabilityTextToAudio
+
+		// Route request
+		decision, err := r.RouteRequest(req.Context(), annotations)
+		if err != nil {
+			writeRoutingError(w, err)
+			return
+		}
+
+		// An explicit target bypasses capability filtering
+		if !decision.Backend.SupportsTextToAudio() {
+			writeError(w, http.StatusBadRequest, "Backend does not support speech synthesis", "invalid_request_error")
+			return
+		}
+
+		// Check if backend supports the model
+		if !decision.Backend.SupportsModel(speechReq.Model) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Model %s not available", speechReq.Model), "model_not_found")
+			return
+		}
+
+		tracker := newUsageTracker(req.Context(), decision, "/v1/audio/speech", speechReq.Model, speechReq.Input)
+		synthReq := ConvertSpeechRequest(&speechReq, format)
+
+		// Prefer streaming so playback can start before synthesis finishes
+		stream, err := decision.Backend.SynthesizeSpeechStream(req.Context(), synthReq)
+		if err == nil {
+			defer stream.Close()
+			WriteRoutingHeaders(w, decision)
+			streamErr := streamSpeech(w, stream, format)
+			tracker.Finish(0, nil, streamErr)
+			if streamErr != nil && logging.Logger != nil {
+				logging.Logger.Warn("Speech stream ended with error",
+					zap.String("backend", decision.Backend.ID()),
+					zap.Error(streamErr),
+				)
+			}
+			return
+		}
+
+		if logging.Logger != nil {
+			logging.Logger.Debug("Speech streaming unavailable, synthesizing whole clip",
+				zap.String("backend", decision.Backend.ID()),
+				zap.Error(err),
+			)
+		}
+
+		// Execute request
+		resp, err := decision.Backend.SynthesizeSpeech(req.Context(), synthReq)
+		if err != nil {
+			tracker.Finish(0, nil, err)
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Speech synthesis failed: %v", err), "internal_error")
+			return
+		}
+		tracker.Finish(0, resp.Stats, nil)
+
+		// Write routing headers
+		WriteRoutingHeaders(w, decision)
+
+		audio, audioFormat := resp.AudioData, resp.Format
+		if audioFormat == "" {
+			audioFormat = format
+		}
+		// Raw PCM can be wrapped losslessly when the client asked for WAV
+		if audioFormat == backends.AudioFormatPCM && format == backends.AudioFormatWAV {
+			audio, audioFormat = wrapPCMAsWAV(audio, resp.SampleRate), backends.AudioFormatWAV
+		}
+
+		w.Header().Set("Content-Type", speechContentType(audioFormat))
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(audio)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(audio)
+	}
+}
+
+// ConvertSpeechRequest converts an OpenAI speech request to the internal format
+func ConvertSpeechRequest(req *SpeechRequest, format backends.AudioFormat) *backends.SynthesizeRequest {
+	speed := req.Speed
+	if speed == 0 {
+		speed = 1.0
+	}
+	return &backends.SynthesizeRequest{
+		Text:   req.Input,
+		Model:  req.Model,
+		Voice:  req.Voice,
+		Format: format,
+		Speed:  float32(speed),
+	}
+}
+
+// streamSpeech copies audio chunks to the client as they arrive. The
+// content type comes from the first chunk, since backends may not honour
+// the requested format.
+func streamSpeech(w http.ResponseWriter, stream backends.AudioStreamWriter, requested backends.AudioFormat) error {
+	flusher, _ := w.(http.Flusher)
+	started := false
+
+	start := func(format backends.AudioFormat) {
+		if format == "" {
+			format = requested
+		}
+		w.Header().Set("Content-Type", speechContentType(format))
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if !started {
+				writeError(w, http.StatusInternalServerError, fmt.Sprintf("Speech synthesis failed: %v", err), "internal_error")
+			}
+			return err
+		}
+
+		if !started {
+			start(chunk.Format)
+		}
+		if len(chunk.Data) > 0 {
+			if _, err := w.Write(chunk.Data); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if chunk.Done {
+			break
+		}
+	}
+
+	if !started {
+		start(requested)
+	}
+	return nil
+}
+
+// speechFormat maps an OpenAI response_format to an internal audio format
+func speechFormat(format string) (backends.AudioFormat, bool) {
+	switch strings.ToLower(format) {
+	case SpeechFormatMP3:
+		return backends.AudioFormatMP3, true
+	case SpeechFormatWAV:
+		return backends.AudioFormatWAV, true
+	case SpeechFormatOpus, SpeechFormatOGG:
+		return backends.AudioFormatOPUS, true
+	case SpeechFormatFLAC:
+		return backends.AudioFormatFLAC, true
+	case SpeechFormatPCM:
+		return backends.AudioFormatPCM, true
+	}
+	return "", false
+}
+
+// speechContentType returns the MIME type for an audio format. Opus is
+// served in an Ogg container, as OpenAI does.
+func speechContentType(format backends.AudioFormat) string {
+	switch format {
+	case backends.AudioFormatMP3:
+		return "audio/mpeg"
+	case backends.AudioFormatWAV:
+		return "audio/wav"
+	case backends.AudioFormatOPUS:
+		return "audio/ogg"
+	case backends.AudioFormatFLAC:
+		return "audio/flac"
+	case backends.AudioFormatPCM:
+		return "audio/pcm"
+	}
+	return "application/octet-stream"
+}
+
+// wrapPCMAsWAV prepends a RIFF header to 16-bit mono PCM
+func wrapPCMAsWAV(pcm []byte, sampleRate int32) []byte {
+	if sampleRate <= 0 {
+		sampleRate = 22050
+	}
+	const channels, bitsPerSample = 1, 16
+	byteRate := uint32(sampleRate) * channels * bitsPerSample / 8
+
+	var b bytes.Buffer
+	b.Grow(44 + len(pcm))
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+len(pcm)))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&b, binary.LittleEndian, uint16(channels))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&b, binary.LittleEndian, byteRate)
+	binary.Write(&b, binary.LittleEndian, uint16(channels*bitsPerSample/8))
+	binary.Write(&b, binary.LittleEndian, uint16(bitsPerSample))
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(pcm)))
+	b.Write(pcm)
+	return b.Bytes()
+}
@@ -0,0 +1,186 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// speechBackend is a mockBackend with text-to-speech support
+type speechBackend struct {
+	mockBackend
+	resp    *backends.SynthesizeResponse
+	chunks  []*backends.AudioChunk // non-nil enables streaming
+	lastReq *backends.SynthesizeRequest
+}
+
+func (m *speechBackend) SupportsTextToAudio() bool { return true }
+
+func (m *speechBackend) SynthesizeSpeech(ctx context.Context, req *backends.SynthesizeRequest) (*backends.SynthesizeResponse, error) {
+	m.lastReq = req
+	return m.resp, nil
+}
+
+func (m *speechBackend) SynthesizeSpeechStream(ctx context.Context, req *backends.SynthesizeRequest) (backends.AudioStreamWriter, error) {
+	m.lastReq = req
+	if m.chunks == nil {
+		return nil, fmt.Errorf("not implemented")
+	}
+	return &chunkStream{chunks: m.chunks}, nil
+}
+
+type chunkStream struct {
+	chunks []*backends.AudioChunk
+}
+
+func (s *chunkStream) Recv() (*backends.AudioChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	c := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return c, nil
+}
+
+func (s *chunkStream) Close() error { return nil }
+
+func newSpeechRouter(backend *speechBackend) *router.Router {
+	r := router.NewRouter(router.Config{})
+	// The text-only backend must be skipped by capability filtering
+	r.RegisterBackend(&mockBackend{id: "text-backend", supportsModel: true})
+	backend.mockBackend = mockBackend{id: "tts-backend", supportsModel: true}
+	r.RegisterBackend(backend)
+	return r
+}
+
+func newSpeechRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestHandleSpeech_MP3(t *testing.T) {
+	backend := &speechBackend{resp: &backends.SynthesizeResponse{AudioData: []byte("ID3mp3data"), Format: backends.AudioFormatMP3}}
+	r := newSpeechRouter(backend)
+	w := httptest.NewRecorder()
+
+	HandleSpeech(r)(w, newSpeechRequest(`{"model":"piper","input":"hello","voice":"alloy"}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "audio/mpeg" {
+		t.Errorf("Content-Type = %q, want audio/mpeg", ct)
+	}
+	if w.Body.String() != "ID3mp3data" {
+		t.Errorf("Unexpected body %q", w.Body.String())
+	}
+	if backend.lastReq.Voice != "alloy" || backend.lastReq.Format != backends.AudioFormatMP3 || backend.lastReq.Speed != 1.0 {
+		t.Errorf("Unexpected backend request %+v", backend.lastReq)
+	}
+}
+
+func TestHandleSpeech_WrapsPCMAsWAV(t *testing.T) {
+	pcm := []byte{1, 2, 3, 4}
+	backend := &speechBackend{resp: &backends.SynthesizeResponse{AudioData: pcm, Format: backends.AudioFormatPCM, SampleRate: 16000}}
+	r := newSpeechRouter(backend)
+	w := httptest.NewRecorder()
+
+	HandleSpeech(r)(w, newSpeechRequest(`{"model":"piper","input":"hello","response_format":"wav"}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "audio/wav" {
+		t.Errorf("Content-Type = %q, want audio/wav", ct)
+	}
+	body := w.Body.Bytes()
+	if len(body) != 44+len(pcm) || string(body[:4]) != "RIFF" || string(body[8:12]) != "WAVE" {
+		t.Fatalf("Expected WAV header, got % x", body)
+	}
+	if !bytes.Equal(body[44:], pcm) {
+		t.Errorf("PCM payload not preserved")
+	}
+}
+
+func TestHandleSpeech_Streaming(t *testing.T) {
+	backend := &speechBackend{chunks: []*backends.AudioChunk{
+		{Data: []byte("Ogg"), Format: backends.AudioFormatOPUS},
+		{Data: []byte("S-data"), Format: backends.AudioFormatOPUS},
+		{Done: true},
+	}}
+	r := newSpeechRouter(backend)
+	w := httptest.NewRecorder()
+
+	HandleSpeech(r)(w, newSpeechRequest(`{"model":"piper","input":"hello","response_format":"ogg"}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "audio/ogg" {
+		t.Errorf("Content-Type = %q, want audio/ogg", ct)
+	}
+	if w.Body.String() != "OggS-data" {
+		t.Errorf("Unexpected body %q", w.Body.String())
+	}
+	if w.Header().Get("X-Backend-Used") != "tts-backend" {
+		t.Errorf("Expected routing to tts-backend, got %q", w.Header().Get("X-Backend-Used"))
+	}
+}
+
+func TestHandleSpeech_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"missing model", `{"input":"hello"}`, http.StatusBadRequest},
+		{"missing input", `{"model":"piper","input":"  "}`, http.StatusBadRequest},
+		{"bad format", `{"model":"piper","input":"hello","response_format":"aac"}`, http.StatusBadRequest},
+		{"bad speed", `{"model":"piper","input":"hello","speed":9}`, http.StatusBadRequest},
+		{"too long", fmt.Sprintf(`{"model":"piper","input":%q}`, strings.Repeat("a", MaxSpeechInputChars+1)), http.StatusBadRequest},
+		{"invalid json", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newSpeechRouter(&speechBackend{})
+			w := httptest.NewRecorder()
+			HandleSpeech(r)(w, newSpeechRequest(tt.body))
+			if w.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleSpeech_NoTTSBackend(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "text-backend", supportsModel: true})
+	w := httptest.NewRecorder()
+
+	HandleSpeech(r)(w, newSpeechRequest(`{"model":"piper","input":"hello"}`))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleSpeech_MethodNotAllowed(t *testing.T) {
+	r := newSpeechRouter(&speechBackend{})
+	w := httptest.NewRecorder()
+
+	HandleSpeech(r)(w, httptest.NewRequest(http.MethodGet, "/v1/audio/speech", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
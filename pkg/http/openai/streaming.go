@@ -1,17 +1,230 @@
 package openai
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
 )
 
+// sseFrame is a marshalled chunk waiting to be written to the client
+type sseFrame struct {
+	data   *bytes.Buffer // pooled, returned once written
+	tokens int
+}
+
+// finishReasonStop is shared by every final chunk; encoding only reads it
+var finishReasonStop = "stop"
+
+// encodeChatChunk encodes a chat completion chunk as an SSE frame. The
+// buffer comes from the pool and goes back with putSSEBuffer once written.
+func encodeChatChunk(token string, done bool, completionID, model string, created int64) (*bytes.Buffer, error) {
+	var finishReason *string
+	if done {
+		finishReason = &finishReasonStop
+	}
+	return encodeChatDelta(ChatCompletionChunkDelta{Content: token}, finishReason, completionID, model, created)
+}
+
+// encodeChatDelta encodes a chat completion chunk carrying any delta, like
+// encodeChatChunk
+func encodeChatDelta(delta ChatCompletionChunkDelta, finishReason *string, completionID, model string, created int64) (*bytes.Buffer, error) {
+	chunk := getChatChunk()
+	defer putChatChunk(chunk)
+
+	chunk.ID = completionID
+	chunk.Created = created
+	chunk.Model = model
+	chunk.Choices[0].Delta = delta
+	chunk.Choices[0].FinishReason = finishReason
+	return encodeSSE(chunk)
+}
+
+// encodeCompletionChunk encodes a text completion chunk as an SSE frame,
+// like encodeChatChunk
+func encodeCompletionChunk(token string, done bool, completionID, model string, created int64) (*bytes.Buffer, error) {
+	chunk := getCompletionChunk()
+	defer putCompletionChunk(chunk)
+
+	chunk.ID = completionID
+	chunk.Created = created
+	chunk.Model = model
+	chunk.Choices[0].Text = token
+	if done {
+		chunk.Choices[0].FinishReason = &finishReasonStop
+	}
+	return encodeSSE(chunk)
+}
+
+// encodeSSE writes v as an SSE data frame into a pooled buffer, avoiding
+// the copies of marshalling to a slice and formatting it as a string
+func encodeSSE(v interface{}) (*bytes.Buffer, error) {
+	buf := getSSEBuffer()
+	buf.WriteString("data: ")
+	// Encode ends the JSON with a newline; a blank line ends the frame
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		putSSEBuffer(buf)
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf, nil
+}
+
+// keepaliveComment is sent while a stream is silent. SSE clients ignore
+// comments, but proxies and clients with read timeouts see a live link.
+const keepaliveComment = ": keepalive\n\n"
+
+// chunkResult is a chunk received from a backend stream, or its error
+type chunkResult struct {
+	chunk *backends.StreamChunk
+	err   error
+}
+
+// receiveChunks receives from a backend stream on a goroutine until the
+// final chunk or an error, so the handler can send keepalives and notice a
+// client disconnect while the backend is silent. stop closes the stream,
+// ending a Recv in progress, and waits for the goroutine to exit.
+func receiveChunks(reader backends.StreamReader) (results <-chan chunkResult, stop func()) {
+	out := make(chan chunkResult)
+	quit := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			chunk, err := reader.Recv()
+			select {
+			case out <- chunkResult{chunk: chunk, err: err}:
+			case <-quit:
+				return
+			}
+			if err != nil || chunk.Done {
+				return
+			}
+		}
+	}()
+	return out, func() {
+		reader.Close()
+		close(quit)
+		<-exited
+	}
+}
+
+// streamWatch times the keepalives and idle timeout of an SSE stream.
+// Both restart whenever the backend sends a chunk.
+type streamWatch struct {
+	cfg       streaming.Config
+	keepalive *time.Timer // nil when disabled
+	idle      *time.Timer // nil without an idle timeout
+	batch     *time.Timer // nil until tokens are first held back
+}
+
+func newStreamWatch(cfg streaming.Config) *streamWatch {
+	sw := &streamWatch{cfg: cfg}
+	if cfg.KeepaliveInterval > 0 {
+		sw.keepalive = time.NewTimer(cfg.KeepaliveInterval)
+	}
+	if cfg.IdleTimeout > 0 {
+		sw.idle = time.NewTimer(cfg.IdleTimeout)
+	}
+	return sw
+}
+
+// keepaliveC fires when a keepalive is due
+func (sw *streamWatch) keepaliveC() <-chan time.Time {
+	if sw.keepalive == nil {
+		return nil
+	}
+	return sw.keepalive.C
+}
+
+// idleC fires when the backend has been silent for the idle timeout
+func (sw *streamWatch) idleC() <-chan time.Time {
+	if sw.idle == nil {
+		return nil
+	}
+	return sw.idle.C
+}
+
+// batchC fires when tokens held back by the pacer are due, for backends
+// that stall mid-batch
+func (sw *streamWatch) batchC() <-chan time.Time {
+	if sw.batch == nil {
+		return nil
+	}
+	return sw.batch.C
+}
+
+// held schedules a flush of the tokens stream holds back, if any
+func (sw *streamWatch) held(stream *streaming.Stream) {
+	d, ok := stream.FlushDue()
+	if !ok {
+		return
+	}
+	if sw.batch == nil {
+		sw.batch = time.NewTimer(d)
+		return
+	}
+	sw.batch.Stop()
+	select {
+	case <-sw.batch.C:
+	default:
+	}
+	sw.batch.Reset(d)
+}
+
+// received restarts the timers after backend activity
+func (sw *streamWatch) received() {
+	if sw.keepalive != nil {
+		sw.keepalive.Reset(sw.cfg.KeepaliveInterval)
+	}
+	if sw.idle != nil {
+		sw.idle.Reset(sw.cfg.IdleTimeout)
+	}
+}
+
+// keptAlive schedules the next keepalive
+func (sw *streamWatch) keptAlive() {
+	sw.keepalive.Reset(sw.cfg.KeepaliveInterval)
+}
+
+// idleError is the error ending a stream that hit the idle timeout
+func (sw *streamWatch) idleError() error {
+	return fmt.Errorf("backend sent nothing for %s", sw.cfg.IdleTimeout)
+}
+
+func (sw *streamWatch) stop() {
+	if sw.keepalive != nil {
+		sw.keepalive.Stop()
+	}
+	if sw.idle != nil {
+		sw.idle.Stop()
+	}
+	if sw.batch != nil {
+		sw.batch.Stop()
+	}
+}
+
 // StreamChatCompletion streams a chat completion response in OpenAI SSE format
 func StreamChatCompletion(w http.ResponseWriter, reader backends.StreamReader, model string, completionID string) error {
-	defer reader.Close()
+	return StreamChatCompletionContext(context.Background(), w, reader, model, completionID)
+}
+
+// StreamChatCompletionContext is StreamChatCompletion for a request
+// context. When ctx ends, as when the client disconnects, the backend
+// stream is closed so the backend stops generating. While the backend is
+// silent, keepalive comments are sent, and the stream ends with an error
+// event after the configured idle timeout.
+func StreamChatCompletionContext(ctx context.Context, w http.ResponseWriter, reader backends.StreamReader, model string, completionID string) error {
+	results, stopReceiving := receiveChunks(reader)
+	defer stopReceiving()
 
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -26,25 +239,41 @@ func StreamChatCompletion(w http.ResponseWriter, reader backends.StreamReader, m
 
 	timestamp := time.Now().Unix()
 	index := 0
+	integrity := streaming.NewIntegrity(streaming.Resumed(ctx))
+
+	// Tool calls are sent as they complete; the final chunk then ends the
+	// choice with finish_reason tool_calls
+	toolCalls := 0
+	finishReason := &finishReasonStop
+
+	// Track send pacing so constrained links can be batched
+	stream := streaming.Default.Open("sse", "", model)
+	defer stream.Close()
 
 	// Channel for backpressure control
-	writeChan := make(chan []byte, 10) // Buffer 10 chunks
+	writeChan := make(chan sseFrame, 10) // Buffer 10 chunks
 	errChan := make(chan error, 1)
 	done := make(chan struct{})
 
 	// Writer goroutine with timeout protection
 	go func() {
 		defer close(done)
-		for data := range writeChan {
+		for frame := range writeChan {
 			// Write with timeout protection (detect slow clients)
 			written := make(chan bool, 1)
-			go func() {
-				fmt.Fprintf(w, "data: %s\n\n", string(data))
+			writeStart := time.Now()
+			go func(frame sseFrame) {
+				n, _ := w.Write(frame.data.Bytes())
 				if flusher, ok := w.(http.Flusher); ok {
 					flusher.Flush()
 				}
+				putSSEBuffer(frame.data)
+				if frame.tokens > 0 {
+					// Keepalives aren't paced
+					stream.RecordWrite(n, frame.tokens, time.Since(writeStart))
+				}
 				written <- true
-			}()
+			}(frame)
 
 			select {
 			case <-written:
@@ -57,81 +286,133 @@ func StreamChatCompletion(w http.ResponseWriter, reader backends.StreamReader, m
 		}
 	}()
 
+	watch := newStreamWatch(streaming.Default.Config())
+	defer watch.stop()
+
+	// sendTokens queues a content frame for the writer
+	sendTokens := func(token string, tokens int, finish *string) error {
+		data, err := encodeChatDelta(ChatCompletionChunkDelta{Content: token}, finish, completionID, model, timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to marshal chunk: %w", err)
+		}
+		integrity.Add(token, tokens)
+
+		// Send to writer with backpressure (blocking)
+		select {
+		case writeChan <- sseFrame{data: data, tokens: tokens}:
+			return nil
+		case err := <-errChan:
+			// Writer encountered error (slow client)
+			return err
+		case <-time.After(5 * time.Second):
+			// Backpressure timeout - client can't keep up
+			return fmt.Errorf("backpressure timeout - client too slow")
+		}
+	}
+
 	// Reader loop
 	for {
-		chunk, err := reader.Recv()
+		var chunk *backends.StreamChunk
+		var err error
+		select {
+		case r := <-results:
+			chunk, err = r.chunk, r.err
+			watch.received()
+		case <-watch.keepaliveC():
+			buf := getSSEBuffer()
+			buf.WriteString(keepaliveComment)
+			select {
+			case writeChan <- sseFrame{data: buf}:
+			default:
+				// Frames are already waiting to be written
+				putSSEBuffer(buf)
+			}
+			watch.keptAlive()
+			continue
+		case <-watch.batchC():
+			// The backend stalled with tokens held back
+			if token, tokens := stream.Flush(); tokens > 0 {
+				if err := sendTokens(token, tokens, nil); err != nil {
+					close(writeChan)
+					<-done
+					return err
+				}
+			}
+			continue
+		case <-watch.idleC():
+			if token, tokens := stream.Flush(); tokens > 0 {
+				sendTokens(token, tokens, nil)
+			}
+			close(writeChan)
+			<-done
+			err := watch.idleError()
+			writeStreamError(w, err)
+			return err
+		case <-ctx.Done():
+			// The client went away: stop writing; the deferred stop
+			// closes the backend stream
+			close(writeChan)
+			<-done
+			return ctx.Err()
+		}
+
+		if errors.Is(err, io.EOF) && stream.Pending() > 0 {
+			// Flush tokens still held back by the pacer
+			chunk, err = &backends.StreamChunk{Done: true}, nil
+		}
 		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				// Send what the client is owed before the error, so a
+				// failed stream never looks like a short complete one
+				if token, tokens := stream.Flush(); tokens > 0 {
+					sendTokens(token, tokens, nil)
+				}
+			}
 			close(writeChan)
 			<-done // Wait for writer to finish
 
 			// Check if it's a normal EOF or an error
-			if err.Error() != "EOF" {
-				// Send error event to client
-				errorEvent := map[string]interface{}{
-					"error": map[string]interface{}{
-						"message": err.Error(),
-						"type":    "stream_error",
-						"code":    "backend_error",
-					},
-				}
-				errorJSON, _ := json.Marshal(errorEvent)
-				fmt.Fprintf(w, "event: error\ndata: %s\n\n", string(errorJSON))
-				if flusher, ok := w.(http.Flusher); ok {
-					flusher.Flush()
-				}
+			if !errors.Is(err, io.EOF) {
+				writeStreamError(w, err)
 				return err
 			}
 			break
 		}
 
-		// Get chunk from pool
-		openaiChunk := getChatChunk()
+		if len(chunk.ToolCalls) > 0 {
+			delta := ChatCompletionChunkDelta{ToolCalls: openAIToolCalls(chunk.ToolCalls, toolCalls)}
+			data, err := encodeChatDelta(delta, nil, completionID, model, timestamp)
+			if err != nil {
+				close(writeChan)
+				<-done
+				return fmt.Errorf("failed to marshal chunk: %w", err)
+			}
+			select {
+			case writeChan <- sseFrame{data: data}:
+			case err := <-errChan:
+				close(writeChan)
+				<-done
+				return err
+			}
+			toolCalls += len(chunk.ToolCalls)
+			finishReason = &finishReasonToolCalls
+		}
 
-		// Populate chunk fields
-		openaiChunk.ID = completionID
-		openaiChunk.Object = "chat.completion.chunk"
-		openaiChunk.Created = timestamp
-		openaiChunk.Model = model
+		// Hold tokens back while the client link is constrained
+		token, tokens := stream.Batch(chunk.Token, chunk.Done)
+		if tokens == 0 {
+			watch.held(stream)
+			continue
+		}
 
+		var finish *string
 		if chunk.Done {
-			// Final chunk with finish_reason
-			finishReason := "stop"
-			openaiChunk.Choices[0].Index = 0
-			openaiChunk.Choices[0].Delta.Content = chunk.Token
-			openaiChunk.Choices[0].FinishReason = &finishReason
-		} else {
-			// Regular chunk
-			openaiChunk.Choices[0].Index = 0
-			openaiChunk.Choices[0].Delta.Content = chunk.Token
-			openaiChunk.Choices[0].FinishReason = nil
-		}
-
-		// Marshal to JSON
-		data, err := json.Marshal(openaiChunk)
-		if err != nil {
-			putChatChunk(openaiChunk) // Return to pool
-			close(writeChan)
-			<-done
-			return fmt.Errorf("failed to marshal chunk: %w", err)
+			finish = finishReason
 		}
-
-		// Return chunk to pool
-		putChatChunk(openaiChunk)
-
-		// Send to writer with backpressure (blocking)
-		select {
-		case writeChan <- data:
-			// Sent successfully
-		case err := <-errChan:
-			// Writer encountered error (slow client)
+		if err := sendTokens(token, tokens, finish); err != nil {
 			close(writeChan)
 			<-done
 			return err
-		case <-time.After(5 * time.Second):
-			// Backpressure timeout - client can't keep up
-			close(writeChan)
-			<-done
-			return fmt.Errorf("backpressure timeout - client too slow")
 		}
 
 		index++
@@ -154,7 +435,8 @@ func StreamChatCompletion(w http.ResponseWriter, reader backends.StreamReader, m
 		// No error
 	}
 
-	// Send [DONE] message
+	// Send the integrity frame and [DONE] message
+	writeIntegrity(w, integrity)
 	fmt.Fprintf(w, "data: [DONE]\n\n")
 
 	// Final flush
@@ -167,7 +449,15 @@ func StreamChatCompletion(w http.ResponseWriter, reader backends.StreamReader, m
 
 // StreamCompletion streams a completion response in OpenAI SSE format
 func StreamCompletion(w http.ResponseWriter, reader backends.StreamReader, model string, completionID string) error {
-	defer reader.Close()
+	return StreamCompletionContext(context.Background(), w, reader, model, completionID)
+}
+
+// StreamCompletionContext is StreamCompletion for a request context, with
+// the disconnect handling, keepalives and idle timeout of
+// StreamChatCompletionContext
+func StreamCompletionContext(ctx context.Context, w http.ResponseWriter, reader backends.StreamReader, model string, completionID string) error {
+	results, stopReceiving := receiveChunks(reader)
+	defer stopReceiving()
 
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -181,53 +471,97 @@ func StreamCompletion(w http.ResponseWriter, reader backends.StreamReader, model
 
 	timestamp := time.Now().Unix()
 	index := 0
+	integrity := streaming.NewIntegrity(streaming.Resumed(ctx))
 
-	for {
-		chunk, err := reader.Recv()
-		if err != nil {
-			// End of stream
-			break
-		}
-
-		// Get chunk from pool
-		openaiChunk := getCompletionChunk()
+	// Track send pacing so constrained links can be batched
+	stream := streaming.Default.Open("sse", "", model)
+	defer stream.Close()
 
-		// Populate chunk fields
-		openaiChunk.ID = completionID
-		openaiChunk.Object = "text_completion.chunk"
-		openaiChunk.Created = timestamp
-		openaiChunk.Model = model
+	watch := newStreamWatch(streaming.Default.Config())
+	defer watch.stop()
 
-		if chunk.Done {
-			// Final chunk with finish_reason
-			finishReason := "stop"
-			openaiChunk.Choices[0].Text = chunk.Token
-			openaiChunk.Choices[0].Index = 0
-			openaiChunk.Choices[0].FinishReason = &finishReason
-		} else {
-			// Regular chunk
-			openaiChunk.Choices[0].Text = chunk.Token
-			openaiChunk.Choices[0].Index = 0
-			openaiChunk.Choices[0].FinishReason = nil
-		}
-
-		// Marshal to JSON
-		data, err := json.Marshal(openaiChunk)
+	// writeTokens writes a frame of text to the client
+	writeTokens := func(token string, tokens int, done bool) error {
+		data, err := encodeCompletionChunk(token, done, completionID, model, timestamp)
 		if err != nil {
-			putCompletionChunk(openaiChunk) // Return to pool
 			return fmt.Errorf("failed to marshal chunk: %w", err)
 		}
+		integrity.Add(token, tokens)
 
 		// Write SSE formatted data
-		fmt.Fprintf(w, "data: %s\n\n", string(data))
+		writeStart := time.Now()
+		n, _ := w.Write(data.Bytes())
+		putSSEBuffer(data)
 
 		// Flush the data immediately
 		if flusher, ok := w.(http.Flusher); ok {
 			flusher.Flush()
 		}
+		stream.RecordWrite(n, tokens, time.Since(writeStart))
+		return nil
+	}
 
-		// Return chunk to pool
-		putCompletionChunk(openaiChunk)
+	for {
+		var chunk *backends.StreamChunk
+		var err error
+		select {
+		case r := <-results:
+			chunk, err = r.chunk, r.err
+			watch.received()
+		case <-watch.keepaliveC():
+			io.WriteString(w, keepaliveComment)
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			watch.keptAlive()
+			continue
+		case <-watch.batchC():
+			// The backend stalled with tokens held back
+			if token, tokens := stream.Flush(); tokens > 0 {
+				if err := writeTokens(token, tokens, false); err != nil {
+					return err
+				}
+			}
+			continue
+		case <-watch.idleC():
+			if token, tokens := stream.Flush(); tokens > 0 {
+				writeTokens(token, tokens, false)
+			}
+			err := watch.idleError()
+			writeStreamError(w, err)
+			return err
+		case <-ctx.Done():
+			// The client went away; the deferred stop closes the backend stream
+			return ctx.Err()
+		}
+
+		if errors.Is(err, io.EOF) && stream.Pending() > 0 {
+			// Flush tokens still held back by the pacer
+			chunk, err = &backends.StreamChunk{Done: true}, nil
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// End of stream
+				break
+			}
+			// Send what the client is owed, then the error
+			if token, tokens := stream.Flush(); tokens > 0 {
+				writeTokens(token, tokens, false)
+			}
+			writeStreamError(w, err)
+			return err
+		}
+
+		// Hold tokens back while the client link is constrained
+		token, tokens := stream.Batch(chunk.Token, chunk.Done)
+		if tokens == 0 {
+			watch.held(stream)
+			continue
+		}
+
+		if err := writeTokens(token, tokens, chunk.Done); err != nil {
+			return err
+		}
 
 		index++
 
@@ -237,7 +571,8 @@ func StreamCompletion(w http.ResponseWriter, reader backends.StreamReader, model
 		}
 	}
 
-	// Send [DONE] message
+	// Send the integrity frame and [DONE] message
+	writeIntegrity(w, integrity)
 	fmt.Fprintf(w, "data: [DONE]\n\n")
 
 	// Final flush
@@ -247,3 +582,68 @@ func StreamCompletion(w http.ResponseWriter, reader backends.StreamReader, model
 
 	return nil
 }
+
+// announceColdStart tells a streaming client that the backend must load
+// model before the first token. The SSE stream is opened early and a
+// comment such as ": loading model llama3:8b, ~12s" is sent, which OpenAI
+// clients ignore but keeps them from treating the wait as a hung
+// connection. Until the returned function is called with the outcome of
+// the wait, progress follows as ": progress {...}" comments carrying the
+// JSON of a backends.Progress. It returns nil, writing nothing, when the
+// model is warm.
+func announceColdStart(ctx context.Context, w http.ResponseWriter, decision *router.RoutingDecision, model string) func(error) {
+	cs := decision.ColdStart(ctx, model)
+	if cs == nil {
+		return nil
+	}
+
+	WriteRoutingHeaders(w, decision)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("X-Cold-Start", cs.Reason)
+	if cs.Estimate > 0 {
+		w.Header().Set("X-Cold-Start-Estimate-Ms", fmt.Sprintf("%d", cs.Estimate.Milliseconds()))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, ": %s\n\n", cs.Message())
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return backends.WatchLoad(decision.Backend.ID(), cs, func(p backends.Progress) {
+		data, _ := json.Marshal(p)
+		fmt.Fprintf(w, ": progress %s\n\n", data)
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	})
+}
+
+// writeIntegrity sends the frame ending a completed stream with the token
+// count and SHA-256 of its content, so clients can tell a complete stream
+// from one cut short. It is an SSE comment, ": integrity {...}", which
+// OpenAI clients ignore.
+func writeIntegrity(w io.Writer, integrity *streaming.Integrity) {
+	data, _ := json.Marshal(integrity.Summary())
+	fmt.Fprintf(w, ": integrity %s\n\n", data)
+}
+
+// writeStreamError sends an error event on an SSE stream that has already
+// started, when a status code can no longer be sent
+func writeStreamError(w http.ResponseWriter, err error) {
+	errorEvent := map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": err.Error(),
+			"type":    "stream_error",
+			"code":    "backend_error",
+		},
+	}
+	errorJSON, _ := json.Marshal(errorEvent)
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", string(errorJSON))
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -1,14 +1,19 @@
 package openai
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
 )
 
 // MockStreamReader implements backends.StreamReader for testing
@@ -618,15 +623,16 @@ func TestStreamCompletion_ActualError(t *testing.T) {
 	recorder := httptest.NewRecorder()
 
 	err := StreamCompletion(recorder, reader, "text-davinci-003", "cmpl-error")
-	// StreamCompletion doesn't return errors from reader - it just breaks the loop
-	if err != nil {
-		t.Errorf("Expected no error from StreamCompletion (it breaks on reader error), got %v", err)
+	if err == nil {
+		t.Error("Expected error from reader")
 	}
 
-	// But it should still have written something to the response
 	body := recorder.Body.String()
-	if body == "" {
-		t.Error("Expected response body to be written before error")
+	if !strings.Contains(body, "incomplete") || !strings.Contains(body, "db error") {
+		t.Errorf("Expected the partial text then the error, got %q", body)
+	}
+	if strings.Contains(body, "[DONE]") {
+		t.Errorf("Expected a failed stream not to end cleanly, got %q", body)
 	}
 }
 
@@ -1558,3 +1564,206 @@ func TestStreamCompletion_NilFinishReasonInitially(t *testing.T) {
 		t.Error("Expected final chunk with finish_reason='stop'")
 	}
 }
+
+// blockingStreamReader sends the chunks written to it and blocks between
+// them until closed
+type blockingStreamReader struct {
+	chunks chan *backends.StreamChunk
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newBlockingStreamReader() *blockingStreamReader {
+	return &blockingStreamReader{chunks: make(chan *backends.StreamChunk), closed: make(chan struct{})}
+}
+
+func (b *blockingStreamReader) Recv() (*backends.StreamChunk, error) {
+	select {
+	case chunk := <-b.chunks:
+		return chunk, nil
+	case <-b.closed:
+		return nil, io.EOF
+	}
+}
+
+func (b *blockingStreamReader) Close() error {
+	b.once.Do(func() { close(b.closed) })
+	return nil
+}
+
+func (b *blockingStreamReader) isClosed() bool {
+	select {
+	case <-b.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func withStreamConfig(t *testing.T, cfg streaming.Config) {
+	prev := streaming.Default
+	streaming.SetDefault(streaming.NewTracker(cfg))
+	t.Cleanup(func() { streaming.SetDefault(prev) })
+}
+
+func TestStreamChatCompletion_KeepaliveWhileSilent(t *testing.T) {
+	withStreamConfig(t, streaming.Config{KeepaliveInterval: 10 * time.Millisecond})
+	reader := newBlockingStreamReader()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		reader.chunks <- &backends.StreamChunk{Token: "late", Done: true}
+	}()
+
+	recorder := httptest.NewRecorder()
+	if err := StreamChatCompletionContext(context.Background(), recorder, reader, "gpt-4", "chatcmpl-keepalive"); err != nil {
+		t.Fatalf("StreamChatCompletionContext failed: %v", err)
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, ": keepalive\n\n") || !strings.Contains(body, "late") || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Errorf("Expected keepalives before the reply, got %q", body)
+	}
+}
+
+func TestStreamChatCompletion_ClientDisconnect(t *testing.T) {
+	withStreamConfig(t, streaming.Config{})
+	reader := newBlockingStreamReader()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		reader.chunks <- &backends.StreamChunk{Token: "first"}
+		cancel()
+	}()
+
+	recorder := httptest.NewRecorder()
+	err := StreamChatCompletionContext(ctx, recorder, reader, "gpt-4", "chatcmpl-gone")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if !reader.isClosed() {
+		t.Error("Expected the backend stream closed")
+	}
+	if body := recorder.Body.String(); strings.Contains(body, "[DONE]") {
+		t.Errorf("Expected no end of stream for a gone client, got %q", body)
+	}
+}
+
+func TestStreamCompletion_ErrorAfterHeldTokens(t *testing.T) {
+	// Every write counts as slow, so tokens after the first are held back
+	withStreamConfig(t, streaming.Config{Enabled: true, SlowWriteThreshold: time.Nanosecond, MaxBatchTokens: 100, MaxBatchDelay: time.Minute})
+	reader := NewMockStreamReaderWithError([]*backends.StreamChunk{
+		{Token: "a"}, {Token: "b"}, {Token: "c"},
+	}, fmt.Errorf("backend reset"))
+
+	recorder := httptest.NewRecorder()
+	err := StreamCompletionContext(context.Background(), recorder, reader, "text-davinci-003", "cmpl-held")
+	if err == nil || !strings.Contains(err.Error(), "backend reset") {
+		t.Fatalf("Expected the backend error, got %v", err)
+	}
+	body := recorder.Body.String()
+	held, failed := strings.Index(body, `"text":"bc"`), strings.Index(body, "event: error")
+	if held < 0 || failed < held {
+		t.Errorf("Expected the held tokens flushed before the error, got %q", body)
+	}
+	if strings.Contains(body, "[DONE]") {
+		t.Errorf("Expected a failed stream not to end cleanly, got %q", body)
+	}
+}
+
+func TestStreamChatCompletion_BatchDelayFlushesStall(t *testing.T) {
+	withStreamConfig(t, streaming.Config{Enabled: true, SlowWriteThreshold: time.Nanosecond, MaxBatchTokens: 100, MaxBatchDelay: 20 * time.Millisecond})
+	reader := newBlockingStreamReader()
+	go func() {
+		reader.chunks <- &backends.StreamChunk{Token: "a"}
+		reader.chunks <- &backends.StreamChunk{Token: "b"}
+		// Stall well past MaxBatchDelay with "b" held back
+		time.Sleep(150 * time.Millisecond)
+		reader.chunks <- &backends.StreamChunk{Token: "c", Done: true}
+	}()
+
+	recorder := httptest.NewRecorder()
+	if err := StreamChatCompletionContext(context.Background(), recorder, reader, "gpt-4", "chatcmpl-stall"); err != nil {
+		t.Fatalf("StreamChatCompletionContext failed: %v", err)
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, `"content":"b"`) || strings.Contains(body, `"content":"bc"`) {
+		t.Errorf("Expected the held token flushed during the stall, got %q", body)
+	}
+}
+
+func TestStreamCompletion_IdleTimeout(t *testing.T) {
+	withStreamConfig(t, streaming.Config{KeepaliveInterval: 5 * time.Millisecond, IdleTimeout: 50 * time.Millisecond})
+	reader := newBlockingStreamReader()
+
+	recorder := httptest.NewRecorder()
+	err := StreamCompletionContext(context.Background(), recorder, reader, "text-davinci-003", "cmpl-idle")
+	if err == nil || !strings.Contains(err.Error(), "sent nothing for 50ms") {
+		t.Fatalf("Expected an idle timeout, got %v", err)
+	}
+	if !reader.isClosed() {
+		t.Error("Expected the backend stream closed")
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, ": keepalive\n\n") || !strings.Contains(body, "event: error") {
+		t.Errorf("Expected keepalives then an error event, got %q", body)
+	}
+}
+
+// integrityFrame returns the integrity frame of an SSE body, and whether it
+// came before [DONE]
+func integrityFrame(t *testing.T, body string) (streaming.IntegritySummary, bool) {
+	t.Helper()
+	var summary streaming.IntegritySummary
+	start := strings.Index(body, ": integrity ")
+	if start < 0 {
+		t.Fatalf("Expected an integrity frame, got %q", body)
+	}
+	line := body[start+len(": integrity "):]
+	line = line[:strings.Index(line, "\n")]
+	if err := json.Unmarshal([]byte(line), &summary); err != nil {
+		t.Fatalf("Failed to decode integrity frame %q: %v", line, err)
+	}
+	return summary, start < strings.Index(body, "data: [DONE]")
+}
+
+func TestStreamChatCompletion_IntegrityFrame(t *testing.T) {
+	reader := NewMockStreamReader([]*backends.StreamChunk{
+		{Token: "Hello"},
+		{Token: ", world", Done: true},
+	})
+	recorder := httptest.NewRecorder()
+
+	if err := StreamChatCompletion(recorder, reader, "gpt-4", "chatcmpl-sum"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	summary, beforeDone := integrityFrame(t, recorder.Body.String())
+	if !beforeDone {
+		t.Error("Expected the integrity frame before [DONE]")
+	}
+	want := streaming.NewIntegrity("")
+	want.Add("Hello, world", 2)
+	if summary != want.Summary() {
+		t.Errorf("Expected %+v, got %+v", want.Summary(), summary)
+	}
+}
+
+func TestStreamCompletion_IntegrityFrameResumed(t *testing.T) {
+	reader := NewMockStreamReader([]*backends.StreamChunk{
+		{Token: " world", Done: true},
+	})
+	recorder := httptest.NewRecorder()
+	ctx := streaming.WithResumed(context.Background(), "Hello")
+
+	if err := StreamCompletionContext(ctx, recorder, reader, "text-davinci-003", "cmpl-sum"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	summary, _ := integrityFrame(t, recorder.Body.String())
+	want := streaming.NewIntegrity("")
+	want.Add("Hello world", 0)
+	if summary.SHA256 != want.Summary().SHA256 {
+		t.Errorf("Expected the hash to cover the resumed text, got %+v", summary)
+	}
+	if summary.ResumedBytes != 5 || summary.Tokens != 1 {
+		t.Errorf("Expected 5 resumed bytes and 1 new token, got %+v", summary)
+	}
+}
@@ -0,0 +1,133 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// finishReasonToolCalls ends a choice whose model called tools
+var finishReasonToolCalls = "tool_calls"
+
+// chatTools validates a chat request's tools and tool_choice, returning
+// the tools to offer the model and the backend tool choice: "" for the
+// model to decide, "required", or a tool name. tool_choice "none" offers
+// no tools.
+func chatTools(req *ChatCompletionRequest) ([]backends.ToolDefinition, string, error) {
+	choice, err := parseToolChoice(req.ToolChoice)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(req.Tools) == 0 {
+		if choice != "" && choice != "none" {
+			return nil, "", fmt.Errorf("tool_choice requires tools")
+		}
+		return nil, "", nil
+	}
+
+	tools := make([]backends.ToolDefinition, 0, len(req.Tools))
+	names := make(map[string]bool, len(req.Tools))
+	for i, tool := range req.Tools {
+		if tool.Type != "function" {
+			return nil, "", fmt.Errorf("tools[%d]: unsupported type %q", i, tool.Type)
+		}
+		if tool.Function.Name == "" {
+			return nil, "", fmt.Errorf("tools[%d]: function name is required", i)
+		}
+		if names[tool.Function.Name] {
+			return nil, "", fmt.Errorf("tools[%d]: duplicate function %s", i, tool.Function.Name)
+		}
+		names[tool.Function.Name] = true
+
+		def := backends.ToolDefinition{Name: tool.Function.Name, Description: tool.Function.Description}
+		if tool.Function.Parameters != nil {
+			if def.Parameters, err = json.Marshal(tool.Function.Parameters); err != nil {
+				return nil, "", fmt.Errorf("tools[%d]: invalid parameters: %v", i, err)
+			}
+		}
+		tools = append(tools, def)
+	}
+
+	switch choice {
+	case "none":
+		return nil, "", nil
+	case "", "required":
+	default:
+		if !names[choice] {
+			return nil, "", fmt.Errorf("tool_choice names unknown function %s", choice)
+		}
+	}
+	return tools, choice, nil
+}
+
+// parseToolChoice reads tool_choice: "auto" (or unset) becomes ""
+func parseToolChoice(v interface{}) (string, error) {
+	switch c := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		switch c {
+		case "auto":
+			return "", nil
+		case "none", "required":
+			return c, nil
+		}
+	case map[string]interface{}:
+		if function, ok := c["function"].(map[string]interface{}); ok && c["type"] == "function" {
+			if name, ok := function["name"].(string); ok && name != "" {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid tool_choice")
+}
+
+// chatMessages converts a conversation for backends that call tools. Tool
+// results name the function they answer, for backends that match results
+// by name rather than call ID.
+func chatMessages(messages []ChatCompletionMessage) []backends.ChatMessage {
+	called := make(map[string]string)
+	out := make([]backends.ChatMessage, len(messages))
+	for i, msg := range messages {
+		out[i] = backends.ChatMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+			Name:       msg.Name,
+		}
+		for _, call := range msg.ToolCalls {
+			called[call.ID] = call.Function.Name
+			out[i].ToolCalls = append(out[i].ToolCalls, backends.ToolCall{
+				ID:        call.ID,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			})
+		}
+		if out[i].Name == "" && msg.ToolCallID != "" {
+			out[i].Name = called[msg.ToolCallID]
+		}
+	}
+	return out
+}
+
+// openAIToolCalls converts a backend's tool calls, numbering them from
+// first in stream deltas (first < 0 for a complete message)
+func openAIToolCalls(calls []backends.ToolCall, first int) []ChatCompletionToolCall {
+	out := make([]ChatCompletionToolCall, len(calls))
+	for i, call := range calls {
+		out[i] = ChatCompletionToolCall{
+			ID:   call.ID,
+			Type: "function",
+			Function: ChatCompletionFunctionCall{
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			},
+		}
+		if first >= 0 {
+			index := first + i
+			out[i].Index = &index
+		}
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// toolBackend calls tools, answering with calls and recording the request
+type toolBackend struct {
+	mockBackendWithStream
+	lastReq *backends.GenerateRequest
+}
+
+func (m *toolBackend) SupportsToolCalling() bool { return true }
+
+func (m *toolBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	m.lastReq = req
+	return &backends.GenerateResponse{
+		ToolCalls: []backends.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Dublin"}`}},
+	}, nil
+}
+
+func (m *toolBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	m.lastReq = req
+	return m.mockBackendWithStream.GenerateStream(ctx, req)
+}
+
+const weatherTools = `"tools":[{"type":"function","function":{"name":"get_weather","description":"Current weather","parameters":{"type":"object","properties":{"city":{"type":"string"}}}}}]`
+
+func newToolRouter() (*router.Router, *toolBackend) {
+	r := router.NewRouter(router.Config{})
+	// The backend without tool calling must be skipped by capability filtering
+	r.RegisterBackend(&mockBackend{id: "plain-backend", supportsModel: true})
+	backend := &toolBackend{mockBackendWithStream: mockBackendWithStream{
+		mockBackend: &mockBackend{id: "tool-backend", supportsModel: true, supportsStream: true},
+	}}
+	r.RegisterBackend(backend)
+	return r, backend
+}
+
+func TestHandleChatCompletion_ToolCalls(t *testing.T) {
+	r, backend := newToolRouter()
+	body := `{"model":"llama3.1","messages":[
+		{"role":"user","content":"Weather in Dublin?"},
+		{"role":"assistant","content":"","tool_calls":[{"id":"call_0","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Cork\"}"}}]},
+		{"role":"tool","tool_call_id":"call_0","content":"12C"}
+	],` + weatherTools + `}`
+	w := httptest.NewRecorder()
+	HandleChatCompletion(r)(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("X-Backend-Used") != "tool-backend" {
+		t.Errorf("Expected routing to tool-backend, got %q", w.Header().Get("X-Backend-Used"))
+	}
+
+	var resp ChatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("Expected a tool call, got %+v", choice)
+	}
+	if call := choice.Message.ToolCalls[0]; call.ID != "call_1" || call.Type != "function" || call.Function.Arguments != `{"city":"Dublin"}` || call.Index != nil {
+		t.Errorf("Unexpected tool call %+v", call)
+	}
+
+	req := backend.lastReq
+	if len(req.Tools) != 1 || req.Tools[0].Name != "get_weather" || !strings.Contains(string(req.Tools[0].Parameters), `"city"`) {
+		t.Errorf("Expected the tool passed to the backend, got %+v", req.Tools)
+	}
+	if len(req.Messages) != 3 || req.Messages[1].ToolCalls[0].Name != "get_weather" || req.Messages[2].Name != "get_weather" {
+		t.Errorf("Expected the conversation with the tool result named, got %+v", req.Messages)
+	}
+}
+
+func TestHandleChatCompletion_ToolCallsStreaming(t *testing.T) {
+	r, backend := newToolRouter()
+	backend.reader = &mockStreamReader{chunks: []backends.StreamChunk{
+		{ToolCalls: []backends.ToolCall{{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Dublin"}`}}},
+		{ToolCalls: []backends.ToolCall{{ID: "call_2", Name: "get_weather", Arguments: `{"city":"Cork"}`}}},
+		{Done: true},
+	}}
+	body := `{"model":"llama3.1","stream":true,"tool_choice":{"type":"function","function":{"name":"get_weather"}},"messages":[{"role":"user","content":"Weather?"}],` + weatherTools + `}`
+	w := httptest.NewRecorder()
+	HandleChatCompletion(r)(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if backend.lastReq.ToolChoice != "get_weather" {
+		t.Errorf("Expected the tool choice passed on, got %q", backend.lastReq.ToolChoice)
+	}
+
+	var calls []ChatCompletionToolCall
+	var finish string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk ChatCompletionChunk
+		if json.Unmarshal([]byte(data), &chunk) != nil || len(chunk.Choices) == 0 {
+			continue
+		}
+		calls = append(calls, chunk.Choices[0].Delta.ToolCalls...)
+		if chunk.Choices[0].FinishReason != nil {
+			finish = *chunk.Choices[0].FinishReason
+		}
+	}
+	if len(calls) != 2 || *calls[0].Index != 0 || *calls[1].Index != 1 || calls[1].Function.Arguments != `{"city":"Cork"}` {
+		t.Errorf("Expected two indexed tool call deltas, got %+v", calls)
+	}
+	if finish != "tool_calls" {
+		t.Errorf("Expected finish_reason tool_calls, got %q", finish)
+	}
+}
+
+func TestHandleChatCompletion_InvalidTools(t *testing.T) {
+	r, _ := newToolRouter()
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"unknown choice", `"tool_choice":{"type":"function","function":{"name":"get_time"}},` + weatherTools, "unknown function"},
+		{"bad choice", `"tool_choice":"always",` + weatherTools, "invalid tool_choice"},
+		{"choice without tools", `"tool_choice":"required"`, "requires tools"},
+		{"bad type", `"tools":[{"type":"retrieval","function":{"name":"x"}}]`, "unsupported type"},
+		{"no name", `"tools":[{"type":"function","function":{}}]`, "name is required"},
+		{"with n", `"n":2,` + weatherTools, "combined with n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"model":"llama3.1","messages":[{"role":"user","content":"hi"}],` + tt.body + `}`
+			w := httptest.NewRecorder()
+			HandleChatCompletion(r)(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("Expected 400 containing %q, got %d: %s", tt.want, w.Code, w.Body)
+			}
+		})
+	}
+
+	// tool_choice none offers no tools, so any backend can answer
+	_, backend := newToolRouter()
+	plain := router.NewRouter(router.Config{})
+	plain.RegisterBackend(backend.mockBackend)
+	body := `{"model":"llama3.1","messages":[{"role":"user","content":"hi"}],"tool_choice":"none",` + weatherTools + `}`
+	w := httptest.NewRecorder()
+	HandleChatCompletion(plain)(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 with tool_choice none, got %d: %s", w.Code, w.Body)
+	}
+}
@@ -1,5 +1,7 @@
 package openai
 
+import "github.com/daoneill/ollama-proxy/pkg/router"
+
 // OpenAI API compatible request/response types
 
 // ChatCompletionRequest represents a request to /v1/chat/completions
@@ -16,13 +18,46 @@ type ChatCompletionRequest struct {
 	FrequencyPenalty *float32                       `json:"frequency_penalty,omitempty"`
 	LogitBias        map[string]float32             `json:"logit_bias,omitempty"`
 	User             string                         `json:"user,omitempty"`
+	SessionID        string                         `json:"session_id,omitempty"` // pins the conversation to one backend, defaults to user
+	ResumeFrom       string                         `json:"resume_from,omitempty"` // reply text received before a stream broke, to continue from
+	Tools            []ChatCompletionTool           `json:"tools,omitempty"`
+	ToolChoice       interface{}                    `json:"tool_choice,omitempty"` // "none", "auto", "required" or {"type":"function","function":{"name":...}}
 }
 
 // ChatCompletionMessage represents a message in the chat
 type ChatCompletionMessage struct {
-	Role    string `json:"role"`    // system, user, assistant, function
-	Content string `json:"content"`
-	Name    string `json:"name,omitempty"`
+	Role       string                   `json:"role"` // system, user, assistant, tool
+	Content    string                   `json:"content"`
+	Name       string                   `json:"name,omitempty"`
+	ToolCalls  []ChatCompletionToolCall `json:"tool_calls,omitempty"`   // assistant
+	ToolCallID string                   `json:"tool_call_id,omitempty"` // tool: the call answered
+}
+
+// ChatCompletionTool is a tool the model may call
+type ChatCompletionTool struct {
+	Type     string                 `json:"type"` // function
+	Function ChatCompletionFunction `json:"function"`
+}
+
+// ChatCompletionFunction describes a function tool
+type ChatCompletionFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"` // JSON schema
+}
+
+// ChatCompletionToolCall is a model's call of a tool
+type ChatCompletionToolCall struct {
+	Index    *int                       `json:"index,omitempty"` // stream deltas only
+	ID       string                     `json:"id"`
+	Type     string                     `json:"type"` // function
+	Function ChatCompletionFunctionCall `json:"function"`
+}
+
+// ChatCompletionFunctionCall is the function and arguments of a tool call
+type ChatCompletionFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON object
 }
 
 // ChatCompletionResponse represents a response from /v1/chat/completions
@@ -33,13 +68,16 @@ type ChatCompletionResponse struct {
 	Model   string                   `json:"model"`
 	Choices []ChatCompletionChoice   `json:"choices"`
 	Usage   ChatCompletionUsage      `json:"usage"`
+
+	// Every ensemble member's answer, with X-Ensemble-Candidates
+	Ensemble *router.EnsembleResult `json:"ensemble,omitempty"`
 }
 
 // ChatCompletionChoice represents a completion choice
 type ChatCompletionChoice struct {
 	Index        int                   `json:"index"`
 	Message      ChatCompletionMessage `json:"message"`
-	FinishReason string                `json:"finish_reason"` // stop, length, content_filter, null
+	FinishReason string                `json:"finish_reason"` // stop, length, tool_calls, content_filter, null
 }
 
 // ChatCompletionUsage represents token usage statistics
@@ -67,8 +105,9 @@ type ChatCompletionChunkChoice struct {
 
 // ChatCompletionChunkDelta represents the incremental content in a chunk
 type ChatCompletionChunkDelta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role      string                   `json:"role,omitempty"`
+	Content   string                   `json:"content,omitempty"`
+	ToolCalls []ChatCompletionToolCall `json:"tool_calls,omitempty"`
 }
 
 // CompletionRequest represents a request to /v1/completions (legacy)
@@ -89,6 +128,7 @@ type CompletionRequest struct {
 	BestOf           *int               `json:"best_of,omitempty"`
 	LogitBias        map[string]float32 `json:"logit_bias,omitempty"`
 	User             string             `json:"user,omitempty"`
+	ResumeFrom       string             `json:"resume_from,omitempty"` // text received before a stream broke, to continue from
 }
 
 // CompletionResponse represents a response from /v1/completions
@@ -99,6 +139,9 @@ type CompletionResponse struct {
 	Model   string             `json:"model"`
 	Choices []CompletionChoice `json:"choices"`
 	Usage   CompletionUsage    `json:"usage"`
+
+	// Every ensemble member's answer, with X-Ensemble-Candidates
+	Ensemble *router.EnsembleResult `json:"ensemble,omitempty"`
 }
 
 // CompletionChoice represents a completion choice
@@ -138,6 +181,7 @@ type EmbeddingRequest struct {
 	Input          interface{} `json:"input"` // string or []string
 	User           string      `json:"user,omitempty"`
 	EncodingFormat string      `json:"encoding_format,omitempty"` // "float" or "base64"
+	Dimensions     int         `json:"dimensions,omitempty"`
 }
 
 // EmbeddingResponse represents a response from /v1/embeddings
@@ -153,6 +197,12 @@ type EmbeddingData struct {
 	Object    string    `json:"object"` // "embedding"
 	Index     int       `json:"index"`
 	Embedding []float32 `json:"embedding"`
+
+	// Error is set instead of Embedding when this input of a batch failed
+	Error *ErrorDetail `json:"error,omitempty"`
+
+	// base64 writes Embedding as base64 little-endian float32s
+	base64 bool
 }
 
 // EmbeddingUsage represents token usage for embeddings
@@ -161,10 +211,199 @@ type EmbeddingUsage struct {
 	TotalTokens  int32 `json:"total_tokens"`
 }
 
+// TranscriptionRequest holds the form fields of /v1/audio/transcriptions
+type TranscriptionRequest struct {
+	Model          string
+	Language       string
+	Prompt         string
+	ResponseFormat string // "json", "text", "srt", "vtt", "verbose_json"
+	Filename       string // uploaded file name, used to infer the audio format
+}
+
+// TranscriptionResponse represents the json response from /v1/audio/transcriptions
+type TranscriptionResponse struct {
+	Text string `json:"text"`
+}
+
+// VerboseTranscriptionResponse represents the verbose_json response
+type VerboseTranscriptionResponse struct {
+	Task     string                 `json:"task"` // "transcribe"
+	Language string                 `json:"language,omitempty"`
+	Duration float64                `json:"duration"` // seconds
+	Text     string                 `json:"text"`
+	Segments []TranscriptionSegment `json:"segments"`
+}
+
+// TranscriptionSegment represents a timed segment in verbose_json
+type TranscriptionSegment struct {
+	ID    int     `json:"id"`
+	Start float64 `json:"start"` // seconds
+	End   float64 `json:"end"`   // seconds
+	Text  string  `json:"text"`
+}
+
+// SpeechRequest represents a request to /v1/audio/speech
+type SpeechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice,omitempty"`
+	ResponseFormat string  `json:"response_format,omitempty"` // "mp3", "wav", "opus", "ogg", "flac", "pcm"
+	Speed          float64 `json:"speed,omitempty"`           // 0.25-4.0, default 1.0
+}
+
+// ImageGenerationRequest represents a request to /v1/images/generations
+// (and the form fields of /v1/images/edits)
+type ImageGenerationRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n,omitempty"`               // 1-10, default 1
+	Size           string `json:"size,omitempty"`            // "WIDTHxHEIGHT", default "1024x1024"
+	ResponseFormat string `json:"response_format,omitempty"` // "url" (default) or "b64_json"
+	OutputFormat   string `json:"output_format,omitempty"`   // "png" (default), "jpeg", "webp"
+	Quality        string `json:"quality,omitempty"`         // passed to the backend as an option
+	Style          string `json:"style,omitempty"`           // passed to the backend as an option
+	User           string `json:"user,omitempty"`
+}
+
+// ImageResponse represents a response from the images endpoints
+type ImageResponse struct {
+	Created int64       `json:"created"`
+	Data    []ImageData `json:"data"`
+}
+
+// ImageData represents one generated image
+type ImageData struct {
+	URL           string `json:"url,omitempty"`
+	B64JSON       string `json:"b64_json,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+// VideoUploadRequest opens an upload session at /v1/video/uploads
+type VideoUploadRequest struct {
+	Filename string `json:"filename,omitempty"`
+	Size     int64  `json:"size"`             // total bytes
+	Format   string `json:"format,omitempty"` // "mp4", "webm", ... (default: the filename's extension)
+}
+
+// VideoUpload is the state of an upload session
+type VideoUpload struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"` // "video.upload"
+	Filename  string `json:"filename,omitempty"`
+	Format    string `json:"format"`
+	Size      int64  `json:"size"`
+	Received  int64  `json:"received"` // bytes stored; the next chunk starts here
+	Complete  bool   `json:"complete"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// VideoAnalysisRequest starts an analysis of a completed upload at
+// /v1/video/analyses
+type VideoAnalysisRequest struct {
+	UploadID string            `json:"upload_id"`
+	Model    string            `json:"model"`
+	Task     string            `json:"task,omitempty"` // "describe" (default), "transcribe", "caption", "track"
+	Options  map[string]string `json:"options,omitempty"`
+}
+
+// VideoAnalysis is the state of an analysis job, also sent as its
+// progress events
+type VideoAnalysis struct {
+	ID          string  `json:"id"`
+	Object      string  `json:"object"` // "video.analysis"
+	UploadID    string  `json:"upload_id"`
+	Model       string  `json:"model"`
+	Task        string  `json:"task"`
+	Status      string  `json:"status"`   // queued, running, succeeded, failed, cancelled
+	Progress    float64 `json:"progress"` // 0-1
+	Backend     string  `json:"backend,omitempty"`
+	Error       string  `json:"error,omitempty"`
+	ResultURL   string  `json:"result_url,omitempty"` // once succeeded
+	CreatedAt   int64   `json:"created_at"`
+	CompletedAt int64   `json:"completed_at,omitempty"`
+}
+
+// VideoAnalysisResult is the stored result of a succeeded analysis
+type VideoAnalysisResult struct {
+	ID       string               `json:"id"`
+	Model    string               `json:"model"`
+	Task     string               `json:"task"`
+	Text     string               `json:"text"`
+	Captions []VideoCaption       `json:"captions,omitempty"`
+	Objects  []VideoTrackedObject `json:"objects,omitempty"`
+}
+
+// VideoCaption is a time-aligned caption
+type VideoCaption struct {
+	Text       string  `json:"text"`
+	StartMs    int64   `json:"start_ms"`
+	EndMs      int64   `json:"end_ms"`
+	Confidence float32 `json:"confidence,omitempty"`
+}
+
+// VideoTrackedObject is an object tracked across frames
+type VideoTrackedObject struct {
+	Label      string             `json:"label"`
+	TrackID    int32              `json:"track_id"`
+	Confidence float32            `json:"confidence,omitempty"`
+	Frames     []VideoObjectFrame `json:"frames,omitempty"`
+}
+
+// VideoObjectFrame is a tracked object's bounding box in one frame
+type VideoObjectFrame struct {
+	Frame  int32 `json:"frame"`
+	TimeMs int64 `json:"time_ms"`
+	X      int32 `json:"x"`
+	Y      int32 `json:"y"`
+	Width  int32 `json:"width"`
+	Height int32 `json:"height"`
+}
+
+// VideoGenerationRequest starts a text-to-video job at
+// /v1/video/generations
+type VideoGenerationRequest struct {
+	Prompt   string            `json:"prompt"`
+	Model    string            `json:"model"`
+	Width    int32             `json:"width,omitempty"`
+	Height   int32             `json:"height,omitempty"`
+	FPS      int32             `json:"fps,omitempty"`
+	Duration int32             `json:"duration,omitempty"` // seconds
+	Format   string            `json:"format,omitempty"`   // e.g. "mp4"
+	Seed     int64             `json:"seed,omitempty"`     // 0 = random, fixed when the job starts so it can resume
+	Options  map[string]string `json:"options,omitempty"`
+}
+
+// VideoGeneration is the state of a generation job, also sent as its
+// progress events
+type VideoGeneration struct {
+	ID           string  `json:"id"`
+	Object       string  `json:"object"` // "video.generation"
+	Model        string  `json:"model"`
+	Prompt       string  `json:"prompt"`
+	Status       string  `json:"status"`   // queued, running, succeeded, failed, cancelled
+	Progress     float64 `json:"progress"` // 0-1
+	Backend      string  `json:"backend,omitempty"`
+	Frames       int32   `json:"frames"`             // frames generated and checkpointed
+	Previews     int     `json:"previews,omitempty"` // preview images received; the latest is at {id}/preview
+	Resumes      int     `json:"resumes,omitempty"`  // restarts from a checkpoint
+	Error        string  `json:"error,omitempty"`
+	ResultURL    string  `json:"result_url,omitempty"` // once succeeded
+	CreatedAt    int64   `json:"created_at"`
+	CheckpointAt int64   `json:"checkpoint_at,omitempty"`
+	CompletedAt  int64   `json:"completed_at,omitempty"`
+}
+
 // ModelsResponse represents a response from /v1/models
 type ModelsResponse struct {
-	Object string  `json:"object"` // "list"
-	Data   []Model `json:"data"`
+	Object   string          `json:"object"` // "list"
+	Data     []Model         `json:"data"`
+	Metadata *ModelsMetadata `json:"metadata,omitempty"`
+}
+
+// ModelsMetadata carries proxy notices alongside the models list
+type ModelsMetadata struct {
+	Banner      string `json:"banner,omitempty"`
+	Maintenance bool   `json:"maintenance,omitempty"`
 }
 
 // Model represents a model in the models list
@@ -189,4 +428,7 @@ type ErrorDetail struct {
 	Type    string `json:"type"`
 	Param   string `json:"param,omitempty"`
 	Code    string `json:"code,omitempty"`
+
+	// RequestID is the X-Request-ID the response carries, for reports
+	RequestID string `json:"request_id,omitempty"`
 }
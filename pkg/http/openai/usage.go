@@ -0,0 +1,14 @@
+package openai
+
+import (
+	"context"
+
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+)
+
+// newUsageTracker starts accounting for a request in the usage recorder
+// and the audit log
+func newUsageTracker(ctx context.Context, decision *router.RoutingDecision, endpoint, model, prompt string) *usage.Tracker {
+	return usage.Track(ctx, decision, endpoint, model, prompt)
+}
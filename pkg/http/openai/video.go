@@ -0,0 +1,458 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/google/uuid"
+)
+
+// Video endpoints. Uploads, analyses and generations are addressed below
+// these paths, e.g. /v1/video/uploads/{id} and /v1/video/analyses/{id}/events.
+const (
+	VideoUploadsPath     = "/v1/video/uploads"
+	VideoAnalysesPath    = "/v1/video/analyses"
+	VideoGenerationsPath = "/v1/video/generations"
+)
+
+// Video store defaults
+const (
+	DefaultMaxVideoUploadBytes   = 8 * 1024 * 1024 * 1024
+	DefaultMaxVideoStoreBytes    = 4 * DefaultMaxVideoUploadBytes // all uploads together
+	DefaultMaxVideoUploadsPerKey = 4
+	DefaultVideoUploadTTL        = 24 * time.Hour // since the last chunk
+	DefaultVideoResultTTL        = 24 * time.Hour // since the analysis ended
+	MaxVideoChunkBytes           = 64 * 1024 * 1024
+	DefaultVideoCheckpoint       = 5 * time.Second
+)
+
+// VideoStoreConfig configures a VideoStore. Zero values use the defaults.
+type VideoStoreConfig struct {
+	Dir              string        // uploads and results (default: a directory under os.TempDir)
+	MaxUploadBytes   int64         // largest video accepted
+	MaxTotalBytes    int64         // disk reserved by all open uploads together
+	MaxUploadsPerKey int           // open uploads per API key (or per address without auth)
+	UploadTTL        time.Duration // idle uploads are removed after this
+	ResultTTL        time.Duration // finished analyses and their results are removed after this
+
+	// How often a running generation records its progress, so it resumes
+	// from there after a restart
+	CheckpointInterval time.Duration
+}
+
+// VideoStore holds resumable video uploads and the analyses run on them.
+// Videos too large for a request body are uploaded in chunks to a file
+// under Dir, then analyzed in the background with progress reported as
+// server-sent events and the result kept as a JSON file. It also runs
+// text-to-video generations, checkpointed under Dir so they outlive the
+// process.
+type VideoStore struct {
+	cfg VideoStoreConfig
+
+	mu          sync.Mutex
+	uploads     map[string]*videoUpload
+	analyses    map[string]*videoAnalysis
+	generations map[string]*videoGeneration
+
+	generating sync.WaitGroup // running generations
+}
+
+// videoUpload is an upload session. mu serializes chunks and deletion.
+type videoUpload struct {
+	mu        sync.Mutex
+	info      VideoUpload // Received and Complete guarded by VideoStore.mu
+	owner     string      // see videoOwner; only the owner may use the session
+	path      string
+	expiresAt time.Time
+}
+
+// videoOwner identifies who an upload belongs to: the API key, or the
+// client address when the request is unauthenticated
+func videoOwner(req *http.Request) string {
+	if info, ok := auth.KeyInfoFromContext(req.Context()); ok {
+		return "key:" + info.ClientID()
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// NewVideoStore creates a video store, creating its directory
+func NewVideoStore(cfg VideoStoreConfig) (*VideoStore, error) {
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(os.TempDir(), "ollama-proxy-video")
+	}
+	if cfg.MaxUploadBytes <= 0 {
+		cfg.MaxUploadBytes = DefaultMaxVideoUploadBytes
+	}
+	if cfg.MaxTotalBytes <= 0 {
+		cfg.MaxTotalBytes = max(DefaultMaxVideoStoreBytes, cfg.MaxUploadBytes)
+	}
+	if cfg.MaxUploadsPerKey <= 0 {
+		cfg.MaxUploadsPerKey = DefaultMaxVideoUploadsPerKey
+	}
+	if cfg.UploadTTL <= 0 {
+		cfg.UploadTTL = DefaultVideoUploadTTL
+	}
+	if cfg.ResultTTL <= 0 {
+		cfg.ResultTTL = DefaultVideoResultTTL
+	}
+	if cfg.CheckpointInterval <= 0 {
+		cfg.CheckpointInterval = DefaultVideoCheckpoint
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating video directory: %w", err)
+	}
+	return &VideoStore{
+		cfg:         cfg,
+		uploads:     make(map[string]*videoUpload),
+		analyses:    make(map[string]*videoAnalysis),
+		generations: make(map[string]*videoGeneration),
+	}, nil
+}
+
+// evictLocked removes expired uploads and finished jobs with their files
+func (s *VideoStore) evictLocked(now time.Time) {
+	for id, up := range s.uploads {
+		if now.After(up.expiresAt) {
+			delete(s.uploads, id)
+			os.Remove(up.path)
+		}
+	}
+	for id, a := range s.analyses {
+		if a.finished() && now.After(a.expiresAt) {
+			delete(s.analyses, id)
+			os.Remove(a.resultPath)
+		}
+	}
+	for id, g := range s.generations {
+		if g.expired(now) {
+			delete(s.generations, id)
+			g.removeFiles()
+		}
+	}
+}
+
+// upload returns an upload session of owner that has not expired. Other
+// owners' sessions are reported as missing, so their IDs can't be probed.
+func (s *VideoStore) upload(id, owner string) (*videoUpload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictLocked(time.Now())
+	up, ok := s.uploads[id]
+	if !ok || up.owner != owner {
+		return nil, false
+	}
+	return up, true
+}
+
+// uploadInfo returns a copy of an upload's state
+func (s *VideoStore) uploadInfo(up *videoUpload) VideoUpload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := up.info
+	info.ExpiresAt = up.expiresAt.Unix()
+	return info
+}
+
+// HandleVideoUploads serves upload sessions:
+//
+//	POST   /v1/video/uploads       open a session (VideoUploadRequest)
+//	PUT    /v1/video/uploads/{id}  store a chunk at its Content-Range
+//	GET    /v1/video/uploads/{id}  the session state, to resume from Received
+//	DELETE /v1/video/uploads/{id}  discard the upload
+//
+// Chunks must arrive in order: a chunk that does not start at Received is
+// rejected with 409 and the current state, so an interrupted client
+// resumes where the stored bytes end.
+func (s *VideoStore) HandleVideoUploads() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(req.URL.Path, VideoUploadsPath), "/")
+		if id == "" {
+			if req.Method != http.MethodPost {
+				writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+				return
+			}
+			s.createUpload(w, req)
+			return
+		}
+
+		up, ok := s.upload(id, videoOwner(req))
+		if !ok {
+			writeError(w, http.StatusNotFound, "Upload not found or expired", "not_found")
+			return
+		}
+		switch req.Method {
+		case http.MethodGet, http.MethodHead:
+			writeJSON(w, http.StatusOK, s.uploadInfo(up))
+		case http.MethodPut:
+			s.writeChunk(w, req, up)
+		case http.MethodDelete:
+			// Wait for a chunk being written before removing its file
+			up.mu.Lock()
+			s.mu.Lock()
+			delete(s.uploads, id)
+			s.mu.Unlock()
+			os.Remove(up.path)
+			up.mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+		}
+	}
+}
+
+func (s *VideoStore) createUpload(w http.ResponseWriter, req *http.Request) {
+	var upReq VideoUploadRequest
+	if err := json.NewDecoder(io.LimitReader(req.Body, 64*1024)).Decode(&upReq); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	if upReq.Size <= 0 {
+		writeError(w, http.StatusBadRequest, "size is required", "invalid_request_error")
+		return
+	}
+	if upReq.Size > s.cfg.MaxUploadBytes {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Videos must be under %d MB", s.cfg.MaxUploadBytes/(1024*1024)), "invalid_request_error")
+		return
+	}
+	format := strings.ToLower(upReq.Format)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(upReq.Filename)), ".")
+	}
+	if format == "" {
+		writeError(w, http.StatusBadRequest, "format is required when the filename has no extension", "invalid_request_error")
+		return
+	}
+
+	filename := ""
+	if upReq.Filename != "" {
+		filename = filepath.Base(upReq.Filename)
+	}
+
+	// Reserve the declared size up front, so accepted uploads always fit
+	id := uuid.New().String()
+	up := &videoUpload{
+		info: VideoUpload{
+			ID:       id,
+			Object:   "video.upload",
+			Filename: filename,
+			Format:   format,
+			Size:     upReq.Size,
+		},
+		owner:     videoOwner(req),
+		path:      filepath.Join(s.cfg.Dir, id+".video"),
+		expiresAt: time.Now().Add(s.cfg.UploadTTL),
+	}
+	if status, message := s.reserveUpload(up); status != 0 {
+		code := "insufficient_storage"
+		if status == http.StatusTooManyRequests {
+			code = "rate_limit_exceeded"
+		}
+		writeError(w, status, message, code)
+		return
+	}
+
+	f, err := os.OpenFile(up.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		s.mu.Lock()
+		delete(s.uploads, id)
+		s.mu.Unlock()
+		writeError(w, http.StatusInternalServerError, "Failed to create upload", "internal_error")
+		return
+	}
+	f.Close()
+
+	w.Header().Set("Location", VideoUploadsPath+"/"+id)
+	writeJSON(w, http.StatusCreated, s.uploadInfo(up))
+}
+
+// reserveUpload registers up if its owner is below MaxUploadsPerKey and
+// its size fits in MaxTotalBytes alongside the other open uploads. It
+// returns the status to refuse with, or 0.
+func (s *VideoStore) reserveUpload(up *videoUpload) (int, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictLocked(time.Now())
+
+	reserved, sessions := int64(0), 0
+	for _, other := range s.uploads {
+		reserved += other.info.Size
+		if other.owner == up.owner {
+			sessions++
+		}
+	}
+	if sessions >= s.cfg.MaxUploadsPerKey {
+		return http.StatusTooManyRequests,
+			fmt.Sprintf("Too many open uploads (limit %d): delete one first", s.cfg.MaxUploadsPerKey)
+	}
+	if reserved+up.info.Size > s.cfg.MaxTotalBytes {
+		return http.StatusInsufficientStorage, "Not enough upload space left, try again later"
+	}
+	s.uploads[up.info.ID] = up
+	return 0, ""
+}
+
+// writeChunk stores the chunk in the request body. The chunk is kept only
+// if it arrives whole, so Received always ends on a chunk boundary.
+func (s *VideoStore) writeChunk(w http.ResponseWriter, req *http.Request, up *videoUpload) {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+
+	info := s.uploadInfo(up)
+	start, end, total, err := parseContentRange(req.Header.Get("Content-Range"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "invalid_request_error")
+		return
+	}
+	if total != info.Size || end >= info.Size {
+		writeError(w, http.StatusRequestedRangeNotSatisfiable,
+			fmt.Sprintf("Content-Range exceeds the upload size %d", info.Size), "invalid_request_error")
+		return
+	}
+	if start != info.Received {
+		// Out of order or a retry of a stored chunk: tell the client where to resume
+		writeJSON(w, http.StatusConflict, info)
+		return
+	}
+	length := end - start + 1
+	if length > MaxVideoChunkBytes {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Chunks must be under %d MB", MaxVideoChunkBytes/(1024*1024)), "invalid_request_error")
+		return
+	}
+
+	f, err := os.OpenFile(up.path, os.O_WRONLY, 0)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to open upload", "internal_error")
+		return
+	}
+	defer f.Close()
+
+	written, err := io.Copy(io.NewOffsetWriter(f, start), io.LimitReader(req.Body, length))
+	if err == nil && written < length {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		f.Truncate(start)
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Incomplete chunk (%d of %d bytes): resume from %d", written, length, start), "invalid_request_error")
+		return
+	}
+
+	s.mu.Lock()
+	up.info.Received = end + 1
+	up.info.Complete = up.info.Received == up.info.Size
+	up.expiresAt = time.Now().Add(s.cfg.UploadTTL)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, s.uploadInfo(up))
+}
+
+// parseContentRange parses "bytes START-END/TOTAL"
+func parseContentRange(header string) (start, end, total int64, err error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if ok {
+		rng, size, okSize := strings.Cut(spec, "/")
+		first, last, okRange := strings.Cut(rng, "-")
+		if okSize && okRange {
+			start, errStart := strconv.ParseInt(first, 10, 64)
+			end, errEnd := strconv.ParseInt(last, 10, 64)
+			total, errTotal := strconv.ParseInt(size, 10, 64)
+			if errStart == nil && errEnd == nil && errTotal == nil && start >= 0 && end >= start {
+				return start, end, total, nil
+			}
+		}
+	}
+	return 0, 0, 0, errors.New("Content-Range must be \"bytes START-END/TOTAL\"")
+}
+
+// jobFeed sends a background job's state to its subscribers, such as event
+// streams, on every change. Subscribers that are not keeping up miss
+// intermediate states, never the final one, after which their channels are
+// closed. Jobs publish and subscribe with their own lock held, so no change
+// falls between reading the state and subscribing.
+type jobFeed[T any] struct {
+	mu   sync.Mutex
+	subs map[chan T]struct{}
+}
+
+// publish sends state to the subscribers, closing their channels if it is
+// final
+func (f *jobFeed[T]) publish(state T, final bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		if final {
+			// Make room so the final state always arrives
+			select {
+			case <-ch:
+			default:
+			}
+		}
+		select {
+		case ch <- state:
+		default:
+		}
+		if final {
+			close(ch)
+		}
+	}
+	if final {
+		f.subs = nil
+	}
+}
+
+// subscribe returns a channel of later states and a function to stop
+// receiving them
+func (f *jobFeed[T]) subscribe() (<-chan T, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan T, 16)
+	if f.subs == nil {
+		f.subs = make(map[chan T]struct{})
+	}
+	f.subs[ch] = struct{}{}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[ch]; ok {
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Close cancels the analyses in progress and stops the generations,
+// waiting for them to checkpoint so they resume on the next start
+func (s *VideoStore) Close() {
+	s.mu.Lock()
+	for _, a := range s.analyses {
+		a.cancel()
+	}
+	for _, g := range s.generations {
+		g.cancel()
+	}
+	s.mu.Unlock()
+	s.generating.Wait()
+}
@@ -0,0 +1,433 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Video analysis statuses
+const (
+	VideoStatusQueued    = "queued"
+	VideoStatusRunning   = "running"
+	VideoStatusSucceeded = "succeeded"
+	VideoStatusFailed    = "failed"
+	VideoStatusCancelled = "cancelled"
+)
+
+// VideoProgressInterval is how often a running analysis reports progress
+var VideoProgressInterval = time.Second
+
+// videoHeartbeatInterval keeps idle event streams open through proxies
+const videoHeartbeatInterval = 15 * time.Second
+
+// videoAnalysis is an analysis job. Its state is sent to subscribers on
+// every change; their channels are closed when it finishes.
+type videoAnalysis struct {
+	mu         sync.Mutex
+	info       VideoAnalysis
+	feed       jobFeed[VideoAnalysis]
+	cancel     context.CancelFunc
+	resultPath string
+	expiresAt  time.Time // once finished
+}
+
+func (a *videoAnalysis) snapshot() VideoAnalysis {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.info
+}
+
+func (a *videoAnalysis) finished() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return isFinalVideoStatus(a.info.Status)
+}
+
+func isFinalVideoStatus(status string) bool {
+	return status == VideoStatusSucceeded || status == VideoStatusFailed || status == VideoStatusCancelled
+}
+
+// update changes the job's state and publishes it
+func (a *videoAnalysis) update(fn func(*VideoAnalysis)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if isFinalVideoStatus(a.info.Status) {
+		return
+	}
+	fn(&a.info)
+	a.feed.publish(a.info, isFinalVideoStatus(a.info.Status))
+}
+
+// subscribe returns the current state and a channel of later ones, closed
+// once the job finishes (nil if it already has)
+func (a *videoAnalysis) subscribe() (VideoAnalysis, <-chan VideoAnalysis, func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if isFinalVideoStatus(a.info.Status) {
+		return a.info, nil, func() {}
+	}
+	ch, unsubscribe := a.feed.subscribe()
+	return a.info, ch, unsubscribe
+}
+
+// analysis returns an analysis that has not expired
+func (s *VideoStore) analysis(id string) (*videoAnalysis, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictLocked(time.Now())
+	a, ok := s.analyses[id]
+	return a, ok
+}
+
+// HandleVideoAnalyses serves analyses of completed uploads:
+//
+//	POST   /v1/video/analyses              start one (VideoAnalysisRequest), 202
+//	GET    /v1/video/analyses/{id}         its state
+//	GET    /v1/video/analyses/{id}/events  its state as server-sent events until it ends
+//	GET    /v1/video/analyses/{id}/result  the stored VideoAnalysisResult
+//	DELETE /v1/video/analyses/{id}         cancel it, or discard its result
+//
+// Analyses are routed like other requests, to a backend that supports
+// video analysis, and run in the background so multi-gigabyte videos
+// don't hold a request open.
+func (s *VideoStore) HandleVideoAnalyses(r *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(req.URL.Path, VideoAnalysesPath), "/")
+		if rest == "" {
+			if req.Method != http.MethodPost {
+				writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+				return
+			}
+			s.startAnalysis(w, req, r)
+			return
+		}
+
+		id, sub, _ := strings.Cut(rest, "/")
+		a, ok := s.analysis(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, "Analysis not found or expired", "not_found")
+			return
+		}
+
+		switch {
+		case sub == "" && req.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, a.snapshot())
+		case sub == "" && req.Method == http.MethodDelete:
+			a.cancel()
+			if a.finished() {
+				s.mu.Lock()
+				delete(s.analyses, id)
+				s.mu.Unlock()
+				os.Remove(a.resultPath)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case sub == "events" && req.Method == http.MethodGet:
+			serveVideoEvents(w, req, a)
+		case sub == "result" && req.Method == http.MethodGet:
+			if info := a.snapshot(); info.Status != VideoStatusSucceeded {
+				writeJSON(w, http.StatusConflict, info)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			http.ServeFile(w, req, a.resultPath)
+		case sub == "" || sub == "events" || sub == "result":
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+		default:
+			writeError(w, http.StatusNotFound, "Not found", "not_found")
+		}
+	}
+}
+
+func (s *VideoStore) startAnalysis(w http.ResponseWriter, req *http.Request, r *router.Router) {
+	var aReq VideoAnalysisRequest
+	if err := json.NewDecoder(io.LimitReader(req.Body, 64*1024)).Decode(&aReq); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	if aReq.Model == "" {
+		writeError(w, http.StatusBadRequest, "Model is required", "invalid_request_error")
+		return
+	}
+	if aReq.Task == "" {
+		aReq.Task = "describe"
+	}
+	up, ok := s.upload(aReq.UploadID, videoOwner(req))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Upload not found or expired", "not_found")
+		return
+	}
+	upload := s.uploadInfo(up)
+	if !upload.Complete {
+		writeJSON(w, http.StatusConflict, upload)
+		return
+	}
+
+	// Parse routing headers now; the analysis outlives the request
+	annotations := ParseRoutingHeaders(req)
+	if annotations.MediaType == "" {
+		annotations.MediaType = backends.MediaTypeVideo
+	}
+	annotations.Capability = backends.CapabilityVideoToText
+
+	id := uuid.New().String()
+	// Keep the request's identity (key, tenant, labels) for usage records
+	ctx, cancel := context.WithCancel(context.WithoutCancel(req.Context()))
+	a := &videoAnalysis{
+		info: VideoAnalysis{
+			ID:        id,
+			Object:    "video.analysis",
+			UploadID:  upload.ID,
+			Model:     aReq.Model,
+			Task:      aReq.Task,
+			Status:    VideoStatusQueued,
+			CreatedAt: time.Now().Unix(),
+		},
+		cancel:     cancel,
+		resultPath: filepath.Join(s.cfg.Dir, id+".result.json"),
+	}
+
+	s.mu.Lock()
+	s.evictLocked(time.Now())
+	s.analyses[id] = a
+	// The video is analyzed from its file; keep it while the analysis runs
+	up.expiresAt = time.Now().Add(s.cfg.UploadTTL)
+	s.mu.Unlock()
+
+	go s.runAnalysis(ctx, r, a, annotations, up.path, upload, &aReq)
+
+	w.Header().Set("Location", VideoAnalysesPath+"/"+id)
+	writeJSON(w, http.StatusAccepted, a.snapshot())
+}
+
+// runAnalysis routes the analysis and runs it on the uploaded file
+func (s *VideoStore) runAnalysis(ctx context.Context, r *router.Router, a *videoAnalysis,
+	annotations *backends.Annotations, path string, upload VideoUpload, aReq *VideoAnalysisRequest) {
+	defer a.cancel()
+
+	result, err := s.analyze(ctx, r, a, annotations, path, upload, aReq)
+	if err == nil {
+		err = writeJSONFile(a.resultPath, result)
+	}
+
+	// Set before finishing, so eviction never sees a finished analysis
+	// without its expiry
+	s.mu.Lock()
+	a.expiresAt = time.Now().Add(s.cfg.ResultTTL)
+	s.mu.Unlock()
+
+	a.update(func(info *VideoAnalysis) {
+		info.CompletedAt = time.Now().Unix()
+		switch {
+		case err == nil:
+			info.Status = VideoStatusSucceeded
+			info.Progress = 1
+			info.ResultURL = VideoAnalysesPath + "/" + info.ID + "/result"
+		case ctx.Err() != nil:
+			info.Status = VideoStatusCancelled
+		default:
+			info.Status = VideoStatusFailed
+			info.Error = err.Error()
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		logging.Logger.Warn("Video analysis failed", zap.String("id", a.info.ID), zap.Error(err))
+	}
+}
+
+func (s *VideoStore) analyze(ctx context.Context, r *router.Router, a *videoAnalysis,
+	annotations *backends.Annotations, path string, upload VideoUpload, aReq *VideoAnalysisRequest) (*VideoAnalysisResult, error) {
+	decision, err := r.RouteRequest(ctx, annotations)
+	if err != nil {
+		return nil, fmt.Errorf("routing failed: %w", err)
+	}
+	// An explicit target bypasses capability filtering
+	if !decision.Backend.SupportsVideoToText() {
+		return nil, fmt.Errorf("backend %s does not support video analysis", decision.Backend.ID())
+	}
+	if !decision.Backend.SupportsModel(aReq.Model) {
+		return nil, fmt.Errorf("model %s not available", aReq.Model)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening upload: %w", err)
+	}
+	defer f.Close()
+
+	// Progress is how much of the video the backend has read, unless it
+	// reports its own
+	video := &countingReader{r: f}
+	var reported atomic.Uint32 // backend-reported progress, in 1/10000
+	a.update(func(info *VideoAnalysis) {
+		info.Status = VideoStatusRunning
+		info.Backend = decision.Backend.ID()
+	})
+	stopProgress := make(chan struct{})
+	defer close(stopProgress)
+	go func() {
+		ticker := time.NewTicker(VideoProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopProgress:
+				return
+			case <-ticker.C:
+				progress := float64(video.n.Load()) / float64(upload.Size)
+				if p := float64(reported.Load()) / 10000; p > 0 {
+					progress = p
+				}
+				a.update(func(info *VideoAnalysis) { info.Progress = min(progress, 0.99) })
+			}
+		}
+	}()
+
+	tracker := newUsageTracker(ctx, decision, VideoAnalysesPath, aReq.Model, "")
+	req := &backends.VideoAnalysisRequest{
+		VideoStream: video,
+		Model:       aReq.Model,
+		Task:        aReq.Task,
+		Format:      backends.VideoFormat(upload.Format),
+		Options:     aReq.Options,
+	}
+
+	result := &VideoAnalysisResult{ID: a.info.ID, Model: aReq.Model, Task: aReq.Task}
+	stream, err := decision.Backend.AnalyzeVideoStream(ctx, req)
+	if err == nil {
+		defer stream.Close()
+		var text strings.Builder
+		for {
+			chunk, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				tracker.Finish(0, nil, err)
+				return nil, fmt.Errorf("video analysis failed: %w", err)
+			}
+			text.WriteString(chunk.Text)
+			if chunk.Progress > 0 {
+				reported.Store(uint32(chunk.Progress * 10000))
+			}
+			if chunk.Done {
+				break
+			}
+		}
+		result.Text = text.String()
+		tracker.Finish(estimateTokens(result.Text), nil, nil)
+		return result, nil
+	}
+
+	// No streaming analysis: run it whole, from the start of the file
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	video.n.Store(0)
+	resp, err := decision.Backend.AnalyzeVideo(ctx, req)
+	if err != nil {
+		tracker.Finish(0, nil, err)
+		return nil, fmt.Errorf("video analysis failed: %w", err)
+	}
+	tracker.Finish(estimateTokens(resp.Text), resp.Stats, nil)
+
+	result.Text = resp.Text
+	for _, c := range resp.Captions {
+		result.Captions = append(result.Captions, VideoCaption{Text: c.Text, StartMs: c.StartMs, EndMs: c.EndMs, Confidence: c.Confidence})
+	}
+	for _, o := range resp.Objects {
+		obj := VideoTrackedObject{Label: o.Label, TrackID: o.TrackID, Confidence: o.Confidence}
+		for _, fr := range o.Frames {
+			obj.Frames = append(obj.Frames, VideoObjectFrame{
+				Frame: fr.FrameNum, TimeMs: fr.TimeMs,
+				X: fr.BBoxX, Y: fr.BBoxY, Width: fr.BBoxWidth, Height: fr.BBoxHeight,
+			})
+		}
+		result.Objects = append(result.Objects, obj)
+	}
+	return result, nil
+}
+
+// writeJSONFile stores v as JSON, replacing the file atomically
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("storing %s: %w", filepath.Base(path), err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// serveVideoEvents streams an analysis's state as server-sent events until
+// it finishes
+func serveVideoEvents(w http.ResponseWriter, req *http.Request, a *videoAnalysis) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming not supported", "internal_error")
+		return
+	}
+	current, updates, unsubscribe := a.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(info VideoAnalysis) {
+		data, _ := json.Marshal(info)
+		fmt.Fprintf(w, "event: video_analysis\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+	send(current)
+	if updates == nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(videoHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case info, ok := <-updates:
+			if !ok {
+				return
+			}
+			send(info)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
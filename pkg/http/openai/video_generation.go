@@ -0,0 +1,570 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// VideoResumeOption is the generation option telling a backend which frame
+// to continue from when a job resumes from a checkpoint. Chunks for frames
+// already stored are skipped, so backends that ignore it regenerate them
+// but the stored video is unaffected.
+const VideoResumeOption = "resume_from_frame"
+
+// generationStateSuffix names the checkpoint file of a generation
+const generationStateSuffix = ".generation.json"
+
+// generationState is a generation's checkpoint file
+type generationState struct {
+	Info        VideoGeneration        `json:"info"`
+	Request     VideoGenerationRequest `json:"request"`
+	Annotations *backends.Annotations  `json:"annotations"`
+	KeyName     string                 `json:"key_name,omitempty"`
+	Tenant      string                 `json:"tenant,omitempty"`
+	Bytes       int64                  `json:"bytes"`                // video stored up to Info.Frames
+	ExpiresAt   int64                  `json:"expires_at,omitempty"` // once finished
+}
+
+// videoGeneration is a generation job. Like an analysis, its state is sent
+// to subscribers on every change and their channels are closed when it
+// finishes.
+type videoGeneration struct {
+	mu        sync.Mutex
+	state     generationState
+	feed      jobFeed[VideoGeneration]
+	preview   []byte // latest preview image
+	expiresAt time.Time
+
+	cancel    context.CancelFunc
+	cancelled atomic.Bool // by the client, as opposed to a shutdown
+
+	videoPath string
+	statePath string
+}
+
+func (g *videoGeneration) snapshot() VideoGeneration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state.Info
+}
+
+func (g *videoGeneration) finished() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return isFinalVideoStatus(g.state.Info.Status)
+}
+
+func (g *videoGeneration) expired(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return isFinalVideoStatus(g.state.Info.Status) && now.After(g.expiresAt)
+}
+
+func (g *videoGeneration) removeFiles() {
+	os.Remove(g.videoPath)
+	os.Remove(g.statePath)
+}
+
+// update changes the job's state under its lock and publishes it
+func (g *videoGeneration) update(fn func(*generationState)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if isFinalVideoStatus(g.state.Info.Status) {
+		return
+	}
+	fn(&g.state)
+	g.feed.publish(g.state.Info, isFinalVideoStatus(g.state.Info.Status))
+}
+
+// subscribe returns the current state and a channel of later ones, closed
+// once the job finishes (nil if it already has)
+func (g *videoGeneration) subscribe() (VideoGeneration, <-chan VideoGeneration, func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if isFinalVideoStatus(g.state.Info.Status) {
+		return g.state.Info, nil, func() {}
+	}
+	ch, unsubscribe := g.feed.subscribe()
+	return g.state.Info, ch, unsubscribe
+}
+
+// latestPreview returns the latest preview image, nil if none arrived
+func (g *videoGeneration) latestPreview() []byte {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.preview
+}
+
+// checkpoint writes the job's state file
+func (g *videoGeneration) checkpoint() error {
+	g.mu.Lock()
+	g.state.Info.CheckpointAt = time.Now().Unix()
+	state := g.state
+	g.mu.Unlock()
+	return writeJSONFile(g.statePath, state)
+}
+
+// generation returns a generation that has not expired
+func (s *VideoStore) generation(id string) (*videoGeneration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictLocked(time.Now())
+	g, ok := s.generations[id]
+	return g, ok
+}
+
+// HandleVideoGenerations serves text-to-video jobs:
+//
+//	POST   /v1/video/generations              start one (VideoGenerationRequest), 202
+//	GET    /v1/video/generations/{id}         its state
+//	GET    /v1/video/generations/{id}/events  its state and preview images as server-sent events until it ends
+//	GET    /v1/video/generations/{id}/preview the latest preview image
+//	GET    /v1/video/generations/{id}/result  the generated video
+//	DELETE /v1/video/generations/{id}         cancel it, or discard its video
+//
+// Generations run for minutes on local hardware, so they run in the
+// background and checkpoint the frames received; after a restart,
+// ResumeGenerations continues them from the last checkpoint.
+func (s *VideoStore) HandleVideoGenerations(r *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(req.URL.Path, VideoGenerationsPath), "/")
+		if rest == "" {
+			if req.Method != http.MethodPost {
+				writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+				return
+			}
+			s.startGeneration(w, req, r)
+			return
+		}
+
+		id, sub, _ := strings.Cut(rest, "/")
+		g, ok := s.generation(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, "Generation not found or expired", "not_found")
+			return
+		}
+
+		switch {
+		case sub == "" && req.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, g.snapshot())
+		case sub == "" && req.Method == http.MethodDelete:
+			g.cancelled.Store(true)
+			g.cancel()
+			if g.finished() {
+				s.mu.Lock()
+				delete(s.generations, id)
+				s.mu.Unlock()
+				g.removeFiles()
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case sub == "events" && req.Method == http.MethodGet:
+			serveGenerationEvents(w, req, g)
+		case sub == "preview" && req.Method == http.MethodGet:
+			preview := g.latestPreview()
+			if preview == nil {
+				writeError(w, http.StatusNotFound, "No preview yet", "not_found")
+				return
+			}
+			w.Header().Set("Content-Type", http.DetectContentType(preview))
+			w.Write(preview)
+		case sub == "result" && req.Method == http.MethodGet:
+			if info := g.snapshot(); info.Status != VideoStatusSucceeded {
+				writeJSON(w, http.StatusConflict, info)
+				return
+			}
+			w.Header().Set("Content-Type", "video/"+g.state.Request.Format)
+			http.ServeFile(w, req, g.videoPath)
+		case sub == "" || sub == "events" || sub == "preview" || sub == "result":
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "method_not_allowed")
+		default:
+			writeError(w, http.StatusNotFound, "Not found", "not_found")
+		}
+	}
+}
+
+func (s *VideoStore) startGeneration(w http.ResponseWriter, req *http.Request, r *router.Router) {
+	var gReq VideoGenerationRequest
+	if err := json.NewDecoder(io.LimitReader(req.Body, 64*1024)).Decode(&gReq); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err), "invalid_request_error")
+		return
+	}
+	if gReq.Model == "" {
+		writeError(w, http.StatusBadRequest, "Model is required", "invalid_request_error")
+		return
+	}
+	if gReq.Prompt == "" {
+		writeError(w, http.StatusBadRequest, "Prompt is required", "invalid_request_error")
+		return
+	}
+	if gReq.Width < 0 || gReq.Height < 0 || gReq.FPS < 0 || gReq.Duration < 0 {
+		writeError(w, http.StatusBadRequest, "width, height, fps and duration cannot be negative", "invalid_request_error")
+		return
+	}
+	gReq.Format = strings.ToLower(gReq.Format)
+	if gReq.Format == "" {
+		gReq.Format = string(backends.VideoFormatMP4)
+	}
+	// A resumed job must continue the same video
+	if gReq.Seed == 0 {
+		gReq.Seed = rand.Int63()
+	}
+
+	// Parse routing headers now; the generation outlives the request
+	annotations := ParseRoutingHeaders(req)
+	if annotations.MediaType == "" {
+		annotations.MediaType = backends.MediaTypeVideo
+	}
+	annotations.Capability = backends.CapabilityTextToVideo
+
+	id := uuid.New().String()
+	state := generationState{
+		Info: VideoGeneration{
+			ID:        id,
+			Object:    "video.generation",
+			Model:     gReq.Model,
+			Prompt:    gReq.Prompt,
+			Status:    VideoStatusQueued,
+			CreatedAt: time.Now().Unix(),
+		},
+		Request:     gReq,
+		Annotations: annotations,
+	}
+	if key, ok := auth.KeyInfoFromContext(req.Context()); ok {
+		state.KeyName, state.Tenant = key.Name, key.Tenant
+	}
+	g := s.newGeneration(state)
+	if err := g.checkpoint(); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to store generation", "internal_error")
+		return
+	}
+
+	// Keep the request's identity (key, tenant, labels) for usage records
+	s.runGeneration(context.WithoutCancel(req.Context()), r, g)
+
+	w.Header().Set("Location", VideoGenerationsPath+"/"+id)
+	writeJSON(w, http.StatusAccepted, g.snapshot())
+}
+
+func (s *VideoStore) newGeneration(state generationState) *videoGeneration {
+	return &videoGeneration{
+		state:     state,
+		videoPath: filepath.Join(s.cfg.Dir, state.Info.ID+".generated"),
+		statePath: filepath.Join(s.cfg.Dir, state.Info.ID+generationStateSuffix),
+	}
+}
+
+// ResumeGenerations loads the generations checkpointed under Dir, resuming
+// those a shutdown interrupted. It returns how many were resumed.
+func (s *VideoStore) ResumeGenerations(r *router.Router) int {
+	paths, _ := filepath.Glob(filepath.Join(s.cfg.Dir, "*"+generationStateSuffix))
+	resumed := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		var state generationState
+		if err == nil {
+			err = json.Unmarshal(data, &state)
+		}
+		if err != nil || state.Info.ID == "" {
+			if logging.Logger != nil {
+				logging.Logger.Warn("Skipping unreadable video generation checkpoint", zap.String("path", path), zap.Error(err))
+			}
+			continue
+		}
+
+		g := s.newGeneration(state)
+		if isFinalVideoStatus(state.Info.Status) {
+			// Kept for download until it expires
+			g.expiresAt = time.Unix(state.ExpiresAt, 0)
+			g.cancel = func() {}
+			s.mu.Lock()
+			s.generations[state.Info.ID] = g
+			s.mu.Unlock()
+			continue
+		}
+
+		g.state.Info.Status = VideoStatusQueued
+		g.state.Info.Resumes++
+		ctx := context.Background()
+		if state.KeyName != "" {
+			ctx = auth.WithKeyInfo(ctx, auth.APIKeyInfo{Name: state.KeyName, Tenant: state.Tenant})
+		}
+		s.runGeneration(ctx, r, g)
+		resumed++
+		if logging.Logger != nil {
+			logging.Logger.Info("Resuming video generation",
+				zap.String("id", state.Info.ID),
+				zap.Int32("frames", state.Info.Frames),
+			)
+		}
+	}
+
+	s.mu.Lock()
+	s.evictLocked(time.Now())
+	s.mu.Unlock()
+	return resumed
+}
+
+// runGeneration registers a job and runs it in the background
+func (s *VideoStore) runGeneration(ctx context.Context, r *router.Router, g *videoGeneration) {
+	ctx, g.cancel = context.WithCancel(ctx)
+	s.mu.Lock()
+	s.evictLocked(time.Now())
+	s.generations[g.state.Info.ID] = g
+	s.mu.Unlock()
+
+	s.generating.Add(1)
+	go func() {
+		defer s.generating.Done()
+		defer g.cancel()
+
+		err := s.generate(ctx, r, g)
+		if err != nil && ctx.Err() != nil && !g.cancelled.Load() {
+			// Interrupted by a shutdown: resume from the checkpoint next start
+			if err := g.checkpoint(); err != nil && logging.Logger != nil {
+				logging.Logger.Error("Failed to checkpoint video generation", zap.String("id", g.state.Info.ID), zap.Error(err))
+			}
+			return
+		}
+
+		g.update(func(state *generationState) {
+			info := &state.Info
+			info.CompletedAt = time.Now().Unix()
+			switch {
+			case err == nil:
+				info.Status = VideoStatusSucceeded
+				info.Progress = 1
+				info.ResultURL = VideoGenerationsPath + "/" + info.ID + "/result"
+			case ctx.Err() != nil:
+				info.Status = VideoStatusCancelled
+			default:
+				info.Status = VideoStatusFailed
+				info.Error = err.Error()
+			}
+			// Set with the final status, so eviction never sees a
+			// finished generation without its expiry
+			g.expiresAt = time.Now().Add(s.cfg.ResultTTL)
+			state.ExpiresAt = g.expiresAt.Unix()
+		})
+		if err := g.checkpoint(); err != nil && logging.Logger != nil {
+			logging.Logger.Error("Failed to store video generation", zap.String("id", g.state.Info.ID), zap.Error(err))
+		}
+		if err != nil && ctx.Err() == nil && logging.Logger != nil {
+			logging.Logger.Warn("Video generation failed", zap.String("id", g.state.Info.ID), zap.Error(err))
+		}
+	}()
+}
+
+// generate routes the job and appends the frames it streams to the video
+// file, from the last checkpoint on
+func (s *VideoStore) generate(ctx context.Context, r *router.Router, g *videoGeneration) error {
+	g.mu.Lock()
+	state := g.state
+	g.mu.Unlock()
+	gReq := state.Request
+
+	decision, err := r.RouteRequest(ctx, state.Annotations)
+	if err != nil {
+		return fmt.Errorf("routing failed: %w", err)
+	}
+	// An explicit target bypasses capability filtering
+	if !decision.Backend.SupportsTextToVideo() {
+		return fmt.Errorf("backend %s does not support video generation", decision.Backend.ID())
+	}
+	if !decision.Backend.SupportsModel(gReq.Model) {
+		return fmt.Errorf("model %s not available", gReq.Model)
+	}
+
+	f, err := os.OpenFile(g.videoPath, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening video: %w", err)
+	}
+	defer f.Close()
+	// Drop anything written after the checkpoint
+	if err := f.Truncate(state.Bytes); err != nil {
+		return fmt.Errorf("restoring checkpoint: %w", err)
+	}
+
+	g.update(func(state *generationState) {
+		state.Info.Status = VideoStatusRunning
+		state.Info.Backend = decision.Backend.ID()
+	})
+
+	options := make(map[string]string, len(gReq.Options)+1)
+	for k, v := range gReq.Options {
+		options[k] = v
+	}
+	if state.Info.Frames > 0 {
+		options[VideoResumeOption] = strconv.Itoa(int(state.Info.Frames))
+	}
+	req := &backends.VideoGenRequest{
+		Prompt:   gReq.Prompt,
+		Model:    gReq.Model,
+		Width:    gReq.Width,
+		Height:   gReq.Height,
+		FPS:      gReq.FPS,
+		Duration: gReq.Duration,
+		Format:   backends.VideoFormat(gReq.Format),
+		Seed:     gReq.Seed,
+		Options:  options,
+	}
+	tracker := newUsageTracker(ctx, decision, VideoGenerationsPath, gReq.Model, gReq.Prompt)
+
+	stream, err := decision.Backend.GenerateVideoStream(ctx, req)
+	if err != nil {
+		// No streaming generation: run it whole, without checkpoints
+		resp, err := decision.Backend.GenerateVideo(ctx, req)
+		if err != nil {
+			tracker.Finish(0, nil, err)
+			return fmt.Errorf("video generation failed: %w", err)
+		}
+		tracker.Finish(0, resp.Stats, nil)
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(resp.VideoData, 0); err != nil {
+			return fmt.Errorf("storing video: %w", err)
+		}
+		return f.Sync()
+	}
+	defer stream.Close()
+
+	offset := state.Bytes
+	next := state.Info.Frames
+	totalFrames := gReq.FPS * gReq.Duration
+	lastCheckpoint := time.Now()
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			tracker.Finish(0, nil, err)
+			return fmt.Errorf("video generation failed: %w", err)
+		}
+
+		stored := false
+		if len(chunk.FrameData) > 0 && chunk.FrameNum >= next {
+			if _, err := f.WriteAt(chunk.FrameData, offset); err != nil {
+				return fmt.Errorf("storing video: %w", err)
+			}
+			offset += int64(len(chunk.FrameData))
+			next = chunk.FrameNum + 1
+			stored = true
+		}
+
+		g.update(func(state *generationState) {
+			info := &state.Info
+			if stored {
+				info.Frames = next
+				state.Bytes = offset
+			}
+			switch {
+			case chunk.Progress > 0:
+				info.Progress = min(float64(chunk.Progress), 0.99)
+			case totalFrames > 0:
+				info.Progress = min(float64(next)/float64(totalFrames), 0.99)
+			}
+			if len(chunk.Preview) > 0 {
+				g.preview = chunk.Preview
+				info.Previews++
+			}
+		})
+
+		if time.Since(lastCheckpoint) >= s.cfg.CheckpointInterval {
+			if err := f.Sync(); err != nil {
+				return fmt.Errorf("storing video: %w", err)
+			}
+			if err := g.checkpoint(); err != nil && logging.Logger != nil {
+				logging.Logger.Warn("Failed to checkpoint video generation", zap.String("id", state.Info.ID), zap.Error(err))
+			}
+			lastCheckpoint = time.Now()
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		tracker.Finish(0, nil, err)
+		return err
+	}
+	tracker.Finish(0, nil, nil)
+	return f.Sync()
+}
+
+// serveGenerationEvents streams a generation's state as server-sent
+// events until it finishes, with each new preview image as a
+// video_preview event
+func serveGenerationEvents(w http.ResponseWriter, req *http.Request, g *videoGeneration) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming not supported", "internal_error")
+		return
+	}
+	current, updates, unsubscribe := g.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	previews := 0
+	send := func(info VideoGeneration) {
+		data, _ := json.Marshal(info)
+		fmt.Fprintf(w, "event: video_generation\ndata: %s\n\n", data)
+		if info.Previews > previews {
+			previews = info.Previews
+			if preview := g.latestPreview(); preview != nil {
+				data, _ := json.Marshal(map[string]interface{}{
+					"frames":       info.Frames,
+					"content_type": http.DetectContentType(preview),
+					"data":         base64.StdEncoding.EncodeToString(preview),
+				})
+				fmt.Fprintf(w, "event: video_preview\ndata: %s\n\n", data)
+			}
+		}
+		flusher.Flush()
+	}
+	send(current)
+	if updates == nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(videoHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case info, ok := <-updates:
+			if !ok {
+				return
+			}
+			send(info)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
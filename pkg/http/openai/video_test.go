@@ -0,0 +1,511 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// videoBackend is a mockBackend analyzing video as its byte count. With
+// release set, it waits for it before reading.
+type videoBackend struct {
+	mockBackend
+	release chan struct{}
+	format  backends.VideoFormat
+}
+
+func (m *videoBackend) SupportsVideoToText() bool { return true }
+
+func (m *videoBackend) AnalyzeVideo(ctx context.Context, req *backends.VideoAnalysisRequest) (*backends.VideoAnalysisResponse, error) {
+	if m.release != nil {
+		select {
+		case <-m.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	m.format = req.Format
+	data, err := io.ReadAll(req.VideoStream)
+	if err != nil {
+		return nil, err
+	}
+	return &backends.VideoAnalysisResponse{
+		Text:     fmt.Sprintf("%d bytes: %s", len(data), data),
+		Captions: []backends.VideoCaption{{Text: "intro", StartMs: 0, EndMs: 1500}},
+	}, nil
+}
+
+func newVideoServer(t *testing.T, backend backends.Backend) *httptest.Server {
+	t.Helper()
+	store, err := NewVideoStore(VideoStoreConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewVideoStore failed: %v", err)
+	}
+	t.Cleanup(store.Close)
+
+	r := router.NewRouter(router.Config{})
+	// The text-only backend must be skipped by capability filtering
+	r.RegisterBackend(&mockBackend{id: "text-backend", supportsModel: true})
+	r.RegisterBackend(backend)
+
+	mux := http.NewServeMux()
+	mux.Handle(VideoUploadsPath+"/", store.HandleVideoUploads())
+	mux.Handle(VideoUploadsPath, store.HandleVideoUploads())
+	mux.Handle(VideoAnalysesPath+"/", store.HandleVideoAnalyses(r))
+	mux.Handle(VideoAnalysesPath, store.HandleVideoAnalyses(r))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func doVideo(t *testing.T, method, url, contentRange, body string, out interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if contentRange != "" {
+		req.Header.Set("Content-Range", contentRange)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+// uploadVideo uploads data in two chunks, retrying the first as a client
+// resuming after a lost response would
+func uploadVideo(t *testing.T, srv *httptest.Server, data string) VideoUpload {
+	t.Helper()
+	var up VideoUpload
+	body := fmt.Sprintf(`{"filename":"clip.WEBM","size":%d}`, len(data))
+	if code := doVideo(t, http.MethodPost, srv.URL+VideoUploadsPath, "", body, &up); code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating the upload, got %d", code)
+	}
+	if up.Format != "webm" || up.Filename != "clip.WEBM" {
+		t.Errorf("Unexpected upload %+v", up)
+	}
+	url := srv.URL + VideoUploadsPath + "/" + up.ID
+
+	half := len(data) / 2
+	first := fmt.Sprintf("bytes 0-%d/%d", half-1, len(data))
+	if code := doVideo(t, http.MethodPut, url, first, data[:half], &up); code != http.StatusOK || up.Received != int64(half) {
+		t.Fatalf("Expected the first chunk stored, got %d: %+v", code, up)
+	}
+	if code := doVideo(t, http.MethodPut, url, first, data[:half], &up); code != http.StatusConflict || up.Received != int64(half) {
+		t.Fatalf("Expected 409 resuming at %d, got %d: %+v", half, code, up)
+	}
+	rest := fmt.Sprintf("bytes %d-%d/%d", half, len(data)-1, len(data))
+	if code := doVideo(t, http.MethodPut, url, rest, data[half:], &up); code != http.StatusOK || !up.Complete {
+		t.Fatalf("Expected the upload complete, got %d: %+v", code, up)
+	}
+	return up
+}
+
+func TestVideoUpload_Chunks(t *testing.T) {
+	srv := newVideoServer(t, &videoBackend{mockBackend: mockBackend{id: "video", supportsModel: true}})
+	var up VideoUpload
+	doVideo(t, http.MethodPost, srv.URL+VideoUploadsPath, "", `{"filename":"clip.mp4","size":8}`, &up)
+	url := srv.URL + VideoUploadsPath + "/" + up.ID
+
+	cases := []struct {
+		contentRange, body string
+		want               int
+	}{
+		{"", "abcd", http.StatusBadRequest},
+		{"bytes 0-3/9", "abcd", http.StatusRequestedRangeNotSatisfiable},
+		{"bytes 4-7/8", "abcd", http.StatusConflict},
+		{"bytes 0-3/8", "ab", http.StatusBadRequest}, // short body
+	}
+	for _, tc := range cases {
+		if code := doVideo(t, http.MethodPut, url, tc.contentRange, tc.body, nil); code != tc.want {
+			t.Errorf("Expected %d for %q, got %d", tc.want, tc.contentRange, code)
+		}
+	}
+	// The short chunk was not kept
+	if doVideo(t, http.MethodGet, url, "", "", &up); up.Received != 0 {
+		t.Errorf("Expected nothing stored, got %d bytes", up.Received)
+	}
+
+	// Analyses need the whole video
+	body := fmt.Sprintf(`{"upload_id":%q,"model":"video-model"}`, up.ID)
+	if code := doVideo(t, http.MethodPost, srv.URL+VideoAnalysesPath, "", body, nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 analyzing an incomplete upload, got %d", code)
+	}
+
+	if code := doVideo(t, http.MethodDelete, url, "", "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting the upload, got %d", code)
+	}
+	if code := doVideo(t, http.MethodGet, url, "", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", code)
+	}
+}
+
+// doVideoAs calls handler as the API key name
+func doVideoAs(handler http.Handler, key, method, url, body string, out interface{}) int {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: key}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if out != nil {
+		json.NewDecoder(rec.Body).Decode(out)
+	}
+	return rec.Code
+}
+
+func TestVideoUpload_Owner(t *testing.T) {
+	store, err := NewVideoStore(VideoStoreConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewVideoStore failed: %v", err)
+	}
+	t.Cleanup(store.Close)
+	uploads := store.HandleVideoUploads()
+	analyses := store.HandleVideoAnalyses(router.NewRouter(router.Config{}))
+
+	var up VideoUpload
+	if code := doVideoAs(uploads, "alice", http.MethodPost, VideoUploadsPath, `{"filename":"clip.mp4","size":8}`, &up); code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating the upload, got %d", code)
+	}
+	url := VideoUploadsPath + "/" + up.ID
+
+	// Another key can't see, write, analyze or delete it
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		if code := doVideoAs(uploads, "mallory", method, url, "abcd", nil); code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s by another key, got %d", method, code)
+		}
+	}
+	body := fmt.Sprintf(`{"upload_id":%q,"model":"video-model"}`, up.ID)
+	if code := doVideoAs(analyses, "mallory", http.MethodPost, VideoAnalysesPath, body, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 analyzing another key's upload, got %d", code)
+	}
+
+	if code := doVideoAs(uploads, "alice", http.MethodGet, url, "", nil); code != http.StatusOK {
+		t.Errorf("Expected the owner to see the upload, got %d", code)
+	}
+	if code := doVideoAs(uploads, "alice", http.MethodDelete, url, "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting the upload, got %d", code)
+	}
+}
+
+func TestVideoUpload_Limits(t *testing.T) {
+	store, err := NewVideoStore(VideoStoreConfig{Dir: t.TempDir(), MaxTotalBytes: 20, MaxUploadsPerKey: 2})
+	if err != nil {
+		t.Fatalf("NewVideoStore failed: %v", err)
+	}
+	t.Cleanup(store.Close)
+	uploads := store.HandleVideoUploads()
+
+	create := func(key string, size int) (VideoUpload, int) {
+		var up VideoUpload
+		code := doVideoAs(uploads, key, http.MethodPost, VideoUploadsPath, fmt.Sprintf(`{"filename":"clip.mp4","size":%d}`, size), &up)
+		return up, code
+	}
+
+	first, _ := create("alice", 8)
+	if _, code := create("alice", 4); code != http.StatusCreated {
+		t.Fatalf("Expected the second upload accepted, got %d", code)
+	}
+	if _, code := create("alice", 1); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 past the per-key limit, got %d", code)
+	}
+	// 12 of 20 bytes are reserved
+	if _, code := create("bob", 9); code != http.StatusInsufficientStorage {
+		t.Errorf("Expected 507 past the total quota, got %d", code)
+	}
+	if _, code := create("bob", 8); code != http.StatusCreated {
+		t.Errorf("Expected an upload that fits accepted, got %d", code)
+	}
+
+	// Deleting frees both the session and its space
+	doVideoAs(uploads, "alice", http.MethodDelete, VideoUploadsPath+"/"+first.ID, "", nil)
+	if _, code := create("alice", 8); code != http.StatusCreated {
+		t.Errorf("Expected an upload accepted after delete, got %d", code)
+	}
+}
+
+func TestVideoAnalysis_ProgressAndResult(t *testing.T) {
+	backend := &videoBackend{mockBackend: mockBackend{id: "video", supportsModel: true}, release: make(chan struct{})}
+	srv := newVideoServer(t, backend)
+	up := uploadVideo(t, srv, "0123456789")
+
+	var job VideoAnalysis
+	body := fmt.Sprintf(`{"upload_id":%q,"model":"video-model","task":"caption"}`, up.ID)
+	if code := doVideo(t, http.MethodPost, srv.URL+VideoAnalysesPath, "", body, &job); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	url := srv.URL + VideoAnalysesPath + "/" + job.ID
+
+	resp, err := http.Get(url + "/events")
+	if err != nil {
+		t.Fatalf("Events request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+	close(backend.release)
+
+	// Read events until the stream ends with the final state
+	var last VideoAnalysis
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			json.Unmarshal([]byte(data), &last)
+		}
+	}
+	if last.Status != VideoStatusSucceeded || last.Progress != 1 || last.Backend != "video" {
+		t.Fatalf("Expected a succeeded final event, got %+v", last)
+	}
+	if backend.format != backends.VideoFormatWEBM {
+		t.Errorf("Expected the upload's format passed to the backend, got %q", backend.format)
+	}
+
+	var result VideoAnalysisResult
+	if code := doVideo(t, http.MethodGet, srv.URL+last.ResultURL, "", "", &result); code != http.StatusOK {
+		t.Fatalf("Expected 200 for the result, got %d", code)
+	}
+	if result.Text != "10 bytes: 0123456789" || result.Task != "caption" || len(result.Captions) != 1 || result.Captions[0].EndMs != 1500 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	if code := doVideo(t, http.MethodDelete, url, "", "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting the analysis, got %d", code)
+	}
+	if code := doVideo(t, http.MethodGet, url+"/result", "", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", code)
+	}
+}
+
+func TestVideoAnalysis_Cancel(t *testing.T) {
+	backend := &videoBackend{mockBackend: mockBackend{id: "video", supportsModel: true}, release: make(chan struct{})}
+	srv := newVideoServer(t, backend)
+	up := uploadVideo(t, srv, "0123456789")
+
+	var job VideoAnalysis
+	doVideo(t, http.MethodPost, srv.URL+VideoAnalysesPath, "", fmt.Sprintf(`{"upload_id":%q,"model":"video-model"}`, up.ID), &job)
+	url := srv.URL + VideoAnalysesPath + "/" + job.ID
+	if code := doVideo(t, http.MethodDelete, url, "", "", nil); code != http.StatusNoContent {
+		t.Fatalf("Expected 204 cancelling, got %d", code)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != VideoStatusCancelled && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		doVideo(t, http.MethodGet, url, "", "", &job)
+	}
+	if job.Status != VideoStatusCancelled {
+		t.Fatalf("Expected the analysis cancelled, got %+v", job)
+	}
+	if code := doVideo(t, http.MethodGet, url+"/result", "", "", nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 for the result of a cancelled analysis, got %d", code)
+	}
+}
+
+// genBackend is a mockBackend generating frames "f0".."fN-1", each with a
+// preview. It blocks before sending frame blockAt until release is closed.
+type genBackend struct {
+	mockBackend
+	frames  int32
+	blockAt int32
+	release chan struct{}
+
+	mu      sync.Mutex
+	options []map[string]string
+}
+
+func (m *genBackend) SupportsTextToVideo() bool { return true }
+
+func (m *genBackend) GenerateVideoStream(ctx context.Context, req *backends.VideoGenRequest) (backends.VideoStreamReader, error) {
+	m.mu.Lock()
+	m.options = append(m.options, req.Options)
+	m.mu.Unlock()
+	return &genStream{ctx: ctx, backend: m}, nil
+}
+
+type genStream struct {
+	ctx     context.Context
+	backend *genBackend
+	next    int32
+}
+
+func (s *genStream) Recv() (*backends.VideoChunk, error) {
+	if s.next == s.backend.frames {
+		return nil, io.EOF
+	}
+	if s.next == s.backend.blockAt && s.backend.release != nil {
+		select {
+		case <-s.backend.release:
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		}
+	}
+	n := s.next
+	s.next++
+	return &backends.VideoChunk{
+		FrameData: []byte(fmt.Sprintf("f%d", n)),
+		FrameNum:  n,
+		Preview:   []byte("\x89PNG\r\n\x1a\npreview"),
+		Done:      s.next == s.backend.frames,
+	}, nil
+}
+
+func (s *genStream) Close() error { return nil }
+
+func newGenerationServer(t *testing.T, dir string, backend backends.Backend) (*VideoStore, *router.Router, *httptest.Server) {
+	t.Helper()
+	store, err := NewVideoStore(VideoStoreConfig{Dir: dir, CheckpointInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("NewVideoStore failed: %v", err)
+	}
+	t.Cleanup(store.Close)
+
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&mockBackend{id: "text-backend", supportsModel: true})
+	r.RegisterBackend(backend)
+
+	mux := http.NewServeMux()
+	mux.Handle(VideoGenerationsPath+"/", store.HandleVideoGenerations(r))
+	mux.Handle(VideoGenerationsPath, store.HandleVideoGenerations(r))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return store, r, srv
+}
+
+// waitForGeneration polls a generation until cond holds
+func waitForGeneration(t *testing.T, url string, cond func(VideoGeneration) bool) VideoGeneration {
+	t.Helper()
+	var job VideoGeneration
+	deadline := time.Now().Add(5 * time.Second)
+	for doVideo(t, http.MethodGet, url, "", "", &job); !cond(job); doVideo(t, http.MethodGet, url, "", "", &job) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting, last state %+v", job)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return job
+}
+
+func TestVideoGeneration_EventsPreviewAndResult(t *testing.T) {
+	backend := &genBackend{mockBackend: mockBackend{id: "video", supportsModel: true}, frames: 3, release: make(chan struct{})}
+	_, _, srv := newGenerationServer(t, t.TempDir(), backend)
+
+	var job VideoGeneration
+	if code := doVideo(t, http.MethodPost, srv.URL+VideoGenerationsPath, "", `{"model":"video-model","prompt":"a cat","fps":1,"duration":3}`, &job); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	url := srv.URL + VideoGenerationsPath + "/" + job.ID
+
+	resp, err := http.Get(url + "/events")
+	if err != nil {
+		t.Fatalf("Events request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	close(backend.release)
+
+	var last VideoGeneration
+	previews := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() == "event: video_preview" {
+			previews++
+		}
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok && strings.Contains(data, `"object"`) {
+			json.Unmarshal([]byte(data), &last)
+		}
+	}
+	if last.Status != VideoStatusSucceeded || last.Frames != 3 || last.Backend != "video" {
+		t.Fatalf("Expected a succeeded final event, got %+v", last)
+	}
+	if previews == 0 {
+		t.Error("Expected preview events")
+	}
+
+	resp, err = http.Get(url + "/preview")
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("Expected the latest preview as a PNG, got %v %v", resp, err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(srv.URL + last.ResultURL)
+	if err != nil {
+		t.Fatalf("Result request failed: %v", err)
+	}
+	video, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(video) != "f0f1f2" || resp.Header.Get("Content-Type") != "video/mp4" {
+		t.Errorf("Unexpected result %q (%s)", video, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestVideoGeneration_ResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	backend := &genBackend{mockBackend: mockBackend{id: "video", supportsModel: true}, frames: 4, blockAt: 2, release: make(chan struct{})}
+	store, _, srv := newGenerationServer(t, dir, backend)
+
+	var job VideoGeneration
+	doVideo(t, http.MethodPost, srv.URL+VideoGenerationsPath, "", `{"model":"video-model","prompt":"a cat"}`, &job)
+	waitForGeneration(t, srv.URL+VideoGenerationsPath+"/"+job.ID, func(g VideoGeneration) bool { return g.Frames == 2 })
+
+	// Shut down mid-generation
+	store.Close()
+
+	// The restarted proxy's backend regenerates from the first frame,
+	// ignoring the resume option
+	restarted := &genBackend{mockBackend: mockBackend{id: "video", supportsModel: true}, frames: 4}
+	store, r, srv := newGenerationServer(t, dir, restarted)
+	if resumed := store.ResumeGenerations(r); resumed != 1 {
+		t.Fatalf("Expected 1 generation resumed, got %d", resumed)
+	}
+	url := srv.URL + VideoGenerationsPath + "/" + job.ID
+	job = waitForGeneration(t, url, func(g VideoGeneration) bool { return isFinalVideoStatus(g.Status) })
+	if job.Status != VideoStatusSucceeded || job.Resumes != 1 || job.Frames != 4 {
+		t.Fatalf("Expected the resumed generation to succeed, got %+v", job)
+	}
+	if got := restarted.options[0][VideoResumeOption]; got != "2" {
+		t.Errorf("Expected the backend asked to resume from frame 2, got %q", got)
+	}
+
+	resp, err := http.Get(url + "/result")
+	if err != nil {
+		t.Fatalf("Result request failed: %v", err)
+	}
+	video, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(video) != "f0f1f2f3" {
+		t.Errorf("Expected each frame stored once, got %q", video)
+	}
+}
+
+func TestVideoGeneration_CancelIsNotResumed(t *testing.T) {
+	dir := t.TempDir()
+	backend := &genBackend{mockBackend: mockBackend{id: "video", supportsModel: true}, frames: 4, blockAt: 1, release: make(chan struct{})}
+	store, _, srv := newGenerationServer(t, dir, backend)
+
+	var job VideoGeneration
+	doVideo(t, http.MethodPost, srv.URL+VideoGenerationsPath, "", `{"model":"video-model","prompt":"a cat"}`, &job)
+	url := srv.URL + VideoGenerationsPath + "/" + job.ID
+	if code := doVideo(t, http.MethodDelete, url, "", "", nil); code != http.StatusNoContent {
+		t.Fatalf("Expected 204 cancelling, got %d", code)
+	}
+	waitForGeneration(t, url, func(g VideoGeneration) bool { return g.Status == VideoStatusCancelled })
+	store.Close()
+
+	store, r, _ := newGenerationServer(t, dir, backend)
+	if resumed := store.ResumeGenerations(r); resumed != 0 {
+		t.Errorf("Expected the cancelled generation not resumed, got %d", resumed)
+	}
+}
@@ -0,0 +1,383 @@
+package postprocess
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedTags are the elements strip_html keeps, with the attributes each
+// may carry. Any other tag is removed and its text kept.
+var allowedTags = map[string][]string{
+	"a": {"href", "title"}, "abbr": {"title"}, "b": nil, "blockquote": {"cite"},
+	"br": nil, "caption": nil, "cite": nil, "code": nil, "dd": nil, "del": nil,
+	"details": {"open"}, "div": nil, "dl": nil, "dt": nil, "em": nil,
+	"figcaption": nil, "figure": nil, "h1": nil, "h2": nil, "h3": nil, "h4": nil,
+	"h5": nil, "h6": nil, "hr": nil, "i": nil, "img": {"src", "alt", "title", "width", "height"},
+	"ins": nil, "kbd": nil, "li": nil, "mark": nil, "ol": {"start"}, "p": nil,
+	"pre": nil, "q": {"cite"}, "s": nil, "samp": nil, "small": nil, "span": nil,
+	"strong": nil, "sub": nil, "summary": nil, "sup": nil, "table": nil,
+	"tbody": nil, "td": {"colspan", "rowspan", "align"}, "tfoot": nil,
+	"th": {"colspan", "rowspan", "align"}, "thead": nil, "tr": nil, "u": nil,
+	"ul": nil, "var": nil,
+}
+
+// droppedElements are removed along with their content: they run script,
+// embed other documents, or hold raw text that isn't parsed as HTML
+var droppedElements = map[string]bool{
+	"applet": true, "iframe": true, "math": true, "noembed": true, "noframes": true,
+	"noscript": true, "object": true, "plaintext": true, "script": true, "select": true,
+	"style": true, "svg": true, "template": true, "textarea": true, "title": true, "xmp": true,
+}
+
+// urlAttrs are kept only when their URL is relative or has a safe scheme
+var (
+	urlAttrs    = map[string]bool{"cite": true, "href": true, "src": true}
+	safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+)
+
+// autolinkRe matches a markdown autolink such as <https://example.com>,
+// which the tokenizer would otherwise read as a tag
+var autolinkRe = regexp.MustCompile(`^<(?:([a-zA-Z][a-zA-Z0-9+.-]{1,31}):[^<>\s]*|[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9.-]+)>$`)
+
+// safeURL reports whether a URL is relative or uses a safe scheme
+func safeURL(value string) bool {
+	// Browsers ignore control characters and whitespace inside a scheme
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+	colon := strings.IndexByte(value, ':')
+	if colon < 0 || strings.ContainsAny(value[:colon], "/?#") {
+		return true
+	}
+	return safeSchemes[strings.ToLower(value[:colon])]
+}
+
+// renderTag writes an allowed start tag with only its allowed attributes
+func renderTag(sb *strings.Builder, tok html.Token, allowed []string) {
+	sb.WriteString("<" + tok.Data)
+	for _, attr := range tok.Attr {
+		if attr.Namespace != "" || !slices.Contains(allowed, attr.Key) {
+			continue
+		}
+		if urlAttrs[attr.Key] && !safeURL(attr.Val) {
+			continue
+		}
+		sb.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+	if tok.Type == html.SelfClosingTagToken {
+		sb.WriteString(" /")
+	}
+	sb.WriteString(">")
+}
+
+// sanitizeHTML keeps the allowed markup in prose and removes the rest.
+// Text is copied as written so markdown and entities survive. open is the
+// offset of a tag, comment or dropped element left unfinished at the end of
+// prose, -1 if none; it is removed.
+func sanitizeHTML(prose string) (string, int) {
+	var sb strings.Builder
+	z := html.NewTokenizer(strings.NewReader(prose))
+	offset, open := 0, -1
+	dropping, depth := "", 0
+	for {
+		tt := z.Next()
+		raw := string(z.Raw())
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF && offset < len(prose) && open < 0 {
+				open = offset
+			}
+			return sb.String(), open
+		}
+		start := offset
+		offset += len(raw)
+
+		switch tt {
+		case html.TextToken:
+			if dropping == "" {
+				sb.WriteString(raw)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch {
+			case dropping != "":
+				if tok.Data == dropping && tt == html.StartTagToken {
+					depth++
+				}
+			case droppedElements[tok.Data]:
+				if tt == html.StartTagToken {
+					dropping, depth, open = tok.Data, 1, start
+				}
+			case autolinkRe.MatchString(raw):
+				if m := autolinkRe.FindStringSubmatch(raw); m[1] == "" || safeSchemes[strings.ToLower(m[1])] {
+					sb.WriteString(raw)
+				}
+			default:
+				if allowed, ok := allowedTags[tok.Data]; ok {
+					renderTag(&sb, tok, allowed)
+				}
+			}
+		case html.EndTagToken:
+			tok := z.Token()
+			switch {
+			case dropping != "":
+				if tok.Data == dropping {
+					if depth--; depth == 0 {
+						dropping, open = "", -1
+					}
+				}
+			default:
+				if _, ok := allowedTags[tok.Data]; ok {
+					sb.WriteString("</" + tok.Data + ">")
+				}
+			}
+		case html.CommentToken, html.DoctypeToken:
+			// Removed; one still open at the end holds the rest of prose
+			if offset == len(prose) && !strings.HasSuffix(raw, ">") && open < 0 {
+				open = start
+			}
+		}
+	}
+}
+
+// sanitizeProse strips unsafe HTML and script links from prose
+func sanitizeProse(prose string) string {
+	prose, _ = sanitizeHTML(prose)
+	return scriptURLLinkRe.ReplaceAllString(prose, "](#)")
+}
+
+// stripHTML keeps an allowlist of harmless markup such as <b> and <a href>,
+// removing any other tag, attribute or script URL a client could run when
+// it renders the response. Script-like elements lose their content too.
+type stripHTML struct{}
+
+func (stripHTML) Name() string { return StepStripHTML }
+
+func (stripHTML) Process(ctx context.Context, text string) string {
+	return mapProse(text, sanitizeProse)
+}
+
+func (stripHTML) Filter(ctx context.Context) StreamFilter {
+	return &htmlFilter{}
+}
+
+// htmlFilter sanitizes a stream. Prose is sent once no tag, dropped
+// element, code span or link is left open in it, so it sanitizes the same
+// on its own as within the whole response; the rest waits for more text.
+type htmlFilter struct {
+	pending string // received but not yet sent
+	fence   string // open code fence, "" if none
+	midLine bool   // part of the current line was already sent
+}
+
+func (f *htmlFilter) Write(token string) string {
+	f.pending += token
+	return f.drain(false)
+}
+
+func (f *htmlFilter) Flush() string {
+	return f.drain(true)
+}
+
+// drain sends what can be sent of the pending text, all of it when final
+func (f *htmlFilter) drain(final bool) string {
+	var sb strings.Builder
+	for f.pending != "" {
+		line, complete := f.pending, false
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line, complete = line[:i+1], true
+		}
+
+		if !f.midLine {
+			if !complete && !final && mayBeFence(line) {
+				break
+			}
+			marker := fenceMarker(line)
+			if f.fence == "" && marker != "" {
+				f.fence = marker
+				sb.WriteString(f.take(len(line)))
+				continue
+			}
+			if f.fence != "" && strings.HasPrefix(marker, f.fence) && strings.TrimSpace(line) == marker {
+				f.fence = ""
+				sb.WriteString(f.take(len(line)))
+				continue
+			}
+		}
+		if f.fence != "" {
+			sb.WriteString(f.take(len(line)))
+			continue
+		}
+
+		prose := f.pending[:proseEnd(f.pending)]
+		n := len(prose)
+		if !final && n == len(f.pending) {
+			n = settled(prose)
+		}
+		for _, seg := range splitInlineCode(f.take(n)) {
+			if seg.code {
+				sb.WriteString(seg.text)
+			} else {
+				sb.WriteString(sanitizeProse(seg.text))
+			}
+		}
+		if n < len(prose) {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// take removes the first n bytes of the pending text and returns them
+func (f *htmlFilter) take(n int) string {
+	taken := f.pending[:n]
+	f.pending = f.pending[n:]
+	if n > 0 {
+		f.midLine = !strings.HasSuffix(taken, "\n")
+	}
+	return taken
+}
+
+// proseEnd returns where the first line after the current one opening a
+// code fence starts, len(text) if none does
+func proseEnd(text string) int {
+	i := strings.IndexByte(text, '\n')
+	for i >= 0 && i+1 < len(text) {
+		rest := text[i+1:]
+		next := strings.IndexByte(rest, '\n')
+		line := rest
+		if next >= 0 {
+			line = rest[:next+1]
+		}
+		if fenceMarker(line) != "" {
+			return i + 1
+		}
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return len(text)
+}
+
+// mayBeFence reports whether an unfinished line could still turn out to
+// be a code fence marker
+func mayBeFence(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	return fenceMarker(line) != "" || strings.Trim(trimmed, "`") == "" || strings.Trim(trimmed, "~") == ""
+}
+
+// settled returns how much of streamed prose can be sanitized before the
+// rest arrives: the text before any possible code fence, code span, script
+// link, tag or dropped element still open at its end
+func settled(prose string) int {
+	n := len(prose)
+	for {
+		cut := n
+		if i := strings.LastIndexByte(prose[:cut], '\n'); i >= 0 && mayBeFence(prose[i+1:cut]) {
+			cut = i + 1
+		}
+		if i := openCodeSpan(prose[:cut]); i >= 0 {
+			cut = i
+		}
+		if i := openLink(prose[:cut]); i >= 0 {
+			cut = i
+		}
+		if i := strings.LastIndexByte(prose[:cut], '<'); i >= 0 && !strings.Contains(prose[i:cut], ">") && mayBeTag(prose[i+1:cut]) {
+			cut = i
+		}
+		if i := openTag(prose[:cut]); i >= 0 {
+			cut = i
+		}
+		if cut == n {
+			return n
+		}
+		n = cut
+	}
+}
+
+// openCodeSpan returns where the first backtick run without a closing run
+// starts, -1 if every run is closed
+func openCodeSpan(text string) int {
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		n := 0
+		for i+n < len(text) && text[i+n] == '`' {
+			n++
+		}
+		end := closingRun(text, i+n, n)
+		if end < 0 {
+			return i
+		}
+		i = end
+	}
+	return -1
+}
+
+// openLink returns where the first markdown link destination without its
+// closing parenthesis starts, or a trailing "]" that may begin one; -1 if
+// there is none
+func openLink(text string) int {
+	for i := 0; ; {
+		j := strings.Index(text[i:], "](")
+		if j < 0 {
+			if strings.HasSuffix(text, "]") {
+				return len(text) - 1
+			}
+			return -1
+		}
+		start := i + j
+		depth, end := 0, -1
+		for k := start + 1; k < len(text) && end < 0; k++ {
+			switch text[k] {
+			case '(':
+				depth++
+			case ')':
+				if depth--; depth == 0 {
+					end = k + 1
+				}
+			}
+		}
+		if end < 0 {
+			return start
+		}
+		i = end
+	}
+}
+
+// mayBeTag reports whether the text after a '<' could still become a tag
+func mayBeTag(rest string) bool {
+	if rest == "" {
+		return true
+	}
+	c := rest[0]
+	return c == '/' || c == '!' || c == '?' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// openTag returns where a tag or dropped element left open in the prose
+// parts of text starts, -1 if none is
+func openTag(text string) int {
+	offset := 0
+	for _, seg := range splitInlineCode(text) {
+		if !seg.code {
+			if _, open := sanitizeHTML(seg.text); open >= 0 {
+				return offset + open
+			}
+		}
+		offset += len(seg.text)
+	}
+	return -1
+}
@@ -0,0 +1,278 @@
+// Package postprocess applies a configurable chain of steps to the final
+// text of model responses, e.g. stripping unsafe HTML or appending RAG
+// citations. Every HTTP protocol (OpenAI, Ollama and WebSocket) runs its
+// responses through the same chain.
+package postprocess
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// Built-in step names
+const (
+	StepStripHTML         = "strip_html"
+	StepNormalizeMarkdown = "normalize_markdown"
+	StepCitations         = "citations"
+)
+
+// Step transforms a response's final text
+type Step interface {
+	Name() string
+	Process(ctx context.Context, text string) string
+}
+
+// Appender is a step that can also finish a streamed response. Tokens
+// already sent can't be rewritten, so Append only returns text to add after
+// them; streamed is everything sent so far.
+type Appender interface {
+	Append(ctx context.Context, streamed string) string
+}
+
+// Filter is a step that can also rewrite a streamed response as it
+// arrives, through a StreamFilter created for each stream
+type Filter interface {
+	Filter(ctx context.Context) StreamFilter
+}
+
+// StreamFilter rewrites one stream. Write returns the text that can be
+// sent for token, holding back whatever depends on text still to come;
+// Flush returns the rest when the stream ends.
+type StreamFilter interface {
+	Write(token string) string
+	Flush() string
+}
+
+var builtins = map[string]func() Step{
+	StepStripHTML:         func() Step { return stripHTML{} },
+	StepNormalizeMarkdown: func() Step { return normalizeMarkdown{} },
+	StepCitations:         func() Step { return citations{} },
+}
+
+// Names returns the built-in step names in sorted order
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Chain runs steps in order. A nil chain leaves responses unchanged.
+type Chain struct {
+	steps []Step
+}
+
+// NewChain creates a chain from steps
+func NewChain(steps ...Step) *Chain {
+	return &Chain{steps: steps}
+}
+
+// Build resolves built-in step names into a chain
+func Build(names []string) (*Chain, error) {
+	steps := make([]Step, 0, len(names))
+	for _, name := range names {
+		newStep, ok := builtins[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown post-processing step: %s", name)
+		}
+		steps = append(steps, newStep())
+	}
+	return NewChain(steps...), nil
+}
+
+// Steps returns the names of the chain's steps
+func (c *Chain) Steps() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, len(c.steps))
+	for i, step := range c.steps {
+		names[i] = step.Name()
+	}
+	return names
+}
+
+// Apply runs every step over a complete response
+func (c *Chain) Apply(ctx context.Context, text string) string {
+	if c == nil {
+		return text
+	}
+	for _, step := range c.steps {
+		text = step.Process(ctx, text)
+	}
+	return text
+}
+
+// Response returns resp with its text post-processed, leaving resp itself
+// untouched so the raw output can still be cached
+func (c *Chain) Response(ctx context.Context, resp *backends.GenerateResponse) *backends.GenerateResponse {
+	if c == nil || resp == nil {
+		return resp
+	}
+	processed := *resp
+	processed.Response = c.Apply(ctx, resp.Response)
+	return &processed
+}
+
+// Stream wraps a streamed response so the chain's filters rewrite its
+// tokens and its appenders add their text to the final chunk. Steps that
+// are neither don't apply.
+func (c *Chain) Stream(ctx context.Context, reader backends.StreamReader) backends.StreamReader {
+	if c == nil {
+		return reader
+	}
+	r := &streamReader{
+		StreamReader: reader,
+		ctx:          ctx,
+		chain:        c,
+		filters:      make([]StreamFilter, len(c.steps)),
+		streamed:     make([]strings.Builder, len(c.steps)),
+	}
+	for i, step := range c.steps {
+		if filter, ok := step.(Filter); ok {
+			r.filters[i] = filter.Filter(ctx)
+		}
+	}
+	return r
+}
+
+// streamReader runs a stream's tokens through the chain
+type streamReader struct {
+	backends.StreamReader
+	ctx      context.Context
+	chain    *Chain
+	filters  []StreamFilter    // per step, nil if the step isn't a Filter
+	streamed []strings.Builder // per appender step, what it has passed on
+	finished bool
+}
+
+// pass runs text through the chain's steps in order. At the end of the
+// stream the filters flush and the appenders add their text, so each step
+// sees everything the steps before it sent.
+func (r *streamReader) pass(text string, final bool) string {
+	for i, step := range r.chain.steps {
+		if filter := r.filters[i]; filter != nil {
+			text = filter.Write(text)
+			if final {
+				text += filter.Flush()
+			}
+		}
+		if appender, ok := step.(Appender); ok {
+			r.streamed[i].WriteString(text)
+			if final {
+				added := appender.Append(r.ctx, r.streamed[i].String())
+				r.streamed[i].WriteString(added)
+				text += added
+			}
+		}
+	}
+	return text
+}
+
+func (r *streamReader) Recv() (*backends.StreamChunk, error) {
+	for {
+		if r.finished {
+			return nil, io.EOF
+		}
+		chunk, err := r.StreamReader.Recv()
+		if err == io.EOF {
+			// Backend ended without a final chunk
+			r.finished = true
+			if text := r.pass("", true); text != "" {
+				return &backends.StreamChunk{Token: text, Done: true}, nil
+			}
+			return nil, io.EOF
+		}
+		if err != nil || chunk == nil {
+			return chunk, err
+		}
+
+		passed := *chunk
+		passed.Token = r.pass(chunk.Token, chunk.Done)
+		if chunk.Done {
+			r.finished = true
+			return &passed, nil
+		}
+		if passed.Token == "" && chunk.Token != "" {
+			// Held back by a filter until more arrives
+			continue
+		}
+		return &passed, nil
+	}
+}
+
+// Default is the chain applied by the HTTP handlers, nil when
+// post-processing is disabled
+var Default *Chain
+
+// SetDefault sets the chain applied by the HTTP handlers
+func SetDefault(c *Chain) {
+	Default = c
+}
+
+// Source is a document retrieved for a request, cited as [n] by its
+// position in the request's sources
+type Source struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// SourcesHeader carries the retrieval stage's sources as a JSON array, e.g.
+// `X-Retrieval-Sources: [{"title":"Runbook","url":"https://..."}]`
+const SourcesHeader = "X-Retrieval-Sources"
+
+// MaxSources caps the sources a request may carry
+const MaxSources = 50
+
+type sourcesKey struct{}
+
+// WithSources returns a context carrying a request's retrieved sources
+func WithSources(ctx context.Context, sources []Source) context.Context {
+	return context.WithValue(ctx, sourcesKey{}, sources)
+}
+
+// SourcesFromContext returns the request's retrieved sources, nil if none
+func SourcesFromContext(ctx context.Context) []Source {
+	sources, _ := ctx.Value(sourcesKey{}).([]Source)
+	return sources
+}
+
+// ParseSources decodes an X-Retrieval-Sources value
+func ParseSources(value string) ([]Source, error) {
+	var sources []Source
+	if err := json.Unmarshal([]byte(value), &sources); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of sources: %w", err)
+	}
+	if len(sources) > MaxSources {
+		return nil, fmt.Errorf("too many sources: %d (max %d)", len(sources), MaxSources)
+	}
+	return sources, nil
+}
+
+// Middleware reads X-Retrieval-Sources into the request context, rejecting
+// malformed values with 400
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(SourcesHeader)
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sources, err := ParseSources(header)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid %s: %v", SourcesHeader, err), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithSources(r.Context(), sources)))
+	})
+}
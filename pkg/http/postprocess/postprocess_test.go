@@ -0,0 +1,231 @@
+package postprocess
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func mustBuild(t *testing.T, names ...string) *Chain {
+	t.Helper()
+	chain, err := Build(names)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	return chain
+}
+
+func TestStripHTML(t *testing.T) {
+	chain := mustBuild(t, StepStripHTML)
+	input := "Hello <b>world</b><script>alert(1)</script> " +
+		`<a href="javascript:alert(1)" onclick="steal()">link</a> <iframe src="x"></iframe>` +
+		"[click](javascript:alert(1))\n\n```html\n<script>kept()</script>\n```\nand `<script>` inline"
+
+	got := chain.Apply(context.Background(), input)
+	want := "Hello <b>world</b> <a>link</a> [click](#)\n\n```html\n<script>kept()</script>\n```\nand `<script>` inline"
+	if got != want {
+		t.Errorf("Unexpected sanitized output:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestStripHTML_Allowlist(t *testing.T) {
+	chain := mustBuild(t, StepStripHTML)
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"attributes", `<a href="https://x.y/?a=1&amp;b=2" style="x" title='t"'>x</a>`, `<a href="https://x.y/?a=1&amp;b=2" title="t&#34;">x</a>`},
+		{"encoded scheme", `<a href="jav&#x09;ascript:alert(1)">x</a> <img src=" JAVASCRIPT:x" alt="a">`, `<a>x</a> <img alt="a">`},
+		{"unknown tags keep text", `<custom onclick="x">text</custom><form><input></form>`, "text"},
+		{"raw text elements", "<title><script>alert(1)</script></title><svg><svg></svg>x</svg>after", "after"},
+		{"comments", "a<!-- <script>x</script> -->b<![CDATA[c]]>", "ab"},
+		{"markdown text", "Q&A: 1 < 2 && 3 > 2 &amp; more", "Q&A: 1 < 2 && 3 > 2 &amp; more"},
+		{"autolinks", "<https://example.com/a> <me@example.com> <javascript:alert(1)>", "<https://example.com/a> <me@example.com> "},
+		{"unclosed", "before<script>alert(1)", "before"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chain.Apply(context.Background(), tt.input); got != tt.want {
+				t.Errorf("Unexpected sanitized output:\n got: %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeMarkdown(t *testing.T) {
+	chain := mustBuild(t, StepNormalizeMarkdown)
+	input := "\r\nIntro\r\n\r\n\r\n\r\n• one\n• two\n\n```go\nfunc main() {\n\n\n\n}\n"
+
+	got := chain.Apply(context.Background(), input)
+	want := "Intro\n\n- one\n- two\n\n```go\nfunc main() {\n\n\n\n}\n```"
+	if got != want {
+		t.Errorf("Unexpected normalized output:\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestCitations(t *testing.T) {
+	chain := mustBuild(t, StepCitations)
+	ctx := WithSources(context.Background(), []Source{
+		{Title: "Runbook", URL: "https://docs.example/runbook"},
+		{Title: "FAQ"},
+		{URL: "https://docs.example/limits"},
+	})
+
+	got := chain.Apply(ctx, "Restart the service [3], see [the guide](https://x) and [1]. Ignore [7].")
+	want := "Restart the service [3], see [the guide](https://x) and [1]. Ignore [7]." +
+		"\n\nSources:\n[1] [Runbook](https://docs.example/runbook)\n[3] <https://docs.example/limits>"
+	if got != want {
+		t.Errorf("Unexpected citations:\n got: %q\nwant: %q", got, want)
+	}
+
+	// Without markers every source is listed
+	if got := chain.Apply(ctx, "Restart it."); !strings.Contains(got, "[2] FAQ") {
+		t.Errorf("Expected all sources listed, got %q", got)
+	}
+
+	// Without sources nothing changes
+	if got := chain.Apply(context.Background(), "Restart it [1]."); got != "Restart it [1]." {
+		t.Errorf("Expected no citations without sources, got %q", got)
+	}
+}
+
+func TestBuild_UnknownStep(t *testing.T) {
+	if _, err := Build([]string{"strip_html", "translate"}); err == nil {
+		t.Error("Expected an error for an unknown step")
+	}
+}
+
+func TestNilChain(t *testing.T) {
+	var chain *Chain
+	if got := chain.Apply(context.Background(), "<script>x</script>"); got != "<script>x</script>" {
+		t.Errorf("Expected a nil chain to leave text unchanged, got %q", got)
+	}
+	resp := &backends.GenerateResponse{Response: "hi"}
+	if chain.Response(context.Background(), resp) != resp {
+		t.Error("Expected a nil chain to return the response as is")
+	}
+}
+
+type sliceStream struct {
+	chunks []*backends.StreamChunk
+}
+
+func (s *sliceStream) Recv() (*backends.StreamChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *sliceStream) Close() error { return nil }
+
+func readAll(t *testing.T, reader backends.StreamReader) (string, int) {
+	t.Helper()
+	var sb strings.Builder
+	done := 0
+	for {
+		chunk, err := reader.Recv()
+		if err == io.EOF {
+			return sb.String(), done
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		sb.WriteString(chunk.Token)
+		if chunk.Done {
+			done++
+		}
+	}
+}
+
+func TestChain_Stream(t *testing.T) {
+	chain := mustBuild(t, StepStripHTML, StepNormalizeMarkdown, StepCitations)
+	ctx := WithSources(context.Background(), []Source{{Title: "Runbook"}})
+
+	reader := chain.Stream(ctx, &sliceStream{chunks: []*backends.StreamChunk{
+		{Token: "See [1]:\n```sh\n"},
+		{Token: "restart", Done: true},
+	}})
+	got, done := readAll(t, reader)
+	want := "See [1]:\n```sh\nrestart\n```\n\nSources:\n[1] Runbook"
+	if got != want || done != 1 {
+		t.Errorf("Unexpected stream (%d done chunks):\n got: %q\nwant: %q", done, got, want)
+	}
+
+	// A stream ending without a done chunk gets one carrying the suffix
+	reader = chain.Stream(ctx, &sliceStream{chunks: []*backends.StreamChunk{{Token: "Done."}}})
+	if got, done := readAll(t, reader); got != "Done.\n\nSources:\n[1] Runbook" || done != 1 {
+		t.Errorf("Expected a final chunk with the citations, got %q (%d done chunks)", got, done)
+	}
+}
+
+func TestChain_StreamStripHTML(t *testing.T) {
+	chain := mustBuild(t, StepStripHTML)
+	ctx := context.Background()
+	inputs := []string{
+		"Hi <b>there</b><script>alert(1)</script> <a href=\"javascript:x\" title=\"a>b\">link</a>",
+		"Use `<script>` or\n```html\n<script>kept()</script>\n```\nthen <iframe>gone</iframe> [x](javascript:alert(1)) done",
+		"<!-- hidden --> 1 < 2 <https://example.com> <svg><svg></svg></svg>end\n~~~\n<b>code</b>\n~~~",
+	}
+	for _, input := range inputs {
+		want := chain.Apply(ctx, input)
+		// Every split of the response into two chunks, and one byte per chunk
+		for i := 0; i <= len(input); i++ {
+			reader := chain.Stream(ctx, &sliceStream{chunks: []*backends.StreamChunk{
+				{Token: input[:i]}, {Token: input[i:], Done: true},
+			}})
+			if got, _ := readAll(t, reader); got != want {
+				t.Fatalf("Split at %d of %q:\n got: %q\nwant: %q", i, input, got, want)
+			}
+		}
+		var chunks []*backends.StreamChunk
+		for i := range input {
+			chunks = append(chunks, &backends.StreamChunk{Token: input[i : i+1]})
+		}
+		if got, _ := readAll(t, chain.Stream(ctx, &sliceStream{chunks: chunks})); got != want {
+			t.Errorf("Byte-wise stream of %q:\n got: %q\nwant: %q", input, got, want)
+		}
+	}
+
+	// Settled text is sent as it arrives; an unfinished tag waits
+	reader := chain.Stream(ctx, &sliceStream{chunks: []*backends.StreamChunk{
+		{Token: "Safe <b>text</b> <scr"}, {Token: "ipt>x</script>!", Done: true},
+	}})
+	chunk, err := reader.Recv()
+	if err != nil || chunk.Token != "Safe <b>text</b> " {
+		t.Fatalf("Expected the settled prefix first, got %+v (%v)", chunk, err)
+	}
+	if chunk, err = reader.Recv(); err != nil || chunk.Token != "!" || !chunk.Done {
+		t.Errorf("Expected the sanitized rest in the final chunk, got %+v (%v)", chunk, err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var sources []Source
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sources = SourcesFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set(SourcesHeader, `[{"title":"Runbook","url":"https://docs.example/runbook"}]`)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || len(sources) != 1 || sources[0].Title != "Runbook" {
+		t.Errorf("Expected the sources in the context, got %d %+v", rec.Code, sources)
+	}
+
+	req.Header.Set(SourcesHeader, `{"title":"Runbook"}`)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed sources, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,229 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// segment is a run of response text, either markdown prose or code
+type segment struct {
+	text string
+	code bool
+}
+
+// splitCode splits markdown into prose and code (fenced blocks and inline
+// code spans), so steps leave code examples alone. It also returns the
+// fence still open at the end of the text, "" if none.
+func splitCode(text string) ([]segment, string) {
+	var segments []segment
+	var prose strings.Builder
+	fence := ""
+
+	flushProse := func() {
+		if prose.Len() > 0 {
+			segments = append(segments, splitInlineCode(prose.String())...)
+			prose.Reset()
+		}
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		marker := fenceMarker(line)
+		switch {
+		case fence == "" && marker != "":
+			flushProse()
+			fence = marker
+			segments = append(segments, segment{text: line, code: true})
+		case fence != "":
+			segments = append(segments, segment{text: line, code: true})
+			if strings.HasPrefix(marker, fence) && strings.TrimSpace(line) == marker {
+				fence = ""
+			}
+		default:
+			prose.WriteString(line)
+		}
+	}
+	flushProse()
+	return segments, fence
+}
+
+// fenceMarker returns the ``` or ~~~ run opening a fenced code line
+func fenceMarker(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return ""
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == trimmed[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// splitInlineCode splits prose around `inline code` spans. A backtick run
+// without a closing run of the same length is literal text.
+func splitInlineCode(text string) []segment {
+	var segments []segment
+	start := 0
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		n := 0
+		for i+n < len(text) && text[i+n] == '`' {
+			n++
+		}
+		end := closingRun(text, i+n, n)
+		if end < 0 {
+			i += n
+			continue
+		}
+		if i > start {
+			segments = append(segments, segment{text: text[start:i]})
+		}
+		segments = append(segments, segment{text: text[i:end], code: true})
+		start, i = end, end
+	}
+	if start < len(text) {
+		segments = append(segments, segment{text: text[start:]})
+	}
+	return segments
+}
+
+// closingRun returns the end of the next run of exactly n backticks at or
+// after from, -1 if there is none
+func closingRun(text string, from, n int) int {
+	for i := from; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		run := 0
+		for i+run < len(text) && text[i+run] == '`' {
+			run++
+		}
+		if run == n {
+			return i + run
+		}
+		i += run
+	}
+	return -1
+}
+
+// mapProse applies fn to the prose in text, leaving code untouched
+func mapProse(text string, fn func(string) string) string {
+	segments, _ := splitCode(text)
+	var sb strings.Builder
+	for _, seg := range segments {
+		if seg.code {
+			sb.WriteString(seg.text)
+		} else {
+			sb.WriteString(fn(seg.text))
+		}
+	}
+	return sb.String()
+}
+
+var (
+	scriptURLLinkRe  = regexp.MustCompile(`(?i)\]\(\s*<?\s*(javascript|vbscript):(?:[^()\s]|\([^()]*\))*\)`)
+	blankLinesRe     = regexp.MustCompile(`\n{3,}`)
+	bulletRe         = regexp.MustCompile(`(?m)^(\s*)• `)
+	citationMarkerRe = regexp.MustCompile(`\[(\d+)\](\()?`)
+)
+
+// normalizeMarkdown tidies model markdown: Unix line endings, at most one
+// blank line between blocks, "-" bullets and no unterminated code fence
+type normalizeMarkdown struct{}
+
+func (normalizeMarkdown) Name() string { return StepNormalizeMarkdown }
+
+func (normalizeMarkdown) Process(ctx context.Context, text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = mapProse(text, func(prose string) string {
+		prose = blankLinesRe.ReplaceAllString(prose, "\n\n")
+		return bulletRe.ReplaceAllString(prose, "$1- ")
+	})
+	text = strings.Trim(text, "\n")
+	if _, fence := splitCode(text); fence != "" {
+		text += "\n" + fence
+	}
+	return text
+}
+
+// Append closes a code fence the stream left open
+func (normalizeMarkdown) Append(ctx context.Context, streamed string) string {
+	if _, fence := splitCode(streamed); fence != "" {
+		return "\n" + fence
+	}
+	return ""
+}
+
+// citations lists the request's retrieved sources after the response. When
+// the response cites sources as [n] only those are listed, otherwise all.
+type citations struct{}
+
+func (citations) Name() string { return StepCitations }
+
+func (c citations) Process(ctx context.Context, text string) string {
+	return text + c.Append(ctx, text)
+}
+
+func (citations) Append(ctx context.Context, streamed string) string {
+	sources := SourcesFromContext(ctx)
+	if len(sources) == 0 {
+		return ""
+	}
+
+	cited := citedSources(streamed, len(sources))
+	if len(cited) == 0 {
+		for i := range sources {
+			cited = append(cited, i+1)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\nSources:")
+	for _, n := range cited {
+		src := sources[n-1]
+		switch {
+		case src.Title != "" && src.URL != "":
+			fmt.Fprintf(&sb, "\n[%d] [%s](%s)", n, src.Title, src.URL)
+		case src.URL != "":
+			fmt.Fprintf(&sb, "\n[%d] <%s>", n, src.URL)
+		default:
+			fmt.Fprintf(&sb, "\n[%d] %s", n, src.Title)
+		}
+	}
+	return sb.String()
+}
+
+// citedSources returns the source numbers cited as [n] in the prose of
+// text, in order. Markdown links like [1](url) are not citations.
+func citedSources(text string, count int) []int {
+	seen := make(map[int]bool)
+	var cited []int
+	mapProse(text, func(prose string) string {
+		for _, m := range citationMarkerRe.FindAllStringSubmatch(prose, -1) {
+			if m[2] != "" {
+				continue
+			}
+			n, err := strconv.Atoi(m[1])
+			if err != nil || n < 1 || n > count || seen[n] {
+				continue
+			}
+			seen[n] = true
+			cited = append(cited, n)
+		}
+		return prose
+	})
+	sort.Ints(cited)
+	return cited
+}
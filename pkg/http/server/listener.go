@@ -0,0 +1,115 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+)
+
+// Listener is an address the server's routes are served on under its own
+// policy, e.g. a loopback-only admin listener without authentication next
+// to a LAN listener that requires it
+type Listener struct {
+	Name       string
+	Addr       string
+	Groups     []Group                 // route groups served, empty = all
+	Skip       []string                // named middleware bypassed, see middleware.Skippable
+	Middleware []middleware.Middleware // runs on every request, before the route's chain
+	TLS        *tls.Config             // with certificates loaded, nil = plain HTTP
+}
+
+// ServeListener serves l until the server is shut down
+func (s *Server) ServeListener(l Listener) error {
+	srv := &http.Server{
+		Addr:              l.Addr,
+		Handler:           s.listenerHandler(l),
+		ReadHeaderTimeout: s.timeouts.ReadHeader,
+		IdleTimeout:       s.timeouts.Idle,
+		TLSConfig:         l.TLS,
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	s.listeners = append(s.listeners, srv)
+	s.mu.Unlock()
+
+	if l.TLS != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// listenerHandler serves the routes of l's groups with its middleware, and
+// 404s the rest
+func (s *Server) listenerHandler(l Listener) http.Handler {
+	routes := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.exposes(l.Groups, r) {
+			http.NotFound(w, r)
+			return
+		}
+		s.serve(w, r)
+	})
+	handler := middleware.Chain(l.Middleware...)(routes)
+	if slices.Contains(l.Skip, "auth") {
+		handler = localOnly(handler)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(middleware.WithSkipped(r.Context(), l.Skip)))
+	})
+}
+
+// exposes reports whether the route r matches belongs to one of groups.
+// Paths matching no route are left to the mux to 404 or redirect.
+func (s *Server) exposes(groups []Group, r *http.Request) bool {
+	if len(groups) == 0 {
+		return true
+	}
+	_, pattern := s.mux.Handler(r)
+
+	s.mu.RLock()
+	group, ok := s.groups[pattern]
+	s.mu.RUnlock()
+	return !ok || slices.Contains(groups, group)
+}
+
+// localOnly guards a listener that skips authentication. Such a listener
+// is bound to loopback, but a browser on the same machine can still reach
+// it: a page POSTing cross-origin sends an Origin header, and one using
+// DNS rebinding names its own host. Local tools such as curl and proxyctl
+// send neither.
+func localOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			http.Error(w, "Cross-origin requests are not allowed on this listener", http.StatusForbidden)
+			return
+		}
+		if !loopbackHost(r.Host) {
+			http.Error(w, "Host must be a loopback address on this listener", http.StatusMisdirectedRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackHost reports whether a Host header names localhost or a
+// loopback address, with or without a port
+func loopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+)
+
+func TestServer_ListenerPolicy(t *testing.T) {
+	s := New(":0", Timeouts{})
+	s.Use(Admin, middleware.Skippable("auth", tag("auth")))
+	s.HandleFunc(Health, "/healthz", ok)
+	s.HandleFunc(Admin, "/admin/drain", ok)
+	s.HandleFunc(Inference, "/v1/models", ok)
+
+	// A loopback admin listener: no inference, no authentication
+	admin := s.listenerHandler(Listener{Name: "admin", Groups: []Group{Health, Admin}, Skip: []string{"auth"}, Middleware: []middleware.Middleware{tag("local")}})
+	// A LAN listener serving everything with authentication
+	lan := s.listenerHandler(Listener{Name: "lan"})
+
+	tests := []struct {
+		handler http.Handler
+		path    string
+		code    int
+		chain   string
+	}{
+		{admin, "/healthz", http.StatusOK, "local"},
+		{admin, "/admin/drain", http.StatusOK, "local"},
+		{admin, "/v1/models", http.StatusNotFound, "local"},
+		{lan, "/admin/drain", http.StatusOK, "auth"},
+		{lan, "/v1/models", http.StatusOK, ""},
+	}
+	for i, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:8080"+tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%d %s: expected %d, got %d", i, tt.path, tt.code, rec.Code)
+		}
+		if got := strings.Join(rec.Header().Values("X-Chain"), ","); got != tt.chain {
+			t.Errorf("%d %s: expected chain %q, got %q", i, tt.path, tt.chain, got)
+		}
+	}
+}
+
+func TestServer_AuthSkippedListenerIsLocalOnly(t *testing.T) {
+	s := New(":0", Timeouts{})
+	s.HandleFunc(Admin, "/admin/stop-all", ok)
+	local := s.listenerHandler(Listener{Name: "admin", Skip: []string{"auth"}})
+	lan := s.listenerHandler(Listener{Name: "lan"})
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		url     string
+		origin  string
+		code    int
+	}{
+		{"curl on loopback", local, "http://127.0.0.1:8081/admin/stop-all", "", http.StatusOK},
+		{"localhost", local, "http://localhost/admin/stop-all", "", http.StatusOK},
+		{"IPv6 loopback", local, "http://[::1]:8081/admin/stop-all", "", http.StatusOK},
+		{"cross-origin page", local, "http://127.0.0.1:8081/admin/stop-all", "https://evil.example", http.StatusForbidden},
+		{"DNS rebinding", local, "http://evil.example:8081/admin/stop-all", "", http.StatusMisdirectedRequest},
+		{"authenticated listener", lan, "http://proxy.lan/admin/stop-all", "https://dash.lan", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.url, nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		tt.handler.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.code, rec.Code)
+		}
+	}
+}
+
+func TestServer_ShutdownStopsListeners(t *testing.T) {
+	s := New(":0", Timeouts{})
+	s.HandleFunc(Health, "/healthz", ok)
+
+	errc := make(chan error, 1)
+	go func() { errc <- s.ServeListener(Listener{Name: "local", Addr: "127.0.0.1:0"}) }()
+
+	// Wait for the listener to be tracked before shutting down
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.RLock()
+		n := len(s.listeners)
+		s.mu.RUnlock()
+		if n == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("Expected ErrServerClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Listener still serving after shutdown")
+	}
+
+	// Listeners started after shutdown do not serve
+	if err := s.ServeListener(Listener{Name: "late", Addr: "127.0.0.1:0"}); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Expected ErrServerClosed for a late listener, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+// Package server builds the proxy's HTTP front end. Each Server owns its own
+// mux, so several proxies can run side by side in one process, and routes are
+// registered into groups (health, admin, inference) that each carry their
+// own middleware chain.
+//
+// Timeouts are applied per route: streaming routes (SSE, WebSocket, chat
+// completions) get their own write deadline, usually much longer than the
+// one for ordinary request/response routes.
+//
+// The same routes can be served on several listeners, each exposing some of
+// the groups under its own policy (see Listener).
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+)
+
+// Group is a set of routes sharing a middleware chain
+type Group string
+
+const (
+	Health    Group = "health"    // probes, status and documentation
+	Admin     Group = "admin"     // runtime control, behind authentication
+	Inference Group = "inference" // OpenAI and Ollama compatible APIs
+)
+
+// DefaultReadHeaderTimeout bounds how long a client may take to send request headers
+const DefaultReadHeaderTimeout = 10 * time.Second
+
+// DefaultIdleTimeout is how long a keep-alive connection may sit unused
+const DefaultIdleTimeout = 2 * time.Minute
+
+// Timeouts configures the server. Zero Read, Write and StreamWrite mean no limit.
+type Timeouts struct {
+	ReadHeader  time.Duration // request headers, defaults to DefaultReadHeaderTimeout
+	Read        time.Duration // whole request including the body
+	Write       time.Duration // response of a non-streaming route
+	StreamWrite time.Duration // response of a streaming route
+	Idle        time.Duration // keep-alive connections, defaults to DefaultIdleTimeout
+}
+
+// Route describes a registered route
+type Route struct {
+	Pattern   string `json:"pattern"`
+	Group     Group  `json:"group"`
+	Streaming bool   `json:"streaming"`
+}
+
+// Server serves the proxy's HTTP routes from its own mux
+type Server struct {
+	mux      *http.ServeMux
+	timeouts Timeouts
+	srv      *http.Server
+
+	mu        sync.RWMutex
+	chains    map[Group][]middleware.Middleware
+	outer     []middleware.Middleware
+	routes    []Route
+	groups    map[string]Group // route pattern -> group
+	handler   http.Handler
+	listeners []*http.Server
+	closed    bool
+}
+
+// New creates a server listening on addr once started
+func New(addr string, timeouts Timeouts) *Server {
+	if timeouts.ReadHeader == 0 {
+		timeouts.ReadHeader = DefaultReadHeaderTimeout
+	}
+	if timeouts.Idle == 0 {
+		timeouts.Idle = DefaultIdleTimeout
+	}
+
+	s := &Server{
+		mux:      http.NewServeMux(),
+		timeouts: timeouts,
+		chains:   make(map[Group][]middleware.Middleware),
+		groups:   make(map[string]Group),
+	}
+	s.handler = s.mux
+	// Read and write deadlines are set per route, so the server-wide ones
+	// stay unset and cannot cut a stream short
+	s.srv = &http.Server{
+		Addr:              addr,
+		Handler:           http.HandlerFunc(s.serve),
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		IdleTimeout:       timeouts.Idle,
+	}
+	return s
+}
+
+// Use appends middleware to a group's chain. The first one listed runs
+// first. Routes already registered in the group keep the chain they had.
+func (s *Server) Use(group Group, mws ...middleware.Middleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chains[group] = append(s.chains[group], mws...)
+}
+
+// Wrap adds middleware around the whole mux, e.g. to set headers on every response
+func (s *Server) Wrap(mw middleware.Middleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outer = append(s.outer, mw)
+	s.handler = middleware.Chain(s.outer...)(s.mux)
+}
+
+// Handle registers a non-streaming route in a group
+func (s *Server) Handle(group Group, pattern string, handler http.Handler) {
+	s.handle(group, pattern, handler, false)
+}
+
+// HandleFunc registers a non-streaming handler function in a group
+func (s *Server) HandleFunc(group Group, pattern string, handler http.HandlerFunc) {
+	s.handle(group, pattern, handler, false)
+}
+
+// HandleStream registers a route whose responses may stream, such as SSE
+// or WebSocket, so it gets the streaming write timeout
+func (s *Server) HandleStream(group Group, pattern string, handler http.Handler) {
+	s.handle(group, pattern, handler, true)
+}
+
+func (s *Server) handle(group Group, pattern string, handler http.Handler, streaming bool) {
+	s.mu.Lock()
+	chain := middleware.Chain(s.chains[group]...)
+	s.routes = append(s.routes, Route{Pattern: pattern, Group: group, Streaming: streaming})
+	s.groups[pattern] = group
+	s.mu.Unlock()
+
+	write := s.timeouts.Write
+	if streaming {
+		write = s.timeouts.StreamWrite
+	}
+	s.mux.Handle(pattern, deadlines(s.timeouts.Read, write, chain(handler)))
+}
+
+// Routes returns the registered routes sorted by pattern
+func (s *Server) Routes() []Route {
+	s.mu.RLock()
+	routes := make([]Route, len(s.routes))
+	copy(routes, s.routes)
+	s.mu.RUnlock()
+
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Pattern < routes[j].Pattern })
+	return routes
+}
+
+// Handler returns the mux with its outer middleware, for serving the same
+// routes from another listener
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serve)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	handler := s.handler
+	s.mu.RUnlock()
+	handler.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves plain HTTP until the server is shut down
+func (s *Server) ListenAndServe() error {
+	return s.srv.ListenAndServe()
+}
+
+// ListenAndServeTLS serves HTTPS until the server is shut down
+func (s *Server) ListenAndServeTLS(config *tls.Config, certFile, keyFile string) error {
+	s.srv.TLSConfig = config
+	return s.srv.ListenAndServeTLS(certFile, keyFile)
+}
+
+// Shutdown stops accepting connections on every listener and waits for
+// active ones until ctx ends
+func (s *Server) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, srv := range s.stop() {
+		errs = append(errs, srv.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every listener and all connections immediately
+func (s *Server) Close() error {
+	var errs []error
+	for _, srv := range s.stop() {
+		errs = append(errs, srv.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// stop marks the server closed, so no listener starts after it, and returns
+// the http.Servers to stop
+func (s *Server) stop() []*http.Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return append([]*http.Server{s.srv}, s.listeners...)
+}
+
+// deadlines sets the connection's read and write deadlines for one request.
+// Writers that cannot take deadlines (e.g. test recorders) are served as is.
+func deadlines(read, write time.Duration, next http.Handler) http.Handler {
+	if read <= 0 && write <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		now := time.Now()
+		if read > 0 {
+			_ = rc.SetReadDeadline(now.Add(read))
+		}
+		if write > 0 {
+			_ = rc.SetWriteDeadline(now.Add(write))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
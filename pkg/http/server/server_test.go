@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+)
+
+// tag returns middleware that appends name to the X-Chain response header
+func tag(name string) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "ok")
+}
+
+func TestServer_GroupChains(t *testing.T) {
+	s := New(":0", Timeouts{})
+	s.Use(Admin, tag("recover"), tag("auth"))
+	s.Use(Inference, tag("drain"))
+	s.HandleFunc(Health, "/healthz", ok)
+	s.Handle(Admin, "/admin/drain", http.HandlerFunc(ok))
+	s.HandleStream(Inference, "/v1/chat/completions", http.HandlerFunc(ok))
+
+	tests := []struct {
+		path  string
+		chain string
+	}{
+		{"/healthz", ""},
+		{"/admin/drain", "recover,auth"},
+		{"/v1/chat/completions", "drain"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", tt.path, rec.Code)
+		}
+		if got := strings.Join(rec.Header().Values("X-Chain"), ","); got != tt.chain {
+			t.Errorf("%s: expected chain %q, got %q", tt.path, tt.chain, got)
+		}
+	}
+
+	routes := s.Routes()
+	if len(routes) != 3 || routes[0].Pattern != "/admin/drain" || routes[0].Group != Admin {
+		t.Fatalf("Unexpected routes %+v", routes)
+	}
+	if !routes[2].Streaming || routes[1].Streaming {
+		t.Errorf("Expected only the inference route streaming, got %+v", routes)
+	}
+}
+
+func TestServer_AdminGroupRequiresAdmin(t *testing.T) {
+	keys := auth.Config{Enabled: true, APIKeys: map[string]auth.APIKeyInfo{
+		"admin-key":     {Name: "ops", Permissions: []string{auth.PermissionAdmin}, Enabled: true},
+		"inference-key": {Name: "app", Permissions: []string{"inference"}, Enabled: true},
+	}}
+	s := New(":0", Timeouts{})
+	s.Use(Admin, auth.APIKeyMiddleware(keys), auth.RequirePermission(auth.PermissionAdmin))
+	s.Use(Inference, auth.APIKeyMiddleware(keys))
+	s.HandleFunc(Health, "/healthz", ok)
+	s.HandleFunc(Inference, "/v1/models", ok)
+	for _, pattern := range []string{"/admin/drain", "/admin/stop-all", "/admin/models", "/admin/diagnostics", "/admin/ha", "/v1/usage"} {
+		s.HandleFunc(Admin, pattern, ok)
+	}
+	s.HandleStream(Admin, "/admin/events", http.HandlerFunc(ok))
+
+	serve := func(method, path, key string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	admin := 0
+	for _, route := range s.Routes() {
+		if route.Group != Admin {
+			continue
+		}
+		admin++
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+			if code := serve(method, route.Pattern, "inference-key"); code != http.StatusForbidden {
+				t.Errorf("%s %s: expected 403 for a non-admin key, got %d", method, route.Pattern, code)
+			}
+		}
+		if code := serve(http.MethodGet, route.Pattern, "admin-key"); code != http.StatusOK {
+			t.Errorf("%s: expected 200 for the admin key, got %d", route.Pattern, code)
+		}
+	}
+	if admin != 7 {
+		t.Errorf("Expected 7 admin routes walked, got %d", admin)
+	}
+	if code := serve(http.MethodGet, "/v1/models", "inference-key"); code != http.StatusOK {
+		t.Errorf("Expected inference routes open to the inference key, got %d", code)
+	}
+}
+
+func TestServer_Wrap(t *testing.T) {
+	s := New(":0", Timeouts{})
+	s.Use(Health, tag("recover"))
+	s.HandleFunc(Health, "/healthz", ok)
+	s.Wrap(tag("outer"))
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if got := strings.Join(rec.Header().Values("X-Chain"), ","); got != "outer,recover" {
+		t.Errorf("Expected outer middleware first, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound || rec.Header().Get("X-Chain") != "outer" {
+		t.Errorf("Expected outer middleware on unknown paths too, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestServer_IndependentInstances(t *testing.T) {
+	a := New(":0", Timeouts{})
+	b := New(":0", Timeouts{})
+	a.HandleFunc(Health, "/healthz", ok)
+	b.HandleFunc(Health, "/healthz", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "b")
+	})
+
+	for want, s := range map[string]*Server{"ok": a, "b": b} {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if rec.Body.String() != want {
+			t.Errorf("Expected %q, got %q", want, rec.Body.String())
+		}
+	}
+}
+
+func TestServer_WriteTimeouts(t *testing.T) {
+	s := New(":0", Timeouts{Write: 50 * time.Millisecond})
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		io.WriteString(w, "done")
+	}
+	s.HandleFunc(Health, "/healthz", ok)
+	s.HandleFunc(Inference, "/v1/models", slow)
+	s.HandleStream(Inference, "/v1/chat/completions", http.HandlerFunc(slow))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.srv.Serve(lis)
+	defer s.Shutdown(context.Background())
+	base := "http://" + lis.Addr().String()
+
+	// The streaming route has no limit, even on a connection whose last
+	// request set a deadline
+	for i, path := range []string{"/healthz", "/v1/chat/completions"} {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "done" && string(body) != "ok" {
+			t.Errorf("Request %d: expected the full response, got %q", i, body)
+		}
+	}
+
+	// The non-streaming route exceeds its deadline and the connection drops
+	if resp, err := http.Get(base + "/v1/models"); err == nil {
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr == nil && string(body) == "done" {
+			t.Error("Expected the non-streaming route cut off by the write timeout")
+		}
+	}
+}
+
+func TestNew_Defaults(t *testing.T) {
+	s := New(":8080", Timeouts{Idle: time.Minute})
+	if s.srv.ReadHeaderTimeout != DefaultReadHeaderTimeout || s.srv.IdleTimeout != time.Minute {
+		t.Errorf("Unexpected server timeouts %+v", s.srv)
+	}
+	if s.srv.ReadTimeout != 0 || s.srv.WriteTimeout != 0 {
+		t.Error("Expected read and write deadlines left to routes")
+	}
+}
@@ -0,0 +1,166 @@
+// Package shaping fills in generation settings a client leaves unset.
+// Defaults for max_tokens and temperature come from the server, the
+// requested model and the current efficiency mode, so generations stay
+// bounded in battery modes without clients having to know about them. An
+// API key may override the server's defaults. Every HTTP protocol (OpenAI,
+// Ollama and WebSocket) shapes its requests through the same Shaper.
+package shaping
+
+import (
+	"context"
+	"path"
+	"sort"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/efficiency"
+)
+
+// Defaults are generation settings for requests that leave them unset. A
+// zero MaxTokens or nil Temperature sets nothing.
+type Defaults struct {
+	MaxTokens   int32
+	Temperature *float32
+}
+
+// Config configures a Shaper
+type Config struct {
+	Default Defaults                               // every request
+	Models  map[string]Defaults                    // model name or glob, e.g. "llama3:*"
+	Modes   map[efficiency.EfficiencyMode]Defaults // the effective efficiency mode
+
+	// Limit caps max_tokens on every request, including values the client
+	// or its API key set (nil = no cap)
+	Limit LimitSource
+}
+
+// ModeSource returns the effective efficiency mode
+type ModeSource func() efficiency.EfficiencyMode
+
+// LimitSource returns the max_tokens cap currently in force, 0 for none
+type LimitSource func() int32
+
+// Shaper resolves the defaults for a request. A nil Shaper leaves requests
+// unchanged.
+type Shaper struct {
+	cfg   Config
+	globs []string // model globs, most specific first
+	mode  ModeSource
+}
+
+// New creates a Shaper. mode may be nil when efficiency modes are disabled,
+// in which case Config.Modes is ignored.
+func New(cfg Config, mode ModeSource) *Shaper {
+	s := &Shaper{cfg: cfg, mode: mode}
+	for pattern := range cfg.Models {
+		if isGlob(pattern) {
+			s.globs = append(s.globs, pattern)
+		}
+	}
+	// A longer pattern is the more specific one
+	sort.Slice(s.globs, func(i, j int) bool {
+		if len(s.globs[i]) != len(s.globs[j]) {
+			return len(s.globs[i]) > len(s.globs[j])
+		}
+		return s.globs[i] < s.globs[j]
+	})
+	return s
+}
+
+// Resolve returns the defaults for a request for model. The model's
+// defaults override the server's; the efficiency mode's max_tokens then
+// caps the result, so a battery mode can only shorten generations, and its
+// temperature wins. The calling API key's own defaults override all of
+// them.
+func (s *Shaper) Resolve(ctx context.Context, model string) Defaults {
+	if s == nil {
+		return Defaults{}
+	}
+
+	d := s.cfg.Default
+	if m, ok := s.forModel(model); ok {
+		d = d.override(m)
+	}
+	if s.mode != nil {
+		if m, ok := s.cfg.Modes[s.mode()]; ok {
+			if m.MaxTokens > 0 && (d.MaxTokens == 0 || m.MaxTokens < d.MaxTokens) {
+				d.MaxTokens = m.MaxTokens
+			}
+			if m.Temperature != nil {
+				d.Temperature = m.Temperature
+			}
+		}
+	}
+	if info, ok := auth.KeyInfoFromContext(ctx); ok && info.Shaping != nil {
+		d = d.override(Defaults{MaxTokens: info.Shaping.MaxTokens, Temperature: info.Shaping.Temperature})
+	}
+	return d
+}
+
+// Apply fills the options the client left unset with the defaults for
+// model. maxTokensSet and temperatureSet report whether the client set
+// them; explicit values, including a zero temperature, are never changed
+// except that max_tokens is held to Config.Limit.
+func (s *Shaper) Apply(ctx context.Context, model string, opts *backends.GenerationOptions, maxTokensSet, temperatureSet bool) {
+	if s == nil || opts == nil {
+		return
+	}
+	if !maxTokensSet || !temperatureSet {
+		d := s.Resolve(ctx, model)
+		if !maxTokensSet && d.MaxTokens > 0 {
+			opts.MaxTokens = d.MaxTokens
+		}
+		if !temperatureSet && d.Temperature != nil {
+			opts.Temperature = *d.Temperature
+			opts.TemperatureSet = true
+		}
+	}
+	if s.cfg.Limit != nil {
+		if limit := s.cfg.Limit(); limit > 0 && (opts.MaxTokens <= 0 || opts.MaxTokens > limit) {
+			opts.MaxTokens = limit
+		}
+	}
+}
+
+// forModel returns the defaults for model: an exact name wins over globs
+func (s *Shaper) forModel(model string) (Defaults, bool) {
+	if d, ok := s.cfg.Models[model]; ok {
+		return d, true
+	}
+	for _, pattern := range s.globs {
+		if ok, _ := path.Match(pattern, model); ok {
+			return s.cfg.Models[pattern], true
+		}
+	}
+	return Defaults{}, false
+}
+
+// override returns d with the settings o sets
+func (d Defaults) override(o Defaults) Defaults {
+	if o.MaxTokens > 0 {
+		d.MaxTokens = o.MaxTokens
+	}
+	if o.Temperature != nil {
+		d.Temperature = o.Temperature
+	}
+	return d
+}
+
+func isGlob(pattern string) bool {
+	for _, c := range pattern {
+		switch c {
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// Default is the Shaper applied by the HTTP handlers, nil when request
+// shaping is disabled
+var Default *Shaper
+
+// SetDefault sets the Shaper applied by the HTTP handlers
+func SetDefault(s *Shaper) {
+	Default = s
+}
@@ -0,0 +1,148 @@
+package shaping
+
+import (
+	"context"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/efficiency"
+)
+
+func temp(v float32) *float32 { return &v }
+
+func testShaper(mode *efficiency.EfficiencyMode) *Shaper {
+	return New(Config{
+		Default: Defaults{MaxTokens: 2048, Temperature: temp(0.7)},
+		Models: map[string]Defaults{
+			"llama3:*":         {MaxTokens: 1024},
+			"llama3:70b*":      {MaxTokens: 512},
+			"codellama:7b":     {Temperature: temp(0.2)},
+			"mistral:instruct": {MaxTokens: 4096},
+		},
+		Modes: map[efficiency.EfficiencyMode]Defaults{
+			efficiency.ModeUltraEfficiency: {MaxTokens: 256, Temperature: temp(0.5)},
+			efficiency.ModeEfficiency:      {MaxTokens: 4096},
+		},
+	}, func() efficiency.EfficiencyMode { return *mode })
+}
+
+func TestResolve(t *testing.T) {
+	mode := efficiency.ModeBalanced
+	s := testShaper(&mode)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		mode      efficiency.EfficiencyMode
+		model     string
+		maxTokens int32
+		temp      float32
+	}{
+		{"server default", efficiency.ModeBalanced, "phi3", 2048, 0.7},
+		{"model glob", efficiency.ModeBalanced, "llama3:8b", 1024, 0.7},
+		{"most specific glob", efficiency.ModeBalanced, "llama3:70b-instruct", 512, 0.7},
+		{"exact model", efficiency.ModeBalanced, "codellama:7b", 2048, 0.2},
+		{"battery mode caps", efficiency.ModeUltraEfficiency, "mistral:instruct", 256, 0.5},
+		{"mode never lengthens", efficiency.ModeEfficiency, "llama3:8b", 1024, 0.7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode = tt.mode
+			d := s.Resolve(ctx, tt.model)
+			if d.MaxTokens != tt.maxTokens {
+				t.Errorf("Expected max_tokens %d, got %d", tt.maxTokens, d.MaxTokens)
+			}
+			if d.Temperature == nil || *d.Temperature != tt.temp {
+				t.Errorf("Expected temperature %v, got %v", tt.temp, d.Temperature)
+			}
+		})
+	}
+}
+
+func TestResolve_KeyOverride(t *testing.T) {
+	mode := efficiency.ModeUltraEfficiency
+	s := testShaper(&mode)
+	ctx := auth.WithKeyInfo(context.Background(), auth.APIKeyInfo{
+		Name:    "batch",
+		Shaping: &auth.Shaping{MaxTokens: 8192},
+	})
+
+	d := s.Resolve(ctx, "llama3:8b")
+	if d.MaxTokens != 8192 {
+		t.Errorf("Expected the key's max_tokens 8192 to win, got %d", d.MaxTokens)
+	}
+	if d.Temperature == nil || *d.Temperature != 0.5 {
+		t.Errorf("Expected the mode's temperature to remain, got %v", d.Temperature)
+	}
+}
+
+func TestApply(t *testing.T) {
+	mode := efficiency.ModeUltraEfficiency
+	s := testShaper(&mode)
+	ctx := context.Background()
+
+	opts := &backends.GenerationOptions{}
+	s.Apply(ctx, "phi3", opts, false, false)
+	if opts.MaxTokens != 256 || opts.Temperature != 0.5 {
+		t.Errorf("Expected unset options to take the defaults, got max_tokens=%d temperature=%v", opts.MaxTokens, opts.Temperature)
+	}
+
+	// Explicit values, including a zero temperature, are kept
+	opts = &backends.GenerationOptions{MaxTokens: 4000}
+	s.Apply(ctx, "phi3", opts, true, true)
+	if opts.MaxTokens != 4000 || opts.Temperature != 0 {
+		t.Errorf("Expected explicit options to be kept, got max_tokens=%d temperature=%v", opts.MaxTokens, opts.Temperature)
+	}
+
+	var disabled *Shaper
+	opts = &backends.GenerationOptions{}
+	disabled.Apply(ctx, "phi3", opts, false, false)
+	if opts.MaxTokens != 0 {
+		t.Errorf("Expected a nil shaper to leave options unchanged, got max_tokens=%d", opts.MaxTokens)
+	}
+}
+
+func TestResolve_NoModeSource(t *testing.T) {
+	s := New(Config{
+		Modes: map[efficiency.EfficiencyMode]Defaults{
+			efficiency.ModeBalanced: {MaxTokens: 128},
+		},
+	}, nil)
+	if d := s.Resolve(context.Background(), "phi3"); d.MaxTokens != 0 {
+		t.Errorf("Expected mode defaults to be ignored without efficiency modes, got %d", d.MaxTokens)
+	}
+}
+
+func TestApply_Limit(t *testing.T) {
+	limit := int32(300)
+	s := New(Config{
+		Default: Defaults{MaxTokens: 2048},
+		Limit:   func() int32 { return limit },
+	}, nil)
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name      string
+		maxTokens int32
+		set       bool
+		want      int32
+	}{
+		{"default capped", 0, false, 300},
+		{"explicit capped", 4000, true, 300},
+		{"explicit under the cap", 100, true, 100},
+	} {
+		opts := &backends.GenerationOptions{MaxTokens: tt.maxTokens}
+		s.Apply(ctx, "phi3", opts, tt.set, true)
+		if opts.MaxTokens != tt.want {
+			t.Errorf("%s: expected max_tokens %d, got %d", tt.name, tt.want, opts.MaxTokens)
+		}
+	}
+
+	limit = 0
+	opts := &backends.GenerationOptions{MaxTokens: 4000}
+	s.Apply(ctx, "phi3", opts, true, true)
+	if opts.MaxTokens != 4000 {
+		t.Errorf("Expected no cap when the limit lifts, got %d", opts.MaxTokens)
+	}
+}
@@ -8,8 +8,13 @@ import (
 	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/http/postprocess"
+	"github.com/daoneill/ollama-proxy/pkg/http/shaping"
 	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
 	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
@@ -17,10 +22,7 @@ import (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// TODO: Implement proper CORS policy
-		return true
-	},
+	CheckOrigin:     CheckOrigin,
 }
 
 // WebSocketRequest represents an incoming WebSocket request
@@ -32,6 +34,12 @@ type WebSocketRequest struct {
 	Stream      bool                   `json:"stream,omitempty"`
 	Priority    string                 `json:"priority,omitempty"` // "best-effort", "normal", "high", "critical"
 	MaxLatency  int32                  `json:"max_latency_ms,omitempty"`
+
+	// Sources retrieved for the prompt, cited by the citations post-processing step
+	Sources []postprocess.Source `json:"sources,omitempty"`
+
+	// Text received before an earlier stream broke; the stream continues it
+	ResumeFrom string `json:"resume_from,omitempty"`
 }
 
 // WebSocketChunk represents a streaming response chunk
@@ -41,17 +49,30 @@ type WebSocketChunk struct {
 	Done      bool    `json:"done"`
 	Error     *string `json:"error,omitempty"`
 
+	// Status frames sent before the first token, e.g. while a model loads
+	Status     string  `json:"status,omitempty"`
+	ColdStart  string  `json:"cold_start,omitempty"`  // model_load or container_start
+	EstimateMs int64   `json:"estimate_ms,omitempty"` // expected wait, 0 if unknown
+	Progress   float64 `json:"progress,omitempty"`    // percent of the wait elapsed
+	EtaMs      int64   `json:"eta_ms,omitempty"`      // remaining wait, 0 if unknown
+
 	// Performance metrics
 	TTFT        int64   `json:"ttft_ms,omitempty"`         // Time to first token
 	TotalTimeMs int64   `json:"total_time_ms,omitempty"`
 	TokenCount  int     `json:"token_count,omitempty"`
 	TokensPerSec float32 `json:"tokens_per_sec,omitempty"`
+
+	// Final chunk of a completed stream: token count and SHA-256 of the
+	// content, to detect a truncated or corrupted stream
+	Integrity *streaming.IntegritySummary `json:"integrity,omitempty"`
 }
 
 // WebSocketError represents an error response
 type WebSocketError struct {
-	Error     string `json:"error"`
-	RequestID string `json:"request_id,omitempty"`
+	Error        string `json:"error"`
+	Code         string `json:"code,omitempty"` // set for limit errors, e.g. "token_rate_exceeded"
+	RequestID    string `json:"request_id,omitempty"`
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"`
 }
 
 // HandleWebSocketStream provides low-latency WebSocket streaming
@@ -68,10 +89,45 @@ func HandleWebSocketStream(r *router.Router) http.HandlerFunc {
 		// Set read deadline
 		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 
+		limiter := limiterFor(clientID(req))
+
 		// Read initial request
 		var streamReq WebSocketRequest
-		if err := conn.ReadJSON(&streamReq); err != nil {
-			sendError(conn, "invalid request", streamReq.RequestID)
+		if err := limiter.readRequest(conn, &streamReq); err != nil {
+			if _, tooLarge := err.(errMessageTooLarge); !tooLarge {
+				sendError(conn, "invalid request", streamReq.RequestID)
+			}
+			return
+		}
+		if streamReq.RequestID == "" {
+			// Correlate with the upgrade request's X-Request-ID
+			streamReq.RequestID = middleware.GetRequestID(req.Context())
+		}
+		if len(streamReq.Sources) > postprocess.MaxSources {
+			sendError(conn, fmt.Sprintf("too many sources (max %d)", postprocess.MaxSources), streamReq.RequestID)
+			return
+		}
+		if streamReq.ResumeFrom != "" && !streamReq.Stream {
+			sendError(conn, "resume_from requires stream", streamReq.RequestID)
+			return
+		}
+		if len(streamReq.Sources) == 0 {
+			// Fall back to sources sent with the upgrade request
+			streamReq.Sources = postprocess.SourcesFromContext(req.Context())
+		}
+
+		if !limiter.acquire() {
+			sendLimitError(conn, CodeTooManyRequests,
+				fmt.Sprintf("too many concurrent requests for this client (limit %d)", limiter.limits.MaxConcurrentRequests),
+				streamReq.RequestID, 0)
+			return
+		}
+		defer limiter.release()
+
+		if !limiter.hasTokenBudget() {
+			sendLimitError(conn, CodeTokenRateExceeded,
+				fmt.Sprintf("token rate limit exceeded (%d tokens/min per client)", limiter.limits.TokensPerMinute),
+				streamReq.RequestID, limiter.tokenRetryAfter())
 			return
 		}
 
@@ -111,36 +167,89 @@ func HandleWebSocketStream(r *router.Router) http.HandlerFunc {
 
 		// Convert WebSocket request to internal format
 		internalReq := convertWebSocketRequest(&streamReq)
+		shapeWebSocketRequest(req.Context(), internalReq, &streamReq)
+
+		// Accounted against the key that authenticated the upgrade
+		tracker := usage.Track(req.Context(), decision, req.URL.Path, internalReq.Model, internalReq.Prompt)
 
 		// Start streaming
 		if streamReq.Stream {
-			handleStreamingRequest(conn, decision.Backend, internalReq, &streamReq)
+			loading := sendColdStart(req.Context(), conn, decision, internalReq.Model, streamReq.RequestID)
+			handleStreamingRequest(conn, limiter, tracker, decision.Backend, internalReq, &streamReq, loading)
 		} else {
-			handleNonStreamingRequest(conn, decision.Backend, internalReq, &streamReq)
+			handleNonStreamingRequest(conn, tracker, decision.Backend, internalReq, &streamReq)
 		}
 	}
 }
 
-// handleStreamingRequest processes a streaming WebSocket request
-func handleStreamingRequest(conn *websocket.Conn, backend backends.Backend, req *backends.GenerateRequest, wsReq *WebSocketRequest) {
-	ctx := context.Background()
+// sendColdStart sends a status frame ahead of the tokens when the backend
+// must load model first, so the client can show progress instead of
+// timing out. Progress frames follow until the returned function is
+// called with the outcome of the wait; it is nil when the model is warm.
+func sendColdStart(ctx context.Context, conn *websocket.Conn, decision *router.RoutingDecision, model, requestID string) func(error) {
+	cs := decision.ColdStart(ctx, model)
+	if cs == nil {
+		return nil
+	}
+	conn.WriteJSON(WebSocketChunk{
+		RequestID:  requestID,
+		Status:     cs.Message(),
+		ColdStart:  cs.Reason,
+		EstimateMs: cs.Estimate.Milliseconds(),
+	})
+
+	return backends.WatchLoad(decision.Backend.ID(), cs, func(p backends.Progress) {
+		conn.WriteJSON(WebSocketChunk{
+			RequestID: requestID,
+			Status:    p.Message(),
+			ColdStart: cs.Reason,
+			Progress:  p.Percent,
+			EtaMs:     p.ETAMs,
+		})
+	})
+}
+
+// handleStreamingRequest processes a streaming WebSocket request.
+// loading, when set, ends the cold start progress frames once the backend
+// has answered.
+func handleStreamingRequest(conn *websocket.Conn, limiter *connLimiter, tracker *usage.Tracker, backend backends.Backend, req *backends.GenerateRequest, wsReq *WebSocketRequest, loading func(error)) {
+	ctx := postprocess.WithSources(context.Background(), wsReq.Sources)
 	startTime := time.Now()
 
 	reader, err := backend.GenerateStream(ctx, req)
+	if loading != nil {
+		loading(err)
+	}
 	if err != nil {
+		tracker.FinishResponse(nil, err)
 		sendError(conn, fmt.Sprintf("stream start failed: %v", err), wsReq.RequestID)
 		return
 	}
 	defer reader.Close()
+	reader = tracker.Stream(postprocess.Default.Stream(ctx, reader))
+
+	// Record what was streamed, however the stream ends
+	var streamErr error
+	defer func() { tracker.FinishResponse(nil, streamErr) }()
+
+	// Track send pacing so constrained links can be batched
+	stream := streaming.Default.Open("websocket", conn.RemoteAddr().String(), req.Model)
+	defer stream.Close()
 
 	var firstTokenTime *time.Time
 	tokenCount := 0
+	integrity := streaming.NewIntegrity(wsReq.ResumeFrom)
 
 	// Stream chunks directly with minimal transformation
 	for {
 		chunk, err := reader.Recv()
+		if err == io.EOF && stream.Pending() > 0 {
+			// Flush tokens still held back by the pacer
+			chunk, err = &backends.StreamChunk{Done: true}, nil
+		}
 		if err != nil {
 			if err != io.EOF {
+				streamErr = err
 				errorMsg := err.Error()
 				wsChunk := WebSocketChunk{
 					RequestID: wsReq.RequestID,
@@ -159,15 +268,33 @@ func handleStreamingRequest(conn *websocket.Conn, backend backends.Backend, req
 		}
 		tokenCount++
 
+		// Hold tokens back while the client link is constrained
+		token, tokens := stream.Batch(chunk.Token, chunk.Done)
+		if tokens == 0 {
+			continue
+		}
+
+		// Stop the stream politely once the connection's token budget is spent
+		if !limiter.allowTokens(tokens) {
+			sendLimitError(conn, CodeTokenRateExceeded,
+				fmt.Sprintf("token rate limit exceeded (%d tokens/min per client)", limiter.limits.TokensPerMinute),
+				wsReq.RequestID, limiter.tokenRetryAfter())
+			break
+		}
+
 		// Passthrough mode: Send chunk with minimal transformation
 		wsChunk := WebSocketChunk{
 			RequestID: wsReq.RequestID,
-			Token:     chunk.Token,
+			Token:     token,
 			Done:      chunk.Done,
 		}
+		integrity.Add(token, tokens)
 
 		// Add metrics on final chunk
 		if chunk.Done {
+			summary := integrity.Summary()
+			wsChunk.Integrity = &summary
+
 			elapsed := time.Since(startTime)
 			wsChunk.TotalTimeMs = elapsed.Milliseconds()
 			wsChunk.TokenCount = tokenCount
@@ -183,10 +310,13 @@ func handleStreamingRequest(conn *websocket.Conn, backend backends.Backend, req
 
 		// Write with timeout
 		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		writeStart := time.Now()
 		if err := conn.WriteJSON(wsChunk); err != nil {
 			logging.Logger.Error("WebSocket write failed", zap.Error(err))
+			streamErr = err
 			break
 		}
+		stream.RecordWrite(len(token), tokens, time.Since(writeStart))
 
 		if chunk.Done {
 			break
@@ -195,15 +325,17 @@ func handleStreamingRequest(conn *websocket.Conn, backend backends.Backend, req
 }
 
 // handleNonStreamingRequest processes a non-streaming WebSocket request
-func handleNonStreamingRequest(conn *websocket.Conn, backend backends.Backend, req *backends.GenerateRequest, wsReq *WebSocketRequest) {
-	ctx := context.Background()
+func handleNonStreamingRequest(conn *websocket.Conn, tracker *usage.Tracker, backend backends.Backend, req *backends.GenerateRequest, wsReq *WebSocketRequest) {
+	ctx := postprocess.WithSources(context.Background(), wsReq.Sources)
 	startTime := time.Now()
 
 	response, err := backend.Generate(ctx, req)
+	tracker.FinishResponse(response, err)
 	if err != nil {
 		sendError(conn, fmt.Sprintf("generation failed: %v", err), wsReq.RequestID)
 		return
 	}
+	response = postprocess.Default.Response(ctx, response)
 
 	elapsed := time.Since(startTime)
 
@@ -223,10 +355,24 @@ func handleNonStreamingRequest(conn *websocket.Conn, backend backends.Backend, r
 	}
 }
 
+// shapeWebSocketRequest bounds a generation the client left open: unset
+// max_tokens and temperature take the request shaping defaults
+func shapeWebSocketRequest(ctx context.Context, req *backends.GenerateRequest, wsReq *WebSocketRequest) {
+	if shaping.Default == nil {
+		return
+	}
+	if req.Options == nil {
+		req.Options = &backends.GenerationOptions{}
+	}
+	_, maxTokensSet := wsReq.Options["max_tokens"].(float64)
+	_, temperatureSet := wsReq.Options["temperature"].(float64)
+	shaping.Default.Apply(ctx, wsReq.Model, req.Options, maxTokensSet, temperatureSet)
+}
+
 // convertWebSocketRequest converts WebSocket request to internal format
 func convertWebSocketRequest(wsReq *WebSocketRequest) *backends.GenerateRequest {
 	req := &backends.GenerateRequest{
-		Prompt: wsReq.Prompt,
+		Prompt: wsReq.Prompt + wsReq.ResumeFrom, // a resumed stream continues its text
 		Model:  wsReq.Model,
 	}
 
@@ -236,6 +382,7 @@ func convertWebSocketRequest(wsReq *WebSocketRequest) *backends.GenerateRequest
 
 		if temp, ok := wsReq.Options["temperature"].(float64); ok {
 			options.Temperature = float32(temp)
+			options.TemperatureSet = true
 		}
 		if topP, ok := wsReq.Options["top_p"].(float64); ok {
 			options.TopP = float32(topP)
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -8,15 +9,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/daoneill/ollama-proxy/pkg/audit"
+	"github.com/daoneill/ollama-proxy/pkg/auth"
 	"github.com/daoneill/ollama-proxy/pkg/backends"
 	"github.com/daoneill/ollama-proxy/pkg/logging"
 	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
 	"github.com/gorilla/websocket"
 )
 
@@ -243,6 +249,57 @@ func TestWebSocketStreamingMessageHandling(t *testing.T) {
 	}
 }
 
+// Test: Final chunk carries the integrity of a resumed stream
+func TestWebSocketStreamingIntegrity(t *testing.T) {
+	r := createTestRouter()
+	backend := &MockBackend{
+		id:      "mock1",
+		healthy: true,
+		streamChunks: []*backends.StreamChunk{
+			{Token: " world", Done: false},
+			{Token: "!", Done: true},
+		},
+	}
+	r.RegisterBackend(backend)
+
+	server := createTestServer(r)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to establish WebSocket connection: %v", err)
+	}
+	defer conn.Close()
+
+	req := WebSocketRequest{
+		RequestID:  "test-resume",
+		Model:      "test-model",
+		Prompt:     "Say hello",
+		Stream:     true,
+		ResumeFrom: "Hello",
+	}
+	if err := conn.WriteJSON(req); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	var chunk WebSocketChunk
+	for !chunk.Done {
+		if err := conn.ReadJSON(&chunk); err != nil {
+			t.Fatalf("Failed to read chunk: %v", err)
+		}
+		if !chunk.Done && chunk.Integrity != nil {
+			t.Error("Expected integrity only on the final chunk")
+		}
+	}
+
+	want := streaming.NewIntegrity("Hello")
+	want.Add(" world!", 2)
+	if chunk.Integrity == nil || *chunk.Integrity != want.Summary() {
+		t.Errorf("Expected integrity %+v, got %+v", want.Summary(), chunk.Integrity)
+	}
+}
+
 // Test: Message handling - non-streaming request
 func TestWebSocketNonStreamingMessageHandling(t *testing.T) {
 	r := createTestRouter()
@@ -1801,3 +1858,120 @@ func TestWebSocketReadDeadlineEnforcement(t *testing.T) {
 		t.Error("Expected Done=true")
 	}
 }
+
+// Test: streamed and single responses are recorded against the key that
+// authenticated the upgrade
+func TestWebSocketRecordsUsage(t *testing.T) {
+	recorder := usage.NewRecorder(usage.DefaultConfig())
+	prev := usage.Default()
+	usage.SetDefault(recorder)
+	defer usage.SetDefault(prev)
+
+	r := createTestRouter()
+	r.RegisterBackend(&MockBackend{
+		id:      "mock1",
+		healthy: true,
+		streamChunks: []*backends.StreamChunk{
+			{Token: "hello"},
+			{Token: " world", Done: true, Stats: &backends.GenerationStats{TokensGenerated: 2}},
+		},
+	})
+
+	handler := HandleWebSocketStream(r)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handler(w, req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: "ws-key"})))
+	}))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/stream/ws"
+
+	for _, stream := range []bool{true, false} {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("Failed to establish WebSocket connection: %v", err)
+		}
+		if err := conn.WriteJSON(WebSocketRequest{Model: "test-model", Prompt: "hello there", Stream: stream}); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		for {
+			var chunk WebSocketChunk
+			if err := conn.ReadJSON(&chunk); err != nil {
+				t.Fatalf("Failed to read chunk: %v", err)
+			}
+			if chunk.Done {
+				break
+			}
+		}
+		conn.Close()
+	}
+
+	// Streams are recorded once the handler returns. Handlers of earlier
+	// tests may still be finishing, so only this key's records count.
+	var records []usage.Record
+	deadline := time.Now().Add(2 * time.Second)
+	for len(records) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		records = records[:0]
+		for _, rec := range recorder.Query(time.Now().Add(-time.Minute), time.Now().Add(time.Minute)) {
+			if rec.Key == "ws-key" {
+				records = append(records, rec)
+			}
+		}
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected streamed and single responses recorded, got %d", len(records))
+	}
+	for _, rec := range records {
+		if rec.Key != "ws-key" || rec.Endpoint != "/v1/stream/ws" || rec.Backend != "mock1" || rec.Status != "success" {
+			t.Errorf("Unexpected record: %+v", rec)
+		}
+		if rec.PromptTokens == 0 || rec.CompletionTokens == 0 {
+			t.Errorf("Expected token counts, got %+v", rec)
+		}
+	}
+}
+
+// Test: WebSocket requests are written to the audit log
+func TestWebSocketAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.NewLogger(audit.Config{Path: path})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer auditLog.Close()
+	audit.SetDefault(auditLog)
+	defer audit.SetDefault(nil)
+
+	r := createTestRouter()
+	r.RegisterBackend(&MockBackend{id: "mock1", healthy: true, streamErr: fmt.Errorf("model not loaded")})
+
+	handler := HandleWebSocketStream(r)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handler(w, req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: "ws-key"})))
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/stream/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to establish WebSocket connection: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(WebSocketRequest{RequestID: "ws-audit", Model: "test-model", Prompt: "hello", Stream: true}); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	var wsErr WebSocketError
+	if err := conn.ReadJSON(&wsErr); err != nil || wsErr.Error == "" {
+		t.Fatalf("Expected a stream error, got %+v (%v)", wsErr, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	var rec audit.Record
+	if err := json.Unmarshal(bytes.TrimSpace(data), &rec); err != nil {
+		t.Fatalf("Expected one JSONL record, got %q: %v", data, err)
+	}
+	if rec.Key != "ws-key" || rec.Endpoint != "/v1/stream/ws" || rec.Backend != "mock1" || rec.Status != "error" || rec.Error == "" {
+		t.Errorf("Unexpected audit record: %+v", rec)
+	}
+}
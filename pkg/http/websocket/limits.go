@@ -0,0 +1,203 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+// DefaultMaxMessageBytes bounds a client frame when no limit is configured
+const DefaultMaxMessageBytes = 1024 * 1024
+
+// Error codes sent in WebSocketError when a connection limit is hit
+const (
+	CodeMessageTooLarge   = "message_too_large"
+	CodeTooManyRequests   = "too_many_requests"
+	CodeTokenRateExceeded = "token_rate_exceeded"
+)
+
+// Limits bounds what a single client may consume over WebSocket. A client
+// is its API key, or its address when unauthenticated; the concurrency cap
+// and token budget span all of its connections, so reconnecting neither
+// resets the budget nor gets around the cap.
+type Limits struct {
+	MaxConcurrentRequests int   // in-flight requests per client (0 = unlimited)
+	MaxMessageBytes       int64 // largest client message (0 = DefaultMaxMessageBytes)
+	TokensPerMinute       int   // streamed tokens per client (0 = unlimited)
+}
+
+// limiterSweepInterval is how often idle client limiters are dropped
+const limiterSweepInterval = time.Minute
+
+var (
+	limitsMu      sync.Mutex
+	defaultLimits Limits
+	limiters      = map[string]*connLimiter{}
+	lastSweep     time.Time
+)
+
+// SetLimits replaces the process-wide per-client limits. Clients start
+// afresh under the new values.
+func SetLimits(l Limits) {
+	limitsMu.Lock()
+	defaultLimits = l
+	limiters = map[string]*connLimiter{}
+	limitsMu.Unlock()
+}
+
+// limiterFor returns the limiter shared by every connection of client
+func limiterFor(client string) *connLimiter {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+
+	if now := time.Now(); now.Sub(lastSweep) >= limiterSweepInterval {
+		lastSweep = now
+		for id, l := range limiters {
+			if l.idle() {
+				delete(limiters, id)
+			}
+		}
+	}
+
+	l, ok := limiters[client]
+	if !ok {
+		l = newConnLimiter(defaultLimits)
+		limiters[client] = l
+	}
+	return l
+}
+
+// clientID identifies who a WebSocket upgrade belongs to for limits
+func clientID(req *http.Request) string {
+	if info, ok := auth.KeyInfoFromContext(req.Context()); ok {
+		return "key:" + info.ClientID()
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// connLimiter enforces Limits for one client across its connections
+type connLimiter struct {
+	limits Limits
+
+	mu       sync.Mutex
+	inflight int
+
+	tokens *rate.Limiter // nil when unlimited
+}
+
+func newConnLimiter(l Limits) *connLimiter {
+	if l.MaxMessageBytes <= 0 {
+		l.MaxMessageBytes = DefaultMaxMessageBytes
+	}
+	c := &connLimiter{limits: l}
+	if l.TokensPerMinute > 0 {
+		c.tokens = rate.NewLimiter(rate.Limit(float64(l.TokensPerMinute)/60), l.TokensPerMinute)
+	}
+	return c
+}
+
+// acquire reserves a request slot, returning false at the concurrency cap
+func (c *connLimiter) acquire() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.limits.MaxConcurrentRequests > 0 && c.inflight >= c.limits.MaxConcurrentRequests {
+		return false
+	}
+	c.inflight++
+	return true
+}
+
+func (c *connLimiter) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight > 0 {
+		c.inflight--
+	}
+}
+
+// idle reports whether the limiter holds no state worth keeping: nothing
+// in flight and a full token budget
+func (c *connLimiter) idle() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight > 0 {
+		return false
+	}
+	return c.tokens == nil || c.tokens.Tokens() >= float64(c.limits.TokensPerMinute)
+}
+
+// hasTokenBudget reports whether the client may start generating
+func (c *connLimiter) hasTokenBudget() bool {
+	return c.tokens == nil || c.tokens.Tokens() >= 1
+}
+
+// allowTokens debits n streamed tokens, returning false once the client's
+// budget is spent
+func (c *connLimiter) allowTokens(n int) bool {
+	if c.tokens == nil || n <= 0 {
+		return true
+	}
+	return c.tokens.AllowN(time.Now(), n)
+}
+
+// tokenRetryAfter is how long until one minute's budget has refilled a token
+func (c *connLimiter) tokenRetryAfter() time.Duration {
+	if c.tokens == nil {
+		return 0
+	}
+	return time.Duration(float64(time.Minute) / float64(c.limits.TokensPerMinute))
+}
+
+// errMessageTooLarge is returned by readRequest after the client was told
+type errMessageTooLarge struct{ limit int64 }
+
+func (e errMessageTooLarge) Error() string {
+	return fmt.Sprintf("message exceeds %d bytes", e.limit)
+}
+
+// readRequest reads one client message, replying with an error frame and a
+// 1009 close when it exceeds the size limit
+func (c *connLimiter) readRequest(conn *websocket.Conn, v interface{}) error {
+	_, r, err := conn.NextReader()
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, c.limits.MaxMessageBytes+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > c.limits.MaxMessageBytes {
+		tooLarge := errMessageTooLarge{limit: c.limits.MaxMessageBytes}
+		sendLimitError(conn, CodeMessageTooLarge, tooLarge.Error(), "", 0)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseMessageTooBig, tooLarge.Error()),
+			time.Now().Add(time.Second))
+		return tooLarge
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// sendLimitError sends a limit error frame; the connection stays open
+func sendLimitError(conn *websocket.Conn, code, message, requestID string, retryAfter time.Duration) {
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	conn.WriteJSON(WebSocketError{
+		Error:        message,
+		Code:         code,
+		RequestID:    requestID,
+		RetryAfterMs: retryAfter.Milliseconds(),
+	})
+}
@@ -0,0 +1,198 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/gorilla/websocket"
+)
+
+// useLimits installs per-client limits for one test
+func useLimits(t *testing.T, l Limits) {
+	t.Helper()
+	SetLimits(l)
+	t.Cleanup(func() { SetLimits(Limits{}) })
+}
+
+func dialTestServer(t *testing.T, chunks []*backends.StreamChunk) *websocket.Conn {
+	t.Helper()
+	r := createTestRouter()
+	r.RegisterBackend(&MockBackend{id: "mock1", healthy: true, streamChunks: chunks})
+
+	server := createTestServer(r)
+	t.Cleanup(server.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to establish WebSocket connection: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestConnLimiter_Concurrency(t *testing.T) {
+	l := newConnLimiter(Limits{MaxConcurrentRequests: 2})
+	if !l.acquire() || !l.acquire() {
+		t.Fatal("Expected two slots")
+	}
+	if l.acquire() {
+		t.Error("Expected third acquire to fail")
+	}
+	l.release()
+	if !l.acquire() {
+		t.Error("Expected acquire after release to succeed")
+	}
+
+	unlimited := newConnLimiter(Limits{})
+	for i := 0; i < 100; i++ {
+		if !unlimited.acquire() {
+			t.Fatal("Expected unlimited slots")
+		}
+	}
+}
+
+func TestConnLimiter_Tokens(t *testing.T) {
+	l := newConnLimiter(Limits{TokensPerMinute: 10})
+	if !l.hasTokenBudget() {
+		t.Fatal("Expected a fresh budget")
+	}
+	if !l.allowTokens(10) {
+		t.Error("Expected the full budget to be usable")
+	}
+	if l.allowTokens(1) {
+		t.Error("Expected budget to be exhausted")
+	}
+	if l.hasTokenBudget() {
+		t.Error("Expected no budget left")
+	}
+	if got := l.tokenRetryAfter(); got.Seconds() != 6 {
+		t.Errorf("Expected 6s retry after, got %s", got)
+	}
+
+	if !newConnLimiter(Limits{}).allowTokens(1 << 20) {
+		t.Error("Expected unlimited tokens")
+	}
+}
+
+func TestWebSocketMessageTooLarge(t *testing.T) {
+	useLimits(t, Limits{MaxMessageBytes: 64})
+	conn := dialTestServer(t, []*backends.StreamChunk{{Token: "hi", Done: true}})
+
+	req := WebSocketRequest{RequestID: "big", Model: "test-model", Prompt: strings.Repeat("x", 200), Stream: true}
+	if err := conn.WriteJSON(req); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	var errMsg WebSocketError
+	if err := conn.ReadJSON(&errMsg); err != nil {
+		t.Fatalf("Failed to read error frame: %v", err)
+	}
+	if errMsg.Code != CodeMessageTooLarge {
+		t.Errorf("Expected code %s, got %+v", CodeMessageTooLarge, errMsg)
+	}
+
+	// Server closes with 1009 after the error frame
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Errorf("Expected 1009 close, got %v", err)
+	}
+}
+
+func TestWebSocketTokenRateExceeded(t *testing.T) {
+	useLimits(t, Limits{TokensPerMinute: 2})
+	conn := dialTestServer(t, []*backends.StreamChunk{
+		{Token: "one", Done: false},
+		{Token: "two", Done: false},
+		{Token: "three", Done: false},
+		{Token: "four", Done: true},
+	})
+
+	req := WebSocketRequest{RequestID: "tpm", Model: "test-model", Prompt: "hello", Stream: true}
+	if err := conn.WriteJSON(req); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	var tokens int
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Stream ended without a limit error: %v", err)
+		}
+		var errMsg WebSocketError
+		if json.Unmarshal(data, &errMsg) == nil && errMsg.Code != "" {
+			if errMsg.Code != CodeTokenRateExceeded || errMsg.RequestID != "tpm" || errMsg.RetryAfterMs <= 0 {
+				t.Errorf("Unexpected limit error: %+v", errMsg)
+			}
+			break
+		}
+		tokens++
+	}
+
+	if tokens != 2 {
+		t.Errorf("Expected 2 chunks before the limit, got %d", tokens)
+	}
+}
+
+func TestWebSocketTokenBudgetSpansConnections(t *testing.T) {
+	useLimits(t, Limits{TokensPerMinute: 2})
+	r := createTestRouter()
+	r.RegisterBackend(&MockBackend{id: "mock1", healthy: true, streamChunks: []*backends.StreamChunk{
+		{Token: "one", Done: false},
+		{Token: "two", Done: true},
+	}})
+	server := createTestServer(r)
+	t.Cleanup(server.Close)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	stream := func(id string) WebSocketError {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("Failed to establish WebSocket connection: %v", err)
+		}
+		defer conn.Close()
+		if err := conn.WriteJSON(WebSocketRequest{RequestID: id, Model: "test-model", Prompt: "hello", Stream: true}); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return WebSocketError{}
+			}
+			var errMsg WebSocketError
+			if json.Unmarshal(data, &errMsg) == nil && errMsg.Code != "" {
+				return errMsg
+			}
+		}
+	}
+
+	if errMsg := stream("first"); errMsg.Code != "" {
+		t.Fatalf("Expected the first stream within budget, got %+v", errMsg)
+	}
+	// Reconnecting must not refill the budget the first stream spent
+	if errMsg := stream("second"); errMsg.Code != CodeTokenRateExceeded {
+		t.Errorf("Expected %s on a new connection, got %+v", CodeTokenRateExceeded, errMsg)
+	}
+}
+
+func TestClientID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/stream/ws", nil)
+	req.RemoteAddr = "192.0.2.7:50123"
+	if got := clientID(req); got != "addr:192.0.2.7" {
+		t.Errorf("Expected the address without port, got %q", got)
+	}
+
+	// Keys are told apart by ID, as names can repeat
+	keyed := req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{ID: "0a1b", Name: "app"}))
+	if got := clientID(keyed); got != "key:0a1b" {
+		t.Errorf("Expected the API key ID, got %q", got)
+	}
+	keyed = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: "app"}))
+	if got := clientID(keyed); got != "key:name:app" {
+		t.Errorf("Expected the API key name without an ID, got %q", got)
+	}
+}
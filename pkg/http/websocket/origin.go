@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// OriginPolicy decides which browser origins may open a WebSocket.
+//
+// WebSocket upgrades are not covered by the same-origin policy, so without
+// this check any page open in a local browser could connect to the proxy
+// (cross-site WebSocket hijacking). Requests without an Origin header come
+// from non-browser clients and are allowed; same-origin requests are
+// allowed when the proxy is reached under an IP address, localhost or one
+// of its configured hostnames; anything else must match the allow list.
+//
+// The Host header alone can't vouch for same-origin: a page on a domain
+// rebound to the proxy's address sends matching Origin and Host headers.
+type OriginPolicy struct {
+	origins   *middleware.OriginMatcher
+	hostnames map[string]bool // the proxy's own names, lower case
+}
+
+// NewOriginPolicy builds a policy from allowed origins such as
+// "https://app.example.com", "https://*.example.com" or "*" (any origin),
+// and the hostnames the proxy is served under besides localhost
+func NewOriginPolicy(allowed, hostnames []string) *OriginPolicy {
+	p := &OriginPolicy{
+		origins:   middleware.NewOriginMatcher(allowed),
+		hostnames: map[string]bool{"localhost": true},
+	}
+	for _, name := range hostnames {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			p.hostnames[name] = true
+		}
+	}
+	return p
+}
+
+// Allowed reports whether the upgrade request may proceed
+func (p *OriginPolicy) Allowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// Browsers always send Origin on upgrades; fetch metadata catches
+		// the odd one that strips it
+		return r.Header.Get("Sec-Fetch-Site") != "cross-site"
+	}
+	if p.origins.AllowAll() {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	hostname := strings.ToLower(u.Hostname())
+	if strings.EqualFold(u.Host, r.Host) && (p.hostnames[hostname] || net.ParseIP(hostname) != nil) {
+		return true
+	}
+
+	return p.origins.Allowed(origin)
+}
+
+var (
+	policyMu      sync.RWMutex
+	defaultPolicy = NewOriginPolicy(nil, nil)
+)
+
+// SetOriginPolicy replaces the process-wide origin policy
+func SetOriginPolicy(p *OriginPolicy) {
+	if p == nil {
+		return
+	}
+	policyMu.Lock()
+	defaultPolicy = p
+	policyMu.Unlock()
+}
+
+// CheckOrigin applies the process-wide origin policy. It is the
+// upgrader's CheckOrigin hook, and serves other WebSocket endpoints too.
+func CheckOrigin(r *http.Request) bool {
+	policyMu.RLock()
+	p := defaultPolicy
+	policyMu.RUnlock()
+
+	if p.Allowed(r) {
+		return true
+	}
+	if logging.Logger != nil {
+		logging.Logger.Warn("WebSocket upgrade rejected: origin not allowed",
+			zap.String("origin", r.Header.Get("Origin")),
+			zap.String("host", r.Host),
+			zap.String("remote_addr", r.RemoteAddr),
+		)
+	}
+	return false
+}
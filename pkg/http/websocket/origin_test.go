@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestOriginPolicy_Allowed(t *testing.T) {
+	policy := NewOriginPolicy([]string{"https://app.example.com", "https://*.trusted.dev/", "https://*.ports.dev:9000"},
+		[]string{"proxy.lan"})
+
+	tests := []struct {
+		name      string
+		host      string
+		origin    string
+		fetchSite string
+		want      bool
+	}{
+		{"no origin (non-browser client)", "localhost:8080", "", "", true},
+		{"no origin but cross-site fetch", "localhost:8080", "", "cross-site", false},
+		{"same origin", "localhost:8080", "http://localhost:8080", "", true},
+		{"same origin different case", "LOCALHOST:8080", "http://localhost:8080", "", true},
+		{"same origin configured hostname", "proxy.lan:8080", "http://proxy.lan:8080", "", true},
+		{"same origin IP address", "192.168.1.5:8080", "http://192.168.1.5:8080", "", true},
+		{"same origin IPv6 loopback", "[::1]:8080", "http://[::1]:8080", "", true},
+		{"rebound domain", "evil.example:8080", "http://evil.example:8080", "", false},
+		{"allowed exact", "localhost:8080", "https://app.example.com", "", true},
+		{"allowed exact upper case", "localhost:8080", "HTTPS://APP.EXAMPLE.COM", "", true},
+		{"allowed wildcard", "localhost:8080", "https://ui.trusted.dev", "", true},
+		{"wildcard wrong scheme", "localhost:8080", "http://ui.trusted.dev", "", false},
+		{"wildcard with port", "localhost:8080", "https://ui.trusted.dev:8443", "", true},
+		{"wildcard fixed port", "localhost:8080", "https://ui.ports.dev:9000", "", true},
+		{"wildcard wrong port", "localhost:8080", "https://ui.ports.dev:9001", "", false},
+		{"wildcard missing port", "localhost:8080", "https://ui.ports.dev", "", false},
+		{"wildcard lookalike", "localhost:8080", "https://eviltrusted.dev", "", false},
+		{"cross origin", "localhost:8080", "https://evil.example", "", false},
+		{"lookalike suffix", "localhost:8080", "https://app.example.com.evil.example", "", false},
+		{"null origin", "localhost:8080", "null", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/stream/ws", nil)
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.fetchSite != "" {
+				req.Header.Set("Sec-Fetch-Site", tt.fetchSite)
+			}
+			if got := policy.Allowed(req); got != tt.want {
+				t.Errorf("Allowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOriginPolicy_AllowAll(t *testing.T) {
+	policy := NewOriginPolicy([]string{"*"}, nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/stream/ws", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	if !policy.Allowed(req) {
+		t.Error("Expected \"*\" to allow any origin")
+	}
+}
+
+func TestWebSocketRejectsCrossOriginUpgrade(t *testing.T) {
+	r := createTestRouter()
+	r.RegisterBackend(&MockBackend{id: "mock1", healthy: true})
+
+	server := createTestServer(r)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	header := http.Header{}
+	header.Set("Origin", "https://evil.example")
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err == nil {
+		t.Fatal("Expected cross-origin upgrade to be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403, got %v", resp)
+	}
+
+	// Allow the origin and retry
+	SetOriginPolicy(NewOriginPolicy([]string{"https://evil.example"}, nil))
+	defer SetOriginPolicy(NewOriginPolicy(nil, nil))
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatalf("Expected allowed origin to connect, got: %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,717 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/pipeline"
+	"go.uber.org/zap"
+)
+
+// Source types
+const (
+	SourceRTSP   = "rtsp"   // network camera or stream server
+	SourceCamera = "camera" // local V4L2 camera
+)
+
+// Media a source feeds its pipeline
+const (
+	MediaVideo = "video" // windows of JPEG frames, as []pipeline.VideoFrame
+	MediaAudio = "audio" // windows of 16 kHz mono WAV, as []byte
+)
+
+// Source states
+const (
+	StateRunning = "running"
+	StateStopped = "stopped"
+)
+
+// Defaults
+const (
+	DefaultFPS          = 1
+	DefaultWindow       = 10 * time.Second
+	DefaultRestartDelay = 5 * time.Second
+	DefaultFFmpegPath   = "ffmpeg"
+)
+
+const (
+	audioSampleRate = 16000
+	keptResults     = 20 // recent window results kept per source
+)
+
+// SourceConfig describes a live input and the pipeline it feeds
+type SourceConfig struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	URL      string `json:"url,omitempty"`    // rtsp
+	Device   string `json:"device,omitempty"` // camera: device manager ID or name, or a /dev/video path
+	Pipeline string `json:"pipeline"`
+	Media    string `json:"media,omitempty"` // "" = video
+
+	// Frames decoded per second (0 = DefaultFPS); the pipeline's frame
+	// sampling policy thins them further
+	FPS int `json:"fps,omitempty"`
+
+	// Media collected per pipeline run (0 = DefaultWindow)
+	Window time.Duration `json:"-"`
+}
+
+// validate checks a source and fills in defaults
+func (c *SourceConfig) validate() error {
+	if c.ID == "" {
+		return fmt.Errorf("source id is required")
+	}
+	if c.Pipeline == "" {
+		return fmt.Errorf("source %s: pipeline is required", c.ID)
+	}
+	switch c.Type {
+	case SourceRTSP:
+		if !strings.HasPrefix(c.URL, "rtsp://") && !strings.HasPrefix(c.URL, "rtsps://") {
+			return fmt.Errorf("source %s: rtsp sources need an rtsp:// url", c.ID)
+		}
+	case SourceCamera:
+		if c.Device == "" {
+			return fmt.Errorf("source %s: camera sources need a device", c.ID)
+		}
+		if c.Media == MediaAudio {
+			return fmt.Errorf("source %s: camera sources carry video only", c.ID)
+		}
+	default:
+		return fmt.Errorf("source %s: invalid type %q (must be rtsp or camera)", c.ID, c.Type)
+	}
+	switch c.Media {
+	case "":
+		c.Media = MediaVideo
+	case MediaVideo, MediaAudio:
+	default:
+		return fmt.Errorf("source %s: invalid media %q (must be video or audio)", c.ID, c.Media)
+	}
+	if c.FPS < 0 || c.Window < 0 {
+		return fmt.Errorf("source %s: fps and window cannot be negative", c.ID)
+	}
+	if c.FPS == 0 {
+		c.FPS = DefaultFPS
+	}
+	if c.Window == 0 {
+		c.Window = DefaultWindow
+	}
+	return nil
+}
+
+// Capture opens a source's decoded media: concatenated JPEG frames for
+// video, 16 kHz mono s16le PCM for audio. input is the RTSP URL or camera
+// path. Closing the reader stops the capture.
+type Capture func(ctx context.Context, cfg SourceConfig, input string) (io.ReadCloser, error)
+
+// Executor runs pipelines; *pipeline.PipelineExecutor implements it
+type Executor interface {
+	Execute(ctx context.Context, p *pipeline.Pipeline, input interface{}) (*pipeline.PipelineResult, error)
+}
+
+// Result is the outcome of one window of media
+type Result struct {
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Frames      int       `json:"frames,omitempty"`
+	Output      string    `json:"output,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int64     `json:"duration_ms"`
+}
+
+// Status is a point-in-time view of a source
+type Status struct {
+	SourceConfig
+	WindowSeconds float64   `json:"window_seconds"`
+	State         string    `json:"state"`
+	StartedAt     time.Time `json:"started_at,omitempty"`
+	Frames        int64     `json:"frames"`
+	Windows       int64     `json:"windows"`
+	Dropped       int64     `json:"dropped"` // windows skipped while the previous one was still running
+	Restarts      int64     `json:"restarts"`
+	LastError     string    `json:"last_error,omitempty"`
+	Recent        []Result  `json:"recent,omitempty"` // newest first
+}
+
+// source is one running or stopped input
+type source struct {
+	cfg SourceConfig
+
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{} // closed when the run loop has exited
+	analyzing bool
+	status    Status
+}
+
+// Manager runs live sources, each feeding windows of media into a
+// pipeline until stopped
+type Manager struct {
+	exec      Executor
+	pipelines func(id string) (*pipeline.Pipeline, error)
+	capture   Capture
+	resolve   func(device string) (string, error)
+
+	restartDelay time.Duration
+
+	mu      sync.Mutex
+	sources map[string]*source
+}
+
+// NewManager creates a manager that looks pipelines up with pipelines and
+// decodes media with capture
+func NewManager(exec Executor, pipelines func(id string) (*pipeline.Pipeline, error), capture Capture) *Manager {
+	return &Manager{
+		exec:         exec,
+		pipelines:    pipelines,
+		capture:      capture,
+		resolve:      resolveDevicePath,
+		restartDelay: DefaultRestartDelay,
+		sources:      make(map[string]*source),
+	}
+}
+
+// SetCameraResolver maps camera source devices to V4L2 paths, e.g. by
+// looking them up in the device manager
+func (m *Manager) SetCameraResolver(resolve func(device string) (string, error)) {
+	m.resolve = resolve
+}
+
+// resolveDevicePath accepts camera devices given as /dev paths
+func resolveDevicePath(device string) (string, error) {
+	if !strings.HasPrefix(device, "/dev/") {
+		return "", fmt.Errorf("unknown camera %q", device)
+	}
+	return device, nil
+}
+
+// Add registers a source without starting it, replacing a stopped source
+// with the same ID
+func (m *Manager) Add(cfg SourceConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.sources[cfg.ID]; ok && existing.running() {
+		return fmt.Errorf("source %s is running", cfg.ID)
+	}
+	m.sources[cfg.ID] = newSource(cfg)
+	return nil
+}
+
+func newSource(cfg SourceConfig) *source {
+	return &source{
+		cfg:    cfg,
+		status: Status{SourceConfig: cfg, WindowSeconds: cfg.Window.Seconds(), State: StateStopped},
+	}
+}
+
+// Start starts a registered source
+func (m *Manager) Start(id string) error {
+	m.mu.Lock()
+	s, ok := m.sources[id]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("source not found: %s", id)
+	}
+
+	p, err := m.pipelines(s.cfg.Pipeline)
+	if err != nil {
+		return fmt.Errorf("source %s: %w", id, err)
+	}
+	input := s.cfg.URL
+	if s.cfg.Type == SourceCamera {
+		if input, err = m.resolve(s.cfg.Device); err != nil {
+			return fmt.Errorf("source %s: %w", id, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return fmt.Errorf("source %s is already running", id)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	s.status.State = StateRunning
+	s.status.StartedAt = time.Now()
+	s.status.LastError = ""
+
+	go m.run(ctx, s, p, input)
+
+	logging.Logger.Info("Live source started",
+		zap.String("source", id),
+		zap.String("type", s.cfg.Type),
+		zap.String("pipeline", s.cfg.Pipeline),
+	)
+	return nil
+}
+
+// Stop stops a source and waits for its last window to finish
+func (m *Manager) Stop(id string) error {
+	m.mu.Lock()
+	s, ok := m.sources[id]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("source not found: %s", id)
+	}
+
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	logging.Logger.Info("Live source stopped", zap.String("source", id))
+	return nil
+}
+
+// StopAll stops every running source
+func (m *Manager) StopAll() {
+	for _, status := range m.List() {
+		if status.State == StateRunning {
+			m.Stop(status.ID)
+		}
+	}
+}
+
+// Get returns a source's status
+func (m *Manager) Get(id string) (Status, bool) {
+	m.mu.Lock()
+	s, ok := m.sources[id]
+	m.mu.Unlock()
+	if !ok {
+		return Status{}, false
+	}
+	return s.snapshot(), true
+}
+
+// List returns every source's status, sorted by ID
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	sources := make([]*source, 0, len(m.sources))
+	for _, s := range m.sources {
+		sources = append(sources, s)
+	}
+	m.mu.Unlock()
+
+	statuses := make([]Status, 0, len(sources))
+	for _, s := range sources {
+		statuses = append(statuses, s.snapshot())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+func (s *source) running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cancel != nil
+}
+
+func (s *source) snapshot() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Recent = append([]Result(nil), s.status.Recent...)
+	return status
+}
+
+// run captures media until ctx ends, restarting the capture when the
+// stream drops
+func (m *Manager) run(ctx context.Context, s *source, p *pipeline.Pipeline, input string) {
+	var analyses sync.WaitGroup
+	defer func() {
+		analyses.Wait()
+		s.mu.Lock()
+		s.cancel = nil
+		s.status.State = StateStopped
+		close(s.done)
+		s.mu.Unlock()
+	}()
+
+	for {
+		err := m.consume(ctx, s, p, input, &analyses)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil || err == io.EOF {
+			err = fmt.Errorf("stream ended")
+		}
+		s.mu.Lock()
+		s.status.LastError = err.Error()
+		s.status.Restarts++
+		s.mu.Unlock()
+		logging.Logger.Warn("Live source interrupted, restarting",
+			zap.String("source", s.cfg.ID),
+			zap.Error(err),
+			zap.Duration("delay", m.restartDelay),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.restartDelay):
+		}
+	}
+}
+
+// consume reads one capture, handing each full window to the pipeline. The
+// partial window left when the stream ends is analyzed too.
+func (m *Manager) consume(ctx context.Context, s *source, p *pipeline.Pipeline, input string, analyses *sync.WaitGroup) error {
+	rc, err := m.capture(ctx, s.cfg, input)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	reader := bufio.NewReader(rc)
+
+	windowStart := time.Now()
+	var frames []pipeline.VideoFrame
+	var pcm []byte
+	flush := func(end time.Time) {
+		if len(frames) == 0 && len(pcm) == 0 {
+			return
+		}
+		var media interface{} = frames
+		if s.cfg.Media == MediaAudio {
+			media = wav(pcm)
+		}
+		m.analyze(ctx, s, p, media, len(frames), windowStart, end, analyses)
+		frames, pcm = nil, nil
+		windowStart = end
+	}
+
+	chunk := make([]byte, audioSampleRate*2) // one second of s16le mono
+	for {
+		var readErr error
+		if s.cfg.Media == MediaAudio {
+			var n int
+			n, readErr = io.ReadFull(reader, chunk)
+			pcm = append(pcm, chunk[:n]...)
+		} else {
+			var data []byte
+			if data, readErr = readJPEG(reader); readErr == nil {
+				frames = append(frames, pipeline.VideoFrame{
+					Index:  len(frames),
+					TimeMs: time.Since(windowStart).Milliseconds(),
+					Data:   data,
+					Format: backends.ImageFormatJPEG,
+				})
+				s.mu.Lock()
+				s.status.Frames++
+				s.mu.Unlock()
+			}
+		}
+
+		now := time.Now()
+		if readErr != nil {
+			if ctx.Err() == nil {
+				flush(now)
+			}
+			if readErr == io.ErrUnexpectedEOF {
+				readErr = io.EOF
+			}
+			return readErr
+		}
+		if now.Sub(windowStart) >= s.cfg.Window {
+			flush(now)
+		}
+	}
+}
+
+// analyze runs the pipeline over a window in the background. A window that
+// arrives while the previous one is still running is dropped, so a slow
+// backend falls behind by skipping footage rather than queueing it.
+func (m *Manager) analyze(ctx context.Context, s *source, p *pipeline.Pipeline, media interface{}, frames int, start, end time.Time, analyses *sync.WaitGroup) {
+	s.mu.Lock()
+	if s.analyzing {
+		s.status.Dropped++
+		s.mu.Unlock()
+		return
+	}
+	s.analyzing = true
+	s.mu.Unlock()
+
+	analyses.Add(1)
+	go func() {
+		defer analyses.Done()
+		began := time.Now()
+		result := Result{WindowStart: start, WindowEnd: end, Frames: frames}
+
+		pr, err := m.exec.Execute(ctx, p, media)
+		if err == nil && pr.Error != nil {
+			err = pr.Error
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Output = outputText(pr.FinalOutput)
+		}
+		result.DurationMs = time.Since(began).Milliseconds()
+
+		s.mu.Lock()
+		s.analyzing = false
+		s.status.Windows++
+		s.status.Recent = append([]Result{result}, s.status.Recent...)
+		if len(s.status.Recent) > keptResults {
+			s.status.Recent = s.status.Recent[:keptResults]
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			logging.Logger.Warn("Live source window failed",
+				zap.String("source", s.cfg.ID),
+				zap.Error(err),
+			)
+			return
+		}
+		logging.Logger.Info("Live source window analyzed",
+			zap.String("source", s.cfg.ID),
+			zap.Int("frames", frames),
+			zap.String("output", result.Output),
+		)
+	}()
+}
+
+// outputText renders a pipeline's final output for the status
+func outputText(output interface{}) string {
+	switch v := output.(type) {
+	case string:
+		return v
+	case []byte:
+		return fmt.Sprintf("%d bytes", len(v))
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// readJPEG reads the next JPEG image from a stream of concatenated JPEGs,
+// skipping anything before its start-of-image marker
+func readJPEG(r *bufio.Reader) ([]byte, error) {
+	var prev byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if prev == 0xFF && b == 0xD8 {
+			break
+		}
+		prev = b
+	}
+
+	data := []byte{0xFF, 0xD8}
+	prev = 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		data = append(data, b)
+		// Entropy-coded data stuffs 0xFF with 0x00, so the end-of-image
+		// marker can't occur inside a frame
+		if prev == 0xFF && b == 0xD9 {
+			return data, nil
+		}
+		prev = b
+	}
+}
+
+// wav wraps 16 kHz mono s16le PCM in a WAV header
+func wav(pcm []byte) []byte {
+	data := make([]byte, 44+len(pcm))
+	copy(data[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:8], uint32(36+len(pcm)))
+	copy(data[8:12], "WAVE")
+	copy(data[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(data[16:20], 16)
+	binary.LittleEndian.PutUint16(data[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(data[22:24], 1) // mono
+	binary.LittleEndian.PutUint32(data[24:28], audioSampleRate)
+	binary.LittleEndian.PutUint32(data[28:32], audioSampleRate*2)
+	binary.LittleEndian.PutUint16(data[32:34], 2)
+	binary.LittleEndian.PutUint16(data[34:36], 16)
+	copy(data[36:40], "data")
+	binary.LittleEndian.PutUint32(data[40:44], uint32(len(pcm)))
+	copy(data[44:], pcm)
+	return data
+}
+
+// FFmpegCapture decodes sources with an ffmpeg binary (path "" = ffmpeg
+// from PATH)
+func FFmpegCapture(path string) Capture {
+	if path == "" {
+		path = DefaultFFmpegPath
+	}
+	return func(ctx context.Context, cfg SourceConfig, input string) (io.ReadCloser, error) {
+		cmd := exec.CommandContext(ctx, path, ffmpegArgs(cfg, input)...)
+		stderr := &tailBuffer{}
+		cmd.Stderr = stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+		}
+		return &ffmpegReader{ReadCloser: stdout, cmd: cmd, stderr: stderr}, nil
+	}
+}
+
+// ffmpegArgs builds the ffmpeg command line for a source
+func ffmpegArgs(cfg SourceConfig, input string) []string {
+	args := []string{"-hide_banner", "-loglevel", "error", "-nostdin"}
+	if cfg.Type == SourceCamera {
+		args = append(args, "-f", "v4l2", "-i", input)
+	} else {
+		args = append(args, "-rtsp_transport", "tcp", "-i", input)
+	}
+
+	if cfg.Media == MediaAudio {
+		return append(args, "-vn", "-ac", "1", "-ar", fmt.Sprint(audioSampleRate), "-f", "s16le", "pipe:1")
+	}
+	return append(args, "-an", "-vf", fmt.Sprintf("fps=%d", cfg.FPS),
+		"-f", "image2pipe", "-c:v", "mjpeg", "-q:v", "5", "pipe:1")
+}
+
+// ffmpegReader reads ffmpeg's output and stops the process on Close
+type ffmpegReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr *tailBuffer
+}
+
+// Read reports ffmpeg's last error message when the stream ends
+func (f *ffmpegReader) Read(p []byte) (int, error) {
+	n, err := f.ReadCloser.Read(p)
+	if err == io.EOF {
+		if msg := f.stderr.String(); msg != "" {
+			err = fmt.Errorf("ffmpeg: %s", msg)
+		}
+	}
+	return n, err
+}
+
+func (f *ffmpegReader) Close() error {
+	f.ReadCloser.Close()
+	f.cmd.Process.Kill()
+	f.cmd.Wait()
+	return nil
+}
+
+// tailBuffer keeps the last line written to it
+type tailBuffer struct {
+	mu   sync.Mutex
+	last string
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, line := range strings.Split(string(p), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			t.last = line
+		}
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+// startRequest is the admin API payload: a new source, or just the ID of a
+// registered one
+type startRequest struct {
+	SourceConfig
+	WindowSeconds float64 `json:"window_seconds"`
+}
+
+// Handler serves the admin sources endpoint. GET lists sources (or one,
+// with ?id=); POST starts a source; DELETE ?id= stops one.
+func (m *Manager) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		code := http.StatusOK
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req startRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.WindowSeconds < 0 {
+				http.Error(w, "window_seconds cannot be negative", http.StatusBadRequest)
+				return
+			}
+			id = req.ID
+			if req.Type != "" {
+				req.Window = time.Duration(req.WindowSeconds * float64(time.Second))
+				if err := m.Add(req.SourceConfig); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if err := m.Start(id); err != nil {
+				code := http.StatusBadRequest
+				if _, ok := m.Get(id); !ok {
+					code = http.StatusNotFound
+				}
+				http.Error(w, err.Error(), code)
+				return
+			}
+			code = http.StatusAccepted
+		case http.MethodDelete:
+			if id == "" {
+				http.Error(w, "id is required", http.StatusBadRequest)
+				return
+			}
+			if err := m.Stop(id); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if id == "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"sources": m.List()})
+			return
+		}
+		status, ok := m.Get(id)
+		if !ok {
+			http.Error(w, "source not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(status)
+	}
+}
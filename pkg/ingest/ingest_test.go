@@ -0,0 +1,267 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/pipeline"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	if err := logging.InitLogger("info", false); err != nil {
+		panic(err)
+	}
+	defer logging.Sync()
+
+	os.Exit(m.Run())
+}
+
+// fakeJPEG is the smallest byte sequence readJPEG accepts as a frame
+func fakeJPEG(n byte) []byte {
+	return []byte{0xFF, 0xD8, n, 0xFF, 0x00, 0xFF, 0xD9}
+}
+
+// fakeExecutor records pipeline inputs
+type fakeExecutor struct {
+	mu     sync.Mutex
+	inputs []interface{}
+	block  chan struct{} // when set, Execute waits on it
+}
+
+func (f *fakeExecutor) Execute(ctx context.Context, p *pipeline.Pipeline, input interface{}) (*pipeline.PipelineResult, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inputs = append(f.inputs, input)
+	if frames, ok := input.([]pipeline.VideoFrame); ok {
+		return &pipeline.PipelineResult{Success: true, FinalOutput: fmt.Sprintf("%d frames", len(frames))}, nil
+	}
+	return &pipeline.PipelineResult{Success: true, FinalOutput: "speech"}, nil
+}
+
+func newTestManager(exec Executor, capture Capture) *Manager {
+	m := NewManager(exec, func(id string) (*pipeline.Pipeline, error) {
+		if id != "describe" {
+			return nil, fmt.Errorf("pipeline not found: %s", id)
+		}
+		return &pipeline.Pipeline{ID: id}, nil
+	}, capture)
+	m.restartDelay = time.Hour
+	return m
+}
+
+// waitFor polls until cond holds
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReadJPEG(t *testing.T) {
+	stream := append([]byte("junk"), fakeJPEG(1)...)
+	stream = append(stream, fakeJPEG(2)...)
+	stream = append(stream, 0xFF, 0xD8, 3) // truncated
+	r := bufio.NewReader(bytes.NewReader(stream))
+
+	for i := byte(1); i <= 2; i++ {
+		frame, err := readJPEG(r)
+		if err != nil || !bytes.Equal(frame, fakeJPEG(i)) {
+			t.Fatalf("Frame %d: got %v, %v", i, frame, err)
+		}
+	}
+	if _, err := readJPEG(r); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected ErrUnexpectedEOF for a truncated frame, got %v", err)
+	}
+}
+
+func TestManager_FeedsFramesToPipeline(t *testing.T) {
+	exec := &fakeExecutor{}
+	var opened []string
+	capture := func(ctx context.Context, cfg SourceConfig, input string) (io.ReadCloser, error) {
+		opened = append(opened, input)
+		stream := append(append(fakeJPEG(1), fakeJPEG(2)...), fakeJPEG(3)...)
+		return io.NopCloser(bytes.NewReader(stream)), nil
+	}
+	m := newTestManager(exec, capture)
+	m.SetCameraResolver(func(device string) (string, error) { return "/dev/video0", nil })
+
+	if err := m.Add(SourceConfig{ID: "door", Type: SourceCamera, Device: "Front door", Pipeline: "describe"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start("door"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		status, _ := m.Get("door")
+		return status.Windows == 1
+	})
+	if err := m.Stop("door"); err != nil {
+		t.Fatal(err)
+	}
+
+	status, _ := m.Get("door")
+	if status.State != StateStopped || status.Frames != 3 || status.Restarts != 1 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if len(status.Recent) != 1 || status.Recent[0].Output != "3 frames" || status.Recent[0].Frames != 3 {
+		t.Errorf("Unexpected results %+v", status.Recent)
+	}
+	if len(opened) != 1 || opened[0] != "/dev/video0" {
+		t.Errorf("Expected the resolved camera opened, got %v", opened)
+	}
+	frames := exec.inputs[0].([]pipeline.VideoFrame)
+	if frames[2].Index != 2 || !bytes.Equal(frames[2].Data, fakeJPEG(3)) {
+		t.Errorf("Unexpected frame %+v", frames[2])
+	}
+}
+
+func TestManager_AudioWindowsAreWAV(t *testing.T) {
+	exec := &fakeExecutor{}
+	capture := func(ctx context.Context, cfg SourceConfig, input string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(make([]byte, 1000))), nil
+	}
+	m := newTestManager(exec, capture)
+	if err := m.Add(SourceConfig{ID: "lobby", Type: SourceRTSP, URL: "rtsp://cam/stream", Pipeline: "describe", Media: MediaAudio}); err != nil {
+		t.Fatal(err)
+	}
+	m.Start("lobby")
+	waitFor(t, func() bool {
+		status, _ := m.Get("lobby")
+		return status.Windows == 1
+	})
+	m.StopAll()
+
+	audio := exec.inputs[0].([]byte)
+	if len(audio) != 1044 || string(audio[:4]) != "RIFF" || string(audio[8:12]) != "WAVE" {
+		t.Errorf("Expected a WAV of the captured PCM, got %d bytes", len(audio))
+	}
+}
+
+func TestManager_DropsWindowsWhileBusy(t *testing.T) {
+	exec := &fakeExecutor{block: make(chan struct{})}
+	frames := make(chan []byte)
+	capture := func(ctx context.Context, cfg SourceConfig, input string) (io.ReadCloser, error) {
+		r, w := io.Pipe()
+		go func() {
+			for {
+				select {
+				case frame := <-frames:
+					w.Write(frame)
+				case <-ctx.Done():
+					w.Close()
+					return
+				}
+			}
+		}()
+		return r, nil
+	}
+	m := newTestManager(exec, capture)
+	m.Add(SourceConfig{ID: "yard", Type: SourceRTSP, URL: "rtsp://cam/yard", Pipeline: "describe", Window: time.Nanosecond})
+	m.Start("yard")
+
+	frames <- fakeJPEG(1) // starts the first window, which blocks
+	frames <- fakeJPEG(2) // dropped
+	waitFor(t, func() bool {
+		status, _ := m.Get("yard")
+		return status.Dropped == 1
+	})
+	close(exec.block)
+	m.Stop("yard")
+
+	if status, _ := m.Get("yard"); status.Windows != 1 {
+		t.Errorf("Expected one analyzed window, got %+v", status)
+	}
+}
+
+func TestSourceConfig_Validate(t *testing.T) {
+	tests := []struct {
+		cfg SourceConfig
+		err string
+	}{
+		{SourceConfig{ID: "a", Type: SourceRTSP, URL: "http://cam", Pipeline: "p"}, "rtsp://"},
+		{SourceConfig{ID: "a", Type: SourceCamera, Pipeline: "p"}, "need a device"},
+		{SourceConfig{ID: "a", Type: SourceCamera, Device: "/dev/video0", Pipeline: "p", Media: MediaAudio}, "video only"},
+		{SourceConfig{ID: "a", Type: "file", Pipeline: "p"}, "invalid type"},
+		{SourceConfig{ID: "a", Type: SourceRTSP, URL: "rtsp://cam"}, "pipeline is required"},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.validate(); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.cfg, tt.err, err)
+		}
+	}
+
+	cfg := SourceConfig{ID: "a", Type: SourceRTSP, URL: "rtsp://cam", Pipeline: "p"}
+	if err := cfg.validate(); err != nil || cfg.Media != MediaVideo || cfg.FPS != DefaultFPS || cfg.Window != DefaultWindow {
+		t.Errorf("Expected defaults filled in, got %+v, %v", cfg, err)
+	}
+}
+
+func TestManager_Handler(t *testing.T) {
+	block := make(chan struct{})
+	capture := func(ctx context.Context, cfg SourceConfig, input string) (io.ReadCloser, error) {
+		r, w := io.Pipe()
+		go func() {
+			<-ctx.Done()
+			w.Close()
+		}()
+		return r, nil
+	}
+	m := newTestManager(&fakeExecutor{block: block}, capture)
+	h := m.Handler()
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/admin/sources",
+		strings.NewReader(`{"id":"gate","type":"rtsp","url":"rtsp://cam/gate","pipeline":"describe","window_seconds":30}`)))
+	var status Status
+	json.NewDecoder(rec.Body).Decode(&status)
+	if rec.Code != http.StatusAccepted || status.State != StateRunning || status.WindowSeconds != 30 {
+		t.Fatalf("Expected the source started, got %d %+v", rec.Code, status)
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/admin/sources", strings.NewReader(`{"id":"gate"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 starting a running source, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/admin/sources", strings.NewReader(`{"id":"missing"}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown source, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodDelete, "/admin/sources?id=gate", nil))
+	json.NewDecoder(rec.Body).Decode(&status)
+	if rec.Code != http.StatusOK || status.State != StateStopped {
+		t.Errorf("Expected the source stopped, got %d %+v", rec.Code, status)
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/admin/sources", nil))
+	var list struct{ Sources []Status }
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Sources) != 1 || list.Sources[0].ID != "gate" {
+		t.Errorf("Expected the stopped source listed, got %+v", list.Sources)
+	}
+}
@@ -0,0 +1,222 @@
+package labels
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// Header carries a request's labels, e.g. "X-Labels: team=ml,app=docsbot"
+const Header = "X-Labels"
+
+// OtherValue is reported on metrics in place of the values of labels that
+// allow any value, to keep metric cardinality bounded
+const OtherValue = "other"
+
+// Defaults for Config
+const (
+	DefaultMaxLabels      = 8
+	DefaultMaxValueLength = 64
+)
+
+// maxKeyLength bounds label names
+const maxKeyLength = 32
+
+// Labels are free-form tags a client attaches to a request
+type Labels map[string]string
+
+// String formats labels in header form, sorted by name
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + l[k]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ValidName reports whether s is a label name: lowercase letters, digits,
+// '_', '-' and '.'
+func ValidName(s string) bool {
+	if s == "" || len(s) > maxKeyLength {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidValue reports whether s is a label value of at most maxLength
+// letters, digits and "_-.:/"
+func ValidValue(s string, maxLength int) bool {
+	if s == "" || len(s) > maxLength {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '_' || c == '-' || c == '.' || c == ':' || c == '/') {
+			return false
+		}
+	}
+	return true
+}
+
+// Config controls which labels clients may attach
+type Config struct {
+	// Allowed maps each accepted label to its allowed values. An empty list
+	// allows any value; such labels are reported as OtherValue on metrics.
+	Allowed map[string][]string
+
+	// MaxLabels caps labels per request (0 = DefaultMaxLabels)
+	MaxLabels int
+
+	// MaxValueLength caps the length of a value (0 = DefaultMaxValueLength)
+	MaxValueLength int
+
+	// Reject fails requests carrying labels outside the allowlist with 400
+	// instead of dropping those labels
+	Reject bool
+}
+
+// Validator checks request labels against an allowlist
+type Validator struct {
+	cfg     Config
+	allowed map[string]map[string]bool // label -> values, nil = any value
+}
+
+// NewValidator creates a validator. Label names are matched lowercase.
+func NewValidator(cfg Config) *Validator {
+	if cfg.MaxLabels <= 0 {
+		cfg.MaxLabels = DefaultMaxLabels
+	}
+	if cfg.MaxValueLength <= 0 {
+		cfg.MaxValueLength = DefaultMaxValueLength
+	}
+
+	v := &Validator{cfg: cfg, allowed: make(map[string]map[string]bool, len(cfg.Allowed))}
+	for key, values := range cfg.Allowed {
+		var set map[string]bool
+		if len(values) > 0 {
+			set = make(map[string]bool, len(values))
+			for _, value := range values {
+				set[value] = true
+			}
+		}
+		v.allowed[strings.ToLower(key)] = set
+	}
+	return v
+}
+
+// Parse reads a header value such as "team=ml, app=docsbot" and returns
+// the labels the allowlist accepts. Labels it does not accept are dropped
+// and the first of them is described by the error.
+func (v *Validator) Parse(header string) (Labels, error) {
+	accepted := make(Labels)
+	var firstErr error
+	reject := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, found := strings.Cut(pair, "=")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch {
+		case !found || !ValidName(key):
+			reject(fmt.Errorf("malformed label %q", pair))
+			continue
+		case !ValidValue(value, v.cfg.MaxValueLength):
+			reject(fmt.Errorf("invalid value for label %s", key))
+			continue
+		}
+
+		values, ok := v.allowed[key]
+		switch {
+		case !ok:
+			reject(fmt.Errorf("label %s is not allowed", key))
+		case values != nil && !values[value]:
+			reject(fmt.Errorf("value %q is not allowed for label %s", value, key))
+		case len(accepted) >= v.cfg.MaxLabels:
+			reject(fmt.Errorf("too many labels (max %d)", v.cfg.MaxLabels))
+		default:
+			accepted[key] = value
+		}
+	}
+	return accepted, firstErr
+}
+
+// Middleware reads X-Labels into the request context. Labels outside the
+// allowlist are dropped, or the request is rejected with 400 when the
+// validator is configured to.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(Header)
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		labels, err := v.Parse(header)
+		if err != nil {
+			if v.cfg.Reject {
+				http.Error(w, fmt.Sprintf("Invalid %s: %v", Header, err), http.StatusBadRequest)
+				return
+			}
+			if logging.Logger != nil {
+				logging.Logger.Debug("Dropped request labels", zap.String("labels", header), zap.Error(err))
+			}
+		}
+		if len(labels) > 0 {
+			r = r.WithContext(NewContext(r.Context(), labels))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MetricValue is the value a label is reported with on metrics: the value
+// itself when the allowlist enumerates the label's values, else OtherValue
+func (v *Validator) MetricValue(key, value string) string {
+	if v.allowed[key] == nil {
+		return OtherValue
+	}
+	return value
+}
+
+// Observe counts a completed request under each of its labels
+func (v *Validator) Observe(labels Labels, backendID, status string, tokens int64) {
+	for key, value := range labels {
+		metrics.RecordLabeledRequest(key, v.MetricValue(key, value), backendID, status, tokens)
+	}
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying labels
+func NewContext(ctx context.Context, labels Labels) context.Context {
+	return context.WithValue(ctx, contextKey{}, labels)
+}
+
+// FromContext returns the request's labels, nil when it has none
+func FromContext(ctx context.Context) Labels {
+	labels, _ := ctx.Value(contextKey{}).(Labels)
+	return labels
+}
@@ -0,0 +1,94 @@
+package labels
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testValidator(reject bool) *Validator {
+	return NewValidator(Config{
+		Allowed: map[string][]string{
+			"team": {"ml", "search"},
+			"App":  nil,
+		},
+		MaxLabels: 3,
+		Reject:    reject,
+	})
+}
+
+func TestValidator_Parse(t *testing.T) {
+	v := testValidator(false)
+
+	got, err := v.Parse("team=ml, app=docsbot")
+	if err != nil {
+		t.Fatalf("Expected labels to be accepted, got %v", err)
+	}
+	if got.String() != "app=docsbot,team=ml" {
+		t.Errorf("Expected app and team, got %q", got.String())
+	}
+
+	tests := []struct {
+		header string
+		want   string
+		err    string
+	}{
+		{"team=ml,env=prod", "team=ml", "label env is not allowed"},
+		{"team=finance,app=x", "app=x", `value "finance" is not allowed for label team`},
+		{"app=has space", "", "invalid value for label app"},
+		{"Team=ml,app", "team=ml", `malformed label "app"`},
+		{"app=" + strings.Repeat("x", 65), "", "invalid value for label app"},
+	}
+	for _, tt := range tests {
+		got, err := v.Parse(tt.header)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q): expected error %q, got %v", tt.header, tt.err, err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q): expected %q to be kept, got %q", tt.header, tt.want, got.String())
+		}
+	}
+}
+
+func TestValidator_Middleware(t *testing.T) {
+	var seen Labels
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set(Header, "team=ml,env=prod")
+	rec := httptest.NewRecorder()
+	testValidator(false).Middleware(next).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || seen.String() != "team=ml" {
+		t.Errorf("Expected env to be dropped, got %d %q", rec.Code, seen.String())
+	}
+
+	seen = nil
+	rec = httptest.NewRecorder()
+	testValidator(true).Middleware(next).ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || seen != nil {
+		t.Errorf("Expected 400 when rejecting, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "label env is not allowed") {
+		t.Errorf("Expected the rejected label in the error, got %q", rec.Body.String())
+	}
+
+	// Requests without labels pass through untouched
+	rec = httptest.NewRecorder()
+	testValidator(true).Middleware(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if rec.Code != http.StatusOK || seen != nil {
+		t.Errorf("Expected unlabelled request to pass, got %d %q", rec.Code, seen.String())
+	}
+}
+
+func TestValidator_MetricValue(t *testing.T) {
+	v := testValidator(false)
+	if got := v.MetricValue("team", "ml"); got != "ml" {
+		t.Errorf("Expected enumerated value on metrics, got %q", got)
+	}
+	if got := v.MetricValue("app", "docsbot"); got != OtherValue {
+		t.Errorf("Expected free value reported as %q, got %q", OtherValue, got)
+	}
+}
@@ -0,0 +1,332 @@
+// Package langdetect detects the natural language of prompts so routing,
+// policies and pipelines can treat non-English requests differently (many
+// small local models only handle English well).
+package langdetect
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultMinConfidence is the confidence below which a detection is not
+// acted on by routing or pipelines
+const DefaultMinConfidence = 0.5
+
+// Detection sources reported in Result.Source
+const (
+	SourceScript    = "script"    // dominant non-Latin writing system
+	SourceStopwords = "stopwords" // Latin-script function-word frequency
+	SourceLocale    = "locale"    // client locale, text was inconclusive
+	SourceDefault   = "default"   // nothing to go on
+)
+
+// Result is a language detection outcome
+type Result struct {
+	Language   string  // ISO 639-1 code, "" when unknown
+	Confidence float64 // 0.0-1.0
+	Source     string  // how the language was determined
+}
+
+// Detector detects the language of text. localeHint is the client's
+// preferred language (e.g. from Accept-Language) and may be empty; it
+// should only break ties or cover text too short to judge.
+type Detector interface {
+	Detect(text, localeHint string) Result
+}
+
+// DetectorFunc adapts a function to the Detector interface
+type DetectorFunc func(text, localeHint string) Result
+
+// Detect implements Detector
+func (f DetectorFunc) Detect(text, localeHint string) Result {
+	return f(text, localeHint)
+}
+
+var (
+	mu            sync.RWMutex
+	registry      = map[string]Detector{}
+	defaultDet    Detector
+	minConfidence = DefaultMinConfidence
+)
+
+func init() {
+	defaultDet = NewHeuristicDetector()
+	registry["heuristic"] = defaultDet
+	registry["none"] = DetectorFunc(func(string, string) Result { return Result{Source: SourceDefault} })
+}
+
+// Register makes a detector selectable by name (routing.language_detection.detector)
+func Register(name string, d Detector) {
+	if name == "" || d == nil {
+		return
+	}
+	mu.Lock()
+	registry[name] = d
+	mu.Unlock()
+}
+
+// Lookup returns a registered detector
+func Lookup(name string) (Detector, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	d, ok := registry[name]
+	return d, ok
+}
+
+// SetDefault replaces the process-wide detector
+func SetDefault(d Detector) {
+	if d == nil {
+		return
+	}
+	mu.Lock()
+	defaultDet = d
+	mu.Unlock()
+}
+
+// SetMinConfidence sets the threshold used by Confident
+func SetMinConfidence(v float64) {
+	if v <= 0 || v > 1 {
+		v = DefaultMinConfidence
+	}
+	mu.Lock()
+	minConfidence = v
+	mu.Unlock()
+}
+
+// Detect runs the process-wide detector
+func Detect(text, localeHint string) Result {
+	mu.RLock()
+	d := defaultDet
+	mu.RUnlock()
+	return d.Detect(text, localeHint)
+}
+
+// Confident reports whether r is reliable enough to act on
+func (r Result) Confident() bool {
+	mu.RLock()
+	threshold := minConfidence
+	mu.RUnlock()
+	return r.Language != "" && r.Confidence >= threshold
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying a detection result
+func NewContext(ctx context.Context, r Result) context.Context {
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+// FromContext returns the detection result stored by NewContext
+func FromContext(ctx context.Context) (Result, bool) {
+	r, ok := ctx.Value(contextKey{}).(Result)
+	return r, ok
+}
+
+// LocaleHint returns the primary language subtag of the most preferred
+// entry in an Accept-Language header ("fr-CA,en;q=0.8" -> "fr")
+func LocaleHint(acceptLanguage string) string {
+	type entry struct {
+		lang string
+		q    float64
+	}
+	var entries []entry
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		primary, _, _ := strings.Cut(tag, "-")
+		primary, _, _ = strings.Cut(primary, "_")
+		entries = append(entries, entry{lang: strings.ToLower(primary), q: q})
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+	return entries[0].lang
+}
+
+// Matches reports whether lang is in languages. An empty list matches
+// everything; entries are primary subtags and compared case-insensitively.
+func Matches(lang string, languages []string) bool {
+	if len(languages) == 0 {
+		return true
+	}
+	for _, l := range languages {
+		if strings.EqualFold(l, lang) || l == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// HeuristicDetector detects language from the writing system and, for
+// Latin script, from the frequency of common function words. It needs no
+// model and runs in microseconds, which suits the request path.
+type HeuristicDetector struct {
+	stopwords map[string][]string // word -> languages
+}
+
+// latinStopwords are high-frequency function words, chosen to be mostly
+// distinct between languages
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "what", "how", "with", "for", "this", "you", "can", "please", "write", "explain", "why"},
+	"es": {"el", "los", "las", "es", "y", "que", "del", "por", "para", "una", "cómo", "qué", "está", "pero", "con", "explica", "escribe", "por favor"},
+	"fr": {"le", "les", "est", "et", "des", "une", "que", "pour", "dans", "qui", "pas", "avec", "vous", "je", "comment", "pourquoi", "écris", "explique"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "ich", "sie", "wie", "was", "warum", "für", "auf", "bitte", "schreibe", "erkläre"},
+	"it": {"il", "gli", "della", "che", "è", "per", "una", "non", "sono", "come", "perché", "cosa", "con", "scrivi", "spiega"},
+	"pt": {"o", "os", "da", "do", "que", "é", "não", "uma", "para", "com", "como", "por que", "você", "escreva", "explique"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "wat", "hoe", "waarom", "ik", "je", "schrijf", "leg"},
+}
+
+// NewHeuristicDetector creates the built-in detector
+func NewHeuristicDetector() *HeuristicDetector {
+	d := &HeuristicDetector{stopwords: make(map[string][]string)}
+	for lang, words := range latinStopwords {
+		for _, w := range words {
+			d.stopwords[w] = append(d.stopwords[w], lang)
+		}
+	}
+	return d
+}
+
+// scripts maps writing systems to the language they most likely indicate
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// Detect implements Detector
+func (d *HeuristicDetector) Detect(text, localeHint string) Result {
+	hint := strings.ToLower(localeHint)
+
+	counts := make(map[string]int)
+	letters, latin := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.lang]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return fromHint(hint)
+	}
+
+	// Non-Latin scripts identify the language almost on their own
+	if latin*2 < letters {
+		// Kana marks Japanese even when Han characters outnumber it
+		if counts["ja"] > 0 {
+			counts["ja"] += counts["zh"]
+			delete(counts, "zh")
+		}
+		// Han-only text is ambiguous between Chinese and Japanese kanji
+		if _, ok := counts["zh"]; ok && hint == "ja" {
+			counts["ja"] += counts["zh"]
+			delete(counts, "zh")
+		}
+		best, bestCount := "", 0
+		for lang, n := range counts {
+			if n > bestCount || (n == bestCount && lang < best) {
+				best, bestCount = lang, n
+			}
+		}
+		if best != "" {
+			return Result{Language: best, Confidence: float64(bestCount) / float64(letters), Source: SourceScript}
+		}
+	}
+
+	return d.detectLatin(text, hint)
+}
+
+func (d *HeuristicDetector) detectLatin(text, hint string) Result {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) == 0 {
+		return fromHint(hint)
+	}
+
+	scores := make(map[string]float64)
+	for i, w := range words {
+		for _, lang := range d.stopwords[w] {
+			scores[lang]++
+		}
+		if i+1 < len(words) {
+			for _, lang := range d.stopwords[w+" "+words[i+1]] {
+				scores[lang] += 2
+			}
+		}
+	}
+	// The client's locale breaks ties
+	if _, ok := latinStopwords[hint]; ok {
+		scores[hint] += 0.5
+	}
+
+	ranked := make([]string, 0, len(scores))
+	for lang := range scores {
+		ranked = append(ranked, lang)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if scores[ranked[i]] != scores[ranked[j]] {
+			return scores[ranked[i]] > scores[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	if len(ranked) == 0 || scores[ranked[0]] < 1 {
+		return fromHint(hint)
+	}
+	best, second := ranked[0], 0.0
+	if len(ranked) > 1 {
+		second = scores[ranked[1]]
+	}
+
+	// Confidence grows with evidence and with the margin over the runner-up
+	evidence := scores[best] / float64(len(words)) * 4
+	if evidence > 1 {
+		evidence = 1
+	}
+	margin := (scores[best] - second) / scores[best]
+	return Result{Language: best, Confidence: evidence * (0.5 + margin/2), Source: SourceStopwords}
+}
+
+func fromHint(hint string) Result {
+	if hint == "" {
+		return Result{Source: SourceDefault}
+	}
+	return Result{Language: hint, Confidence: 0.3, Source: SourceLocale}
+}
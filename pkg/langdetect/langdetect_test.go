@@ -0,0 +1,126 @@
+package langdetect
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHeuristicDetector(t *testing.T) {
+	d := NewHeuristicDetector()
+	tests := []struct {
+		text   string
+		hint   string
+		want   string
+		source string
+	}{
+		{"What is the capital of France and why is it important?", "", "en", SourceStopwords},
+		{"¿Cómo está el tiempo hoy en Madrid? Explica por favor", "", "es", SourceStopwords},
+		{"Pourquoi le ciel est bleu? Explique avec des exemples", "", "fr", SourceStopwords},
+		{"Warum ist der Himmel blau? Bitte erkläre das", "", "de", SourceStopwords},
+		{"Как дела? Расскажи мне о погоде", "", "ru", SourceScript},
+		{"今日は天気がいいですね", "", "ja", SourceScript},
+		{"今天天气很好", "", "zh", SourceScript},
+		{"今天天气", "ja", "ja", SourceScript},
+		{"안녕하세요 날씨가 좋네요", "", "ko", SourceScript},
+		{"12345 !!!", "de", "de", SourceLocale},
+		{"", "", "", SourceDefault},
+	}
+
+	for _, tt := range tests {
+		got := d.Detect(tt.text, tt.hint)
+		if got.Language != tt.want || got.Source != tt.source {
+			t.Errorf("Detect(%q, %q) = %+v, want %s via %s", tt.text, tt.hint, got, tt.want, tt.source)
+		}
+	}
+}
+
+func TestHeuristicDetector_HintBreaksTies(t *testing.T) {
+	d := NewHeuristicDetector()
+	// "de" and "is" are stopwords in several languages
+	if got := d.Detect("de is", "nl"); got.Language != "nl" {
+		t.Errorf("Expected locale hint to break tie, got %+v", got)
+	}
+}
+
+func TestResultConfident(t *testing.T) {
+	defer SetMinConfidence(DefaultMinConfidence)
+
+	d := NewHeuristicDetector()
+	if r := d.Detect("What is the best way to learn how to write code in Go?", ""); !r.Confident() {
+		t.Errorf("Expected confident English detection, got %+v", r)
+	}
+	if r := d.Detect("hello", "fr"); r.Confident() {
+		t.Errorf("Locale-only guess should not be confident, got %+v", r)
+	}
+
+	SetMinConfidence(0.2)
+	if r := d.Detect("hello", "fr"); !r.Confident() {
+		t.Errorf("Expected locale guess to pass lowered threshold, got %+v", r)
+	}
+}
+
+func TestLocaleHint(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"fr-CA,fr;q=0.9,en;q=0.8": "fr",
+		"en;q=0.5, de-DE":         "de",
+		"*":                       "",
+		"pt_BR":                   "pt",
+		"es;q=0, it":              "it",
+	}
+	for header, want := range tests {
+		if got := LocaleHint(header); got != want {
+			t.Errorf("LocaleHint(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestRegistryAndDefault(t *testing.T) {
+	if _, ok := Lookup("heuristic"); !ok {
+		t.Fatal("heuristic detector not registered")
+	}
+	if r := mustLookup(t, "none").Detect("What is the time?", ""); r.Language != "" {
+		t.Errorf("none detector returned %+v", r)
+	}
+
+	fixed := DetectorFunc(func(string, string) Result { return Result{Language: "xx", Confidence: 1} })
+	Register("fixed", fixed)
+	SetDefault(mustLookup(t, "fixed"))
+	defer SetDefault(mustLookup(t, "heuristic"))
+
+	if r := Detect("anything", ""); r.Language != "xx" {
+		t.Errorf("Expected custom default detector, got %+v", r)
+	}
+}
+
+func mustLookup(t *testing.T, name string) Detector {
+	t.Helper()
+	d, ok := Lookup(name)
+	if !ok {
+		t.Fatalf("detector %q not registered", name)
+	}
+	return d
+}
+
+func TestContext(t *testing.T) {
+	ctx := NewContext(context.Background(), Result{Language: "fr", Confidence: 0.9})
+	r, ok := FromContext(ctx)
+	if !ok || r.Language != "fr" {
+		t.Errorf("FromContext = %+v, %v", r, ok)
+	}
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("Expected no result in empty context")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	if !Matches("fr", nil) {
+		t.Error("Empty list should match everything")
+	}
+	if !Matches("EN", []string{"en"}) {
+		t.Error("Expected case-insensitive match")
+	}
+	if Matches("fr", []string{"en", "de"}) {
+		t.Error("fr should not match [en de]")
+	}
+}
@@ -0,0 +1,407 @@
+// Package lifecycle starts and stops the proxy's subsystems (thermal
+// monitoring, devices, D-Bus services, servers, ...) in a fixed order with
+// per-step timeouts, and restarts one on its own from the admin API
+// without taking the rest of the process down.
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout bounds a subsystem's Start or Stop when it sets none
+const DefaultTimeout = 30 * time.Second
+
+// Subsystem states
+const (
+	StateStopped = "stopped"
+	StateRunning = "running"
+	StateFailed  = "failed"
+)
+
+// Admin actions
+const (
+	ActionStart   = "start"
+	ActionStop    = "stop"
+	ActionRestart = "restart"
+)
+
+// ErrFixed is returned when asked to start, stop or restart a fixed
+// subsystem on its own
+var ErrFixed = errors.New("subsystem starts and stops only with the process")
+
+// Subsystem is a part of the proxy started and stopped as a unit
+type Subsystem struct {
+	Name string
+
+	// Start brings the subsystem up; Stop takes it down so a later Start
+	// can bring it up again. Either may be nil.
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+
+	// Timeout bounds each Start and Stop (0 = DefaultTimeout). A call that
+	// overruns is reported as failed and left to finish in the background.
+	Timeout time.Duration
+
+	// Fixed subsystems start and stop only with the process, e.g. the
+	// servers that carry the admin API itself
+	Fixed bool
+
+	// Manual subsystems are left out of StartAll and started only by
+	// Start, e.g. resources held only while this HA node is active
+	Manual bool
+}
+
+// Status is a point-in-time view of a subsystem
+type Status struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Fixed     bool      `json:"fixed,omitempty"`
+	Manual    bool      `json:"manual,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Since     time.Time `json:"since,omitempty"`
+	Restarts  int       `json:"restarts"`
+	Order     int       `json:"order"`
+	TimeoutMs int64     `json:"timeout_ms"`
+}
+
+type entry struct {
+	Subsystem
+
+	op sync.Mutex // serializes Start and Stop of this subsystem
+
+	state    string
+	err      error
+	since    time.Time
+	restarts int
+}
+
+// Manager starts subsystems in the order they were registered and stops
+// them in reverse, so each one can rely on those registered before it
+type Manager struct {
+	mu         sync.Mutex
+	subsystems []*entry
+	byName     map[string]*entry
+}
+
+// NewManager creates a manager with no subsystems
+func NewManager() *Manager {
+	return &Manager{byName: make(map[string]*entry)}
+}
+
+// Register adds s after every subsystem registered so far. It isn't
+// started until Start or StartAll.
+func (m *Manager) Register(s Subsystem) error {
+	if s.Name == "" {
+		return errors.New("subsystem name is required")
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = DefaultTimeout
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.byName[s.Name]; ok {
+		return fmt.Errorf("subsystem %s already registered", s.Name)
+	}
+	e := &entry{Subsystem: s, state: StateStopped}
+	m.subsystems = append(m.subsystems, e)
+	m.byName[s.Name] = e
+	return nil
+}
+
+// StartAll starts every stopped or failed subsystem in order, except
+// manual ones. A subsystem failing to start doesn't stop the rest; the
+// failures are returned together.
+func (m *Manager) StartAll(ctx context.Context) error {
+	var errs []error
+	for _, e := range m.entries() {
+		if e.Manual {
+			continue
+		}
+		if err := m.start(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StopAll stops every running or failed subsystem in reverse order
+func (m *Manager) StopAll(ctx context.Context) error {
+	var errs []error
+	entries := m.entries()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := m.stop(ctx, entries[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Start starts the named subsystem if it isn't running
+func (m *Manager) Start(ctx context.Context, name string) error {
+	e, err := m.lookup(name)
+	if err != nil {
+		return err
+	}
+	return m.start(ctx, e)
+}
+
+// Stop stops the named subsystem unless it is already stopped
+func (m *Manager) Stop(ctx context.Context, name string) error {
+	e, err := m.lookup(name)
+	if err != nil {
+		return err
+	}
+	return m.stop(ctx, e)
+}
+
+// Restart stops the named subsystem and starts it again, leaving the rest
+// of the process running. Fixed subsystems can't be restarted.
+func (m *Manager) Restart(ctx context.Context, name string) error {
+	e, err := m.lookup(name)
+	if err != nil {
+		return err
+	}
+	if e.Fixed {
+		return fmt.Errorf("subsystem %s: %w", name, ErrFixed)
+	}
+	if err := m.stop(ctx, e); err != nil {
+		return err
+	}
+	if err := m.start(ctx, e); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	e.restarts++
+	m.mu.Unlock()
+	return nil
+}
+
+// Get returns the named subsystem's status
+func (m *Manager) Get(name string) (Status, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, e := range m.subsystems {
+		if e.Name == name {
+			return e.status(i), true
+		}
+	}
+	return Status{}, false
+}
+
+// Statuses returns every subsystem in start order
+func (m *Manager) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]Status, len(m.subsystems))
+	for i, e := range m.subsystems {
+		statuses[i] = e.status(i)
+	}
+	return statuses
+}
+
+// status returns e's status at order. Caller must hold m.mu.
+func (e *entry) status(order int) Status {
+	s := Status{
+		Name:      e.Name,
+		State:     e.state,
+		Fixed:     e.Fixed,
+		Manual:    e.Manual,
+		Since:     e.since,
+		Restarts:  e.restarts,
+		Order:     order,
+		TimeoutMs: e.Timeout.Milliseconds(),
+	}
+	if e.err != nil {
+		s.Error = e.err.Error()
+	}
+	return s
+}
+
+func (m *Manager) entries() []*entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]*entry, len(m.subsystems))
+	copy(entries, m.subsystems)
+	return entries
+}
+
+func (m *Manager) lookup(name string) (*entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("subsystem not found: %s", name)
+	}
+	return e, nil
+}
+
+func (m *Manager) start(ctx context.Context, e *entry) error {
+	e.op.Lock()
+	defer e.op.Unlock()
+	if m.state(e) == StateRunning {
+		return nil
+	}
+
+	err := run(ctx, e.Timeout, e.Start)
+	if err != nil {
+		err = fmt.Errorf("start %s: %w", e.Name, err)
+		m.set(e, StateFailed, err)
+		logging.Logger.Warn("Subsystem failed to start", zap.String("subsystem", e.Name), zap.Error(err))
+		return err
+	}
+	m.set(e, StateRunning, nil)
+	logging.Logger.Info("Subsystem started", zap.String("subsystem", e.Name))
+	return nil
+}
+
+func (m *Manager) stop(ctx context.Context, e *entry) error {
+	e.op.Lock()
+	defer e.op.Unlock()
+	// A failed subsystem is stopped too, to clean up after a partial start
+	if m.state(e) == StateStopped {
+		return nil
+	}
+
+	err := run(ctx, e.Timeout, e.Stop)
+	if err != nil {
+		err = fmt.Errorf("stop %s: %w", e.Name, err)
+		m.set(e, StateFailed, err)
+		logging.Logger.Warn("Subsystem failed to stop", zap.String("subsystem", e.Name), zap.Error(err))
+		return err
+	}
+	m.set(e, StateStopped, nil)
+	logging.Logger.Info("Subsystem stopped", zap.String("subsystem", e.Name))
+	return nil
+}
+
+func (m *Manager) state(e *entry) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return e.state
+}
+
+func (m *Manager) set(e *entry, state string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.state = state
+	e.err = err
+	e.since = time.Now()
+}
+
+// run calls fn with a context bounded by timeout, giving up on it once
+// the context is done
+func run(ctx context.Context, timeout time.Duration, fn func(context.Context) error) error {
+	if fn == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// actionRequest is the body of a POST to Handler
+type actionRequest struct {
+	Name   string `json:"name"`
+	Action string `json:"action"` // start, stop or restart (default)
+}
+
+// Handler serves the admin subsystems endpoint. GET lists subsystems (or
+// one, with ?name=); POST {"name": ..., "action": "restart"} starts, stops
+// or restarts one.
+func (m *Manager) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req actionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Name != "" {
+				name = req.Name
+			}
+			if name == "" {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+			if err := m.apply(r.Context(), name, req.Action); err != nil {
+				code := http.StatusInternalServerError
+				switch {
+				case errors.Is(err, errUnknownAction):
+					code = http.StatusBadRequest
+				case errors.Is(err, ErrFixed):
+					code = http.StatusConflict
+				}
+				if _, ok := m.Get(name); !ok {
+					code = http.StatusNotFound
+				}
+				http.Error(w, err.Error(), code)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if name == "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"subsystems": m.Statuses()})
+			return
+		}
+		status, ok := m.Get(name)
+		if !ok {
+			http.Error(w, "subsystem not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+var errUnknownAction = errors.New("action must be start, stop or restart")
+
+// apply runs an admin action on the named subsystem. The action outlives
+// the request that asked for it, bounded only by the subsystem's timeout.
+func (m *Manager) apply(ctx context.Context, name, action string) error {
+	ctx = context.WithoutCancel(ctx)
+	switch action {
+	case ActionRestart, "":
+		return m.Restart(ctx, name)
+	}
+
+	e, err := m.lookup(name)
+	if err != nil {
+		return err
+	}
+	if e.Fixed {
+		return fmt.Errorf("subsystem %s: %w", name, ErrFixed)
+	}
+	switch action {
+	case ActionStart:
+		return m.start(ctx, e)
+	case ActionStop:
+		return m.stop(ctx, e)
+	}
+	return errUnknownAction
+}
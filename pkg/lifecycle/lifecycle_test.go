@@ -0,0 +1,218 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	if err := logging.InitLogger("info", false); err != nil {
+		panic(err)
+	}
+	defer logging.Sync()
+
+	os.Exit(m.Run())
+}
+
+// recorder logs the order subsystems start and stop in
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) subsystem(name string) Subsystem {
+	return Subsystem{
+		Name:  name,
+		Start: func(context.Context) error { r.record("start " + name); return nil },
+		Stop:  func(context.Context) error { r.record("stop " + name); return nil },
+	}
+}
+
+func (r *recorder) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.calls, ", ")
+}
+
+func TestManager_StartAllStopAllOrder(t *testing.T) {
+	m := NewManager()
+	rec := &recorder{}
+	for _, name := range []string{"thermal", "dbus", "servers"} {
+		if err := m.Register(rec.subsystem(name)); err != nil {
+			t.Fatalf("Register %s failed: %v", name, err)
+		}
+	}
+
+	if err := m.StartAll(context.Background()); err != nil {
+		t.Fatalf("StartAll failed: %v", err)
+	}
+	// Running subsystems aren't started twice
+	m.StartAll(context.Background())
+	if err := m.StopAll(context.Background()); err != nil {
+		t.Fatalf("StopAll failed: %v", err)
+	}
+
+	want := "start thermal, start dbus, start servers, stop servers, stop dbus, stop thermal"
+	if got := rec.String(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestManager_Register(t *testing.T) {
+	m := NewManager()
+	if err := m.Register(Subsystem{}); err == nil {
+		t.Error("Expected an error for a subsystem without a name")
+	}
+	m.Register(Subsystem{Name: "thermal"})
+	if err := m.Register(Subsystem{Name: "thermal"}); err == nil {
+		t.Error("Expected an error registering a name twice")
+	}
+	if status, _ := m.Get("thermal"); status.TimeoutMs != DefaultTimeout.Milliseconds() || status.State != StateStopped {
+		t.Errorf("Expected a stopped subsystem with the default timeout, got %+v", status)
+	}
+}
+
+func TestManager_Restart(t *testing.T) {
+	m := NewManager()
+	rec := &recorder{}
+	m.Register(rec.subsystem("thermal"))
+	m.Register(rec.subsystem("dbus"))
+	m.StartAll(context.Background())
+
+	if err := m.Restart(context.Background(), "dbus"); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if got, want := rec.String(), "start thermal, start dbus, stop dbus, start dbus"; got != want {
+		t.Errorf("Expected only dbus restarted: %q, got %q", want, got)
+	}
+	if status, _ := m.Get("dbus"); status.Restarts != 1 || status.State != StateRunning {
+		t.Errorf("Expected dbus running after 1 restart, got %+v", status)
+	}
+
+	if err := m.Restart(context.Background(), "missing"); err == nil {
+		t.Error("Expected an error restarting an unknown subsystem")
+	}
+}
+
+func TestManager_FixedAndManual(t *testing.T) {
+	m := NewManager()
+	rec := &recorder{}
+	servers := rec.subsystem("servers")
+	servers.Fixed = true
+	devices := rec.subsystem("virtual-devices")
+	devices.Manual = true
+	m.Register(devices)
+	m.Register(servers)
+
+	m.StartAll(context.Background())
+	if got := rec.String(); got != "start servers" {
+		t.Errorf("Expected the manual subsystem left out of StartAll, got %q", got)
+	}
+
+	if err := m.Restart(context.Background(), "servers"); !errors.Is(err, ErrFixed) {
+		t.Errorf("Expected ErrFixed restarting a fixed subsystem, got %v", err)
+	}
+	if err := m.Start(context.Background(), "virtual-devices"); err != nil {
+		t.Errorf("Expected a manual subsystem to start on request, got %v", err)
+	}
+}
+
+func TestManager_Failures(t *testing.T) {
+	m := NewManager()
+	rec := &recorder{}
+	m.Register(Subsystem{
+		Name:  "devices",
+		Start: func(context.Context) error { return errors.New("no udev") },
+		Stop:  func(context.Context) error { rec.record("stop devices"); return nil },
+	})
+	m.Register(Subsystem{
+		Name:    "dbus",
+		Timeout: 10 * time.Millisecond,
+		Start: func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		},
+	})
+	m.Register(rec.subsystem("servers"))
+
+	err := m.StartAll(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no udev") || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected both failures reported, got %v", err)
+	}
+	if got := rec.String(); got != "start servers" {
+		t.Errorf("Expected the rest to start despite the failures, got %q", got)
+	}
+	status, _ := m.Get("devices")
+	if status.State != StateFailed || status.Error == "" {
+		t.Errorf("Expected devices failed with its error, got %+v", status)
+	}
+
+	// Failed subsystems are stopped too, to clean up a partial start
+	m.StopAll(context.Background())
+	if got := rec.String(); !strings.HasSuffix(got, "stop devices") {
+		t.Errorf("Expected the failed subsystem stopped, got %q", got)
+	}
+}
+
+func TestManager_Handler(t *testing.T) {
+	m := NewManager()
+	rec := &recorder{}
+	m.Register(rec.subsystem("dbus"))
+	servers := rec.subsystem("servers")
+	servers.Fixed = true
+	m.Register(servers)
+	m.StartAll(context.Background())
+	handler := m.Handler()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/admin/subsystems", nil))
+	var list struct {
+		Subsystems []Status `json:"subsystems"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil || len(list.Subsystems) != 2 || list.Subsystems[1].Name != "servers" {
+		t.Fatalf("Expected both subsystems in order, got %+v (%v)", list, err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"restart", `{"name":"dbus"}`, http.StatusOK},
+		{"stop", `{"name":"dbus","action":"stop"}`, http.StatusOK},
+		{"unknown action", `{"name":"dbus","action":"reload"}`, http.StatusBadRequest},
+		{"fixed", `{"name":"servers","action":"restart"}`, http.StatusConflict},
+		{"unknown subsystem", `{"name":"missing"}`, http.StatusNotFound},
+		{"no name", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodPost, "/admin/subsystems", strings.NewReader(tt.body)))
+			if w.Code != tt.code {
+				t.Errorf("Expected %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if status, _ := m.Get("dbus"); status.State != StateStopped || status.Restarts != 1 {
+		t.Errorf("Expected dbus restarted once then stopped, got %+v", status)
+	}
+}
@@ -0,0 +1,226 @@
+// Package limits bounds the size of requests before they reach a handler:
+// request bodies per endpoint class, and the estimated length of prompts,
+// which an API key can lower or raise for itself. Requests over a limit
+// get 413 Request Entity Too Large with a JSON error naming the limit.
+package limits
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+)
+
+// Class is a kind of request body with its own size limit
+type Class string
+
+const (
+	ClassJSON  Class = "json"  // generation, embedding, speech and image generation requests
+	ClassAudio Class = "audio" // multipart audio uploads
+	ClassImage Class = "image" // multipart image uploads
+)
+
+// Default body limits per class
+const (
+	DefaultJSONBytes  = 10 * 1024 * 1024
+	DefaultAudioBytes = 25 * 1024 * 1024
+	DefaultImageBytes = 9 * 1024 * 1024
+)
+
+// Error codes in 413 responses
+const (
+	CodeBodyTooLarge  = "request_too_large"
+	CodePromptTooLong = "prompt_too_long"
+)
+
+// Config sets the limits
+type Config struct {
+	// MaxBodyBytes is the largest body per class; 0 or absent is unlimited
+	MaxBodyBytes map[Class]int64
+
+	// MaxPromptTokens is the longest prompt, in estimated tokens, for JSON
+	// requests; 0 is unlimited. Keys with their own limit use that instead.
+	MaxPromptTokens int
+}
+
+// DefaultConfig returns the default body limits and no prompt limit
+func DefaultConfig() Config {
+	return Config{MaxBodyBytes: map[Class]int64{
+		ClassJSON:  DefaultJSONBytes,
+		ClassAudio: DefaultAudioBytes,
+		ClassImage: DefaultImageBytes,
+	}}
+}
+
+// Limiter enforces the limits
+type Limiter struct {
+	cfg Config
+}
+
+// New creates a limiter
+func New(cfg Config) *Limiter {
+	return &Limiter{cfg: cfg}
+}
+
+// Middleware enforces class's body limit on every request. A declared
+// Content-Length over the limit is refused up front; otherwise the body
+// is cut off when it grows past the limit. JSON bodies are read here, so
+// a chunked body over the limit gets the same 413, and checked against
+// the prompt limit, which needs the API key from the auth middleware to
+// run first.
+func (l *Limiter) Middleware(class Class) func(http.Handler) http.Handler {
+	maxBytes := l.cfg.MaxBodyBytes[class]
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if maxBytes > 0 {
+				if r.ContentLength > maxBytes {
+					l.bodyTooLarge(w, r, class, maxBytes)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+
+			if class != ClassJSON || r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					l.bodyTooLarge(w, r, class, maxBytes)
+					return
+				}
+				writeError(w, r, http.StatusBadRequest, "Failed to read request body", "invalid_request_error", 0)
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(data))
+
+			maxTokens := l.cfg.MaxPromptTokens
+			if info, ok := auth.KeyInfoFromContext(r.Context()); ok && info.MaxPromptTokens > 0 {
+				maxTokens = info.MaxPromptTokens
+			}
+			if tokens := PromptTokens(data); maxTokens > 0 && tokens > maxTokens {
+				metrics.RecordRequestTooLarge(string(class), "prompt")
+				writeError(w, r, http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("Prompt is about %d tokens, over the limit of %d", tokens, maxTokens),
+					CodePromptTooLong, int64(maxTokens))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (l *Limiter) bodyTooLarge(w http.ResponseWriter, r *http.Request, class Class, maxBytes int64) {
+	metrics.RecordRequestTooLarge(string(class), "body")
+	writeError(w, r, http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("Request body exceeds the %s limit of %d bytes", class, maxBytes),
+		CodeBodyTooLarge, maxBytes)
+}
+
+// PromptTokens estimates the prompt length of an OpenAI or Ollama request
+// body in tokens (~4 characters each): its prompt, input, system, suffix
+// and message contents. Token arrays count one token per element. Bodies
+// that aren't JSON count as empty and are left to the handler to refuse.
+func PromptTokens(body []byte) int {
+	var req struct {
+		Prompt   json.RawMessage `json:"prompt"`
+		Input    json.RawMessage `json:"input"`
+		System   string          `json:"system"`
+		Suffix   string          `json:"suffix"`
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return 0
+	}
+
+	chars, tokens := len(req.System)+len(req.Suffix), 0
+	for _, raw := range []json.RawMessage{req.Prompt, req.Input} {
+		c, t := textLength(raw)
+		chars, tokens = chars+c, tokens+t
+	}
+	for _, m := range req.Messages {
+		c, t := textLength(m.Content)
+		chars, tokens = chars+c, tokens+t
+	}
+	return (chars+3)/4 + tokens
+}
+
+// textLength returns the characters of text in a prompt field - a string,
+// an array of strings, token IDs or content parts - and the token IDs
+func textLength(raw json.RawMessage) (chars, tokens int) {
+	if len(raw) == 0 {
+		return 0, 0
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return len(s), 0
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(raw, &items) == nil {
+		for _, item := range items {
+			c, t := textLength(item)
+			chars, tokens = chars+c, tokens+t
+		}
+		return chars, tokens
+	}
+	var part struct {
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(raw, &part) == nil {
+		return len(part.Text), 0
+	}
+	var n float64
+	if json.Unmarshal(raw, &n) == nil {
+		return 0, 1
+	}
+	return 0, 0
+}
+
+// writeError writes a structured error: the Ollama API's {"error": "..."}
+// on /api/ routes and OpenAI's error object elsewhere, both with the code
+// and limit, and the request ID
+func writeError(w http.ResponseWriter, r *http.Request, status int, message, code string, limit int64) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	requestID := middleware.GetRequestID(r.Context())
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		resp := map[string]interface{}{
+			"error": message,
+			"code":  code,
+			"limit": limit,
+		}
+		if requestID != "" {
+			resp["request_id"] = requestID
+		}
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+	detail := map[string]interface{}{
+		"message": message,
+		"type":    "invalid_request_error",
+		"code":    code,
+		"limit":   limit,
+	}
+	if requestID != "" {
+		detail["request_id"] = requestID
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": detail})
+}
@@ -0,0 +1,125 @@
+package limits
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+)
+
+// echoHandler replies with the body it read
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(data)
+	})
+}
+
+func TestMiddleware_BodyLimit(t *testing.T) {
+	l := New(Config{MaxBodyBytes: map[Class]int64{ClassJSON: 64, ClassAudio: 16}})
+
+	small := `{"prompt":"hi"}`
+	w := httptest.NewRecorder()
+	l.Middleware(ClassJSON)(echoHandler()).ServeHTTP(w, httptest.NewRequest("POST", "/v1/completions", strings.NewReader(small)))
+	if w.Code != http.StatusOK || w.Body.String() != small {
+		t.Errorf("Expected a small body to pass through intact, got %d %q", w.Code, w.Body)
+	}
+
+	// Content-Length declared over the limit
+	big := `{"prompt":"` + strings.Repeat("x", 100) + `"}`
+	w = httptest.NewRecorder()
+	l.Middleware(ClassJSON)(echoHandler()).ServeHTTP(w, httptest.NewRequest("POST", "/v1/completions", strings.NewReader(big)))
+	var resp struct {
+		Error struct {
+			Code  string `json:"code"`
+			Limit int64  `json:"limit"`
+		} `json:"error"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusRequestEntityTooLarge || resp.Error.Code != CodeBodyTooLarge || resp.Error.Limit != 64 {
+		t.Errorf("Expected a structured 413, got %d %+v", w.Code, resp)
+	}
+
+	// Chunked body with no Content-Length grows past the limit
+	req := httptest.NewRequest("POST", "/api/generate", io.MultiReader(strings.NewReader(big)))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	l.Middleware(ClassJSON)(echoHandler()).ServeHTTP(w, req)
+	var ollama struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	json.NewDecoder(w.Body).Decode(&ollama)
+	if w.Code != http.StatusRequestEntityTooLarge || ollama.Code != CodeBodyTooLarge || ollama.Error == "" {
+		t.Errorf("Expected an Ollama-style 413 for a chunked body, got %d %+v", w.Code, ollama)
+	}
+
+	// Other classes cut the body off in the handler
+	req = httptest.NewRequest("POST", "/v1/audio/transcriptions", strings.NewReader(strings.Repeat("a", 32)))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	l.Middleware(ClassAudio)(echoHandler()).ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected the handler to see the body cut off, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_PromptLimit(t *testing.T) {
+	l := New(Config{MaxBodyBytes: map[Class]int64{ClassJSON: 1 << 20}, MaxPromptTokens: 10})
+	call := func(body string, info *auth.APIKeyInfo) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+		if info != nil {
+			req = req.WithContext(auth.WithKeyInfo(req.Context(), *info))
+		}
+		w := httptest.NewRecorder()
+		l.Middleware(ClassJSON)(echoHandler()).ServeHTTP(w, req)
+		return w
+	}
+
+	long := `{"messages":[{"role":"user","content":"` + strings.Repeat("word ", 20) + `"}]}`
+	w := call(long, nil)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), CodePromptTooLong) {
+		t.Errorf("Expected a long prompt to be refused, got %d %s", w.Code, w.Body)
+	}
+	if w := call(`{"messages":[{"role":"user","content":"hi"}]}`, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected a short prompt to pass, got %d", w.Code)
+	}
+	if w := call(long, &auth.APIKeyInfo{Name: "batch", MaxPromptTokens: 1000}); w.Code != http.StatusOK {
+		t.Errorf("Expected a key's own limit to win, got %d", w.Code)
+	}
+	if w := call(`{"messages":[{"role":"user","content":"hello world"}]}`, &auth.APIKeyInfo{Name: "tight", MaxPromptTokens: 2}); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a key's lower limit to apply, got %d", w.Code)
+	}
+}
+
+func TestPromptTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"prompt", `{"prompt":"abcdefgh"}`, 2},
+		{"system and prompt", `{"system":"abcd","prompt":"abcd"}`, 2},
+		{"prompt list", `{"prompt":["abcd","abcd"]}`, 2},
+		{"token ids", `{"prompt":[1,2,3]}`, 3},
+		{"embedding input", `{"input":["abcdefgh"]}`, 2},
+		{"messages", `{"messages":[{"role":"system","content":"abcd"},{"role":"user","content":"abcd"}]}`, 2},
+		{"content parts", `{"messages":[{"role":"user","content":[{"type":"text","text":"abcdefgh"},{"type":"image_url","image_url":{"url":"data:..."}}]}]}`, 2},
+		{"not json", `prompt=hi`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PromptTokens([]byte(tt.body)); got != tt.want {
+				t.Errorf("PromptTokens(%s) = %d, want %d", tt.body, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package logging
 
 import (
+	"context"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -43,6 +45,29 @@ func InitLogger(level string, production bool) error {
 	return nil
 }
 
+type fieldsKey struct{}
+
+// WithFields returns a context whose logger adds fields to every entry,
+// e.g. the ID of the request being served
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	existing, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	merged := make([]zap.Field, 0, len(existing)+len(fields))
+	merged = append(append(merged, existing...), fields...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FromContext returns the global logger with the fields stored in ctx
+func FromContext(ctx context.Context) *zap.Logger {
+	logger := Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if fields, ok := ctx.Value(fieldsKey{}).([]zap.Field); ok {
+		return logger.With(fields...)
+	}
+	return logger
+}
+
 // Sync flushes any buffered log entries
 func Sync() {
 	if Logger != nil {
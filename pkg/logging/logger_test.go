@@ -1,10 +1,12 @@
 package logging
 
 import (
+	"context"
 	"testing"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestInitLogger(t *testing.T) {
@@ -252,3 +254,29 @@ func TestProductionVsDevelopmentMode(t *testing.T) {
 		Debug("development mode test")
 	})
 }
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	Logger = zap.New(core)
+	defer func() { Logger = nil }()
+
+	ctx := WithFields(context.Background(), zap.String("request_id", "abc"))
+	ctx = WithFields(ctx, zap.String("tenant", "ml"))
+	FromContext(ctx).Info("routed")
+	FromContext(context.Background()).Info("plain")
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["request_id"] != "abc" || fields["tenant"] != "ml" {
+		t.Errorf("Expected the context's fields, got %v", fields)
+	}
+	if len(entries[1].Context) != 0 {
+		t.Errorf("Expected no fields without a context, got %v", entries[1].Context)
+	}
+
+	Logger = nil
+	FromContext(ctx).Info("dropped") // must not panic
+}
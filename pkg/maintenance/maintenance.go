@@ -0,0 +1,218 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// DefaultRetryAfter is sent when maintenance is enabled without an explicit value
+const DefaultRetryAfter = 5 * time.Minute
+
+// Status is a point-in-time view of the maintenance switch
+type Status struct {
+	Enabled           bool      `json:"enabled"`
+	Message           string    `json:"message,omitempty"`
+	RetryAfterSeconds int       `json:"retry_after_seconds,omitempty"`
+	Since             time.Time `json:"since,omitempty"`
+	Banner            string    `json:"banner,omitempty"`
+}
+
+// State holds the global maintenance switch and informational banner
+type State struct {
+	mu         sync.RWMutex
+	enabled    bool
+	message    string
+	retryAfter time.Duration
+	since      time.Time
+	banner     string
+
+	listeners []func(Status)
+}
+
+// New creates a new maintenance state (disabled)
+func New() *State {
+	return &State{}
+}
+
+// Default is the process-wide maintenance state
+var Default = New()
+
+// SetDefault replaces the process-wide maintenance state
+func SetDefault(s *State) {
+	if s != nil {
+		Default = s
+	}
+}
+
+// Enable turns maintenance mode on. New data-plane requests get 503.
+func (s *State) Enable(message string, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = DefaultRetryAfter
+	}
+
+	s.mu.Lock()
+	if !s.enabled {
+		s.since = time.Now()
+	}
+	s.enabled = true
+	s.message = message
+	s.retryAfter = retryAfter
+	s.mu.Unlock()
+
+	if logging.Logger != nil {
+		logging.Logger.Warn("Maintenance mode enabled",
+			zap.String("message", message),
+			zap.Duration("retry_after", retryAfter),
+		)
+	}
+	s.notify()
+}
+
+// Disable turns maintenance mode off
+func (s *State) Disable() {
+	s.mu.Lock()
+	wasEnabled := s.enabled
+	s.enabled = false
+	s.message = ""
+	s.since = time.Time{}
+	s.mu.Unlock()
+
+	if wasEnabled && logging.Logger != nil {
+		logging.Logger.Info("Maintenance mode disabled")
+	}
+	s.notify()
+}
+
+// SetBanner sets the informational banner shown in /v1/models (empty clears it)
+func (s *State) SetBanner(banner string) {
+	s.mu.Lock()
+	s.banner = banner
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Enabled reports whether maintenance mode is on
+func (s *State) Enabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.enabled
+}
+
+// Banner returns the current banner
+func (s *State) Banner() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.banner
+}
+
+// Status returns the current state
+func (s *State) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := Status{
+		Enabled: s.enabled,
+		Message: s.message,
+		Since:   s.since,
+		Banner:  s.banner,
+	}
+	if s.enabled {
+		status.RetryAfterSeconds = int(s.retryAfter.Seconds())
+	}
+	return status
+}
+
+// OnChange registers a callback invoked after every state change
+func (s *State) OnChange(fn func(Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+func (s *State) notify() {
+	s.mu.RLock()
+	listeners := make([]func(Status), len(s.listeners))
+	copy(listeners, s.listeners)
+	s.mu.RUnlock()
+
+	status := s.Status()
+	for _, fn := range listeners {
+		fn(status)
+	}
+}
+
+// Middleware rejects requests with 503 and Retry-After while maintenance is on.
+// Apply it to data-plane routes only so admin and metrics endpoints stay up.
+func (s *State) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := s.Status()
+		if !status.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		message := status.Message
+		if message == "" {
+			message = "Service is under maintenance"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(status.RetryAfterSeconds))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": message,
+				"type":    "service_unavailable",
+				"code":    "maintenance",
+			},
+		})
+	})
+}
+
+// updateRequest is the admin API payload
+type updateRequest struct {
+	Enabled           *bool   `json:"enabled"`
+	Message           string  `json:"message"`
+	RetryAfterSeconds int     `json:"retry_after_seconds"`
+	Banner            *string `json:"banner"`
+}
+
+// Handler serves the admin maintenance endpoint.
+// GET returns the status; POST updates it with a JSON body.
+func (s *State) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			// Fall through to status response
+		case http.MethodPost, http.MethodPut:
+			var req updateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			if req.Banner != nil {
+				s.SetBanner(*req.Banner)
+			}
+			if req.Enabled != nil {
+				if *req.Enabled {
+					s.Enable(req.Message, time.Duration(req.RetryAfterSeconds)*time.Second)
+				} else {
+					s.Disable()
+				}
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Status())
+	}
+}
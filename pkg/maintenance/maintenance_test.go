@@ -0,0 +1,151 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	if err := logging.InitLogger("info", false); err != nil {
+		panic(err)
+	}
+	defer logging.Sync()
+
+	os.Exit(m.Run())
+}
+
+func TestState_EnableDisable(t *testing.T) {
+	s := New()
+	if s.Enabled() {
+		t.Fatal("Expected new state to be disabled")
+	}
+
+	s.Enable("upgrading GPUs", 0)
+	status := s.Status()
+	if !status.Enabled {
+		t.Error("Expected maintenance to be enabled")
+	}
+	if status.RetryAfterSeconds != int(DefaultRetryAfter.Seconds()) {
+		t.Errorf("Expected default retry-after %v, got %ds", DefaultRetryAfter, status.RetryAfterSeconds)
+	}
+	if status.Since.IsZero() {
+		t.Error("Expected Since to be set")
+	}
+
+	s.Disable()
+	status = s.Status()
+	if status.Enabled || status.Message != "" || status.RetryAfterSeconds != 0 {
+		t.Errorf("Expected cleared status after disable, got %+v", status)
+	}
+}
+
+func TestState_Banner(t *testing.T) {
+	s := New()
+	s.SetBanner("Downtime Saturday 02:00 UTC")
+
+	if s.Banner() != "Downtime Saturday 02:00 UTC" {
+		t.Errorf("Unexpected banner: %s", s.Banner())
+	}
+	if s.Enabled() {
+		t.Error("Banner must not enable maintenance")
+	}
+}
+
+func TestState_OnChange(t *testing.T) {
+	s := New()
+
+	var got []Status
+	s.OnChange(func(status Status) {
+		got = append(got, status)
+	})
+
+	s.Enable("", time.Minute)
+	s.Disable()
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(got))
+	}
+	if !got[0].Enabled || got[1].Enabled {
+		t.Errorf("Unexpected notification sequence: %+v", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	s := New()
+	handler := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 when not in maintenance, got %d", rec.Code)
+	}
+
+	s.Enable("back soon", 2*time.Minute)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/chat/completions", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 in maintenance, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "120" {
+		t.Errorf("Expected Retry-After 120, got %q", got)
+	}
+	if !strings.Contains(rec.Body.String(), "back soon") {
+		t.Errorf("Expected maintenance message in body, got %s", rec.Body.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s := New()
+	handler := s.Handler()
+
+	body := `{"enabled": true, "message": "db migration", "retry_after_seconds": 60, "banner": "maintenance in progress"}`
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("POST", "/admin/maintenance", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if !status.Enabled || status.Message != "db migration" || status.RetryAfterSeconds != 60 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if status.Banner != "maintenance in progress" {
+		t.Errorf("Expected banner to be set, got %q", status.Banner)
+	}
+
+	// GET returns the current status
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/admin/maintenance", nil))
+	if !strings.Contains(rec.Body.String(), `"enabled":true`) {
+		t.Errorf("Expected enabled status, got %s", rec.Body.String())
+	}
+
+	// Invalid body
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("POST", "/admin/maintenance", strings.NewReader("{")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid body, got %d", rec.Code)
+	}
+
+	// Unsupported method
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("DELETE", "/admin/maintenance", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,284 @@
+package mediaio
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"golang.org/x/time/rate"
+)
+
+// Media types accounted separately
+const (
+	MediaAudio = "audio"
+	MediaImage = "image"
+	MediaVideo = "video"
+)
+
+// AnonymousTenant groups requests made without an API key
+const AnonymousTenant = "anonymous"
+
+// Directions used in stats and metrics
+const (
+	DirectionIn  = "in"  // request bodies (uploads)
+	DirectionOut = "out" // response bodies (generated artifacts)
+)
+
+// Config configures per-tenant throughput caps. Rates are bytes per
+// second across both directions; zero means unlimited.
+type Config struct {
+	DefaultBytesPerSec float64
+	TenantBytesPerSec  map[string]float64 // per-tenant overrides
+	BurstBytes         int                // bucket size, defaults to one second of rate
+}
+
+// Usage is the accumulated I/O of one tenant for one media type
+type Usage struct {
+	Tenant           string  `json:"tenant"`
+	MediaType        string  `json:"media_type"`
+	Requests         int64   `json:"requests"`
+	BytesIn          int64   `json:"bytes_in"`
+	BytesOut         int64   `json:"bytes_out"`
+	ThrottledSecs    float64 `json:"throttled_seconds"`
+	LimitBytesPerSec float64 `json:"limit_bytes_per_second,omitempty"`
+}
+
+type usageKey struct {
+	tenant    string
+	mediaType string
+}
+
+// Meter accounts media I/O per tenant and enforces throughput caps
+type Meter struct {
+	cfg Config
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	usage    map[usageKey]*Usage
+}
+
+// NewMeter creates a meter
+func NewMeter(cfg Config) *Meter {
+	return &Meter{
+		cfg:      cfg,
+		limiters: make(map[string]*rate.Limiter),
+		usage:    make(map[usageKey]*Usage),
+	}
+}
+
+// limitFor returns the configured rate for tenant (0 = unlimited)
+func (m *Meter) limitFor(tenant string) float64 {
+	if limit, ok := m.cfg.TenantBytesPerSec[tenant]; ok {
+		return limit
+	}
+	return m.cfg.DefaultBytesPerSec
+}
+
+// limiter returns the tenant's shared limiter, or nil when uncapped
+func (m *Meter) limiter(tenant string) *rate.Limiter {
+	limit := m.limitFor(tenant)
+	if limit <= 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if l, ok := m.limiters[tenant]; ok {
+		return l
+	}
+	burst := m.cfg.BurstBytes
+	if burst <= 0 {
+		burst = int(limit)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l := rate.NewLimiter(rate.Limit(limit), burst)
+	m.limiters[tenant] = l
+	return l
+}
+
+// entry returns the usage record for tenant and media type, creating it
+func (m *Meter) entry(tenant, mediaType string) *Usage {
+	key := usageKey{tenant: tenant, mediaType: mediaType}
+	u, ok := m.usage[key]
+	if !ok {
+		u = &Usage{Tenant: tenant, MediaType: mediaType}
+		m.usage[key] = u
+	}
+	return u
+}
+
+// record adds transferred bytes and throttle time to the tenant's usage
+func (m *Meter) record(tenant, mediaType, direction string, n int, throttled time.Duration) {
+	if n <= 0 && throttled <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	u := m.entry(tenant, mediaType)
+	if direction == DirectionIn {
+		u.BytesIn += int64(n)
+	} else {
+		u.BytesOut += int64(n)
+	}
+	u.ThrottledSecs += throttled.Seconds()
+	m.mu.Unlock()
+
+	if n > 0 {
+		metrics.RecordMediaBytes(mediaType, direction, n)
+	}
+	if throttled > 0 {
+		metrics.RecordMediaThrottle(mediaType, throttled.Seconds())
+	}
+}
+
+// Usage returns the accumulated usage ordered by tenant and media type
+func (m *Meter) Usage() []Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Usage, 0, len(m.usage))
+	for _, u := range m.usage {
+		copied := *u
+		copied.LimitBytesPerSec = m.limitFor(u.Tenant)
+		list = append(list, copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Tenant != list[j].Tenant {
+			return list[i].Tenant < list[j].Tenant
+		}
+		return list[i].MediaType < list[j].MediaType
+	})
+	return list
+}
+
+// Wrap meters an artifact-heavy handler. Request and response bodies are
+// counted against the caller's tenant and paced to its throughput cap.
+// It must run after authentication so the tenant is known.
+func (m *Meter) Wrap(mediaType string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant := auth.TenantFromContext(r.Context())
+		if tenant == "" {
+			tenant = AnonymousTenant
+		}
+
+		m.mu.Lock()
+		m.entry(tenant, mediaType).Requests++
+		m.mu.Unlock()
+		metrics.RecordMediaRequest(mediaType)
+
+		t := &throttle{
+			meter:     m,
+			ctx:       r.Context(),
+			tenant:    tenant,
+			mediaType: mediaType,
+			limiter:   m.limiter(tenant),
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &meteredBody{ReadCloser: r.Body, throttle: t}
+		}
+		next(&meteredWriter{ResponseWriter: w, throttle: t}, r)
+	}
+}
+
+// Handler serves per-tenant media I/O usage as JSON
+func (m *Meter) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"default_limit_bytes_per_second": m.cfg.DefaultBytesPerSec,
+			"usage":                          m.Usage(),
+		})
+	}
+}
+
+// throttle paces one request's transfers against its tenant's limiter
+type throttle struct {
+	meter     *Meter
+	ctx       context.Context
+	tenant    string
+	mediaType string
+	limiter   *rate.Limiter
+}
+
+// wait blocks until n bytes may be transferred, returning the time spent
+func (t *throttle) wait(n int) (time.Duration, error) {
+	if t.limiter == nil || n <= 0 {
+		return 0, nil
+	}
+
+	start := time.Now()
+	burst := t.limiter.Burst()
+	for n > 0 {
+		chunk := n
+		if chunk > burst {
+			chunk = burst
+		}
+		if err := t.limiter.WaitN(t.ctx, chunk); err != nil {
+			return time.Since(start), err
+		}
+		n -= chunk
+	}
+	return time.Since(start), nil
+}
+
+// meteredBody counts and paces uploads
+type meteredBody struct {
+	io.ReadCloser
+	throttle *throttle
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		waited, waitErr := b.throttle.wait(n)
+		b.throttle.meter.record(b.throttle.tenant, b.throttle.mediaType, DirectionIn, n, waited)
+		if waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// meteredWriter counts and paces downloads
+type meteredWriter struct {
+	http.ResponseWriter
+	throttle *throttle
+}
+
+func (w *meteredWriter) Write(b []byte) (int, error) {
+	waited, err := w.throttle.wait(len(b))
+	if err != nil {
+		w.throttle.meter.record(w.throttle.tenant, w.throttle.mediaType, DirectionOut, 0, waited)
+		return 0, err
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.throttle.meter.record(w.throttle.tenant, w.throttle.mediaType, DirectionOut, n, waited)
+	return n, err
+}
+
+// Flush keeps streaming responses working through the meter
+func (w *meteredWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer (used by http.ResponseController)
+func (w *meteredWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
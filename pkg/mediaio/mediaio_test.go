@@ -0,0 +1,115 @@
+package mediaio
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+)
+
+// echoHandler reads the upload and writes it back
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+	w.Write(data)
+}
+
+func TestMeter_AccountsPerTenantAndMediaType(t *testing.T) {
+	m := NewMeter(Config{})
+	handler := m.Wrap(MediaImage, echoHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/edits", bytes.NewReader(make([]byte, 1000)))
+	req = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: "key-1", Tenant: "team-a"}))
+	handler(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/images/edits", bytes.NewReader(make([]byte, 10)))
+	handler(httptest.NewRecorder(), req)
+
+	usage := m.Usage()
+	if len(usage) != 2 {
+		t.Fatalf("Expected 2 usage entries, got %d: %+v", len(usage), usage)
+	}
+
+	anon, teamA := usage[0], usage[1]
+	if anon.Tenant != AnonymousTenant || anon.BytesIn != 10 || anon.BytesOut != 10 {
+		t.Errorf("Unexpected anonymous usage: %+v", anon)
+	}
+	if teamA.Tenant != "team-a" || teamA.MediaType != MediaImage || teamA.Requests != 1 {
+		t.Errorf("Unexpected team-a usage: %+v", teamA)
+	}
+	if teamA.BytesIn != 1000 || teamA.BytesOut != 1000 {
+		t.Errorf("Expected 1000 bytes each way, got in=%d out=%d", teamA.BytesIn, teamA.BytesOut)
+	}
+}
+
+func TestMeter_ThrottlesCappedTenant(t *testing.T) {
+	m := NewMeter(Config{
+		TenantBytesPerSec: map[string]float64{"heavy": 10000},
+		BurstBytes:        1000,
+	})
+	handler := m.Wrap(MediaVideo, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 3000))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/videos/1", nil)
+	req = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: "heavy"}))
+
+	start := time.Now()
+	handler(httptest.NewRecorder(), req)
+	elapsed := time.Since(start)
+
+	// 1000-byte burst, then 2000 bytes at 10KB/s
+	if elapsed < 150*time.Millisecond {
+		t.Errorf("Expected capped transfer to take ~200ms, took %v", elapsed)
+	}
+	usage := m.Usage()
+	if len(usage) != 1 || usage[0].ThrottledSecs <= 0 {
+		t.Errorf("Expected throttle time to be recorded, got %+v", usage)
+	}
+	if usage[0].LimitBytesPerSec != 10000 {
+		t.Errorf("Expected limit in usage, got %v", usage[0].LimitBytesPerSec)
+	}
+}
+
+func TestMeter_UncappedTenantNotThrottled(t *testing.T) {
+	m := NewMeter(Config{TenantBytesPerSec: map[string]float64{"heavy": 100}})
+	handler := m.Wrap(MediaAudio, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 100000))
+	})
+
+	start := time.Now()
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/audio/speech", nil))
+	if time.Since(start) > 50*time.Millisecond {
+		t.Error("Tenant without a cap must not be throttled")
+	}
+}
+
+func TestMeter_Handler(t *testing.T) {
+	m := NewMeter(Config{DefaultBytesPerSec: 5e6})
+	m.Wrap(MediaAudio, echoHandler)(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", bytes.NewReader([]byte("abc"))))
+
+	w := httptest.NewRecorder()
+	m.Handler()(w, httptest.NewRequest(http.MethodGet, "/admin/io", nil))
+
+	var body struct {
+		DefaultLimit float64 `json:"default_limit_bytes_per_second"`
+		Usage        []Usage `json:"usage"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if body.DefaultLimit != 5e6 || len(body.Usage) != 1 || body.Usage[0].BytesIn != 3 {
+		t.Errorf("Unexpected handler response: %+v", body)
+	}
+
+	w = httptest.NewRecorder()
+	m.Handler()(w, httptest.NewRequest(http.MethodPost, "/admin/io", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
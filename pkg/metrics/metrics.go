@@ -59,6 +59,168 @@ var (
 		[]string{"backend_id", "priority"},
 	)
 
+	QueueWaitSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ollama_proxy_queue_wait_seconds",
+			Help:    "Time requests waited for a backend slot",
+			Buckets: []float64{.005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"backend_id", "priority"},
+	)
+
+	QueueRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_queue_rejected_total",
+			Help: "Requests rejected because a backend queue was full",
+		},
+		[]string{"backend_id", "priority"},
+	)
+
+	// Cold starts
+	ColdStartsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_cold_starts_total",
+			Help: "Streaming requests announced as waiting for a model load or container start",
+		},
+		[]string{"backend_id", "reason"},
+	)
+
+	// Rate limiting
+	RateLimitedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_rate_limited_total",
+			Help: "Requests rejected by rate limiting, by budget (ip, key or key_stream)",
+		},
+		[]string{"budget"},
+	)
+
+	RequestsTooLargeTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_requests_too_large_total",
+			Help: "Requests rejected with 413 by body class and the limit exceeded (body or prompt)",
+		},
+		[]string{"class", "limit"},
+	)
+
+	// Client request labels (X-Labels)
+	LabeledRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_labeled_requests_total",
+			Help: "Completed requests by client label, backend and status",
+		},
+		[]string{"label", "value", "backend_id", "status"},
+	)
+
+	LabeledTokensTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_labeled_tokens_total",
+			Help: "Prompt plus completion tokens by client label and backend",
+		},
+		[]string{"label", "value", "backend_id"},
+	)
+
+	// Federation between proxies
+	FederatedRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_federated_requests_total",
+			Help: "Signed requests received from peer proxies, by peer and result (verified or rejected)",
+		},
+		[]string{"peer", "result"},
+	)
+
+	// Circuit breakers
+	BackendCircuitState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ollama_proxy_backend_circuit_state",
+			Help: "Backend circuit breaker state (0=closed, 1=open, 2=half-open)",
+		},
+		[]string{"backend_id"},
+	)
+
+	BackendCircuitTransitionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_backend_circuit_transitions_total",
+			Help: "Backend circuit breaker state changes, by the state entered",
+		},
+		[]string{"backend_id", "state"},
+	)
+
+	BackendCircuitRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_backend_circuit_rejected_total",
+			Help: "Requests rejected by an open backend circuit breaker",
+		},
+		[]string{"backend_id"},
+	)
+
+	// Hedged requests
+	HedgedRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_hedged_requests_total",
+			Help: "Latency-critical requests duplicated to a second backend, by the backend that answered first",
+		},
+		[]string{"primary", "hedge", "winner"},
+	)
+
+	// Ensemble requests
+	EnsembleRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_ensemble_requests_total",
+			Help: "Requests answered by an ensemble of backends, by how the answer was chosen",
+		},
+		[]string{"method"},
+	)
+
+	// Speculative decoding
+	SpeculativeTokensTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_speculative_tokens_total",
+			Help: "Draft tokens checked by a verifying backend, by whether they were accepted",
+		},
+		[]string{"draft", "verifier", "outcome"},
+	)
+
+	// Load balancing
+	BackendInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ollama_proxy_backend_inflight_requests",
+			Help: "Requests routed to a backend that have not finished",
+		},
+		[]string{"backend_id"},
+	)
+
+	BackendLatencyEWMA = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ollama_proxy_backend_latency_ewma_ms",
+			Help: "Exponentially weighted moving average of observed backend latency",
+		},
+		[]string{"backend_id"},
+	)
+
+	BenchmarkTTFT = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ollama_proxy_benchmark_ttft_ms",
+			Help: "Time to first token of the standard benchmark prompt",
+		},
+		[]string{"backend_id", "model"},
+	)
+
+	BenchmarkTokensPerSecond = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ollama_proxy_benchmark_tokens_per_second",
+			Help: "Generation rate of the standard benchmark prompt",
+		},
+		[]string{"backend_id", "model"},
+	)
+
+	LoadBalancerPicksTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_load_balancer_picks_total",
+			Help: "Backends chosen by load-balancing strategy",
+		},
+		[]string{"strategy", "backend_id"},
+	)
+
 	// Thermal metrics
 	BackendTemperature = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -85,14 +247,39 @@ var (
 		[]string{"backend_id", "hardware"},
 	)
 
+	// Accelerator utilization, sampled per device
+	DeviceUtilization = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ollama_proxy_device_utilization_percent",
+			Help: "Busy share of an NPU or GPU over the last sampling interval",
+		},
+		[]string{"device", "name", "hardware"},
+	)
+
+	DeviceMemoryUsed = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ollama_proxy_device_memory_used_bytes",
+			Help: "Memory in use on an NPU or GPU, where the device reports it",
+		},
+		[]string{"device", "name", "hardware"},
+	)
+
 	EnergyConsumed = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "ollama_proxy_energy_consumed_wh",
-			Help: "Total energy consumed in watt-hours",
+			Help: "Energy measured by RAPL or NVML and attributed to requests, in watt-hours",
 		},
 		[]string{"backend_id", "hardware"},
 	)
 
+	DevicePower = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ollama_proxy_device_power_watts",
+			Help: "Measured power draw of a hardware class over the last sampling interval",
+		},
+		[]string{"hardware"},
+	)
+
 	// Routing metrics
 	RoutingDecisionsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -163,8 +350,146 @@ var (
 		},
 		[]string{"cache_type"},
 	)
+
+	// Media I/O for artifact-heavy endpoints (audio, image, video)
+	MediaRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_media_requests_total",
+			Help: "Requests to artifact-heavy endpoints by media type",
+		},
+		[]string{"media_type"},
+	)
+
+	MediaBytesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_media_bytes_total",
+			Help: "Bytes transferred by artifact-heavy endpoints by media type and direction (in, out)",
+		},
+		[]string{"media_type", "direction"},
+	)
+
+	MediaThrottleSeconds = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_media_throttle_seconds_total",
+			Help: "Time media transfers were held back by tenant throughput caps",
+		},
+		[]string{"media_type"},
+	)
+
+	// Conversation sessions pinned to a backend
+	SessionsActive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ollama_proxy_sessions_active",
+			Help: "Conversation sessions currently pinned to a backend",
+		},
+	)
+
+	SessionLookupsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_session_lookups_total",
+			Help: "Session affinity lookups by result (hit, miss, override, repinned)",
+		},
+		[]string{"result"},
+	)
+
+	SessionEvictionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_session_evictions_total",
+			Help: "Sessions dropped by reason (expired, capacity, removed)",
+		},
+		[]string{"reason"},
+	)
+
+	SessionBoostsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_session_priority_boosts_total",
+			Help: "Turns of interactive sessions raised a priority level",
+		},
+	)
+
+	// Requests sent off the machine to cloud backends
+	CloudTokensTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_cloud_tokens_total",
+			Help: "Tokens billed by cloud backends by direction (input, output)",
+		},
+		[]string{"backend", "model", "direction"},
+	)
+
+	CloudCostUSDTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_cloud_cost_usd_total",
+			Help: "Billed cost of cloud backend requests in USD",
+		},
+		[]string{"backend", "model"},
+	)
+
+	// Requests cancelled by the kill switch
+	GenerationsStoppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_generations_stopped_total",
+			Help: "In-flight requests cancelled by the kill switch",
+		},
+		[]string{"backend_id"},
+	)
+
+	// Recovered panics
+	PanicsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ollama_proxy_panics_total",
+			Help: "Total recovered panics by scope (http, grpc, pipeline, background)",
+		},
+		[]string{"scope"},
+	)
 )
 
+// RecordMediaRequest counts a request to an artifact-heavy endpoint
+func RecordMediaRequest(mediaType string) {
+	MediaRequestsTotal.WithLabelValues(mediaType).Inc()
+}
+
+// RecordMediaBytes records bytes transferred for a media type
+func RecordMediaBytes(mediaType, direction string, n int) {
+	MediaBytesTotal.WithLabelValues(mediaType, direction).Add(float64(n))
+}
+
+// RecordMediaThrottle records time a media transfer waited on a throughput cap
+func RecordMediaThrottle(mediaType string, seconds float64) {
+	MediaThrottleSeconds.WithLabelValues(mediaType).Add(seconds)
+}
+
+// SetSessionsActive sets the number of pinned sessions
+func SetSessionsActive(n int) {
+	SessionsActive.Set(float64(n))
+}
+
+// RecordSessionLookup records the result of a session affinity lookup
+func RecordSessionLookup(result string) {
+	SessionLookupsTotal.WithLabelValues(result).Inc()
+}
+
+// RecordSessionEviction records a session dropped from the session table
+func RecordSessionEviction(reason string) {
+	SessionEvictionsTotal.WithLabelValues(reason).Inc()
+}
+
+// RecordCloudRequest records the tokens and cost of a cloud backend request
+func RecordCloudRequest(backend, model string, inputTokens, outputTokens int, costUSD float64) {
+	CloudTokensTotal.WithLabelValues(backend, model, "input").Add(float64(inputTokens))
+	CloudTokensTotal.WithLabelValues(backend, model, "output").Add(float64(outputTokens))
+	CloudCostUSDTotal.WithLabelValues(backend, model).Add(costUSD)
+}
+
+// RecordGenerationStopped records a request cancelled by the kill switch
+func RecordGenerationStopped(backendID string) {
+	GenerationsStoppedTotal.WithLabelValues(backendID).Inc()
+}
+
+// RecordSessionBoost records a session turn raised a priority level
+func RecordSessionBoost() {
+	SessionBoostsTotal.Inc()
+}
+
 // RecordRequest records a completed request
 func RecordRequest(backendID, model, status string, durationSec float64) {
 	RequestsTotal.WithLabelValues(backendID, model, status).Inc()
@@ -195,6 +520,93 @@ func SetBackendQueueDepth(backendID, priority string, depth int) {
 	BackendQueueDepth.WithLabelValues(backendID, priority).Set(float64(depth))
 }
 
+// RecordQueueWait records how long a request waited for a backend slot
+func RecordQueueWait(backendID, priority string, seconds float64) {
+	QueueWaitSeconds.WithLabelValues(backendID, priority).Observe(seconds)
+}
+
+// RecordQueueRejected records a request rejected by a full backend queue
+func RecordQueueRejected(backendID, priority string) {
+	QueueRejectedTotal.WithLabelValues(backendID, priority).Inc()
+}
+
+// RecordCircuitState records a backend circuit breaker entering a state
+// (0=closed, 1=open, 2=half-open)
+func RecordCircuitState(backendID string, state int, name string) {
+	BackendCircuitState.WithLabelValues(backendID).Set(float64(state))
+	BackendCircuitTransitionsTotal.WithLabelValues(backendID, name).Inc()
+}
+
+// RecordCircuitRejected records a request rejected by an open circuit
+func RecordCircuitRejected(backendID string) {
+	BackendCircuitRejectedTotal.WithLabelValues(backendID).Inc()
+}
+
+// RecordHedgedRequest records a hedged request and which backend won.
+// winner is "primary", "hedge" or "none" when both failed.
+func RecordHedgedRequest(primary, hedge, winner string) {
+	HedgedRequestsTotal.WithLabelValues(primary, hedge, winner).Inc()
+}
+
+// RecordEnsembleRequest records an ensemble request. method is "vote",
+// "judge", "single" or "none" when every member failed.
+func RecordEnsembleRequest(method string) {
+	EnsembleRequestsTotal.WithLabelValues(method).Inc()
+}
+
+// RecordSpeculativeTokens records a round of draft verification
+func RecordSpeculativeTokens(draft, verifier string, accepted, rejected int) {
+	SpeculativeTokensTotal.WithLabelValues(draft, verifier, "accepted").Add(float64(accepted))
+	SpeculativeTokensTotal.WithLabelValues(draft, verifier, "rejected").Add(float64(rejected))
+}
+
+// RecordColdStart records a cold start announced to a client
+func RecordColdStart(backendID, reason string) {
+	ColdStartsTotal.WithLabelValues(backendID, reason).Inc()
+}
+
+// RecordRateLimited records a request rejected by a rate limit budget
+func RecordRateLimited(budget string) {
+	RateLimitedTotal.WithLabelValues(budget).Inc()
+}
+
+// RecordRequestTooLarge counts a request refused for its body or prompt size
+func RecordRequestTooLarge(class, limit string) {
+	RequestsTooLargeTotal.WithLabelValues(class, limit).Inc()
+}
+
+// RecordFederatedRequest records a signed request from a peer proxy
+func RecordFederatedRequest(peer, result string) {
+	FederatedRequestsTotal.WithLabelValues(peer, result).Inc()
+}
+
+// RecordLabeledRequest records a completed request under one of its labels
+func RecordLabeledRequest(label, value, backendID, status string, tokens int64) {
+	LabeledRequestsTotal.WithLabelValues(label, value, backendID, status).Inc()
+	LabeledTokensTotal.WithLabelValues(label, value, backendID).Add(float64(tokens))
+}
+
+// SetBackendInFlight sets the number of unfinished requests on a backend
+func SetBackendInFlight(backendID string, n int) {
+	BackendInFlight.WithLabelValues(backendID).Set(float64(n))
+}
+
+// SetBackendLatencyEWMA sets the smoothed observed latency of a backend
+func SetBackendLatencyEWMA(backendID string, ms float64) {
+	BackendLatencyEWMA.WithLabelValues(backendID).Set(ms)
+}
+
+// SetBenchmarkResult sets the latest benchmark of a model on a backend
+func SetBenchmarkResult(backendID, model string, ttftMs, tokensPerSecond float64) {
+	BenchmarkTTFT.WithLabelValues(backendID, model).Set(ttftMs)
+	BenchmarkTokensPerSecond.WithLabelValues(backendID, model).Set(tokensPerSecond)
+}
+
+// RecordLoadBalancerPick records a backend chosen by a load-balancing strategy
+func RecordLoadBalancerPick(strategy, backendID string) {
+	LoadBalancerPicksTotal.WithLabelValues(strategy, backendID).Inc()
+}
+
 // SetBackendTemperature sets the temperature of a backend
 func SetBackendTemperature(backendID, hardware string, tempCelsius float64) {
 	BackendTemperature.WithLabelValues(backendID, hardware).Set(tempCelsius)
@@ -210,11 +622,26 @@ func SetBackendPower(backendID, hardware string, watts float64) {
 	BackendPower.WithLabelValues(backendID, hardware).Set(watts)
 }
 
+// SetDeviceUtilization sets the sampled utilization of an accelerator
+func SetDeviceUtilization(device, name, hardware string, percent float64) {
+	DeviceUtilization.WithLabelValues(device, name, hardware).Set(percent)
+}
+
+// SetDeviceMemoryUsed sets the memory in use on an accelerator
+func SetDeviceMemoryUsed(device, name, hardware string, bytes uint64) {
+	DeviceMemoryUsed.WithLabelValues(device, name, hardware).Set(float64(bytes))
+}
+
 // RecordEnergyConsumed records energy consumption
 func RecordEnergyConsumed(backendID, hardware string, wattHours float64) {
 	EnergyConsumed.WithLabelValues(backendID, hardware).Add(wattHours)
 }
 
+// SetDevicePower sets the measured power draw of a hardware class
+func SetDevicePower(hardware string, watts float64) {
+	DevicePower.WithLabelValues(hardware).Set(watts)
+}
+
 // RecordRoutingDecision records a routing decision
 func RecordRoutingDecision(reason, backendID string) {
 	RoutingDecisionsTotal.WithLabelValues(reason, backendID).Inc()
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Middleware wraps an http.Handler
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middleware so the first one listed runs first (outermost)
+func Chain(mws ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+const skippedKey contextKey = "skipped_middleware"
+
+// WithSkipped marks named middleware to be bypassed for a request, e.g.
+// authentication on a listener only reachable from this machine
+func WithSkipped(ctx context.Context, names []string) context.Context {
+	if len(names) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, skippedKey, names)
+}
+
+// Skippable bypasses mw for requests whose context skips name
+func Skippable(name string, mw Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipped, _ := r.Context().Value(skippedKey).([]string); slices.Contains(skipped, name) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Registry maps middleware names to implementations so chains can be
+// declared in config
+type Registry struct {
+	mu    sync.RWMutex
+	named map[string]Middleware
+}
+
+// NewRegistry creates an empty middleware registry
+func NewRegistry() *Registry {
+	return &Registry{
+		named: make(map[string]Middleware),
+	}
+}
+
+// Register adds (or replaces) a named middleware
+func (r *Registry) Register(name string, mw Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.named[name] = mw
+}
+
+// Names returns the registered middleware names in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.named))
+	for name := range r.named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build resolves a list of names into a single middleware. Each one is
+// bypassed for requests that skip its name.
+func (r *Registry) Build(names []string) (Middleware, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mws := make([]Middleware, 0, len(names))
+	for _, name := range names {
+		mw, ok := r.named[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown middleware: %s", name)
+		}
+		mws = append(mws, Skippable(name, mw))
+	}
+	return Chain(mws...), nil
+}
+
+// RouteChains selects a middleware chain per route. Routes are matched
+// exactly, or by prefix when the pattern ends in "*" (longest prefix wins).
+// Unmatched routes use Default.
+type RouteChains struct {
+	Default []string
+	Routes  map[string][]string
+}
+
+// Lookup returns the middleware names configured for a path
+func (rc RouteChains) Lookup(path string) []string {
+	if names, ok := rc.Routes[path]; ok {
+		return names
+	}
+
+	best := -1
+	var match []string
+	for pattern, names := range rc.Routes {
+		if !strings.HasSuffix(pattern, "*") {
+			continue
+		}
+		prefix := strings.TrimSuffix(pattern, "*")
+		if strings.HasPrefix(path, prefix) && len(prefix) > best {
+			best = len(prefix)
+			match = names
+		}
+	}
+	if best >= 0 {
+		return match
+	}
+
+	return rc.Default
+}
+
+// Wrap builds the chain for a route and applies it to the handler.
+// Panic recovery always runs outermost regardless of configuration.
+func (rc RouteChains) Wrap(reg *Registry, path string, handler http.Handler) (http.Handler, error) {
+	mw, err := reg.Build(rc.Lookup(path))
+	if err != nil {
+		return nil, fmt.Errorf("route %s: %w", path, err)
+	}
+	return HTTPRecovery(mw(handler)), nil
+}
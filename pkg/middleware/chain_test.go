@@ -0,0 +1,334 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tagMiddleware appends its name to a header so ordering can be checked
+func tagMiddleware(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+}
+
+func TestChain_Order(t *testing.T) {
+	handler := Chain(tagMiddleware("a"), tagMiddleware("b"), tagMiddleware("c"))(okHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	got := rec.Header().Values("X-Chain")
+	want := []string{"a", "b", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected order %v, got %v", want, got)
+	}
+}
+
+func TestRegistry_Build(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("a", tagMiddleware("a"))
+	reg.Register("b", tagMiddleware("b"))
+
+	mw, err := reg.Build([]string{"b", "a"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mw(okHandler()).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	got := rec.Header().Values("X-Chain")
+	want := []string{"b", "a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected order %v, got %v", want, got)
+	}
+
+	if names := reg.Names(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("Expected sorted names [a b], got %v", names)
+	}
+
+	// Requests can bypass middleware by name
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	mw(okHandler()).ServeHTTP(rec, req.WithContext(WithSkipped(req.Context(), []string{"b"})))
+	if got := rec.Header().Values("X-Chain"); !reflect.DeepEqual(got, []string{"a"}) || rec.Body.String() != "ok" {
+		t.Errorf("Expected only a to run, got %v", got)
+	}
+}
+
+func TestRegistry_BuildUnknown(t *testing.T) {
+	reg := NewRegistry()
+
+	_, err := reg.Build([]string{"missing"})
+	if err == nil {
+		t.Fatal("Expected error for unknown middleware")
+	}
+	if !strings.Contains(err.Error(), "unknown middleware: missing") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRouteChains_Lookup(t *testing.T) {
+	rc := RouteChains{
+		Default: []string{"auth", "rate_limit"},
+		Routes: map[string][]string{
+			"/v1/models":   {"rate_limit"},
+			"/v1/*":        {"auth"},
+			"/v1/images/*": {"auth", "rate_limit:strict"},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/v1/models", []string{"rate_limit"}},
+		{"/v1/chat/completions", []string{"auth"}},
+		{"/v1/images/generations", []string{"auth", "rate_limit:strict"}},
+		{"/health", []string{"auth", "rate_limit"}},
+	}
+
+	for _, tt := range tests {
+		if got := rc.Lookup(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lookup(%s): expected %v, got %v", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestRouteChains_WrapRecoversPanics(t *testing.T) {
+	reg := NewRegistry()
+	rc := RouteChains{}
+
+	handler, err := rc.Wrap(reg, "/panic", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 after panic, got %d", rec.Code)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	handler := CORS([]string{"https://app.example.com"})(okHandler())
+
+	// Allowed origin
+	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected allowed origin echoed, got %q", got)
+	}
+
+	// Disallowed origin
+	req = httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS header for disallowed origin, got %q", got)
+	}
+
+	// Preflight
+	req = httptest.NewRequest("OPTIONS", "/v1/chat/completions", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for preflight, got %d", rec.Code)
+	}
+}
+
+func TestCORSWithConfig(t *testing.T) {
+	handler := CORSWithConfig(CORSConfig{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"X-Backend"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})(okHandler())
+
+	// Subdomain origin on a streaming request
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set("Origin", "https://chat.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the request to reach the handler, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://chat.example.com" {
+		t.Errorf("Expected the origin echoed with credentials, got %q", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "true" || rec.Header().Get("Access-Control-Expose-Headers") != "X-Backend" {
+		t.Errorf("Expected credentials and exposed headers, got %v", rec.Header())
+	}
+
+	// Preflight never reaches the handler
+	req = httptest.NewRequest("OPTIONS", "/v1/chat/completions", nil)
+	req.Header.Set("Origin", "https://chat.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for preflight, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Authorization, Content-Type" {
+		t.Errorf("Expected the allowed headers, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Expected max age 600, got %q", got)
+	}
+
+	// The bare domain and other schemes don't match the wildcard
+	for _, origin := range []string{"https://example.com", "http://chat.example.com", "https://evilexample.com"} {
+		req = httptest.NewRequest("OPTIONS", "/v1/chat/completions", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no CORS header for %s, got %q", origin, got)
+		}
+	}
+
+	// Wildcards match ports too, any port unless one is given
+	handler = CORSWithConfig(CORSConfig{AllowedOrigins: []string{"https://*.example.com", "https://*.example.org:8443"}})(okHandler())
+	for origin, want := range map[string]bool{
+		"https://chat.example.com:8443": true,
+		"https://chat.example.org:8443": true,
+		"https://chat.example.org:9443": false,
+		"https://chat.example.org":      false,
+	} {
+		req = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		req.Header.Set("Origin", origin)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin") != ""; got != want {
+			t.Errorf("Expected allowed=%v for %s, got %v", want, origin, got)
+		}
+	}
+
+	// Any origin and any requested header without credentials
+	handler = CORSWithConfig(CORSConfig{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})(okHandler())
+	req = httptest.NewRequest("OPTIONS", "/v1/stream/ws", nil)
+	req.Header.Set("Origin", "https://anywhere.test")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "x-priority")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Headers") != "x-priority" {
+		t.Errorf("Expected any origin and the requested header, got %v", rec.Header())
+	}
+}
+
+func TestOriginMatcher(t *testing.T) {
+	m := NewOriginMatcher([]string{"https://App.example.com/", "https://*.example.org:8443"})
+	tests := map[string]bool{
+		"https://app.example.com":        true,
+		"https://APP.example.com":        true,
+		"http://app.example.com":         false,
+		"https://api.example.org:8443":   true,
+		"https://api.example.org":        false,
+		"https://example.org:8443":       false,
+		"https://evil.test/.example.org": false,
+		"not a url":                      false,
+	}
+	for origin, want := range tests {
+		if got := m.Allowed(origin); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", origin, got, want)
+		}
+	}
+	if m.AllowAll() {
+		t.Error("Expected AllowAll to be false without \"*\"")
+	}
+	if !NewOriginMatcher([]string{"*"}).Allowed("https://anywhere.test") {
+		t.Error("Expected \"*\" to allow any origin")
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	handler := BodyLimit(8)(okHandler())
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader("this body is too long"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader("short"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for small body, got %d", rec.Code)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	calls := 0
+	handler := ResponseCache(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/models", nil))
+		if rec.Body.String() != `{"ok":true}` {
+			t.Errorf("Unexpected body: %s", rec.Body.String())
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected handler to be called once, got %d", calls)
+	}
+
+	// Different API keys must not share cache entries
+	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer other")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if calls != 2 {
+		t.Errorf("Expected separate cache entry per Authorization header, got %d calls", calls)
+	}
+
+	// POST is never cached
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/models", nil))
+	if calls != 3 {
+		t.Errorf("Expected POST to bypass cache, got %d calls", calls)
+	}
+}
+
+func TestAccessLog_PassesThrough(t *testing.T) {
+	handler := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("Expected status to pass through, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures cross-origin access for browser clients
+type CORSConfig struct {
+	// AllowedOrigins are origins such as "https://app.example.com",
+	// "https://*.example.com" or "*" (any origin)
+	AllowedOrigins []string
+
+	// AllowedMethods a preflight may approve; empty allows GET, HEAD,
+	// POST, PUT, DELETE and OPTIONS
+	AllowedMethods []string
+
+	// AllowedHeaders are request headers scripts may send; "*" approves
+	// whatever a preflight asks for
+	AllowedHeaders []string
+
+	// ExposedHeaders are response headers scripts may read
+	ExposedHeaders []string
+
+	// AllowCredentials lets browsers send cookies and read responses to
+	// credentialed requests. The origin is then always echoed, as browsers
+	// refuse "*" with credentials.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight; 0 leaves it to them
+	MaxAge time.Duration
+}
+
+var defaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "DELETE", "OPTIONS"}
+
+// CORSWithConfig adds cross-origin headers for allowed origins and answers
+// preflight requests itself, before authentication or any handler sees
+// them. Preflights from other origins get no CORS headers, so browsers
+// refuse the request.
+func CORSWithConfig(cfg CORSConfig) Middleware {
+	origins := NewOriginMatcher(cfg.AllowedOrigins)
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(methods, ", ")
+	anyHeader := false
+	for _, h := range cfg.AllowedHeaders {
+		if h == "*" {
+			anyHeader = true
+		}
+	}
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := ""
+	if cfg.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			h := w.Header()
+
+			if origin != "" && origins.Allowed(origin) {
+				if origins.AllowAll() && !cfg.AllowCredentials {
+					h.Set("Access-Control-Allow-Origin", "*")
+				} else {
+					h.Set("Access-Control-Allow-Origin", origin)
+					h.Add("Vary", "Origin")
+				}
+				if cfg.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+
+				if preflight {
+					h.Add("Vary", "Access-Control-Request-Method")
+					h.Add("Vary", "Access-Control-Request-Headers")
+					h.Set("Access-Control-Allow-Methods", allowMethods)
+					if requested := r.Header.Get("Access-Control-Request-Headers"); anyHeader && requested != "" {
+						h.Set("Access-Control-Allow-Headers", requested)
+					} else if allowHeaders != "" {
+						h.Set("Access-Control-Allow-Headers", allowHeaders)
+					}
+					if maxAge != "" {
+						h.Set("Access-Control-Max-Age", maxAge)
+					}
+				} else if exposeHeaders != "" {
+					h.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+			}
+
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// OriginMatcher matches Origin headers against allowed origins such as
+// "https://app.example.com", "https://*.example.com" or "*" (any origin)
+type OriginMatcher struct {
+	allowAll  bool
+	exact     map[string]bool
+	wildcards []wildcardOrigin
+}
+
+// wildcardOrigin is "scheme://*.example.com[:port]". Without a port it
+// matches subdomains on any port.
+type wildcardOrigin struct {
+	scheme, suffix, port string // suffix is ".example.com"
+}
+
+// NewOriginMatcher builds a matcher from allowed origins
+func NewOriginMatcher(allowed []string) *OriginMatcher {
+	m := &OriginMatcher{exact: make(map[string]bool)}
+	for _, origin := range allowed {
+		origin = strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "*":
+			m.allowAll = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*")
+			w := wildcardOrigin{scheme: scheme, suffix: host}
+			if i := strings.LastIndex(host, ":"); i >= 0 {
+				w.suffix, w.port = host[:i], host[i+1:]
+			}
+			m.wildcards = append(m.wildcards, w)
+		case origin != "":
+			m.exact[origin] = true
+		}
+	}
+	return m
+}
+
+// AllowAll reports whether any origin is allowed
+func (m *OriginMatcher) AllowAll() bool {
+	return m.allowAll
+}
+
+// Allowed reports whether an Origin header value is allowed
+func (m *OriginMatcher) Allowed(origin string) bool {
+	if m.allowAll {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if m.exact[strings.ToLower(u.Scheme+"://"+u.Host)] {
+		return true
+	}
+	hostname := strings.ToLower(u.Hostname())
+	for _, w := range m.wildcards {
+		if strings.EqualFold(u.Scheme, w.scheme) && strings.HasSuffix(hostname, w.suffix) &&
+			(w.port == "" || u.Port() == w.port) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// Recovery scopes used as the "scope" label on the panic counter
+const (
+	ScopeHTTP       = "http"
+	ScopeGRPC       = "grpc"
+	ScopeHandler    = "handler"
+	ScopePipeline   = "pipeline"
+	ScopeBackground = "background"
+)
+
+// DefaultMaxCrashFiles caps the crash log directory when unset
+const DefaultMaxCrashFiles = 50
+
+// CrashReporter records recovered panics: a log line, a counter, and, when
+// a directory is configured, a crash file holding the full stack trace
+type CrashReporter struct {
+	mu       sync.Mutex
+	dir      string
+	maxFiles int
+
+	// onReport, when set, is called after each report (for tests)
+	onReport func(path string)
+}
+
+// NewCrashReporter creates a reporter writing crash files to dir. An empty
+// dir disables crash files; panics are still logged and counted.
+func NewCrashReporter(dir string, maxFiles int) (*CrashReporter, error) {
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxCrashFiles
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create crash log directory: %w", err)
+		}
+	}
+	return &CrashReporter{dir: dir, maxFiles: maxFiles}, nil
+}
+
+// DefaultCrashReporter is used by all recovery helpers
+var DefaultCrashReporter = &CrashReporter{maxFiles: DefaultMaxCrashFiles}
+
+// SetCrashReporter replaces the process-wide crash reporter
+func SetCrashReporter(c *CrashReporter) {
+	if c != nil {
+		DefaultCrashReporter = c
+	}
+}
+
+// Report logs a recovered panic, counts it and writes a crash file. It
+// returns the crash file path, or "" when none was written.
+func (c *CrashReporter) Report(scope, name string, rec interface{}, stack []byte, fields ...zap.Field) string {
+	metrics.PanicsTotal.WithLabelValues(scope).Inc()
+
+	path, err := c.writeCrashFile(scope, name, rec, stack)
+
+	if logging.Logger != nil {
+		logFields := append([]zap.Field{
+			zap.String("scope", scope),
+			zap.String("name", name),
+			zap.Any("panic", rec),
+			zap.String("stack", string(stack)),
+		}, fields...)
+		if path != "" {
+			logFields = append(logFields, zap.String("crash_file", path))
+		}
+		if err != nil {
+			logFields = append(logFields, zap.NamedError("crash_file_error", err))
+		}
+		logging.Logger.Error("Panic recovered", logFields...)
+	}
+	if c.onReport != nil {
+		c.onReport(path)
+	}
+	return path
+}
+
+func (c *CrashReporter) writeCrashFile(scope, name string, rec interface{}, stack []byte) (string, error) {
+	if c.dir == "" {
+		return "", nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	file := fmt.Sprintf("crash-%s-%s.log", now.UTC().Format("20060102T150405.000000000"), sanitizeScope(scope))
+	path := filepath.Join(c.dir, file)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "time: %s\n", now.Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "scope: %s\n", scope)
+	fmt.Fprintf(&b, "name: %s\n", name)
+	fmt.Fprintf(&b, "pid: %d\n", os.Getpid())
+	fmt.Fprintf(&b, "panic: %v\n\n", rec)
+	b.Write(stack)
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return "", err
+	}
+	c.prune()
+	return path, nil
+}
+
+// prune removes the oldest crash files beyond maxFiles. Names sort by time.
+func (c *CrashReporter) prune() {
+	files, err := filepath.Glob(filepath.Join(c.dir, "crash-*.log"))
+	if err != nil || len(files) <= c.maxFiles {
+		return
+	}
+	sort.Strings(files)
+	for _, f := range files[:len(files)-c.maxFiles] {
+		os.Remove(f)
+	}
+}
+
+func sanitizeScope(scope string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, scope)
+}
+
+// RecoverScope recovers a panic in the calling goroutine and reports it.
+// It must be deferred directly: defer middleware.RecoverScope(scope, name)
+func RecoverScope(scope, name string) {
+	if rec := recover(); rec != nil {
+		DefaultCrashReporter.Report(scope, name, rec, debug.Stack())
+	}
+}
+
+// Safe runs fn, recovering and reporting a panic. It returns true if fn
+// panicked. Background loops call it per iteration so one bad tick doesn't
+// take down the loop.
+func Safe(scope, name string, fn func()) (panicked bool) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			DefaultCrashReporter.Report(scope, name, rec, debug.Stack())
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// Go runs fn in a new goroutine with panic recovery
+func Go(name string, fn func()) {
+	go Safe(ScopeBackground, name, fn)
+}
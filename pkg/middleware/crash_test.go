@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// useCrashReporter installs a reporter writing to a temp dir for one test
+func useCrashReporter(t *testing.T, maxFiles int) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "crashes")
+	c, err := NewCrashReporter(dir, maxFiles)
+	if err != nil {
+		t.Fatalf("NewCrashReporter failed: %v", err)
+	}
+	prev := DefaultCrashReporter
+	SetCrashReporter(c)
+	t.Cleanup(func() { SetCrashReporter(prev) })
+	return dir
+}
+
+func crashFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "crash-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestCrashReporter_WritesCrashFile(t *testing.T) {
+	dir := useCrashReporter(t, 0)
+	before := testutil.ToFloat64(metrics.PanicsTotal.WithLabelValues(ScopeBackground))
+
+	if !Safe(ScopeBackground, "test-loop", func() { panic("boom") }) {
+		t.Fatal("Expected Safe to report a panic")
+	}
+
+	files := crashFiles(t, dir)
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 crash file, got %d", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"scope: background", "name: test-loop", "panic: boom", "goroutine"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Crash file missing %q:\n%s", want, data)
+		}
+	}
+
+	if after := testutil.ToFloat64(metrics.PanicsTotal.WithLabelValues(ScopeBackground)); after != before+1 {
+		t.Errorf("Expected panic counter to increase by 1, got %v -> %v", before, after)
+	}
+}
+
+func TestCrashReporter_Prunes(t *testing.T) {
+	dir := useCrashReporter(t, 2)
+
+	for i := 0; i < 4; i++ {
+		Safe(ScopeBackground, "loop", func() { panic(i) })
+	}
+
+	if files := crashFiles(t, dir); len(files) != 2 {
+		t.Errorf("Expected 2 crash files after pruning, got %d", len(files))
+	}
+}
+
+func TestCrashReporter_NoDir(t *testing.T) {
+	c, err := NewCrashReporter("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := c.Report(ScopeHandler, "x", "boom", nil); path != "" {
+		t.Errorf("Expected no crash file without a dir, got %s", path)
+	}
+}
+
+func TestSafe_NoPanic(t *testing.T) {
+	called := false
+	if Safe(ScopeBackground, "ok", func() { called = true }) {
+		t.Error("Expected Safe to return false without a panic")
+	}
+	if !called {
+		t.Error("Expected fn to be called")
+	}
+}
+
+func TestGo_RecoversPanic(t *testing.T) {
+	dir := useCrashReporter(t, 0)
+
+	// Wait for the report itself, which runs after fn returns, so the
+	// reporter isn't restored while the goroutine still uses it
+	reported := make(chan string, 1)
+	DefaultCrashReporter.onReport = func(path string) { reported <- path }
+
+	Go("worker", func() {
+		panic("worker exploded")
+	})
+
+	select {
+	case path := <-reported:
+		if path == "" {
+			t.Error("Expected the report to write a crash file")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panic was not reported")
+	}
+	if len(crashFiles(t, dir)) != 1 {
+		t.Error("Expected a crash file from the background goroutine")
+	}
+}
+
+func TestRecoverScope(t *testing.T) {
+	dir := useCrashReporter(t, 0)
+
+	func() {
+		defer RecoverScope(ScopeBackground, "deferred")
+		panic("deferred boom")
+	}()
+
+	if len(crashFiles(t, dir)) != 1 {
+		t.Error("Expected RecoverScope to write a crash file")
+	}
+}
+
+func TestHTTPRecovery_WritesCrashFile(t *testing.T) {
+	dir := useCrashReporter(t, 0)
+
+	handler := HTTPRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler exploded")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", rec.Code)
+	}
+	files := crashFiles(t, dir)
+	if len(files) != 1 || !strings.HasSuffix(files[0], "-http.log") {
+		t.Errorf("Expected one http crash file, got %v", files)
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryRecoveryInterceptor converts panics in unary gRPC handlers into
+// codes.Internal errors
+func UnaryRecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				DefaultCrashReporter.Report(ScopeGRPC, info.FullMethod, rec, debug.Stack(),
+					zap.String("request_id", GetRequestID(ctx)),
+				)
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoveryInterceptor converts panics in streaming gRPC handlers into
+// codes.Internal errors
+func StreamRecoveryInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				DefaultCrashReporter.Report(ScopeGRPC, info.FullMethod, rec, debug.Stack(),
+					zap.String("request_id", GetRequestID(ss.Context())),
+				)
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
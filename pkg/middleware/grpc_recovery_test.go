@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+}
+
+func (fakeServerStream) Context() context.Context { return context.Background() }
+
+func TestUnaryRecoveryInterceptor(t *testing.T) {
+	useCrashReporter(t, 0)
+	interceptor := UnaryRecoveryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/compute.ComputeService/Generate"}
+
+	_, err := interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("unary exploded")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected codes.Internal, got %v", err)
+	}
+
+	resp, err := interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "resp", nil
+	})
+	if err != nil || resp != "resp" {
+		t.Errorf("Expected passthrough, got %v, %v", resp, err)
+	}
+}
+
+func TestStreamRecoveryInterceptor(t *testing.T) {
+	useCrashReporter(t, 0)
+	interceptor := StreamRecoveryInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/compute.ComputeService/GenerateStream"}
+
+	err := interceptor(nil, fakeServerStream{}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		panic("stream exploded")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected codes.Internal, got %v", err)
+	}
+}
+
+func TestUnaryRequestIDInterceptor(t *testing.T) {
+	interceptor := UnaryRequestIDInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/compute.ComputeService/Generate"}
+	var seen string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		seen = GetRequestID(ctx)
+		return nil, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadata, "trace-789"))
+	interceptor(ctx, "req", info, handler)
+	if seen != "trace-789" {
+		t.Errorf("Expected the caller's ID, got %q", seen)
+	}
+
+	interceptor(context.Background(), "req", info, handler)
+	if len(seen) != 36 {
+		t.Errorf("Expected a generated ID, got %q", seen)
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// incomingRequestID returns the ID a caller sent in x-request-id metadata,
+// or a new one, and sends it back in the response header
+func incomingRequestID(ctx context.Context) context.Context {
+	var supplied string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadata); len(values) > 0 {
+			supplied = values[0]
+		}
+	}
+	id := requestID(supplied)
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadata, id))
+	return ContextWithRequestID(ctx, id)
+}
+
+// UnaryRequestIDInterceptor gives every unary RPC a request ID, taken from
+// x-request-id metadata when the caller sent a usable one
+func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(incomingRequestID(ctx), req)
+	}
+}
+
+// StreamRequestIDInterceptor gives every streaming RPC a request ID, taken
+// from x-request-id metadata when the caller sent a usable one
+func StreamRequestIDInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &requestIDStream{ServerStream: ss, ctx: incomingRequestID(ss.Context())})
+	}
+}
+
+// requestIDStream is a server stream whose context carries the request ID
+type requestIDStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestIDStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/labels"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.status = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytes += n
+	return n, err
+}
+
+// Flush keeps streaming responses working through the recorder
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer (used by http.ResponseController)
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// AccessLog logs one line per request with status and duration
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		if logging.Logger != nil {
+			fields := []zap.Field{
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", rec.status),
+				zap.Int("bytes", rec.bytes),
+				zap.Duration("duration", time.Since(start)),
+				zap.String("request_id", GetRequestID(r.Context())),
+			}
+			if l := labels.FromContext(r.Context()); len(l) > 0 {
+				fields = append(fields, zap.String("labels", l.String()))
+			}
+			logging.Logger.Info("HTTP request", fields...)
+		}
+	})
+}
+
+// CORS adds cross-origin headers for the allowed origins ("*" allows any)
+// and answers preflight requests directly
+func CORS(allowedOrigins []string) Middleware {
+	return CORSWithConfig(CORSConfig{
+		AllowedOrigins: allowedOrigins,
+		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID"},
+	})
+}
+
+// BodyLimit rejects request bodies larger than maxBytes
+func BodyLimit(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// maxCacheEntries triggers a sweep of expired entries
+const maxCacheEntries = 1024
+
+// cachedResponse is a stored GET response
+type cachedResponse struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// cacheRecorder buffers a response so it can be stored
+type cacheRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (cr *cacheRecorder) WriteHeader(code int) {
+	cr.status = code
+	cr.ResponseWriter.WriteHeader(code)
+}
+
+func (cr *cacheRecorder) Write(b []byte) (int, error) {
+	if cr.status == 0 {
+		cr.status = http.StatusOK
+	}
+	cr.body.Write(b)
+	return cr.ResponseWriter.Write(b)
+}
+
+// ResponseCache caches successful GET responses for ttl. Entries are keyed
+// by URL and Authorization header so different API keys never share a
+// cached body.
+func ResponseCache(ttl time.Duration) Middleware {
+	var mu sync.Mutex
+	entries := make(map[string]*cachedResponse)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.URL.String() + "|" + r.Header.Get("Authorization")
+			now := time.Now()
+
+			mu.Lock()
+			entry, ok := entries[key]
+			if ok && now.After(entry.expires) {
+				delete(entries, key)
+				ok = false
+			}
+			mu.Unlock()
+
+			if ok {
+				for k, v := range entry.header {
+					w.Header()[k] = v
+				}
+				w.Header().Set("X-Cache", "HIT")
+				w.WriteHeader(entry.status)
+				w.Write(entry.body)
+				return
+			}
+
+			rec := &cacheRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			if rec.status == http.StatusOK {
+				mu.Lock()
+				if len(entries) >= maxCacheEntries {
+					for k, e := range entries {
+						if now.After(e.expires) {
+							delete(entries, k)
+						}
+					}
+				}
+				entries[key] = &cachedResponse{
+					status:  rec.status,
+					header:  w.Header().Clone(),
+					body:    rec.body.Bytes(),
+					expires: now.Add(ttl),
+				}
+				mu.Unlock()
+			}
+		})
+	}
+}
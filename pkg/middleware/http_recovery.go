@@ -4,37 +4,12 @@ import (
 	"net/http"
 	"runtime/debug"
 
-	"github.com/daoneill/ollama-proxy/pkg/logging"
 	"go.uber.org/zap"
 )
 
 // HTTPRecovery is HTTP middleware that recovers from panics
 func HTTPRecovery(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if r := recover(); r != nil {
-				requestID := ""
-				if ctx := r.(*http.Request).Context(); ctx != nil {
-					requestID = GetRequestID(ctx)
-				}
-
-				if logging.Logger != nil {
-					logging.Logger.Error("HTTP panic recovered",
-						zap.Any("panic", r),
-						zap.String("stack", string(debug.Stack())),
-						zap.String("request_id", requestID),
-						zap.String("method", r.(*http.Request).Method),
-						zap.String("path", r.(*http.Request).URL.Path),
-					)
-				}
-
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte("Internal Server Error"))
-			}
-		}()
-
-		next.ServeHTTP(w, r)
-	})
+	return RecoveryHandlerFunc(next.ServeHTTP)
 }
 
 // RecoveryHandlerFunc wraps an http.HandlerFunc with panic recovery
@@ -47,15 +22,10 @@ func RecoveryHandlerFunc(handler http.HandlerFunc) http.HandlerFunc {
 					requestID = GetRequestID(ctx)
 				}
 
-				if logging.Logger != nil {
-					logging.Logger.Error("HTTP panic recovered",
-						zap.Any("panic", rec),
-						zap.String("stack", string(debug.Stack())),
-						zap.String("request_id", requestID),
-						zap.String("method", r.Method),
-						zap.String("path", r.URL.Path),
-					)
-				}
+				DefaultCrashReporter.Report(ScopeHTTP, r.URL.Path, rec, debug.Stack(),
+					zap.String("request_id", requestID),
+					zap.String("method", r.Method),
+				)
 
 				w.WriteHeader(http.StatusInternalServerError)
 				w.Write([]byte("Internal Server Error"))
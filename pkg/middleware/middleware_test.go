@@ -3,6 +3,9 @@ package middleware
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/daoneill/ollama-proxy/pkg/logging"
@@ -210,3 +213,54 @@ func TestContextKeyType(t *testing.T) {
 		t.Error("RequestIDKey should be of type contextKey")
 	}
 }
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen, header string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, header = GetRequestID(r.Context()), r.Header.Get(RequestIDHeader)
+	}))
+
+	// A caller's ID is kept and echoed
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set(RequestIDHeader, "trace-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if seen != "trace-123" || rec.Header().Get(RequestIDHeader) != "trace-123" {
+		t.Errorf("Expected the caller's ID in the context and response, got %q and %q", seen, rec.Header().Get(RequestIDHeader))
+	}
+
+	// Missing and unusable IDs are replaced, and handlers see the new one
+	for _, supplied := range []string{"", "has spaces", strings.Repeat("x", 200)} {
+		req = httptest.NewRequest("GET", "/v1/models", nil)
+		if supplied != "" {
+			req.Header.Set(RequestIDHeader, supplied)
+		}
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if len(seen) != 36 || header != seen || rec.Header().Get(RequestIDHeader) != seen {
+			t.Errorf("Expected a generated ID for %q, got context %q, header %q, response %q", supplied, seen, header, rec.Header().Get(RequestIDHeader))
+		}
+	}
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	var got string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(RequestIDHeader)
+	}))
+	defer backend.Close()
+	client := &http.Client{Transport: RequestIDTransport(nil)}
+
+	req, _ := http.NewRequestWithContext(ContextWithRequestID(context.Background(), "trace-456"), "GET", backend.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if got != "trace-456" {
+		t.Errorf("Expected the context's ID sent to the backend, got %q", got)
+	}
+	if req.Header.Get(RequestIDHeader) != "" {
+		t.Error("Expected the caller's request to be left unchanged")
+	}
+}
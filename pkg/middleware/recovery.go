@@ -5,19 +5,21 @@ import (
 	"fmt"
 	"runtime/debug"
 
-	"github.com/daoneill/ollama-proxy/pkg/logging"
 	"go.uber.org/zap"
 )
 
 // RecoverPanic recovers from panics in handler functions
-func RecoverPanic(ctx context.Context, handler func() error) (err error) {
+func RecoverPanic(ctx context.Context, handler func() error) error {
+	return RecoverPanicScope(ctx, ScopeHandler, "", handler)
+}
+
+// RecoverPanicScope recovers from a panic in handler, reporting it under
+// scope and returning it as an error
+func RecoverPanicScope(ctx context.Context, scope, name string, handler func() error) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			requestID := GetRequestID(ctx)
-			logging.Error("Panic recovered",
-				zap.Any("panic", r),
-				zap.String("stack", string(debug.Stack())),
-				zap.String("request_id", requestID),
+			DefaultCrashReporter.Report(scope, name, r, debug.Stack(),
+				zap.String("request_id", GetRequestID(ctx)),
 			)
 			err = fmt.Errorf("internal server error: panic recovered")
 		}
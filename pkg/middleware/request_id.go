@@ -2,18 +2,37 @@ package middleware
 
 import (
 	"context"
+	"net/http"
 
+	"github.com/daoneill/ollama-proxy/pkg/logging"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 type contextKey string
 
 const RequestIDKey contextKey = "request_id"
 
+// RequestIDHeader carries a request's ID in and out of the proxy, and to
+// the backends serving it
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDMetadata is the gRPC metadata key carrying a request's ID
+const RequestIDMetadata = "x-request-id"
+
+// maxRequestIDLength bounds caller-supplied IDs, which end up in logs
+const maxRequestIDLength = 128
+
 // WithRequestID adds a request ID to the context
 func WithRequestID(ctx context.Context) context.Context {
-	requestID := uuid.New().String()
-	return context.WithValue(ctx, RequestIDKey, requestID)
+	return ContextWithRequestID(ctx, uuid.New().String())
+}
+
+// ContextWithRequestID stores id in the context, and adds it to the
+// fields of the context's logger
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, RequestIDKey, id)
+	return logging.WithFields(ctx, zap.String("request_id", id))
 }
 
 // GetRequestID retrieves the request ID from context
@@ -23,3 +42,64 @@ func GetRequestID(ctx context.Context) string {
 	}
 	return ""
 }
+
+// ValidRequestID reports whether a caller-supplied ID can be used as is:
+// non-empty, at most 128 characters and printable ASCII without spaces
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestID returns the caller's ID when it is usable, or a new one
+func requestID(supplied string) string {
+	if ValidRequestID(supplied) {
+		return supplied
+	}
+	return uuid.New().String()
+}
+
+// RequestID gives every request an ID: the caller's X-Request-ID, or a
+// new one when it is missing or unusable. The ID is stored in the
+// context, replaces the request header so routing annotations see it,
+// and is echoed in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestID(r.Header.Get(RequestIDHeader))
+		r.Header.Set(RequestIDHeader, id)
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(ContextWithRequestID(r.Context(), id)))
+	})
+}
+
+// requestIDTransport adds the request ID in a request's context to calls
+// made on its behalf
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+// RequestIDTransport wraps an HTTP transport so outgoing requests carry
+// X-Request-ID from their context; nil wraps http.DefaultTransport
+func RequestIDTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &requestIDTransport{base: base}
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := GetRequestID(req.Context())
+	if id == "" || req.Header.Get(RequestIDHeader) != "" {
+		return t.base.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set(RequestIDHeader, id)
+	return t.base.RoundTrip(req)
+}
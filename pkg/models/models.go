@@ -0,0 +1,258 @@
+// Package models manages the models installed on backends that can pull
+// and delete them, such as Ollama, for the admin API and the gRPC
+// ModelService
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// ErrBackendNotFound is returned for an unknown backend ID
+var ErrBackendNotFound = errors.New("backend not found")
+
+// Backends looks up registered backends; *router.Router implements it
+type Backends interface {
+	GetBackend(id string) (backends.Backend, bool)
+	ListBackends() []backends.Backend
+}
+
+// BackendModels is the models installed on one backend
+type BackendModels struct {
+	Backend string                    `json:"backend"`
+	Models  []backends.InstalledModel `json:"models"`
+	Error   string                    `json:"error,omitempty"` // backend unreachable
+}
+
+// Manager lists, pulls and deletes models on registered backends
+type Manager struct {
+	backends Backends
+}
+
+// NewManager creates a manager for the backends b
+func NewManager(b Backends) *Manager {
+	return &Manager{backends: b}
+}
+
+// backend returns the backend with id if it manages models
+func (m *Manager) backend(id string) (backends.Backend, error) {
+	b, ok := m.backends.GetBackend(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBackendNotFound, id)
+	}
+	if !backends.ManagesModels(b) {
+		return nil, fmt.Errorf("%w: %s", backends.ErrModelsNotManaged, id)
+	}
+	return b, nil
+}
+
+// List returns the models installed on backendID, or on every backend
+// that manages models when backendID is empty. An unreachable backend is
+// reported in its entry rather than failing the whole list.
+func (m *Manager) List(ctx context.Context, backendID string) ([]BackendModels, error) {
+	var targets []backends.Backend
+	if backendID != "" {
+		b, err := m.backend(backendID)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, b)
+	} else {
+		for _, b := range m.backends.ListBackends() {
+			if backends.ManagesModels(b) {
+				targets = append(targets, b)
+			}
+		}
+		sort.Slice(targets, func(i, j int) bool { return targets[i].ID() < targets[j].ID() })
+	}
+
+	list := make([]BackendModels, 0, len(targets))
+	for _, b := range targets {
+		entry := BackendModels{Backend: b.ID(), Models: []backends.InstalledModel{}}
+		installed, err := backends.InstalledModels(ctx, b)
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			sort.Slice(installed, func(i, j int) bool { return installed[i].Name < installed[j].Name })
+			entry.Models = installed
+		}
+		list = append(list, entry)
+	}
+	return list, nil
+}
+
+// Pull downloads model onto backendID. progress, which may be nil, is
+// called with each progress event of the pull, ending with the Done
+// event; it is not called after Pull returns.
+func (m *Manager) Pull(ctx context.Context, backendID, model string, progress func(backends.Progress)) error {
+	if model == "" {
+		return errors.New("model is required")
+	}
+	b, err := m.backend(backendID)
+	if err != nil {
+		return err
+	}
+
+	logging.Logger.Info("Pulling model",
+		zap.String("backend", backendID),
+		zap.String("model", model),
+	)
+
+	if progress == nil {
+		return backends.PullModel(ctx, b, model)
+	}
+
+	// The backend reports progress to the process-wide broker; forward
+	// this pull's events
+	events, unsubscribe := backends.DefaultProgress.Subscribe(64)
+	forwarded := make(chan bool)
+	go func() {
+		sawDone := false
+		for p := range events {
+			if p.Backend == backendID && p.Model == model && p.Phase == backends.ProgressPull {
+				progress(p)
+				sawDone = sawDone || p.Done
+			}
+		}
+		forwarded <- sawDone
+	}()
+
+	err = backends.PullModel(ctx, b, model)
+	unsubscribe()
+	if !<-forwarded {
+		// The pull failed before the backend reported any progress
+		done := backends.Progress{Backend: backendID, Model: model, Phase: backends.ProgressPull, Done: true, Percent: 100}
+		if err != nil {
+			done.Percent, done.Error = 0, err.Error()
+		}
+		progress(done)
+	}
+	return err
+}
+
+// Delete removes model from backendID
+func (m *Manager) Delete(ctx context.Context, backendID, model string) error {
+	if model == "" {
+		return errors.New("model is required")
+	}
+	b, err := m.backend(backendID)
+	if err != nil {
+		return err
+	}
+	return backends.DeleteModel(ctx, b, model)
+}
+
+// httpStatus maps a management error to a response status
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrBackendNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, backends.ErrModelsNotManaged):
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
+
+// Handler serves the installed models (GET ?backend=) and deletes a model
+// (DELETE ?backend=&model=)
+func (m *Manager) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		backendID, model := r.URL.Query().Get("backend"), r.URL.Query().Get("model")
+
+		switch r.Method {
+		case http.MethodGet:
+			list, err := m.List(r.Context(), backendID)
+			if err != nil {
+				http.Error(w, err.Error(), httpStatus(err))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"backends": list})
+
+		case http.MethodDelete:
+			if backendID == "" || model == "" {
+				http.Error(w, "backend and model are required", http.StatusBadRequest)
+				return
+			}
+			if err := m.Delete(r.Context(), backendID, model); err != nil {
+				http.Error(w, err.Error(), httpStatus(err))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "backend": backendID, "model": model})
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// pullRequest is the admin API pull payload
+type pullRequest struct {
+	Backend string `json:"backend"`
+	Model   string `json:"model"`
+	Stream  bool   `json:"stream"` // stream progress as server-sent events
+}
+
+// PullHandler pulls a model onto a backend (POST {"backend", "model"}).
+// The response is sent when the pull completes, or with "stream": true
+// progress is streamed as model_progress server-sent events ending with
+// the done event, which carries any error.
+func (m *Manager) PullHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req pullRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Backend == "" || req.Model == "" {
+			http.Error(w, "backend and model are required", http.StatusBadRequest)
+			return
+		}
+		// Report a bad target before committing to a stream
+		if _, err := m.backend(req.Backend); err != nil {
+			http.Error(w, err.Error(), httpStatus(err))
+			return
+		}
+
+		if !req.Stream {
+			if err := m.Pull(r.Context(), req.Backend, req.Model, nil); err != nil {
+				http.Error(w, err.Error(), httpStatus(err))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"status": "success", "backend": req.Backend, "model": req.Model})
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		m.Pull(r.Context(), req.Backend, req.Model, func(p backends.Progress) {
+			data, _ := json.Marshal(p)
+			fmt.Fprintf(w, "event: model_progress\ndata: %s\n\n", data)
+			flusher.Flush()
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	// Initialize logger for tests
+	if err := logging.InitLogger("info", false); err != nil {
+		panic(err)
+	}
+	defer logging.Sync()
+
+	os.Exit(m.Run())
+}
+
+// fakeBackend is an Ollama-like backend with installed models
+type fakeBackend struct {
+	backends.Backend
+	id      string
+	manages bool
+	pullErr error
+
+	mu        sync.Mutex
+	installed []string
+}
+
+func (f *fakeBackend) ID() string                     { return f.id }
+func (f *fakeBackend) ManagesModels() bool            { return f.manages }
+func (f *fakeBackend) IsHealthy() bool                { return true }
+func (f *fakeBackend) SupportsModel(name string) bool { return true }
+
+func (f *fakeBackend) InstalledModels(ctx context.Context) ([]backends.InstalledModel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var models []backends.InstalledModel
+	for _, name := range f.installed {
+		models = append(models, backends.InstalledModel{Name: name, SizeBytes: 100})
+	}
+	return models, nil
+}
+
+// PullModel reports progress as Ollama does
+func (f *fakeBackend) PullModel(ctx context.Context, model string) error {
+	if f.pullErr != nil {
+		return f.pullErr
+	}
+	for _, pct := range []float64{0, 50} {
+		backends.DefaultProgress.Publish(backends.Progress{Backend: f.id, Model: model, Phase: backends.ProgressPull, Status: "pulling", Percent: pct})
+	}
+	// Another pull at the same time is not reported
+	backends.DefaultProgress.Publish(backends.Progress{Backend: f.id, Model: "other", Phase: backends.ProgressPull})
+	f.mu.Lock()
+	f.installed = append(f.installed, model)
+	f.mu.Unlock()
+	backends.DefaultProgress.Publish(backends.Progress{Backend: f.id, Model: model, Phase: backends.ProgressPull, Percent: 100, Done: true})
+	return nil
+}
+
+func (f *fakeBackend) DeleteModel(ctx context.Context, model string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, name := range f.installed {
+		if name == model {
+			f.installed = append(f.installed[:i], f.installed[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("model not found")
+}
+
+// fakeBackends is a registry of backends
+type fakeBackends []backends.Backend
+
+func (fb fakeBackends) GetBackend(id string) (backends.Backend, bool) {
+	for _, b := range fb {
+		if b.ID() == id {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+func (fb fakeBackends) ListBackends() []backends.Backend { return fb }
+
+func newTestManager() (*Manager, *fakeBackend) {
+	npu := &fakeBackend{id: "ollama-npu", manages: true, installed: []string{"qwen2.5:0.5b"}}
+	gpu := &fakeBackend{id: "ollama-nvidia", manages: true, installed: []string{"llama3:8b", "codellama:13b"}}
+	cloud := &fakeBackend{id: "openai"}
+	return NewManager(fakeBackends{npu, gpu, cloud}), gpu
+}
+
+func TestManager_List(t *testing.T) {
+	m, _ := newTestManager()
+
+	list, err := m.List(context.Background(), "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 2 || list[0].Backend != "ollama-npu" || list[1].Backend != "ollama-nvidia" {
+		t.Fatalf("Expected the two managed backends, got %+v", list)
+	}
+	if models := list[1].Models; len(models) != 2 || models[0].Name != "codellama:13b" {
+		t.Errorf("Expected sorted models, got %+v", models)
+	}
+
+	if _, err := m.List(context.Background(), "missing"); !errors.Is(err, ErrBackendNotFound) {
+		t.Errorf("Expected ErrBackendNotFound, got %v", err)
+	}
+	if _, err := m.List(context.Background(), "openai"); !errors.Is(err, backends.ErrModelsNotManaged) {
+		t.Errorf("Expected ErrModelsNotManaged, got %v", err)
+	}
+}
+
+func TestManager_PullProgress(t *testing.T) {
+	m, gpu := newTestManager()
+
+	var events []backends.Progress
+	err := m.Pull(context.Background(), "ollama-nvidia", "mistral:7b", func(p backends.Progress) {
+		events = append(events, p)
+	})
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if len(events) != 3 || !events[2].Done || events[1].Percent != 50 {
+		t.Errorf("Expected this pull's three events ending with done, got %+v", events)
+	}
+	if has, _ := backends.HasModel(context.Background(), gpu, "mistral:7b"); !has {
+		t.Error("Expected the model to be installed")
+	}
+
+	// A pull failing before the backend reports progress still ends with
+	// a done event
+	gpu.pullErr = errors.New("connection refused")
+	events = nil
+	err = m.Pull(context.Background(), "ollama-nvidia", "phi3", func(p backends.Progress) {
+		events = append(events, p)
+	})
+	if err == nil {
+		t.Fatal("Expected pull error")
+	}
+	if len(events) != 1 || !events[0].Done || events[0].Error != "connection refused" {
+		t.Errorf("Expected a done event with the error, got %+v", events)
+	}
+}
+
+func TestHandler_ListAndDelete(t *testing.T) {
+	m, gpu := newTestManager()
+	handler := m.Handler()
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/admin/models?backend=ollama-nvidia", nil))
+	var resp struct {
+		Backends []BackendModels `json:"backends"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if len(resp.Backends) != 1 || len(resp.Backends[0].Models) != 2 {
+		t.Errorf("Unexpected list %+v", resp.Backends)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("DELETE", "/admin/models?backend=ollama-nvidia&model=llama3:8b", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if has, _ := backends.HasModel(context.Background(), gpu, "llama3:8b"); has {
+		t.Error("Expected the model to be deleted")
+	}
+
+	for target, want := range map[string]int{
+		"/admin/models?backend=ollama-nvidia&model=llama3:8b": http.StatusBadGateway, // already gone
+		"/admin/models?backend=missing&model=llama3:8b":       http.StatusNotFound,
+		"/admin/models?backend=openai&model=gpt-4o":           http.StatusBadRequest,
+		"/admin/models?backend=ollama-nvidia":                 http.StatusBadRequest,
+	} {
+		rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("DELETE", target, nil))
+		if rec.Code != want {
+			t.Errorf("DELETE %s: expected %d, got %d", target, want, rec.Code)
+		}
+	}
+}
+
+func TestPullHandler(t *testing.T) {
+	m, _ := newTestManager()
+	handler := m.PullHandler()
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("POST", "/admin/models/pull", strings.NewReader(`{"backend":"ollama-npu","model":"phi3"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"success"`) {
+		t.Errorf("Expected success, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("POST", "/admin/models/pull", strings.NewReader(`{"backend":"ollama-npu","model":"gemma2","stream":true}`)))
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+	var last backends.Progress
+	events := 0
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			json.Unmarshal([]byte(data), &last)
+			events++
+		}
+	}
+	if events != 3 || !last.Done || last.Model != "gemma2" {
+		t.Errorf("Expected three events ending with done, got %d ending %+v", events, last)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("POST", "/admin/models/pull", strings.NewReader(`{"backend":"openai","model":"gpt-4o","stream":true}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a backend without model management, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,336 @@
+package models
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// Defaults for PlacementConfig
+const (
+	DefaultPlacementInterval    = 5 * time.Minute
+	DefaultPlacementWindow      = time.Hour
+	DefaultPlacementTopModels   = 5
+	DefaultPlacementMinRequests = 10
+	DefaultPlacementPullTimeout = 30 * time.Minute
+)
+
+// Placement states
+const (
+	PlacementPresent = "present" // already installed
+	PlacementPulling = "pulling"
+	PlacementPulled  = "pulled" // installed by placement
+	PlacementFailed  = "failed"
+)
+
+// PlacementTier is the backends that should hold models up to a size
+type PlacementTier struct {
+	MaxParamsB float64  // largest model, in billions of parameters; 0 = no limit
+	Backends   []string // backend IDs
+}
+
+// PlacementConfig configures the placement controller
+type PlacementConfig struct {
+	Interval    time.Duration // how often placement is reconciled
+	Window      time.Duration // demand is counted over this window
+	TopModels   int           // most requested models placed
+	MinRequests int           // requests in the window before a model is placed
+	PullTimeout time.Duration // per pull
+
+	// Tiers in order; a model goes to the first tier it fits. A model
+	// whose size can't be read from its name goes to the first tier
+	// without a limit.
+	Tiers []PlacementTier
+}
+
+// PlacementStatus is one model's placement on one backend
+type PlacementStatus struct {
+	Model    string    `json:"model"`
+	Backend  string    `json:"backend"`
+	Requests int       `json:"requests"` // in the window
+	State    string    `json:"state"`
+	Error    string    `json:"error,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Placement makes sure the most requested models are installed on the
+// hardware tier that suits their size, pulling them ahead of demand. It
+// never deletes models.
+type Placement struct {
+	manager *Manager
+	config  PlacementConfig
+	now     func() time.Time
+
+	mu     sync.Mutex
+	demand map[string]map[int64]int // model -> minute -> requests
+	status map[string]*PlacementStatus
+}
+
+// NewPlacement creates a placement controller pulling through m
+func NewPlacement(m *Manager, cfg PlacementConfig) *Placement {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultPlacementInterval
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultPlacementWindow
+	}
+	if cfg.TopModels <= 0 {
+		cfg.TopModels = DefaultPlacementTopModels
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = DefaultPlacementMinRequests
+	}
+	if cfg.PullTimeout <= 0 {
+		cfg.PullTimeout = DefaultPlacementPullTimeout
+	}
+	return &Placement{
+		manager: m,
+		config:  cfg,
+		now:     time.Now,
+		demand:  make(map[string]map[int64]int),
+		status:  make(map[string]*PlacementStatus),
+	}
+}
+
+// Observe counts a request for model. It is cheap enough for the request
+// path.
+func (p *Placement) Observe(model string) {
+	if model == "" {
+		return
+	}
+	minute := p.now().Unix() / 60
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counts, ok := p.demand[model]
+	if !ok {
+		counts = make(map[int64]int)
+		p.demand[model] = counts
+	}
+	counts[minute]++
+}
+
+// modelDemand is a model's requests in the window
+type modelDemand struct {
+	model    string
+	requests int
+}
+
+// topModels drops demand older than the window and returns the most
+// requested models with at least MinRequests
+func (p *Placement) topModels() []modelDemand {
+	oldest := p.now().Add(-p.config.Window).Unix() / 60
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var top []modelDemand
+	for model, counts := range p.demand {
+		total := 0
+		for minute, n := range counts {
+			if minute < oldest {
+				delete(counts, minute)
+				continue
+			}
+			total += n
+		}
+		if len(counts) == 0 {
+			delete(p.demand, model)
+		}
+		if total >= p.config.MinRequests {
+			top = append(top, modelDemand{model, total})
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].requests != top[j].requests {
+			return top[i].requests > top[j].requests
+		}
+		return top[i].model < top[j].model
+	})
+	if len(top) > p.config.TopModels {
+		top = top[:p.config.TopModels]
+	}
+	return top
+}
+
+// tierFor returns the backends model should be on, nil if no tier fits
+func (p *Placement) tierFor(model string) []string {
+	size, known := ParamsB(model)
+	for _, tier := range p.config.Tiers {
+		if tier.MaxParamsB == 0 || (known && size <= tier.MaxParamsB) {
+			return tier.Backends
+		}
+	}
+	return nil
+}
+
+// Reconcile pulls the most requested models onto their tier's backends,
+// one at a time, and updates the placement status
+func (p *Placement) Reconcile(ctx context.Context) {
+	top := p.topModels()
+
+	// Forget placements of models that are no longer in demand
+	wanted := make(map[string]bool)
+	for _, d := range top {
+		for _, backendID := range p.tierFor(d.model) {
+			wanted[d.model+"/"+backendID] = true
+		}
+	}
+	p.mu.Lock()
+	for key := range p.status {
+		if !wanted[key] {
+			delete(p.status, key)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, d := range top {
+		for _, backendID := range p.tierFor(d.model) {
+			if ctx.Err() != nil {
+				return
+			}
+			p.place(ctx, d, backendID)
+		}
+	}
+}
+
+// place makes sure d.model is on backendID
+func (p *Placement) place(ctx context.Context, d modelDemand, backendID string) {
+	b, err := p.manager.backend(backendID)
+	if err != nil || !b.IsHealthy() || !b.SupportsModel(d.model) {
+		return // retried next reconcile
+	}
+
+	installed, err := backends.HasModel(ctx, b, d.model)
+	if err != nil {
+		return
+	}
+	if installed {
+		// A model placement pulled stays reported as pulled
+		state := PlacementPresent
+		if prev := p.get(d.model, backendID); prev != nil && prev.State == PlacementPulled {
+			state = PlacementPulled
+		}
+		p.set(d, backendID, state, "")
+		return
+	}
+
+	logging.Logger.Info("Placing model",
+		zap.String("model", d.model),
+		zap.String("backend", backendID),
+		zap.Int("requests", d.requests),
+	)
+	p.set(d, backendID, PlacementPulling, "")
+
+	pullCtx, cancel := context.WithTimeout(ctx, p.config.PullTimeout)
+	defer cancel()
+	if err := p.manager.Pull(pullCtx, backendID, d.model, nil); err != nil {
+		logging.Logger.Warn("Model placement failed",
+			zap.String("model", d.model),
+			zap.String("backend", backendID),
+			zap.Error(err),
+		)
+		p.set(d, backendID, PlacementFailed, err.Error())
+		return
+	}
+	p.set(d, backendID, PlacementPulled, "")
+}
+
+func (p *Placement) get(model, backendID string) *PlacementStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, ok := p.status[model+"/"+backendID]; ok {
+		status := *s
+		return &status
+	}
+	return nil
+}
+
+// set records a placement's state; Updated is when the state last changed
+func (p *Placement) set(d modelDemand, backendID, state, errMsg string) {
+	key := d.model + "/" + backendID
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	updated := p.now()
+	if prev, ok := p.status[key]; ok && prev.State == state {
+		updated = prev.Updated
+	}
+	p.status[key] = &PlacementStatus{
+		Model:    d.model,
+		Backend:  backendID,
+		Requests: d.requests,
+		State:    state,
+		Error:    errMsg,
+		Updated:  updated,
+	}
+}
+
+// Status returns the placements of the models in demand on backendID, or
+// on every backend when backendID is empty, most requested first
+func (p *Placement) Status(backendID string) []PlacementStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var list []PlacementStatus
+	for _, s := range p.status {
+		if backendID == "" || s.Backend == backendID {
+			list = append(list, *s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Requests != list[j].Requests {
+			return list[i].Requests > list[j].Requests
+		}
+		if list[i].Model != list[j].Model {
+			return list[i].Model < list[j].Model
+		}
+		return list[i].Backend < list[j].Backend
+	})
+	return list
+}
+
+// Run reconciles placement every Interval until ctx is cancelled
+func (p *Placement) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Reconcile(ctx)
+		}
+	}
+}
+
+// paramsRe matches a parameter count in a model tag, e.g. "8b" in
+// "llama3:8b-instruct", "8x7b" in "mixtral:8x7b" or "22m" in
+// "all-minilm:22m"
+var paramsRe = regexp.MustCompile(`(?i)(?:^|[:\-_])(?:(\d+)x)?(\d+(?:\.\d+)?)([bm])(?:$|[\-_.:])`)
+
+// ParamsB returns the size of model in billions of parameters, read from
+// its name, and false if the name doesn't say
+func ParamsB(model string) (float64, bool) {
+	m := paramsRe.FindStringSubmatch(model)
+	if m == nil {
+		return 0, false
+	}
+	size, err := strconv.ParseFloat(m[2], 64)
+	if err != nil {
+		return 0, false
+	}
+	if m[1] != "" {
+		experts, _ := strconv.Atoi(m[1])
+		size *= float64(experts)
+	}
+	if strings.EqualFold(m[3], "m") {
+		size /= 1000
+	}
+	return size, true
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestParamsB(t *testing.T) {
+	tests := []struct {
+		model string
+		want  float64
+		known bool
+	}{
+		{"qwen2.5:0.5b", 0.5, true},
+		{"llama3:8b", 8, true},
+		{"llama3.1:70b-instruct-q4_0", 70, true},
+		{"mixtral:8x7b", 56, true},
+		{"all-minilm:22m", 0.022, true},
+		{"llama3", 0, false},
+		{"phi3:mini", 0, false},
+		{"qwen2.5", 0, false}, // a version, not a size
+	}
+	for _, tt := range tests {
+		got, known := ParamsB(tt.model)
+		if got != tt.want || known != tt.known {
+			t.Errorf("ParamsB(%q) = %v, %v; want %v, %v", tt.model, got, known, tt.want, tt.known)
+		}
+	}
+}
+
+func newTestPlacement() (*Placement, *fakeBackend, *fakeBackend) {
+	npu := &fakeBackend{id: "ollama-npu", manages: true}
+	gpu := &fakeBackend{id: "ollama-nvidia", manages: true, installed: []string{"llama3:8b"}}
+	p := NewPlacement(NewManager(fakeBackends{npu, gpu}), PlacementConfig{
+		TopModels:   2,
+		MinRequests: 3,
+		Tiers: []PlacementTier{
+			{MaxParamsB: 3, Backends: []string{"ollama-npu"}},
+			{Backends: []string{"ollama-nvidia"}},
+		},
+	})
+	return p, npu, gpu
+}
+
+func observe(p *Placement, model string, n int) {
+	for i := 0; i < n; i++ {
+		p.Observe(model)
+	}
+}
+
+func TestPlacement_PlacesTopModelsByTier(t *testing.T) {
+	p, npu, gpu := newTestPlacement()
+	observe(p, "qwen2.5:0.5b", 10)
+	observe(p, "llama3:8b", 5)
+	observe(p, "mistral:7b", 4) // outside the top two
+	observe(p, "phi3:mini", 2)  // below MinRequests
+
+	p.Reconcile(context.Background())
+
+	if has, _ := backends.HasModel(context.Background(), npu, "qwen2.5:0.5b"); !has {
+		t.Error("Expected the small model pulled onto the NPU")
+	}
+	if has, _ := backends.HasModel(context.Background(), gpu, "mistral:7b"); has {
+		t.Error("Expected only the top models placed")
+	}
+
+	status := p.Status("")
+	if len(status) != 2 {
+		t.Fatalf("Expected two placements, got %+v", status)
+	}
+	if status[0].Model != "qwen2.5:0.5b" || status[0].Backend != "ollama-npu" || status[0].State != PlacementPulled || status[0].Requests != 10 {
+		t.Errorf("Unexpected placement %+v", status[0])
+	}
+	if status[1].Model != "llama3:8b" || status[1].Backend != "ollama-nvidia" || status[1].State != PlacementPresent {
+		t.Errorf("Unexpected placement %+v", status[1])
+	}
+	if got := p.Status("ollama-npu"); len(got) != 1 {
+		t.Errorf("Expected one placement on ollama-npu, got %+v", got)
+	}
+
+	// Still reported as pulled once installed
+	p.Reconcile(context.Background())
+	if got := p.Status("ollama-npu"); got[0].State != PlacementPulled {
+		t.Errorf("Expected pulled, got %s", got[0].State)
+	}
+}
+
+func TestPlacement_DemandExpires(t *testing.T) {
+	p, _, _ := newTestPlacement()
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	observe(p, "llama3:8b", 5)
+	p.Reconcile(context.Background())
+	if len(p.Status("")) != 1 {
+		t.Fatal("Expected the model placed")
+	}
+
+	now = now.Add(DefaultPlacementWindow + 2*time.Minute)
+	p.Reconcile(context.Background())
+	if got := p.Status(""); len(got) != 0 {
+		t.Errorf("Expected placements forgotten once demand expired, got %+v", got)
+	}
+}
+
+func TestPlacement_PullFailure(t *testing.T) {
+	p, npu, _ := newTestPlacement()
+	npu.pullErr = errors.New("disk full")
+	observe(p, "qwen2.5:0.5b", 3)
+
+	p.Reconcile(context.Background())
+	got := p.Status("ollama-npu")
+	if len(got) != 1 || got[0].State != PlacementFailed || got[0].Error != "disk full" {
+		t.Errorf("Expected a failed placement, got %+v", got)
+	}
+}
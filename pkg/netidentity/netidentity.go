@@ -0,0 +1,228 @@
+// Package netidentity authenticates requests by the network peer they come
+// from rather than an API key. On a tailnet the peer's Tailscale identity
+// (user, node and tags) is looked up from tailscaled; on a plain WireGuard
+// network, whose peers can only send from the addresses their keys allow,
+// each peer's address range is given a name.
+//
+// Identities are mapped to auth.APIKeyInfo, so quotas, rate limits and
+// admin permissions apply to them as they do to keys.
+package netidentity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// DefaultCacheTTL is how long a peer's identity is reused before it is
+// looked up again
+const DefaultCacheTTL = time.Minute
+
+// ErrUnknownPeer is returned by a Resolver for addresses that are not peers
+var ErrUnknownPeer = errors.New("not a known network peer")
+
+// Identity is who a network peer is
+type Identity struct {
+	LoginName   string   // owning user, e.g. alice@example.com; empty on WireGuard
+	DisplayName string   // user's display name
+	Node        string   // machine name, e.g. laptop
+	Tags        []string // ACL tags of the node, e.g. tag:server
+}
+
+// Names returns the names the identity can be configured under, most
+// specific first: the node, its tags, then the user
+func (id Identity) Names() []string {
+	names := make([]string, 0, len(id.Tags)+2)
+	if id.Node != "" {
+		names = append(names, id.Node)
+	}
+	names = append(names, id.Tags...)
+	if id.LoginName != "" {
+		names = append(names, id.LoginName)
+	}
+	return names
+}
+
+// Resolver looks up the identity of the peer at addr (ip:port)
+type Resolver interface {
+	WhoIs(ctx context.Context, addr string) (Identity, error)
+}
+
+// Config configures an Authenticator
+type Config struct {
+	// Networks peers connect from; requests from other addresses use the
+	// fallback authentication
+	Networks []netip.Prefix
+
+	// Identities maps names (see Identity.Names) to the key metadata the
+	// peer authenticates as. An empty Name is filled with the matched name.
+	Identities map[string]auth.APIKeyInfo
+
+	// Default is used for peers not listed in Identities, nil = they need
+	// an API key
+	Default *auth.APIKeyInfo
+
+	CacheTTL time.Duration // default DefaultCacheTTL
+}
+
+// Authenticator authenticates requests from network peers
+type Authenticator struct {
+	resolver Resolver
+	config   Config
+
+	mu    sync.Mutex
+	cache map[netip.Addr]cachedIdentity
+}
+
+type cachedIdentity struct {
+	identity Identity
+	err      error
+	expires  time.Time
+}
+
+// New creates an authenticator resolving peers with resolver
+func New(resolver Resolver, cfg Config) *Authenticator {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = DefaultCacheTTL
+	}
+	identities := make(map[string]auth.APIKeyInfo, len(cfg.Identities))
+	for name, info := range cfg.Identities {
+		identities[strings.ToLower(name)] = info
+	}
+	cfg.Identities = identities
+	return &Authenticator{resolver: resolver, config: cfg, cache: make(map[netip.Addr]cachedIdentity)}
+}
+
+type identityContextKey struct{}
+
+// FromContext returns the network identity a request authenticated with
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityContextKey{}).(Identity)
+	return id, ok
+}
+
+// Middleware authenticates requests from peers as their identity. Requests
+// carrying an Authorization header, from outside the peer networks, or from
+// peers that cannot be resolved or are not configured go to fallback.
+func (a *Authenticator) Middleware(fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		other := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				other.ServeHTTP(w, r)
+				return
+			}
+			addr, err := netip.ParseAddrPort(r.RemoteAddr)
+			if err != nil || !a.fromPeer(addr.Addr()) {
+				other.ServeHTTP(w, r)
+				return
+			}
+
+			id, err := a.resolve(r.Context(), addr)
+			if err != nil {
+				if !errors.Is(err, ErrUnknownPeer) && logging.Logger != nil {
+					logging.Logger.Warn("Failed to resolve network peer",
+						zap.String("addr", r.RemoteAddr),
+						zap.Error(err),
+					)
+				}
+				other.ServeHTTP(w, r)
+				return
+			}
+			info, ok := a.keyInfo(id)
+			if !ok {
+				other.ServeHTTP(w, r)
+				return
+			}
+			if !info.Enabled {
+				http.Error(w, "Network identity is disabled", http.StatusForbidden)
+				return
+			}
+
+			ctx := auth.WithKeyInfo(r.Context(), info)
+			ctx = context.WithValue(ctx, identityContextKey{}, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (a *Authenticator) fromPeer(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, network := range a.config.Networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the peer's identity, from the cache while it is fresh.
+// Unknown peers are cached too, so they are not looked up on every request.
+func (a *Authenticator) resolve(ctx context.Context, addr netip.AddrPort) (Identity, error) {
+	ip := addr.Addr().Unmap()
+	now := time.Now()
+
+	a.mu.Lock()
+	cached, ok := a.cache[ip]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.identity, cached.err
+	}
+
+	id, err := a.resolver.WhoIs(ctx, addr.String())
+	if err != nil && !errors.Is(err, ErrUnknownPeer) {
+		return id, err // lookup failed, try again next request
+	}
+
+	a.mu.Lock()
+	for peer, c := range a.cache {
+		if now.After(c.expires) {
+			delete(a.cache, peer)
+		}
+	}
+	a.cache[ip] = cachedIdentity{identity: id, err: err, expires: now.Add(a.config.CacheTTL)}
+	a.mu.Unlock()
+	return id, err
+}
+
+// keyInfo returns the key metadata id authenticates as, false when it is
+// not configured
+func (a *Authenticator) keyInfo(id Identity) (auth.APIKeyInfo, bool) {
+	names := id.Names()
+	for _, name := range names {
+		if info, ok := a.config.Identities[strings.ToLower(name)]; ok {
+			if info.Name == "" {
+				info.Name = name
+			}
+			info.ID = "netidentity:" + strings.ToLower(name)
+			return info, true
+		}
+	}
+	if a.config.Default == nil || len(names) == 0 {
+		return auth.APIKeyInfo{}, false
+	}
+	info := *a.config.Default
+	if info.Name == "" {
+		// Usage and audit records then show who made the request
+		info.Name = id.LoginName
+		if info.Name == "" {
+			info.Name = id.Node
+		}
+	}
+	// Quotas and rate limits are kept per client ID, so each user (or
+	// untagged machine) gets their own even when the default names them
+	// all alike
+	info.ID = "netidentity:" + id.LoginName
+	if id.LoginName == "" {
+		info.ID = "netidentity:" + id.Node
+	}
+	return info, true
+}
@@ -0,0 +1,249 @@
+package netidentity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+)
+
+// fakeResolver answers from a table of IPs and counts lookups
+type fakeResolver struct {
+	peers   map[string]Identity
+	err     error
+	lookups int
+}
+
+func (f *fakeResolver) WhoIs(_ context.Context, addr string) (Identity, error) {
+	f.lookups++
+	if f.err != nil {
+		return Identity{}, f.err
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	id, ok := f.peers[host]
+	if !ok {
+		return Identity{}, ErrUnknownPeer
+	}
+	return id, nil
+}
+
+// whoami reports the key the request authenticated as, or "fallback"
+func whoami(a *Authenticator) http.Handler {
+	fallback := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Auth", "fallback")
+			next.ServeHTTP(w, r)
+		})
+	}
+	return a.Middleware(fallback)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := auth.KeyInfoFromContext(r.Context()); ok {
+			w.Header().Set("X-Auth", info.Name+":"+info.Tenant)
+		}
+	}))
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	resolver := &fakeResolver{peers: map[string]Identity{
+		"100.64.0.1": {LoginName: "alice@example.com", Node: "laptop"},
+		"100.64.0.2": {Node: "build", Tags: []string{"tag:ci"}},
+		"100.64.0.3": {LoginName: "bob@example.com", Node: "phone"},
+		"100.64.0.4": {LoginName: "mallory@example.com", Node: "desktop"},
+	}}
+	a := New(resolver, Config{
+		Networks: TailnetNetworks,
+		Identities: map[string]auth.APIKeyInfo{
+			"Alice@example.com": {Tenant: "home", Enabled: true},
+			"tag:ci":            {Name: "ci", Tenant: "builds", Enabled: true},
+			"desktop":           {Enabled: false},
+		},
+		Default: &auth.APIKeyInfo{Tenant: "guests", Enabled: true},
+	})
+	handler := whoami(a)
+
+	tests := []struct {
+		remote string
+		header string
+		code   int
+		auth   string
+	}{
+		{"100.64.0.1:5000", "", http.StatusOK, "alice@example.com:home"}, // by user, names fold case
+		{"100.64.0.2:5000", "", http.StatusOK, "ci:builds"},              // by tag
+		{"100.64.0.3:5000", "", http.StatusOK, "bob@example.com:guests"}, // unlisted, default
+		{"100.64.0.4:5000", "", http.StatusForbidden, ""},                // the machine is disabled
+		{"100.64.0.9:5000", "", http.StatusOK, "fallback"},               // not a peer
+		{"192.168.1.5:5000", "", http.StatusOK, "fallback"},              // not the tailnet
+		{"100.64.0.1:5000", "Bearer sk-test", http.StatusOK, "fallback"}, // API keys win
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.RemoteAddr = tt.remote
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.code || rec.Header().Get("X-Auth") != tt.auth {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.remote, tt.code, tt.auth, rec.Code, rec.Header().Get("X-Auth"))
+		}
+	}
+
+	// Identities, including unknown peers, are cached
+	lookups := resolver.lookups
+	for _, remote := range []string{"100.64.0.1:6000", "100.64.0.9:6000"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.RemoteAddr = remote
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if resolver.lookups != lookups {
+		t.Errorf("Expected cached identities, got %d more lookups", resolver.lookups-lookups)
+	}
+
+	// Without a default, unlisted peers need an API key
+	a = New(resolver, Config{Networks: TailnetNetworks})
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.RemoteAddr = "100.64.0.3:5000"
+	rec := httptest.NewRecorder()
+	whoami(a).ServeHTTP(rec, req)
+	if got := rec.Header().Get("X-Auth"); got != "fallback" {
+		t.Errorf("Expected unlisted peers to fall back, got %q", got)
+	}
+}
+
+func TestAuthenticator_DefaultClientsAreSeparate(t *testing.T) {
+	resolver := &fakeResolver{peers: map[string]Identity{
+		"100.64.0.1": {LoginName: "alice@example.com", Node: "laptop"},
+		"100.64.0.3": {LoginName: "bob@example.com", Node: "phone"},
+	}}
+	a := New(resolver, Config{
+		Networks: TailnetNetworks,
+		Default:  &auth.APIKeyInfo{Name: "guest", Tenant: "guests", Enabled: true},
+	})
+
+	var infos []auth.APIKeyInfo
+	passthrough := func(next http.Handler) http.Handler { return next }
+	handler := a.Middleware(passthrough)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := auth.KeyInfoFromContext(r.Context())
+		infos = append(infos, info)
+	}))
+	for _, remote := range []string{"100.64.0.1:5000", "100.64.0.3:5000"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.RemoteAddr = remote
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(infos) != 2 || infos[0].Name != "guest" || infos[1].Name != "guest" {
+		t.Fatalf("Expected both users to authenticate as the default key, got %+v", infos)
+	}
+	// Rate limits key on the client ID and quotas on its fingerprint
+	if infos[0].ClientID() == infos[1].ClientID() {
+		t.Errorf("Expected separate clients for users sharing the default, both got %q", infos[0].ClientID())
+	}
+	if usage.ClientFingerprint(infos[0]) == usage.ClientFingerprint(infos[1]) {
+		t.Error("Expected users sharing the default to have separate quota buckets")
+	}
+}
+
+func TestAuthenticator_LookupFailure(t *testing.T) {
+	resolver := &fakeResolver{err: errors.New("tailscaled not running")}
+	handler := whoami(New(resolver, Config{Networks: TailnetNetworks, Default: &auth.APIKeyInfo{Enabled: true}}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.RemoteAddr = "100.64.0.1:5000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("X-Auth"); got != "fallback" {
+			t.Errorf("Expected a failed lookup to fall back, got %q", got)
+		}
+	}
+	if resolver.lookups != 2 {
+		t.Errorf("Expected failed lookups retried, got %d lookups", resolver.lookups)
+	}
+}
+
+func TestTailscale_WhoIs(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "tailscaled.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "local-tailscaled.sock" || r.URL.Path != "/localapi/v0/whois" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var resp whoIsResponse
+		switch r.URL.Query().Get("addr") {
+		case "100.64.0.1:5000":
+			resp.Node.Name = "laptop.tail1234.ts.net."
+			resp.UserProfile.LoginName = "alice@example.com"
+			resp.UserProfile.DisplayName = "Alice"
+		case "100.64.0.2:5000":
+			resp.Node.Name = "build.tail1234.ts.net."
+			resp.Node.Tags = []string{"tag:ci"}
+			resp.UserProfile.LoginName = "tagged-devices"
+		default:
+			http.Error(w, "no match for IP:port", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	srv.Listener = lis
+	srv.Start()
+	defer srv.Close()
+
+	ts := NewTailscale(socket)
+	id, err := ts.WhoIs(context.Background(), "100.64.0.1:5000")
+	if err != nil {
+		t.Fatalf("WhoIs failed: %v", err)
+	}
+	if id.Node != "laptop" || id.LoginName != "alice@example.com" || id.DisplayName != "Alice" {
+		t.Errorf("Unexpected identity %+v", id)
+	}
+
+	id, err = ts.WhoIs(context.Background(), "100.64.0.2:5000")
+	if err != nil || id.Node != "build" || id.LoginName != "" || len(id.Tags) != 1 {
+		t.Errorf("Expected a tagged node without a user, got %+v, %v", id, err)
+	}
+
+	if _, err := ts.WhoIs(context.Background(), "100.64.0.9:5000"); !errors.Is(err, ErrUnknownPeer) {
+		t.Errorf("Expected ErrUnknownPeer, got %v", err)
+	}
+}
+
+func TestWireGuard_WhoIs(t *testing.T) {
+	wg, err := NewWireGuard(map[string]string{
+		"10.8.0.0/24": "office",
+		"10.8.0.2":    "laptop",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for addr, want := range map[string]string{"10.8.0.2:5000": "laptop", "10.8.0.7:5000": "office"} {
+		if id, err := wg.WhoIs(context.Background(), addr); err != nil || id.Node != want {
+			t.Errorf("%s: expected %s, got %+v, %v", addr, want, id, err)
+		}
+	}
+	if _, err := wg.WhoIs(context.Background(), "10.9.0.1:5000"); !errors.Is(err, ErrUnknownPeer) {
+		t.Errorf("Expected ErrUnknownPeer, got %v", err)
+	}
+	if got := len(wg.Networks()); got != 2 {
+		t.Errorf("Expected 2 networks, got %d", got)
+	}
+
+	if _, err := NewWireGuard(map[string]string{"10.8.0.0/33": "bad"}); err == nil {
+		t.Error("Expected an error for an invalid range")
+	}
+	if p, _ := ParsePrefix("10.8.0.9/24"); p != netip.MustParsePrefix("10.8.0.0/24") {
+		t.Errorf("Expected the range masked, got %s", p)
+	}
+}
@@ -0,0 +1,99 @@
+package netidentity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// DefaultTailscaleSocket is where tailscaled serves its LocalAPI on Linux
+const DefaultTailscaleSocket = "/var/run/tailscale/tailscaled.sock"
+
+// TailnetNetworks are the address ranges Tailscale assigns to nodes
+var TailnetNetworks = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("fd7a:115c:a1e0::/48"),
+}
+
+// Tailscale resolves peers through the LocalAPI of the local tailscaled,
+// which knows the node and user behind every tailnet address
+type Tailscale struct {
+	client *http.Client
+}
+
+// NewTailscale creates a resolver talking to tailscaled on socket
+// (DefaultTailscaleSocket when empty)
+func NewTailscale(socket string) *Tailscale {
+	if socket == "" {
+		socket = DefaultTailscaleSocket
+	}
+	var dialer net.Dialer
+	return &Tailscale{client: &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}}
+}
+
+// whoIsResponse is the part of the LocalAPI whois response used
+type whoIsResponse struct {
+	Node struct {
+		Name string   `json:"Name"` // FQDN, e.g. laptop.tail1234.ts.net.
+		Tags []string `json:"Tags"`
+	} `json:"Node"`
+	UserProfile struct {
+		LoginName   string `json:"LoginName"`
+		DisplayName string `json:"DisplayName"`
+	} `json:"UserProfile"`
+}
+
+// WhoIs looks up the tailnet node and user at addr
+func (t *Tailscale) WhoIs(ctx context.Context, addr string) (Identity, error) {
+	// tailscaled only accepts LocalAPI requests for this host
+	u := "http://local-tailscaled.sock/localapi/v0/whois?addr=" + url.QueryEscape(addr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return Identity{}, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return Identity{}, fmt.Errorf("tailscale whois: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return Identity{}, ErrUnknownPeer
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Identity{}, fmt.Errorf("tailscale whois: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var who whoIsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&who); err != nil {
+		return Identity{}, fmt.Errorf("tailscale whois: %w", err)
+	}
+
+	id := Identity{
+		Node: strings.TrimSuffix(who.Node.Name, "."),
+		Tags: who.Node.Tags,
+	}
+	// The machine name without the tailnet's domain
+	if host, _, ok := strings.Cut(id.Node, "."); ok {
+		id.Node = host
+	}
+	// Tagged nodes belong to the tailnet rather than the user who added them
+	if len(id.Tags) == 0 {
+		id.LoginName = who.UserProfile.LoginName
+		id.DisplayName = who.UserProfile.DisplayName
+	}
+	return id, nil
+}
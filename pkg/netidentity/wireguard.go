@@ -0,0 +1,75 @@
+package netidentity
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+)
+
+// WireGuard names the peers of a WireGuard network by the addresses they
+// may send from (their AllowedIPs), which WireGuard enforces
+type WireGuard struct {
+	peers []wireGuardPeer
+}
+
+type wireGuardPeer struct {
+	prefix netip.Prefix
+	name   string
+}
+
+// NewWireGuard creates a resolver from peer address ranges (CIDR or a
+// single address) to names
+func NewWireGuard(peers map[string]string) (*WireGuard, error) {
+	wg := &WireGuard{}
+	for cidr, name := range peers {
+		prefix, err := ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		wg.peers = append(wg.peers, wireGuardPeer{prefix: prefix, name: name})
+	}
+	return wg, nil
+}
+
+// Networks returns the address ranges of the peers
+func (wg *WireGuard) Networks() []netip.Prefix {
+	networks := make([]netip.Prefix, len(wg.peers))
+	for i, p := range wg.peers {
+		networks[i] = p.prefix
+	}
+	return networks
+}
+
+// WhoIs returns the peer whose range holds addr, the narrowest when ranges
+// overlap
+func (wg *WireGuard) WhoIs(_ context.Context, addr string) (Identity, error) {
+	ap, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		return Identity{}, err
+	}
+	ip := ap.Addr().Unmap()
+
+	best := -1
+	for i, p := range wg.peers {
+		if p.prefix.Contains(ip) && (best < 0 || p.prefix.Bits() > wg.peers[best].prefix.Bits()) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return Identity{}, ErrUnknownPeer
+	}
+	return Identity{Node: wg.peers[best].name}, nil
+}
+
+// ParsePrefix parses a CIDR, or a single address as a prefix holding only it
+func ParsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
@@ -0,0 +1,489 @@
+// Package openapi builds the OpenAPI 3.1 document served at /openapi.json.
+// HTTP packages describe their routes with the same Go types their
+// handlers decode and encode, so schemas are derived from code and cannot
+// drift from what the handlers actually accept.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the OpenAPI specification version emitted
+const Version = "3.1.0"
+
+// Schema is a JSON Schema object
+type Schema map[string]interface{}
+
+// Header describes a request or response header
+type Header struct {
+	Name        string
+	Description string
+	Schema      Schema // defaults to string
+}
+
+// Parameter describes a path or query parameter
+type Parameter struct {
+	Name        string
+	In          string // "path" or "query"
+	Description string
+	Required    bool
+	Schema      Schema // defaults to string
+}
+
+// Content is one media type of a request or response body. Body is either
+// a Go value whose type is reflected into a schema, or a Schema.
+type Content struct {
+	Type string // e.g. "application/json", "text/event-stream"
+	Body interface{}
+}
+
+// OneOf is a body matching exactly one of the given values or schemas
+type OneOf []interface{}
+
+// AllOf is a body matching all of the given values or schemas
+type AllOf []interface{}
+
+// EventStream is a server-sent event stream whose data lines are JSON
+// encodings of Event
+type EventStream struct {
+	Event interface{}
+}
+
+// Response describes one response status
+type Response struct {
+	Status      int
+	Description string
+	Content     []Content
+	Headers     []Header
+}
+
+// ErrorType is one entry of an API's error taxonomy
+type ErrorType struct {
+	Type        string `json:"type"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+// ErrorModel is an error body shape and the error types returned with it
+type ErrorModel struct {
+	Body  interface{}
+	Types []ErrorType
+}
+
+// Operation describes one method on one path
+type Operation struct {
+	Method      string
+	Path        string
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	Parameters  []Parameter
+	Headers     []Header  // request headers
+	Request     []Content // request body content types
+	Responses   []Response
+	Errors      *ErrorModel // error responses, one per status in the taxonomy
+}
+
+// Document accumulates operations and renders the OpenAPI document
+type Document struct {
+	mu sync.Mutex
+
+	title       string
+	version     string
+	description string
+	bearerAuth  bool
+
+	operations []Operation
+	common     []Response
+	taxonomies []*ErrorModel
+
+	schemas   map[string]Schema
+	typeNames map[reflect.Type]string
+}
+
+// NewDocument creates an empty document
+func NewDocument(title, version, description string) *Document {
+	return &Document{
+		title:       title,
+		version:     version,
+		description: description,
+		schemas:     make(map[string]Schema),
+		typeNames:   make(map[reflect.Type]string),
+	}
+}
+
+// SetBearerAuth marks every operation as requiring an API key sent as a
+// bearer token
+func (d *Document) SetBearerAuth(enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.bearerAuth = enabled
+}
+
+// Add registers an operation
+func (d *Document) Add(op Operation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.operations = append(d.operations, op)
+	if op.Errors != nil && !d.hasTaxonomy(op.Errors) {
+		d.taxonomies = append(d.taxonomies, op.Errors)
+	}
+}
+
+// AddCommonResponse adds a response to every operation, for statuses
+// produced by middleware (authentication, rate limiting, maintenance)
+func (d *Document) AddCommonResponse(resp Response) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.common = append(d.common, resp)
+}
+
+func (d *Document) hasTaxonomy(model *ErrorModel) bool {
+	for _, existing := range d.taxonomies {
+		if existing == model {
+			return true
+		}
+	}
+	return false
+}
+
+// Build renders the document
+func (d *Document) Build() map[string]interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	paths := make(map[string]map[string]interface{})
+	for _, op := range d.operations {
+		if paths[op.Path] == nil {
+			paths[op.Path] = make(map[string]interface{})
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = d.buildOperation(op)
+	}
+
+	doc := map[string]interface{}{
+		"openapi": Version,
+		"info": map[string]interface{}{
+			"title":       d.title,
+			"version":     d.version,
+			"description": d.description,
+		},
+		"paths": paths,
+	}
+
+	schemas := make(map[string]Schema, len(d.schemas))
+	for name, schema := range d.schemas {
+		schemas[name] = schema
+	}
+	components := map[string]interface{}{
+		"schemas": schemas,
+	}
+	if d.bearerAuth {
+		components["securitySchemes"] = map[string]interface{}{
+			"apiKey": map[string]interface{}{
+				"type":   "http",
+				"scheme": "bearer",
+			},
+		}
+		doc["security"] = []map[string][]string{{"apiKey": {}}}
+	}
+	doc["components"] = components
+
+	// The error taxonomy is also listed as a whole so generators and
+	// clients can map error types without walking every operation
+	if len(d.taxonomies) > 0 {
+		var types []ErrorType
+		for _, model := range d.taxonomies {
+			types = append(types, model.Types...)
+		}
+		doc["x-error-types"] = types
+	}
+
+	return doc
+}
+
+// Handler serves the document as JSON
+func (d *Document) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d.Build())
+	}
+}
+
+func (d *Document) buildOperation(op Operation) map[string]interface{} {
+	out := map[string]interface{}{
+		"operationId": op.ID,
+		"summary":     op.Summary,
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if len(op.Tags) > 0 {
+		out["tags"] = op.Tags
+	}
+
+	var params []map[string]interface{}
+	for _, p := range op.Parameters {
+		params = append(params, map[string]interface{}{
+			"name":        p.Name,
+			"in":          p.In,
+			"description": p.Description,
+			"required":    p.Required || p.In == "path",
+			"schema":      schemaOrString(p.Schema),
+		})
+	}
+	for _, h := range op.Headers {
+		params = append(params, map[string]interface{}{
+			"name":        h.Name,
+			"in":          "header",
+			"description": h.Description,
+			"schema":      schemaOrString(h.Schema),
+		})
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	if len(op.Request) > 0 {
+		out["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  d.buildContent(op.Request),
+		}
+	}
+
+	responses := make(map[string]interface{})
+	for _, resp := range op.Responses {
+		responses[strconv.Itoa(resp.Status)] = d.buildResponse(resp)
+	}
+	if op.Errors != nil {
+		for status, resp := range d.errorResponses(op.Errors) {
+			responses[status] = resp
+		}
+	}
+	for _, resp := range d.common {
+		key := strconv.Itoa(resp.Status)
+		if _, ok := responses[key]; !ok {
+			responses[key] = d.buildResponse(resp)
+		}
+	}
+	out["responses"] = responses
+
+	return out
+}
+
+// errorResponses groups a taxonomy by status, one response per status
+// whose description lists the error types it can carry
+func (d *Document) errorResponses(model *ErrorModel) map[string]interface{} {
+	byStatus := make(map[int][]ErrorType)
+	for _, et := range model.Types {
+		byStatus[et.Status] = append(byStatus[et.Status], et)
+	}
+
+	out := make(map[string]interface{}, len(byStatus))
+	for status, types := range byStatus {
+		lines := make([]string, 0, len(types))
+		names := make([]string, 0, len(types))
+		for _, et := range types {
+			lines = append(lines, fmt.Sprintf("`%s`: %s", et.Type, et.Description))
+			names = append(names, et.Type)
+		}
+		out[strconv.Itoa(status)] = map[string]interface{}{
+			"description": strings.Join(lines, "\n\n"),
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": d.schemaOf(model.Body)},
+			},
+			"x-error-types": names,
+		}
+	}
+	return out
+}
+
+func (d *Document) buildResponse(resp Response) map[string]interface{} {
+	description := resp.Description
+	if description == "" {
+		description = http.StatusText(resp.Status)
+	}
+	out := map[string]interface{}{"description": description}
+	if len(resp.Content) > 0 {
+		out["content"] = d.buildContent(resp.Content)
+	}
+	if len(resp.Headers) > 0 {
+		headers := make(map[string]interface{}, len(resp.Headers))
+		for _, h := range resp.Headers {
+			headers[h.Name] = map[string]interface{}{
+				"description": h.Description,
+				"schema":      schemaOrString(h.Schema),
+			}
+		}
+		out["headers"] = headers
+	}
+	return out
+}
+
+func (d *Document) buildContent(contents []Content) map[string]interface{} {
+	out := make(map[string]interface{}, len(contents))
+	for _, c := range contents {
+		out[c.Type] = map[string]interface{}{"schema": d.schemaOf(c.Body)}
+	}
+	return out
+}
+
+func schemaOrString(s Schema) Schema {
+	if s == nil {
+		return Schema{"type": "string"}
+	}
+	return s
+}
+
+// schemaOf returns the schema for v. A Schema is returned unchanged; any
+// other value is reflected, with named struct types registered under
+// components/schemas and referenced by $ref.
+func (d *Document) schemaOf(v interface{}) Schema {
+	switch v := v.(type) {
+	case nil:
+		return Schema{}
+	case Schema:
+		return v
+	case OneOf:
+		return Schema{"oneOf": d.schemaList(v)}
+	case AllOf:
+		return Schema{"allOf": d.schemaList(v)}
+	case EventStream:
+		return Schema{
+			"type":        "string",
+			"description": "Server-sent events; each data line is a JSON event, and the stream ends with data: [DONE]",
+			"x-event":     d.schemaOf(v.Event),
+		}
+	}
+	return d.schemaFor(reflect.TypeOf(v))
+}
+
+func (d *Document) schemaList(values []interface{}) []Schema {
+	list := make([]Schema, len(values))
+	for i, v := range values {
+		list[i] = d.schemaOf(v)
+	}
+	return list
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (d *Document) schemaFor(t reflect.Type) Schema {
+	if t == timeType {
+		return Schema{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return d.schemaFor(t.Elem())
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return Schema{"type": "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return Schema{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return Schema{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return Schema{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return Schema{"type": "number", "format": "double"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": d.schemaFor(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": d.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + d.register(t)}
+	}
+	return Schema{}
+}
+
+// register adds a named struct type to components/schemas. Types sharing
+// a name across packages are qualified with their package name.
+func (d *Document) register(t reflect.Type) string {
+	if name, ok := d.typeNames[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := d.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+
+	// Reserve the name first so recursive types terminate
+	d.typeNames[t] = name
+	d.schemas[name] = Schema{}
+	d.schemas[name] = d.structSchema(t)
+	return name
+}
+
+func (d *Document) structSchema(t reflect.Type) Schema {
+	properties := make(map[string]interface{})
+	var required []string
+	d.collectFields(t, properties, &required)
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// collectFields adds t's JSON fields, flattening embedded structs the way
+// encoding/json does
+func (d *Document) collectFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.collectFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = d.schemaFor(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	Duration int64 `json:"duration,omitempty"`
+}
+
+type testRequest struct {
+	Model    string           `json:"model"`
+	Stream   *bool            `json:"stream,omitempty"`
+	Tags     []string         `json:"tags"`
+	Data     []byte           `json:"data,omitempty"`
+	Labels   map[string]int32 `json:"labels,omitempty"`
+	Created  time.Time        `json:"created"`
+	Child    *testRequest     `json:"child,omitempty"`
+	Ignored  string           `json:"-"`
+	internal string
+	testMetrics
+}
+
+type testError struct {
+	Message string `json:"message"`
+}
+
+func TestSchemaOf_Reflection(t *testing.T) {
+	d := NewDocument("test", "1", "")
+	ref := d.schemaOf(testRequest{})
+	if ref["$ref"] != "#/components/schemas/testRequest" {
+		t.Fatalf("Expected $ref to testRequest, got %v", ref)
+	}
+
+	schema := d.schemas["testRequest"]
+	props := schema["properties"].(map[string]interface{})
+
+	for _, name := range []string{"model", "stream", "tags", "data", "labels", "created", "child", "duration"} {
+		if _, ok := props[name]; !ok {
+			t.Errorf("Expected property %s", name)
+		}
+	}
+	for _, name := range []string{"Ignored", "internal", "-"} {
+		if _, ok := props[name]; ok {
+			t.Errorf("Property %s must not be exported", name)
+		}
+	}
+
+	if got := schema["required"]; !reflect.DeepEqual(got, []string{"created", "model", "tags"}) {
+		t.Errorf("Unexpected required list: %v", got)
+	}
+	if props["stream"].(Schema)["type"] != "boolean" {
+		t.Errorf("Expected pointer to unwrap, got %v", props["stream"])
+	}
+	if props["data"].(Schema)["contentEncoding"] != "base64" {
+		t.Errorf("Expected []byte as base64 string, got %v", props["data"])
+	}
+	if props["created"].(Schema)["format"] != "date-time" {
+		t.Errorf("Expected time.Time as date-time, got %v", props["created"])
+	}
+	if props["child"].(Schema)["$ref"] != "#/components/schemas/testRequest" {
+		t.Errorf("Expected recursive $ref, got %v", props["child"])
+	}
+}
+
+func TestSchemaOf_NameCollision(t *testing.T) {
+	d := NewDocument("test", "1", "")
+	d.schemas["testError"] = Schema{"type": "object"} // registered by another package
+
+	ref := d.schemaOf(testError{})
+	if ref["$ref"] != "#/components/schemas/openapi.testError" {
+		t.Errorf("Expected package-qualified name on collision, got %v", ref)
+	}
+}
+
+func TestDocument_Build(t *testing.T) {
+	d := NewDocument("proxy", "1.2.3", "test API")
+	d.SetBearerAuth(true)
+	d.AddCommonResponse(Response{Status: http.StatusTooManyRequests, Description: "Rate limited"})
+
+	model := &ErrorModel{Body: testError{}, Types: []ErrorType{
+		{Type: "bad", Status: http.StatusBadRequest, Description: "Bad input"},
+		{Type: "busy", Status: http.StatusServiceUnavailable, Description: "Busy"},
+		{Type: "down", Status: http.StatusServiceUnavailable, Description: "Down"},
+	}}
+	d.Add(Operation{
+		Method:  http.MethodPost,
+		Path:    "/v1/things",
+		ID:      "createThing",
+		Headers: []Header{{Name: "X-Thing", Description: "thing"}},
+		Request: []Content{{Type: "application/json", Body: testRequest{}}},
+		Responses: []Response{{
+			Status:  http.StatusOK,
+			Content: []Content{{Type: "text/event-stream", Body: EventStream{Event: testMetrics{}}}},
+			Headers: []Header{{Name: "X-Served-By"}},
+		}},
+		Errors: model,
+	})
+
+	// Round-trip through JSON to inspect the rendered document
+	raw, err := json.Marshal(d.Build())
+	if err != nil {
+		t.Fatalf("Failed to marshal document: %v", err)
+	}
+	var doc struct {
+		OpenAPI  string                `json:"openapi"`
+		Security []map[string][]string `json:"security"`
+		Paths    map[string]map[string]struct {
+			OperationID string                            `json:"operationId"`
+			Parameters  []map[string]interface{}          `json:"parameters"`
+			Responses   map[string]map[string]interface{} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+		ErrorTypes []ErrorType `json:"x-error-types"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	if doc.OpenAPI != Version || len(doc.Security) != 1 {
+		t.Errorf("Unexpected header fields: %s %v", doc.OpenAPI, doc.Security)
+	}
+	op := doc.Paths["/v1/things"]["post"]
+	if op.OperationID != "createThing" || len(op.Parameters) != 1 || op.Parameters[0]["in"] != "header" {
+		t.Errorf("Unexpected operation: %+v", op)
+	}
+	for _, status := range []string{"200", "400", "503", "429"} {
+		if _, ok := op.Responses[status]; !ok {
+			t.Errorf("Expected %s response", status)
+		}
+	}
+	if types := op.Responses["503"]["x-error-types"].([]interface{}); len(types) != 2 {
+		t.Errorf("Expected both 503 error types, got %v", types)
+	}
+	if len(doc.ErrorTypes) != 3 {
+		t.Errorf("Expected top-level taxonomy, got %v", doc.ErrorTypes)
+	}
+	for _, name := range []string{"testRequest", "testMetrics", "testError"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("Expected component schema %s", name)
+		}
+	}
+}
+
+func TestDocument_Handler(t *testing.T) {
+	d := NewDocument("proxy", "1", "")
+
+	w := httptest.NewRecorder()
+	d.Handler()(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected response: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	w = httptest.NewRecorder()
+	d.Handler()(w, httptest.NewRequest(http.MethodPost, "/openapi.json", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
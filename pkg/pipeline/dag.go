@@ -0,0 +1,387 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/langdetect"
+)
+
+// MergeStrategy is how a stage combines the outputs of its dependencies
+type MergeStrategy string
+
+const (
+	MergeConcat   MergeStrategy = "concat"   // Join text outputs in dependency order
+	MergeTemplate MergeStrategy = "template" // Render a template over the outputs
+	MergeBestOf   MergeStrategy = "best_of"  // Pick one output
+)
+
+// What a best_of merge picks
+const (
+	SelectConfidence = "confidence" // Highest confidence (default)
+	SelectLongest    = "longest"    // Longest output
+	SelectFastest    = "fastest"    // First dependency to finish
+)
+
+// defaultMergeSeparator separates concatenated outputs
+const defaultMergeSeparator = "\n\n"
+
+// Merge configures how a fan-in stage combines its dependencies' outputs.
+// Dependencies that failed or were skipped are left out; a template sees
+// them as empty.
+type Merge struct {
+	Strategy MergeStrategy
+
+	// Separator goes between concatenated outputs (default a blank line)
+	Separator string
+
+	// Template renders a template merge, with the outputs keyed by stage
+	// ID: {{.summary}}, or {{index . "stage-id"}} for IDs with dashes
+	Template *template.Template
+
+	// Select is what a best_of merge picks: confidence, longest or fastest
+	Select string
+}
+
+// IsGraph reports whether the pipeline's stages declare dependencies, and
+// so run as a graph rather than one after another
+func (p *Pipeline) IsGraph() bool {
+	for _, stage := range p.Stages {
+		if len(stage.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// stageOrder checks a pipeline's stage graph and returns its stages in an
+// order where every stage comes after its dependencies. It refuses
+// duplicate stage IDs, unknown or repeated dependencies, cycles and merges
+// without enough inputs.
+func stageOrder(stages []*Stage) ([]*Stage, error) {
+	byID := make(map[string]*Stage, len(stages))
+	for _, stage := range stages {
+		if _, dup := byID[stage.ID]; dup {
+			return nil, fmt.Errorf("duplicate stage id %s", stage.ID)
+		}
+		byID[stage.ID] = stage
+	}
+
+	pending := make(map[string]int, len(stages))
+	dependents := make(map[string][]string, len(stages))
+	for _, stage := range stages {
+		seen := make(map[string]bool, len(stage.DependsOn))
+		for _, dep := range stage.DependsOn {
+			switch {
+			case dep == stage.ID:
+				return nil, fmt.Errorf("stage %s depends on itself", stage.ID)
+			case byID[dep] == nil:
+				return nil, fmt.Errorf("stage %s depends on unknown stage %s", stage.ID, dep)
+			case seen[dep]:
+				return nil, fmt.Errorf("stage %s lists dependency %s twice", stage.ID, dep)
+			}
+			seen[dep] = true
+			dependents[dep] = append(dependents[dep], stage.ID)
+		}
+		pending[stage.ID] = len(stage.DependsOn)
+
+		if err := checkMerge(stage); err != nil {
+			return nil, fmt.Errorf("stage %s: %w", stage.ID, err)
+		}
+	}
+
+	// Kahn's algorithm, keeping declaration order among ready stages
+	order := make([]*Stage, 0, len(stages))
+	ready := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		if pending[stage.ID] == 0 {
+			ready = append(ready, stage.ID)
+		}
+	}
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, byID[id])
+		for _, next := range dependents[id] {
+			if pending[next]--; pending[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	if len(order) < len(stages) {
+		var cycle []string
+		for _, stage := range stages {
+			if pending[stage.ID] > 0 {
+				cycle = append(cycle, stage.ID)
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle between stages %s", strings.Join(cycle, ", "))
+	}
+	return order, nil
+}
+
+// checkMerge checks a stage's merge settings against its dependencies
+func checkMerge(stage *Stage) error {
+	if stage.Type == StageTypeMerge && len(stage.DependsOn) < 2 {
+		return fmt.Errorf("merge stages need at least two dependencies")
+	}
+	m := stage.Merge
+	if m == nil {
+		return nil
+	}
+	if len(stage.DependsOn) < 2 {
+		return fmt.Errorf("merge needs at least two dependencies")
+	}
+	switch m.Strategy {
+	case MergeConcat:
+	case MergeTemplate:
+		if m.Template == nil {
+			return fmt.Errorf("template merge needs a template")
+		}
+	case MergeBestOf:
+		switch m.Select {
+		case "", SelectConfidence, SelectLongest, SelectFastest:
+		default:
+			return fmt.Errorf("invalid best_of select: %s", m.Select)
+		}
+	default:
+		return fmt.Errorf("invalid merge strategy: %s", m.Strategy)
+	}
+	return nil
+}
+
+// executeGraph runs a pipeline whose stages declare dependencies. Each
+// stage starts as soon as its dependencies finish, so independent
+// branches run at the same time on their own backends. Stages without
+// dependencies take the pipeline input; the final output is that of the
+// last declared stage nothing depends on.
+func (pe *PipelineExecutor) executeGraph(ctx context.Context, pipeline *Pipeline, input interface{}, startTime time.Time) (*PipelineResult, error) {
+	result := &PipelineResult{
+		PipelineID:   pipeline.ID,
+		StageResults: make([]*StageResult, 0, len(pipeline.Stages)),
+	}
+	if lang, ok := langdetect.FromContext(ctx); ok && lang.Confident() {
+		result.Language = lang.Language
+	}
+
+	order, err := stageOrder(pipeline.Stages)
+	if err != nil {
+		result.Error = fmt.Errorf("invalid stage graph: %w", err)
+		result.TotalTimeMs = time.Since(startTime).Milliseconds()
+		return result, result.Error
+	}
+	continueOnError := pipeline.Options != nil && pipeline.Options.ContinueOnError
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The first failure stops the stages still to run, unless the
+	// pipeline continues on error
+	var failOnce sync.Once
+	var failure error
+	fail := func(stage *Stage, err error) {
+		failOnce.Do(func() {
+			failure = fmt.Errorf("stage %s failed: %w", stage.ID, err)
+			cancel()
+		})
+	}
+
+	type node struct {
+		result   *StageResult
+		finished time.Time
+		done     chan struct{}
+	}
+	nodes := make(map[string]*node, len(order))
+	for _, stage := range order {
+		nodes[stage.ID] = &node{done: make(chan struct{})}
+	}
+
+	for _, stage := range order {
+		go func(stage *Stage, n *node) {
+			defer close(n.done)
+			deps := make([]*StageResult, len(stage.DependsOn))
+			finished := make([]time.Time, len(stage.DependsOn))
+			for i, dep := range stage.DependsOn {
+				<-nodes[dep].done
+				deps[i], finished[i] = nodes[dep].result, nodes[dep].finished
+			}
+
+			if ctx.Err() != nil {
+				n.result = skippedStage(stage)
+				if !continueOnError {
+					fail(stage, ctx.Err())
+				}
+				return
+			}
+			res, err := pe.runGraphStage(ctx, stage, input, deps, finished)
+			n.result, n.finished = res, time.Now()
+			if err != nil && !continueOnError {
+				fail(stage, err)
+			}
+		}(stage, nodes[stage.ID])
+	}
+	for _, stage := range order {
+		<-nodes[stage.ID].done
+	}
+
+	// Results are reported in declaration order, not completion order
+	dependedOn := make(map[string]bool)
+	for _, stage := range pipeline.Stages {
+		result.StageResults = append(result.StageResults, nodes[stage.ID].result)
+		for _, dep := range stage.DependsOn {
+			dependedOn[dep] = true
+		}
+	}
+	result.TotalTimeMs = time.Since(startTime).Milliseconds()
+
+	if failure != nil {
+		result.Error = failure
+		return result, failure
+	}
+
+	for i := len(pipeline.Stages) - 1; i >= 0; i-- {
+		stage := pipeline.Stages[i]
+		if !dependedOn[stage.ID] {
+			if res := nodes[stage.ID].result; ranSuccessfully(res) {
+				result.FinalOutput = res.Output
+			}
+			break
+		}
+	}
+	result.Success = true
+	return result, nil
+}
+
+// runGraphStage runs one stage of a graph on its dependencies' outputs.
+// A stage whose dependencies all failed or were skipped is skipped too.
+func (pe *PipelineExecutor) runGraphStage(ctx context.Context, stage *Stage, input interface{}, deps []*StageResult, finished []time.Time) (*StageResult, error) {
+	stageInput := input
+	selected := ""
+	if len(deps) > 0 {
+		ran := 0
+		for _, dep := range deps {
+			if ranSuccessfully(dep) {
+				ran++
+				stageInput = dep.Output
+			}
+		}
+		if ran == 0 {
+			return skippedStage(stage), nil
+		}
+		if len(deps) > 1 {
+			var err error
+			stageInput, selected, err = mergeOutputs(stage, deps, finished)
+			if err != nil {
+				now := time.Now()
+				return &StageResult{
+					StageID:  stage.ID,
+					Error:    fmt.Errorf("merge failed: %w", err),
+					Metadata: &StageMetadata{StartTime: now, EndTime: now},
+				}, err
+			}
+		}
+	}
+
+	res, err := pe.runStage(ctx, stage, stageInput)
+	if selected != "" && res.Metadata != nil {
+		res.Metadata.SelectedStage = selected
+	}
+	return res, err
+}
+
+// mergeOutputs combines the outputs of a fan-in stage's dependencies,
+// returning the dependency chosen by a best_of merge
+func mergeOutputs(stage *Stage, deps []*StageResult, finished []time.Time) (interface{}, string, error) {
+	m := stage.Merge
+	if m == nil {
+		m = &Merge{Strategy: MergeConcat}
+	}
+
+	switch m.Strategy {
+	case MergeConcat:
+		sep := m.Separator
+		if sep == "" {
+			sep = defaultMergeSeparator
+		}
+		parts := make([]string, 0, len(deps))
+		for i, dep := range deps {
+			if !ranSuccessfully(dep) {
+				continue
+			}
+			text, ok := dep.Output.(string)
+			if !ok {
+				return nil, "", fmt.Errorf("concat needs text, stage %s produced %T", stage.DependsOn[i], dep.Output)
+			}
+			parts = append(parts, text)
+		}
+		return strings.Join(parts, sep), "", nil
+
+	case MergeTemplate:
+		data := make(map[string]interface{}, len(deps))
+		for i, dep := range deps {
+			data[stage.DependsOn[i]] = ""
+			if ranSuccessfully(dep) {
+				data[stage.DependsOn[i]] = dep.Output
+			}
+		}
+		var buf bytes.Buffer
+		if err := m.Template.Execute(&buf, data); err != nil {
+			return nil, "", err
+		}
+		return buf.String(), "", nil
+
+	case MergeBestOf:
+		best := -1
+		var bestScore float64
+		for i, dep := range deps {
+			if !ranSuccessfully(dep) {
+				continue
+			}
+			var score float64
+			switch m.Select {
+			case SelectLongest:
+				score = float64(outputLength(dep.Output))
+			case SelectFastest:
+				score = -float64(finished[i].UnixNano())
+			default:
+				if dep.Metadata != nil {
+					score = dep.Metadata.Confidence
+				}
+			}
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		return deps[best].Output, stage.DependsOn[best], nil
+	}
+	return nil, "", fmt.Errorf("invalid merge strategy: %s", m.Strategy)
+}
+
+func ranSuccessfully(res *StageResult) bool {
+	return res != nil && res.Success && !res.Skipped
+}
+
+func skippedStage(stage *Stage) *StageResult {
+	now := time.Now()
+	return &StageResult{
+		StageID:  stage.ID,
+		Skipped:  true,
+		Metadata: &StageMetadata{StartTime: now, EndTime: now},
+	}
+}
+
+// outputLength measures text and binary outputs for a longest best_of
+func outputLength(output interface{}) int {
+	switch v := output.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	}
+	return 0
+}
@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// rendezvousBackend answers only once every backend sharing its group has
+// been called, so it proves stages ran at the same time
+type rendezvousBackend struct {
+	*MockBackend
+	group *sync.WaitGroup
+}
+
+func (b *rendezvousBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	b.group.Done()
+	waited := make(chan struct{})
+	go func() {
+		b.group.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(2 * time.Second):
+		return nil, errors.New("stages did not run in parallel")
+	}
+	return &backends.GenerateResponse{Response: b.id + ": " + req.Prompt}, nil
+}
+
+func TestStageOrder(t *testing.T) {
+	stage := func(id string, deps ...string) *Stage {
+		return &Stage{ID: id, Type: StageTypeTextGen, DependsOn: deps}
+	}
+
+	order, err := stageOrder([]*Stage{stage("merge", "a", "b"), stage("a", "root"), stage("b", "root"), stage("root")})
+	if err != nil {
+		t.Fatalf("stageOrder failed: %v", err)
+	}
+	var ids []string
+	for _, s := range order {
+		ids = append(ids, s.ID)
+	}
+	if got := strings.Join(ids, ","); got != "root,a,b,merge" {
+		t.Errorf("Expected root,a,b,merge, got %s", got)
+	}
+
+	tests := []struct {
+		name   string
+		stages []*Stage
+		want   string
+	}{
+		{"cycle", []*Stage{stage("a", "c"), stage("b", "a"), stage("c", "b"), stage("d")}, "cycle between stages a, b, c"},
+		{"self", []*Stage{stage("a", "a")}, "depends on itself"},
+		{"unknown", []*Stage{stage("a", "missing")}, "unknown stage missing"},
+		{"repeated", []*Stage{stage("a"), stage("b", "a", "a")}, "twice"},
+		{"duplicate id", []*Stage{stage("a"), stage("a")}, "duplicate stage id"},
+		{"lonely merge", []*Stage{stage("a"), {ID: "m", Type: StageTypeMerge, DependsOn: []string{"a"}}}, "at least two"},
+		{"bad strategy", []*Stage{stage("a"), stage("b"), {ID: "m", Type: StageTypeMerge, DependsOn: []string{"a", "b"}, Merge: &Merge{Strategy: "vote"}}}, "invalid merge strategy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := stageOrder(tt.stages); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadGraphPipeline(t *testing.T) {
+	pl := NewPipelineLoader()
+	def, err := ParseDefinition([]byte(`
+id: review
+stages:
+  - id: draft
+    type: text_generation
+  - id: critique
+    type: text_generation
+    depends_on: [draft]
+  - id: rewrite
+    type: text_generation
+    depends_on: [draft]
+  - id: report
+    type: merge
+    depends_on: [critique, rewrite]
+    merge:
+      strategy: template
+      template: "{{.critique}} / {{.rewrite}}"
+`))
+	if err != nil {
+		t.Fatalf("ParseDefinition failed: %v", err)
+	}
+	p, err := pl.Validate(def)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if !p.IsGraph() || p.Stages[3].Merge == nil || p.Stages[3].Merge.Template == nil {
+		t.Errorf("Expected a graph with a template merge, got %+v", p.Stages[3])
+	}
+
+	def.Stages[0].DependsOn = []string{"report"}
+	if _, err := pl.Validate(def); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected the cycle to be refused at load, got %v", err)
+	}
+
+	def.Stages[0].DependsOn = nil
+	def.Stages[3].Merge.Template = "{{.critique"
+	if _, err := pl.Validate(def); err == nil {
+		t.Error("Expected an invalid template to be refused at load")
+	}
+}
+
+func TestExecuteGraph_ParallelFanIn(t *testing.T) {
+	var group sync.WaitGroup
+	group.Add(2)
+	left := &rendezvousBackend{MockBackend: NewMockBackend("left"), group: &group}
+	right := &rendezvousBackend{MockBackend: NewMockBackend("right"), group: &group}
+	pe := NewPipelineExecutor([]backends.Backend{left, right})
+
+	p := &Pipeline{
+		ID: "fan",
+		Stages: []*Stage{
+			{ID: "a", Type: StageTypeTextGen, PreferredBackend: "left"},
+			{ID: "b", Type: StageTypeTextGen, PreferredBackend: "right"},
+			{ID: "both", Type: StageTypeMerge, DependsOn: []string{"a", "b"}, Merge: &Merge{Strategy: MergeConcat, Separator: " | "}},
+		},
+		Options: &PipelineOptions{},
+	}
+	result, err := pe.Execute(context.Background(), p, "hi")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.FinalOutput != "left: hi | right: hi" {
+		t.Errorf("Unexpected output: %v", result.FinalOutput)
+	}
+	if len(result.StageResults) != 3 || result.StageResults[0].Backend != "left" || result.StageResults[1].Backend != "right" {
+		t.Errorf("Expected results in declaration order, got %+v", result.StageResults)
+	}
+}
+
+func TestExecuteGraph_BestOfAndFailures(t *testing.T) {
+	failing := NewMockBackend("failing")
+	failing.generateErr = errors.New("backend down")
+	pe := NewPipelineExecutor([]backends.Backend{NewMockBackend("ok"), failing})
+
+	stages := func() []*Stage {
+		return []*Stage{
+			{ID: "short", Type: StageTypeTextGen, PreferredBackend: "ok"},
+			{ID: "long", Type: StageTypeTextGen, PreferredBackend: "ok", DependsOn: []string{"short"}},
+			{ID: "broken", Type: StageTypeTextGen, PreferredBackend: "failing"},
+			{ID: "pick", Type: StageTypeMerge, DependsOn: []string{"short", "long", "broken"}, Merge: &Merge{Strategy: MergeBestOf, Select: SelectLongest}},
+		}
+	}
+
+	// A failed branch is left out of the merge when the pipeline continues
+	result, err := pe.Execute(context.Background(), &Pipeline{ID: "best", Stages: stages(), Options: &PipelineOptions{ContinueOnError: true}}, "q")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if want := "Mock response to: Mock response to: q"; result.FinalOutput != want {
+		t.Errorf("Expected the longest output %q, got %v", want, result.FinalOutput)
+	}
+	if pick := result.StageResults[3]; pick.Metadata.SelectedStage != "long" {
+		t.Errorf("Expected long to be selected, got %q", pick.Metadata.SelectedStage)
+	}
+
+	// Otherwise the failure stops the pipeline
+	result, err = pe.Execute(context.Background(), &Pipeline{ID: "best", Stages: stages(), Options: &PipelineOptions{}}, "q")
+	if err == nil || !strings.Contains(err.Error(), "stage broken failed") {
+		t.Fatalf("Expected the broken stage to fail the pipeline, got %v", err)
+	}
+	if result.Success || result.StageResults[3].Success {
+		t.Errorf("Expected the merge not to run, got %+v", result.StageResults[3])
+	}
+}
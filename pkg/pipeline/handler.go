@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxDefinitionBytes bounds the body of a pipeline definition request
+const maxDefinitionBytes = 1 << 20
+
+// validateResponse is the body of a POST /admin/pipelines/validate reply
+type validateResponse struct {
+	Valid  bool   `json:"valid"`
+	Error  string `json:"error,omitempty"`
+	Stages int    `json:"stages,omitempty"`
+}
+
+// Handler serves /admin/pipelines: GET lists the pipeline definitions, or
+// returns the one named by ?id=, POST creates a pipeline, PUT replaces the
+// definition of ?id= and DELETE removes it. Definitions are sent as JSON
+// or YAML.
+func (pl *PipelineLoader) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		switch r.Method {
+		case http.MethodGet:
+			if id == "" {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"pipelines": pl.Definitions(),
+				})
+				return
+			}
+			def, ok := pl.Definition(id)
+			if !ok {
+				http.Error(w, ErrPipelineNotFound.Error()+": "+id, http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(def)
+		case http.MethodPost:
+			def, err := readDefinition(r)
+			if err != nil {
+				http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			saved, err := pl.Create(def)
+			if err != nil {
+				pipelineStoreError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(saved)
+		case http.MethodPut:
+			if id == "" {
+				http.Error(w, "id is required", http.StatusBadRequest)
+				return
+			}
+			def, err := readDefinition(r)
+			if err != nil {
+				http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if def.ID == "" {
+				def.ID = id
+			} else if def.ID != id {
+				http.Error(w, "definition id does not match ?id=", http.StatusBadRequest)
+				return
+			}
+			saved, err := pl.Update(def)
+			if err != nil {
+				pipelineStoreError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(saved)
+		case http.MethodDelete:
+			if id == "" {
+				http.Error(w, "id is required", http.StatusBadRequest)
+				return
+			}
+			if err := pl.Delete(id); err != nil {
+				pipelineStoreError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// ValidateHandler serves POST /admin/pipelines/validate, which checks a
+// definition without saving it
+func (pl *PipelineLoader) ValidateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		def, err := readDefinition(r)
+		if err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var resp validateResponse
+		if pipeline, err := pl.Validate(def); err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Valid = true
+			resp.Stages = len(pipeline.Stages)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// readDefinition decodes a pipeline definition from a request body. YAML
+// is a superset of JSON, so either is accepted.
+func readDefinition(r *http.Request) (PipelineYAML, error) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxDefinitionBytes))
+	if err != nil {
+		return PipelineYAML{}, err
+	}
+	return ParseDefinition(data)
+}
+
+// ParseDefinition decodes a pipeline definition written as JSON or YAML,
+// refusing unknown fields so typos are not silently dropped
+func ParseDefinition(data []byte) (PipelineYAML, error) {
+	var def PipelineYAML
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&def); err != nil {
+		return PipelineYAML{}, err
+	}
+	return def, nil
+}
+
+// pipelineStoreError writes a pipeline store error with a matching status
+func pipelineStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidPipeline):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrPipelineNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrPipelineExists), errors.Is(err, ErrPipelineConfigured):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -3,6 +3,9 @@ package pipeline
 import (
 	"fmt"
 	"os"
+	"sync"
+	"text/template"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,92 +15,156 @@ type PipelineConfig struct {
 	Pipelines []PipelineYAML `yaml:"pipelines"`
 }
 
-// PipelineYAML represents a pipeline in YAML format
+// PipelineYAML represents a pipeline in YAML format. The JSON tags let the
+// admin API accept and return definitions as JSON.
 type PipelineYAML struct {
-	ID          string       `yaml:"id"`
-	Name        string       `yaml:"name"`
-	Description string       `yaml:"description"`
-	Stages      []StageYAML  `yaml:"stages"`
-	Options     OptionsYAML  `yaml:"options"`
+	ID          string           `yaml:"id" json:"id"`
+	Name        string           `yaml:"name,omitempty" json:"name,omitempty"`
+	Description string           `yaml:"description,omitempty" json:"description,omitempty"`
+	Stages      []StageYAML      `yaml:"stages" json:"stages"`
+	Options     OptionsYAML      `yaml:"options,omitempty" json:"options"`
+	Reservation *ReservationYAML `yaml:"reservation,omitempty" json:"reservation,omitempty"`
+}
+
+// ReservationYAML represents a backend reservation in YAML
+type ReservationYAML struct {
+	Backend  string `yaml:"backend,omitempty" json:"backend,omitempty"`
+	Hardware string `yaml:"hardware,omitempty" json:"hardware,omitempty"`
+	Timeout  string `yaml:"timeout,omitempty" json:"timeout,omitempty"` // e.g. "30s"
+	Required bool   `yaml:"required,omitempty" json:"required,omitempty"`
 }
 
 // StageYAML represents a stage in YAML format
 type StageYAML struct {
-	ID                string                 `yaml:"id"`
-	Type              string                 `yaml:"type"`
-	Description       string                 `yaml:"description"`
-	PreferredBackend  string                 `yaml:"preferred_backend"`
-	PreferredHardware string                 `yaml:"preferred_hardware"`
-	Model             string                 `yaml:"model"`
-	ForwardingPolicy  ForwardingPolicyYAML   `yaml:"forwarding_policy"`
-	InputTransform    map[string]interface{} `yaml:"input_transform"`
-	OutputTransform   map[string]interface{} `yaml:"output_transform"`
+	ID                string                 `yaml:"id" json:"id"`
+	Type              string                 `yaml:"type" json:"type"`
+	Description       string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	PreferredBackend  string                 `yaml:"preferred_backend,omitempty" json:"preferred_backend,omitempty"`
+	PreferredHardware string                 `yaml:"preferred_hardware,omitempty" json:"preferred_hardware,omitempty"`
+	Model             string                 `yaml:"model,omitempty" json:"model,omitempty"`
+	Tool              string                 `yaml:"tool,omitempty" json:"tool,omitempty"` // tool stages: a tool from pipelines.tools
+	Languages         []string               `yaml:"languages,omitempty" json:"languages,omitempty"`
+	ForwardingPolicy  ForwardingPolicyYAML   `yaml:"forwarding_policy,omitempty" json:"forwarding_policy"`
+	FrameSampling     *FrameSamplingYAML     `yaml:"frame_sampling,omitempty" json:"frame_sampling,omitempty"`
+	InputTransform    map[string]interface{} `yaml:"input_transform,omitempty" json:"input_transform,omitempty"`
+	OutputTransform   map[string]interface{} `yaml:"output_transform,omitempty" json:"output_transform,omitempty"`
+	DependsOn         []string               `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Merge             *MergeYAML             `yaml:"merge,omitempty" json:"merge,omitempty"`
+}
+
+// MergeYAML represents how a fan-in stage combines its inputs in YAML
+type MergeYAML struct {
+	Strategy  string `yaml:"strategy" json:"strategy"`                       // concat, template, best_of
+	Separator string `yaml:"separator,omitempty" json:"separator,omitempty"` // concat
+	Template  string `yaml:"template,omitempty" json:"template,omitempty"`   // template
+	Select    string `yaml:"select,omitempty" json:"select,omitempty"`       // best_of: confidence, longest, fastest
 }
 
 // ForwardingPolicyYAML represents forwarding policy in YAML
 type ForwardingPolicyYAML struct {
-	EnableConfidenceCheck bool     `yaml:"enable_confidence_check"`
-	MinConfidence         float64  `yaml:"min_confidence"`
-	MaxRetries            int      `yaml:"max_retries"`
-	EscalationPath        []string `yaml:"escalation_path"`
-	EnableThermalCheck    bool     `yaml:"enable_thermal_check"`
-	MaxTemperature        float64  `yaml:"max_temperature"`
-	MaxFanPercent         int      `yaml:"max_fan_percent"`
-	EnableQualityCheck    bool     `yaml:"enable_quality_check"`
-	QualityThreshold      float64  `yaml:"quality_threshold"`
+	EnableConfidenceCheck bool     `yaml:"enable_confidence_check,omitempty" json:"enable_confidence_check,omitempty"`
+	MinConfidence         float64  `yaml:"min_confidence,omitempty" json:"min_confidence,omitempty"`
+	MaxRetries            int      `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`
+	EscalationPath        []string `yaml:"escalation_path,omitempty" json:"escalation_path,omitempty"`
+	EnableThermalCheck    bool     `yaml:"enable_thermal_check,omitempty" json:"enable_thermal_check,omitempty"`
+	MaxTemperature        float64  `yaml:"max_temperature,omitempty" json:"max_temperature,omitempty"`
+	MaxFanPercent         int      `yaml:"max_fan_percent,omitempty" json:"max_fan_percent,omitempty"`
+	EnableQualityCheck    bool     `yaml:"enable_quality_check,omitempty" json:"enable_quality_check,omitempty"`
+	QualityThreshold      float64  `yaml:"quality_threshold,omitempty" json:"quality_threshold,omitempty"`
+}
+
+// FrameSamplingYAML represents a video stage's frame sampling in YAML
+type FrameSamplingYAML struct {
+	Strategy       string  `yaml:"strategy,omitempty" json:"strategy,omitempty"` // uniform, scene_change, keyframes
+	Interval       string  `yaml:"interval,omitempty" json:"interval,omitempty"` // e.g. "2s"
+	SceneThreshold float64 `yaml:"scene_threshold,omitempty" json:"scene_threshold,omitempty"`
+	MaxFrames      int     `yaml:"max_frames,omitempty" json:"max_frames,omitempty"`
 }
 
 // OptionsYAML represents pipeline options in YAML
 type OptionsYAML struct {
-	EnableStreaming  bool `yaml:"enable_streaming"`
-	PreserveContext  bool `yaml:"preserve_context"`
-	ContinueOnError  bool `yaml:"continue_on_error"`
-	CollectMetrics   bool `yaml:"collect_metrics"`
-	ParallelStages   bool `yaml:"parallel_stages"`
-	LatencyCritical  bool `yaml:"latency_critical"`
+	EnableStreaming bool `yaml:"enable_streaming,omitempty" json:"enable_streaming"`
+	PreserveContext bool `yaml:"preserve_context,omitempty" json:"preserve_context"`
+	ContinueOnError bool `yaml:"continue_on_error,omitempty" json:"continue_on_error"`
+	CollectMetrics  bool `yaml:"collect_metrics,omitempty" json:"collect_metrics"`
+	ParallelStages  bool `yaml:"parallel_stages,omitempty" json:"parallel_stages"`
+	LatencyCritical bool `yaml:"latency_critical,omitempty" json:"latency_critical"`
 }
 
-// PipelineLoader loads pipelines from YAML configuration
+// PipelineLoader loads pipelines from YAML configuration, and manages the
+// definitions added at runtime through the admin API
 type PipelineLoader struct {
+	mu        sync.RWMutex
 	pipelines map[string]*Pipeline
+	defs      map[string]Definition
+
+	// configFile is the pipelines config file last loaded, and fileDefs the
+	// definitions it held, which a deleted override falls back to
+	configFile string
+	fileDefs   map[string]PipelineYAML
+
+	// definitionsDir holds one file per pipeline defined at runtime
+	definitionsDir string
 }
 
 // NewPipelineLoader creates a new pipeline loader
 func NewPipelineLoader() *PipelineLoader {
 	return &PipelineLoader{
 		pipelines: make(map[string]*Pipeline),
+		defs:      make(map[string]Definition),
+		fileDefs:  make(map[string]PipelineYAML),
 	}
 }
 
 // LoadFromFile loads pipelines from a YAML file
 func (pl *PipelineLoader) LoadFromFile(path string) error {
-	data, err := os.ReadFile(path)
+	defs, err := readPipelineConfig(path)
 	if err != nil {
-		return fmt.Errorf("failed to read pipeline config: %w", err)
+		return err
 	}
 
-	var config PipelineConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse pipeline config: %w", err)
-	}
-
-	for _, pipelineYAML := range config.Pipelines {
+	pipelines := make(map[string]*Pipeline, len(defs))
+	for _, pipelineYAML := range defs {
 		pipeline, err := pl.convertYAMLToPipeline(pipelineYAML)
 		if err != nil {
 			return fmt.Errorf("failed to convert pipeline %s: %w", pipelineYAML.ID, err)
 		}
+		pipelines[pipeline.ID] = pipeline
+	}
 
-		pl.pipelines[pipeline.ID] = pipeline
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.configFile = path
+	for _, pipelineYAML := range defs {
+		pl.fileDefs[pipelineYAML.ID] = pipelineYAML
+		pl.pipelines[pipelineYAML.ID] = pipelines[pipelineYAML.ID]
+		pl.defs[pipelineYAML.ID] = Definition{PipelineYAML: pipelineYAML, Source: SourceConfigFile}
 	}
 
 	return nil
 }
 
+// readPipelineConfig reads the pipeline definitions in a pipelines config file
+func readPipelineConfig(path string) ([]PipelineYAML, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline config: %w", err)
+	}
+
+	var config PipelineConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline config: %w", err)
+	}
+	return config.Pipelines, nil
+}
+
 // GetPipeline retrieves a pipeline by ID
 func (pl *PipelineLoader) GetPipeline(id string) (*Pipeline, error) {
+	pl.mu.RLock()
 	pipeline, ok := pl.pipelines[id]
+	pl.mu.RUnlock()
 	if !ok {
-		return nil, fmt.Errorf("pipeline not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
 	}
 
 	return pipeline, nil
@@ -105,11 +172,15 @@ func (pl *PipelineLoader) GetPipeline(id string) (*Pipeline, error) {
 
 // AddPipeline adds a pipeline to the loader (primarily for testing)
 func (pl *PipelineLoader) AddPipeline(pipeline *Pipeline) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
 	pl.pipelines[pipeline.ID] = pipeline
 }
 
 // ListPipelines returns all loaded pipeline IDs
 func (pl *PipelineLoader) ListPipelines() []string {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
 	ids := make([]string, 0, len(pl.pipelines))
 	for id := range pl.pipelines {
 		ids = append(ids, id)
@@ -137,6 +208,32 @@ func (pl *PipelineLoader) convertYAMLToPipeline(yamlPipeline PipelineYAML) (*Pip
 		Options:     pl.convertYAMLToOptions(yamlPipeline.Options),
 	}
 
+	// Stage graphs are checked for unknown dependencies and cycles here,
+	// not when a request runs into them
+	if pipeline.IsGraph() {
+		if _, err := stageOrder(stages); err != nil {
+			return nil, err
+		}
+	}
+
+	if res := yamlPipeline.Reservation; res != nil {
+		if res.Backend == "" && res.Hardware == "" {
+			return nil, fmt.Errorf("reservation needs a backend or hardware")
+		}
+		pipeline.Reservation = &Reservation{
+			Backend:  res.Backend,
+			Hardware: res.Hardware,
+			Required: res.Required,
+		}
+		if res.Timeout != "" {
+			timeout, err := time.ParseDuration(res.Timeout)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("invalid reservation timeout: %s", res.Timeout)
+			}
+			pipeline.Reservation.Timeout = timeout
+		}
+	}
+
 	return pipeline, nil
 }
 
@@ -149,6 +246,12 @@ func (pl *PipelineLoader) convertYAMLToStage(yamlStage StageYAML) (*Stage, error
 		PreferredBackend:  yamlStage.PreferredBackend,
 		PreferredHardware: yamlStage.PreferredHardware,
 		Model:             yamlStage.Model,
+		Languages:         yamlStage.Languages,
+		DependsOn:         yamlStage.DependsOn,
+		Tool:              yamlStage.Tool,
+	}
+	if stage.Type == StageTypeTool && stage.Tool == "" {
+		return nil, fmt.Errorf("tool stages need a tool")
 	}
 
 	// Convert forwarding policy
@@ -168,11 +271,77 @@ func (pl *PipelineLoader) convertYAMLToStage(yamlStage StageYAML) (*Stage, error
 		}
 	}
 
+	if fs := yamlStage.FrameSampling; fs != nil {
+		sampling, err := convertYAMLToFrameSampling(fs)
+		if err != nil {
+			return nil, err
+		}
+		stage.FrameSampling = sampling
+	}
+
+	if m := yamlStage.Merge; m != nil {
+		merge, err := convertYAMLToMerge(m)
+		if err != nil {
+			return nil, err
+		}
+		stage.Merge = merge
+	}
+
 	// TODO: Convert input/output transforms (requires template engine)
 
 	return stage, nil
 }
 
+// convertYAMLToFrameSampling validates and converts a frame-sampling policy
+func convertYAMLToFrameSampling(fs *FrameSamplingYAML) (*FrameSampling, error) {
+	sampling := &FrameSampling{
+		Strategy:       FrameSamplingStrategy(fs.Strategy),
+		SceneThreshold: fs.SceneThreshold,
+		MaxFrames:      fs.MaxFrames,
+	}
+	switch sampling.Strategy {
+	case "":
+		sampling.Strategy = SamplingUniform
+	case SamplingUniform, SamplingSceneChange, SamplingKeyframes:
+	default:
+		return nil, fmt.Errorf("invalid frame sampling strategy: %s", fs.Strategy)
+	}
+	if fs.Interval != "" {
+		interval, err := time.ParseDuration(fs.Interval)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid frame sampling interval: %s", fs.Interval)
+		}
+		sampling.Interval = interval
+	}
+	if fs.SceneThreshold < 0 || fs.SceneThreshold > 1 {
+		return nil, fmt.Errorf("frame sampling scene_threshold must be between 0 and 1")
+	}
+	if fs.MaxFrames < 0 {
+		return nil, fmt.Errorf("frame sampling max_frames cannot be negative")
+	}
+	return sampling, nil
+}
+
+// convertYAMLToMerge converts a merge, parsing its template
+func convertYAMLToMerge(m *MergeYAML) (*Merge, error) {
+	merge := &Merge{
+		Strategy:  MergeStrategy(m.Strategy),
+		Separator: m.Separator,
+		Select:    m.Select,
+	}
+	if merge.Strategy == "" {
+		merge.Strategy = MergeConcat
+	}
+	if m.Template != "" {
+		tmpl, err := template.New("merge").Option("missingkey=zero").Parse(m.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid merge template: %w", err)
+		}
+		merge.Template = tmpl
+	}
+	return merge, nil
+}
+
 // convertYAMLToOptions converts YAML options to PipelineOptions
 func (pl *PipelineLoader) convertYAMLToOptions(yamlOptions OptionsYAML) *PipelineOptions {
 	return &PipelineOptions{
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/langdetect"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
 )
 
 // StageType defines the type of processing stage
@@ -36,6 +38,8 @@ const (
 
 	// Generic
 	StageTypeCustom StageType = "custom" // Custom processing
+	StageTypeMerge  StageType = "merge"  // Combine the outputs of several stages
+	StageTypeTool   StageType = "tool"   // Call a configured command or webhook
 )
 
 // Stage represents one step in a processing pipeline
@@ -52,9 +56,30 @@ type Stage struct {
 	// Model selection
 	Model string // Model to use for this stage
 
+	// Tool stages: the configured tool to call
+	Tool string
+
+	// Branching: run only for prompts in these languages (ISO 639-1).
+	// Empty runs for every language; when the language is unknown a
+	// restricted stage is skipped.
+	Languages []string
+
 	// Forwarding policy
 	ForwardingPolicy *ForwardingPolicy
 
+	// Graph: stages whose outputs feed this one. A pipeline where any
+	// stage declares dependencies runs as a graph, and its stages without
+	// any take the pipeline input.
+	DependsOn []string
+
+	// Fan-in: how the outputs of several dependencies are combined (nil =
+	// concatenated)
+	Merge *Merge
+
+	// Video stages given []VideoFrame: frames analyzed on an image backend
+	// (nil = every frame)
+	FrameSampling *FrameSampling
+
 	// Input/Output transformation
 	InputTransform  func(interface{}) (interface{}, error)
 	OutputTransform func(interface{}) (interface{}, error)
@@ -91,6 +116,10 @@ type Pipeline struct {
 
 	// Execution options
 	Options *PipelineOptions
+
+	// Backend held for the whole execution (nil = stages scheduled
+	// independently)
+	Reservation *Reservation
 }
 
 // PipelineOptions controls pipeline execution
@@ -120,6 +149,7 @@ type StageResult struct {
 	Output    interface{}
 	Metadata  *StageMetadata
 	Error     error
+	Skipped   bool // Stage did not apply to this input (language branch)
 }
 
 // StageMetadata contains execution metadata
@@ -137,6 +167,9 @@ type StageMetadata struct {
 	Forwarded      bool
 	ForwardReason  string
 	AttemptCount   int
+
+	// Best-of merges: the dependency whose output was chosen
+	SelectedStage string
 }
 
 // PipelineResult represents the complete pipeline execution result
@@ -147,12 +180,25 @@ type PipelineResult struct {
 	FinalOutput  interface{}
 	TotalTimeMs  int64
 	TotalEnergyWh float64
+	Language     string // Detected input language, "" if unknown
 	Error        error
+
+	// Backend reserved for the execution, "" when it ran unreserved, and
+	// how long the reservation waited
+	ReservedBackend   string
+	ReservationWaitMs int64
 }
 
 // PipelineExecutor executes multi-stage pipelines
 type PipelineExecutor struct {
 	backendRegistry map[string]backends.Backend
+
+	// Pipelines waiting for and holding reserved backends
+	reservations *reservationTable
+	reserveFunc  ReserveFunc
+
+	// Commands and webhooks tool stages may call, by name
+	tools map[string]Tool
 }
 
 // NewPipelineExecutor creates a new pipeline executor
@@ -164,6 +210,7 @@ func NewPipelineExecutor(backendList []backends.Backend) *PipelineExecutor {
 
 	return &PipelineExecutor{
 		backendRegistry: registry,
+		reservations:    newReservationTable(),
 	}
 }
 
@@ -175,9 +222,35 @@ func (pe *PipelineExecutor) Execute(ctx context.Context, pipeline *Pipeline, inp
 		StageResults: make([]*StageResult, 0),
 	}
 
+	ctx = withInputLanguage(ctx, pipeline, input)
+	if lang, ok := langdetect.FromContext(ctx); ok && lang.Confident() {
+		result.Language = lang.Language
+	}
+
+	// Hold the reserved backend until the last stage finishes
+	reserved, release, err := pe.reserve(ctx, pipeline, result)
+	if err != nil {
+		result.Error = err
+		result.TotalTimeMs = time.Since(startTime).Milliseconds()
+		return result, err
+	}
+	defer release()
+	ctx = withReservedBackend(ctx, reserved)
+
+	// Stages declaring dependencies run as a graph
+	if pipeline.IsGraph() {
+		graph, err := pe.executeGraph(ctx, pipeline, input, startTime)
+		graph.ReservedBackend = result.ReservedBackend
+		graph.ReservationWaitMs = result.ReservationWaitMs
+		return graph, err
+	}
+
 	// Check if parallel execution is enabled
 	if pipeline.Options != nil && pipeline.Options.ParallelStages {
-		return pe.executeParallel(ctx, pipeline, input, startTime)
+		parallel, err := pe.executeParallel(ctx, pipeline, input, startTime)
+		parallel.ReservedBackend = result.ReservedBackend
+		parallel.ReservationWaitMs = result.ReservationWaitMs
+		return parallel, err
 	}
 
 	// Sequential execution (default)
@@ -185,7 +258,7 @@ func (pe *PipelineExecutor) Execute(ctx context.Context, pipeline *Pipeline, inp
 
 	// Execute each stage
 	for i, stage := range pipeline.Stages {
-		stageResult, err := pe.executeStage(ctx, stage, currentInput)
+		stageResult, err := pe.runStage(ctx, stage, currentInput)
 		result.StageResults = append(result.StageResults, stageResult)
 
 		if err != nil && !pipeline.Options.ContinueOnError {
@@ -212,6 +285,9 @@ func (pe *PipelineExecutor) executeParallel(ctx context.Context, pipeline *Pipel
 		PipelineID:   pipeline.ID,
 		StageResults: make([]*StageResult, 0),
 	}
+	if lang, ok := langdetect.FromContext(ctx); ok && lang.Confident() {
+		result.Language = lang.Language
+	}
 
 	// Group stages by dependency level
 	// For now, we assume all stages at the same level can run in parallel
@@ -248,7 +324,7 @@ func (pe *PipelineExecutor) executeParallel(ctx context.Context, pipeline *Pipel
 	for groupIdx, group := range parallelGroups {
 		if len(group) == 1 {
 			// Single stage, execute normally
-			stageResult, err := pe.executeStage(ctx, group[0], currentInput)
+			stageResult, err := pe.runStage(ctx, group[0], currentInput)
 			result.StageResults = append(result.StageResults, stageResult)
 
 			if err != nil && !pipeline.Options.ContinueOnError {
@@ -273,7 +349,7 @@ func (pe *PipelineExecutor) executeParallel(ctx context.Context, pipeline *Pipel
 				}
 
 				go func(task *stageTask) {
-					task.result, task.err = pe.executeStage(ctx, task.stage, task.input)
+					task.result, task.err = pe.runStage(ctx, task.stage, task.input)
 					resultChan <- task
 				}(tasks[i])
 			}
@@ -307,6 +383,64 @@ func (pe *PipelineExecutor) executeParallel(ctx context.Context, pipeline *Pipel
 	return result, nil
 }
 
+// runStage executes a stage, turning a panic in the stage or its transforms
+// into a failed StageResult instead of crashing the worker
+func (pe *PipelineExecutor) runStage(ctx context.Context, stage *Stage, input interface{}) (result *StageResult, err error) {
+	if !stageMatchesLanguage(ctx, stage) {
+		now := time.Now()
+		return &StageResult{
+			StageID:  stage.ID,
+			Skipped:  true,
+			Metadata: &StageMetadata{StartTime: now, EndTime: now},
+		}, nil
+	}
+
+	err = middleware.RecoverPanicScope(ctx, middleware.ScopePipeline, stage.ID, func() error {
+		var stageErr error
+		result, stageErr = pe.executeStage(ctx, stage, input)
+		return stageErr
+	})
+	if result == nil {
+		result = &StageResult{
+			StageID:  stage.ID,
+			Success:  false,
+			Error:    err,
+			Metadata: &StageMetadata{StartTime: time.Now(), EndTime: time.Now()},
+		}
+	}
+	return result, err
+}
+
+// withInputLanguage detects the language of a text input when a stage
+// branches on it and the caller hasn't already supplied one
+func withInputLanguage(ctx context.Context, pipeline *Pipeline, input interface{}) context.Context {
+	if _, ok := langdetect.FromContext(ctx); ok {
+		return ctx
+	}
+	text, ok := input.(string)
+	if !ok {
+		return ctx
+	}
+	for _, stage := range pipeline.Stages {
+		if len(stage.Languages) > 0 {
+			return langdetect.NewContext(ctx, langdetect.Detect(text, ""))
+		}
+	}
+	return ctx
+}
+
+// stageMatchesLanguage reports whether a language-restricted stage applies
+func stageMatchesLanguage(ctx context.Context, stage *Stage) bool {
+	if len(stage.Languages) == 0 {
+		return true
+	}
+	lang, ok := langdetect.FromContext(ctx)
+	if !ok || !lang.Confident() {
+		return false
+	}
+	return langdetect.Matches(lang.Language, stage.Languages)
+}
+
 // executeStage executes a single stage with forwarding support
 func (pe *PipelineExecutor) executeStage(ctx context.Context, stage *Stage, input interface{}) (*StageResult, error) {
 	metadata := &StageMetadata{
@@ -328,8 +462,27 @@ func (pe *PipelineExecutor) executeStage(ctx context.Context, stage *Stage, inpu
 		}
 	}
 
+	// Merge stages only combine their dependencies' outputs
+	if stage.Type == StageTypeMerge {
+		return pe.finishStage(stage, processedInput, metadata)
+	}
+
+	// Tool stages call a configured command or webhook, not a backend
+	if stage.Type == StageTypeTool {
+		output, err := pe.callTool(ctx, stage, processedInput)
+		if err != nil {
+			return &StageResult{
+				StageID:  stage.ID,
+				Success:  false,
+				Error:    err,
+				Metadata: metadata,
+			}, err
+		}
+		return pe.finishStage(stage, output, metadata)
+	}
+
 	// Select backend
-	backend, err := pe.selectBackend(stage)
+	backend, err := pe.selectStageBackend(ctx, stage)
 	if err != nil {
 		return &StageResult{
 			StageID:  stage.ID,
@@ -387,6 +540,31 @@ func (pe *PipelineExecutor) executeStage(ctx context.Context, stage *Stage, inpu
 	}, nil
 }
 
+// finishStage completes a stage that ran without a backend, applying its
+// output transform
+func (pe *PipelineExecutor) finishStage(stage *Stage, output interface{}, metadata *StageMetadata) (*StageResult, error) {
+	if stage.OutputTransform != nil {
+		var err error
+		output, err = stage.OutputTransform(output)
+		if err != nil {
+			return &StageResult{
+				StageID:  stage.ID,
+				Success:  false,
+				Error:    fmt.Errorf("output transform failed: %w", err),
+				Metadata: metadata,
+			}, err
+		}
+	}
+	metadata.EndTime = time.Now()
+	metadata.DurationMs = metadata.EndTime.Sub(metadata.StartTime).Milliseconds()
+	return &StageResult{
+		StageID:  stage.ID,
+		Success:  true,
+		Output:   output,
+		Metadata: metadata,
+	}, nil
+}
+
 // executeWithForwarding executes with confidence-based forwarding
 func (pe *PipelineExecutor) executeWithForwarding(
 	ctx context.Context,
@@ -813,6 +991,9 @@ func (pe *PipelineExecutor) executeTextToImage(
 	default:
 		return nil, fmt.Errorf("expected string or *ImageGenRequest for text-to-image, got %T", input)
 	}
+	if req.InitImage != nil && !backends.SupportsImageEdit(backend) {
+		return nil, fmt.Errorf("backend %s does not support image edits", backend.ID())
+	}
 
 	// Use streaming generation for progressive updates
 	stream, err := backend.GenerateImageStream(ctx, req)
@@ -877,6 +1058,11 @@ func (pe *PipelineExecutor) executeVideoToText(
 	stage *Stage,
 	input interface{},
 ) (interface{}, error) {
+	// Decoded frames are sampled and sent to an image backend
+	if frames, ok := input.([]VideoFrame); ok {
+		return pe.executeFrames(ctx, backend, stage, frames)
+	}
+
 	// Check backend capability
 	if !backend.SupportsVideoToText() {
 		return nil, fmt.Errorf("backend %s does not support video-to-text", backend.ID())
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/langdetect"
 )
 
 // MockBackend implements the backends.Backend interface for testing
@@ -1259,3 +1260,97 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestExecuteStagePanicRecovered(t *testing.T) {
+	backend := NewMockBackend("test-backend")
+	executor := NewPipelineExecutor([]backends.Backend{backend})
+
+	pipeline := &Pipeline{
+		ID: "panicking-pipeline",
+		Stages: []*Stage{
+			{
+				ID:    "stage1",
+				Type:  StageTypeTextGen,
+				Model: "llama3:7b",
+				InputTransform: func(input interface{}) (interface{}, error) {
+					panic("transform exploded")
+				},
+			},
+		},
+		Options: &PipelineOptions{ContinueOnError: false},
+	}
+
+	result, err := executor.Execute(context.Background(), pipeline, "test prompt")
+	if err == nil {
+		t.Fatal("Expected error from panicking stage")
+	}
+	if len(result.StageResults) != 1 || result.StageResults[0].Success {
+		t.Errorf("Expected one failed stage result, got %+v", result.StageResults)
+	}
+}
+
+func TestExecuteParallelPanicRecovered(t *testing.T) {
+	backend := NewMockBackend("test-backend")
+	executor := NewPipelineExecutor([]backends.Backend{backend})
+
+	pipeline := &Pipeline{
+		ID: "parallel-panic",
+		Stages: []*Stage{
+			{ID: "ok", Type: StageTypeTextGen, Model: "llama3:7b"},
+			{
+				ID:    "boom",
+				Type:  StageTypeTextGen,
+				Model: "llama3:7b",
+				OutputTransform: func(output interface{}) (interface{}, error) {
+					panic("output exploded")
+				},
+			},
+		},
+		Options: &PipelineOptions{ParallelStages: true, ContinueOnError: true},
+	}
+
+	result, err := executor.Execute(context.Background(), pipeline, "test prompt")
+	if err != nil {
+		t.Fatalf("Expected ContinueOnError to absorb the panic, got: %v", err)
+	}
+	if len(result.StageResults) != 2 {
+		t.Fatalf("Expected 2 stage results, got %d", len(result.StageResults))
+	}
+}
+
+func TestExecuteLanguageBranching(t *testing.T) {
+	backend := NewMockBackend("test-backend")
+	executor := NewPipelineExecutor([]backends.Backend{backend})
+
+	pipeline := &Pipeline{
+		ID: "language-branch",
+		Stages: []*Stage{
+			{ID: "translate", Type: StageTypeTextGen, Model: "llama3:7b", Languages: []string{"fr", "de"}},
+			{ID: "english-only", Type: StageTypeTextGen, Model: "llama3:7b", Languages: []string{"en"}},
+		},
+		Options: &PipelineOptions{},
+	}
+
+	result, err := executor.Execute(context.Background(), pipeline, "Pourquoi le ciel est bleu? Explique avec des exemples")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.Language != "fr" {
+		t.Errorf("Expected detected language fr, got %q", result.Language)
+	}
+	if result.StageResults[0].Skipped || !result.StageResults[1].Skipped {
+		t.Errorf("Expected translate to run and english-only to be skipped, got %+v %+v",
+			result.StageResults[0], result.StageResults[1])
+	}
+
+	// A language supplied by the caller takes precedence over detection
+	ctx := langdetect.NewContext(context.Background(), langdetect.Result{Language: "en", Confidence: 1})
+	result, err = executor.Execute(ctx, pipeline, "Pourquoi le ciel est bleu?")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !result.StageResults[0].Skipped || result.StageResults[1].Skipped {
+		t.Errorf("Expected only english-only to run, got %+v %+v",
+			result.StageResults[0], result.StageResults[1])
+	}
+}
@@ -0,0 +1,231 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// DefaultReservationTimeout is the longest a pipeline waits for its
+// reserved backend when the reservation sets no timeout
+const DefaultReservationTimeout = 30 * time.Second
+
+// ErrReservationTimeout is returned when a required reservation could not
+// be acquired in time
+var ErrReservationTimeout = errors.New("timed out waiting for reserved backend")
+
+// Reservation holds one backend for a pipeline's whole execution, so its
+// stages aren't rescheduled elsewhere between steps and the backend's
+// model and caches stay warm. It is soft: other requests still reach the
+// backend (the router only defers best-effort ones), but pipelines
+// reserving the same backend run one at a time, waiting in arrival order.
+type Reservation struct {
+	Backend  string // backend ID to reserve
+	Hardware string // or the first backend on this hardware, e.g. "npu"
+
+	// Timeout is the longest to wait for the backend (0 =
+	// DefaultReservationTimeout). When it passes, the pipeline runs
+	// unreserved unless Required is set, in which case it fails.
+	Timeout  time.Duration
+	Required bool
+}
+
+// ReserveFunc is told when a pipeline execution holds backendID, e.g. to
+// defer best-effort requests from it in the router. The returned function
+// ends that.
+type ReserveFunc func(owner, backendID string) (release func())
+
+// reservationTable queues pipeline executions for each reserved backend
+type reservationTable struct {
+	mu   sync.Mutex
+	held map[string]*backendHold // backend ID -> hold
+	seq  atomic.Uint64
+}
+
+// backendHold marks a held backend and lists the executions waiting for
+// it, oldest first
+type backendHold struct {
+	waiters []*reservationWaiter
+}
+
+type reservationWaiter struct {
+	ready   chan struct{} // closed when the backend is handed over
+	granted bool
+}
+
+func newReservationTable() *reservationTable {
+	return &reservationTable{held: make(map[string]*backendHold)}
+}
+
+// acquire waits for backendID and returns the function handing it to the
+// next waiter. It fails when timeout passes or ctx ends first.
+func (t *reservationTable) acquire(ctx context.Context, backendID string, timeout time.Duration) (func(), error) {
+	t.mu.Lock()
+	hold, busy := t.held[backendID]
+	if !busy {
+		t.held[backendID] = &backendHold{}
+		t.mu.Unlock()
+		return t.releaseFunc(backendID), nil
+	}
+	w := &reservationWaiter{ready: make(chan struct{})}
+	hold.waiters = append(hold.waiters, w)
+	t.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+		return t.releaseFunc(backendID), nil
+	case <-timer.C:
+		err = ErrReservationTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if w.granted {
+		// Handed over as the wait ended; pass it on
+		t.releaseLocked(backendID)
+		return nil, err
+	}
+	for i, other := range hold.waiters {
+		if other == w {
+			hold.waiters = append(hold.waiters[:i], hold.waiters[i+1:]...)
+			break
+		}
+	}
+	return nil, err
+}
+
+func (t *reservationTable) releaseFunc(backendID string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.releaseLocked(backendID)
+		})
+	}
+}
+
+// releaseLocked hands backendID to the oldest waiter, or frees it
+func (t *reservationTable) releaseLocked(backendID string) {
+	hold, ok := t.held[backendID]
+	if !ok {
+		return
+	}
+	if len(hold.waiters) == 0 {
+		delete(t.held, backendID)
+		return
+	}
+	next := hold.waiters[0]
+	hold.waiters = hold.waiters[1:]
+	next.granted = true
+	close(next.ready)
+}
+
+// waiting returns how many executions wait for backendID
+func (t *reservationTable) waiting(backendID string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if hold, ok := t.held[backendID]; ok {
+		return len(hold.waiters)
+	}
+	return 0
+}
+
+// SetReserveFunc sets the function told about held reservations (nil =
+// none)
+func (pe *PipelineExecutor) SetReserveFunc(fn ReserveFunc) {
+	pe.reserveFunc = fn
+}
+
+// reserve acquires the pipeline's reservation, if it has one. It returns
+// the reserved backend (nil when running unreserved) and the function
+// ending the reservation.
+func (pe *PipelineExecutor) reserve(ctx context.Context, pipeline *Pipeline, result *PipelineResult) (backends.Backend, func(), error) {
+	res := pipeline.Reservation
+	if res == nil {
+		return nil, func() {}, nil
+	}
+
+	backend, err := pe.reservationBackend(res)
+	if err != nil {
+		if res.Required {
+			return nil, nil, err
+		}
+		return nil, func() {}, nil
+	}
+
+	timeout := res.Timeout
+	if timeout <= 0 {
+		timeout = DefaultReservationTimeout
+	}
+	start := time.Now()
+	release, err := pe.reservations.acquire(ctx, backend.ID(), timeout)
+	result.ReservationWaitMs = time.Since(start).Milliseconds()
+	if err != nil {
+		if res.Required || ctx.Err() != nil {
+			return nil, nil, fmt.Errorf("reserving %s for pipeline %s: %w", backend.ID(), pipeline.ID, err)
+		}
+		return nil, func() {}, nil
+	}
+
+	if pe.reserveFunc != nil {
+		owner := fmt.Sprintf("pipeline:%s:%d", pipeline.ID, pe.reservations.seq.Add(1))
+		unhook := pe.reserveFunc(owner, backend.ID())
+		held := release
+		release = func() {
+			unhook()
+			held()
+		}
+	}
+	result.ReservedBackend = backend.ID()
+	return backend, release, nil
+}
+
+// reservationBackend resolves the backend a reservation names
+func (pe *PipelineExecutor) reservationBackend(res *Reservation) (backends.Backend, error) {
+	if res.Backend != "" {
+		return pe.getBackendByID(res.Backend)
+	}
+	if res.Hardware != "" {
+		for _, backend := range pe.backendRegistry {
+			if backend.Hardware() == res.Hardware {
+				return backend, nil
+			}
+		}
+		return nil, fmt.Errorf("no backend found with hardware: %s", res.Hardware)
+	}
+	return nil, fmt.Errorf("reservation names no backend or hardware")
+}
+
+type reservedBackendKey struct{}
+
+// withReservedBackend records the backend reserved for the pipeline
+// running under ctx
+func withReservedBackend(ctx context.Context, backend backends.Backend) context.Context {
+	if backend == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, reservedBackendKey{}, backend)
+}
+
+// selectStageBackend pins a stage to the pipeline's reserved backend
+// unless the stage names its own backend or other hardware
+func (pe *PipelineExecutor) selectStageBackend(ctx context.Context, stage *Stage) (backends.Backend, error) {
+	if reserved, ok := ctx.Value(reservedBackendKey{}).(backends.Backend); ok && stage.PreferredBackend == "" {
+		if stage.PreferredHardware == "" || stage.PreferredHardware == reserved.Hardware() {
+			return reserved, nil
+		}
+	}
+	return pe.selectBackend(stage)
+}
@@ -0,0 +1,150 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func reservedPipeline(res *Reservation) *Pipeline {
+	return &Pipeline{
+		ID: "summarize",
+		Stages: []*Stage{
+			{ID: "draft", Type: StageTypeTextGen, Model: "llama3:7b"},
+			{ID: "refine", Type: StageTypeTextGen, Model: "llama3:7b"},
+			{ID: "review", Type: StageTypeTextGen, Model: "llama3:7b", PreferredBackend: "cpu"},
+		},
+		Options:     &PipelineOptions{},
+		Reservation: res,
+	}
+}
+
+func TestExecute_ReservationPinsStages(t *testing.T) {
+	npu := NewMockBackend("npu")
+	npu.hardware = "npu"
+	executor := NewPipelineExecutor([]backends.Backend{npu, NewMockBackend("cpu")})
+
+	var owners []string
+	released := 0
+	executor.SetReserveFunc(func(owner, backendID string) func() {
+		if backendID != "npu" {
+			t.Errorf("Expected npu reserved, got %s", backendID)
+		}
+		owners = append(owners, owner)
+		return func() { released++ }
+	})
+
+	result, err := executor.Execute(context.Background(), reservedPipeline(&Reservation{Hardware: "npu"}), "notes")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.ReservedBackend != "npu" {
+		t.Errorf("Expected npu reserved, got %q", result.ReservedBackend)
+	}
+	for i, want := range []string{"npu", "npu", "cpu"} {
+		if got := result.StageResults[i].Backend; got != want {
+			t.Errorf("Expected stage %d on %s, got %s", i, want, got)
+		}
+	}
+	if len(owners) != 1 || owners[0] != "pipeline:summarize:1" || released != 1 {
+		t.Errorf("Expected one reservation held and released, got %v, %d releases", owners, released)
+	}
+	if executor.reservations.waiting("npu") != 0 {
+		t.Error("Expected the reservation freed")
+	}
+}
+
+func TestReservationTable_QueuesInOrder(t *testing.T) {
+	table := newReservationTable()
+	ctx := context.Background()
+
+	release, err := table.acquire(ctx, "npu", time.Second)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	order := make(chan int, 2)
+	for i := 1; i <= 2; i++ {
+		// Queue the waiters one after the other
+		go func(i int) {
+			next, err := table.acquire(ctx, "npu", time.Second)
+			if err != nil {
+				t.Errorf("waiter %d: %v", i, err)
+				return
+			}
+			order <- i
+			next()
+		}(i)
+		for table.waiting("npu") != i {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	release()
+	release() // releasing twice must not hand over twice
+	if first, second := <-order, <-order; first != 1 || second != 2 {
+		t.Errorf("Expected waiters served in arrival order, got %d then %d", first, second)
+	}
+}
+
+func TestExecute_ReservationTimeout(t *testing.T) {
+	executor := NewPipelineExecutor([]backends.Backend{NewMockBackend("npu"), NewMockBackend("cpu")})
+
+	// Another execution holds the backend throughout
+	release, _ := executor.reservations.acquire(context.Background(), "npu", time.Second)
+	defer release()
+
+	_, err := executor.Execute(context.Background(), reservedPipeline(&Reservation{Backend: "npu", Timeout: 10 * time.Millisecond, Required: true}), "notes")
+	if !errors.Is(err, ErrReservationTimeout) {
+		t.Errorf("Expected a reservation timeout, got %v", err)
+	}
+
+	// A soft reservation runs unreserved instead
+	result, err := executor.Execute(context.Background(), reservedPipeline(&Reservation{Backend: "npu", Timeout: 10 * time.Millisecond}), "notes")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.ReservedBackend != "" || result.ReservationWaitMs < 10 {
+		t.Errorf("Expected an unreserved run after waiting, got %q after %dms", result.ReservedBackend, result.ReservationWaitMs)
+	}
+	if executor.reservations.waiting("npu") != 0 {
+		t.Error("Expected timed-out waiters to leave the queue")
+	}
+}
+
+func TestLoadFromFile_Reservation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipelines.yaml")
+	os.WriteFile(path, []byte(`pipelines:
+  - id: summarize
+    reservation:
+      hardware: npu
+      timeout: 5s
+      required: true
+    stages:
+      - id: draft
+        type: text_generation
+`), 0o644)
+
+	loader := NewPipelineLoader()
+	if err := loader.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	p, _ := loader.GetPipeline("summarize")
+	if res := p.Reservation; res == nil || res.Hardware != "npu" || res.Timeout != 5*time.Second || !res.Required {
+		t.Errorf("Unexpected reservation %+v", p.Reservation)
+	}
+
+	os.WriteFile(path, []byte(`pipelines:
+  - id: summarize
+    reservation:
+      timeout: 5s
+`), 0o644)
+	if err := NewPipelineLoader().LoadFromFile(path); err == nil {
+		t.Error("Expected an error for a reservation without backend or hardware")
+	}
+}
@@ -0,0 +1,282 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg" // frame decoders for scene-change detection
+	_ "image/png"
+	"strings"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// FrameSamplingStrategy selects which frames of a video reach image backends
+type FrameSamplingStrategy string
+
+const (
+	SamplingUniform     FrameSamplingStrategy = "uniform"      // one frame per interval
+	SamplingSceneChange FrameSamplingStrategy = "scene_change" // frames that differ from the last sampled
+	SamplingKeyframes   FrameSamplingStrategy = "keyframes"    // frames the decoder marked as keyframes
+)
+
+// Frame sampling defaults
+const (
+	DefaultSamplingInterval = time.Second
+	DefaultSceneThreshold   = 0.3
+)
+
+// FrameSampling is a video stage's frame-sampling policy. Video frames are
+// sampled before each is dispatched to the stage's backend as an image, so
+// the policy bounds how many backend requests a video costs.
+type FrameSampling struct {
+	Strategy FrameSamplingStrategy
+
+	// Uniform: time between sampled frames (0 = DefaultSamplingInterval)
+	Interval time.Duration
+
+	// Scene change: mean luminance difference from the last sampled frame,
+	// 0-1, that starts a new scene (0 = DefaultSceneThreshold)
+	SceneThreshold float64
+
+	// Most frames sent, thinned evenly across the selection (0 = no limit)
+	MaxFrames int
+}
+
+// VideoFrame is one decoded frame of a video, the input of video stages
+// with frame sampling
+type VideoFrame struct {
+	Index    int
+	TimeMs   int64
+	Data     []byte // encoded image
+	Format   backends.ImageFormat
+	Keyframe bool // set by decoders that report keyframes
+}
+
+// SampleFrames returns the frames a policy selects, in order. Keyframe
+// sampling falls back to uniform sampling when no frame is marked as a
+// keyframe. Scene-change detection decodes frames as JPEG or PNG.
+func SampleFrames(frames []VideoFrame, policy *FrameSampling) ([]VideoFrame, error) {
+	if policy == nil || len(frames) == 0 {
+		return frames, nil
+	}
+
+	var selected []VideoFrame
+	switch policy.Strategy {
+	case SamplingUniform, "":
+		selected = sampleUniform(frames, policy.Interval)
+
+	case SamplingKeyframes:
+		for _, frame := range frames {
+			if frame.Keyframe {
+				selected = append(selected, frame)
+			}
+		}
+		if len(selected) == 0 {
+			selected = sampleUniform(frames, policy.Interval)
+		}
+
+	case SamplingSceneChange:
+		var err error
+		if selected, err = sampleSceneChanges(frames, policy.SceneThreshold); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown frame sampling strategy: %s", policy.Strategy)
+	}
+
+	return thinFrames(selected, policy.MaxFrames), nil
+}
+
+// sampleUniform keeps the first frame of each interval
+func sampleUniform(frames []VideoFrame, interval time.Duration) []VideoFrame {
+	if interval <= 0 {
+		interval = DefaultSamplingInterval
+	}
+	intervalMs := interval.Milliseconds()
+	if intervalMs < 1 {
+		intervalMs = 1
+	}
+
+	var selected []VideoFrame
+	next := int64(0)
+	for i, frame := range frames {
+		if i == 0 || frame.TimeMs >= next {
+			selected = append(selected, frame)
+			next = frame.TimeMs + intervalMs
+		}
+	}
+	return selected
+}
+
+// sampleSceneChanges keeps the first frame and each frame whose luminance
+// differs from the last kept frame by at least threshold
+func sampleSceneChanges(frames []VideoFrame, threshold float64) ([]VideoFrame, error) {
+	if threshold <= 0 {
+		threshold = DefaultSceneThreshold
+	}
+
+	var selected []VideoFrame
+	var last []float64
+	for _, frame := range frames {
+		sig, err := frameSignature(frame.Data)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", frame.Index, err)
+		}
+		if last == nil || signatureDistance(last, sig) >= threshold {
+			selected = append(selected, frame)
+			last = sig
+		}
+	}
+	return selected, nil
+}
+
+// signatureGrid is the side of the luminance grid frames are compared on
+const signatureGrid = 8
+
+// frameSignature returns the mean luminance (0-1) of each cell of a grid
+// over the frame, a cheap summary that ignores noise and small motion
+func frameSignature(data []byte) ([]float64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding frame: %w", err)
+	}
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("empty frame")
+	}
+
+	sums := make([]float64, signatureGrid*signatureGrid)
+	counts := make([]int, len(sums))
+	width, height := bounds.Dx(), bounds.Dy()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := (y - bounds.Min.Y) * signatureGrid / height
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cell := row*signatureGrid + (x-bounds.Min.X)*signatureGrid/width
+			r, g, b, _ := img.At(x, y).RGBA()
+			sums[cell] += (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+			counts[cell]++
+		}
+	}
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= float64(counts[i])
+		}
+	}
+	return sums, nil
+}
+
+// signatureDistance is the mean absolute difference of two signatures
+func signatureDistance(a, b []float64) float64 {
+	var total float64
+	for i := range a {
+		d := a[i] - b[i]
+		if d < 0 {
+			d = -d
+		}
+		total += d
+	}
+	return total / float64(len(a))
+}
+
+// thinFrames keeps at most max frames, evenly spaced and including the first
+func thinFrames(frames []VideoFrame, max int) []VideoFrame {
+	if max <= 0 || len(frames) <= max {
+		return frames
+	}
+	thinned := make([]VideoFrame, max)
+	for i := range thinned {
+		thinned[i] = frames[i*len(frames)/max]
+	}
+	return thinned
+}
+
+// executeFrames samples video frames and analyzes each sampled frame on an
+// image backend. The output is one line per frame prefixed with its time.
+func (pe *PipelineExecutor) executeFrames(
+	ctx context.Context,
+	backend backends.Backend,
+	stage *Stage,
+	frames []VideoFrame,
+) (interface{}, error) {
+	if !backend.SupportsImageToText() {
+		return nil, fmt.Errorf("backend %s does not support image-to-text for video frames", backend.ID())
+	}
+
+	sampled, err := SampleFrames(frames, stage.FrameSampling)
+	if err != nil {
+		return nil, fmt.Errorf("frame sampling failed: %w", err)
+	}
+
+	var sb strings.Builder
+	for _, frame := range sampled {
+		format := frame.Format
+		if format == "" {
+			format = backends.ImageFormatJPEG
+		}
+		resp, err := backend.AnalyzeImage(ctx, &backends.ImageAnalysisRequest{
+			ImageData: frame.Data,
+			Model:     stage.Model,
+			Task:      "caption",
+			Format:    format,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("frame %d analysis failed: %w", frame.Index, err)
+		}
+		fmt.Fprintf(&sb, "[%s] %s\n", time.Duration(frame.TimeMs)*time.Millisecond, resp.Text)
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// StageEstimate is the expected cost of one stage
+type StageEstimate struct {
+	StageID   string
+	Backend   string
+	Frames    int // video frames in the input, 0 for other inputs
+	Calls     int // backend requests: the sampled frames, or 1
+	LatencyMs int64
+	EnergyWh  float64
+}
+
+// PipelineEstimate is the expected cost of a pipeline execution, from the
+// backends' average latency and power draw
+type PipelineEstimate struct {
+	Stages    []StageEstimate
+	LatencyMs int64 // stages run sequentially
+	EnergyWh  float64
+}
+
+// Estimate predicts the latency and energy of running a pipeline on an
+// input without running it, so callers can check the cost of a video
+// before dispatching its frames. Frame sampling is applied to a []VideoFrame
+// input of the first stage; every other stage is counted as one request.
+func (pe *PipelineExecutor) Estimate(pipeline *Pipeline, input interface{}) (*PipelineEstimate, error) {
+	estimate := &PipelineEstimate{}
+	for i, stage := range pipeline.Stages {
+		backend, err := pe.selectBackend(stage)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d (%s): %w", i, stage.ID, err)
+		}
+
+		stageEstimate := StageEstimate{StageID: stage.ID, Backend: backend.ID(), Calls: 1}
+		if frames, ok := input.([]VideoFrame); ok && i == 0 {
+			sampled, err := SampleFrames(frames, stage.FrameSampling)
+			if err != nil {
+				return nil, fmt.Errorf("stage %d (%s): %w", i, stage.ID, err)
+			}
+			stageEstimate.Frames = len(frames)
+			stageEstimate.Calls = len(sampled)
+		}
+		stageEstimate.LatencyMs = int64(stageEstimate.Calls) * int64(backend.AvgLatencyMs())
+		stageEstimate.EnergyWh = float64(stageEstimate.LatencyMs) * backend.PowerWatts() / 3600000
+
+		estimate.Stages = append(estimate.Stages, stageEstimate)
+		estimate.LatencyMs += stageEstimate.LatencyMs
+		estimate.EnergyWh += stageEstimate.EnergyWh
+	}
+	return estimate, nil
+}
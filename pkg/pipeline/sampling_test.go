@@ -0,0 +1,204 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// solidFrame encodes a small single-colour PNG
+func solidFrame(t *testing.T, gray uint8) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = gray
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Encoding frame failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// testFrames returns n frames 250ms apart
+func testFrames(n int) []VideoFrame {
+	frames := make([]VideoFrame, n)
+	for i := range frames {
+		frames[i] = VideoFrame{Index: i, TimeMs: int64(i) * 250}
+	}
+	return frames
+}
+
+func frameIndexes(frames []VideoFrame) []int {
+	indexes := make([]int, len(frames))
+	for i, frame := range frames {
+		indexes[i] = frame.Index
+	}
+	return indexes
+}
+
+func equalIndexes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSampleFrames_Strategies(t *testing.T) {
+	keyframes := testFrames(8)
+	keyframes[0].Keyframe = true
+	keyframes[5].Keyframe = true
+
+	cases := []struct {
+		name   string
+		frames []VideoFrame
+		policy *FrameSampling
+		want   []int
+	}{
+		{"uniform default", testFrames(10), &FrameSampling{Strategy: SamplingUniform}, []int{0, 4, 8}},
+		{"uniform interval", testFrames(6), &FrameSampling{Strategy: SamplingUniform, Interval: 500 * time.Millisecond}, []int{0, 2, 4}},
+		{"max frames", testFrames(10), &FrameSampling{Interval: time.Millisecond, MaxFrames: 3}, []int{0, 3, 6}},
+		{"keyframes", keyframes, &FrameSampling{Strategy: SamplingKeyframes}, []int{0, 5}},
+		{"keyframes fallback", testFrames(5), &FrameSampling{Strategy: SamplingKeyframes}, []int{0, 4}},
+		{"no policy", testFrames(3), nil, []int{0, 1, 2}},
+	}
+	for _, tc := range cases {
+		sampled, err := SampleFrames(tc.frames, tc.policy)
+		if err != nil {
+			t.Fatalf("%s: SampleFrames failed: %v", tc.name, err)
+		}
+		if got := frameIndexes(sampled); !equalIndexes(got, tc.want) {
+			t.Errorf("%s: expected frames %v, got %v", tc.name, tc.want, got)
+		}
+	}
+
+	if _, err := SampleFrames(testFrames(2), &FrameSampling{Strategy: "random"}); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+func TestSampleFrames_SceneChange(t *testing.T) {
+	// Two scenes with a small flicker in the first
+	grays := []uint8{20, 25, 20, 220, 215, 220}
+	frames := testFrames(len(grays))
+	for i, gray := range grays {
+		frames[i].Data = solidFrame(t, gray)
+	}
+
+	sampled, err := SampleFrames(frames, &FrameSampling{Strategy: SamplingSceneChange})
+	if err != nil {
+		t.Fatalf("SampleFrames failed: %v", err)
+	}
+	if got := frameIndexes(sampled); !equalIndexes(got, []int{0, 3}) {
+		t.Errorf("Expected a frame per scene, got %v", got)
+	}
+
+	frames[2].Data = []byte("not an image")
+	if _, err := SampleFrames(frames, &FrameSampling{Strategy: SamplingSceneChange}); err == nil {
+		t.Error("Expected an error for an undecodable frame")
+	}
+}
+
+func TestVideoStage_SampledFramesAndEstimate(t *testing.T) {
+	var analyzed int
+	backend := &mockBackend{
+		id:                "vision",
+		supportsImageText: true,
+		analyzeImageFunc: func(ctx context.Context, req *backends.ImageAnalysisRequest) (*backends.ImageAnalysisResponse, error) {
+			analyzed++
+			return &backends.ImageAnalysisResponse{Text: string(req.ImageData)}, nil
+		},
+	}
+	executor := NewPipelineExecutor([]backends.Backend{backend})
+
+	frames := testFrames(8)
+	for i := range frames {
+		frames[i].Data = []byte{'a' + byte(i)}
+	}
+	p := &Pipeline{
+		ID: "video-captions",
+		Stages: []*Stage{{
+			ID:            "frames",
+			Type:          StageTypeVideoAnalysis,
+			FrameSampling: &FrameSampling{Strategy: SamplingUniform},
+		}},
+		Options: &PipelineOptions{},
+	}
+
+	// The estimate counts the sampled frames without analyzing them
+	estimate, err := executor.Estimate(p, frames)
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	stage := estimate.Stages[0]
+	if stage.Frames != 8 || stage.Calls != 2 || estimate.LatencyMs != 200 || analyzed != 0 {
+		t.Errorf("Unexpected estimate %+v (%d frames analyzed)", stage, analyzed)
+	}
+	if want := 200 * 10.0 / 3600000; estimate.EnergyWh != want {
+		t.Errorf("Expected %v Wh, got %v", want, estimate.EnergyWh)
+	}
+
+	result, err := executor.Execute(context.Background(), p, frames)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if want := "[0s] a\n[1s] e"; result.FinalOutput != want || analyzed != 2 {
+		t.Errorf("Expected %q from 2 frames, got %q from %d", want, result.FinalOutput, analyzed)
+	}
+
+	backend.supportsImageText = false
+	if _, err := executor.Execute(context.Background(), p, frames); err == nil || !strings.Contains(err.Error(), "image-to-text") {
+		t.Errorf("Expected an error from a backend without image-to-text, got %v", err)
+	}
+}
+
+func TestLoadFromFile_FrameSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipelines.yaml")
+	os.WriteFile(path, []byte(`pipelines:
+  - id: scenes
+    stages:
+      - id: describe
+        type: video_summary
+        frame_sampling:
+          strategy: scene_change
+          scene_threshold: 0.4
+          max_frames: 20
+`), 0o644)
+
+	loader := NewPipelineLoader()
+	if err := loader.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	p, _ := loader.GetPipeline("scenes")
+	want := FrameSampling{Strategy: SamplingSceneChange, SceneThreshold: 0.4, MaxFrames: 20}
+	if fs := p.Stages[0].FrameSampling; fs == nil || *fs != want {
+		t.Errorf("Unexpected frame sampling %+v", fs)
+	}
+
+	for _, bad := range []string{"strategy: random", "interval: soon", "scene_threshold: 2", "max_frames: -1"} {
+		os.WriteFile(path, []byte(`pipelines:
+  - id: scenes
+    stages:
+      - id: describe
+        type: video_summary
+        frame_sampling:
+          `+bad+`
+`), 0o644)
+		if err := NewPipelineLoader().LoadFromFile(path); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
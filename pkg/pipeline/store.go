@@ -0,0 +1,317 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Errors returned when managing pipeline definitions
+var (
+	ErrPipelineNotFound   = errors.New("pipeline not found")
+	ErrPipelineExists     = errors.New("pipeline already exists")
+	ErrPipelineConfigured = errors.New("pipeline is defined in the pipelines config file")
+	ErrInvalidPipeline    = errors.New("invalid pipeline definition")
+	ErrNoDefinitionsDir   = errors.New("no pipeline definitions directory is configured")
+)
+
+// Where a pipeline definition comes from
+const (
+	SourceConfigFile     = "config_file"
+	SourceDefinitionsDir = "definitions_dir"
+)
+
+// Definition is a pipeline definition and where it was loaded from
+type Definition struct {
+	PipelineYAML `yaml:",inline"`
+	Source       string `yaml:"-" json:"source"`
+}
+
+var pipelineIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// knownStageTypes are the stage types a definition may use
+var knownStageTypes = map[StageType]bool{
+	StageTypeTextGen: true, StageTypeEmbed: true,
+	StageTypeAudioToText: true, StageTypeTextToAudio: true, StageTypeAudioEnhance: true, StageTypeAudioTranslate: true,
+	StageTypeImageToText: true, StageTypeTextToImage: true, StageTypeImageEdit: true, StageTypeImageEnhance: true,
+	StageTypeVideoToText: true, StageTypeTextToVideo: true, StageTypeVideoAnalysis: true, StageTypeVideoSummary: true,
+	StageTypeCustom: true, StageTypeMerge: true, StageTypeTool: true,
+}
+
+// Validate checks a definition more strictly than loading the pipelines
+// config file does, and returns the pipeline it describes. Errors wrap
+// ErrInvalidPipeline.
+func (pl *PipelineLoader) Validate(def PipelineYAML) (*Pipeline, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidPipeline, fmt.Sprintf(format, args...))
+	}
+	if !pipelineIDPattern.MatchString(def.ID) {
+		return nil, invalid("id %q must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", def.ID)
+	}
+	if len(def.Stages) == 0 {
+		return nil, invalid("pipeline %s has no stages", def.ID)
+	}
+	seen := make(map[string]bool, len(def.Stages))
+	for i, stage := range def.Stages {
+		if stage.ID == "" {
+			return nil, invalid("stage %d has no id", i)
+		}
+		if seen[stage.ID] {
+			return nil, invalid("duplicate stage id %s", stage.ID)
+		}
+		seen[stage.ID] = true
+		if !knownStageTypes[StageType(stage.Type)] {
+			return nil, invalid("stage %s has unknown type %q", stage.ID, stage.Type)
+		}
+	}
+	pipeline, err := pl.convertYAMLToPipeline(def)
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	return pipeline, nil
+}
+
+// Definitions returns the pipeline definitions, sorted by ID. Pipelines
+// added in code with AddPipeline have no definition and are not listed.
+func (pl *PipelineLoader) Definitions() []Definition {
+	pl.mu.RLock()
+	defs := make([]Definition, 0, len(pl.defs))
+	for _, def := range pl.defs {
+		defs = append(defs, def)
+	}
+	pl.mu.RUnlock()
+	sort.Slice(defs, func(i, j int) bool { return defs[i].ID < defs[j].ID })
+	return defs
+}
+
+// Definition returns the definition of a pipeline
+func (pl *PipelineLoader) Definition(id string) (Definition, bool) {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	def, ok := pl.defs[id]
+	return def, ok
+}
+
+// SetDefinitionsDir sets the directory holding pipelines defined at
+// runtime, one <id>.yaml file each, creating it if needed, and loads the
+// definitions in it. They override pipelines of the same ID in the
+// pipelines config file.
+func (pl *PipelineLoader) SetDefinitionsDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create pipeline definitions directory: %w", err)
+	}
+	defs, err := readDefinitionsDir(dir)
+	if err != nil {
+		return err
+	}
+	pipelines := make(map[string]*Pipeline, len(defs))
+	for _, def := range defs {
+		pipeline, err := pl.Validate(def)
+		if err != nil {
+			return fmt.Errorf("pipeline definition %s: %w", def.ID, err)
+		}
+		pipelines[def.ID] = pipeline
+	}
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.definitionsDir = dir
+	for _, def := range defs {
+		pl.pipelines[def.ID] = pipelines[def.ID]
+		pl.defs[def.ID] = Definition{PipelineYAML: def, Source: SourceDefinitionsDir}
+	}
+	return nil
+}
+
+// Reload re-reads the pipelines config file and the definitions directory
+// and swaps in the result. On error the pipelines in use are kept.
+// Pipelines added with AddPipeline survive unless a definition replaces
+// them.
+func (pl *PipelineLoader) Reload() error {
+	pl.mu.RLock()
+	configFile, dir := pl.configFile, pl.definitionsDir
+	pl.mu.RUnlock()
+
+	pipelines := make(map[string]*Pipeline)
+	defs := make(map[string]Definition)
+	fileDefs := make(map[string]PipelineYAML)
+	if configFile != "" {
+		fromFile, err := readPipelineConfig(configFile)
+		if err != nil {
+			return err
+		}
+		for _, def := range fromFile {
+			pipeline, err := pl.convertYAMLToPipeline(def)
+			if err != nil {
+				return fmt.Errorf("failed to convert pipeline %s: %w", def.ID, err)
+			}
+			pipelines[def.ID] = pipeline
+			defs[def.ID] = Definition{PipelineYAML: def, Source: SourceConfigFile}
+			fileDefs[def.ID] = def
+		}
+	}
+	if dir != "" {
+		fromDir, err := readDefinitionsDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, def := range fromDir {
+			pipeline, err := pl.Validate(def)
+			if err != nil {
+				return fmt.Errorf("pipeline definition %s: %w", def.ID, err)
+			}
+			pipelines[def.ID] = pipeline
+			defs[def.ID] = Definition{PipelineYAML: def, Source: SourceDefinitionsDir}
+		}
+	}
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	for id, pipeline := range pl.pipelines {
+		if _, defined := pl.defs[id]; !defined {
+			if _, replaced := pipelines[id]; !replaced {
+				pipelines[id] = pipeline
+			}
+		}
+	}
+	pl.pipelines, pl.defs, pl.fileDefs = pipelines, defs, fileDefs
+	return nil
+}
+
+// Create adds a pipeline definition, saving it to the definitions directory
+func (pl *PipelineLoader) Create(def PipelineYAML) (Definition, error) {
+	return pl.save(def, func(exists bool) error {
+		if exists {
+			return fmt.Errorf("%w: %s", ErrPipelineExists, def.ID)
+		}
+		return nil
+	})
+}
+
+// Update replaces a pipeline's definition, saving it to the definitions
+// directory. Updating a pipeline from the pipelines config file overrides
+// it there.
+func (pl *PipelineLoader) Update(def PipelineYAML) (Definition, error) {
+	return pl.save(def, func(exists bool) error {
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrPipelineNotFound, def.ID)
+		}
+		return nil
+	})
+}
+
+// save validates and writes a definition once check approves of whether a
+// pipeline with its ID exists
+func (pl *PipelineLoader) save(def PipelineYAML, check func(exists bool) error) (Definition, error) {
+	pipeline, err := pl.Validate(def)
+	if err != nil {
+		return Definition{}, err
+	}
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if pl.definitionsDir == "" {
+		return Definition{}, ErrNoDefinitionsDir
+	}
+	_, exists := pl.defs[def.ID]
+	if err := check(exists); err != nil {
+		return Definition{}, err
+	}
+	if err := writeDefinition(pl.definitionsPath(def.ID), def); err != nil {
+		return Definition{}, err
+	}
+	saved := Definition{PipelineYAML: def, Source: SourceDefinitionsDir}
+	pl.pipelines[def.ID] = pipeline
+	pl.defs[def.ID] = saved
+	return saved, nil
+}
+
+// Delete removes a pipeline defined at runtime. A pipeline that overrode
+// one in the pipelines config file falls back to the file's definition;
+// one defined only in the config file cannot be deleted.
+func (pl *PipelineLoader) Delete(id string) error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	def, ok := pl.defs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPipelineNotFound, id)
+	}
+	if def.Source == SourceConfigFile {
+		return fmt.Errorf("%w: %s", ErrPipelineConfigured, id)
+	}
+	if err := os.Remove(pl.definitionsPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete pipeline definition: %w", err)
+	}
+
+	if fileDef, ok := pl.fileDefs[id]; ok {
+		if pipeline, err := pl.convertYAMLToPipeline(fileDef); err == nil {
+			pl.pipelines[id] = pipeline
+			pl.defs[id] = Definition{PipelineYAML: fileDef, Source: SourceConfigFile}
+			return nil
+		}
+	}
+	delete(pl.pipelines, id)
+	delete(pl.defs, id)
+	return nil
+}
+
+// definitionsPath is the file holding a runtime pipeline definition
+func (pl *PipelineLoader) definitionsPath(id string) string {
+	return filepath.Join(pl.definitionsDir, id+".yaml")
+}
+
+// readDefinitionsDir reads the pipeline definitions in a directory, one per
+// <id>.yaml file. A definition without an ID takes the file's name.
+func readDefinitionsDir(dir string) ([]PipelineYAML, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline definitions directory: %w", err)
+	}
+	var defs []PipelineYAML
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pipeline definition: %w", err)
+		}
+		var def PipelineYAML
+		if err := yaml.Unmarshal(data, &def); err != nil {
+			return nil, fmt.Errorf("failed to parse pipeline definition %s: %w", entry.Name(), err)
+		}
+		id := strings.TrimSuffix(entry.Name(), ".yaml")
+		if def.ID == "" {
+			def.ID = id
+		} else if def.ID != id {
+			return nil, fmt.Errorf("pipeline definition %s has id %s; it must be saved as %s.yaml", entry.Name(), def.ID, def.ID)
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// writeDefinition saves a definition through a temporary file, so a crash
+// or a watcher never sees it half written
+func writeDefinition(path string, def PipelineYAML) error {
+	data, err := yaml.Marshal(def)
+	if err != nil {
+		return fmt.Errorf("failed to encode pipeline definition: %w", err)
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write pipeline definition: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write pipeline definition: %w", err)
+	}
+	return nil
+}
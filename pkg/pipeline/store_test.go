@@ -0,0 +1,270 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPipelinesConfig = `pipelines:
+  - id: voice
+    name: Voice
+    stages:
+      - id: stt
+        type: audio_to_text
+      - id: llm
+        type: text_generation
+`
+
+func textPipeline(id string) PipelineYAML {
+	return PipelineYAML{ID: id, Name: id, Stages: []StageYAML{{ID: "llm", Type: "text_generation"}}}
+}
+
+// newTestStore loads testPipelinesConfig and a definitions directory
+func newTestStore(t *testing.T) (*PipelineLoader, string, string) {
+	t.Helper()
+	root := t.TempDir()
+	configFile := filepath.Join(root, "pipelines.yaml")
+	if err := os.WriteFile(configFile, []byte(testPipelinesConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "pipelines.d")
+	pl := NewPipelineLoader()
+	if err := pl.LoadFromFile(configFile); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if err := pl.SetDefinitionsDir(dir); err != nil {
+		t.Fatalf("SetDefinitionsDir failed: %v", err)
+	}
+	return pl, configFile, dir
+}
+
+func TestValidate(t *testing.T) {
+	pl := NewPipelineLoader()
+	tests := []struct {
+		name string
+		def  PipelineYAML
+	}{
+		{"bad id", PipelineYAML{ID: "../etc", Stages: []StageYAML{{ID: "a", Type: "custom"}}}},
+		{"no stages", PipelineYAML{ID: "empty"}},
+		{"stage without id", PipelineYAML{ID: "p", Stages: []StageYAML{{Type: "custom"}}}},
+		{"duplicate stage", PipelineYAML{ID: "p", Stages: []StageYAML{{ID: "a", Type: "custom"}, {ID: "a", Type: "custom"}}}},
+		{"unknown type", PipelineYAML{ID: "p", Stages: []StageYAML{{ID: "a", Type: "telepathy"}}}},
+		{"bad sampling", PipelineYAML{ID: "p", Stages: []StageYAML{{ID: "a", Type: "video_to_text", FrameSampling: &FrameSamplingYAML{Strategy: "random"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := pl.Validate(tt.def); !errors.Is(err, ErrInvalidPipeline) {
+				t.Errorf("Expected ErrInvalidPipeline, got %v", err)
+			}
+		})
+	}
+
+	p, err := pl.Validate(textPipeline("chat"))
+	if err != nil || len(p.Stages) != 1 {
+		t.Errorf("Expected a valid pipeline, got %+v, %v", p, err)
+	}
+}
+
+func TestPipelineStore(t *testing.T) {
+	pl, _, dir := newTestStore(t)
+
+	if _, err := pl.Create(textPipeline("voice")); !errors.Is(err, ErrPipelineExists) {
+		t.Errorf("Expected ErrPipelineExists, got %v", err)
+	}
+	if _, err := pl.Update(textPipeline("missing")); !errors.Is(err, ErrPipelineNotFound) {
+		t.Errorf("Expected ErrPipelineNotFound, got %v", err)
+	}
+	if err := pl.Delete("voice"); !errors.Is(err, ErrPipelineConfigured) {
+		t.Errorf("Expected ErrPipelineConfigured deleting a config file pipeline, got %v", err)
+	}
+
+	def, err := pl.Create(textPipeline("chat"))
+	if err != nil || def.Source != SourceDefinitionsDir {
+		t.Fatalf("Create failed: %+v, %v", def, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "chat.yaml")); err != nil {
+		t.Errorf("Expected the definition saved, got %v", err)
+	}
+	if _, err := pl.GetPipeline("chat"); err != nil {
+		t.Errorf("Expected the pipeline usable at once, got %v", err)
+	}
+
+	// Updating a config file pipeline overrides it, and deleting the
+	// override brings the file's definition back
+	if _, err := pl.Update(textPipeline("voice")); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if p, _ := pl.GetPipeline("voice"); len(p.Stages) != 1 {
+		t.Errorf("Expected the override in use, got %d stages", len(p.Stages))
+	}
+	if err := pl.Delete("voice"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if def, _ := pl.Definition("voice"); def.Source != SourceConfigFile || len(def.Stages) != 2 {
+		t.Errorf("Expected the config file definition back, got %+v", def)
+	}
+
+	// Definitions survive a restart
+	restarted := NewPipelineLoader()
+	if err := restarted.SetDefinitionsDir(dir); err != nil {
+		t.Fatalf("SetDefinitionsDir failed: %v", err)
+	}
+	if defs := restarted.Definitions(); len(defs) != 1 || defs[0].ID != "chat" {
+		t.Errorf("Expected chat to be restored, got %+v", defs)
+	}
+
+	if err := pl.Delete("chat"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := pl.GetPipeline("chat"); !errors.Is(err, ErrPipelineNotFound) {
+		t.Errorf("Expected chat to be gone, got %v", err)
+	}
+
+	if _, err := NewPipelineLoader().Create(textPipeline("chat")); !errors.Is(err, ErrNoDefinitionsDir) {
+		t.Errorf("Expected ErrNoDefinitionsDir, got %v", err)
+	}
+}
+
+func TestPipelineStore_Reload(t *testing.T) {
+	pl, configFile, dir := newTestStore(t)
+	pl.AddPipeline(&Pipeline{ID: "builtin"})
+
+	if err := os.WriteFile(filepath.Join(dir, "chat.yaml"), []byte("name: Chat\nstages:\n  - id: llm\n    type: text_generation\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configFile, []byte("pipelines: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	ids := pl.ListPipelines()
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "builtin,chat" {
+		t.Errorf("Expected builtin and chat after reload, got %s", ids)
+	}
+
+	// A broken definition keeps the pipelines in use
+	if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("stages: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pl.Reload(); err == nil {
+		t.Error("Expected a reload error for an invalid definition")
+	}
+	if _, err := pl.GetPipeline("chat"); err != nil {
+		t.Errorf("Expected chat to be kept, got %v", err)
+	}
+}
+
+func TestPipelineStore_Watch(t *testing.T) {
+	pl, configFile, _ := newTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloaded := make(chan error, 10)
+	done := make(chan error, 1)
+	go func() { done <- pl.Watch(ctx, func(err error) { reloaded <- err }) }()
+	time.Sleep(50 * time.Millisecond)
+
+	config := testPipelinesConfig + "  - id: summary\n    stages:\n      - id: llm\n        type: text_generation\n"
+	if err := os.WriteFile(configFile, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a reload after the config file changed")
+	}
+	if _, err := pl.GetPipeline("summary"); err != nil {
+		t.Errorf("Expected the new pipeline after reload, got %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Watch to stop cleanly, got %v", err)
+	}
+}
+
+func TestPipelineHandler(t *testing.T) {
+	pl, _, _ := newTestStore(t)
+	handler := pl.Handler()
+	call := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	w := call("POST", "/admin/pipelines", `{"id":"chat","stages":[{"id":"llm","type":"text_generation"}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", w.Code, w.Body)
+	}
+	if w := call("POST", "/admin/pipelines", `{"id":"chat","stages":[{"id":"llm","type":"text_generation"}]}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 creating twice, got %d", w.Code)
+	}
+	if w := call("POST", "/admin/pipelines", `{"id":"typo","stagez":[]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown field, got %d", w.Code)
+	}
+	if w := call("POST", "/admin/pipelines", `{"id":"bad","stages":[{"id":"x","type":"nope"}]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid definition, got %d", w.Code)
+	}
+
+	// YAML works too
+	w = call("PUT", "/admin/pipelines?id=chat", "stages:\n  - id: stt\n    type: audio_to_text\n  - id: llm\n    type: text_generation\n")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 updating, got %d %s", w.Code, w.Body)
+	}
+	w = call("GET", "/admin/pipelines?id=chat", "")
+	var def Definition
+	json.NewDecoder(w.Body).Decode(&def)
+	if def.ID != "chat" || len(def.Stages) != 2 || def.Source != SourceDefinitionsDir {
+		t.Errorf("Unexpected definition: %+v", def)
+	}
+
+	w = call("GET", "/admin/pipelines", "")
+	var list struct {
+		Pipelines []Definition `json:"pipelines"`
+	}
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.Pipelines) != 2 || list.Pipelines[0].ID != "chat" || list.Pipelines[1].ID != "voice" {
+		t.Errorf("Unexpected pipelines: %+v", list.Pipelines)
+	}
+
+	if w := call("DELETE", "/admin/pipelines?id=voice", ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 deleting a config file pipeline, got %d", w.Code)
+	}
+	if w := call("DELETE", "/admin/pipelines?id=chat", ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", w.Code)
+	}
+	if w := call("GET", "/admin/pipelines?id=chat", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", w.Code)
+	}
+
+	validate := func(body string) validateResponse {
+		w := httptest.NewRecorder()
+		pl.ValidateHandler().ServeHTTP(w, httptest.NewRequest("POST", "/admin/pipelines/validate", strings.NewReader(body)))
+		var resp validateResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+	if resp := validate(`{"id":"ok","stages":[{"id":"llm","type":"text_generation"}]}`); !resp.Valid || resp.Stages != 1 {
+		t.Errorf("Expected a valid definition, got %+v", resp)
+	}
+	if resp := validate(`{"id":"ok","stages":[]}`); resp.Valid || resp.Error == "" {
+		t.Errorf("Expected an invalid definition, got %+v", resp)
+	}
+	if _, ok := pl.Definition("ok"); ok {
+		t.Error("Expected validation not to save the definition")
+	}
+}
@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+)
+
+// Tool limits applied when a tool leaves them unset
+const (
+	DefaultToolTimeout        = 30 * time.Second
+	DefaultToolMaxOutputBytes = 1 << 20
+)
+
+// Tool is a local command or webhook that tool stages call with their
+// input. Only tools configured on the executor can be called, so pipeline
+// definitions cannot run arbitrary programs.
+type Tool interface {
+	Call(ctx context.Context, input []byte) ([]byte, error)
+}
+
+// CommandTool runs a program with the input on stdin and returns its
+// stdout. It runs without a shell, with only Env as its environment, in a
+// fresh temporary directory unless Dir is set, and is killed when it
+// exceeds its timeout or output limit.
+type CommandTool struct {
+	Command        []string // program and arguments
+	Env            []string // KEY=value, the whole environment
+	Dir            string
+	Timeout        time.Duration
+	MaxOutputBytes int64
+}
+
+// Call runs the command
+func (t *CommandTool) Call(ctx context.Context, input []byte) ([]byte, error) {
+	if len(t.Command) == 0 {
+		return nil, errors.New("tool has no command")
+	}
+	ctx, cancel := context.WithTimeout(ctx, toolTimeout(t.Timeout))
+	defer cancel()
+
+	dir := t.Dir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "pipeline-tool-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tool directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	cmd := exec.CommandContext(ctx, t.Command[0], t.Command[1:]...)
+	cmd.Env = t.Env
+	if cmd.Env == nil {
+		cmd.Env = []string{}
+	}
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	stdout := &limitedBuffer{max: toolMaxOutput(t.MaxOutputBytes), overflow: cancel}
+	stderr := &limitedBuffer{max: 4096}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Children that keep the output open must not hold the stage up
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	switch {
+	case stdout.exceeded:
+		return nil, fmt.Errorf("tool output exceeds %d bytes", stdout.max)
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("tool timed out after %s", toolTimeout(t.Timeout))
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.buf.String()); msg != "" {
+			return nil, fmt.Errorf("tool failed: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("tool failed: %w", err)
+	}
+	return stdout.buf.Bytes(), nil
+}
+
+// HTTPTool POSTs the input to a webhook and returns the response body
+type HTTPTool struct {
+	URL            string
+	Headers        map[string]string
+	Timeout        time.Duration
+	MaxOutputBytes int64
+
+	// Client sends the requests (nil = a client carrying request IDs)
+	Client *http.Client
+}
+
+var defaultToolClient = &http.Client{Transport: middleware.RequestIDTransport(nil)}
+
+// Call sends the input to the webhook
+func (t *HTTPTool) Call(ctx context.Context, input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, toolTimeout(t.Timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(input))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tool request: %w", err)
+	}
+	if json.Valid(input) {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	for name, value := range t.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := t.Client
+	if client == nil {
+		client = defaultToolClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("tool timed out after %s", toolTimeout(t.Timeout))
+		}
+		return nil, fmt.Errorf("tool request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	max := toolMaxOutput(t.MaxOutputBytes)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(body) > 200 {
+			body = body[:200]
+		}
+		return nil, fmt.Errorf("tool returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if int64(len(body)) > max {
+		return nil, fmt.Errorf("tool output exceeds %d bytes", max)
+	}
+	return body, nil
+}
+
+// SetTools sets the tools tool stages may call, by name
+func (pe *PipelineExecutor) SetTools(tools map[string]Tool) {
+	pe.tools = tools
+}
+
+// callTool runs a tool stage's tool on its input. Text and binary inputs
+// are passed as they are, anything else as JSON; output that is valid
+// UTF-8 becomes text for the next stage.
+func (pe *PipelineExecutor) callTool(ctx context.Context, stage *Stage, input interface{}) (interface{}, error) {
+	tool, ok := pe.tools[stage.Tool]
+	if !ok {
+		return nil, fmt.Errorf("tool %q is not configured", stage.Tool)
+	}
+
+	var data []byte
+	switch v := input.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case nil:
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("tool input cannot be encoded: %w", err)
+		}
+		data = encoded
+	}
+
+	output, err := tool.Call(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.Valid(output) {
+		return string(output), nil
+	}
+	return output, nil
+}
+
+func toolTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return DefaultToolTimeout
+	}
+	return d
+}
+
+func toolMaxOutput(n int64) int64 {
+	if n <= 0 {
+		return DefaultToolMaxOutputBytes
+	}
+	return n
+}
+
+// limitedBuffer keeps up to max bytes. Output beyond that is dropped,
+// and overflow, if set, is called once.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int64
+	exceeded bool
+	overflow func()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - int64(b.buf.Len()); int64(len(p)) > room {
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		if !b.exceeded {
+			b.exceeded = true
+			if b.overflow != nil {
+				b.overflow()
+			}
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
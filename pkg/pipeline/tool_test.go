@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestCommandTool(t *testing.T) {
+	ctx := context.Background()
+
+	cat := &CommandTool{Command: []string{"/bin/cat"}}
+	if out, err := cat.Call(ctx, []byte("hello")); err != nil || string(out) != "hello" {
+		t.Errorf("Expected the input echoed, got %q, %v", out, err)
+	}
+
+	// Only the configured environment reaches the command
+	env := &CommandTool{Command: []string{"/bin/sh", "-c", "echo \"$TOOL_MODE:$HOME\""}, Env: []string{"TOOL_MODE=strict"}}
+	if out, err := env.Call(ctx, nil); err != nil || strings.TrimSpace(string(out)) != "strict:" {
+		t.Errorf("Expected only TOOL_MODE set, got %q, %v", out, err)
+	}
+
+	slow := &CommandTool{Command: []string{"/bin/sh", "-c", "sleep 5"}, Timeout: 100 * time.Millisecond}
+	start := time.Now()
+	if _, err := slow.Call(ctx, nil); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("Expected the command to be killed at its timeout")
+	}
+
+	chatty := &CommandTool{Command: []string{"/bin/cat"}, MaxOutputBytes: 4}
+	if _, err := chatty.Call(ctx, []byte("too much output")); err == nil || !strings.Contains(err.Error(), "exceeds 4 bytes") {
+		t.Errorf("Expected the output limit to be enforced, got %v", err)
+	}
+
+	failing := &CommandTool{Command: []string{"/bin/sh", "-c", "echo bad input >&2; exit 3"}}
+	if _, err := failing.Call(ctx, nil); err == nil || !strings.Contains(err.Error(), "bad input") {
+		t.Errorf("Expected stderr in the error, got %v", err)
+	}
+}
+
+func TestHTTPTool(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	tool := &HTTPTool{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}
+	out, err := tool.Call(ctx, []byte(`{"q":"go"}`))
+	if err != nil || string(out) != `application/json {"q":"go"}` {
+		t.Errorf("Expected the JSON input echoed, got %q, %v", out, err)
+	}
+
+	tool.MaxOutputBytes = 8
+	if _, err := tool.Call(ctx, []byte("plain text")); err == nil || !strings.Contains(err.Error(), "exceeds 8 bytes") {
+		t.Errorf("Expected the output limit to be enforced, got %v", err)
+	}
+
+	denied := &HTTPTool{URL: srv.URL}
+	if _, err := denied.Call(ctx, []byte("x")); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected the status in the error, got %v", err)
+	}
+}
+
+func TestExecuteToolStage(t *testing.T) {
+	pe := NewPipelineExecutor([]backends.Backend{NewMockBackend("llm")})
+	pe.SetTools(map[string]Tool{"upper": &CommandTool{Command: []string{"/bin/sh", "-c", "tr a-z A-Z"}}})
+
+	p := &Pipeline{
+		ID: "tooled",
+		Stages: []*Stage{
+			{ID: "shout", Type: StageTypeTool, Tool: "upper"},
+			{ID: "answer", Type: StageTypeTextGen},
+		},
+		Options: &PipelineOptions{},
+	}
+	result, err := pe.Execute(context.Background(), p, "hi")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.FinalOutput != "Mock response to: HI" {
+		t.Errorf("Expected the tool output passed on, got %v", result.FinalOutput)
+	}
+
+	p.Stages[0].Tool = "rm"
+	if _, err := pe.Execute(context.Background(), p, "hi"); err == nil || !strings.Contains(err.Error(), `"rm" is not configured`) {
+		t.Errorf("Expected an unconfigured tool to fail, got %v", err)
+	}
+
+	if _, err := NewPipelineLoader().Validate(PipelineYAML{ID: "p", Stages: []StageYAML{{ID: "t", Type: "tool"}}}); err == nil || !strings.Contains(err.Error(), "need a tool") {
+		t.Errorf("Expected a tool stage without a tool to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce lets a burst of file events, such as an editor's save,
+// settle into a single reload
+const reloadDebounce = 250 * time.Millisecond
+
+// Watch reloads the pipelines whenever the pipelines config file or a
+// definition in the definitions directory changes, until ctx is done.
+// onReload is called with the result of each reload, or with a watcher
+// error. Watch returns once ctx is done, or at once if the files cannot be
+// watched.
+func (pl *PipelineLoader) Watch(ctx context.Context, onReload func(error)) error {
+	pl.mu.RLock()
+	configFile, dir := pl.configFile, pl.definitionsDir
+	pl.mu.RUnlock()
+
+	// Editors often replace a file rather than write it, so the config
+	// file's directory is watched rather than the file itself
+	var dirs []string
+	if configFile != "" {
+		configFile = filepath.Clean(configFile)
+		dirs = append(dirs, filepath.Dir(configFile))
+	}
+	if dir != "" {
+		dir = filepath.Clean(dir)
+		dirs = append(dirs, dir)
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch pipelines: %w", err)
+	}
+	defer watcher.Close()
+
+	for i, d := range dirs {
+		if i > 0 && d == dirs[0] {
+			continue
+		}
+		if err := watcher.Add(d); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", d, err)
+		}
+	}
+
+	relevant := func(name string) bool {
+		name = filepath.Clean(name)
+		if configFile != "" && name == configFile {
+			return true
+		}
+		return dir != "" && filepath.Dir(name) == dir && filepath.Ext(name) == ".yaml"
+	}
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op != fsnotify.Chmod && relevant(event.Name) {
+				pending = time.After(reloadDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if onReload != nil {
+				onReload(fmt.Errorf("watching pipelines: %w", err))
+			}
+		case <-pending:
+			pending = nil
+			err := pl.Reload()
+			if onReload != nil {
+				onReload(err)
+			}
+		}
+	}
+}
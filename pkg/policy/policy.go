@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/langdetect"
 )
 
 // UserTier defines user access level
@@ -49,11 +51,19 @@ type Policy struct {
 	// Time-based policies
 	quietHours      bool // 10pm-6am
 	peakHours       bool // 9am-5pm weekdays
+
+	// Language policies: backendID -> prompt languages it may serve
+	backendLanguages map[string][]string
+
+	// Label policies: "label=value" -> backends its requests may use
+	labelBackends map[string][]string
 }
 
 func NewPolicy() *Policy {
 	return &Policy{
-		budgets: make(map[string]*PowerBudget),
+		budgets:          make(map[string]*PowerBudget),
+		backendLanguages: make(map[string][]string),
+		labelBackends:    make(map[string][]string),
 	}
 }
 
@@ -193,6 +203,71 @@ func (p *Policy) MaxAllowedPower() int {
 	}
 }
 
+// SetBackendLanguages restricts a backend to the given prompt languages
+// (ISO 639-1). An empty list removes the restriction.
+func (p *Policy) SetBackendLanguages(backendID string, languages []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(languages) == 0 {
+		delete(p.backendLanguages, backendID)
+		return
+	}
+	p.backendLanguages[backendID] = languages
+}
+
+// CheckLanguage checks whether a backend may serve a prompt in the given
+// language. Unknown languages ("") are always allowed.
+func (p *Policy) CheckLanguage(backendID, language string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	languages, restricted := p.backendLanguages[backendID]
+	if !restricted || language == "" || langdetect.Matches(language, languages) {
+		return nil
+	}
+	return fmt.Errorf("backend %s does not serve language %q (supports %v)", backendID, language, languages)
+}
+
+// SetLabelBackends restricts requests labelled label=value (X-Labels) to
+// the given backends. An empty list removes the restriction.
+func (p *Policy) SetLabelBackends(label, value string, backendIDs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rule := label + "=" + value
+	if len(backendIDs) == 0 {
+		delete(p.labelBackends, rule)
+		return
+	}
+	p.labelBackends[rule] = backendIDs
+}
+
+// CheckLabels checks whether a backend may serve a request with the given
+// labels. Every label with a restriction must allow the backend.
+func (p *Policy) CheckLabels(backendID string, labels map[string]string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for label, value := range labels {
+		allowed, restricted := p.labelBackends[label+"="+value]
+		if !restricted {
+			continue
+		}
+		permitted := false
+		for _, id := range allowed {
+			if id == backendID {
+				permitted = true
+				break
+			}
+		}
+		if !permitted {
+			return fmt.Errorf("requests labelled %s=%s may not use backend %s (allowed %v)", label, value, backendID, allowed)
+		}
+	}
+	return nil
+}
+
 // ShouldThrottle checks if we should throttle high-power backends
 func (p *Policy) ShouldThrottle() bool {
 	p.mu.RLock()
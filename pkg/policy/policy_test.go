@@ -1017,3 +1017,49 @@ func TestCheckAndDeduct_MultipleHourlyResets(t *testing.T) {
 		t.Errorf("Hourly reset not working properly: first=%d, second=%d", firstCount, secondCount)
 	}
 }
+
+func TestCheckLanguage(t *testing.T) {
+	p := NewPolicy()
+	p.SetBackendLanguages("ollama-npu", []string{"en"})
+
+	if err := p.CheckLanguage("ollama-npu", "en"); err != nil {
+		t.Errorf("Expected English allowed on NPU, got %v", err)
+	}
+	if err := p.CheckLanguage("ollama-npu", "ja"); err == nil {
+		t.Error("Expected Japanese rejected on English-only NPU")
+	}
+	if err := p.CheckLanguage("ollama-npu", ""); err != nil {
+		t.Errorf("Unknown language should be allowed, got %v", err)
+	}
+	if err := p.CheckLanguage("ollama-nvidia", "ja"); err != nil {
+		t.Errorf("Unrestricted backend should allow any language, got %v", err)
+	}
+
+	p.SetBackendLanguages("ollama-npu", nil)
+	if err := p.CheckLanguage("ollama-npu", "ja"); err != nil {
+		t.Errorf("Expected restriction removed, got %v", err)
+	}
+}
+
+func TestCheckLabels(t *testing.T) {
+	p := NewPolicy()
+	p.SetLabelBackends("team", "ml", []string{"ollama-nvidia", "ollama-igpu"})
+
+	if err := p.CheckLabels("ollama-nvidia", map[string]string{"team": "ml", "app": "docsbot"}); err != nil {
+		t.Errorf("Expected team=ml allowed on NVIDIA, got %v", err)
+	}
+	if err := p.CheckLabels("ollama-npu", map[string]string{"team": "ml"}); err == nil {
+		t.Error("Expected team=ml rejected on NPU")
+	}
+	if err := p.CheckLabels("ollama-npu", map[string]string{"team": "search"}); err != nil {
+		t.Errorf("Other values should be unrestricted, got %v", err)
+	}
+	if err := p.CheckLabels("ollama-npu", nil); err != nil {
+		t.Errorf("Unlabelled requests should be unrestricted, got %v", err)
+	}
+
+	p.SetLabelBackends("team", "ml", nil)
+	if err := p.CheckLabels("ollama-npu", map[string]string{"team": "ml"}); err != nil {
+		t.Errorf("Expected restriction removed, got %v", err)
+	}
+}
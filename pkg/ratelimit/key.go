@@ -0,0 +1,271 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"golang.org/x/time/rate"
+)
+
+// KeyRateLimiter limits authenticated requests per API key, with separate
+// budgets for streaming and non-streaming requests. Requests without a key
+// budget (unauthenticated, or no per-key limit configured) fall back to
+// the per-IP limiter.
+type KeyRateLimiter struct {
+	defaults auth.RateLimit // for keys without their own limit
+	fallback *IPRateLimiter // nil = unlimited
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter // key ID + budget -> bucket
+	lastSeen map[string]time.Time
+
+	cleanupInterval time.Duration
+	expiryDuration  time.Duration
+}
+
+// NewKeyRateLimiter creates a per-API-key rate limiter. defaults applies to
+// keys without a RateLimit of their own; a zero Rate leaves them to
+// fallback.
+func NewKeyRateLimiter(defaults auth.RateLimit, fallback *IPRateLimiter) *KeyRateLimiter {
+	kl := &KeyRateLimiter{
+		defaults:        defaults,
+		fallback:        fallback,
+		limiters:        make(map[string]*rate.Limiter),
+		lastSeen:        make(map[string]time.Time),
+		cleanupInterval: 5 * time.Minute,
+		expiryDuration:  10 * time.Minute,
+	}
+
+	go kl.cleanupLoop()
+
+	return kl
+}
+
+// budget picks the bucket a request draws from. The name keys the bucket;
+// ok is false when the key has no budget.
+func budget(limit auth.RateLimit, streaming bool) (name string, r rate.Limit, burst int, ok bool) {
+	name, r, burst = "key", rate.Limit(limit.Rate), limit.Burst
+	if streaming && limit.StreamRate > 0 {
+		name, r, burst = "key_stream", rate.Limit(limit.StreamRate), limit.StreamBurst
+	}
+	if r <= 0 {
+		return "", 0, 0, false
+	}
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(float64(r))))
+	}
+	return name, r, burst, true
+}
+
+// Reserve takes a token from key's budget. When the budget is spent it
+// returns false and how long until a token is available.
+func (kl *KeyRateLimiter) Reserve(key string, limit auth.RateLimit, streaming bool) (bool, time.Duration) {
+	name, r, burst, ok := budget(limit, streaming)
+	if !ok {
+		return true, 0
+	}
+
+	id := key + "\x00" + name
+	kl.mu.Lock()
+	limiter, exists := kl.limiters[id]
+	if !exists {
+		limiter = rate.NewLimiter(r, burst)
+		kl.limiters[id] = limiter
+	}
+	kl.lastSeen[id] = time.Now()
+	kl.mu.Unlock()
+
+	return reserve(limiter)
+}
+
+// limitFor returns the budget of an authenticated key, if it has one
+func (kl *KeyRateLimiter) limitFor(info auth.APIKeyInfo) (auth.RateLimit, bool) {
+	limit := kl.defaults
+	if info.RateLimit != nil {
+		limit = *info.RateLimit
+	}
+	return limit, limit.Rate > 0 || limit.StreamRate > 0
+}
+
+// Middleware creates an HTTP middleware for per-key rate limiting. It must
+// run after authentication so the key is known.
+func (kl *KeyRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, authenticated := auth.KeyInfoFromContext(r.Context())
+		limit, hasBudget := kl.limitFor(info)
+		if !authenticated || !hasBudget {
+			if kl.fallback == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			kl.fallback.Middleware(next).ServeHTTP(w, r)
+			return
+		}
+
+		streaming := IsStreaming(r)
+		if ok, retryAfter := kl.Reserve(info.ClientID(), limit, streaming); !ok {
+			name, _, _, _ := budget(limit, streaming)
+			metrics.RecordRateLimited(name)
+			writeRateLimited(w, retryAfter)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cleanupLoop periodically removes stale limiters
+func (kl *KeyRateLimiter) cleanupLoop() {
+	ticker := time.NewTicker(kl.cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		middleware.Safe(middleware.ScopeBackground, "ratelimit-key-cleanup", kl.cleanup)
+	}
+}
+
+// cleanup removes limiters that haven't been accessed recently
+func (kl *KeyRateLimiter) cleanup() {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	now := time.Now()
+	for id, lastSeen := range kl.lastSeen {
+		if now.Sub(lastSeen) > kl.expiryDuration {
+			delete(kl.limiters, id)
+			delete(kl.lastSeen, id)
+		}
+	}
+}
+
+// streamingPaths always stream
+var streamingPaths = []string{"/v1/stream/"}
+
+// ollamaStreamingPaths stream unless the body sets "stream": false
+var ollamaStreamingPaths = map[string]bool{
+	"/api/generate": true,
+	"/api/chat":     true,
+}
+
+// maxPeekBytes bounds how much of a body IsStreaming reads looking for
+// "stream"; a body that sets it later is counted as non-streaming
+const maxPeekBytes = 64 << 10
+
+// peekedBody replays the peeked bytes before the rest of the body
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// IsStreaming reports whether a request asks for a streamed response: a
+// WebSocket upgrade, a streaming-only path, or a JSON body with "stream"
+// set (Ollama's generate and chat stream by default). The body is read and
+// restored for the handler.
+func IsStreaming(r *http.Request) bool {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return true
+	}
+	for _, prefix := range streamingPaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	contentType := r.Header.Get("Content-Type")
+	if r.Body == nil || r.Method != http.MethodPost || (contentType != "" && !strings.Contains(contentType, "json")) {
+		return false
+	}
+
+	// Read only as far as the top-level "stream", and at most maxPeekBytes
+	var peeked bytes.Buffer
+	stream, err := streamField(io.TeeReader(io.LimitReader(r.Body, maxPeekBytes), &peeked))
+	r.Body = peekedBody{Reader: io.MultiReader(&peeked, r.Body), Closer: r.Body}
+	if err != nil {
+		return false
+	}
+	if stream == nil {
+		return ollamaStreamingPaths[r.URL.Path]
+	}
+	return *stream
+}
+
+// streamField scans a JSON object for its top-level "stream" boolean,
+// without keeping the other values. It returns nil when there is none.
+func streamField(r io.Reader) (*bool, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key == "stream" {
+			var stream bool
+			if err := dec.Decode(&stream); err != nil {
+				return nil, err
+			}
+			return &stream, nil
+		}
+		if err := skipValue(dec); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// skipValue reads past the next value, token by token
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// reserve takes a token from limiter, or reports how long until one is
+// available without taking it
+func reserve(limiter *rate.Limiter) (bool, time.Duration) {
+	res := limiter.Reserve()
+	if !res.OK() {
+		return false, time.Second
+	}
+	delay := res.Delay()
+	if delay == 0 {
+		return true, 0
+	}
+	res.Cancel()
+	return false, delay
+}
+
+// writeRateLimited rejects a request with 429 and Retry-After in whole
+// seconds
+func writeRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+)
+
+// keyRequest builds a request authenticated as key name
+func keyRequest(name string, limit *auth.RateLimit, path, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "10.0.0.1:1234" // every client behind the same reverse proxy
+	if name != "" {
+		req = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: name, Enabled: true, RateLimit: limit}))
+	}
+	return req
+}
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestKeyRateLimiter_PerKey(t *testing.T) {
+	kl := NewKeyRateLimiter(auth.RateLimit{Rate: 1, Burst: 2}, NewIPRateLimiter(100, 100))
+	handler := kl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < 2; i++ {
+		if rec := serve(handler, keyRequest("alice", nil, "/v1/chat/completions", `{}`)); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+	rec := serve(handler, keyRequest("alice", nil, "/v1/chat/completions", `{}`))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected alice to be limited, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", got)
+	}
+
+	// Another key from the same IP has its own budget
+	if rec := serve(handler, keyRequest("bob", nil, "/v1/chat/completions", `{}`)); rec.Code != http.StatusOK {
+		t.Errorf("Expected bob to be allowed, got %d", rec.Code)
+	}
+
+	// So does another key with the same name
+	req := keyRequest("", nil, "/v1/chat/completions", `{}`)
+	req = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{ID: "other-alice", Name: "alice", Enabled: true}))
+	if rec := serve(handler, req); rec.Code != http.StatusOK {
+		t.Errorf("Expected a second key named alice to be allowed, got %d", rec.Code)
+	}
+}
+
+func TestKeyRateLimiter_StreamingBudget(t *testing.T) {
+	limit := &auth.RateLimit{Rate: 10, Burst: 10, StreamRate: 0.1, StreamBurst: 1}
+	kl := NewKeyRateLimiter(auth.RateLimit{}, nil)
+	handler := kl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	stream := `{"model":"llama3","stream":true}`
+	if rec := serve(handler, keyRequest("alice", limit, "/v1/chat/completions", stream)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected first stream to be allowed, got %d", rec.Code)
+	}
+	rec := serve(handler, keyRequest("alice", limit, "/v1/chat/completions", stream))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected second stream to be limited, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Expected Retry-After 10, got %q", got)
+	}
+
+	// Non-streaming requests still have budget
+	if rec := serve(handler, keyRequest("alice", limit, "/v1/chat/completions", `{"stream":false}`)); rec.Code != http.StatusOK {
+		t.Errorf("Expected non-streaming request to be allowed, got %d", rec.Code)
+	}
+}
+
+func TestKeyRateLimiter_Fallback(t *testing.T) {
+	kl := NewKeyRateLimiter(auth.RateLimit{}, NewIPRateLimiter(1, 1))
+	handler := kl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// Unauthenticated requests and keys without a budget share the IP limit
+	if rec := serve(handler, keyRequest("", nil, "/v1/models", `{}`)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected first request to be allowed, got %d", rec.Code)
+	}
+	rec := serve(handler, keyRequest("alice", nil, "/v1/models", `{}`))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected IP limit with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+
+	// A key with its own budget is not held to the IP limit
+	if rec := serve(handler, keyRequest("alice", &auth.RateLimit{Rate: 5}, "/v1/models", `{}`)); rec.Code != http.StatusOK {
+		t.Errorf("Expected key budget to apply, got %d", rec.Code)
+	}
+}
+
+func TestIsStreaming(t *testing.T) {
+	tests := []struct {
+		path string
+		body string
+		want bool
+	}{
+		{"/v1/chat/completions", `{"stream":true}`, true},
+		{"/v1/chat/completions", `{"model":"x"}`, false},
+		{"/api/generate", `{"model":"x"}`, true},
+		{"/api/chat", `{"stream":false}`, false},
+		{"/api/embeddings", `{"model":"x"}`, false},
+		{"/v1/stream/ws", ``, true},
+		{"/v1/completions", `not json`, false},
+		{"/v1/chat/completions", `{"messages":[{"role":"user","content":"hi","stream":false}],"stream":true}`, true},
+		{"/api/chat", `{"options":{"stream":true},"model":"x"}`, true},
+		{"/v1/chat/completions", `{"prompt":"` + strings.Repeat("x", maxPeekBytes) + `","stream":true}`, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		if got := IsStreaming(req); got != tt.want {
+			t.Errorf("IsStreaming(%s %s) = %v, want %v", tt.path, tt.body, got, tt.want)
+		}
+
+		// The handler still sees the whole body
+		if !strings.HasPrefix(tt.path, "/v1/stream/") {
+			data, _ := io.ReadAll(req.Body)
+			if string(data) != tt.body {
+				t.Errorf("Expected body to be restored, got %q", data)
+			}
+		}
+	}
+
+	// Multipart uploads are not read
+	req := httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", strings.NewReader(`{"stream":true}`))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	if IsStreaming(req) {
+		t.Error("Expected multipart request not to be inspected")
+	}
+}
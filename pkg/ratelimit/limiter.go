@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
 	"golang.org/x/time/rate"
 )
 
@@ -66,7 +68,7 @@ func (rl *IPRateLimiter) cleanupLoop() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		rl.cleanup()
+		middleware.Safe(middleware.ScopeBackground, "ratelimit-cleanup", rl.cleanup)
 	}
 }
 
@@ -89,8 +91,9 @@ func (rl *IPRateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ip := getIP(r)
 
-		if !rl.Allow(ip) {
-			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		if ok, retryAfter := reserve(rl.getLimiter(ip)); !ok {
+			metrics.RecordRateLimited("ip")
+			writeRateLimited(w, retryAfter)
 			return
 		}
 
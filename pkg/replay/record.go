@@ -0,0 +1,274 @@
+// Package replay records routing decisions and re-runs them against a
+// candidate configuration, so routing config edits can be checked against
+// real traffic before they are deployed.
+//
+// Records are anonymized: they hold the routing features of a request
+// (model, routing annotations, detected language) and the state of every
+// backend at decision time, never prompts, API keys, tenants or request IDs.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"go.uber.org/zap"
+)
+
+// Record is one anonymized routing decision
+type Record struct {
+	Time        time.Time      `json:"time"`
+	Request     Features       `json:"request"`
+	Constraints *Constraints   `json:"mode_constraints,omitempty"`
+	Backends    []BackendState `json:"backends"`
+	BackendID   string         `json:"backend_id,omitempty"` // "" when routing failed
+	Reason      string         `json:"reason,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// Features are the request properties routing depends on
+type Features struct {
+	Model                 string `json:"model,omitempty"`
+	Target                string `json:"target,omitempty"`
+	MediaType             string `json:"media_type,omitempty"`
+	Capability            string `json:"capability,omitempty"`
+	Language              string `json:"language,omitempty"`
+	Priority              int    `json:"priority"`
+	LatencyCritical       bool   `json:"latency_critical,omitempty"`
+	PreferPowerEfficiency bool   `json:"prefer_power_efficiency,omitempty"`
+	MaxLatencyMs          int32  `json:"max_latency_ms,omitempty"`
+	MaxPowerWatts         int32  `json:"max_power_watts,omitempty"`
+}
+
+// Constraints are the efficiency limits in force, e.g. a quiet window
+type Constraints struct {
+	Reason          string   `json:"reason"`
+	MaxPowerWatts   int32    `json:"max_power_watts,omitempty"`
+	AllowedBackends []string `json:"allowed_backends,omitempty"`
+}
+
+// BackendState is one backend's state at decision time
+type BackendState struct {
+	ID            string   `json:"id"`
+	Hardware      string   `json:"hardware,omitempty"`
+	Healthy       bool     `json:"healthy"`
+	AvgLatencyMs  int32    `json:"avg_latency_ms"`
+	InFlight      [4]int   `json:"in_flight"` // by priority, best-effort first
+	LatencyEWMAMs float64  `json:"latency_ewma_ms,omitempty"`
+	Capabilities  []string `json:"capabilities,omitempty"` // routed media operations supported
+	TemperatureC  float64  `json:"temperature_c,omitempty"`
+	Throttling    bool     `json:"throttling,omitempty"`
+}
+
+// ThermalLookup reports the thermal state of a hardware type
+type ThermalLookup func(hardware string) (temperatureC float64, throttling bool, ok bool)
+
+// routedCapabilities are the operations routing filters backends on
+var routedCapabilities = []backends.Capability{
+	backends.CapabilityAudioToText,
+	backends.CapabilityTextToAudio,
+	backends.CapabilityTextToImage,
+	backends.CapabilityImageToImage,
+	backends.CapabilityToolCalling,
+}
+
+// NewRecord converts an observed decision into an anonymized record
+func NewRecord(d *router.DecisionRecord, thermal ThermalLookup, now time.Time) Record {
+	a := d.Annotations
+	rec := Record{
+		Time: now.UTC(),
+		Request: Features{
+			Model:                 a.Model,
+			Target:                a.Target,
+			MediaType:             string(a.MediaType),
+			Capability:            string(a.Capability),
+			Language:              a.Language,
+			Priority:              int(a.Priority),
+			LatencyCritical:       a.LatencyCritical,
+			PreferPowerEfficiency: a.PreferPowerEfficiency,
+			MaxLatencyMs:          a.MaxLatencyMs,
+			MaxPowerWatts:         a.MaxPowerWatts,
+		},
+		Backends: make([]BackendState, 0, len(d.Backends)),
+	}
+	if mc := d.Constraints; mc != nil {
+		rec.Constraints = &Constraints{
+			Reason:          mc.Reason,
+			MaxPowerWatts:   mc.MaxPowerWatts,
+			AllowedBackends: mc.AllowedBackends,
+		}
+	}
+
+	for _, snap := range d.Backends {
+		b := snap.Backend
+		state := BackendState{
+			ID:            b.ID(),
+			Hardware:      b.Hardware(),
+			Healthy:       snap.Healthy,
+			AvgLatencyMs:  b.AvgLatencyMs(),
+			InFlight:      snap.InFlight,
+			LatencyEWMAMs: snap.LatencyEWMAMs,
+		}
+		for _, c := range routedCapabilities {
+			if backends.SupportsCapability(b, c) {
+				state.Capabilities = append(state.Capabilities, string(c))
+			}
+		}
+		if thermal != nil {
+			if temp, throttling, ok := thermal(b.Hardware()); ok {
+				state.TemperatureC = temp
+				state.Throttling = throttling
+			}
+		}
+		rec.Backends = append(rec.Backends, state)
+	}
+
+	if d.Decision != nil && d.Decision.Backend != nil {
+		rec.BackendID = d.Decision.Backend.ID()
+		rec.Reason = d.Decision.Reason
+	}
+	if d.Err != nil {
+		rec.Error = d.Err.Error()
+	}
+	return rec
+}
+
+// RecorderConfig configures the decision log
+type RecorderConfig struct {
+	Path       string
+	SampleRate float64 // fraction of decisions recorded, (0, 1], 0 = all
+	MaxSizeMB  int     // rotate to Path.1 beyond this size (0 = never)
+}
+
+// Recorder appends sampled routing decisions to a JSONL file
+type Recorder struct {
+	cfg     RecorderConfig
+	thermal ThermalLookup
+	now     func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	rand   *rand.Rand
+	failed bool // a write error was logged and not yet followed by a success
+}
+
+// NewRecorder opens (or creates) the decision log
+func NewRecorder(cfg RecorderConfig, thermal ThermalLookup) (*Recorder, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("decision log requires a path")
+	}
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+
+	rec := &Recorder{
+		cfg:     cfg,
+		thermal: thermal,
+		now:     time.Now,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if err := rec.open(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (rec *Recorder) open() error {
+	if err := os.MkdirAll(filepath.Dir(rec.cfg.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create decision log directory: %w", err)
+	}
+	f, err := os.OpenFile(rec.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open decision log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat decision log: %w", err)
+	}
+	rec.file = f
+	rec.size = info.Size()
+	return nil
+}
+
+// Observe records a routing decision; it is a router.DecisionObserver
+func (rec *Recorder) Observe(d *router.DecisionRecord) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.file == nil || rec.rand.Float64() >= rec.cfg.SampleRate {
+		return
+	}
+
+	line, err := json.Marshal(NewRecord(d, rec.thermal, rec.now()))
+	if err == nil {
+		line = append(line, '\n')
+		var n int
+		n, err = rec.file.Write(line)
+		rec.size += int64(n)
+	}
+	if err == nil && rec.cfg.MaxSizeMB > 0 && rec.size >= int64(rec.cfg.MaxSizeMB)*1024*1024 {
+		err = rec.rotate()
+	}
+
+	// Log the first failure of a run rather than every decision
+	if err != nil && !rec.failed {
+		logging.Logger.Warn("Failed to record routing decision", zap.Error(err))
+	}
+	rec.failed = err != nil
+}
+
+// rotate moves the log to Path.1, replacing an older rotation
+func (rec *Recorder) rotate() error {
+	if err := rec.file.Close(); err != nil {
+		return fmt.Errorf("failed to close decision log: %w", err)
+	}
+	rec.file = nil
+	if err := os.Rename(rec.cfg.Path, rec.cfg.Path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate decision log: %w", err)
+	}
+	return rec.open()
+}
+
+// Close closes the decision log
+func (rec *Recorder) Close() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.file == nil {
+		return nil
+	}
+	err := rec.file.Close()
+	rec.file = nil
+	return err
+}
+
+// ReadRecords parses a JSONL decision log
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,311 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// Candidate is the routing configuration decisions are replayed against
+type Candidate struct {
+	Router   router.Config
+	Backends []BackendSpec
+}
+
+// BackendSpec is a backend as configured in the candidate
+type BackendSpec struct {
+	ID           string
+	Type         string
+	Hardware     string
+	PowerWatts   float64
+	AvgLatencyMs int32 // used when the backend has no recorded state
+	Priority     int
+
+	SupportedModelPatterns []string
+	PreferredModels        []string
+	ExcludedPatterns       []string
+}
+
+// Change is a decision that routes differently under the candidate
+type Change struct {
+	Index        int       `json:"index"` // position in the decision log
+	Time         time.Time `json:"time"`
+	Model        string    `json:"model,omitempty"`
+	Before       string    `json:"before"` // backend ID, "" when routing failed
+	After        string    `json:"after"`
+	BeforeReason string    `json:"before_reason,omitempty"`
+	AfterReason  string    `json:"after_reason,omitempty"`
+}
+
+// Report summarizes a replay
+type Report struct {
+	Decisions int            `json:"decisions"`
+	Changed   int            `json:"changed"`
+	Before    map[string]int `json:"before"` // backend ID -> decisions, "" = failed
+	After     map[string]int `json:"after"`
+	Changes   []Change       `json:"changes"`
+}
+
+// Replay re-runs recorded decisions through a router built from the
+// candidate. Each decision sees the backend health, load and efficiency
+// constraints that were recorded with it. Recorded backends missing from
+// the candidate are treated as removed; candidate backends without
+// recorded state are treated as healthy and idle.
+func Replay(records []Record, c Candidate) *Report {
+	cfg := c.Router
+	cfg.Scheduler = router.SchedulerConfig{} // replay never waits for slots
+	r := router.NewRouter(cfg)
+
+	pool := make([]*replayBackend, 0, len(c.Backends))
+	for _, spec := range c.Backends {
+		b := &replayBackend{spec: spec}
+		if err := r.RegisterBackend(b); err == nil {
+			pool = append(pool, b)
+		}
+	}
+
+	var constraints *router.ModeConstraints
+	r.SetModeConstraints(func() (router.ModeConstraints, bool) {
+		if constraints == nil {
+			return router.ModeConstraints{}, false
+		}
+		return *constraints, true
+	})
+
+	report := &Report{
+		Decisions: len(records),
+		Before:    make(map[string]int),
+		After:     make(map[string]int),
+	}
+	for i, rec := range records {
+		for _, b := range pool {
+			state, ok := rec.backend(b.spec.ID)
+			b.restore(state, ok)
+			r.RestoreLoad(b.spec.ID, state.InFlight, state.LatencyEWMAMs)
+		}
+		constraints = rec.Constraints.router()
+
+		annotations := rec.Request.annotations()
+		after, afterReason := "", ""
+		decision, err := r.RouteRequest(context.Background(), annotations)
+		if err != nil {
+			afterReason = err.Error()
+		} else {
+			after, afterReason = decision.Backend.ID(), decision.Reason
+		}
+
+		report.Before[rec.BackendID]++
+		report.After[after]++
+		if after != rec.BackendID {
+			beforeReason := rec.Reason
+			if rec.Error != "" {
+				beforeReason = rec.Error
+			}
+			report.Changed++
+			report.Changes = append(report.Changes, Change{
+				Index:        i,
+				Time:         rec.Time,
+				Model:        rec.Request.Model,
+				Before:       rec.BackendID,
+				After:        after,
+				BeforeReason: beforeReason,
+				AfterReason:  afterReason,
+			})
+		}
+	}
+	return report
+}
+
+// WriteText prints a human-readable summary with at most limit changes
+// (0 = all)
+func (rep *Report) WriteText(w io.Writer, limit int) {
+	fmt.Fprintf(w, "Replayed %d decisions: %d would change", rep.Decisions, rep.Changed)
+	if rep.Decisions > 0 {
+		fmt.Fprintf(w, " (%.1f%%)", 100*float64(rep.Changed)/float64(rep.Decisions))
+	}
+	fmt.Fprintln(w)
+
+	ids := make(map[string]bool)
+	for id := range rep.Before {
+		ids[id] = true
+	}
+	for id := range rep.After {
+		ids[id] = true
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	fmt.Fprintf(w, "\n%-24s %8s %8s %8s\n", "BACKEND", "BEFORE", "AFTER", "DELTA")
+	for _, id := range sorted {
+		before, after := rep.Before[id], rep.After[id]
+		fmt.Fprintf(w, "%-24s %8d %8d %+8d\n", label(id), before, after, after-before)
+	}
+
+	if len(rep.Changes) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nChanged decisions:")
+	for i, c := range rep.Changes {
+		if limit > 0 && i == limit {
+			fmt.Fprintf(w, "  ... %d more\n", len(rep.Changes)-limit)
+			break
+		}
+		model := c.Model
+		if model == "" {
+			model = "-"
+		}
+		fmt.Fprintf(w, "  #%d %s %s: %s -> %s\n", c.Index, c.Time.Format(time.RFC3339), model, label(c.Before), label(c.After))
+		fmt.Fprintf(w, "      was: %s\n      now: %s\n", c.BeforeReason, c.AfterReason)
+	}
+}
+
+func label(backendID string) string {
+	if backendID == "" {
+		return "(no backend)"
+	}
+	return backendID
+}
+
+// backend returns the recorded state of backendID
+func (rec Record) backend(backendID string) (BackendState, bool) {
+	for _, state := range rec.Backends {
+		if state.ID == backendID {
+			return state, true
+		}
+	}
+	return BackendState{}, false
+}
+
+func (c *Constraints) router() *router.ModeConstraints {
+	if c == nil {
+		return nil
+	}
+	return &router.ModeConstraints{
+		Reason:          c.Reason,
+		MaxPowerWatts:   c.MaxPowerWatts,
+		AllowedBackends: c.AllowedBackends,
+	}
+}
+
+func (f Features) annotations() *backends.Annotations {
+	return &backends.Annotations{
+		Model:                 f.Model,
+		Target:                f.Target,
+		MediaType:             backends.MediaType(f.MediaType),
+		Capability:            backends.Capability(f.Capability),
+		Language:              f.Language,
+		Priority:              backends.Priority(f.Priority),
+		LatencyCritical:       f.LatencyCritical,
+		PreferPowerEfficiency: f.PreferPowerEfficiency,
+		MaxLatencyMs:          f.MaxLatencyMs,
+		MaxPowerWatts:         f.MaxPowerWatts,
+	}
+}
+
+// replayBackend stands in for a configured backend. Only the methods the
+// router consults are implemented; the embedded interface is nil.
+type replayBackend struct {
+	backends.Backend
+
+	spec         BackendSpec
+	healthy      bool
+	avgLatencyMs int32
+	capabilities map[string]bool
+}
+
+// restore applies a recorded state, or healthy and idle without one
+func (b *replayBackend) restore(state BackendState, recorded bool) {
+	b.healthy = !recorded || state.Healthy
+	b.avgLatencyMs = b.spec.AvgLatencyMs
+	if recorded && state.AvgLatencyMs > 0 {
+		b.avgLatencyMs = state.AvgLatencyMs
+	}
+	b.capabilities = make(map[string]bool, len(state.Capabilities))
+	for _, c := range state.Capabilities {
+		b.capabilities[c] = true
+	}
+}
+
+func (b *replayBackend) ID() string             { return b.spec.ID }
+func (b *replayBackend) Type() string           { return b.spec.Type }
+func (b *replayBackend) Name() string           { return b.spec.ID }
+func (b *replayBackend) Hardware() string       { return b.spec.Hardware }
+func (b *replayBackend) IsHealthy() bool        { return b.healthy }
+func (b *replayBackend) PowerWatts() float64    { return b.spec.PowerWatts }
+func (b *replayBackend) AvgLatencyMs() int32    { return b.avgLatencyMs }
+func (b *replayBackend) Priority() int          { return b.spec.Priority }
+func (b *replayBackend) SupportsGenerate() bool { return true }
+func (b *replayBackend) SupportsStream() bool   { return true }
+
+func (b *replayBackend) SupportsAudioToText() bool {
+	return b.capabilities[string(backends.CapabilityAudioToText)]
+}
+
+func (b *replayBackend) SupportsTextToAudio() bool {
+	return b.capabilities[string(backends.CapabilityTextToAudio)]
+}
+
+func (b *replayBackend) SupportsTextToImage() bool {
+	return b.capabilities[string(backends.CapabilityTextToImage)]
+}
+
+func (b *replayBackend) SupportsImageEdit() bool {
+	return b.capabilities[string(backends.CapabilityImageToImage)]
+}
+
+func (b *replayBackend) SupportsToolCalling() bool {
+	return b.capabilities[string(backends.CapabilityToolCalling)]
+}
+
+// SupportsModel applies the candidate's model_capability patterns the way
+// the Ollama backend does
+func (b *replayBackend) SupportsModel(modelName string) bool {
+	for _, pattern := range b.spec.ExcludedPatterns {
+		if matchesPattern(modelName, pattern) {
+			return false
+		}
+	}
+	if len(b.spec.SupportedModelPatterns) == 0 {
+		return true
+	}
+	for _, pattern := range b.spec.SupportedModelPatterns {
+		if matchesPattern(modelName, pattern) {
+			return true
+		}
+	}
+	for _, preferred := range b.spec.PreferredModels {
+		if modelName == preferred {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern checks if a model name matches a pattern
+func matchesPattern(modelName, pattern string) bool {
+	if pattern == "*" || modelName == pattern {
+		return true
+	}
+	// "*:0.5b" matches "qwen2.5:0.5b"
+	if strings.HasPrefix(pattern, "*:") {
+		return strings.HasSuffix(modelName, ":"+strings.TrimPrefix(pattern, "*:"))
+	}
+	// "llama3:*" matches "llama3:70b"
+	if strings.HasSuffix(pattern, ":*") {
+		return strings.HasPrefix(modelName, strings.TrimSuffix(pattern, ":*")+":")
+	}
+	// "*70b*" matches any model containing "70b"
+	if strings.HasPrefix(pattern, "*") && strings.HasSuffix(pattern, "*") {
+		return strings.Contains(modelName, strings.Trim(pattern, "*"))
+	}
+	return false
+}
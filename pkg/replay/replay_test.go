@@ -0,0 +1,188 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+var testSpecs = []BackendSpec{
+	{ID: "ollama-npu", Hardware: "npu", PowerWatts: 3, AvgLatencyMs: 800, Priority: 1},
+	{ID: "ollama-nvidia", Hardware: "nvidia", PowerWatts: 55, AvgLatencyMs: 150, Priority: 10},
+}
+
+// recordDecisions routes requests through a live router with the recorder
+// attached and returns the decision log
+func recordDecisions(t *testing.T, requests ...*backends.Annotations) []Record {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	rec, err := NewRecorder(RecorderConfig{Path: path}, func(hardware string) (float64, bool, bool) {
+		return 71.5, hardware == "nvidia", true
+	})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	r := router.NewRouter(router.Config{})
+	for _, spec := range testSpecs {
+		b := &replayBackend{spec: spec}
+		b.restore(BackendState{}, false)
+		r.RegisterBackend(b)
+	}
+	r.SetDecisionObserver(rec.Observe)
+	for _, a := range requests {
+		r.RouteRequest(context.Background(), a)
+	}
+	rec.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open decision log: %v", err)
+	}
+	defer f.Close()
+	records, err := ReadRecords(f)
+	if err != nil {
+		t.Fatalf("ReadRecords failed: %v", err)
+	}
+	return records
+}
+
+func TestRecorder_Anonymized(t *testing.T) {
+	records := recordDecisions(t, &backends.Annotations{
+		Model:           "llama3:8b",
+		LatencyCritical: true,
+		RequestID:       "req-secret",
+		Custom:          map[string]string{"user": "alice"},
+	})
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+
+	rec := records[0]
+	if rec.Request.Model != "llama3:8b" || !rec.Request.LatencyCritical || rec.BackendID != "ollama-nvidia" {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+	if len(rec.Backends) != 2 || rec.Backends[1].TemperatureC != 71.5 || !rec.Backends[1].Throttling {
+		t.Errorf("Expected backend and thermal state, got %+v", rec.Backends)
+	}
+
+	line, _ := json.Marshal(rec)
+	for _, secret := range []string{"req-secret", "alice"} {
+		if strings.Contains(string(line), secret) {
+			t.Errorf("Record leaks %q: %s", secret, line)
+		}
+	}
+}
+
+func TestRecorder_SampleRate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	rec, err := NewRecorder(RecorderConfig{Path: path, SampleRate: 0.000001}, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	d := &router.DecisionRecord{}
+	for i := 0; i < 100; i++ {
+		rec.Observe(d)
+	}
+	rec.Close()
+
+	data, _ := os.ReadFile(path)
+	if len(data) != 0 {
+		t.Errorf("Expected sampling to drop decisions, got %d bytes", len(data))
+	}
+}
+
+func TestReplay_SameConfigUnchanged(t *testing.T) {
+	records := recordDecisions(t,
+		&backends.Annotations{LatencyCritical: true},
+		&backends.Annotations{PreferPowerEfficiency: true},
+		&backends.Annotations{MaxPowerWatts: 10},
+	)
+
+	report := Replay(records, Candidate{Backends: testSpecs})
+	if report.Decisions != 3 || report.Changed != 0 {
+		t.Errorf("Expected identical decisions, got %+v", report)
+	}
+}
+
+func TestReplay_ReportsChanges(t *testing.T) {
+	records := recordDecisions(t,
+		&backends.Annotations{LatencyCritical: true},
+		&backends.Annotations{LatencyCritical: true},
+	)
+
+	// Removing the NVIDIA backend moves its traffic to the NPU
+	report := Replay(records, Candidate{Backends: testSpecs[:1]})
+	if report.Changed != 2 || report.After["ollama-npu"] != 2 || report.Before["ollama-nvidia"] != 2 {
+		t.Fatalf("Expected traffic to move to the NPU, got %+v", report)
+	}
+	if c := report.Changes[0]; c.Before != "ollama-nvidia" || c.After != "ollama-npu" || c.AfterReason == "" {
+		t.Errorf("Unexpected change: %+v", c)
+	}
+
+	// Round-robin spreads the same requests over both backends
+	report = Replay(records, Candidate{
+		Router:   router.Config{LoadBalancing: router.LoadBalancingConfig{Strategy: router.StrategyRoundRobin}},
+		Backends: testSpecs,
+	})
+	if report.Changed != 1 {
+		t.Errorf("Expected one request to move under round-robin, got %+v", report)
+	}
+
+	var out bytes.Buffer
+	report.WriteText(&out, 0)
+	if !strings.Contains(out.String(), "Replayed 2 decisions: 1 would change") {
+		t.Errorf("Unexpected text report:\n%s", out.String())
+	}
+}
+
+func TestReplay_RecordedState(t *testing.T) {
+	records := []Record{{
+		Request:   Features{LatencyCritical: true},
+		BackendID: "ollama-npu",
+		Backends: []BackendState{
+			{ID: "ollama-npu", Healthy: true},
+			{ID: "ollama-nvidia", Healthy: false},
+		},
+	}, {
+		Request:     Features{LatencyCritical: true},
+		Constraints: &Constraints{Reason: "quiet window: meeting", AllowedBackends: []string{"ollama-npu"}},
+		BackendID:   "ollama-npu",
+		Backends: []BackendState{
+			{ID: "ollama-npu", Healthy: true},
+			{ID: "ollama-nvidia", Healthy: true},
+		},
+	}}
+
+	// The unhealthy backend and the quiet window are both honoured
+	report := Replay(records, Candidate{Backends: testSpecs})
+	if report.Changed != 0 {
+		t.Errorf("Expected recorded health and constraints to be replayed, got %+v", report.Changes)
+	}
+}
+
+func TestReplayBackend_SupportsModel(t *testing.T) {
+	b := &replayBackend{spec: BackendSpec{
+		SupportedModelPatterns: []string{"*:0.5b", "llama3:*"},
+		ExcludedPatterns:       []string{"*70b*"},
+		PreferredModels:        []string{"phi3"},
+	}}
+	for model, want := range map[string]bool{
+		"qwen2.5:0.5b": true,
+		"llama3:8b":    true,
+		"llama3:70b":   false,
+		"phi3":         true,
+		"mistral":      false,
+	} {
+		if got := b.SupportsModel(model); got != want {
+			t.Errorf("SupportsModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
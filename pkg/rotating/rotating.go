@@ -0,0 +1,136 @@
+// Package rotating appends to a file that is rotated by size, keeping
+// older contents as path.1 ... path.N
+package rotating
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config configures a rotating file
+type Config struct {
+	Path       string
+	Name       string      // used in error messages, e.g. "audit log"
+	MaxSizeMB  int         // rotate when the file exceeds this size (0 = never)
+	MaxBackups int         // rotated files to keep as path.1 ... path.N
+	DirMode    os.FileMode // for a missing parent directory (0 = 0o755)
+	FileMode   os.FileMode // for a new file (0 = 0o640)
+}
+
+// File is an append-only file rotated once it grows past MaxSizeMB. It is
+// not safe for concurrent use; callers serialize writes themselves.
+type File struct {
+	cfg  Config
+	file *os.File
+	size int64
+}
+
+// Open opens (or creates) the file
+func Open(cfg Config) (*File, error) {
+	if cfg.Name == "" {
+		cfg.Name = "file"
+	}
+	if cfg.DirMode == 0 {
+		cfg.DirMode = 0o755
+	}
+	if cfg.FileMode == 0 {
+		cfg.FileMode = 0o640
+	}
+	f := &File{cfg: cfg}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.cfg.Path), f.cfg.DirMode); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", f.cfg.Name, err)
+	}
+
+	file, err := os.OpenFile(f.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, f.cfg.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.cfg.Name, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %s: %w", f.cfg.Name, err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Size returns the size of the current file, 0 for one just rotated
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// Write appends p, reopening the file if an earlier rotation failed
+func (f *File) Write(p []byte) (int, error) {
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("failed to write %s: %w", f.cfg.Name, err)
+	}
+	return n, nil
+}
+
+// Sync flushes what was written to disk, then rotates the file if it has
+// reached MaxSizeMB
+func (f *File) Sync() error {
+	if f.file == nil {
+		return nil
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", f.cfg.Name, err)
+	}
+
+	if f.cfg.MaxSizeMB > 0 && f.size >= int64(f.cfg.MaxSizeMB)*1024*1024 {
+		return f.rotate()
+	}
+	return nil
+}
+
+// rotate shifts path -> path.1 -> path.2 ... dropping the oldest
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", f.cfg.Name, err)
+	}
+	f.file = nil
+
+	path := f.cfg.Path
+	if f.cfg.MaxBackups <= 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", f.cfg.Name, err)
+		}
+		return f.open()
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", path, f.cfg.MaxBackups))
+	for i := f.cfg.MaxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate %s: %w", f.cfg.Name, err)
+	}
+	return f.open()
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
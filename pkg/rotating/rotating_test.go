@@ -0,0 +1,84 @@
+package rotating
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFile_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "out.log")
+	f, err := Open(Config{Path: path, MaxSizeMB: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	chunk := bytes.Repeat([]byte("x"), 1024*1024)
+	for i := 0; i < 4; i++ {
+		if _, err := f.Write(chunk); err != nil {
+			t.Fatalf("Write %d failed: %v", i, err)
+		}
+		if err := f.Sync(); err != nil {
+			t.Fatalf("Sync %d failed: %v", i, err)
+		}
+		if f.Size() != 0 {
+			t.Errorf("Size after rotation = %d, want 0", f.Size())
+		}
+	}
+
+	for _, name := range []string{"out.log", "out.log.1", "out.log.2"} {
+		if _, err := os.Stat(filepath.Join(dir, "nested", name)); err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nested", "out.log.3")); !os.IsNotExist(err) {
+		t.Error("Expected backups beyond MaxBackups to be removed")
+	}
+}
+
+func TestFile_RotationWithoutBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.log")
+	f, err := Open(Config{Path: path, MaxSizeMB: 1})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(bytes.Repeat([]byte("x"), 1024*1024)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected the file to be recreated: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Size = %d, want 0 after truncating rotation", info.Size())
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Error("Expected no backup with MaxBackups 0")
+	}
+}
+
+func TestFile_KeepsExistingSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(Config{Path: path})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	if f.Size() != 6 {
+		t.Errorf("Size = %d, want 6", f.Size())
+	}
+}
@@ -0,0 +1,110 @@
+package router
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// Reservation holds backends for a live workload. While it is active,
+// best-effort requests are deferred from those backends: routed elsewhere
+// when possible, otherwise rejected so the client retries later.
+type Reservation struct {
+	Owner      string   // e.g. "meeting:ollama-npu"
+	BackendIDs []string // backends serving the live workload
+	Reason     string   // shown in logs and routing errors
+
+	// MaxLowPriority best-effort requests may still run concurrently on a
+	// reserved backend (0 = defer all of them)
+	MaxLowPriority int
+}
+
+// AdmissionController decides whether a request may be placed on a backend
+// given the active reservations
+type AdmissionController struct {
+	mu           sync.RWMutex
+	reservations map[string]Reservation // owner -> reservation
+}
+
+// NewAdmissionController creates an admission controller with no reservations
+func NewAdmissionController() *AdmissionController {
+	return &AdmissionController{
+		reservations: make(map[string]Reservation),
+	}
+}
+
+// Reserve adds or replaces the reservation for res.Owner
+func (a *AdmissionController) Reserve(res Reservation) {
+	a.mu.Lock()
+	a.reservations[res.Owner] = res
+	a.mu.Unlock()
+
+	if logging.Logger != nil {
+		logging.Logger.Info("Backends reserved, deferring best-effort requests",
+			zap.String("owner", res.Owner),
+			zap.Strings("backends", res.BackendIDs),
+			zap.Int("max_low_priority", res.MaxLowPriority),
+		)
+	}
+}
+
+// Release removes the reservation for owner
+func (a *AdmissionController) Release(owner string) {
+	a.mu.Lock()
+	_, existed := a.reservations[owner]
+	delete(a.reservations, owner)
+	a.mu.Unlock()
+
+	if existed && logging.Logger != nil {
+		logging.Logger.Info("Backend reservation released", zap.String("owner", owner))
+	}
+}
+
+// Reservations returns the active reservations ordered by owner
+func (a *AdmissionController) Reservations() []Reservation {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	list := make([]Reservation, 0, len(a.reservations))
+	for _, res := range a.reservations {
+		list = append(list, res)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Owner < list[j].Owner })
+	return list
+}
+
+// Admit reports whether a request of the given priority may run on
+// backendID. lowInflight is the number of best-effort requests already
+// running there. When denied, the blocking reservation is returned.
+func (a *AdmissionController) Admit(backendID string, priority backends.Priority, lowInflight int) (bool, *Reservation) {
+	if priority > backends.PriorityBestEffort {
+		return true, nil
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, res := range a.reservations {
+		for _, id := range res.BackendIDs {
+			if id == backendID && lowInflight >= res.MaxLowPriority {
+				res := res
+				return false, &res
+			}
+		}
+	}
+	return true, nil
+}
+
+// admit checks a backend against the router's admission controller
+func (r *Router) admit(backend backends.Backend, priority backends.Priority) (bool, *Reservation) {
+	lowInflight := r.queueMgr.GetPriorityBreakdown(backend.ID())[backends.PriorityBestEffort]
+	return r.admission.Admit(backend.ID(), priority, lowInflight)
+}
+
+// Admission returns the router's admission controller
+func (r *Router) Admission() *AdmissionController {
+	return r.admission
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestAdmissionController_Admit(t *testing.T) {
+	ac := NewAdmissionController()
+	ac.Reserve(Reservation{Owner: "meeting:npu", BackendIDs: []string{"npu", "igpu"}, MaxLowPriority: 1})
+
+	if ok, _ := ac.Admit("npu", backends.PriorityNormal, 5); !ok {
+		t.Error("Normal priority should never be deferred")
+	}
+	if ok, _ := ac.Admit("cpu", backends.PriorityBestEffort, 5); !ok {
+		t.Error("Unreserved backend should admit best-effort requests")
+	}
+	if ok, _ := ac.Admit("igpu", backends.PriorityBestEffort, 0); !ok {
+		t.Error("Expected best-effort admitted below MaxLowPriority")
+	}
+	ok, res := ac.Admit("igpu", backends.PriorityBestEffort, 1)
+	if ok || res == nil || res.Owner != "meeting:npu" {
+		t.Errorf("Expected best-effort deferred by meeting:npu, got %v %+v", ok, res)
+	}
+
+	ac.Release("meeting:npu")
+	if ok, _ := ac.Admit("igpu", backends.PriorityBestEffort, 1); !ok {
+		t.Error("Expected best-effort admitted after release")
+	}
+	if len(ac.Reservations()) != 0 {
+		t.Errorf("Expected no reservations, got %+v", ac.Reservations())
+	}
+}
+
+func TestRouteRequest_DefersBestEffortFromReservedBackend(t *testing.T) {
+	router := NewRouter(Config{})
+	router.RegisterBackend(&MockBackend{id: "npu", hardware: "npu", healthy: true, avgLatencyMs: 50})
+	router.RegisterBackend(&MockBackend{id: "cpu", hardware: "cpu", healthy: true, avgLatencyMs: 800})
+	router.Admission().Reserve(Reservation{Owner: "meeting:npu", BackendIDs: []string{"npu"}})
+
+	route := func(annotations *backends.Annotations) string {
+		t.Helper()
+		decision, err := router.RouteRequest(context.Background(), annotations)
+		if err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+		return decision.Backend.(*QueueTrackingBackend).Backend.ID()
+	}
+
+	if id := route(&backends.Annotations{LatencyCritical: true, Priority: backends.PriorityBestEffort}); id != "cpu" {
+		t.Errorf("Expected best-effort request deferred to cpu, got %s", id)
+	}
+	if id := route(&backends.Annotations{Target: "npu", Priority: backends.PriorityBestEffort}); id != "cpu" {
+		t.Errorf("Expected explicit best-effort target deferred to cpu, got %s", id)
+	}
+	if id := route(&backends.Annotations{LatencyCritical: true, Priority: backends.PriorityNormal}); id != "npu" {
+		t.Errorf("Expected normal priority request on npu, got %s", id)
+	}
+}
+
+func TestRouteRequest_DeferredWhenOnlyReservedBackend(t *testing.T) {
+	router := NewRouter(Config{})
+	router.RegisterBackend(&MockBackend{id: "npu", healthy: true})
+	router.Admission().Reserve(Reservation{Owner: "meeting:npu", BackendIDs: []string{"npu"}})
+
+	_, err := router.RouteRequest(context.Background(), &backends.Annotations{Priority: backends.PriorityBestEffort})
+	if err == nil || !strings.Contains(err.Error(), "deferred=meeting:npu") {
+		t.Errorf("Expected deferred routing error, got %v", err)
+	}
+
+	router.Admission().Release("meeting:npu")
+	if _, err := router.RouteRequest(context.Background(), &backends.Annotations{Priority: backends.PriorityBestEffort}); err != nil {
+		t.Errorf("Expected routing after release, got %v", err)
+	}
+}
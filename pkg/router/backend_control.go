@@ -0,0 +1,82 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// BackendStatus is a backend as served by BackendsHandler
+type BackendStatus struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Type         string  `json:"type"`
+	Hardware     string  `json:"hardware"`
+	Healthy      bool    `json:"healthy"`
+	Enabled      bool    `json:"enabled"` // false while routing to it is paused
+	Priority     int     `json:"priority"`
+	PowerWatts   float64 `json:"power_watts"`
+	AvgLatencyMs int32   `json:"avg_latency_ms"`
+	QueueDepth   int     `json:"queue_depth"` // requests routed to it that have not finished
+}
+
+// backendControlRequest is the backends admin API payload
+type backendControlRequest struct {
+	Backend string `json:"backend"`
+	Enabled bool   `json:"enabled"`
+}
+
+// BackendStatuses returns every registered backend sorted by ID
+func (r *Router) BackendStatuses() []BackendStatus {
+	list := r.ListBackends()
+	statuses := make([]BackendStatus, 0, len(list))
+	for _, b := range list {
+		statuses = append(statuses, BackendStatus{
+			ID:           b.ID(),
+			Name:         b.Name(),
+			Type:         b.Type(),
+			Hardware:     b.Hardware(),
+			Healthy:      b.IsHealthy(),
+			Enabled:      !r.kill.Paused(b.ID()),
+			Priority:     b.Priority(),
+			PowerWatts:   b.PowerWatts(),
+			AvgLatencyMs: b.AvgLatencyMs(),
+			QueueDepth:   r.queueMgr.GetRawQueueDepth(b.ID()),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+// BackendsHandler serves the backends admin endpoint. GET lists backends;
+// POST {"backend": "npu", "enabled": false} stops routing new requests to
+// one, letting those in flight finish, and "enabled": true routes to it
+// again.
+func (r *Router) BackendsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var body backendControlRequest
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if _, ok := r.GetBackend(body.Backend); !ok {
+				http.Error(w, "backend not found: "+body.Backend, http.StatusNotFound)
+				return
+			}
+			if body.Enabled {
+				r.kill.Resume(body.Backend)
+			} else {
+				r.kill.Pause(body.Backend)
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"backends": r.BackendStatuses()})
+	}
+}
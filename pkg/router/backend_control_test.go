@@ -0,0 +1,53 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestRouter_BackendsHandler(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "npu", healthy: true, priority: 1})
+	r.RegisterBackend(&MockBackend{id: "gpu", healthy: true, priority: 2})
+	handler := r.BackendsHandler()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/admin/backends", strings.NewReader(`{"backend":"gpu","enabled":false}`)))
+	var response struct{ Backends []BackendStatus }
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || len(response.Backends) != 2 {
+		t.Fatalf("Unexpected response %d %+v", w.Code, response)
+	}
+	if gpu := response.Backends[0]; gpu.ID != "gpu" || gpu.Enabled {
+		t.Errorf("Expected gpu disabled, got %+v", gpu)
+	}
+
+	// Requests avoid the disabled backend
+	for i := 0; i < 5; i++ {
+		decision, err := r.RouteRequest(context.Background(), &backends.Annotations{})
+		if err != nil || decision.Backend.ID() != "npu" {
+			t.Fatalf("Expected routing to npu, got %v, %v", decision, err)
+		}
+	}
+	if npu := r.BackendStatuses()[1]; npu.QueueDepth != 5 {
+		t.Errorf("Expected 5 requests queued on npu, got %d", npu.QueueDepth)
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/admin/backends", strings.NewReader(`{"backend":"gpu","enabled":true}`)))
+	if r.KillSwitch().Paused("gpu") {
+		t.Error("Expected gpu enabled again")
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/admin/backends", strings.NewReader(`{"backend":"tpu"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown backend, got %d", w.Code)
+	}
+}
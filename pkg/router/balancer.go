@@ -0,0 +1,408 @@
+package router
+
+import (
+	"math"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/langdetect"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+)
+
+// Load-balancing strategies selectable in routing.load_balancing
+const (
+	StrategyScore            = "score"             // power/latency scoring (default)
+	StrategyRoundRobin       = "round_robin"       // rotate through eligible backends
+	StrategyWeighted         = "weighted"          // smooth weighted round-robin
+	StrategyLeastOutstanding = "least_outstanding" // fewest unfinished requests
+	StrategyEWMALatency      = "ewma_latency"      // lowest observed latency, scaled by load
+)
+
+// DefaultEWMADecay is the weight given to the newest latency sample
+const DefaultEWMADecay = 0.3
+
+// LoadBalancingConfig selects how requests are spread over the backends
+// that are eligible for them
+type LoadBalancingConfig struct {
+	// Strategy applies to models without an entry in Models
+	// ("" or "score" = power/latency scoring)
+	Strategy string
+
+	// Models overrides the strategy per model name or glob pattern, e.g.
+	// {"llama3*": "least_outstanding"}. Exact names win over patterns.
+	Models map[string]string
+
+	// Weights are relative backend weights for the weighted strategy
+	// (backend ID -> weight, default 1)
+	Weights map[string]int
+
+	// EWMADecay is the weight of the newest latency sample, in (0, 1]
+	EWMADecay float64
+}
+
+// LoadView is the live per-backend load balancers consult
+type LoadView interface {
+	// InFlight is the number of requests routed to the backend that have
+	// not finished, including ones waiting for a scheduler slot
+	InFlight(backendID string) int
+
+	// LatencyMs is the smoothed observed latency, 0 before the first sample
+	LatencyMs(backendID string) float64
+}
+
+// Balancer picks one of several eligible backends. candidates is never
+// empty and is ordered by backend ID.
+type Balancer interface {
+	Pick(candidates []backends.Backend, load LoadView) backends.Backend
+}
+
+// BalancerFactory creates a balancer. The global strategy and every model
+// override get their own instance, so stateful strategies rotate
+// independently.
+type BalancerFactory func(cfg LoadBalancingConfig) Balancer
+
+var (
+	balancersMu sync.RWMutex
+	balancers   = map[string]BalancerFactory{
+		StrategyRoundRobin: func(LoadBalancingConfig) Balancer { return &roundRobinBalancer{} },
+		StrategyWeighted: func(cfg LoadBalancingConfig) Balancer {
+			return &weightedBalancer{weights: cfg.Weights, current: make(map[string]int)}
+		},
+		StrategyLeastOutstanding: func(LoadBalancingConfig) Balancer { return &leastOutstandingBalancer{} },
+		StrategyEWMALatency:      func(LoadBalancingConfig) Balancer { return &ewmaLatencyBalancer{} },
+	}
+)
+
+// RegisterBalancer makes a strategy selectable by name (routing.load_balancing)
+func RegisterBalancer(name string, f BalancerFactory) {
+	if name == "" || name == StrategyScore || f == nil {
+		return
+	}
+	balancersMu.Lock()
+	balancers[name] = f
+	balancersMu.Unlock()
+}
+
+// HasBalancer reports whether a strategy name is known
+func HasBalancer(name string) bool {
+	if name == "" || name == StrategyScore {
+		return true
+	}
+	balancersMu.RLock()
+	defer balancersMu.RUnlock()
+	_, ok := balancers[name]
+	return ok
+}
+
+// newBalancer creates the named balancer, nil for scoring or unknown names
+func newBalancer(name string, cfg LoadBalancingConfig) Balancer {
+	balancersMu.RLock()
+	f, ok := balancers[name]
+	balancersMu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return f(cfg)
+}
+
+// namedBalancer is a strategy resolved for a model pattern
+type namedBalancer struct {
+	pattern  string
+	strategy string
+	balancer Balancer
+}
+
+// loadBalancer resolves the strategy for each request
+type loadBalancer struct {
+	global namedBalancer
+	exact  map[string]namedBalancer
+	globs  []namedBalancer // sorted by pattern
+}
+
+// newLoadBalancer builds the strategies in cfg. It returns nil when every
+// model uses scoring.
+func newLoadBalancer(cfg LoadBalancingConfig) *loadBalancer {
+	lb := &loadBalancer{exact: make(map[string]namedBalancer)}
+	active := false
+
+	if b := newBalancer(cfg.Strategy, cfg); b != nil {
+		lb.global = namedBalancer{strategy: cfg.Strategy, balancer: b}
+		active = true
+	}
+	for pattern, strategy := range cfg.Models {
+		nb := namedBalancer{pattern: pattern, strategy: strategy, balancer: newBalancer(strategy, cfg)}
+		if nb.balancer != nil {
+			active = true
+		}
+		if isGlob(pattern) {
+			lb.globs = append(lb.globs, nb)
+		} else {
+			lb.exact[pattern] = nb
+		}
+	}
+	sort.Slice(lb.globs, func(i, j int) bool { return lb.globs[i].pattern < lb.globs[j].pattern })
+
+	if !active {
+		return nil
+	}
+	return lb
+}
+
+// forModel returns the strategy for model; a nil balancer means scoring
+func (lb *loadBalancer) forModel(model string) namedBalancer {
+	if lb == nil {
+		return namedBalancer{}
+	}
+	if model != "" {
+		if nb, ok := lb.exact[model]; ok {
+			return nb
+		}
+		for _, nb := range lb.globs {
+			if ok, _ := path.Match(nb.pattern, model); ok {
+				return nb
+			}
+		}
+	}
+	return lb.global
+}
+
+func isGlob(pattern string) bool {
+	for _, c := range pattern {
+		switch c {
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// balance picks among candidates with a load-balancing strategy. It
+// reports false when the request's model uses scoring.
+func (r *Router) balance(candidates []backends.Backend, annotations *backends.Annotations) (backends.Backend, string, bool) {
+	nb := r.balancer.forModel(annotations.Model)
+	if nb.balancer == nil {
+		return nil, "", false
+	}
+
+	pool := balancePool(candidates, annotations, r.backendLanguages)
+	selected := nb.balancer.Pick(pool, r.load)
+	if selected == nil {
+		selected = pool[0]
+	}
+	metrics.RecordLoadBalancerPick(nb.strategy, selected.ID())
+	return selected, "Load balanced: " + nb.strategy, true
+}
+
+// balancePool narrows candidates to those serving the requested model and
+// fitting the prompt language, when any do, ordered by backend ID.
+// Strategies then spread load over equally suitable backends only.
+func balancePool(candidates []backends.Backend, annotations *backends.Annotations, languages map[string][]string) []backends.Backend {
+	pool := candidates
+	if annotations.Model != "" {
+		pool = narrow(pool, func(b backends.Backend) bool { return b.SupportsModel(annotations.Model) })
+	}
+	if annotations.Language != "" {
+		pool = narrow(pool, func(b backends.Backend) bool {
+			langs := languages[b.ID()]
+			return len(langs) == 0 || langdetect.Matches(annotations.Language, langs)
+		})
+	}
+
+	sorted := make([]backends.Backend, len(pool))
+	copy(sorted, pool)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID() < sorted[j].ID() })
+	return sorted
+}
+
+// narrow keeps the backends matching keep, or all of them when none do
+func narrow(pool []backends.Backend, keep func(backends.Backend) bool) []backends.Backend {
+	var kept []backends.Backend
+	for _, b := range pool {
+		if keep(b) {
+			kept = append(kept, b)
+		}
+	}
+	if len(kept) == 0 {
+		return pool
+	}
+	return kept
+}
+
+// roundRobinBalancer rotates through the candidates
+type roundRobinBalancer struct {
+	next atomic.Uint64
+}
+
+func (b *roundRobinBalancer) Pick(candidates []backends.Backend, _ LoadView) backends.Backend {
+	n := b.next.Add(1) - 1
+	return candidates[n%uint64(len(candidates))]
+}
+
+// weightedBalancer is smooth weighted round-robin: each backend receives
+// picks in proportion to its weight, interleaved rather than in bursts
+type weightedBalancer struct {
+	weights map[string]int
+
+	mu      sync.Mutex
+	current map[string]int // backend ID -> running weight
+}
+
+func (b *weightedBalancer) weight(backendID string) int {
+	if w, ok := b.weights[backendID]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+func (b *weightedBalancer) Pick(candidates []backends.Backend, _ LoadView) backends.Backend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total := 0
+	var best backends.Backend
+	for _, c := range candidates {
+		w := b.weight(c.ID())
+		total += w
+		b.current[c.ID()] += w
+		if best == nil || b.current[c.ID()] > b.current[best.ID()] {
+			best = c
+		}
+	}
+	b.current[best.ID()] -= total
+	return best
+}
+
+// leastOutstandingBalancer picks the backend with the fewest unfinished
+// requests. Ties go to the least utilized hardware when the load view
+// knows it, otherwise rotate.
+type leastOutstandingBalancer struct {
+	next atomic.Uint64
+}
+
+func (b *leastOutstandingBalancer) Pick(candidates []backends.Backend, load LoadView) backends.Backend {
+	offset := int((b.next.Add(1) - 1) % uint64(len(candidates)))
+	util, _ := load.(UtilizationView)
+	busy := func(c backends.Backend) float64 {
+		if util == nil {
+			return 0
+		}
+		percent, _ := util.Utilization(c.Hardware())
+		return percent
+	}
+
+	var best backends.Backend
+	bestLoad, bestBusy := math.MaxInt, 0.0
+	for i := range candidates {
+		c := candidates[(offset+i)%len(candidates)]
+		n := load.InFlight(c.ID())
+		if n > bestLoad {
+			continue
+		}
+		if percent := busy(c); n < bestLoad || percent < bestBusy {
+			best, bestLoad, bestBusy = c, n, percent
+		}
+	}
+	return best
+}
+
+// ewmaLatencyBalancer picks the backend with the lowest smoothed latency
+// multiplied by its outstanding requests plus one, so a fast backend stops
+// attracting traffic once it queues up. Backends without samples use their
+// configured average latency.
+type ewmaLatencyBalancer struct{}
+
+func (b *ewmaLatencyBalancer) Pick(candidates []backends.Backend, load LoadView) backends.Backend {
+	var best backends.Backend
+	bestCost := math.Inf(1)
+	for _, c := range candidates {
+		latency := load.LatencyMs(c.ID())
+		if latency == 0 {
+			latency = float64(c.AvgLatencyMs())
+		}
+		if cost := latency * float64(load.InFlight(c.ID())+1); cost < bestCost {
+			best, bestCost = c, cost
+		}
+	}
+	return best
+}
+
+// latencyTracker keeps an exponentially weighted moving average of each
+// backend's observed latency. A nil *latencyTracker ignores samples.
+type latencyTracker struct {
+	decay float64
+
+	mu   sync.RWMutex
+	ewma map[string]float64 // backend ID -> ms
+}
+
+func newLatencyTracker(decay float64) *latencyTracker {
+	if decay <= 0 || decay > 1 {
+		decay = DefaultEWMADecay
+	}
+	return &latencyTracker{decay: decay, ewma: make(map[string]float64)}
+}
+
+// observe folds one request's latency into the backend's average
+func (lt *latencyTracker) observe(backendID string, d time.Duration) {
+	if lt == nil {
+		return
+	}
+	ms := float64(d) / float64(time.Millisecond)
+
+	lt.mu.Lock()
+	prev, ok := lt.ewma[backendID]
+	if ok {
+		ms = lt.decay*ms + (1-lt.decay)*prev
+	}
+	lt.ewma[backendID] = ms
+	lt.mu.Unlock()
+
+	metrics.SetBackendLatencyEWMA(backendID, ms)
+}
+
+// restore overwrites a backend's average, 0 clears it
+func (lt *latencyTracker) restore(backendID string, ms float64) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	if ms <= 0 {
+		delete(lt.ewma, backendID)
+		return
+	}
+	lt.ewma[backendID] = ms
+}
+
+func (lt *latencyTracker) get(backendID string) float64 {
+	if lt == nil {
+		return 0
+	}
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	return lt.ewma[backendID]
+}
+
+// routerLoad is the router's LoadView
+type routerLoad struct {
+	queueMgr    *QueueManager
+	latency     *latencyTracker
+	estimator   *LatencyEstimator // by model and prompt length
+	utilization UtilizationSource // nil = not monitored
+}
+
+func (l routerLoad) InFlight(backendID string) int {
+	return l.queueMgr.GetRawQueueDepth(backendID)
+}
+
+func (l routerLoad) LatencyMs(backendID string) float64 {
+	return l.latency.get(backendID)
+}
+
+func (l routerLoad) Utilization(hardware string) (float64, bool) {
+	if l.utilization == nil {
+		return 0, false
+	}
+	return l.utilization(hardware)
+}
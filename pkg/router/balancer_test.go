@@ -0,0 +1,175 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func newBalancedRouter(t *testing.T, cfg LoadBalancingConfig, ids ...string) *Router {
+	t.Helper()
+	r := NewRouter(Config{LoadBalancing: cfg})
+	for _, id := range ids {
+		r.RegisterBackend(&MockBackend{id: id, healthy: true, avgLatencyMs: 100})
+	}
+	return r
+}
+
+// pick routes one request and reports the chosen backend
+func pick(t *testing.T, r *Router, annotations *backends.Annotations) string {
+	t.Helper()
+	decision, err := r.RouteRequest(context.Background(), annotations)
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	return decision.Backend.(*QueueTrackingBackend).Backend.ID()
+}
+
+func TestLoadBalancing_RoundRobin(t *testing.T) {
+	r := newBalancedRouter(t, LoadBalancingConfig{Strategy: StrategyRoundRobin}, "a", "b", "c")
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		got = append(got, pick(t, r, &backends.Annotations{}))
+	}
+	if strings.Join(got, ",") != "a,b,c,a,b,c" {
+		t.Errorf("Expected rotation a,b,c, got %v", got)
+	}
+}
+
+func TestLoadBalancing_Weighted(t *testing.T) {
+	r := newBalancedRouter(t, LoadBalancingConfig{
+		Strategy: StrategyWeighted,
+		Weights:  map[string]int{"a": 3},
+	}, "a", "b")
+
+	var got []string
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		id := pick(t, r, &backends.Annotations{})
+		got = append(got, id)
+		counts[id]++
+	}
+	if counts["a"] != 6 || counts["b"] != 2 {
+		t.Errorf("Expected a:6 b:2, got %v", counts)
+	}
+	// Smooth weighting interleaves the lighter backend
+	if strings.Join(got[:4], ",") != "a,a,b,a" {
+		t.Errorf("Expected interleaved picks, got %v", got)
+	}
+}
+
+func TestLoadBalancing_LeastOutstanding(t *testing.T) {
+	r := newBalancedRouter(t, LoadBalancingConfig{Strategy: StrategyLeastOutstanding}, "a", "b", "c")
+	r.queueMgr.MarkRequestStart("a", backends.PriorityNormal)
+	r.queueMgr.MarkRequestStart("a", backends.PriorityNormal)
+	r.queueMgr.MarkRequestStart("b", backends.PriorityNormal)
+
+	// Routing marks each request in flight, so c then fills up to b
+	if id := pick(t, r, &backends.Annotations{}); id != "c" {
+		t.Errorf("Expected idle backend c, got %s", id)
+	}
+	if id := pick(t, r, &backends.Annotations{}); id == "a" {
+		t.Errorf("Expected busiest backend a to be avoided, got %s", id)
+	}
+}
+
+func TestLoadBalancing_EWMALatency(t *testing.T) {
+	r := newBalancedRouter(t, LoadBalancingConfig{Strategy: StrategyEWMALatency, EWMADecay: 0.5}, "fast", "slow")
+	r.load.latency.observe("fast", 100*time.Millisecond)
+	r.load.latency.observe("slow", 400*time.Millisecond)
+
+	if id := pick(t, r, &backends.Annotations{}); id != "fast" {
+		t.Errorf("Expected fast backend, got %s", id)
+	}
+
+	// fast slows down: 0.5*1000 + 0.5*100 = 550ms
+	r.load.latency.observe("fast", time.Second)
+	if got := r.load.LatencyMs("fast"); got != 550 {
+		t.Errorf("Expected EWMA 550ms, got %.1f", got)
+	}
+	if id := pick(t, r, &backends.Annotations{}); id != "slow" {
+		t.Errorf("Expected slow backend once fast degrades, got %s", id)
+	}
+}
+
+func TestLoadBalancing_PerModel(t *testing.T) {
+	r := NewRouter(Config{LoadBalancing: LoadBalancingConfig{
+		Models: map[string]string{
+			"llama3*":      StrategyRoundRobin,
+			"llama3:70b":   StrategyScore,
+			"phi3:unknown": "no-such-strategy",
+		},
+	}})
+	r.RegisterBackend(&MockBackend{id: "a", healthy: true, priority: 10})
+	r.RegisterBackend(&MockBackend{id: "b", healthy: true})
+
+	decision, _ := r.RouteRequest(context.Background(), &backends.Annotations{Model: "llama3:8b"})
+	if !strings.Contains(decision.Reason, StrategyRoundRobin) {
+		t.Errorf("Expected round-robin for llama3:8b, got reason %q", decision.Reason)
+	}
+	if id := pick(t, r, &backends.Annotations{Model: "llama3:8b"}); id != "b" {
+		t.Errorf("Expected second llama3 request on b, got %s", id)
+	}
+
+	// Exact names win over patterns; other models keep scoring
+	for _, model := range []string{"llama3:70b", "mistral", "phi3:unknown"} {
+		decision, _ := r.RouteRequest(context.Background(), &backends.Annotations{Model: model})
+		if strings.HasPrefix(decision.Reason, "Load balanced") {
+			t.Errorf("Expected scoring for %s, got reason %q", model, decision.Reason)
+		}
+	}
+}
+
+func TestLoadBalancing_PrefersModelSupport(t *testing.T) {
+	r := NewRouter(Config{LoadBalancing: LoadBalancingConfig{Strategy: StrategyRoundRobin}})
+	r.RegisterBackend(&MockBackend{id: "a", healthy: true, modelPatterns: []string{"qwen*"}})
+	r.RegisterBackend(&MockBackend{id: "b", healthy: true, modelPatterns: []string{"llama*"}})
+
+	for i := 0; i < 3; i++ {
+		if id := pick(t, r, &backends.Annotations{Model: "llama3"}); id != "b" {
+			t.Fatalf("Expected only the backend serving llama3, got %s", id)
+		}
+	}
+}
+
+type lastBalancer struct{}
+
+func (lastBalancer) Pick(candidates []backends.Backend, _ LoadView) backends.Backend {
+	return candidates[len(candidates)-1]
+}
+
+func TestRegisterBalancer(t *testing.T) {
+	RegisterBalancer("last", func(LoadBalancingConfig) Balancer { return lastBalancer{} })
+	if !HasBalancer("last") || HasBalancer("missing") {
+		t.Fatal("Expected registered strategy to be known")
+	}
+
+	r := newBalancedRouter(t, LoadBalancingConfig{Strategy: "last"}, "a", "b")
+	if id := pick(t, r, &backends.Annotations{}); id != "b" {
+		t.Errorf("Expected custom strategy to pick b, got %s", id)
+	}
+}
+
+func TestQueueTrackingBackend_EmbedTracksLoad(t *testing.T) {
+	r := newBalancedRouter(t, LoadBalancingConfig{Strategy: StrategyLeastOutstanding}, "a")
+
+	decision, err := r.RouteRequest(context.Background(), &backends.Annotations{})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if n := r.load.InFlight("a"); n != 1 {
+		t.Fatalf("Expected 1 request in flight, got %d", n)
+	}
+
+	decision.Backend.Embed(context.Background(), &backends.EmbedRequest{Text: "hello"})
+	if n := r.load.InFlight("a"); n != 0 {
+		t.Errorf("Expected embed to finish the request, got %d in flight", n)
+	}
+	if _, ok := r.load.latency.ewma["a"]; !ok {
+		t.Error("Expected embed latency to be observed")
+	}
+}
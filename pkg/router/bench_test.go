@@ -0,0 +1,73 @@
+//go:build !race
+
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// benchRouter is a router with the four backends of a typical laptop
+func benchRouter() *Router {
+	r := NewRouter(Config{PowerAware: true, AutoOptimize: true})
+	for _, b := range []*MockBackend{
+		{id: "ollama-npu", healthy: true, powerWatts: 3, avgLatencyMs: 800, priority: 1},
+		{id: "ollama-igpu", healthy: true, powerWatts: 12, avgLatencyMs: 350, priority: 2},
+		{id: "ollama-nvidia", healthy: true, powerWatts: 55, avgLatencyMs: 150, priority: 3},
+		{id: "ollama-cpu", healthy: true, powerWatts: 28, avgLatencyMs: 1200, priority: 0},
+	} {
+		r.RegisterBackend(b)
+	}
+	return r
+}
+
+var benchAnnotations = map[string]backends.Annotations{
+	"balanced":         {Priority: backends.PriorityNormal},
+	"latency-critical": {Priority: backends.PriorityCritical, LatencyCritical: true},
+	"power-efficient":  {Priority: backends.PriorityNormal, PreferPowerEfficiency: true},
+	"target":           {Priority: backends.PriorityNormal, Target: "ollama-igpu"},
+}
+
+// routeOnce routes a copy of annotations, as the routing constraints
+// rewrite them, and releases the backend's queue slot
+func routeOnce(tb testing.TB, r *Router, annotations backends.Annotations) {
+	decision, err := r.RouteRequest(context.Background(), &annotations)
+	if err != nil {
+		tb.Fatalf("RouteRequest failed: %v", err)
+	}
+	r.queueMgr.MarkRequestEnd(decision.Backend.(*QueueTrackingBackend).Backend.ID(), annotations.Priority)
+}
+
+func BenchmarkRouteRequest(b *testing.B) {
+	for name, annotations := range benchAnnotations {
+		b.Run(name, func(b *testing.B) {
+			r := benchRouter()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				routeOnce(b, r, annotations)
+			}
+		})
+	}
+}
+
+// TestRouteRequest_AllocBudget fails when a routing decision allocates more
+// than it did when the budget was last reviewed. Raise a budget only for a
+// deliberate feature, never to absorb an accidental regression.
+func TestRouteRequest_AllocBudget(t *testing.T) {
+	budgets := map[string]float64{
+		"balanced":         10,
+		"latency-critical": 10,
+		"power-efficient":  10,
+		"target":           6,
+	}
+	for name, annotations := range benchAnnotations {
+		r := benchRouter()
+		allocs := testing.AllocsPerRun(100, func() { routeOnce(t, r, annotations) })
+		if allocs > budgets[name] {
+			t.Errorf("%s: routing allocates %.0f times, budget %.0f", name, allocs, budgets[name])
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package router
+
+import (
+	"strconv"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// CostWeights weigh the terms of a backend's routing cost; the candidate
+// with the lowest cost is selected. Only the ratios between weights matter.
+type CostWeights struct {
+	Energy  float64 // joules per request, relative to the hungriest candidate
+	Latency float64 // predicted latency, relative to the slowest candidate
+	Thermal float64 // thermal headroom used by the backend's hardware
+	Queue   float64 // requests waiting on the backend
+}
+
+// DefaultCostWeights weigh every term equally
+var DefaultCostWeights = CostWeights{Energy: 1, Latency: 1, Thermal: 1, Queue: 1}
+
+// CostWeightSource returns the weights in force, e.g. those of the current
+// efficiency mode
+type CostWeightSource func() CostWeights
+
+// ThermalHeadroomSource returns how much of a hardware type's thermal
+// headroom is used (0-1), false when it is unknown
+type ThermalHeadroomSource func(hardware string) (float64, bool)
+
+// queueCostSaturation queued requests cost the full queue weight
+const queueCostSaturation = 8
+
+// pointsPerCost converts the point adjustments scoring has always used
+// (backend priority, language fit, utilization) to cost
+const pointsPerCost = 1000.0
+
+// backendCost is one candidate's routing cost. The four terms are already
+// weighted, so they sum to the cost before adjustments.
+type backendCost struct {
+	Energy  float64
+	Latency float64
+	Thermal float64
+	Queue   float64
+	Adjust  float64 // backend priority, language fit and utilization
+	Total   float64
+}
+
+// SetCostWeights installs the source of the weights used to score
+// candidates (nil = DefaultCostWeights). PowerAware and AutoOptimize
+// double the energy and latency weights on top.
+func (r *Router) SetCostWeights(source CostWeightSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.costWeights = source
+}
+
+// SetThermalHeadroom installs the source of the thermal cost term
+// (nil = thermal headroom is not considered)
+func (r *Router) SetThermalHeadroom(source ThermalHeadroomSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.headroom = source
+}
+
+// costWeightsLocked returns the weights for a request: the configured
+// ones, shifted towards what the request asks for. Caller must hold r.mu.
+func (r *Router) costWeightsLocked(annotations *backends.Annotations) CostWeights {
+	w := DefaultCostWeights
+	if r.costWeights != nil {
+		w = r.costWeights()
+	}
+	if r.powerAware {
+		w.Energy *= 2
+	}
+	if r.autoOptimize {
+		w.Latency *= 2
+	}
+	if annotations.PreferPowerEfficiency {
+		w.Energy *= 4
+	}
+	if annotations.LatencyCritical {
+		w.Latency *= 4
+	}
+	if w.Energy+w.Latency+w.Thermal+w.Queue <= 0 {
+		return DefaultCostWeights
+	}
+	return w
+}
+
+// predictedLatencyMs is how long backend is expected to take: the latency
+// observed for the model at a similar prompt length, else the model's
+// benchmarked time to first token, else the backend's live average, else
+// its advertised one. Caller must hold r.mu.
+func (r *Router) predictedLatencyMs(backend backends.Backend, annotations *backends.Annotations) float64 {
+	model := annotations.Model
+	if ms, ok := r.load.estimator.Estimate(backend.ID(), model, annotations.PromptTokens); ok {
+		return ms
+	}
+	if model != "" {
+		if p, ok := r.performance[performanceKey(backend.ID(), model)]; ok && p.TTFTMs > 0 {
+			return p.TTFTMs
+		}
+	}
+	if ms := r.load.LatencyMs(backend.ID()); ms > 0 {
+		return ms
+	}
+	return float64(backend.AvgLatencyMs())
+}
+
+// thermalCost is the thermal headroom used by backend's hardware, 0 when
+// unknown. Caller must hold r.mu.
+func (r *Router) thermalCost(backend backends.Backend) float64 {
+	if r.headroom == nil {
+		return 0
+	}
+	used, ok := r.headroom(backend.Hardware())
+	if !ok {
+		return 0
+	}
+	return used
+}
+
+// weighCosts turns the raw energy (J) and latency (ms) left in each
+// candidate's cost into weighted terms and totals them
+func weighCosts(scored []candidateScore, w CostWeights) {
+	var maxEnergy, maxLatency float64
+	for i := range scored {
+		maxEnergy = max(maxEnergy, scored[i].cost.Energy)
+		maxLatency = max(maxLatency, scored[i].cost.Latency)
+	}
+	sum := w.Energy + w.Latency + w.Thermal + w.Queue
+
+	for i := range scored {
+		c := &scored[i].cost
+		c.Energy = w.Energy * ratio(c.Energy, maxEnergy) / sum
+		c.Latency = w.Latency * ratio(c.Latency, maxLatency) / sum
+		c.Thermal = w.Thermal * c.Thermal / sum
+		c.Queue = w.Queue * c.Queue / sum
+		c.Total = c.Energy + c.Latency + c.Thermal + c.Queue + c.Adjust
+		scored[i].score = -c.Total
+	}
+}
+
+func ratio(v, limit float64) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return v / limit
+}
+
+// explainCost describes the best candidate's cost and those of the next
+// few, e.g. "Selected: balanced (cost 0.41: energy 0.22, latency 0.12,
+// thermal 0.00, queue 0.06, adjust 0.01; next ollama-npu 0.47)"
+func explainCost(objective string, scored []candidateScore) string {
+	var buf [256]byte // keeps the reason off the heap until it is returned
+	b := append(buf[:0], "Selected: "...)
+	b = append(b, objective...)
+
+	best := scored[0].cost
+	b = append(b, " (cost "...)
+	b = strconv.AppendFloat(b, best.Total, 'f', 2, 64)
+	b = append(b, ": energy "...)
+	b = strconv.AppendFloat(b, best.Energy, 'f', 2, 64)
+	b = append(b, ", latency "...)
+	b = strconv.AppendFloat(b, best.Latency, 'f', 2, 64)
+	b = append(b, ", thermal "...)
+	b = strconv.AppendFloat(b, best.Thermal, 'f', 2, 64)
+	b = append(b, ", queue "...)
+	b = strconv.AppendFloat(b, best.Queue, 'f', 2, 64)
+	if best.Adjust != 0 {
+		b = append(b, ", adjust "...)
+		b = strconv.AppendFloat(b, best.Adjust, 'f', 2, 64)
+	}
+
+	for i, c := range scored[1:min(len(scored), 4)] {
+		if i == 0 {
+			b = append(b, "; next "...)
+		} else {
+			b = append(b, ", "...)
+		}
+		b = append(b, c.backend.ID()...)
+		b = append(b, ' ')
+		b = strconv.AppendFloat(b, c.cost.Total, 'f', 2, 64)
+	}
+	b = append(b, ')')
+	return string(b)
+}
+
+// alternativesByCost lists the other scored candidates cheapest first,
+// then any other healthy backends. Caller must hold r.mu.
+func (r *Router) alternativesByCost(scored []candidateScore) []string {
+	alternatives := make([]string, 0, len(r.backends))
+	for _, c := range scored[1:] {
+		alternatives = append(alternatives, c.backend.ID())
+	}
+
+next:
+	for id, backend := range r.backends {
+		if !backend.IsHealthy() {
+			continue
+		}
+		for _, c := range scored {
+			if c.backend.ID() == id {
+				continue next
+			}
+		}
+		alternatives = append(alternatives, id)
+	}
+	return alternatives
+}
@@ -0,0 +1,84 @@
+package router
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func costRouter() *Router {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "nvidia", hardware: "nvidia", healthy: true, powerWatts: 55, avgLatencyMs: 150, priority: 3})
+	r.RegisterBackend(&MockBackend{id: "igpu", hardware: "igpu", healthy: true, powerWatts: 12, avgLatencyMs: 350, priority: 2})
+	r.RegisterBackend(&MockBackend{id: "npu", hardware: "npu", healthy: true, powerWatts: 3, avgLatencyMs: 800, priority: 1})
+	return r
+}
+
+func routeCost(t *testing.T, r *Router, annotations backends.Annotations) *RoutingDecision {
+	t.Helper()
+	decision, err := r.RouteRequest(context.Background(), &annotations)
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	r.queueMgr.MarkRequestEnd(decision.Backend.ID(), annotations.Priority)
+	return decision
+}
+
+func TestRouter_CostWeights(t *testing.T) {
+	r := costRouter()
+	weights := CostWeights{Energy: 0.25, Latency: 3, Thermal: 0.5, Queue: 1}
+	r.SetCostWeights(func() CostWeights { return weights })
+
+	decision := routeCost(t, r, backends.Annotations{})
+	if decision.Backend.ID() != "nvidia" {
+		t.Errorf("Expected the fastest backend when latency dominates, got %s", decision.Backend.ID())
+	}
+	if !strings.HasPrefix(decision.Reason, "Selected: balanced (cost ") || !strings.Contains(decision.Reason, "; next igpu ") {
+		t.Errorf("Expected the costs in the reason, got %q", decision.Reason)
+	}
+	if want := []string{"igpu", "npu"}; !reflect.DeepEqual(decision.Alternatives, want) {
+		t.Errorf("Expected alternatives cheapest first %v, got %v", want, decision.Alternatives)
+	}
+
+	// The source is consulted on every request, e.g. after a mode change
+	weights = CostWeights{Energy: 3, Latency: 0.5, Thermal: 1, Queue: 1}
+	if decision := routeCost(t, r, backends.Annotations{}); decision.Backend.ID() != "npu" {
+		t.Errorf("Expected the most efficient backend when energy dominates, got %s", decision.Backend.ID())
+	}
+
+	// Requests shift the weights towards what they ask for
+	decision = routeCost(t, r, backends.Annotations{LatencyCritical: true})
+	if decision.Backend.ID() != "igpu" || !strings.HasPrefix(decision.Reason, "Selected: latency-critical") {
+		t.Errorf("Expected latency-critical requests off the slowest backend, got %s: %q", decision.Backend.ID(), decision.Reason)
+	}
+}
+
+func TestRouter_CostTerms(t *testing.T) {
+	r := costRouter()
+	r.SetCostWeights(func() CostWeights { return CostWeights{Latency: 1, Thermal: 1} })
+
+	if decision := routeCost(t, r, backends.Annotations{}); decision.Backend.ID() != "nvidia" {
+		t.Fatalf("Expected the fastest backend, got %s", decision.Backend.ID())
+	}
+
+	// Observed latency replaces the advertised one
+	r.load.latency.restore("nvidia", 900)
+	if decision := routeCost(t, r, backends.Annotations{}); decision.Backend.ID() != "igpu" {
+		t.Errorf("Expected the backend with the lowest live latency, got %s", decision.Backend.ID())
+	}
+
+	// A backend close to critical temperature is avoided
+	r.SetThermalHeadroom(func(hardware string) (float64, bool) {
+		return map[string]float64{"igpu": 0.9}[hardware], hardware != "unknown"
+	})
+	decision := routeCost(t, r, backends.Annotations{})
+	if decision.Backend.ID() != "npu" {
+		t.Errorf("Expected the backend with thermal headroom, got %s", decision.Backend.ID())
+	}
+	if !strings.Contains(decision.Reason, "thermal 0.00") {
+		t.Errorf("Expected no thermal cost for the selected backend, got %q", decision.Reason)
+	}
+}
@@ -0,0 +1,321 @@
+package router
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// Built-in scheduling policies
+const (
+	PolicyFIFO      = "fifo"       // arrival order, priority ignored
+	PolicyPriority  = "priority"   // highest priority first, then arrival order (default)
+	PolicyEDF       = "edf"        // earliest deadline first; requests without one go last
+	PolicyFairShare = "fair_share" // round-robin across tenants
+)
+
+// Waiter is a request waiting for a backend slot
+type Waiter struct {
+	Priority backends.Priority
+	Tenant   string    // from the API key, "" when unauthenticated
+	Deadline time.Time // zero when the request has none
+	Arrival  time.Time
+	Seq      uint64 // arrival order on the backend, breaks Arrival ties
+
+	ready chan struct{} // closed when granted or displaced
+	err   error         // set when displaced
+}
+
+// Discipline orders the waiters of one backend. The scheduler creates one
+// per backend and calls it with its lock held, so implementations need no
+// locking of their own.
+type Discipline interface {
+	// Push queues a waiter
+	Push(w *Waiter)
+	// Pop removes and returns the waiter to run next, nil when empty
+	Pop() *Waiter
+	// Remove drops a waiter that gave up and reports whether it was queued
+	Remove(w *Waiter) bool
+	// Victim removes and returns a queued waiter to reject so that incoming
+	// can take its place in a full queue, or nil to reject incoming instead
+	Victim(incoming *Waiter) *Waiter
+	// Len returns the number of queued waiters
+	Len() int
+}
+
+// DisciplineFactory creates an empty queue for one backend
+type DisciplineFactory func() Discipline
+
+var (
+	disciplinesMu sync.RWMutex
+	disciplines   = map[string]DisciplineFactory{
+		PolicyFIFO:      func() Discipline { return &fifoDiscipline{} },
+		PolicyPriority:  func() Discipline { return &priorityDiscipline{} },
+		PolicyEDF:       func() Discipline { return &edfDiscipline{} },
+		PolicyFairShare: func() Discipline { return &fairShareDiscipline{} },
+	}
+)
+
+// RegisterDiscipline makes a scheduling policy available to
+// routing.scheduling.policy, replacing any policy of the same name
+func RegisterDiscipline(name string, factory DisciplineFactory) {
+	disciplinesMu.Lock()
+	defer disciplinesMu.Unlock()
+	disciplines[name] = factory
+}
+
+// HasDiscipline reports whether a scheduling policy is registered
+func HasDiscipline(name string) bool {
+	disciplinesMu.RLock()
+	defer disciplinesMu.RUnlock()
+	_, ok := disciplines[name]
+	return ok
+}
+
+// disciplineFactory returns the factory for name, falling back to priority
+// scheduling for an empty or unknown name
+func disciplineFactory(name string) DisciplineFactory {
+	disciplinesMu.RLock()
+	defer disciplinesMu.RUnlock()
+	if factory, ok := disciplines[name]; ok {
+		return factory
+	}
+	return disciplines[PolicyPriority]
+}
+
+// removeWaiter deletes w from queue, preserving order
+func removeWaiter(queue []*Waiter, w *Waiter) ([]*Waiter, bool) {
+	for i, candidate := range queue {
+		if candidate == w {
+			return append(queue[:i:i], queue[i+1:]...), true
+		}
+	}
+	return queue, false
+}
+
+// fifoDiscipline serves waiters in arrival order. A full queue rejects
+// new requests whatever their priority.
+type fifoDiscipline struct {
+	queue []*Waiter
+}
+
+func (d *fifoDiscipline) Push(w *Waiter) { d.queue = append(d.queue, w) }
+
+func (d *fifoDiscipline) Pop() *Waiter {
+	if len(d.queue) == 0 {
+		return nil
+	}
+	w := d.queue[0]
+	d.queue = d.queue[1:]
+	return w
+}
+
+func (d *fifoDiscipline) Remove(w *Waiter) bool {
+	var ok bool
+	d.queue, ok = removeWaiter(d.queue, w)
+	return ok
+}
+
+func (d *fifoDiscipline) Victim(*Waiter) *Waiter { return nil }
+func (d *fifoDiscipline) Len() int               { return len(d.queue) }
+
+// priorityDiscipline serves the highest priority first, then in arrival
+// order. A full queue displaces the newest waiter of lower priority.
+type priorityDiscipline struct {
+	waiting [backends.PriorityCritical + 1][]*Waiter // FIFO per priority
+}
+
+func (d *priorityDiscipline) Push(w *Waiter) {
+	p := clampPriority(w.Priority)
+	d.waiting[p] = append(d.waiting[p], w)
+}
+
+func (d *priorityDiscipline) Pop() *Waiter {
+	for p := backends.PriorityCritical; p >= backends.PriorityBestEffort; p-- {
+		if queue := d.waiting[p]; len(queue) > 0 {
+			d.waiting[p] = queue[1:]
+			return queue[0]
+		}
+	}
+	return nil
+}
+
+func (d *priorityDiscipline) Remove(w *Waiter) bool {
+	p := clampPriority(w.Priority)
+	var ok bool
+	d.waiting[p], ok = removeWaiter(d.waiting[p], w)
+	return ok
+}
+
+func (d *priorityDiscipline) Victim(incoming *Waiter) *Waiter {
+	for p := backends.PriorityBestEffort; p < clampPriority(incoming.Priority); p++ {
+		if queue := d.waiting[p]; len(queue) > 0 {
+			d.waiting[p] = queue[:len(queue)-1]
+			return queue[len(queue)-1]
+		}
+	}
+	return nil
+}
+
+func (d *priorityDiscipline) Len() int {
+	n := 0
+	for _, queue := range d.waiting {
+		n += len(queue)
+	}
+	return n
+}
+
+// edfDiscipline serves the earliest deadline first. Waiters without a
+// deadline follow in arrival order. A full queue displaces the waiter with
+// the latest deadline when the incoming request is due sooner.
+type edfDiscipline struct {
+	queue []*Waiter // sorted, most urgent first
+}
+
+// edfBefore reports whether a is due before b
+func edfBefore(a, b *Waiter) bool {
+	switch {
+	case a.Deadline.IsZero() != b.Deadline.IsZero():
+		return !a.Deadline.IsZero()
+	case !a.Deadline.Equal(b.Deadline):
+		return a.Deadline.Before(b.Deadline)
+	default:
+		return a.Seq < b.Seq
+	}
+}
+
+func (d *edfDiscipline) Push(w *Waiter) {
+	i := sort.Search(len(d.queue), func(i int) bool { return edfBefore(w, d.queue[i]) })
+	d.queue = append(d.queue, nil)
+	copy(d.queue[i+1:], d.queue[i:])
+	d.queue[i] = w
+}
+
+func (d *edfDiscipline) Pop() *Waiter {
+	if len(d.queue) == 0 {
+		return nil
+	}
+	w := d.queue[0]
+	d.queue = d.queue[1:]
+	return w
+}
+
+func (d *edfDiscipline) Remove(w *Waiter) bool {
+	var ok bool
+	d.queue, ok = removeWaiter(d.queue, w)
+	return ok
+}
+
+func (d *edfDiscipline) Victim(incoming *Waiter) *Waiter {
+	if len(d.queue) == 0 {
+		return nil
+	}
+	last := d.queue[len(d.queue)-1]
+	if !edfBefore(incoming, last) {
+		return nil
+	}
+	d.queue = d.queue[:len(d.queue)-1]
+	return last
+}
+
+func (d *edfDiscipline) Len() int { return len(d.queue) }
+
+// fairShareDiscipline takes turns between tenants so that one tenant's
+// burst cannot starve the others; each tenant's own requests are served by
+// priority, then arrival. A full queue displaces the newest waiter of the
+// tenant with the most waiting when the incoming tenant has fewer.
+type fairShareDiscipline struct {
+	tenants []string // round-robin order of tenants with waiters
+	queues  map[string]*priorityDiscipline
+	n       int
+}
+
+func (d *fairShareDiscipline) Push(w *Waiter) {
+	if d.queues == nil {
+		d.queues = make(map[string]*priorityDiscipline)
+	}
+	q, ok := d.queues[w.Tenant]
+	if !ok {
+		q = &priorityDiscipline{}
+		d.queues[w.Tenant] = q
+		d.tenants = append(d.tenants, w.Tenant)
+	}
+	q.Push(w)
+	d.n++
+}
+
+func (d *fairShareDiscipline) Pop() *Waiter {
+	if len(d.tenants) == 0 {
+		return nil
+	}
+	tenant := d.tenants[0]
+	q := d.queues[tenant]
+	w := q.Pop()
+	d.n--
+
+	// The tenant goes to the back of the line, or leaves it when drained
+	d.tenants = d.tenants[1:]
+	if q.Len() > 0 {
+		d.tenants = append(d.tenants, tenant)
+	} else {
+		delete(d.queues, tenant)
+	}
+	return w
+}
+
+func (d *fairShareDiscipline) Remove(w *Waiter) bool {
+	q, ok := d.queues[w.Tenant]
+	if !ok || !q.Remove(w) {
+		return false
+	}
+	d.n--
+	d.dropIfEmpty(w.Tenant)
+	return true
+}
+
+func (d *fairShareDiscipline) Victim(incoming *Waiter) *Waiter {
+	var heaviest string
+	most := 0
+	for _, tenant := range d.tenants {
+		if n := d.queues[tenant].Len(); n > most {
+			heaviest, most = tenant, n
+		}
+	}
+	incomingLen := 0
+	if q, ok := d.queues[incoming.Tenant]; ok {
+		incomingLen = q.Len()
+	}
+	if most == 0 || incomingLen+1 >= most {
+		return nil
+	}
+
+	// Take the heaviest tenant's least important, newest waiter
+	q := d.queues[heaviest]
+	for p := backends.PriorityBestEffort; p <= backends.PriorityCritical; p++ {
+		if queue := q.waiting[p]; len(queue) > 0 {
+			q.waiting[p] = queue[:len(queue)-1]
+			d.n--
+			d.dropIfEmpty(heaviest)
+			return queue[len(queue)-1]
+		}
+	}
+	return nil
+}
+
+// dropIfEmpty forgets a tenant with nothing left queued
+func (d *fairShareDiscipline) dropIfEmpty(tenant string) {
+	if d.queues[tenant].Len() > 0 {
+		return
+	}
+	delete(d.queues, tenant)
+	for i, t := range d.tenants {
+		if t == tenant {
+			d.tenants = append(d.tenants[:i:i], d.tenants[i+1:]...)
+			break
+		}
+	}
+}
+
+func (d *fairShareDiscipline) Len() int { return d.n }
@@ -0,0 +1,304 @@
+package router
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// drain pops every waiter and returns their sequence numbers
+func drain(d Discipline) []uint64 {
+	var order []uint64
+	for w := d.Pop(); w != nil; w = d.Pop() {
+		order = append(order, w.Seq)
+	}
+	return order
+}
+
+func equalSeqs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDisciplines_Order(t *testing.T) {
+	base := time.Unix(1000, 0)
+	waiters := func() []*Waiter {
+		return []*Waiter{
+			{Seq: 1, Priority: backends.PriorityBestEffort, Tenant: "a", Deadline: base.Add(3 * time.Second)},
+			{Seq: 2, Priority: backends.PriorityNormal, Tenant: "a"},
+			{Seq: 3, Priority: backends.PriorityCritical, Tenant: "a", Deadline: base.Add(5 * time.Second)},
+			{Seq: 4, Priority: backends.PriorityNormal, Tenant: "b", Deadline: base.Add(time.Second)},
+			{Seq: 5, Priority: backends.PriorityNormal, Tenant: "c"},
+		}
+	}
+
+	for policy, want := range map[string][]uint64{
+		PolicyFIFO:      {1, 2, 3, 4, 5},
+		PolicyPriority:  {3, 2, 4, 5, 1},
+		PolicyEDF:       {4, 1, 3, 2, 5},
+		PolicyFairShare: {3, 4, 5, 2, 1},
+	} {
+		d := disciplineFactory(policy)()
+		for _, w := range waiters() {
+			d.Push(w)
+		}
+		if d.Len() != 5 {
+			t.Errorf("%s: expected 5 queued, got %d", policy, d.Len())
+		}
+		if got := drain(d); !equalSeqs(got, want) {
+			t.Errorf("%s: dequeued %v, want %v", policy, got, want)
+		}
+		if d.Len() != 0 {
+			t.Errorf("%s: expected empty queue, got %d", policy, d.Len())
+		}
+	}
+}
+
+func TestDisciplines_Remove(t *testing.T) {
+	for _, policy := range []string{PolicyFIFO, PolicyPriority, PolicyEDF, PolicyFairShare} {
+		d := disciplineFactory(policy)()
+		a := &Waiter{Seq: 1, Tenant: "a"}
+		b := &Waiter{Seq: 2, Tenant: "b"}
+		d.Push(a)
+		d.Push(b)
+
+		if !d.Remove(a) || d.Remove(a) {
+			t.Errorf("%s: expected a single successful remove", policy)
+		}
+		if got := drain(d); !equalSeqs(got, []uint64{2}) {
+			t.Errorf("%s: dequeued %v after remove", policy, got)
+		}
+	}
+}
+
+func TestDisciplines_Victim(t *testing.T) {
+	base := time.Unix(1000, 0)
+
+	fifo := disciplineFactory(PolicyFIFO)()
+	fifo.Push(&Waiter{Seq: 1})
+	if fifo.Victim(&Waiter{Priority: backends.PriorityCritical}) != nil {
+		t.Error("fifo: expected new requests to be rejected")
+	}
+
+	edf := disciplineFactory(PolicyEDF)()
+	edf.Push(&Waiter{Seq: 1, Deadline: base.Add(time.Second)})
+	edf.Push(&Waiter{Seq: 2})
+	if v := edf.Victim(&Waiter{Seq: 3, Deadline: base.Add(2 * time.Second)}); v == nil || v.Seq != 2 {
+		t.Errorf("edf: expected the waiter without a deadline displaced, got %+v", v)
+	}
+	if v := edf.Victim(&Waiter{Seq: 4, Deadline: base.Add(2 * time.Second)}); v != nil {
+		t.Errorf("edf: expected a later deadline to be rejected, got %+v", v)
+	}
+
+	fair := disciplineFactory(PolicyFairShare)()
+	for i := uint64(1); i <= 3; i++ {
+		fair.Push(&Waiter{Seq: i, Tenant: "heavy"})
+	}
+	fair.Push(&Waiter{Seq: 4, Tenant: "light"})
+	if v := fair.Victim(&Waiter{Tenant: "light"}); v == nil || v.Seq != 3 {
+		t.Errorf("fair_share: expected the heavy tenant's newest waiter displaced, got %+v", v)
+	}
+	if v := fair.Victim(&Waiter{Tenant: "heavy"}); v != nil {
+		t.Errorf("fair_share: expected the heavy tenant to be rejected, got %+v", v)
+	}
+	if fair.Len() != 3 {
+		t.Errorf("fair_share: expected 3 queued, got %d", fair.Len())
+	}
+}
+
+func TestRegisterDiscipline(t *testing.T) {
+	if HasDiscipline("lifo") {
+		t.Fatal("lifo should not be registered yet")
+	}
+	RegisterDiscipline("lifo", func() Discipline { return &lifoDiscipline{} })
+	defer func() {
+		disciplinesMu.Lock()
+		delete(disciplines, "lifo")
+		disciplinesMu.Unlock()
+	}()
+	if !HasDiscipline("lifo") {
+		t.Fatal("Expected lifo to be registered")
+	}
+
+	d := disciplineFactory("lifo")()
+	d.Push(&Waiter{Seq: 1})
+	d.Push(&Waiter{Seq: 2})
+	if got := drain(d); !equalSeqs(got, []uint64{2, 1}) {
+		t.Errorf("Expected the registered policy, dequeued %v", got)
+	}
+
+	// Unknown names fall back to priority scheduling
+	if _, ok := disciplineFactory("nope")().(*priorityDiscipline); !ok {
+		t.Error("Expected priority scheduling for an unknown policy")
+	}
+}
+
+// lifoDiscipline is a policy registered from outside the built-ins
+type lifoDiscipline struct{ fifoDiscipline }
+
+func (d *lifoDiscipline) Pop() *Waiter {
+	if len(d.queue) == 0 {
+		return nil
+	}
+	w := d.queue[len(d.queue)-1]
+	d.queue = d.queue[:len(d.queue)-1]
+	return w
+}
+
+func TestScheduler_FairSharePolicy(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{Enabled: true, DefaultMaxConcurrent: 1, Policy: PolicyFairShare})
+	tenant := func(name string) context.Context {
+		return auth.WithKeyInfo(context.Background(), auth.APIKeyInfo{Name: name})
+	}
+
+	release, err := s.Acquire(tenant("etl"), "gpu", backends.PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 4)
+	acquire := func(name string) {
+		rel, err := s.Acquire(tenant(name), "gpu", backends.PriorityNormal)
+		if err != nil {
+			t.Errorf("Acquire(%s) failed: %v", name, err)
+			return
+		}
+		order <- name
+		rel()
+	}
+	for i, name := range []string{"etl", "etl", "etl", "app"} {
+		go acquire(name)
+		waitQueued(t, s, "gpu", i+1)
+	}
+	release()
+
+	// The app request is served second, not behind the whole ETL backlog
+	want := []string{"etl", "app", "etl", "etl"}
+	for i, name := range want {
+		if got := <-order; got != name {
+			t.Errorf("dequeue %d: got %s, want %s", i, got, name)
+		}
+	}
+}
+
+func TestScheduler_EDFPolicy(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{Enabled: true, DefaultMaxConcurrent: 1, Policy: PolicyEDF})
+	release, _ := s.Acquire(context.Background(), "gpu", backends.PriorityNormal)
+
+	order := make(chan string, 2)
+	go func() {
+		rel, err := s.Acquire(context.Background(), "gpu", backends.PriorityNormal)
+		if err == nil {
+			order <- "no deadline"
+			rel()
+		}
+	}()
+	waitQueued(t, s, "gpu", 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		rel, err := s.Acquire(ctx, "gpu", backends.PriorityBestEffort)
+		if err == nil {
+			order <- "deadline"
+			rel()
+		}
+	}()
+	waitQueued(t, s, "gpu", 2)
+	release()
+
+	if first := <-order; first != "deadline" {
+		t.Errorf("Expected the request with a deadline first, got %s", first)
+	}
+	<-order
+}
+
+// loadTrace reads a recorded trace from testdata/traces
+func loadTrace(t *testing.T, name string) []TraceJob {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "traces", name+".jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to open trace: %v", err)
+	}
+	defer f.Close()
+	trace, err := ReadTrace(f)
+	if err != nil {
+		t.Fatalf("Failed to read trace %s: %v", name, err)
+	}
+	return trace
+}
+
+// compare simulates every built-in policy on a trace
+func compare(t *testing.T, name string, cfg SimulationConfig) map[string]SimulationResult {
+	t.Helper()
+	trace := loadTrace(t, name)
+	results := make(map[string]SimulationResult)
+	for _, policy := range []string{PolicyFIFO, PolicyPriority, PolicyEDF, PolicyFairShare} {
+		cfg.Policy = policy
+		r := Simulate(trace, cfg)
+		if r.Jobs != len(trace) {
+			t.Errorf("%s/%s: simulated %d of %d jobs", name, policy, r.Jobs, len(trace))
+		}
+		t.Logf("%-14s %-10s mean %6.0fms p95 %6.0fms max %6.0fms misses %3d rejected %3d",
+			name, policy, r.MeanWaitMs, r.P95WaitMs, r.MaxWaitMs, r.DeadlineMisses, r.Rejected)
+		results[policy] = r
+	}
+	return results
+}
+
+func TestSimulate_MixedPriority(t *testing.T) {
+	results := compare(t, "mixed_priority", SimulationConfig{MaxConcurrent: 2})
+
+	fifo, prio := results[PolicyFIFO], results[PolicyPriority]
+	if prio.WaitByPriority["critical"] >= fifo.WaitByPriority["critical"]/2 {
+		t.Errorf("Expected priority scheduling to at least halve critical waits: fifo %.0fms, priority %.0fms",
+			fifo.WaitByPriority["critical"], prio.WaitByPriority["critical"])
+	}
+}
+
+func TestSimulate_TenantBurst(t *testing.T) {
+	results := compare(t, "tenant_burst", SimulationConfig{MaxConcurrent: 2})
+
+	fifo, fair := results[PolicyFIFO], results[PolicyFairShare]
+	if fair.WaitByTenant["app"] >= fifo.WaitByTenant["app"]/2 {
+		t.Errorf("Expected fair share to shield the app tenant: fifo %.0fms, fair_share %.0fms",
+			fifo.WaitByTenant["app"], fair.WaitByTenant["app"])
+	}
+}
+
+func TestSimulate_Deadlines(t *testing.T) {
+	results := compare(t, "deadlines", SimulationConfig{MaxConcurrent: 2})
+
+	if fifo, edf := results[PolicyFIFO], results[PolicyEDF]; edf.DeadlineMisses >= fifo.DeadlineMisses {
+		t.Errorf("Expected EDF to miss fewer deadlines: fifo %d, edf %d", fifo.DeadlineMisses, edf.DeadlineMisses)
+	}
+}
+
+func TestSimulate_QueueDepth(t *testing.T) {
+	trace := []TraceJob{
+		{ArrivalMs: 0, DurationMs: 100, Priority: 1},
+		{ArrivalMs: 1, DurationMs: 100, Priority: 0},
+		{ArrivalMs: 2, DurationMs: 100, Priority: 3},
+		{ArrivalMs: 3, DurationMs: 100, Priority: 0},
+	}
+
+	r := Simulate(trace, SimulationConfig{Policy: PolicyPriority, MaxConcurrent: 1, MaxQueueDepth: 1})
+	if r.Rejected != 2 {
+		t.Fatalf("Expected 2 rejections, got %+v", r)
+	}
+	// The critical request displaced the best-effort one and ran second
+	if _, ran := r.WaitByPriority["best-effort"]; ran || r.WaitByPriority["critical"] != 98 {
+		t.Errorf("Unexpected waits: %+v", r.WaitByPriority)
+	}
+}
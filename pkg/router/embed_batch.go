@@ -0,0 +1,207 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	proxyerrors "github.com/daoneill/ollama-proxy/pkg/errors"
+)
+
+const (
+	// DefaultEmbedChunkSize is how many inputs of a batch a backend takes
+	// at a time
+	DefaultEmbedChunkSize = 32
+	// DefaultEmbedConcurrency caps concurrent embeddings of a batch on a
+	// backend without a scheduler limit
+	DefaultEmbedConcurrency = 4
+)
+
+// EmbedBatchConfig controls how embedding batches are split across backends
+type EmbedBatchConfig struct {
+	ChunkSize     int // default DefaultEmbedChunkSize
+	MaxPerBackend int // default DefaultEmbedConcurrency
+}
+
+// EmbedItem is the outcome of embedding one input of a batch
+type EmbedItem struct {
+	Index     int
+	Embedding []float32
+	Backend   string // "" when no backend took the input
+	Err       error
+}
+
+// EmbedBatchResult holds the items of a batch in input order
+type EmbedBatchResult struct {
+	Items    []EmbedItem
+	Backends []string // that embedded at least one input, sorted
+	Failed   int
+}
+
+// embedSpan is a chunk of a batch, inputs [start, end)
+type embedSpan struct {
+	start, end int
+}
+
+// EmbedBatch embeds inputs with model across every healthy backend that
+// serves it. The batch is split into chunks that backends take as they
+// finish the last, each running up to its concurrency limit at once, so
+// faster backends embed more of it. An input that fails is reported in its
+// item; the batch only fails when no backend can embed model. dimensions
+// is passed on to backends as EmbedRequest.Dimensions.
+func (r *Router) EmbedBatch(ctx context.Context, annotations *backends.Annotations, model string, dimensions int, inputs []string) (*EmbedBatchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("routing cancelled: %w", err)
+	}
+
+	pool, err := r.embedPool(annotations, model)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	cfg := r.embedBatch
+	r.mu.RUnlock()
+	chunkSize := cfg.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultEmbedChunkSize
+	}
+
+	// Queue every chunk up front so workers leaving early never block it
+	spans := make(chan embedSpan, (len(inputs)+chunkSize-1)/chunkSize)
+	for start := 0; start < len(inputs); start += chunkSize {
+		spans <- embedSpan{start: start, end: min(start+chunkSize, len(inputs))}
+	}
+	close(spans)
+
+	result := &EmbedBatchResult{Items: make([]EmbedItem, len(inputs))}
+	for i := range result.Items {
+		result.Items[i].Index = i
+	}
+
+	var wg sync.WaitGroup
+	for _, backend := range pool {
+		for n := r.embedConcurrency(backend.ID(), cfg); n > 0; n-- {
+			wg.Add(1)
+			go func(backend backends.Backend) {
+				defer wg.Done()
+				for span := range spans {
+					for i := span.start; i < span.end; i++ {
+						r.embedItem(ctx, backend, annotations, model, dimensions, inputs[i], &result.Items[i])
+					}
+					// Leave the rest of the batch to the healthy backends
+					if !backend.IsHealthy() {
+						return
+					}
+				}
+			}(backend)
+		}
+	}
+	wg.Wait()
+
+	used := make(map[string]bool)
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Backend == "" && item.Err == nil {
+			item.Err = fmt.Errorf("no healthy backend left to embed input %d", i)
+		}
+		if item.Err != nil {
+			result.Failed++
+			continue
+		}
+		used[item.Backend] = true
+	}
+	for id := range used {
+		result.Backends = append(result.Backends, id)
+	}
+	sort.Strings(result.Backends)
+	return result, nil
+}
+
+// embedItem embeds one input on backend, tracked like a routed request so
+// it counts towards the backend's queue depth and concurrency limit
+func (r *Router) embedItem(ctx context.Context, backend backends.Backend, annotations *backends.Annotations, model string, dimensions int, input string, item *EmbedItem) {
+	item.Backend = backend.ID()
+	if err := ctx.Err(); err != nil {
+		item.Err = err
+		return
+	}
+
+	r.mu.RLock()
+	tracked := r.trackBackend(backend, annotations)
+	r.mu.RUnlock()
+
+	resp, err := tracked.Embed(ctx, &backends.EmbedRequest{Text: input, Model: model, Dimensions: dimensions})
+	if err != nil {
+		item.Err = err
+		return
+	}
+	item.Embedding = resp.Embedding
+}
+
+// embedPool returns the backends a batch for model is split across: the
+// explicit target when it can take it, otherwise every candidate that
+// embeds model
+func (r *Router) embedPool(annotations *backends.Annotations, model string) ([]backends.Backend, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.kill.PausedAll() {
+		return nil, fmt.Errorf("generation paused by operator")
+	}
+	mc := r.applyModeConstraints(annotations)
+
+	embeds := func(backend backends.Backend) bool {
+		return backend.SupportsEmbed() && backend.SupportsModel(model)
+	}
+
+	if annotations.Target != "" && annotations.Target != "auto" {
+		if backend, exists := r.backends[annotations.Target]; exists && embeds(backend) {
+			if admitted, _ := r.admit(backend, annotations.Priority); backend.IsHealthy() && admitted && mc.allows(backend.ID()) && !r.kill.Paused(backend.ID()) &&
+				r.labelsAllow(backend.ID(), annotations.Labels) {
+				return []backends.Backend{backend}, nil
+			}
+		}
+	}
+
+	var pool []backends.Backend
+	for _, backend := range r.filterCandidates(annotations) {
+		if embeds(backend) {
+			pool = append(pool, backend)
+		}
+	}
+	if len(pool) == 0 {
+		healthy := 0
+		for _, backend := range r.backends {
+			if backend.IsHealthy() {
+				healthy++
+			}
+		}
+		constraints := []string{"embeddings", fmt.Sprintf("model=%s", model)}
+		if mc != nil {
+			constraints = append(constraints, mc.Reason)
+		}
+		return nil, proxyerrors.NewNoBackendsError(len(r.backends), healthy, constraints)
+	}
+
+	// Stable order keeps chunk assignment reproducible in tests
+	sort.Slice(pool, func(i, j int) bool { return pool[i].ID() < pool[j].ID() })
+	return pool, nil
+}
+
+// embedConcurrency is how many inputs of a batch backendID embeds at once:
+// its scheduler limit when it has one, so a batch never queues behind
+// itself, otherwise cfg.MaxPerBackend
+func (r *Router) embedConcurrency(backendID string, cfg EmbedBatchConfig) int {
+	if r.scheduler != nil {
+		if limit := r.scheduler.Limit(backendID); limit > 0 {
+			return limit
+		}
+	}
+	if cfg.MaxPerBackend > 0 {
+		return cfg.MaxPerBackend
+	}
+	return DefaultEmbedConcurrency
+}
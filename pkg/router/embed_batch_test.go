@@ -0,0 +1,99 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// embedBackend embeds text as its length, failing on "bad", and records
+// how many embeddings it ran at once
+type embedBackend struct {
+	MockBackend
+	mu     sync.Mutex
+	active int
+	peak   int
+	embeds int
+}
+
+func (e *embedBackend) SupportsEmbed() bool { return true }
+
+func (e *embedBackend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	e.mu.Lock()
+	e.active++
+	e.embeds++
+	e.peak = max(e.peak, e.active)
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.active--
+		e.mu.Unlock()
+	}()
+
+	time.Sleep(time.Millisecond)
+	if req.Text == "bad" {
+		return nil, errors.New("input rejected")
+	}
+	return &backends.EmbedResponse{Embedding: []float32{float32(len(req.Text))}}, nil
+}
+
+func TestRouter_EmbedBatchSplitsAcrossBackends(t *testing.T) {
+	r := NewRouter(Config{
+		Scheduler:  SchedulerConfig{Enabled: true, MaxConcurrent: map[string]int{"npu": 2}},
+		EmbedBatch: EmbedBatchConfig{ChunkSize: 4, MaxPerBackend: 3},
+	})
+	npu := &embedBackend{MockBackend: MockBackend{id: "npu", healthy: true}}
+	gpu := &embedBackend{MockBackend: MockBackend{id: "gpu", healthy: true}}
+	chat := &MockBackend{id: "chat", healthy: true} // no embeddings
+	r.RegisterBackend(npu)
+	r.RegisterBackend(gpu)
+	r.RegisterBackend(chat)
+
+	inputs := make([]string, 200)
+	for i := range inputs {
+		inputs[i] = fmt.Sprintf("input %d", i)
+	}
+	inputs[7] = "bad"
+
+	result, err := r.EmbedBatch(context.Background(), &backends.Annotations{}, "nomic-embed-text", 0, inputs)
+	if err != nil {
+		t.Fatalf("EmbedBatch failed: %v", err)
+	}
+	if result.Failed != 1 || result.Items[7].Err == nil {
+		t.Errorf("Expected only input 7 to fail, got %d failures", result.Failed)
+	}
+	for i, item := range result.Items {
+		if item.Index != i {
+			t.Fatalf("Expected items in input order, got %d at %d", item.Index, i)
+		}
+		if i != 7 && (item.Err != nil || item.Embedding[0] != float32(len(inputs[i]))) {
+			t.Errorf("Unexpected item %d: %+v", i, item)
+		}
+	}
+	if len(result.Backends) != 2 || npu.embeds == 0 || gpu.embeds == 0 {
+		t.Errorf("Expected the batch split across both embedding backends, got %v", result.Backends)
+	}
+	if npu.peak > 2 {
+		t.Errorf("Expected at most the scheduler limit of 2 on npu, got %d", npu.peak)
+	}
+	if gpu.peak > 3 {
+		t.Errorf("Expected at most 3 concurrent embeddings on gpu, got %d", gpu.peak)
+	}
+	if r.queueMgr.GetRawQueueDepth("npu") != 0 || r.queueMgr.GetRawQueueDepth("gpu") != 0 {
+		t.Error("Expected no requests left in flight")
+	}
+}
+
+func TestRouter_EmbedBatchNoBackends(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "chat", healthy: true})
+
+	if _, err := r.EmbedBatch(context.Background(), &backends.Annotations{}, "nomic-embed-text", 0, []string{"a", "b"}); err == nil {
+		t.Error("Expected an error without an embedding backend")
+	}
+}
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/energy"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+)
+
+// SetEnergyMeter installs the meter that measures the energy of each
+// request, reported in its stats in place of the backend's estimate (nil =
+// estimated from the backend's PowerWatts)
+func (r *Router) SetEnergyMeter(meter *energy.Meter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.energy = meter
+}
+
+// measure ends span and records the energy it measured against the
+// backend; ok is false when the backend's hardware isn't metered
+func (qtb *QueueTrackingBackend) measure(span *energy.Span) (wh float64, ok bool) {
+	wh, ok = span.End()
+	if ok {
+		metrics.RecordEnergyConsumed(qtb.Backend.ID(), qtb.Backend.Hardware(), wh)
+	}
+	return wh, ok
+}
+
+// measuredStats returns stats with EnergyWh replaced by a measurement,
+// allocating stats when the backend reported none
+func measuredStats(stats *backends.GenerationStats, wh float64) *backends.GenerationStats {
+	if stats == nil {
+		stats = &backends.GenerationStats{}
+	}
+	stats.EnergyWh = float32(wh)
+	stats.EnergyMeasured = true
+	return stats
+}
+
+// setMeasuredEnergy reports wh in the stats of a single-response
+// operation's result. Handlers account sequences generated together by
+// the first one's stats, so it carries the energy of them all.
+func setMeasuredEnergy(resp any, wh float64) {
+	switch r := resp.(type) {
+	case *backends.GenerateResponse:
+		if r != nil {
+			r.Stats = measuredStats(r.Stats, wh)
+		}
+	case []*backends.GenerateResponse:
+		if len(r) > 0 && r[0] != nil {
+			r[0].Stats = measuredStats(r[0].Stats, wh)
+		}
+	case *backends.EmbedResponse:
+		if r != nil {
+			r.Stats = measuredStats(r.Stats, wh)
+		}
+	case *backends.TranscribeResponse:
+		if r != nil {
+			r.Stats = measuredStats(r.Stats, wh)
+		}
+	case *backends.SynthesizeResponse:
+		if r != nil {
+			r.Stats = measuredStats(r.Stats, wh)
+		}
+	case *backends.ImageGenResponse:
+		if r != nil {
+			r.Stats = measuredStats(r.Stats, wh)
+		}
+	}
+}
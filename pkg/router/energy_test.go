@@ -0,0 +1,93 @@
+package router
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/energy"
+)
+
+// risingSource reports a cpu counter that gains 100 J on every read
+type risingSource struct{ joules float64 }
+
+func (s *risingSource) Name() string { return "rising" }
+
+func (s *risingSource) Read(ctx context.Context) ([]energy.Counter, error) {
+	s.joules += 100
+	return []energy.Counter{{Hardware: "cpu", Domain: "package-0", Joules: s.joules}}, nil
+}
+
+// newRunningMeter returns a meter that has sampled cpu power twice
+func newRunningMeter(t *testing.T) *energy.Meter {
+	t.Helper()
+	meter := energy.NewMeter(time.Second, &risingSource{})
+	meter.Sample(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	meter.Sample(context.Background())
+	return meter
+}
+
+func TestQueueTrackingBackend_MeasuresEnergy(t *testing.T) {
+	meter := newRunningMeter(t)
+	qtb := &QueueTrackingBackend{
+		Backend:  &MockBackend{id: "cpu-backend", hardware: "cpu", healthy: true},
+		queueMgr: NewQueueManager(),
+		energy:   meter,
+	}
+
+	resp, err := qtb.Generate(context.Background(), &backends.GenerateRequest{})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Stats == nil || !resp.Stats.EnergyMeasured || resp.Stats.EnergyWh <= 0 {
+		t.Errorf("Expected measured energy in the stats, got %+v", resp.Stats)
+	}
+
+	// Hardware without a source keeps the backend's estimate
+	qtb.Backend = &MockBackend{id: "npu-backend", hardware: "npu", healthy: true}
+	resp, _ = qtb.Generate(context.Background(), &backends.GenerateRequest{})
+	if resp.Stats != nil && resp.Stats.EnergyMeasured {
+		t.Errorf("Expected no measurement for unmetered hardware, got %+v", resp.Stats)
+	}
+}
+
+// doneStreamReader sends a single final chunk
+type doneStreamReader struct{ sent bool }
+
+func (r *doneStreamReader) Recv() (*backends.StreamChunk, error) {
+	if r.sent {
+		return nil, context.Canceled
+	}
+	r.sent = true
+	return &backends.StreamChunk{Token: "hi", Done: true}, nil
+}
+
+func (r *doneStreamReader) Close() error { return nil }
+
+func TestQueueTrackingBackend_MeasuresStreamEnergy(t *testing.T) {
+	meter := newRunningMeter(t)
+	qtb := &QueueTrackingBackend{
+		Backend: &mockBackendWithStreamReader{
+			MockBackend:  MockBackend{id: "cpu-backend", hardware: "cpu", healthy: true},
+			streamReader: &doneStreamReader{},
+		},
+		queueMgr: NewQueueManager(),
+		energy:   meter,
+	}
+
+	reader, err := qtb.GenerateStream(context.Background(), &backends.GenerateRequest{})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	defer reader.Close()
+
+	chunk, err := reader.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if chunk.Stats == nil || !chunk.Stats.EnergyMeasured || chunk.Stats.EnergyWh <= 0 {
+		t.Errorf("Expected measured energy in the final chunk, got %+v", chunk.Stats)
+	}
+}
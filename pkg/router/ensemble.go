@@ -0,0 +1,484 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/confidence"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+)
+
+// DefaultEnsembleSize is how many backends an X-Ensemble request is sent to
+const DefaultEnsembleSize = 3
+
+// ensembleVoteMaxWords is the longest answer, in words, counted as a label
+// that can be voted on, such as "positive" or "spam". Longer, free-form
+// answers are picked by the judge.
+const ensembleVoteMaxWords = 4
+
+// EnsembleConfig sends requests annotated Ensemble, or for one of the
+// aliases, to several backends at once and returns the best answer
+type EnsembleConfig struct {
+	Size int // backends queried for an Ensemble request, 2-3 (default DefaultEnsembleSize)
+
+	// Aliases are model names that query several models together, e.g.
+	// {"ensemble-small": {"llama3.2:3b", "qwen2.5:3b", "phi3:mini"}}
+	Aliases map[string][]string
+
+	// Judge scores free-form answers, the best scoring one wins (nil =
+	// heuristics with the default confidence config)
+	Judge confidence.ConfidenceScorer
+}
+
+// SetEnsemble enables ensemble requests (nil = disabled)
+func (r *Router) SetEnsemble(cfg *EnsembleConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cfg != nil && (cfg.Size < 2 || cfg.Size > 3) {
+		sized := *cfg
+		sized.Size = DefaultEnsembleSize
+		cfg = &sized
+	}
+	r.ensemble = cfg
+}
+
+// EnsembleCandidate is one member's answer to an ensemble request
+type EnsembleCandidate struct {
+	Backend  string  `json:"backend"`
+	Model    string  `json:"model"`
+	Response string  `json:"response,omitempty"`
+	Votes    int     `json:"votes,omitempty"`
+	Score    float64 `json:"score,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Winner   bool    `json:"winner,omitempty"`
+}
+
+// EnsembleResult describes how an ensemble request's answer was chosen.
+// Method is "vote" when the members answered with short labels and most
+// agreed, "judge" when the best scoring answer won, or "single" when only
+// one member answered.
+type EnsembleResult struct {
+	Method     string              `json:"method"`
+	Winner     string              `json:"winner"`
+	Candidates []EnsembleCandidate `json:"candidates"`
+}
+
+// ensembleMember is a backend and the model it answers with
+type ensembleMember struct {
+	backend backends.Backend
+	model   string
+}
+
+// EnsembleBackend sends Generate and GenerateStream to every member in
+// parallel and returns one answer: the majority for classification-style
+// prompts, whose answers are short labels, or otherwise the one the judge
+// scores highest. Streams wait for every member and arrive as one chunk.
+//
+// Other calls go to the first member. ID reports the backend whose answer
+// won once it is chosen.
+type EnsembleBackend struct {
+	backends.Backend // first member
+	alias            string
+	members          []ensembleMember
+	track            func(backends.Backend) backends.Backend
+	judge            confidence.ConfidenceScorer
+	candidates       bool
+
+	mu     sync.Mutex
+	winner backends.Backend
+	result *EnsembleResult
+}
+
+// ID returns the ID of the backend whose answer won, the first member
+// until then
+func (eb *EnsembleBackend) ID() string {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	if eb.winner != nil {
+		return eb.winner.ID()
+	}
+	return eb.Backend.ID()
+}
+
+// Members returns the IDs of the backends the request goes to
+func (eb *EnsembleBackend) Members() []string {
+	ids := make([]string, len(eb.members))
+	for i, m := range eb.members {
+		ids[i] = m.backend.ID()
+	}
+	return ids
+}
+
+// Result returns how the answer was chosen, nil until it has been
+func (eb *EnsembleBackend) Result() *EnsembleResult {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	return eb.result
+}
+
+// ReturnsCandidates reports whether the client asked for every member's
+// answer alongside the winner (X-Ensemble-Candidates)
+func (eb *EnsembleBackend) ReturnsCandidates() bool {
+	return eb.candidates
+}
+
+// SupportsModel accepts the alias the ensemble was routed for
+func (eb *EnsembleBackend) SupportsModel(model string) bool {
+	return (eb.alias != "" && model == eb.alias) || eb.Backend.SupportsModel(model)
+}
+
+// SupportsStream is true: streams are answered from Generate
+func (eb *EnsembleBackend) SupportsStream() bool {
+	return true
+}
+
+// ColdStart asks the first member whether model needs loading
+func (eb *EnsembleBackend) ColdStart(ctx context.Context, model string) *backends.ColdStart {
+	return backends.CheckColdStart(ctx, eb.Backend, eb.members[0].requestModel(model))
+}
+
+// SupportsSequences asks the first member whether it generates several
+// completions in one request
+func (eb *EnsembleBackend) SupportsSequences() bool {
+	return backends.SupportsSequences(eb.Backend)
+}
+
+// GenerateSequences goes to the first member alone: several completions
+// already give the client answers to choose from
+func (eb *EnsembleBackend) GenerateSequences(ctx context.Context, req *backends.GenerateRequest, n, bestOf int) ([]*backends.GenerateResponse, error) {
+	return backends.NativeSequences(ctx, eb.Backend, eb.members[0].request(req), n, bestOf)
+}
+
+// requestModel is the model the member answers a request for model with
+func (m ensembleMember) requestModel(model string) string {
+	if m.model != "" {
+		return m.model
+	}
+	return model
+}
+
+// request returns req for the member's model
+func (m ensembleMember) request(req *backends.GenerateRequest) *backends.GenerateRequest {
+	if m.model == "" || m.model == req.Model {
+		return req
+	}
+	copied := *req
+	copied.Model = m.model
+	return &copied
+}
+
+// ensembleAnswer is a member's response to the request
+type ensembleAnswer struct {
+	backend backends.Backend
+	model   string
+	resp    *backends.GenerateResponse
+	err     error
+}
+
+// Generate queries every member and returns the chosen answer. Its stats
+// report the energy every member used. It fails only when every member
+// does.
+func (eb *EnsembleBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	answers := make([]ensembleAnswer, len(eb.members))
+	var wg sync.WaitGroup
+	for i, m := range eb.members {
+		backend := m.backend
+		if i > 0 {
+			backend = eb.track(backend)
+		}
+		answers[i] = ensembleAnswer{backend: backend, model: m.requestModel(req.Model)}
+		wg.Add(1)
+		go func(a *ensembleAnswer, r *backends.GenerateRequest) {
+			defer wg.Done()
+			a.resp, a.err = a.backend.Generate(ctx, r)
+		}(&answers[i], m.request(req))
+	}
+	wg.Wait()
+
+	var ok []*ensembleAnswer
+	var firstErr error
+	energyWh := float32(0)
+	for i := range answers {
+		a := &answers[i]
+		if a.err != nil {
+			if firstErr == nil {
+				firstErr = a.err
+			}
+			continue
+		}
+		if a.resp == nil {
+			continue
+		}
+		ok = append(ok, a)
+		if a.resp.Stats != nil {
+			energyWh += a.resp.Stats.EnergyWh
+		}
+	}
+	if len(ok) == 0 {
+		metrics.RecordEnsembleRequest("none")
+		if firstErr == nil {
+			firstErr = fmt.Errorf("no ensemble member answered")
+		}
+		return nil, firstErr
+	}
+
+	result := &EnsembleResult{Candidates: make([]EnsembleCandidate, len(answers))}
+	for i, a := range answers {
+		result.Candidates[i] = EnsembleCandidate{Backend: a.backend.ID(), Model: a.model}
+		if a.err != nil {
+			result.Candidates[i].Error = a.err.Error()
+		} else if a.resp != nil && eb.candidates {
+			result.Candidates[i].Response = a.resp.Response
+		}
+	}
+
+	winner := eb.choose(ctx, req.Prompt, answers, ok, result)
+	result.Winner = winner.backend.ID()
+	for i := range answers {
+		if &answers[i] == winner {
+			result.Candidates[i].Winner = true
+		}
+	}
+	metrics.RecordEnsembleRequest(result.Method)
+
+	eb.mu.Lock()
+	eb.winner = winner.backend
+	eb.result = result
+	eb.mu.Unlock()
+
+	resp := *winner.resp
+	if resp.Stats != nil {
+		stats := *resp.Stats
+		stats.EnergyWh = energyWh
+		resp.Stats = &stats
+	}
+	return &resp, nil
+}
+
+// choose picks the winning answer and records the method and each
+// candidate's votes or score in result. ok are the answers that succeeded,
+// in member order, so ties go to the best routed member.
+func (eb *EnsembleBackend) choose(ctx context.Context, prompt string, answers []ensembleAnswer, ok []*ensembleAnswer, result *EnsembleResult) *ensembleAnswer {
+	if len(ok) == 1 {
+		result.Method = "single"
+		return ok[0]
+	}
+
+	// Short labels are voted on; a majority needs at least two agreeing
+	if labels, allShort := voteLabels(ok); allShort {
+		votes := make(map[string]int, len(ok))
+		for _, label := range labels {
+			votes[label]++
+		}
+		best := 0
+		for i, label := range labels {
+			if votes[label] > votes[labels[best]] {
+				best = i
+			}
+		}
+		if votes[labels[best]] > 1 {
+			for i, a := range ok {
+				result.Candidates[indexOf(answers, a)].Votes = votes[labels[i]]
+			}
+			result.Method = "vote"
+			return ok[best]
+		}
+	}
+
+	// Free-form answers, or labels all different: the judge picks
+	judge := eb.judge
+	if judge == nil {
+		judge = confidence.NewConfidenceEstimator(nil)
+	}
+	scores := make([]float64, len(ok))
+	var wg sync.WaitGroup
+	for i, a := range ok {
+		wg.Add(1)
+		go func(i int, a *ensembleAnswer) {
+			defer wg.Done()
+			score, err := judge.Score(ctx, &confidence.Answer{
+				Prompt:        prompt,
+				Response:      a.resp.Response,
+				Model:         a.model,
+				Backend:       a.backend,
+				TokenLogprobs: a.resp.TokenLogprobs,
+			})
+			if err == nil {
+				scores[i] = score.Overall
+			}
+		}(i, a)
+	}
+	wg.Wait()
+
+	best := 0
+	for i, a := range ok {
+		result.Candidates[indexOf(answers, a)].Score = scores[i]
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	result.Method = "judge"
+	return ok[best]
+}
+
+// voteLabels normalizes each answer to a label for voting, lower case
+// without surrounding punctuation. allShort is false when an answer is too
+// long to be a label.
+func voteLabels(answers []*ensembleAnswer) (labels []string, allShort bool) {
+	labels = make([]string, len(answers))
+	for i, a := range answers {
+		label := strings.ToLower(strings.TrimFunc(a.resp.Response, func(r rune) bool {
+			return unicode.IsSpace(r) || unicode.IsPunct(r)
+		}))
+		words := strings.Fields(label)
+		if len(words) == 0 || len(words) > ensembleVoteMaxWords {
+			return nil, false
+		}
+		labels[i] = strings.Join(words, " ")
+	}
+	return labels, true
+}
+
+// indexOf returns a's position among answers
+func indexOf(answers []ensembleAnswer, a *ensembleAnswer) int {
+	for i := range answers {
+		if &answers[i] == a {
+			return i
+		}
+	}
+	return -1
+}
+
+// GenerateStream answers with the chosen answer as a single chunk, since
+// every member must finish before one is chosen
+func (eb *EnsembleBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	resp, err := eb.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &ensembleStreamReader{chunk: &backends.StreamChunk{
+		Token: resp.Response,
+		Done:  true,
+		Stats: resp.Stats,
+	}}, nil
+}
+
+// ensembleStreamReader sends the chosen answer, then io.EOF
+type ensembleStreamReader struct {
+	chunk *backends.StreamChunk
+}
+
+func (r *ensembleStreamReader) Recv() (*backends.StreamChunk, error) {
+	if r.chunk == nil {
+		return nil, io.EOF
+	}
+	chunk := r.chunk
+	r.chunk = nil
+	return chunk, nil
+}
+
+func (r *ensembleStreamReader) Close() error {
+	return nil
+}
+
+// ensembleLocked wraps the routed backend to query an ensemble when the
+// request is annotated Ensemble or is for an alias, nil when ensembles are
+// disabled or an Ensemble request finds no second backend. models are the
+// alias's models, nil for an Ensemble request; an alias always gets an
+// ensemble, if only of one member, to answer for its models. Caller must
+// hold r.mu.
+func (r *Router) ensembleLocked(selected, dispatched backends.Backend, annotations *backends.Annotations, alias string, models []string) *EnsembleBackend {
+	if r.ensemble == nil || (!annotations.Ensemble && models == nil) {
+		return nil
+	}
+	if annotations.Target != "" && annotations.Target != "auto" {
+		return nil
+	}
+
+	candidates, _, _ := r.fitMemory(r.filterCandidates(annotations), annotations)
+	var members []ensembleMember
+	if models == nil {
+		// The requested model on as many backends as configured, the
+		// routed one first
+		members = append(members, ensembleMember{backend: selected})
+		var others []backends.Backend
+		for _, backend := range candidates {
+			if backend.ID() == selected.ID() {
+				continue
+			}
+			if annotations.Model != "" && !backend.SupportsModel(annotations.Model) {
+				continue
+			}
+			others = append(others, backend)
+		}
+		if len(others) > 0 {
+			for _, c := range r.scoreCandidates(others, annotations) {
+				if len(members) == r.ensemble.Size {
+					break
+				}
+				members = append(members, ensembleMember{backend: c.backend})
+			}
+		}
+	} else {
+		// Each of the alias's models on the best backend serving it,
+		// preferring backends not already answering
+		members = append(members, ensembleMember{backend: selected, model: models[0]})
+		used := map[string]bool{selected.ID(): true}
+		for _, model := range models[1:] {
+			var serving []backends.Backend
+			for _, backend := range candidates {
+				if backend.SupportsModel(model) {
+					serving = append(serving, backend)
+				}
+			}
+			if len(serving) == 0 {
+				continue
+			}
+			scored := r.scoreCandidates(serving, annotations)
+			best := scored[0].backend
+			for _, c := range scored {
+				if !used[c.backend.ID()] {
+					best = c.backend
+					break
+				}
+			}
+			used[best.ID()] = true
+			members = append(members, ensembleMember{backend: best, model: model})
+		}
+	}
+	if len(members) < 2 && models == nil {
+		return nil
+	}
+
+	// The routed member is already tracked, the others are once queried
+	members[0].backend = dispatched
+	return &EnsembleBackend{
+		Backend:    dispatched,
+		alias:      alias,
+		members:    members,
+		track:      func(b backends.Backend) backends.Backend { return r.trackBackend(b, annotations) },
+		judge:      r.ensemble.Judge,
+		candidates: annotations.EnsembleCandidates,
+	}
+}
+
+// ensembleAliasLocked resolves a model alias to the models it queries,
+// nil when annotations' model is not an alias. Routing continues for the
+// first model. Caller must hold r.mu.
+func (r *Router) ensembleAliasLocked(annotations *backends.Annotations) (alias string, models []string) {
+	if r.ensemble == nil || annotations.Model == "" {
+		return "", nil
+	}
+	models, ok := r.ensemble.Aliases[annotations.Model]
+	if !ok || len(models) == 0 {
+		return "", nil
+	}
+	alias = annotations.Model
+	annotations.Model = models[0]
+	return alias, models
+}
@@ -0,0 +1,201 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/confidence"
+)
+
+// ensembleMockBackend answers every request with answer, recording the
+// models it was asked for
+type ensembleMockBackend struct {
+	*MockBackend
+	answer string
+	err    error
+
+	mu     sync.Mutex
+	models []string
+}
+
+func (e *ensembleMockBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	e.mu.Lock()
+	e.models = append(e.models, req.Model)
+	e.mu.Unlock()
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &backends.GenerateResponse{Response: e.answer, Stats: &backends.GenerationStats{EnergyWh: 1}}, nil
+}
+
+// lengthScorer scores longer answers higher
+type lengthScorer struct{}
+
+func (lengthScorer) Score(_ context.Context, answer *confidence.Answer) (*confidence.ConfidenceScore, error) {
+	return &confidence.ConfidenceScore{Overall: float64(len(answer.Response)) / 100}, nil
+}
+
+// newEnsembleRouter registers backends a, b and c, scoring in that order,
+// answering with answers
+func newEnsembleRouter(cfg *EnsembleConfig, answers ...string) (*Router, []*ensembleMockBackend) {
+	r := NewRouter(Config{})
+	var members []*ensembleMockBackend
+	for i, id := range []string{"a", "b", "c"} {
+		m := &ensembleMockBackend{
+			MockBackend: &MockBackend{id: id, healthy: true, avgLatencyMs: int32(10 * (i + 1))},
+			answer:      answers[i],
+		}
+		r.RegisterBackend(m)
+		members = append(members, m)
+	}
+	r.SetEnsemble(cfg)
+	return r, members
+}
+
+func routeEnsemble(t *testing.T, r *Router, annotations *backends.Annotations) *EnsembleBackend {
+	t.Helper()
+	decision, err := r.RouteRequest(context.Background(), annotations)
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	eb, ok := decision.Backend.(*EnsembleBackend)
+	if !ok {
+		t.Fatalf("Expected an ensemble decision, got %T", decision.Backend)
+	}
+	if len(decision.Ensemble) != len(eb.members) {
+		t.Errorf("Expected the decision to list %d members, got %v", len(eb.members), decision.Ensemble)
+	}
+	return eb
+}
+
+func TestEnsemble_VotesOnLabels(t *testing.T) {
+	r, _ := newEnsembleRouter(&EnsembleConfig{Judge: lengthScorer{}}, "Negative", "Positive.", " positive")
+	eb := routeEnsemble(t, r, &backends.Annotations{Ensemble: true})
+
+	resp, err := eb.Generate(context.Background(), &backends.GenerateRequest{Prompt: "Sentiment?"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Response != "Positive." || eb.ID() != "b" {
+		t.Errorf("Expected the majority label from b, got %q from %s", resp.Response, eb.ID())
+	}
+	result := eb.Result()
+	if result.Method != "vote" || result.Candidates[1].Votes != 2 || !result.Candidates[1].Winner {
+		t.Errorf("Expected b to win the vote 2-1, got %+v", result)
+	}
+	if resp.Stats.EnergyWh != 3 {
+		t.Errorf("Expected the energy of every member, got %v", resp.Stats.EnergyWh)
+	}
+}
+
+func TestEnsemble_JudgePicksFreeForm(t *testing.T) {
+	r, _ := newEnsembleRouter(&EnsembleConfig{Judge: lengthScorer{}},
+		"Paris is the capital of France.",
+		"The capital of France is Paris, on the Seine.",
+		"Paris.")
+	eb := routeEnsemble(t, r, &backends.Annotations{Ensemble: true, EnsembleCandidates: true})
+
+	resp, err := eb.Generate(context.Background(), &backends.GenerateRequest{Prompt: "Capital of France?"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if eb.ID() != "b" || resp.Response != "The capital of France is Paris, on the Seine." {
+		t.Errorf("Expected the judge to pick b's answer, got %q from %s", resp.Response, eb.ID())
+	}
+	result := eb.Result()
+	if result.Method != "judge" || result.Winner != "b" {
+		t.Errorf("Expected a judged win for b, got %+v", result)
+	}
+	for _, c := range result.Candidates {
+		if c.Response == "" || c.Score == 0 {
+			t.Errorf("Expected every candidate's answer and score, got %+v", c)
+		}
+	}
+}
+
+func TestEnsemble_MemberFailure(t *testing.T) {
+	r, members := newEnsembleRouter(&EnsembleConfig{Size: 2}, "yes", "yes", "yes")
+	members[0].err = errors.New("connection refused")
+	eb := routeEnsemble(t, r, &backends.Annotations{Ensemble: true})
+	if len(eb.members) != 2 {
+		t.Fatalf("Expected 2 members, got %v", eb.Members())
+	}
+
+	if _, err := eb.Generate(context.Background(), &backends.GenerateRequest{}); err != nil {
+		t.Fatalf("Expected the other member to answer, got %v", err)
+	}
+	result := eb.Result()
+	if result.Method != "single" || result.Winner != "b" || result.Candidates[0].Error == "" {
+		t.Errorf("Expected b to answer alone, got %+v", result)
+	}
+
+	// Every member failing returns an error
+	members[1].err = errors.New("out of memory")
+	eb = routeEnsemble(t, r, &backends.Annotations{Ensemble: true})
+	if _, err := eb.Generate(context.Background(), &backends.GenerateRequest{}); err == nil {
+		t.Error("Expected an error when every member fails")
+	}
+}
+
+func TestEnsemble_Alias(t *testing.T) {
+	r, members := newEnsembleRouter(&EnsembleConfig{Aliases: map[string][]string{"duo": {"m1", "m2"}}}, "yes", "yes", "no")
+	members[0].modelPatterns = []string{"m1"}
+	members[1].modelPatterns = []string{"m1", "m2"}
+	members[2].modelPatterns = []string{"other"}
+
+	eb := routeEnsemble(t, r, &backends.Annotations{Model: "duo"})
+	if !eb.SupportsModel("duo") {
+		t.Error("Expected the ensemble to serve its alias")
+	}
+	if _, err := eb.Generate(context.Background(), &backends.GenerateRequest{Model: "duo"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(members[0].models) != 1 || members[0].models[0] != "m1" || len(members[1].models) != 1 || members[1].models[0] != "m2" {
+		t.Errorf("Expected m1 on a and m2 on b, got %v and %v", members[0].models, members[1].models)
+	}
+	if len(members[2].models) != 0 {
+		t.Errorf("Expected c, serving neither model, left out, got %v", members[2].models)
+	}
+}
+
+func TestEnsemble_Stream(t *testing.T) {
+	r, _ := newEnsembleRouter(&EnsembleConfig{}, "spam", "spam", "ham")
+	eb := routeEnsemble(t, r, &backends.Annotations{Ensemble: true})
+
+	reader, err := eb.GenerateStream(context.Background(), &backends.GenerateRequest{})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	defer reader.Close()
+
+	chunk, err := reader.Recv()
+	if err != nil || chunk.Token != "spam" || !chunk.Done {
+		t.Fatalf("Expected the chosen answer as one chunk, got %v, %v", chunk, err)
+	}
+	if _, err := reader.Recv(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the answer, got %v", err)
+	}
+}
+
+func TestEnsemble_NotWithoutConfigOrAnnotation(t *testing.T) {
+	r, _ := newEnsembleRouter(nil, "yes", "yes", "yes")
+	decision, _ := r.RouteRequest(context.Background(), &backends.Annotations{Ensemble: true})
+	if _, ok := decision.Backend.(*EnsembleBackend); ok || decision.Ensemble != nil {
+		t.Error("Expected no ensemble with ensembles disabled")
+	}
+
+	r.SetEnsemble(&EnsembleConfig{})
+	decision, _ = r.RouteRequest(context.Background(), &backends.Annotations{})
+	if _, ok := decision.Backend.(*EnsembleBackend); ok {
+		t.Error("Expected no ensemble for a request not annotated Ensemble")
+	}
+
+	decision, _ = r.RouteRequest(context.Background(), &backends.Annotations{Ensemble: true, Target: "a"})
+	if _, ok := decision.Backend.(*EnsembleBackend); ok {
+		t.Error("Expected no ensemble for an explicit target")
+	}
+}
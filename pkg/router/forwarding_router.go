@@ -2,10 +2,15 @@ package router
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/cache"
 	"github.com/daoneill/ollama-proxy/pkg/confidence"
 )
 
@@ -15,6 +20,17 @@ type ForwardingRouter struct {
 	thermalRouter      *ThermalRouter
 	confidenceEstimator *confidence.ConfidenceEstimator
 	config             *ForwardingConfig
+	configMu           sync.RWMutex // guards the settings in ForwardingPolicy and the scorer
+
+	// Auto-pulls in flight (backend/model), shared by concurrent requests
+	pullMu sync.Mutex
+	pulls  map[string]*pullCall
+}
+
+// pullCall is an auto-pull other requests for the model can wait on
+type pullCall struct {
+	done chan struct{}
+	err  error
 }
 
 // ForwardingConfig configures forwarding behavior
@@ -34,6 +50,15 @@ type ForwardingConfig struct {
 
 	// Fallback behavior
 	ReturnBestAttempt bool // Return best attempt even if below threshold
+
+	// Scorer judges each attempt's answer; nil = length and pattern
+	// heuristics. Answers it fails to score get the heuristic score.
+	Scorer confidence.ConfidenceScorer
+
+	// Model provisioning: when no backend in the path can serve the model,
+	// pull it onto the first escalation target that manages models
+	AutoPull        bool
+	AutoPullTimeout time.Duration // 0 = bounded by the request only
 }
 
 // DefaultForwardingConfig returns sensible defaults
@@ -89,6 +114,7 @@ type ForwardingResult struct {
 	Attempts       []*ForwardingAttempt
 	TotalAttempts  int
 	Forwarded      bool
+	FromCache      bool // answered from the response cache, no attempts made
 
 	// Timing
 	TotalLatencyMs int32
@@ -113,8 +139,26 @@ func (fr *ForwardingRouter) GenerateWithForwarding(
 
 	startTime := time.Now()
 
+	// Serve repeated requests from the response cache
+	cacheKey := ""
+	if annotations != nil && annotations.CacheEnabled && cache.Default != nil {
+		cacheKey = cache.Key(ctx, model, prompt, nil)
+		if entry, ok := cache.Default.Get(cacheKey); ok {
+			if backend := fr.findBackend(entry.BackendID); backend != nil {
+				result.FinalResponse = entry.Response
+				result.FinalBackend = backend
+				result.FinalConfidence = fr.confidenceEstimator.Estimate(prompt, entry.Response, model, backend)
+				result.FromCache = true
+				result.Reasoning = append(result.Reasoning,
+					fmt.Sprintf("Cache hit (response from %s)", entry.BackendID))
+				result.TotalLatencyMs = int32(time.Since(startTime).Milliseconds())
+				return result, nil
+			}
+		}
+	}
+
 	// Build escalation path if not specified
-	escalationPath := fr.config.EscalationPath
+	minConfidence, escalationPath := fr.policy()
 	if len(escalationPath) == 0 {
 		escalationPath = fr.buildEscalationPath(model)
 	}
@@ -127,14 +171,22 @@ func (fr *ForwardingRouter) GenerateWithForwarding(
 	bestConfidence := 0.0
 
 	for attemptNum, backendID := range escalationPath {
-		if attemptNum >= fr.config.MaxRetries {
+		// Get backend
+		backend := fr.findBackend(backendID)
+
+		// The retry limit applies to local backends; the cloud rung is
+		// still tried when they all fall short
+		if attemptNum >= fr.config.MaxRetries && (backend == nil || !backends.IsCloud(backend)) {
+			if fr.hasCloudRung(escalationPath[attemptNum:]) {
+				result.Reasoning = append(result.Reasoning,
+					fmt.Sprintf("Max retries (%d) reached, skipping %s for the cloud rung", fr.config.MaxRetries, backendID))
+				continue
+			}
 			result.Reasoning = append(result.Reasoning,
 				fmt.Sprintf("Max retries (%d) reached", fr.config.MaxRetries))
 			break
 		}
 
-		// Get backend
-		backend := fr.findBackend(backendID)
 		if backend == nil {
 			result.Reasoning = append(result.Reasoning,
 				fmt.Sprintf("Backend %s not found, skipping", backendID))
@@ -191,7 +243,7 @@ func (fr *ForwardingRouter) GenerateWithForwarding(
 				attemptNum+1, backendID, attempt.Confidence.Overall, attempt.Confidence.Reasoning))
 
 		// Check if confidence meets threshold
-		if attempt.Confidence.Overall >= fr.config.MinConfidence {
+		if attempt.Confidence.Overall >= minConfidence {
 			// Success! Use this response
 			result.FinalResponse = attempt.Response
 			result.FinalBackend = backend
@@ -200,7 +252,16 @@ func (fr *ForwardingRouter) GenerateWithForwarding(
 
 			result.Reasoning = append(result.Reasoning,
 				fmt.Sprintf("✓ Confidence threshold met (%.2f >= %.2f), using response",
-					attempt.Confidence.Overall, fr.config.MinConfidence))
+					attempt.Confidence.Overall, minConfidence))
+
+			// Only confident answers are worth repeating
+			if cacheKey != "" {
+				cache.Default.Put(cacheKey, cache.Entry{
+					Response:  attempt.Response,
+					BackendID: backendID,
+					Model:     model,
+				})
+			}
 
 			break
 		}
@@ -208,7 +269,7 @@ func (fr *ForwardingRouter) GenerateWithForwarding(
 		// Confidence too low, will try next backend
 		result.Reasoning = append(result.Reasoning,
 			fmt.Sprintf("✗ Confidence too low (%.2f < %.2f), forwarding to next backend",
-				attempt.Confidence.Overall, fr.config.MinConfidence))
+				attempt.Confidence.Overall, minConfidence))
 	}
 
 	// If no attempt met threshold, use best attempt if configured
@@ -223,6 +284,35 @@ func (fr *ForwardingRouter) GenerateWithForwarding(
 				bestConfidence))
 	}
 
+	// No backend could serve the model: provision it on an escalation
+	// target and try there
+	if result.FinalResponse == "" && !bestAttempt.Success && fr.config.AutoPull {
+		if backend, backendID := fr.autoPullTarget(ctx, escalationPath, model); backend != nil {
+			result.Reasoning = append(result.Reasoning,
+				fmt.Sprintf("Pulling %s onto %s", model, backendID))
+			if err := fr.pull(ctx, backend, backendID, model); err != nil {
+				result.Reasoning = append(result.Reasoning,
+					fmt.Sprintf("Auto-pull onto %s failed: %v", backendID, err))
+			} else {
+				attempt := fr.tryBackend(ctx, backend, backendID, prompt, model, annotations)
+				result.Attempts = append(result.Attempts, attempt)
+				result.TotalAttempts++
+				if attempt.Success {
+					result.FinalResponse = attempt.Response
+					result.FinalBackend = backend
+					result.FinalConfidence = attempt.Confidence
+					result.Forwarded = true
+					result.Reasoning = append(result.Reasoning,
+						fmt.Sprintf("Using response from %s after auto-pull (confidence %.2f)",
+							backendID, attempt.Confidence.Overall))
+				} else {
+					result.Reasoning = append(result.Reasoning,
+						fmt.Sprintf("Attempt on %s after auto-pull failed: %v", backendID, attempt.Error))
+				}
+			}
+		}
+	}
+
 	// If still no response, return error
 	if result.FinalResponse == "" {
 		return result, fmt.Errorf("all backends failed or returned low confidence")
@@ -254,6 +344,10 @@ func (fr *ForwardingRouter) tryBackend(
 		Model:  model,
 		Prompt: prompt,
 	}
+	scorer := fr.scorer()
+	if confidence.UsesLogprobs(scorer) {
+		req.Options = &backends.GenerationOptions{Logprobs: true}
+	}
 
 	resp, err := backend.Generate(ctx, req)
 	if err != nil {
@@ -267,16 +361,94 @@ func (fr *ForwardingRouter) tryBackend(
 	attempt.Success = true
 
 	// Estimate confidence
-	attempt.Confidence = fr.confidenceEstimator.Estimate(
-		prompt,
-		resp.Response,
-		model,
-		backend,
-	)
+	attempt.Confidence = fr.score(ctx, scorer, &confidence.Answer{
+		Prompt:        prompt,
+		Response:      resp.Response,
+		Model:         model,
+		Backend:       backend,
+		TokenLogprobs: resp.TokenLogprobs,
+	})
 
 	return attempt
 }
 
+// score scores an answer with scorer, falling back to the heuristics when
+// there is none or it fails (e.g. the judge model is unreachable)
+func (fr *ForwardingRouter) score(ctx context.Context, scorer confidence.ConfidenceScorer, answer *confidence.Answer) *confidence.ConfidenceScore {
+	if scorer != nil {
+		score, err := scorer.Score(ctx, answer)
+		if err == nil {
+			return score
+		}
+		score = fr.confidenceEstimator.Estimate(answer.Prompt, answer.Response, answer.Model, answer.Backend)
+		score.Reasoning += fmt.Sprintf(" (scorer failed: %v)", err)
+		return score
+	}
+	return fr.confidenceEstimator.Estimate(answer.Prompt, answer.Response, answer.Model, answer.Backend)
+}
+
+// autoPullTarget returns the first escalation target, a backend after the
+// first in path, that can pull model and does not have it yet
+func (fr *ForwardingRouter) autoPullTarget(ctx context.Context, path []string, model string) (backends.Backend, string) {
+	if len(path) < 2 || model == "" {
+		return nil, ""
+	}
+	for _, backendID := range path[1:] {
+		backend := fr.findBackend(backendID)
+		if backend == nil || !backends.ManagesModels(backend) || !backend.SupportsModel(model) {
+			continue
+		}
+		if fr.config.RespectThermalLimits && !backend.IsHealthy() {
+			continue
+		}
+		// A backend that has the model failed for another reason
+		if has, err := backends.HasModel(ctx, backend, model); err != nil || has {
+			continue
+		}
+		return backend, backendID
+	}
+	return nil, ""
+}
+
+// pull pulls model onto backend within AutoPullTimeout. Concurrent
+// requests for the same model wait for one pull.
+func (fr *ForwardingRouter) pull(ctx context.Context, backend backends.Backend, backendID, model string) error {
+	key := backendID + "/" + model
+	fr.pullMu.Lock()
+	call, inFlight := fr.pulls[key]
+	if !inFlight {
+		if fr.pulls == nil {
+			fr.pulls = make(map[string]*pullCall)
+		}
+		call = &pullCall{done: make(chan struct{})}
+		fr.pulls[key] = call
+	}
+	fr.pullMu.Unlock()
+
+	if inFlight {
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	pullCtx := ctx
+	if fr.config.AutoPullTimeout > 0 {
+		var cancel context.CancelFunc
+		pullCtx, cancel = context.WithTimeout(ctx, fr.config.AutoPullTimeout)
+		defer cancel()
+	}
+	call.err = backends.PullModel(pullCtx, backend, model)
+
+	fr.pullMu.Lock()
+	delete(fr.pulls, key)
+	fr.pullMu.Unlock()
+	close(call.done)
+	return call.err
+}
+
 // buildEscalationPath creates default escalation path based on model
 func (fr *ForwardingRouter) buildEscalationPath(model string) []string {
 	// Default escalation: NPU → Intel GPU → NVIDIA GPU
@@ -298,7 +470,31 @@ func (fr *ForwardingRouter) buildEscalationPath(model string) []string {
 	// Final fallback: CPU
 	path = append(path, "ollama-cpu")
 
-	return path
+	// Last rung: cloud backends, when local backends can't meet the
+	// confidence threshold or run the model at all
+	return append(path, fr.cloudBackendIDs()...)
+}
+
+// cloudBackendIDs returns the IDs of registered cloud backends
+func (fr *ForwardingRouter) cloudBackendIDs() []string {
+	var ids []string
+	for _, backend := range fr.baseRouter.ListBackends() {
+		if backends.IsCloud(backend) {
+			ids = append(ids, backend.ID())
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// hasCloudRung reports whether any backend in path is a cloud backend
+func (fr *ForwardingRouter) hasCloudRung(path []string) bool {
+	for _, backendID := range path {
+		if backend := fr.findBackend(backendID); backend != nil && backends.IsCloud(backend) {
+			return true
+		}
+	}
+	return false
 }
 
 // findBackend looks up backend by ID
@@ -367,6 +563,7 @@ func (fr *ForwardingRouter) GenerateWithForwardingStream(
 ) (backends.StreamReader, backends.Backend, error) {
 
 	// Pre-analyze prompt to select best backend
+	minConfidence, _ := fr.policy()
 	escalationPath := fr.buildEscalationPath(model)
 
 	// Try to predict which backend will succeed
@@ -389,7 +586,7 @@ func (fr *ForwardingRouter) GenerateWithForwardingStream(
 		// Estimate confidence for this backend+model combo
 		estimatedConfidence := fr.confidenceEstimator.EstimateForPrompt(prompt, model)
 
-		if estimatedConfidence >= fr.config.MinConfidence {
+		if estimatedConfidence >= minConfidence {
 			// This backend should be good enough
 			req := &backends.GenerateRequest{
 				Model:  model,
@@ -406,15 +603,114 @@ func (fr *ForwardingRouter) GenerateWithForwardingStream(
 		}
 	}
 
+	// No backend could serve the model: provision it on an escalation target
+	if fr.config.AutoPull {
+		if backend, backendID := fr.autoPullTarget(ctx, escalationPath, model); backend != nil {
+			if err := fr.pull(ctx, backend, backendID, model); err != nil {
+				return nil, nil, fmt.Errorf("auto-pull of %s onto %s failed: %w", model, backendID, err)
+			}
+			stream, err := backend.GenerateStream(ctx, &backends.GenerateRequest{Model: model, Prompt: prompt})
+			if err != nil {
+				return nil, nil, err
+			}
+			return stream, backend, nil
+		}
+	}
+
 	return nil, nil, fmt.Errorf("no suitable backend found for streaming")
 }
 
 // SetEscalationPath allows dynamic escalation path configuration
 func (fr *ForwardingRouter) SetEscalationPath(path []string) {
-	fr.config.EscalationPath = path
+	fr.configMu.Lock()
+	defer fr.configMu.Unlock()
+	fr.config.EscalationPath = append([]string{}, path...)
 }
 
 // SetMinConfidence allows dynamic confidence threshold
 func (fr *ForwardingRouter) SetMinConfidence(threshold float64) {
+	fr.configMu.Lock()
+	defer fr.configMu.Unlock()
 	fr.config.MinConfidence = threshold
 }
+
+// SetScorer replaces the scorer judging attempts, nil = heuristics
+func (fr *ForwardingRouter) SetScorer(scorer confidence.ConfidenceScorer) {
+	fr.configMu.Lock()
+	defer fr.configMu.Unlock()
+	fr.config.Scorer = scorer
+}
+
+func (fr *ForwardingRouter) scorer() confidence.ConfidenceScorer {
+	fr.configMu.RLock()
+	defer fr.configMu.RUnlock()
+	return fr.config.Scorer
+}
+
+// policy returns the confidence threshold and escalation path in effect
+func (fr *ForwardingRouter) policy() (float64, []string) {
+	fr.configMu.RLock()
+	defer fr.configMu.RUnlock()
+	return fr.config.MinConfidence, fr.config.EscalationPath
+}
+
+// ForwardingPolicy is the part of the forwarding configuration that can be
+// changed at runtime. An empty escalation path is built per model.
+type ForwardingPolicy struct {
+	MinConfidence  float64  `json:"min_confidence"`
+	EscalationPath []string `json:"escalation_path"`
+}
+
+// Policy returns the current forwarding policy
+func (fr *ForwardingRouter) Policy() ForwardingPolicy {
+	minConfidence, path := fr.policy()
+	return ForwardingPolicy{
+		MinConfidence:  minConfidence,
+		EscalationPath: append([]string{}, path...),
+	}
+}
+
+// policyUpdate is the forwarding admin API payload; omitted fields are unchanged
+type policyUpdate struct {
+	MinConfidence  *float64  `json:"min_confidence"`
+	EscalationPath *[]string `json:"escalation_path"`
+}
+
+// Handler serves the forwarding policy: GET returns it and PUT or PATCH
+// changes min_confidence and/or escalation_path. Every backend in the path
+// must be registered.
+func (fr *ForwardingRouter) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPatch:
+			var req policyUpdate
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.MinConfidence != nil && (*req.MinConfidence < 0 || *req.MinConfidence > 1) {
+				http.Error(w, "min_confidence must be between 0 and 1", http.StatusBadRequest)
+				return
+			}
+			if req.EscalationPath != nil {
+				for _, id := range *req.EscalationPath {
+					if fr.findBackend(id) == nil {
+						http.Error(w, "unknown backend in escalation_path: "+id, http.StatusBadRequest)
+						return
+					}
+				}
+				fr.SetEscalationPath(*req.EscalationPath)
+			}
+			if req.MinConfidence != nil {
+				fr.SetMinConfidence(*req.MinConfidence)
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fr.Policy())
+	}
+}
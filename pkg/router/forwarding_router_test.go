@@ -2,10 +2,18 @@ package router
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/cache"
+	"github.com/daoneill/ollama-proxy/pkg/confidence"
 	"github.com/daoneill/ollama-proxy/pkg/thermal"
 )
 
@@ -451,6 +459,251 @@ func TestForwardingRouter_MaxRetries(t *testing.T) {
 	t.Logf("Attempts: %d (max: %d)", result.TotalAttempts, forwardingCfg.MaxRetries)
 }
 
+func TestForwardingRouter_CloudRung(t *testing.T) {
+	baseRouter := NewRouter(Config{DefaultBackendID: "backend-1"})
+	baseRouter.RegisterBackend(&mockBackendForRouter{id: "backend-1", hardware: "npu", healthy: true})
+	baseRouter.RegisterBackend(&mockBackendForRouter{id: "backend-2", hardware: "igpu", healthy: true})
+	baseRouter.RegisterBackend(&mockBackendForRouter{id: "cloud-1", hardware: backends.HardwareCloud, healthy: true})
+
+	forwardingRouter := NewForwardingRouter(baseRouter, nil, &ForwardingConfig{
+		Enabled:           true,
+		MinConfidence:     1.0, // never met
+		MaxRetries:        1,
+		EscalationPath:    []string{"backend-1", "backend-2", "cloud-1"},
+		ReturnBestAttempt: true,
+	})
+
+	result, err := forwardingRouter.GenerateWithForwarding(context.Background(), "Test prompt", "test-model", &backends.Annotations{})
+	if err != nil {
+		t.Fatalf("GenerateWithForwarding failed: %v", err)
+	}
+
+	// The retry limit stops local escalation but not the cloud rung
+	var tried []string
+	for _, attempt := range result.Attempts {
+		tried = append(tried, attempt.BackendID)
+	}
+	if len(tried) != 2 || tried[0] != "backend-1" || tried[1] != "cloud-1" {
+		t.Errorf("Expected backend-1 then cloud-1, got %v", tried)
+	}
+
+	// Built escalation paths end with the cloud backends
+	path := forwardingRouter.buildEscalationPath("llama3:70b")
+	if path[len(path)-1] != "cloud-1" {
+		t.Errorf("Expected cloud-1 as the last rung, got %v", path)
+	}
+}
+
+// pullableBackend is an Ollama-like backend that fails for models it has
+// not pulled
+type pullableBackend struct {
+	mockBackendForRouter
+	mu        sync.Mutex
+	installed map[string]bool
+	pulls     int
+}
+
+func (m *pullableBackend) ManagesModels() bool { return true }
+func (m *pullableBackend) InstalledModels(ctx context.Context) ([]backends.InstalledModel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var installed []backends.InstalledModel
+	for name := range m.installed {
+		installed = append(installed, backends.InstalledModel{Name: name})
+	}
+	return installed, nil
+}
+func (m *pullableBackend) PullModel(ctx context.Context, model string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pulls++
+	m.installed[model] = true
+	return nil
+}
+func (m *pullableBackend) DeleteModel(ctx context.Context, model string) error { return nil }
+func (m *pullableBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	m.mu.Lock()
+	installed := m.installed[req.Model]
+	m.mu.Unlock()
+	if !installed {
+		return nil, fmt.Errorf("model %q not found, try pulling it first", req.Model)
+	}
+	return m.mockBackendForRouter.Generate(ctx, req)
+}
+
+func TestForwardingRouter_AutoPull(t *testing.T) {
+	npu := &pullableBackend{mockBackendForRouter: mockBackendForRouter{id: "ollama-npu", hardware: "npu", healthy: true}, installed: map[string]bool{}}
+	gpu := &pullableBackend{mockBackendForRouter: mockBackendForRouter{id: "ollama-nvidia", hardware: "nvidia", healthy: true}, installed: map[string]bool{}}
+	baseRouter := NewRouter(Config{})
+	baseRouter.RegisterBackend(npu)
+	baseRouter.RegisterBackend(gpu)
+
+	cfg := &ForwardingConfig{
+		Enabled:           true,
+		MinConfidence:     0.0,
+		MaxRetries:        3,
+		EscalationPath:    []string{"ollama-npu", "ollama-nvidia"},
+		ReturnBestAttempt: true,
+	}
+	forwardingRouter := NewForwardingRouter(baseRouter, nil, cfg)
+
+	// Without the policy the request fails
+	if _, err := forwardingRouter.GenerateWithForwarding(context.Background(), "Test prompt", "phi3:mini", &backends.Annotations{}); err == nil {
+		t.Fatal("Expected failure without auto-pull")
+	}
+
+	// With it the model is pulled onto the escalation target, not the
+	// original backend
+	cfg.AutoPull = true
+	result, err := forwardingRouter.GenerateWithForwarding(context.Background(), "Test prompt", "phi3:mini", &backends.Annotations{})
+	if err != nil {
+		t.Fatalf("GenerateWithForwarding failed: %v", err)
+	}
+	if result.FinalBackend.ID() != "ollama-nvidia" || !result.Forwarded {
+		t.Errorf("Expected the response from ollama-nvidia, got %s", result.FinalBackend.ID())
+	}
+	if npu.pulls != 0 || gpu.pulls != 1 {
+		t.Errorf("Expected one pull onto ollama-nvidia, got npu=%d gpu=%d", npu.pulls, gpu.pulls)
+	}
+
+	// Once pulled the model is served without pulling again
+	if _, err := forwardingRouter.GenerateWithForwarding(context.Background(), "Test prompt", "phi3:mini", &backends.Annotations{}); err != nil {
+		t.Fatalf("GenerateWithForwarding failed: %v", err)
+	}
+	if gpu.pulls != 1 {
+		t.Errorf("Expected no second pull, got %d", gpu.pulls)
+	}
+}
+
+func TestForwardingRouter_AutoPullSharedByConcurrentRequests(t *testing.T) {
+	gpu := &pullableBackend{mockBackendForRouter: mockBackendForRouter{id: "ollama-nvidia", healthy: true}, installed: map[string]bool{}}
+	forwardingRouter := NewForwardingRouter(NewRouter(Config{}), nil, &ForwardingConfig{AutoPull: true})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var pulls int
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(first bool) {
+			defer wg.Done()
+			forwardingRouter.pull(context.Background(), &blockingPull{pullableBackend: gpu, started: started, release: release, first: first, pulls: &pulls}, "ollama-nvidia", "phi3")
+		}(i == 0)
+		if i == 0 {
+			<-started
+		}
+	}
+	time.Sleep(20 * time.Millisecond) // let the other requests join the pull
+	close(release)
+	wg.Wait()
+	if pulls != 1 {
+		t.Errorf("Expected one pull shared by three requests, got %d", pulls)
+	}
+}
+
+// blockingPull holds its pull until released
+type blockingPull struct {
+	*pullableBackend
+	started, release chan struct{}
+	first            bool
+	pulls            *int
+}
+
+func (b *blockingPull) PullModel(ctx context.Context, model string) error {
+	*b.pulls++
+	if b.first {
+		close(b.started)
+	}
+	<-b.release
+	return nil
+}
+
+func TestForwardingRouter_ResponseCache(t *testing.T) {
+	c, _ := cache.New(cache.DefaultConfig())
+	cache.SetDefault(c)
+	defer cache.SetDefault(nil)
+
+	baseRouter := NewRouter(Config{DefaultBackendID: "backend-1"})
+	baseRouter.RegisterBackend(&mockBackendForRouter{id: "backend-1", hardware: "npu", healthy: true})
+
+	forwardingRouter := NewForwardingRouter(baseRouter, nil, &ForwardingConfig{
+		Enabled:           true,
+		MinConfidence:     0.0,
+		MaxRetries:        1,
+		EscalationPath:    []string{"backend-1"},
+		ReturnBestAttempt: true,
+	})
+
+	generate := func() *ForwardingResult {
+		t.Helper()
+		result, err := forwardingRouter.GenerateWithForwarding(
+			context.Background(), "Test prompt", "test-model", &backends.Annotations{CacheEnabled: true})
+		if err != nil {
+			t.Fatalf("GenerateWithForwarding failed: %v", err)
+		}
+		return result
+	}
+
+	if first := generate(); first.FromCache {
+		t.Error("Expected first request to reach a backend")
+	}
+
+	second := generate()
+	if !second.FromCache || second.TotalAttempts != 0 {
+		t.Errorf("Expected cached answer without attempts, got FromCache=%v attempts=%d",
+			second.FromCache, second.TotalAttempts)
+	}
+	if second.FinalResponse != "Test response from backend-1" || second.FinalBackend.ID() != "backend-1" {
+		t.Errorf("Unexpected cached result: %q from %v", second.FinalResponse, second.FinalBackend)
+	}
+}
+
+// stubScorer scores answers by the backend that gave them
+type stubScorer struct {
+	scores map[string]float64
+	err    error
+}
+
+func (s *stubScorer) Score(_ context.Context, answer *confidence.Answer) (*confidence.ConfidenceScore, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &confidence.ConfidenceScore{Overall: s.scores[answer.Backend.ID()], Reasoning: "stub"}, nil
+}
+
+func TestForwardingRouter_Scorer(t *testing.T) {
+	baseRouter := NewRouter(Config{DefaultBackendID: "backend-1"})
+	baseRouter.RegisterBackend(&mockBackendForRouter{id: "backend-1", hardware: "npu", healthy: true})
+	baseRouter.RegisterBackend(&mockBackendForRouter{id: "backend-2", hardware: "nvidia", healthy: true})
+
+	scorer := &stubScorer{scores: map[string]float64{"backend-1": 0.2, "backend-2": 0.9}}
+	fr := NewForwardingRouter(baseRouter, nil, &ForwardingConfig{
+		Enabled:        true,
+		MinConfidence:  0.5,
+		MaxRetries:     3,
+		EscalationPath: []string{"backend-1", "backend-2"},
+		Scorer:         scorer,
+	})
+
+	result, err := fr.GenerateWithForwarding(context.Background(), "Test prompt", "test-model", &backends.Annotations{})
+	if err != nil {
+		t.Fatalf("GenerateWithForwarding failed: %v", err)
+	}
+	if result.FinalBackend.ID() != "backend-2" || result.FinalConfidence.Overall != 0.9 || !result.Forwarded {
+		t.Errorf("Expected the scorer to escalate to backend-2, got %s at %.2f", result.FinalBackend.ID(), result.FinalConfidence.Overall)
+	}
+
+	// A failing scorer falls back to the heuristics
+	fr.SetScorer(&stubScorer{err: fmt.Errorf("judge unreachable")})
+	result, err = fr.GenerateWithForwarding(context.Background(), "Test prompt", "test-model", &backends.Annotations{})
+	if err != nil {
+		t.Fatalf("GenerateWithForwarding failed: %v", err)
+	}
+	if got := result.Attempts[0].Confidence.Reasoning; !strings.Contains(got, "judge unreachable") {
+		t.Errorf("Expected the scorer failure noted, got %q", got)
+	}
+}
+
 func TestIsSmallModel(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -1695,3 +1948,38 @@ func TestForwardingRouter_MaxRetriesEnforced(t *testing.T) {
 	t.Logf("Successfully enforced MaxRetries=%d (total attempts: %d)",
 		forwardingCfg.MaxRetries, result.TotalAttempts)
 }
+
+func TestForwardingRouter_Handler(t *testing.T) {
+	router := NewRouter(Config{})
+	router.RegisterBackend(&MockBackend{id: "npu", healthy: true})
+	router.RegisterBackend(&MockBackend{id: "nvidia", healthy: true})
+	fr := NewForwardingRouter(router, nil, DefaultForwardingConfig())
+	handler := fr.Handler()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPut, "/admin/routing",
+		strings.NewReader(`{"min_confidence":0.9,"escalation_path":["npu","nvidia"]}`)))
+	var policy ForwardingPolicy
+	json.NewDecoder(w.Body).Decode(&policy)
+	if w.Code != http.StatusOK || policy.MinConfidence != 0.9 || len(policy.EscalationPath) != 2 {
+		t.Fatalf("Expected the policy updated, got %d %+v", w.Code, policy)
+	}
+
+	// Omitted fields are left alone
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPatch, "/admin/routing", strings.NewReader(`{"min_confidence":0.6}`)))
+	if policy := fr.Policy(); policy.MinConfidence != 0.6 || len(policy.EscalationPath) != 2 {
+		t.Errorf("Expected only min_confidence changed, got %+v", policy)
+	}
+
+	for _, body := range []string{`{"min_confidence":1.5}`, `{"escalation_path":["tpu"]}`} {
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPut, "/admin/routing", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+	if policy := fr.Policy(); policy.MinConfidence != 0.6 || policy.EscalationPath[0] != "npu" {
+		t.Errorf("Expected rejected updates to change nothing, got %+v", policy)
+	}
+}
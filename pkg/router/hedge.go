@@ -0,0 +1,247 @@
+package router
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+)
+
+// DefaultHedgeDelay is how long a latency-critical request waits for its
+// backend before a hedged duplicate goes to a second one
+const DefaultHedgeDelay = 250 * time.Millisecond
+
+// HedgingConfig enables hedged requests for latency-critical annotations
+type HedgingConfig struct {
+	Enabled bool
+	Delay   time.Duration // default DefaultHedgeDelay
+}
+
+// HedgedBackend sends a request to the primary backend and, if no response
+// arrives within the delay or the primary fails, a duplicate to the hedge
+// backend. The first success is returned and the other request is
+// cancelled. For streams the race is to the first chunk.
+//
+// Only Generate and GenerateStream are hedged; other calls go to the
+// primary. ID reports the backend that answered once the race is decided.
+type HedgedBackend struct {
+	backends.Backend // primary
+	hedgeID          string
+	startHedge       func() backends.Backend
+	delay            time.Duration
+
+	mu     sync.Mutex
+	winner backends.Backend
+	hedged bool
+}
+
+// ID returns the ID of the backend that answered, the primary until then
+func (hb *HedgedBackend) ID() string {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	if hb.winner != nil {
+		return hb.winner.ID()
+	}
+	return hb.Backend.ID()
+}
+
+// HedgeID returns the backend a duplicate goes to
+func (hb *HedgedBackend) HedgeID() string {
+	return hb.hedgeID
+}
+
+// Hedged reports whether a duplicate was sent
+func (hb *HedgedBackend) Hedged() bool {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	return hb.hedged
+}
+
+// ColdStart asks the primary whether model needs loading
+func (hb *HedgedBackend) ColdStart(ctx context.Context, model string) *backends.ColdStart {
+	return backends.CheckColdStart(ctx, hb.Backend, model)
+}
+
+// SupportsImageEdit asks the primary whether it edits images
+func (hb *HedgedBackend) SupportsImageEdit() bool {
+	return backends.SupportsImageEdit(hb.Backend)
+}
+
+// SupportsToolCalling asks the primary whether it offers models tools
+func (hb *HedgedBackend) SupportsToolCalling() bool {
+	return backends.SupportsToolCalling(hb.Backend)
+}
+
+// SupportsSequences asks the primary whether it generates several
+// completions in one request
+func (hb *HedgedBackend) SupportsSequences() bool {
+	return backends.SupportsSequences(hb.Backend)
+}
+
+// GenerateSequences goes to the primary unhedged: duplicating a request
+// for several completions would cost more than it saves
+func (hb *HedgedBackend) GenerateSequences(ctx context.Context, req *backends.GenerateRequest, n, bestOf int) ([]*backends.GenerateResponse, error) {
+	return backends.NativeSequences(ctx, hb.Backend, req, n, bestOf)
+}
+
+// hedgeAttempt is one of the racing requests
+type hedgeAttempt[T any] struct {
+	backend backends.Backend
+	hedge   bool
+	result  T
+	err     error
+}
+
+// race runs op on the primary, then on the hedge after the delay or once
+// the primary fails. It returns the first success, with the cancel
+// function of the winning request's context, and cancels the loser; a
+// loser finishing late is passed to discard.
+func race[T any](ctx context.Context, hb *HedgedBackend, op func(context.Context, backends.Backend) (T, error), discard func(T)) (T, context.CancelFunc, error) {
+	results := make(chan hedgeAttempt[T], 2)
+	cancels := make(map[bool]context.CancelFunc, 2)
+	launch := func(b backends.Backend, hedge bool) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels[hedge] = cancel
+		go func() {
+			result, err := op(attemptCtx, b)
+			results <- hedgeAttempt[T]{backend: b, hedge: hedge, result: result, err: err}
+		}()
+	}
+
+	launch(hb.Backend, false)
+	pending := 1
+	timer := time.NewTimer(hb.delay)
+	defer timer.Stop()
+
+	launchHedge := func() {
+		hb.mu.Lock()
+		hb.hedged = true
+		hb.mu.Unlock()
+		launch(hb.startHedge(), true)
+		pending++
+	}
+
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if _, launched := cancels[true]; !launched {
+				launchHedge()
+			}
+
+		case attempt := <-results:
+			pending--
+			if attempt.err == nil {
+				hb.mu.Lock()
+				hb.winner = attempt.backend
+				hedged := hb.hedged
+				hb.mu.Unlock()
+
+				if cancelLoser, ok := cancels[!attempt.hedge]; ok {
+					cancelLoser()
+					if pending > 0 {
+						// Release the loser's resources once it returns
+						go func() {
+							if loser := <-results; loser.err == nil {
+								discard(loser.result)
+							}
+						}()
+					}
+				}
+				if hedged {
+					winner := "primary"
+					if attempt.hedge {
+						winner = "hedge"
+					}
+					metrics.RecordHedgedRequest(hb.Backend.ID(), hb.hedgeID, winner)
+				}
+				return attempt.result, cancels[attempt.hedge], nil
+			}
+
+			cancels[attempt.hedge]()
+			if firstErr == nil {
+				firstErr = attempt.err
+			}
+			if _, launched := cancels[true]; !launched && ctx.Err() == nil {
+				// The primary failed early: retry on the hedge right away
+				launchHedge()
+				continue
+			}
+			if pending == 0 {
+				if hb.Hedged() {
+					metrics.RecordHedgedRequest(hb.Backend.ID(), hb.hedgeID, "none")
+				}
+				var zero T
+				return zero, nil, firstErr
+			}
+		}
+	}
+}
+
+// Generate races the primary and, after the delay, the hedge backend
+func (hb *HedgedBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	resp, cancel, err := race(ctx, hb, func(ctx context.Context, b backends.Backend) (*backends.GenerateResponse, error) {
+		return b.Generate(ctx, req)
+	}, func(*backends.GenerateResponse) {})
+	if cancel != nil {
+		cancel()
+	}
+	return resp, err
+}
+
+// startedStream is a stream that has produced its first chunk
+type startedStream struct {
+	reader backends.StreamReader
+	first  *backends.StreamChunk
+	err    error // io.EOF when the stream ended before any chunk
+}
+
+// GenerateStream races the primary and, after the delay, the hedge backend
+// to the first chunk. The losing stream is cancelled and closed.
+func (hb *HedgedBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	started, cancel, err := race(ctx, hb, func(ctx context.Context, b backends.Backend) (*startedStream, error) {
+		reader, err := b.GenerateStream(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		first, err := reader.Recv()
+		if err != nil && err != io.EOF {
+			reader.Close()
+			return nil, err
+		}
+		return &startedStream{reader: reader, first: first, err: err}, nil
+	}, func(s *startedStream) {
+		s.reader.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hedgedStreamReader{startedStream: started, cancel: cancel}, nil
+}
+
+// hedgedStreamReader replays the winning stream's first chunk and cancels
+// its context when closed
+type hedgedStreamReader struct {
+	*startedStream
+	cancel   context.CancelFunc
+	replayed bool
+}
+
+func (r *hedgedStreamReader) Recv() (*backends.StreamChunk, error) {
+	if !r.replayed {
+		r.replayed = true
+		if r.first != nil || r.err != nil {
+			return r.first, r.err
+		}
+	}
+	return r.reader.Recv()
+}
+
+func (r *hedgedStreamReader) Close() error {
+	err := r.reader.Close()
+	r.cancel()
+	return err
+}
@@ -0,0 +1,189 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// hedgeMockBackend answers after delay unless its context is cancelled first
+type hedgeMockBackend struct {
+	*MockBackend
+	delay     time.Duration
+	err       error
+	cancelled atomic.Bool
+	calls     atomic.Int32
+}
+
+func (h *hedgeMockBackend) wait(ctx context.Context) error {
+	h.calls.Add(1)
+	select {
+	case <-time.After(h.delay):
+		return h.err
+	case <-ctx.Done():
+		h.cancelled.Store(true)
+		return ctx.Err()
+	}
+}
+
+func (h *hedgeMockBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	if err := h.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &backends.GenerateResponse{Response: h.id}, nil
+}
+
+func (h *hedgeMockBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	if err := h.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &hedgeMockStream{chunks: []*backends.StreamChunk{{Token: h.id}, {Done: true}}}, nil
+}
+
+type hedgeMockStream struct {
+	chunks []*backends.StreamChunk
+}
+
+func (s *hedgeMockStream) Recv() (*backends.StreamChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return chunk, nil
+}
+
+func (s *hedgeMockStream) Close() error { return nil }
+
+// newHedgingRouter registers a primary that scores best on latency and a
+// hedge that scores second
+func newHedgingRouter(primary, hedge *hedgeMockBackend) *Router {
+	r := NewRouter(Config{Hedging: HedgingConfig{Enabled: true, Delay: 20 * time.Millisecond}})
+	primary.MockBackend = &MockBackend{id: "primary", healthy: true, avgLatencyMs: 10}
+	hedge.MockBackend = &MockBackend{id: "hedge", healthy: true, avgLatencyMs: 50}
+	r.RegisterBackend(primary)
+	r.RegisterBackend(hedge)
+	return r
+}
+
+func routeHedged(t *testing.T, r *Router) *HedgedBackend {
+	t.Helper()
+	decision, err := r.RouteRequest(context.Background(), &backends.Annotations{LatencyCritical: true})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	hb, ok := decision.Backend.(*HedgedBackend)
+	if !ok || decision.Hedge != "hedge" {
+		t.Fatalf("Expected a hedged decision, got %T hedge=%q", decision.Backend, decision.Hedge)
+	}
+	return hb
+}
+
+func TestHedging_SlowPrimaryLoses(t *testing.T) {
+	primary := &hedgeMockBackend{delay: time.Second}
+	hedge := &hedgeMockBackend{delay: 0}
+	hb := routeHedged(t, newHedgingRouter(primary, hedge))
+
+	start := time.Now()
+	resp, err := hb.Generate(context.Background(), &backends.GenerateRequest{})
+	if err != nil || resp.Response != "hedge" {
+		t.Fatalf("Expected the hedge to answer, got %v, %v", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the hedge to answer without waiting for the primary, took %v", elapsed)
+	}
+	if !hb.Hedged() || hb.ID() != "hedge" {
+		t.Errorf("Expected the decision to report the hedge, got hedged=%v id=%s", hb.Hedged(), hb.ID())
+	}
+
+	// The losing primary is cancelled
+	deadline := time.Now().Add(time.Second)
+	for !primary.cancelled.Load() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !primary.cancelled.Load() {
+		t.Error("Expected the primary's request to be cancelled")
+	}
+}
+
+func TestHedging_FastPrimaryNotHedged(t *testing.T) {
+	primary := &hedgeMockBackend{delay: 0}
+	hedge := &hedgeMockBackend{delay: 0}
+	hb := routeHedged(t, newHedgingRouter(primary, hedge))
+
+	resp, err := hb.Generate(context.Background(), &backends.GenerateRequest{})
+	if err != nil || resp.Response != "primary" {
+		t.Fatalf("Expected the primary to answer, got %v, %v", resp, err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if hb.Hedged() || hedge.calls.Load() != 0 {
+		t.Errorf("Expected no duplicate when the primary answers in time")
+	}
+}
+
+func TestHedging_PrimaryFailureRetriesAtOnce(t *testing.T) {
+	// The hedge takes as long as it advertises, so its live latency
+	// doesn't undercut the primary's
+	primary := &hedgeMockBackend{err: errors.New("connection refused")}
+	hedge := &hedgeMockBackend{delay: 50 * time.Millisecond}
+	r := NewRouter(Config{Hedging: HedgingConfig{Enabled: true, Delay: time.Hour}})
+	primary.MockBackend = &MockBackend{id: "primary", healthy: true, avgLatencyMs: 10}
+	hedge.MockBackend = &MockBackend{id: "hedge", healthy: true, avgLatencyMs: 50}
+	r.RegisterBackend(primary)
+	r.RegisterBackend(hedge)
+	hb := routeHedged(t, r)
+
+	resp, err := hb.Generate(context.Background(), &backends.GenerateRequest{})
+	if err != nil || resp.Response != "hedge" {
+		t.Fatalf("Expected the hedge to answer after the primary failed, got %v, %v", resp, err)
+	}
+
+	// Both failing returns the first error
+	hedge.err = errors.New("out of memory")
+	hb = routeHedged(t, r)
+	if _, err := hb.Generate(context.Background(), &backends.GenerateRequest{}); err == nil || err.Error() != "connection refused" {
+		t.Errorf("Expected the primary's error, got %v", err)
+	}
+}
+
+func TestHedging_Stream(t *testing.T) {
+	primary := &hedgeMockBackend{delay: time.Second}
+	hedge := &hedgeMockBackend{delay: 0}
+	hb := routeHedged(t, newHedgingRouter(primary, hedge))
+
+	reader, err := hb.GenerateStream(context.Background(), &backends.GenerateRequest{})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	defer reader.Close()
+
+	chunk, err := reader.Recv()
+	if err != nil || chunk.Token != "hedge" {
+		t.Fatalf("Expected the hedge's first chunk replayed, got %v, %v", chunk, err)
+	}
+	if chunk, err := reader.Recv(); err != nil || !chunk.Done {
+		t.Errorf("Expected the rest of the hedge's stream, got %v, %v", chunk, err)
+	}
+}
+
+func TestHedging_NotForExplicitTargetOrNormalRequests(t *testing.T) {
+	r := newHedgingRouter(&hedgeMockBackend{}, &hedgeMockBackend{})
+
+	for _, annotations := range []*backends.Annotations{
+		{},
+		{LatencyCritical: true, Target: "primary"},
+	} {
+		decision, err := r.RouteRequest(context.Background(), annotations)
+		if err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+		if _, hedged := decision.Backend.(*HedgedBackend); hedged || decision.Hedge != "" {
+			t.Errorf("Expected no hedging for %+v", annotations)
+		}
+	}
+}
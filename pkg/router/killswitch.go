@@ -0,0 +1,218 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrGenerationStopped is the cancellation cause of requests stopped by the
+// kill switch
+var ErrGenerationStopped = errors.New("generation stopped by operator")
+
+// KillSwitch is an emergency stop: it cancels in-flight requests, on every
+// backend or one, and can pause routing to them until resumed
+type KillSwitch struct {
+	mu        sync.Mutex
+	next      uint64
+	running   map[uint64]runningRequest
+	pausedAll bool
+	paused    map[string]bool // backend ID -> paused
+}
+
+// runningRequest is an in-flight request the kill switch can cancel
+type runningRequest struct {
+	backendID string
+	cancel    context.CancelCauseFunc
+}
+
+// KillSwitchStatus is a point-in-time view of the kill switch
+type KillSwitchStatus struct {
+	Running        int      `json:"running"`
+	PausedAll      bool     `json:"paused_all"`
+	PausedBackends []string `json:"paused_backends,omitempty"`
+}
+
+// NewKillSwitch creates a kill switch with nothing paused
+func NewKillSwitch() *KillSwitch {
+	return &KillSwitch{
+		running: make(map[uint64]runningRequest),
+		paused:  make(map[string]bool),
+	}
+}
+
+// track registers a request on backendID. It returns the context the
+// request must run under and a function to call when it finishes.
+func (k *KillSwitch) track(ctx context.Context, backendID string) (context.Context, func()) {
+	if k == nil {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	k.mu.Lock()
+	id := k.next
+	k.next++
+	k.running[id] = runningRequest{backendID: backendID, cancel: cancel}
+	k.mu.Unlock()
+
+	return ctx, func() {
+		k.mu.Lock()
+		delete(k.running, id)
+		k.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Stop cancels the in-flight requests on backendID, or on every backend
+// when backendID is empty, and returns how many it cancelled. With pause,
+// new requests are not routed there until Resume.
+func (k *KillSwitch) Stop(backendID string, pause bool) int {
+	k.mu.Lock()
+	if pause {
+		if backendID == "" {
+			k.pausedAll = true
+		} else {
+			k.paused[backendID] = true
+		}
+	}
+	var stopped []runningRequest
+	for id, req := range k.running {
+		if backendID == "" || req.backendID == backendID {
+			stopped = append(stopped, req)
+			delete(k.running, id)
+		}
+	}
+	k.mu.Unlock()
+
+	for _, req := range stopped {
+		req.cancel(ErrGenerationStopped)
+		metrics.RecordGenerationStopped(req.backendID)
+	}
+
+	if logging.Logger != nil {
+		logging.Logger.Warn("Generations stopped",
+			zap.String("backend", backendID),
+			zap.Int("cancelled", len(stopped)),
+			zap.Bool("paused", pause),
+		)
+	}
+	return len(stopped)
+}
+
+// Pause stops new requests being routed to backendID, or to every backend
+// when backendID is empty, and lets in-flight requests finish
+func (k *KillSwitch) Pause(backendID string) {
+	k.mu.Lock()
+	if backendID == "" {
+		k.pausedAll = true
+	} else {
+		k.paused[backendID] = true
+	}
+	k.mu.Unlock()
+
+	if logging.Logger != nil {
+		logging.Logger.Info("Routing paused", zap.String("backend", backendID))
+	}
+}
+
+// Resume lets requests be routed to backendID again, or to every backend
+// when backendID is empty
+func (k *KillSwitch) Resume(backendID string) {
+	k.mu.Lock()
+	if backendID == "" {
+		k.pausedAll = false
+		k.paused = make(map[string]bool)
+	} else {
+		delete(k.paused, backendID)
+	}
+	k.mu.Unlock()
+
+	if logging.Logger != nil {
+		logging.Logger.Info("Generations resumed", zap.String("backend", backendID))
+	}
+}
+
+// Paused reports whether requests may not be routed to backendID
+func (k *KillSwitch) Paused(backendID string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.pausedAll || k.paused[backendID]
+}
+
+// PausedAll reports whether every backend is paused
+func (k *KillSwitch) PausedAll() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.pausedAll
+}
+
+// Status returns the current state
+func (k *KillSwitch) Status() KillSwitchStatus {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	status := KillSwitchStatus{
+		Running:   len(k.running),
+		PausedAll: k.pausedAll,
+	}
+	for id := range k.paused {
+		status.PausedBackends = append(status.PausedBackends, id)
+	}
+	sort.Strings(status.PausedBackends)
+	return status
+}
+
+// killSwitchRequest is the admin API payload
+type killSwitchRequest struct {
+	Backend string `json:"backend"` // empty = every backend
+	Pause   bool   `json:"pause"`   // stop admitting new requests
+	Resume  bool   `json:"resume"`  // undo a pause instead of stopping
+}
+
+// Handler serves the admin kill switch endpoint. GET returns the status;
+// POST stops generations, or resumes them with {"resume": true}. An empty
+// body stops everything without pausing.
+func (k *KillSwitch) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := struct {
+			KillSwitchStatus
+			Cancelled int `json:"cancelled"`
+		}{}
+
+		switch r.Method {
+		case http.MethodGet:
+			// Fall through to status response
+		case http.MethodPost:
+			var req killSwitchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Resume {
+				k.Resume(req.Backend)
+			} else {
+				response.Cancelled = k.Stop(req.Backend, req.Pause)
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		response.KillSwitchStatus = k.Status()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// KillSwitch returns the router's kill switch
+func (r *Router) KillSwitch() *KillSwitch {
+	return r.kill
+}
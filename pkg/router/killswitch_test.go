@@ -0,0 +1,156 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestKillSwitch_StopCancelsInFlight(t *testing.T) {
+	r := NewRouter(Config{})
+	slow := &hedgeMockBackend{MockBackend: &MockBackend{id: "gpu", healthy: true}, delay: 5 * time.Second}
+	r.RegisterBackend(slow)
+
+	decision, err := r.RouteRequest(context.Background(), &backends.Annotations{})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := decision.Backend.Generate(context.Background(), &backends.GenerateRequest{})
+		errc <- err
+	}()
+
+	// Wait for the generation to register
+	deadline := time.Now().Add(time.Second)
+	for r.KillSwitch().Status().Running == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Generation never registered with the kill switch")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if n := r.KillSwitch().Stop("", false); n != 1 {
+		t.Errorf("Expected 1 cancelled generation, got %d", n)
+	}
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrGenerationStopped) {
+			t.Errorf("Expected ErrGenerationStopped, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Generation was not cancelled")
+	}
+	if !slow.cancelled.Load() {
+		t.Error("Expected the backend to see its context cancelled")
+	}
+	if running := r.KillSwitch().Status().Running; running != 0 {
+		t.Errorf("Expected no running generations, got %d", running)
+	}
+}
+
+func TestKillSwitch_StopOneBackend(t *testing.T) {
+	k := NewKillSwitch()
+	gpuCtx, gpuDone := k.track(context.Background(), "gpu")
+	defer gpuDone()
+	npuCtx, npuDone := k.track(context.Background(), "npu")
+	defer npuDone()
+
+	if n := k.Stop("gpu", false); n != 1 {
+		t.Errorf("Expected 1 cancelled generation, got %d", n)
+	}
+	if context.Cause(gpuCtx) != ErrGenerationStopped {
+		t.Errorf("Expected the gpu generation to be stopped, got %v", context.Cause(gpuCtx))
+	}
+	if npuCtx.Err() != nil {
+		t.Error("Expected the npu generation to keep running")
+	}
+}
+
+func TestKillSwitch_Pause(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "gpu", healthy: true, avgLatencyMs: 10})
+	r.RegisterBackend(&MockBackend{id: "npu", healthy: true, avgLatencyMs: 50})
+	ks := r.KillSwitch()
+
+	ks.Stop("gpu", true)
+	for i := 0; i < 3; i++ {
+		decision, err := r.RouteRequest(context.Background(), &backends.Annotations{Target: "gpu"})
+		if err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+		if decision.Backend.ID() != "npu" {
+			t.Errorf("Expected paused gpu to be skipped, got %s", decision.Backend.ID())
+		}
+	}
+
+	ks.Stop("", true)
+	if _, err := r.RouteRequest(context.Background(), &backends.Annotations{}); err == nil || !strings.Contains(err.Error(), "paused") {
+		t.Errorf("Expected routing to be paused, got: %v", err)
+	}
+
+	ks.Resume("")
+	if status := ks.Status(); status.PausedAll || len(status.PausedBackends) > 0 {
+		t.Errorf("Expected nothing paused after resume, got %+v", status)
+	}
+	if _, err := r.RouteRequest(context.Background(), &backends.Annotations{}); err != nil {
+		t.Errorf("Expected routing to resume, got: %v", err)
+	}
+}
+
+func TestKillSwitch_Handler(t *testing.T) {
+	ks := NewKillSwitch()
+	_, done := ks.track(context.Background(), "gpu")
+	defer done()
+
+	rec := httptest.NewRecorder()
+	ks.Handler()(rec, httptest.NewRequest(http.MethodPost, "/admin/stop-all", strings.NewReader(`{"backend":"gpu","pause":true}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var resp struct {
+		Cancelled      int      `json:"cancelled"`
+		PausedBackends []string `json:"paused_backends"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Cancelled != 1 || len(resp.PausedBackends) != 1 || resp.PausedBackends[0] != "gpu" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	// An empty body stops everything
+	rec = httptest.NewRecorder()
+	ks.Handler()(rec, httptest.NewRequest(http.MethodPost, "/admin/stop-all", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for an empty body, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	ks.Handler()(rec, httptest.NewRequest(http.MethodPost, "/admin/stop-all", strings.NewReader(`{"resume":true}`)))
+	if ks.Paused("gpu") {
+		t.Error("Expected gpu to be resumed")
+	}
+}
+
+func TestKillSwitch_PauseKeepsInFlight(t *testing.T) {
+	k := NewKillSwitch()
+	ctx, done := k.track(context.Background(), "gpu")
+	defer done()
+
+	k.Pause("gpu")
+	if !k.Paused("gpu") || k.Paused("npu") {
+		t.Error("Expected only gpu paused")
+	}
+	if ctx.Err() != nil || k.Status().Running != 1 {
+		t.Error("Expected the in-flight request left running")
+	}
+}
@@ -0,0 +1,21 @@
+package router
+
+// LabelPolicy decides which backends may serve requests carrying client
+// labels (X-Labels), e.g. *policy.Policy
+type LabelPolicy interface {
+	CheckLabels(backendID string, labels map[string]string) error
+}
+
+// SetLabelPolicy installs the policy consulted on every routing decision
+// (nil = labels don't restrict backends)
+func (r *Router) SetLabelPolicy(policy LabelPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.labelPolicy = policy
+}
+
+// labelsAllow reports whether backendID may serve a request with labels.
+// Caller must hold r.mu.
+func (r *Router) labelsAllow(backendID string, labels map[string]string) bool {
+	return r.labelPolicy == nil || len(labels) == 0 || r.labelPolicy.CheckLabels(backendID, labels) == nil
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/policy"
+)
+
+func TestRouteRequest_LabelPolicy(t *testing.T) {
+	router := NewRouter(Config{})
+	router.RegisterBackend(&MockBackend{id: "ollama-nvidia", healthy: true, powerWatts: 150, priority: 10})
+	router.RegisterBackend(&MockBackend{id: "ollama-npu", healthy: true, powerWatts: 3, priority: 1})
+
+	labelPolicy := policy.NewPolicy()
+	labelPolicy.SetLabelBackends("team", "ml", []string{"ollama-npu"})
+	router.SetLabelPolicy(labelPolicy)
+
+	// Even an explicit target outside the allowed set is overridden
+	annotations := &backends.Annotations{Target: "ollama-nvidia", Labels: map[string]string{"team": "ml"}}
+	decision, err := router.RouteRequest(context.Background(), annotations)
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if decision.Backend.ID() != "ollama-npu" {
+		t.Errorf("Expected team=ml to be kept on ollama-npu, got %s", decision.Backend.ID())
+	}
+
+	// Other labels and unlabelled requests are not restricted
+	for _, labels := range []map[string]string{{"team": "search"}, nil} {
+		decision, err = router.RouteRequest(context.Background(), &backends.Annotations{Target: "ollama-nvidia", Labels: labels})
+		if err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+		if decision.Backend.ID() != "ollama-nvidia" {
+			t.Errorf("Expected explicit target for labels %v, got %s", labels, decision.Backend.ID())
+		}
+	}
+}
+
+func TestRouteRequest_LabelPolicyNoBackend(t *testing.T) {
+	router := NewRouter(Config{})
+	router.RegisterBackend(&MockBackend{id: "ollama-nvidia", healthy: true, powerWatts: 150})
+
+	labelPolicy := policy.NewPolicy()
+	labelPolicy.SetLabelBackends("team", "ml", []string{"ollama-npu"})
+	router.SetLabelPolicy(labelPolicy)
+
+	_, err := router.RouteRequest(context.Background(), &backends.Annotations{Labels: map[string]string{"team": "ml"}})
+	if err == nil || !strings.Contains(err.Error(), "label team=ml") {
+		t.Errorf("Expected no-backend error naming the label, got %v", err)
+	}
+}
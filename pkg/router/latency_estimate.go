@@ -0,0 +1,226 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// promptBuckets are the upper bounds, in estimated tokens, of the prompt
+// lengths latency is tracked separately for; longer prompts share a last
+// bucket. Prompt processing dominates time to first token, so a backend
+// that answers short prompts quickly can still be slow on long ones.
+var promptBuckets = [...]int32{256, 1024, 4096, 16384}
+
+// promptBucket returns the bucket promptTokens falls in
+func promptBucket(promptTokens int32) int {
+	for i, limit := range promptBuckets {
+		if promptTokens < limit {
+			return i
+		}
+	}
+	return len(promptBuckets)
+}
+
+// LatencyEstimate is the latency observed on a backend for one model and
+// prompt length bucket
+type LatencyEstimate struct {
+	Backend    string    `json:"backend"`
+	Model      string    `json:"model"`
+	MinTokens  int32     `json:"min_prompt_tokens"` // lower bound of the bucket
+	LatencyMs  float64   `json:"latency_ms"`        // moving average
+	Samples    int64     `json:"samples"`
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+type estimateKey struct {
+	backend string
+	model   string
+	bucket  int
+}
+
+// LatencyEstimator predicts a request's latency on a backend from the
+// latency observed there for the same model and a similar prompt length:
+// an exponentially weighted moving average per backend, model and prompt
+// length bucket. A nil *LatencyEstimator ignores samples and estimates
+// nothing.
+type LatencyEstimator struct {
+	decay float64
+
+	mu        sync.RWMutex
+	estimates map[estimateKey]LatencyEstimate
+}
+
+// NewLatencyEstimator creates an estimator weighting each new sample by
+// decay (0-1, DefaultEWMADecay when out of range)
+func NewLatencyEstimator(decay float64) *LatencyEstimator {
+	if decay <= 0 || decay > 1 {
+		decay = DefaultEWMADecay
+	}
+	return &LatencyEstimator{decay: decay, estimates: make(map[estimateKey]LatencyEstimate)}
+}
+
+// Observe folds one request's latency into the estimate for its backend,
+// model and prompt length. Requests without a model are not tracked.
+func (e *LatencyEstimator) Observe(backendID, model string, promptTokens int32, d time.Duration) {
+	if e == nil || model == "" {
+		return
+	}
+	ms := float64(d) / float64(time.Millisecond)
+	key := estimateKey{backend: backendID, model: strings.ToLower(model), bucket: promptBucket(promptTokens)}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	est, ok := e.estimates[key]
+	if ok {
+		ms = e.decay*ms + (1-e.decay)*est.LatencyMs
+	}
+	e.estimates[key] = LatencyEstimate{
+		Backend:    backendID,
+		Model:      key.model,
+		MinTokens:  bucketMinTokens(key.bucket),
+		LatencyMs:  ms,
+		Samples:    est.Samples + 1,
+		ObservedAt: time.Now(),
+	}
+}
+
+// Estimate returns the predicted latency of a request for model with a
+// prompt of promptTokens on backendID. Without samples for that prompt
+// length, the nearest length observed for the model is used; false when
+// the model was never observed on the backend.
+func (e *LatencyEstimator) Estimate(backendID, model string, promptTokens int32) (float64, bool) {
+	if e == nil || model == "" {
+		return 0, false
+	}
+	key := estimateKey{backend: backendID, model: strings.ToLower(model), bucket: promptBucket(promptTokens)}
+	bucket := key.bucket
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if est, ok := e.estimates[key]; ok {
+		return est.LatencyMs, true
+	}
+	for dist := 1; dist <= len(promptBuckets); dist++ {
+		for _, b := range [2]int{bucket - dist, bucket + dist} {
+			key.bucket = b
+			if est, ok := e.estimates[key]; ok {
+				return est.LatencyMs, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Estimates returns every estimate sorted by backend, model and prompt length
+func (e *LatencyEstimator) Estimates() []LatencyEstimate {
+	e.mu.RLock()
+	list := make([]LatencyEstimate, 0, len(e.estimates))
+	for _, est := range e.estimates {
+		list = append(list, est)
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Backend != list[j].Backend {
+			return list[i].Backend < list[j].Backend
+		}
+		if list[i].Model != list[j].Model {
+			return list[i].Model < list[j].Model
+		}
+		return list[i].MinTokens < list[j].MinTokens
+	})
+	return list
+}
+
+// Load restores estimates saved by Save. A missing file is not an error.
+func (e *LatencyEstimator) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved []LatencyEstimate
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, est := range saved {
+		if est.Model == "" || est.LatencyMs <= 0 {
+			continue
+		}
+		key := estimateKey{backend: est.Backend, model: strings.ToLower(est.Model), bucket: promptBucket(est.MinTokens)}
+		est.Model = key.model
+		est.MinTokens = bucketMinTokens(key.bucket)
+		e.estimates[key] = est
+	}
+	return nil
+}
+
+// Save writes the estimates to path, then renames them into place so a
+// crash never leaves a truncated file
+func (e *LatencyEstimator) Save(path string) error {
+	data, err := json.MarshalIndent(e.Estimates(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to save latency estimates: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save latency estimates: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save latency estimates: %w", err)
+	}
+	return nil
+}
+
+// RunPersistence saves the estimates to path every interval until ctx is
+// done. Errors are passed to onError.
+func (e *LatencyEstimator) RunPersistence(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	if path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Save(path); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func bucketMinTokens(bucket int) int32 {
+	if bucket == 0 {
+		return 0
+	}
+	return promptBuckets[bucket-1]
+}
+
+// LatencyEstimator returns the router's per-model latency estimator, e.g.
+// to persist it between runs
+func (r *Router) LatencyEstimator() *LatencyEstimator {
+	return r.load.estimator
+}
@@ -0,0 +1,115 @@
+package router
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestLatencyEstimator_Buckets(t *testing.T) {
+	e := NewLatencyEstimator(0.5)
+	e.Observe("nvidia", "Llama3", 100, 200*time.Millisecond)
+	e.Observe("nvidia", "llama3", 100, 400*time.Millisecond)
+	e.Observe("nvidia", "llama3", 5000, 2*time.Second)
+	e.Observe("nvidia", "", 100, time.Hour)
+
+	tests := []struct {
+		backend, model string
+		promptTokens   int32
+		want           float64
+		ok             bool
+	}{
+		{"nvidia", "llama3", 0, 300, true},       // averaged, model names fold case
+		{"nvidia", "llama3", 4096, 2000, true},   // its own bucket
+		{"nvidia", "llama3", 300, 300, true},     // nearest observed bucket
+		{"nvidia", "llama3", 100000, 2000, true}, // longest prompts share the last bucket
+		{"nvidia", "mistral", 100, 0, false},
+		{"igpu", "llama3", 100, 0, false},
+		{"nvidia", "", 100, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := e.Estimate(tt.backend, tt.model, tt.promptTokens)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Estimate(%s, %q, %d) = %v, %v; expected %v, %v", tt.backend, tt.model, tt.promptTokens, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	var nilEstimator *LatencyEstimator
+	nilEstimator.Observe("nvidia", "llama3", 0, time.Second)
+	if _, ok := nilEstimator.Estimate("nvidia", "llama3", 0); ok {
+		t.Error("Expected a nil estimator to estimate nothing")
+	}
+}
+
+func TestLatencyEstimator_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "latency.json")
+
+	e := NewLatencyEstimator(0.3)
+	e.Observe("nvidia", "llama3", 100, 250*time.Millisecond)
+	e.Observe("nvidia", "llama3", 2000, time.Second)
+	if err := e.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	restored := NewLatencyEstimator(0.3)
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want, got := e.Estimates(), restored.Estimates()
+	for i := range got {
+		if i < len(want) && got[i].ObservedAt.Equal(want[i].ObservedAt) {
+			got[i].ObservedAt = want[i].ObservedAt
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v restored, got %+v", want, got)
+	}
+	if got := restored.Estimates()[1].MinTokens; got != 1024 {
+		t.Errorf("Expected the bucket's lower bound saved, got %d", got)
+	}
+
+	if err := NewLatencyEstimator(0.3).Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Expected a missing file to be ignored, got %v", err)
+	}
+}
+
+func TestRouter_EstimatedLatencyFromObservations(t *testing.T) {
+	r := costRouter()
+	r.SetCostWeights(func() CostWeights { return CostWeights{Latency: 1} })
+	annotations := backends.Annotations{Model: "llama3", PromptTokens: 3000}
+
+	decision := routeCost(t, r, annotations)
+	if decision.Backend.ID() != "nvidia" || decision.EstimatedLatencyMs != 150 {
+		t.Fatalf("Expected the advertised latency before any observations, got %s %dms", decision.Backend.ID(), decision.EstimatedLatencyMs)
+	}
+
+	// Long prompts turn out slow on nvidia; short ones don't
+	r.LatencyEstimator().Observe("nvidia", "llama3", 3000, 1200*time.Millisecond)
+	r.LatencyEstimator().Observe("nvidia", "llama3", 10, 100*time.Millisecond)
+	r.LatencyEstimator().Observe("igpu", "llama3", 3000, 500*time.Millisecond)
+
+	decision = routeCost(t, r, annotations)
+	if decision.Backend.ID() != "igpu" || decision.EstimatedLatencyMs != 500 {
+		t.Errorf("Expected the backend observed fastest for long prompts, got %s %dms", decision.Backend.ID(), decision.EstimatedLatencyMs)
+	}
+	annotations.PromptTokens = 10
+	if decision := routeCost(t, r, annotations); decision.Backend.ID() != "nvidia" || decision.EstimatedLatencyMs != 100 {
+		t.Errorf("Expected the backend observed fastest for short prompts, got %s %dms", decision.Backend.ID(), decision.EstimatedLatencyMs)
+	}
+
+	// Completed requests feed the estimate for their model and prompt length
+	decision, err := r.RouteRequest(context.Background(), &backends.Annotations{Target: "npu", Model: "phi3", PromptTokens: 600})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if _, err := decision.Backend.Generate(context.Background(), &backends.GenerateRequest{Model: "phi3"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, ok := r.LatencyEstimator().Estimate("npu", "phi3", 600); !ok {
+		t.Error("Expected the request's latency observed")
+	}
+}
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/models"
+)
+
+// MemorySource returns the free memory, in bytes, of the roomiest
+// accelerator of a hardware type, false when it is unknown
+type MemorySource func(hardware string) (uint64, bool)
+
+// DefaultContextLength is the KV cache assumed for a request that sets no
+// context length, Ollama's default
+const DefaultContextLength = 4096
+
+// DefaultMemoryHeadroom is left free beyond a request's estimate, for the
+// runtime's own buffers and estimates that run short
+const DefaultMemoryHeadroom = 512 << 20
+
+// DefaultResidentWindow is how long a model stays loaded after its last
+// request, Ollama's default keep_alive
+const DefaultResidentWindow = 5 * time.Minute
+
+// weightBytesPerParam sizes a model's weights at 4-bit quantization, the
+// usual Ollama download, with its overhead
+const weightBytesPerParam = 0.6
+
+// kvBytesPerTokenPerB sizes the fp16 KV cache per token of context per
+// billion parameters, e.g. 128 KiB a token for an 8B model
+const kvBytesPerTokenPerB = 16 << 10
+
+// MemoryAdmissionConfig configures the check that a request fits in the
+// memory of the accelerator it is routed to
+type MemoryAdmissionConfig struct {
+	Headroom             uint64        // bytes left free beyond the estimate, 0 = DefaultMemoryHeadroom
+	DefaultContextLength int32         // for requests that set none, 0 = DefaultContextLength
+	ResidentWindow       time.Duration // a model routed this recently counts as loaded, 0 = DefaultResidentWindow
+}
+
+// memoryAdmission rejects backends whose accelerators can't hold a request
+type memoryAdmission struct {
+	source MemorySource
+	config MemoryAdmissionConfig
+
+	mu       sync.Mutex
+	resident map[string]map[string]time.Time // backend -> model -> last routed
+}
+
+// EstimateMemory returns the accelerator memory a request for model needs:
+// its weights unless already loaded, plus the KV cache for contextLength
+// tokens of each of batch sequences. It reports false when the model's
+// name doesn't give its size.
+func EstimateMemory(model string, contextLength, batch int32, loaded bool) (uint64, bool) {
+	paramsB, ok := models.ParamsB(model)
+	if !ok {
+		return 0, false
+	}
+	if batch < 1 {
+		batch = 1
+	}
+	need := paramsB * kvBytesPerTokenPerB * float64(contextLength) * float64(batch)
+	if !loaded {
+		need += paramsB * 1e9 * weightBytesPerParam
+	}
+	return uint64(need), true
+}
+
+// SetMemoryAdmission installs the source of free accelerator memory that
+// requests are checked against before dispatch (nil = not checked)
+func (r *Router) SetMemoryAdmission(source MemorySource, cfg MemoryAdmissionConfig) {
+	if cfg.Headroom == 0 {
+		cfg.Headroom = DefaultMemoryHeadroom
+	}
+	if cfg.DefaultContextLength <= 0 {
+		cfg.DefaultContextLength = DefaultContextLength
+	}
+	if cfg.ResidentWindow <= 0 {
+		cfg.ResidentWindow = DefaultResidentWindow
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if source == nil {
+		r.memory = nil
+		return
+	}
+	r.memory = &memoryAdmission{
+		source:   source,
+		config:   cfg,
+		resident: make(map[string]map[string]time.Time),
+	}
+}
+
+// fits reports whether backend has the memory for the request, with the
+// estimate and the free memory it was checked against. Backends whose
+// memory is unknown, and models whose size is, always fit.
+func (m *memoryAdmission) fits(backend backends.Backend, annotations *backends.Annotations) (bool, uint64, uint64) {
+	if m == nil || annotations.Model == "" {
+		return true, 0, 0
+	}
+	free, ok := m.source(backend.Hardware())
+	if !ok {
+		return true, 0, 0
+	}
+	contextLength := annotations.ContextLength
+	if contextLength <= 0 {
+		contextLength = m.config.DefaultContextLength
+	}
+	need, ok := EstimateMemory(annotations.Model, contextLength, annotations.Batch, m.loaded(backend.ID(), annotations.Model))
+	if !ok {
+		return true, 0, 0
+	}
+	return need+m.config.Headroom <= free, need + m.config.Headroom, free
+}
+
+// loaded reports whether model was routed to backend within the resident
+// window, so its weights are already counted as used
+func (m *memoryAdmission) loaded(backendID, model string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	last, ok := m.resident[backendID][strings.ToLower(model)]
+	return ok && time.Since(last) < m.config.ResidentWindow
+}
+
+// routed records model as loaded on backend
+func (m *memoryAdmission) routed(backendID, model string) {
+	if m == nil || model == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.resident[backendID] == nil {
+		m.resident[backendID] = make(map[string]time.Time)
+	}
+	m.resident[backendID][strings.ToLower(model)] = time.Now()
+}
+
+// fitMemory drops the candidates that would run out of memory serving the
+// request. When it drops them all, need is the smallest estimate and free
+// the most memory any of them had. Caller must hold r.mu.
+func (r *Router) fitMemory(candidates []backends.Backend, annotations *backends.Annotations) (fit []backends.Backend, need, free uint64) {
+	if r.memory == nil {
+		return candidates, 0, 0
+	}
+	fit = make([]backends.Backend, 0, len(candidates))
+	for _, backend := range candidates {
+		ok, n, f := r.memory.fits(backend, annotations)
+		if ok {
+			fit = append(fit, backend)
+			continue
+		}
+		if need == 0 || n < need {
+			need = n
+		}
+		if f > free {
+			free = f
+		}
+	}
+	return fit, need, free
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	proxyerrors "github.com/daoneill/ollama-proxy/pkg/errors"
+)
+
+func TestEstimateMemory(t *testing.T) {
+	cold, ok := EstimateMemory("llama3:8b", 4096, 1, false)
+	if !ok || cold != 4800000000+512<<20 {
+		t.Errorf("Expected weights plus 512 MiB of KV cache, got %d, %v", cold, ok)
+	}
+	if warm, _ := EstimateMemory("llama3:8b", 4096, 1, true); warm != 512<<20 {
+		t.Errorf("Expected only the KV cache once loaded, got %d", warm)
+	}
+	if batch, _ := EstimateMemory("llama3:8b", 4096, 4, true); batch != 2<<30 {
+		t.Errorf("Expected a KV cache per sequence, got %d", batch)
+	}
+	if _, ok := EstimateMemory("llama3", 4096, 1, false); ok {
+		t.Error("Expected no estimate for a model without a size in its name")
+	}
+}
+
+func TestRouter_MemoryAdmission(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "nvidia", hardware: "nvidia", healthy: true, avgLatencyMs: 50})
+	r.RegisterBackend(&MockBackend{id: "igpu", hardware: "igpu", healthy: true, avgLatencyMs: 400})
+	r.SetMemoryAdmission(func(hardware string) (uint64, bool) {
+		switch hardware {
+		case "nvidia":
+			return 6 << 30, true
+		case "igpu":
+			return 12 << 30, true
+		}
+		return 0, false
+	}, MemoryAdmissionConfig{})
+
+	route := func(contextLength int32) (*RoutingDecision, error) {
+		return r.RouteRequest(context.Background(), &backends.Annotations{
+			Model:           "llama3:8b",
+			ContextLength:   contextLength,
+			LatencyCritical: true,
+		})
+	}
+
+	// A long context doesn't fit next to the weights on the 6 GiB GPU
+	decision, err := route(32768)
+	if err != nil || decision.Backend.ID() != "igpu" {
+		t.Fatalf("Expected re-routing to the roomier GPU, got %v, %v", decision, err)
+	}
+
+	decision, err = route(0)
+	if err != nil || decision.Backend.ID() != "nvidia" {
+		t.Fatalf("Expected the faster GPU at the default context, got %v, %v", decision, err)
+	}
+
+	// Once loaded there, only the KV cache has to fit
+	decision, err = route(32768)
+	if err != nil || decision.Backend.ID() != "nvidia" {
+		t.Errorf("Expected the GPU holding the model, got %v, %v", decision, err)
+	}
+
+	// An explicit target without the memory falls back to auto-selection
+	decision, err = r.RouteRequest(context.Background(), &backends.Annotations{Model: "qwen2:7b", ContextLength: 32768, Target: "nvidia"})
+	if err != nil || decision.Backend.ID() == "nvidia" {
+		t.Errorf("Expected the target skipped, got %v, %v", decision, err)
+	}
+
+	// Nothing has room for a 70B model
+	_, err = r.RouteRequest(context.Background(), &backends.Annotations{Model: "llama3:70b"})
+	var memoryErr *proxyerrors.InsufficientMemoryError
+	if !errors.As(err, &memoryErr) {
+		t.Fatalf("Expected an insufficient memory error, got %v", err)
+	}
+	if memoryErr.AvailableBytes != 12<<30 || memoryErr.RequiredBytes <= memoryErr.AvailableBytes {
+		t.Errorf("Unexpected error details %+v", memoryErr)
+	}
+	if memoryErr.Code() != proxyerrors.CodeInsufficientMemory {
+		t.Errorf("Expected code %d, got %d", proxyerrors.CodeInsufficientMemory, memoryErr.Code())
+	}
+}
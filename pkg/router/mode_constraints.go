@@ -0,0 +1,67 @@
+package router
+
+import (
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// ModeConstraints are efficiency limits enforced on every request while
+// in force, regardless of the request's annotations or the selected mode.
+// A quiet window uses them to keep inference on silent backends.
+type ModeConstraints struct {
+	Reason          string   // e.g. "quiet window: meeting"
+	MaxPowerWatts   int32    // 0 = no cap
+	AllowedBackends []string // empty = any backend
+}
+
+// ModeConstraintSource reports the constraints currently in force
+type ModeConstraintSource func() (ModeConstraints, bool)
+
+// SetModeConstraints installs the source consulted on every routing
+// decision (nil = no constraints)
+func (r *Router) SetModeConstraints(source ModeConstraintSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modeConstraints = source
+}
+
+// activeModeConstraints returns the constraints in force, or nil when none
+// apply. Caller must hold r.mu.
+func (r *Router) activeModeConstraints() *ModeConstraints {
+	if r.modeConstraints == nil {
+		return nil
+	}
+	mc, ok := r.modeConstraints()
+	if !ok {
+		return nil
+	}
+	return &mc
+}
+
+// applyModeConstraints tightens annotations to the constraints in force
+// and returns them, or nil when none apply. Caller must hold r.mu.
+func (r *Router) applyModeConstraints(annotations *backends.Annotations) *ModeConstraints {
+	mc := r.activeModeConstraints()
+	if mc == nil {
+		return nil
+	}
+
+	if mc.MaxPowerWatts > 0 && (annotations.MaxPowerWatts == 0 || annotations.MaxPowerWatts > mc.MaxPowerWatts) {
+		annotations.MaxPowerWatts = mc.MaxPowerWatts
+	}
+	annotations.PreferPowerEfficiency = true
+	annotations.LatencyCritical = false
+	return mc
+}
+
+// allows reports whether backendID may serve requests under the constraints
+func (mc *ModeConstraints) allows(backendID string) bool {
+	if mc == nil || len(mc.AllowedBackends) == 0 {
+		return true
+	}
+	for _, id := range mc.AllowedBackends {
+		if id == backendID {
+			return true
+		}
+	}
+	return false
+}
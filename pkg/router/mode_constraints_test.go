@@ -0,0 +1,64 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestRouteRequest_ModeConstraints(t *testing.T) {
+	router := NewRouter(Config{})
+	router.RegisterBackend(&MockBackend{id: "ollama-nvidia", healthy: true, powerWatts: 150, priority: 10})
+	router.RegisterBackend(&MockBackend{id: "ollama-npu", healthy: true, powerWatts: 3, priority: 1})
+
+	quiet := true
+	router.SetModeConstraints(func() (ModeConstraints, bool) {
+		return ModeConstraints{
+			Reason:          "quiet window: meeting",
+			MaxPowerWatts:   15,
+			AllowedBackends: []string{"ollama-npu", "ollama-igpu"},
+		}, quiet
+	})
+
+	// Even an explicit target outside the allowed set is overridden
+	annotations := &backends.Annotations{Target: "ollama-nvidia", LatencyCritical: true}
+	decision, err := router.RouteRequest(context.Background(), annotations)
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if decision.Backend.ID() != "ollama-npu" {
+		t.Errorf("Expected quiet window to force ollama-npu, got %s", decision.Backend.ID())
+	}
+	if !strings.Contains(decision.Reason, "quiet window: meeting") {
+		t.Errorf("Expected reason to mention quiet window, got %q", decision.Reason)
+	}
+	if annotations.MaxPowerWatts != 15 || annotations.LatencyCritical {
+		t.Errorf("Expected annotations tightened, got power=%d latencyCritical=%v",
+			annotations.MaxPowerWatts, annotations.LatencyCritical)
+	}
+
+	// Outside the window the request is honored
+	quiet = false
+	decision, err = router.RouteRequest(context.Background(), &backends.Annotations{Target: "ollama-nvidia"})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if decision.Backend.ID() != "ollama-nvidia" {
+		t.Errorf("Expected explicit target outside quiet window, got %s", decision.Backend.ID())
+	}
+}
+
+func TestRouteRequest_ModeConstraintsNoBackend(t *testing.T) {
+	router := NewRouter(Config{})
+	router.RegisterBackend(&MockBackend{id: "ollama-nvidia", healthy: true, powerWatts: 150})
+	router.SetModeConstraints(func() (ModeConstraints, bool) {
+		return ModeConstraints{Reason: "quiet window: manual", AllowedBackends: []string{"ollama-npu"}}, true
+	})
+
+	_, err := router.RouteRequest(context.Background(), &backends.Annotations{})
+	if err == nil || !strings.Contains(err.Error(), "quiet window: manual") {
+		t.Errorf("Expected no-backend error naming the quiet window, got %v", err)
+	}
+}
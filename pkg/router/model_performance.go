@@ -0,0 +1,65 @@
+package router
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModelPerformance is a backend's measured speed serving one model
+type ModelPerformance struct {
+	Backend         string    `json:"backend"`
+	Model           string    `json:"model"`
+	TTFTMs          float64   `json:"ttft_ms"` // time to first token
+	TokensPerSecond float64   `json:"tokens_per_second"`
+	MeasuredAt      time.Time `json:"measured_at"`
+}
+
+func performanceKey(backendID, model string) string {
+	return backendID + "\x00" + strings.ToLower(model)
+}
+
+// SetModelPerformance replaces the table of measured model speeds. When
+// the requested model was measured on a backend, scoring uses its time to
+// first token in place of the backend's live or advertised average latency.
+func (r *Router) SetModelPerformance(table []ModelPerformance) {
+	perf := make(map[string]ModelPerformance, len(table))
+	for _, p := range table {
+		perf[performanceKey(p.Backend, p.Model)] = p
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.performance = perf
+}
+
+// ModelPerformance returns the measured model speeds sorted by backend
+// and model
+func (r *Router) ModelPerformance() []ModelPerformance {
+	r.mu.RLock()
+	table := make([]ModelPerformance, 0, len(r.performance))
+	for _, p := range r.performance {
+		table = append(table, p)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(table, func(i, j int) bool {
+		if table[i].Backend != table[j].Backend {
+			return table[i].Backend < table[j].Backend
+		}
+		return table[i].Model < table[j].Model
+	})
+	return table
+}
+
+// latencyMs is the latency backend is scored on for model: the measured
+// time to first token when there is one, else the backend's average.
+// Caller must hold r.mu.
+func (r *Router) latencyMs(backendID string, avgLatencyMs int32, model string) float64 {
+	if model != "" {
+		if p, ok := r.performance[performanceKey(backendID, model)]; ok && p.TTFTMs > 0 {
+			return p.TTFTMs
+		}
+	}
+	return float64(avgLatencyMs)
+}
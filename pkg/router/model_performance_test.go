@@ -0,0 +1,57 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestRouter_ModelPerformance(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "nvidia", hardware: "nvidia", healthy: true, avgLatencyMs: 150})
+	r.RegisterBackend(&MockBackend{id: "igpu", hardware: "igpu", healthy: true, avgLatencyMs: 400})
+
+	route := func(model string) string {
+		decision, err := r.RouteRequest(context.Background(), &backends.Annotations{Model: model, LatencyCritical: true})
+		if err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+		return decision.Backend.ID()
+	}
+	if got := route("qwen2:7b"); got != "nvidia" {
+		t.Fatalf("Expected the lower average latency without measurements, got %s", got)
+	}
+
+	// The iGPU measured faster on this model, e.g. the GPU has to offload it
+	r.SetModelPerformance([]ModelPerformance{
+		{Backend: "nvidia", Model: "Qwen2:7b", TTFTMs: 900, TokensPerSecond: 12},
+		{Backend: "igpu", Model: "qwen2:7b", TTFTMs: 300, TokensPerSecond: 20},
+	})
+	if got := route("qwen2:7b"); got != "igpu" {
+		t.Errorf("Expected the measured faster backend, got %s", got)
+	}
+	if got := route("llama3:8b"); got != "nvidia" {
+		t.Errorf("Expected averages for an unmeasured model, got %s", got)
+	}
+
+	table := r.ModelPerformance()
+	if len(table) != 2 || table[0].Backend != "igpu" {
+		t.Errorf("Expected the table sorted by backend, got %+v", table)
+	}
+}
+
+func TestRouter_InFlight(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "a", healthy: true})
+	r.RegisterBackend(&MockBackend{id: "b", healthy: true})
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.RouteRequest(context.Background(), &backends.Annotations{}); err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+	}
+	if n := r.InFlight(); n != 3 {
+		t.Errorf("Expected 3 requests in flight, got %d", n)
+	}
+}
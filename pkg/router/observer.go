@@ -0,0 +1,98 @@
+package router
+
+import (
+	"sort"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// DecisionObserver is told about every routing decision, e.g. to record
+// decisions for offline replay. It runs on the request path after the
+// router lock is released and must not block.
+type DecisionObserver func(rec *DecisionRecord)
+
+// DecisionRecord is a routing decision together with the state it was
+// made against
+type DecisionRecord struct {
+	// Annotations as received, before mode constraints tightened them
+	Annotations backends.Annotations
+
+	// Constraints in force, nil when none applied
+	Constraints *ModeConstraints
+
+	// Backends is the state of every registered backend, ordered by ID
+	Backends []BackendSnapshot
+
+	Decision *RoutingDecision // nil when routing failed
+	Err      error
+
+	// Key is the name of the API key the request authenticated as, ""
+	// when unauthenticated
+	Key string
+
+	observer DecisionObserver
+}
+
+// BackendSnapshot is one backend's state at decision time
+type BackendSnapshot struct {
+	Backend       backends.Backend
+	Healthy       bool
+	InFlight      [backends.PriorityCritical + 1]int // unfinished requests by priority
+	LatencyEWMAMs float64
+}
+
+// SetDecisionObserver installs fn to be called after every RouteRequest
+// (nil = none)
+func (r *Router) SetDecisionObserver(fn DecisionObserver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observer = fn
+}
+
+// AddDecisionObserver installs fn to be called after any observer already
+// set, so several consumers can watch decisions
+func (r *Router) AddDecisionObserver(fn DecisionObserver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if prev := r.observer; prev != nil {
+		r.observer = func(rec *DecisionRecord) {
+			prev(rec)
+			fn(rec)
+		}
+		return
+	}
+	r.observer = fn
+}
+
+// newDecisionRecordLocked snapshots the router for the observer, or
+// returns nil when none is set. Caller must hold r.mu.
+func (r *Router) newDecisionRecordLocked(annotations *backends.Annotations) *DecisionRecord {
+	if r.observer == nil {
+		return nil
+	}
+
+	rec := &DecisionRecord{
+		Annotations: *annotations,
+		Backends:    make([]BackendSnapshot, 0, len(r.backends)),
+		observer:    r.observer,
+	}
+	for id, backend := range r.backends {
+		rec.Backends = append(rec.Backends, BackendSnapshot{
+			Backend:       backend,
+			Healthy:       backend.IsHealthy(),
+			InFlight:      r.queueMgr.GetPriorityBreakdown(id),
+			LatencyEWMAMs: r.load.LatencyMs(id),
+		})
+	}
+	sort.Slice(rec.Backends, func(i, j int) bool {
+		return rec.Backends[i].Backend.ID() < rec.Backends[j].Backend.ID()
+	})
+	return rec
+}
+
+// RestoreLoad overwrites a backend's load counters. It exists for replaying
+// recorded decisions offline and must not be used on a serving router.
+func (r *Router) RestoreLoad(backendID string, inFlight [backends.PriorityCritical + 1]int, latencyEWMAMs float64) {
+	r.queueMgr.restore(backendID, inFlight)
+	r.load.latency.restore(backendID, latencyEWMAMs)
+}
@@ -0,0 +1,81 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestDecisionObserver(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "npu", healthy: true, powerWatts: 3})
+	r.RegisterBackend(&MockBackend{id: "gpu", healthy: false, powerWatts: 55})
+	r.SetModeConstraints(func() (ModeConstraints, bool) {
+		return ModeConstraints{Reason: "quiet window: test", MaxPowerWatts: 10}, true
+	})
+
+	var records []*DecisionRecord
+	r.SetDecisionObserver(func(rec *DecisionRecord) { records = append(records, rec) })
+
+	decision, err := r.RouteRequest(context.Background(), &backends.Annotations{LatencyCritical: true})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 observed decision, got %d", len(records))
+	}
+
+	rec := records[0]
+	if !rec.Annotations.LatencyCritical || rec.Annotations.MaxPowerWatts != 0 {
+		t.Errorf("Expected annotations as received, got %+v", rec.Annotations)
+	}
+	if rec.Constraints == nil || rec.Constraints.MaxPowerWatts != 10 {
+		t.Errorf("Expected constraints in force, got %+v", rec.Constraints)
+	}
+	if len(rec.Backends) != 2 || rec.Backends[0].Backend.ID() != "gpu" || rec.Backends[0].Healthy {
+		t.Errorf("Expected ordered backend snapshot, got %+v", rec.Backends)
+	}
+	if rec.Decision != decision {
+		t.Error("Expected the decision to be observed")
+	}
+
+	// Failed routing is observed too
+	r.RouteRequest(context.Background(), &backends.Annotations{MaxPowerWatts: 1})
+	if len(records) != 2 || records[1].Err == nil || records[1].Decision != nil {
+		t.Errorf("Expected failed decision to be observed, got %+v", records[len(records)-1])
+	}
+}
+
+func TestAddDecisionObserver(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "npu", healthy: true})
+
+	var order []string
+	r.AddDecisionObserver(func(rec *DecisionRecord) { order = append(order, "first") })
+	r.AddDecisionObserver(func(rec *DecisionRecord) { order = append(order, "second") })
+	if _, err := r.RouteRequest(context.Background(), &backends.Annotations{}); err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("Expected both observers in order, got %v", order)
+	}
+}
+
+func TestRestoreLoad(t *testing.T) {
+	r := NewRouter(Config{})
+	r.queueMgr.MarkRequestStart("a", backends.PriorityNormal)
+
+	r.RestoreLoad("a", [4]int{0, 0, 2, 1}, 250)
+	if n := r.load.InFlight("a"); n != 3 {
+		t.Errorf("Expected 3 in flight, got %d", n)
+	}
+	if ms := r.load.LatencyMs("a"); ms != 250 {
+		t.Errorf("Expected 250ms, got %.0f", ms)
+	}
+
+	r.RestoreLoad("a", [4]int{}, 0)
+	if r.load.InFlight("a") != 0 || r.load.LatencyMs("a") != 0 {
+		t.Error("Expected load to be cleared")
+	}
+}
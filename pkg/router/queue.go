@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/energy"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
 )
 
 // BackendQueue tracks pending requests for a backend
@@ -103,7 +105,10 @@ func (qm *QueueManager) MarkRequestStart(backendID string, priority backends.Pri
 		queue.priorityCounts[priority]++
 	}
 	queue.lastUpdate = time.Now()
+	pending := queue.pending
 	queue.mu.Unlock()
+
+	metrics.SetBackendInFlight(backendID, pending)
 }
 
 // MarkRequestEnd decrements queue depth
@@ -130,7 +135,26 @@ func (qm *QueueManager) MarkRequestEnd(backendID string, priority backends.Prior
 	}
 
 	queue.lastUpdate = time.Now()
+	pending := queue.pending
 	queue.mu.Unlock()
+
+	metrics.SetBackendInFlight(backendID, pending)
+}
+
+// restore overwrites a backend's pending counts by priority
+func (qm *QueueManager) restore(backendID string, counts [4]int) {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	pending := 0
+	for _, n := range counts {
+		pending += n
+	}
+	qm.queues[backendID] = &BackendQueue{
+		pending:        pending,
+		priorityCounts: counts,
+		lastUpdate:     time.Now(),
+	}
 }
 
 // GetAllQueueStats returns queue statistics for all backends
@@ -162,43 +186,218 @@ type QueueStats struct {
 }
 
 // QueueTrackingBackend wraps a backend to automatically track queue depth
+// and observed latency and, when a scheduler is set, to wait for a
+// concurrency slot
 type QueueTrackingBackend struct {
 	backends.Backend
-	queueMgr *QueueManager
-	priority backends.Priority
+	queueMgr  *QueueManager
+	priority  backends.Priority
+	scheduler *Scheduler
+	latency   *latencyTracker
+	kill      *KillSwitch
+	deadline  time.Time // from the X-Deadline-Ms header, zero when absent
+
+	// Latency is also estimated per model and prompt length
+	estimator    *LatencyEstimator
+	model        string
+	promptTokens int32
+
+	// Measures the energy each request uses (nil = not measured)
+	energy *energy.Meter
+
+	// Attaches retry advice to rejections (nil = none)
+	router *Router
+}
+
+// acquire waits for a scheduler slot; without a scheduler it returns at once
+func (qtb *QueueTrackingBackend) acquire(ctx context.Context) (func(), error) {
+	if qtb.scheduler == nil {
+		return func() {}, nil
+	}
+	release, err := qtb.scheduler.acquire(ctx, qtb.Backend.ID(), qtb.priority, qtb.deadline)
+	if err != nil && qtb.router != nil {
+		err = qtb.router.withRetryAdvice(err, qtb.Backend.ID())
+	}
+	return release, err
+}
+
+// ColdStart asks the wrapped backend whether model needs loading
+func (qtb *QueueTrackingBackend) ColdStart(ctx context.Context, model string) *backends.ColdStart {
+	return backends.CheckColdStart(ctx, qtb.Backend, model)
+}
+
+// ColdStart reports whether the selected backend must load model before
+// responding, so streaming handlers can tell the client instead of
+// appearing hung. It returns nil when the model is warm or the backend
+// cannot tell.
+func (d *RoutingDecision) ColdStart(ctx context.Context, model string) *backends.ColdStart {
+	cs := backends.CheckColdStart(ctx, d.Backend, model)
+	if cs != nil {
+		metrics.RecordColdStart(d.Backend.ID(), cs.Reason)
+	}
+	return cs
+}
+
+// observe records the latency of the request on the backend
+func (qtb *QueueTrackingBackend) observe(d time.Duration) {
+	qtb.latency.observe(qtb.Backend.ID(), d)
+	qtb.estimator.Observe(qtb.Backend.ID(), qtb.model, qtb.promptTokens, d)
+}
+
+// track runs a single-response operation on the backend: it waits for a
+// scheduler slot, records the backend's latency and measured energy on
+// success and marks the request finished. op runs under a context the
+// kill switch can cancel.
+func track[T any](ctx context.Context, qtb *QueueTrackingBackend, op func(ctx context.Context) (T, error)) (T, error) {
+	defer qtb.queueMgr.MarkRequestEnd(qtb.Backend.ID(), qtb.priority)
+
+	ctx, done := qtb.kill.track(ctx, qtb.Backend.ID())
+	defer done()
+
+	release, err := qtb.acquire(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer release()
+
+	span := qtb.energy.Start(qtb.Backend.Hardware())
+	start := time.Now()
+	resp, err := op(ctx)
+	wh, measured := qtb.measure(span)
+	if err != nil && context.Cause(ctx) == ErrGenerationStopped {
+		err = ErrGenerationStopped
+	}
+	if err == nil {
+		qtb.observe(time.Since(start))
+		if measured {
+			setMeasuredEnergy(resp, wh)
+		}
+	}
+	return resp, err
 }
 
 // Generate wraps the underlying backend's Generate to track queue depth
 func (qtb *QueueTrackingBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
-	defer qtb.queueMgr.MarkRequestEnd(qtb.Backend.ID(), qtb.priority)
-	return qtb.Backend.Generate(ctx, req)
+	return track(ctx, qtb, func(ctx context.Context) (*backends.GenerateResponse, error) {
+		return qtb.Backend.Generate(ctx, req)
+	})
+}
+
+// SupportsImageEdit asks the wrapped backend whether it edits images
+func (qtb *QueueTrackingBackend) SupportsImageEdit() bool {
+	return backends.SupportsImageEdit(qtb.Backend)
+}
+
+// SupportsToolCalling asks the wrapped backend whether it offers models
+// tools
+func (qtb *QueueTrackingBackend) SupportsToolCalling() bool {
+	return backends.SupportsToolCalling(qtb.Backend)
+}
+
+// SupportsSequences asks the wrapped backend whether it generates several
+// completions in one request
+func (qtb *QueueTrackingBackend) SupportsSequences() bool {
+	return backends.SupportsSequences(qtb.Backend)
+}
+
+// GenerateSequences wraps the underlying backend's GenerateSequences to
+// track queue depth
+func (qtb *QueueTrackingBackend) GenerateSequences(ctx context.Context, req *backends.GenerateRequest, n, bestOf int) ([]*backends.GenerateResponse, error) {
+	return track(ctx, qtb, func(ctx context.Context) ([]*backends.GenerateResponse, error) {
+		return backends.NativeSequences(ctx, qtb.Backend, req, n, bestOf)
+	})
+}
+
+// Embed wraps the underlying backend's Embed to track queue depth
+func (qtb *QueueTrackingBackend) Embed(ctx context.Context, req *backends.EmbedRequest) (*backends.EmbedResponse, error) {
+	return track(ctx, qtb, func(ctx context.Context) (*backends.EmbedResponse, error) {
+		return qtb.Backend.Embed(ctx, req)
+	})
+}
+
+// TranscribeAudio wraps the underlying backend's TranscribeAudio to track queue depth
+func (qtb *QueueTrackingBackend) TranscribeAudio(ctx context.Context, req *backends.TranscribeRequest) (*backends.TranscribeResponse, error) {
+	return track(ctx, qtb, func(ctx context.Context) (*backends.TranscribeResponse, error) {
+		return qtb.Backend.TranscribeAudio(ctx, req)
+	})
+}
+
+// SynthesizeSpeech wraps the underlying backend's SynthesizeSpeech to track queue depth
+func (qtb *QueueTrackingBackend) SynthesizeSpeech(ctx context.Context, req *backends.SynthesizeRequest) (*backends.SynthesizeResponse, error) {
+	return track(ctx, qtb, func(ctx context.Context) (*backends.SynthesizeResponse, error) {
+		return qtb.Backend.SynthesizeSpeech(ctx, req)
+	})
+}
+
+// GenerateImage wraps the underlying backend's GenerateImage to track queue depth
+func (qtb *QueueTrackingBackend) GenerateImage(ctx context.Context, req *backends.ImageGenRequest) (*backends.ImageGenResponse, error) {
+	return track(ctx, qtb, func(ctx context.Context) (*backends.ImageGenResponse, error) {
+		return qtb.Backend.GenerateImage(ctx, req)
+	})
 }
 
 // GenerateStream wraps the underlying backend's GenerateStream to track queue depth
 func (qtb *QueueTrackingBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	ctx, done := qtb.kill.track(ctx, qtb.Backend.ID())
+	release, err := qtb.acquire(ctx)
+	if err != nil {
+		done()
+		qtb.queueMgr.MarkRequestEnd(qtb.Backend.ID(), qtb.priority)
+		return nil, err
+	}
+
+	span := qtb.energy.Start(qtb.Backend.Hardware())
+	start := time.Now()
 	reader, err := qtb.Backend.GenerateStream(ctx, req)
 	if err != nil {
+		qtb.measure(span)
+		release()
+		done()
 		qtb.queueMgr.MarkRequestEnd(qtb.Backend.ID(), qtb.priority)
 		return nil, err
 	}
+	// Streams are measured to the backend's first response, since their
+	// total duration depends on the output length
+	qtb.observe(time.Since(start))
 
 	// Wrap reader to mark end when stream closes
 	return &trackingStreamReader{
 		StreamReader: reader,
+		measure: func() (float64, bool) {
+			return qtb.measure(span)
+		},
 		onClose: func() {
+			qtb.measure(span)
+			release()
+			done()
 			qtb.queueMgr.MarkRequestEnd(qtb.Backend.ID(), qtb.priority)
 		},
 	}, nil
 }
 
 // trackingStreamReader wraps a StreamReader to call onClose when closed
+// and report the stream's measured energy in its final chunk
 type trackingStreamReader struct {
 	backends.StreamReader
+	measure func() (float64, bool) // nil = not measured
 	onClose func()
 	closed  bool
 	mu      sync.Mutex
 }
 
+// Recv returns the next chunk, with the measured energy of the whole
+// stream in the stats of the final one
+func (tsr *trackingStreamReader) Recv() (*backends.StreamChunk, error) {
+	chunk, err := tsr.StreamReader.Recv()
+	if err == nil && chunk != nil && chunk.Done && tsr.measure != nil {
+		if wh, ok := tsr.measure(); ok {
+			chunk.Stats = measuredStats(chunk.Stats, wh)
+		}
+	}
+	return chunk, err
+}
+
 // Close calls the underlying Close and the onClose callback
 func (tsr *trackingStreamReader) Close() error {
 	tsr.mu.Lock()
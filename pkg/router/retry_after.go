@@ -0,0 +1,196 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defaults for RetryAfterConfig
+const (
+	DefaultRetryAfterMin = time.Second
+	DefaultRetryAfterMax = 2 * time.Minute
+)
+
+// RetryAfterDebugHeader explains how Retry-After was computed
+const RetryAfterDebugHeader = "X-Retry-After-Debug"
+
+// CooldownSource returns how long a hardware type must cool before
+// requests may run on it again, 0 when it is usable
+type CooldownSource func(hardware string) time.Duration
+
+// RetryAfterConfig bounds the Retry-After sent with rejected requests
+type RetryAfterConfig struct {
+	Min   time.Duration // default 1s
+	Max   time.Duration // default 2m
+	Debug bool          // explain the computation in X-Retry-After-Debug
+}
+
+// RetryAdvice is when a client should retry a request that was rejected
+// for lack of capacity, and what that is based on
+type RetryAdvice struct {
+	Backend    string        // "" when no backend could be routed to
+	QueueDepth int           // requests routed to the backend that have not finished
+	Slots      int           // requests it runs at once, 0 = unlimited
+	LatencyMs  float64       // expected time per request
+	Drain      time.Duration // until the queue ahead has run
+	Cooldown   time.Duration // until the hardware may be used again
+	After      time.Duration // the longer of the two, within the configured bounds
+
+	debug bool
+}
+
+// Seconds is After in whole seconds, rounded up, as sent in Retry-After
+func (a RetryAdvice) Seconds() int {
+	seconds := int(math.Ceil(a.After.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// String is the X-Retry-After-Debug value
+func (a RetryAdvice) String() string {
+	backend := a.Backend
+	if backend == "" {
+		backend = "none"
+	}
+	return fmt.Sprintf("backend=%s; queue=%d; slots=%d; latency_ms=%.0f; drain=%s; cooldown=%s; retry_after=%d",
+		backend, a.QueueDepth, a.Slots, a.LatencyMs,
+		a.Drain.Round(100*time.Millisecond), a.Cooldown.Round(time.Second), a.Seconds())
+}
+
+// RetryableError is a rejection that carries advice on when to retry
+type RetryableError struct {
+	Err    error
+	Advice RetryAdvice
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// SetRetryAdvice configures the advice attached to rejected requests.
+// cooldown reports overheated hardware (nil = queues only).
+func (r *Router) SetRetryAdvice(cooldown CooldownSource, cfg RetryAfterConfig) {
+	if cfg.Min <= 0 {
+		cfg.Min = DefaultRetryAfterMin
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = DefaultRetryAfterMax
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cooldown = cooldown
+	r.retryAfter = cfg
+}
+
+// RetryAdvice estimates when backendID can take another request: once the
+// requests queued on it have run and its hardware has cooled. With no
+// backend (routing failed), it is the soonest any healthy backend frees up.
+func (r *Router) RetryAdvice(backendID string) RetryAdvice {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if backend, ok := r.backends[backendID]; ok {
+		return r.retryAdviceLocked(backend.ID(), backend.Hardware(), backend.AvgLatencyMs())
+	}
+
+	var best *RetryAdvice
+	for id, backend := range r.backends {
+		if !backend.IsHealthy() {
+			continue
+		}
+		advice := r.retryAdviceLocked(id, backend.Hardware(), backend.AvgLatencyMs())
+		if best == nil || advice.After < best.After || (advice.After == best.After && id < best.Backend) {
+			best = &advice
+		}
+	}
+	if best == nil {
+		return r.boundRetryLocked(RetryAdvice{})
+	}
+	return *best
+}
+
+// retryAdviceLocked computes one backend's advice. Caller must hold r.mu.
+func (r *Router) retryAdviceLocked(id, hardware string, avgLatencyMs int32) RetryAdvice {
+	advice := RetryAdvice{
+		Backend:    id,
+		QueueDepth: r.queueMgr.GetRawQueueDepth(id),
+		LatencyMs:  r.load.LatencyMs(id),
+	}
+	if advice.LatencyMs == 0 {
+		advice.LatencyMs = float64(avgLatencyMs)
+	}
+	if r.scheduler != nil {
+		advice.Slots = r.scheduler.Limit(id)
+	}
+
+	// Without a limit the backend's own queue usually runs one at a time
+	parallel := advice.Slots
+	if parallel <= 0 {
+		parallel = 1
+	}
+	advice.Drain = time.Duration(float64(advice.QueueDepth) * advice.LatencyMs / float64(parallel) * float64(time.Millisecond))
+
+	if r.cooldown != nil {
+		advice.Cooldown = r.cooldown(hardware)
+	}
+	return r.boundRetryLocked(advice)
+}
+
+// boundRetryLocked sets After from the drain and cooldown, within the
+// configured bounds. Caller must hold r.mu.
+func (r *Router) boundRetryLocked(advice RetryAdvice) RetryAdvice {
+	cfg := r.retryAfter
+	if cfg.Min <= 0 {
+		cfg.Min = DefaultRetryAfterMin
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = DefaultRetryAfterMax
+	}
+
+	advice.After = advice.Drain
+	if advice.Cooldown > advice.After {
+		advice.After = advice.Cooldown
+	}
+	if advice.After < cfg.Min {
+		advice.After = cfg.Min
+	}
+	if advice.After > cfg.Max {
+		advice.After = cfg.Max
+	}
+	advice.debug = cfg.Debug
+	return advice
+}
+
+// withRetryAdvice attaches advice for backendID to a rejection. Requests
+// the client itself cancelled get none.
+func (r *Router) withRetryAdvice(err error, backendID string) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &RetryableError{Err: err, Advice: r.RetryAdvice(backendID)}
+}
+
+// WriteRetryAfter sets Retry-After on the response to a request rejected
+// with err, from the advice it carries or one second without any, and
+// X-Retry-After-Debug when enabled
+func WriteRetryAfter(h http.Header, err error) {
+	var retryable *RetryableError
+	if !errors.As(err, &retryable) {
+		h.Set("Retry-After", "1")
+		return
+	}
+	h.Set("Retry-After", strconv.Itoa(retryable.Advice.Seconds()))
+	if retryable.Advice.debug {
+		h.Set(RetryAfterDebugHeader, retryable.Advice.String())
+	}
+}
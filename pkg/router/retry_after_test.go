@@ -0,0 +1,98 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	proxyerrors "github.com/daoneill/ollama-proxy/pkg/errors"
+)
+
+func TestRouter_RetryAdviceFromQueue(t *testing.T) {
+	r := NewRouter(Config{Scheduler: SchedulerConfig{
+		Enabled:              true,
+		DefaultMaxConcurrent: 2,
+		QueueTimeout:         10 * time.Millisecond,
+	}})
+	r.RegisterBackend(&MockBackend{id: "nvidia", hardware: "nvidia", healthy: true, avgLatencyMs: 1500})
+	r.SetRetryAdvice(nil, RetryAfterConfig{Debug: true})
+
+	// Two requests hold the slots and a third waits behind them
+	for i := 0; i < 3; i++ {
+		if _, err := r.RouteRequest(context.Background(), &backends.Annotations{}); err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		release, _ := r.Scheduler().Acquire(context.Background(), "nvidia", backends.PriorityNormal)
+		defer release()
+	}
+
+	decision, err := r.RouteRequest(context.Background(), &backends.Annotations{})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	_, err = decision.Backend.Generate(context.Background(), &backends.GenerateRequest{})
+	var timeoutErr *proxyerrors.BackendTimeoutError
+	var retryable *RetryableError
+	if !errors.As(err, &timeoutErr) || !errors.As(err, &retryable) {
+		t.Fatalf("Expected a queue timeout with retry advice, got %v", err)
+	}
+
+	// Four requests of 1.5s each, two at a time
+	advice := retryable.Advice
+	if advice.Backend != "nvidia" || advice.QueueDepth != 4 || advice.Slots != 2 || advice.Drain != 3*time.Second {
+		t.Errorf("Unexpected advice %+v", advice)
+	}
+
+	h := http.Header{}
+	WriteRetryAfter(h, err)
+	if h.Get("Retry-After") != "3" {
+		t.Errorf("Expected Retry-After 3, got %q", h.Get("Retry-After"))
+	}
+	if debug := h.Get(RetryAfterDebugHeader); !strings.Contains(debug, "queue=4") || !strings.Contains(debug, "drain=3s") {
+		t.Errorf("Unexpected debug header %q", debug)
+	}
+}
+
+func TestRouter_RetryAdviceCooldown(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "nvidia", hardware: "nvidia", healthy: true, avgLatencyMs: 100, powerWatts: 55})
+	r.RegisterBackend(&MockBackend{id: "igpu", hardware: "igpu", healthy: true, avgLatencyMs: 400, powerWatts: 12})
+	r.SetRetryAdvice(func(hardware string) time.Duration {
+		switch hardware {
+		case "nvidia":
+			return 5 * time.Minute
+		case "igpu":
+			return 30 * time.Second
+		}
+		return 0
+	}, RetryAfterConfig{Max: time.Minute})
+
+	if advice := r.RetryAdvice("nvidia"); advice.Cooldown != 5*time.Minute || advice.Seconds() != 60 {
+		t.Errorf("Expected the cooldown capped at the maximum, got %+v", advice)
+	}
+
+	// Without a backend, the one that cools first
+	if advice := r.RetryAdvice(""); advice.Backend != "igpu" || advice.Seconds() != 30 {
+		t.Errorf("Expected the soonest backend, got %+v", advice)
+	}
+
+	// Routing failures carry the same advice
+	_, err := r.RouteRequest(context.Background(), &backends.Annotations{MaxPowerWatts: 1})
+	h := http.Header{}
+	WriteRetryAfter(h, err)
+	if err == nil || h.Get("Retry-After") != "30" || h.Get(RetryAfterDebugHeader) != "" {
+		t.Errorf("Expected Retry-After 30 without the debug header, got %v %v", err, h)
+	}
+
+	h = http.Header{}
+	WriteRetryAfter(h, errors.New("queue full"))
+	if h.Get("Retry-After") != "1" {
+		t.Errorf("Expected the one-second default without advice, got %q", h.Get("Retry-After"))
+	}
+}
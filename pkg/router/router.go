@@ -3,13 +3,17 @@ package router
 import (
 	"context"
 	"fmt"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/daoneill/ollama-proxy/pkg/auth"
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/energy"
+	"github.com/daoneill/ollama-proxy/pkg/langdetect"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
 	proxyerrors "github.com/daoneill/ollama-proxy/pkg/errors"
+	"github.com/daoneill/ollama-proxy/pkg/speculative"
 )
 
 // RoutingDecision contains the result of routing logic
@@ -28,7 +32,20 @@ type RoutingDecision struct {
 
 	// Workload detection
 	DetectedMediaType  string   // Auto-detected workload type
+	DetectedLanguage   string   // Prompt language used for routing
 	RoutingHints       []string // Reasoning chain for routing decision
+
+	// Backend a hedged duplicate goes to if Backend is slow, "" if none
+	Hedge string
+
+	// Speculative decoding of the request, nil when Backend decodes alone
+	Speculative *speculative.Backend
+
+	// Backends an ensemble request is sent to, nil when routed to one
+	Ensemble []string
+
+	// Thermal state of Backend's hardware at dispatch, nil when unmonitored
+	Thermal *ThermalSnapshot
 }
 
 // Router handles intelligent routing to backends
@@ -41,23 +58,128 @@ type Router struct {
 
 	// Queue management for priority-aware routing
 	queueMgr         *QueueManager
+
+	// Languages each backend's models handle well (absent = any)
+	backendLanguages map[string][]string
+
+	// Defers best-effort requests from reserved backends
+	admission *AdmissionController
+
+	// Per-backend concurrency limits and priority queueing (nil = disabled)
+	scheduler *Scheduler
+
+	// Efficiency limits enforced on every request, e.g. quiet windows
+	modeConstraints ModeConstraintSource
+
+	// Backends requests with client labels may use (nil = any)
+	labelPolicy LabelPolicy
+
+	// Load-balancing strategies (nil = scoring for every model) and the
+	// live per-backend load they consult
+	balancer *loadBalancer
+	load     routerLoad
+
+	// Told about every routing decision (nil = none)
+	observer DecisionObserver
+
+	// Duplicates latency-critical requests to a second backend
+	hedging HedgingConfig
+
+	// How embedding batches are split across backends
+	embedBatch EmbedBatchConfig
+
+	// Drafts tokens for requests annotated Speculative (nil = disabled)
+	speculative *speculative.Decoder
+
+	// Queries several backends for requests annotated Ensemble or for an
+	// alias (nil = disabled)
+	ensemble *EnsembleConfig
+
+	// Thermal state recorded on each decision (nil = not monitored)
+	thermal ThermalSource
+
+	// Sampled accelerator utilization (nil = not monitored)
+	utilization UtilizationSource
+
+	// Measures the energy of each request (nil = estimated by the backend)
+	energy *energy.Meter
+
+	// Rejects backends a request would run out of memory on (nil = not checked)
+	memory *memoryAdmission
+
+	// Measured per-model speeds, by backend and model (nil = none)
+	performance map[string]ModelPerformance
+
+	// Weights of the cost candidates are scored by (nil = defaults) and
+	// the thermal headroom of their hardware (nil = not considered)
+	costWeights CostWeightSource
+	headroom    ThermalHeadroomSource
+
+	// Advice on when to retry attached to rejected requests
+	cooldown   CooldownSource
+	retryAfter RetryAfterConfig
+
+	// Emergency stop for in-flight requests and new admissions
+	kill *KillSwitch
 }
 
 // Config for router initialization
 type Config struct {
 	DefaultBackendID string
-	PowerAware       bool
-	AutoOptimize     bool
+	PowerAware       bool // doubles the energy cost weight
+	AutoOptimize     bool // doubles the latency cost weight
+
+	// BackendLanguages restricts backends to prompt languages, e.g.
+	// {"ollama-npu": {"en"}} for a small English-only model
+	BackendLanguages map[string][]string
+
+	// Scheduler queues requests beyond per-backend concurrency limits
+	Scheduler SchedulerConfig
+
+	// LoadBalancing replaces scoring with a load-balancing strategy,
+	// globally or per model
+	LoadBalancing LoadBalancingConfig
+
+	// Hedging sends latency-critical requests to a second backend when
+	// the first is slow to answer
+	Hedging HedgingConfig
+
+	// EmbedBatch splits batches of embedding inputs across backends
+	EmbedBatch EmbedBatchConfig
 }
 
 // NewRouter creates a new router instance
 func NewRouter(cfg Config) *Router {
+	var scheduler *Scheduler
+	if cfg.Scheduler.Enabled {
+		scheduler = NewScheduler(cfg.Scheduler)
+	}
+
+	queueMgr := NewQueueManager()
+
+	hedging := cfg.Hedging
+	if hedging.Delay <= 0 {
+		hedging.Delay = DefaultHedgeDelay
+	}
+
 	return &Router{
 		backends:         make(map[string]backends.Backend),
 		defaultBackendID: cfg.DefaultBackendID,
 		powerAware:       cfg.PowerAware,
 		autoOptimize:     cfg.AutoOptimize,
-		queueMgr:         NewQueueManager(),
+		queueMgr:         queueMgr,
+		backendLanguages: cfg.BackendLanguages,
+		admission:        NewAdmissionController(),
+		scheduler:        scheduler,
+		balancer:         newLoadBalancer(cfg.LoadBalancing),
+		load: routerLoad{
+			queueMgr: queueMgr,
+			latency:   newLatencyTracker(cfg.LoadBalancing.EWMADecay),
+			estimator: NewLatencyEstimator(cfg.LoadBalancing.EWMADecay),
+		},
+		hedging:    hedging,
+		embedBatch: cfg.EmbedBatch,
+		kill:       NewKillSwitch(),
 	}
 }
 
@@ -96,20 +218,48 @@ func (r *Router) ListBackends() []backends.Backend {
 	return list
 }
 
+// InFlight returns the number of requests routed to any backend that have
+// not finished
+func (r *Router) InFlight() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n := 0
+	for id := range r.backends {
+		n += r.queueMgr.GetRawQueueDepth(id)
+	}
+	return n
+}
+
 // RouteRequest intelligently selects a backend based on annotations
 func (r *Router) RouteRequest(ctx context.Context, annotations *backends.Annotations) (*RoutingDecision, error) {
-	// Add deadline if not set
-	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
-		defer cancel()
+	// Callers that built annotations without routing headers still get
+	// the request's ID in decision records
+	if annotations != nil && annotations.RequestID == "" {
+		annotations.RequestID = middleware.GetRequestID(ctx)
+	}
+	decision, rec, err := r.route(ctx, annotations)
+	if rec != nil {
+		// Observers run outside the router lock
+		rec.Decision, rec.Err = decision, err
+		if info, ok := auth.KeyInfoFromContext(ctx); ok {
+			rec.Key = info.Name
+		}
+		rec.observer(rec)
+	}
+	if err != nil {
+		return nil, r.withRetryAdvice(err, "")
 	}
+	return decision, nil
+}
 
-	// Check context before expensive operations
-	select {
-	case <-ctx.Done():
-		return nil, fmt.Errorf("routing cancelled: %w", ctx.Err())
-	default:
+// route selects a backend and, when a decision observer is set, captures
+// the state the decision was made against
+func (r *Router) route(ctx context.Context, annotations *backends.Annotations) (*RoutingDecision, *DecisionRecord, error) {
+	// Check context before expensive operations. Routing itself never
+	// blocks, so it needs no deadline of its own.
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("routing cancelled: %w", err)
 	}
 
 	r.mu.RLock()
@@ -117,15 +267,36 @@ func (r *Router) RouteRequest(ctx context.Context, annotations *backends.Annotat
 
 	var selectedBackend backends.Backend
 	var reason string
+	var scored []candidateScore
+
+	if r.kill.PausedAll() {
+		return nil, nil, fmt.Errorf("generation paused by operator")
+	}
+
+	// Snapshot the request before constraints rewrite its annotations
+	rec := r.newDecisionRecordLocked(annotations)
+
+	// Ensemble aliases route for their first model
+	alias, aliasModels := r.ensembleAliasLocked(annotations)
+
+	// Globally enforced limits override the request's own preferences
+	mc := r.applyModeConstraints(annotations)
+	if rec != nil {
+		rec.Constraints = mc
+	}
 
 	// If specific target requested, try that first
 	if annotations.Target != "" && annotations.Target != "auto" {
 		if backend, exists := r.backends[annotations.Target]; exists {
-			if backend.IsHealthy() {
+			admitted, _ := r.admit(backend, annotations.Priority)
+			fits, _, _ := r.memory.fits(backend, annotations)
+			if backend.IsHealthy() && admitted && fits && mc.allows(backend.ID()) && !r.kill.Paused(backend.ID()) &&
+				r.labelsAllow(backend.ID(), annotations.Labels) {
 				selectedBackend = backend
 				reason = fmt.Sprintf("Explicit target: %s", annotations.Target)
 			}
-			// Target unhealthy, fall through to auto-selection
+			// Target unhealthy, reserved, out of memory, constrained,
+			// paused or ruled out by labels, fall through to auto-selection
 		}
 	}
 
@@ -144,9 +315,28 @@ func (r *Router) RouteRequest(ctx context.Context, annotations *backends.Annotat
 			if annotations.MediaType != "" {
 				constraints = append(constraints, fmt.Sprintf("media=%s", annotations.MediaType))
 			}
+			if annotations.Capability != "" {
+				constraints = append(constraints, fmt.Sprintf("capability=%s", annotations.Capability))
+			}
 			if annotations.Target != "" {
 				constraints = append(constraints, fmt.Sprintf("target=%s", annotations.Target))
 			}
+			if annotations.Priority == backends.PriorityBestEffort {
+				for _, res := range r.admission.Reservations() {
+					constraints = append(constraints, fmt.Sprintf("deferred=%s", res.Owner))
+				}
+			}
+			if mc != nil {
+				constraints = append(constraints, mc.Reason)
+			}
+			if r.labelPolicy != nil {
+				for label, value := range annotations.Labels {
+					constraints = append(constraints, fmt.Sprintf("label %s=%s", label, value))
+				}
+			}
+			for _, id := range r.kill.Status().PausedBackends {
+				constraints = append(constraints, fmt.Sprintf("paused=%s", id))
+			}
 
 			// Count healthy backends
 			healthyCount := 0
@@ -156,35 +346,140 @@ func (r *Router) RouteRequest(ctx context.Context, annotations *backends.Annotat
 				}
 			}
 
-			return nil, proxyerrors.NewNoBackendsError(len(r.backends), healthyCount, constraints)
+			return nil, rec, proxyerrors.NewNoBackendsError(len(r.backends), healthyCount, constraints)
 		}
 
-		// Score and rank candidates
-		scored := r.scoreCandidates(candidates, annotations)
+		// Re-route away from backends the request would run out of memory on
+		candidates, need, free := r.fitMemory(candidates, annotations)
+		if len(candidates) == 0 {
+			return nil, rec, proxyerrors.NewInsufficientMemoryError(annotations.Model, need, free)
+		}
 
-		// Select best candidate
-		best := scored[0]
-		selectedBackend = best.backend
-		reason = best.reason
+		if balanced, balancedReason, ok := r.balance(candidates, annotations); ok {
+			// Spread load with the model's strategy
+			selectedBackend = balanced
+			reason = balancedReason
+		} else {
+			// Score and rank candidates
+			scored = r.scoreCandidates(candidates, annotations)
+
+			// Select best candidate
+			best := scored[0]
+			selectedBackend = best.backend
+			reason = best.reason
+		}
+	}
+	if mc != nil {
+		reason = fmt.Sprintf("%s (%s)", reason, mc.Reason)
 	}
 
-	// Mark request start in queue
-	r.queueMgr.MarkRequestStart(selectedBackend.ID(), annotations.Priority)
+	r.memory.routed(selectedBackend.ID(), annotations.Model)
 
-	// Wrap backend with queue tracking
-	trackedBackend := &QueueTrackingBackend{
-		Backend:  selectedBackend,
-		queueMgr: r.queueMgr,
-		priority: annotations.Priority,
+	// Drafting for a speculative request happens inside the tracked
+	// backend, so its rounds count as one request. Ensembles query every
+	// member in full, so aren't drafted for.
+	dispatched := selectedBackend
+	var spec *speculative.Backend
+	if r.ensemble == nil || (!annotations.Ensemble && aliasModels == nil) {
+		spec = r.speculateLocked(selectedBackend, annotations)
+	}
+	if spec != nil {
+		dispatched = spec
+		reason = fmt.Sprintf("%s (speculative, drafts on %s)", reason, spec.DraftID())
 	}
 
-	return &RoutingDecision{
-		Backend:            trackedBackend,
+	// Alternatives cheapest first when candidates were scored
+	var alternatives []string
+	if scored != nil {
+		alternatives = r.alternativesByCost(scored)
+	} else {
+		alternatives = r.getAlternatives(selectedBackend.ID())
+	}
+
+	decision := &RoutingDecision{
+		Backend:            r.trackBackend(dispatched, annotations),
 		Reason:             reason,
 		EstimatedPowerW:    selectedBackend.PowerWatts(),
-		EstimatedLatencyMs: selectedBackend.AvgLatencyMs(),
-		Alternatives:       r.getAlternatives(selectedBackend.ID()),
-	}, nil
+		EstimatedLatencyMs: int32(r.predictedLatencyMs(selectedBackend, annotations)),
+		Alternatives:       alternatives,
+		DetectedLanguage:   annotations.Language,
+		Thermal:            r.thermalSnapshotLocked(selectedBackend),
+		Speculative:        spec,
+	}
+
+	// Ensembles query every member, so aren't hedged either
+	if ens := r.ensembleLocked(selectedBackend, decision.Backend, annotations, alias, aliasModels); ens != nil {
+		decision.Backend = ens
+		decision.Ensemble = ens.Members()
+		decision.Reason = fmt.Sprintf("%s (ensemble of %s)", reason, strings.Join(decision.Ensemble, ", "))
+		return decision, rec, nil
+	}
+
+	// Latency-critical requests may race a second backend, unless already
+	// sharing the work with a draft backend
+	if hedge := r.hedgeCandidateLocked(selectedBackend, annotations); hedge != nil && spec == nil {
+		decision.Backend = &HedgedBackend{
+			Backend:    decision.Backend,
+			hedgeID:    hedge.ID(),
+			startHedge: func() backends.Backend { return r.trackBackend(hedge, annotations) },
+			delay:      r.hedging.Delay,
+		}
+		decision.Hedge = hedge.ID()
+	}
+
+	return decision, rec, nil
+}
+
+// trackBackend marks a request started on backend and wraps the backend to
+// track its queue depth, scheduling and latency
+func (r *Router) trackBackend(backend backends.Backend, annotations *backends.Annotations) *QueueTrackingBackend {
+	r.queueMgr.MarkRequestStart(backend.ID(), annotations.Priority)
+
+	tracked := &QueueTrackingBackend{
+		Backend:      backend,
+		queueMgr:     r.queueMgr,
+		priority:     annotations.Priority,
+		scheduler:    r.scheduler,
+		latency:      r.load.latency,
+		estimator:    r.load.estimator,
+		model:        annotations.Model,
+		promptTokens: annotations.PromptTokens,
+		kill:         r.kill,
+		energy:       r.energy,
+		router:       r,
+	}
+	if annotations.DeadlineMs > 0 {
+		tracked.deadline = time.UnixMilli(annotations.DeadlineMs)
+	}
+	return tracked
+}
+
+// hedgeCandidateLocked picks the backend a latency-critical request may be
+// duplicated to: the best-scoring other candidate that serves the model.
+// Requests for an explicit target are not hedged.
+func (r *Router) hedgeCandidateLocked(selected backends.Backend, annotations *backends.Annotations) backends.Backend {
+	if !r.hedging.Enabled || !annotations.LatencyCritical {
+		return nil
+	}
+	if annotations.Target != "" && annotations.Target != "auto" {
+		return nil
+	}
+
+	var others []backends.Backend
+	candidates, _, _ := r.fitMemory(r.filterCandidates(annotations), annotations)
+	for _, backend := range candidates {
+		if backend.ID() == selected.ID() {
+			continue
+		}
+		if annotations.Model != "" && !backend.SupportsModel(annotations.Model) {
+			continue
+		}
+		others = append(others, backend)
+	}
+	if len(others) == 0 {
+		return nil
+	}
+	return r.scoreCandidates(others, annotations)[0].backend
 }
 
 // candidateScore holds backend with its score
@@ -192,11 +487,13 @@ type candidateScore struct {
 	backend backends.Backend
 	score   float64
 	reason  string
+	cost    backendCost // set by scoreCandidates
 }
 
 // filterCandidates returns backends that meet basic requirements
 func (r *Router) filterCandidates(annotations *backends.Annotations) []backends.Backend {
-	var candidates []backends.Backend
+	candidates := make([]backends.Backend, 0, len(r.backends))
+	mc := r.activeModeConstraints()
 
 	for _, backend := range r.backends {
 		// Must be healthy
@@ -204,6 +501,31 @@ func (r *Router) filterCandidates(annotations *backends.Annotations) []backends.
 			continue
 		}
 
+		// Must be permitted by globally enforced limits
+		if !mc.allows(backend.ID()) {
+			continue
+		}
+
+		// Must not be paused by the kill switch
+		if r.kill.Paused(backend.ID()) {
+			continue
+		}
+
+		// Must be allowed for the request's labels
+		if !r.labelsAllow(backend.ID(), annotations.Labels) {
+			continue
+		}
+
+		// Must support the requested operation
+		if !backends.SupportsCapability(backend, annotations.Capability) {
+			continue
+		}
+
+		// Best-effort work is deferred from backends reserved for live use
+		if admitted, _ := r.admit(backend, annotations.Priority); !admitted {
+			continue
+		}
+
 		// Check max latency constraint
 		if annotations.MaxLatencyMs > 0 {
 			if backend.AvgLatencyMs() > annotations.MaxLatencyMs {
@@ -221,92 +543,110 @@ func (r *Router) filterCandidates(annotations *backends.Annotations) []backends.
 		candidates = append(candidates, backend)
 	}
 
-	return candidates
+	return localFirst(candidates, annotations.Model)
 }
 
-// scoreCandidates assigns scores to candidates based on preferences
-func (r *Router) scoreCandidates(candidates []backends.Backend, annotations *backends.Annotations) []candidateScore {
-	scored := make([]candidateScore, 0, len(candidates))
-
+// localFirst keeps cloud backends out of automatic selection while a local
+// candidate can serve the model, so requests only leave the machine when
+// nothing local can take them
+func localFirst(candidates []backends.Backend, model string) []backends.Backend {
+	hasCloud := false
 	for _, backend := range candidates {
-		score := 0.0
-		reasons := []string{}
-
-		// Base score from backend priority
-		score += float64(backend.Priority()) * 10.0
-
-		// Latency optimization
-		if annotations.LatencyCritical || r.autoOptimize {
-			// Lower latency = higher score
-			// NVIDIA (~150ms) gets ~850 points
-			// NPU (~800ms) gets ~200 points
-			latencyScore := 1000.0 - float64(backend.AvgLatencyMs())
-			score += latencyScore * 2 // Weight latency heavily
-			if annotations.LatencyCritical {
-				reasons = append(reasons, "latency-critical")
-			}
+		if backends.IsCloud(backend) {
+			hasCloud = true
+			break
 		}
+	}
+	if !hasCloud {
+		return candidates
+	}
 
-		// Power efficiency optimization
-		if annotations.PreferPowerEfficiency || r.powerAware {
-			// Lower power = higher score
-			// NPU (3W) gets ~970 points
-			// NVIDIA (55W) gets ~450 points
-			powerScore := 1000.0 - (backend.PowerWatts() * 10)
-			score += powerScore * 1.5 // Weight power efficiency
-			if annotations.PreferPowerEfficiency {
-				reasons = append(reasons, "power-efficient")
+	var local, cloud []backends.Backend
+	servesModel := false
+	for _, backend := range candidates {
+		if backends.IsCloud(backend) {
+			if model == "" || backend.SupportsModel(model) {
+				cloud = append(cloud, backend)
 			}
+			continue
 		}
+		local = append(local, backend)
+		if model == "" || backend.SupportsModel(model) {
+			servesModel = true
+		}
+	}
+	if servesModel || len(cloud) == 0 {
+		return local
+	}
+	return cloud
+}
 
-		// If no specific preference, use balanced scoring
-		if !annotations.LatencyCritical && !annotations.PreferPowerEfficiency {
-			// Balanced: consider both latency and power
-			latencyScore := 1000.0 - float64(backend.AvgLatencyMs())
-			powerScore := 1000.0 - (backend.PowerWatts() * 10)
-			score += (latencyScore + powerScore) / 2
-			reasons = append(reasons, "balanced")
+// scoreCandidates ranks candidates by cost, cheapest first: a weighted sum
+// of the energy and predicted latency of the request on each, the thermal
+// headroom its hardware has used and the requests queued on it. Only the
+// best candidate's reason is set.
+func (r *Router) scoreCandidates(candidates []backends.Backend, annotations *backends.Annotations) []candidateScore {
+	scored := make([]candidateScore, len(candidates))
+
+	for i, backend := range candidates {
+		latencyMs := r.predictedLatencyMs(backend, annotations)
+		cost := backendCost{
+			Energy:  backend.PowerWatts() * latencyMs / 1000,
+			Latency: latencyMs,
+			Thermal: r.thermalCost(backend),
 		}
 
-		// Queue depth penalty - avoid congested backends
+		// Queued requests - avoid congested backends
 		queueDepth := r.queueMgr.GetQueueDepth(backend.ID(), annotations.Priority)
-		queuePenalty := float64(queueDepth) * 50.0 // -50 points per pending request
-		score -= queuePenalty
-		if queueDepth > 0 {
-			reasons = append(reasons, fmt.Sprintf("queue-depth-%d", queueDepth))
-		}
+		cost.Queue = min(float64(queueDepth)/queueCostSaturation, 1)
 
-		// Priority boost for critical requests
-		if annotations.Priority == backends.PriorityCritical {
-			score += 500.0 // Strong boost for voice/realtime
-			reasons = append(reasons, "critical-priority")
-		} else if annotations.Priority == backends.PriorityHigh {
-			score += 200.0 // Moderate boost for high priority
-			reasons = append(reasons, "high-priority")
-		}
+		// Backend priority breaks ties
+		points := float64(backend.Priority()) * 10.0
 
-		if len(reasons) == 0 {
-			reasons = append(reasons, "default-scoring")
-		}
+		// Utilization penalty - the hardware may be busy with work the
+		// queue doesn't see
+		penalty, _ := r.utilizationPenalty(backend)
+		points -= penalty
 
-		scored = append(scored, candidateScore{
-			backend: backend,
-			score:   score,
-			reason:  fmt.Sprintf("Selected: %s", reasons[0]),
-		})
+		// Language fit - steer prompts away from models that can't handle them
+		langScore, _ := r.languageScore(backend, annotations)
+		points += langScore
+
+		cost.Adjust = -points / pointsPerCost
+		scored[i] = candidateScore{backend: backend, cost: cost}
 	}
 
-	// Sort by score descending
-	sort.Slice(scored, func(i, j int) bool {
-		return scored[i].score > scored[j].score
-	})
+	weighCosts(scored, r.costWeightsLocked(annotations))
+	sortScored(scored)
+
+	objective := "balanced"
+	if annotations.LatencyCritical {
+		objective = "latency-critical"
+	} else if annotations.PreferPowerEfficiency {
+		objective = "power-efficient"
+	}
+	scored[0].reason = explainCost(objective, scored)
 
 	return scored
 }
 
+// languageScore rewards backends declared for the prompt's language and
+// heavily penalises ones declared for other languages. A mismatched backend
+// stays eligible so requests still succeed when it is the only option.
+func (r *Router) languageScore(backend backends.Backend, annotations *backends.Annotations) (float64, string) {
+	langs := r.backendLanguages[backend.ID()]
+	if annotations.Language == "" || len(langs) == 0 {
+		return 0, ""
+	}
+	if langdetect.Matches(annotations.Language, langs) {
+		return 300.0, "language-match"
+	}
+	return -2000.0, "language-mismatch"
+}
+
 // getAlternatives returns IDs of other backends (excluding the given one)
 func (r *Router) getAlternatives(excludeID string) []string {
-	alternatives := []string{}
+	alternatives := make([]string, 0, len(r.backends))
 	for id, backend := range r.backends {
 		if id != excludeID && backend.IsHealthy() {
 			alternatives = append(alternatives, id)
@@ -330,7 +670,7 @@ func (r *Router) FallbackRequest(ctx context.Context, excludeBackends []string,
 	candidates := []backends.Backend{}
 	healthyCount := 0
 	for id, backend := range r.backends {
-		if exclude[id] {
+		if exclude[id] || r.kill.Paused(id) || !r.labelsAllow(id, annotations.Labels) {
 			continue
 		}
 		if backend.IsHealthy() {
@@ -338,6 +678,7 @@ func (r *Router) FallbackRequest(ctx context.Context, excludeBackends []string,
 			candidates = append(candidates, backend)
 		}
 	}
+	candidates = localFirst(candidates, annotations.Model)
 
 	if len(candidates) == 0 {
 		excludedList := make([]string, 0, len(excludeBackends))
@@ -354,8 +695,8 @@ func (r *Router) FallbackRequest(ctx context.Context, excludeBackends []string,
 		Backend:            best.backend,
 		Reason:             fmt.Sprintf("Fallback: %s", best.reason),
 		EstimatedPowerW:    best.backend.PowerWatts(),
-		EstimatedLatencyMs: best.backend.AvgLatencyMs(),
-		Alternatives:       r.getAlternatives(best.backend.ID()),
+		EstimatedLatencyMs: int32(r.predictedLatencyMs(best.backend, annotations)),
+		Alternatives:       r.alternativesByCost(scored),
 	}, nil
 }
 
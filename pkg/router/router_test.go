@@ -571,6 +571,36 @@ func TestRouter_RouteRequest_NoBackends(t *testing.T) {
 	}
 }
 
+func TestRouter_CloudLastResort(t *testing.T) {
+	router := NewRouter(Config{PowerAware: true})
+	router.RegisterBackend(&MockBackend{id: "local", hardware: "gpu", healthy: true, powerWatts: 55, avgLatencyMs: 400, modelPatterns: []string{"*:7b"}})
+	router.RegisterBackend(&MockBackend{id: "cloud", hardware: backends.HardwareCloud, healthy: true, avgLatencyMs: 300, modelPatterns: []string{"*:7b", "*:405b"}})
+
+	// A cheaper-looking cloud backend is not picked while a local one serves the model
+	decision, err := router.RouteRequest(context.Background(), &backends.Annotations{Model: "llama3:7b", PreferPowerEfficiency: true})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if decision.Backend.ID() != "local" {
+		t.Errorf("Expected the local backend, got %s", decision.Backend.ID())
+	}
+
+	// Models no local backend can run go to the cloud
+	decision, err = router.RouteRequest(context.Background(), &backends.Annotations{Model: "llama3:405b"})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if decision.Backend.ID() != "cloud" {
+		t.Errorf("Expected the cloud backend, got %s", decision.Backend.ID())
+	}
+
+	// As do fallbacks when every local backend is excluded
+	decision, err = router.FallbackRequest(context.Background(), []string{"local"}, &backends.Annotations{})
+	if err != nil || decision.Backend.ID() != "cloud" {
+		t.Errorf("Expected fallback to the cloud backend, got %v", err)
+	}
+}
+
 func TestRouter_FallbackRequest_NoBackends(t *testing.T) {
 	router := NewRouter(Config{})
 
@@ -1198,3 +1228,110 @@ func TestRouter_ScoreCandidates_DefaultScoring(t *testing.T) {
 
 	t.Logf("Selected backend: %s with reason: %s", decision.Backend.ID(), decision.Reason)
 }
+
+// audioMockBackend is a MockBackend with speech-to-text support
+type audioMockBackend struct {
+	MockBackend
+}
+
+func (m *audioMockBackend) SupportsAudioToText() bool { return true }
+
+func TestRouteRequest_CapabilityFilter(t *testing.T) {
+	router := NewRouter(Config{})
+
+	// The text backend is faster, so it would win without the filter
+	router.RegisterBackend(&MockBackend{id: "text-backend", hardware: "gpu", healthy: true, avgLatencyMs: 50})
+	router.RegisterBackend(&audioMockBackend{MockBackend{id: "whisper-backend", hardware: "cpu", healthy: true, avgLatencyMs: 500}})
+
+	annotations := &backends.Annotations{
+		LatencyCritical: true,
+		Capability:      backends.CapabilityAudioToText,
+	}
+
+	decision, err := router.RouteRequest(context.Background(), annotations)
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if id := decision.Backend.(*QueueTrackingBackend).Backend.ID(); id != "whisper-backend" {
+		t.Errorf("Expected whisper-backend (only audio-capable), got '%s'", id)
+	}
+
+	// No backend supports the capability
+	router2 := NewRouter(Config{})
+	router2.RegisterBackend(&MockBackend{id: "text-backend", healthy: true})
+	_, err = router2.RouteRequest(context.Background(), annotations)
+	if err == nil || !strings.Contains(err.Error(), "capability=audio_to_text") {
+		t.Errorf("Expected capability constraint in error, got: %v", err)
+	}
+}
+
+// imageOnlyMockBackend is an image generator with no text support
+type imageOnlyMockBackend struct {
+	MockBackend
+}
+
+func (m *imageOnlyMockBackend) SupportsGenerate() bool    { return false }
+func (m *imageOnlyMockBackend) SupportsTextToImage() bool { return true }
+
+func TestRouteRequest_TextSkipsImageOnlyBackends(t *testing.T) {
+	router := NewRouter(Config{})
+
+	// The image backend is faster, so it would win text requests unfiltered
+	router.RegisterBackend(&imageOnlyMockBackend{MockBackend{id: "sdwebui", hardware: "gpu", healthy: true, avgLatencyMs: 50}})
+	router.RegisterBackend(&MockBackend{id: "text-backend", hardware: "cpu", healthy: true, avgLatencyMs: 500})
+
+	decision, err := router.RouteRequest(context.Background(), &backends.Annotations{LatencyCritical: true})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if id := decision.Backend.(*QueueTrackingBackend).Backend.ID(); id != "text-backend" {
+		t.Errorf("Expected text-backend for a text request, got '%s'", id)
+	}
+
+	decision, err = router.RouteRequest(context.Background(), &backends.Annotations{Capability: backends.CapabilityTextToImage})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if id := decision.Backend.(*QueueTrackingBackend).Backend.ID(); id != "sdwebui" {
+		t.Errorf("Expected sdwebui for an image request, got '%s'", id)
+	}
+}
+
+func TestRouteRequest_LanguageAware(t *testing.T) {
+	router := NewRouter(Config{
+		BackendLanguages: map[string][]string{"npu-en": {"en"}},
+	})
+
+	// The English-only NPU is faster, so it wins for English prompts
+	router.RegisterBackend(&MockBackend{id: "npu-en", hardware: "npu", healthy: true, avgLatencyMs: 50})
+	router.RegisterBackend(&MockBackend{id: "gpu-multi", hardware: "gpu", healthy: true, avgLatencyMs: 400})
+
+	route := func(lang string) string {
+		t.Helper()
+		decision, err := router.RouteRequest(context.Background(), &backends.Annotations{LatencyCritical: true, Language: lang})
+		if err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+		if decision.DetectedLanguage != lang {
+			t.Errorf("DetectedLanguage = %q, want %q", decision.DetectedLanguage, lang)
+		}
+		return decision.Backend.(*QueueTrackingBackend).Backend.ID()
+	}
+
+	if id := route("en"); id != "npu-en" {
+		t.Errorf("Expected npu-en for English, got %s", id)
+	}
+	if id := route("fr"); id != "gpu-multi" {
+		t.Errorf("Expected gpu-multi for French, got %s", id)
+	}
+	if id := route(""); id != "npu-en" {
+		t.Errorf("Expected npu-en when language unknown, got %s", id)
+	}
+
+	// A mismatched backend is still used when it is the only one
+	solo := NewRouter(Config{BackendLanguages: map[string][]string{"npu-en": {"en"}}})
+	solo.RegisterBackend(&MockBackend{id: "npu-en", healthy: true})
+	if _, err := solo.RouteRequest(context.Background(), &backends.Annotations{Language: "fr"}); err != nil {
+		t.Errorf("Expected fallback to mismatched backend, got %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	proxyerrors "github.com/daoneill/ollama-proxy/pkg/errors"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+)
+
+// SchedulerConfig limits how many requests run concurrently on each backend.
+// Requests beyond the limit wait in a per-backend queue ordered by the
+// configured policy; the default dequeues by priority (critical first), then
+// in arrival order.
+type SchedulerConfig struct {
+	Enabled bool
+
+	// Policy names the Discipline that orders waiting requests: fifo,
+	// priority (default), edf, fair_share or a registered policy
+	Policy string
+
+	// DefaultMaxConcurrent applies to backends without an entry in
+	// MaxConcurrent (0 = unlimited)
+	DefaultMaxConcurrent int
+	MaxConcurrent        map[string]int // backend ID -> limit
+
+	// MaxQueueDepth caps waiting requests per backend (0 = unlimited). When
+	// full, a new request displaces the waiter the policy picks (under
+	// priority, the newest of lower priority) or is rejected.
+	MaxQueueDepth int
+
+	// QueueTimeout is the longest a request waits for a slot (0 = until
+	// the request context ends)
+	QueueTimeout time.Duration
+}
+
+// Scheduler is a per-backend admission queue. It enforces concurrency
+// limits, timeouts and queue depth; the Discipline decides who runs next.
+type Scheduler struct {
+	cfg           SchedulerConfig
+	newDiscipline DisciplineFactory
+
+	mu    sync.Mutex
+	slots map[string]*backendSlots // backend ID -> slots
+}
+
+// backendSlots tracks running and waiting requests for one backend
+type backendSlots struct {
+	limit    int
+	active   int
+	queue    Discipline
+	queuedBy [backends.PriorityCritical + 1]int // waiting requests per priority
+	seq      uint64
+}
+
+// SchedulerStats is a snapshot of one backend's queue
+type SchedulerStats struct {
+	BackendID     string         `json:"backend_id"`
+	MaxConcurrent int            `json:"max_concurrent"`
+	Active        int            `json:"active"`
+	Queued        int            `json:"queued"`
+	QueuedBy      map[string]int `json:"queued_by_priority"`
+}
+
+// NewScheduler creates a scheduler
+func NewScheduler(cfg SchedulerConfig) *Scheduler {
+	return &Scheduler{
+		cfg:           cfg,
+		newDiscipline: disciplineFactory(cfg.Policy),
+		slots:         make(map[string]*backendSlots),
+	}
+}
+
+// Scheduler returns the router's request scheduler, nil when disabled
+func (r *Router) Scheduler() *Scheduler {
+	return r.scheduler
+}
+
+// priorityLabel names a priority for metrics and stats
+func priorityLabel(p backends.Priority) string {
+	switch p {
+	case backends.PriorityBestEffort:
+		return "best-effort"
+	case backends.PriorityHigh:
+		return "high"
+	case backends.PriorityCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// clampPriority keeps out-of-range priorities inside the waiting table
+func clampPriority(p backends.Priority) backends.Priority {
+	if p < backends.PriorityBestEffort {
+		return backends.PriorityBestEffort
+	}
+	if p > backends.PriorityCritical {
+		return backends.PriorityCritical
+	}
+	return p
+}
+
+// slotsLocked returns the slots for backendID, creating them on first use
+func (s *Scheduler) slotsLocked(backendID string) *backendSlots {
+	bs, ok := s.slots[backendID]
+	if !ok {
+		limit := s.cfg.DefaultMaxConcurrent
+		if l, ok := s.cfg.MaxConcurrent[backendID]; ok {
+			limit = l
+		}
+		bs = &backendSlots{limit: limit, queue: s.newDiscipline()}
+		s.slots[backendID] = bs
+	}
+	return bs
+}
+
+// Limit returns backendID's concurrency limit (0 = unlimited)
+func (s *Scheduler) Limit(backendID string) int {
+	if l, ok := s.cfg.MaxConcurrent[backendID]; ok {
+		return l
+	}
+	return s.cfg.DefaultMaxConcurrent
+}
+
+func (bs *backendSlots) queued() int {
+	return bs.queue.Len()
+}
+
+// Acquire waits for a slot on backendID and returns a function that frees
+// it. It fails when the queue is full, the wait times out, or ctx ends.
+// The tenant and deadline the policy sees come from ctx.
+func (s *Scheduler) Acquire(ctx context.Context, backendID string, priority backends.Priority) (func(), error) {
+	return s.acquire(ctx, backendID, priority, time.Time{})
+}
+
+// acquire is Acquire with an explicit deadline, used when the request
+// carries one that is not on its context
+func (s *Scheduler) acquire(ctx context.Context, backendID string, priority backends.Priority, deadline time.Time) (func(), error) {
+	priority = clampPriority(priority)
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+
+	s.mu.Lock()
+	bs := s.slotsLocked(backendID)
+	if bs.limit <= 0 || (bs.active < bs.limit && bs.queued() == 0) {
+		bs.active++
+		s.mu.Unlock()
+		return s.releaseFunc(backendID), nil
+	}
+
+	bs.seq++
+	w := &Waiter{
+		Priority: priority,
+		Tenant:   auth.TenantFromContext(ctx),
+		Deadline: deadline,
+		Arrival:  time.Now(),
+		Seq:      bs.seq,
+		ready:    make(chan struct{}),
+	}
+
+	if s.cfg.MaxQueueDepth > 0 && bs.queued() >= s.cfg.MaxQueueDepth {
+		if !s.displaceLocked(backendID, bs, w) {
+			depth := bs.queued()
+			s.mu.Unlock()
+			metrics.RecordQueueRejected(backendID, priorityLabel(priority))
+			return nil, &proxyerrors.BackendCapacityError{
+				BackendID:  backendID,
+				QueueDepth: depth,
+				MaxQueue:   s.cfg.MaxQueueDepth,
+			}
+		}
+	}
+
+	bs.queue.Push(w)
+	bs.queuedBy[priority]++
+	s.updateMetricsLocked(backendID, bs)
+	s.mu.Unlock()
+
+	start := time.Now()
+	var timeout <-chan time.Time
+	if s.cfg.QueueTimeout > 0 {
+		timer := time.NewTimer(s.cfg.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		metrics.RecordQueueWait(backendID, priorityLabel(priority), time.Since(start).Seconds())
+		if w.err != nil {
+			return nil, w.err
+		}
+		return s.releaseFunc(backendID), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = proxyerrors.NewBackendTimeoutError(backendID, "queue wait", s.cfg.QueueTimeout.String())
+	}
+	metrics.RecordQueueWait(backendID, priorityLabel(priority), time.Since(start).Seconds())
+
+	s.abandon(backendID, w)
+	return nil, err
+}
+
+// abandon removes a waiter that gave up. If it was granted a slot in the
+// meantime, the slot is passed on.
+func (s *Scheduler) abandon(backendID string, w *Waiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bs := s.slotsLocked(backendID)
+	if bs.queue.Remove(w) {
+		bs.queuedBy[w.Priority]--
+		s.updateMetricsLocked(backendID, bs)
+		return
+	}
+
+	// Not queued any more: it was either displaced or granted a slot
+	if w.err == nil {
+		s.releaseLocked(backendID, bs)
+	}
+}
+
+// displaceLocked rejects the waiter the policy gives up for incoming to
+// make room. It reports whether one was found.
+func (s *Scheduler) displaceLocked(backendID string, bs *backendSlots, incoming *Waiter) bool {
+	victim := bs.queue.Victim(incoming)
+	if victim == nil {
+		return false
+	}
+	bs.queuedBy[victim.Priority]--
+	victim.err = &proxyerrors.BackendCapacityError{
+		BackendID:  backendID,
+		QueueDepth: s.cfg.MaxQueueDepth,
+		MaxQueue:   s.cfg.MaxQueueDepth,
+	}
+	close(victim.ready)
+	metrics.RecordQueueRejected(backendID, priorityLabel(victim.Priority))
+	return true
+}
+
+// releaseFunc returns an idempotent release for one acquired slot
+func (s *Scheduler) releaseFunc(backendID string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.releaseLocked(backendID, s.slotsLocked(backendID))
+		})
+	}
+}
+
+// releaseLocked frees a slot and hands it to the waiter the policy picks
+func (s *Scheduler) releaseLocked(backendID string, bs *backendSlots) {
+	bs.active--
+	if bs.active < 0 {
+		bs.active = 0 // Safety check
+	}
+
+	if next := bs.queue.Pop(); next != nil {
+		bs.queuedBy[next.Priority]--
+		bs.active++
+		close(next.ready)
+	}
+	s.updateMetricsLocked(backendID, bs)
+}
+
+// updateMetricsLocked publishes queue depth per priority
+func (s *Scheduler) updateMetricsLocked(backendID string, bs *backendSlots) {
+	for p := backends.PriorityBestEffort; p <= backends.PriorityCritical; p++ {
+		metrics.SetBackendQueueDepth(backendID, priorityLabel(p), bs.queuedBy[p])
+	}
+}
+
+// Stats returns queue snapshots ordered by backend ID
+func (s *Scheduler) Stats() []SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]SchedulerStats, 0, len(s.slots))
+	for id, bs := range s.slots {
+		st := SchedulerStats{
+			BackendID:     id,
+			MaxConcurrent: bs.limit,
+			Active:        bs.active,
+			Queued:        bs.queued(),
+			QueuedBy:      make(map[string]int),
+		}
+		for p := backends.PriorityBestEffort; p <= backends.PriorityCritical; p++ {
+			st.QueuedBy[priorityLabel(p)] = bs.queuedBy[p]
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].BackendID < stats[j].BackendID })
+	return stats
+}
+
+// Handler serves the queue admin endpoint
+func (s *Scheduler) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		policy := s.cfg.Policy
+		if !HasDiscipline(policy) {
+			policy = PolicyPriority
+		}
+		response := map[string]interface{}{
+			"policy":                 policy,
+			"default_max_concurrent": s.cfg.DefaultMaxConcurrent,
+			"max_queue_depth":        s.cfg.MaxQueueDepth,
+			"queue_timeout_ms":       s.cfg.QueueTimeout.Milliseconds(),
+			"backends":               s.Stats(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
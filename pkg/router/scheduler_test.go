@@ -0,0 +1,177 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	proxyerrors "github.com/daoneill/ollama-proxy/pkg/errors"
+)
+
+// waitQueued blocks until backendID has n waiting requests
+func waitQueued(t *testing.T, s *Scheduler, backendID string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, st := range s.Stats() {
+			if st.BackendID == backendID && st.Queued == n {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d queued requests on %s: %+v", n, backendID, s.Stats())
+}
+
+func TestScheduler_PriorityDequeue(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{Enabled: true, DefaultMaxConcurrent: 1})
+
+	release, err := s.Acquire(context.Background(), "npu", backends.PriorityNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan backends.Priority, 3)
+	acquire := func(p backends.Priority) {
+		rel, err := s.Acquire(context.Background(), "npu", p)
+		if err != nil {
+			t.Errorf("Acquire(%d) failed: %v", p, err)
+			return
+		}
+		order <- p
+		rel()
+	}
+
+	go acquire(backends.PriorityBestEffort)
+	waitQueued(t, s, "npu", 1)
+	go acquire(backends.PriorityNormal)
+	waitQueued(t, s, "npu", 2)
+	go acquire(backends.PriorityCritical)
+	waitQueued(t, s, "npu", 3)
+
+	release()
+	release() // idempotent
+
+	want := []backends.Priority{backends.PriorityCritical, backends.PriorityNormal, backends.PriorityBestEffort}
+	for i, p := range want {
+		if got := <-order; got != p {
+			t.Errorf("dequeue %d: got priority %d, want %d", i, got, p)
+		}
+	}
+
+	st := s.Stats()[0]
+	if st.Active != 0 || st.Queued != 0 {
+		t.Errorf("Expected idle backend after releases, got %+v", st)
+	}
+}
+
+func TestScheduler_QueueFull(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{Enabled: true, DefaultMaxConcurrent: 1, MaxQueueDepth: 1})
+
+	release, _ := s.Acquire(context.Background(), "gpu", backends.PriorityNormal)
+	defer release()
+
+	displaced := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(context.Background(), "gpu", backends.PriorityBestEffort)
+		displaced <- err
+	}()
+	waitQueued(t, s, "gpu", 1)
+
+	// A higher priority request takes the best-effort request's place
+	go s.Acquire(context.Background(), "gpu", backends.PriorityHigh)
+
+	var capacityErr *proxyerrors.BackendCapacityError
+	if err := <-displaced; !errors.As(err, &capacityErr) {
+		t.Errorf("Expected displaced best-effort request to get capacity error, got %v", err)
+	}
+	waitQueued(t, s, "gpu", 1)
+
+	// Nothing of lower priority is left to displace
+	if _, err := s.Acquire(context.Background(), "gpu", backends.PriorityNormal); !errors.As(err, &capacityErr) {
+		t.Errorf("Expected capacity error when queue full, got %v", err)
+	}
+}
+
+func TestScheduler_TimeoutAndCancel(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{Enabled: true, DefaultMaxConcurrent: 1, QueueTimeout: 10 * time.Millisecond})
+
+	release, _ := s.Acquire(context.Background(), "cpu", backends.PriorityNormal)
+
+	var timeoutErr *proxyerrors.BackendTimeoutError
+	if _, err := s.Acquire(context.Background(), "cpu", backends.PriorityNormal); !errors.As(err, &timeoutErr) {
+		t.Errorf("Expected queue wait timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Acquire(ctx, "cpu", backends.PriorityCritical); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled, got %v", err)
+	}
+
+	// Abandoned waiters must not hold slots
+	release()
+	rel, err := s.Acquire(context.Background(), "cpu", backends.PriorityNormal)
+	if err != nil {
+		t.Fatalf("Expected free slot after abandoned waits, got %v", err)
+	}
+	rel()
+}
+
+func TestScheduler_PerBackendLimits(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{
+		Enabled:       true,
+		MaxConcurrent: map[string]int{"npu": 1},
+		QueueTimeout:  10 * time.Millisecond,
+	})
+
+	// No default limit: unlisted backends are unlimited
+	for i := 0; i < 5; i++ {
+		if _, err := s.Acquire(context.Background(), "gpu", backends.PriorityNormal); err != nil {
+			t.Fatalf("Expected unlimited gpu, got %v", err)
+		}
+	}
+
+	s.Acquire(context.Background(), "npu", backends.PriorityNormal)
+	if _, err := s.Acquire(context.Background(), "npu", backends.PriorityNormal); err == nil {
+		t.Error("Expected npu limited to one concurrent request")
+	}
+}
+
+func TestRouteRequest_SchedulerLimitsGenerate(t *testing.T) {
+	router := NewRouter(Config{Scheduler: SchedulerConfig{
+		Enabled:              true,
+		DefaultMaxConcurrent: 1,
+		QueueTimeout:         10 * time.Millisecond,
+	}})
+	router.RegisterBackend(&MockBackend{id: "backend-1", healthy: true})
+
+	route := func() backends.Backend {
+		t.Helper()
+		decision, err := router.RouteRequest(context.Background(), &backends.Annotations{Priority: backends.PriorityNormal})
+		if err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+		return decision.Backend
+	}
+
+	// Sequential requests each get the slot back
+	for i := 0; i < 3; i++ {
+		if _, err := route().Generate(context.Background(), &backends.GenerateRequest{}); err != nil {
+			t.Fatalf("Generate %d failed: %v", i, err)
+		}
+	}
+
+	// A held slot queues the next request until it times out
+	release, _ := router.Scheduler().Acquire(context.Background(), "backend-1", backends.PriorityNormal)
+	defer release()
+	var timeoutErr *proxyerrors.BackendTimeoutError
+	if _, err := route().Generate(context.Background(), &backends.GenerateRequest{}); !errors.As(err, &timeoutErr) {
+		t.Errorf("Expected queue timeout while saturated, got %v", err)
+	}
+	if depth := router.queueMgr.GetRawQueueDepth("backend-1"); depth != 0 {
+		t.Errorf("Expected queue tracking to be released, got depth %d", depth)
+	}
+}
@@ -0,0 +1,174 @@
+package router
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// TraceJob is one request of a scheduling trace, as it arrived at a backend
+type TraceJob struct {
+	ArrivalMs  int64  `json:"arrival_ms"`  // offset from the start of the trace
+	DurationMs int64  `json:"duration_ms"` // time on the backend once running
+	Priority   int    `json:"priority"`
+	Tenant     string `json:"tenant,omitempty"`
+	DeadlineMs int64  `json:"deadline_ms,omitempty"` // after arrival, 0 = none
+}
+
+// ReadTrace parses a JSONL scheduling trace
+func ReadTrace(r io.Reader) ([]TraceJob, error) {
+	var trace []TraceJob
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var job TraceJob
+		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		trace = append(trace, job)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return trace, nil
+}
+
+// SimulationConfig is the backend a trace is replayed against
+type SimulationConfig struct {
+	Policy        string
+	MaxConcurrent int // must be positive
+	MaxQueueDepth int // 0 = unlimited
+}
+
+// SimulationResult summarizes how a policy served a trace. Waits are in
+// milliseconds and cover completed jobs only.
+type SimulationResult struct {
+	Policy         string             `json:"policy"`
+	Jobs           int                `json:"jobs"`
+	Rejected       int                `json:"rejected"`
+	DeadlineMisses int                `json:"deadline_misses"` // completed after their deadline
+	MeanWaitMs     float64            `json:"mean_wait_ms"`
+	P95WaitMs      float64            `json:"p95_wait_ms"`
+	MaxWaitMs      float64            `json:"max_wait_ms"`
+	WaitByPriority map[string]float64 `json:"mean_wait_by_priority_ms"`
+	WaitByTenant   map[string]float64 `json:"mean_wait_by_tenant_ms"`
+}
+
+// Simulate replays a trace through a scheduling policy on a single backend
+// in virtual time. It uses the same Discipline as the live scheduler, so
+// policies can be compared on recorded traffic without running it.
+func Simulate(trace []TraceJob, cfg SimulationConfig) SimulationResult {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 1
+	}
+	jobs := make([]TraceJob, len(trace))
+	copy(jobs, trace)
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].ArrivalMs < jobs[j].ArrivalMs })
+
+	epoch := time.Unix(0, 0)
+	at := func(ms int64) time.Time { return epoch.Add(time.Duration(ms) * time.Millisecond) }
+
+	queue := disciplineFactory(cfg.Policy)()
+	job := make(map[*Waiter]TraceJob, len(jobs))
+	var running []int64 // completion times
+	waits := make([]float64, 0, len(jobs))
+	byPriority := make(map[string][]float64)
+	byTenant := make(map[string][]float64)
+
+	result := SimulationResult{Policy: cfg.Policy, Jobs: len(jobs)}
+	start := func(j TraceJob, now int64) {
+		wait := float64(now - j.ArrivalMs)
+		waits = append(waits, wait)
+		label := priorityLabel(clampPriority(backends.Priority(j.Priority)))
+		byPriority[label] = append(byPriority[label], wait)
+		byTenant[j.Tenant] = append(byTenant[j.Tenant], wait)
+
+		finish := now + j.DurationMs
+		if j.DeadlineMs > 0 && finish > j.ArrivalMs+j.DeadlineMs {
+			result.DeadlineMisses++
+		}
+		running = append(running, finish)
+	}
+
+	next := 0
+	for next < len(jobs) || len(running) > 0 {
+		// Completions at the same instant free their slots before arrivals
+		first := -1
+		for i, finish := range running {
+			if first < 0 || finish < running[first] {
+				first = i
+			}
+		}
+		if first >= 0 && (next == len(jobs) || running[first] <= jobs[next].ArrivalMs) {
+			now := running[first]
+			running = append(running[:first], running[first+1:]...)
+			if w := queue.Pop(); w != nil {
+				start(job[w], now)
+				delete(job, w)
+			}
+			continue
+		}
+
+		j := jobs[next]
+		next++
+		if len(running) < cfg.MaxConcurrent && queue.Len() == 0 {
+			start(j, j.ArrivalMs)
+			continue
+		}
+
+		w := &Waiter{
+			Priority: clampPriority(backends.Priority(j.Priority)),
+			Tenant:   j.Tenant,
+			Arrival:  at(j.ArrivalMs),
+			Seq:      uint64(next),
+		}
+		if j.DeadlineMs > 0 {
+			w.Deadline = at(j.ArrivalMs + j.DeadlineMs)
+		}
+		if cfg.MaxQueueDepth > 0 && queue.Len() >= cfg.MaxQueueDepth {
+			result.Rejected++
+			victim := queue.Victim(w)
+			if victim == nil {
+				continue
+			}
+			delete(job, victim)
+		}
+		queue.Push(w)
+		job[w] = j
+	}
+
+	result.MeanWaitMs = mean(waits)
+	if len(waits) > 0 {
+		sort.Float64s(waits)
+		result.P95WaitMs = waits[int(math.Ceil(0.95*float64(len(waits))))-1]
+		result.MaxWaitMs = waits[len(waits)-1]
+	}
+	result.WaitByPriority = make(map[string]float64, len(byPriority))
+	for label, w := range byPriority {
+		result.WaitByPriority[label] = mean(w)
+	}
+	result.WaitByTenant = make(map[string]float64, len(byTenant))
+	for tenant, w := range byTenant {
+		result.WaitByTenant[tenant] = mean(w)
+	}
+	return result
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,427 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/thermal"
+)
+
+// The harness below replays thousands of synthetic requests against
+// simulated backends so routing and forwarding policies can be checked as
+// properties rather than single cases. Nothing sleeps: latency is sampled
+// and time is virtual, and every run with the same seed is identical.
+
+// latencyDist samples a request latency in milliseconds
+type latencyDist func(rng *rand.Rand) float64
+
+func fixedLatency(ms float64) latencyDist {
+	return func(*rand.Rand) float64 { return ms }
+}
+
+func normalLatency(meanMs, stddevMs float64) latencyDist {
+	return func(rng *rand.Rand) float64 {
+		return math.Max(1, meanMs+rng.NormFloat64()*stddevMs)
+	}
+}
+
+// lognormalLatency has the long tail of real generation latencies
+func lognormalLatency(medianMs, sigma float64) latencyDist {
+	return func(rng *rand.Rand) float64 {
+		return medianMs * math.Exp(rng.NormFloat64()*sigma)
+	}
+}
+
+// thermalTrajectory is a backend's temperature at a point in virtual time
+type thermalTrajectory func(at time.Duration) float64
+
+// thermalCycle swings between min and max degrees over period, e.g. a GPU
+// heating under a batch job and cooling between them
+func thermalCycle(min, max float64, period time.Duration) thermalTrajectory {
+	return func(at time.Duration) float64 {
+		phase := 2 * math.Pi * float64(at) / float64(period)
+		return min + (max-min)*(1-math.Cos(phase))/2
+	}
+}
+
+// simWindow is a span of virtual time
+type simWindow struct {
+	from, to time.Duration
+}
+
+// simStats is what a simulated backend saw
+type simStats struct {
+	calls     int
+	failures  int
+	hot       int // calls while at or above critical temperature
+	unhealthy int // calls while reporting unhealthy
+	totalMs   float64
+}
+
+// simBackend is a backend whose latency, failures, health and temperature
+// follow a script
+type simBackend struct {
+	*MockBackend
+	sim *simulation
+
+	latency  latencyDist
+	failRate float64
+	thermal  thermalTrajectory // nil = not monitored
+	outages  []simWindow
+
+	stats     simStats
+	successes int
+	observed  float64 // total latency of successes
+}
+
+func newSimBackend(id, hardware string, estimateMs int32, powerWatts float64, priority int, latency latencyDist) *simBackend {
+	return &simBackend{
+		MockBackend: &MockBackend{
+			id:           id,
+			hardware:     hardware,
+			healthy:      true,
+			powerWatts:   powerWatts,
+			avgLatencyMs: estimateMs,
+			priority:     priority,
+		},
+		latency: latency,
+	}
+}
+
+func (b *simBackend) IsHealthy() bool {
+	for _, w := range b.outages {
+		if b.sim.now >= w.from && b.sim.now < w.to {
+			return false
+		}
+	}
+	return true
+}
+
+// AvgLatencyMs is the mean of observed successes, or the configured
+// estimate before any, as Ollama backends report it
+func (b *simBackend) AvgLatencyMs() int32 {
+	if b.successes == 0 {
+		return b.MockBackend.AvgLatencyMs()
+	}
+	return int32(b.observed / float64(b.successes))
+}
+
+func (b *simBackend) temperature() (float64, bool) {
+	if b.thermal == nil {
+		return 0, false
+	}
+	return b.thermal(b.sim.now), true
+}
+
+// Generate samples a latency, slowed down on hot hardware, and fails at
+// the configured rate
+func (b *simBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	b.stats.calls++
+	if !b.IsHealthy() {
+		b.stats.unhealthy++
+	}
+
+	latencyMs := b.latency(b.sim.rng)
+	if temp, ok := b.temperature(); ok {
+		if temp >= b.sim.critical {
+			b.stats.hot++
+		}
+		if temp > b.sim.warning {
+			latencyMs *= 1 + (temp-b.sim.warning)/(b.sim.critical-b.sim.warning)
+		}
+	}
+	b.stats.totalMs += latencyMs
+
+	if b.sim.rng.Float64() < b.failRate {
+		b.stats.failures++
+		return nil, fmt.Errorf("%s: simulated failure", b.id)
+	}
+	b.successes++
+	b.observed += latencyMs
+
+	return &backends.GenerateResponse{
+		Response: "The capital of France is Paris, which is also its largest city.",
+		Stats: &backends.GenerationStats{
+			TotalTimeMs:     int32(latencyMs),
+			TokensGenerated: 14,
+		},
+	}, nil
+}
+
+// simulation is a virtual clock, a seeded random source and a thermal
+// monitor fed from the backends' trajectories
+type simulation struct {
+	rng     *rand.Rand
+	now     time.Duration
+	step    time.Duration // virtual time between requests
+	monitor *thermal.ThermalMonitor
+
+	warning, critical float64
+
+	backends []*simBackend
+}
+
+func newSimulation(seed int64, step time.Duration, sims ...*simBackend) *simulation {
+	s := &simulation{
+		rng:      rand.New(rand.NewSource(seed)),
+		step:     step,
+		monitor:  thermal.NewThermalMonitor(nil, 0),
+		warning:  70,
+		critical: 85,
+		backends: sims,
+	}
+	for _, b := range sims {
+		b.sim = s
+	}
+	s.updateThermal()
+	return s
+}
+
+func (s *simulation) updateThermal() {
+	for _, b := range s.backends {
+		if temp, ok := b.temperature(); ok {
+			s.monitor.SetState(b.hardware, &thermal.ThermalState{Temperature: temp})
+		}
+	}
+}
+
+// simResult is the outcome of a run
+type simResult struct {
+	served []string // backend that served each request, "" if it failed
+	stats  map[string]simStats
+}
+
+// run sends n requests through dispatch, which returns the ID of the
+// backend that served the request
+func (s *simulation) run(n int, dispatch func(ctx context.Context) (string, error)) simResult {
+	ctx := context.Background()
+	result := simResult{served: make([]string, n), stats: make(map[string]simStats)}
+	for i := 0; i < n; i++ {
+		if id, err := dispatch(ctx); err == nil {
+			result.served[i] = id
+		}
+		s.now += s.step
+		s.updateThermal()
+	}
+	for _, b := range s.backends {
+		result.stats[b.id] = b.stats
+	}
+	return result
+}
+
+func (r simResult) successRate() float64 {
+	ok := 0
+	for _, id := range r.served {
+		if id != "" {
+			ok++
+		}
+	}
+	return float64(ok) / float64(len(r.served))
+}
+
+// share is the fraction of requests from the from'th on that id served
+func (r simResult) share(id string, from int) float64 {
+	n := 0
+	for _, served := range r.served[from:] {
+		if served == id {
+			n++
+		}
+	}
+	return float64(n) / float64(len(r.served)-from)
+}
+
+func routed(r *Router, annotations backends.Annotations) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		ann := annotations
+		decision, err := r.RouteRequest(ctx, &ann)
+		if err != nil {
+			return "", err
+		}
+		_, err = decision.Backend.Generate(ctx, &backends.GenerateRequest{Model: "llama3:8b", Prompt: "What is the capital of France?"})
+		// The router times requests by the wall clock; give it the
+		// virtual latency instead
+		r.load.latency.restore(decision.Backend.ID(), float64(decision.Backend.AvgLatencyMs()))
+		if err != nil {
+			return "", err
+		}
+		return decision.Backend.ID(), nil
+	}
+}
+
+func thermallyRouted(tr *ThermalRouter, annotations backends.Annotations) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		prompt := "What is the capital of France?"
+		ann := annotations
+		decision, err := tr.RouteRequestWithModel(ctx, prompt, "llama3:8b", &ann)
+		if err != nil {
+			return "", err
+		}
+		if _, err := decision.Backend.Generate(ctx, &backends.GenerateRequest{Model: "llama3:8b", Prompt: prompt}); err != nil {
+			return "", err
+		}
+		return decision.Backend.ID(), nil
+	}
+}
+
+// heterogeneousFleet is an NPU, an Intel GPU and an NVIDIA GPU with
+// realistic latency spreads; the NVIDIA GPU runs hot in cycles
+func heterogeneousFleet() []*simBackend {
+	npu := newSimBackend("ollama-npu", "npu", 800, 3, 1, lognormalLatency(800, 0.3))
+	igpu := newSimBackend("ollama-igpu", "igpu", 350, 12, 3, normalLatency(350, 60))
+	nvidia := newSimBackend("ollama-nvidia", "nvidia", 150, 55, 5, lognormalLatency(150, 0.4))
+	nvidia.thermal = thermalCycle(55, 92, 10*time.Minute)
+	return []*simBackend{npu, igpu, nvidia}
+}
+
+func TestSimulation_Deterministic(t *testing.T) {
+	runOnce := func() simResult {
+		fleet := heterogeneousFleet()
+		fleet[1].failRate = 0.1
+		sim := newSimulation(42, time.Second, fleet...)
+		tr := NewThermalRouter(Config{}, sim.monitor)
+		for _, b := range fleet {
+			tr.RegisterBackend(b)
+		}
+		return sim.run(2000, thermallyRouted(tr, backends.Annotations{LatencyCritical: true}))
+	}
+
+	first, second := runOnce(), runOnce()
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected identical runs with the same seed, got %+v and %+v", first.stats, second.stats)
+	}
+}
+
+func TestSimulation_ThermalRouterAvoidsCriticalHardware(t *testing.T) {
+	fleet := heterogeneousFleet()
+	sim := newSimulation(1, time.Second, fleet...)
+	tr := NewThermalRouter(Config{}, sim.monitor)
+	for _, b := range fleet {
+		tr.RegisterBackend(b)
+	}
+
+	result := sim.run(5000, thermallyRouted(tr, backends.Annotations{LatencyCritical: true}))
+	for id, stats := range result.stats {
+		if stats.hot > 0 {
+			t.Errorf("%s: %d requests dispatched at or above critical temperature", id, stats.hot)
+		}
+	}
+	if result.stats["ollama-nvidia"].calls == 0 {
+		t.Error("Expected the NVIDIA GPU used while cool")
+	}
+	if result.successRate() != 1 {
+		t.Errorf("Expected every request served, got %.3f", result.successRate())
+	}
+
+	// The same fleet without thermal awareness does run hot, so the
+	// trajectory really crosses the limit
+	fleet = heterogeneousFleet()
+	sim = newSimulation(1, time.Second, fleet...)
+	r := NewRouter(Config{})
+	for _, b := range fleet {
+		r.RegisterBackend(b)
+	}
+	result = sim.run(5000, routed(r, backends.Annotations{LatencyCritical: true}))
+	if result.stats["ollama-nvidia"].hot == 0 {
+		t.Error("Expected the thermally unaware router to dispatch to hot hardware")
+	}
+}
+
+func TestSimulation_NeverRoutesToUnhealthyBackends(t *testing.T) {
+	fleet := heterogeneousFleet()
+	fleet[0].outages = []simWindow{{10 * time.Minute, 25 * time.Minute}}
+	fleet[1].outages = []simWindow{{20 * time.Minute, 40 * time.Minute}, {50 * time.Minute, 55 * time.Minute}}
+	fleet[2].outages = []simWindow{{0, 5 * time.Minute}, {30 * time.Minute, 45 * time.Minute}}
+	sim := newSimulation(7, time.Second, fleet...)
+	r := NewRouter(Config{PowerAware: true})
+	for _, b := range fleet {
+		r.RegisterBackend(b)
+	}
+
+	for _, ann := range []backends.Annotations{{}, {LatencyCritical: true}, {PreferPowerEfficiency: true}} {
+		result := sim.run(1200, routed(r, ann))
+		for id, stats := range result.stats {
+			if stats.unhealthy > 0 {
+				t.Errorf("%+v: %s received %d requests while unhealthy", ann, id, stats.unhealthy)
+			}
+		}
+		// Some backend is up at every point, so every request is served
+		if result.successRate() != 1 {
+			t.Errorf("%+v: expected every request served, got %.3f", ann, result.successRate())
+		}
+	}
+}
+
+func TestSimulation_ForwardingMasksBackendFailures(t *testing.T) {
+	npu := newSimBackend("ollama-npu", "npu", 800, 3, 1, lognormalLatency(800, 0.3))
+	igpu := newSimBackend("ollama-igpu", "igpu", 350, 12, 3, normalLatency(350, 60))
+	nvidia := newSimBackend("ollama-nvidia", "nvidia", 150, 55, 5, fixedLatency(150))
+	npu.failRate, igpu.failRate, nvidia.failRate = 0.2, 0.3, 0.5
+	sim := newSimulation(3, time.Second, npu, igpu, nvidia)
+
+	base := NewRouter(Config{})
+	for _, b := range sim.backends {
+		base.RegisterBackend(b)
+	}
+	fr := NewForwardingRouter(base, nil, &ForwardingConfig{
+		Enabled:              true,
+		MinConfidence:        0, // accept the first answer
+		MaxRetries:           3,
+		EscalationPath:       []string{"ollama-npu", "ollama-igpu", "ollama-nvidia"},
+		RespectThermalLimits: true,
+	})
+
+	const requests = 4000
+	result := sim.run(requests, func(ctx context.Context) (string, error) {
+		res, err := fr.GenerateWithForwarding(ctx, "What is the capital of France?", "llama3:8b", nil)
+		if err != nil {
+			return "", err
+		}
+		return res.FinalBackend.ID(), nil
+	})
+
+	// Every failure escalates to exactly the next backend in the path
+	if got, want := result.stats["ollama-igpu"].calls, result.stats["ollama-npu"].failures; got != want {
+		t.Errorf("Expected %d escalations to ollama-igpu, got %d", want, got)
+	}
+	if got, want := result.stats["ollama-nvidia"].calls, result.stats["ollama-igpu"].failures; got != want {
+		t.Errorf("Expected %d escalations to ollama-nvidia, got %d", want, got)
+	}
+
+	// A request fails only when the whole path does: 0.2*0.3*0.5 = 3%
+	if rate := result.successRate(); rate < 0.95 {
+		t.Errorf("Expected forwarding to mask failures (~97%% served), got %.3f", rate)
+	}
+	for _, b := range sim.backends {
+		// Within four standard deviations of the configured rate
+		stats := result.stats[b.id]
+		observed := float64(stats.failures) / float64(stats.calls)
+		if tolerance := 4 * math.Sqrt(b.failRate*(1-b.failRate)/float64(stats.calls)); math.Abs(observed-b.failRate) > tolerance {
+			t.Errorf("%s: simulated failure rate %.3f, configured %.2f", b.id, observed, b.failRate)
+		}
+	}
+}
+
+func TestSimulation_LatencyCriticalFollowsObservedLatency(t *testing.T) {
+	// The NVIDIA estimate is optimistic: it really runs at ~400ms, slower
+	// than the Intel GPU
+	igpu := newSimBackend("ollama-igpu", "igpu", 200, 12, 3, normalLatency(250, 40))
+	nvidia := newSimBackend("ollama-nvidia", "nvidia", 100, 55, 5, lognormalLatency(400, 0.3))
+	sim := newSimulation(11, time.Second, igpu, nvidia)
+	r := NewRouter(Config{})
+	r.RegisterBackend(igpu)
+	r.RegisterBackend(nvidia)
+
+	result := sim.run(3000, routed(r, backends.Annotations{LatencyCritical: true}))
+	if result.share("ollama-nvidia", 0) == 0 {
+		t.Fatal("Expected the NVIDIA GPU chosen on its estimate at first")
+	}
+	if share := result.share("ollama-igpu", 1500); share < 0.9 {
+		t.Errorf("Expected latency-critical traffic to settle on the faster Intel GPU, got %.2f", share)
+	}
+}
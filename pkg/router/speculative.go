@@ -0,0 +1,31 @@
+package router
+
+import (
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/speculative"
+)
+
+// SetSpeculativeDecoder installs the decoder used for requests annotated
+// Speculative (nil = speculative decoding disabled)
+func (r *Router) SetSpeculativeDecoder(decoder *speculative.Decoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.speculative = decoder
+}
+
+// speculateLocked wraps selected to decode speculatively when the request
+// asks for it and selected can verify drafts from another backend, nil
+// otherwise. Caller must hold r.mu.
+func (r *Router) speculateLocked(selected backends.Backend, annotations *backends.Annotations) *speculative.Backend {
+	if r.speculative == nil || !annotations.Speculative {
+		return nil
+	}
+	draft := r.speculative.Draft()
+	if draft.ID() == selected.ID() || !draft.IsHealthy() || r.kill.Paused(draft.ID()) {
+		return nil
+	}
+	if !backends.SupportsDraftVerification(selected) {
+		return nil
+	}
+	return r.speculative.Wrap(selected)
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/speculative"
+)
+
+// verifyingMockBackend can score drafts
+type verifyingMockBackend struct {
+	*MockBackend
+}
+
+func (v *verifyingMockBackend) SupportsDraftVerification() bool { return true }
+
+func (v *verifyingMockBackend) VerifyDraft(ctx context.Context, req *backends.GenerateRequest, draft string) (*backends.DraftVerdict, error) {
+	return &backends.DraftVerdict{Done: true}, nil
+}
+
+func newSpeculativeRouter() *Router {
+	r := NewRouter(Config{Hedging: HedgingConfig{Enabled: true}})
+	npu := &MockBackend{id: "npu", healthy: true, avgLatencyMs: 500}
+	r.RegisterBackend(npu)
+	r.RegisterBackend(&verifyingMockBackend{&MockBackend{id: "nvidia", healthy: true, avgLatencyMs: 10}})
+	r.RegisterBackend(&MockBackend{id: "cpu", healthy: true, avgLatencyMs: 100})
+	r.SetSpeculativeDecoder(speculative.NewDecoder(npu, speculative.Config{DraftModel: "small"}))
+	return r
+}
+
+func TestRouter_SpeculativeWrapsVerifier(t *testing.T) {
+	r := newSpeculativeRouter()
+
+	decision, err := r.RouteRequest(context.Background(), &backends.Annotations{LatencyCritical: true, Speculative: true})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if decision.Speculative == nil || decision.Speculative.DraftID() != "npu" {
+		t.Fatalf("Expected speculative decoding drafting on npu, got %+v", decision)
+	}
+	if decision.Backend.ID() != "nvidia" || decision.Hedge != "" {
+		t.Errorf("Expected the unhedged verifier, got %s hedge=%q", decision.Backend.ID(), decision.Hedge)
+	}
+
+	// Without the annotation the request is hedged as usual
+	decision, err = r.RouteRequest(context.Background(), &backends.Annotations{LatencyCritical: true})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if decision.Speculative != nil || decision.Hedge == "" {
+		t.Errorf("Expected a plain hedged decision, got %+v", decision)
+	}
+}
+
+func TestRouter_SpeculativeNeedsVerifier(t *testing.T) {
+	r := newSpeculativeRouter()
+
+	for _, target := range []string{"cpu", "npu"} {
+		decision, err := r.RouteRequest(context.Background(), &backends.Annotations{Target: target, Speculative: true})
+		if err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+		if decision.Speculative != nil {
+			t.Errorf("Expected %s to decode alone", target)
+		}
+	}
+}
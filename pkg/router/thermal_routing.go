@@ -19,8 +19,13 @@ type ThermalRouter struct {
 
 // NewThermalRouter creates a router with thermal monitoring
 func NewThermalRouter(cfg Config, thermalMonitor *thermal.ThermalMonitor) *ThermalRouter {
+	r := NewRouter(cfg)
+	if thermalMonitor != nil {
+		r.SetThermalSource(thermalMonitor.GetState)
+		r.SetThermalHeadroom(thermalMonitor.PredictedHeadroomUsed)
+	}
 	return &ThermalRouter{
-		Router:           NewRouter(cfg),
+		Router:           r,
 		thermalMonitor:   thermalMonitor,
 		workloadDetector: workload.NewDetector(),
 	}
@@ -42,9 +47,15 @@ func (tr *ThermalRouter) RouteRequestWithModel(
 	tr.mu.RLock()
 	defer tr.mu.RUnlock()
 
+	// Globally enforced limits override the request's own preferences
+	mc := tr.applyModeConstraints(annotations)
+
 	// Step 1: Detect workload type and get routing hints
 	hints := tr.workloadDetector.GetRoutingHints(prompt, requestedModel, annotations)
 	reasoningChain := hints.ReasoningChain
+	if mc != nil {
+		reasoningChain = append(reasoningChain, "Enforcing "+mc.Reason)
+	}
 
 	// Step 2: Determine which model to use
 	modelToUse := requestedModel
@@ -85,6 +96,13 @@ func (tr *ThermalRouter) RouteRequestWithModel(
 	reasoningChain = append(reasoningChain,
 		fmt.Sprintf("Thermally healthy backends: %d", len(thermalHealthy)))
 
+	// Step 4b: Shift load away from backends heading for critical temperature
+	if cooler := tr.filterByThermalTrend(thermalHealthy); len(cooler) < len(thermalHealthy) {
+		reasoningChain = append(reasoningChain,
+			fmt.Sprintf("Avoiding %d backends predicted to reach critical temperature", len(thermalHealthy)-len(cooler)))
+		thermalHealthy = cooler
+	}
+
 	// Step 5: Apply constraints (latency, power)
 	constrained := tr.filterByConstraints(thermalHealthy, annotations)
 	if len(constrained) == 0 {
@@ -96,14 +114,26 @@ func (tr *ThermalRouter) RouteRequestWithModel(
 		if annotations.MaxPowerWatts > 0 {
 			constraints = append(constraints, fmt.Sprintf("power<%dW", annotations.MaxPowerWatts))
 		}
+		if mc != nil {
+			constraints = append(constraints, mc.Reason)
+		}
 		return nil, proxyerrors.NewNoBackendsError(len(thermalHealthy), len(thermalHealthy), constraints)
 	}
 
-	// Step 6: Score remaining candidates
-	scored := tr.scoreCandidatesWithHints(constrained, annotations, hints)
+	// Step 6: Re-route away from backends the request would run out of memory on
+	sized := *annotations
+	sized.Model = modelToUse
+	fitting, need, free := tr.fitMemory(constrained, &sized)
+	if len(fitting) == 0 {
+		return nil, proxyerrors.NewInsufficientMemoryError(modelToUse, need, free)
+	}
+
+	// Step 7: Score remaining candidates
+	scored := tr.scoreCandidatesWithHints(fitting, annotations, hints)
 
 	// Select best candidate
 	best := scored[0]
+	tr.memory.routed(best.backend.ID(), modelToUse)
 	thermalState := tr.thermalMonitor.GetState(best.backend.Hardware())
 
 	thermalInfo := ""
@@ -119,14 +149,16 @@ func (tr *ThermalRouter) RouteRequestWithModel(
 		Backend:            best.backend,
 		Reason:             best.reason + thermalInfo,
 		EstimatedPowerW:    best.backend.PowerWatts(),
-		EstimatedLatencyMs: best.backend.AvgLatencyMs(),
+		EstimatedLatencyMs: int32(tr.predictedLatencyMs(best.backend, annotations)),
 		Alternatives:       tr.getAlternatives(best.backend.ID()),
 		ModelRequested:     requestedModel,
 		ModelUsed:          modelToUse,
 		ModelSubstituted:   modelSubstituted,
 		SubstitutionReason: substitutionReason,
 		DetectedMediaType:  string(hints.DetectedMediaType),
+		DetectedLanguage:   annotations.Language,
 		RoutingHints:       reasoningChain,
+		Thermal:            newThermalSnapshot(tr.thermalMonitor.GetState, best.backend.Hardware()),
 	}, nil
 }
 
@@ -171,11 +203,39 @@ func (tr *ThermalRouter) filterByThermalHealth(candidates []backends.Backend) []
 	return healthy
 }
 
+// filterByThermalTrend drops backends whose temperature trend reaches
+// critical within the prediction horizon, unless that would drop them all
+func (tr *ThermalRouter) filterByThermalTrend(candidates []backends.Backend) []backends.Backend {
+	var cooler []backends.Backend
+
+	for _, backend := range candidates {
+		if !tr.thermalMonitor.PredictedCritical(backend.Hardware()) {
+			cooler = append(cooler, backend)
+		}
+	}
+
+	if len(cooler) == 0 {
+		return candidates
+	}
+	return cooler
+}
+
 // filterByConstraints filters by latency and power constraints
 func (tr *ThermalRouter) filterByConstraints(candidates []backends.Backend, annotations *backends.Annotations) []backends.Backend {
 	var filtered []backends.Backend
+	mc := tr.activeModeConstraints()
 
 	for _, backend := range candidates {
+		// Must be permitted by globally enforced limits
+		if !mc.allows(backend.ID()) {
+			continue
+		}
+
+		// Must be allowed for the request's labels
+		if !tr.labelsAllow(backend.ID(), annotations.Labels) {
+			continue
+		}
+
 		// Check max latency constraint
 		if annotations.MaxLatencyMs > 0 {
 			if backend.AvgLatencyMs() > annotations.MaxLatencyMs {
@@ -190,6 +250,11 @@ func (tr *ThermalRouter) filterByConstraints(candidates []backends.Backend, anno
 			}
 		}
 
+		// Best-effort work is deferred from backends reserved for live use
+		if admitted, _ := tr.admit(backend, annotations.Priority); !admitted {
+			continue
+		}
+
 		filtered = append(filtered, backend)
 	}
 
@@ -213,10 +278,11 @@ func (tr *ThermalRouter) scoreCandidatesWithHints(
 
 		// Base score from backend priority
 		score += float64(backend.Priority()) * 10.0
+		latencyMs := tr.latencyMs(backend.ID(), backend.AvgLatencyMs(), annotations.Model)
 
 		// Workload-specific preferences
 		if hints.PreferLowLatency {
-			latencyScore := 1000.0 - float64(backend.AvgLatencyMs())
+			latencyScore := 1000.0 - latencyMs
 			score += latencyScore * 2.5 // Strong preference for low latency
 			reasons = append(reasons, "low-latency-workload")
 		}
@@ -229,7 +295,7 @@ func (tr *ThermalRouter) scoreCandidatesWithHints(
 
 		// Annotation overrides
 		if annotations.LatencyCritical {
-			latencyScore := 1000.0 - float64(backend.AvgLatencyMs())
+			latencyScore := 1000.0 - latencyMs
 			score += latencyScore * 2
 			reasons = append(reasons, "latency-critical")
 		}
@@ -240,6 +306,12 @@ func (tr *ThermalRouter) scoreCandidatesWithHints(
 			reasons = append(reasons, "power-efficient")
 		}
 
+		// Language fit
+		if langScore, langReason := tr.languageScore(backend, annotations); langReason != "" {
+			score += langScore
+			reasons = append(reasons, langReason)
+		}
+
 		// THERMAL PENALTY
 		thermalPenalty := tr.thermalMonitor.GetThermalPenalty(backend.Hardware())
 		score -= thermalPenalty
@@ -248,6 +320,14 @@ func (tr *ThermalRouter) scoreCandidatesWithHints(
 			reasons = append(reasons, "thermal-penalty")
 		}
 
+		// Utilization penalty
+		if penalty, reason := tr.utilizationPenalty(backend); penalty > 0 {
+			score -= penalty
+			if reason != "" {
+				reasons = append(reasons, reason)
+			}
+		}
+
 		// Quiet mode preference
 		if preferQuiet {
 			thermalState := tr.thermalMonitor.GetState(backend.Hardware())
@@ -259,7 +339,7 @@ func (tr *ThermalRouter) scoreCandidatesWithHints(
 
 		// If no specific preference, use balanced scoring
 		if !annotations.LatencyCritical && !annotations.PreferPowerEfficiency && !hints.PreferLowLatency && !hints.PreferLowPower {
-			latencyScore := 1000.0 - float64(backend.AvgLatencyMs())
+			latencyScore := 1000.0 - latencyMs
 			powerScore := 1000.0 - (backend.PowerWatts() * 10)
 			score += (latencyScore + powerScore) / 2
 			reasons = append(reasons, "balanced")
@@ -331,10 +411,11 @@ func (tr *ThermalRouter) scoreCandidatesThermal(candidates []backends.Backend, a
 
 		// Base score from backend priority
 		score += float64(backend.Priority()) * 10.0
+		latencyMs := tr.latencyMs(backend.ID(), backend.AvgLatencyMs(), annotations.Model)
 
 		// Latency optimization
 		if annotations.LatencyCritical || tr.autoOptimize {
-			latencyScore := 1000.0 - float64(backend.AvgLatencyMs())
+			latencyScore := 1000.0 - latencyMs
 			score += latencyScore * 2
 			if annotations.LatencyCritical {
 				reasons = append(reasons, "latency-critical")
@@ -358,6 +439,14 @@ func (tr *ThermalRouter) scoreCandidatesThermal(candidates []backends.Backend, a
 			reasons = append(reasons, "thermal-penalty")
 		}
 
+		// Utilization penalty
+		if penalty, reason := tr.utilizationPenalty(backend); penalty > 0 {
+			score -= penalty
+			if reason != "" {
+				reasons = append(reasons, reason)
+			}
+		}
+
 		// Quiet mode preference
 		if preferQuiet {
 			thermalState := tr.thermalMonitor.GetState(backend.Hardware())
@@ -369,7 +458,7 @@ func (tr *ThermalRouter) scoreCandidatesThermal(candidates []backends.Backend, a
 
 		// If no specific preference, use balanced scoring
 		if !annotations.LatencyCritical && !annotations.PreferPowerEfficiency {
-			latencyScore := 1000.0 - float64(backend.AvgLatencyMs())
+			latencyScore := 1000.0 - latencyMs
 			powerScore := 1000.0 - (backend.PowerWatts() * 10)
 			score += (latencyScore + powerScore) / 2
 			reasons = append(reasons, "balanced")
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/daoneill/ollama-proxy/pkg/backends"
 	"github.com/daoneill/ollama-proxy/pkg/thermal"
@@ -688,3 +689,33 @@ func TestThermalRouter_RouteRequestWithModel_WithWorkloadHints(t *testing.T) {
 		}
 	}
 }
+
+func TestThermalRouter_FilterByThermalTrend(t *testing.T) {
+	monitor := thermal.NewThermalMonitor(nil, 0)
+	tr := NewThermalRouter(Config{}, monitor)
+
+	// nvidia has reached 70°C heating 0.5°C/s, so critical (85°C) is 30s away
+	start := time.Now().Add(-10 * time.Second)
+	for i := 0; i < 3; i++ {
+		monitor.SetState("nvidia", &thermal.ThermalState{Temperature: 65 + 2.5*float64(i), UpdatedAt: start.Add(time.Duration(i) * 5 * time.Second)})
+		monitor.SetState("cpu", &thermal.ThermalState{Temperature: 60, UpdatedAt: start.Add(time.Duration(i) * 5 * time.Second)})
+	}
+
+	heating := &MockBackend{id: "gpu", hardware: "nvidia", healthy: true, avgLatencyMs: 50}
+	steady := &MockBackend{id: "cpu", hardware: "cpu", healthy: true, avgLatencyMs: 500}
+
+	cooler := tr.filterByThermalTrend([]backends.Backend{heating, steady})
+	if len(cooler) != 1 || cooler[0].ID() != "cpu" {
+		t.Errorf("Expected only the steady backend, got %v", cooler)
+	}
+
+	// With no alternative the heating backend is kept
+	if only := tr.filterByThermalTrend([]backends.Backend{heating}); len(only) != 1 {
+		t.Errorf("Expected the heating backend kept as the only candidate, got %v", only)
+	}
+
+	// The base router weighs the projected temperature too
+	if used, _ := monitor.PredictedHeadroomUsed("nvidia"); used != 1 {
+		t.Errorf("Expected the heating hardware's headroom fully used, got %v", used)
+	}
+}
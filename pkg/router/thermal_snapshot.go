@@ -0,0 +1,47 @@
+package router
+
+import (
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/thermal"
+)
+
+// ThermalSnapshot is the thermal state of the selected backend's hardware
+// when a request was dispatched, for correlating slow generations with
+// throttling after the fact
+type ThermalSnapshot struct {
+	Hardware string
+	thermal.ThermalState
+	CapturedAt time.Time // when the decision was made; UpdatedAt is the reading's age
+}
+
+// ThermalSource returns the current thermal state of a hardware type, nil
+// when it is unknown
+type ThermalSource func(hardware string) *thermal.ThermalState
+
+// SetThermalSource installs the source whose state is recorded on every
+// routing decision (nil = none)
+func (r *Router) SetThermalSource(source ThermalSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.thermal = source
+}
+
+// thermalSnapshotLocked returns the thermal state of backend's hardware,
+// nil without a source or a reading. Caller must hold r.mu.
+func (r *Router) thermalSnapshotLocked(backend backends.Backend) *ThermalSnapshot {
+	if r.thermal == nil {
+		return nil
+	}
+	return newThermalSnapshot(r.thermal, backend.Hardware())
+}
+
+// newThermalSnapshot copies source's current state for hardware
+func newThermalSnapshot(source ThermalSource, hardware string) *ThermalSnapshot {
+	state := source(hardware)
+	if state == nil {
+		return nil
+	}
+	return &ThermalSnapshot{Hardware: hardware, ThermalState: *state, CapturedAt: time.Now()}
+}
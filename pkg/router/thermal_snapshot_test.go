@@ -0,0 +1,46 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/thermal"
+)
+
+func TestRouteRequest_ThermalSnapshot(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "gpu", hardware: "nvidia", healthy: true})
+
+	// Without a thermal source decisions carry no snapshot
+	decision, err := r.RouteRequest(context.Background(), &backends.Annotations{})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if decision.Thermal != nil {
+		t.Errorf("Expected no thermal snapshot, got %+v", decision.Thermal)
+	}
+
+	state := &thermal.ThermalState{Temperature: 83.5, FanPercent: 90, Throttling: true}
+	r.SetThermalSource(func(hardware string) *thermal.ThermalState {
+		if hardware == "nvidia" {
+			return state
+		}
+		return nil
+	})
+
+	decision, err = r.RouteRequest(context.Background(), &backends.Annotations{})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	snap := decision.Thermal
+	if snap == nil || snap.Hardware != "nvidia" || snap.Temperature != 83.5 || !snap.Throttling || snap.CapturedAt.IsZero() {
+		t.Fatalf("Expected the backend's thermal state at dispatch, got %+v", snap)
+	}
+
+	// The snapshot is a copy, unaffected by later readings
+	state.Temperature = 60
+	if snap.Temperature != 83.5 {
+		t.Errorf("Expected the snapshot to keep the dispatch-time temperature, got %.1f", snap.Temperature)
+	}
+}
@@ -0,0 +1,48 @@
+package router
+
+import (
+	"fmt"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+// UtilizationSource returns the sampled utilization (0-100) of a hardware
+// type's accelerators, false when it is unknown
+type UtilizationSource func(hardware string) (float64, bool)
+
+// UtilizationView is implemented by LoadViews that also know how busy
+// each backend's hardware is, including with work the proxy didn't send
+type UtilizationView interface {
+	Utilization(hardware string) (float64, bool)
+}
+
+// utilizationPenaltyPerPercent scores a fully busy accelerator like eight
+// queued requests
+const utilizationPenaltyPerPercent = 4.0
+
+// SetUtilizationSource installs the source consulted when scoring and
+// load balancing (nil = in-flight requests only)
+func (r *Router) SetUtilizationSource(source UtilizationSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.utilization = source
+	r.load.utilization = source
+}
+
+// utilizationPenalty is the score deducted for backend's hardware being
+// busy, with a routing reason once it is at least half busy. Caller must
+// hold r.mu.
+func (r *Router) utilizationPenalty(backend backends.Backend) (float64, string) {
+	if r.utilization == nil {
+		return 0, ""
+	}
+	percent, ok := r.utilization(backend.Hardware())
+	if !ok {
+		return 0, ""
+	}
+	var reason string
+	if percent >= 50 {
+		reason = fmt.Sprintf("utilization-%.0f%%", percent)
+	}
+	return percent * utilizationPenaltyPerPercent, reason
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+func TestRouter_AvoidsBusyHardware(t *testing.T) {
+	r := NewRouter(Config{})
+	r.RegisterBackend(&MockBackend{id: "nvidia", hardware: "nvidia", healthy: true, avgLatencyMs: 100})
+	r.RegisterBackend(&MockBackend{id: "igpu", hardware: "igpu", healthy: true, avgLatencyMs: 300})
+
+	decision, err := r.RouteRequest(context.Background(), &backends.Annotations{LatencyCritical: true})
+	if err != nil || decision.Backend.ID() != "nvidia" {
+		t.Fatalf("Expected the faster backend while idle, got %v, %v", decision, err)
+	}
+
+	// Another process keeps the NVIDIA GPU busy
+	r.SetUtilizationSource(func(hardware string) (float64, bool) {
+		if hardware == "nvidia" {
+			return 95, true
+		}
+		return 0, hardware == "igpu"
+	})
+	decision, err = r.RouteRequest(context.Background(), &backends.Annotations{LatencyCritical: true})
+	if err != nil {
+		t.Fatalf("RouteRequest failed: %v", err)
+	}
+	if decision.Backend.ID() != "igpu" {
+		t.Errorf("Expected the idle backend, got %s (%s)", decision.Backend.ID(), decision.Reason)
+	}
+
+	// A backend whose hardware is unknown is not penalised
+	scored := r.scoreCandidates([]backends.Backend{&MockBackend{id: "cpu", hardware: "cpu", healthy: true}}, &backends.Annotations{})
+	if strings.Contains(scored[0].reason, "utilization") {
+		t.Errorf("Expected no utilization reason, got %s", scored[0].reason)
+	}
+}
+
+func TestLeastOutstanding_TiesGoToIdleHardware(t *testing.T) {
+	r := NewRouter(Config{LoadBalancing: LoadBalancingConfig{Strategy: StrategyLeastOutstanding}})
+	r.RegisterBackend(&MockBackend{id: "a", hardware: "nvidia", healthy: true})
+	r.RegisterBackend(&MockBackend{id: "b", hardware: "igpu", healthy: true})
+	r.SetUtilizationSource(func(hardware string) (float64, bool) {
+		if hardware == "nvidia" {
+			return 80, true
+		}
+		return 10, true
+	})
+
+	for i := 0; i < 4; i++ {
+		decision, err := r.RouteRequest(context.Background(), &backends.Annotations{})
+		if err != nil {
+			t.Fatalf("RouteRequest failed: %v", err)
+		}
+		if decision.Backend.ID() != "b" {
+			t.Errorf("Expected the idle backend on a tie, got %s", decision.Backend.ID())
+		}
+		// Finish the request so in-flight counts stay tied
+		r.queueMgr.MarkRequestEnd(decision.Backend.ID(), backends.PriorityNormal)
+	}
+}
@@ -0,0 +1,368 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/authz"
+	"github.com/daoneill/ollama-proxy/pkg/drain"
+	"github.com/daoneill/ollama-proxy/pkg/pipeline"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// AdminServer implements the gRPC AdminService
+type AdminServer struct {
+	pb.UnimplementedAdminServiceServer
+	auth      auth.Config
+	drainer   *drain.Drainer
+	reload    func(context.Context) error
+	pipelines *pipeline.PipelineLoader
+}
+
+// NewAdminServer creates an admin server. Calls are authorized against
+// authCfg, whose key store (if any) the key management calls change.
+// reload reloads the configuration; nil leaves Reload unimplemented.
+func NewAdminServer(authCfg auth.Config, drainer *drain.Drainer, reload func(context.Context) error) *AdminServer {
+	return &AdminServer{auth: authCfg, drainer: drainer, reload: reload}
+}
+
+// SetPipelines enables the pipeline definition calls
+func (s *AdminServer) SetPipelines(loader *pipeline.PipelineLoader) {
+	s.pipelines = loader
+}
+
+// authorize checks the caller's API key has the admin permission
+func (s *AdminServer) authorize(ctx context.Context) error {
+	return authorizeAdmin(ctx, s.auth)
+}
+
+// authorizeAdmin checks the API key of a call has the admin permission.
+// Without authentication every caller is allowed, as on the HTTP admin API.
+func authorizeAdmin(ctx context.Context, authCfg auth.Config) error {
+	// Calls authorized by role have had their access checked already
+	if _, ok := authz.IdentityFromContext(ctx); ok {
+		return nil
+	}
+	if !authCfg.Enabled {
+		return nil
+	}
+
+	key, ok := auth.MetadataKey(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	info, ok := auth.ValidateAPIKey(authCfg, key)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "invalid or disabled API key")
+	}
+	if !auth.HasPermission(info, auth.PermissionAdmin) {
+		return status.Error(codes.PermissionDenied, "API key lacks the "+auth.PermissionAdmin+" permission")
+	}
+	return nil
+}
+
+// Reload reloads the configuration file
+func (s *AdminServer) Reload(ctx context.Context, req *pb.ReloadRequest) (*pb.ReloadResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if s.reload == nil {
+		return nil, status.Error(codes.Unimplemented, "reload is not available")
+	}
+	if err := s.reload(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "reload failed: %v", err)
+	}
+	return &pb.ReloadResponse{ReloadedAtUnix: time.Now().Unix()}, nil
+}
+
+// Drain starts draining, after which the proxy shuts down
+func (s *AdminServer) Drain(ctx context.Context, req *pb.DrainRequest) (*pb.DrainStatus, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if req.TimeoutSeconds < 0 {
+		return nil, status.Error(codes.InvalidArgument, "timeout_seconds cannot be negative")
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "admin request"
+	}
+	s.drainer.Start(reason, time.Duration(req.TimeoutSeconds)*time.Second)
+	return drainStatus(s.drainer.Status()), nil
+}
+
+// GetDrainStatus reports the drain status
+func (s *AdminServer) GetDrainStatus(ctx context.Context, req *pb.GetDrainStatusRequest) (*pb.DrainStatus, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return drainStatus(s.drainer.Status()), nil
+}
+
+// ListAPIKeys lists the API keys with their values masked
+func (s *AdminServer) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if s.auth.Keys == nil {
+		return &pb.ListAPIKeysResponse{}, nil
+	}
+	entries := s.auth.Keys.List()
+	resp := &pb.ListAPIKeysResponse{Keys: make([]*pb.APIKey, 0, len(entries))}
+	for _, entry := range entries {
+		resp.Keys = append(resp.Keys, apiKey(entry))
+	}
+	return resp, nil
+}
+
+// CreateAPIKey generates an API key
+func (s *AdminServer) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.keysManaged(); err != nil {
+		return nil, err
+	}
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	info := auth.APIKeyInfo{Name: req.Name, Tenant: req.Tenant, Permissions: req.Permissions}
+	key, entry, err := s.auth.Keys.Create(info)
+	if err != nil {
+		return nil, keyError(err)
+	}
+	return &pb.CreateAPIKeyResponse{Info: apiKey(entry), Key: key}, nil
+}
+
+// SetAPIKeyEnabled enables or disables an API key
+func (s *AdminServer) SetAPIKeyEnabled(ctx context.Context, req *pb.SetAPIKeyEnabledRequest) (*pb.APIKey, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.keysManaged(); err != nil {
+		return nil, err
+	}
+	entry, err := s.auth.Keys.SetEnabled(req.Name, req.Enabled)
+	if err != nil {
+		return nil, keyError(err)
+	}
+	return apiKey(entry), nil
+}
+
+// DeleteAPIKey deletes an API key
+func (s *AdminServer) DeleteAPIKey(ctx context.Context, req *pb.DeleteAPIKeyRequest) (*pb.DeleteAPIKeyResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.keysManaged(); err != nil {
+		return nil, err
+	}
+	if err := s.auth.Keys.Delete(req.Name); err != nil {
+		return nil, keyError(err)
+	}
+	return &pb.DeleteAPIKeyResponse{}, nil
+}
+
+// ListPipelines lists the pipeline definitions
+func (s *AdminServer) ListPipelines(ctx context.Context, req *pb.ListPipelinesRequest) (*pb.ListPipelinesResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if s.pipelines == nil {
+		return &pb.ListPipelinesResponse{}, nil
+	}
+	defs := s.pipelines.Definitions()
+	resp := &pb.ListPipelinesResponse{Pipelines: make([]*pb.PipelineDefinition, 0, len(defs))}
+	for _, def := range defs {
+		out, err := pipelineDefinition(def)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Pipelines = append(resp.Pipelines, out)
+	}
+	return resp, nil
+}
+
+// GetPipeline returns a pipeline definition
+func (s *AdminServer) GetPipeline(ctx context.Context, req *pb.GetPipelineRequest) (*pb.PipelineDefinition, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.pipelinesEnabled(); err != nil {
+		return nil, err
+	}
+	def, ok := s.pipelines.Definition(req.Id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "%v: %s", pipeline.ErrPipelineNotFound, req.Id)
+	}
+	out, err := pipelineDefinition(def)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+// CreatePipeline saves a new pipeline definition
+func (s *AdminServer) CreatePipeline(ctx context.Context, req *pb.SavePipelineRequest) (*pb.PipelineDefinition, error) {
+	return s.savePipeline(ctx, req, (*pipeline.PipelineLoader).Create)
+}
+
+// UpdatePipeline replaces a pipeline definition
+func (s *AdminServer) UpdatePipeline(ctx context.Context, req *pb.SavePipelineRequest) (*pb.PipelineDefinition, error) {
+	return s.savePipeline(ctx, req, (*pipeline.PipelineLoader).Update)
+}
+
+func (s *AdminServer) savePipeline(ctx context.Context, req *pb.SavePipelineRequest, save func(*pipeline.PipelineLoader, pipeline.PipelineYAML) (pipeline.Definition, error)) (*pb.PipelineDefinition, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.pipelinesEnabled(); err != nil {
+		return nil, err
+	}
+	def, err := pipeline.ParseDefinition([]byte(req.Definition))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid definition: %v", err)
+	}
+	saved, err := save(s.pipelines, def)
+	if err != nil {
+		return nil, pipelineError(err)
+	}
+	out, err := pipelineDefinition(saved)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+// ValidatePipeline checks a pipeline definition without saving it
+func (s *AdminServer) ValidatePipeline(ctx context.Context, req *pb.ValidatePipelineRequest) (*pb.ValidatePipelineResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	def, err := pipeline.ParseDefinition([]byte(req.Definition))
+	if err != nil {
+		return &pb.ValidatePipelineResponse{Error: err.Error()}, nil
+	}
+	loader := s.pipelines
+	if loader == nil {
+		loader = pipeline.NewPipelineLoader()
+	}
+	p, err := loader.Validate(def)
+	if err != nil {
+		return &pb.ValidatePipelineResponse{Error: err.Error()}, nil
+	}
+	return &pb.ValidatePipelineResponse{Valid: true, Stages: int32(len(p.Stages))}, nil
+}
+
+// DeletePipeline deletes a pipeline created at runtime
+func (s *AdminServer) DeletePipeline(ctx context.Context, req *pb.DeletePipelineRequest) (*pb.DeletePipelineResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.pipelinesEnabled(); err != nil {
+		return nil, err
+	}
+	if err := s.pipelines.Delete(req.Id); err != nil {
+		return nil, pipelineError(err)
+	}
+	return &pb.DeletePipelineResponse{}, nil
+}
+
+// pipelinesEnabled reports whether pipelines can be managed
+func (s *AdminServer) pipelinesEnabled() error {
+	if s.pipelines == nil {
+		return status.Error(codes.FailedPrecondition, "pipelines are disabled")
+	}
+	return nil
+}
+
+// pipelineError maps a pipeline store error to a gRPC status
+func pipelineError(err error) error {
+	switch {
+	case errors.Is(err, pipeline.ErrInvalidPipeline):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, pipeline.ErrPipelineNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, pipeline.ErrPipelineExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, pipeline.ErrPipelineConfigured), errors.Is(err, pipeline.ErrNoDefinitionsDir):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func pipelineDefinition(def pipeline.Definition) (*pb.PipelineDefinition, error) {
+	data, err := yaml.Marshal(def.PipelineYAML)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PipelineDefinition{
+		Id:          def.ID,
+		Name:        def.Name,
+		Description: def.Description,
+		Stages:      int32(len(def.Stages)),
+		Source:      def.Source,
+		Definition:  string(data),
+	}, nil
+}
+
+// keysManaged reports whether API keys can be changed: changes to keys
+// nobody checks would only mislead
+func (s *AdminServer) keysManaged() error {
+	if !s.auth.Enabled || s.auth.Keys == nil {
+		return status.Error(codes.FailedPrecondition, "authentication is disabled")
+	}
+	return nil
+}
+
+// keyError maps a key store error to a gRPC status
+func keyError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrKeyNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, auth.ErrKeyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, auth.ErrKeyConfigured):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func apiKey(entry auth.KeyEntry) *pb.APIKey {
+	return &pb.APIKey{
+		Name:        entry.Name,
+		Tenant:      entry.Tenant,
+		Permissions: entry.Permissions,
+		Enabled:     entry.Enabled,
+		KeyHint:     entry.Hint,
+	}
+}
+
+func drainStatus(st drain.Status) *pb.DrainStatus {
+	out := &pb.DrainStatus{
+		Draining: st.Draining,
+		Reason:   st.Reason,
+		InFlight: int32(st.InFlight),
+	}
+	if !st.Since.IsZero() {
+		out.SinceUnix = st.Since.Unix()
+		out.DeadlineUnix = st.Deadline.Unix()
+	}
+	if len(st.ByKind) > 0 {
+		out.ByKind = make(map[string]int32, len(st.ByKind))
+		for kind, n := range st.ByKind {
+			out.ByKind[kind] = int32(n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/drain"
+	"github.com/daoneill/ollama-proxy/pkg/pipeline"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func withKey(key string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+key))
+}
+
+func newTestAdminServer(reload func(context.Context) error) (*AdminServer, *auth.KeyStore) {
+	keys := auth.NewKeyStore(map[string]auth.APIKeyInfo{
+		"admin-key": {Name: "ops", Permissions: []string{auth.PermissionAdmin}, Enabled: true},
+		"user-key":  {Name: "app", Permissions: []string{"read"}, Enabled: true},
+	})
+	return NewAdminServer(auth.Config{Enabled: true, Keys: keys}, drain.New(0), reload), keys
+}
+
+func TestAdminServer_Authorization(t *testing.T) {
+	s, _ := newTestAdminServer(nil)
+
+	tests := []struct {
+		ctx  context.Context
+		want codes.Code
+	}{
+		{context.Background(), codes.Unauthenticated},
+		{withKey("wrong"), codes.Unauthenticated},
+		{withKey("user-key"), codes.PermissionDenied},
+		{withKey("admin-key"), codes.OK},
+	}
+	for _, tt := range tests {
+		if _, err := s.ListAPIKeys(tt.ctx, &pb.ListAPIKeysRequest{}); status.Code(err) != tt.want {
+			t.Errorf("Expected %s, got %v", tt.want, err)
+		}
+	}
+
+	// Without authentication every caller is allowed, but keys can't change
+	open := NewAdminServer(auth.Config{}, drain.New(0), nil)
+	if _, err := open.GetDrainStatus(context.Background(), &pb.GetDrainStatusRequest{}); err != nil {
+		t.Errorf("Expected calls to be allowed without authentication, got %v", err)
+	}
+	if _, err := open.CreateAPIKey(context.Background(), &pb.CreateAPIKeyRequest{Name: "ci"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition creating a key without authentication, got %v", err)
+	}
+	if _, err := open.Reload(context.Background(), &pb.ReloadRequest{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("Expected Unimplemented without a reload hook, got %v", err)
+	}
+}
+
+func TestAdminServer_APIKeys(t *testing.T) {
+	s, keys := newTestAdminServer(nil)
+	ctx := withKey("admin-key")
+
+	created, err := s.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "ci", Permissions: []string{auth.PermissionAdmin}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if created.Key == "" || !created.Info.Enabled || created.Info.KeyHint == "" {
+		t.Errorf("Unexpected created key: %+v", created)
+	}
+	if _, ok := keys.Lookup(created.Key); !ok {
+		t.Error("Expected the key in the store")
+	}
+	// The new key can administer the proxy itself
+	if _, err := s.ListAPIKeys(withKey(created.Key), &pb.ListAPIKeysRequest{}); err != nil {
+		t.Errorf("Expected the new admin key to be accepted, got %v", err)
+	}
+	if _, err := s.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{Name: "ci"}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists, got %v", err)
+	}
+	if _, err := s.CreateAPIKey(ctx, &pb.CreateAPIKeyRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without a name, got %v", err)
+	}
+
+	list, _ := s.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{})
+	if len(list.Keys) != 3 || list.Keys[0].Name != "app" || list.Keys[1].Name != "ci" {
+		t.Errorf("Unexpected keys: %+v", list.Keys)
+	}
+	for _, k := range list.Keys {
+		if k.KeyHint == created.Key || k.KeyHint == "admin-key" {
+			t.Errorf("Expected key values to be masked, got %q", k.KeyHint)
+		}
+	}
+
+	key, err := s.SetAPIKeyEnabled(ctx, &pb.SetAPIKeyEnabledRequest{Name: "app", Enabled: false})
+	if err != nil || key.Enabled {
+		t.Errorf("Expected app to be disabled, got %+v, %v", key, err)
+	}
+	if _, err := s.DeleteAPIKey(ctx, &pb.DeleteAPIKeyRequest{Name: "ci"}); err != nil {
+		t.Errorf("DeleteAPIKey failed: %v", err)
+	}
+	if _, err := s.DeleteAPIKey(ctx, &pb.DeleteAPIKeyRequest{Name: "ci"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound deleting twice, got %v", err)
+	}
+}
+
+func TestAdminServer_DrainAndReload(t *testing.T) {
+	reloadErr := errors.New("invalid configuration")
+	reloads := 0
+	s, _ := newTestAdminServer(func(ctx context.Context) error {
+		reloads++
+		if reloads > 1 {
+			return reloadErr
+		}
+		return nil
+	})
+	ctx := withKey("admin-key")
+
+	if resp, err := s.Reload(ctx, &pb.ReloadRequest{}); err != nil || resp.ReloadedAtUnix == 0 {
+		t.Errorf("Expected a successful reload, got %+v, %v", resp, err)
+	}
+	if _, err := s.Reload(ctx, &pb.ReloadRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for a failed reload, got %v", err)
+	}
+
+	if st, _ := s.GetDrainStatus(ctx, &pb.GetDrainStatusRequest{}); st.Draining {
+		t.Error("Expected no drain before Drain")
+	}
+	if _, err := s.Drain(ctx, &pb.DrainRequest{TimeoutSeconds: -1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a negative timeout, got %v", err)
+	}
+	st, err := s.Drain(ctx, &pb.DrainRequest{TimeoutSeconds: 60})
+	if err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if !st.Draining || st.Reason != "admin request" || st.DeadlineUnix-st.SinceUnix != 60 {
+		t.Errorf("Unexpected drain status: %+v", st)
+	}
+}
+
+func TestAdminServer_Pipelines(t *testing.T) {
+	s, _ := newTestAdminServer(nil)
+	ctx := withKey("admin-key")
+
+	if _, err := s.CreatePipeline(ctx, &pb.SavePipelineRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without pipelines, got %v", err)
+	}
+
+	loader := pipeline.NewPipelineLoader()
+	if err := loader.SetDefinitionsDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	s.SetPipelines(loader)
+
+	def := "id: chat\nstages:\n  - id: llm\n    type: text_generation\n"
+	created, err := s.CreatePipeline(ctx, &pb.SavePipelineRequest{Definition: def})
+	if err != nil || created.Id != "chat" || created.Stages != 1 || created.Source != pipeline.SourceDefinitionsDir {
+		t.Fatalf("CreatePipeline failed: %+v, %v", created, err)
+	}
+	if _, err := s.CreatePipeline(ctx, &pb.SavePipelineRequest{Definition: def}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists, got %v", err)
+	}
+	if _, err := s.UpdatePipeline(ctx, &pb.SavePipelineRequest{Definition: `{"id":"chat","stages":[]}`}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an invalid definition, got %v", err)
+	}
+
+	// The returned definition can be edited and sent back
+	got, err := s.GetPipeline(ctx, &pb.GetPipelineRequest{Id: "chat"})
+	if err != nil {
+		t.Fatalf("GetPipeline failed: %v", err)
+	}
+	if _, err := s.UpdatePipeline(ctx, &pb.SavePipelineRequest{Definition: got.Definition + "description: edited\n"}); err != nil {
+		t.Errorf("UpdatePipeline failed: %v", err)
+	}
+
+	list, _ := s.ListPipelines(ctx, &pb.ListPipelinesRequest{})
+	if len(list.Pipelines) != 1 || list.Pipelines[0].Description != "edited" {
+		t.Errorf("Unexpected pipelines: %+v", list.Pipelines)
+	}
+
+	resp, err := s.ValidatePipeline(ctx, &pb.ValidatePipelineRequest{Definition: "id: x\nstages:\n  - id: a\n    type: nope\n"})
+	if err != nil || resp.Valid || resp.Error == "" {
+		t.Errorf("Expected an invalid definition, got %+v, %v", resp, err)
+	}
+
+	if _, err := s.DeletePipeline(ctx, &pb.DeletePipelineRequest{Id: "chat"}); err != nil {
+		t.Errorf("DeletePipeline failed: %v", err)
+	}
+	if _, err := s.GetPipeline(ctx, &pb.GetPipelineRequest{Id: "chat"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound after delete, got %v", err)
+	}
+}
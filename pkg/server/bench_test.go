@@ -0,0 +1,64 @@
+//go:build !race
+
+package server
+
+import (
+	"testing"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+var (
+	benchAnnotations = &pb.JobAnnotations{
+		Target:          "auto",
+		LatencyCritical: true,
+		MaxLatencyMs:    500,
+		Custom:          map[string]string{"team": "voice"},
+	}
+	benchOptions = &pb.GenerationOptions{MaxTokens: 256, Temperature: 0.7, TopP: 0.9}
+	benchStats   = &backends.GenerationStats{TimeToFirstTokenMs: 120, TotalTimeMs: 2400, TokensGenerated: 180}
+
+	// Sinks keep the compiler from optimising conversions away
+	annotationsSink *backends.Annotations
+	optionsSink     *backends.GenerationOptions
+	statsSink       *pb.GenerationStats
+)
+
+func BenchmarkConvertAnnotations(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		annotationsSink = convertAnnotations(benchAnnotations)
+	}
+}
+
+func BenchmarkConvertGenerationOptions(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		optionsSink = convertGenerationOptions(benchOptions)
+	}
+}
+
+func BenchmarkConvertStats(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		statsSink = convertStats(benchStats)
+	}
+}
+
+// TestConvert_AllocBudget fails when converting between proto and internal
+// types allocates more than the converted value itself
+func TestConvert_AllocBudget(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fn   func()
+	}{
+		{"convertAnnotations", func() { annotationsSink = convertAnnotations(benchAnnotations) }},
+		{"convertGenerationOptions", func() { optionsSink = convertGenerationOptions(benchOptions) }},
+		{"convertStats", func() { statsSink = convertStats(benchStats) }},
+	} {
+		if allocs := testing.AllocsPerRun(100, tc.fn); allocs > 1 {
+			t.Errorf("%s allocates %.0f times, budget 1", tc.name, allocs)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
+	"go.uber.org/zap"
+)
+
+// chatMessage is one message of a Chat conversation
+type chatMessage struct {
+	role    string
+	content string
+}
+
+// chatSession is the state of one Chat stream: the conversation so far and
+// the backend serving it
+type chatSession struct {
+	s      *ComputeServer
+	stream pb.ComputeService_ChatServer
+
+	model       string
+	annotations *backends.Annotations // from the first turn
+	messages    []chatMessage
+	backendID   string // served the last reply, "" before the first
+	turns       int32
+
+	reply   *chatReply     // being generated, nil when idle
+	pending []*pb.ChatTurn // sent while a reply was generated
+	closing bool           // client finished sending
+}
+
+// chatReply is a reply being generated. Its events are sent by the
+// generating goroutine and the channel is closed when it ends.
+type chatReply struct {
+	turn      int32
+	cancel    context.CancelFunc
+	events    chan chatEvent
+	text      strings.Builder
+	sentFirst bool
+	done      bool
+	stats     *backends.GenerationStats
+	integrity *streaming.Integrity
+	cancelled bool
+	err       error
+}
+
+// chatEvent is the routed backend, a chunk of the reply or an error
+type chatEvent struct {
+	backend string
+	chunk   *backends.StreamChunk
+	err     error
+}
+
+// Chat holds a conversation on a bidirectional stream. Each user turn is
+// answered with the whole conversation as context, on the backend that
+// answered the previous turn while it stays healthy. Turns sent during a
+// reply are answered in order after it; a cancel stops the reply in
+// progress and keeps what was sent of it in the conversation. When the
+// client closes its side, queued turns are answered before the stream ends.
+func (s *ComputeServer) Chat(stream pb.ComputeService_ChatServer) error {
+	ctx := stream.Context()
+	cs := &chatSession{s: s, stream: stream}
+	defer func() {
+		if cs.reply != nil {
+			cs.reply.cancel()
+		}
+	}()
+
+	// Receive on a goroutine so cancellations arrive while replies stream
+	requests := make(chan *pb.ChatRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		if cs.reply == nil && len(cs.pending) > 0 {
+			turn := cs.pending[0]
+			cs.pending = cs.pending[1:]
+			if err := cs.startTurn(ctx, turn); err != nil {
+				return err
+			}
+			continue
+		}
+		if cs.closing && cs.reply == nil {
+			return nil
+		}
+
+		var events chan chatEvent
+		if cs.reply != nil {
+			events = cs.reply.events
+		}
+
+		select {
+		case req := <-requests:
+			switch r := req.Request.(type) {
+			case *pb.ChatRequest_Turn:
+				cs.pending = append(cs.pending, r.Turn)
+			case *pb.ChatRequest_Cancel:
+				if cs.reply != nil {
+					cs.reply.cancelled = true
+					cs.reply.cancel()
+				}
+			}
+
+		case err := <-recvErr:
+			if err != io.EOF {
+				return err
+			}
+			cs.closing = true
+			recvErr = nil
+
+		case ev, ok := <-events:
+			if !ok {
+				if err := cs.finishReply(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := cs.handleEvent(ev); err != nil {
+				return err
+			}
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// startTurn adds a turn to the conversation and starts generating the
+// reply to user turns
+func (cs *chatSession) startTurn(ctx context.Context, turn *pb.ChatTurn) error {
+	if cs.model == "" {
+		cs.model = turn.Model
+		cs.annotations = convertAnnotations(turn.Annotations)
+		cs.annotations.RequestID = middleware.GetRequestID(ctx)
+	} else if turn.Model != "" {
+		cs.model = turn.Model
+	}
+
+	role := strings.ToLower(turn.Role)
+	if role == "" {
+		role = "user"
+	}
+	if turn.Content == "" {
+		return cs.stream.Send(&pb.ChatResponse{Done: true, Turn: cs.turns, Error: "turn has no content"})
+	}
+	if role == "system" {
+		cs.messages = append(cs.messages, chatMessage{role: role, content: turn.Content})
+		return nil
+	}
+	if role != "user" {
+		return cs.stream.Send(&pb.ChatResponse{Done: true, Turn: cs.turns, Error: fmt.Sprintf("unsupported role %q", turn.Role)})
+	}
+
+	cs.turns++
+	cs.messages = append(cs.messages, chatMessage{role: role, content: turn.Content})
+
+	annotations := *cs.annotations
+	annotations.Model = cs.model
+	// Keep the conversation on its backend; the router falls back to
+	// normal selection when it is unhealthy
+	if cs.backendID != "" && (annotations.Target == "" || annotations.Target == "auto") {
+		annotations.Target = cs.backendID
+	}
+	req := &backends.GenerateRequest{
+		Prompt:  chatPrompt(cs.messages),
+		Model:   cs.model,
+		Options: convertGenerationOptions(turn.Options),
+	}
+
+	replyCtx, cancel := context.WithCancel(ctx)
+	reply := &chatReply{turn: cs.turns, cancel: cancel, events: make(chan chatEvent), integrity: streaming.NewIntegrity("")}
+	cs.reply = reply
+	go cs.s.generateReply(replyCtx, &annotations, req, reply.events)
+	return nil
+}
+
+// generateReply routes and streams one reply into events
+func (s *ComputeServer) generateReply(ctx context.Context, annotations *backends.Annotations, req *backends.GenerateRequest, events chan<- chatEvent) {
+	defer close(events)
+	send := func(ev chatEvent) bool {
+		select {
+		case events <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	decision, err := s.router.RouteRequest(ctx, annotations)
+	if err != nil {
+		send(chatEvent{err: fmt.Errorf("routing failed: %w", err)})
+		return
+	}
+	if !send(chatEvent{backend: decision.Backend.ID()}) {
+		return
+	}
+
+	reader, err := decision.Backend.GenerateStream(ctx, req)
+	if err != nil {
+		send(chatEvent{err: fmt.Errorf("streaming failed: %w", err)})
+		return
+	}
+	defer reader.Close()
+
+	for {
+		chunk, err := reader.Recv()
+		if err != nil {
+			send(chatEvent{err: err})
+			return
+		}
+		if !send(chatEvent{chunk: chunk}) || chunk.Done {
+			return
+		}
+	}
+}
+
+// handleEvent forwards an event of the reply in progress to the client
+func (cs *chatSession) handleEvent(ev chatEvent) error {
+	reply := cs.reply
+	switch {
+	case ev.err != nil:
+		reply.err = ev.err
+		return nil
+
+	case ev.backend != "":
+		if ev.backend != cs.backendID && cs.backendID != "" {
+			logging.FromContext(cs.stream.Context()).Info("Chat moved to another backend",
+				zap.String("from", cs.backendID),
+				zap.String("to", ev.backend),
+			)
+		}
+		cs.backendID = ev.backend
+		return nil
+	}
+
+	chunk := ev.chunk
+	if reply.cancelled || (chunk.Token == "" && !chunk.Done) {
+		return nil
+	}
+	if chunk.Done {
+		// finishReply sends the final message once the stream ends
+		reply.done = true
+		reply.stats = chunk.Stats
+		if chunk.Token == "" {
+			return nil
+		}
+	}
+	resp := &pb.ChatResponse{Token: chunk.Token, Turn: reply.turn}
+	if !reply.sentFirst {
+		resp.BackendUsed = cs.backendID
+		reply.sentFirst = true
+	}
+	reply.text.WriteString(chunk.Token)
+	reply.integrity.Add(chunk.Token, 1)
+	return cs.stream.Send(resp)
+}
+
+// finishReply ends the reply in progress: completed and cancelled replies
+// join the conversation, failed turns leave it
+func (cs *chatSession) finishReply() error {
+	reply := cs.reply
+	cs.reply = nil
+	reply.cancel()
+
+	resp := &pb.ChatResponse{Done: true, Turn: reply.turn}
+	if !reply.sentFirst {
+		resp.BackendUsed = cs.backendID
+	}
+	switch {
+	case reply.cancelled:
+		resp.Cancelled = true
+	case reply.done && reply.err == nil:
+		resp.Stats = convertStats(reply.stats)
+		resp.Integrity = convertIntegrity(reply.integrity.Summary())
+	default:
+		err := reply.err
+		if err == nil || (errors.Is(err, io.EOF) && reply.text.Len() > 0) {
+			// The backend ended the stream without a final chunk
+			break
+		}
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("backend returned no reply")
+		}
+		resp.Error = err.Error()
+		cs.messages = cs.messages[:len(cs.messages)-1]
+		return cs.stream.Send(resp)
+	}
+
+	if reply.text.Len() > 0 {
+		cs.messages = append(cs.messages, chatMessage{role: "assistant", content: reply.text.String()})
+	}
+	return cs.stream.Send(resp)
+}
+
+// chatPrompt renders the conversation with role markers, ending with the
+// assistant's cue
+func chatPrompt(messages []chatMessage) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		switch msg.role {
+		case "system":
+			sb.WriteString("System: ")
+		case "assistant":
+			sb.WriteString("Assistant: ")
+		default:
+			sb.WriteString("User: ")
+		}
+		sb.WriteString(msg.content)
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("Assistant:")
+	return sb.String()
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"google.golang.org/grpc/metadata"
+)
+
+// MockChatStream is a mock bidirectional Chat stream fed by the test
+type MockChatStream struct {
+	ctx  context.Context
+	in   chan *pb.ChatRequest // closed for EOF
+	sent chan *pb.ChatResponse
+}
+
+func newMockChatStream(ctx context.Context) *MockChatStream {
+	return &MockChatStream{ctx: ctx, in: make(chan *pb.ChatRequest), sent: make(chan *pb.ChatResponse, 64)}
+}
+
+func (m *MockChatStream) Recv() (*pb.ChatRequest, error) {
+	select {
+	case req, ok := <-m.in:
+		if !ok {
+			return nil, io.EOF
+		}
+		return req, nil
+	case <-m.ctx.Done():
+		return nil, m.ctx.Err()
+	}
+}
+
+func (m *MockChatStream) Send(resp *pb.ChatResponse) error {
+	m.sent <- resp
+	return nil
+}
+
+func (m *MockChatStream) Context() context.Context     { return m.ctx }
+func (m *MockChatStream) SendMsg(v interface{}) error  { return nil }
+func (m *MockChatStream) RecvMsg(v interface{}) error  { return nil }
+func (m *MockChatStream) SendHeader(metadata.MD) error { return nil }
+func (m *MockChatStream) SetHeader(metadata.MD) error  { return nil }
+func (m *MockChatStream) SetTrailer(metadata.MD)       {}
+
+func (m *MockChatStream) turn(content string) {
+	m.in <- &pb.ChatRequest{Request: &pb.ChatRequest_Turn{Turn: &pb.ChatTurn{Content: content, Model: "llama3:8b"}}}
+}
+
+// reply collects a reply up to its final message
+func (m *MockChatStream) reply(t *testing.T) (string, *pb.ChatResponse) {
+	t.Helper()
+	var text strings.Builder
+	for {
+		select {
+		case resp := <-m.sent:
+			text.WriteString(resp.Token)
+			if resp.Done {
+				return text.String(), resp
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a reply")
+		}
+	}
+}
+
+// chatBackend records prompts and streams a reply echoing the last line
+// of the user's message. With hold set, it sends one token and waits for
+// cancellation.
+type chatBackend struct {
+	MockBackend
+	mu      sync.Mutex
+	prompts []string
+	hold    bool
+}
+
+func (b *chatBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	b.mu.Lock()
+	b.prompts = append(b.prompts, req.Prompt)
+	hold := b.hold
+	b.mu.Unlock()
+
+	lines := strings.Split(req.Prompt, "\n")
+	last := strings.TrimPrefix(lines[len(lines)-2], "User: ")
+	if hold {
+		return &heldStream{ctx: ctx}, nil
+	}
+	return &MockStreamReader{chunks: []*backends.StreamChunk{
+		{Token: "re: "},
+		{Token: last, Done: true, Stats: &backends.GenerationStats{TokensGenerated: 2}},
+	}}, nil
+}
+
+func (b *chatBackend) Prompts() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.prompts...)
+}
+
+// heldStream sends "partial" and then blocks until cancelled
+type heldStream struct {
+	ctx  context.Context
+	sent bool
+}
+
+func (s *heldStream) Recv() (*backends.StreamChunk, error) {
+	if !s.sent {
+		s.sent = true
+		return &backends.StreamChunk{Token: "partial"}, nil
+	}
+	<-s.ctx.Done()
+	return nil, s.ctx.Err()
+}
+
+func (s *heldStream) Close() error { return nil }
+
+func runChat(t *testing.T, r *router.Router) (*MockChatStream, chan error, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newMockChatStream(ctx)
+	done := make(chan error, 1)
+	go func() { done <- NewComputeServer(r).Chat(stream) }()
+	return stream, done, cancel
+}
+
+func TestChat_KeepsContextAndBackend(t *testing.T) {
+	fast := &chatBackend{MockBackend: MockBackend{id: "fast", healthy: true, avgLatencyMs: 100}}
+	slow := &chatBackend{MockBackend: MockBackend{id: "slow", healthy: true, avgLatencyMs: 300}}
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(fast)
+	r.RegisterBackend(slow)
+
+	stream, done, cancel := runChat(t, r)
+	defer cancel()
+
+	stream.turn("hello")
+	text, final := stream.reply(t)
+	if text != "re: hello" || final.Turn != 1 || final.Stats.GetTokensGenerated() != 2 {
+		t.Fatalf("Unexpected first reply %q, %+v", text, final)
+	}
+
+	// The conversation stays on its backend though it is now the slower
+	fast.avgLatencyMs = 500
+	stream.turn("again")
+	if text, final = stream.reply(t); text != "re: again" || final.Turn != 2 {
+		t.Fatalf("Unexpected second reply %q, %+v", text, final)
+	}
+
+	prompts := fast.Prompts()
+	if len(prompts) != 2 || len(slow.Prompts()) != 0 {
+		t.Fatalf("Expected both turns on the first backend, got %d and %d", len(prompts), len(slow.Prompts()))
+	}
+	if want := "User: hello\nAssistant: re: hello\nUser: again\nAssistant:"; prompts[1] != want {
+		t.Errorf("Expected the conversation as context, got %q", prompts[1])
+	}
+
+	close(stream.in)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the stream to end cleanly, got %v", err)
+	}
+}
+
+func TestChat_Cancel(t *testing.T) {
+	backend := &chatBackend{MockBackend: MockBackend{id: "gpu", healthy: true}, hold: true}
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(backend)
+
+	stream, done, cancel := runChat(t, r)
+	defer cancel()
+
+	stream.turn("write an essay")
+	if first := <-stream.sent; first.Token != "partial" || first.BackendUsed != "gpu" {
+		t.Fatalf("Unexpected first chunk %+v", first)
+	}
+	stream.in <- &pb.ChatRequest{Request: &pb.ChatRequest_Cancel{Cancel: &pb.ChatCancel{}}}
+	if _, final := stream.reply(t); !final.Cancelled {
+		t.Fatalf("Expected a cancelled reply, got %+v", final)
+	}
+
+	// The conversation continues with what was sent of the reply
+	backend.mu.Lock()
+	backend.hold = false
+	backend.mu.Unlock()
+	stream.turn("shorter")
+	if text, _ := stream.reply(t); text != "re: shorter" {
+		t.Errorf("Unexpected reply %q", text)
+	}
+	if prompts := backend.Prompts(); !strings.Contains(prompts[1], "Assistant: partial\nUser: shorter") {
+		t.Errorf("Expected the partial reply in context, got %q", prompts[1])
+	}
+
+	close(stream.in)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the stream to end cleanly, got %v", err)
+	}
+}
+
+func TestChat_QueuedTurnsAnsweredBeforeClose(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&chatBackend{MockBackend: MockBackend{id: "npu", healthy: true}})
+
+	stream, done, cancel := runChat(t, r)
+	defer cancel()
+
+	stream.turn("one")
+	stream.turn("two")
+	close(stream.in)
+
+	for i, want := range []string{"re: one", "re: two"} {
+		if text, final := stream.reply(t); text != want || final.Turn != int32(i+1) {
+			t.Errorf("Expected %q for turn %d, got %q (%+v)", want, i+1, text, final)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Expected the stream to end cleanly, got %v", err)
+	}
+}
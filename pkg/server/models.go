@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ModelServer implements the gRPC ModelService
+type ModelServer struct {
+	pb.UnimplementedModelServiceServer
+	manager *models.Manager
+	auth    auth.Config
+}
+
+// NewModelServer creates a model management server. Pulls and deletes
+// need an API key with the admin permission under authCfg.
+func NewModelServer(m *models.Manager, authCfg auth.Config) *ModelServer {
+	return &ModelServer{manager: m, auth: authCfg}
+}
+
+// ListModels returns the models installed on a backend, or on every
+// backend that manages models
+func (s *ModelServer) ListModels(ctx context.Context, req *pb.ListModelsRequest) (*pb.ListModelsResponse, error) {
+	list, err := s.manager.List(ctx, req.BackendId)
+	if err != nil {
+		return nil, modelError(err)
+	}
+
+	resp := &pb.ListModelsResponse{Backends: make([]*pb.BackendModels, 0, len(list))}
+	for _, entry := range list {
+		bm := &pb.BackendModels{
+			BackendId: entry.Backend,
+			Models:    make([]*pb.InstalledModel, 0, len(entry.Models)),
+			Error:     entry.Error,
+		}
+		for _, m := range entry.Models {
+			im := &pb.InstalledModel{Name: m.Name, SizeBytes: m.SizeBytes, Digest: m.Digest}
+			if !m.ModifiedAt.IsZero() {
+				im.ModifiedAtUnix = m.ModifiedAt.Unix()
+			}
+			bm.Models = append(bm.Models, im)
+		}
+		resp.Backends = append(resp.Backends, bm)
+	}
+	return resp, nil
+}
+
+// PullModel pulls a model onto a backend, streaming its progress
+func (s *ModelServer) PullModel(req *pb.PullModelRequest, stream pb.ModelService_PullModelServer) error {
+	if req.BackendId == "" || req.Model == "" {
+		return status.Error(codes.InvalidArgument, "backend_id and model are required")
+	}
+	if err := authorizeAdmin(stream.Context(), s.auth); err != nil {
+		return err
+	}
+
+	var sendErr error
+	err := s.manager.Pull(stream.Context(), req.BackendId, req.Model, func(p backends.Progress) {
+		if sendErr != nil || p.Error != "" {
+			return // a failed pull ends with the error status
+		}
+		sendErr = stream.Send(&pb.PullModelProgress{
+			Status:    p.Status,
+			Completed: p.Completed,
+			Total:     p.Total,
+			Percent:   float32(p.Percent),
+			EtaMs:     p.ETAMs,
+			Done:      p.Done,
+		})
+	})
+	if err != nil {
+		return modelError(err)
+	}
+	return sendErr
+}
+
+// DeleteModel deletes a model from a backend
+func (s *ModelServer) DeleteModel(ctx context.Context, req *pb.DeleteModelRequest) (*pb.DeleteModelResponse, error) {
+	if req.BackendId == "" || req.Model == "" {
+		return nil, status.Error(codes.InvalidArgument, "backend_id and model are required")
+	}
+	if err := authorizeAdmin(ctx, s.auth); err != nil {
+		return nil, err
+	}
+	if err := s.manager.Delete(ctx, req.BackendId, req.Model); err != nil {
+		return nil, modelError(err)
+	}
+	return &pb.DeleteModelResponse{}, nil
+}
+
+// modelError maps a model management error to a gRPC status
+func modelError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrBackendNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, backends.ErrModelsNotManaged):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/models"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pullingBackend is a MockBackend whose models can be pulled and deleted
+type pullingBackend struct {
+	*MockBackend
+	installed []string
+	pullErr   error
+}
+
+func (p *pullingBackend) ManagesModels() bool { return true }
+
+func (p *pullingBackend) InstalledModels(ctx context.Context) ([]backends.InstalledModel, error) {
+	var installed []backends.InstalledModel
+	for _, name := range p.installed {
+		installed = append(installed, backends.InstalledModel{Name: name, SizeBytes: 42, ModifiedAt: time.Unix(1700000000, 0)})
+	}
+	return installed, nil
+}
+
+func (p *pullingBackend) PullModel(ctx context.Context, model string) error {
+	if p.pullErr != nil {
+		backends.DefaultProgress.Publish(backends.Progress{Backend: p.id, Model: model, Phase: backends.ProgressPull, Done: true, Error: p.pullErr.Error()})
+		return p.pullErr
+	}
+	backends.DefaultProgress.Publish(backends.Progress{Backend: p.id, Model: model, Phase: backends.ProgressPull, Status: "pulling", Total: 200, Completed: 100, Percent: 50})
+	p.installed = append(p.installed, model)
+	backends.DefaultProgress.Publish(backends.Progress{Backend: p.id, Model: model, Phase: backends.ProgressPull, Percent: 100, Done: true})
+	return nil
+}
+
+func (p *pullingBackend) DeleteModel(ctx context.Context, model string) error {
+	return errors.New("model not found")
+}
+
+// mockPullStream records the progress sent to a PullModel client
+type mockPullStream struct {
+	grpc.ServerStream
+	sent []*pb.PullModelProgress
+}
+
+func (m *mockPullStream) Send(p *pb.PullModelProgress) error {
+	m.sent = append(m.sent, p)
+	return nil
+}
+
+func (m *mockPullStream) Context() context.Context { return context.Background() }
+
+func newTestModelServer() (*ModelServer, *pullingBackend) {
+	backend := &pullingBackend{
+		MockBackend: &MockBackend{id: "ollama-nvidia", healthy: true, supportsGenerate: true},
+		installed:   []string{"llama3:8b"},
+	}
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(backend)
+	r.RegisterBackend(&MockBackend{id: "openai", healthy: true, supportsGenerate: true})
+	return NewModelServer(models.NewManager(r), auth.Config{}), backend
+}
+
+func TestModelServer_ListModels(t *testing.T) {
+	s, _ := newTestModelServer()
+
+	resp, err := s.ListModels(context.Background(), &pb.ListModelsRequest{})
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(resp.Backends) != 1 || resp.Backends[0].BackendId != "ollama-nvidia" {
+		t.Fatalf("Expected only the managed backend, got %v", resp.Backends)
+	}
+	if m := resp.Backends[0].Models; len(m) != 1 || m[0].Name != "llama3:8b" || m[0].ModifiedAtUnix != 1700000000 {
+		t.Errorf("Unexpected models %v", m)
+	}
+
+	_, err = s.ListModels(context.Background(), &pb.ListModelsRequest{BackendId: "openai"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for an unmanaged backend, got %v", err)
+	}
+}
+
+func TestModelServer_PullModel(t *testing.T) {
+	s, backend := newTestModelServer()
+
+	stream := &mockPullStream{}
+	if err := s.PullModel(&pb.PullModelRequest{BackendId: "ollama-nvidia", Model: "mistral:7b"}, stream); err != nil {
+		t.Fatalf("PullModel failed: %v", err)
+	}
+	if len(stream.sent) != 2 || stream.sent[0].Completed != 100 || !stream.sent[1].Done {
+		t.Errorf("Expected progress then done, got %v", stream.sent)
+	}
+
+	backend.pullErr = errors.New("manifest unknown")
+	stream = &mockPullStream{}
+	err := s.PullModel(&pb.PullModelRequest{BackendId: "ollama-nvidia", Model: "nope"}, stream)
+	if status.Code(err) != codes.Unavailable || len(stream.sent) != 0 {
+		t.Errorf("Expected the failure as an error status, got %v after %v", err, stream.sent)
+	}
+
+	err = s.PullModel(&pb.PullModelRequest{BackendId: "missing", Model: "phi3"}, &mockPullStream{})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestModelServer_DeleteModel(t *testing.T) {
+	s, _ := newTestModelServer()
+
+	_, err := s.DeleteModel(context.Background(), &pb.DeleteModelRequest{BackendId: "ollama-nvidia"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without a model, got %v", err)
+	}
+	_, err = s.DeleteModel(context.Background(), &pb.DeleteModelRequest{BackendId: "ollama-nvidia", Model: "phi3"})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected the backend error, got %v", err)
+	}
+}
+
+func TestModelServer_Authorization(t *testing.T) {
+	r := router.NewRouter(router.Config{})
+	r.RegisterBackend(&pullingBackend{MockBackend: &MockBackend{id: "ollama-nvidia", healthy: true, supportsGenerate: true}})
+	keys := auth.NewKeyStore(map[string]auth.APIKeyInfo{
+		"admin-key": {Name: "ops", Permissions: []string{auth.PermissionAdmin}, Enabled: true},
+		"user-key":  {Name: "app", Permissions: []string{"inference"}, Enabled: true},
+	})
+	s := NewModelServer(models.NewManager(r), auth.Config{Enabled: true, Keys: keys})
+
+	// Listing is open to any key; pulling and deleting need admin
+	if _, err := s.ListModels(withKey("user-key"), &pb.ListModelsRequest{}); err != nil {
+		t.Errorf("Expected ListModels to be allowed, got %v", err)
+	}
+	req := &pb.DeleteModelRequest{BackendId: "ollama-nvidia", Model: "phi3"}
+	if _, err := s.DeleteModel(withKey("user-key"), req); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied deleting without admin, got %v", err)
+	}
+	if _, err := s.DeleteModel(withKey("admin-key"), req); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected the admin delete to reach the backend, got %v", err)
+	}
+	stream := &mockPullStream{}
+	if err := s.PullModel(&pb.PullModelRequest{BackendId: "ollama-nvidia", Model: "mistral:7b"}, stream); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated pulling without a key, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip" // registers the gzip codec so clients can compress requests
+	"google.golang.org/grpc/keepalive"
+)
+
+// Default message size limits. gRPC's own 4 MiB default rejects most
+// image and audio payloads, so the proxy ships with more headroom.
+const (
+	DefaultMaxRecvMsgSize = 32 * 1024 * 1024
+	DefaultMaxSendMsgSize = 32 * 1024 * 1024
+)
+
+// GRPCOptions holds tunable gRPC server settings
+type GRPCOptions struct {
+	MaxRecvMsgSize int // bytes, 0 = DefaultMaxRecvMsgSize
+	MaxSendMsgSize int // bytes, 0 = DefaultMaxSendMsgSize
+
+	// Compression forces response compression ("gzip"). Empty leaves it
+	// up to the client, which may still send gzip-compressed requests.
+	Compression string
+
+	// Server keepalive pings (0 = gRPC default)
+	KeepaliveTime     time.Duration
+	KeepaliveTimeout  time.Duration
+	MaxConnectionIdle time.Duration
+
+	// Keepalive enforcement for client pings
+	KeepaliveMinTime    time.Duration
+	PermitWithoutStream bool
+}
+
+// ServerOptions converts the settings into grpc.ServerOption values
+func (o GRPCOptions) ServerOptions() []grpc.ServerOption {
+	recv := o.MaxRecvMsgSize
+	if recv <= 0 {
+		recv = DefaultMaxRecvMsgSize
+	}
+	send := o.MaxSendMsgSize
+	if send <= 0 {
+		send = DefaultMaxSendMsgSize
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(recv),
+		grpc.MaxSendMsgSize(send),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:              o.KeepaliveTime,
+			Timeout:           o.KeepaliveTimeout,
+			MaxConnectionIdle: o.MaxConnectionIdle,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             o.KeepaliveMinTime,
+			PermitWithoutStream: o.PermitWithoutStream,
+		}),
+	}
+
+	if o.Compression != "" && o.Compression != "none" {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(compressUnary(o.Compression)),
+			grpc.ChainStreamInterceptor(compressStream(o.Compression)),
+		)
+	}
+
+	return opts
+}
+
+// compressUnary sets the response compressor for unary calls
+func compressUnary(name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		grpc.SetSendCompressor(ctx, name)
+		return handler(ctx, req)
+	}
+}
+
+// compressStream sets the response compressor for streaming calls
+func compressStream(name string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		grpc.SetSendCompressor(ss.Context(), name)
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestGRPCOptions_Defaults(t *testing.T) {
+	opts := GRPCOptions{}.ServerOptions()
+
+	// recv, send, keepalive params, enforcement policy
+	if len(opts) != 4 {
+		t.Errorf("Expected 4 server options, got %d", len(opts))
+	}
+}
+
+func TestGRPCOptions_Compression(t *testing.T) {
+	tests := []struct {
+		compression string
+		expected    int
+	}{
+		{"", 4},
+		{"none", 4},
+		{"gzip", 6},
+	}
+
+	for _, tt := range tests {
+		opts := GRPCOptions{Compression: tt.compression}.ServerOptions()
+		if len(opts) != tt.expected {
+			t.Errorf("Compression %q: expected %d options, got %d", tt.compression, tt.expected, len(opts))
+		}
+	}
+}
+
+func TestGRPCOptions_ServerStarts(t *testing.T) {
+	opts := GRPCOptions{
+		MaxRecvMsgSize: 64 * 1024 * 1024,
+		Compression:    "gzip",
+	}.ServerOptions()
+
+	srv := grpc.NewServer(opts...)
+	defer srv.Stop()
+
+	if srv == nil {
+		t.Fatal("Expected gRPC server to be created")
+	}
+}
+
+func TestCompressUnary_CallsHandler(t *testing.T) {
+	interceptor := compressUnary("gzip")
+
+	called := false
+	resp, err := interceptor(context.Background(), "req", &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return "resp", nil
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !called {
+		t.Error("Expected handler to be called")
+	}
+	if resp != "resp" {
+		t.Errorf("Expected handler response, got %v", resp)
+	}
+}
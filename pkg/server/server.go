@@ -3,14 +3,20 @@ package server
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	pb "github.com/daoneill/ollama-proxy/api/gen/go"
 	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/cache"
 	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
 	"github.com/daoneill/ollama-proxy/pkg/pipeline"
 	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
 )
 
 // ComputeServer implements the gRPC ComputeService
@@ -42,7 +48,8 @@ func (s *ComputeServer) SetPipelineExecutor(pe *pipeline.PipelineExecutor, pl *p
 
 // Generate performs text generation with intelligent routing
 func (s *ComputeServer) Generate(ctx context.Context, req *pb.GenerateRequest) (*pb.GenerateResponse, error) {
-	logging.Logger.Info("Generate request received",
+	log := logging.FromContext(ctx)
+	log.Info("Generate request received",
 		zap.String("prompt", truncate(req.Prompt, 50)),
 		zap.String("model", req.Model),
 		zap.String("target", req.Annotations.GetTarget()),
@@ -52,10 +59,16 @@ func (s *ComputeServer) Generate(ctx context.Context, req *pb.GenerateRequest) (
 
 	// Convert annotations
 	annotations := convertAnnotations(req.Annotations)
+	annotations.RequestID = middleware.GetRequestID(ctx)
+	annotations.Model = req.Model
+	annotations.PromptTokens = backends.EstimatePromptTokens(req.Prompt)
+	if req.Options != nil {
+		annotations.ContextLength = req.Options.ContextLength
+	}
 
 	// Use forwarding router if available
 	if s.forwardingRouter != nil {
-		logging.Logger.Info("Using confidence-based forwarding")
+		log.Info("Using confidence-based forwarding")
 
 		forwardingResult, err := s.forwardingRouter.GenerateWithForwarding(
 			ctx,
@@ -65,29 +78,34 @@ func (s *ComputeServer) Generate(ctx context.Context, req *pb.GenerateRequest) (
 		)
 
 		if err != nil {
-			logging.Logger.Error("Forwarding failed", zap.Error(err))
+			log.Error("Forwarding failed", zap.Error(err))
 			return nil, fmt.Errorf("forwarding failed: %w", err)
 		}
 
 		// Log forwarding details
 		if forwardingResult.Forwarded {
-			logging.Logger.Info("Request forwarded",
+			log.Info("Request forwarded",
 				zap.Int("total_attempts", forwardingResult.TotalAttempts),
 				zap.String("final_backend", forwardingResult.FinalBackend.ID()),
 				zap.Float64("confidence", forwardingResult.FinalConfidence.Overall),
 			)
 		} else {
-			logging.Logger.Info("No forwarding needed",
+			log.Info("No forwarding needed",
 				zap.String("backend", forwardingResult.FinalBackend.ID()),
 				zap.Float64("confidence", forwardingResult.FinalConfidence.Overall),
 			)
 		}
 
+		if !forwardingResult.FromCache {
+			trackUsage(ctx, &router.RoutingDecision{Backend: forwardingResult.FinalBackend}, generateMethod, req, start).
+				FinishResponse(&backends.GenerateResponse{Response: forwardingResult.FinalResponse}, nil)
+		}
+
 		// Build response with forwarding metadata
 		resp := &pb.GenerateResponse{
 			Response:    forwardingResult.FinalResponse,
 			BackendUsed: forwardingResult.FinalBackend.ID(),
-			FromCache:   false,
+			FromCache:   forwardingResult.FromCache,
 			Routing: &pb.RoutingMetadata{
 				Backend:             forwardingResult.FinalBackend.ID(),
 				Reason:              fmt.Sprintf("Confidence: %.2f", forwardingResult.FinalConfidence.Overall),
@@ -100,7 +118,7 @@ func (s *ComputeServer) Generate(ctx context.Context, req *pb.GenerateRequest) (
 		}
 
 		elapsed := time.Since(start)
-		logging.Logger.Info("Generate completed",
+		log.Info("Generate completed",
 			zap.Duration("elapsed", elapsed),
 			zap.String("backend", forwardingResult.FinalBackend.ID()),
 		)
@@ -109,37 +127,59 @@ func (s *ComputeServer) Generate(ctx context.Context, req *pb.GenerateRequest) (
 	}
 
 	// Fallback to standard routing (no forwarding)
-	logging.Logger.Info("Using standard routing", zap.String("reason", "forwarding disabled"))
+	log.Info("Using standard routing", zap.String("reason", "forwarding disabled"))
+
+	// Build backend request
+	backendReq := &backends.GenerateRequest{
+		Prompt:  req.Prompt,
+		Model:   req.Model,
+		Options: convertGenerationOptions(req.Options),
+	}
+
+	// Serve repeated requests from the response cache
+	cacheKey := ""
+	if annotations.CacheEnabled && cache.Default != nil {
+		cacheKey = cache.Key(ctx, req.Model, req.Prompt, backendReq.Options)
+		if entry, ok := cache.Default.Get(cacheKey); ok {
+			log.Info("Generate served from cache",
+				zap.String("backend", entry.BackendID),
+				zap.Duration("elapsed", time.Since(start)),
+			)
+			return &pb.GenerateResponse{
+				Response:    entry.Response,
+				BackendUsed: entry.BackendID,
+				FromCache:   true,
+				Routing: &pb.RoutingMetadata{
+					Backend: entry.BackendID,
+					Reason:  "Cache hit",
+				},
+				Stats: convertStats(entry.Stats),
+			}, nil
+		}
+	}
 
 	decision, err := s.router.RouteRequest(ctx, annotations)
 	if err != nil {
-		logging.Logger.Error("Routing failed", zap.Error(err))
+		log.Error("Routing failed", zap.Error(err))
 		return nil, fmt.Errorf("routing failed: %w", err)
 	}
 
-	logging.Logger.Info("Request routed",
+	log.Info("Request routed",
 		zap.String("backend", decision.Backend.ID()),
 		zap.String("reason", decision.Reason),
 	)
 
-	// Build backend request
-	backendReq := &backends.GenerateRequest{
-		Prompt:  req.Prompt,
-		Model:   req.Model,
-		Options: convertGenerationOptions(req.Options),
-	}
-
 	// Execute on backend
 	backendResp, err := decision.Backend.Generate(ctx, backendReq)
 	if err != nil {
-		logging.Logger.Error("Backend generation failed",
+		log.Error("Backend generation failed",
 			zap.String("backend", decision.Backend.ID()),
 			zap.Error(err),
 		)
 
 		// Try fallback
 		if fallbackDecision, fallbackErr := s.router.FallbackRequest(ctx, []string{decision.Backend.ID()}, annotations); fallbackErr == nil {
-			logging.Logger.Info("Falling back to alternative backend",
+			log.Info("Falling back to alternative backend",
 				zap.String("fallback_backend", fallbackDecision.Backend.ID()),
 			)
 			backendResp, err = fallbackDecision.Backend.Generate(ctx, backendReq)
@@ -149,15 +189,17 @@ func (s *ComputeServer) Generate(ctx context.Context, req *pb.GenerateRequest) (
 		}
 
 		if err != nil {
+			trackUsage(ctx, decision, generateMethod, req, start).FinishResponse(nil, err)
 			return nil, fmt.Errorf("generation failed: %w", err)
 		}
 	}
+	trackUsage(ctx, decision, generateMethod, req, start).FinishResponse(backendResp, nil)
 
 	// Build response
 	resp := &pb.GenerateResponse{
 		Response:    backendResp.Response,
 		BackendUsed: decision.Backend.ID(),
-		FromCache:   false, // TODO: implement caching
+		FromCache:   false,
 		Routing: &pb.RoutingMetadata{
 			Backend:             decision.Backend.ID(),
 			Reason:              decision.Reason,
@@ -168,8 +210,17 @@ func (s *ComputeServer) Generate(ctx context.Context, req *pb.GenerateRequest) (
 		Stats: convertStats(backendResp.Stats),
 	}
 
+	if cacheKey != "" {
+		cache.Default.Put(cacheKey, cache.Entry{
+			Response:  backendResp.Response,
+			Stats:     backendResp.Stats,
+			BackendID: decision.Backend.ID(),
+			Model:     req.Model,
+		})
+	}
+
 	elapsed := time.Since(start)
-	logging.Logger.Info("Generate completed",
+	log.Info("Generate completed",
 		zap.Duration("elapsed", elapsed),
 		zap.String("backend", decision.Backend.ID()),
 		zap.Float64("tokens_per_second", float64(backendResp.Stats.TokensPerSecond)),
@@ -180,47 +231,80 @@ func (s *ComputeServer) Generate(ctx context.Context, req *pb.GenerateRequest) (
 
 // GenerateStream performs streaming text generation
 func (s *ComputeServer) GenerateStream(req *pb.GenerateRequest, stream pb.ComputeService_GenerateStreamServer) error {
-	logging.Logger.Info("GenerateStream request received",
+	log := logging.FromContext(stream.Context())
+	log.Info("GenerateStream request received",
 		zap.String("prompt", truncate(req.Prompt, 50)),
 		zap.String("model", req.Model),
 		zap.String("target", req.Annotations.GetTarget()),
 	)
 
+	start := time.Now()
+
 	// Convert annotations
 	annotations := convertAnnotations(req.Annotations)
+	annotations.RequestID = middleware.GetRequestID(stream.Context())
+	annotations.Model = req.Model
+	annotations.PromptTokens = backends.EstimatePromptTokens(req.Prompt)
+	if req.Options != nil {
+		annotations.ContextLength = req.Options.ContextLength
+	}
 
 	// Route request
 	decision, err := s.router.RouteRequest(stream.Context(), annotations)
 	if err != nil {
-		logging.Logger.Error("GenerateStream routing failed", zap.Error(err))
+		log.Error("GenerateStream routing failed", zap.Error(err))
 		return fmt.Errorf("routing failed: %w", err)
 	}
 
-	logging.Logger.Info("GenerateStream routed",
+	log.Info("GenerateStream routed",
 		zap.String("backend", decision.Backend.ID()),
 		zap.String("reason", decision.Reason),
 	)
 
-	// Build backend request
+	// Build backend request; a resumed stream continues its text
 	backendReq := &backends.GenerateRequest{
-		Prompt:  req.Prompt,
+		Prompt:  req.Prompt + req.ResumeFrom,
 		Model:   req.Model,
 		Options: convertGenerationOptions(req.Options),
 	}
 
+	// Send headers before the first token when the model has to load, so
+	// clients can show progress instead of timing out. Headers are sent
+	// once, so later progress is only on the admin event stream.
+	var loading func(error)
+	if cs := decision.ColdStart(stream.Context(), req.Model); cs != nil {
+		stream.SendHeader(metadata.Pairs(
+			"x-cold-start", cs.Reason,
+			"x-cold-start-message", cs.Message(),
+			"x-cold-start-estimate-ms", strconv.FormatInt(cs.Estimate.Milliseconds(), 10),
+		))
+		loading = backends.WatchLoad(decision.Backend.ID(), cs, nil)
+	}
+
 	// Start streaming from backend
 	reader, err := decision.Backend.GenerateStream(stream.Context(), backendReq)
+	if loading != nil {
+		loading(err)
+	}
+	tracker := trackUsage(stream.Context(), decision, generateStreamMethod, req, start)
 	if err != nil {
-		logging.Logger.Error("GenerateStream backend failed",
+		tracker.FinishResponse(nil, err)
+		log.Error("GenerateStream backend failed",
 			zap.String("backend", decision.Backend.ID()),
 			zap.Error(err),
 		)
 		return fmt.Errorf("streaming failed: %w", err)
 	}
 	defer reader.Close()
+	reader = tracker.Stream(reader)
+
+	// Record what was streamed, however the stream ends
+	var streamErr error
+	defer func() { tracker.FinishResponse(nil, streamErr) }()
 
 	// Send first message with backend info
 	firstChunk := true
+	integrity := streaming.NewIntegrity(req.ResumeFrom)
 
 	// Stream chunks
 	for {
@@ -229,6 +313,7 @@ func (s *ComputeServer) GenerateStream(req *pb.GenerateRequest, stream pb.Comput
 			if err.Error() == "EOF" {
 				break
 			}
+			streamErr = err
 			return err
 		}
 
@@ -242,15 +327,23 @@ func (s *ComputeServer) GenerateStream(req *pb.GenerateRequest, stream pb.Comput
 			firstChunk = false
 		}
 
+		if chunk.Token != "" {
+			integrity.Add(chunk.Token, 1)
+		}
+		if chunk.Done {
+			resp.Integrity = convertIntegrity(integrity.Summary())
+		}
+
 		if chunk.Done && chunk.Stats != nil {
 			resp.Stats = convertStats(chunk.Stats)
-			logging.Logger.Info("GenerateStream completed",
+			log.Info("GenerateStream completed",
 				zap.String("backend", decision.Backend.ID()),
 				zap.Float64("tokens_per_second", float64(chunk.Stats.TokensPerSecond)),
 			)
 		}
 
 		if err := stream.Send(resp); err != nil {
+			streamErr = err
 			return err
 		}
 
@@ -262,14 +355,32 @@ func (s *ComputeServer) GenerateStream(req *pb.GenerateRequest, stream pb.Comput
 	return nil
 }
 
+// Methods usage and audit records are kept under
+const (
+	generateMethod       = "/compute.v1.ComputeService/Generate"
+	generateStreamMethod = "/compute.v1.ComputeService/GenerateStream"
+	embedMethod          = "/compute.v1.ComputeService/Embed"
+)
+
+// trackUsage starts accounting for a generation that began at start, in
+// the usage recorder and audit log the HTTP handlers share
+func trackUsage(ctx context.Context, decision *router.RoutingDecision, method string, req *pb.GenerateRequest, start time.Time) *usage.Tracker {
+	tracker := usage.Track(ctx, decision, method, req.Model, req.Prompt)
+	tracker.Start = start
+	return tracker
+}
+
 // Embed generates embeddings
 func (s *ComputeServer) Embed(ctx context.Context, req *pb.EmbedRequest) (*pb.EmbedResponse, error) {
-	logging.Logger.Info("Embed request received",
+	log := logging.FromContext(ctx)
+	log.Info("Embed request received",
 		zap.String("text", truncate(req.Text, 50)),
 		zap.String("model", req.Model),
 	)
 
 	annotations := convertAnnotations(req.Annotations)
+	annotations.RequestID = middleware.GetRequestID(ctx)
+	annotations.Model = req.Model
 
 	decision, err := s.router.RouteRequest(ctx, annotations)
 	if err != nil {
@@ -281,7 +392,9 @@ func (s *ComputeServer) Embed(ctx context.Context, req *pb.EmbedRequest) (*pb.Em
 		Model: req.Model,
 	}
 
+	tracker := usage.Track(ctx, decision, embedMethod, req.Model, req.Text)
 	backendResp, err := decision.Backend.Embed(ctx, backendReq)
+	tracker.Finish(0, nil, err)
 	if err != nil {
 		return nil, fmt.Errorf("embedding failed: %w", err)
 	}
@@ -368,11 +481,14 @@ func (s *ComputeServer) HealthCheck(ctx context.Context, req *pb.HealthCheckRequ
 // Helper functions
 
 func convertAnnotations(pb *pb.JobAnnotations) *backends.Annotations {
+	// The proto has no priority field; gRPC callers get normal priority so
+	// admission reservations only defer explicitly best-effort work
 	if pb == nil {
-		return &backends.Annotations{}
+		return &backends.Annotations{Priority: backends.PriorityNormal}
 	}
 
 	return &backends.Annotations{
+		Priority:              backends.PriorityNormal,
 		Target:                pb.Target,
 		LatencyCritical:       pb.LatencyCritical,
 		PreferPowerEfficiency: pb.PreferPowerEfficiency,
@@ -398,6 +514,16 @@ func convertGenerationOptions(pb *pb.GenerationOptions) *backends.GenerationOpti
 	}
 }
 
+// convertIntegrity converts a stream's integrity frame to protobuf
+func convertIntegrity(summary streaming.IntegritySummary) *pb.StreamIntegrity {
+	return &pb.StreamIntegrity{
+		Tokens:       int32(summary.Tokens),
+		Bytes:        summary.Bytes,
+		Sha256:       summary.SHA256,
+		ResumedBytes: summary.ResumedBytes,
+	}
+}
+
 func convertStats(stats *backends.GenerationStats) *pb.GenerationStats {
 	if stats == nil {
 		return nil
@@ -435,7 +561,8 @@ func truncate(s string, max int) string {
 
 // ExecutePipeline executes a multi-stage processing pipeline
 func (s *ComputeServer) ExecutePipeline(ctx context.Context, req *pb.ExecutePipelineRequest) (*pb.ExecutePipelineResponse, error) {
-	logging.Logger.Info("ExecutePipeline started",
+	log := logging.FromContext(ctx)
+	log.Info("ExecutePipeline started",
 		zap.String("pipeline_id", req.PipelineId),
 	)
 
@@ -497,6 +624,7 @@ func (s *ComputeServer) ExecutePipeline(ctx context.Context, req *pb.ExecutePipe
 				Forwarded:     stageResult.Metadata.Forwarded,
 				ForwardReason: stageResult.Metadata.ForwardReason,
 				AttemptCount:  int32(stageResult.Metadata.AttemptCount),
+				SelectedStage: stageResult.Metadata.SelectedStage,
 			}
 		}
 
@@ -531,7 +659,7 @@ func (s *ComputeServer) ExecutePipeline(ctx context.Context, req *pb.ExecutePipe
 		response.Error = result.Error.Error()
 	}
 
-	logging.Logger.Info("ExecutePipeline completed",
+	log.Info("ExecutePipeline completed",
 		zap.String("pipeline_id", req.PipelineId),
 		zap.Bool("success", result.Success),
 		zap.Int("stage_count", len(result.StageResults)),
@@ -543,7 +671,8 @@ func (s *ComputeServer) ExecutePipeline(ctx context.Context, req *pb.ExecutePipe
 
 // ExecutePipelineStream executes a pipeline with streaming output
 func (s *ComputeServer) ExecutePipelineStream(req *pb.ExecutePipelineRequest, stream pb.ComputeService_ExecutePipelineStreamServer) error {
-	logging.Logger.Info("ExecutePipelineStream started",
+	log := logging.FromContext(stream.Context())
+	log.Info("ExecutePipelineStream started",
 		zap.String("pipeline_id", req.PipelineId),
 	)
 
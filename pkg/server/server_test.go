@@ -2,20 +2,27 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc/metadata"
 
 	pb "github.com/daoneill/ollama-proxy/api/gen/go"
+	"github.com/daoneill/ollama-proxy/pkg/audit"
+	"github.com/daoneill/ollama-proxy/pkg/auth"
 	"github.com/daoneill/ollama-proxy/pkg/backends"
 	"github.com/daoneill/ollama-proxy/pkg/logging"
 	"github.com/daoneill/ollama-proxy/pkg/pipeline"
 	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/streaming"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
 )
 
 // TestMain initializes the logger for all tests
@@ -503,6 +510,128 @@ func TestGenerateStreamSuccess(t *testing.T) {
 	}
 }
 
+func TestGenerateStreamIntegrity(t *testing.T) {
+	backend := &MockBackend{
+		id:             "backend-1",
+		healthy:        true,
+		supportsStream: true,
+	}
+
+	r := router.NewRouter(router.Config{DefaultBackendID: "backend-1"})
+	r.RegisterBackend(backend)
+
+	server := NewComputeServer(r)
+
+	req := &pb.GenerateRequest{
+		Prompt:     "test prompt",
+		Model:      "test-model",
+		ResumeFrom: "a",
+	}
+
+	stream := &MockGenerateStream{
+		ctx: context.Background(),
+	}
+
+	if err := server.GenerateStream(req, stream); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	last := stream.sent[len(stream.sent)-1]
+	if !last.Done || last.Integrity == nil {
+		t.Fatalf("Expected integrity on the final message, got %+v", last)
+	}
+	want := streaming.NewIntegrity("a")
+	want.Add("test token", 2)
+	summary := want.Summary()
+	if last.Integrity.Sha256 != summary.SHA256 || last.Integrity.Tokens != 2 || last.Integrity.ResumedBytes != 1 {
+		t.Errorf("Expected integrity %+v, got %+v", summary, last.Integrity)
+	}
+}
+
+func TestGenerateRecordsUsage(t *testing.T) {
+	recorder := usage.NewRecorder(usage.DefaultConfig())
+	prev := usage.Default()
+	usage.SetDefault(recorder)
+	defer usage.SetDefault(prev)
+
+	backend := &MockBackend{id: "backend-1", healthy: true, supportsGenerate: true, supportsStream: true, streamEOF: true}
+	r := router.NewRouter(router.Config{DefaultBackendID: "backend-1"})
+	r.RegisterBackend(backend)
+	server := NewComputeServer(r)
+
+	// The authz interceptor stores the calling key
+	ctx := auth.WithKeyInfo(context.Background(), auth.APIKeyInfo{Name: "grpc-key"})
+	req := &pb.GenerateRequest{Prompt: "test prompt", Model: "test-model"}
+	if _, err := server.Generate(ctx, req); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if err := server.GenerateStream(req, &MockGenerateStream{ctx: ctx}); err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	records := recorder.Query(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if len(records) != 2 {
+		t.Fatalf("Expected both calls recorded, got %d", len(records))
+	}
+	for i, endpoint := range []string{"/compute.v1.ComputeService/Generate", "/compute.v1.ComputeService/GenerateStream"} {
+		rec := records[i]
+		if rec.Key != "grpc-key" || rec.Endpoint != endpoint || rec.Backend != "backend-1" || rec.Status != "success" {
+			t.Errorf("Unexpected record: %+v", rec)
+		}
+		if rec.PromptTokens == 0 || rec.CompletionTokens == 0 {
+			t.Errorf("Expected token counts, got %+v", rec)
+		}
+	}
+
+	// Failed generations count against the key too
+	backend.generateErr = errors.New("backend down")
+	server.Generate(ctx, req)
+	if records := recorder.Query(time.Now().Add(-time.Minute), time.Now().Add(time.Minute)); len(records) != 3 || records[2].Status != "error" {
+		t.Errorf("Expected the failure recorded, got %+v", records)
+	}
+}
+
+func TestGenerateAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.NewLogger(audit.Config{Path: path})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer auditLog.Close()
+	audit.SetDefault(auditLog)
+	defer audit.SetDefault(nil)
+
+	backend := &MockBackend{id: "backend-1", healthy: true, supportsGenerate: true, supportsEmbed: true}
+	r := router.NewRouter(router.Config{DefaultBackendID: "backend-1"})
+	r.RegisterBackend(backend)
+	server := NewComputeServer(r)
+
+	ctx := auth.WithKeyInfo(context.Background(), auth.APIKeyInfo{Name: "grpc-key"})
+	if _, err := server.Generate(ctx, &pb.GenerateRequest{Prompt: "test prompt", Model: "test-model"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	backend.embedErr = errors.New("embedding model missing")
+	server.Embed(ctx, &pb.EmbedRequest{Text: "embed me", Model: "embed-model"})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected two audit records, got %q", data)
+	}
+	var gen, embed audit.Record
+	json.Unmarshal([]byte(lines[0]), &gen)
+	json.Unmarshal([]byte(lines[1]), &embed)
+	if gen.Key != "grpc-key" || gen.Endpoint != "/compute.v1.ComputeService/Generate" || gen.Status != "success" || gen.PromptHash == "" {
+		t.Errorf("Unexpected Generate audit record: %+v", gen)
+	}
+	if embed.Endpoint != "/compute.v1.ComputeService/Embed" || embed.Status != "error" || embed.Error == "" {
+		t.Errorf("Unexpected Embed audit record: %+v", embed)
+	}
+}
+
 func TestGenerateStreamBackendError(t *testing.T) {
 	backend := &MockBackend{
 		id:             "backend-1",
@@ -1400,6 +1529,10 @@ func TestConvertAnnotationsNil(t *testing.T) {
 	if result.Target != "" {
 		t.Error("Expected empty target")
 	}
+
+	if result.Priority != backends.PriorityNormal {
+		t.Errorf("Expected normal priority, got %v", result.Priority)
+	}
 }
 
 func TestConvertGenerationOptions(t *testing.T) {
@@ -0,0 +1,415 @@
+// Package session pins multi-turn conversations to the backend that served
+// their earlier turns, so the backend's KV cache for the conversation prefix
+// is reused instead of being rebuilt on whichever backend the router picks
+// next. Conversations are identified by the request's session_id (or user)
+// field, scoped to the caller's tenant.
+//
+// Sessions also track how interactive they are: each turn that follows the
+// previous one within MaxTurnGap adds to a score that decays with HalfLife.
+// With boosting enabled, turns of sessions scoring above the threshold are
+// raised a priority level, so a user chatting gets ahead of batch work
+// queued on the same backend until they go quiet.
+package session
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+)
+
+const (
+	// DefaultTTL is how long an idle session stays pinned
+	DefaultTTL = 30 * time.Minute
+
+	// DefaultMaxSessions bounds the session table
+	DefaultMaxSessions = 10000
+
+	// DefaultMaxTurnGap is the longest pause between turns that still
+	// counts as interactive
+	DefaultMaxTurnGap = 2 * time.Minute
+
+	// DefaultHalfLife is how quickly a session's interactivity decays
+	DefaultHalfLife = 5 * time.Minute
+
+	// DefaultBoostThreshold is the interactivity a session needs to be
+	// boosted: about three quick turns in a row
+	DefaultBoostThreshold = 1.5
+)
+
+// Lookup results reported in metrics
+const (
+	ResultHit      = "hit"      // session pinned, routed to its backend
+	ResultMiss     = "miss"     // new or expired session
+	ResultOverride = "override" // X-Target-Backend replaced the pinned backend
+	ResultRepinned = "repinned" // pinned backend unavailable, moved to another
+)
+
+// Eviction reasons reported in metrics
+const (
+	EvictExpired  = "expired"
+	EvictCapacity = "capacity"
+	EvictRemoved  = "removed"
+)
+
+// Config configures the session manager
+type Config struct {
+	TTL         time.Duration // idle time before a session expires
+	MaxSessions int           // least recently used sessions are evicted beyond this
+
+	// Boost raises the priority of interactive sessions
+	Boost BoostConfig
+}
+
+// BoostConfig configures the interactivity score and priority boost
+type BoostConfig struct {
+	Enabled    bool
+	MaxTurnGap time.Duration // turns closer together than this count as interactive
+	HalfLife   time.Duration // time for the score to halve
+	Threshold  float64       // score at which turns are boosted
+}
+
+// Key identifies a conversation within a tenant
+type Key struct {
+	Tenant string
+	ID     string
+}
+
+// Session is a pinned conversation
+type Session struct {
+	Tenant    string    `json:"tenant,omitempty"`
+	ID        string    `json:"id"`
+	BackendID string    `json:"backend_id"`
+	Turns     int       `json:"turns"`
+	Created   time.Time `json:"created"`
+	LastSeen  time.Time `json:"last_seen"`
+
+	// Interactivity is the score as of LastSeen
+	Interactivity float64 `json:"interactivity"`
+}
+
+// Stats summarizes session affinity
+type Stats struct {
+	Active    int            `json:"active"`
+	Lookups   map[string]int `json:"lookups"`
+	Evictions map[string]int `json:"evictions"`
+	Boosts    int            `json:"boosts"`
+}
+
+// Manager maps sessions to backends with TTL expiry and LRU eviction. A nil
+// *Manager is valid and pins nothing.
+type Manager struct {
+	cfg Config
+	now func() time.Time
+
+	mu        sync.Mutex
+	ll        *list.List            // front = most recently used
+	items     map[Key]*list.Element // key -> element holding *Session
+	lookups   map[string]int
+	evictions map[string]int
+	boosts    int
+}
+
+// Default is the process-wide session manager (nil = affinity disabled)
+var Default *Manager
+
+// SetDefault replaces the process-wide session manager
+func SetDefault(m *Manager) {
+	Default = m
+}
+
+// New creates a session manager
+func New(cfg Config) *Manager {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	if cfg.MaxSessions <= 0 {
+		cfg.MaxSessions = DefaultMaxSessions
+	}
+	if cfg.Boost.MaxTurnGap <= 0 {
+		cfg.Boost.MaxTurnGap = DefaultMaxTurnGap
+	}
+	if cfg.Boost.HalfLife <= 0 {
+		cfg.Boost.HalfLife = DefaultHalfLife
+	}
+	if cfg.Boost.Threshold <= 0 {
+		cfg.Boost.Threshold = DefaultBoostThreshold
+	}
+	return &Manager{
+		cfg:       cfg,
+		now:       time.Now,
+		ll:        list.New(),
+		items:     make(map[Key]*list.Element),
+		lookups:   make(map[string]int),
+		evictions: make(map[string]int),
+	}
+}
+
+// KeyFor scopes a client-supplied session ID to the caller's tenant, so
+// one tenant cannot steer another tenant's conversation
+func KeyFor(ctx context.Context, id string) Key {
+	return Key{Tenant: auth.TenantFromContext(ctx), ID: id}
+}
+
+// Lookup returns the backend a session is pinned to. Expired sessions are
+// dropped and reported as a miss.
+func (m *Manager) Lookup(key Key) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if ok && m.expired(el.Value.(*Session)) {
+		m.removeElement(el, EvictExpired)
+		ok = false
+	}
+	if !ok {
+		m.recordLookup(ResultMiss)
+		return "", false
+	}
+
+	m.ll.MoveToFront(el)
+	return el.Value.(*Session).BackendID, true
+}
+
+// Pin records that backendID served the session's latest turn, refreshing
+// its TTL. result is the lookup outcome for this turn when it differs from
+// a plain hit or miss (ResultOverride or ResultRepinned), otherwise "".
+func (m *Manager) Pin(key Key, backendID, result string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if el, ok := m.items[key]; ok {
+		s := el.Value.(*Session)
+		s.BackendID = backendID
+		s.Turns++
+		s.Interactivity = m.interactivity(s, now)
+		if now.Sub(s.LastSeen) <= m.cfg.Boost.MaxTurnGap {
+			s.Interactivity++
+		}
+		s.LastSeen = now
+		m.ll.MoveToFront(el)
+		if result == "" {
+			result = ResultHit
+		}
+		m.recordLookup(result)
+		return
+	}
+
+	if result != "" {
+		m.recordLookup(result)
+	}
+	m.items[key] = m.ll.PushFront(&Session{
+		Tenant:    key.Tenant,
+		ID:        key.ID,
+		BackendID: backendID,
+		Turns:     1,
+		Created:   now,
+		LastSeen:  now,
+	})
+	for m.ll.Len() > m.cfg.MaxSessions {
+		m.removeElement(m.ll.Back(), EvictCapacity)
+	}
+	metrics.SetSessionsActive(m.ll.Len())
+}
+
+// Boosted reports whether a session's next turn should be raised a
+// priority level: boosting is enabled and the session's interactivity,
+// decayed to now, has reached the threshold
+func (m *Manager) Boosted(key Key) bool {
+	if m == nil || !m.cfg.Boost.Enabled {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok || m.expired(el.Value.(*Session)) {
+		return false
+	}
+	if m.interactivity(el.Value.(*Session), m.now()) < m.cfg.Boost.Threshold {
+		return false
+	}
+	m.boosts++
+	metrics.RecordSessionBoost()
+	return true
+}
+
+// Remove ends a session
+func (m *Manager) Remove(key Key) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if ok {
+		m.removeElement(el, EvictRemoved)
+	}
+	return ok
+}
+
+// Sweep drops expired sessions and returns how many were removed
+func (m *Manager) Sweep() int {
+	if m == nil {
+		return 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	// Least recently used sessions are at the back and expire first
+	for el := m.ll.Back(); el != nil; {
+		prev := el.Prev()
+		if !m.expired(el.Value.(*Session)) {
+			break
+		}
+		m.removeElement(el, EvictExpired)
+		removed++
+		el = prev
+	}
+	return removed
+}
+
+// RunJanitor sweeps expired sessions every interval until ctx is done
+func (m *Manager) RunJanitor(ctx context.Context, interval time.Duration) {
+	if m == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			middleware.Safe(middleware.ScopeBackground, "session-janitor", func() { m.Sweep() })
+		}
+	}
+}
+
+// Sessions returns the live sessions, most recently used first
+func (m *Manager) Sessions() []Session {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions := make([]Session, 0, m.ll.Len())
+	for el := m.ll.Front(); el != nil; el = el.Next() {
+		s := el.Value.(*Session)
+		if !m.expired(s) {
+			sessions = append(sessions, *s)
+		}
+	}
+	return sessions
+}
+
+// Stats returns session counters
+func (m *Manager) Stats() Stats {
+	if m == nil {
+		return Stats{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := Stats{
+		Active:    m.ll.Len(),
+		Lookups:   make(map[string]int, len(m.lookups)),
+		Evictions: make(map[string]int, len(m.evictions)),
+		Boosts:    m.boosts,
+	}
+	for k, v := range m.lookups {
+		stats.Lookups[k] = v
+	}
+	for k, v := range m.evictions {
+		stats.Evictions[k] = v
+	}
+	return stats
+}
+
+// Handler serves session stats (GET) and ends a session
+// (DELETE ?tenant=&id=)
+func (m *Manager) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodDelete:
+			key := Key{Tenant: r.URL.Query().Get("tenant"), ID: r.URL.Query().Get("id")}
+			if key.ID == "" {
+				http.Error(w, "id is required", http.StatusBadRequest)
+				return
+			}
+			if !m.Remove(key) {
+				http.Error(w, "session not found", http.StatusNotFound)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		response := map[string]interface{}{
+			"ttl_seconds":   int64(m.cfg.TTL.Seconds()),
+			"max_sessions":  m.cfg.MaxSessions,
+			"boost_enabled": m.cfg.Boost.Enabled,
+			"stats":         m.Stats(),
+			"sessions":      m.Sessions(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (m *Manager) expired(s *Session) bool {
+	return m.now().Sub(s.LastSeen) > m.cfg.TTL
+}
+
+// interactivity returns a session's score decayed to at
+func (m *Manager) interactivity(s *Session, at time.Time) float64 {
+	elapsed := at.Sub(s.LastSeen)
+	if elapsed <= 0 {
+		return s.Interactivity
+	}
+	return s.Interactivity * math.Exp2(-elapsed.Seconds()/m.cfg.Boost.HalfLife.Seconds())
+}
+
+// removeElement drops a session; callers hold m.mu
+func (m *Manager) removeElement(el *list.Element, reason string) {
+	s := el.Value.(*Session)
+	m.ll.Remove(el)
+	delete(m.items, Key{Tenant: s.Tenant, ID: s.ID})
+	m.evictions[reason]++
+	metrics.RecordSessionEviction(reason)
+	metrics.SetSessionsActive(m.ll.Len())
+}
+
+// recordLookup counts a lookup result; callers hold m.mu
+func (m *Manager) recordLookup(result string) {
+	m.lookups[result]++
+	metrics.RecordSessionLookup(result)
+}
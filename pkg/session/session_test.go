@@ -0,0 +1,222 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+)
+
+// newTestManager returns a manager with a controllable clock
+func newTestManager(cfg Config) (*Manager, *time.Time) {
+	m := New(cfg)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func TestManager_PinAndLookup(t *testing.T) {
+	m, _ := newTestManager(Config{})
+	key := Key{Tenant: "team-a", ID: "conv-1"}
+
+	if _, ok := m.Lookup(key); ok {
+		t.Fatal("Expected miss for a new session")
+	}
+	m.Pin(key, "ollama-igpu", "")
+
+	backendID, ok := m.Lookup(key)
+	if !ok || backendID != "ollama-igpu" {
+		t.Fatalf("Expected pinned backend ollama-igpu, got %q %v", backendID, ok)
+	}
+	m.Pin(key, "ollama-igpu", "")
+
+	sessions := m.Sessions()
+	if len(sessions) != 1 || sessions[0].Turns != 2 {
+		t.Errorf("Expected one session with 2 turns, got %+v", sessions)
+	}
+	stats := m.Stats()
+	if stats.Lookups[ResultMiss] != 1 || stats.Lookups[ResultHit] != 1 {
+		t.Errorf("Unexpected lookup stats: %+v", stats.Lookups)
+	}
+}
+
+func TestManager_InteractiveBoost(t *testing.T) {
+	m, now := newTestManager(Config{Boost: BoostConfig{Enabled: true}})
+	key := Key{ID: "chat"}
+
+	// Quick turns build up interactivity
+	for i := 0; i < 3; i++ {
+		if m.Boosted(key) {
+			t.Fatalf("Turn %d boosted before the session was interactive", i+1)
+		}
+		m.Pin(key, "ollama-igpu", "")
+		*now = now.Add(20 * time.Second)
+	}
+	if !m.Boosted(key) {
+		t.Fatalf("Expected boost after quick turns, interactivity %.2f", m.Sessions()[0].Interactivity)
+	}
+
+	// It decays while the user is away
+	*now = now.Add(DefaultHalfLife)
+	if m.Boosted(key) {
+		t.Error("Expected boost to decay after a pause")
+	}
+
+	// A slow turn adds nothing
+	*now = now.Add(DefaultMaxTurnGap + time.Second)
+	m.Pin(key, "ollama-igpu", "")
+	if m.Boosted(key) {
+		t.Error("Expected no boost after a slow turn")
+	}
+	if m.Stats().Boosts != 1 {
+		t.Errorf("Expected one boost recorded, got %d", m.Stats().Boosts)
+	}
+}
+
+func TestManager_BoostDisabled(t *testing.T) {
+	m, now := newTestManager(Config{})
+	key := Key{ID: "chat"}
+	for i := 0; i < 5; i++ {
+		m.Pin(key, "ollama-igpu", "")
+		*now = now.Add(5 * time.Second)
+	}
+	if m.Boosted(key) {
+		t.Error("Expected no boost when boosting is disabled")
+	}
+	if m.Sessions()[0].Interactivity < DefaultBoostThreshold {
+		t.Error("Expected interactivity to be tracked regardless")
+	}
+}
+
+func TestManager_TenantScoped(t *testing.T) {
+	m, _ := newTestManager(Config{})
+	m.Pin(Key{Tenant: "team-a", ID: "conv-1"}, "ollama-npu", "")
+
+	if _, ok := m.Lookup(Key{Tenant: "team-b", ID: "conv-1"}); ok {
+		t.Error("Sessions must not be shared between tenants")
+	}
+}
+
+func TestManager_TTLExpiry(t *testing.T) {
+	m, now := newTestManager(Config{TTL: time.Minute})
+	key := Key{ID: "conv-1"}
+	m.Pin(key, "ollama-npu", "")
+
+	*now = now.Add(30 * time.Second)
+	if _, ok := m.Lookup(key); !ok {
+		t.Fatal("Expected session within TTL")
+	}
+	m.Pin(key, "ollama-npu", "") // refreshes the TTL
+
+	*now = now.Add(50 * time.Second)
+	if _, ok := m.Lookup(key); !ok {
+		t.Fatal("Expected TTL to be refreshed by the last turn")
+	}
+
+	*now = now.Add(2 * time.Minute)
+	if _, ok := m.Lookup(key); ok {
+		t.Fatal("Expected session to expire")
+	}
+	if m.Stats().Evictions[EvictExpired] != 1 {
+		t.Errorf("Expected expired eviction, got %+v", m.Stats().Evictions)
+	}
+}
+
+func TestManager_Sweep(t *testing.T) {
+	m, now := newTestManager(Config{TTL: time.Minute})
+	m.Pin(Key{ID: "old"}, "a", "")
+	*now = now.Add(45 * time.Second)
+	m.Pin(Key{ID: "new"}, "b", "")
+
+	*now = now.Add(30 * time.Second)
+	if removed := m.Sweep(); removed != 1 {
+		t.Fatalf("Expected 1 expired session, removed %d", removed)
+	}
+	if _, ok := m.Lookup(Key{ID: "new"}); !ok {
+		t.Error("Sweep removed a live session")
+	}
+}
+
+func TestManager_CapacityEviction(t *testing.T) {
+	m, _ := newTestManager(Config{MaxSessions: 2})
+	m.Pin(Key{ID: "1"}, "a", "")
+	m.Pin(Key{ID: "2"}, "a", "")
+	m.Lookup(Key{ID: "1"}) // 2 is now least recently used
+	m.Pin(Key{ID: "3"}, "a", "")
+
+	if _, ok := m.Lookup(Key{ID: "2"}); ok {
+		t.Error("Expected least recently used session to be evicted")
+	}
+	if _, ok := m.Lookup(Key{ID: "1"}); !ok {
+		t.Error("Expected recently used session to survive")
+	}
+	if m.Stats().Evictions[EvictCapacity] != 1 {
+		t.Errorf("Expected capacity eviction, got %+v", m.Stats().Evictions)
+	}
+}
+
+func TestManager_PinResults(t *testing.T) {
+	m, _ := newTestManager(Config{})
+	key := Key{ID: "conv-1"}
+	m.Pin(key, "a", "")
+	m.Pin(key, "b", ResultOverride)
+	m.Pin(key, "c", ResultRepinned)
+
+	backendID, _ := m.Lookup(key)
+	if backendID != "c" {
+		t.Errorf("Expected session re-pinned to c, got %s", backendID)
+	}
+	stats := m.Stats()
+	if stats.Lookups[ResultOverride] != 1 || stats.Lookups[ResultRepinned] != 1 {
+		t.Errorf("Unexpected lookup stats: %+v", stats.Lookups)
+	}
+}
+
+func TestManager_NilSafe(t *testing.T) {
+	var m *Manager
+	m.Pin(Key{ID: "x"}, "a", "")
+	if _, ok := m.Lookup(Key{ID: "x"}); ok {
+		t.Error("Nil manager must not pin")
+	}
+}
+
+func TestKeyFor(t *testing.T) {
+	ctx := auth.WithKeyInfo(context.Background(), auth.APIKeyInfo{Name: "key-1", Tenant: "team-a"})
+	if key := KeyFor(ctx, "conv-1"); key.Tenant != "team-a" || key.ID != "conv-1" {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+}
+
+func TestManager_Handler(t *testing.T) {
+	m, _ := newTestManager(Config{})
+	m.Pin(Key{Tenant: "team-a", ID: "conv-1"}, "a", "")
+
+	w := httptest.NewRecorder()
+	m.Handler()(w, httptest.NewRequest(http.MethodGet, "/admin/sessions", nil))
+	var body struct {
+		Stats    Stats     `json:"stats"`
+		Sessions []Session `json:"sessions"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if body.Stats.Active != 1 || len(body.Sessions) != 1 || body.Sessions[0].BackendID != "a" {
+		t.Errorf("Unexpected response: %+v", body)
+	}
+
+	w = httptest.NewRecorder()
+	m.Handler()(w, httptest.NewRequest(http.MethodDelete, "/admin/sessions?tenant=team-a&id=conv-1", nil))
+	if w.Code != http.StatusOK || m.Stats().Active != 0 {
+		t.Errorf("Expected session removed, got %d active=%d", w.Code, m.Stats().Active)
+	}
+
+	w = httptest.NewRecorder()
+	m.Handler()(w, httptest.NewRequest(http.MethodDelete, "/admin/sessions?id=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown session, got %d", w.Code)
+	}
+}
@@ -0,0 +1,315 @@
+// Package speculative implements speculative decoding across backends: a
+// small, cheap model drafts a few tokens and the routed model checks them
+// all in one forward pass, keeping the longest prefix it agrees with, in
+// fewer of the routed model's (expensive) forward passes. Both sides see
+// the request's prompt raw, without the model's chat template, so replies
+// can differ from the same request decoded normally.
+package speculative
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+)
+
+const (
+	// DefaultDraftTokens is how many tokens are drafted per round
+	DefaultDraftTokens = 8
+	// DefaultMaxTokens caps generation when the request sets no limit
+	DefaultMaxTokens = 256
+)
+
+// Config for speculative decoding
+type Config struct {
+	DraftModel  string // small model on the draft backend
+	DraftTokens int    // per round, default DefaultDraftTokens
+	MaxTokens   int    // default DefaultMaxTokens
+}
+
+// Stats of a speculatively decoded request
+type Stats struct {
+	Rounds         int // verifier forward passes
+	DraftedTokens  int // in the verifier's tokenizer
+	AcceptedTokens int
+}
+
+// AcceptanceRate is the share of drafted tokens the verifier kept
+func (s Stats) AcceptanceRate() float64 {
+	if s.DraftedTokens == 0 {
+		return 0
+	}
+	return float64(s.AcceptedTokens) / float64(s.DraftedTokens)
+}
+
+// Decoder drafts on one backend for verification on another
+type Decoder struct {
+	draft backends.Backend
+	cfg   Config
+}
+
+// NewDecoder creates a decoder drafting with cfg.DraftModel on draft
+func NewDecoder(draft backends.Backend, cfg Config) *Decoder {
+	if cfg.DraftTokens <= 0 {
+		cfg.DraftTokens = DefaultDraftTokens
+	}
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = DefaultMaxTokens
+	}
+	return &Decoder{draft: draft, cfg: cfg}
+}
+
+// Draft returns the backend drafts are generated on
+func (d *Decoder) Draft() backends.Backend {
+	return d.draft
+}
+
+// Wrap returns verifier decoding speculatively
+func (d *Decoder) Wrap(verifier backends.Backend) *Backend {
+	return &Backend{Backend: verifier, decoder: d}
+}
+
+// Backend decodes Generate and GenerateStream requests speculatively, with
+// the embedded backend verifying. Requests go straight to it when it can't
+// verify drafts or the draft backend is unhealthy.
+type Backend struct {
+	backends.Backend // verifier
+	decoder          *Decoder
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// Stats returns the speculative decoding stats of the requests so far
+func (b *Backend) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stats
+}
+
+// DraftID returns the backend drafts are generated on
+func (b *Backend) DraftID() string {
+	return b.decoder.draft.ID()
+}
+
+func (b *Backend) speculating() bool {
+	return backends.SupportsDraftVerification(b.Backend) && b.decoder.draft.IsHealthy()
+}
+
+// Generate decodes req speculatively
+func (b *Backend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	if !b.speculating() {
+		return b.Backend.Generate(ctx, req)
+	}
+
+	start := time.Now()
+	s := b.newSession(req)
+	for !s.done {
+		if _, err := s.round(ctx); err != nil {
+			if s.out.Len() == 0 && s.draftFailed {
+				return b.Backend.Generate(ctx, req)
+			}
+			return nil, err
+		}
+	}
+	return &backends.GenerateResponse{
+		Response: s.out.String(),
+		Stats:    s.generationStats(start),
+	}, nil
+}
+
+// GenerateStream decodes req speculatively, streaming the text accepted in
+// each round as a chunk
+func (b *Backend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	if !b.speculating() {
+		return b.Backend.GenerateStream(ctx, req)
+	}
+	return &stream{ctx: ctx, session: b.newSession(req), start: time.Now()}, nil
+}
+
+// session is the state of one speculatively decoded request
+type session struct {
+	b         *Backend
+	req       *backends.GenerateRequest
+	maxTokens int
+	stop      []string
+
+	out         strings.Builder
+	tokens      int
+	done        bool
+	draftFailed bool
+}
+
+func (b *Backend) newSession(req *backends.GenerateRequest) *session {
+	s := &session{b: b, req: req, maxTokens: b.decoder.cfg.MaxTokens}
+	if req.Options != nil {
+		if req.Options.MaxTokens > 0 {
+			s.maxTokens = int(req.Options.MaxTokens)
+		}
+		s.stop = req.Options.Stop
+	}
+	return s
+}
+
+// round drafts tokens, has the verifier check them and returns the text
+// it accepted
+func (s *session) round(ctx context.Context) (string, error) {
+	d := s.b.decoder
+	prompt := s.req.Prompt + s.out.String()
+
+	draft, err := s.draft(ctx, prompt)
+	if err != nil {
+		s.draftFailed = true
+		return "", fmt.Errorf("drafting on %s failed: %w", d.draft.ID(), err)
+	}
+
+	verdict, err := backends.VerifyDraft(ctx, s.b.Backend, &backends.GenerateRequest{
+		Prompt: prompt,
+		Model:  s.req.Model,
+	}, draft)
+	if err != nil {
+		return "", fmt.Errorf("verifying draft failed: %w", err)
+	}
+
+	s.b.mu.Lock()
+	s.b.stats.Rounds++
+	s.b.stats.DraftedTokens += verdict.DraftTokens
+	s.b.stats.AcceptedTokens += verdict.AcceptedTokens
+	s.b.mu.Unlock()
+	metrics.RecordSpeculativeTokens(d.draft.ID(), s.b.Backend.ID(), verdict.AcceptedTokens, verdict.DraftTokens-verdict.AcceptedTokens)
+
+	text := verdict.Accepted + verdict.Next
+	s.tokens += verdict.AcceptedTokens
+	if verdict.Next != "" {
+		s.tokens++
+	}
+	s.done = verdict.Done || text == "" || s.tokens >= s.maxTokens
+
+	// Stop sequences may span rounds, so look for them in the whole output
+	before := s.out.Len()
+	s.out.WriteString(text)
+	if i, ok := indexStop(s.out.String(), s.stop); ok {
+		full := s.out.String()
+		s.out.Reset()
+		s.out.WriteString(full[:i])
+		s.done = true
+		if i < before {
+			return "", nil
+		}
+		return full[before:i], nil
+	}
+	return text, nil
+}
+
+// draft greedily generates up to DraftTokens tokens continuing prompt. The
+// draft backend may not honour a token limit, so the stream is closed once
+// enough chunks arrived.
+func (s *session) draft(ctx context.Context, prompt string) (string, error) {
+	d := s.b.decoder
+	reader, err := d.draft.GenerateStream(ctx, &backends.GenerateRequest{
+		Prompt: prompt,
+		Model:  d.cfg.DraftModel,
+		Options: &backends.GenerationOptions{
+			MaxTokens: int32(d.cfg.DraftTokens),
+			TopK:      1,
+			Raw:       true,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var draft strings.Builder
+	for i := 0; i < d.cfg.DraftTokens; i++ {
+		chunk, err := reader.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		draft.WriteString(chunk.Token)
+		if chunk.Done {
+			break
+		}
+	}
+	return draft.String(), nil
+}
+
+func (s *session) generationStats(start time.Time) *backends.GenerationStats {
+	elapsed := time.Since(start)
+	stats := &backends.GenerationStats{
+		TotalTimeMs:     int32(elapsed.Milliseconds()),
+		TokensGenerated: int32(s.tokens),
+	}
+	if elapsed > 0 {
+		stats.TokensPerSecond = float32(float64(s.tokens) / elapsed.Seconds())
+	}
+	return stats
+}
+
+// indexStop returns where the first stop sequence in text begins
+func indexStop(text string, stop []string) (int, bool) {
+	first, found := len(text), false
+	for _, seq := range stop {
+		if seq == "" {
+			continue
+		}
+		if i := strings.Index(text, seq); i >= 0 && i < first {
+			first, found = i, true
+		}
+	}
+	return first, found
+}
+
+// stream runs a round per Recv
+type stream struct {
+	ctx     context.Context
+	session *session
+	start   time.Time
+	closed  bool
+
+	// The verifier's own stream, when drafting failed before any output
+	fallback backends.StreamReader
+}
+
+func (st *stream) Recv() (*backends.StreamChunk, error) {
+	if st.fallback != nil {
+		return st.fallback.Recv()
+	}
+	s := st.session
+	for !st.closed && !s.done {
+		text, err := s.round(st.ctx)
+		if err != nil {
+			if s.out.Len() == 0 && s.draftFailed {
+				if st.fallback, err = s.b.Backend.GenerateStream(st.ctx, s.req); err != nil {
+					return nil, err
+				}
+				return st.fallback.Recv()
+			}
+			return nil, err
+		}
+		if text != "" {
+			return &backends.StreamChunk{Token: text}, nil
+		}
+	}
+	if st.closed {
+		return nil, io.EOF
+	}
+	st.closed = true
+	return &backends.StreamChunk{Done: true, Stats: s.generationStats(st.start)}, nil
+}
+
+func (st *stream) Close() error {
+	st.closed = true
+	if st.fallback != nil {
+		return st.fallback.Close()
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package speculative
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+)
+
+const prompt = "Count:"
+
+// words splits text into tokens of one word with its leading space
+func words(text string) []string {
+	var tokens []string
+	for _, w := range strings.Fields(text) {
+		tokens = append(tokens, " "+w)
+	}
+	return tokens
+}
+
+// draftBackend continues prompts with its guess of the output, word for
+// word
+type draftBackend struct {
+	backends.Backend
+	guess string
+	err   error
+	reqs  []*backends.GenerateRequest
+}
+
+func (d *draftBackend) ID() string      { return "npu" }
+func (d *draftBackend) IsHealthy() bool { return true }
+
+func (d *draftBackend) GenerateStream(ctx context.Context, req *backends.GenerateRequest) (backends.StreamReader, error) {
+	d.reqs = append(d.reqs, req)
+	if d.err != nil {
+		return nil, d.err
+	}
+	// Continue the guess from the word the output has reached
+	guess := words(d.guess)
+	if n := len(words(req.Prompt[len(prompt):])); n < len(guess) {
+		guess = guess[n:]
+	} else {
+		guess = nil
+	}
+	return &tokenStream{tokens: guess}, nil
+}
+
+type tokenStream struct {
+	tokens []string
+}
+
+func (s *tokenStream) Recv() (*backends.StreamChunk, error) {
+	if len(s.tokens) == 0 {
+		return nil, io.EOF
+	}
+	tok := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	return &backends.StreamChunk{Token: tok}, nil
+}
+
+func (s *tokenStream) Close() error { return nil }
+
+// verifierBackend greedily generates target
+type verifierBackend struct {
+	backends.Backend
+	target    string
+	verify    bool
+	rounds    int
+	generated bool
+}
+
+func (v *verifierBackend) ID() string                      { return "nvidia" }
+func (v *verifierBackend) SupportsDraftVerification() bool { return v.verify }
+
+func (v *verifierBackend) Generate(ctx context.Context, req *backends.GenerateRequest) (*backends.GenerateResponse, error) {
+	v.generated = true
+	return &backends.GenerateResponse{Response: v.target}, nil
+}
+
+func (v *verifierBackend) VerifyDraft(ctx context.Context, req *backends.GenerateRequest, draft string) (*backends.DraftVerdict, error) {
+	v.rounds++
+	remaining := words((prompt + v.target)[len(req.Prompt):])
+	verdict := &backends.DraftVerdict{}
+	for i, tok := range words(draft) {
+		verdict.DraftTokens++
+		if verdict.Next != "" {
+			continue
+		}
+		if i >= len(remaining) || tok != remaining[i] {
+			if i < len(remaining) {
+				verdict.Next = remaining[i]
+			} else {
+				verdict.Done = true
+			}
+			continue
+		}
+		verdict.Accepted += tok
+		verdict.AcceptedTokens++
+	}
+	if verdict.Next == "" && !verdict.Done {
+		if verdict.AcceptedTokens < len(remaining) {
+			verdict.Next = remaining[verdict.AcceptedTokens]
+		} else {
+			verdict.Done = true
+		}
+	}
+	return verdict, nil
+}
+
+func TestBackend_GenerateMatchesVerifier(t *testing.T) {
+	draft := &draftBackend{guess: " one two tree four five six seven eight"}
+	verifier := &verifierBackend{target: " one two three four five six", verify: true}
+	b := NewDecoder(draft, Config{DraftModel: "qwen2.5:0.5b", DraftTokens: 4}).Wrap(verifier)
+
+	resp, err := b.Generate(context.Background(), &backends.GenerateRequest{Prompt: prompt, Model: "llama3:70b"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Response != verifier.target {
+		t.Errorf("Expected the verifier's output %q, got %q", verifier.target, resp.Response)
+	}
+	if resp.Stats.TokensGenerated != 6 {
+		t.Errorf("Expected 6 tokens, got %d", resp.Stats.TokensGenerated)
+	}
+
+	// Round 1 keeps " one two" and corrects " tree", round 2 accepts
+	// " four five six" and finds the end
+	stats := b.Stats()
+	if stats.Rounds != 2 || verifier.rounds != 2 {
+		t.Errorf("Expected 2 rounds, got %+v", stats)
+	}
+	if stats.AcceptedTokens != 5 || stats.DraftedTokens != 8 {
+		t.Errorf("Expected 5 of 8 draft tokens accepted, got %+v", stats)
+	}
+	for _, req := range draft.reqs {
+		if req.Model != "qwen2.5:0.5b" || !req.Options.Raw || req.Options.TopK != 1 {
+			t.Errorf("Expected greedy raw drafts with the draft model, got %+v %+v", req, req.Options)
+		}
+	}
+}
+
+func TestBackend_StopsAtLimits(t *testing.T) {
+	draft := &draftBackend{guess: " one two three four five six"}
+	verifier := &verifierBackend{target: " one two three four five six", verify: true}
+	b := NewDecoder(draft, Config{DraftModel: "small", DraftTokens: 2}).Wrap(verifier)
+
+	resp, err := b.Generate(context.Background(), &backends.GenerateRequest{
+		Prompt:  prompt,
+		Options: &backends.GenerationOptions{Stop: []string{" four"}},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Response != " one two three" {
+		t.Errorf("Expected output cut at the stop sequence, got %q", resp.Response)
+	}
+
+	resp, err = b.Generate(context.Background(), &backends.GenerateRequest{
+		Prompt:  prompt,
+		Options: &backends.GenerationOptions{MaxTokens: 3},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Response != " one two three" {
+		t.Errorf("Expected output cut at max tokens, got %q", resp.Response)
+	}
+}
+
+func TestBackend_Stream(t *testing.T) {
+	draft := &draftBackend{guess: " one two tree four five six"}
+	verifier := &verifierBackend{target: " one two three four five six", verify: true}
+	b := NewDecoder(draft, Config{DraftModel: "small", DraftTokens: 4}).Wrap(verifier)
+
+	reader, err := b.GenerateStream(context.Background(), &backends.GenerateRequest{Prompt: prompt})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	defer reader.Close()
+
+	var out strings.Builder
+	var chunks int
+	for {
+		chunk, err := reader.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if chunk.Done {
+			break
+		}
+		out.WriteString(chunk.Token)
+		chunks++
+	}
+	if out.String() != verifier.target || chunks != 2 {
+		t.Errorf("Expected the output in one chunk per round, got %q in %d", out.String(), chunks)
+	}
+	if _, err := reader.Recv(); err != io.EOF {
+		t.Errorf("Expected EOF after the last chunk, got %v", err)
+	}
+}
+
+func TestBackend_FallsBack(t *testing.T) {
+	// The verifier can't score drafts
+	draft := &draftBackend{guess: " one"}
+	verifier := &verifierBackend{target: " one two"}
+	b := NewDecoder(draft, Config{DraftModel: "small"}).Wrap(verifier)
+	if _, err := b.Generate(context.Background(), &backends.GenerateRequest{Prompt: prompt}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !verifier.generated || len(draft.reqs) != 0 {
+		t.Error("Expected plain generation on the verifier")
+	}
+
+	// Drafting fails before any output
+	draft = &draftBackend{err: errors.New("npu offline")}
+	verifier = &verifierBackend{target: " one two", verify: true}
+	b = NewDecoder(draft, Config{DraftModel: "small"}).Wrap(verifier)
+	resp, err := b.Generate(context.Background(), &backends.GenerateRequest{Prompt: prompt})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !verifier.generated || resp.Response != verifier.target {
+		t.Errorf("Expected plain generation after a draft failure, got %q", resp.Response)
+	}
+}
@@ -0,0 +1,73 @@
+package streaming
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+)
+
+// Integrity hashes the content a stream sends, for a final frame that lets
+// clients detect a stream cut short or corrupted by a proxy restart or a
+// network reset. A stream that ends without the frame was truncated; one
+// whose content doesn't match it was corrupted.
+type Integrity struct {
+	hash    hash.Hash
+	tokens  int
+	bytes   int64
+	resumed int64
+}
+
+// IntegritySummary is the final frame's content. Bytes and SHA256 cover
+// the whole content, including text resumed from an earlier stream;
+// Tokens counts only the tokens this stream sent.
+type IntegritySummary struct {
+	Tokens       int    `json:"tokens"`
+	Bytes        int64  `json:"bytes"`
+	SHA256       string `json:"sha256"`
+	ResumedBytes int64  `json:"resumed_bytes,omitempty"`
+}
+
+// NewIntegrity starts hashing a stream that continues resumed, the text a
+// client received before its earlier stream broke ("" for a new stream)
+func NewIntegrity(resumed string) *Integrity {
+	i := &Integrity{hash: sha256.New()}
+	i.hash.Write([]byte(resumed))
+	i.bytes = int64(len(resumed))
+	i.resumed = i.bytes
+	return i
+}
+
+// Add records a frame of text carrying tokens tokens as sent
+func (i *Integrity) Add(text string, tokens int) {
+	i.hash.Write([]byte(text))
+	i.bytes += int64(len(text))
+	i.tokens += tokens
+}
+
+// Summary returns the frame describing everything sent so far
+func (i *Integrity) Summary() IntegritySummary {
+	return IntegritySummary{
+		Tokens:       i.tokens,
+		Bytes:        i.bytes,
+		SHA256:       hex.EncodeToString(i.hash.Sum(nil)),
+		ResumedBytes: i.resumed,
+	}
+}
+
+type resumedKey struct{}
+
+// WithResumed records on ctx the text a client received before its stream
+// broke, which the new stream continues
+func WithResumed(ctx context.Context, text string) context.Context {
+	if text == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, resumedKey{}, text)
+}
+
+// Resumed returns the text recorded by WithResumed, "" for a new stream
+func Resumed(ctx context.Context) string {
+	text, _ := ctx.Value(resumedKey{}).(string)
+	return text
+}
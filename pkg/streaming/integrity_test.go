@@ -0,0 +1,62 @@
+package streaming
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestIntegrity_Summary(t *testing.T) {
+	integrity := NewIntegrity("")
+	integrity.Add("Hello", 1)
+	integrity.Add(", world", 2)
+
+	summary := integrity.Summary()
+	if summary.Tokens != 3 {
+		t.Errorf("Expected 3 tokens, got %d", summary.Tokens)
+	}
+	if summary.Bytes != int64(len("Hello, world")) {
+		t.Errorf("Expected %d bytes, got %d", len("Hello, world"), summary.Bytes)
+	}
+	if summary.SHA256 != sha256Hex("Hello, world") {
+		t.Errorf("Expected sha256 of the content, got %s", summary.SHA256)
+	}
+	if summary.ResumedBytes != 0 {
+		t.Errorf("Expected no resumed bytes, got %d", summary.ResumedBytes)
+	}
+}
+
+func TestIntegrity_Resumed(t *testing.T) {
+	integrity := NewIntegrity("Hello")
+	integrity.Add(", world", 2)
+
+	summary := integrity.Summary()
+	if summary.Tokens != 2 {
+		t.Errorf("Expected only this stream's 2 tokens, got %d", summary.Tokens)
+	}
+	if summary.ResumedBytes != 5 || summary.Bytes != 12 {
+		t.Errorf("Expected 5 resumed of 12 bytes, got %d of %d", summary.ResumedBytes, summary.Bytes)
+	}
+	if summary.SHA256 != sha256Hex("Hello, world") {
+		t.Errorf("Expected sha256 of the whole content, got %s", summary.SHA256)
+	}
+}
+
+func TestWithResumed(t *testing.T) {
+	ctx := context.Background()
+	if Resumed(ctx) != "" {
+		t.Errorf("Expected no resumed text on a new context")
+	}
+	if WithResumed(ctx, "") != ctx {
+		t.Errorf("Expected empty text to leave the context unchanged")
+	}
+	if got := Resumed(WithResumed(ctx, "partial")); got != "partial" {
+		t.Errorf("Expected resumed text 'partial', got %q", got)
+	}
+}
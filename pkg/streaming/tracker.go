@@ -0,0 +1,363 @@
+package streaming
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config controls bandwidth-aware pacing for token streams
+type Config struct {
+	// Enabled turns on token batching for constrained links.
+	// Stats are always collected so the debug endpoint stays useful.
+	Enabled bool
+
+	// SlowWriteThreshold is the smoothed write latency above which a
+	// client link is considered constrained
+	SlowWriteThreshold time.Duration
+
+	// MaxBatchTokens caps how many tokens are merged into one frame
+	MaxBatchTokens int
+
+	// MaxBatchDelay caps how long tokens may be held back before a flush
+	MaxBatchDelay time.Duration
+
+	// KeepaliveInterval is how long an SSE stream may stay silent before a
+	// keepalive comment is sent (negative disables keepalives)
+	KeepaliveInterval time.Duration
+
+	// IdleTimeout ends a stream whose backend sends nothing for this long
+	// (0 = no limit)
+	IdleTimeout time.Duration
+}
+
+// DefaultConfig returns conservative pacing defaults
+func DefaultConfig() Config {
+	return Config{
+		Enabled:            false,
+		SlowWriteThreshold: 50 * time.Millisecond,
+		MaxBatchTokens:     8,
+		MaxBatchDelay:      250 * time.Millisecond,
+		KeepaliveInterval:  15 * time.Second,
+	}
+}
+
+// ewmaAlpha weights the most recent write when smoothing write latency
+const ewmaAlpha = 0.2
+
+// StreamStats is a point-in-time view of a single stream
+type StreamStats struct {
+	ID            string    `json:"id"`
+	Transport     string    `json:"transport"` // "sse" or "websocket"
+	RemoteAddr    string    `json:"remote_addr,omitempty"`
+	Model         string    `json:"model,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	DurationMs    int64     `json:"duration_ms"`
+	Frames        int64     `json:"frames"`
+	Tokens        int64     `json:"tokens"`
+	BatchedTokens int64     `json:"batched_tokens"`
+	BytesSent     int64     `json:"bytes_sent"`
+	AvgWriteMs    float64   `json:"avg_write_ms"`
+	MaxWriteMs    float64   `json:"max_write_ms"`
+	BytesPerSec   float64   `json:"bytes_per_sec"`
+	Constrained   bool      `json:"constrained"`
+	Active        bool      `json:"active"`
+}
+
+// Tracker keeps pacing state for all active streams
+type Tracker struct {
+	mu      sync.RWMutex
+	cfg     Config
+	streams map[string]*Stream
+	recent  []StreamStats // recently closed streams, newest last
+	seq     uint64
+
+	maxRecent int
+}
+
+// NewTracker creates a new stream tracker
+func NewTracker(cfg Config) *Tracker {
+	defaults := DefaultConfig()
+	if cfg.SlowWriteThreshold <= 0 {
+		cfg.SlowWriteThreshold = defaults.SlowWriteThreshold
+	}
+	if cfg.MaxBatchTokens <= 0 {
+		cfg.MaxBatchTokens = defaults.MaxBatchTokens
+	}
+	if cfg.MaxBatchDelay <= 0 {
+		cfg.MaxBatchDelay = defaults.MaxBatchDelay
+	}
+	if cfg.KeepaliveInterval == 0 {
+		cfg.KeepaliveInterval = defaults.KeepaliveInterval
+	}
+
+	return &Tracker{
+		cfg:       cfg,
+		streams:   make(map[string]*Stream),
+		maxRecent: 50,
+	}
+}
+
+// Default is the process-wide tracker used by the HTTP streaming handlers
+var Default = NewTracker(DefaultConfig())
+
+// SetDefault replaces the process-wide tracker
+func SetDefault(t *Tracker) {
+	if t != nil {
+		Default = t
+	}
+}
+
+// Config returns the tracker configuration
+func (t *Tracker) Config() Config {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.cfg
+}
+
+// Open registers a new stream
+func (t *Tracker) Open(transport, remoteAddr, model string) *Stream {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seq++
+	s := &Stream{
+		tracker:    t,
+		id:         fmt.Sprintf("%s-%d", transport, t.seq),
+		transport:  transport,
+		remoteAddr: remoteAddr,
+		model:      model,
+		startedAt:  time.Now(),
+	}
+	t.streams[s.id] = s
+	return s
+}
+
+// Snapshot returns stats for active streams followed by recently closed ones
+func (t *Tracker) Snapshot() []StreamStats {
+	t.mu.RLock()
+	active := make([]*Stream, 0, len(t.streams))
+	for _, s := range t.streams {
+		active = append(active, s)
+	}
+	recent := make([]StreamStats, len(t.recent))
+	copy(recent, t.recent)
+	t.mu.RUnlock()
+
+	out := make([]StreamStats, 0, len(active)+len(recent))
+	for _, s := range active {
+		out = append(out, s.Stats())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].StartedAt.Before(out[j].StartedAt)
+	})
+
+	return append(out, recent...)
+}
+
+// ActiveCount returns the number of open streams
+func (t *Tracker) ActiveCount() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.streams)
+}
+
+// Handler serves the streams debug endpoint
+func (t *Tracker) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := t.Config()
+		response := map[string]interface{}{
+			"bandwidth_aware":         cfg.Enabled,
+			"slow_write_threshold_ms": cfg.SlowWriteThreshold.Milliseconds(),
+			"max_batch_tokens":        cfg.MaxBatchTokens,
+			"max_batch_delay_ms":      cfg.MaxBatchDelay.Milliseconds(),
+			"active":                  t.ActiveCount(),
+			"streams":                 t.Snapshot(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// remove drops a closed stream and keeps its final stats
+func (t *Tracker) remove(s *Stream, final StreamStats) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.streams, s.id)
+	t.recent = append(t.recent, final)
+	if len(t.recent) > t.maxRecent {
+		t.recent = t.recent[len(t.recent)-t.maxRecent:]
+	}
+}
+
+// Stream tracks send pacing for a single client connection
+type Stream struct {
+	tracker    *Tracker
+	id         string
+	transport  string
+	remoteAddr string
+	model      string
+	startedAt  time.Time
+
+	mu            sync.Mutex
+	frames        int64
+	tokens        int64
+	batchedTokens int64
+	bytesSent     int64
+	writeTime     time.Duration
+	avgWrite      time.Duration // EWMA of write latency
+	maxWrite      time.Duration
+	closed        bool
+
+	// Pending batch
+	pending      strings.Builder
+	pendingCount int
+	pendingSince time.Time
+}
+
+// ID returns the stream identifier
+func (s *Stream) ID() string {
+	return s.id
+}
+
+// RecordWrite records one frame written to the client
+func (s *Stream) RecordWrite(bytes int, tokens int, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frames++
+	s.tokens += int64(tokens)
+	s.bytesSent += int64(bytes)
+	s.writeTime += d
+	if d > s.maxWrite {
+		s.maxWrite = d
+	}
+
+	if s.frames == 1 {
+		s.avgWrite = d
+	} else {
+		s.avgWrite = time.Duration(ewmaAlpha*float64(d) + (1-ewmaAlpha)*float64(s.avgWrite))
+	}
+}
+
+// Constrained reports whether the link looks slow enough to batch tokens
+func (s *Stream) Constrained() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.constrainedLocked()
+}
+
+func (s *Stream) constrainedLocked() bool {
+	return s.frames > 0 && s.avgWrite >= s.tracker.cfg.SlowWriteThreshold
+}
+
+// Batch offers a token to the pacer. It returns the text to send and the
+// number of tokens it carries; a zero count means the token is being held
+// back until the batch fills up. Final chunks always flush.
+func (s *Stream) Batch(token string, done bool) (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg := s.tracker.cfg
+	if !cfg.Enabled || (!s.constrainedLocked() && s.pendingCount == 0) {
+		return token, 1
+	}
+
+	if s.pendingCount == 0 {
+		s.pendingSince = time.Now()
+	}
+	s.pending.WriteString(token)
+	s.pendingCount++
+
+	if !done && s.pendingCount < cfg.MaxBatchTokens && time.Since(s.pendingSince) < cfg.MaxBatchDelay {
+		return "", 0
+	}
+
+	return s.flushLocked()
+}
+
+// Flush returns the tokens held back, and their count, for sending when
+// the batch is due but no token arrives, or before reporting an error
+func (s *Stream) Flush() (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+func (s *Stream) flushLocked() (string, int) {
+	count := s.pendingCount
+	if count > 1 {
+		s.batchedTokens += int64(count)
+	}
+	text := s.pending.String()
+	s.pending.Reset()
+	s.pendingCount = 0
+	return text, count
+}
+
+// Pending returns the number of tokens currently held back
+func (s *Stream) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pendingCount
+}
+
+// FlushDue returns how long until the tokens held back have waited
+// MaxBatchDelay and must be flushed, and false when none are held back.
+// Batch only checks the delay as tokens arrive, so a stream whose backend
+// stalls sets a timer with it.
+func (s *Stream) FlushDue() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pendingCount == 0 {
+		return 0, false
+	}
+	return max(s.tracker.cfg.MaxBatchDelay-time.Since(s.pendingSince), 0), true
+}
+
+// Stats returns a snapshot of the stream counters
+func (s *Stream) Stats() StreamStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Since(s.startedAt)
+	stats := StreamStats{
+		ID:            s.id,
+		Transport:     s.transport,
+		RemoteAddr:    s.remoteAddr,
+		Model:         s.model,
+		StartedAt:     s.startedAt,
+		DurationMs:    elapsed.Milliseconds(),
+		Frames:        s.frames,
+		Tokens:        s.tokens,
+		BatchedTokens: s.batchedTokens,
+		BytesSent:     s.bytesSent,
+		AvgWriteMs:    float64(s.avgWrite) / float64(time.Millisecond),
+		MaxWriteMs:    float64(s.maxWrite) / float64(time.Millisecond),
+		Constrained:   s.constrainedLocked(),
+		Active:        !s.closed,
+	}
+	if s.writeTime > 0 {
+		stats.BytesPerSec = float64(s.bytesSent) / s.writeTime.Seconds()
+	}
+	return stats
+}
+
+// Close unregisters the stream
+func (s *Stream) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.tracker.remove(s, s.Stats())
+}
@@ -0,0 +1,229 @@
+package streaming
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewTracker_Defaults(t *testing.T) {
+	tr := NewTracker(Config{Enabled: true})
+	cfg := tr.Config()
+
+	defaults := DefaultConfig()
+	if cfg.SlowWriteThreshold != defaults.SlowWriteThreshold {
+		t.Errorf("Expected slow write threshold %v, got %v", defaults.SlowWriteThreshold, cfg.SlowWriteThreshold)
+	}
+	if cfg.MaxBatchTokens != defaults.MaxBatchTokens {
+		t.Errorf("Expected max batch tokens %d, got %d", defaults.MaxBatchTokens, cfg.MaxBatchTokens)
+	}
+	if cfg.MaxBatchDelay != defaults.MaxBatchDelay {
+		t.Errorf("Expected max batch delay %v, got %v", defaults.MaxBatchDelay, cfg.MaxBatchDelay)
+	}
+	if !cfg.Enabled {
+		t.Error("Expected Enabled to be preserved")
+	}
+}
+
+func TestTracker_OpenClose(t *testing.T) {
+	tr := NewTracker(DefaultConfig())
+
+	s1 := tr.Open("sse", "", "llama3")
+	s2 := tr.Open("websocket", "127.0.0.1:1234", "llama3")
+
+	if s1.ID() == s2.ID() {
+		t.Errorf("Expected unique stream IDs, got %s twice", s1.ID())
+	}
+	if tr.ActiveCount() != 2 {
+		t.Errorf("Expected 2 active streams, got %d", tr.ActiveCount())
+	}
+
+	s1.Close()
+	s1.Close() // double close is a no-op
+
+	if tr.ActiveCount() != 1 {
+		t.Errorf("Expected 1 active stream, got %d", tr.ActiveCount())
+	}
+
+	snap := tr.Snapshot()
+	if len(snap) != 2 {
+		t.Fatalf("Expected 2 streams in snapshot, got %d", len(snap))
+	}
+	if snap[0].ID != s2.ID() || !snap[0].Active {
+		t.Errorf("Expected active stream %s first, got %+v", s2.ID(), snap[0])
+	}
+	if snap[1].ID != s1.ID() || snap[1].Active {
+		t.Errorf("Expected closed stream %s last, got %+v", s1.ID(), snap[1])
+	}
+}
+
+func TestStream_RecordWrite(t *testing.T) {
+	tr := NewTracker(Config{SlowWriteThreshold: 10 * time.Millisecond})
+	s := tr.Open("sse", "", "")
+
+	s.RecordWrite(100, 1, time.Millisecond)
+	if s.Constrained() {
+		t.Error("Expected fast link not to be constrained")
+	}
+
+	s.RecordWrite(100, 1, 100*time.Millisecond)
+	if !s.Constrained() {
+		t.Error("Expected slow write to mark link constrained")
+	}
+
+	stats := s.Stats()
+	if stats.Frames != 2 {
+		t.Errorf("Expected 2 frames, got %d", stats.Frames)
+	}
+	if stats.BytesSent != 200 {
+		t.Errorf("Expected 200 bytes sent, got %d", stats.BytesSent)
+	}
+	if stats.MaxWriteMs != 100 {
+		t.Errorf("Expected max write 100ms, got %v", stats.MaxWriteMs)
+	}
+	if stats.BytesPerSec <= 0 {
+		t.Errorf("Expected positive throughput, got %v", stats.BytesPerSec)
+	}
+}
+
+func TestStream_BatchDisabled(t *testing.T) {
+	tr := NewTracker(Config{Enabled: false, SlowWriteThreshold: time.Millisecond})
+	s := tr.Open("sse", "", "")
+	s.RecordWrite(10, 1, time.Second)
+
+	token, count := s.Batch("hello", false)
+	if token != "hello" || count != 1 {
+		t.Errorf("Expected passthrough when disabled, got %q/%d", token, count)
+	}
+}
+
+func TestStream_BatchUnconstrained(t *testing.T) {
+	tr := NewTracker(Config{Enabled: true, SlowWriteThreshold: time.Second})
+	s := tr.Open("sse", "", "")
+	s.RecordWrite(10, 1, time.Millisecond)
+
+	token, count := s.Batch("hello", false)
+	if token != "hello" || count != 1 {
+		t.Errorf("Expected passthrough on fast link, got %q/%d", token, count)
+	}
+}
+
+func TestStream_BatchConstrained(t *testing.T) {
+	tr := NewTracker(Config{
+		Enabled:            true,
+		SlowWriteThreshold: time.Millisecond,
+		MaxBatchTokens:     3,
+		MaxBatchDelay:      time.Hour,
+	})
+	s := tr.Open("websocket", "", "")
+	s.RecordWrite(10, 1, 10*time.Millisecond)
+
+	if _, count := s.Batch("a", false); count != 0 {
+		t.Errorf("Expected first token held back, got count %d", count)
+	}
+	if _, count := s.Batch("b", false); count != 0 {
+		t.Errorf("Expected second token held back, got count %d", count)
+	}
+	if s.Pending() != 2 {
+		t.Errorf("Expected 2 pending tokens, got %d", s.Pending())
+	}
+
+	token, count := s.Batch("c", false)
+	if token != "abc" || count != 3 {
+		t.Errorf("Expected batch flush at max tokens, got %q/%d", token, count)
+	}
+	if s.Pending() != 0 {
+		t.Errorf("Expected no pending tokens after flush, got %d", s.Pending())
+	}
+
+	s.Batch("d", false)
+	token, count = s.Batch("", true)
+	if token != "d" || count != 2 {
+		t.Errorf("Expected final chunk to flush, got %q/%d", token, count)
+	}
+
+	if stats := s.Stats(); stats.BatchedTokens != 5 {
+		t.Errorf("Expected 5 batched tokens, got %d", stats.BatchedTokens)
+	}
+}
+
+func TestStream_BatchMaxDelay(t *testing.T) {
+	tr := NewTracker(Config{
+		Enabled:            true,
+		SlowWriteThreshold: time.Millisecond,
+		MaxBatchTokens:     100,
+		MaxBatchDelay:      time.Millisecond,
+	})
+	s := tr.Open("sse", "", "")
+	s.RecordWrite(10, 1, 10*time.Millisecond)
+
+	s.Batch("a", false)
+	time.Sleep(5 * time.Millisecond)
+
+	token, count := s.Batch("b", false)
+	if token != "ab" || count != 2 {
+		t.Errorf("Expected flush after max delay, got %q/%d", token, count)
+	}
+}
+
+func TestStream_FlushDue(t *testing.T) {
+	tr := NewTracker(Config{
+		Enabled:            true,
+		SlowWriteThreshold: time.Millisecond,
+		MaxBatchTokens:     100,
+		MaxBatchDelay:      time.Second,
+	})
+	s := tr.Open("sse", "", "")
+	s.RecordWrite(10, 1, 10*time.Millisecond)
+
+	if _, due := s.FlushDue(); due {
+		t.Error("Expected no flush due with nothing held back")
+	}
+	s.Batch("a", false)
+	s.Batch("b", false)
+	if d, due := s.FlushDue(); !due || d <= 0 || d > time.Second {
+		t.Errorf("Expected a flush due within the max delay, got %v/%v", d, due)
+	}
+
+	// A stalled backend sends nothing more, so the timer flushes
+	token, count := s.Flush()
+	if token != "ab" || count != 2 || s.Pending() != 0 {
+		t.Errorf("Expected the held tokens flushed, got %q/%d", token, count)
+	}
+	if _, count := s.Flush(); count != 0 {
+		t.Errorf("Expected nothing left to flush, got %d", count)
+	}
+}
+
+func TestTracker_Handler(t *testing.T) {
+	tr := NewTracker(Config{Enabled: true})
+	s := tr.Open("sse", "", "llama3")
+	defer s.Close()
+
+	rec := httptest.NewRecorder()
+	tr.Handler()(rec, httptest.NewRequest("GET", "/debug/streams", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected application/json, got %s", ct)
+	}
+
+	var body struct {
+		BandwidthAware bool          `json:"bandwidth_aware"`
+		Active         int           `json:"active"`
+		Streams        []StreamStats `json:"streams"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if !body.BandwidthAware {
+		t.Error("Expected bandwidth_aware to be true")
+	}
+	if body.Active != 1 || len(body.Streams) != 1 {
+		t.Errorf("Expected 1 active stream, got active=%d streams=%d", body.Active, len(body.Streams))
+	}
+	if len(body.Streams) == 1 && body.Streams[0].Model != "llama3" {
+		t.Errorf("Expected model llama3, got %s", body.Streams[0].Model)
+	}
+}
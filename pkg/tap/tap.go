@@ -0,0 +1,302 @@
+// Package tap streams live routing decisions and completed requests to
+// debugging clients over a WebSocket, like tcpdump for the proxy. Events
+// are summaries: names, sizes and timings, never prompts, responses,
+// headers or key secrets. Each client is capped to a rate and the number
+// of clients is bounded, so a busy proxy does not spend itself describing
+// its own traffic.
+package tap
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	proxyws "github.com/daoneill/ollama-proxy/pkg/http/websocket"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+// Defaults for Config
+const (
+	DefaultRate       = 20 // events per second per client
+	DefaultMaxClients = 4
+)
+
+// Event types
+const (
+	EventDecision = "decision" // a routing decision, made before the backend runs
+	EventRequest  = "request"  // a completed request
+)
+
+const (
+	// clientBuffer is how many events a client can fall behind by before
+	// further ones are dropped
+	clientBuffer = 64
+
+	// writeWait bounds a single event write to a client
+	writeWait = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	CheckOrigin:     proxyws.CheckOrigin,
+}
+
+// Config caps what the tap may cost
+type Config struct {
+	Rate       float64 // events per second sent to each client
+	MaxClients int     // concurrent clients, more get 503
+}
+
+// Event is a sanitized summary of a decision or request
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	RequestID string    `json:"request_id,omitempty"`
+	Key       string    `json:"key,omitempty"` // API key name, never the key itself
+	Tenant    string    `json:"tenant,omitempty"`
+	Endpoint  string    `json:"endpoint,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	Backend   string    `json:"backend,omitempty"`
+	Hardware  string    `json:"hardware,omitempty"`
+	Reason    string    `json:"reason,omitempty"` // why the backend was chosen
+	Status    string    `json:"status,omitempty"` // "success" or "error"
+	Error     string    `json:"error,omitempty"`
+
+	PromptTokens     int64 `json:"prompt_tokens,omitempty"`
+	CompletionTokens int64 `json:"completion_tokens,omitempty"`
+	DurationMs       int64 `json:"duration_ms,omitempty"`
+
+	// Dropped counts events matching the client's filter that were not
+	// sent since the previous one, over the rate cap or while it was slow
+	Dropped int64 `json:"dropped,omitempty"`
+}
+
+// Filter selects events by model, backend, API key name and type. Empty
+// fields match everything; models match case-insensitively.
+type Filter struct {
+	Model   string
+	Backend string
+	Key     string
+	Type    string
+}
+
+// Match reports whether e passes the filter
+func (f Filter) Match(e *Event) bool {
+	return (f.Model == "" || strings.EqualFold(f.Model, e.Model)) &&
+		(f.Backend == "" || f.Backend == e.Backend) &&
+		(f.Key == "" || f.Key == e.Key) &&
+		(f.Type == "" || f.Type == e.Type)
+}
+
+// client is one connected tap
+type client struct {
+	filter  Filter
+	limiter *rate.Limiter
+	events  chan Event
+	dropped int64 // guarded by Tap.mu
+}
+
+// Tap fans events out to connected clients
+type Tap struct {
+	cfg Config
+
+	active  atomic.Int32 // connected clients, checked before building events
+	mu      sync.Mutex
+	clients map[*client]struct{}
+}
+
+// New creates a tap
+func New(cfg Config) *Tap {
+	if cfg.Rate <= 0 {
+		cfg.Rate = DefaultRate
+	}
+	if cfg.MaxClients <= 0 {
+		cfg.MaxClients = DefaultMaxClients
+	}
+	return &Tap{cfg: cfg, clients: make(map[*client]struct{})}
+}
+
+// Active reports whether any client is connected
+func (t *Tap) Active() bool {
+	return t.active.Load() > 0
+}
+
+// Publish sends e to every client whose filter it matches. It never
+// blocks: events over a client's rate, or that it is too slow to take,
+// are counted as dropped.
+func (t *Tap) Publish(e Event) {
+	if !t.Active() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for c := range t.clients {
+		if !c.filter.Match(&e) {
+			continue
+		}
+		if !c.limiter.Allow() {
+			c.dropped++
+			continue
+		}
+		sent := e
+		sent.Dropped = c.dropped
+		select {
+		case c.events <- sent:
+			c.dropped = 0
+		default:
+			c.dropped++
+		}
+	}
+}
+
+// ObserveDecision publishes a routing decision. It is a
+// router.DecisionObserver.
+func (t *Tap) ObserveDecision(rec *router.DecisionRecord) {
+	if !t.Active() {
+		return
+	}
+	e := Event{
+		Time:      time.Now(),
+		Type:      EventDecision,
+		RequestID: rec.Annotations.RequestID,
+		Key:       rec.Key,
+		Model:     rec.Annotations.Model,
+		Status:    "success",
+	}
+	if rec.Err != nil {
+		e.Status = "error"
+		e.Error = rec.Err.Error()
+	}
+	if dec := rec.Decision; dec != nil {
+		if dec.ModelUsed != "" {
+			e.Model = dec.ModelUsed
+		}
+		e.Backend = dec.Backend.ID()
+		e.Hardware = dec.Backend.Hardware()
+		e.Reason = dec.Reason
+	}
+	t.Publish(e)
+}
+
+// ObserveUsage publishes a completed request. It is a usage.Recorder
+// subscriber.
+func (t *Tap) ObserveUsage(rec usage.Record) {
+	if !t.Active() {
+		return
+	}
+	t.Publish(Event{
+		Time:             rec.Time,
+		Type:             EventRequest,
+		RequestID:        rec.RequestID,
+		Key:              rec.Key,
+		Tenant:           rec.Tenant,
+		Endpoint:         rec.Endpoint,
+		Model:            rec.Model,
+		Backend:          rec.Backend,
+		Hardware:         rec.Hardware,
+		Status:           rec.Status,
+		PromptTokens:     rec.PromptTokens,
+		CompletionTokens: rec.CompletionTokens,
+		DurationMs:       rec.DurationMs,
+	})
+}
+
+// subscribe adds a client, nil when MaxClients are already connected
+func (t *Tap) subscribe(f Filter) *client {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.clients) >= t.cfg.MaxClients {
+		return nil
+	}
+	burst := int(t.cfg.Rate)
+	if burst < 1 {
+		burst = 1
+	}
+	c := &client{
+		filter:  f,
+		limiter: rate.NewLimiter(rate.Limit(t.cfg.Rate), burst),
+		events:  make(chan Event, clientBuffer),
+	}
+	t.clients[c] = struct{}{}
+	t.active.Add(1)
+	return c
+}
+
+func (t *Tap) unsubscribe(c *client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.clients[c]; ok {
+		delete(t.clients, c)
+		t.active.Add(-1)
+	}
+}
+
+// Handler streams matching events to a WebSocket client until it
+// disconnects, one JSON object per message. The query parameters model,
+// backend, key and type filter the events. Clients send nothing.
+func (t *Tap) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		filter := Filter{
+			Model:   q.Get("model"),
+			Backend: q.Get("backend"),
+			Key:     q.Get("key"),
+			Type:    q.Get("type"),
+		}
+		switch filter.Type {
+		case "", EventDecision, EventRequest:
+		default:
+			http.Error(w, "type must be decision or request", http.StatusBadRequest)
+			return
+		}
+
+		c := t.subscribe(filter)
+		if c == nil {
+			http.Error(w, "Too many taps connected", http.StatusServiceUnavailable)
+			return
+		}
+		defer t.unsubscribe(c)
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // the upgrader has replied
+		}
+		defer conn.Close()
+
+		// The request's read deadline would end the connection; reads only
+		// watch for the client going away
+		conn.SetReadDeadline(time.Time{})
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-gone:
+				return
+			case e := <-c.events:
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := conn.WriteJSON(e); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
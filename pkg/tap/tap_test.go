@@ -0,0 +1,114 @@
+package tap
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+	"github.com/daoneill/ollama-proxy/pkg/usage"
+	"github.com/gorilla/websocket"
+)
+
+type fakeBackend struct {
+	backends.Backend
+	id string
+}
+
+func (f *fakeBackend) ID() string       { return f.id }
+func (f *fakeBackend) Hardware() string { return "nvidia" }
+
+func decision(model, backend, key string) *router.DecisionRecord {
+	return &router.DecisionRecord{
+		Annotations: backends.Annotations{Model: model},
+		Decision:    &router.RoutingDecision{Backend: &fakeBackend{id: backend}, Reason: "lowest latency"},
+		Key:         key,
+	}
+}
+
+func TestTap_Publish(t *testing.T) {
+	tp := New(Config{Rate: 2, MaxClients: 2})
+	if tp.Active() {
+		t.Error("Expected an idle tap")
+	}
+	tp.ObserveDecision(decision("llama3", "ollama-nvidia", "alice")) // nobody listening
+
+	all := tp.subscribe(Filter{})
+	alice := tp.subscribe(Filter{Model: "LLAMA3", Key: "alice"})
+	if tp.subscribe(Filter{}) != nil {
+		t.Error("Expected clients beyond MaxClients refused")
+	}
+
+	tp.ObserveDecision(decision("llama3", "ollama-nvidia", "alice"))
+	tp.ObserveDecision(&router.DecisionRecord{Annotations: backends.Annotations{Model: "llama3"}, Key: "bob", Err: errors.New("no backend fits")})
+	tp.ObserveUsage(usage.Record{Model: "llama3", Backend: "ollama-nvidia", Key: "alice", Status: "success", CompletionTokens: 12})
+
+	// Two events per client fit the burst; the third is over the rate
+	if got := len(all.events); got != 2 {
+		t.Fatalf("Expected 2 events within the rate cap, got %d", got)
+	}
+	if e := <-all.events; e.Type != EventDecision || e.Backend != "ollama-nvidia" || e.Key != "alice" {
+		t.Errorf("Unexpected event %+v", e)
+	}
+	if e := <-all.events; e.Status != "error" || e.Error == "" {
+		t.Errorf("Expected the failed decision, got %+v", e)
+	}
+	if got := len(alice.events); got != 2 {
+		t.Fatalf("Expected alice's 2 events, got %d", got)
+	}
+	<-alice.events
+	if e := <-alice.events; e.Type != EventRequest || e.CompletionTokens != 12 {
+		t.Errorf("Expected the completed request, got %+v", e)
+	}
+
+	// The next event sent reports what the cap dropped
+	time.Sleep(600 * time.Millisecond)
+	tp.ObserveUsage(usage.Record{Model: "llama3", Key: "bob"})
+	if e := <-all.events; e.Dropped != 1 {
+		t.Errorf("Expected 1 dropped event reported, got %+v", e)
+	}
+
+	tp.unsubscribe(all)
+	tp.unsubscribe(alice)
+	if tp.Active() {
+		t.Error("Expected the tap idle once clients leave")
+	}
+}
+
+func TestTap_Handler(t *testing.T) {
+	tp := New(Config{MaxClients: 1})
+	srv := httptest.NewServer(tp.Handler())
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	if resp, err := http.Get(srv.URL + "?type=prompt"); err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown type, got %v, %v", resp, err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?backend=ollama-npu&type=decision", nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected a second tap refused with 503, got %v", err)
+	}
+
+	tp.ObserveDecision(decision("llama3", "ollama-nvidia", ""))
+	tp.ObserveUsage(usage.Record{Model: "qwen2", Backend: "ollama-npu"})
+	tp.ObserveDecision(decision("qwen2", "ollama-npu", ""))
+
+	var e Event
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if err := conn.ReadJSON(&e); err != nil {
+		t.Fatalf("Expected an event, got %v", err)
+	}
+	if e.Type != EventDecision || e.Backend != "ollama-npu" || e.Model != "qwen2" {
+		t.Errorf("Expected only the matching decision, got %+v", e)
+	}
+}
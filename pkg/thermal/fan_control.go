@@ -0,0 +1,371 @@
+package thermal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// Fan profiles, each capping fan speed at the matching ThermalConfig limit
+const (
+	FanProfileQuiet    = "quiet"    // FanQuiet
+	FanProfileModerate = "moderate" // FanModerate
+	FanProfileLoud     = "loud"     // FanLoud
+)
+
+// DefaultFanControlInterval is how often fan speeds are re-evaluated when
+// FanControlConfig.Interval is unset
+const DefaultFanControlInterval = 5 * time.Second
+
+// staleReadingIntervals is how many fan control or monitor intervals,
+// whichever is longer, a reading may age before it is no longer trusted
+const staleReadingIntervals = 3
+
+// FanProfile sets how eagerly fans slow down under a profile. Fans speed
+// up as soon as the curve asks; they slow down only once the temperature
+// has fallen Hysteresis below the point on the curve for the lower speed,
+// and no sooner than MinInterval after the last change, so a temperature
+// hovering around a curve point doesn't make them oscillate.
+type FanProfile struct {
+	Hysteresis  float64       // Celsius
+	MinInterval time.Duration // between two changes of one device's fan speed
+}
+
+// DefaultFanProfiles are used for profiles FanControlConfig.Profiles
+// leaves out. Quiet changes least often so ramps are rarely heard.
+var DefaultFanProfiles = map[string]FanProfile{
+	FanProfileQuiet:    {Hysteresis: 5, MinInterval: 30 * time.Second},
+	FanProfileModerate: {Hysteresis: 3, MinInterval: 15 * time.Second},
+	FanProfileLoud:     {Hysteresis: 2, MinInterval: 5 * time.Second},
+}
+
+// CurvePoint is a fan speed for a temperature
+type CurvePoint struct {
+	Temperature float64 // Celsius
+	Percent     int
+}
+
+// FanCurve maps temperature to fan speed, interpolating linearly between
+// points sorted by temperature and holding the end points beyond them
+type FanCurve []CurvePoint
+
+// DefaultFanCurve runs fans at 20% 20°C below TempWarning, FanModerate at
+// TempWarning and 100% at TempCritical
+func DefaultFanCurve(config *ThermalConfig) FanCurve {
+	return FanCurve{
+		{Temperature: config.TempWarning - 20, Percent: 20},
+		{Temperature: config.TempWarning, Percent: config.FanModerate},
+		{Temperature: config.TempCritical, Percent: 100},
+	}
+}
+
+// Percent is the fan speed for temperature
+func (c FanCurve) Percent(temperature float64) int {
+	if len(c) == 0 {
+		return 0
+	}
+	if temperature <= c[0].Temperature {
+		return c[0].Percent
+	}
+	for i := 1; i < len(c); i++ {
+		if temperature <= c[i].Temperature {
+			lo, hi := c[i-1], c[i]
+			ratio := (temperature - lo.Temperature) / (hi.Temperature - lo.Temperature)
+			return lo.Percent + int(ratio*float64(hi.Percent-lo.Percent)+0.5)
+		}
+	}
+	return c[len(c)-1].Percent
+}
+
+// FanDevice is a device whose fans the controller drives
+type FanDevice struct {
+	Hardware string // as reported by the monitor, e.g. "nvidia" or "cpu"
+	Driver   FanDriver
+	Curve    FanCurve // DefaultFanCurve when empty
+}
+
+// FanControlConfig configures a FanController
+type FanControlConfig struct {
+	Devices  []FanDevice
+	Profiles map[string]FanProfile // DefaultFanProfiles for those left out
+	Interval time.Duration         // DefaultFanControlInterval when 0
+
+	// DryRun works out and reports fan speeds without setting them
+	DryRun bool
+}
+
+// FanStatus is the fan control state of one device
+type FanStatus struct {
+	Hardware       string    `json:"hardware"`
+	Driver         string    `json:"driver"`
+	Profile        string    `json:"profile"`
+	LimitPercent   int       `json:"limit_percent"`
+	TargetPercent  int       `json:"target_percent"`  // what the curve asks for, within the limit
+	AppliedPercent int       `json:"applied_percent"` // last speed set, -1 before the first
+	Temperature    float64   `json:"temperature"`
+	DryRun         bool      `json:"dry_run,omitempty"`
+	ChangedAt      time.Time `json:"changed_at,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// FanController sets fan speeds from each device's curve, capped by the
+// fan limit of the current profile
+type FanController struct {
+	monitor *ThermalMonitor
+	config  FanControlConfig
+	profile func() string
+
+	mu       sync.Mutex
+	statuses map[string]*FanStatus
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewFanController creates a controller for devices the monitor reports.
+// profile returns the current profile (FanProfileModerate when nil); the
+// proxy derives it from the efficiency mode.
+func NewFanController(monitor *ThermalMonitor, config FanControlConfig, profile func() string) *FanController {
+	if config.Interval <= 0 {
+		config.Interval = DefaultFanControlInterval
+	}
+	profiles := make(map[string]FanProfile, len(DefaultFanProfiles))
+	for name, p := range DefaultFanProfiles {
+		profiles[name] = p
+	}
+	for name, p := range config.Profiles {
+		profiles[name] = p
+	}
+	config.Profiles = profiles
+	if profile == nil {
+		profile = func() string { return FanProfileModerate }
+	}
+
+	fc := &FanController{
+		monitor:  monitor,
+		config:   config,
+		profile:  profile,
+		statuses: make(map[string]*FanStatus),
+	}
+	for i := range fc.config.Devices {
+		device := &fc.config.Devices[i]
+		if len(device.Curve) == 0 {
+			device.Curve = DefaultFanCurve(monitor.GetConfig())
+		}
+		sort.Slice(device.Curve, func(a, b int) bool { return device.Curve[a].Temperature < device.Curve[b].Temperature })
+		fc.statuses[device.Hardware] = &FanStatus{
+			Hardware:       device.Hardware,
+			Driver:         device.Driver.Kind(),
+			AppliedPercent: -1,
+			DryRun:         config.DryRun,
+		}
+	}
+	return fc
+}
+
+// Start begins adjusting fans every Interval
+func (fc *FanController) Start() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	fc.cancel = cancel
+	fc.done = make(chan struct{})
+	go fc.loop(ctx, fc.done)
+}
+
+// Stop stops adjusting fans and hands every device back to automatic
+// control
+func (fc *FanController) Stop() {
+	fc.mu.Lock()
+	cancel, done := fc.cancel, fc.done
+	fc.cancel = nil
+	fc.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, device := range fc.config.Devices {
+		status := fc.statuses[device.Hardware]
+		if status.AppliedPercent < 0 || fc.config.DryRun {
+			continue
+		}
+		if err := device.Driver.Restore(); err != nil {
+			logging.Logger.Warn("Failed to restore automatic fan control",
+				zap.String("hardware", device.Hardware),
+				zap.Error(err),
+			)
+		}
+		status.AppliedPercent = -1
+	}
+}
+
+func (fc *FanController) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(fc.config.Interval)
+	defer ticker.Stop()
+
+	fc.apply(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			middleware.Safe(middleware.ScopeBackground, "fan-control", func() { fc.apply(now) })
+		}
+	}
+}
+
+// apply moves every device's fans towards its curve
+func (fc *FanController) apply(now time.Time) {
+	profileName := fc.profile()
+	profile, ok := fc.config.Profiles[profileName]
+	if !ok {
+		profileName, profile = FanProfileModerate, fc.config.Profiles[FanProfileModerate]
+	}
+	limit := fc.limit(profileName)
+
+	staleAfter := staleReadingIntervals * max(fc.config.Interval, fc.monitor.updateInterval)
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, device := range fc.config.Devices {
+		status := fc.statuses[device.Hardware]
+		state := fc.monitor.GetState(device.Hardware)
+		switch {
+		case state == nil:
+			fc.failSafe(device, status, "no temperature reading")
+		case now.Sub(state.UpdatedAt) > staleAfter:
+			fc.failSafe(device, status, fmt.Sprintf("temperature reading is stale (%s old)", now.Sub(state.UpdatedAt).Round(time.Second)))
+		default:
+			fc.applyDevice(device, status, state.Temperature, profileName, profile, limit, now)
+		}
+	}
+}
+
+// failSafe hands a device without a trustworthy reading back to its
+// firmware, which still sees the temperature, rather than holding a speed
+// set for a temperature that may since have climbed. Caller must hold
+// fc.mu.
+func (fc *FanController) failSafe(device FanDevice, status *FanStatus, reason string) {
+	status.Error = reason
+	if status.AppliedPercent < 0 || fc.config.DryRun {
+		// Never taken over, or only pretending: the firmware is in control
+		status.AppliedPercent = -1
+		return
+	}
+
+	logging.Logger.Warn("Restoring automatic fan control",
+		zap.String("hardware", device.Hardware),
+		zap.String("reason", reason),
+	)
+	if err := device.Driver.Restore(); err != nil {
+		// Without automatic control, full speed is the safe choice
+		if err := device.Driver.SetFanPercent(100); err != nil {
+			status.Error = fmt.Sprintf("%s; failed to restore automatic control or set 100%%: %v", reason, err)
+			return
+		}
+		status.AppliedPercent = 100
+		status.Error = reason + "; running at 100% as automatic control could not be restored"
+		return
+	}
+	status.AppliedPercent = -1
+}
+
+// applyDevice decides and sets one device's fan speed. Caller must hold
+// fc.mu.
+func (fc *FanController) applyDevice(device FanDevice, status *FanStatus, temperature float64, profileName string, profile FanProfile, limit int, now time.Time) {
+	// Protecting the hardware beats keeping it quiet
+	if temperature >= fc.monitor.GetConfig().TempCritical {
+		limit = 100
+	}
+
+	status.Profile = profileName
+	status.LimitPercent = limit
+	status.Temperature = temperature
+	status.TargetPercent = min(device.Curve.Percent(temperature), limit)
+
+	applied := status.AppliedPercent
+	next := applied
+	switch {
+	case applied < 0, status.TargetPercent > applied:
+		// Speed up at once
+		next = status.TargetPercent
+	case applied > limit, now.Sub(status.ChangedAt) >= profile.MinInterval:
+		// Slow down only to the speed the curve asks for Hysteresis higher;
+		// a quieter profile's limit takes effect without waiting
+		if down := min(device.Curve.Percent(temperature+profile.Hysteresis), limit); down < applied {
+			next = down
+		}
+	}
+	if next == applied {
+		return
+	}
+
+	if fc.config.DryRun {
+		logging.Logger.Info("Fan control dry run: would set fan speed",
+			zap.String("hardware", device.Hardware),
+			zap.Int("percent", next),
+			zap.Float64("temperature", temperature),
+			zap.String("profile", profileName),
+		)
+	} else if err := device.Driver.SetFanPercent(next); err != nil {
+		status.Error = err.Error()
+		logging.Logger.Warn("Failed to set fan speed",
+			zap.String("hardware", device.Hardware),
+			zap.Int("percent", next),
+			zap.Error(err),
+		)
+		return
+	}
+	status.AppliedPercent = next
+	status.ChangedAt = now
+	status.Error = ""
+}
+
+// limit is the fan limit profile caps speeds at
+func (fc *FanController) limit(profile string) int {
+	config := fc.monitor.GetConfig()
+	switch profile {
+	case FanProfileQuiet:
+		return config.FanQuiet
+	case FanProfileLoud:
+		return config.FanLoud
+	}
+	return config.FanModerate
+}
+
+// Status returns the fan control state of hardware; false if the
+// controller doesn't drive it
+func (fc *FanController) Status(hardware string) (FanStatus, bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	status, ok := fc.statuses[hardware]
+	if !ok {
+		return FanStatus{}, false
+	}
+	return *status, true
+}
+
+// Statuses returns every driven device sorted by hardware
+func (fc *FanController) Statuses() []FanStatus {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	statuses := make([]FanStatus, 0, len(fc.statuses))
+	for _, status := range fc.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Hardware < statuses[j].Hardware })
+	return statuses
+}
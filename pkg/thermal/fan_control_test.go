@@ -0,0 +1,274 @@
+package thermal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+)
+
+func TestMain(m *testing.M) {
+	logging.InitLogger("info", false)
+	os.Exit(m.Run())
+}
+
+// fakeFan records the speeds it is set to
+type fakeFan struct {
+	set        []int
+	restored   int
+	err        error
+	restoreErr error
+}
+
+func (f *fakeFan) Kind() string { return "fake" }
+
+func (f *fakeFan) SetFanPercent(percent int) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.set = append(f.set, percent)
+	return nil
+}
+
+func (f *fakeFan) Restore() error {
+	if f.restoreErr != nil {
+		return f.restoreErr
+	}
+	f.restored++
+	return nil
+}
+
+func TestFanCurve_Percent(t *testing.T) {
+	curve := DefaultFanCurve(NewThermalMonitor(nil, time.Second).GetConfig()) // 50°C 20%, 70°C 60%, 85°C 100%
+
+	tests := []struct {
+		temperature float64
+		want        int
+	}{
+		{30, 20},
+		{50, 20},
+		{60, 40},
+		{70, 60},
+		{80, 87},
+		{85, 100},
+		{99, 100},
+	}
+	for _, tt := range tests {
+		if got := curve.Percent(tt.temperature); got != tt.want {
+			t.Errorf("Percent(%v) = %d, want %d", tt.temperature, got, tt.want)
+		}
+	}
+
+	if got := (FanCurve{}).Percent(60); got != 0 {
+		t.Errorf("Expected an empty curve to give 0, got %d", got)
+	}
+}
+
+func TestFanController_Hysteresis(t *testing.T) {
+	tm := NewThermalMonitor(nil, time.Second) // moderate limit 60%
+	fan := &fakeFan{}
+	fc := NewFanController(tm, FanControlConfig{Devices: []FanDevice{{Hardware: "nvidia", Driver: fan}}}, nil)
+	now := time.Now()
+
+	step := func(temperature float64, at time.Duration) int {
+		t.Helper()
+		tm.SetState("nvidia", &ThermalState{Temperature: temperature, UpdatedAt: now.Add(at)})
+		fc.apply(now.Add(at))
+		status, ok := fc.Status("nvidia")
+		if !ok {
+			t.Fatal("Expected a status for nvidia")
+		}
+		return status.AppliedPercent
+	}
+
+	if got := step(60, 0); got != 40 {
+		t.Errorf("Expected the first reading to set 40%%, got %d", got)
+	}
+	if got := step(65, time.Second); got != 50 {
+		t.Errorf("Expected heating to speed up at once to 50%%, got %d", got)
+	}
+
+	// 63°C asks for 46%, but 66°C (63 + 3 hysteresis) still asks for more
+	// than the fans are running at
+	if got := step(63, time.Minute); got != 50 {
+		t.Errorf("Expected a small drop to hold 50%%, got %d", got)
+	}
+
+	if got := step(60, 2*time.Minute); got != 46 {
+		t.Errorf("Expected to slow down to the speed for 63°C (46%%), got %d", got)
+	}
+
+	// Far enough below again, but too soon after the last change
+	if got := step(55, 2*time.Minute+time.Second); got != 46 {
+		t.Errorf("Expected no slowdown within the min interval, got %d", got)
+	}
+	if got := step(55, 2*time.Minute+15*time.Second); got != 36 {
+		t.Errorf("Expected to slow down to the speed for 58°C (36%%) after the min interval, got %d", got)
+	}
+
+	if want := []int{40, 50, 46, 36}; fmt.Sprint(fan.set) != fmt.Sprint(want) {
+		t.Errorf("Expected the driver to be set to %v, got %v", want, fan.set)
+	}
+}
+
+func TestFanController_ProfileLimits(t *testing.T) {
+	tm := NewThermalMonitor(nil, time.Second) // quiet 30%, moderate 60%, loud 85%
+	fan := &fakeFan{}
+	profile := FanProfileLoud
+	fc := NewFanController(tm, FanControlConfig{Devices: []FanDevice{{Hardware: "cpu", Driver: fan}}}, func() string { return profile })
+	now := time.Now()
+
+	tm.SetState("cpu", &ThermalState{Temperature: 80, UpdatedAt: now})
+	fc.apply(now)
+	status, _ := fc.Status("cpu")
+	if status.AppliedPercent != 85 || status.LimitPercent != 85 || status.Profile != FanProfileLoud {
+		t.Errorf("Expected 87%% capped to the loud limit of 85%%, got %+v", status)
+	}
+
+	// A quieter profile applies at once, regardless of the min interval
+	profile = FanProfileQuiet
+	fc.apply(now.Add(time.Second))
+	if status, _ = fc.Status("cpu"); status.AppliedPercent != 30 {
+		t.Errorf("Expected the quiet limit of 30%% at once, got %+v", status)
+	}
+
+	// At the critical temperature protecting the hardware wins
+	tm.SetState("cpu", &ThermalState{Temperature: 86, UpdatedAt: now.Add(2 * time.Second)})
+	fc.apply(now.Add(2 * time.Second))
+	if status, _ = fc.Status("cpu"); status.AppliedPercent != 100 || status.LimitPercent != 100 {
+		t.Errorf("Expected the limit lifted to 100%% at critical, got %+v", status)
+	}
+
+	// Unknown profiles fall back to moderate, whose limit applies at once
+	// down to the speed for 63°C (60 + 3 hysteresis)
+	profile = "turbo"
+	tm.SetState("cpu", &ThermalState{Temperature: 60, UpdatedAt: now.Add(3 * time.Second)})
+	fc.apply(now.Add(3 * time.Second))
+	if status, _ = fc.Status("cpu"); status.Profile != FanProfileModerate || status.LimitPercent != 60 || status.AppliedPercent != 46 {
+		t.Errorf("Expected the moderate limit for an unknown profile, got %+v", status)
+	}
+}
+
+func TestFanController_DryRun(t *testing.T) {
+	tm := NewThermalMonitor(nil, time.Second)
+	fan := &fakeFan{}
+	fc := NewFanController(tm, FanControlConfig{
+		Devices: []FanDevice{{Hardware: "nvidia", Driver: fan, Curve: FanCurve{{Temperature: 40, Percent: 10}, {Temperature: 80, Percent: 50}}}},
+		DryRun:  true,
+	}, nil)
+
+	tm.SetState("nvidia", &ThermalState{Temperature: 60, UpdatedAt: time.Now()})
+	fc.apply(time.Now())
+
+	status, _ := fc.Status("nvidia")
+	if !status.DryRun || status.AppliedPercent != 30 || status.TargetPercent != 30 {
+		t.Errorf("Expected a dry run to report 30%%, got %+v", status)
+	}
+	if len(fan.set) != 0 {
+		t.Errorf("Expected a dry run not to touch the fans, got %v", fan.set)
+	}
+
+	fc.Start()
+	fc.Stop()
+	if fan.restored != 0 {
+		t.Errorf("Expected a dry run not to restore fans it never set, got %d", fan.restored)
+	}
+}
+
+func TestFanController_DriverError(t *testing.T) {
+	tm := NewThermalMonitor(nil, time.Second)
+	fan := &fakeFan{err: errors.New("permission denied")}
+	fc := NewFanController(tm, FanControlConfig{Devices: []FanDevice{{Hardware: "cpu", Driver: fan}}}, nil)
+
+	tm.SetState("cpu", &ThermalState{Temperature: 60, UpdatedAt: time.Now()})
+	fc.apply(time.Now())
+
+	status, _ := fc.Status("cpu")
+	if status.Error == "" || status.AppliedPercent != -1 {
+		t.Errorf("Expected the error reported and nothing applied, got %+v", status)
+	}
+
+	fan.err = nil
+	fc.apply(time.Now())
+	if status, _ = fc.Status("cpu"); status.Error != "" || status.AppliedPercent != 40 {
+		t.Errorf("Expected a retry to apply and clear the error, got %+v", status)
+	}
+}
+
+func TestFanController_StaleReading(t *testing.T) {
+	tm := NewThermalMonitor(nil, time.Second) // stale after 3 x 5s interval
+	fan := &fakeFan{}
+	fc := NewFanController(tm, FanControlConfig{Devices: []FanDevice{{Hardware: "cpu", Driver: fan}}}, nil)
+	now := time.Now()
+
+	tm.SetState("cpu", &ThermalState{Temperature: 60, UpdatedAt: now})
+	fc.apply(now)
+	if status, _ := fc.Status("cpu"); status.AppliedPercent != 40 {
+		t.Fatalf("Expected 40%% from a fresh reading, got %+v", status)
+	}
+
+	// The monitor stopped updating: the firmware takes the fans back
+	fc.apply(now.Add(20 * time.Second))
+	status, _ := fc.Status("cpu")
+	if fan.restored != 1 || status.AppliedPercent != -1 || !strings.Contains(status.Error, "stale") {
+		t.Errorf("Expected a stale reading to restore automatic control, got restored=%d %+v", fan.restored, status)
+	}
+
+	// A fresh reading takes control again and clears the error
+	tm.SetState("cpu", &ThermalState{Temperature: 60, UpdatedAt: now.Add(21 * time.Second)})
+	fc.apply(now.Add(21 * time.Second))
+	if status, _ = fc.Status("cpu"); status.AppliedPercent != 40 || status.Error != "" {
+		t.Errorf("Expected control resumed on a fresh reading, got %+v", status)
+	}
+}
+
+func TestFanController_RestoreFailsRunsFlatOut(t *testing.T) {
+	tm := NewThermalMonitor(nil, time.Second)
+	fan := &fakeFan{restoreErr: errors.New("read-only")}
+	fc := NewFanController(tm, FanControlConfig{Devices: []FanDevice{{Hardware: "nvidia", Driver: fan}}}, nil)
+	now := time.Now()
+
+	// Before any reading the fans were never taken over
+	fc.apply(now)
+	if status, _ := fc.Status("nvidia"); len(fan.set) != 0 || status.Error == "" {
+		t.Errorf("Expected a missing reading reported without touching the fans, got %v %+v", fan.set, status)
+	}
+
+	tm.SetState("nvidia", &ThermalState{Temperature: 60, UpdatedAt: now})
+	fc.apply(now)
+	fc.apply(now.Add(time.Minute))
+
+	// Automatic control can't be restored, so run flat out
+	status, _ := fc.Status("nvidia")
+	if status.AppliedPercent != 100 || fan.set[len(fan.set)-1] != 100 || !strings.Contains(status.Error, "stale") {
+		t.Errorf("Expected 100%% when automatic control can't be restored, got %v %+v", fan.set, status)
+	}
+}
+
+func TestFanController_StopRestores(t *testing.T) {
+	tm := NewThermalMonitor(nil, time.Second)
+	tm.SetState("cpu", &ThermalState{Temperature: 60, UpdatedAt: time.Now()})
+	cpu, gpu := &fakeFan{}, &fakeFan{}
+	fc := NewFanController(tm, FanControlConfig{Devices: []FanDevice{
+		{Hardware: "cpu", Driver: cpu},
+		{Hardware: "nvidia", Driver: gpu}, // never reported, never set
+	}}, nil)
+
+	fc.Start()
+	fc.Stop()
+	if cpu.restored != 1 || gpu.restored != 0 {
+		t.Errorf("Expected only the fan that was set to be restored, got cpu=%d nvidia=%d", cpu.restored, gpu.restored)
+	}
+
+	statuses := fc.Statuses()
+	if len(statuses) != 2 || statuses[0].Hardware != "cpu" || statuses[0].AppliedPercent != -1 {
+		t.Errorf("Expected both devices back under automatic control, got %+v", statuses)
+	}
+	if _, ok := fc.Status("npu"); ok {
+		t.Error("Expected no status for a device the controller doesn't drive")
+	}
+}
@@ -0,0 +1,122 @@
+package thermal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Fan driver kinds
+const (
+	FanDriverHwmon  = "hwmon"  // sysfs hwmon PWM channel
+	FanDriverNBFC   = "nbfc"   // NoteBook FanControl service
+	FanDriverNvidia = "nvidia" // nvidia-settings GPU fan
+)
+
+// FanDriver sets the speed of one device's fans
+type FanDriver interface {
+	// Kind is the driver kind, e.g. FanDriverHwmon
+	Kind() string
+
+	// SetFanPercent takes manual control of the fans and runs them at
+	// percent (0-100)
+	SetFanPercent(percent int) error
+
+	// Restore hands the fans back to automatic (firmware) control
+	Restore() error
+}
+
+// runCommand runs an external fan tool; replaced in tests
+var runCommand = func(name string, args ...string) error {
+	if output, err := exec.Command(name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// NewFanDriver creates a driver of kind. path is the hwmon PWM file (e.g.
+// /sys/class/hwmon/hwmon3/pwm1) for hwmon and the GPU index (default "0")
+// for nvidia; nbfc ignores it.
+func NewFanDriver(kind, path string) (FanDriver, error) {
+	switch kind {
+	case FanDriverHwmon:
+		if path == "" {
+			return nil, fmt.Errorf("hwmon fan driver needs the PWM path")
+		}
+		return &hwmonFan{path: path}, nil
+	case FanDriverNBFC:
+		return nbfcFan{}, nil
+	case FanDriverNvidia:
+		if path == "" {
+			path = "0"
+		}
+		if _, err := strconv.Atoi(path); err != nil {
+			return nil, fmt.Errorf("nvidia fan driver needs a GPU index, got %q", path)
+		}
+		return &nvidiaFan{gpu: path}, nil
+	}
+	return nil, fmt.Errorf("unknown fan driver %q", kind)
+}
+
+// hwmonFan drives a PWM channel: pwmN takes a duty cycle of 0-255 and
+// pwmN_enable selects manual (1) or automatic (2) control
+type hwmonFan struct {
+	path string
+}
+
+func (h *hwmonFan) Kind() string { return FanDriverHwmon }
+
+func (h *hwmonFan) SetFanPercent(percent int) error {
+	if err := os.WriteFile(h.path+"_enable", []byte("1"), 0644); err != nil {
+		return fmt.Errorf("failed to take manual control of %s: %w", h.path, err)
+	}
+	duty := clampPercent(percent) * 255 / 100
+	if err := os.WriteFile(h.path, []byte(strconv.Itoa(duty)), 0644); err != nil {
+		return fmt.Errorf("failed to set %s: %w", h.path, err)
+	}
+	return nil
+}
+
+func (h *hwmonFan) Restore() error {
+	if err := os.WriteFile(h.path+"_enable", []byte("2"), 0644); err != nil {
+		return fmt.Errorf("failed to restore automatic control of %s: %w", h.path, err)
+	}
+	return nil
+}
+
+// nbfcFan drives laptop fans through the nbfc service
+type nbfcFan struct{}
+
+func (nbfcFan) Kind() string { return FanDriverNBFC }
+
+func (nbfcFan) SetFanPercent(percent int) error {
+	return runCommand("nbfc", "set", "-s", strconv.Itoa(clampPercent(percent)))
+}
+
+func (nbfcFan) Restore() error {
+	return runCommand("nbfc", "set", "-a")
+}
+
+// nvidiaFan drives an NVIDIA GPU's fans through nvidia-settings (needs
+// Coolbits enabled in the X server configuration)
+type nvidiaFan struct {
+	gpu string
+}
+
+func (n *nvidiaFan) Kind() string { return FanDriverNvidia }
+
+func (n *nvidiaFan) SetFanPercent(percent int) error {
+	return runCommand("nvidia-settings",
+		"-a", fmt.Sprintf("[gpu:%s]/GPUFanControlState=1", n.gpu),
+		"-a", fmt.Sprintf("[fan:%s]/GPUTargetFanSpeed=%d", n.gpu, clampPercent(percent)))
+}
+
+func (n *nvidiaFan) Restore() error {
+	return runCommand("nvidia-settings", "-a", fmt.Sprintf("[gpu:%s]/GPUFanControlState=0", n.gpu))
+}
+
+func clampPercent(percent int) int {
+	return min(max(percent, 0), 100)
+}
@@ -0,0 +1,102 @@
+package thermal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewFanDriver(t *testing.T) {
+	tests := []struct {
+		kind, path string
+		wantErr    bool
+	}{
+		{FanDriverHwmon, "/sys/class/hwmon/hwmon3/pwm1", false},
+		{FanDriverHwmon, "", true},
+		{FanDriverNBFC, "", false},
+		{FanDriverNvidia, "", false},
+		{FanDriverNvidia, "1", false},
+		{FanDriverNvidia, "gpu1", true},
+		{"ipmi", "", true},
+	}
+	for _, tt := range tests {
+		driver, err := NewFanDriver(tt.kind, tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewFanDriver(%q, %q) error = %v, wantErr %v", tt.kind, tt.path, err, tt.wantErr)
+			continue
+		}
+		if err == nil && driver.Kind() != tt.kind {
+			t.Errorf("NewFanDriver(%q) kind = %q", tt.kind, driver.Kind())
+		}
+	}
+}
+
+func TestHwmonFan(t *testing.T) {
+	pwm := filepath.Join(t.TempDir(), "pwm1")
+	driver, err := NewFanDriver(FanDriverHwmon, pwm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if err := driver.SetFanPercent(40); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(pwm + "_enable"); got != "1" {
+		t.Errorf("Expected manual control, got pwm1_enable=%s", got)
+	}
+	if got := read(pwm); got != "102" {
+		t.Errorf("Expected 40%% as a duty cycle of 102, got %s", got)
+	}
+
+	if err := driver.SetFanPercent(150); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(pwm); got != "255" {
+		t.Errorf("Expected speeds above 100%% clamped to 255, got %s", got)
+	}
+
+	if err := driver.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(pwm + "_enable"); got != "2" {
+		t.Errorf("Expected automatic control after Restore, got pwm1_enable=%s", got)
+	}
+}
+
+func TestCommandFans(t *testing.T) {
+	var commands []string
+	saved := runCommand
+	runCommand = func(name string, args ...string) error {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		return nil
+	}
+	defer func() { runCommand = saved }()
+
+	nbfc, _ := NewFanDriver(FanDriverNBFC, "")
+	nbfc.SetFanPercent(55)
+	nbfc.Restore()
+
+	nvidia, _ := NewFanDriver(FanDriverNvidia, "1")
+	nvidia.SetFanPercent(-5)
+	nvidia.Restore()
+
+	want := []string{
+		"nbfc set -s 55",
+		"nbfc set -a",
+		"nvidia-settings -a [gpu:1]/GPUFanControlState=1 -a [fan:1]/GPUTargetFanSpeed=0",
+		"nvidia-settings -a [gpu:1]/GPUFanControlState=0",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected commands\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(commands, "\n"))
+	}
+}
@@ -3,14 +3,19 @@ package thermal
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
 )
 
 // ThermalState represents thermal status of a device
@@ -22,6 +27,9 @@ type ThermalState struct {
 	Utilization int       // GPU/NPU utilization percentage
 	Throttling  bool      // Is thermal throttling active?
 	UpdatedAt   time.Time // Last update timestamp
+
+	// Fan control applied to the device, set on copies served by /thermal
+	FanControl *FanStatus `json:",omitempty"`
 }
 
 // ThermalMonitor tracks thermal state of all backends
@@ -38,6 +46,11 @@ type ThermalMonitor struct {
 
 	// Thresholds
 	config *ThermalConfig
+
+	// Recent timestamped readings per hardware, for trend prediction
+	history             map[string][]reading
+	predicted           map[string]bool // hardware ID -> last prediction was critical
+	predictionListeners []func(Prediction)
 }
 
 // ThermalConfig defines thermal limits
@@ -54,6 +67,10 @@ type ThermalConfig struct {
 
 	// Cooling wait time
 	CooldownTime   time.Duration // Wait before retrying hot backend
+
+	// Trend prediction
+	PredictionSamples int           // Readings the slope is fitted over (default 6)
+	PredictionHorizon time.Duration // Shift load away if critical within this (default 1m)
 }
 
 // NewThermalMonitor creates a new thermal monitor
@@ -78,32 +95,43 @@ func NewThermalMonitor(config *ThermalConfig, updateInterval time.Duration) *The
 		ctx:            ctx,
 		cancel:         cancel,
 		config:         config,
+		history:        make(map[string][]reading),
+		predicted:      make(map[string]bool),
 	}
 
 	return tm
 }
 
-// Start begins monitoring
+// Start begins monitoring, again after a Stop
 func (tm *ThermalMonitor) Start() {
-	go tm.monitorLoop()
+	tm.mu.Lock()
+	if tm.ctx.Err() != nil {
+		tm.ctx, tm.cancel = context.WithCancel(context.Background())
+	}
+	ctx := tm.ctx
+	tm.mu.Unlock()
+
+	go tm.monitorLoop(ctx)
 }
 
 // Stop stops monitoring
 func (tm *ThermalMonitor) Stop() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 	tm.cancel()
 }
 
-// monitorLoop continuously updates thermal state
-func (tm *ThermalMonitor) monitorLoop() {
+// monitorLoop continuously updates thermal state until ctx is done
+func (tm *ThermalMonitor) monitorLoop(ctx context.Context) {
 	ticker := time.NewTicker(tm.updateInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-tm.ctx.Done():
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			tm.updateAll()
+			middleware.Safe(middleware.ScopeBackground, "thermal-monitor", tm.updateAll)
 		}
 	}
 }
@@ -112,30 +140,22 @@ func (tm *ThermalMonitor) monitorLoop() {
 func (tm *ThermalMonitor) updateAll() {
 	// Update NVIDIA GPU
 	if state, err := tm.getNVIDIAState(); err == nil {
-		tm.mu.Lock()
-		tm.states["nvidia"] = state
-		tm.mu.Unlock()
+		tm.record("nvidia", state)
 	}
 
 	// Update Intel GPU
 	if state, err := tm.getIntelGPUState(); err == nil {
-		tm.mu.Lock()
-		tm.states["igpu"] = state
-		tm.mu.Unlock()
+		tm.record("igpu", state)
 	}
 
 	// Update NPU (Intel)
 	if state, err := tm.getIntelNPUState(); err == nil {
-		tm.mu.Lock()
-		tm.states["npu"] = state
-		tm.mu.Unlock()
+		tm.record("npu", state)
 	}
 
 	// Update CPU
 	if state, err := tm.getCPUState(); err == nil {
-		tm.mu.Lock()
-		tm.states["cpu"] = state
-		tm.mu.Unlock()
+		tm.record("cpu", state)
 	}
 }
 
@@ -369,6 +389,13 @@ func (tm *ThermalMonitor) GetState(hardware string) *ThermalState {
 	return nil
 }
 
+// SetState records a reading for hardware from outside the monitor's own
+// sensors, e.g. a remote agent or a simulation. A running monitor
+// overwrites it on its next update of that hardware.
+func (tm *ThermalMonitor) SetState(hardware string, state *ThermalState) {
+	tm.record(hardware, state)
+}
+
 // GetAllStates returns all thermal states
 func (tm *ThermalMonitor) GetAllStates() map[string]*ThermalState {
 	tm.mu.RLock()
@@ -428,6 +455,41 @@ func (tm *ThermalMonitor) CanUse(hardware string) (bool, string) {
 	return true, ""
 }
 
+// Cooldown is how long hardware should be left to cool before requests
+// are retried on it: CooldownTime while CanUse rejects it, else 0
+func (tm *ThermalMonitor) Cooldown(hardware string) time.Duration {
+	if usable, _ := tm.CanUse(hardware); usable {
+		return 0
+	}
+	return tm.config.CooldownTime
+}
+
+// HeadroomUsed is how much of hardware's thermal headroom is used, from 0
+// at TempWarning or below to 1 at TempCritical or while throttling; false
+// when its state is unknown
+func (tm *ThermalMonitor) HeadroomUsed(hardware string) (float64, bool) {
+	state := tm.GetState(hardware)
+	if state == nil {
+		return 0, false
+	}
+	if state.Throttling {
+		return 1, true
+	}
+	return tm.headroomAt(state.Temperature), true
+}
+
+// headroomAt is the headroom used at temperature, from 0 at TempWarning
+// to 1 at TempCritical
+func (tm *ThermalMonitor) headroomAt(temperature float64) float64 {
+	if temperature >= tm.config.TempCritical {
+		return 1
+	}
+	if temperature <= tm.config.TempWarning {
+		return 0
+	}
+	return (temperature - tm.config.TempWarning) / (tm.config.TempCritical - tm.config.TempWarning)
+}
+
 // GetThermalPenalty calculates routing penalty based on thermal state
 // Higher penalty = less preferred
 func (tm *ThermalMonitor) GetThermalPenalty(hardware string) float64 {
@@ -503,6 +565,86 @@ func (tm *ThermalMonitor) GetConfig() *ThermalConfig {
 	return tm.config
 }
 
+// HardwareStatus is one device's thermal state as served by Handler
+type HardwareStatus struct {
+	Hardware    string    `json:"hardware"`
+	Temperature float64   `json:"temperature"`
+	FanSpeed    int       `json:"fan_speed"`
+	FanPercent  int       `json:"fan_percent"`
+	PowerDraw   float64   `json:"power_draw"`
+	Utilization int       `json:"utilization"`
+	Throttling  bool      `json:"throttling"`
+	Usable      bool      `json:"usable"`
+	Reason      string    `json:"reason,omitempty"` // why the device is not usable
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Set while the temperature trend reaches critical within the horizon
+	PredictedCritical bool    `json:"predicted_critical,omitempty"`
+	SecondsToCritical float64 `json:"seconds_to_critical,omitempty"`
+}
+
+// HardwareStatuses returns every monitored device sorted by hardware
+func (tm *ThermalMonitor) HardwareStatuses() []HardwareStatus {
+	hardware := make([]HardwareStatus, 0)
+	for hw, state := range tm.GetAllStates() {
+		if state == nil {
+			continue
+		}
+		usable, reason := tm.CanUse(hw)
+		prediction, _ := tm.Predict(hw)
+		hardware = append(hardware, HardwareStatus{
+			Hardware:    hw,
+			Temperature: state.Temperature,
+			FanSpeed:    state.FanSpeed,
+			FanPercent:  state.FanPercent,
+			PowerDraw:   state.PowerDraw,
+			Utilization: state.Utilization,
+			Throttling:  state.Throttling,
+			Usable:      usable,
+			Reason:      reason,
+			UpdatedAt:   state.UpdatedAt,
+
+			PredictedCritical: prediction.Critical,
+			SecondsToCritical: prediction.TimeToCritical.Seconds(),
+		})
+	}
+	sort.Slice(hardware, func(i, j int) bool { return hardware[i].Hardware < hardware[j].Hardware })
+	return hardware
+}
+
+// Handler serves the thermal state of every monitored device along with
+// the configured thresholds (GET only)
+func (tm *ThermalMonitor) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		throttling := false
+		hardware := tm.HardwareStatuses()
+		for _, hw := range hardware {
+			throttling = throttling || hw.Throttling
+		}
+
+		response := map[string]interface{}{
+			"throttling": throttling,
+			"hardware":   hardware,
+			"thresholds": map[string]interface{}{
+				"temp_warning":  tm.config.TempWarning,
+				"temp_critical": tm.config.TempCritical,
+				"temp_shutdown": tm.config.TempShutdown,
+				"fan_quiet":     tm.config.FanQuiet,
+				"fan_moderate":  tm.config.FanModerate,
+				"fan_loud":      tm.config.FanLoud,
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
 // String returns human-readable thermal state
 func (ts *ThermalState) String() string {
 	return fmt.Sprintf("%.1f°C, Fan:%d%%, Power:%.1fW, Util:%d%%, Throttle:%v",
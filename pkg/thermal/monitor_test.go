@@ -1,7 +1,10 @@
 package thermal
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -109,6 +112,20 @@ func TestThermalMonitor_GetState(t *testing.T) {
 	}
 }
 
+func TestThermalMonitor_SetState(t *testing.T) {
+	tm := NewThermalMonitor(nil, 5*time.Second)
+
+	tm.SetState("nvidia", &ThermalState{Temperature: 90.0})
+	if canUse, _ := tm.CanUse("nvidia"); canUse {
+		t.Error("Expected hardware set above critical to be unusable")
+	}
+
+	tm.SetState("nvidia", &ThermalState{Temperature: 60.0})
+	if state := tm.GetState("nvidia"); state == nil || state.Temperature != 60.0 {
+		t.Errorf("Expected the latest reading, got %+v", state)
+	}
+}
+
 func TestThermalMonitor_GetAllStates(t *testing.T) {
 	tm := NewThermalMonitor(nil, 5*time.Second)
 
@@ -249,6 +266,25 @@ func TestThermalMonitor_CanUse(t *testing.T) {
 	if canUse {
 		t.Errorf("Expected CanUse=false for throttling, got true")
 	}
+
+	if d := tm.Cooldown("hot"); d != 2*time.Minute {
+		t.Errorf("Expected the configured cooldown for unusable hardware, got %s", d)
+	}
+	if d := tm.Cooldown("normal"); d != 0 {
+		t.Errorf("Expected no cooldown for usable hardware, got %s", d)
+	}
+
+	tm.mu.Lock()
+	tm.states["warm"] = &ThermalState{Temperature: 76.0, UpdatedAt: time.Now()}
+	tm.mu.Unlock()
+	for hardware, want := range map[string]float64{"normal": 0, "warm": 0.4, "hot": 1, "throttling": 1} {
+		if used, ok := tm.HeadroomUsed(hardware); !ok || used < want-0.001 || used > want+0.001 {
+			t.Errorf("Expected %s to use %.1f of its headroom, got %.3f", hardware, want, used)
+		}
+	}
+	if _, ok := tm.HeadroomUsed("unknown"); ok {
+		t.Error("Expected no headroom reading for unknown hardware")
+	}
 }
 
 func TestThermalMonitor_GetThermalPenalty(t *testing.T) {
@@ -1677,3 +1713,36 @@ func TestThermalMonitor_LargeScaleSimulation(t *testing.T) {
 		t.Error("Failed to find coolest backend from large set")
 	}
 }
+
+func TestThermalMonitor_Handler(t *testing.T) {
+	tm := NewThermalMonitor(nil, time.Minute)
+	tm.SetState("nvidia", &ThermalState{Temperature: 90, FanPercent: 95})
+	tm.SetState("npu", &ThermalState{Temperature: 40})
+
+	w := httptest.NewRecorder()
+	tm.Handler()(w, httptest.NewRequest(http.MethodGet, "/admin/thermal", nil))
+
+	var response struct {
+		Throttling bool             `json:"throttling"`
+		Hardware   []HardwareStatus `json:"hardware"`
+		Thresholds map[string]float64
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Hardware) != 2 || response.Hardware[0].Hardware != "npu" || !response.Hardware[0].Usable {
+		t.Fatalf("Unexpected hardware %+v", response.Hardware)
+	}
+	if nvidia := response.Hardware[1]; nvidia.Usable || !strings.Contains(nvidia.Reason, "critical") {
+		t.Errorf("Expected the hot GPU unusable, got %+v", nvidia)
+	}
+	if response.Thresholds["temp_critical"] != 85 {
+		t.Errorf("Expected thresholds included, got %v", response.Thresholds)
+	}
+
+	w = httptest.NewRecorder()
+	tm.Handler()(w, httptest.NewRequest(http.MethodPost, "/admin/thermal", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", w.Code)
+	}
+}
@@ -0,0 +1,163 @@
+package thermal
+
+import (
+	"time"
+)
+
+const (
+	// DefaultPredictionSamples is how many recent readings the temperature
+	// trend is fitted over when ThermalConfig.PredictionSamples is unset
+	DefaultPredictionSamples = 6
+
+	// DefaultPredictionHorizon is how far ahead the trend is projected when
+	// ThermalConfig.PredictionHorizon is unset
+	DefaultPredictionHorizon = time.Minute
+)
+
+// reading is one timestamped temperature in a hardware's history
+type reading struct {
+	temperature float64
+	at          time.Time
+}
+
+// Prediction is where hardware's temperature is heading, from the slope of
+// its recent readings
+type Prediction struct {
+	Hardware    string
+	Temperature float64 // latest reading in Celsius
+	Slope       float64 // Celsius per second, positive while heating
+
+	// Critical is set while the hardware is at TempCritical or is
+	// projected to reach it within the prediction horizon
+	Critical bool
+
+	// TimeToCritical is how long until TempCritical at Slope; 0 when
+	// already there or not heating
+	TimeToCritical time.Duration
+}
+
+// predictionSamples is PredictionSamples, DefaultPredictionSamples when unset
+func (c *ThermalConfig) predictionSamples() int {
+	if c.PredictionSamples < 2 {
+		return DefaultPredictionSamples
+	}
+	return c.PredictionSamples
+}
+
+// predictionHorizon is PredictionHorizon, DefaultPredictionHorizon when unset
+func (c *ThermalConfig) predictionHorizon() time.Duration {
+	if c.PredictionHorizon <= 0 {
+		return DefaultPredictionHorizon
+	}
+	return c.PredictionHorizon
+}
+
+// OnPrediction registers fn to be called whenever a hardware's prediction
+// turns critical or stops being critical. fn must not block.
+func (tm *ThermalMonitor) OnPrediction(fn func(Prediction)) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.predictionListeners = append(tm.predictionListeners, fn)
+}
+
+// record stores state as hardware's latest reading, adds it to the trend
+// history and notifies prediction listeners of a change. Readings without
+// an UpdatedAt are kept but can't be placed on the trend.
+func (tm *ThermalMonitor) record(hardware string, state *ThermalState) {
+	tm.mu.Lock()
+	tm.states[hardware] = state
+	if state != nil && !state.UpdatedAt.IsZero() {
+		history := append(tm.history[hardware], reading{temperature: state.Temperature, at: state.UpdatedAt})
+		if n := tm.config.predictionSamples(); len(history) > n {
+			history = history[len(history)-n:]
+		}
+		tm.history[hardware] = history
+	}
+
+	prediction, ok := tm.predictLocked(hardware)
+	if !ok || prediction.Critical == tm.predicted[hardware] {
+		tm.mu.Unlock()
+		return
+	}
+	tm.predicted[hardware] = prediction.Critical
+	listeners := make([]func(Prediction), len(tm.predictionListeners))
+	copy(listeners, tm.predictionListeners)
+	tm.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(prediction)
+	}
+}
+
+// Predict returns hardware's temperature trend; false until it has at
+// least two timestamped readings
+func (tm *ThermalMonitor) Predict(hardware string) (Prediction, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.predictLocked(hardware)
+}
+
+// predictLocked fits a least-squares line through hardware's history.
+// Caller must hold tm.mu.
+func (tm *ThermalMonitor) predictLocked(hardware string) (Prediction, bool) {
+	history := tm.history[hardware]
+	if len(history) < 2 {
+		return Prediction{}, false
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for _, r := range history {
+		x := r.at.Sub(history[0].at).Seconds()
+		sumX += x
+		sumY += r.temperature
+		sumXY += x * r.temperature
+		sumXX += x * x
+	}
+	n := float64(len(history))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return Prediction{}, false
+	}
+
+	latest := history[len(history)-1].temperature
+	prediction := Prediction{
+		Hardware:    hardware,
+		Temperature: latest,
+		Slope:       (n*sumXY - sumX*sumY) / denominator,
+	}
+	switch {
+	case latest >= tm.config.TempCritical:
+		prediction.Critical = true
+	case prediction.Slope > 0:
+		prediction.TimeToCritical = time.Duration((tm.config.TempCritical - latest) / prediction.Slope * float64(time.Second))
+		prediction.Critical = prediction.TimeToCritical <= tm.config.predictionHorizon()
+	}
+	return prediction, true
+}
+
+// PredictedCritical reports whether hardware is projected to reach
+// TempCritical within the prediction horizon
+func (tm *ThermalMonitor) PredictedCritical(hardware string) bool {
+	prediction, ok := tm.Predict(hardware)
+	return ok && prediction.Critical
+}
+
+// PredictedHeadroomUsed is HeadroomUsed for the temperature hardware is
+// heading for: the higher of its current reading and the trend projected
+// over the prediction horizon. Hardware about to overheat costs as much
+// as hardware already at TempCritical, so load shifts away before it gets
+// there.
+func (tm *ThermalMonitor) PredictedHeadroomUsed(hardware string) (float64, bool) {
+	state := tm.GetState(hardware)
+	if state == nil {
+		return 0, false
+	}
+	if state.Throttling {
+		return 1, true
+	}
+	temperature := state.Temperature
+	if prediction, ok := tm.Predict(hardware); ok && prediction.Slope > 0 {
+		temperature = max(temperature, prediction.Temperature+prediction.Slope*tm.config.predictionHorizon().Seconds())
+	}
+	return tm.headroomAt(temperature), true
+}
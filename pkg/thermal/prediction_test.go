@@ -0,0 +1,122 @@
+package thermal
+
+import (
+	"testing"
+	"time"
+)
+
+// setTrend records temperatures for hardware one interval apart, following
+// on from its latest reading
+func setTrend(tm *ThermalMonitor, hardware string, interval time.Duration, temperatures ...float64) {
+	at := time.Now()
+	if state := tm.GetState(hardware); state != nil {
+		at = state.UpdatedAt.Add(interval)
+	}
+	for i, temp := range temperatures {
+		tm.SetState(hardware, &ThermalState{Temperature: temp, UpdatedAt: at.Add(time.Duration(i) * interval)})
+	}
+}
+
+func TestThermalMonitor_Predict(t *testing.T) {
+	tm := NewThermalMonitor(nil, 5*time.Second) // critical at 85°C
+
+	if _, ok := tm.Predict("nvidia"); ok {
+		t.Error("Expected no prediction without readings")
+	}
+
+	// 1°C every 5s from 70°C: 0.2°C/s, critical in 75s, beyond the 1m horizon
+	setTrend(tm, "nvidia", 5*time.Second, 68, 69, 70)
+	prediction, ok := tm.Predict("nvidia")
+	if !ok {
+		t.Fatal("Expected a prediction from 3 readings")
+	}
+	if prediction.Slope < 0.199 || prediction.Slope > 0.201 {
+		t.Errorf("Expected a slope of 0.2°C/s, got %v", prediction.Slope)
+	}
+	if prediction.TimeToCritical != 75*time.Second || prediction.Critical {
+		t.Errorf("Expected critical in 75s, outside the horizon, got %+v", prediction)
+	}
+
+	// Heating faster brings critical inside the horizon
+	setTrend(tm, "nvidia", 5*time.Second, 72, 75, 78)
+	if prediction, _ := tm.Predict("nvidia"); !prediction.Critical || !tm.PredictedCritical("nvidia") {
+		t.Errorf("Expected nvidia predicted critical, got %+v", prediction)
+	}
+}
+
+func TestThermalMonitor_Predict_Samples(t *testing.T) {
+	tm := NewThermalMonitor(&ThermalConfig{TempWarning: 70, TempCritical: 85, PredictionSamples: 3}, 5*time.Second)
+
+	// A past spike falls out of the last 3 samples, leaving a flat trend
+	setTrend(tm, "cpu", time.Second, 50, 80, 60, 60, 60)
+	prediction, _ := tm.Predict("cpu")
+	if prediction.Slope != 0 || prediction.Critical {
+		t.Errorf("Expected a flat trend over the last 3 readings, got %+v", prediction)
+	}
+}
+
+func TestThermalMonitor_Predict_UntimedReadings(t *testing.T) {
+	tm := NewThermalMonitor(nil, 5*time.Second)
+
+	tm.SetState("npu", &ThermalState{Temperature: 40})
+	tm.SetState("npu", &ThermalState{Temperature: 84})
+	if _, ok := tm.Predict("npu"); ok {
+		t.Error("Expected readings without UpdatedAt to be left off the trend")
+	}
+}
+
+func TestThermalMonitor_OnPrediction(t *testing.T) {
+	tm := NewThermalMonitor(nil, 5*time.Second)
+
+	var events []Prediction
+	tm.OnPrediction(func(p Prediction) { events = append(events, p) })
+
+	setTrend(tm, "nvidia", 5*time.Second, 60, 60, 60)
+	if len(events) != 0 {
+		t.Fatalf("Expected no event for a steady temperature, got %+v", events)
+	}
+
+	setTrend(tm, "nvidia", 5*time.Second, 70, 75, 80)
+	if len(events) != 1 || !events[0].Critical || events[0].Hardware != "nvidia" {
+		t.Fatalf("Expected one critical prediction for nvidia, got %+v", events)
+	}
+	if events[0].TimeToCritical <= 0 || events[0].TimeToCritical > time.Minute {
+		t.Errorf("Expected critical within the minute, got %v", events[0].TimeToCritical)
+	}
+
+	setTrend(tm, "nvidia", 5*time.Second, 70, 65, 60)
+	if len(events) != 2 || events[1].Critical {
+		t.Errorf("Expected the prediction cleared once cooling, got %+v", events)
+	}
+}
+
+func TestThermalMonitor_PredictedHeadroomUsed(t *testing.T) {
+	tm := NewThermalMonitor(nil, 5*time.Second) // warning 70°C, critical 85°C
+
+	if _, ok := tm.PredictedHeadroomUsed("igpu"); ok {
+		t.Error("Expected unknown hardware to report no headroom")
+	}
+
+	// Steady at 75°C: a third of the headroom, as now
+	setTrend(tm, "igpu", 10*time.Second, 75, 75)
+	used, _ := tm.PredictedHeadroomUsed("igpu")
+	now, _ := tm.HeadroomUsed("igpu")
+	if used != now {
+		t.Errorf("Expected a steady temperature to use the current headroom %v, got %v", now, used)
+	}
+
+	// Heating 0.1°C/s at 71°C projects 77°C in a minute
+	setTrend(tm, "cpu", 10*time.Second, 70, 71)
+	used, _ = tm.PredictedHeadroomUsed("cpu")
+	if want := 7.0 / 15; used < want-0.001 || used > want+0.001 {
+		t.Errorf("Expected the projected headroom %v, got %v", want, used)
+	}
+
+	// Cooling never reports less than the current reading
+	setTrend(tm, "nvidia", 10*time.Second, 80, 78)
+	used, _ = tm.PredictedHeadroomUsed("nvidia")
+	now, _ = tm.HeadroomUsed("nvidia")
+	if used != now {
+		t.Errorf("Expected a cooling device to use the current headroom %v, got %v", now, used)
+	}
+}
@@ -0,0 +1,301 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// ErrRejected marks a batch the sink will never accept (e.g. HTTP 400).
+// The exporter drops such batches instead of retrying them.
+var ErrRejected = errors.New("usage export rejected")
+
+// Sink delivers batches of usage records to an external system
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// ExporterConfig controls buffering and retry behaviour
+type ExporterConfig struct {
+	// BufferSize caps queued records; the oldest are dropped when full
+	BufferSize int
+
+	// BatchSize is the maximum number of records per sink write
+	BatchSize int
+
+	// FlushInterval is how often partial batches are written
+	FlushInterval time.Duration
+
+	// InitialBackoff and MaxBackoff bound the retry delay after a failed write
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultExporterConfig returns sensible defaults for a single proxy
+func DefaultExporterConfig() ExporterConfig {
+	return ExporterConfig{
+		BufferSize:     10000,
+		BatchSize:      100,
+		FlushInterval:  5 * time.Second,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+	}
+}
+
+// ExportStats reports exporter delivery counters
+type ExportStats struct {
+	Sink     string `json:"sink"`
+	Queued   int    `json:"queued"`
+	Exported int64  `json:"exported"`
+	Dropped  int64  `json:"dropped"`
+	Failures int64  `json:"failures"`
+}
+
+// Exporter buffers usage records and delivers them to a sink, retrying
+// failed writes with exponential backoff. Records are only removed from the
+// buffer once the sink accepts them, so transient outages lose nothing unless
+// the buffer overflows.
+type Exporter struct {
+	sink Sink
+	cfg  ExporterConfig
+
+	mu       sync.Mutex
+	buf      []Record
+	head     uint64 // sequence number of buf[0]
+	exported int64
+	dropped  int64
+	failures int64
+
+	notify chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	final  context.Context
+}
+
+// NewExporter creates a new exporter for the sink
+func NewExporter(sink Sink, cfg ExporterConfig) *Exporter {
+	def := DefaultExporterConfig()
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = def.BufferSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = def.FlushInterval
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = def.InitialBackoff
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+
+	return &Exporter{
+		sink:   sink,
+		cfg:    cfg,
+		notify: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Enqueue adds a record to the export buffer. It never blocks; pass it to
+// Recorder.Subscribe.
+func (e *Exporter) Enqueue(rec Record) {
+	e.mu.Lock()
+	e.buf = append(e.buf, rec)
+	if over := len(e.buf) - e.cfg.BufferSize; over > 0 {
+		e.buf = e.buf[over:]
+		e.head += uint64(over)
+		e.dropped += int64(over)
+	}
+	full := len(e.buf) >= e.cfg.BatchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start runs the delivery loop in the background
+func (e *Exporter) Start() {
+	go e.run()
+}
+
+// Stop flushes queued records until ctx expires, then closes the sink
+func (e *Exporter) Stop(ctx context.Context) error {
+	e.final = ctx
+	close(e.stop)
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+	return e.sink.Close()
+}
+
+// Stats returns delivery counters
+func (e *Exporter) Stats() ExportStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return ExportStats{
+		Sink:     e.sink.Name(),
+		Queued:   len(e.buf),
+		Exported: e.exported,
+		Dropped:  e.dropped,
+		Failures: e.failures,
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	backoff := e.cfg.InitialBackoff
+	for {
+		select {
+		case <-e.stop:
+			e.drain()
+			return
+		case <-ticker.C:
+		case <-e.notify:
+		}
+
+		for {
+			ok, more := e.flushBatch(context.Background())
+			if !ok {
+				// Wait before retrying, but stay responsive to shutdown
+				select {
+				case <-e.stop:
+					e.drain()
+					return
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+				if backoff > e.cfg.MaxBackoff {
+					backoff = e.cfg.MaxBackoff
+				}
+				continue
+			}
+			backoff = e.cfg.InitialBackoff
+			if !more {
+				break
+			}
+		}
+	}
+}
+
+// drain writes everything left in the buffer until the stop context expires
+func (e *Exporter) drain() {
+	ctx := e.final
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for ctx.Err() == nil {
+		ok, more := e.flushBatch(ctx)
+		if !ok {
+			select {
+			case <-ctx.Done():
+			case <-time.After(e.cfg.InitialBackoff):
+			}
+			continue
+		}
+		if !more {
+			return
+		}
+	}
+
+	if stats := e.Stats(); stats.Queued > 0 && logging.Logger != nil {
+		logging.Logger.Warn("Usage export stopped with undelivered records",
+			zap.String("sink", stats.Sink),
+			zap.Int("queued", stats.Queued),
+		)
+	}
+}
+
+// flushBatch writes the oldest batch. ok is false when the write should be
+// retried; more reports whether further records are queued.
+func (e *Exporter) flushBatch(ctx context.Context) (ok bool, more bool) {
+	e.mu.Lock()
+	n := len(e.buf)
+	if n == 0 {
+		e.mu.Unlock()
+		return true, false
+	}
+	if n > e.cfg.BatchSize {
+		n = e.cfg.BatchSize
+	}
+	batch := make([]Record, n)
+	copy(batch, e.buf[:n])
+	start := e.head
+	e.mu.Unlock()
+
+	// A panicking sink is treated as a retryable failure
+	var err error
+	if middleware.Safe(middleware.ScopeBackground, "usage-export", func() { err = e.sink.Write(ctx, batch) }) {
+		err = fmt.Errorf("sink %s panicked", e.sink.Name())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err != nil && !errors.Is(err, ErrRejected) {
+		e.failures++
+		if logging.Logger != nil {
+			logging.Logger.Warn("Usage export failed, will retry",
+				zap.String("sink", e.sink.Name()),
+				zap.Int("records", n),
+				zap.Error(err),
+			)
+		}
+		return false, true
+	}
+
+	if err != nil {
+		e.dropped += int64(n)
+		if logging.Logger != nil {
+			logging.Logger.Error("Usage export rejected, dropping batch",
+				zap.String("sink", e.sink.Name()),
+				zap.Int("records", n),
+				zap.Error(err),
+			)
+		}
+	} else {
+		e.exported += int64(n)
+	}
+
+	// Overflow may have evicted part of the batch while the write was in flight
+	if end := start + uint64(n); end > e.head {
+		remove := int(end - e.head)
+		if remove > len(e.buf) {
+			remove = len(e.buf)
+		}
+		e.buf = e.buf[remove:]
+		e.head += uint64(remove)
+	}
+	return true, len(e.buf) > 0
+}
+
+// StatsHandler serves exporter delivery counters as JSON
+func (e *Exporter) StatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(e.Stats())
+	}
+}
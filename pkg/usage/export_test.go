@@ -0,0 +1,169 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink fails the first failN writes and records delivered batches
+type fakeSink struct {
+	mu      sync.Mutex
+	failN   int
+	err     error
+	writes  int
+	records []Record
+}
+
+func (f *fakeSink) Name() string { return "fake" }
+func (f *fakeSink) Close() error { return nil }
+
+func (f *fakeSink) Write(ctx context.Context, records []Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes++
+	if f.writes <= f.failN {
+		if f.err != nil {
+			return f.err
+		}
+		return fmt.Errorf("collector unavailable")
+	}
+	f.records = append(f.records, records...)
+	return nil
+}
+
+func (f *fakeSink) delivered() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.records)
+}
+
+func testExporterConfig() ExporterConfig {
+	return ExporterConfig{
+		BatchSize:      2,
+		FlushInterval:  10 * time.Millisecond,
+		InitialBackoff: 5 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for condition")
+}
+
+func TestExporter_RetriesUntilDelivered(t *testing.T) {
+	sink := &fakeSink{failN: 3}
+	e := NewExporter(sink, testExporterConfig())
+	e.Start()
+
+	for i := 0; i < 5; i++ {
+		e.Enqueue(Record{RequestID: fmt.Sprintf("req-%d", i)})
+	}
+
+	waitFor(t, func() bool { return sink.delivered() == 5 })
+	if err := e.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	stats := e.Stats()
+	if stats.Exported != 5 || stats.Failures != 3 || stats.Dropped != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	for i, rec := range sink.records {
+		if rec.RequestID != fmt.Sprintf("req-%d", i) {
+			t.Errorf("Expected records in order, got %s at %d", rec.RequestID, i)
+		}
+	}
+}
+
+func TestExporter_RejectedBatchDropped(t *testing.T) {
+	sink := &fakeSink{failN: 1, err: fmt.Errorf("%w: bad schema", ErrRejected)}
+	e := NewExporter(sink, testExporterConfig())
+	e.Start()
+
+	e.Enqueue(Record{RequestID: "a"})
+	e.Enqueue(Record{RequestID: "b"})
+	e.Enqueue(Record{RequestID: "c"})
+
+	waitFor(t, func() bool { return sink.delivered() == 1 })
+	e.Stop(context.Background())
+
+	stats := e.Stats()
+	if stats.Dropped != 2 || stats.Exported != 1 {
+		t.Errorf("Expected rejected batch of 2 to be dropped, got %+v", stats)
+	}
+}
+
+func TestExporter_BufferOverflowDropsOldest(t *testing.T) {
+	sink := &fakeSink{}
+	cfg := testExporterConfig()
+	cfg.BufferSize = 3
+	e := NewExporter(sink, cfg)
+
+	// Not started: records accumulate
+	for i := 0; i < 5; i++ {
+		e.Enqueue(Record{RequestID: fmt.Sprintf("req-%d", i)})
+	}
+
+	stats := e.Stats()
+	if stats.Queued != 3 || stats.Dropped != 2 {
+		t.Fatalf("Expected 3 queued and 2 dropped, got %+v", stats)
+	}
+
+	e.Start()
+	if err := e.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if sink.delivered() != 3 || sink.records[0].RequestID != "req-2" {
+		t.Errorf("Expected newest 3 records to be flushed on stop, got %+v", sink.records)
+	}
+}
+
+func TestExporter_StopHonoursDeadline(t *testing.T) {
+	sink := &fakeSink{failN: 1000}
+	e := NewExporter(sink, testExporterConfig())
+	e.Start()
+	e.Enqueue(Record{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	e.Stop(ctx)
+	if time.Since(start) > time.Second {
+		t.Error("Expected Stop to return once the deadline passed")
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Error("Expected context deadline to be reached")
+	}
+	if e.Stats().Queued != 1 {
+		t.Errorf("Expected undelivered record to remain queued, got %+v", e.Stats())
+	}
+}
+
+func TestExporter_SubscribedToRecorder(t *testing.T) {
+	sink := &fakeSink{}
+	e := NewExporter(sink, testExporterConfig())
+	r := NewRecorder(DefaultConfig())
+	r.Subscribe(e.Enqueue)
+	e.Start()
+
+	r.Record(Record{Tenant: "lab-a"})
+
+	waitFor(t, func() bool { return sink.delivered() == 1 })
+	e.Stop(context.Background())
+	if sink.records[0].Tenant != "lab-a" {
+		t.Errorf("Expected recorded usage to be exported, got %+v", sink.records[0])
+	}
+}
@@ -0,0 +1,116 @@
+package usage
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/daoneill/ollama-proxy/pkg/rotating"
+)
+
+// FileSinkConfig configures a rotating JSONL or CSV file sink
+type FileSinkConfig struct {
+	Path       string
+	Format     string // "jsonl" (default) or "csv"
+	MaxSizeMB  int    // rotate when the file exceeds this size (0 = never)
+	MaxBackups int    // rotated files to keep as path.1 ... path.N
+}
+
+// FileSink appends usage records to a local file for billing pipelines
+// that pick up files (e.g. via a log shipper) rather than receive pushes
+type FileSink struct {
+	cfg  FileSinkConfig
+	mu   sync.Mutex
+	file *rotating.File
+}
+
+// NewFileSink opens (or creates) the export file
+func NewFileSink(cfg FileSinkConfig) (*FileSink, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("file sink requires a path")
+	}
+	if cfg.Format == "" {
+		cfg.Format = "jsonl"
+	}
+	if cfg.Format != "jsonl" && cfg.Format != "csv" {
+		return nil, fmt.Errorf("unsupported file sink format: %s", cfg.Format)
+	}
+
+	file, err := rotating.Open(rotating.Config{
+		Path:       cfg.Path,
+		Name:       "export file",
+		MaxSizeMB:  cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// New CSV files start with a header row
+	s := &FileSink{cfg: cfg, file: file}
+	if cfg.Format == "csv" && file.Size() == 0 {
+		if err := s.writeCSV(nil); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Name returns the sink name
+func (s *FileSink) Name() string {
+	return "file:" + s.cfg.Path
+}
+
+// Write appends the batch and syncs it to disk
+func (s *FileSink) Write(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.cfg.Format == "csv" {
+		rows := make([][]string, len(records))
+		for i, rec := range records {
+			rows[i] = rec.CSVRow()
+		}
+		err = s.writeCSV(rows)
+	} else {
+		err = s.writeJSONL(records)
+	}
+	if err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) writeJSONL(records []Record) error {
+	buf := make([]byte, 0, 256*len(records))
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrRejected, err)
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	_, err := s.file.Write(buf)
+	return err
+}
+
+// writeCSV writes rows, preceded by the header row when the file is new
+// or was just rotated
+func (s *FileSink) writeCSV(rows [][]string) error {
+	if s.file.Size() == 0 {
+		rows = append([][]string{RecordsCSVHeader}, rows...)
+	}
+	return csv.NewWriter(s.file).WriteAll(rows)
+}
+
+// Close closes the export file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,105 @@
+package usage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSink_JSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage", "records.jsonl")
+	sink, err := NewFileSink(FileSinkConfig{Path: path})
+	if err != nil {
+		t.Fatalf("NewFileSink failed: %v", err)
+	}
+
+	records := []Record{
+		{Time: time.Now(), Tenant: "lab-a", PromptTokens: 3},
+		{Time: time.Now(), Tenant: "lab-b", CompletionTokens: 9},
+	}
+	if err := sink.Write(context.Background(), records); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	sink.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open export: %v", err)
+	}
+	defer f.Close()
+
+	var got []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("Invalid JSONL line: %v", err)
+		}
+		got = append(got, rec)
+	}
+	if len(got) != 2 || got[1].Tenant != "lab-b" || got[1].CompletionTokens != 9 {
+		t.Errorf("Unexpected exported records: %+v", got)
+	}
+}
+
+func TestFileSink_CSVHeaderOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.csv")
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileSink(FileSinkConfig{Path: path, Format: "csv"})
+		if err != nil {
+			t.Fatalf("NewFileSink failed: %v", err)
+		}
+		sink.Write(context.Background(), []Record{{Tenant: "lab-a"}})
+		sink.Close()
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected header plus 2 rows across reopen, got %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "time,") || strings.HasPrefix(lines[2], "time,") {
+		t.Errorf("Expected a single header row, got %v", lines)
+	}
+}
+
+func TestFileSink_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "records.jsonl")
+	sink, err := NewFileSink(FileSinkConfig{Path: path, MaxSizeMB: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("NewFileSink failed: %v", err)
+	}
+	defer sink.Close()
+
+	// ~1.2MB per write forces a rotation each time
+	big := make([]Record, 6000)
+	for i := range big {
+		big[i] = Record{Tenant: "lab-a", Model: "llama3.1:8b-instruct", Backend: "ollama-nvidia"}
+	}
+	for i := 0; i < 4; i++ {
+		if err := sink.Write(context.Background(), big); err != nil {
+			t.Fatalf("Write %d failed: %v", i, err)
+		}
+	}
+
+	for _, name := range []string{"records.jsonl", "records.jsonl.1", "records.jsonl.2"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "records.jsonl.3")); !os.IsNotExist(err) {
+		t.Error("Expected backups beyond MaxBackups to be removed")
+	}
+}
+
+func TestNewFileSink_InvalidFormat(t *testing.T) {
+	if _, err := NewFileSink(FileSinkConfig{Path: filepath.Join(t.TempDir(), "x"), Format: "xml"}); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
@@ -0,0 +1,51 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// quotaError refuses an RPC with codes.ResourceExhausted, and returns the
+// limit and the seconds until it resets as retry-after and
+// x-quota-exceeded metadata, like the HTTP headers
+func (l *Ledger) quotaError(limit string, reset time.Time) (metadata.MD, error) {
+	md := metadata.Pairs(
+		"retry-after", strconv.Itoa(l.retryAfter(reset)),
+		"x-quota-exceeded", limit,
+	)
+	return md, status.Error(codes.ResourceExhausted,
+		fmt.Sprintf("Quota exceeded (%s), resets at %s", limit, reset.Format(time.RFC3339)))
+}
+
+// UnaryInterceptor refuses RPCs from keys that have used up their quota,
+// like Middleware. It must run after the interceptor that authenticates
+// the key.
+func (l *Ledger) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if limit, reset := l.check(ctx); limit != "" {
+			md, err := l.quotaError(limit, reset)
+			grpc.SetHeader(ctx, md)
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor refuses streaming RPCs like UnaryInterceptor
+func (l *Ledger) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if limit, reset := l.check(ss.Context()); limit != "" {
+			md, err := l.quotaError(limit, reset)
+			ss.SetHeader(md)
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,76 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestLedger_UnaryInterceptor(t *testing.T) {
+	now := time.Date(2026, 10, 15, 23, 59, 30, 0, time.UTC)
+	l := newTestLedger(auth.Quota{DailyRequests: 1}, &now)
+	interceptor := l.UnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/compute.v1.ComputeService/Generate"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	ctx := auth.WithKeyInfo(context.Background(), auth.APIKeyInfo{Name: "alice"})
+
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("Expected the first call allowed, got %v", err)
+	}
+	l.Observe(Record{Time: now, Key: "alice"})
+
+	_, err := interceptor(ctx, nil, info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted once the quota is used, got %v", err)
+	}
+
+	// Unauthenticated calls are not limited
+	if _, err := interceptor(context.Background(), nil, info, handler); err != nil {
+		t.Errorf("Expected unauthenticated call allowed, got %v", err)
+	}
+}
+
+// quotaStream is a server stream carrying a context and recording headers
+type quotaStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *quotaStream) Context() context.Context { return s.ctx }
+
+func (s *quotaStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestLedger_StreamInterceptor(t *testing.T) {
+	now := time.Date(2026, 10, 15, 23, 59, 30, 0, time.UTC)
+	l := newTestLedger(auth.Quota{}, &now)
+	info := &grpc.StreamServerInfo{FullMethod: "/compute.v1.ComputeService/GenerateStream"}
+	called := false
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+	quota := auth.Quota{DailyTokens: 10}
+	ss := &quotaStream{ctx: auth.WithKeyInfo(context.Background(), auth.APIKeyInfo{Name: "bob", Quota: &quota})}
+
+	l.Observe(Record{Time: now, Key: "bob", CompletionTokens: 10})
+	err := l.StreamInterceptor()(nil, ss, info, handler)
+	if status.Code(err) != codes.ResourceExhausted || called {
+		t.Fatalf("Expected the stream refused, got %v (handler called: %v)", err, called)
+	}
+	if got := ss.header.Get("x-quota-exceeded"); len(got) != 1 || got[0] != LimitDailyTokens {
+		t.Errorf("Expected x-quota-exceeded %s, got %v", LimitDailyTokens, got)
+	}
+	if got := ss.header.Get("retry-after"); len(got) != 1 || got[0] != "30" {
+		t.Errorf("Expected retry-after of 30 seconds, got %v", got)
+	}
+}
@@ -0,0 +1,289 @@
+package usage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/metrics"
+)
+
+// Quota limits, as reported when one is exceeded
+const (
+	LimitDailyTokens     = "daily_tokens"
+	LimitMonthlyTokens   = "monthly_tokens"
+	LimitDailyRequests   = "daily_requests"
+	LimitMonthlyRequests = "monthly_requests"
+)
+
+// Ledger keeps each API key's running usage for the current UTC day and
+// month. It enforces quotas against those totals and serves them to the
+// key's owner. Keys are told apart by ClientFingerprint, as names may
+// repeat. Feed it with Recorder.Subscribe.
+type Ledger struct {
+	defaults auth.Quota // for keys without their own quota
+	now      func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]*keyLedger
+}
+
+// keyLedger is one key's usage in the current periods
+type keyLedger struct {
+	day      time.Time
+	month    time.Time
+	daily    Summary
+	monthly  Summary
+	backends map[string]*Summary // this month
+}
+
+// NewLedger creates a ledger. defaults applies to keys without a Quota of
+// their own; a zero Quota leaves them unlimited.
+func NewLedger(defaults auth.Quota) *Ledger {
+	return &Ledger{
+		defaults: defaults,
+		now:      time.Now,
+		keys:     make(map[string]*keyLedger),
+	}
+}
+
+// dayStart and monthStart are the UTC period boundaries quotas reset at
+func dayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// MonthStart returns the start of the quota month containing t, the
+// oldest usage a ledger needs to be rebuilt from
+func MonthStart(t time.Time) time.Time {
+	return monthStart(t)
+}
+
+// ClientFingerprint identifies the credential of a record without storing
+// its ClientID, which for API keys is derived from the key itself
+func ClientFingerprint(info auth.APIKeyInfo) string {
+	sum := sha256.Sum256([]byte(info.ClientID()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// clientOf returns the ledger key of a record. Records kept from before
+// fingerprints were recorded fall back to their key's name.
+func clientOf(rec Record) string {
+	if rec.Client != "" {
+		return rec.Client
+	}
+	return ClientFingerprint(auth.APIKeyInfo{Name: rec.Key})
+}
+
+// entryLocked returns key's ledger, resetting periods that have ended
+func (l *Ledger) entryLocked(key string, now time.Time) *keyLedger {
+	kl, ok := l.keys[key]
+	if !ok {
+		kl = &keyLedger{backends: make(map[string]*Summary)}
+		l.keys[key] = kl
+	}
+	if day := dayStart(now); !kl.day.Equal(day) {
+		kl.day = day
+		kl.daily = Summary{}
+	}
+	if month := monthStart(now); !kl.month.Equal(month) {
+		kl.month = month
+		kl.monthly = Summary{}
+		kl.backends = make(map[string]*Summary)
+	}
+	return kl
+}
+
+// Observe adds a completed request to its key's totals. Records from
+// earlier periods are ignored, so restored history can be replayed.
+func (l *Ledger) Observe(rec Record) {
+	now := l.now()
+	if rec.Time.Before(monthStart(now)) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	kl := l.entryLocked(clientOf(rec), now)
+	kl.monthly.add(rec)
+	if !rec.Time.Before(kl.day) {
+		kl.daily.add(rec)
+	}
+	backend, ok := kl.backends[rec.Backend]
+	if !ok {
+		backend = &Summary{Group: map[string]string{"backend": rec.Backend}}
+		kl.backends[rec.Backend] = backend
+	}
+	backend.add(rec)
+}
+
+// quotaFor returns the quota of an authenticated key, if it has one
+func (l *Ledger) quotaFor(info auth.APIKeyInfo) (auth.Quota, bool) {
+	q := l.defaults
+	if info.Quota != nil {
+		q = *info.Quota
+	}
+	return q, q != auth.Quota{}
+}
+
+// Exceeded returns the first limit of q that key has used up and when it
+// resets, or "" when the key is within its quota. Monthly limits are
+// checked first since they reset last.
+func (l *Ledger) Exceeded(key auth.APIKeyInfo, q auth.Quota) (string, time.Time) {
+	now := l.now()
+	l.mu.Lock()
+	kl := l.entryLocked(ClientFingerprint(key), now)
+	daily, monthly := kl.daily, kl.monthly
+	nextDay, nextMonth := kl.day.AddDate(0, 0, 1), kl.month.AddDate(0, 1, 0)
+	l.mu.Unlock()
+
+	over := func(used, limit int64) bool { return limit > 0 && used >= limit }
+	switch {
+	case over(monthly.PromptTokens+monthly.CompletionTokens, q.MonthlyTokens):
+		return LimitMonthlyTokens, nextMonth
+	case over(monthly.Requests, q.MonthlyRequests):
+		return LimitMonthlyRequests, nextMonth
+	case over(daily.PromptTokens+daily.CompletionTokens, q.DailyTokens):
+		return LimitDailyTokens, nextDay
+	case over(daily.Requests, q.DailyRequests):
+		return LimitDailyRequests, nextDay
+	}
+	return "", time.Time{}
+}
+
+// check returns the quota limit the request's key has used up and when
+// it resets, or "" when the request may proceed. Unauthenticated requests
+// and keys without a quota are not limited.
+func (l *Ledger) check(ctx context.Context) (string, time.Time) {
+	info, ok := auth.KeyInfoFromContext(ctx)
+	if !ok {
+		return "", time.Time{}
+	}
+	q, limited := l.quotaFor(info)
+	if !limited {
+		return "", time.Time{}
+	}
+	limit, reset := l.Exceeded(info, q)
+	if limit != "" {
+		metrics.RecordRateLimited("quota")
+	}
+	return limit, reset
+}
+
+// retryAfter returns the whole seconds until reset, at least 1
+func (l *Ledger) retryAfter(reset time.Time) int {
+	seconds := int(math.Ceil(reset.Sub(l.now()).Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// Middleware rejects requests from keys that have used up their quota
+// with 429 and a Retry-After of the time until the quota resets. Usage is
+// counted when a request completes, so the request that crosses a limit
+// is served and the next one is rejected. It must run after
+// authentication; unauthenticated requests are not limited.
+func (l *Ledger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, reset := l.check(r.Context())
+		if limit == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(l.retryAfter(reset)))
+		w.Header().Set("X-Quota-Exceeded", limit)
+		http.Error(w, fmt.Sprintf("Quota exceeded (%s), resets at %s", limit, reset.Format(time.RFC3339)),
+			http.StatusTooManyRequests)
+	})
+}
+
+// PeriodUsage is a key's usage in one quota period
+type PeriodUsage struct {
+	From     time.Time `json:"from"`
+	ResetsAt time.Time `json:"resets_at"`
+	Summary
+	TokenQuota   int64 `json:"token_quota,omitempty"`
+	RequestQuota int64 `json:"request_quota,omitempty"`
+}
+
+// KeyUsage is the /v1/usage response
+type KeyUsage struct {
+	Key      string      `json:"key,omitempty"`
+	Day      PeriodUsage `json:"day"`
+	Month    PeriodUsage `json:"month"`
+	Backends []Summary   `json:"backends"` // this month
+}
+
+// Usage returns key's usage in the current day and month
+func (l *Ledger) Usage(key auth.APIKeyInfo, q auth.Quota) KeyUsage {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	kl := l.entryLocked(ClientFingerprint(key), now)
+	u := KeyUsage{
+		Key: key.Name,
+		Day: PeriodUsage{
+			From:         kl.day,
+			ResetsAt:     kl.day.AddDate(0, 0, 1),
+			Summary:      kl.daily,
+			TokenQuota:   q.DailyTokens,
+			RequestQuota: q.DailyRequests,
+		},
+		Month: PeriodUsage{
+			From:         kl.month,
+			ResetsAt:     kl.month.AddDate(0, 1, 0),
+			Summary:      kl.monthly,
+			TokenQuota:   q.MonthlyTokens,
+			RequestQuota: q.MonthlyRequests,
+		},
+		Backends: make([]Summary, 0, len(kl.backends)),
+	}
+	for _, s := range kl.backends {
+		u.Backends = append(u.Backends, *s)
+	}
+	sort.Slice(u.Backends, func(i, j int) bool {
+		if u.Backends[i].Requests != u.Backends[j].Requests {
+			return u.Backends[i].Requests > u.Backends[j].Requests
+		}
+		return u.Backends[i].Group["backend"] < u.Backends[j].Group["backend"]
+	})
+	return u
+}
+
+// Handler serves the caller's own usage and quota (GET /v1/usage). Without
+// authentication all requests share the anonymous key.
+func (l *Ledger) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var q auth.Quota
+		info, ok := auth.KeyInfoFromContext(r.Context())
+		if ok {
+			q, _ = l.quotaFor(info)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(l.Usage(info, q))
+	}
+}
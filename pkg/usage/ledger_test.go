@@ -0,0 +1,198 @@
+package usage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+)
+
+// newTestLedger returns a ledger whose clock is at now
+func newTestLedger(defaults auth.Quota, now *time.Time) *Ledger {
+	l := NewLedger(defaults)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestLedger_PeriodsReset(t *testing.T) {
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	l := newTestLedger(auth.Quota{}, &now)
+
+	l.Observe(Record{Time: now.AddDate(0, 0, -40), Key: "alice", PromptTokens: 999}) // last month
+	l.Observe(Record{Time: now.Add(-24 * time.Hour), Key: "alice", PromptTokens: 10, Backend: "gpu"})
+	l.Observe(Record{Time: now, Key: "alice", PromptTokens: 5, CompletionTokens: 5, Backend: "npu"})
+	l.Observe(Record{Time: now, Key: "bob", PromptTokens: 100})
+
+	u := l.Usage(auth.APIKeyInfo{Name: "alice"}, auth.Quota{})
+	if u.Day.Requests != 1 || u.Day.PromptTokens != 5 {
+		t.Errorf("Expected one request today, got %+v", u.Day.Summary)
+	}
+	if u.Month.Requests != 2 || u.Month.PromptTokens != 15 {
+		t.Errorf("Expected two requests this month, got %+v", u.Month.Summary)
+	}
+	if len(u.Backends) != 2 {
+		t.Errorf("Expected usage on two backends, got %+v", u.Backends)
+	}
+
+	// Both periods roll over at midnight UTC on the first
+	now = now.Add(2 * time.Hour)
+	u = l.Usage(auth.APIKeyInfo{Name: "alice"}, auth.Quota{})
+	if u.Day.Requests != 0 || u.Month.Requests != 0 || len(u.Backends) != 0 {
+		t.Errorf("Expected usage to reset for the new month, got %+v", u)
+	}
+	if want := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC); !u.Month.ResetsAt.Equal(want) {
+		t.Errorf("Expected month to reset at %v, got %v", want, u.Month.ResetsAt)
+	}
+}
+
+func TestLedger_Exceeded(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	l := newTestLedger(auth.Quota{}, &now)
+	q := auth.Quota{DailyTokens: 100, MonthlyRequests: 3}
+
+	l.Observe(Record{Time: now, Key: "alice", PromptTokens: 60})
+	if limit, _ := l.Exceeded(auth.APIKeyInfo{Name: "alice"}, q); limit != "" {
+		t.Errorf("Expected alice within quota, got %s", limit)
+	}
+
+	l.Observe(Record{Time: now, Key: "alice", CompletionTokens: 40})
+	limit, reset := l.Exceeded(auth.APIKeyInfo{Name: "alice"}, q)
+	if limit != LimitDailyTokens || !reset.Equal(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected daily token limit until midnight, got %s %v", limit, reset)
+	}
+
+	// The monthly limit outlasts the daily one
+	l.Observe(Record{Time: now, Key: "alice"})
+	if limit, _ := l.Exceeded(auth.APIKeyInfo{Name: "alice"}, q); limit != LimitMonthlyRequests {
+		t.Errorf("Expected monthly request limit, got %s", limit)
+	}
+
+	now = now.AddDate(0, 0, 1)
+	if limit, _ := l.Exceeded(auth.APIKeyInfo{Name: "alice"}, auth.Quota{DailyTokens: 100}); limit != "" {
+		t.Errorf("Expected daily quota to reset, got %s", limit)
+	}
+}
+
+func TestLedger_Middleware(t *testing.T) {
+	now := time.Date(2026, 10, 15, 23, 59, 30, 0, time.UTC)
+	l := newTestLedger(auth.Quota{DailyRequests: 1}, &now)
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(info *auth.APIKeyInfo) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		if info != nil {
+			req = req.WithContext(auth.WithKeyInfo(req.Context(), *info))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	alice := &auth.APIKeyInfo{Name: "alice", Enabled: true}
+
+	if rec := request(alice); rec.Code != http.StatusOK {
+		t.Fatalf("Expected first request allowed, got %d", rec.Code)
+	}
+	l.Observe(Record{Time: now, Key: "alice"})
+
+	rec := request(alice)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected quota to be enforced, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Expected Retry-After until midnight, got %q", got)
+	}
+	if got := rec.Header().Get("X-Quota-Exceeded"); got != LimitDailyRequests {
+		t.Errorf("Expected X-Quota-Exceeded daily_requests, got %q", got)
+	}
+
+	// A key's own quota replaces the default
+	alice.Quota = &auth.Quota{DailyRequests: 10}
+	if rec := request(alice); rec.Code != http.StatusOK {
+		t.Errorf("Expected the key's own quota to apply, got %d", rec.Code)
+	}
+
+	// Unauthenticated requests are not limited
+	if rec := request(nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected unauthenticated request allowed, got %d", rec.Code)
+	}
+}
+
+func TestLedger_Handler(t *testing.T) {
+	now := time.Now()
+	l := newTestLedger(auth.Quota{MonthlyTokens: 1000}, &now)
+	l.Observe(Record{Time: now, Key: "alice", Backend: "gpu", PromptTokens: 7, CompletionTokens: 3, EnergyWh: 0.5})
+	l.Observe(Record{Time: now, Key: "bob", Backend: "gpu", PromptTokens: 50})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/usage", nil)
+	req = req.WithContext(auth.WithKeyInfo(req.Context(), auth.APIKeyInfo{Name: "alice"}))
+	rec := httptest.NewRecorder()
+	l.Handler()(rec, req)
+
+	var u KeyUsage
+	if err := json.NewDecoder(rec.Body).Decode(&u); err != nil {
+		t.Fatalf("Failed to decode usage: %v", err)
+	}
+	if u.Key != "alice" || u.Month.PromptTokens != 7 || u.Month.CompletionTokens != 3 || u.Month.EnergyWh != 0.5 {
+		t.Errorf("Expected only alice's usage, got %+v", u)
+	}
+	if u.Month.TokenQuota != 1000 || u.Day.TokenQuota != 0 {
+		t.Errorf("Expected the default quota, got day %d month %d", u.Day.TokenQuota, u.Month.TokenQuota)
+	}
+	if len(u.Backends) != 1 || u.Backends[0].Group["backend"] != "gpu" {
+		t.Errorf("Expected per-backend usage, got %+v", u.Backends)
+	}
+}
+
+func TestLedger_HandlerNonAdminKey(t *testing.T) {
+	now := time.Now()
+	l := newTestLedger(auth.Quota{DailyRequests: 100}, &now)
+	batch := ClientFingerprint(auth.APIKeyInfo{ID: auth.HashKey("sk-batch")})
+	l.Observe(Record{Time: now, Key: "batch", Client: batch, Backend: "gpu", PromptTokens: 20, CompletionTokens: 5})
+	l.Observe(Record{Time: now, Key: "ops", Backend: "gpu", PromptTokens: 900})
+
+	// /v1/usage sits behind authentication only, not the admin permission
+	handler := auth.APIKeyMiddleware(auth.Config{
+		Enabled: true,
+		APIKeys: map[string]auth.APIKeyInfo{
+			"sk-batch": {Name: "batch", Enabled: true, Permissions: []string{"inference"}},
+			"sk-ops":   {Name: "ops", Enabled: true, Permissions: []string{auth.PermissionAdmin}},
+		},
+	})(l.Handler())
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/usage", nil)
+	req.Header.Set("Authorization", "Bearer sk-batch")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for a non-admin key, got %d", rec.Code)
+	}
+
+	var u KeyUsage
+	if err := json.NewDecoder(rec.Body).Decode(&u); err != nil {
+		t.Fatalf("Failed to decode usage: %v", err)
+	}
+	if u.Key != "batch" || u.Day.PromptTokens != 20 || u.Day.CompletionTokens != 5 || u.Day.RequestQuota != 100 {
+		t.Errorf("Expected the key's own usage and quota, got %+v", u)
+	}
+}
+
+func TestLedger_KeysSharingAName(t *testing.T) {
+	now := time.Now()
+	l := newTestLedger(auth.Quota{DailyRequests: 1}, &now)
+	first := auth.APIKeyInfo{ID: "key-one", Name: "app", Enabled: true}
+	second := auth.APIKeyInfo{ID: "key-two", Name: "app", Enabled: true}
+
+	l.Observe(Record{Time: now, Key: "app", Client: ClientFingerprint(first)})
+	if limit, _ := l.Exceeded(first, auth.Quota{DailyRequests: 1}); limit != LimitDailyRequests {
+		t.Errorf("Expected the first key over its quota, got %q", limit)
+	}
+	if limit, _ := l.Exceeded(second, auth.Quota{DailyRequests: 1}); limit != "" {
+		t.Errorf("Expected a key sharing the name to keep its own quota, got %q", limit)
+	}
+	if u := l.Usage(second, auth.Quota{}); u.Key != "app" || u.Day.Requests != 0 {
+		t.Errorf("Expected no usage for the second key, got %+v", u.Day.Summary)
+	}
+}
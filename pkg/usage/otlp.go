@@ -0,0 +1,192 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTLPSinkConfig configures export as OpenTelemetry log records
+type OTLPSinkConfig struct {
+	// Endpoint is the OTLP/HTTP logs URL, e.g. http://collector:4318/v1/logs
+	Endpoint string
+
+	// Headers are added to every request (e.g. authentication)
+	Headers map[string]string
+
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+
+	Timeout time.Duration
+}
+
+// OTLPSink pushes usage records to an OpenTelemetry collector using the
+// OTLP/HTTP JSON encoding, one log record per request
+type OTLPSink struct {
+	cfg    OTLPSinkConfig
+	client *http.Client
+}
+
+// NewOTLPSink creates a new OTLP logs sink
+func NewOTLPSink(cfg OTLPSinkConfig) (*OTLPSink, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("otlp sink requires an endpoint")
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "ollama-proxy"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &OTLPSink{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// Name returns the sink name
+func (s *OTLPSink) Name() string {
+	return "otlp:" + s.cfg.Endpoint
+}
+
+// Write posts the batch as a single ExportLogsServiceRequest
+func (s *OTLPSink) Write(ctx context.Context, records []Record) error {
+	body, err := json.Marshal(s.encode(records))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp export failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode >= 500:
+		// Retryable per the OTLP/HTTP specification
+		return fmt.Errorf("otlp export failed: status %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: otlp status %d", ErrRejected, resp.StatusCode)
+	}
+}
+
+// Close is a no-op; the HTTP client has nothing to release
+func (s *OTLPSink) Close() error {
+	return nil
+}
+
+// OTLP JSON types (subset of opentelemetry-proto logs/v1)
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // int64 is a string in proto3 JSON
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	SeverityText string         `json:"severityText"`
+	Body         otlpValue      `json:"body"`
+	Attributes   []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+func otlpString(key, v string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue{StringValue: &v}}
+}
+
+func otlpInt(key string, v int64) otlpKeyValue {
+	s := strconv.FormatInt(v, 10)
+	return otlpKeyValue{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+func otlpDouble(key string, v float64) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue{DoubleValue: &v}}
+}
+
+func (s *OTLPSink) encode(records []Record) otlpLogsRequest {
+	scope := otlpScopeLogs{LogRecords: make([]otlpLogRecord, 0, len(records))}
+	scope.Scope.Name = "ollama-proxy/usage"
+
+	body := "usage"
+	for _, rec := range records {
+		attributes := []otlpKeyValue{
+			otlpString("usage.request_id", rec.RequestID),
+			otlpString("usage.tenant", rec.Tenant),
+			otlpString("usage.key", rec.Key),
+			otlpString("usage.endpoint", rec.Endpoint),
+			otlpString("usage.model", rec.Model),
+			otlpString("usage.backend", rec.Backend),
+			otlpString("usage.hardware", rec.Hardware),
+			otlpString("usage.status", rec.Status),
+			otlpInt("usage.prompt_tokens", rec.PromptTokens),
+			otlpInt("usage.completion_tokens", rec.CompletionTokens),
+			otlpInt("usage.duration_ms", rec.DurationMs),
+			otlpDouble("usage.energy_wh", rec.EnergyWh),
+			otlpDouble("usage.cost_usd", rec.CostUSD),
+		}
+		keys := make([]string, 0, len(rec.Labels))
+		for k := range rec.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			attributes = append(attributes, otlpString("usage.label."+k, rec.Labels[k]))
+		}
+
+		scope.LogRecords = append(scope.LogRecords, otlpLogRecord{
+			TimeUnixNano: strconv.FormatInt(rec.Time.UnixNano(), 10),
+			SeverityText: "INFO",
+			Body:         otlpValue{StringValue: &body},
+			Attributes:   attributes,
+		})
+	}
+
+	resource := otlpResourceLogs{ScopeLogs: []otlpScopeLogs{scope}}
+	resource.Resource.Attributes = []otlpKeyValue{otlpString("service.name", s.cfg.ServiceName)}
+
+	return otlpLogsRequest{ResourceLogs: []otlpResourceLogs{resource}}
+}
@@ -0,0 +1,78 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOTLPSink_Write(t *testing.T) {
+	var got otlpLogsRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sink, err := NewOTLPSink(OTLPSinkConfig{
+		Endpoint: srv.URL + "/v1/logs",
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+	})
+	if err != nil {
+		t.Fatalf("NewOTLPSink failed: %v", err)
+	}
+
+	rec := Record{Time: time.Unix(1700000000, 0), Tenant: "lab-a", PromptTokens: 12, CostUSD: 0.01}
+	if err := sink.Write(context.Background(), []Record{rec}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if auth != "Bearer token" {
+		t.Errorf("Expected configured headers to be sent, got %q", auth)
+	}
+	if len(got.ResourceLogs) != 1 || len(got.ResourceLogs[0].ScopeLogs[0].LogRecords) != 1 {
+		t.Fatalf("Unexpected OTLP payload: %+v", got)
+	}
+	logRec := got.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if logRec.TimeUnixNano != "1700000000000000000" {
+		t.Errorf("Unexpected timestamp: %s", logRec.TimeUnixNano)
+	}
+
+	attrs := make(map[string]otlpValue)
+	for _, kv := range logRec.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if v := attrs["usage.tenant"].StringValue; v == nil || *v != "lab-a" {
+		t.Errorf("Expected tenant attribute, got %+v", attrs["usage.tenant"])
+	}
+	if v := attrs["usage.prompt_tokens"].IntValue; v == nil || *v != "12" {
+		t.Errorf("Expected prompt_tokens as int string, got %+v", attrs["usage.prompt_tokens"])
+	}
+}
+
+func TestOTLPSink_StatusHandling(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink, _ := NewOTLPSink(OTLPSinkConfig{Endpoint: srv.URL})
+
+	err := sink.Write(context.Background(), []Record{{}})
+	if err == nil || errors.Is(err, ErrRejected) {
+		t.Errorf("Expected retryable error for 503, got %v", err)
+	}
+
+	status = http.StatusBadRequest
+	err = sink.Write(context.Background(), []Record{{}})
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("Expected ErrRejected for 400, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package usage
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/labels"
+	"github.com/daoneill/ollama-proxy/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// Record is a single completed request for accounting purposes
+type Record struct {
+	Time             time.Time `json:"time"`
+	RequestID        string    `json:"request_id,omitempty"`
+	Tenant           string    `json:"tenant,omitempty"`
+	Key              string    `json:"key,omitempty"`    // API key name, never the key itself
+	Client           string    `json:"client,omitempty"` // see ClientFingerprint
+	Endpoint         string    `json:"endpoint"`
+	Model            string    `json:"model"`
+	Backend          string    `json:"backend"`
+	Hardware         string    `json:"hardware,omitempty"`
+	Status           string    `json:"status"` // "success" or "error"
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	DurationMs       int64     `json:"duration_ms"`
+	EnergyWh         float64   `json:"energy_wh"`
+	CostUSD          float64   `json:"cost_usd"`
+
+	// Labels the client attached with X-Labels
+	Labels labels.Labels `json:"labels,omitempty"`
+}
+
+// TotalTokens returns prompt plus completion tokens
+func (r Record) TotalTokens() int64 {
+	return r.PromptTokens + r.CompletionTokens
+}
+
+// Config controls retention and pricing for usage records
+type Config struct {
+	// MaxRecords caps how many records are kept in memory
+	MaxRecords int
+
+	// Retention drops records older than this (0 = keep until MaxRecords)
+	Retention time.Duration
+
+	// PricePerKWh converts energy into cost for local hardware
+	PricePerKWh float64
+
+	// PricePer1KTokens sets a per-backend token price (e.g. cloud backends)
+	PricePer1KTokens map[string]float64
+
+	// Store persists every record (nil = memory only)
+	Store Store
+}
+
+// DefaultConfig returns in-memory defaults (30 days, 100k records)
+func DefaultConfig() Config {
+	return Config{
+		MaxRecords: 100000,
+		Retention:  30 * 24 * time.Hour,
+	}
+}
+
+// Recorder keeps recent usage records and fans them out to subscribers
+type Recorder struct {
+	mu          sync.RWMutex
+	cfg         Config
+	records     []Record
+	subscribers []func(Record)
+}
+
+// NewRecorder creates a new usage recorder
+func NewRecorder(cfg Config) *Recorder {
+	if cfg.MaxRecords <= 0 {
+		cfg.MaxRecords = DefaultConfig().MaxRecords
+	}
+	return &Recorder{
+		cfg: cfg,
+	}
+}
+
+// defaultRecorder is swapped while requests may be recording to it
+var defaultRecorder atomic.Pointer[Recorder]
+
+func init() {
+	defaultRecorder.Store(NewRecorder(DefaultConfig()))
+}
+
+// Default returns the process-wide recorder used by the HTTP, WebSocket
+// and gRPC handlers
+func Default() *Recorder {
+	return defaultRecorder.Load()
+}
+
+// SetDefault replaces the process-wide recorder
+func SetDefault(r *Recorder) {
+	if r != nil {
+		defaultRecorder.Store(r)
+	}
+}
+
+// Subscribe registers a callback invoked for every new record
+func (r *Recorder) Subscribe(fn func(Record)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// Record stores a usage record, filling in cost from the pricing config
+func (r *Recorder) Record(rec Record) {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+
+	r.mu.Lock()
+	if rec.CostUSD == 0 {
+		rec.CostUSD = r.costLocked(rec)
+	}
+
+	r.records = append(r.records, rec)
+	r.pruneLocked(rec.Time)
+
+	subscribers := make([]func(Record), len(r.subscribers))
+	copy(subscribers, r.subscribers)
+	r.mu.Unlock()
+
+	if r.cfg.Store != nil {
+		if err := r.cfg.Store.Append(rec); err != nil && logging.Logger != nil {
+			logging.Logger.Warn("Failed to persist usage record", zap.Error(err))
+		}
+	}
+
+	for _, fn := range subscribers {
+		fn(rec)
+	}
+}
+
+// Restore loads records since the given time from the store, keeping
+// those within retention in memory, and drops older ones from the store.
+// The loaded records are returned so running totals (e.g. quotas) can be
+// rebuilt from them.
+func (r *Recorder) Restore(since time.Time) ([]Record, error) {
+	if r.cfg.Store == nil {
+		return nil, nil
+	}
+	records, err := r.cfg.Store.Load(since)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.records = append(append(make([]Record, 0, len(records)+len(r.records)), records...), r.records...)
+	r.pruneLocked(time.Now())
+	r.mu.Unlock()
+
+	return records, r.cfg.Store.Prune(since)
+}
+
+func (r *Recorder) costLocked(rec Record) float64 {
+	cost := rec.EnergyWh / 1000 * r.cfg.PricePerKWh
+	if price, ok := r.cfg.PricePer1KTokens[rec.Backend]; ok {
+		cost += float64(rec.TotalTokens()) / 1000 * price
+	}
+	return cost
+}
+
+func (r *Recorder) pruneLocked(now time.Time) {
+	drop := 0
+	if over := len(r.records) - r.cfg.MaxRecords; over > 0 {
+		drop = over
+	}
+	if r.cfg.Retention > 0 {
+		cutoff := now.Add(-r.cfg.Retention)
+		for drop < len(r.records) && r.records[drop].Time.Before(cutoff) {
+			drop++
+		}
+	}
+	if drop > 0 {
+		r.records = append(r.records[:0:0], r.records[drop:]...)
+	}
+}
+
+// Query returns records with from <= Time < to
+func (r *Recorder) Query(from, to time.Time) []Record {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Record, 0)
+	for _, rec := range r.records {
+		if !rec.Time.Before(from) && rec.Time.Before(to) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// Len returns the number of stored records
+func (r *Recorder) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.records)
+}
@@ -0,0 +1,63 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorder_CostFromEnergyAndTokens(t *testing.T) {
+	r := NewRecorder(Config{
+		PricePerKWh:      0.30,
+		PricePer1KTokens: map[string]float64{"openai": 0.002},
+	})
+
+	r.Record(Record{Backend: "ollama-npu", EnergyWh: 10})
+	r.Record(Record{Backend: "openai", PromptTokens: 500, CompletionTokens: 1500})
+
+	records := r.Query(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if got := records[0].CostUSD; got < 0.0029 || got > 0.0031 {
+		t.Errorf("Expected energy cost 0.003, got %f", got)
+	}
+	if got := records[1].CostUSD; got < 0.0039 || got > 0.0041 {
+		t.Errorf("Expected token cost 0.004, got %f", got)
+	}
+}
+
+func TestRecorder_Retention(t *testing.T) {
+	r := NewRecorder(Config{MaxRecords: 3, Retention: time.Hour})
+	now := time.Now()
+
+	r.Record(Record{Time: now.Add(-2 * time.Hour), Model: "old"})
+	r.Record(Record{Time: now, Model: "a"})
+	if r.Len() != 1 {
+		t.Errorf("Expected expired record to be dropped, got %d records", r.Len())
+	}
+
+	r.Record(Record{Time: now, Model: "b"})
+	r.Record(Record{Time: now, Model: "c"})
+	r.Record(Record{Time: now, Model: "d"})
+	if r.Len() != 3 {
+		t.Errorf("Expected MaxRecords cap of 3, got %d", r.Len())
+	}
+	if first := r.Query(now.Add(-time.Minute), now.Add(time.Minute))[0]; first.Model != "b" {
+		t.Errorf("Expected oldest record to be evicted, first is %s", first.Model)
+	}
+}
+
+func TestRecorder_Subscribe(t *testing.T) {
+	r := NewRecorder(DefaultConfig())
+
+	var got []Record
+	r.Subscribe(func(rec Record) { got = append(got, rec) })
+	r.Record(Record{Tenant: "lab-a"})
+
+	if len(got) != 1 || got[0].Tenant != "lab-a" {
+		t.Errorf("Expected subscriber to receive record, got %+v", got)
+	}
+	if got[0].Time.IsZero() {
+		t.Error("Expected record time to be filled in")
+	}
+}
@@ -0,0 +1,335 @@
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GroupFields are the dimensions reports can be grouped by. Reports can
+// also be grouped by a request label with "label:<name>".
+var GroupFields = []string{"tenant", "key", "model", "backend", "hardware", "endpoint"}
+
+// labelField prefixes group fields that name a request label
+const labelField = "label:"
+
+// Summary aggregates usage for one group
+type Summary struct {
+	Group            map[string]string `json:"group,omitempty"`
+	Requests         int64             `json:"requests"`
+	Errors           int64             `json:"errors"`
+	PromptTokens     int64             `json:"prompt_tokens"`
+	CompletionTokens int64             `json:"completion_tokens"`
+	EnergyWh         float64           `json:"energy_wh"`
+	CostUSD          float64           `json:"cost_usd"`
+	AvgLatencyMs     float64           `json:"avg_latency_ms"`
+
+	totalLatencyMs int64
+}
+
+// Report is an aggregated usage report over a time window
+type Report struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	GroupBy []string  `json:"group_by"`
+	Totals  Summary   `json:"totals"`
+	Groups  []Summary `json:"groups"`
+}
+
+// fieldValue returns a record's value for a group field
+func fieldValue(rec Record, field string) string {
+	switch field {
+	case "tenant":
+		return rec.Tenant
+	case "key":
+		return rec.Key
+	case "model":
+		return rec.Model
+	case "backend":
+		return rec.Backend
+	case "hardware":
+		return rec.Hardware
+	case "endpoint":
+		return rec.Endpoint
+	}
+	if name, ok := strings.CutPrefix(field, labelField); ok {
+		return rec.Labels[name]
+	}
+	return ""
+}
+
+func (s *Summary) add(rec Record) {
+	s.Requests++
+	if rec.Status != "success" {
+		s.Errors++
+	}
+	s.PromptTokens += rec.PromptTokens
+	s.CompletionTokens += rec.CompletionTokens
+	s.EnergyWh += rec.EnergyWh
+	s.CostUSD += rec.CostUSD
+	s.totalLatencyMs += rec.DurationMs
+	s.AvgLatencyMs = float64(s.totalLatencyMs) / float64(s.Requests)
+}
+
+// Aggregate groups records by the given fields. Groups are sorted by cost,
+// then by request count, both descending.
+func Aggregate(records []Record, groupBy []string) ([]Summary, Summary) {
+	var totals Summary
+	groups := make(map[string]*Summary)
+	order := make([]string, 0)
+
+	for _, rec := range records {
+		totals.add(rec)
+
+		values := make([]string, len(groupBy))
+		for i, field := range groupBy {
+			values[i] = fieldValue(rec, field)
+		}
+		key := strings.Join(values, "\x00")
+
+		summary, ok := groups[key]
+		if !ok {
+			group := make(map[string]string, len(groupBy))
+			for i, field := range groupBy {
+				group[field] = values[i]
+			}
+			summary = &Summary{Group: group}
+			groups[key] = summary
+			order = append(order, key)
+		}
+		summary.add(rec)
+	}
+
+	out := make([]Summary, 0, len(order))
+	for _, key := range order {
+		out = append(out, *groups[key])
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].CostUSD != out[j].CostUSD {
+			return out[i].CostUSD > out[j].CostUSD
+		}
+		return out[i].Requests > out[j].Requests
+	})
+
+	return out, totals
+}
+
+// BuildReport queries the recorder and aggregates the results
+func (r *Recorder) BuildReport(from, to time.Time, groupBy []string) Report {
+	groups, totals := Aggregate(r.Query(from, to), groupBy)
+	return Report{
+		From:    from,
+		To:      to,
+		GroupBy: groupBy,
+		Totals:  totals,
+		Groups:  groups,
+	}
+}
+
+// ParseWindow parses a report window such as "1h", "24h", "7d" or "30d"
+func ParseWindow(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid window: %s", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window: %s", s)
+	}
+	return d, nil
+}
+
+// parseRange reads from/to/window query parameters (default: last 24h)
+func parseRange(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	to := time.Now()
+
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %s", v)
+		}
+		to = t
+	}
+
+	if v := q.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %s", v)
+		}
+		return from, to, nil
+	}
+
+	window := 24 * time.Hour
+	if v := q.Get("window"); v != "" {
+		d, err := ParseWindow(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		window = d
+	}
+	return to.Add(-window), to, nil
+}
+
+// parseGroupBy reads the group_by parameter (default: tenant)
+func parseGroupBy(r *http.Request) ([]string, error) {
+	v := r.URL.Query().Get("group_by")
+	if v == "" {
+		return []string{"tenant"}, nil
+	}
+
+	valid := make(map[string]bool, len(GroupFields))
+	for _, f := range GroupFields {
+		valid[f] = true
+	}
+
+	fields := strings.Split(v, ",")
+	for i, f := range fields {
+		f = strings.TrimSpace(f)
+		if !valid[f] && !(strings.HasPrefix(f, labelField) && len(f) > len(labelField)) {
+			return nil, fmt.Errorf("invalid group_by field: %s (valid: %s, label:<name>)", f, strings.Join(GroupFields, ", "))
+		}
+		fields[i] = f
+	}
+	return fields, nil
+}
+
+// ReportHandler serves /admin/reports.
+// Query parameters: window (e.g. 24h, 7d), from/to (RFC3339),
+// group_by (comma separated: tenant, key, model, backend, hardware, endpoint,
+// label:<name>) and format (json or csv).
+func (r *Recorder) ReportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		from, to, err := parseRange(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		groupBy, err := parseGroupBy(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report := r.BuildReport(from, to, groupBy)
+
+		if req.URL.Query().Get("format") == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", "attachment; filename=usage-report.csv")
+			writeReportCSV(w, report)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// RecordsHandler serves /admin/reports/records with raw records in the window
+func (r *Recorder) RecordsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		from, to, err := parseRange(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		records := r.Query(from, to)
+
+		if req.URL.Query().Get("format") == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", "attachment; filename=usage-records.csv")
+			WriteRecordsCSV(w, records)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"from":    from,
+			"to":      to,
+			"records": records,
+		})
+	}
+}
+
+func writeReportCSV(w http.ResponseWriter, report Report) {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	header := append([]string{}, report.GroupBy...)
+	header = append(header, "requests", "errors", "prompt_tokens", "completion_tokens", "energy_wh", "cost_usd", "avg_latency_ms")
+	cw.Write(header)
+
+	for _, g := range report.Groups {
+		row := make([]string, 0, len(header))
+		for _, field := range report.GroupBy {
+			row = append(row, g.Group[field])
+		}
+		row = append(row,
+			strconv.FormatInt(g.Requests, 10),
+			strconv.FormatInt(g.Errors, 10),
+			strconv.FormatInt(g.PromptTokens, 10),
+			strconv.FormatInt(g.CompletionTokens, 10),
+			strconv.FormatFloat(g.EnergyWh, 'f', 4, 64),
+			strconv.FormatFloat(g.CostUSD, 'f', 6, 64),
+			strconv.FormatFloat(g.AvgLatencyMs, 'f', 1, 64),
+		)
+		cw.Write(row)
+	}
+}
+
+// RecordsCSVHeader is the column order used for CSV record exports
+var RecordsCSVHeader = []string{
+	"time", "request_id", "tenant", "key", "endpoint", "model", "backend", "hardware",
+	"status", "prompt_tokens", "completion_tokens", "duration_ms", "energy_wh", "cost_usd", "labels",
+}
+
+// CSVRow formats a record using RecordsCSVHeader order
+func (r Record) CSVRow() []string {
+	return []string{
+		r.Time.UTC().Format(time.RFC3339Nano),
+		r.RequestID,
+		r.Tenant,
+		r.Key,
+		r.Endpoint,
+		r.Model,
+		r.Backend,
+		r.Hardware,
+		r.Status,
+		strconv.FormatInt(r.PromptTokens, 10),
+		strconv.FormatInt(r.CompletionTokens, 10),
+		strconv.FormatInt(r.DurationMs, 10),
+		strconv.FormatFloat(r.EnergyWh, 'f', 4, 64),
+		strconv.FormatFloat(r.CostUSD, 'f', 6, 64),
+		r.Labels.String(),
+	}
+}
+
+// WriteRecordsCSV writes records with a header row
+func WriteRecordsCSV(w http.ResponseWriter, records []Record) {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	cw.Write(RecordsCSVHeader)
+	for _, rec := range records {
+		cw.Write(rec.CSVRow())
+	}
+}
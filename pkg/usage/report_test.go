@@ -0,0 +1,165 @@
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleRecorder() *Recorder {
+	r := NewRecorder(DefaultConfig())
+	now := time.Now()
+	r.Record(Record{Time: now, Tenant: "lab-a", Model: "llama3", Backend: "ollama-nvidia", Status: "success", PromptTokens: 10, CompletionTokens: 20, DurationMs: 100, EnergyWh: 1, CostUSD: 0.5})
+	r.Record(Record{Time: now, Tenant: "lab-a", Model: "qwen", Backend: "ollama-npu", Status: "error", DurationMs: 300, CostUSD: 0.1})
+	r.Record(Record{Time: now, Tenant: "lab-b", Model: "llama3", Backend: "ollama-nvidia", Status: "success", PromptTokens: 5, CompletionTokens: 5, DurationMs: 50, EnergyWh: 0.5, CostUSD: 0.2})
+	r.Record(Record{Time: now.Add(-48 * time.Hour), Tenant: "lab-c", Status: "success"})
+	return r
+}
+
+func TestAggregate(t *testing.T) {
+	r := sampleRecorder()
+	groups, totals := Aggregate(r.Query(time.Now().Add(-time.Hour), time.Now().Add(time.Minute)), []string{"tenant"})
+
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 tenants, got %d", len(groups))
+	}
+	if groups[0].Group["tenant"] != "lab-a" {
+		t.Errorf("Expected highest-cost tenant first, got %s", groups[0].Group["tenant"])
+	}
+	if groups[0].Requests != 2 || groups[0].Errors != 1 {
+		t.Errorf("Unexpected lab-a summary: %+v", groups[0])
+	}
+	if groups[0].AvgLatencyMs != 200 {
+		t.Errorf("Expected avg latency 200, got %f", groups[0].AvgLatencyMs)
+	}
+	if totals.Requests != 3 || totals.PromptTokens != 15 {
+		t.Errorf("Unexpected totals: %+v", totals)
+	}
+}
+
+func TestAggregate_ByLabel(t *testing.T) {
+	records := []Record{
+		{Model: "llama3", Status: "success", PromptTokens: 10, Labels: map[string]string{"app": "docsbot", "team": "ml"}},
+		{Model: "llama3", Status: "success", PromptTokens: 5, Labels: map[string]string{"app": "docsbot"}},
+		{Model: "llama3", Status: "success", PromptTokens: 1},
+	}
+	groups, _ := Aggregate(records, []string{"label:app"})
+	if len(groups) != 2 {
+		t.Fatalf("Expected labelled and unlabelled groups, got %+v", groups)
+	}
+	if groups[0].Group["label:app"] != "docsbot" || groups[0].Requests != 2 || groups[0].PromptTokens != 15 {
+		t.Errorf("Unexpected docsbot summary: %+v", groups[0])
+	}
+	if groups[1].Group["label:app"] != "" {
+		t.Errorf("Expected unlabelled requests grouped under \"\", got %+v", groups[1])
+	}
+
+	if row := records[0].CSVRow(); row[len(row)-1] != "app=docsbot,team=ml" {
+		t.Errorf("Expected labels in the last CSV column, got %q", row[len(row)-1])
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	cases := map[string]time.Duration{
+		"1h":  time.Hour,
+		"24h": 24 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+	}
+	for in, want := range cases {
+		got, err := ParseWindow(in)
+		if err != nil || got != want {
+			t.Errorf("ParseWindow(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0d", "-1h", "week"} {
+		if _, err := ParseWindow(in); err == nil {
+			t.Errorf("Expected error for window %q", in)
+		}
+	}
+}
+
+func TestReportHandler_JSON(t *testing.T) {
+	r := sampleRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/reports?window=24h&group_by=tenant,model", nil)
+	w := httptest.NewRecorder()
+
+	r.ReportHandler()(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var report Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if len(report.Groups) != 3 {
+		t.Errorf("Expected 3 tenant/model groups, got %d", len(report.Groups))
+	}
+	if report.Totals.Requests != 3 {
+		t.Errorf("Expected the 48h-old record to be outside the window, got %d requests", report.Totals.Requests)
+	}
+
+	// 7 day window includes the older record
+	req = httptest.NewRequest(http.MethodGet, "/admin/reports?window=7d", nil)
+	w = httptest.NewRecorder()
+	r.ReportHandler()(w, req)
+	json.NewDecoder(w.Body).Decode(&report)
+	if report.Totals.Requests != 4 {
+		t.Errorf("Expected 4 requests over 7d, got %d", report.Totals.Requests)
+	}
+}
+
+func TestReportHandler_CSV(t *testing.T) {
+	r := sampleRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/reports?group_by=backend&format=csv", nil)
+	w := httptest.NewRecorder()
+
+	r.ReportHandler()(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Expected text/csv, got %s", ct)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected header plus 2 backend rows, got %d", len(rows))
+	}
+	if rows[0][0] != "backend" || rows[0][1] != "requests" {
+		t.Errorf("Unexpected header: %v", rows[0])
+	}
+}
+
+func TestReportHandler_BadParams(t *testing.T) {
+	r := sampleRecorder()
+	for _, query := range []string{"group_by=user", "group_by=label:", "window=soon", "from=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/reports?"+query, nil)
+		w := httptest.NewRecorder()
+		r.ReportHandler()(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestRecordsHandler_CSV(t *testing.T) {
+	r := sampleRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/reports/records?format=csv", nil)
+	w := httptest.NewRecorder()
+
+	r.RecordsHandler()(w, req)
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 4 {
+		t.Errorf("Expected header plus 3 records, got %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "time,request_id,tenant") {
+		t.Errorf("Unexpected header: %s", lines[0])
+	}
+}
@@ -0,0 +1,206 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists usage records so accounting and quotas survive restarts
+type Store interface {
+	// Append persists a single record
+	Append(rec Record) error
+
+	// Load returns stored records with Time >= since, oldest first
+	Load(since time.Time) ([]Record, error)
+
+	// Prune deletes records older than before
+	Prune(before time.Time) error
+
+	Close() error
+}
+
+// StoreFactory opens a store at a configured location (a path, a DSN, ...)
+type StoreFactory func(location string) (Store, error)
+
+var (
+	storesMu sync.RWMutex
+	stores   = map[string]StoreFactory{
+		"file": func(location string) (Store, error) { return NewFileStore(location) },
+	}
+)
+
+// RegisterStore makes a store selectable by name (server.reports.store.type)
+func RegisterStore(name string, f StoreFactory) {
+	if name == "" || f == nil {
+		return
+	}
+	storesMu.Lock()
+	stores[name] = f
+	storesMu.Unlock()
+}
+
+// HasStore reports whether a store name is known
+func HasStore(name string) bool {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+	_, ok := stores[name]
+	return ok
+}
+
+// OpenStore opens a registered store
+func OpenStore(name, location string) (Store, error) {
+	storesMu.RLock()
+	f, ok := stores[name]
+	storesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown usage store: %s", name)
+	}
+	return f(location)
+}
+
+// FileStore keeps usage records in an append-only JSONL file. Prune
+// rewrites the file without the expired records.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileStore opens (or creates) the store file
+func NewFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file store requires a path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create usage store directory: %w", err)
+	}
+
+	s := &FileStore{path: path}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open usage store: %w", err)
+	}
+	s.file = f
+	return nil
+}
+
+// Append writes the record as one line
+func (s *FileStore) Append(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("usage store is closed")
+	}
+	_, err = s.file.Write(append(data, '\n'))
+	return err
+}
+
+// Load reads records since the given time. Lines that cannot be parsed,
+// such as one cut short by a crash, are skipped.
+func (s *FileStore) Load(since time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadLocked(since)
+}
+
+func (s *FileStore) loadLocked(since time.Time) ([]Record, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage store: %w", err)
+	}
+	defer f.Close()
+
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var rec Record
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil || rec.Time.Before(since) {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage store: %w", err)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+// Prune rewrites the file with only the records at or after before. The
+// new file replaces the old one atomically.
+func (s *FileStore) Prune(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("usage store is closed")
+	}
+
+	records, err := s.loadLocked(before)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to prune usage store: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to prune usage store: %w", err)
+	}
+
+	// Appends must go to the new file
+	s.file.Close()
+	return s.open()
+}
+
+// Close closes the store file
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,95 @@
+package usage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore_AppendLoadPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage", "records.jsonl")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, model := range []string{"old", "a", "b"} {
+		if err := store.Append(Record{Time: now.Add(time.Duration(i-2) * time.Hour), Model: model}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// A line cut short by a crash is skipped
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o640)
+	f.WriteString(`{"time":"` + now.Format(time.RFC3339) + `","mod`)
+	f.Close()
+
+	records, err := store.Load(now.Add(-90 * time.Minute))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Model != "a" || records[1].Model != "b" {
+		t.Fatalf("Expected records a and b, got %+v", records)
+	}
+
+	if err := store.Prune(now.Add(-90 * time.Minute)); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	store.Append(Record{Time: now, Model: "c"})
+	records, _ = store.Load(time.Time{})
+	if len(records) != 3 || records[0].Model != "a" || records[2].Model != "c" {
+		t.Errorf("Expected pruned store to keep appending, got %+v", records)
+	}
+}
+
+func TestRecorder_Restore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	store, _ := NewFileStore(path)
+	now := time.Now()
+
+	r := NewRecorder(Config{Retention: time.Hour, Store: store})
+	r.Record(Record{Time: now.Add(-3 * time.Hour), Key: "alice", Model: "expired"})
+	r.Record(Record{Time: now.Add(-2 * time.Hour), Key: "alice", Model: "month"})
+	r.Record(Record{Time: now, Key: "alice", Model: "recent"})
+	store.Close()
+
+	// A restarted proxy gets its history back
+	store, _ = NewFileStore(path)
+	defer store.Close()
+	r = NewRecorder(Config{Retention: time.Hour, Store: store})
+	restored, err := r.Restore(now.Add(-150 * time.Minute))
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if len(restored) != 2 {
+		t.Errorf("Expected 2 restored records, got %d", len(restored))
+	}
+	if r.Len() != 1 {
+		t.Errorf("Expected only records within retention in memory, got %d", r.Len())
+	}
+	if all, _ := store.Load(time.Time{}); len(all) != 2 {
+		t.Errorf("Expected records before since to be pruned, got %d", len(all))
+	}
+}
+
+func TestOpenStore(t *testing.T) {
+	if !HasStore("file") {
+		t.Fatal("Expected the file store to be registered")
+	}
+	if _, err := OpenStore("nope", ""); err == nil {
+		t.Error("Expected an error for an unknown store")
+	}
+
+	RegisterStore("memory", func(string) (Store, error) { return nil, nil })
+	defer func() {
+		storesMu.Lock()
+		delete(stores, "memory")
+		storesMu.Unlock()
+	}()
+	if !HasStore("memory") {
+		t.Error("Expected the registered store to be known")
+	}
+}
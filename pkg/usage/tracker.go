@@ -0,0 +1,174 @@
+package usage
+
+import (
+	"context"
+	"time"
+
+	"github.com/daoneill/ollama-proxy/pkg/audit"
+	"github.com/daoneill/ollama-proxy/pkg/auth"
+	"github.com/daoneill/ollama-proxy/pkg/backends"
+	"github.com/daoneill/ollama-proxy/pkg/labels"
+	"github.com/daoneill/ollama-proxy/pkg/middleware"
+	"github.com/daoneill/ollama-proxy/pkg/router"
+)
+
+// EstimateTokens provides a rough token count estimate
+// This is a simple approximation: ~4 characters per token on average
+func EstimateTokens(text string) int32 {
+	if text == "" {
+		return 0
+	}
+	// Rough approximation: divide character count by 4
+	return int32((len(text) + 3) / 4)
+}
+
+// Tracker collects accounting data for a single request, whichever API
+// surface (HTTP, WebSocket or gRPC) serves it
+type Tracker struct {
+	// Start is when the request began; the duration is measured from it
+	Start time.Time
+
+	ctx          context.Context
+	decision     *router.RoutingDecision
+	endpoint     string
+	model        string
+	prompt       string // hashed into the audit log
+	promptTokens int32
+	stream       *StreamCounter
+}
+
+// Track starts accounting for a request; call Finish or FinishResponse
+// when it ends. The key and labels are read from ctx.
+func Track(ctx context.Context, decision *router.RoutingDecision, endpoint, model, prompt string) *Tracker {
+	return &Tracker{
+		Start:        time.Now(),
+		ctx:          ctx,
+		decision:     decision,
+		endpoint:     endpoint,
+		model:        model,
+		prompt:       prompt,
+		promptTokens: EstimateTokens(prompt),
+	}
+}
+
+// Stream counts the tokens read through reader, for FinishResponse
+func (t *Tracker) Stream(reader backends.StreamReader) backends.StreamReader {
+	t.stream = &StreamCounter{StreamReader: reader}
+	return t.stream
+}
+
+// FinishResponse records the request. Streamed requests use the tokens
+// counted by Stream and pass a nil resp.
+func (t *Tracker) FinishResponse(resp *backends.GenerateResponse, err error) {
+	switch {
+	case t.stream != nil:
+		t.Finish(t.stream.CompletionTokens(), t.stream.Stats, err)
+	case resp != nil:
+		tokens := EstimateTokens(resp.Response)
+		if resp.Stats != nil && resp.Stats.TokensGenerated > 0 {
+			tokens = resp.Stats.TokensGenerated
+		}
+		t.Finish(tokens, resp.Stats, err)
+	default:
+		t.Finish(0, nil, err)
+	}
+}
+
+// Finish records the request in the default recorder and the audit log.
+// Energy comes from the backend's stats when reported, otherwise it is
+// estimated from its power draw.
+func (t *Tracker) Finish(completionTokens int32, stats *backends.GenerationStats, err error) {
+	duration := time.Since(t.Start)
+
+	energyWh := 0.0
+	if stats != nil && stats.EnergyWh > 0 {
+		energyWh = float64(stats.EnergyWh)
+	} else {
+		energyWh = t.decision.Backend.PowerWatts() * duration.Hours()
+	}
+
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+
+	model := t.model
+	if t.decision.ModelUsed != "" {
+		model = t.decision.ModelUsed
+	}
+
+	rec := Record{
+		RequestID:        middleware.GetRequestID(t.ctx),
+		Endpoint:         t.endpoint,
+		Model:            model,
+		Backend:          t.decision.Backend.ID(),
+		Hardware:         t.decision.Backend.Hardware(),
+		Status:           status,
+		PromptTokens:     int64(t.promptTokens),
+		CompletionTokens: int64(completionTokens),
+		DurationMs:       duration.Milliseconds(),
+		EnergyWh:         energyWh,
+		Labels:           labels.FromContext(t.ctx),
+	}
+	if stats != nil && stats.CostUSD > 0 {
+		// Billed by a cloud backend from its reported usage
+		rec.CostUSD = stats.CostUSD
+	}
+	if info, ok := auth.KeyInfoFromContext(t.ctx); ok {
+		rec.Key = info.Name
+		rec.Client = ClientFingerprint(info)
+		rec.Tenant = auth.TenantFromContext(t.ctx)
+	}
+
+	Default().Record(rec)
+
+	auditRec := audit.Record{
+		Time:             time.Now(),
+		RequestID:        rec.RequestID,
+		Key:              rec.Key,
+		Tenant:           rec.Tenant,
+		Endpoint:         rec.Endpoint,
+		Model:            rec.Model,
+		Backend:          rec.Backend,
+		Status:           rec.Status,
+		LatencyMs:        rec.DurationMs,
+		PromptTokens:     rec.PromptTokens,
+		CompletionTokens: rec.CompletionTokens,
+		Labels:           rec.Labels,
+	}
+	if err != nil {
+		auditRec.Error = err.Error()
+	}
+	if th := t.decision.Thermal; th != nil {
+		auditRec.Thermal = &audit.Thermal{TemperatureC: th.Temperature, FanPercent: th.FanPercent, Throttling: th.Throttling}
+	}
+	audit.Default().Log(auditRec, t.prompt)
+}
+
+// StreamCounter counts streamed tokens and captures final stats
+type StreamCounter struct {
+	backends.StreamReader
+	Tokens int32
+	Stats  *backends.GenerationStats
+}
+
+func (r *StreamCounter) Recv() (*backends.StreamChunk, error) {
+	chunk, err := r.StreamReader.Recv()
+	if chunk != nil {
+		if chunk.Token != "" {
+			r.Tokens += EstimateTokens(chunk.Token)
+		}
+		if chunk.Stats != nil {
+			r.Stats = chunk.Stats
+		}
+	}
+	return chunk, err
+}
+
+// CompletionTokens prefers the backend's reported count over the estimate
+func (r *StreamCounter) CompletionTokens() int32 {
+	if r.Stats != nil && r.Stats.TokensGenerated > 0 {
+		return r.Stats.TokensGenerated
+	}
+	return r.Tokens
+}
@@ -0,0 +1,94 @@
+// OpenAI JavaScript SDK flows through the proxy's /v1 endpoints.
+import assert from "node:assert/strict";
+import { test } from "node:test";
+import OpenAI from "openai";
+
+const PROXY_URL = process.env.PROXY_URL ?? "http://localhost:8080";
+const CHAT_MODEL = process.env.CHAT_MODEL ?? "qwen2.5:0.5b";
+const EMBED_MODEL = process.env.EMBED_MODEL ?? "all-minilm";
+
+// Auth is off in the contract config; the SDK still needs a key
+const client = new OpenAI({ baseURL: `${PROXY_URL}/v1`, apiKey: "contract", maxRetries: 0 });
+
+const messages = [{ role: "user", content: "Reply with the single word: hello" }];
+
+test("models list", async () => {
+  const ids = [];
+  for await (const model of client.models.list()) ids.push(model.id);
+  assert.ok(ids.includes(CHAT_MODEL), `${CHAT_MODEL} not in ${ids}`);
+});
+
+test("chat completion", async () => {
+  const resp = await client.chat.completions.create({ model: CHAT_MODEL, messages, max_tokens: 16, temperature: 0 });
+
+  assert.equal(resp.object, "chat.completion");
+  assert.equal(resp.choices[0].message.role, "assistant");
+  assert.ok(resp.choices[0].message.content);
+  assert.ok(resp.usage.completion_tokens > 0);
+});
+
+test("chat completion stream", async () => {
+  const stream = await client.chat.completions.create({
+    model: CHAT_MODEL,
+    messages,
+    max_tokens: 16,
+    temperature: 0,
+    stream: true,
+  });
+
+  let text = "";
+  let finishReason = null;
+  for await (const chunk of stream) {
+    assert.equal(chunk.object, "chat.completion.chunk");
+    const choice = chunk.choices[0];
+    if (!choice) continue;
+    text += choice.delta.content ?? "";
+    finishReason = choice.finish_reason ?? finishReason;
+  }
+  assert.ok(text.trim());
+  assert.ok(["stop", "length"].includes(finishReason), `finish_reason ${finishReason}`);
+});
+
+test("chat completion stream helper", async () => {
+  const runner = client.beta.chat.completions.stream({ model: CHAT_MODEL, messages, max_tokens: 16 });
+  const completion = await runner.finalChatCompletion();
+  assert.ok(completion.choices[0].message.content);
+});
+
+test("embeddings", async () => {
+  const resp = await client.embeddings.create({ model: EMBED_MODEL, input: ["The quick brown fox", "jumps over the lazy dog"] });
+
+  assert.deepEqual(resp.data.map((d) => d.index), [0, 1]);
+  assert.ok(resp.data[0].embedding.length > 0);
+  assert.equal(resp.data[0].embedding.length, resp.data[1].embedding.length);
+});
+
+test("tools", { todo: "the proxy does not forward tools or return tool_calls yet" }, async () => {
+  const resp = await client.chat.completions.create({
+    model: CHAT_MODEL,
+    messages: [{ role: "user", content: "What is the weather in Paris?" }],
+    tools: [
+      {
+        type: "function",
+        function: {
+          name: "get_weather",
+          description: "Get the current weather in a city",
+          parameters: { type: "object", properties: { city: { type: "string" } }, required: ["city"] },
+        },
+      },
+    ],
+    temperature: 0,
+  });
+
+  const call = resp.choices[0].message.tool_calls?.[0];
+  assert.equal(resp.choices[0].finish_reason, "tool_calls");
+  assert.equal(call?.function.name, "get_weather");
+  assert.ok("city" in JSON.parse(call.function.arguments));
+});
+
+test("unknown model error", async () => {
+  await assert.rejects(
+    client.chat.completions.create({ model: "no-such-model:1b", messages, max_tokens: 4 }),
+    (err) => err instanceof OpenAI.APIError && err.status >= 400 && Boolean(err.error?.message),
+  );
+});
@@ -0,0 +1,20 @@
+import os
+
+import ollama
+import openai
+import pytest
+
+PROXY_URL = os.environ.get("PROXY_URL", "http://localhost:8080")
+CHAT_MODEL = os.environ.get("CHAT_MODEL", "qwen2.5:0.5b")
+EMBED_MODEL = os.environ.get("EMBED_MODEL", "all-minilm")
+
+
+@pytest.fixture(scope="session")
+def client():
+    # Auth is off in the contract config; the SDK still needs a key
+    return openai.OpenAI(base_url=PROXY_URL + "/v1", api_key="contract", max_retries=0)
+
+
+@pytest.fixture(scope="session")
+def ollama_client():
+    return ollama.Client(host=PROXY_URL)
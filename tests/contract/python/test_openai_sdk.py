@@ -0,0 +1,127 @@
+"""OpenAI Python SDK flows through the proxy's /v1 endpoints."""
+
+import json
+
+import openai
+import pytest
+
+from conftest import CHAT_MODEL, EMBED_MODEL
+
+PROMPT = [{"role": "user", "content": "Reply with the single word: hello"}]
+
+WEATHER_TOOL = {
+    "type": "function",
+    "function": {
+        "name": "get_weather",
+        "description": "Get the current weather in a city",
+        "parameters": {
+            "type": "object",
+            "properties": {"city": {"type": "string"}},
+            "required": ["city"],
+        },
+    },
+}
+
+
+def test_models_list(client):
+    ids = [m.id for m in client.models.list()]
+    assert CHAT_MODEL in ids
+
+
+def test_chat_completion(client):
+    resp = client.chat.completions.create(model=CHAT_MODEL, messages=PROMPT, max_tokens=16, temperature=0)
+
+    assert resp.object == "chat.completion"
+    assert resp.id
+    choice = resp.choices[0]
+    assert choice.message.role == "assistant"
+    assert choice.message.content
+    assert choice.finish_reason in ("stop", "length")
+    assert resp.usage.completion_tokens > 0
+    assert resp.usage.total_tokens == resp.usage.prompt_tokens + resp.usage.completion_tokens
+
+
+def test_chat_completion_stream(client):
+    stream = client.chat.completions.create(
+        model=CHAT_MODEL, messages=PROMPT, max_tokens=16, temperature=0, stream=True
+    )
+
+    chunks = list(stream)
+    assert chunks, "no chunks before [DONE]"
+    assert all(c.object == "chat.completion.chunk" for c in chunks)
+    assert len({c.id for c in chunks}) == 1, "chunk ids differ within one completion"
+    text = "".join(c.choices[0].delta.content or "" for c in chunks if c.choices)
+    assert text.strip()
+    finish = [c.choices[0].finish_reason for c in chunks if c.choices and c.choices[0].finish_reason]
+    assert finish and finish[-1] in ("stop", "length")
+
+
+@pytest.mark.xfail(strict=True, reason="the proxy reports finish_reason stop even when max_tokens cut the answer")
+def test_chat_completion_max_tokens(client):
+    resp = client.chat.completions.create(
+        model=CHAT_MODEL,
+        messages=[{"role": "user", "content": "Count from one to one hundred in words."}],
+        max_tokens=5,
+    )
+    assert resp.choices[0].finish_reason == "length"
+    assert resp.usage.completion_tokens <= 5
+
+
+def test_completion(client):
+    resp = client.completions.create(model=CHAT_MODEL, prompt="The capital of France is", max_tokens=8, temperature=0)
+
+    assert resp.object == "text_completion"
+    assert resp.choices[0].text
+    assert resp.usage.completion_tokens > 0
+
+
+def test_completion_stream(client):
+    stream = client.completions.create(
+        model=CHAT_MODEL, prompt="The capital of France is", max_tokens=8, temperature=0, stream=True
+    )
+    text = "".join(c.choices[0].text for c in stream if c.choices)
+    assert text.strip()
+
+
+@pytest.mark.parametrize("encoding_format", ["float", "base64"])
+def test_embeddings(client, encoding_format):
+    inputs = ["The quick brown fox", "jumps over the lazy dog"]
+    resp = client.embeddings.create(model=EMBED_MODEL, input=inputs, encoding_format=encoding_format)
+
+    assert resp.object == "list"
+    assert [d.index for d in resp.data] == [0, 1]
+    dims = {len(d.embedding) for d in resp.data}
+    assert len(dims) == 1 and dims.pop() > 0
+    assert all(isinstance(x, float) for x in resp.data[0].embedding)
+
+
+def test_embeddings_single_input(client):
+    resp = client.embeddings.create(model=EMBED_MODEL, input="hello")
+    assert len(resp.data) == 1
+    assert resp.data[0].embedding
+
+
+@pytest.mark.xfail(strict=True, reason="the proxy does not forward tools or return tool_calls yet")
+def test_chat_completion_tools(client):
+    resp = client.chat.completions.create(
+        model=CHAT_MODEL,
+        messages=[{"role": "user", "content": "What is the weather in Paris?"}],
+        tools=[WEATHER_TOOL],
+        tool_choice="auto",
+        temperature=0,
+    )
+
+    choice = resp.choices[0]
+    assert choice.finish_reason == "tool_calls"
+    call = choice.message.tool_calls[0]
+    assert call.type == "function" and call.function.name == "get_weather"
+    assert "city" in json.loads(call.function.arguments)
+
+
+def test_unknown_model_error(client):
+    with pytest.raises(openai.APIStatusError) as exc:
+        client.chat.completions.create(model="no-such-model:1b", messages=PROMPT, max_tokens=4)
+
+    assert exc.value.status_code >= 400
+    body = exc.value.body
+    assert isinstance(body, dict) and body.get("message"), f"error body not in OpenAI format: {body!r}"